@@ -3,27 +3,71 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
-	"time"
+	"strings"
 
+	"github.com/mabhi256/tasker/internal/app"
 	"github.com/mabhi256/tasker/internal/config"
 	"github.com/mabhi256/tasker/internal/database"
-	"github.com/mabhi256/tasker/internal/handler"
+	"github.com/mabhi256/tasker/internal/database/seed"
+	"github.com/mabhi256/tasker/internal/lib/shutdown"
 	"github.com/mabhi256/tasker/internal/logging"
-	"github.com/mabhi256/tasker/internal/repository"
-	"github.com/mabhi256/tasker/internal/router"
 	"github.com/mabhi256/tasker/internal/server"
 	"github.com/mabhi256/tasker/internal/service"
 )
 
-const DefaultContextTimeout = 30
-
 func main() {
-	cfg, err := config.LoadConfig()
+	modeFlag := flag.String("mode", "", "what to run: all, api or worker (overrides TASKER_MODE)")
+	configFlag := flag.String("config", "", "JSON config file to load (overrides "+config.FileEnv+")")
+	overrides := map[string]string{}
+	flag.Func("set", "set a config value by key, such as observability.logging.level=debug (overrides the config file and env, repeatable)",
+		func(s string) error {
+			key, value, ok := strings.Cut(s, "=")
+			if !ok || key == "" {
+				return errors.New("expected key=value")
+			}
+			overrides[key] = value
+			return nil
+		})
+	flag.Parse()
+
+	if flag.Arg(0) == "migrate" {
+		if err := runMigrate(flag.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, "migrate:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "seed" {
+		if err := runSeed(flag.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, "seed:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	sources := config.DefaultSources()
+	if *configFlag != "" {
+		sources.File = *configFlag
+	}
+	if *modeFlag != "" {
+		mode, err := config.ParseMode(*modeFlag)
+		if err != nil {
+			panic(err.Error())
+		}
+		overrides["mode"] = string(mode)
+	}
+	sources.Flags = overrides
+
+	cfg, err := config.Load(sources)
 	if err != nil {
-		panic("failed to load config: " + err.Error())
+		fmt.Fprintln(os.Stderr, "failed to load config:", err)
+		os.Exit(1)
 	}
 
 	// Initialize New Relic logger service
@@ -31,9 +75,14 @@ func main() {
 	defer loggerService.Shutdown()
 
 	log := logging.NewLoggerWithService(cfg.Observability, loggerService)
+	log.Info().Interface("config", cfg.Dump()).Msg("effective config")
 
 	if cfg.Primary.Env != "local" {
 		if err := database.Migrate(context.Background(), &log, cfg); err != nil {
+			var drift *database.DriftError
+			if errors.As(err, &drift) {
+				log.Fatal().Err(err).Msg("refusing to start, the database schema has drifted from the migrations")
+			}
 			log.Fatal().Err(err).Msg("failed to migrate database")
 		}
 	}
@@ -44,36 +93,105 @@ func main() {
 		log.Fatal().Err(err).Msg("failed to initialize server")
 	}
 
-	// Initialize repositories, services, and handlers
-	repos := repository.NewRepositories(srv)
-	services, serviceErr := service.NewServices(srv, repos)
-	if serviceErr != nil {
-		log.Fatal().Err(serviceErr).Msg("could not create services")
+	// Demo data for local development, seeded again each start
+	if cfg.Primary.Env == "local" && cfg.Database.SeedOnStart {
+		if err := seed.Run(context.Background(), srv.DB.Pool, &log, seed.Demo(demoMembers(cfg)...)...); err != nil {
+			log.Warn().Err(err).Msg("failed to seed demo data")
+		}
 	}
-	handlers := handler.NewHandlers(srv, services)
 
-	// Initialize router
-	r := router.NewRouter(srv, handlers, services)
+	// Repositories, services and handlers are built as the mode asks for
+	// them, so each mode only builds what it runs
+	container := app.New(srv)
+
+	log.Info().Str("mode", string(cfg.Mode)).Msg("starting tasker")
+
+	// Store the usage this instance counts for cost attribution
+	usage := container.UsageFlusher()
+	usage.Start()
+
+	// Drop reference data other instances changed
+	srv.RefCache.Start()
+
+	// Apply config changes on SIGHUP and when the config file changes
+	reloader := container.ConfigReloader()
+	reloader.Start()
 
-	// Setup HTTP server
-	srv.SetupHttpServer(r)
-	go func() {
-		if err = srv.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			log.Fatal().Err(err).Msg("failed to start server")
+	var outbox *service.OutboxRelay
+	if cfg.Mode.RunsWorker() {
+		// Process background jobs
+		if err := container.WireJobs(); err != nil {
+			log.Fatal().Err(err).Msg("could not create services")
 		}
-	}()
+		if err := srv.Job.Start(); err != nil {
+			log.Fatal().Err(err).Msg("failed to start job server")
+		}
+
+		// Run recurring cron jobs through the job server
+		cronRunner, err := container.CronRunner()
+		if err != nil {
+			log.Fatal().Err(err).Msg("could not create cron runner")
+		}
+		srv.Job.SetCronRunner(cronRunner)
+		srv.Job.StartScheduler()
+
+		// Relay events recorded in the outbox to webhooks and realtime subscribers
+		outbox = container.OutboxRelay()
+		outbox.Start()
+	}
+
+	var validationReport *service.ValidationReporter
+	if cfg.Mode.RunsAPI() {
+		r, err := container.Router()
+		if err != nil {
+			log.Fatal().Err(err).Msg("could not create router")
+		}
+
+		// Serve websocket connections and route events to them across instances
+		srv.Realtime.Start()
+
+		// Keep the results the health probes answer with current. Every
+		// check is registered once the router is built.
+		srv.Health.Start()
+
+		// Sync read-only mode across instances and release it once a
+		// read-only database accepts writes again
+		srv.ReadOnly.Start()
+
+		// Report the request fields that fail validation
+		validationReport = container.ValidationReporter()
+		validationReport.Start()
+
+		// Setup HTTP server
+		srv.SetupHttpServer(r)
+		go func() {
+			if err = srv.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Fatal().Err(err).Msg("failed to start server")
+			}
+		}()
+	}
 
 	// Wait for interrupt signal
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 	<-ctx.Done()
 
-	// Create shutdown timeout to gracefully shutdown the server
-	ctx, cancel := context.WithTimeout(context.Background(), DefaultContextTimeout*time.Second)
-	if err = srv.Shutdown(ctx); err != nil {
+	stop() // Release signal notification resources
+	reloader.Stop()
+
+	// Store what requests and tasks left behind before Redis and the
+	// database are closed
+	flush := []shutdown.Stage{}
+	if outbox != nil {
+		flush = append(flush, shutdown.Stage{Name: "outbox", Timeout: cfg.Shutdown.Flush, Run: outbox.Shutdown})
+	}
+	if validationReport != nil {
+		flush = append(flush, shutdown.Stage{Name: "validation report", Timeout: cfg.Shutdown.Flush, Run: shutdown.Stop(validationReport.Stop)})
+	}
+	flush = append(flush, shutdown.Stage{Name: "usage", Timeout: cfg.Shutdown.Flush, Run: shutdown.Stop(usage.Stop)})
+
+	if err = srv.Shutdown(context.Background(), flush...); err != nil {
 		log.Fatal().Err(err).Msg("server forced to shutdown")
 	}
-	stop()   // Release signal notification resources
-	cancel() // Release timeout context resources
 
 	log.Info().Msg("server exited properly")
 }
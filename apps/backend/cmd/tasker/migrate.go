@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mabhi256/tasker/internal/config"
+	"github.com/mabhi256/tasker/internal/database"
+	"github.com/mabhi256/tasker/internal/logging"
+)
+
+const migrateUsage = `usage: tasker migrate <command> [flags]
+
+commands:
+  up      apply pending migrations, up to --to if given
+  down    revert the latest migration, the latest -steps, or down to -to
+  status  list migrations, whether they are applied, and any schema drift
+  new     create an empty migration: tasker migrate new [-dir dir] <name>
+
+up and down take -dry-run to print the SQL they would run instead, and refuse
+to run in prod while the schema has drifted from the migrations
+`
+
+// runMigrate runs a migrate subcommand with the arguments that follow it
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return errors.New("missing migrate command")
+	}
+
+	command, args := args[0], args[1:]
+	flags := flag.NewFlagSet("migrate "+command, flag.ExitOnError)
+	to := flags.Int("to", -1, "version to migrate to")
+	steps := flags.Int("steps", 1, "number of migrations down reverts")
+	dryRun := flags.Bool("dry-run", false, "print the SQL that would run without running it")
+	dir := flags.String("dir", "internal/database/migrations", "directory new migrations are created in")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	switch command {
+	case "new":
+		if flags.NArg() != 1 {
+			return errors.New("usage: tasker migrate new [-dir dir] <name>")
+		}
+		path, err := database.NewMigrationFile(*dir, flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		fmt.Println("created", path)
+		return nil
+	case "up", "down", "status":
+	default:
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return fmt.Errorf("unknown migrate command %q", command)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	log := logging.NewLoggerWithService(cfg.Observability, nil)
+
+	ctx := context.Background()
+	migrator, err := database.NewMigrator(ctx, &log, cfg)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close(ctx)
+
+	current, err := migrator.CurrentVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	if command == "status" {
+		return printMigrationStatus(ctx, migrator, current)
+	}
+
+	target := int32(*to)
+	switch {
+	case command == "down" && *steps < 1:
+		return fmt.Errorf("-steps must be at least 1, got %d", *steps)
+	case command == "up" && *to < 0:
+		target = migrator.LatestVersion()
+	case command == "up" && target < current:
+		return fmt.Errorf("version %d is behind the current version %d, use down to revert", target, current)
+	case command == "down" && *to < 0:
+		target = max(current-int32(*steps), 0)
+	case command == "down" && target > current:
+		return fmt.Errorf("version %d is ahead of the current version %d, use up to apply", target, current)
+	}
+
+	if *dryRun {
+		return printMigrationPlan(ctx, migrator, target)
+	}
+
+	if err := migrator.GuardDrift(ctx, cfg.Primary.Env); err != nil {
+		return err
+	}
+
+	_, err = migrator.MigrateTo(ctx, target)
+	return err
+}
+
+func printMigrationStatus(ctx context.Context, migrator *database.Migrator, current int32) error {
+	states, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("current version %d of %d\n\n", current, migrator.LatestVersion())
+	for _, state := range states {
+		status := "pending"
+		if state.Applied {
+			status = "applied"
+		}
+		reversible := ""
+		if !state.Reversible {
+			reversible = " (irreversible)"
+		}
+		fmt.Printf("%-8s %s%s\n", status, state.Name, reversible)
+	}
+
+	var drift *database.DriftError
+	err = migrator.CheckDrift(ctx)
+	if errors.As(err, &drift) {
+		fmt.Println("\nschema drift:")
+		for _, problem := range drift.Problems {
+			fmt.Println("  " + problem)
+		}
+		return nil
+	}
+	return err
+}
+
+func printMigrationPlan(ctx context.Context, migrator *database.Migrator, target int32) error {
+	steps, err := migrator.Plan(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	if len(steps) == 0 {
+		fmt.Println("-- nothing to migrate")
+		return nil
+	}
+
+	for _, step := range steps {
+		fmt.Printf("-- %s %s\n", step.Direction, step.Name)
+		if step.Rollout != "" {
+			fmt.Printf("-- held back until rollout %s is backfilled\n", step.Rollout)
+		}
+		fmt.Printf("%s\n\n", step.SQL)
+	}
+	return nil
+}
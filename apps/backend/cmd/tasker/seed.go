@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/mabhi256/tasker/internal/config"
+	"github.com/mabhi256/tasker/internal/database"
+	"github.com/mabhi256/tasker/internal/database/seed"
+	"github.com/mabhi256/tasker/internal/logging"
+)
+
+// runSeed seeds the demo data set with the arguments that follow seed
+func runSeed(args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	member := flags.String("member", "", "user to add to the demo team workspace, defaults to the dev auth user")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if cfg.Primary.Env == "prod" {
+		return fmt.Errorf("refusing to seed demo data in prod")
+	}
+	log := logging.NewLoggerWithService(cfg.Observability, nil)
+
+	db, err := database.New(cfg, &log, nil)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	members := demoMembers(cfg)
+	if *member != "" {
+		members = []string{*member}
+	}
+
+	return seed.Run(context.Background(), db.Pool, &log, seed.Demo(members...)...)
+}
+
+// demoMembers lets the dev auth user see the demo data
+func demoMembers(cfg *config.Config) []string {
+	if cfg.Auth.Dev != nil && cfg.Auth.Dev.UserID != "" {
+		return []string{cfg.Auth.Dev.UserID}
+	}
+	return nil
+}
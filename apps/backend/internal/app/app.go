@@ -0,0 +1,197 @@
+// Package app wires the server's repositories, services, handlers and
+// middleware together. Each dependency is built the first time something
+// asks for it, along with what it depends on, so a run mode only builds the
+// part of the application it uses: the worker doesn't build handlers, and a
+// test or a command can ask for a single service.
+package app
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/cron"
+	"github.com/mabhi256/tasker/internal/handler"
+	"github.com/mabhi256/tasker/internal/lib/aws"
+	"github.com/mabhi256/tasker/internal/lib/snapshotter"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/repository"
+	"github.com/mabhi256/tasker/internal/router"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/mabhi256/tasker/internal/service"
+)
+
+// Container builds and holds the application's dependencies. It isn't safe
+// for concurrent use; wire what a run mode needs before serving.
+type Container struct {
+	server *server.Server
+
+	repositories *repository.Repositories
+	services     service.Services
+	handlers     *handler.Handlers
+	middlewares  *middleware.Middlewares
+	router       *echo.Echo
+	aws          *aws.AWS
+	snapshotter  *snapshotter.Snapshotter
+	jobsWired    bool
+}
+
+func New(s *server.Server) *Container {
+	return &Container{server: s}
+}
+
+func (c *Container) Server() *server.Server {
+	return c.server
+}
+
+// provide builds a dependency the first time it's asked for, and returns
+// the same one after that
+func provide[T any](slot **T, build func() *T) *T {
+	if *slot == nil {
+		*slot = build()
+	}
+	return *slot
+}
+
+// provideErr is provide for dependencies that can fail to build. A failed
+// build is tried again the next time.
+func provideErr[T any](slot **T, build func() (*T, error)) (*T, error) {
+	if *slot == nil {
+		value, err := build()
+		if err != nil {
+			return nil, err
+		}
+		*slot = value
+	}
+	return *slot, nil
+}
+
+func (c *Container) Repositories() *repository.Repositories {
+	return provide(&c.repositories, func() *repository.Repositories {
+		return repository.NewRepositories(c.server)
+	})
+}
+
+// Services builds every service, for the API
+func (c *Container) Services() (*service.Services, error) {
+	if _, err := c.TodoService(); err != nil {
+		return nil, err
+	}
+	if _, err := c.ReportService(); err != nil {
+		return nil, err
+	}
+	if _, err := c.SnapshotService(); err != nil {
+		return nil, err
+	}
+	if _, err := c.CopyService(); err != nil {
+		return nil, err
+	}
+	if _, err := c.ChangelogService(); err != nil {
+		return nil, err
+	}
+
+	c.services.Job = c.server.Job
+	c.AuthService()
+	c.CommentService()
+	c.CategoryService()
+	c.TagService()
+	c.AdminService()
+	c.WorkspaceService()
+	c.ExportService()
+	c.WebhookService()
+	c.OutboxRelay()
+	c.JobAdminService()
+	c.JobFailureService()
+	c.RealtimeService()
+	c.DigestService()
+	c.WeeklyReportService()
+	c.UsageFlusher()
+	c.ValidationReporter()
+	c.ConfigReloader()
+	c.PushService()
+	c.RolloutService()
+	c.BackfillService()
+	c.SearchService()
+	c.DependencyService()
+	c.AuditService()
+	c.ProvisioningService()
+	c.SSOService()
+	c.IPAllowlistService()
+	c.SessionService()
+	c.APIKeyService()
+	c.TrialService()
+	c.ResolveService()
+	c.CapabilityService()
+	c.QuotaService()
+	c.ActivityService()
+	c.EmailTrackingService()
+
+	return &c.services, nil
+}
+
+// WireJobs gives the job server the services its tasks run with. Only a
+// worker processes tasks; enqueuing them needs none of it.
+func (c *Container) WireJobs() error {
+	if c.jobsWired {
+		return nil
+	}
+
+	reportService, err := c.ReportService()
+	if err != nil {
+		return err
+	}
+	todoService, err := c.TodoService()
+	if err != nil {
+		return err
+	}
+
+	jobs := c.server.Job
+	jobs.SetAuthService(c.AuthService())
+	jobs.SetExportRunner(c.ExportService())
+	jobs.SetWebhookDeliverer(c.WebhookService())
+	jobs.SetAuditForwarder(c.AuditService())
+	jobs.SetFailureRecorder(c.JobFailureService())
+	jobs.SetDigestSender(c.DigestService())
+	jobs.SetWeeklyReportSender(c.WeeklyReportService())
+	jobs.SetPushSender(c.PushService())
+	jobs.SetRolloutBackfiller(c.RolloutService())
+	jobs.SetBackfillRunner(c.BackfillService())
+	jobs.SetReportGenerator(reportService)
+	jobs.SetAttachmentPreviewer(todoService)
+	jobs.SetAttachmentProcessor(todoService)
+
+	// Notification emails are sent by tasks, which record the tracked ones
+	c.server.Email.SetTracker(c.EmailTrackingService())
+
+	c.jobsWired = true
+	return nil
+}
+
+// CronRunner runs the scheduled cron jobs with the server's connections
+func (c *Container) CronRunner() (*cron.ScheduledRunner, error) {
+	return cron.NewScheduledRunner(cron.NewServerJobContext(c.server, c.Repositories()))
+}
+
+func (c *Container) Handlers() (*handler.Handlers, error) {
+	return provideErr(&c.handlers, func() (*handler.Handlers, error) {
+		services, err := c.Services()
+		if err != nil {
+			return nil, err
+		}
+		return handler.NewHandlers(c.server, services), nil
+	})
+}
+
+func (c *Container) Middlewares() *middleware.Middlewares {
+	return provide(&c.middlewares, func() *middleware.Middlewares {
+		return middleware.NewMiddlewares(c.server, c.WorkspaceService(), c.ProvisioningService(),
+			c.SSOService(), c.IPAllowlistService(), c.APIKeyService(), c.TrialService())
+	})
+}
+
+func (c *Container) Router() (*echo.Echo, error) {
+	return provideErr(&c.router, func() (*echo.Echo, error) {
+		handlers, err := c.Handlers()
+		if err != nil {
+			return nil, err
+		}
+		return router.NewRouter(c.server, handlers, c.Middlewares()), nil
+	})
+}
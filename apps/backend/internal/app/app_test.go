@@ -0,0 +1,18 @@
+package app_test
+
+import (
+	"testing"
+
+	"github.com/mabhi256/tasker/internal/app"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerBuildsOnce(t *testing.T) {
+	c := app.New(&server.Server{})
+
+	assert.Same(t, c.Repositories(), c.Repositories())
+	assert.Same(t, c.CategoryService(), c.CategoryService())
+	assert.Same(t, c.AuditService(), c.AuditService())
+	assert.Same(t, c.WorkspaceService(), c.WorkspaceService())
+}
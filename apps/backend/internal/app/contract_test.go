@@ -0,0 +1,31 @@
+package app_test
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/mabhi256/tasker/internal/app"
+	testutil "github.com/mabhi256/tasker/internal/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// TestContract replays the recorded requests under testdata/contract through
+// the router, and fails when a handler's responses drift from the OpenAPI
+// document the API serves
+func TestContract(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping contract test in short mode")
+	}
+
+	recordings := testutil.LoadRecordings(t, filepath.Join("testdata", "contract"))
+
+	_, srv := testutil.SetupContractServer(t)
+	router, err := app.New(srv).Router()
+	require.NoError(t, err)
+
+	doc := testutil.ServedDocument(t, router)
+	header := http.Header{"Authorization": []string{"Bearer " + testutil.ContractToken}}
+
+	testutil.ReplayContract(t, router, doc, recordings, header)
+}
@@ -0,0 +1,162 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/app"
+	"github.com/mabhi256/tasker/internal/database/seed"
+	"github.com/mabhi256/tasker/internal/lib/perfgate"
+	"github.com/mabhi256/tasker/internal/middleware"
+	testutil "github.com/mabhi256/tasker/internal/testing"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	perfGate   = flag.Bool("perf", false, "compare the endpoint benchmarks against testdata/perf/baseline.json")
+	perfUpdate = flag.Bool("perf.update", false, "record the endpoint benchmarks as testdata/perf/baseline.json")
+)
+
+// perfRuns is how many times the gate runs each benchmark, taking the median
+const perfRuns = 5
+
+// perfEndpoint is a request benchmarked against the demo data set. {todoId}
+// in its path is a todo of the team workspace.
+type perfEndpoint struct {
+	name string
+	path string
+}
+
+var perfEndpoints = []perfEndpoint{
+	{name: "ListTodos", path: "/api/v1/todos?page=1&limit=20"},
+	{name: "ListTodosSorted", path: "/api/v1/todos?page=1&limit=20&sort=-priority,title"},
+	{name: "ListTodosFiltered", path: "/api/v1/todos?page=1&limit=20&status=active&search=review"},
+	{name: "GetTodo", path: "/api/v1/todos/{todoId}"},
+	{name: "TodoStats", path: "/api/v1/todos/stats"},
+	{name: "SearchTodos", path: "/api/v1/todos/search?q=release+notes"},
+	{name: "ListCategories", path: "/api/v1/categories?page=1&limit=20"},
+}
+
+// setupPerfServer builds the router on a database seeded with the demo data
+// set, and returns it with the header of requests to the team workspace
+func setupPerfServer(tb testing.TB) (http.Handler, http.Header, uuid.UUID) {
+	tb.Helper()
+
+	// Benchmarks send far more requests than a client is allowed
+	tb.Setenv("TASKER_RATE_LIMIT.RATE", "1000000")
+	tb.Setenv("TASKER_RATE_LIMIT.BURST", "1000000")
+
+	testDB, srv := testutil.SetupContractServer(tb)
+	testutil.Seed(tb, testDB, seed.Demo(testutil.ContractUserID)...)
+
+	var todoID uuid.UUID
+	err := testDB.Pool.QueryRow(context.Background(), `
+		SELECT id FROM todos WHERE workspace_id = $1 ORDER BY id LIMIT 1
+	`, seed.DemoTeamWorkspaceID).Scan(&todoID)
+	require.NoError(tb, err, "failed to find a seeded todo")
+
+	router, err := app.New(srv).Router()
+	require.NoError(tb, err)
+
+	header := http.Header{
+		"Authorization":            []string{"Bearer " + testutil.ContractToken},
+		middleware.WorkspaceHeader: []string{seed.DemoTeamWorkspaceID.String()},
+	}
+
+	return router, header, todoID
+}
+
+// benchmarkEndpoint sends the endpoint's request once per iteration,
+// failing on a response other than 200
+func benchmarkEndpoint(handler http.Handler, header http.Header, todoID uuid.UUID, endpoint perfEndpoint) func(b *testing.B) {
+	path := strings.ReplaceAll(endpoint.path, "{todoId}", todoID.String())
+
+	return func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			for key, vals := range header {
+				req.Header[key] = vals
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				b.Fatalf("GET %s: status %d, body: %s", path, rec.Code, rec.Body.String())
+			}
+		}
+	}
+}
+
+// Run with: go test -run '^$' -bench Endpoints -benchmem ./internal/app
+func BenchmarkEndpoints(b *testing.B) {
+	handler, header, todoID := setupPerfServer(b)
+
+	for _, endpoint := range perfEndpoints {
+		b.Run(endpoint.name, benchmarkEndpoint(handler, header, todoID, endpoint))
+	}
+}
+
+// TestPerfGate benchmarks the endpoints and fails when one got slower or
+// allocates more than its baseline allows. It runs with -perf, and
+// -perf.update records the measurements as the new baseline instead.
+func TestPerfGate(t *testing.T) {
+	if !*perfGate && !*perfUpdate {
+		t.Skip("skipping perf gate without -perf or -perf.update")
+	}
+
+	baselinePath := filepath.Join("testdata", "perf", "baseline.json")
+	baseline, err := perfgate.Load(baselinePath)
+	require.NoError(t, err)
+
+	// SetupContractServer moves to the project root
+	baselinePath, err = filepath.Abs(baselinePath)
+	require.NoError(t, err)
+
+	handler, header, todoID := setupPerfServer(t)
+
+	// Endpoints no longer benchmarked drop out of a recorded baseline
+	if *perfUpdate {
+		baseline.Benchmarks = map[string]perfgate.Measurement{}
+	}
+
+	for _, endpoint := range perfEndpoints {
+		bench := benchmarkEndpoint(handler, header, todoID, endpoint)
+
+		runs := make([]testing.BenchmarkResult, perfRuns)
+		for i := range runs {
+			runs[i] = testing.Benchmark(bench)
+			// A benchmark that failed reports no iterations
+			require.NotZero(t, runs[i].N, "%s failed, rerun it with -bench for its error", endpoint.name)
+		}
+		measured := perfgate.Measure(runs)
+		t.Logf("%s: %d ns/op, %d allocs/op, %d B/op", endpoint.name, measured.NsPerOp, measured.AllocsPerOp, measured.BytesPerOp)
+
+		if *perfUpdate {
+			baseline.Benchmarks[endpoint.name] = measured
+			continue
+		}
+
+		regressions, err := baseline.Compare(endpoint.name, measured)
+		if errors.Is(err, perfgate.ErrNoBaseline) {
+			t.Errorf("%v, record one with -perf.update", err)
+			continue
+		}
+		require.NoError(t, err)
+		for _, r := range regressions {
+			t.Error(r.String())
+		}
+	}
+
+	if *perfUpdate {
+		require.NoError(t, baseline.Save(baselinePath))
+		t.Logf("recorded %d baselines in %s", len(perfEndpoints), baselinePath)
+	}
+}
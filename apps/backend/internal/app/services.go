@@ -0,0 +1,333 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mabhi256/tasker/internal/health"
+	"github.com/mabhi256/tasker/internal/lib/aws"
+	"github.com/mabhi256/tasker/internal/lib/scanner"
+	"github.com/mabhi256/tasker/internal/lib/snapshotter"
+	"github.com/mabhi256/tasker/internal/service"
+)
+
+func (c *Container) AuthService() *service.AuthService {
+	return provide(&c.services.Auth, func() *service.AuthService {
+		return service.NewAuthService(c.server)
+	})
+}
+
+func (c *Container) AuditService() *service.AuditService {
+	return provide(&c.services.Audit, func() *service.AuditService {
+		r := c.Repositories()
+		return service.NewAuditService(c.server, r.Audit, r.Outbox)
+	})
+}
+
+func (c *Container) ExportService() *service.ExportService {
+	return provide(&c.services.Export, func() *service.ExportService {
+		r := c.Repositories()
+		return service.NewExportService(c.server, r.Export, r.Todo)
+	})
+}
+
+func (c *Container) WebhookService() *service.WebhookService {
+	return provide(&c.services.Webhook, func() *service.WebhookService {
+		r := c.Repositories()
+		return service.NewWebhookService(c.server, r.Webhook, c.AuditService())
+	})
+}
+
+func (c *Container) JobFailureService() *service.JobFailureService {
+	return provide(&c.services.JobFailure, func() *service.JobFailureService {
+		r := c.Repositories()
+		return service.NewJobFailureService(c.server, r.JobFailure)
+	})
+}
+
+func (c *Container) DigestService() *service.DigestService {
+	return provide(&c.services.Digest, func() *service.DigestService {
+		r := c.Repositories()
+		return service.NewDigestService(c.server, r.Digest, c.AuthService(), c.EmailTrackingService())
+	})
+}
+
+func (c *Container) WeeklyReportService() *service.WeeklyReportService {
+	return provide(&c.services.WeeklyReport, func() *service.WeeklyReportService {
+		r := c.Repositories()
+		return service.NewWeeklyReportService(c.server, r.WeeklyReport, c.AuthService(), c.EmailTrackingService())
+	})
+}
+
+func (c *Container) PushService() *service.PushService {
+	return provide(&c.services.Push, func() *service.PushService {
+		r := c.Repositories()
+		return service.NewPushService(c.server, r.Device)
+	})
+}
+
+func (c *Container) RolloutService() *service.RolloutService {
+	return provide(&c.services.Rollout, func() *service.RolloutService {
+		r := c.Repositories()
+		return service.NewRolloutService(c.server, r.Rollout)
+	})
+}
+
+func (c *Container) BackfillService() *service.BackfillService {
+	return provide(&c.services.Backfill, func() *service.BackfillService {
+		r := c.Repositories()
+		return service.NewBackfillService(c.server, r.Backfill)
+	})
+}
+
+func (c *Container) CategoryService() *service.CategoryService {
+	return provide(&c.services.Category, func() *service.CategoryService {
+		r := c.Repositories()
+		return service.NewCategoryService(c.server, r.Category)
+	})
+}
+
+func (c *Container) TagService() *service.TagService {
+	return provide(&c.services.Tag, func() *service.TagService {
+		r := c.Repositories()
+		return service.NewTagService(c.server, r.Tag, c.AuditService())
+	})
+}
+
+func (c *Container) CommentService() *service.CommentService {
+	return provide(&c.services.Comment, func() *service.CommentService {
+		r := c.Repositories()
+		return service.NewCommentService(c.server, r.Comment, r.Todo, r.Workspace)
+	})
+}
+
+func (c *Container) AdminService() *service.AdminService {
+	return provide(&c.services.Admin, func() *service.AdminService {
+		r := c.Repositories()
+		return service.NewAdminService(c.server, r.Admin, r.Todo, r.Usage, c.AuditService())
+	})
+}
+
+func (c *Container) WorkspaceService() *service.WorkspaceService {
+	return provide(&c.services.Workspace, func() *service.WorkspaceService {
+		r := c.Repositories()
+		return service.NewWorkspaceService(c.server, r.Workspace, c.AuditService())
+	})
+}
+
+func (c *Container) OutboxRelay() *service.OutboxRelay {
+	return provide(&c.services.Outbox, func() *service.OutboxRelay {
+		r := c.Repositories()
+		return service.NewOutboxRelay(c.server, r.Outbox, c.WebhookService(), c.AuditService())
+	})
+}
+
+func (c *Container) JobAdminService() *service.JobAdminService {
+	return provide(&c.services.JobAdmin, func() *service.JobAdminService {
+		return service.NewJobAdminService(c.server)
+	})
+}
+
+func (c *Container) RealtimeService() *service.RealtimeService {
+	return provide(&c.services.Realtime, func() *service.RealtimeService {
+		return service.NewRealtimeService(c.server)
+	})
+}
+
+func (c *Container) UsageFlusher() *service.UsageFlusher {
+	return provide(&c.services.Usage, func() *service.UsageFlusher {
+		r := c.Repositories()
+		return service.NewUsageFlusher(c.server, r.Usage)
+	})
+}
+
+func (c *Container) QuotaService() *service.QuotaService {
+	return provide(&c.services.Quota, func() *service.QuotaService {
+		r := c.Repositories()
+		return service.NewQuotaService(c.server, r.Usage)
+	})
+}
+
+func (c *Container) ValidationReporter() *service.ValidationReporter {
+	return provide(&c.services.Validation, func() *service.ValidationReporter {
+		return service.NewValidationReporter(c.server)
+	})
+}
+
+func (c *Container) ConfigReloader() *service.ConfigReloader {
+	return provide(&c.services.Reload, func() *service.ConfigReloader {
+		return service.NewConfigReloader(c.server)
+	})
+}
+
+func (c *Container) SearchService() *service.SearchService {
+	return provide(&c.services.Search, func() *service.SearchService {
+		r := c.Repositories()
+		return service.NewSearchService(c.server, r.Todo)
+	})
+}
+
+func (c *Container) DependencyService() *service.DependencyService {
+	return provide(&c.services.Dependency, func() *service.DependencyService {
+		r := c.Repositories()
+		return service.NewDependencyService(c.server, r.Todo)
+	})
+}
+
+func (c *Container) ProvisioningService() *service.ProvisioningService {
+	return provide(&c.services.Provisioning, func() *service.ProvisioningService {
+		r := c.Repositories()
+		return service.NewProvisioningService(c.server, r.Provisioning, r.Workspace, r.SSO, c.AuthService(),
+			c.AuditService())
+	})
+}
+
+func (c *Container) SSOService() *service.SSOService {
+	return provide(&c.services.SSO, func() *service.SSOService {
+		r := c.Repositories()
+		return service.NewSSOService(c.server, r.SSO, r.Workspace, c.AuthService(), c.AuditService())
+	})
+}
+
+func (c *Container) IPAllowlistService() *service.IPAllowlistService {
+	return provide(&c.services.IPAllowlist, func() *service.IPAllowlistService {
+		r := c.Repositories()
+		return service.NewIPAllowlistService(c.server, r.IPAllowlist, r.Workspace, c.AuditService())
+	})
+}
+
+func (c *Container) SessionService() *service.SessionService {
+	return provide(&c.services.Session, func() *service.SessionService {
+		return service.NewSessionService(c.server, c.AuthService(), c.AuditService())
+	})
+}
+
+func (c *Container) APIKeyService() *service.APIKeyService {
+	return provide(&c.services.APIKey, func() *service.APIKeyService {
+		r := c.Repositories()
+		return service.NewAPIKeyService(c.server, r.APIKey, c.AuditService())
+	})
+}
+
+func (c *Container) TrialService() *service.TrialService {
+	return provide(&c.services.Trial, func() *service.TrialService {
+		r := c.Repositories()
+		return service.NewTrialService(c.server, r.Trial, c.AuditService())
+	})
+}
+
+func (c *Container) ResolveService() *service.ResolveService {
+	return provide(&c.services.Resolve, func() *service.ResolveService {
+		r := c.Repositories()
+		return service.NewResolveService(c.server, r.Todo, r.Category, r.Comment, r.Workspace, c.AuthService())
+	})
+}
+
+func (c *Container) CapabilityService() *service.CapabilityService {
+	return provide(&c.services.Capability, func() *service.CapabilityService {
+		r := c.Repositories()
+		return service.NewCapabilityService(c.server, r.Workspace)
+	})
+}
+
+func (c *Container) ActivityService() *service.ActivityService {
+	return provide(&c.services.Activity, func() *service.ActivityService {
+		r := c.Repositories()
+		return service.NewActivityService(c.server, r.Activity)
+	})
+}
+
+func (c *Container) EmailTrackingService() *service.EmailTrackingService {
+	return provide(&c.services.EmailTracking, func() *service.EmailTrackingService {
+		r := c.Repositories()
+		return service.NewEmailTrackingService(c.server, r.EmailTracking)
+	})
+}
+
+// awsSetupTimeout bounds preparing the upload bucket when the AWS client is
+// built
+const awsSetupTimeout = 10 * time.Second
+
+// AWS is the client shared by the services that store files. Building it
+// registers the storage health check, so it must be built before the
+// health monitor starts.
+func (c *Container) AWS() (*aws.AWS, error) {
+	return provideErr(&c.aws, func() (*aws.AWS, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), awsSetupTimeout)
+		defer cancel()
+
+		client, err := aws.NewAWS(ctx, c.server)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create AWS client: %w", err)
+		}
+		c.server.Health.Register(health.CheckStorage, health.Storage(client.S3, c.server.Config.AWS.UploadBucket))
+		return client, nil
+	})
+}
+
+func (c *Container) Snapshotter() (*snapshotter.Snapshotter, error) {
+	return provideErr(&c.snapshotter, func() (*snapshotter.Snapshotter, error) {
+		renderer, err := snapshotter.New()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create snapshotter: %w", err)
+		}
+		return renderer, nil
+	})
+}
+
+func (c *Container) TodoService() (*service.TodoService, error) {
+	return provideErr(&c.services.Todo, func() (*service.TodoService, error) {
+		awsClient, err := c.AWS()
+		if err != nil {
+			return nil, err
+		}
+		r := c.Repositories()
+		fileScanner := scanner.New(c.server.Config.Attachments.Scan)
+		return service.NewTodoService(c.server, r.Todo, r.Category, awsClient, fileScanner, c.QuotaService()), nil
+	})
+}
+
+func (c *Container) ReportService() (*service.ReportService, error) {
+	return provideErr(&c.services.Report, func() (*service.ReportService, error) {
+		awsClient, err := c.AWS()
+		if err != nil {
+			return nil, err
+		}
+		r := c.Repositories()
+		return service.NewReportService(c.server, r.Report, r.Todo, r.Workspace, awsClient, c.AuthService()), nil
+	})
+}
+
+func (c *Container) SnapshotService() (*service.SnapshotService, error) {
+	return provideErr(&c.services.Snapshot, func() (*service.SnapshotService, error) {
+		awsClient, err := c.AWS()
+		if err != nil {
+			return nil, err
+		}
+		renderer, err := c.Snapshotter()
+		if err != nil {
+			return nil, err
+		}
+		r := c.Repositories()
+		return service.NewSnapshotService(c.server, r.Todo, r.Snapshot, awsClient, renderer), nil
+	})
+}
+
+func (c *Container) ChangelogService() (*service.ChangelogService, error) {
+	return provideErr(&c.services.Changelog, func() (*service.ChangelogService, error) {
+		return service.NewChangelogService(c.server)
+	})
+}
+
+func (c *Container) CopyService() (*service.CopyService, error) {
+	return provideErr(&c.services.Copy, func() (*service.CopyService, error) {
+		awsClient, err := c.AWS()
+		if err != nil {
+			return nil, err
+		}
+		r := c.Repositories()
+		return service.NewCopyService(c.server, r.Todo, r.Category, r.Workspace, c.QuotaService(),
+			awsClient), nil
+	})
+}
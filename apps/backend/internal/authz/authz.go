@@ -0,0 +1,185 @@
+// Package authz decides whether a workspace member may perform an action.
+// Each action has a policy, which sees the member's workspace role and who
+// owns the resource acted on, so services ask for an action rather than
+// checking roles and owners themselves. Actions without a policy are
+// denied.
+package authz
+
+import (
+	"slices"
+
+	"github.com/mabhi256/tasker/internal/model/workspace"
+)
+
+// Action is what is done to a resource, named resource:verb
+type Action string
+
+const (
+	TodoCreate Action = "todo:create"
+	TodoUpdate Action = "todo:update"
+	TodoDelete Action = "todo:delete"
+	// TodoAttach uploads and deletes the todo's attachments
+	TodoAttach Action = "todo:attach"
+	// TodoLink adds and removes the todo's dependencies
+	TodoLink Action = "todo:link"
+	// TodoCopy copies the todo, with its subtasks and attachments, into
+	// another workspace. The copier also needs to create todos there.
+	TodoCopy Action = "todo:copy"
+
+	CategoryCreate Action = "category:create"
+	CategoryUpdate Action = "category:update"
+	CategoryDelete Action = "category:delete"
+
+	CommentCreate Action = "comment:create"
+	CommentUpdate Action = "comment:update"
+	CommentDelete Action = "comment:delete"
+	CommentReact  Action = "comment:react"
+
+	// TagManage renames and merges tags across the workspace's todos
+	TagManage Action = "tag:manage"
+
+	// WorkspaceUpdate renames the workspace and changes its settings
+	WorkspaceUpdate Action = "workspace:update"
+	WorkspaceDelete Action = "workspace:delete"
+
+	MemberAdd Action = "member:add"
+	// MemberUpdate changes a member's role
+	MemberUpdate Action = "member:update"
+	// MemberRemove removes a member from the workspace. The member owns
+	// their membership.
+	MemberRemove Action = "member:remove"
+
+	// WebhookManage creates, changes and deletes the workspace's webhooks,
+	// and lists and redelivers their deliveries
+	WebhookManage Action = "webhook:manage"
+	// ExportManage schedules and runs the workspace's exports
+	ExportManage Action = "export:manage"
+	// AuditForward manages where the workspace's audit events are forwarded
+	AuditForward Action = "audit:forward"
+	// SSOManage configures the workspace's single sign-on
+	SSOManage Action = "sso:manage"
+	// ProvisioningManage manages the workspace's SCIM token and the roles
+	// its directory groups are given
+	ProvisioningManage Action = "provisioning:manage"
+	// IPAllowlistManage changes the networks the workspace is reached from,
+	// and ends break glass early
+	IPAllowlistManage Action = "ip_allowlist:manage"
+	// IPAllowlistBreakGlass lifts the workspace's IP allowlist for a while
+	IPAllowlistBreakGlass Action = "ip_allowlist:break_glass"
+
+	// AccessReview lists everyone with access to the workspace, with their
+	// roles and API keys
+	AccessReview Action = "access:review"
+)
+
+// Subject is who performs the action
+type Subject struct {
+	UserID string
+	Role   workspace.Role
+}
+
+// Resource is what the action is performed on. OwnerID is empty for
+// resources without an owner, or whose owner doesn't matter to the action.
+type Resource struct {
+	OwnerID string
+}
+
+// Policy reports whether the subject may perform an action on the resource
+type Policy func(sub Subject, res Resource) bool
+
+// HasRole allows subjects with at least the role
+func HasRole(min workspace.Role) Policy {
+	return func(sub Subject, res Resource) bool {
+		return sub.Role.AtLeast(min)
+	}
+}
+
+// IsOwner allows the subject who owns the resource
+func IsOwner(sub Subject, res Resource) bool {
+	return sub.UserID != "" && sub.UserID == res.OwnerID
+}
+
+// All allows what every one of the policies allows
+func All(policies ...Policy) Policy {
+	return func(sub Subject, res Resource) bool {
+		for _, policy := range policies {
+			if !policy(sub, res) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Any allows what one of the policies allows
+func Any(policies ...Policy) Policy {
+	return func(sub Subject, res Resource) bool {
+		for _, policy := range policies {
+			if policy(sub, res) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+var policies = map[Action]Policy{
+	TodoCreate: HasRole(workspace.RoleMember),
+	TodoUpdate: HasRole(workspace.RoleMember),
+	TodoDelete: HasRole(workspace.RoleMember),
+	TodoAttach: HasRole(workspace.RoleMember),
+	TodoLink:   HasRole(workspace.RoleMember),
+	TodoCopy:   HasRole(workspace.RoleMember),
+
+	CategoryCreate: HasRole(workspace.RoleMember),
+	CategoryUpdate: HasRole(workspace.RoleMember),
+	CategoryDelete: HasRole(workspace.RoleMember),
+
+	CommentCreate: HasRole(workspace.RoleMember),
+	// Only the author may edit a comment
+	CommentUpdate: All(HasRole(workspace.RoleMember), IsOwner),
+	// Authors can delete their own comments; workspace admins can moderate any
+	CommentDelete: Any(All(HasRole(workspace.RoleMember), IsOwner), HasRole(workspace.RoleAdmin)),
+	CommentReact:  HasRole(workspace.RoleMember),
+
+	// Relabelling everyone's todos is left to admins
+	TagManage: HasRole(workspace.RoleAdmin),
+
+	WorkspaceUpdate: HasRole(workspace.RoleAdmin),
+	WorkspaceDelete: HasRole(workspace.RoleOwner),
+
+	MemberAdd:    HasRole(workspace.RoleAdmin),
+	MemberUpdate: HasRole(workspace.RoleAdmin),
+	// Members can always leave; removing someone else needs admin
+	MemberRemove: Any(IsOwner, HasRole(workspace.RoleAdmin)),
+
+	WebhookManage:      HasRole(workspace.RoleAdmin),
+	ExportManage:       HasRole(workspace.RoleAdmin),
+	AuditForward:       HasRole(workspace.RoleAdmin),
+	SSOManage:          HasRole(workspace.RoleAdmin),
+	ProvisioningManage: HasRole(workspace.RoleAdmin),
+	IPAllowlistManage:  HasRole(workspace.RoleAdmin),
+	// Only the owner can lift the allowlist, since it also locks out admins
+	IPAllowlistBreakGlass: HasRole(workspace.RoleOwner),
+
+	AccessReview: HasRole(workspace.RoleAdmin),
+}
+
+// Actions lists every action with a policy, sorted
+func Actions() []Action {
+	actions := make([]Action, 0, len(policies))
+	for action := range policies {
+		actions = append(actions, action)
+	}
+	slices.Sort(actions)
+	return actions
+}
+
+// Allowed reports whether the subject may perform the action on the resource
+func Allowed(action Action, sub Subject, res Resource) bool {
+	policy, ok := policies[action]
+	if !ok {
+		return false
+	}
+	return policy(sub, res)
+}
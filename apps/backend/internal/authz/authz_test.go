@@ -0,0 +1,99 @@
+package authz_test
+
+import (
+	"testing"
+
+	"github.com/mabhi256/tasker/internal/authz"
+	"github.com/mabhi256/tasker/internal/model/workspace"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllowed(t *testing.T) {
+	author := authz.Resource{OwnerID: "user-1"}
+	member := authz.Subject{UserID: "user-1", Role: workspace.RoleMember}
+	otherMember := authz.Subject{UserID: "user-2", Role: workspace.RoleMember}
+	viewer := authz.Subject{UserID: "user-1", Role: workspace.RoleViewer}
+	admin := authz.Subject{UserID: "user-3", Role: workspace.RoleAdmin}
+	owner := authz.Subject{UserID: "user-4", Role: workspace.RoleOwner}
+
+	for _, tc := range []struct {
+		name    string
+		action  authz.Action
+		sub     authz.Subject
+		res     authz.Resource
+		allowed bool
+	}{
+		{"member creates todo", authz.TodoCreate, member, authz.Resource{}, true},
+		{"viewer creates todo", authz.TodoCreate, viewer, authz.Resource{}, false},
+		{"member copies todo", authz.TodoCopy, member, authz.Resource{}, true},
+		{"viewer copies todo", authz.TodoCopy, viewer, authz.Resource{}, false},
+		{"admin deletes category", authz.CategoryDelete, admin, authz.Resource{}, true},
+		{"author edits comment", authz.CommentUpdate, member, author, true},
+		{"other member edits comment", authz.CommentUpdate, otherMember, author, false},
+		{"admin edits comment", authz.CommentUpdate, admin, author, false},
+		{"viewer author edits comment", authz.CommentUpdate, viewer, author, false},
+		{"author deletes comment", authz.CommentDelete, member, author, true},
+		{"other member deletes comment", authz.CommentDelete, otherMember, author, false},
+		{"admin deletes comment", authz.CommentDelete, admin, author, true},
+		{"viewer leaves", authz.MemberRemove, viewer, author, true},
+		{"member removes member", authz.MemberRemove, otherMember, author, false},
+		{"admin removes member", authz.MemberRemove, admin, author, true},
+		{"member renames tag", authz.TagManage, member, authz.Resource{}, false},
+		{"admin renames tag", authz.TagManage, admin, authz.Resource{}, true},
+		{"member reviews access", authz.AccessReview, member, authz.Resource{}, false},
+		{"admin reviews access", authz.AccessReview, admin, authz.Resource{}, true},
+		{"member updates workspace", authz.WorkspaceUpdate, member, authz.Resource{}, false},
+		{"admin updates workspace", authz.WorkspaceUpdate, admin, authz.Resource{}, true},
+		{"admin deletes workspace", authz.WorkspaceDelete, admin, authz.Resource{}, false},
+		{"owner deletes workspace", authz.WorkspaceDelete, owner, authz.Resource{}, true},
+		{"member adds member", authz.MemberAdd, member, authz.Resource{}, false},
+		{"admin changes member role", authz.MemberUpdate, admin, author, true},
+		{"member changes own role", authz.MemberUpdate, member, author, false},
+		{"member manages webhooks", authz.WebhookManage, member, authz.Resource{}, false},
+		{"admin manages webhooks", authz.WebhookManage, admin, authz.Resource{}, true},
+		{"admin manages exports", authz.ExportManage, admin, authz.Resource{}, true},
+		{"member forwards audit events", authz.AuditForward, member, authz.Resource{}, false},
+		{"admin configures sso", authz.SSOManage, admin, authz.Resource{}, true},
+		{"member manages provisioning", authz.ProvisioningManage, member, authz.Resource{}, false},
+		{"admin changes ip allowlist", authz.IPAllowlistManage, admin, authz.Resource{}, true},
+		{"admin breaks glass", authz.IPAllowlistBreakGlass, admin, authz.Resource{}, false},
+		{"owner breaks glass", authz.IPAllowlistBreakGlass, owner, authz.Resource{}, true},
+		{"unknown action", authz.Action("todo:teleport"), admin, authz.Resource{}, false},
+	} {
+		assert.Equal(t, tc.allowed, authz.Allowed(tc.action, tc.sub, tc.res), tc.name)
+	}
+}
+
+// TestActions lists the actions the capabilities of each role are built from.
+// The workspace's administration is left to admins, and deleting it or
+// lifting its allowlist to the owner.
+func TestActions(t *testing.T) {
+	allowed := func(role workspace.Role) []authz.Action {
+		var actions []authz.Action
+		for _, action := range authz.Actions() {
+			if authz.Allowed(action, authz.Subject{UserID: "user-1", Role: role}, authz.Resource{}) {
+				actions = append(actions, action)
+			}
+		}
+		return actions
+	}
+
+	admin := []authz.Action{
+		authz.WorkspaceUpdate, authz.MemberAdd, authz.MemberUpdate, authz.WebhookManage,
+		authz.ExportManage, authz.AuditForward, authz.SSOManage, authz.ProvisioningManage,
+		authz.IPAllowlistManage,
+	}
+	owner := []authz.Action{authz.WorkspaceDelete, authz.IPAllowlistBreakGlass}
+
+	assert.Subset(t, allowed(workspace.RoleAdmin), admin)
+	assert.NotContains(t, allowed(workspace.RoleAdmin), owner[0])
+	assert.NotContains(t, allowed(workspace.RoleAdmin), owner[1])
+	assert.Subset(t, allowed(workspace.RoleOwner), append(admin, owner...))
+	for _, action := range append(admin, owner...) {
+		assert.NotContains(t, allowed(workspace.RoleMember), action)
+	}
+}
+
+func TestIsOwnerNeedsUser(t *testing.T) {
+	assert.False(t, authz.IsOwner(authz.Subject{}, authz.Resource{}))
+}
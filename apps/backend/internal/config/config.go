@@ -1,17 +1,19 @@
 package config
 
 import (
-	"os"
+	"fmt"
+	"net/url"
+	"regexp"
 	"strings"
+	"time"
 
-	"github.com/go-playground/validator/v10"
 	_ "github.com/joho/godotenv/autoload"
 	"github.com/knadh/koanf/providers/env"
 	"github.com/knadh/koanf/v2"
-	"github.com/rs/zerolog"
 )
 
 type Config struct {
+	Mode          Mode                 `koanf:"mode" validate:"omitempty,oneof=all api worker"`
 	Primary       Primary              `koanf:"primary" validate:"required"`
 	Server        ServerConfig         `koanf:"server" validate:"required"`
 	Database      DatabaseConfig       `koanf:"database" validate:"required"`
@@ -20,7 +22,79 @@ type Config struct {
 	Email         EmailConfig          `koanf:"email" validate:"required"`
 	AWS           AWSConfig            `koanf:"aws" validate:"required"`
 	Cron          *CronConfig          `koanf:"cron"`
+	HTTPClient    *HTTPClientConfig    `koanf:"http_client"`
+	Fetcher       *FetcherConfig       `koanf:"fetcher"`
+	Outbox        *OutboxConfig        `koanf:"outbox"`
+	Alerts        *AlertsConfig        `koanf:"alerts"`
+	Jobs          *JobsConfig          `koanf:"jobs"`
+	JobFailures   *JobFailuresConfig   `koanf:"job_failures"`
+	Scheduler     *SchedulerConfig     `koanf:"scheduler"`
+	EarlyHints    *EarlyHintsConfig    `koanf:"early_hints"`
+	Realtime      *RealtimeConfig      `koanf:"realtime"`
+	RefCache      *RefCacheConfig      `koanf:"ref_cache"`
+	Usage         *UsageConfig         `koanf:"usage"`
+	Push          *PushConfig          `koanf:"push"`
+	Rollouts      *RolloutsConfig      `koanf:"rollouts"`
+	Backfills     *BackfillsConfig     `koanf:"backfills"`
+	Search        *SearchConfig        `koanf:"search"`
+	Attachments   *AttachmentsConfig   `koanf:"attachments"`
 	Observability *ObservabilityConfig `koanf:"observability"`
+
+	// ValidationReport reports the request fields that failed validation
+	ValidationReport *ValidationReportConfig `koanf:"validation_report"`
+	// ClientVersions turns away obsolete clients
+	ClientVersions *ClientVersionsConfig `koanf:"client_versions"`
+	Shutdown       *ShutdownConfig       `koanf:"shutdown"`
+	// RateLimit limits the requests each client makes to the API
+	RateLimit *RateLimitConfig `koanf:"rate_limit"`
+	// Features are feature flags by name. Features are on unless turned off.
+	Features map[string]bool `koanf:"features"`
+	Reload   *ReloadConfig   `koanf:"reload"`
+	// ReadOnly rejects writes during incident recovery
+	ReadOnly *ReadOnlyConfig `koanf:"read_only"`
+	// Sandbox resets a designated workspace to a fixture data set nightly
+	Sandbox *SandboxConfig `koanf:"sandbox"`
+	// Secrets configures the secret stores values can refer to
+	Secrets *SecretsConfig `koanf:"secrets"`
+	// Trials let visitors try the app without signing up
+	Trials *TrialsConfig `koanf:"trials"`
+	// Exports are scheduled exports to customers' buckets and servers
+	Exports *ExportsConfig `koanf:"exports"`
+
+	// Sources are where the config was loaded from, to reload it from
+	Sources Sources `koanf:"-"`
+	// resolved are the values resolved from secrets
+	resolved *secretRefs
+}
+
+// Mode selects what a process runs, so the API and background processing
+// can be scaled separately
+type Mode string
+
+const (
+	// ModeAll runs the API and the background workers in one process
+	ModeAll Mode = "all"
+	// ModeAPI serves HTTP and websocket requests and only enqueues jobs
+	ModeAPI Mode = "api"
+	// ModeWorker processes jobs, schedules cron jobs and relays the outbox
+	ModeWorker Mode = "worker"
+)
+
+func ParseMode(s string) (Mode, error) {
+	switch mode := Mode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case ModeAll, ModeAPI, ModeWorker:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown mode %q, expected all, api or worker", s)
+	}
+}
+
+func (m Mode) RunsAPI() bool {
+	return m == ModeAll || m == ModeAPI
+}
+
+func (m Mode) RunsWorker() bool {
+	return m == ModeAll || m == ModeWorker
 }
 
 type Primary struct {
@@ -36,37 +110,253 @@ type ServerConfig struct {
 }
 
 type DatabaseConfig struct {
-	Host            string `koanf:"host" validate:"required"`
-	Port            int    `koanf:"port" validate:"required"`
-	User            string `koanf:"user" validate:"required"`
-	Password        string `koanf:"password" validate:"required"`
-	Name            string `koanf:"name" validate:"required"`
-	SSLMode         string `koanf:"ssl_mode" validate:"required"`
-	MaxOpenConns    int    `koanf:"max_open_conns" validate:"required"`
-	MaxIdleConns    int    `koanf:"max_idle_conns" validate:"required"`
-	ConnMaxLifetime int    `koanf:"conn_max_lifetime" validate:"required"`
-	ConnMaxIdleTime int    `koanf:"conn_max_idle_time" validate:"required"`
+	Host         string `koanf:"host" validate:"required"`
+	Port         int    `koanf:"port" validate:"required"`
+	User         string `koanf:"user" validate:"required"`
+	Password     string `koanf:"password" validate:"required"`
+	Name         string `koanf:"name" validate:"required"`
+	SSLMode      string `koanf:"ssl_mode" validate:"required"`
+	MaxOpenConns int    `koanf:"max_open_conns" validate:"required"`
+	// MinConns are kept open while idle, so a burst of requests doesn't wait
+	// for connections to be established
+	MinConns int `koanf:"min_conns"`
+	// MaxIdleConns isn't used: pgx doesn't cap idle connections, it closes
+	// them after ConnMaxIdleTime
+	MaxIdleConns    int `koanf:"max_idle_conns" validate:"required"`
+	ConnMaxLifetime int `koanf:"conn_max_lifetime" validate:"required"`  // seconds
+	ConnMaxIdleTime int `koanf:"conn_max_idle_time" validate:"required"` // seconds
+	// HealthCheckPeriod is how often idle connections are checked, and the
+	// pool topped back up to MinConns
+	HealthCheckPeriod time.Duration `koanf:"health_check_period"`
+	// StatsInterval is how often the pool's stats are logged and recorded
+	// as metrics
+	StatsInterval time.Duration `koanf:"stats_interval"`
+	// ReadTimeout and WriteTimeout bound each statement of a request,
+	// depending on whether it reads or writes
+	ReadTimeout  time.Duration `koanf:"read_timeout"`
+	WriteTimeout time.Duration `koanf:"write_timeout"`
+	// FailoverRetries is how many times a statement that failed because the
+	// database is failing over runs again, each time on fresh connections.
+	// The first retry waits FailoverBackoff, and each next one twice as
+	// long, up to MaxFailoverBackoff.
+	FailoverRetries int           `koanf:"failover_retries"`
+	FailoverBackoff time.Duration `koanf:"failover_backoff"`
+
+	// ReadReplica serves list queries when set
+	ReadReplica *ReadReplicaConfig `koanf:"read_replica"`
+
+	// SeedOnStart seeds the demo data set when the server starts in the
+	// local environment. Deleted demo rows come back on the next start.
+	SeedOnStart bool `koanf:"seed_on_start"`
 }
 
+// ReadReplicaConfig points at a replica of the database, reached with the
+// primary's credentials. Replicas lag behind the primary, so a user's reads
+// go to the primary for StickyWindow after each of their writes.
+type ReadReplicaConfig struct {
+	Host         string        `koanf:"host" validate:"required"`
+	Port         int           `koanf:"port" validate:"required"`
+	StickyWindow time.Duration `koanf:"sticky_window"`
+}
+
+const (
+	DefaultDBHealthCheckPeriod = time.Minute
+	DefaultDBStatsInterval     = time.Minute
+	DefaultDBReadTimeout       = 5 * time.Second
+	DefaultDBWriteTimeout      = 15 * time.Second
+	// The default failover retries span about six seconds, which managed
+	// databases usually take to promote a standby
+	DefaultDBFailoverRetries = 5
+	DefaultDBFailoverBackoff = 250 * time.Millisecond
+	MaxFailoverBackoff       = 2 * time.Second
+)
+
+// DefaultReplicaStickyWindow covers the usual replication lag with room to
+// spare
+const DefaultReplicaStickyWindow = 5 * time.Second
+
 type RedisConfig struct {
 	Address string `koanf:"address" validate:"required"`
 }
 
-// todo: use keycloak for auth
+// AuthConfig selects the provider requests are authenticated with, so
+// self-hosted deployments aren't tied to Clerk
 type AuthConfig struct {
-	SecretKey string `koanf:"secret_key" validate:"required"`
+	// Provider is clerk, the default, oidc or dev
+	Provider AuthProvider `koanf:"provider" validate:"omitempty,oneof=clerk oidc dev"`
+	// SecretKey is Clerk's secret key. Users and sessions are managed through
+	// Clerk's API with it, whichever provider authenticates requests.
+	SecretKey string          `koanf:"secret_key"`
+	OIDC      *OIDCAuthConfig `koanf:"oidc"`
+	Dev       *DevAuthConfig  `koanf:"dev"`
+	// Machine accepts the tokens internal services and CI scripts get with
+	// the client credentials grant. It is independent of Provider and off
+	// when nil.
+	Machine *MachineAuthConfig `koanf:"machine"`
+}
+
+type AuthProvider string
+
+const (
+	AuthProviderClerk AuthProvider = "clerk"
+	// AuthProviderOIDC accepts access tokens of any OpenID Connect provider
+	AuthProviderOIDC AuthProvider = "oidc"
+	// AuthProviderDev accepts one static token, for local development
+	AuthProviderDev AuthProvider = "dev"
+)
+
+// OIDCAuthConfig points at the OpenID Connect provider tokens are issued by.
+// Its keys are found through the discovery document under Issuer.
+type OIDCAuthConfig struct {
+	Issuer string `koanf:"issuer"`
+	// Audience is the aud claim tokens must have, usually the client id
+	Audience string `koanf:"audience"`
+	// AdminSubjects are the users, by sub claim, given the admin role
+	AdminSubjects []string `koanf:"admin_subjects"`
+}
+
+// DevAuthConfig authenticates every request bearing Token as UserID. It is
+// only allowed in the local environment.
+type DevAuthConfig struct {
+	Token  string `koanf:"token"`
+	UserID string `koanf:"user_id"`
+	// Admin gives the user the admin role
+	Admin bool `koanf:"admin"`
+}
+
+// MachineAuthConfig points at the authorization server that issues machine
+// tokens. Its keys are found through the discovery document under Issuer.
+type MachineAuthConfig struct {
+	Issuer string `koanf:"issuer"`
+	// Audience is the aud claim machine tokens must have
+	Audience string `koanf:"audience"`
+}
+
+// validate checks that the selected provider is configured
+func (ac *AuthConfig) validate(env string, p *problems) {
+	switch ac.Provider {
+	case AuthProviderClerk:
+		if ac.SecretKey == "" {
+			p.add("auth.secret_key", "is required with the clerk provider")
+		}
+	case AuthProviderOIDC:
+		if ac.OIDC == nil || ac.OIDC.Issuer == "" {
+			p.add("auth.oidc.issuer", "is required with the oidc provider")
+		}
+		if ac.OIDC == nil || ac.OIDC.Audience == "" {
+			p.add("auth.oidc.audience", "is required with the oidc provider")
+		}
+	case AuthProviderDev:
+		if env != "local" {
+			p.add("auth.provider", "the dev provider is only allowed in the local environment, not %s", env)
+		}
+		if ac.Dev == nil || ac.Dev.Token == "" {
+			p.add("auth.dev.token", "is required with the dev provider")
+		}
+		if ac.Dev == nil || ac.Dev.UserID == "" {
+			p.add("auth.dev.user_id", "is required with the dev provider")
+		}
+	}
+	if ac.Machine != nil {
+		if ac.Machine.Issuer == "" {
+			p.add("auth.machine.issuer", "is required for machine tokens")
+		}
+		if ac.Machine.Audience == "" {
+			p.add("auth.machine.audience", "is required for machine tokens")
+		}
+	}
 }
 
 type EmailConfig struct {
 	ResendAPIKey string `koanf:"resend_api_key" validate:"required"`
+	// AppURL is the web app's URL, against which the relative links of
+	// emails are resolved, such as https://app.example.com
+	AppURL   string               `koanf:"app_url"`
+	Tracking *EmailTrackingConfig `koanf:"tracking"`
+}
+
+// EmailTrackingConfig tracks the opens and clicks of notification emails,
+// through a pixel and links redirected by the API. Workspaces can opt out,
+// and the members of any workspace that did are never tracked.
+type EmailTrackingConfig struct {
+	Enabled bool `koanf:"enabled"`
+	// BaseURL is the API's public URL, which tracking links point to
+	BaseURL string `koanf:"base_url"`
+	// SigningKey signs tracking links, so the redirect can't be used to
+	// send people elsewhere
+	SigningKey string `koanf:"signing_key"`
+	// Engagement of messages is kept this long
+	Retention time.Duration `koanf:"retention"`
+	// Daily digests drop to weekly after this many unengaged digests in a
+	// row
+	DigestBackoffAfter int `koanf:"digest_backoff_after"`
+	// Addresses are considered dead after this many unengaged emails in a
+	// row, and get no more digests
+	DeadAfter int `koanf:"dead_after"`
+}
+
+func DefaultEmailTrackingConfig() *EmailTrackingConfig {
+	return &EmailTrackingConfig{
+		Retention:          90 * 24 * time.Hour,
+		DigestBackoffAfter: 7,
+		DeadAfter:          20,
+	}
 }
 
 type AWSConfig struct {
 	Region          string `koanf:"region" validate:"required"`
-	AccessKeyID     string `koanf:"access_key_id" validate:"required"`
-	SecretAccessKey string `koanf:"secret_access_key" validate:"required"`
+	AccessKeyID     string `koanf:"access_key_id"`
+	SecretAccessKey string `koanf:"secret_access_key"`
 	UploadBucket    string `koanf:"upload_bucket" validate:"required"`
 	EndpointURL     string `koanf:"endpoint_url"`
+	// UsePathStyle addresses buckets in the URL path rather than the host,
+	// as MinIO and LocalStack expect
+	UsePathStyle bool `koanf:"use_path_style"`
+	// Files larger than MultipartThreshold are uploaded in parts of PartSize
+	// bytes, so they are never held in memory whole
+	MultipartThreshold int64 `koanf:"multipart_threshold"`
+	PartSize           int64 `koanf:"part_size"`
+	// MaxAttempts and MaxBackoff bound the retries of throttled and failed
+	// requests, which back off exponentially with jitter
+	MaxAttempts int           `koanf:"max_attempts"`
+	MaxBackoff  time.Duration `koanf:"max_backoff"`
+	// ServerSideEncryption encrypts stored objects with S3 managed keys
+	// (AES256) or a KMS key (aws:kms), or leaves it to the bucket's default
+	ServerSideEncryption string `koanf:"server_side_encryption" validate:"omitempty,oneof=AES256 aws:kms"`
+	KMSKeyID             string `koanf:"kms_key_id"`
+	// Lifecycle rules are applied to the upload bucket at startup
+	Lifecycle *S3LifecycleConfig `koanf:"lifecycle"`
+}
+
+const (
+	DefaultS3MultipartThreshold = 16 << 20
+	DefaultS3PartSize           = 8 << 20
+	// MinS3PartSize is the smallest part S3 accepts, except for the last
+	MinS3PartSize        = 5 << 20
+	DefaultS3MaxAttempts = 3
+	DefaultS3MaxBackoff  = 20 * time.Second
+)
+
+// S3LifecycleConfig expires temporary uploads and abandoned multipart
+// uploads in the upload bucket. Applying it replaces the bucket's lifecycle
+// rules, so it is off unless the bucket is only managed from here.
+type S3LifecycleConfig struct {
+	Enabled bool `koanf:"enabled"`
+	// Objects under TempPrefix expire TempExpirationDays after upload
+	TempPrefix         string `koanf:"temp_prefix"`
+	TempExpirationDays int    `koanf:"temp_expiration_days"`
+	// Multipart uploads left incomplete are aborted, and their parts deleted,
+	// AbortIncompleteDays after they start
+	AbortIncompleteDays int `koanf:"abort_incomplete_days"`
+}
+
+func DefaultS3LifecycleConfig() *S3LifecycleConfig {
+	return &S3LifecycleConfig{
+		Enabled:             false,
+		TempPrefix:          "tmp/",
+		TempExpirationDays:  1,
+		AbortIncompleteDays: 1,
+	}
 }
 
 type CronConfig struct {
@@ -74,6 +364,10 @@ type CronConfig struct {
 	BatchSize                   int `koanf:"batch_size"`
 	ReminderHours               int `koanf:"reminder_hours"`
 	MaxTodosPerUserNotification int `koanf:"max_todos_per_user_notification"`
+	IntegritySampleSize         int `koanf:"integrity_sample_size"`
+	// WeeklyReportHour is when on Monday, in each user's timezone, weekly
+	// reports are sent. The weekly-reports job has to run hourly.
+	WeeklyReportHour int `koanf:"weekly_report_hour" validate:"min=0,max=23"`
 }
 
 func DefaultCronConfig() *CronConfig {
@@ -82,42 +376,670 @@ func DefaultCronConfig() *CronConfig {
 		BatchSize:                   100,
 		ReminderHours:               24,
 		MaxTodosPerUserNotification: 10,
+		IntegritySampleSize:         200,
+		WeeklyReportHour:            9,
 	}
 }
 
+type HTTPClientConfig struct {
+	Timeout          time.Duration `koanf:"timeout"`
+	MaxRetries       int           `koanf:"max_retries"`
+	RetryWaitMin     time.Duration `koanf:"retry_wait_min"`
+	RetryWaitMax     time.Duration `koanf:"retry_wait_max"`
+	RateLimit        float64       `koanf:"rate_limit"` // requests per second, per host
+	RateBurst        int           `koanf:"rate_burst"`
+	BreakerThreshold int           `koanf:"breaker_threshold"` // consecutive failures before the circuit opens
+	BreakerCooldown  time.Duration `koanf:"breaker_cooldown"`
+	// MaxHosts bounds the hosts whose rate limiter and breaker are kept,
+	// forgetting the least recently used past it
+	MaxHosts int `koanf:"max_hosts"`
+}
+
+func DefaultHTTPClientConfig() *HTTPClientConfig {
+	return &HTTPClientConfig{
+		Timeout:          10 * time.Second,
+		MaxRetries:       3,
+		RetryWaitMin:     200 * time.Millisecond,
+		RetryWaitMax:     5 * time.Second,
+		RateLimit:        10,
+		RateBurst:        20,
+		BreakerThreshold: 5,
+		BreakerCooldown:  30 * time.Second,
+		MaxHosts:         1000,
+	}
+}
+
+// FetcherConfig limits requests to user-supplied URLs
+type FetcherConfig struct {
+	Timeout      time.Duration `koanf:"timeout"`
+	MaxBodyBytes int64         `koanf:"max_body_bytes"`
+	MaxRedirects int           `koanf:"max_redirects"`
+	// AllowPrivateNetworks disables address filtering. Only meant for local development.
+	AllowPrivateNetworks bool `koanf:"allow_private_networks"`
+}
+
+func DefaultFetcherConfig() *FetcherConfig {
+	return &FetcherConfig{
+		Timeout:      5 * time.Second,
+		MaxBodyBytes: 2 << 20, // 2 MiB
+		MaxRedirects: 3,
+	}
+}
+
+// OutboxConfig tunes the relay that publishes events from the outbox table
+type OutboxConfig struct {
+	// PollInterval is how often the relay checks for events when no
+	// notification arrives
+	PollInterval time.Duration `koanf:"poll_interval"`
+	BatchSize    int           `koanf:"batch_size"`
+	// MaxAttempts is how often an event is relayed before it is given up
+	// on as dead
+	MaxAttempts int `koanf:"max_attempts" validate:"min=1"`
+	// Published and dead events are kept this long before cleanup
+	RetentionPeriod time.Duration `koanf:"retention_period"`
+}
+
+func DefaultOutboxConfig() *OutboxConfig {
+	return &OutboxConfig{
+		PollInterval:    5 * time.Second,
+		BatchSize:       100,
+		MaxAttempts:     25,
+		RetentionPeriod: 7 * 24 * time.Hour,
+	}
+}
+
+// AlertsConfig routes operational alerts. Without a webhook URL alerts are
+// only logged.
+type AlertsConfig struct {
+	// WebhookURL accepts a JSON body with a "text" field, as Slack and
+	// compatible incoming webhooks do
+	WebhookURL string `koanf:"webhook_url"`
+}
+
+// JobFailuresConfig controls who hears about background tasks that run out
+// of retries. Failures are always logged and recorded.
+type JobFailuresConfig struct {
+	// WatchInterval is how often archived tasks are checked for failures
+	// the job server didn't report, like tasks lost to a crashed worker
+	WatchInterval time.Duration `koanf:"watch_interval"`
+	// AlertEmails are emailed about each new failure
+	AlertEmails []string `koanf:"alert_emails"`
+	// SlackAlerts sends each new failure to the alerts webhook
+	SlackAlerts bool `koanf:"slack_alerts"`
+}
+
+func DefaultJobFailuresConfig() *JobFailuresConfig {
+	return &JobFailuresConfig{
+		WatchInterval: time.Minute,
+		SlackAlerts:   true,
+	}
+}
+
+// UsageConfig controls how resource use counted for cost attribution is
+// stored
+type UsageConfig struct {
+	// FlushInterval is how often each instance adds its counters to the
+	// daily usage table
+	FlushInterval time.Duration `koanf:"flush_interval"`
+}
+
+func DefaultUsageConfig() *UsageConfig {
+	return &UsageConfig{
+		FlushInterval: time.Minute,
+	}
+}
+
+// ValidationReportConfig controls the periodic report of the request fields
+// that failed binding or validation, by route and client
+type ValidationReportConfig struct {
+	// Interval is how often each instance reports the failures it counted
+	Interval time.Duration `koanf:"interval"`
+	// Top is how many of the most frequent failures are reported
+	Top int `koanf:"top"`
+	// MaxFailures bounds the distinct failures counted between reports.
+	// Failures beyond are only counted as dropped.
+	MaxFailures int `koanf:"max_failures"`
+}
+
+func DefaultValidationReportConfig() *ValidationReportConfig {
+	return &ValidationReportConfig{
+		Interval:    time.Hour,
+		Top:         20,
+		MaxFailures: 1000,
+	}
+}
+
+// ShutdownConfig bounds the stages of a graceful shutdown. A stage that runs
+// out of time is left behind and the next one starts, so together they bound
+// how long stopping takes, which should fit the platform's grace period.
+type ShutdownConfig struct {
+	// HTTP is how long in-flight requests get to finish
+	HTTP time.Duration `koanf:"http"`
+	// Jobs is how long active tasks get to finish. Tasks still running
+	// after it are put back in their queue.
+	Jobs time.Duration `koanf:"jobs"`
+	// Flush is how long each flush of what requests and tasks left behind,
+	// such as outbox events, gets
+	Flush time.Duration `koanf:"flush"`
+	// Close is how long closing the connections to Redis and the database
+	// gets
+	Close time.Duration `koanf:"close"`
+}
+
+func DefaultShutdownConfig() *ShutdownConfig {
+	return &ShutdownConfig{
+		HTTP:  10 * time.Second,
+		Jobs:  10 * time.Second,
+		Flush: 5 * time.Second,
+		Close: 5 * time.Second,
+	}
+}
+
+// RateLimitConfig limits the requests each client IP makes to the API
+type RateLimitConfig struct {
+	Rate  float64 `koanf:"rate"` // requests per second
+	Burst int     `koanf:"burst"`
+	// ExpiresIn is how long the limit of a client that stopped making
+	// requests is kept
+	ExpiresIn time.Duration `koanf:"expires_in"`
+}
+
+func DefaultRateLimitConfig() *RateLimitConfig {
+	return &RateLimitConfig{
+		Rate:      20,
+		Burst:     20,
+		ExpiresIn: 3 * time.Minute,
+	}
+}
+
+// ReloadConfig configures reloading the config while the server runs
+type ReloadConfig struct {
+	// WatchInterval is how often the config file is checked for changes
+	WatchInterval time.Duration `koanf:"watch_interval"`
+}
+
+func DefaultReloadConfig() *ReloadConfig {
+	return &ReloadConfig{
+		WatchInterval: 10 * time.Second,
+	}
+}
+
+// ReadOnlyConfig engages read-only mode, in which writes are rejected and
+// reads served as usual, such as while the database is restored. Admins can
+// engage it too, and the database engages it while it rejects writes.
+type ReadOnlyConfig struct {
+	Enabled bool `koanf:"enabled"`
+	// Reason is logged and shown to admins
+	Reason string `koanf:"reason"`
+	// CheckInterval is how often an admin's switch is synced from the other
+	// instances, and a read-only database checked for accepting writes again
+	CheckInterval time.Duration `koanf:"check_interval"`
+}
+
+func DefaultReadOnlyConfig() *ReadOnlyConfig {
+	return &ReadOnlyConfig{
+		CheckInterval: 5 * time.Second,
+	}
+}
+
+// SandboxConfig designates a sandbox workspace, such as for a public demo
+// instance or integration partners to test against. The sandbox-reset job
+// deletes everything in it and seeds the sandbox data set again, so nothing
+// written there lasts beyond the night.
+type SandboxConfig struct {
+	Enabled bool `koanf:"enabled"`
+	// WorkspaceID is the sandbox workspace, which is created by the first
+	// reset. It defaults to an id of its own, apart from any real workspace.
+	WorkspaceID string `koanf:"workspace_id"`
+	// OwnerID owns the sandbox workspace, and defaults to a demo user
+	OwnerID string `koanf:"owner_id"`
+	// Members join the sandbox workspace as admins on every reset
+	Members []string `koanf:"members"`
+}
+
+// TrialsConfig configures anonymous trials: workspaces used with a token
+// instead of an account until a user signs up and claims them
+type TrialsConfig struct {
+	Enabled bool `koanf:"enabled"`
+	// TTL is how long a trial lasts unclaimed before the trial-cleanup job
+	// deletes it
+	TTL time.Duration `koanf:"ttl"`
+}
+
+func DefaultTrialsConfig() *TrialsConfig {
+	return &TrialsConfig{
+		TTL: 7 * 24 * time.Hour,
+	}
+}
+
+// ExportsConfig configures scheduled exports. EncryptionKey seals the
+// credentials of export destinations in the database, see package seal. It
+// is 32 bytes encoded in base64, best kept in a secret store. Without it,
+// destinations with credentials can't be saved.
+type ExportsConfig struct {
+	EncryptionKey string `koanf:"encryption_key"`
+}
+
+// ClientVersionsConfig turns away clients too old to be served safely, such
+// as apps whose sync logic predates a change to the data they sync. Clients
+// name their platform and version in the X-Client-Version header, such as
+// ios/2.3.1. Requests without it, and platforms left out, are always
+// served.
+type ClientVersionsConfig struct {
+	Platforms map[string]ClientPlatformConfig `koanf:"platforms"`
+}
+
+type ClientPlatformConfig struct {
+	// MinVersion is the oldest version served, such as 2.3.0
+	MinVersion string `koanf:"min_version"`
+	// UpgradeURL is where older clients are sent to upgrade, such as the
+	// app's store page
+	UpgradeURL string `koanf:"upgrade_url"`
+}
+
+var versionPattern = regexp.MustCompile(`^v?\d+(\.\d+){0,2}$`)
+
+func (c *ClientVersionsConfig) validate(p *problems) {
+	for platform, pc := range c.Platforms {
+		key := "client_versions.platforms." + platform
+		if !versionPattern.MatchString(pc.MinVersion) {
+			p.add(key+".min_version", "must be a version such as 2.3.0, not %q", pc.MinVersion)
+		}
+		if pc.UpgradeURL != "" {
+			if u, err := url.Parse(pc.UpgradeURL); err != nil || u.Scheme == "" || u.Host == "" {
+				p.add(key+".upgrade_url", "must be an absolute URL")
+			}
+		}
+	}
+}
+
+// RolloutsConfig paces the backfills of expand/contract schema changes, so
+// they don't compete with regular traffic for the database
+type RolloutsConfig struct {
+	BatchSize int `koanf:"batch_size"`
+	// BatchPause is how long to wait between batches
+	BatchPause time.Duration `koanf:"batch_pause"`
+	// MaxRunTime is how long one backfill task runs before it queues the
+	// next one to continue, which keeps it well within the task timeout
+	MaxRunTime time.Duration `koanf:"max_run_time"`
+}
+
+func DefaultRolloutsConfig() *RolloutsConfig {
+	return &RolloutsConfig{
+		BatchSize:  1000,
+		BatchPause: 100 * time.Millisecond,
+		MaxRunTime: 5 * time.Minute,
+	}
+}
+
+// SearchConfig points todo search at an external engine. Without a URL, and
+// while the engine is failing, todos are searched with Postgres full text
+// search instead.
+type SearchConfig struct {
+	URL    string `koanf:"url"`
+	APIKey string `koanf:"api_key"`
+	Index  string `koanf:"index"`
+	// Timeout is kept short, since falling back beats waiting on a slow
+	// engine
+	Timeout time.Duration `koanf:"timeout"`
+	// BreakerThreshold consecutive failures send searches straight to
+	// Postgres for BreakerCooldown
+	BreakerThreshold int           `koanf:"breaker_threshold"`
+	BreakerCooldown  time.Duration `koanf:"breaker_cooldown"`
+}
+
+func DefaultSearchConfig() *SearchConfig {
+	return &SearchConfig{
+		Index:            "todos",
+		Timeout:          2 * time.Second,
+		BreakerThreshold: 3,
+		BreakerCooldown:  30 * time.Second,
+	}
+}
+
+// AttachmentsConfig is the policy uploaded attachments are held to, and
+// how the files derived from them are made
+type AttachmentsConfig struct {
+	// MaxSize is the largest attachment in bytes
+	MaxSize int64 `koanf:"max_size"`
+	// AllowedTypes are the MIME types attachments can have, such as
+	// application/pdf or image/*. Any type is allowed when empty.
+	AllowedTypes []string `koanf:"allowed_types"`
+	// ThumbnailSize is the longest side of image thumbnails in pixels
+	ThumbnailSize int `koanf:"thumbnail_size"`
+	// MaxTextSize bounds the text extracted from a PDF for search, in bytes
+	MaxTextSize int `koanf:"max_text_size"`
+	// Scan is the malware scanning of uploaded files
+	Scan *AttachmentScanConfig `koanf:"scan"`
+	// Quota limits the attachment bytes users and workspaces store
+	Quota *AttachmentQuotaConfig `koanf:"quota"`
+}
+
+// AttachmentQuotaConfig caps the bytes of attachments stored, checked
+// before each upload. A quota of 0 is unlimited.
+type AttachmentQuotaConfig struct {
+	// UserBytes bounds the attachments a user uploaded, across workspaces
+	UserBytes int64 `koanf:"user_bytes" validate:"gte=0"`
+	// WorkspaceBytes bounds the attachments in a workspace
+	WorkspaceBytes int64 `koanf:"workspace_bytes" validate:"gte=0"`
+	// WarnPercent is the share of a quota past which users are warned, as
+	// they are again once it is used up
+	WarnPercent int `koanf:"warn_percent" validate:"lt=100"`
+}
+
+// AttachmentScanConfig selects the scanner attachments are checked with
+// before anything is derived from them. Infected files are moved under
+// QuarantinePrefix and can't be downloaded.
+type AttachmentScanConfig struct {
+	// Provider is none, the default, or clamav
+	Provider ScanProvider `koanf:"provider" validate:"omitempty,oneof=none clamav"`
+	// Address is the host:port of clamd's TCP socket. clamd's
+	// StreamMaxLength should be at least max_size, or larger files are left
+	// unscanned.
+	Address string `koanf:"address"`
+	// Timeout bounds the scan of one file
+	Timeout          time.Duration `koanf:"timeout"`
+	QuarantinePrefix string        `koanf:"quarantine_prefix"`
+}
+
+type ScanProvider string
+
+const (
+	ScanProviderNone   ScanProvider = "none"
+	ScanProviderClamAV ScanProvider = "clamav"
+)
+
+func DefaultAttachmentsConfig() *AttachmentsConfig {
+	return &AttachmentsConfig{
+		MaxSize:       100 << 20,
+		ThumbnailSize: 256,
+		MaxTextSize:   1 << 20,
+		Scan:          DefaultAttachmentScanConfig(),
+		Quota:         DefaultAttachmentQuotaConfig(),
+	}
+}
+
+func DefaultAttachmentQuotaConfig() *AttachmentQuotaConfig {
+	return &AttachmentQuotaConfig{
+		WarnPercent: 80,
+	}
+}
+
+func DefaultAttachmentScanConfig() *AttachmentScanConfig {
+	return &AttachmentScanConfig{
+		Provider:         ScanProviderNone,
+		Address:          "localhost:3310",
+		Timeout:          2 * time.Minute,
+		QuarantinePrefix: "quarantine/",
+	}
+}
+
+// BackfillsConfig paces the data migrations run from the admin API
+type BackfillsConfig struct {
+	// BatchSize applies to backfills that don't set their own
+	BatchSize  int           `koanf:"batch_size"`
+	BatchPause time.Duration `koanf:"batch_pause"`
+	// MaxRunTime is how long one backfill task runs before it queues the
+	// next one to continue from the checkpoint
+	MaxRunTime time.Duration `koanf:"max_run_time"`
+}
+
+func DefaultBackfillsConfig() *BackfillsConfig {
+	return &BackfillsConfig{
+		BatchSize:  1000,
+		BatchPause: 100 * time.Millisecond,
+		MaxRunTime: 5 * time.Minute,
+	}
+}
+
+// PushConfig holds the credentials of the push notification backends. A
+// backend without credentials is disabled, and devices on its platform get
+// nothing.
+type PushConfig struct {
+	FCM     FCMConfig     `koanf:"fcm"`
+	WebPush WebPushConfig `koanf:"web_push"`
+}
+
+type FCMConfig struct {
+	// CredentialsJSON is the JSON key of a Google service account allowed to
+	// send Firebase Cloud Messaging messages
+	CredentialsJSON string `koanf:"credentials_json"`
+}
+
+type WebPushConfig struct {
+	// VAPIDPrivateKey is the raw P-256 private key, base64url encoded. The
+	// public key clients subscribe with is derived from it.
+	VAPIDPrivateKey string `koanf:"vapid_private_key"`
+	// Subject is the contact push services reach out to, a mailto: or
+	// https: URL
+	Subject string `koanf:"subject"`
+	// TTL is how long a push service keeps a message for an offline device
+	TTL time.Duration `koanf:"ttl"`
+}
+
+func DefaultPushConfig() *PushConfig {
+	return &PushConfig{
+		WebPush: WebPushConfig{
+			TTL: 24 * time.Hour,
+		},
+	}
+}
+
+// SchedulerConfig declares when the cron jobs run inside the job server.
+// Schedules maps a job name, as listed by `cron list`, to a cron expression
+// such as "0 9 * * 1" or "@daily". An empty expression disables the job.
+type SchedulerConfig struct {
+	Location  string            `koanf:"location"`
+	Schedules map[string]string `koanf:"schedules"`
+}
+
+func DefaultSchedulerConfig() *SchedulerConfig {
+	return &SchedulerConfig{
+		Location: "UTC",
+		Schedules: map[string]string{
+			"due-date-reminders":    "0 8 * * *",
+			"overdue-notifications": "0 9 * * *",
+			"weekly-reports":        "0 * * * *",
+			"auto-archive":          "0 2 * * *",
+			"export-schedules":      "0 * * * *",
+			"outbox-cleanup":        "30 3 * * *",
+			"snapshot-cleanup":      "45 3 * * *",
+			"attachment-integrity":  "0 4 * * *",
+			"digests":               "*/15 * * * *",
+			"cost-attribution":      "15 0 * * *",
+			// Scheduled todos are published within a minute of their time
+			"publish-scheduled-todos": "* * * * *",
+			"sandbox-reset":           "30 1 * * *",
+			"trial-cleanup":           "0 5 * * *",
+			"email-tracking-cleanup":  "15 5 * * *",
+			"attachment-previews":     "*/30 * * * *",
+			"attachment-processing":   "*/30 * * * *",
+		},
+	}
+}
+
+// withDefaults fills in the jobs and settings the loaded config leaves out.
+// Environment keys can't contain dashes, so job names are written with
+// underscores there.
+func (c *SchedulerConfig) withDefaults() *SchedulerConfig {
+	defaults := DefaultSchedulerConfig()
+
+	schedules := make(map[string]string, len(defaults.Schedules))
+	for name, spec := range defaults.Schedules {
+		schedules[name] = spec
+	}
+	for name, spec := range c.Schedules {
+		schedules[strings.ReplaceAll(name, "_", "-")] = strings.TrimSpace(spec)
+	}
+	c.Schedules = schedules
+
+	if c.Location == "" {
+		c.Location = defaults.Location
+	}
+
+	return c
+}
+
+// SlowConsumerPolicy decides what happens to a realtime connection whose
+// send buffer is full
+type SlowConsumerPolicy string
+
+const (
+	// SlowConsumerDropOldest discards the oldest queued messages to make room
+	SlowConsumerDropOldest SlowConsumerPolicy = "drop_oldest"
+	// SlowConsumerDropNewest discards messages that don't fit
+	SlowConsumerDropNewest SlowConsumerPolicy = "drop_newest"
+	// SlowConsumerDisconnect closes the connection, so the client reconnects
+	// and refetches instead of silently missing events
+	SlowConsumerDisconnect SlowConsumerPolicy = "disconnect"
+)
+
+// RealtimeConfig bounds what each websocket connection may have queued
+// while the client is slow to read
+type RealtimeConfig struct {
+	SendBufferSize     int                `koanf:"send_buffer_size"`  // messages
+	SendBufferBytes    int                `koanf:"send_buffer_bytes"` // total size of queued messages
+	SlowConsumerPolicy SlowConsumerPolicy `koanf:"slow_consumer_policy" validate:"omitempty,oneof=drop_oldest drop_newest disconnect"`
+}
+
+func DefaultRealtimeConfig() *RealtimeConfig {
+	return &RealtimeConfig{
+		SendBufferSize:     64,
+		SendBufferBytes:    1 << 20,
+		SlowConsumerPolicy: SlowConsumerDisconnect,
+	}
+}
+
+// RefCacheConfig bounds the in-process cache of reference data, such as
+// workspace policies
+type RefCacheConfig struct {
+	Size int `koanf:"size"` // entries
+	// TTL is how long an entry is kept, and so how stale it can get when an
+	// invalidation doesn't reach the instance
+	TTL time.Duration `koanf:"ttl"`
+}
+
+func DefaultRefCacheConfig() *RefCacheConfig {
+	return &RefCacheConfig{
+		Size: 10000,
+		TTL:  5 * time.Minute,
+	}
+}
+
+// EarlyHintsConfig tells the web client which origins its next requests go
+// to, so it can open those connections while the API response is still
+// being prepared. Rules maps a name to the routes it applies to and the
+// origins to preconnect to; environment keys add rules or replace the
+// built in ones by name.
+type EarlyHintsConfig struct {
+	Enabled bool                      `koanf:"enabled"`
+	Rules   map[string]EarlyHintsRule `koanf:"rules"`
+}
+
+// EarlyHintsRule lists route patterns as registered under /api/v1, such as
+// "/api/v1/todos/:id". They also match the workspace scoped copies of those
+// routes.
+type EarlyHintsRule struct {
+	Paths   []string `koanf:"paths"`
+	Origins []string `koanf:"origins"`
+}
+
+// DefaultEarlyHintsConfig preconnects to the attachments bucket where todos
+// and their download links are served, and to Clerk's image CDN where the
+// client loads avatars for comments and workspace members
+func DefaultEarlyHintsConfig(awsConfig AWSConfig) *EarlyHintsConfig {
+	return &EarlyHintsConfig{
+		Enabled: true,
+		Rules: map[string]EarlyHintsRule{
+			"attachments": {
+				Paths: []string{
+					"/api/v1/todos/:id",
+					"/api/v1/todos/:id/attachments/:attachmentId/download",
+				},
+				Origins: []string{attachmentsOrigin(awsConfig)},
+			},
+			"avatars": {
+				Paths: []string{
+					"/api/v1/todos/:id",
+					"/api/v1/todos/:id/comments",
+					"/api/v1/workspaces/:workspaceId/members",
+				},
+				Origins: []string{"https://img.clerk.com"},
+			},
+		},
+	}
+}
+
+// attachmentsOrigin is where presigned attachment URLs point. S3 clients
+// address buckets virtual-host style, on AWS and custom endpoints alike.
+func attachmentsOrigin(awsConfig AWSConfig) string {
+	if awsConfig.EndpointURL != "" {
+		if endpoint, err := url.Parse(awsConfig.EndpointURL); err == nil && endpoint.Host != "" {
+			return fmt.Sprintf("%s://%s.%s", endpoint.Scheme, awsConfig.UploadBucket, endpoint.Host)
+		}
+	}
+
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com", awsConfig.UploadBucket, awsConfig.Region)
+}
+
+// withDefaults keeps the built in rules the loaded config doesn't replace
+func (c *EarlyHintsConfig) withDefaults(awsConfig AWSConfig) *EarlyHintsConfig {
+	rules := DefaultEarlyHintsConfig(awsConfig).Rules
+	for name, rule := range c.Rules {
+		rules[name] = rule
+	}
+	c.Rules = rules
+
+	return c
+}
+
+// LoadConfig loads the config from the default sources
 func LoadConfig() (*Config, error) {
-	errLogger := zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr}).With().Timestamp().Logger()
+	return Load(DefaultSources())
+}
 
+// Load loads the config from sources, layered as described on Sources, and
+// fills in the defaults of the values none of them set
+func Load(sources Sources) (*Config, error) {
 	k := koanf.New(".")
+
+	if sources.File != "" {
+		if err := k.Load(fileProvider(sources.File), jsonParser{}); err != nil {
+			return nil, fmt.Errorf("could not load config file %s: %w", sources.File, err)
+		}
+	}
+
 	provider := env.Provider("TASKER_", ".", func(s string) string {
 		return strings.ToLower(strings.TrimPrefix(s, "TASKER_"))
 	})
+	if err := k.Load(provider, nil); err != nil {
+		return nil, fmt.Errorf("could not load env variables: %w", err)
+	}
 
-	err := k.Load(provider, nil)
-	if err != nil {
-		errLogger.Fatal().Err(err).Msg("could not load initial env variables")
+	for key, value := range sources.Flags {
+		if err := k.Set(strings.ToLower(key), value); err != nil {
+			return nil, fmt.Errorf("could not set %s: %w", key, err)
+		}
 	}
 
-	mainConfig := &Config{}
-	err = k.Unmarshal("", mainConfig)
+	// Secrets are resolved once every source is layered, so any of them can
+	// refer to one
+	resolved, err := resolveSecrets(k)
 	if err != nil {
-		errLogger.Fatal().Err(err).Msg("could not unmarshal main config")
+		return nil, err
 	}
 
-	// DEBUG: Print loaded config
-	errLogger.Info().
-		Str("db_host", mainConfig.Database.Host).
-		Int("db_port", mainConfig.Database.Port).
-		Str("db_user", mainConfig.Database.User).
-		Str("db_name", mainConfig.Database.Name).
-		Str("db_ssl", mainConfig.Database.SSLMode).
-		Str("redis", mainConfig.Redis.Address).
-		Msg("DEBUG: Loaded config values")
+	mainConfig := &Config{Sources: sources, resolved: resolved}
+	if err := k.Unmarshal("", mainConfig); err != nil {
+		return nil, fmt.Errorf("could not unmarshal main config: %w", err)
+	}
 
-	validate := validator.New()
-	err = validate.Struct(mainConfig)
-	if err != nil {
-		errLogger.Fatal().Err(err).Msg("could not validate main config")
+	if mainConfig.Mode == "" {
+		mainConfig.Mode = ModeAll
+	}
+
+	if mainConfig.Auth.Provider == "" {
+		mainConfig.Auth.Provider = AuthProviderClerk
 	}
 
 	if mainConfig.Observability == nil {
@@ -125,9 +1047,10 @@ func LoadConfig() (*Config, error) {
 	}
 	mainConfig.Observability.ServiceName = "tasker"
 	mainConfig.Observability.Environment = mainConfig.Primary.Env
-
-	if err := mainConfig.Observability.Validate(); err != nil {
-		errLogger.Fatal().Err(err).Msg("invalid observability config")
+	if mainConfig.Observability.Logging.Access == nil {
+		mainConfig.Observability.Logging.Access = DefaultAccessLogConfig()
+	} else if mainConfig.Observability.Logging.Access.MaxBodySize <= 0 {
+		mainConfig.Observability.Logging.Access.MaxBodySize = DefaultAccessLogConfig().MaxBodySize
 	}
 
 	// Set default cron config if not provided
@@ -135,5 +1058,351 @@ func LoadConfig() (*Config, error) {
 		mainConfig.Cron = DefaultCronConfig()
 	}
 
+	// Set default outbound HTTP client config if not provided
+	if mainConfig.HTTPClient == nil {
+		mainConfig.HTTPClient = DefaultHTTPClientConfig()
+	}
+
+	// Set default fetcher config if not provided
+	if mainConfig.Fetcher == nil {
+		mainConfig.Fetcher = DefaultFetcherConfig()
+	}
+
+	// Set default email tracking config, filling in any limits that were
+	// left out
+	defaultEmailTracking := DefaultEmailTrackingConfig()
+	if mainConfig.Email.Tracking == nil {
+		mainConfig.Email.Tracking = defaultEmailTracking
+	} else {
+		if mainConfig.Email.Tracking.Retention <= 0 {
+			mainConfig.Email.Tracking.Retention = defaultEmailTracking.Retention
+		}
+		if mainConfig.Email.Tracking.DigestBackoffAfter <= 0 {
+			mainConfig.Email.Tracking.DigestBackoffAfter = defaultEmailTracking.DigestBackoffAfter
+		}
+		if mainConfig.Email.Tracking.DeadAfter <= 0 {
+			mainConfig.Email.Tracking.DeadAfter = defaultEmailTracking.DeadAfter
+		}
+	}
+
+	// Set default outbox config if not provided
+	// Configs from before dead events keep the default attempts
+	if mainConfig.Outbox == nil {
+		mainConfig.Outbox = DefaultOutboxConfig()
+	} else if mainConfig.Outbox.MaxAttempts == 0 {
+		mainConfig.Outbox.MaxAttempts = DefaultOutboxConfig().MaxAttempts
+	}
+
+	// Set default scheduler config, keeping any schedules that were provided
+	if mainConfig.Scheduler == nil {
+		mainConfig.Scheduler = DefaultSchedulerConfig()
+	} else {
+		mainConfig.Scheduler.withDefaults()
+	}
+
+	// Alerts are optional
+	if mainConfig.Alerts == nil {
+		mainConfig.Alerts = &AlertsConfig{}
+	}
+
+	// Set default job policies, keeping any policies that were provided
+	if mainConfig.Jobs == nil {
+		mainConfig.Jobs = DefaultJobsConfig()
+	}
+	mainConfig.Jobs.withDefaults()
+
+	// Set default job failure config if not provided
+	if mainConfig.JobFailures == nil {
+		mainConfig.JobFailures = DefaultJobFailuresConfig()
+	} else if mainConfig.JobFailures.WatchInterval <= 0 {
+		mainConfig.JobFailures.WatchInterval = DefaultJobFailuresConfig().WatchInterval
+	}
+
+	// Set default realtime config, filling in any limits that were left out
+	defaultRealtime := DefaultRealtimeConfig()
+	if mainConfig.Realtime == nil {
+		mainConfig.Realtime = defaultRealtime
+	} else {
+		if mainConfig.Realtime.SendBufferSize <= 0 {
+			mainConfig.Realtime.SendBufferSize = defaultRealtime.SendBufferSize
+		}
+		if mainConfig.Realtime.SendBufferBytes <= 0 {
+			mainConfig.Realtime.SendBufferBytes = defaultRealtime.SendBufferBytes
+		}
+		if mainConfig.Realtime.SlowConsumerPolicy == "" {
+			mainConfig.Realtime.SlowConsumerPolicy = defaultRealtime.SlowConsumerPolicy
+		}
+	}
+
+	defaultRefCache := DefaultRefCacheConfig()
+	if mainConfig.RefCache == nil {
+		mainConfig.RefCache = defaultRefCache
+	} else {
+		if mainConfig.RefCache.Size <= 0 {
+			mainConfig.RefCache.Size = defaultRefCache.Size
+		}
+		if mainConfig.RefCache.TTL <= 0 {
+			mainConfig.RefCache.TTL = defaultRefCache.TTL
+		}
+	}
+
+	// Set default usage config if not provided
+	if mainConfig.Usage == nil {
+		mainConfig.Usage = DefaultUsageConfig()
+	} else if mainConfig.Usage.FlushInterval <= 0 {
+		mainConfig.Usage.FlushInterval = DefaultUsageConfig().FlushInterval
+	}
+
+	// Set default validation report config if not provided
+	defaultValidationReport := DefaultValidationReportConfig()
+	if mainConfig.ValidationReport == nil {
+		mainConfig.ValidationReport = defaultValidationReport
+	} else {
+		if mainConfig.ValidationReport.Interval <= 0 {
+			mainConfig.ValidationReport.Interval = defaultValidationReport.Interval
+		}
+		if mainConfig.ValidationReport.Top <= 0 {
+			mainConfig.ValidationReport.Top = defaultValidationReport.Top
+		}
+		if mainConfig.ValidationReport.MaxFailures <= 0 {
+			mainConfig.ValidationReport.MaxFailures = defaultValidationReport.MaxFailures
+		}
+	}
+
+	// Set default shutdown config if not provided
+	defaultShutdown := DefaultShutdownConfig()
+	if mainConfig.Shutdown == nil {
+		mainConfig.Shutdown = defaultShutdown
+	} else {
+		if mainConfig.Shutdown.HTTP <= 0 {
+			mainConfig.Shutdown.HTTP = defaultShutdown.HTTP
+		}
+		if mainConfig.Shutdown.Jobs <= 0 {
+			mainConfig.Shutdown.Jobs = defaultShutdown.Jobs
+		}
+		if mainConfig.Shutdown.Flush <= 0 {
+			mainConfig.Shutdown.Flush = defaultShutdown.Flush
+		}
+		if mainConfig.Shutdown.Close <= 0 {
+			mainConfig.Shutdown.Close = defaultShutdown.Close
+		}
+	}
+
+	// Set default rate limit config, filling in any limits that were left out
+	defaultRateLimit := DefaultRateLimitConfig()
+	if mainConfig.RateLimit == nil {
+		mainConfig.RateLimit = defaultRateLimit
+	} else {
+		if mainConfig.RateLimit.Rate <= 0 {
+			mainConfig.RateLimit.Rate = defaultRateLimit.Rate
+		}
+		if mainConfig.RateLimit.Burst <= 0 {
+			mainConfig.RateLimit.Burst = defaultRateLimit.Burst
+		}
+		if mainConfig.RateLimit.ExpiresIn <= 0 {
+			mainConfig.RateLimit.ExpiresIn = defaultRateLimit.ExpiresIn
+		}
+	}
+
+	// Set default reload config if not provided
+	if mainConfig.Reload == nil {
+		mainConfig.Reload = DefaultReloadConfig()
+	} else if mainConfig.Reload.WatchInterval <= 0 {
+		mainConfig.Reload.WatchInterval = DefaultReloadConfig().WatchInterval
+	}
+
+	// Set default read-only config if not provided
+	if mainConfig.ReadOnly == nil {
+		mainConfig.ReadOnly = DefaultReadOnlyConfig()
+	} else if mainConfig.ReadOnly.CheckInterval <= 0 {
+		mainConfig.ReadOnly.CheckInterval = DefaultReadOnlyConfig().CheckInterval
+	}
+
+	// The sandbox is off unless enabled
+	if mainConfig.Sandbox == nil {
+		mainConfig.Sandbox = &SandboxConfig{}
+	}
+
+	// Set default secrets config, filling in any values not provided
+	defaultSecrets := DefaultSecretsConfig()
+	if mainConfig.Secrets == nil {
+		mainConfig.Secrets = defaultSecrets
+	} else {
+		if mainConfig.Secrets.Timeout <= 0 {
+			mainConfig.Secrets.Timeout = defaultSecrets.Timeout
+		}
+		if mainConfig.Secrets.RotationInterval <= 0 {
+			mainConfig.Secrets.RotationInterval = defaultSecrets.RotationInterval
+		}
+	}
+
+	// Set default trials config if not provided
+	if mainConfig.Trials == nil {
+		mainConfig.Trials = DefaultTrialsConfig()
+	} else if mainConfig.Trials.TTL <= 0 {
+		mainConfig.Trials.TTL = DefaultTrialsConfig().TTL
+	}
+
+	// Exports only need a key once a destination is saved
+	if mainConfig.Exports == nil {
+		mainConfig.Exports = &ExportsConfig{}
+	}
+
+	// Push backends are optional
+	if mainConfig.Push == nil {
+		mainConfig.Push = DefaultPushConfig()
+	} else if mainConfig.Push.WebPush.TTL <= 0 {
+		mainConfig.Push.WebPush.TTL = DefaultPushConfig().WebPush.TTL
+	}
+
+	// Set default rollouts config, filling in any values not provided
+	defaultRollouts := DefaultRolloutsConfig()
+	if mainConfig.Rollouts == nil {
+		mainConfig.Rollouts = defaultRollouts
+	} else {
+		if mainConfig.Rollouts.BatchSize <= 0 {
+			mainConfig.Rollouts.BatchSize = defaultRollouts.BatchSize
+		}
+		if mainConfig.Rollouts.BatchPause < 0 {
+			mainConfig.Rollouts.BatchPause = defaultRollouts.BatchPause
+		}
+		if mainConfig.Rollouts.MaxRunTime <= 0 {
+			mainConfig.Rollouts.MaxRunTime = defaultRollouts.MaxRunTime
+		}
+	}
+
+	defaultBackfills := DefaultBackfillsConfig()
+	if mainConfig.Backfills == nil {
+		mainConfig.Backfills = defaultBackfills
+	} else {
+		if mainConfig.Backfills.BatchSize <= 0 {
+			mainConfig.Backfills.BatchSize = defaultBackfills.BatchSize
+		}
+		if mainConfig.Backfills.BatchPause < 0 {
+			mainConfig.Backfills.BatchPause = defaultBackfills.BatchPause
+		}
+		if mainConfig.Backfills.MaxRunTime <= 0 {
+			mainConfig.Backfills.MaxRunTime = defaultBackfills.MaxRunTime
+		}
+	}
+
+	defaultSearch := DefaultSearchConfig()
+	if mainConfig.Search == nil {
+		mainConfig.Search = defaultSearch
+	} else {
+		if mainConfig.Search.Index == "" {
+			mainConfig.Search.Index = defaultSearch.Index
+		}
+		if mainConfig.Search.Timeout <= 0 {
+			mainConfig.Search.Timeout = defaultSearch.Timeout
+		}
+		if mainConfig.Search.BreakerThreshold <= 0 {
+			mainConfig.Search.BreakerThreshold = defaultSearch.BreakerThreshold
+		}
+		if mainConfig.Search.BreakerCooldown <= 0 {
+			mainConfig.Search.BreakerCooldown = defaultSearch.BreakerCooldown
+		}
+	}
+
+	if mainConfig.AWS.MultipartThreshold <= 0 {
+		mainConfig.AWS.MultipartThreshold = DefaultS3MultipartThreshold
+	}
+	if mainConfig.AWS.PartSize <= 0 {
+		mainConfig.AWS.PartSize = DefaultS3PartSize
+	}
+	if mainConfig.AWS.MaxAttempts <= 0 {
+		mainConfig.AWS.MaxAttempts = DefaultS3MaxAttempts
+	}
+	if mainConfig.AWS.MaxBackoff <= 0 {
+		mainConfig.AWS.MaxBackoff = DefaultS3MaxBackoff
+	}
+
+	defaultLifecycle := DefaultS3LifecycleConfig()
+	if mainConfig.AWS.Lifecycle == nil {
+		mainConfig.AWS.Lifecycle = defaultLifecycle
+	} else {
+		if mainConfig.AWS.Lifecycle.TempPrefix == "" {
+			mainConfig.AWS.Lifecycle.TempPrefix = defaultLifecycle.TempPrefix
+		}
+		if mainConfig.AWS.Lifecycle.TempExpirationDays <= 0 {
+			mainConfig.AWS.Lifecycle.TempExpirationDays = defaultLifecycle.TempExpirationDays
+		}
+		if mainConfig.AWS.Lifecycle.AbortIncompleteDays <= 0 {
+			mainConfig.AWS.Lifecycle.AbortIncompleteDays = defaultLifecycle.AbortIncompleteDays
+		}
+	}
+
+	defaultAttachments := DefaultAttachmentsConfig()
+	if mainConfig.Attachments == nil {
+		mainConfig.Attachments = defaultAttachments
+	} else {
+		if mainConfig.Attachments.MaxSize <= 0 {
+			mainConfig.Attachments.MaxSize = defaultAttachments.MaxSize
+		}
+		if mainConfig.Attachments.ThumbnailSize <= 0 {
+			mainConfig.Attachments.ThumbnailSize = defaultAttachments.ThumbnailSize
+		}
+		if mainConfig.Attachments.MaxTextSize <= 0 {
+			mainConfig.Attachments.MaxTextSize = defaultAttachments.MaxTextSize
+		}
+		if mainConfig.Attachments.Scan == nil {
+			mainConfig.Attachments.Scan = defaultAttachments.Scan
+		} else {
+			scan := mainConfig.Attachments.Scan
+			if scan.Provider == "" {
+				scan.Provider = defaultAttachments.Scan.Provider
+			}
+			if scan.Address == "" {
+				scan.Address = defaultAttachments.Scan.Address
+			}
+			if scan.Timeout <= 0 {
+				scan.Timeout = defaultAttachments.Scan.Timeout
+			}
+			if scan.QuarantinePrefix == "" {
+				scan.QuarantinePrefix = defaultAttachments.Scan.QuarantinePrefix
+			}
+		}
+		if mainConfig.Attachments.Quota == nil {
+			mainConfig.Attachments.Quota = defaultAttachments.Quota
+		} else if mainConfig.Attachments.Quota.WarnPercent <= 0 {
+			mainConfig.Attachments.Quota.WarnPercent = defaultAttachments.Quota.WarnPercent
+		}
+	}
+
+	if mainConfig.Database.HealthCheckPeriod <= 0 {
+		mainConfig.Database.HealthCheckPeriod = DefaultDBHealthCheckPeriod
+	}
+	if mainConfig.Database.StatsInterval <= 0 {
+		mainConfig.Database.StatsInterval = DefaultDBStatsInterval
+	}
+	if mainConfig.Database.ReadTimeout <= 0 {
+		mainConfig.Database.ReadTimeout = DefaultDBReadTimeout
+	}
+	if mainConfig.Database.WriteTimeout <= 0 {
+		mainConfig.Database.WriteTimeout = DefaultDBWriteTimeout
+	}
+	if mainConfig.Database.FailoverRetries <= 0 {
+		mainConfig.Database.FailoverRetries = DefaultDBFailoverRetries
+	}
+	if mainConfig.Database.FailoverBackoff <= 0 {
+		mainConfig.Database.FailoverBackoff = DefaultDBFailoverBackoff
+	}
+
+	if replica := mainConfig.Database.ReadReplica; replica != nil && replica.StickyWindow <= 0 {
+		replica.StickyWindow = DefaultReplicaStickyWindow
+	}
+
+	// Set default early hints config, keeping any rules that were provided
+	if mainConfig.EarlyHints == nil {
+		mainConfig.EarlyHints = DefaultEarlyHintsConfig(mainConfig.AWS)
+	} else {
+		mainConfig.EarlyHints.withDefaults(mainConfig.AWS)
+	}
+
+	// Every problem is reported at once, rather than one per start
+	if err := mainConfig.Validate(); err != nil {
+		return nil, err
+	}
+
 	return mainConfig, nil
 }
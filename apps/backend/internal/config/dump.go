@@ -0,0 +1,117 @@
+package config
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mabhi256/tasker/internal/lib/redact"
+)
+
+// secretFields are the config keys redacted from dumps along with the ones
+// redact always covers, such as passwords, secrets and tokens
+var secretFields = []string{"licensekey", "privatekey", "accesskey", "dsn"}
+
+// Dump is the effective config by key, such as observability.logging.level,
+// with secrets and email addresses redacted, for logging. Secrets that are
+// set show as redacted and those that aren't as empty. Values resolved from
+// a secret store show the reference they were resolved from.
+func (c *Config) Dump() map[string]any {
+	redactor := redact.New(secretFields...)
+
+	values := c.flatten()
+	for key, value := range values {
+		s, ok := value.(string)
+		if !ok {
+			continue
+		}
+		if c.IsSecret(key) {
+			values[key] = c.resolved.refs[key].String()
+			continue
+		}
+
+		name := key[strings.LastIndex(key, ".")+1:]
+		if redactor.Sensitive(name) {
+			if s != "" {
+				values[key] = redact.Placeholder
+			}
+			continue
+		}
+		values[key] = redactor.String(s)
+	}
+
+	return values
+}
+
+// Changed lists the keys whose values differ between the configs, sorted
+func Changed(old, updated *Config) []string {
+	oldValues, updatedValues := old.flatten(), updated.flatten()
+
+	keys := slices.Collect(maps.Keys(oldValues))
+	for key := range updatedValues {
+		if _, ok := oldValues[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	return slices.DeleteFunc(keys, func(key string) bool {
+		return reflect.DeepEqual(oldValues[key], updatedValues[key])
+	})
+}
+
+// flatten maps the config's keys to their values. Unset sections map to nil
+// and empty maps are left out.
+func (c *Config) flatten() map[string]any {
+	values := map[string]any{}
+	flatten(reflect.ValueOf(c).Elem(), "", values)
+	return values
+}
+
+func flatten(v reflect.Value, key string, values map[string]any) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			values[key] = nil
+			return
+		}
+		flatten(v.Elem(), key, values)
+
+	case reflect.Struct:
+		t := v.Type()
+		for i := range t.NumField() {
+			field := t.Field(i)
+			name := field.Tag.Get("koanf")
+			if !field.IsExported() || name == "" || name == "-" {
+				continue
+			}
+			flatten(v.Field(i), joinKey(key, name), values)
+		}
+
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			values[key] = v.Interface()
+			return
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			flatten(iter.Value(), joinKey(key, iter.Key().String()), values)
+		}
+
+	default:
+		if d, ok := v.Interface().(time.Duration); ok {
+			values[key] = d.String()
+			return
+		}
+		values[key] = v.Interface()
+	}
+}
+
+func joinKey(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
@@ -0,0 +1,207 @@
+package config
+
+import (
+	"strings"
+	"time"
+)
+
+// BackoffStrategy decides how long a failed background task waits before
+// it is retried
+type BackoffStrategy string
+
+const (
+	// BackoffDefault uses asynq's backoff, which grows with the fourth power
+	// of the attempt
+	BackoffDefault BackoffStrategy = "default"
+	// BackoffConstant waits BackoffBase between attempts
+	BackoffConstant BackoffStrategy = "constant"
+	// BackoffExponential doubles the wait from BackoffBase after each
+	// attempt, up to BackoffMax, with jitter
+	BackoffExponential BackoffStrategy = "exponential"
+)
+
+// DefaultJobBackoffMax caps exponential backoff for policies that don't
+// set a cap
+const DefaultJobBackoffMax = time.Hour
+
+// JobsConfig sets how background tasks are retried. Policies maps a task
+// type, such as "email:reminder", to its policy. Environment keys can't
+// contain colons, so the type is written with an underscore there, as in
+// TASKER_JOBS.POLICIES.EMAIL_REMINDER.MAX_RETRY. Task types without a
+// policy, and settings a policy leaves out, keep the defaults the task
+// declares.
+type JobsConfig struct {
+	// Concurrency is how many tasks of the critical, default and low queues
+	// each worker runs at once
+	Concurrency int                  `koanf:"concurrency"`
+	Policies    map[string]JobPolicy `koanf:"policies"`
+	// Queues are the queues of outbound integrations. Each is processed by
+	// its own server, so a slow third party only holds up its own tasks.
+	Queues map[string]QueueConfig `koanf:"queues"`
+}
+
+// QueueConfig sizes an integration queue and its circuit breaker, which
+// pauses the queue for BreakerCooldown after BreakerThreshold tasks in a
+// row fail
+type QueueConfig struct {
+	Concurrency      int           `koanf:"concurrency"`
+	BreakerThreshold int           `koanf:"breaker_threshold"`
+	BreakerCooldown  time.Duration `koanf:"breaker_cooldown"`
+}
+
+// JobPolicy overrides how a task type is queued and retried. Tasks are
+// given their retry count, retention and timeout when they are enqueued, so
+// a change applies to tasks enqueued after it; backoff applies to every
+// retry the worker schedules.
+type JobPolicy struct {
+	// MaxRetry is how many times a failed task is retried; 0 disables
+	// retries
+	MaxRetry *int `koanf:"max_retry"`
+	// Retention is how long a completed task is kept for inspection
+	Retention time.Duration `koanf:"retention"`
+	// Timeout bounds each attempt
+	Timeout     time.Duration   `koanf:"timeout"`
+	Backoff     BackoffStrategy `koanf:"backoff"`
+	BackoffBase time.Duration   `koanf:"backoff_base"`
+	BackoffMax  time.Duration   `koanf:"backoff_max"`
+}
+
+// DefaultJobsConfig gives up on reminder emails within minutes, as a
+// reminder that arrives hours late is worse than none, and spreads webhook
+// and audit log deliveries over about two hours so a receiver can recover
+// from an outage. Webhooks, audit forwarding and push deliveries get
+// queues of their own.
+func DefaultJobsConfig() *JobsConfig {
+	reminderRetries := 2
+	return &JobsConfig{
+		Concurrency: 10,
+		Queues: map[string]QueueConfig{
+			"webhooks": {Concurrency: 5, BreakerThreshold: 20, BreakerCooldown: time.Minute},
+			"audit":    {Concurrency: 2, BreakerThreshold: 10, BreakerCooldown: time.Minute},
+			"push":     {Concurrency: 5, BreakerThreshold: 50, BreakerCooldown: 30 * time.Second},
+		},
+		Policies: map[string]JobPolicy{
+			"email:reminder": {
+				MaxRetry:    &reminderRetries,
+				Backoff:     BackoffConstant,
+				BackoffBase: 5 * time.Minute,
+			},
+			"webhook:deliver": {
+				Backoff:     BackoffExponential,
+				BackoffBase: 30 * time.Second,
+				BackoffMax:  6 * time.Hour,
+			},
+			"audit:forward": {
+				Backoff:     BackoffExponential,
+				BackoffBase: 30 * time.Second,
+				BackoffMax:  6 * time.Hour,
+			},
+		},
+	}
+}
+
+// withDefaults keeps the built in policies and queues and fills in the
+// settings the loaded ones leave out
+func (c *JobsConfig) withDefaults() *JobsConfig {
+	defaults := DefaultJobsConfig()
+	if c.Concurrency <= 0 {
+		c.Concurrency = defaults.Concurrency
+	}
+
+	queues := defaults.Queues
+	for name, queue := range c.Queues {
+		if queue.Concurrency <= 0 {
+			queue.Concurrency = queues[name].Concurrency
+		}
+		if queue.BreakerThreshold <= 0 {
+			queue.BreakerThreshold = queues[name].BreakerThreshold
+		}
+		if queue.BreakerCooldown <= 0 {
+			queue.BreakerCooldown = queues[name].BreakerCooldown
+		}
+		queues[name] = queue
+	}
+	c.Queues = queues
+
+	policies := defaults.Policies
+	for name, policy := range c.Policies {
+		taskType := name
+		if !strings.Contains(taskType, ":") {
+			taskType = strings.Replace(taskType, "_", ":", 1)
+		}
+
+		if defaults, ok := policies[taskType]; ok {
+			policy = policy.withDefaults(defaults)
+		}
+		policies[taskType] = policy
+	}
+
+	for taskType, policy := range policies {
+		if policy.Backoff == "" {
+			policy.Backoff = BackoffDefault
+		}
+		if policy.Backoff == BackoffExponential && policy.BackoffMax <= 0 {
+			policy.BackoffMax = DefaultJobBackoffMax
+		}
+		policies[taskType] = policy
+	}
+	c.Policies = policies
+
+	return c
+}
+
+func (p JobPolicy) withDefaults(defaults JobPolicy) JobPolicy {
+	if p.MaxRetry == nil {
+		p.MaxRetry = defaults.MaxRetry
+	}
+	if p.Retention <= 0 {
+		p.Retention = defaults.Retention
+	}
+	if p.Timeout <= 0 {
+		p.Timeout = defaults.Timeout
+	}
+	if p.Backoff == "" {
+		p.Backoff = defaults.Backoff
+		if p.BackoffBase <= 0 {
+			p.BackoffBase = defaults.BackoffBase
+		}
+		if p.BackoffMax <= 0 {
+			p.BackoffMax = defaults.BackoffMax
+		}
+	}
+	return p
+}
+
+func (c *JobsConfig) validate(p *problems) {
+	for name, queue := range c.Queues {
+		key := "jobs.queues." + name
+		switch name {
+		case "critical", "default", "low":
+			p.add(key, "is processed by the main server and can't be configured as an integration queue")
+			continue
+		}
+		if queue.Concurrency <= 0 {
+			p.add(key+".concurrency", "must be positive")
+		}
+		if queue.BreakerThreshold > 0 && queue.BreakerCooldown <= 0 {
+			p.add(key+".breaker_cooldown", "must be positive")
+		}
+	}
+
+	for taskType, policy := range c.Policies {
+		key := "jobs.policies." + taskType
+		if policy.MaxRetry != nil && *policy.MaxRetry < 0 {
+			p.add(key+".max_retry", "must be non-negative")
+		}
+
+		switch policy.Backoff {
+		case BackoffDefault:
+		case BackoffConstant, BackoffExponential:
+			if policy.BackoffBase <= 0 {
+				p.add(key+".backoff_base", "must be positive with %s backoff", policy.Backoff)
+			}
+		default:
+			p.add(key+".backoff", "must be one of: default, constant, exponential, not %q", policy.Backoff)
+		}
+	}
+}
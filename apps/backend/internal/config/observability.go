@@ -1,7 +1,6 @@
 package config
 
 import (
-	"fmt"
 	"slices"
 	"time"
 )
@@ -19,6 +18,28 @@ type LoggingConfig struct {
 	Level              string        `koanf:"level" validate:"required"`
 	Format             string        `koanf:"format" validate:"required"`
 	SlowQueryThreshold time.Duration `koanf:"slow_query_threshold"`
+	// Access configures the log line written for each request
+	Access *AccessLogConfig `koanf:"access"`
+}
+
+// AccessLogConfig configures the access log. Request and response bodies
+// are only captured at the debug level.
+type AccessLogConfig struct {
+	// SampleRates are the fractions of requests logged, by status class:
+	// 2xx, 3xx, 4xx or 5xx. Classes left out are always logged.
+	SampleRates map[string]float64 `koanf:"sample_rates"`
+	// MaxBodySize is how many bytes of each body are captured
+	MaxBodySize int `koanf:"max_body_size"`
+	// RedactFields are redacted along with the built in sensitive fields,
+	// such as passwords, tokens and emails
+	RedactFields []string `koanf:"redact_fields"`
+}
+
+func DefaultAccessLogConfig() *AccessLogConfig {
+	return &AccessLogConfig{
+		SampleRates: map[string]float64{},
+		MaxBodySize: 4096,
+	}
 }
 
 type NewRelicConfig struct {
@@ -32,7 +53,7 @@ type HealthCheckConfig struct {
 	Enabled  bool          `koanf:"enabled"`
 	Interval time.Duration `koanf:"interval" validate:"min=1s"`
 	Timeout  time.Duration `koanf:"timeout" validate:"min=1s"`
-	Checks   []string      `koanf:"checks"`
+	Checks   []string      `koanf:"checks" validate:"dive,oneof=database redis queue storage"`
 }
 
 func DefaultObservabilityConfig() *ObservabilityConfig {
@@ -43,6 +64,7 @@ func DefaultObservabilityConfig() *ObservabilityConfig {
 			Level:              "info",
 			Format:             "json",
 			SlowQueryThreshold: 100 * time.Millisecond,
+			Access:             DefaultAccessLogConfig(),
 		},
 		NewRelic: NewRelicConfig{
 			LicenseKey:                "",
@@ -54,26 +76,36 @@ func DefaultObservabilityConfig() *ObservabilityConfig {
 			Enabled:  true,
 			Interval: 30 * time.Second,
 			Timeout:  5 * time.Second,
-			Checks:   []string{"database", "redis"},
+			Checks:   []string{"database", "redis", "queue"},
 		},
 	}
 }
 
-func (oc *ObservabilityConfig) Validate() error {
+func (oc *ObservabilityConfig) validate(p *problems) {
 	if oc.ServiceName == "" {
-		return fmt.Errorf("service_name is required")
+		p.add("observability.service_name", "is required")
 	}
 
 	validLevels := []string{"debug", "info", "warn", "error"}
 	if !slices.Contains(validLevels, oc.Logging.Level) {
-		return fmt.Errorf("invalid logging level: %s (must be one of: debug, info, warn, error)", oc.Logging.Level)
+		p.add("observability.logging.level", "must be one of: debug, info, warn, error, not %q", oc.Logging.Level)
 	}
 
 	if oc.Logging.SlowQueryThreshold < 0 {
-		return fmt.Errorf("logging slow_query_threshold must be non-negative")
+		p.add("observability.logging.slow_query_threshold", "must be non-negative")
 	}
 
-	return nil
+	if access := oc.Logging.Access; access != nil {
+		for class, rate := range access.SampleRates {
+			key := "observability.logging.access.sample_rates." + class
+			if !slices.Contains([]string{"2xx", "3xx", "4xx", "5xx"}, class) {
+				p.add(key, "is not a status class (must be one of: 2xx, 3xx, 4xx, 5xx)")
+			}
+			if rate < 0 || rate > 1 {
+				p.add(key, "must be between 0 and 1")
+			}
+		}
+	}
 }
 
 func (oc *ObservabilityConfig) GetLogLevel() string {
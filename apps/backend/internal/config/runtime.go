@@ -0,0 +1,80 @@
+package config
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+// Reloadable are the keys, and the sections of keys, whose changes are
+// applied while the server runs. Changes to the others take a restart.
+var Reloadable = []string{
+	"observability.logging.level",
+	"rate_limit",
+	"features",
+	"read_only.enabled",
+	"read_only.reason",
+}
+
+// IsReloadable reports whether changes to the key are applied while the
+// server runs
+func IsReloadable(key string) bool {
+	for _, reloadable := range Reloadable {
+		if key == reloadable || strings.HasPrefix(key, reloadable+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// RuntimeValues are the reloadable config values
+type RuntimeValues struct {
+	LogLevel  string
+	RateLimit RateLimitConfig
+	// Features are the feature flags by lowercased name
+	Features map[string]bool
+}
+
+// Runtime holds the reloadable config values, which code that should see
+// their changes reads from it rather than from the Config
+type Runtime struct {
+	values atomic.Pointer[RuntimeValues]
+}
+
+func NewRuntime(cfg *Config) *Runtime {
+	r := &Runtime{}
+	r.Update(cfg)
+	return r
+}
+
+// Update takes the reloadable values of cfg
+func (r *Runtime) Update(cfg *Config) {
+	features := make(map[string]bool, len(cfg.Features))
+	for name, enabled := range cfg.Features {
+		features[strings.ToLower(name)] = enabled
+	}
+
+	values := &RuntimeValues{
+		LogLevel:  "info",
+		RateLimit: *DefaultRateLimitConfig(),
+		Features:  features,
+	}
+	if cfg.Observability != nil {
+		values.LogLevel = cfg.Observability.GetLogLevel()
+	}
+	if cfg.RateLimit != nil {
+		values.RateLimit = *cfg.RateLimit
+	}
+
+	r.values.Store(values)
+}
+
+func (r *Runtime) Values() *RuntimeValues {
+	return r.values.Load()
+}
+
+// FeatureEnabled reports whether the named feature is on. Features are on
+// unless a flag turns them off.
+func (r *Runtime) FeatureEnabled(name string) bool {
+	enabled, ok := r.Values().Features[strings.ToLower(name)]
+	return !ok || enabled
+}
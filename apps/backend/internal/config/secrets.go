@@ -0,0 +1,164 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/knadh/koanf/v2"
+	"github.com/mabhi256/tasker/internal/lib/secrets"
+)
+
+// SecretsConfig configures the secret stores config values can refer to.
+// Any value, from any source, can be a reference such as
+// aws-sm://prod/tasker#db_password or vault://secret/tasker#resend_api_key,
+// which is resolved when the config is loaded. See package secrets.
+type SecretsConfig struct {
+	AWS   SecretsAWSConfig   `koanf:"aws"`
+	Vault SecretsVaultConfig `koanf:"vault"`
+	// Timeout bounds resolving all of the config's references
+	Timeout time.Duration `koanf:"timeout"`
+	// RotationInterval is how often the database password is fetched again
+	// when it refers to a secret, so new connections log in with a rotated
+	// one. Rotation has to keep the previous password working for longer.
+	RotationInterval time.Duration `koanf:"rotation_interval"`
+}
+
+// SecretsAWSConfig configures AWS Secrets Manager, which is reached with the
+// default AWS credentials rather than the ones of the AWS config
+type SecretsAWSConfig struct {
+	// Region defaults to the AWS config's region
+	Region      string `koanf:"region"`
+	EndpointURL string `koanf:"endpoint_url"`
+}
+
+// SecretsVaultConfig configures a Vault KV version 2 secrets engine. The
+// address and token default to VAULT_ADDR and VAULT_TOKEN.
+type SecretsVaultConfig struct {
+	Address   string `koanf:"address"`
+	Token     string `koanf:"token"`
+	Namespace string `koanf:"namespace"`
+}
+
+func DefaultSecretsConfig() *SecretsConfig {
+	return &SecretsConfig{
+		Timeout:          10 * time.Second,
+		RotationInterval: 5 * time.Minute,
+	}
+}
+
+// secretRefs are the secret references the config's values were resolved
+// from, by key
+type secretRefs struct {
+	resolver *secrets.Resolver
+	refs     map[string]secrets.Ref
+}
+
+// resolveSecrets replaces the values of k that refer to secrets with the
+// secrets. The secrets section itself can't refer to them.
+func resolveSecrets(k *koanf.Koanf) (*secretRefs, error) {
+	refs := make(map[string]secrets.Ref)
+	for key, value := range k.All() {
+		s, ok := value.(string)
+		if !ok || strings.HasPrefix(key, "secrets.") {
+			continue
+		}
+		if ref, ok := secrets.ParseRef(s, secrets.SchemeAWS, secrets.SchemeVault); ok {
+			refs[key] = ref
+		}
+	}
+	if len(refs) == 0 {
+		return &secretRefs{}, nil
+	}
+
+	cfg := DefaultSecretsConfig()
+	if err := k.Unmarshal("secrets", cfg); err != nil {
+		return nil, fmt.Errorf("could not unmarshal secrets config: %w", err)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultSecretsConfig().Timeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+
+	resolver, err := newSecretResolver(ctx, cfg, k.String("aws.region"), refs)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, ref := range refs {
+		secret, err := resolver.Resolve(ctx, ref)
+		if err != nil {
+			return nil, fmt.Errorf("could not resolve %s: %w", key, err)
+		}
+		if err := k.Set(key, secret); err != nil {
+			return nil, fmt.Errorf("could not set %s: %w", key, err)
+		}
+	}
+
+	return &secretRefs{resolver: resolver, refs: refs}, nil
+}
+
+// newSecretResolver sets up the stores refs refer to
+func newSecretResolver(ctx context.Context, cfg *SecretsConfig, awsRegion string, refs map[string]secrets.Ref,
+) (*secrets.Resolver, error) {
+	schemes := make(map[string]bool)
+	for _, ref := range refs {
+		schemes[ref.Scheme] = true
+	}
+
+	client := &http.Client{Timeout: cfg.Timeout}
+	resolver := secrets.NewResolver()
+
+	for scheme := range schemes {
+		switch scheme {
+		case secrets.SchemeAWS:
+			region := cfg.AWS.Region
+			if region == "" {
+				region = awsRegion
+			}
+			provider, err := secrets.NewAWS(ctx, region, cfg.AWS.EndpointURL, client)
+			if err != nil {
+				return nil, err
+			}
+			resolver.Register(secrets.SchemeAWS, provider)
+
+		case secrets.SchemeVault:
+			address, token := cfg.Vault.Address, cfg.Vault.Token
+			if address == "" {
+				address = os.Getenv("VAULT_ADDR")
+			}
+			if token == "" {
+				token = os.Getenv("VAULT_TOKEN")
+			}
+			if address == "" || token == "" {
+				return nil, fmt.Errorf("config refers to vault secrets, but no vault address and token are configured")
+			}
+			resolver.Register(secrets.SchemeVault, secrets.NewVault(address, token, cfg.Vault.Namespace, client))
+		}
+	}
+
+	return resolver, nil
+}
+
+// IsSecret reports whether the key's value was resolved from a secret
+func (c *Config) IsSecret(key string) bool {
+	if c.resolved == nil {
+		return false
+	}
+	_, ok := c.resolved.refs[key]
+	return ok
+}
+
+// ResolveSecret fetches the secret the key's value was resolved from again,
+// such as to pick up a rotated credential
+func (c *Config) ResolveSecret(ctx context.Context, key string) (string, error) {
+	if !c.IsSecret(key) {
+		return "", fmt.Errorf("%s doesn't refer to a secret", key)
+	}
+	return c.resolved.resolver.Resolve(ctx, c.resolved.refs[key])
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+)
+
+// FileEnv names the config file when none is given on the command line
+const FileEnv = "TASKER_CONFIG_FILE"
+
+// Sources are where the config is loaded from. Each source overrides the
+// ones above it:
+//
+//  1. the defaults, filled in for the values no source sets
+//  2. File, a JSON file nested by the same keys as the environment
+//     variables, such as {"observability": {"logging": {"level": "debug"}}}
+//  3. the environment variables, such as TASKER_OBSERVABILITY.LOGGING.LEVEL,
+//     including those set in .env
+//  4. Flags, the values set on the command line by key, such as
+//     observability.logging.level
+type Sources struct {
+	File  string
+	Flags map[string]string
+}
+
+// DefaultSources are the environment and the config file it names, if any
+func DefaultSources() Sources {
+	return Sources{File: os.Getenv(FileEnv)}
+}
+
+// fileProvider reads a config file for koanf to parse
+type fileProvider string
+
+func (p fileProvider) ReadBytes() ([]byte, error) {
+	return os.ReadFile(string(p))
+}
+
+func (p fileProvider) Read() (map[string]any, error) {
+	return nil, errors.New("config file provider requires a parser")
+}
+
+type jsonParser struct{}
+
+func (jsonParser) Unmarshal(b []byte) (map[string]any, error) {
+	var values map[string]any
+	if err := json.Unmarshal(b, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func (jsonParser) Marshal(values map[string]any) ([]byte, error) {
+	return json.Marshal(values)
+}
@@ -0,0 +1,284 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/lib/seal"
+)
+
+// Problem is a config value that is missing or invalid
+type Problem struct {
+	// Key is the value's key, such as database.host
+	Key     string
+	Message string
+}
+
+// EnvName is the environment variable that sets the key, such as
+// TASKER_DATABASE.HOST for database.host
+func EnvName(key string) string {
+	return "TASKER_" + strings.ToUpper(key)
+}
+
+// ValidationError lists every problem of a config, so they can all be fixed
+// before the next start
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid config, %d problem(s):", len(e.Problems))
+	for _, p := range e.Problems {
+		fmt.Fprintf(&b, "\n  %s: %s (%s)", p.Key, p.Message, EnvName(p.Key))
+	}
+	return b.String()
+}
+
+// problems collects the problems found by each section's checks
+type problems []Problem
+
+func (p *problems) add(key, format string, args ...any) {
+	*p = append(*p, Problem{Key: key, Message: fmt.Sprintf(format, args...)})
+}
+
+func (p problems) err() error {
+	if len(p) == 0 {
+		return nil
+	}
+
+	sorted := slices.Clone(p)
+	slices.SortStableFunc(sorted, func(a, b Problem) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return &ValidationError{Problems: sorted}
+}
+
+// Validate checks every section of the config once the defaults are filled
+// in. It returns a *ValidationError with all the problems found, rather than
+// just the first.
+func (c *Config) Validate() error {
+	var p problems
+
+	c.validateTags(&p)
+	c.Server.validate(&p)
+	c.Database.validate(&p)
+	c.Redis.validate(&p)
+	c.Auth.validate(c.Primary.Env, &p)
+	c.Email.validate(&p)
+	c.AWS.validate(&p)
+	if c.Attachments != nil && c.Attachments.Scan != nil {
+		c.Attachments.Scan.validate(&p)
+	}
+	if c.Observability != nil {
+		c.Observability.validate(&p)
+	}
+	if c.Jobs != nil {
+		c.Jobs.validate(&p)
+	}
+	if c.ClientVersions != nil {
+		c.ClientVersions.validate(&p)
+	}
+	if c.Exports != nil && c.Exports.EncryptionKey != "" {
+		if _, err := seal.New(c.Exports.EncryptionKey); err != nil {
+			p.add("exports.encryption_key", "must be 32 bytes encoded in base64")
+		}
+	}
+	if c.Sandbox != nil && c.Sandbox.WorkspaceID != "" {
+		if _, err := uuid.Parse(c.Sandbox.WorkspaceID); err != nil {
+			p.add("sandbox.workspace_id", "must be a UUID")
+		}
+	}
+
+	return p.err()
+}
+
+// derivedKeys are set by Load from other values, whose problems are
+// reported instead
+var derivedKeys = []string{"observability.service_name", "observability.environment"}
+
+// validateTags checks the validate tags of the config's fields, naming each
+// field by its key
+func (c *Config) validateTags(p *problems) {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("koanf"), ",")
+		return name
+	})
+
+	err := validate.Struct(c)
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		if err != nil {
+			p.add("", "%v", err)
+		}
+		return
+	}
+
+	for _, fe := range fieldErrs {
+		// The namespace starts with the name of the Config type
+		_, key, _ := strings.Cut(fe.Namespace(), ".")
+		if slices.Contains(derivedKeys, key) {
+			continue
+		}
+
+		switch fe.Tag() {
+		case "required":
+			p.add(key, "is required")
+		case "oneof":
+			p.add(key, "must be one of: %s, not %q", strings.Join(strings.Fields(fe.Param()), ", "), fmt.Sprint(fe.Value()))
+		case "min":
+			p.add(key, "must be at least %s", fe.Param())
+		default:
+			p.add(key, "fails the %s check", fe.Tag())
+		}
+	}
+}
+
+func validPort(port int) bool {
+	return port > 0 && port <= 65535
+}
+
+func (sc *ServerConfig) validate(p *problems) {
+	if sc.Port != 0 && !validPort(sc.Port) {
+		p.add("server.port", "must be a port between 1 and 65535, not %d", sc.Port)
+	}
+	for key, seconds := range map[string]int{
+		"server.read_timeout":  sc.ReadTimeout,
+		"server.write_timeout": sc.WriteTimeout,
+		"server.idle_timeout":  sc.IdleTimeout,
+	} {
+		if seconds < 0 {
+			p.add(key, "must be a positive number of seconds, not %d", seconds)
+		}
+	}
+	for _, origin := range sc.CorsAllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" {
+			p.add("server.cors_allowed_origins", "%q must be * or an origin such as https://app.example.com", origin)
+		}
+	}
+}
+
+var sslModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+func (dc *DatabaseConfig) validate(p *problems) {
+	if dc.Port != 0 && !validPort(dc.Port) {
+		p.add("database.port", "must be a port between 1 and 65535, not %d", dc.Port)
+	}
+	if dc.SSLMode != "" && !slices.Contains(sslModes, dc.SSLMode) {
+		p.add("database.ssl_mode", "must be one of: %s, not %q", strings.Join(sslModes, ", "), dc.SSLMode)
+	}
+	if dc.MaxOpenConns < 0 {
+		p.add("database.max_open_conns", "must be positive, not %d", dc.MaxOpenConns)
+	}
+	if dc.MinConns < 0 || dc.MaxOpenConns > 0 && dc.MinConns > dc.MaxOpenConns {
+		p.add("database.min_conns", "must be between 0 and max_open_conns (%d), not %d", dc.MaxOpenConns, dc.MinConns)
+	}
+	if dc.ReadReplica != nil && dc.ReadReplica.Port != 0 && !validPort(dc.ReadReplica.Port) {
+		p.add("database.read_replica.port", "must be a port between 1 and 65535, not %d", dc.ReadReplica.Port)
+	}
+}
+
+func (rc *RedisConfig) validate(p *problems) {
+	if rc.Address == "" {
+		return
+	}
+
+	// The address is dialed as it is, so a redis:// URL doesn't work
+	host, port, err := net.SplitHostPort(rc.Address)
+	if err != nil || strings.Contains(rc.Address, "://") {
+		p.add("redis.address", "must be host:port, such as localhost:6379, not %q", rc.Address)
+		return
+	}
+	if n, err := strconv.Atoi(port); err != nil || !validPort(n) {
+		p.add("redis.address", "port %q must be between 1 and 65535", port)
+	}
+	if host == "" {
+		p.add("redis.address", "is missing the host, such as localhost:6379")
+	}
+}
+
+func (ec *EmailConfig) validate(p *problems) {
+	if ec.ResendAPIKey != "" && strings.TrimSpace(ec.ResendAPIKey) != ec.ResendAPIKey {
+		p.add("email.resend_api_key", "has leading or trailing whitespace")
+	}
+	if ec.AppURL != "" && !absoluteURL(ec.AppURL) {
+		p.add("email.app_url", "must be an absolute URL, such as https://app.example.com")
+	}
+
+	if ec.Tracking == nil || !ec.Tracking.Enabled {
+		return
+	}
+	// Tracking links are followed from mail clients, so they need the API's
+	// public URL, and are signed
+	if ec.Tracking.BaseURL == "" {
+		p.add("email.tracking.base_url", "is required with email.tracking.enabled")
+	} else if !absoluteURL(ec.Tracking.BaseURL) {
+		p.add("email.tracking.base_url", "must be an absolute URL, such as https://api.example.com")
+	}
+	if ec.Tracking.SigningKey == "" {
+		p.add("email.tracking.signing_key", "is required with email.tracking.enabled")
+	}
+}
+
+func absoluteURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+func (ac *AWSConfig) validate(p *problems) {
+	// Uploads are signed with these static credentials, so both are needed
+	switch {
+	case ac.AccessKeyID == "" && ac.SecretAccessKey == "":
+		p.add("aws.access_key_id", "is required")
+		p.add("aws.secret_access_key", "is required")
+	case ac.AccessKeyID == "":
+		p.add("aws.access_key_id", "is required with aws.secret_access_key")
+	case ac.SecretAccessKey == "":
+		p.add("aws.secret_access_key", "is required with aws.access_key_id")
+	}
+
+	if ac.EndpointURL != "" {
+		if u, err := url.Parse(ac.EndpointURL); err != nil || u.Scheme == "" || u.Host == "" {
+			p.add("aws.endpoint_url", "must be an absolute URL, such as http://localhost:9000")
+		}
+	}
+
+	if ac.PartSize < MinS3PartSize {
+		p.add("aws.part_size", "must be at least %d bytes (5 MiB), not %d", MinS3PartSize, ac.PartSize)
+	}
+	if ac.MultipartThreshold < ac.PartSize {
+		p.add("aws.multipart_threshold", "must be at least aws.part_size (%d), not %d", ac.PartSize, ac.MultipartThreshold)
+	}
+	if ac.KMSKeyID != "" && ac.ServerSideEncryption != "aws:kms" {
+		p.add("aws.kms_key_id", "requires aws.server_side_encryption to be aws:kms")
+	}
+	// Expiring everything would delete every stored file
+	if ac.Lifecycle != nil && ac.Lifecycle.Enabled && strings.Trim(ac.Lifecycle.TempPrefix, "/") == "" {
+		p.add("aws.lifecycle.temp_prefix", "must name a folder, such as tmp/")
+	}
+}
+
+func (sc *AttachmentScanConfig) validate(p *problems) {
+	if sc.Provider == ScanProviderClamAV {
+		if host, port, err := net.SplitHostPort(sc.Address); err != nil || host == "" || port == "" {
+			p.add("attachments.scan.address", "must be host:port, such as localhost:3310, not %q", sc.Address)
+		}
+	}
+	// Quarantined files would otherwise sit among the attachments
+	prefix := strings.Trim(sc.QuarantinePrefix, "/")
+	if prefix == "" || prefix == "todos" || strings.HasPrefix(prefix, "todos/") {
+		p.add("attachments.scan.quarantine_prefix", "must name a folder outside todos/, such as quarantine/")
+	}
+}
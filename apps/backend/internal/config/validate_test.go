@@ -0,0 +1,120 @@
+package config_test
+
+import (
+	"errors"
+	"maps"
+	"testing"
+
+	"github.com/mabhi256/tasker/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// validFlags are the least a config needs to load
+var validFlags = map[string]string{
+	"primary.env":                 "local",
+	"server.port":                 "8080",
+	"server.read_timeout":         "30",
+	"server.write_timeout":        "30",
+	"server.idle_timeout":         "60",
+	"server.cors_allowed_origins": "http://localhost:3000",
+	"database.host":               "localhost",
+	"database.port":               "5432",
+	"database.user":               "postgres",
+	"database.password":           "postgres",
+	"database.name":               "tasker",
+	"database.ssl_mode":           "disable",
+	"database.max_open_conns":     "25",
+	"database.max_idle_conns":     "25",
+	"database.conn_max_lifetime":  "300",
+	"database.conn_max_idle_time": "300",
+	"auth.secret_key":             "secret",
+	"email.resend_api_key":        "resend_key",
+	"redis.address":               "localhost:6379",
+	"aws.region":                  "us-east-1",
+	"aws.access_key_id":           "minioadmin",
+	"aws.secret_access_key":       "minioadmin",
+	"aws.upload_bucket":           "tasker-uploads",
+	// Setting any observability value leaves the rest of the section empty
+	"observability.logging.level":         "info",
+	"observability.logging.format":        "json",
+	"observability.new_relic.license_key": "license",
+	"observability.health_check.interval": "30s",
+	"observability.health_check.timeout":  "5s",
+}
+
+func load(t *testing.T, overrides map[string]string) (*config.Config, error) {
+	t.Helper()
+
+	flags := maps.Clone(validFlags)
+	maps.Copy(flags, overrides)
+	return config.Load(config.Sources{Flags: flags})
+}
+
+func TestValidate(t *testing.T) {
+	_, err := load(t, nil)
+	require.NoError(t, err)
+}
+
+// TestValidateReportsEveryProblem breaks a value of several sections, checked
+// both by tags and by the sections' own checks, and expects each to be listed
+// in one error, sorted by key
+func TestValidateReportsEveryProblem(t *testing.T) {
+	_, err := load(t, map[string]string{
+		"mode":              "batch",
+		"server.port":       "70000",
+		"database.ssl_mode": "sometimes",
+		"database.name":     "",
+		"redis.address":     "redis://localhost:6379",
+		"aws.endpoint_url":  "localhost:9000",
+	})
+	require.Error(t, err)
+
+	var validationErr *config.ValidationError
+	require.True(t, errors.As(err, &validationErr))
+
+	assert.Equal(t, []string{
+		"aws.endpoint_url",
+		"database.name",
+		"database.ssl_mode",
+		"mode",
+		"redis.address",
+		"server.port",
+	}, problemKeys(validationErr))
+
+	assert.Contains(t, err.Error(), "6 problem(s)")
+	assert.Contains(t, err.Error(), "TASKER_DATABASE.NAME")
+}
+
+// TestValidateDerivedKeys leaves out a value others are derived from, which is
+// reported alone rather than along with each value derived from it
+func TestValidateDerivedKeys(t *testing.T) {
+	_, err := load(t, map[string]string{"primary.env": ""})
+
+	var validationErr *config.ValidationError
+	require.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, []string{"primary.env"}, problemKeys(validationErr))
+}
+
+// TestValidateEncryptionKey rejects an export encryption key of the wrong
+// size
+func TestValidateEncryptionKey(t *testing.T) {
+	_, err := load(t, map[string]string{"exports.encryption_key": "c2hvcnQ="})
+
+	var validationErr *config.ValidationError
+	require.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, []string{"exports.encryption_key"}, problemKeys(validationErr))
+
+	_, err = load(t, map[string]string{
+		"exports.encryption_key": "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=",
+	})
+	require.NoError(t, err)
+}
+
+func problemKeys(err *config.ValidationError) []string {
+	keys := make([]string, 0, len(err.Problems))
+	for _, problem := range err.Problems {
+		keys = append(keys, problem.Key)
+	}
+	return keys
+}
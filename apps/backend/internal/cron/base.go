@@ -7,6 +7,12 @@ import (
 	"github.com/hibiken/asynq"
 	"github.com/mabhi256/tasker/internal/config"
 	"github.com/mabhi256/tasker/internal/database"
+	"github.com/mabhi256/tasker/internal/httpclient"
+	"github.com/mabhi256/tasker/internal/lib/alert"
+	"github.com/mabhi256/tasker/internal/lib/cache"
+	"github.com/mabhi256/tasker/internal/lib/job"
+	"github.com/mabhi256/tasker/internal/lib/refcache"
+	"github.com/mabhi256/tasker/internal/lib/seal"
 	"github.com/mabhi256/tasker/internal/logging"
 	"github.com/mabhi256/tasker/internal/repository"
 	"github.com/mabhi256/tasker/internal/server"
@@ -41,12 +47,25 @@ func NewJobContext() (*JobContext, error) {
 		// DB: 0,
 	})
 
+	httpClient := httpclient.New(cfg.HTTPClient, &loggerInstance)
+
+	sealer, err := seal.NewOptional(cfg.Exports.EncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize sealer: %w", err)
+	}
+
 	srv := &server.Server{
 		Config:        cfg,
 		Logger:        &loggerInstance,
 		LoggerService: loggerService,
 		DB:            db,
 		Redis:         redisClient,
+		HTTPClient:    httpClient,
+		Fetcher:       httpclient.NewFetcher(cfg.Fetcher, cfg.HTTPClient, &loggerInstance),
+		Alerts:        alert.NewNotifier(cfg.Alerts, httpClient, &loggerInstance),
+		Cache:         cache.New(redisClient, &loggerInstance, loggerService),
+		RefCache:      refcache.New(cfg.RefCache, redisClient, &loggerInstance, loggerService),
+		Sealer:        sealer,
 	}
 
 	jobClient, err := initJobClient(cfg)
@@ -67,7 +86,7 @@ func NewJobContext() (*JobContext, error) {
 
 func (c *JobContext) Close() {
 	if c.Server != nil && c.Server.DB != nil {
-		c.Server.DB.Pool.Close()
+		c.Server.DB.Close()
 	}
 	if c.Server != nil && c.Server.Redis != nil {
 		c.Server.Redis.Close()
@@ -88,6 +107,7 @@ func initJobClient(cfg *config.Config) (*asynq.Client, error) {
 	}
 
 	client := asynq.NewClient(redisOpt)
+	job.SetPolicies(cfg.Jobs)
 	return client, nil
 }
 
@@ -121,7 +141,9 @@ func (r *JobRunner) Run() error {
 		Str("job", r.job.Name()).
 		Msg("Starting cron job")
 
-	ctx := context.Background()
+	// Jobs run outside of any request, so their statements aren't bounded
+	// like a request's
+	ctx := database.WithQueryTimeout(context.Background(), 0)
 	err := r.job.Run(ctx, r.ctx)
 	if err != nil {
 		r.ctx.Server.Logger.Error().
@@ -136,3 +158,62 @@ func (r *JobRunner) Run() error {
 		Msg("Cron job completed successfully")
 	return nil
 }
+
+// NewServerJobContext shares the API server's connections with jobs run by
+// the job server's scheduler. It must not be closed.
+func NewServerJobContext(srv *server.Server, repositories *repository.Repositories) *JobContext {
+	return &JobContext{
+		Config:        srv.Config,
+		Server:        srv,
+		JobClient:     srv.Job.Client,
+		Repositories:  repositories,
+		LoggerService: srv.LoggerService,
+	}
+}
+
+// ScheduledRunner runs registered jobs when the job server's scheduler
+// enqueues them
+type ScheduledRunner struct {
+	registry *JobRegistry
+	ctx      *JobContext
+}
+
+// NewScheduledRunner fails if a scheduled job isn't registered, so a typo in
+// the config is caught at startup
+func NewScheduledRunner(jobCtx *JobContext) (*ScheduledRunner, error) {
+	registry := NewJobRegistry()
+
+	for name, spec := range jobCtx.Config.Scheduler.Schedules {
+		if spec == "" {
+			continue
+		}
+		if _, err := registry.Get(name); err != nil {
+			return nil, fmt.Errorf("scheduled job: %w", err)
+		}
+	}
+
+	return &ScheduledRunner{
+		registry: registry,
+		ctx:      jobCtx,
+	}, nil
+}
+
+func (r *ScheduledRunner) RunCronJob(ctx context.Context, name string) error {
+	job, err := r.registry.Get(name)
+	if err != nil {
+		return err
+	}
+
+	r.ctx.Server.Logger.Info().
+		Str("job", job.Name()).
+		Msg("Starting scheduled cron job")
+
+	if err := job.Run(ctx, r.ctx); err != nil {
+		return err
+	}
+
+	r.ctx.Server.Logger.Info().
+		Str("job", job.Name()).
+		Msg("Scheduled cron job completed successfully")
+	return nil
+}
@@ -2,11 +2,24 @@ package cron
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/mabhi256/tasker/internal/database/seed"
+	"github.com/mabhi256/tasker/internal/lib/aws"
 	"github.com/mabhi256/tasker/internal/lib/job"
+	"github.com/mabhi256/tasker/internal/lib/push"
+	"github.com/mabhi256/tasker/internal/lib/usage"
 	"github.com/mabhi256/tasker/internal/model/todo"
+	"github.com/mabhi256/tasker/internal/model/weeklyreport"
 )
 
 type DueDateRemindersJob struct{}
@@ -36,6 +49,7 @@ func (j *DueDateRemindersJob) Run(ctx context.Context, jobCtx *JobContext) error
 
 	userTodos := make(map[string][]string)
 	enqueuedCount := 0
+	scheduledCount := 0
 
 	for _, todo := range todos {
 		if len(userTodos[todo.UserID]) < jobCtx.Config.Cron.MaxTodosPerUserNotification {
@@ -50,7 +64,14 @@ func (j *DueDateRemindersJob) Run(ctx context.Context, jobCtx *JobContext) error
 			TaskType:  "due_date_reminder",
 		}
 
-		err := job.EnqueueReminderEmail(jobCtx.JobClient, reminderTask)
+		// Todos get their reminder email scheduled as their due date is set,
+		// so this only sends the ones that weren't
+		err := job.Enqueue(usage.WithWorkspace(ctx, todo.WorkspaceID), jobCtx.JobClient, reminderTask)
+		if errors.Is(err, asynq.ErrTaskIDConflict) {
+			enqueueReminderPush(usage.WithWorkspace(ctx, todo.WorkspaceID), jobCtx, &todo, "Due soon", *todo.DueDate)
+			scheduledCount++
+			continue
+		}
 		if err != nil {
 			jobCtx.Server.Logger.Error().
 				Err(err).
@@ -60,6 +81,8 @@ func (j *DueDateRemindersJob) Run(ctx context.Context, jobCtx *JobContext) error
 			continue
 		}
 
+		enqueueReminderPush(usage.WithWorkspace(ctx, todo.WorkspaceID), jobCtx, &todo, "Due soon", *todo.DueDate)
+
 		enqueuedCount++
 		jobCtx.Server.Logger.Info().
 			Str("todo_id", todo.ID.String()).
@@ -70,6 +93,7 @@ func (j *DueDateRemindersJob) Run(ctx context.Context, jobCtx *JobContext) error
 
 	jobCtx.Server.Logger.Info().
 		Int("enqueued_count", enqueuedCount).
+		Int("already_scheduled_count", scheduledCount).
 		Int("total_todos", len(todos)).
 		Msg("Due date reminder emails enqueued")
 	for userID, titles := range userTodos {
@@ -82,6 +106,28 @@ func (j *DueDateRemindersJob) Run(ctx context.Context, jobCtx *JobContext) error
 	return nil
 }
 
+// enqueueReminderPush notifies the user's devices alongside the email, and
+// is dropped like the email if it isn't sent by deadline. The email is the
+// reminder of record, so a push that fails to queue is only logged.
+func enqueueReminderPush(ctx context.Context, jobCtx *JobContext, t *todo.Todo, title string, deadline time.Time) {
+	err := job.Enqueue(ctx, jobCtx.JobClient, &job.PushNotificationTask{
+		UserID: t.UserID,
+		Message: push.Message{
+			Title: title,
+			Body:  t.Title,
+			URL:   "/todos?id=" + t.ID.String(),
+			Tag:   "todo-" + t.ID.String(),
+		},
+	}, job.Deadline(deadline))
+	if err != nil {
+		jobCtx.Server.Logger.Error().
+			Err(err).
+			Str("todo_id", t.ID.String()).
+			Str("user_id", t.UserID).
+			Msg("Failed to enqueue reminder push notification")
+	}
+}
+
 // --------------------------
 
 type OverdueNotificationsJob struct{}
@@ -120,7 +166,7 @@ func (j *OverdueNotificationsJob) Run(ctx context.Context, jobCtx *JobContext) e
 			TaskType:  "overdue_notification",
 		}
 
-		err := job.EnqueueReminderEmail(jobCtx.JobClient, overdueTask)
+		err := job.Enqueue(usage.WithWorkspace(ctx, todo.WorkspaceID), jobCtx.JobClient, overdueTask)
 		if err != nil {
 			jobCtx.Server.Logger.Error().
 				Err(err).
@@ -130,6 +176,8 @@ func (j *OverdueNotificationsJob) Run(ctx context.Context, jobCtx *JobContext) e
 			continue
 		}
 
+		enqueueReminderPush(usage.WithWorkspace(ctx, todo.WorkspaceID), jobCtx, &todo, "Overdue", time.Now().Add(job.OverdueNotificationTTL))
+
 		enqueuedCount++
 		jobCtx.Server.Logger.Info().
 			Str("todo_id", todo.ID.String()).
@@ -161,75 +209,75 @@ func (j *WeeklyReportsJob) Name() string {
 }
 
 func (j *WeeklyReportsJob) Description() string {
-	return "Enqueue weekly productivity reports"
+	return "Enqueue weekly reports for users whose Monday morning it is (run hourly)"
 }
 
 func (j *WeeklyReportsJob) Run(ctx context.Context, jobCtx *JobContext) error {
 	now := time.Now()
-	weekAgo := now.AddDate(0, 0, -7)
+	defaultTimezone := jobCtx.Config.Scheduler.Location
+
+	timezones, err := jobCtx.Repositories.WeeklyReport.GetTimezones(ctx)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(timezones, defaultTimezone) {
+		timezones = append(timezones, defaultTimezone)
+	}
 
-	stats, err := jobCtx.Repositories.Todo.GetWeeklyStatsForUsers(ctx, weekAgo, now)
+	var due []string
+	for _, tz := range timezones {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			jobCtx.Server.Logger.Warn().Err(err).Str("timezone", tz).Msg("Skipping unknown timezone")
+			continue
+		}
+		if weeklyreport.Due(now, loc, jobCtx.Config.Cron.WeeklyReportHour) {
+			due = append(due, tz)
+		}
+	}
+	if len(due) == 0 {
+		jobCtx.Server.Logger.Debug().Msg("No timezones due weekly reports")
+		return nil
+	}
+
+	recipients, err := jobCtx.Repositories.WeeklyReport.GetRecipients(ctx, due, defaultTimezone)
 	if err != nil {
 		return err
 	}
 
 	jobCtx.Server.Logger.Info().
-		Int("user_count", len(stats)).
+		Strs("timezones", due).
+		Int("user_count", len(recipients)).
 		Msg("Generating weekly reports")
 
 	enqueuedCount := 0
-	for _, userStats := range stats {
-		completedTodos, err := jobCtx.Repositories.Todo.GetCompletedTodosForUser(ctx, userStats.UserID, weekAgo, now)
-		if err != nil {
-			jobCtx.Server.Logger.Error().
-				Err(err).
-				Str("user_id", userStats.UserID).
-				Msg("Failed to fetch completed todos")
-			completedTodos = []todo.PopulatedTodo{}
-		}
-
-		overdueTodos, err := jobCtx.Repositories.Todo.GetOverdueTodosForUser(ctx, userStats.UserID)
+	for _, r := range recipients {
+		loc, err := time.LoadLocation(r.Timezone)
 		if err != nil {
-			jobCtx.Server.Logger.Error().
-				Err(err).
-				Str("user_id", userStats.UserID).
-				Msg("Failed to fetch overdue todos")
-			overdueTodos = []todo.PopulatedTodo{}
-		}
-
-		weeklyReportTask := &job.WeeklyReportEmailTask{
-			UserID:         userStats.UserID,
-			WeekStart:      weekAgo,
-			WeekEnd:        now,
-			CompletedCount: userStats.CompletedCount,
-			ActiveCount:    userStats.ActiveCount,
-			OverdueCount:   userStats.OverdueCount,
-			CompletedTodos: completedTodos,
-			OverdueTodos:   overdueTodos,
+			continue
 		}
-
-		err = job.EnqueueWeeklyReportEmail(jobCtx.JobClient, weeklyReportTask)
+		weekStart, weekEnd := weeklyreport.Week(now.In(loc))
+
+		// Deduplicated per user and week, so a rerun doesn't send twice
+		err = job.EnqueueWeeklyReportEmail(ctx, jobCtx.JobClient, &job.WeeklyReportEmailTask{
+			UserID:    r.UserID,
+			WeekStart: weekStart,
+			WeekEnd:   weekEnd,
+			Timezone:  r.Timezone,
+		})
 		if err != nil {
 			jobCtx.Server.Logger.Error().
 				Err(err).
-				Str("user_id", userStats.UserID).
+				Str("user_id", r.UserID).
 				Msg("Failed to enqueue weekly report")
 			continue
 		}
-
 		enqueuedCount++
-		jobCtx.Server.Logger.Info().
-			Str("user_id", userStats.UserID).
-			Int("created", userStats.CreatedCount).
-			Int("completed", userStats.CompletedCount).
-			Int("active", userStats.ActiveCount).
-			Int("overdue", userStats.OverdueCount).
-			Msg("Enqueued weekly report")
 	}
 
 	jobCtx.Server.Logger.Info().
 		Int("enqueued_count", enqueuedCount).
-		Int("total_users", len(stats)).
+		Int("total_users", len(recipients)).
 		Msg("Weekly reports enqueued")
 	return nil
 }
@@ -293,3 +341,607 @@ func (j *AutoArchiveJob) Run(ctx context.Context, jobCtx *JobContext) error {
 
 	return nil
 }
+
+// --------
+
+type ExportSchedulesJob struct{}
+
+func (j *ExportSchedulesJob) Name() string {
+	return "export-schedules"
+}
+
+func (j *ExportSchedulesJob) Description() string {
+	return "Enqueue due workspace exports (run at least hourly)"
+}
+
+func (j *ExportSchedulesJob) Run(ctx context.Context, jobCtx *JobContext) error {
+	runs, err := jobCtx.Repositories.Export.ClaimDueSchedules(ctx, time.Now(), jobCtx.Config.Cron.BatchSize)
+	if err != nil {
+		return err
+	}
+
+	jobCtx.Server.Logger.Info().
+		Int("run_count", len(runs)).
+		Msg("Claimed due export schedules")
+
+	enqueuedCount := 0
+	for _, run := range runs {
+		err := job.Enqueue(ctx, jobCtx.JobClient, &job.ExportRunTask{RunID: run.ID})
+		if err != nil {
+			// The run stays pending and shows up in the schedule's history
+			jobCtx.Server.Logger.Error().
+				Err(err).
+				Str("run_id", run.ID.String()).
+				Str("schedule_id", run.ScheduleID.String()).
+				Msg("Failed to enqueue export run")
+			continue
+		}
+		enqueuedCount++
+	}
+
+	jobCtx.Server.Logger.Info().
+		Int("enqueued_count", enqueuedCount).
+		Int("total_runs", len(runs)).
+		Msg("Export runs enqueued")
+
+	return nil
+}
+
+// SealExportDestinationsJob isn't scheduled; it is run once after an
+// encryption key is configured, to seal the credentials saved before
+type SealExportDestinationsJob struct{}
+
+func (j *SealExportDestinationsJob) Name() string {
+	return "seal-export-destinations"
+}
+
+func (j *SealExportDestinationsJob) Description() string {
+	return "Encrypt the credentials of export destinations saved before they were"
+}
+
+func (j *SealExportDestinationsJob) Run(ctx context.Context, jobCtx *JobContext) error {
+	total := 0
+	for {
+		sealed, err := jobCtx.Repositories.Export.SealDestinations(ctx, jobCtx.Config.Cron.BatchSize)
+		if err != nil {
+			return err
+		}
+
+		total += sealed
+		if sealed < jobCtx.Config.Cron.BatchSize {
+			break
+		}
+	}
+
+	jobCtx.Server.Logger.Info().
+		Int("sealed_count", total).
+		Msg("Export destinations sealed")
+
+	return nil
+}
+
+type OutboxCleanupJob struct{}
+
+func (j *OutboxCleanupJob) Name() string {
+	return "outbox-cleanup"
+}
+
+func (j *OutboxCleanupJob) Description() string {
+	return "Delete published and dead outbox events past the retention period"
+}
+
+func (j *OutboxCleanupJob) Run(ctx context.Context, jobCtx *JobContext) error {
+	cutoff := time.Now().Add(-jobCtx.Config.Outbox.RetentionPeriod)
+
+	deleted, err := jobCtx.Repositories.Outbox.DeletePublishedEvents(ctx, cutoff)
+	if err != nil {
+		return err
+	}
+
+	jobCtx.Server.Logger.Info().
+		Int64("deleted_count", deleted).
+		Time("published_before", cutoff).
+		Msg("Deleted published and dead outbox events")
+
+	return nil
+}
+
+type SnapshotCleanupJob struct{}
+
+func (j *SnapshotCleanupJob) Name() string {
+	return "snapshot-cleanup"
+}
+
+func (j *SnapshotCleanupJob) Description() string {
+	return "Delete todo snapshots and their stored files past their retention"
+}
+
+func (j *SnapshotCleanupJob) Run(ctx context.Context, jobCtx *JobContext) error {
+	awsClient, err := aws.NewAWS(ctx, jobCtx.Server)
+	if err != nil {
+		return fmt.Errorf("failed to create AWS client: %w", err)
+	}
+
+	snapshots, err := jobCtx.Repositories.Snapshot.GetExpiredSnapshots(ctx, jobCtx.Config.Cron.BatchSize)
+	if err != nil {
+		return err
+	}
+
+	deletedCount := 0
+	for _, snapshot := range snapshots {
+		// The object goes first, so a failure leaves the row to retry from
+		err := awsClient.S3.DeleteObject(ctx, jobCtx.Config.AWS.UploadBucket, snapshot.ObjectKey)
+		if err != nil {
+			jobCtx.Server.Logger.Error().
+				Err(err).
+				Str("snapshot_id", snapshot.ID.String()).
+				Str("object_key", snapshot.ObjectKey).
+				Msg("Failed to delete snapshot object")
+			continue
+		}
+
+		if err := jobCtx.Repositories.Snapshot.DeleteSnapshot(ctx, snapshot.ID); err != nil {
+			jobCtx.Server.Logger.Error().
+				Err(err).
+				Str("snapshot_id", snapshot.ID.String()).
+				Msg("Failed to delete snapshot")
+			continue
+		}
+
+		deletedCount++
+	}
+
+	jobCtx.Server.Logger.Info().
+		Int("expired_count", len(snapshots)).
+		Int("deleted_count", deletedCount).
+		Msg("Deleted expired todo snapshots")
+
+	return nil
+}
+
+type AttachmentIntegrityJob struct{}
+
+func (j *AttachmentIntegrityJob) Name() string {
+	return "attachment-integrity"
+}
+
+func (j *AttachmentIntegrityJob) Description() string {
+	return "Verify a sample of attachments against their stored objects and alert on missing or corrupt ones"
+}
+
+func (j *AttachmentIntegrityJob) Run(ctx context.Context, jobCtx *JobContext) error {
+	awsClient, err := aws.NewAWS(ctx, jobCtx.Server)
+	if err != nil {
+		return fmt.Errorf("failed to create AWS client: %w", err)
+	}
+
+	attachments, err := jobCtx.Repositories.Todo.GetAttachmentsForIntegrityCheck(ctx, jobCtx.Config.Cron.IntegritySampleSize)
+	if err != nil {
+		return err
+	}
+
+	jobCtx.Server.Logger.Info().
+		Int("attachment_count", len(attachments)).
+		Msg("Sampled attachments for integrity check")
+
+	counts := make(map[todo.IntegrityStatus]int)
+	var newlyFlagged []string
+
+	for _, attachment := range attachments {
+		status, checksum, checkErr, err := verifyAttachment(ctx, awsClient.S3, jobCtx.Config.AWS.UploadBucket, &attachment)
+		if err != nil {
+			// Couldn't reach the object store; leave the attachment for the next run
+			jobCtx.Server.Logger.Error().
+				Err(err).
+				Str("attachment_id", attachment.ID.String()).
+				Msg("Failed to verify attachment")
+			continue
+		}
+
+		err = jobCtx.Repositories.Todo.RecordAttachmentIntegrity(ctx, attachment.ID, status, checksum, checkErr)
+		if err != nil {
+			jobCtx.Server.Logger.Error().
+				Err(err).
+				Str("attachment_id", attachment.ID.String()).
+				Msg("Failed to record attachment integrity")
+			continue
+		}
+
+		counts[status]++
+
+		if status.IsFlagged() {
+			jobCtx.Server.Logger.Warn().
+				Str("attachment_id", attachment.ID.String()).
+				Str("todo_id", attachment.TodoID.String()).
+				Str("download_key", attachment.DownloadKey).
+				Str("status", string(status)).
+				Str("error", *checkErr).
+				Msg("Attachment failed integrity check")
+
+			// Only alert once per attachment, not on every run it stays broken
+			if !attachment.IntegrityStatus.IsFlagged() {
+				newlyFlagged = append(newlyFlagged,
+					fmt.Sprintf("%s (%s): %s", attachment.ID.String(), status, *checkErr))
+			}
+		}
+	}
+
+	jobCtx.Server.Logger.Info().
+		Int("ok", counts[todo.IntegrityStatusOK]).
+		Int("missing", counts[todo.IntegrityStatusMissing]).
+		Int("corrupt", counts[todo.IntegrityStatusCorrupt]).
+		Int("newly_flagged", len(newlyFlagged)).
+		Msg("Attachment integrity check completed")
+
+	if len(newlyFlagged) > 0 {
+		err := jobCtx.Server.Alerts.Send(ctx,
+			fmt.Sprintf("%d attachment(s) failed integrity checks", len(newlyFlagged)),
+			strings.Join(newlyFlagged, "\n"))
+		if err != nil {
+			return fmt.Errorf("failed to send attachment integrity alert: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// verifyAttachment downloads the stored object and compares its size and
+// SHA-256 with the attachment record. Attachments uploaded before checksums
+// were kept have theirs computed here for later checks. An error means the
+// check itself couldn't be done.
+func verifyAttachment(ctx context.Context, s3 aws.ObjectStore, bucket string, attachment *todo.TodoAttachment,
+) (todo.IntegrityStatus, *string, *string, error) {
+	fail := func(status todo.IntegrityStatus, msg string) (todo.IntegrityStatus, *string, *string, error) {
+		return status, nil, &msg, nil
+	}
+
+	body, err := s3.GetObject(ctx, bucket, attachment.DownloadKey)
+	if err != nil {
+		if errors.Is(err, aws.ErrObjectNotFound) {
+			return fail(todo.IntegrityStatusMissing, "object not found")
+		}
+		return "", nil, nil, err
+	}
+	defer body.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, body)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to read object %s: %w", attachment.DownloadKey, err)
+	}
+	checksum := hex.EncodeToString(hash.Sum(nil))
+
+	if attachment.FileSize != nil && size != *attachment.FileSize {
+		return fail(todo.IntegrityStatusCorrupt,
+			fmt.Sprintf("size mismatch: expected %d bytes, found %d", *attachment.FileSize, size))
+	}
+
+	if attachment.ChecksumSHA256 != nil && checksum != *attachment.ChecksumSHA256 {
+		return fail(todo.IntegrityStatusCorrupt,
+			fmt.Sprintf("checksum mismatch: expected %s, found %s", *attachment.ChecksumSHA256, checksum))
+	}
+
+	return todo.IntegrityStatusOK, &checksum, nil, nil
+}
+
+type DigestsJob struct{}
+
+func (j *DigestsJob) Name() string {
+	return "digests"
+}
+
+func (j *DigestsJob) Description() string {
+	return "Enqueue due daily and weekly digest emails (run at least every 15 minutes)"
+}
+
+func (j *DigestsJob) Run(ctx context.Context, jobCtx *JobContext) error {
+	subscriptions, err := jobCtx.Repositories.Digest.ClaimDueSubscriptions(ctx, time.Now(), jobCtx.Config.Cron.BatchSize)
+	if err != nil {
+		return err
+	}
+
+	jobCtx.Server.Logger.Info().
+		Int("subscription_count", len(subscriptions)).
+		Msg("Claimed due digest subscriptions")
+
+	enqueuedCount := 0
+	for _, subscription := range subscriptions {
+		// The period ends at the claimed slot rather than now, so a late run
+		// doesn't shift what the digest covers
+		err := job.EnqueueDigestEmail(ctx, jobCtx.JobClient, &job.DigestEmailTask{
+			UserID:    subscription.UserID,
+			PeriodEnd: subscription.NextRunAt,
+		})
+		if err != nil {
+			// The subscription has moved on to its next slot, so this digest
+			// is skipped
+			jobCtx.Server.Logger.Error().
+				Err(err).
+				Str("user_id", subscription.UserID).
+				Str("subscription_id", subscription.ID.String()).
+				Msg("Failed to enqueue digest email")
+			continue
+		}
+		enqueuedCount++
+	}
+
+	jobCtx.Server.Logger.Info().
+		Int("enqueued_count", enqueuedCount).
+		Int("total_subscriptions", len(subscriptions)).
+		Msg("Digest emails enqueued")
+
+	return nil
+}
+
+type CostAttributionJob struct{}
+
+func (j *CostAttributionJob) Name() string {
+	return "cost-attribution"
+}
+
+func (j *CostAttributionJob) Description() string {
+	return "Record the attachment storage of each workspace for the UTC day that just ended"
+}
+
+func (j *CostAttributionJob) Run(ctx context.Context, jobCtx *JobContext) error {
+	// Run just after midnight UTC, so the snapshot closes out the day the
+	// cost-attribution report covers by default
+	day := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -1)
+
+	workspaceCount, err := jobCtx.Repositories.Usage.RecordStorage(ctx, day)
+	if err != nil {
+		return err
+	}
+
+	jobCtx.Server.Logger.Info().
+		Int("workspace_count", workspaceCount).
+		Str("day", day.Format(time.DateOnly)).
+		Msg("Recorded workspace storage")
+
+	return nil
+}
+
+// --------
+
+type PublishScheduledTodosJob struct{}
+
+func (j *PublishScheduledTodosJob) Name() string {
+	return "publish-scheduled-todos"
+}
+
+func (j *PublishScheduledTodosJob) Description() string {
+	return "Publish scheduled todos whose publish time has passed (run every minute)"
+}
+
+func (j *PublishScheduledTodosJob) Run(ctx context.Context, jobCtx *JobContext) error {
+	// Runs that fall behind publish a batch at a time until they catch up
+	total := 0
+	for {
+		published, err := jobCtx.Repositories.Todo.PublishDueTodos(ctx, time.Now(), jobCtx.Config.Cron.BatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, todo := range published {
+			jobCtx.Server.Logger.Info().
+				Str("todo_id", todo.ID.String()).
+				Str("workspace_id", todo.WorkspaceID.String()).
+				Time("publish_at", *todo.PublishAt).
+				Msg("Published scheduled todo")
+		}
+
+		total += len(published)
+		if len(published) == 0 || len(published) < jobCtx.Config.Cron.BatchSize {
+			break
+		}
+	}
+
+	jobCtx.Server.Logger.Info().
+		Int("published_count", total).
+		Msg("Scheduled todos published")
+
+	return nil
+}
+
+// --------
+
+type SandboxResetJob struct{}
+
+func (j *SandboxResetJob) Name() string {
+	return "sandbox-reset"
+}
+
+func (j *SandboxResetJob) Description() string {
+	return "Reset the sandbox workspace to the sandbox data set, when a sandbox is enabled"
+}
+
+func (j *SandboxResetJob) Run(ctx context.Context, jobCtx *JobContext) error {
+	cfg := jobCtx.Config.Sandbox
+	if !cfg.Enabled {
+		jobCtx.Server.Logger.Info().Msg("Sandbox not enabled, nothing to reset")
+		return nil
+	}
+
+	workspaceID := seed.SandboxWorkspaceID
+	if cfg.WorkspaceID != "" {
+		workspaceID = uuid.MustParse(cfg.WorkspaceID)
+	}
+	ownerID := cfg.OwnerID
+	if ownerID == "" {
+		ownerID = seed.DemoAlice
+	}
+
+	// Clearing and seeding share a transaction, so the sandbox is never seen
+	// empty and a failed reset leaves it as it was
+	seeders := append([]seed.Seeder{seed.ClearWorkspace(workspaceID)}, seed.Sandbox(workspaceID, ownerID, cfg.Members...)...)
+	if err := seed.Run(ctx, jobCtx.Server.DB.Pool, jobCtx.Server.Logger, seeders...); err != nil {
+		return fmt.Errorf("failed to reset sandbox: %w", err)
+	}
+
+	jobCtx.Server.Logger.Info().
+		Str("workspace_id", workspaceID.String()).
+		Int("member_count", len(cfg.Members)).
+		Msg("Sandbox reset")
+
+	return nil
+}
+
+// --------
+
+type TrialCleanupJob struct{}
+
+func (j *TrialCleanupJob) Name() string {
+	return "trial-cleanup"
+}
+
+func (j *TrialCleanupJob) Description() string {
+	return "Delete expired unclaimed trials with their workspaces"
+}
+
+func (j *TrialCleanupJob) Run(ctx context.Context, jobCtx *JobContext) error {
+	total := 0
+	for {
+		deleted, err := jobCtx.Repositories.Trial.DeleteExpiredTrials(ctx, jobCtx.Config.Cron.BatchSize)
+		if err != nil {
+			return err
+		}
+
+		total += deleted
+		if deleted < jobCtx.Config.Cron.BatchSize {
+			break
+		}
+	}
+
+	jobCtx.Server.Logger.Info().
+		Int("deleted_count", total).
+		Msg("Expired trials deleted")
+
+	return nil
+}
+
+type EmailTrackingCleanupJob struct{}
+
+func (j *EmailTrackingCleanupJob) Name() string {
+	return "email-tracking-cleanup"
+}
+
+func (j *EmailTrackingCleanupJob) Description() string {
+	return "Delete tracked email messages older than their retention"
+}
+
+func (j *EmailTrackingCleanupJob) Run(ctx context.Context, jobCtx *JobContext) error {
+	before := time.Now().Add(-jobCtx.Config.Email.Tracking.Retention)
+
+	total := 0
+	for {
+		deleted, err := jobCtx.Repositories.EmailTracking.DeleteMessagesBefore(ctx, before, jobCtx.Config.Cron.BatchSize)
+		if err != nil {
+			return err
+		}
+
+		total += deleted
+		if deleted < jobCtx.Config.Cron.BatchSize {
+			break
+		}
+	}
+
+	jobCtx.Server.Logger.Info().
+		Int("deleted_count", total).
+		Time("before", before).
+		Msg("Tracked email messages deleted")
+
+	return nil
+}
+
+// attachmentPreviewGrace leaves time for the task queued at upload to run
+// before an attachment counts as missed
+const attachmentPreviewGrace = 10 * time.Minute
+
+type AttachmentPreviewsJob struct{}
+
+func (j *AttachmentPreviewsJob) Name() string {
+	return "attachment-previews"
+}
+
+func (j *AttachmentPreviewsJob) Description() string {
+	return "Queue preview extraction for attachments that have none yet"
+}
+
+func (j *AttachmentPreviewsJob) Run(ctx context.Context, jobCtx *JobContext) error {
+	attachments, err := jobCtx.Repositories.Todo.GetAttachmentsPendingPreview(ctx,
+		time.Now().Add(-attachmentPreviewGrace), jobCtx.Config.Cron.BatchSize)
+	if err != nil {
+		return err
+	}
+
+	enqueuedCount := 0
+	queuedCount := 0
+	for _, attachment := range attachments {
+		err := job.Enqueue(ctx, jobCtx.JobClient, &job.AttachmentPreviewTask{AttachmentID: attachment.ID})
+		if errors.Is(err, asynq.ErrTaskIDConflict) {
+			queuedCount++
+			continue
+		}
+		if err != nil {
+			jobCtx.Server.Logger.Error().
+				Err(err).
+				Str("attachment_id", attachment.ID.String()).
+				Msg("Failed to enqueue attachment preview")
+			continue
+		}
+		enqueuedCount++
+	}
+
+	jobCtx.Server.Logger.Info().
+		Int("enqueued_count", enqueuedCount).
+		Int("already_queued_count", queuedCount).
+		Int("total_attachments", len(attachments)).
+		Msg("Attachment previews enqueued")
+
+	return nil
+}
+
+type AttachmentProcessingJob struct{}
+
+func (j *AttachmentProcessingJob) Name() string {
+	return "attachment-processing"
+}
+
+func (j *AttachmentProcessingJob) Description() string {
+	return "Queue processing for attachments that were never processed"
+}
+
+func (j *AttachmentProcessingJob) Run(ctx context.Context, jobCtx *JobContext) error {
+	// The same grace as previews, which are queued with processing
+	attachments, err := jobCtx.Repositories.Todo.GetAttachmentsPendingProcessing(ctx,
+		time.Now().Add(-attachmentPreviewGrace), jobCtx.Config.Cron.BatchSize)
+	if err != nil {
+		return err
+	}
+
+	enqueuedCount := 0
+	queuedCount := 0
+	for _, attachment := range attachments {
+		err := job.Enqueue(ctx, jobCtx.JobClient, &job.AttachmentProcessTask{AttachmentID: attachment.ID})
+		if errors.Is(err, asynq.ErrTaskIDConflict) {
+			queuedCount++
+			continue
+		}
+		if err != nil {
+			jobCtx.Server.Logger.Error().
+				Err(err).
+				Str("attachment_id", attachment.ID.String()).
+				Msg("Failed to enqueue attachment processing")
+			continue
+		}
+		enqueuedCount++
+	}
+
+	jobCtx.Server.Logger.Info().
+		Int("enqueued_count", enqueuedCount).
+		Int("already_queued_count", queuedCount).
+		Int("total_attachments", len(attachments)).
+		Msg("Attachment processing enqueued")
+
+	return nil
+}
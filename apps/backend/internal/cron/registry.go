@@ -18,6 +18,19 @@ func NewJobRegistry() *JobRegistry {
 	registry.Register(&OverdueNotificationsJob{})
 	registry.Register(&WeeklyReportsJob{})
 	registry.Register(&AutoArchiveJob{})
+	registry.Register(&ExportSchedulesJob{})
+	registry.Register(&SealExportDestinationsJob{})
+	registry.Register(&OutboxCleanupJob{})
+	registry.Register(&SnapshotCleanupJob{})
+	registry.Register(&AttachmentIntegrityJob{})
+	registry.Register(&DigestsJob{})
+	registry.Register(&CostAttributionJob{})
+	registry.Register(&PublishScheduledTodosJob{})
+	registry.Register(&SandboxResetJob{})
+	registry.Register(&TrialCleanupJob{})
+	registry.Register(&EmailTrackingCleanupJob{})
+	registry.Register(&AttachmentPreviewsJob{})
+	registry.Register(&AttachmentProcessingJob{})
 
 	return registry
 }
@@ -0,0 +1,24 @@
+package database
+
+import "github.com/mabhi256/tasker/internal/lib/backfill"
+
+// Backfills are the data migrations that can be run from the admin API. New
+// and updated rows are covered by the migration that needs the backfill,
+// usually with a trigger, so a backfill only has to reach the rows written
+// before it.
+var Backfills = backfill.MustRegistry(
+	backfill.Backfill{
+		Name:        "todo_priority_rank",
+		Description: "Fills in priority_rank of todos created before it was added",
+		Iterate:     backfill.UUIDKeyset("todos", "priority_rank IS NULL"),
+		Process:     backfill.UpdateByID("todos", "priority_rank = todo_priority_rank(priority)"),
+	},
+	backfill.Backfill{
+		Name:        "todo_search_vector",
+		Description: "Populates the search index of todos created before it was added",
+		// Building the document is heavier than most updates
+		BatchSize: 500,
+		Iterate:   backfill.UUIDKeyset("todos", "search_vector IS NULL"),
+		Process:   backfill.UpdateByID("todos", "search_vector = todo_search_vector(title, description)"),
+	},
+)
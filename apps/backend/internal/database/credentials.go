@@ -0,0 +1,67 @@
+package database
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mabhi256/tasker/internal/config"
+	"github.com/rs/zerolog"
+)
+
+// passwordKey is the config key of the database password
+const passwordKey = "database.password"
+
+// rotatingPassword logs new connections in with the database password's
+// secret, fetched again once it is older than the rotation interval, so a
+// rotated password is picked up without a restart. Open connections stay
+// logged in with the password they were opened with.
+type rotatingPassword struct {
+	cfg      *config.Config
+	interval time.Duration
+	log      *zerolog.Logger
+
+	mu        sync.Mutex
+	password  string
+	fetchedAt time.Time
+}
+
+// newRotatingPassword returns nil when the password doesn't refer to a
+// secret, and is used as it is
+func newRotatingPassword(cfg *config.Config, logger *zerolog.Logger) *rotatingPassword {
+	if !cfg.IsSecret(passwordKey) {
+		return nil
+	}
+
+	return &rotatingPassword{
+		cfg:       cfg,
+		interval:  cfg.Secrets.RotationInterval,
+		log:       logger,
+		password:  cfg.Database.Password,
+		fetchedAt: time.Now(),
+	}
+}
+
+// beforeConnect sets the current password on a connection about to be
+// opened. A password that can't be fetched is kept until it can.
+func (p *rotatingPassword) beforeConnect(ctx context.Context, connConfig *pgx.ConnConfig) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if time.Since(p.fetchedAt) >= p.interval {
+		password, err := p.cfg.ResolveSecret(ctx, passwordKey)
+		if err != nil {
+			p.log.Warn().Err(err).Msg("failed to fetch the database password, using the previous one")
+		} else {
+			if password != p.password {
+				p.log.Info().Msg("Database password rotated")
+			}
+			p.password = password
+			p.fetchedAt = time.Now()
+		}
+	}
+
+	connConfig.Password = p.password
+	return nil
+}
@@ -0,0 +1,126 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	tern "github.com/jackc/tern/v2/migrate"
+)
+
+// checksumTable records the checksum of each applied migration. tern only
+// records the current version, which can't tell a migration that was
+// edited after it was applied from the one that ran.
+const checksumTable = "schema_version_checksums"
+
+// DriftError reports how the database's applied migrations differ from the
+// embedded ones. A drifted schema isn't the one the code was written for.
+type DriftError struct {
+	Problems []string
+}
+
+func (e *DriftError) Error() string {
+	return fmt.Sprintf("database schema has drifted from the embedded migrations: %s. "+
+		"Deploy the migrations the database was migrated with, or repair %s once the schema is verified",
+		strings.Join(e.Problems, "; "), checksumTable)
+}
+
+// migrationChecksum covers both directions, as either running differently
+// than recorded is drift
+func migrationChecksum(migration *tern.Migration) string {
+	sum := sha256.Sum256([]byte(migration.UpSQL + "\x00" + migration.DownSQL))
+	return hex.EncodeToString(sum[:])
+}
+
+func ensureChecksumTable(ctx context.Context, conn *pgx.Conn) error {
+	_, err := conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS `+checksumTable+` (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			checksum TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("creating %s table: %w", checksumTable, err)
+	}
+	return nil
+}
+
+// recordChecksums records the migrations up to current that aren't recorded
+// yet and forgets the reverted ones. Migrations applied before checksums
+// were recorded are recorded as they are embedded now.
+func (m *Migrator) recordChecksums(ctx context.Context, current int32) error {
+	batch := &pgx.Batch{}
+	batch.Queue(`DELETE FROM `+checksumTable+` WHERE version > $1`, current)
+	for _, migration := range m.tern.Migrations {
+		if migration.Sequence > current {
+			break
+		}
+		batch.Queue(`
+			INSERT INTO `+checksumTable+` (version, name, checksum)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (version) DO NOTHING
+		`, migration.Sequence, migration.Name, migrationChecksum(migration))
+	}
+
+	if err := m.conn.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("recording migration checksums: %w", err)
+	}
+	return nil
+}
+
+// CheckDrift returns a *DriftError if the database has migrations applied
+// that aren't embedded, or ones whose embedded file changed after it was
+// applied
+func (m *Migrator) CheckDrift(ctx context.Context) error {
+	current, err := m.CurrentVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	if latest := m.LatestVersion(); current > latest {
+		problems = append(problems, fmt.Sprintf("database is at version %d but only %d migrations are embedded", current, latest))
+	}
+
+	rows, err := m.conn.Query(ctx, `SELECT version, name, checksum FROM `+checksumTable+` WHERE version <= $1 ORDER BY version`, current)
+	if err != nil {
+		return fmt.Errorf("reading migration checksums: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			version        int32
+			name, checksum string
+		)
+		if err := rows.Scan(&version, &name, &checksum); err != nil {
+			return fmt.Errorf("reading migration checksums: %w", err)
+		}
+
+		if version > m.LatestVersion() {
+			problems = append(problems, fmt.Sprintf("applied migration %s is missing", name))
+			continue
+		}
+
+		migration := m.tern.Migrations[version-1]
+		switch {
+		case migration.Name != name:
+			problems = append(problems, fmt.Sprintf("migration %d was applied as %s but is embedded as %s", version, name, migration.Name))
+		case migrationChecksum(migration) != checksum:
+			problems = append(problems, fmt.Sprintf("migration %s changed after it was applied", name))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("reading migration checksums: %w", err)
+	}
+
+	if len(problems) > 0 {
+		return &DriftError{Problems: problems}
+	}
+	return nil
+}
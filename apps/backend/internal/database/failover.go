@@ -0,0 +1,154 @@
+package database
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mabhi256/tasker/internal/config"
+	"github.com/mabhi256/tasker/internal/sqlerr"
+)
+
+// minResetInterval keeps the statements failing at once during a failover
+// from resetting a pool over and over
+const minResetInterval = time.Second
+
+// resetter resets pools after failover errors, at most once per
+// minResetInterval each
+type resetter struct {
+	mu   sync.Mutex
+	last map[*pgxpool.Pool]time.Time
+}
+
+// reset closes the pool's connections, so the next statements connect
+// again and reach whichever server is the primary by now
+func (r *resetter) reset(pool *pgxpool.Pool) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.last[pool]) < minResetInterval {
+		return false
+	}
+	if r.last == nil {
+		r.last = make(map[*pgxpool.Pool]time.Time)
+	}
+	r.last[pool] = time.Now()
+
+	pool.Reset()
+	return true
+}
+
+// withFailoverRetries runs each statement q runs on pool again when it fails
+// because the database is failing over, see sqlerr.IsFailover. Transactions
+// are left alone, as their earlier statements are lost with the connection;
+// only starting one is retried.
+func (db *Database) withFailoverRetries(q Querier, pool *pgxpool.Pool) Querier {
+	if db.FailoverRetries <= 0 {
+		return q
+	}
+	return &failoverQuerier{db: db, q: q, pool: pool}
+}
+
+// retry runs fn, and after a failover error resets the pool and runs it
+// again, backing off between attempts, until it succeeds, fails otherwise,
+// runs out of retries or ctx ends
+func (db *Database) retry(ctx context.Context, pool *pgxpool.Pool, fn func() error) error {
+	err := fn()
+	backoff := db.FailoverBackoff
+
+	for attempt := 1; attempt <= db.FailoverRetries && err != nil && sqlerr.IsFailover(err); attempt++ {
+		if db.resets.reset(pool) && db.log != nil {
+			db.log.Warn().Err(err).Msg("database is failing over, reconnecting")
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, config.MaxFailoverBackoff)
+
+		err = fn()
+		if db.log != nil {
+			db.log.Debug().Err(err).Int("attempt", attempt).Msg("retried statement after failover error")
+		}
+	}
+
+	return err
+}
+
+type failoverQuerier struct {
+	db   *Database
+	q    Querier
+	pool *pgxpool.Pool
+}
+
+func (f *failoverQuerier) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	var tag pgconn.CommandTag
+	err := f.db.retry(ctx, f.pool, func() error {
+		var err error
+		tag, err = f.q.Exec(ctx, sql, args...)
+		return err
+	})
+	return tag, err
+}
+
+// Query is retried when it fails to start. Rows that fail once read can't
+// be, as some of them were already used.
+func (f *failoverQuerier) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	var rows pgx.Rows
+	err := f.db.retry(ctx, f.pool, func() error {
+		var err error
+		rows, err = f.q.Query(ctx, sql, args...)
+		return err
+	})
+	return rows, err
+}
+
+// QueryRow runs the query when its row is scanned, where its errors are
+// reported, so it can be run again
+func (f *failoverQuerier) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return &failoverRow{f: f, ctx: ctx, sql: sql, args: args}
+}
+
+func (f *failoverQuerier) Begin(ctx context.Context) (pgx.Tx, error) {
+	var tx pgx.Tx
+	err := f.db.retry(ctx, f.pool, func() error {
+		var err error
+		tx, err = f.q.Begin(ctx)
+		return err
+	})
+	return tx, err
+}
+
+type failoverRow struct {
+	f    *failoverQuerier
+	ctx  context.Context
+	sql  string
+	args []any
+}
+
+func (r *failoverRow) Scan(dest ...any) error {
+	return r.f.db.retry(r.ctx, r.f.pool, func() error {
+		return r.f.q.QueryRow(r.ctx, r.sql, r.args...).Scan(dest...)
+	})
+}
+
+// ReadOnly reports whether the primary rejects writes, such as a standby
+// while it is promoted. The pool is reset when it does, so the next check
+// connects to whichever server is the primary by then.
+func (db *Database) ReadOnly(ctx context.Context) (bool, error) {
+	var readOnly string
+	if err := db.Pool.QueryRow(ctx, "SHOW transaction_read_only").Scan(&readOnly); err != nil {
+		return false, err
+	}
+
+	if readOnly == "on" {
+		db.resets.reset(db.Pool)
+		return true, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,88 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mabhi256/tasker/internal/database"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/sqlerr"
+	testutil "github.com/mabhi256/tasker/internal/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailoverRetries(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping failover tests in short mode")
+	}
+
+	testDB, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, testDB.Pool.Config().ConnString())
+	require.NoError(t, err)
+	defer pool.Close()
+
+	// Opens the pool's connections, then has the server close them, as it
+	// does when it shuts down for a failover
+	failover := func(t *testing.T) {
+		require.NoError(t, pool.Ping(ctx))
+		_, err := testDB.Pool.Exec(ctx, `
+			SELECT pg_terminate_backend(pid) FROM pg_stat_activity
+			WHERE datname = current_database() AND pid <> pg_backend_pid()
+		`)
+		require.NoError(t, err)
+
+		// Terminating is asynchronous
+		require.Eventually(t, func() bool {
+			var others int
+			err := testDB.Pool.QueryRow(ctx, `
+				SELECT count(*) FROM pg_stat_activity
+				WHERE datname = current_database() AND pid <> pg_backend_pid()
+			`).Scan(&others)
+			return err == nil && others == 0
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+
+	selectOne := func(q database.Querier) error {
+		var one int
+		return q.QueryRow(ctx, `SELECT 1`).Scan(&one)
+	}
+
+	t.Run("without retries", func(t *testing.T) {
+		db := &database.Database{Pool: pool}
+		failover(t)
+
+		err := selectOne(db.Conn(ctx))
+		require.Error(t, err)
+		assert.True(t, sqlerr.IsFailover(err))
+
+		// Reported as a 503
+		var httpErr *errs.HTTPError
+		require.True(t, errors.As(sqlerr.HandleError(err), &httpErr))
+		assert.Equal(t, http.StatusServiceUnavailable, httpErr.Status)
+	})
+
+	t.Run("with retries", func(t *testing.T) {
+		db := &database.Database{Pool: pool, FailoverRetries: 3, FailoverBackoff: 10 * time.Millisecond}
+
+		failover(t)
+		assert.NoError(t, selectOne(db.Conn(ctx)))
+
+		failover(t)
+		_, err := db.Reader(ctx).Exec(ctx, `SELECT 1`)
+		assert.NoError(t, err)
+
+		// Statements of a transaction aren't retried, starting one is
+		failover(t)
+		assert.NoError(t, db.UnitOfWork(ctx, func(ctx context.Context) error {
+			return selectOne(db.Conn(ctx))
+		}))
+	})
+}
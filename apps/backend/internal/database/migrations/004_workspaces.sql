@@ -0,0 +1,92 @@
+CREATE TABLE workspaces (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    name TEXT NOT NULL,
+    description TEXT,
+    owner_id TEXT NOT NULL,
+    is_personal BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX idx_workspaces_owner_id ON workspaces(owner_id);
+
+-- Every user has at most one personal workspace
+CREATE UNIQUE INDEX workspaces_unique_personal ON workspaces(owner_id) WHERE is_personal;
+
+CREATE TRIGGER set_updated_at_workspaces
+    BEFORE UPDATE ON workspaces
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
+
+CREATE TABLE workspace_members (
+    workspace_id UUID NOT NULL REFERENCES workspaces ON DELETE CASCADE,
+    user_id TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    role TEXT NOT NULL DEFAULT 'member',
+
+    PRIMARY KEY (workspace_id, user_id),
+    CONSTRAINT valid_workspace_role CHECK (role IN ('owner', 'admin', 'member', 'viewer'))
+);
+
+CREATE INDEX idx_workspace_members_user_id ON workspace_members(user_id);
+
+CREATE TRIGGER set_updated_at_workspace_members
+    BEFORE UPDATE ON workspace_members
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
+
+-- Backfill: every existing user gets a personal workspace that owns their data
+INSERT INTO workspaces (name, owner_id, is_personal)
+SELECT
+    'Personal',
+    user_id,
+    TRUE
+FROM
+    (
+        SELECT user_id FROM todos
+        UNION
+        SELECT user_id FROM todo_categories
+        UNION
+        SELECT user_id FROM todo_comments
+    ) existing_users;
+
+INSERT INTO workspace_members (workspace_id, user_id, role)
+SELECT id, owner_id, 'owner' FROM workspaces;
+
+ALTER TABLE todo_categories ADD COLUMN workspace_id UUID REFERENCES workspaces ON DELETE CASCADE;
+ALTER TABLE todos ADD COLUMN workspace_id UUID REFERENCES workspaces ON DELETE CASCADE;
+ALTER TABLE todo_comments ADD COLUMN workspace_id UUID REFERENCES workspaces ON DELETE CASCADE;
+
+UPDATE todo_categories c
+SET workspace_id = w.id
+FROM workspaces w
+WHERE w.owner_id = c.user_id AND w.is_personal;
+
+UPDATE todos t
+SET workspace_id = w.id
+FROM workspaces w
+WHERE w.owner_id = t.user_id AND w.is_personal;
+
+-- Comments follow the todo they belong to
+UPDATE todo_comments com
+SET workspace_id = t.workspace_id
+FROM todos t
+WHERE t.id = com.todo_id;
+
+ALTER TABLE todo_categories ALTER COLUMN workspace_id SET NOT NULL;
+ALTER TABLE todos ALTER COLUMN workspace_id SET NOT NULL;
+ALTER TABLE todo_comments ALTER COLUMN workspace_id SET NOT NULL;
+
+-- Category names are unique per workspace instead of per user
+DROP INDEX todo_categories_unique_name;
+CREATE UNIQUE INDEX todo_categories_unique_name ON todo_categories(workspace_id, name);
+
+CREATE INDEX idx_todo_categories_workspace_id ON todo_categories(workspace_id);
+CREATE INDEX idx_todos_workspace_id ON todos(workspace_id);
+CREATE INDEX idx_todo_comments_workspace_id ON todo_comments(workspace_id);
+
+-- Composite index for workspace todos with status and priority
+CREATE INDEX idx_todos_workspace_status_priority ON todos(workspace_id, status, priority);
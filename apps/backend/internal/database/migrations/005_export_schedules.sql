@@ -0,0 +1,65 @@
+CREATE TABLE export_schedules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    workspace_id UUID NOT NULL REFERENCES workspaces ON DELETE CASCADE,
+    created_by TEXT NOT NULL,
+    name TEXT NOT NULL,
+    format TEXT NOT NULL,
+    destination_type TEXT NOT NULL,
+    -- Destination settings including credentials, never returned by the API
+    destination JSONB NOT NULL,
+    frequency TEXT NOT NULL,
+    hour_utc INT NOT NULL,
+    weekday INT,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    next_run_at TIMESTAMPTZ NOT NULL,
+    last_run_at TIMESTAMPTZ,
+
+    CONSTRAINT valid_export_format CHECK (format IN ('json', 'csv')),
+    CONSTRAINT valid_export_destination_type CHECK (destination_type IN ('s3', 'sftp')),
+    CONSTRAINT valid_export_frequency CHECK (frequency IN ('daily', 'weekly')),
+    CONSTRAINT valid_export_hour CHECK (hour_utc BETWEEN 0 AND 23),
+    CONSTRAINT valid_export_weekday CHECK (
+        (frequency = 'weekly' AND weekday BETWEEN 0 AND 6)
+        OR (frequency = 'daily' AND weekday IS NULL)
+    )
+);
+
+CREATE INDEX idx_export_schedules_workspace_id ON export_schedules(workspace_id);
+
+-- Scheduler scan for due exports
+CREATE INDEX idx_export_schedules_due ON export_schedules(next_run_at) WHERE enabled;
+
+CREATE TRIGGER set_updated_at_export_schedules
+    BEFORE UPDATE ON export_schedules
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
+
+-- One row per export attempt, doubling as the delivery receipt
+CREATE TABLE export_runs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    schedule_id UUID NOT NULL REFERENCES export_schedules ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ,
+    location TEXT,
+    row_count INT,
+    size_bytes BIGINT,
+    checksum_sha256 TEXT,
+    error TEXT,
+
+    CONSTRAINT valid_export_run_status CHECK (status IN ('pending', 'running', 'succeeded', 'failed'))
+);
+
+CREATE INDEX idx_export_runs_schedule_id_created_at ON export_runs(schedule_id, created_at DESC);
+
+CREATE TRIGGER set_updated_at_export_runs
+    BEFORE UPDATE ON export_runs
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
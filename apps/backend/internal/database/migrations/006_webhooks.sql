@@ -0,0 +1,56 @@
+CREATE TABLE webhooks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    workspace_id UUID NOT NULL REFERENCES workspaces ON DELETE CASCADE,
+    created_by TEXT NOT NULL,
+    url TEXT NOT NULL,
+    description TEXT,
+    events TEXT[] NOT NULL,
+    -- Signing secret, only returned when the webhook is created or rotated
+    secret TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+
+    CONSTRAINT valid_webhook_events CHECK (
+        cardinality(events) > 0
+        AND events <@ ARRAY['todo.created', 'todo.completed', 'comment.added']
+    )
+);
+
+CREATE INDEX idx_webhooks_workspace_id ON webhooks(workspace_id);
+
+CREATE TRIGGER set_updated_at_webhooks
+    BEFORE UPDATE ON webhooks
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
+
+-- One row per event sent to a webhook, updated on every attempt
+CREATE TABLE webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    webhook_id UUID NOT NULL REFERENCES webhooks ON DELETE CASCADE,
+    event_id UUID NOT NULL,
+    event_type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    response_status INT,
+    response_body TEXT,
+    error TEXT,
+    duration_ms INT,
+    last_attempt_at TIMESTAMPTZ,
+    delivered_at TIMESTAMPTZ,
+
+    CONSTRAINT valid_webhook_delivery_status CHECK (status IN ('pending', 'succeeded', 'failed')),
+    CONSTRAINT unique_webhook_delivery_event UNIQUE (webhook_id, event_id)
+);
+
+CREATE INDEX idx_webhook_deliveries_webhook_id_created_at ON webhook_deliveries(webhook_id, created_at DESC);
+
+CREATE TRIGGER set_updated_at_webhook_deliveries
+    BEFORE UPDATE ON webhook_deliveries
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
@@ -0,0 +1,33 @@
+-- Domain events written in the same transaction as the change that caused
+-- them, then relayed to webhooks and realtime subscribers
+CREATE TABLE event_outbox (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    workspace_id UUID NOT NULL REFERENCES workspaces ON DELETE CASCADE,
+    event_type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    published_at TIMESTAMPTZ,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    -- Failed events are retried with a backoff
+    available_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_event_outbox_unpublished ON event_outbox(available_at, created_at)
+    WHERE published_at IS NULL;
+
+-- Wake the relay as soon as events are committed. The relay also polls, so
+-- a missed notification only delays an event.
+CREATE OR REPLACE FUNCTION notify_event_outbox()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('event_outbox', '');
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER notify_event_outbox
+    AFTER INSERT ON event_outbox
+    FOR EACH STATEMENT
+    EXECUTE FUNCTION notify_event_outbox();
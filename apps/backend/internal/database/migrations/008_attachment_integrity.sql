@@ -0,0 +1,16 @@
+-- Checksum recorded at upload, and the outcome of the last integrity check
+-- against the stored object
+ALTER TABLE todo_attachments
+    ADD COLUMN checksum_sha256 TEXT,
+    ADD COLUMN integrity_status TEXT NOT NULL DEFAULT 'unverified',
+    ADD COLUMN integrity_checked_at TIMESTAMPTZ,
+    ADD COLUMN integrity_error TEXT,
+    ADD CONSTRAINT valid_attachment_integrity_status CHECK (
+        integrity_status IN ('unverified', 'ok', 'missing', 'corrupt')
+    );
+
+-- Attachments that were never checked, or checked longest ago, are sampled first
+CREATE INDEX idx_todo_attachments_integrity_checked_at ON todo_attachments(integrity_checked_at NULLS FIRST);
+
+CREATE INDEX idx_todo_attachments_integrity_flagged ON todo_attachments(integrity_status)
+    WHERE integrity_status IN ('missing', 'corrupt');
@@ -0,0 +1,15 @@
+-- Open todos are a small share of the table once a workspace has some
+-- history, so the reminder and overdue scans index only those
+CREATE INDEX idx_todos_open_due_date ON todos(due_date)
+    WHERE due_date IS NOT NULL AND status NOT IN ('completed', 'archived');
+
+CREATE INDEX idx_todos_user_open_due_date ON todos(user_id, due_date)
+    WHERE status NOT IN ('completed', 'archived');
+
+-- Auto-archive walks completed todos by completion time
+CREATE INDEX idx_todos_completed_at ON todos(completed_at)
+    WHERE status = 'completed';
+
+-- Todo lists show root todos, newest first, unless a parent is given
+CREATE INDEX idx_todos_workspace_root_created_at ON todos(workspace_id, created_at DESC)
+    WHERE parent_todo_id IS NULL;
@@ -0,0 +1,27 @@
+-- Background tasks that ran out of retries and were archived by the job
+-- server. A task that is retried by hand and fails again counts as another
+-- occurrence of the same failure.
+CREATE TABLE job_failures (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    task_id TEXT NOT NULL,
+    queue TEXT NOT NULL,
+    task_type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    error TEXT NOT NULL,
+    retried INT NOT NULL DEFAULT 0,
+    max_retry INT NOT NULL DEFAULT 0,
+    failed_at TIMESTAMPTZ NOT NULL,
+    occurrences INT NOT NULL DEFAULT 1
+);
+
+CREATE UNIQUE INDEX job_failures_unique_task ON job_failures(queue, task_id);
+CREATE INDEX idx_job_failures_failed_at ON job_failures(failed_at DESC);
+CREATE INDEX idx_job_failures_task_type ON job_failures(task_type);
+
+CREATE TRIGGER set_updated_at_job_failures
+    BEFORE UPDATE ON job_failures
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
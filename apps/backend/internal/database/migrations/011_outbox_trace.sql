@@ -0,0 +1,4 @@
+-- Distributed tracing headers of the request that recorded the event, so
+-- relaying it and the webhook deliveries it causes continue the same trace
+ALTER TABLE event_outbox
+    ADD COLUMN trace JSONB;
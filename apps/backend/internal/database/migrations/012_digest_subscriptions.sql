@@ -0,0 +1,31 @@
+-- Each user's digest email settings. The hour is in the user's timezone, so
+-- the digest arrives at the same local time across DST changes.
+CREATE TABLE digest_subscriptions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    user_id TEXT NOT NULL UNIQUE,
+    frequency TEXT NOT NULL,
+    hour INT NOT NULL,
+    weekday INT,
+    timezone TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    next_run_at TIMESTAMPTZ NOT NULL,
+    last_sent_at TIMESTAMPTZ,
+
+    CONSTRAINT valid_digest_frequency CHECK (frequency IN ('daily', 'weekly')),
+    CONSTRAINT valid_digest_hour CHECK (hour BETWEEN 0 AND 23),
+    CONSTRAINT valid_digest_weekday CHECK (
+        (frequency = 'weekly' AND weekday BETWEEN 0 AND 6)
+        OR (frequency = 'daily' AND weekday IS NULL)
+    )
+);
+
+-- Scheduler scan for due digests
+CREATE INDEX idx_digest_subscriptions_due ON digest_subscriptions(next_run_at) WHERE enabled;
+
+CREATE TRIGGER set_updated_at_digest_subscriptions
+    BEFORE UPDATE ON digest_subscriptions
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
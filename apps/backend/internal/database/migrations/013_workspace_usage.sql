@@ -0,0 +1,23 @@
+-- Resource use charged to each workspace per UTC day, for cost attribution.
+-- Every instance adds the DB time, task executions and email sends it
+-- counted; storage is a snapshot taken by the cost-attribution job. The nil
+-- UUID collects use no workspace could be charged for. Rows have no foreign
+-- key so the report still covers workspaces deleted since.
+CREATE TABLE workspace_usage_daily (
+    day DATE NOT NULL,
+    workspace_id UUID NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    db_time_ms BIGINT NOT NULL DEFAULT 0,
+    job_executions BIGINT NOT NULL DEFAULT 0,
+    emails_sent BIGINT NOT NULL DEFAULT 0,
+    storage_bytes BIGINT NOT NULL DEFAULT 0,
+
+    PRIMARY KEY (day, workspace_id)
+);
+
+CREATE TRIGGER set_updated_at_workspace_usage_daily
+    BEFORE UPDATE ON workspace_usage_daily
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
@@ -0,0 +1,30 @@
+-- Devices users registered for push notifications. The token is the FCM
+-- registration token, or the endpoint of a Web Push subscription, which also
+-- has the keys its messages are encrypted with. A token is unique, so a
+-- device that changes hands moves to its new user.
+CREATE TABLE push_devices (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    user_id TEXT NOT NULL,
+    platform TEXT NOT NULL,
+    token TEXT NOT NULL UNIQUE,
+    p256dh TEXT,
+    auth TEXT,
+    name TEXT,
+    last_used_at TIMESTAMPTZ,
+
+    CONSTRAINT valid_push_platform CHECK (platform IN ('fcm', 'web')),
+    CONSTRAINT valid_push_keys CHECK (
+        (platform = 'web' AND p256dh IS NOT NULL AND auth IS NOT NULL)
+        OR (platform = 'fcm' AND p256dh IS NULL AND auth IS NULL)
+    )
+);
+
+CREATE INDEX idx_push_devices_user_id ON push_devices(user_id);
+
+CREATE TRIGGER set_updated_at_push_devices
+    BEFORE UPDATE ON push_devices
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
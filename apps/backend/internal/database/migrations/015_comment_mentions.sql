@@ -0,0 +1,14 @@
+-- Users mentioned in a comment. A mention is only stored for a member of the
+-- comment's workspace, and goes away with the comment.
+CREATE TABLE comment_mentions (
+    comment_id UUID NOT NULL REFERENCES todo_comments ON DELETE CASCADE,
+    user_id TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    workspace_id UUID NOT NULL REFERENCES workspaces ON DELETE CASCADE,
+
+    PRIMARY KEY (comment_id, user_id)
+);
+
+-- "Comments mentioning me", newest first
+CREATE INDEX idx_comment_mentions_user ON comment_mentions(user_id, workspace_id, created_at DESC);
@@ -0,0 +1,24 @@
+-- Progress of expand/contract backfills. last_id is the keyset cursor, so a
+-- backfill that stops resumes after the last row it reached.
+CREATE TABLE schema_rollouts (
+    name TEXT PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    status TEXT NOT NULL DEFAULT 'pending',
+    last_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000',
+    rows_done BIGINT NOT NULL DEFAULT 0,
+    batches INT NOT NULL DEFAULT 0,
+    pending_rows BIGINT,
+    last_error TEXT,
+    started_at TIMESTAMPTZ,
+    backfilled_at TIMESTAMPTZ,
+    verified_at TIMESTAMPTZ,
+
+    CONSTRAINT valid_rollout_status CHECK (status IN ('pending', 'running', 'backfilled', 'verified', 'failed'))
+);
+
+CREATE TRIGGER set_updated_at_schema_rollouts
+    BEFORE UPDATE ON schema_rollouts
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
@@ -0,0 +1,75 @@
+-- Checkpoints of background backfills. checkpoint is the keyset position of
+-- the last batch, saved in the same transaction as the batch.
+CREATE TABLE backfill_runs (
+    name TEXT PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    status TEXT NOT NULL DEFAULT 'running',
+    checkpoint TEXT NOT NULL DEFAULT '',
+    rows_done BIGINT NOT NULL DEFAULT 0,
+    batches INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMPTZ,
+
+    CONSTRAINT valid_backfill_status CHECK (status IN ('running', 'paused', 'completed', 'aborted', 'failed'))
+);
+
+CREATE TRIGGER set_updated_at_backfill_runs
+    BEFORE UPDATE ON backfill_runs
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
+
+-- Backfills set tasker.backfill for their transaction, so rewriting a row
+-- doesn't count as an edit of it
+CREATE OR REPLACE FUNCTION trigger_set_updated_at()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF current_setting('tasker.backfill', true) = 'on' THEN
+        RETURN NEW;
+    END IF;
+    NEW.updated_at = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Priority as a number, so todos sort low < medium < high. Existing rows are
+-- filled in by the todo_priority_rank backfill.
+CREATE OR REPLACE FUNCTION todo_priority_rank(priority TEXT)
+RETURNS SMALLINT AS $$
+    SELECT CASE priority
+        WHEN 'low' THEN 1
+        WHEN 'medium' THEN 2
+        WHEN 'high' THEN 3
+    END::SMALLINT;
+$$ LANGUAGE sql IMMUTABLE;
+
+-- Search document of a todo. Existing rows are filled in by the
+-- todo_search_vector backfill.
+CREATE OR REPLACE FUNCTION todo_search_vector(title TEXT, description TEXT)
+RETURNS TSVECTOR AS $$
+    SELECT setweight(to_tsvector('simple', COALESCE(title, '')), 'A')
+        || setweight(to_tsvector('simple', COALESCE(description, '')), 'B');
+$$ LANGUAGE sql IMMUTABLE;
+
+ALTER TABLE todos
+    ADD COLUMN priority_rank SMALLINT,
+    ADD COLUMN search_vector TSVECTOR;
+
+CREATE INDEX idx_todos_workspace_priority_rank ON todos(workspace_id, priority_rank);
+CREATE INDEX idx_todos_search_vector ON todos USING GIN (search_vector);
+
+CREATE OR REPLACE FUNCTION trigger_set_todo_derived()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.priority_rank = todo_priority_rank(NEW.priority);
+    NEW.search_vector = todo_search_vector(NEW.title, NEW.description);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER set_todo_derived
+    BEFORE INSERT OR UPDATE OF priority, title, description ON todos
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_todo_derived();
@@ -0,0 +1,18 @@
+-- Replies to a comment. Deleting a comment deletes its replies with it.
+ALTER TABLE todo_comments
+    ADD COLUMN parent_comment_id UUID REFERENCES todo_comments ON DELETE CASCADE;
+
+CREATE INDEX idx_todo_comments_parent_comment_id ON todo_comments(parent_comment_id)
+    WHERE parent_comment_id IS NOT NULL;
+
+-- Emoji reactions. A user reacts to a comment with each emoji at most once.
+CREATE TABLE comment_reactions (
+    comment_id UUID NOT NULL REFERENCES todo_comments ON DELETE CASCADE,
+    user_id TEXT NOT NULL,
+    emoji TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    workspace_id UUID NOT NULL REFERENCES workspaces ON DELETE CASCADE,
+
+    PRIMARY KEY (comment_id, user_id, emoji)
+);
@@ -0,0 +1,16 @@
+-- A todo is blocked by the todos it depends on until they are completed or
+-- archived. Cycles are rejected by the service before a dependency is added.
+CREATE TABLE todo_dependencies (
+    todo_id UUID NOT NULL REFERENCES todos ON DELETE CASCADE,
+    blocked_by_id UUID NOT NULL REFERENCES todos ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    workspace_id UUID NOT NULL REFERENCES workspaces ON DELETE CASCADE,
+
+    PRIMARY KEY (todo_id, blocked_by_id),
+    CONSTRAINT todo_not_blocked_by_itself CHECK (todo_id <> blocked_by_id)
+);
+
+-- "What does this todo block", and loading a workspace's dependency graph
+CREATE INDEX idx_todo_dependencies_blocked_by_id ON todo_dependencies(blocked_by_id);
+CREATE INDEX idx_todo_dependencies_workspace_id ON todo_dependencies(workspace_id);
@@ -0,0 +1,32 @@
+-- Audit events of the admin API aren't about any one workspace, so they go
+-- through the outbox without one
+ALTER TABLE event_outbox
+    ALTER COLUMN workspace_id DROP NOT NULL;
+
+-- Forwarders ship audit events to a SIEM. One without a workspace is global
+-- and receives the audit events of every workspace and of the admin API.
+CREATE TABLE audit_forwarders (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    workspace_id UUID REFERENCES workspaces ON DELETE CASCADE,
+    created_by TEXT NOT NULL,
+    name TEXT NOT NULL,
+    format TEXT NOT NULL,
+    endpoint TEXT NOT NULL,
+    -- Bearer or HEC token, never returned by the API
+    token TEXT,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    last_forwarded_at TIMESTAMPTZ,
+    last_error TEXT,
+
+    CONSTRAINT valid_audit_forwarder_format CHECK (format IN ('syslog', 'http', 'splunk_hec'))
+);
+
+CREATE INDEX idx_audit_forwarders_workspace_id ON audit_forwarders(workspace_id);
+
+CREATE TRIGGER set_updated_at_audit_forwarders
+    BEFORE UPDATE ON audit_forwarders
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
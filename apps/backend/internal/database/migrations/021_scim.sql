@@ -0,0 +1,71 @@
+-- Bearer token an identity provider uses to provision a workspace over
+-- SCIM. Only a hash is stored; the token is shown once when it is created.
+CREATE TABLE scim_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    workspace_id UUID NOT NULL UNIQUE REFERENCES workspaces ON DELETE CASCADE,
+    created_by TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    last_used_at TIMESTAMPTZ
+);
+
+-- Users provisioned by the identity provider. Each maps to a user of the
+-- auth provider, and is a member of the workspace while active.
+CREATE TABLE scim_users (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    workspace_id UUID NOT NULL REFERENCES workspaces ON DELETE CASCADE,
+    user_id TEXT NOT NULL,
+    external_id TEXT,
+    user_name TEXT NOT NULL,
+    email TEXT NOT NULL,
+    given_name TEXT,
+    family_name TEXT,
+    display_name TEXT,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+
+    UNIQUE (workspace_id, user_id)
+);
+
+-- SCIM user names are case insensitive
+CREATE UNIQUE INDEX idx_scim_users_workspace_user_name ON scim_users(workspace_id, LOWER(user_name));
+
+CREATE TRIGGER set_updated_at_scim_users
+    BEFORE UPDATE ON scim_users
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
+
+-- Groups pushed by the identity provider. A workspace admin maps a group to
+-- a workspace role, which its members are given.
+CREATE TABLE scim_groups (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    workspace_id UUID NOT NULL REFERENCES workspaces ON DELETE CASCADE,
+    external_id TEXT,
+    display_name TEXT NOT NULL,
+    role TEXT,
+
+    CONSTRAINT valid_scim_group_role CHECK (role IN ('admin', 'member', 'viewer'))
+);
+
+CREATE UNIQUE INDEX idx_scim_groups_workspace_display_name ON scim_groups(workspace_id, LOWER(display_name));
+
+CREATE TRIGGER set_updated_at_scim_groups
+    BEFORE UPDATE ON scim_groups
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
+
+CREATE TABLE scim_group_members (
+    group_id UUID NOT NULL REFERENCES scim_groups ON DELETE CASCADE,
+    scim_user_id UUID NOT NULL REFERENCES scim_users ON DELETE CASCADE,
+
+    PRIMARY KEY (group_id, scim_user_id)
+);
+
+-- "Which groups is this user in", to work out their role
+CREATE INDEX idx_scim_group_members_scim_user_id ON scim_group_members(scim_user_id);
@@ -0,0 +1,19 @@
+-- OpenID Connect single sign-on for a workspace. While enforced, members
+-- other than the owner must verify with the identity provider, with an
+-- email in one of the allowed domains, before they can use the API.
+CREATE TABLE workspace_sso (
+    workspace_id UUID PRIMARY KEY REFERENCES workspaces ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    created_by TEXT NOT NULL,
+    discovery_url TEXT NOT NULL,
+    client_id TEXT NOT NULL,
+    allowed_domains TEXT[] NOT NULL DEFAULT '{}',
+    enforced BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TRIGGER set_updated_at_workspace_sso
+    BEFORE UPDATE ON workspace_sso
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
@@ -0,0 +1,31 @@
+-- Restricts a workspace to requests from its allowlisted networks while
+-- enabled. Break glass lifts the restriction until break_glass_until, for
+-- owners locked out by a wrong allowlist.
+CREATE TABLE workspace_ip_allowlists (
+    workspace_id UUID PRIMARY KEY REFERENCES workspaces ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_by TEXT NOT NULL,
+    break_glass_until TIMESTAMPTZ,
+    break_glass_by TEXT,
+    break_glass_reason TEXT
+);
+
+CREATE TRIGGER set_updated_at_workspace_ip_allowlists
+    BEFORE UPDATE ON workspace_ip_allowlists
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
+
+CREATE TABLE workspace_ip_allowlist_entries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    workspace_id UUID NOT NULL REFERENCES workspaces ON DELETE CASCADE,
+    created_by TEXT NOT NULL,
+    cidr CIDR NOT NULL,
+    description TEXT,
+
+    UNIQUE (workspace_id, cidr)
+);
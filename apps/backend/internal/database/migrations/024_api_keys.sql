@@ -0,0 +1,19 @@
+-- Keys users create for programmatic access, acting as the user. Only a
+-- hash is stored; the key is shown once when it is created, and hint is
+-- its last characters so users can tell their keys apart.
+CREATE TABLE api_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    scope TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    hint TEXT NOT NULL,
+    last_used_at TIMESTAMPTZ,
+
+    CONSTRAINT valid_api_key_scope CHECK (scope IN ('read_only', 'read_write'))
+);
+
+CREATE INDEX idx_api_keys_user_id ON api_keys(user_id);
+
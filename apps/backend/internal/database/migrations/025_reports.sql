@@ -0,0 +1,26 @@
+-- PDF summaries of a workspace, generated in the background for the user
+-- who asked for one. The PDF is kept in the upload bucket under object_key.
+CREATE TABLE reports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    workspace_id UUID NOT NULL REFERENCES workspaces ON DELETE CASCADE,
+    user_id TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    send_email BOOLEAN NOT NULL DEFAULT FALSE,
+    attempts INT NOT NULL DEFAULT 0,
+    object_key TEXT,
+    size_bytes BIGINT,
+    finished_at TIMESTAMPTZ,
+    error TEXT,
+
+    CONSTRAINT valid_report_status CHECK (status IN ('pending', 'running', 'succeeded', 'failed'))
+);
+
+CREATE INDEX idx_reports_workspace_id_user_id ON reports(workspace_id, user_id);
+
+CREATE TRIGGER set_updated_at_reports
+    BEFORE UPDATE ON reports
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
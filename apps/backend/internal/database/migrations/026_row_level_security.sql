@@ -0,0 +1,52 @@
+-- The workspace and user a connection acts for, set by the application from
+-- the request's scope on every connection it acquires. Either is NULL when
+-- the work has no scope, such as jobs and cron, and isn't constrained then.
+CREATE FUNCTION current_workspace_scope() RETURNS UUID AS $$
+    SELECT NULLIF(current_setting('app.workspace_id', TRUE), '')::UUID
+$$ LANGUAGE sql STABLE;
+
+CREATE FUNCTION current_user_scope() RETURNS TEXT AS $$
+    SELECT NULLIF(current_setting('app.user_id', TRUE), '')
+$$ LANGUAGE sql STABLE;
+
+-- Workspace data is only visible, and only written, within the scoped
+-- workspace. FORCE applies the policies to the tables' owner, which the
+-- application usually connects as. Superusers always bypass them.
+ALTER TABLE todos ENABLE ROW LEVEL SECURITY;
+ALTER TABLE todos FORCE ROW LEVEL SECURITY;
+CREATE POLICY workspace_scope ON todos
+    USING (current_workspace_scope() IS NULL OR workspace_id = current_workspace_scope());
+
+ALTER TABLE todo_categories ENABLE ROW LEVEL SECURITY;
+ALTER TABLE todo_categories FORCE ROW LEVEL SECURITY;
+CREATE POLICY workspace_scope ON todo_categories
+    USING (current_workspace_scope() IS NULL OR workspace_id = current_workspace_scope());
+
+ALTER TABLE todo_comments ENABLE ROW LEVEL SECURITY;
+ALTER TABLE todo_comments FORCE ROW LEVEL SECURITY;
+CREATE POLICY workspace_scope ON todo_comments
+    USING (current_workspace_scope() IS NULL OR workspace_id = current_workspace_scope());
+
+ALTER TABLE comment_mentions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE comment_mentions FORCE ROW LEVEL SECURITY;
+CREATE POLICY workspace_scope ON comment_mentions
+    USING (current_workspace_scope() IS NULL OR workspace_id = current_workspace_scope());
+
+ALTER TABLE comment_reactions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE comment_reactions FORCE ROW LEVEL SECURITY;
+CREATE POLICY workspace_scope ON comment_reactions
+    USING (current_workspace_scope() IS NULL OR workspace_id = current_workspace_scope());
+
+ALTER TABLE todo_dependencies ENABLE ROW LEVEL SECURITY;
+ALTER TABLE todo_dependencies FORCE ROW LEVEL SECURITY;
+CREATE POLICY workspace_scope ON todo_dependencies
+    USING (current_workspace_scope() IS NULL OR workspace_id = current_workspace_scope());
+
+-- Reports also belong to the user who asked for them
+ALTER TABLE reports ENABLE ROW LEVEL SECURITY;
+ALTER TABLE reports FORCE ROW LEVEL SECURITY;
+CREATE POLICY workspace_scope ON reports
+    USING (
+        (current_workspace_scope() IS NULL OR workspace_id = current_workspace_scope())
+        AND (current_user_scope() IS NULL OR user_id = current_user_scope())
+    );
@@ -0,0 +1,27 @@
+-- Frozen copies of a todo, its comments and attachment metadata, kept in the
+-- upload bucket under object_key for audit and sharing. A snapshot outlives
+-- its todo and is deleted with its object once expires_at passes.
+CREATE TABLE todo_snapshots (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    workspace_id UUID NOT NULL REFERENCES workspaces ON DELETE CASCADE,
+    todo_id UUID NOT NULL,
+    created_by TEXT NOT NULL,
+    format TEXT NOT NULL,
+    object_key TEXT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    checksum_sha256 TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+
+    CONSTRAINT valid_snapshot_format CHECK (format IN ('json', 'html'))
+);
+
+CREATE INDEX idx_todo_snapshots_workspace_id_todo_id ON todo_snapshots(workspace_id, todo_id);
+-- Retention cleanup
+CREATE INDEX idx_todo_snapshots_expires_at ON todo_snapshots(expires_at);
+
+ALTER TABLE todo_snapshots ENABLE ROW LEVEL SECURITY;
+ALTER TABLE todo_snapshots FORCE ROW LEVEL SECURITY;
+CREATE POLICY workspace_scope ON todo_snapshots
+    USING (current_workspace_scope() IS NULL OR workspace_id = current_workspace_scope());
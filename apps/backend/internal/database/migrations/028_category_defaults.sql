@@ -0,0 +1,12 @@
+-- Defaults a category gives the todos created in it without their own
+ALTER TABLE todo_categories
+    ADD COLUMN default_priority TEXT,
+    ADD COLUMN default_reminder_minutes INTEGER,
+    ADD CONSTRAINT valid_default_priority CHECK (default_priority IN ('low', 'medium', 'high')),
+    ADD CONSTRAINT valid_default_reminder_minutes CHECK (default_reminder_minutes > 0);
+
+-- How long before it's due a todo's reminder is sent, or the configured
+-- number of hours when NULL
+ALTER TABLE todos
+    ADD COLUMN reminder_minutes INTEGER,
+    ADD CONSTRAINT valid_reminder_minutes CHECK (reminder_minutes > 0);
@@ -0,0 +1,8 @@
+-- Scheduled todos are published, turning active, once publish_at passes.
+-- Published todos keep the time they were published at.
+ALTER TABLE todos
+    ADD COLUMN publish_at TIMESTAMPTZ,
+    ADD CONSTRAINT scheduled_todos_have_publish_at CHECK (status <> 'scheduled' OR publish_at IS NOT NULL);
+
+CREATE INDEX idx_todos_scheduled_publish_at ON todos(publish_at)
+    WHERE status = 'scheduled';
@@ -0,0 +1,18 @@
+-- Anonymous trials of the app without signing up. A trial acts as a user of
+-- its own, trial_<id>, whose personal workspace holds its data, and is
+-- authenticated by a token of which only a hash is stored. Claiming a trial
+-- hands its workspace and data to a signed up user and ends the trial.
+-- Unclaimed trials are deleted, along with their workspace, once expired.
+CREATE TABLE trials (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    user_id TEXT NOT NULL UNIQUE,
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    claimed_by TEXT,
+    claimed_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_trials_expires_at ON trials(expires_at) WHERE claimed_at IS NULL;
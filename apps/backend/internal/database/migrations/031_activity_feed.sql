@@ -0,0 +1,8 @@
+-- The user whose request recorded an event, its actor in the workspace's
+-- activity feed. It is NULL for events recorded outside a request, such as
+-- scheduled todos being published.
+ALTER TABLE event_outbox
+    ADD COLUMN actor_id TEXT;
+
+-- The activity feed lists a workspace's events newest first
+CREATE INDEX idx_event_outbox_workspace_feed ON event_outbox(workspace_id, created_at DESC);
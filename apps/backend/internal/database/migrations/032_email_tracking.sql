@@ -0,0 +1,27 @@
+-- Notification emails whose opens and clicks are tracked, through a pixel
+-- and links redirected by the API. Engagement tunes how often digests are
+-- sent, and stops mail to addresses that look dead. Messages are deleted
+-- after the configured retention.
+CREATE TABLE email_messages (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    user_id TEXT NOT NULL,
+    address TEXT NOT NULL,
+    template TEXT NOT NULL,
+    opened_at TIMESTAMPTZ,
+    open_count INT NOT NULL DEFAULT 0,
+    clicked_at TIMESTAMPTZ,
+    click_count INT NOT NULL DEFAULT 0
+);
+
+-- Engagement is read as the latest messages to an address or user
+CREATE INDEX idx_email_messages_address ON email_messages(address, created_at DESC);
+CREATE INDEX idx_email_messages_user_template ON email_messages(user_id, template, created_at DESC);
+CREATE INDEX idx_email_messages_created_at ON email_messages(created_at);
+
+-- Workspaces opt out of tracking for their members. Members of any
+-- workspace that opted out aren't tracked, so turning it off in a personal
+-- workspace opts the user out.
+ALTER TABLE workspaces
+    ADD COLUMN email_tracking BOOLEAN NOT NULL DEFAULT TRUE;
@@ -0,0 +1,11 @@
+-- What clients need to preview an attachment without downloading it, such
+-- as a PDF's page count, an image's dimensions or the start of a text file,
+-- extracted by a job after upload. preview_extracted_at is set once the job
+-- ran, and preview stays NULL for files that have no preview.
+ALTER TABLE todo_attachments
+    ADD COLUMN preview JSONB,
+    ADD COLUMN preview_extracted_at TIMESTAMPTZ;
+
+-- Attachments the job hasn't run for are picked up by the cron job
+CREATE INDEX idx_todo_attachments_preview_pending ON todo_attachments(created_at)
+    WHERE preview_extracted_at IS NULL;
@@ -0,0 +1,37 @@
+-- Attachments are processed by a job after upload: held to the size and type
+-- policy, and given the files derived from them. Attachments the policy
+-- rejects are kept, but can't be downloaded.
+ALTER TABLE todo_attachments
+    ADD COLUMN processing_status TEXT NOT NULL DEFAULT 'pending',
+    ADD COLUMN processing_error TEXT,
+    ADD COLUMN processed_at TIMESTAMPTZ,
+    ADD CONSTRAINT valid_attachment_processing_status CHECK (
+        processing_status IN ('pending', 'processed', 'rejected')
+    );
+
+-- Attachments the job hasn't run for are picked up by the cron job
+CREATE INDEX idx_todo_attachments_processing_pending ON todo_attachments(created_at)
+    WHERE processing_status = 'pending';
+
+-- The files derived from an attachment, stored next to it: a thumbnail of
+-- an image, or the text of a PDF for the search engine's connector to index
+CREATE TABLE attachment_artifacts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMP(3) WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP(3) WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    attachment_id UUID NOT NULL REFERENCES todo_attachments ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('thumbnail', 'text')),
+    storage_key TEXT NOT NULL,
+    mime_type TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    width INT,
+    height INT,
+
+    UNIQUE (attachment_id, kind)
+);
+
+CREATE TRIGGER set_updated_at_attachment_artifacts
+    BEFORE UPDATE ON attachment_artifacts
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
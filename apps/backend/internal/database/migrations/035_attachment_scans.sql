@@ -0,0 +1,10 @@
+-- Attachments are scanned for malware when they are processed. Infected
+-- files are moved to the quarantine prefix, which download_key then points
+-- at, and the attachment is rejected.
+ALTER TABLE todo_attachments
+    ADD COLUMN scan_status TEXT NOT NULL DEFAULT 'unscanned',
+    ADD COLUMN scan_signature TEXT,
+    ADD COLUMN scanned_at TIMESTAMPTZ,
+    ADD CONSTRAINT valid_attachment_scan_status CHECK (
+        scan_status IN ('unscanned', 'clean', 'infected')
+    );
@@ -0,0 +1,17 @@
+-- ICU collations titles and names are sorted with, one for each language
+-- the API matches user locales to, named locale_<language>. locale_und is
+-- the Unicode root collation, used for the other languages. Unlike byte
+-- order, it sorts accented letters next to their base letters, and capitals
+-- next to the lowercase letters.
+DO $$
+DECLARE
+    lang TEXT;
+BEGIN
+    FOREACH lang IN ARRAY ARRAY[
+        'und', 'ar', 'cs', 'da', 'de', 'el', 'es', 'fi', 'fr', 'he', 'hi', 'hu', 'it', 'ja',
+        'ko', 'nb', 'nl', 'pl', 'pt', 'ro', 'ru', 'sk', 'sv', 'th', 'tr', 'uk', 'vi', 'zh'
+    ] LOOP
+        EXECUTE format('CREATE COLLATION IF NOT EXISTS %I (provider = icu, locale = %L)', 'locale_' || lang, lang);
+    END LOOP;
+END
+$$;
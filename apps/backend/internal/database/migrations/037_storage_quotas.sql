@@ -0,0 +1,99 @@
+-- Attachments carry the workspace of their todo, so their bytes are still
+-- counted against it when a deleted todo takes them along
+ALTER TABLE todo_attachments ADD COLUMN workspace_id UUID REFERENCES workspaces ON DELETE CASCADE;
+
+UPDATE todo_attachments a
+SET
+    workspace_id = t.workspace_id
+FROM
+    todos t
+WHERE
+    t.id = a.todo_id;
+
+ALTER TABLE todo_attachments ALTER COLUMN workspace_id SET NOT NULL;
+
+CREATE OR REPLACE FUNCTION set_attachment_workspace()
+RETURNS TRIGGER AS $$
+BEGIN
+    SELECT workspace_id INTO NEW.workspace_id FROM todos WHERE id = NEW.todo_id;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER set_attachment_workspace
+    BEFORE INSERT ON todo_attachments
+    FOR EACH ROW
+    EXECUTE FUNCTION set_attachment_workspace();
+
+-- Bytes of attachments each user uploaded to each workspace, kept by
+-- trigger so quotas are checked without summing attachments
+CREATE TABLE storage_usage (
+    workspace_id UUID NOT NULL REFERENCES workspaces ON DELETE CASCADE,
+    user_id TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    bytes BIGINT NOT NULL DEFAULT 0,
+    attachments INT NOT NULL DEFAULT 0,
+
+    PRIMARY KEY (workspace_id, user_id)
+);
+
+CREATE INDEX idx_storage_usage_user_id ON storage_usage(user_id);
+
+CREATE TRIGGER set_updated_at_storage_usage
+    BEFORE UPDATE ON storage_usage
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_updated_at();
+
+INSERT INTO
+    storage_usage (workspace_id, user_id, bytes, attachments)
+SELECT
+    workspace_id,
+    uploaded_by,
+    COALESCE(SUM(file_size), 0),
+    COUNT(*)
+FROM
+    todo_attachments
+GROUP BY
+    workspace_id,
+    uploaded_by;
+
+-- Moves the bytes of an attachment out of the usage of its old uploader and
+-- workspace and into the new. The usage row of a deleted workspace may
+-- already be gone, which leaves nothing to subtract from.
+CREATE OR REPLACE FUNCTION track_storage_usage()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE storage_usage
+        SET
+            bytes = bytes - COALESCE(OLD.file_size, 0),
+            attachments = attachments - 1
+        WHERE
+            workspace_id = OLD.workspace_id
+            AND user_id = OLD.uploaded_by;
+    END IF;
+
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        INSERT INTO
+            storage_usage (workspace_id, user_id, bytes, attachments)
+        VALUES
+            (NEW.workspace_id, NEW.uploaded_by, COALESCE(NEW.file_size, 0), 1)
+        ON CONFLICT (workspace_id, user_id) DO UPDATE
+        SET
+            bytes = storage_usage.bytes + EXCLUDED.bytes,
+            attachments = storage_usage.attachments + 1;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER track_storage_usage
+    AFTER INSERT OR DELETE OR UPDATE OF file_size, uploaded_by, workspace_id ON todo_attachments
+    FOR EACH ROW
+    EXECUTE FUNCTION track_storage_usage();
+
+-- Requests made in each workspace, shown next to its storage
+ALTER TABLE workspace_usage_daily ADD COLUMN api_requests BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,9 @@
+-- Reports are either a PDF summary of the workspace's todos, or a CSV of
+-- everyone with access to it, generated for admins of large workspaces
+ALTER TABLE reports ADD COLUMN kind TEXT NOT NULL DEFAULT 'summary';
+
+ALTER TABLE reports ADD CONSTRAINT valid_report_kind CHECK (kind IN ('summary', 'access'));
+
+-- Last activity of each member in the access report
+CREATE INDEX idx_event_outbox_workspace_actor ON event_outbox(workspace_id, actor_id, created_at DESC)
+    WHERE actor_id IS NOT NULL;
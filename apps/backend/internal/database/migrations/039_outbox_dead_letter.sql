@@ -0,0 +1,8 @@
+-- Events that keep failing are set aside once they run out of attempts, so
+-- they neither block the relay nor get retried forever
+ALTER TABLE event_outbox ADD COLUMN dead_at TIMESTAMPTZ;
+
+DROP INDEX idx_event_outbox_unpublished;
+
+CREATE INDEX idx_event_outbox_unpublished ON event_outbox(available_at, created_at)
+    WHERE published_at IS NULL AND dead_at IS NULL;
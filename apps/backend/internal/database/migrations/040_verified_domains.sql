@@ -0,0 +1,12 @@
+-- Domains a workspace proved it owns, by publishing its domain token in a
+-- DNS TXT record. Only they let SCIM provision existing accounts straight
+-- into the workspace.
+ALTER TABLE workspace_sso ADD COLUMN verified_domains TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE workspace_sso ADD COLUMN domain_token TEXT NOT NULL DEFAULT md5(gen_random_uuid()::TEXT);
+
+-- Existing accounts provisioned outside the verified domains are invited,
+-- and only become members once they accept
+ALTER TABLE scim_users ADD COLUMN pending BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- "Which invitations is this user yet to accept"
+CREATE INDEX idx_scim_users_pending_user_id ON scim_users(user_id) WHERE pending;
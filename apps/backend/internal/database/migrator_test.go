@@ -0,0 +1,73 @@
+package database_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mabhi256/tasker/internal/database"
+	testutil "github.com/mabhi256/tasker/internal/testing"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMigrationFile(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"001_setup.sql", "002_todo.sql", "010_job_failures.sql", "README.md"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("SELECT 1;"), 0o644))
+	}
+
+	path, err := database.NewMigrationFile(dir, "add_labels")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "011_add_labels.sql"), path)
+
+	body, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "---- create above / drop below ----")
+
+	path, err = database.NewMigrationFile(dir, "add_label_colors")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "012_add_label_colors.sql"), path)
+}
+
+func TestCheckDrift(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping drift tests in short mode")
+	}
+
+	testDB, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	migrator, err := database.NewMigrator(ctx, &logger, testDB.Config)
+	require.NoError(t, err)
+	defer migrator.Close(ctx)
+
+	// Migrating recorded the checksums of the embedded migrations
+	require.NoError(t, migrator.CheckDrift(ctx))
+
+	_, err = testDB.Pool.Exec(ctx, `UPDATE schema_version_checksums SET checksum = 'edited' WHERE version = 1`)
+	require.NoError(t, err)
+	_, err = testDB.Pool.Exec(ctx, `UPDATE schema_version SET version = $1`, migrator.LatestVersion()+1)
+	require.NoError(t, err)
+
+	var drift *database.DriftError
+	require.ErrorAs(t, migrator.CheckDrift(ctx), &drift)
+	require.Len(t, drift.Problems, 2)
+	assert.Contains(t, drift.Problems[0], "migrations are embedded")
+	assert.Contains(t, drift.Problems[1], "changed after it was applied")
+
+	assert.NoError(t, migrator.GuardDrift(ctx, "dev"))
+	assert.ErrorAs(t, migrator.GuardDrift(ctx, "prod"), &drift)
+
+	// Rolling back stops before a migration without a down section
+	_, err = testDB.Pool.Exec(ctx, `UPDATE schema_version SET version = $1`, migrator.LatestVersion())
+	require.NoError(t, err)
+	_, err = migrator.Rollback(ctx, 1)
+	assert.ErrorContains(t, err, "can't be reverted")
+	_, err = migrator.Rollback(ctx, 0)
+	assert.Error(t, err)
+}
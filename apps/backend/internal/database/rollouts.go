@@ -0,0 +1,11 @@
+package database
+
+import "github.com/mabhi256/tasker/internal/lib/rollout"
+
+// Rollouts are the expand/contract changes in flight. Add one alongside its
+// expand migration, and remove it once its contract migration has shipped.
+//
+// A contract migration is held back by Migrate until the rollout has no
+// pending rows, so it can ship in the same release as the expand and go out
+// with a later deploy once the backfill finishes.
+var Rollouts = rollout.MustRegistry()
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Scope is the workspace and user a request acts for. Row level security
+// policies on workspace data only let a query see and write rows of the
+// scope its context carries, so a repository method that forgets its
+// ownership filter returns nothing instead of another workspace's rows.
+//
+// Work without a scope, such as jobs, cron and admin requests, is not
+// constrained.
+type Scope struct {
+	WorkspaceID uuid.UUID
+	UserID      string
+}
+
+type scopeKey struct{}
+
+// WithScope constrains the queries run with the returned context to scope
+func WithScope(ctx context.Context, scope Scope) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+// ScopeFromContext returns the scope of ctx, and whether it has one
+func ScopeFromContext(ctx context.Context) (Scope, bool) {
+	scope, ok := ctx.Value(scopeKey{}).(Scope)
+	return scope, ok
+}
+
+// settings are the values the row level security policies read, see
+// current_workspace_scope and current_user_scope
+func (s Scope) settings() [2]string {
+	if s.WorkspaceID == uuid.Nil {
+		return [2]string{"", s.UserID}
+	}
+	return [2]string{s.WorkspaceID.String(), s.UserID}
+}
+
+// scoper applies the scope of the context a connection is acquired with to
+// the connection. Settings are only sent when they differ from the ones the
+// connection already has, which for most acquires they don't.
+type scoper struct {
+	applied sync.Map // *pgx.Conn -> [2]string
+}
+
+func (s *scoper) prepare(ctx context.Context, conn *pgx.Conn) (bool, error) {
+	scope, _ := ScopeFromContext(ctx)
+	settings := scope.settings()
+
+	if applied, ok := s.applied.Load(conn); ok && applied.([2]string) == settings {
+		return true, nil
+	}
+
+	_, err := conn.Exec(ctx, `SELECT set_config('app.workspace_id', $1, FALSE), set_config('app.user_id', $2, FALSE)`,
+		settings[0], settings[1])
+	if err != nil {
+		// The connection's settings are unknown now, so don't reuse it
+		s.applied.Delete(conn)
+		return false, fmt.Errorf("failed to apply query scope: %w", err)
+	}
+
+	s.applied.Store(conn, settings)
+	return true, nil
+}
+
+func (s *scoper) forget(conn *pgx.Conn) {
+	s.applied.Delete(conn)
+}
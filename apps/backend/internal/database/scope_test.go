@@ -0,0 +1,77 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/mabhi256/tasker/internal/database"
+	testutil "github.com/mabhi256/tasker/internal/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestScope checks that the row level security policies follow the scope of
+// the context a query runs with. The test database's role is a superuser,
+// which bypasses the policies, so queries switch to an ordinary role.
+func TestScope(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping scope tests in short mode")
+	}
+
+	testDB, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for _, stmt := range []string{
+		`INSERT INTO workspaces (name, owner_id, is_personal) VALUES ('A', 'user-a', TRUE), ('B', 'user-b', TRUE)`,
+		`INSERT INTO todos (workspace_id, user_id, title) SELECT id, owner_id, 'Todo' FROM workspaces`,
+		`CREATE ROLE scoped_app NOLOGIN`,
+		`GRANT SELECT, UPDATE ON todos TO scoped_app`,
+	} {
+		_, err := testDB.Pool.Exec(ctx, stmt)
+		require.NoError(t, err)
+	}
+
+	var workspaceA, workspaceB uuid.UUID
+	require.NoError(t, testDB.Pool.QueryRow(ctx, `SELECT id FROM workspaces WHERE name = 'A'`).Scan(&workspaceA))
+	require.NoError(t, testDB.Pool.QueryRow(ctx, `SELECT id FROM workspaces WHERE name = 'B'`).Scan(&workspaceB))
+
+	asApp := func(ctx context.Context, fn func(tx pgx.Tx) error) {
+		tx, err := testDB.Pool.Begin(ctx)
+		require.NoError(t, err)
+		defer func() { _ = tx.Rollback(ctx) }()
+
+		_, err = tx.Exec(ctx, `SET LOCAL ROLE scoped_app`)
+		require.NoError(t, err)
+		require.NoError(t, fn(tx))
+	}
+
+	countTodos := func(ctx context.Context) int {
+		var n int
+		asApp(ctx, func(tx pgx.Tx) error {
+			return tx.QueryRow(ctx, `SELECT COUNT(*) FROM todos`).Scan(&n)
+		})
+		return n
+	}
+
+	scopedA := database.WithScope(ctx, database.Scope{WorkspaceID: workspaceA, UserID: "user-a"})
+
+	assert.Equal(t, 2, countTodos(ctx))
+	assert.Equal(t, 1, countTodos(scopedA))
+	// The scope doesn't outlive the context, even on a reused connection
+	assert.Equal(t, 2, countTodos(ctx))
+
+	asApp(scopedA, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(scopedA, `UPDATE todos SET title = 'Changed' WHERE workspace_id = $1`, workspaceB)
+		assert.Zero(t, tag.RowsAffected())
+		return err
+	})
+
+	asApp(scopedA, func(tx pgx.Tx) error {
+		_, err := tx.Exec(scopedA, `UPDATE todos SET workspace_id = $1 WHERE workspace_id = $2`, workspaceB, workspaceA)
+		assert.Error(t, err, "moving a row out of the scope must be rejected")
+		return nil
+	})
+}
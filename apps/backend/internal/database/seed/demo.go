@@ -0,0 +1,209 @@
+package seed
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/model/todo"
+	"github.com/mabhi256/tasker/internal/model/workspace"
+)
+
+// Demo users, by the ids the auth provider would give them
+const (
+	DemoAlice = "seed_user_alice"
+	DemoBob   = "seed_user_bob"
+	DemoCarol = "seed_user_carol"
+	DemoDave  = "seed_user_dave"
+)
+
+var demoUsers = []string{DemoAlice, DemoBob, DemoCarol, DemoDave}
+
+// DemoTeamWorkspaceID is the workspace the demo users share
+var DemoTeamWorkspaceID = ID("workspace", "demo-team")
+
+const (
+	demoTeamTodos     = 150
+	demoPersonalTodos = 40
+)
+
+var (
+	demoTeamCategories = []Category{
+		{Name: "Engineering", Color: "#2563eb"},
+		{Name: "Design", Color: "#db2777"},
+		{Name: "Marketing", Color: "#f59e0b"},
+		{Name: "Operations", Color: "#16a34a"},
+	}
+	demoPersonalCategories = []Category{
+		{Name: "Work", Color: "#2563eb"},
+		{Name: "Home", Color: "#16a34a"},
+		{Name: "Errands", Color: "#f59e0b"},
+		{Name: "Learning", Color: "#7c3aed"},
+	}
+
+	demoVerbs = []string{"Draft", "Review", "Update", "Fix", "Plan", "Prepare", "Clean up", "Research", "Ship", "Schedule"}
+	demoNouns = []string{
+		"the onboarding flow", "quarterly budget", "release notes", "the landing page", "team offsite",
+		"customer interview notes", "the API docs", "invoice backlog", "hiring plan", "dependency upgrades",
+		"the design system", "support macros", "the roadmap", "weekly metrics", "travel bookings",
+	}
+	demoTags     = []string{"urgent", "blocked", "quick-win", "follow-up", "research"}
+	demoComments = []string{
+		"I can take this one.", "Blocked on the review, will pick it up tomorrow.", "Looks good to me.",
+		"Can we push this to next week?", "Added the notes to the doc.", "Done, please double check.",
+	}
+	demoStatuses   = []todo.Status{todo.StatusDraft, todo.StatusActive, todo.StatusActive, todo.StatusActive, todo.StatusCompleted, todo.StatusArchived}
+	demoPriorities = []todo.Priority{todo.PriorityLow, todo.PriorityMedium, todo.PriorityMedium, todo.PriorityHigh}
+)
+
+// Demo returns the seeders of the demo data set: four users with a
+// personal workspace each and a team workspace they share, with
+// categories, a few hundred todos and comment threads. members, such as
+// the dev auth user, join the team workspace as admins so they see the
+// data. Due dates are relative to the day the data is first seeded.
+func Demo(members ...string) []Seeder {
+	d := &demo{
+		rand:  rand.New(rand.NewPCG(1, 2)),
+		today: time.Now().UTC().Truncate(24 * time.Hour),
+	}
+
+	team := &Workspace{
+		ID:      DemoTeamWorkspaceID,
+		Name:    "Demo Team",
+		OwnerID: DemoAlice,
+		Members: map[string]workspace.Role{
+			DemoBob:   workspace.RoleAdmin,
+			DemoCarol: workspace.RoleMember,
+			DemoDave:  workspace.RoleViewer,
+		},
+	}
+	for _, member := range members {
+		team.Members[member] = workspace.RoleAdmin
+	}
+	d.workspaces = append(d.workspaces, team)
+	d.addWorkspaceData("demo-team", team.ID, demoUsers[:3], demoTeamCategories, demoTeamTodos)
+
+	for _, user := range demoUsers {
+		personal := &Workspace{
+			ID:       ID("workspace", "personal:"+user),
+			Name:     "Personal",
+			OwnerID:  user,
+			Personal: true,
+		}
+		d.workspaces = append(d.workspaces, personal)
+		d.addWorkspaceData("personal:"+user, personal.ID, []string{user}, demoPersonalCategories, demoPersonalTodos)
+	}
+
+	return []Seeder{
+		Fixtures("demo-workspaces", d.workspaces...),
+		Fixtures("demo-categories", d.categories...),
+		Fixtures("demo-todos", d.todos...),
+		Fixtures("demo-comments", d.comments...),
+	}
+}
+
+type demo struct {
+	rand  *rand.Rand
+	today time.Time
+
+	workspaces []Fixture
+	categories []Fixture
+	todos      []Fixture
+	comments   []Fixture
+}
+
+// addWorkspaceData adds count todos to the workspace, written by users,
+// every tenth with two subtasks and every fifth with a comment thread
+func (d *demo) addWorkspaceData(key string, workspaceID uuid.UUID, users []string, categories []Category,
+	count int,
+) {
+	categoryIDs := make([]uuid.UUID, len(categories))
+	for i, category := range categories {
+		categoryIDs[i] = ID("category", key+":"+category.Name)
+		d.categories = append(d.categories, &Category{
+			ID:          categoryIDs[i],
+			WorkspaceID: workspaceID,
+			UserID:      users[0],
+			Name:        category.Name,
+			Color:       category.Color,
+		})
+	}
+
+	for i := range count {
+		todoKey := fmt.Sprintf("%s:%d", key, i)
+		parent := d.todo(todoKey, workspaceID, users, categoryIDs)
+		d.todos = append(d.todos, parent)
+
+		if i%10 == 0 {
+			for j := range 2 {
+				subtask := d.todo(fmt.Sprintf("%s:%d", todoKey, j), workspaceID, users, categoryIDs)
+				subtask.ParentTodoID = &parent.ID
+				subtask.CategoryID = parent.CategoryID
+				d.todos = append(d.todos, subtask)
+			}
+		}
+
+		if i%5 == 0 {
+			d.addThread(todoKey, workspaceID, parent.ID, users)
+		}
+	}
+}
+
+func (d *demo) todo(key string, workspaceID uuid.UUID, users []string, categoryIDs []uuid.UUID) *Todo {
+	item := &Todo{
+		ID:          ID("todo", key),
+		WorkspaceID: workspaceID,
+		UserID:      pick(d.rand, users),
+		Title:       pick(d.rand, demoVerbs) + " " + pick(d.rand, demoNouns),
+		Status:      pick(d.rand, demoStatuses),
+		Priority:    pick(d.rand, demoPriorities),
+	}
+
+	// Most todos are due within a few weeks either side of today, so
+	// reminders, overdue todos and reports all have something to show
+	if d.rand.IntN(4) > 0 {
+		due := d.today.AddDate(0, 0, d.rand.IntN(42)-14).Add(17 * time.Hour)
+		item.DueDate = &due
+	}
+	if item.Status == todo.StatusCompleted {
+		completed := d.today.AddDate(0, 0, -d.rand.IntN(14)).Add(time.Duration(d.rand.IntN(10)+8) * time.Hour)
+		item.CompletedAt = &completed
+	}
+	if d.rand.IntN(3) > 0 {
+		categoryID := pick(d.rand, categoryIDs)
+		item.CategoryID = &categoryID
+	}
+	if d.rand.IntN(3) == 0 {
+		item.Metadata = &todo.Metadata{Tags: []string{pick(d.rand, demoTags)}}
+	}
+
+	return item
+}
+
+// addThread adds a comment on the todo and up to two replies to it
+func (d *demo) addThread(key string, workspaceID uuid.UUID, todoID uuid.UUID, users []string) {
+	root := &Comment{
+		ID:          ID("comment", key),
+		WorkspaceID: workspaceID,
+		TodoID:      todoID,
+		UserID:      pick(d.rand, users),
+		Content:     pick(d.rand, demoComments),
+	}
+	d.comments = append(d.comments, root)
+
+	for j := range d.rand.IntN(3) {
+		d.comments = append(d.comments, &Comment{
+			ID:              ID("comment", fmt.Sprintf("%s:%d", key, j)),
+			WorkspaceID:     workspaceID,
+			TodoID:          todoID,
+			UserID:          pick(d.rand, users),
+			Content:         pick(d.rand, demoComments),
+			ParentCommentID: &root.ID,
+		})
+	}
+}
+
+func pick[T any](r *rand.Rand, values []T) T {
+	return values[r.IntN(len(values))]
+}
@@ -0,0 +1,164 @@
+package seed
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/mabhi256/tasker/internal/model/todo"
+	"github.com/mabhi256/tasker/internal/model/workspace"
+)
+
+// Fixture is a row, with the rows it can't exist without, that a test or a
+// data set seeds. A fixture without an id is given a new one when seeded,
+// which is then set on it.
+type Fixture interface {
+	queue(batch *pgx.Batch)
+}
+
+// Fixtures seeds the fixtures in one round trip, in the order given
+func Fixtures(name string, fixtures ...Fixture) Seeder {
+	return &fixtureSeeder{name: name, fixtures: fixtures}
+}
+
+type fixtureSeeder struct {
+	name     string
+	fixtures []Fixture
+}
+
+func (s *fixtureSeeder) Name() string {
+	return s.name
+}
+
+func (s *fixtureSeeder) Seed(ctx context.Context, tx pgx.Tx) error {
+	batch := &pgx.Batch{}
+	for _, fixture := range s.fixtures {
+		fixture.queue(batch)
+	}
+	return tx.SendBatch(ctx, batch).Close()
+}
+
+func ensureID(id *uuid.UUID) uuid.UUID {
+	if *id == uuid.Nil {
+		*id = uuid.New()
+	}
+	return *id
+}
+
+// Workspace is a workspace with its owner and members
+type Workspace struct {
+	ID       uuid.UUID
+	Name     string
+	OwnerID  string
+	Personal bool
+	// Members join the workspace besides its owner
+	Members map[string]workspace.Role
+}
+
+func (w *Workspace) queue(batch *pgx.Batch) {
+	id := ensureID(&w.ID)
+
+	batch.Queue(`
+		INSERT INTO workspaces (id, name, owner_id, is_personal)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING
+	`, id, w.Name, w.OwnerID, w.Personal)
+
+	members := map[string]workspace.Role{w.OwnerID: workspace.RoleOwner}
+	for userID, role := range w.Members {
+		if userID != w.OwnerID {
+			members[userID] = role
+		}
+	}
+	for userID, role := range members {
+		batch.Queue(`
+			INSERT INTO workspace_members (workspace_id, user_id, role)
+			VALUES ($1, $2, $3)
+			ON CONFLICT DO NOTHING
+		`, id, userID, role)
+	}
+}
+
+type Category struct {
+	ID          uuid.UUID
+	WorkspaceID uuid.UUID
+	UserID      string
+	Name        string
+	Color       string
+	Description *string
+}
+
+func (c *Category) queue(batch *pgx.Batch) {
+	color := c.Color
+	if color == "" {
+		color = "#6b7280"
+	}
+
+	batch.Queue(`
+		INSERT INTO todo_categories (id, workspace_id, user_id, name, color, description)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT DO NOTHING
+	`, ensureID(&c.ID), c.WorkspaceID, c.UserID, c.Name, color, c.Description)
+}
+
+// Todo is a todo. Status and priority default to draft and medium, and a
+// completed todo is completed when it's seeded unless CompletedAt says
+// otherwise.
+type Todo struct {
+	ID           uuid.UUID
+	WorkspaceID  uuid.UUID
+	UserID       string
+	Title        string
+	Description  *string
+	Status       todo.Status
+	Priority     todo.Priority
+	DueDate      *time.Time
+	CompletedAt  *time.Time
+	ParentTodoID *uuid.UUID
+	CategoryID   *uuid.UUID
+	Metadata     *todo.Metadata
+}
+
+func (t *Todo) queue(batch *pgx.Batch) {
+	status := t.Status
+	if status == "" {
+		status = todo.StatusDraft
+	}
+	priority := t.Priority
+	if priority == "" {
+		priority = todo.PriorityMedium
+	}
+	completedAt := t.CompletedAt
+	if status == todo.StatusCompleted && completedAt == nil {
+		now := time.Now()
+		completedAt = &now
+	}
+
+	batch.Queue(`
+		INSERT INTO todos (
+			id, workspace_id, user_id, title, description, status, priority,
+			due_date, completed_at, parent_todo_id, category_id, metadata
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT DO NOTHING
+	`, ensureID(&t.ID), t.WorkspaceID, t.UserID, t.Title, t.Description, status, priority,
+		t.DueDate, completedAt, t.ParentTodoID, t.CategoryID, t.Metadata)
+}
+
+type Comment struct {
+	ID              uuid.UUID
+	WorkspaceID     uuid.UUID
+	TodoID          uuid.UUID
+	UserID          string
+	Content         string
+	ParentCommentID *uuid.UUID
+}
+
+func (c *Comment) queue(batch *pgx.Batch) {
+	batch.Queue(`
+		INSERT INTO todo_comments (id, workspace_id, todo_id, user_id, content, parent_comment_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT DO NOTHING
+	`, ensureID(&c.ID), c.WorkspaceID, c.TodoID, c.UserID, c.Content, c.ParentCommentID)
+}
@@ -0,0 +1,89 @@
+package seed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/mabhi256/tasker/internal/model/workspace"
+)
+
+// SandboxWorkspaceID is the sandbox workspace unless the config designates
+// another
+var SandboxWorkspaceID = ID("workspace", "sandbox")
+
+const sandboxTodos = 60
+
+// Sandbox returns the seeders of the sandbox data set: a team workspace
+// owned by ownerID, shared with two demo users and members, which join as
+// admins, with categories, todos and comment threads. Every reset seeds the
+// same data, with due dates relative to the day of the reset.
+func Sandbox(workspaceID uuid.UUID, ownerID string, members ...string) []Seeder {
+	d := &demo{
+		rand:  rand.New(rand.NewPCG(3, 4)),
+		today: time.Now().UTC().Truncate(24 * time.Hour),
+	}
+
+	sandbox := &Workspace{
+		ID:      workspaceID,
+		Name:    "Sandbox",
+		OwnerID: ownerID,
+		Members: map[string]workspace.Role{
+			DemoBob:   workspace.RoleMember,
+			DemoCarol: workspace.RoleMember,
+		},
+	}
+	for _, member := range members {
+		sandbox.Members[member] = workspace.RoleAdmin
+	}
+	d.workspaces = append(d.workspaces, sandbox)
+	d.addWorkspaceData("sandbox:"+workspaceID.String(), workspaceID, []string{ownerID, DemoBob, DemoCarol},
+		demoTeamCategories, sandboxTodos)
+
+	return []Seeder{
+		Fixtures("sandbox-workspace", d.workspaces...),
+		Fixtures("sandbox-categories", d.categories...),
+		Fixtures("sandbox-todos", d.todos...),
+		Fixtures("sandbox-comments", d.comments...),
+	}
+}
+
+// ClearWorkspace deletes the workspace and, through its foreign keys,
+// everything in it, so the seeders after it start from nothing. It refuses
+// personal workspaces, which hold a user's own data.
+func ClearWorkspace(workspaceID uuid.UUID) Seeder {
+	return &clearSeeder{workspaceID: workspaceID}
+}
+
+type clearSeeder struct {
+	workspaceID uuid.UUID
+}
+
+func (s *clearSeeder) Name() string {
+	return "clear-workspace"
+}
+
+func (s *clearSeeder) Seed(ctx context.Context, tx pgx.Tx) error {
+	var personal bool
+	err := tx.QueryRow(ctx, `
+		DELETE FROM workspaces
+		WHERE id = $1
+		RETURNING is_personal
+	`, s.workspaceID).Scan(&personal)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete workspace: %w", err)
+	}
+
+	// Run rolls the delete back
+	if personal {
+		return fmt.Errorf("refusing to clear personal workspace %s", s.workspaceID)
+	}
+	return nil
+}
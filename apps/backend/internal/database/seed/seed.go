@@ -0,0 +1,59 @@
+// Package seed fills a database with data for local development and tests.
+// Seeders are idempotent: every row they insert has an id derived from what
+// it represents, so running them again only inserts what is missing and
+// leaves changed rows as they are.
+package seed
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/mabhi256/tasker/internal/database"
+	"github.com/rs/zerolog"
+)
+
+// namespace derives seeded ids, keeping them apart from ids derived by
+// anything else
+var namespace = uuid.MustParse("6f1d0c4e-4b1a-4c83-9a55-0c3e8e1c7a2d")
+
+// Seeder inserts one kind of data. Seeders run in order, so a seeder can
+// refer to rows of the ones before it by their ids.
+type Seeder interface {
+	Name() string
+	Seed(ctx context.Context, tx pgx.Tx) error
+}
+
+// ID returns the id of the seeded row of kind identified by key
+func ID(kind string, key string) uuid.UUID {
+	return uuid.NewSHA1(namespace, []byte(kind+":"+key))
+}
+
+// Run runs the seeders in one transaction, so a failed seeder leaves the
+// database as it was
+func Run(ctx context.Context, db database.Querier, logger *zerolog.Logger, seeders ...Seeder) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin seed transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, seeder := range seeders {
+		start := time.Now()
+		if err := seeder.Seed(ctx, tx); err != nil {
+			return fmt.Errorf("seeder %s failed: %w", seeder.Name(), err)
+		}
+
+		logger.Info().
+			Str("seeder", seeder.Name()).
+			Dur("duration", time.Since(start)).
+			Msg("seeded")
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit seed transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package seed_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mabhi256/tasker/internal/database/seed"
+	"github.com/mabhi256/tasker/internal/model/todo"
+	"github.com/mabhi256/tasker/internal/model/workspace"
+	testutil "github.com/mabhi256/tasker/internal/testing"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDemo(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping seed tests in short mode")
+	}
+
+	testDB, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	count := func(table string) int {
+		var n int
+		require.NoError(t, testDB.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM `+table).Scan(&n))
+		return n
+	}
+	tables := []string{"workspaces", "workspace_members", "todo_categories", "todos", "todo_comments"}
+
+	testutil.Seed(t, testDB, seed.Demo("user_dev")...)
+	counts := make(map[string]int, len(tables))
+	for _, table := range tables {
+		counts[table] = count(table)
+	}
+	assert.Equal(t, 5, counts["workspaces"])
+	assert.Greater(t, counts["todos"], 300)
+
+	var role workspace.Role
+	err := testDB.Pool.QueryRow(ctx, `
+		SELECT role FROM workspace_members WHERE workspace_id = $1 AND user_id = 'user_dev'
+	`, seed.DemoTeamWorkspaceID).Scan(&role)
+	require.NoError(t, err)
+	assert.Equal(t, workspace.RoleAdmin, role)
+
+	// Seeding again only inserts what is missing
+	_, err = testDB.Pool.Exec(ctx, `DELETE FROM todo_comments`)
+	require.NoError(t, err)
+	testutil.Seed(t, testDB, seed.Demo("user_dev")...)
+	for _, table := range tables {
+		assert.Equal(t, counts[table], count(table), table)
+	}
+}
+
+func TestFixtures(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping seed tests in short mode")
+	}
+
+	testDB, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+
+	workspaceID := testutil.SeedWorkspace(t, testDB, "user-1", map[string]workspace.Role{"user-2": workspace.RoleViewer})
+
+	category := &seed.Category{WorkspaceID: workspaceID, UserID: "user-1", Name: "Work"}
+	parent := &seed.Todo{WorkspaceID: workspaceID, UserID: "user-1", Title: "Parent", Status: todo.StatusCompleted}
+	testutil.SeedFixtures(t, testDB, category, parent)
+	testutil.AssertValidUUID(t, category.ID)
+	testutil.AssertValidUUID(t, parent.ID)
+
+	// Fixtures can refer to the ones seeded before them
+	child := &seed.Todo{WorkspaceID: workspaceID, UserID: "user-2", Title: "Child", ParentTodoID: &parent.ID, CategoryID: &category.ID}
+	testutil.SeedFixtures(t, testDB, child)
+
+	ctx := context.Background()
+	var (
+		status      todo.Status
+		completed   bool
+		childParent string
+	)
+	require.NoError(t, testDB.Pool.QueryRow(ctx, `
+		SELECT status, completed_at IS NOT NULL FROM todos WHERE id = $1
+	`, parent.ID).Scan(&status, &completed))
+	assert.Equal(t, todo.StatusCompleted, status)
+	assert.True(t, completed)
+
+	require.NoError(t, testDB.Pool.QueryRow(ctx, `
+		SELECT parent_todo_id::TEXT FROM todos WHERE id = $1
+	`, child.ID).Scan(&childParent))
+	assert.Equal(t, parent.ID.String(), childParent)
+}
+
+func TestSandbox(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping seed tests in short mode")
+	}
+
+	testDB, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	count := func(table string) int {
+		var n int
+		require.NoError(t, testDB.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM `+table+` WHERE workspace_id = $1`,
+			seed.SandboxWorkspaceID).Scan(&n))
+		return n
+	}
+	reset := func() []seed.Seeder {
+		return append([]seed.Seeder{seed.ClearWorkspace(seed.SandboxWorkspaceID)},
+			seed.Sandbox(seed.SandboxWorkspaceID, seed.DemoAlice, "partner")...)
+	}
+
+	testutil.Seed(t, testDB, reset()...)
+	todos := count("todos")
+	assert.Greater(t, todos, 0)
+
+	// Whatever is written to the sandbox is gone after a reset
+	_, err := testDB.Pool.Exec(ctx, `DELETE FROM todos WHERE workspace_id = $1 AND parent_todo_id IS NULL`, seed.SandboxWorkspaceID)
+	require.NoError(t, err)
+	_, err = testDB.Pool.Exec(ctx, `
+		INSERT INTO workspace_members (workspace_id, user_id, role) VALUES ($1, 'visitor', 'member')
+	`, seed.SandboxWorkspaceID)
+	require.NoError(t, err)
+
+	testutil.Seed(t, testDB, reset()...)
+	assert.Equal(t, todos, count("todos"))
+	assert.Equal(t, 4, count("workspace_members"))
+
+	// Personal workspaces are never cleared
+	personal := &seed.Workspace{Name: "Personal", OwnerID: "user-1", Personal: true}
+	testutil.SeedFixtures(t, testDB, personal)
+	personalID := personal.ID
+	logger := zerolog.Nop()
+	require.Error(t, seed.Run(ctx, testDB.Pool, &logger, seed.ClearWorkspace(personalID)))
+
+	var exists bool
+	require.NoError(t, testDB.Pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM workspaces WHERE id = $1)`, personalID).Scan(&exists))
+	assert.True(t, exists)
+}
@@ -0,0 +1,134 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+// explainTimeout bounds the EXPLAIN run for a slow query, which happens
+// outside of the request that ran it
+const explainTimeout = 5 * time.Second
+
+var (
+	stringLiteral = regexp.MustCompile(`'(?:[^']|'')*'`)
+	// Positional parameters are matched too, so they can be kept
+	numericLiteral = regexp.MustCompile(`\$?\b\d+(?:\.\d+)?\b`)
+	whitespace     = regexp.MustCompile(`\s+`)
+	// explainable are the statements EXPLAIN accepts. Without ANALYZE it
+	// plans them without running them, so writes are safe to explain.
+	explainable = regexp.MustCompile(`(?i)^(SELECT|INSERT|UPDATE|DELETE|WITH|VALUES)\b`)
+)
+
+type slowQueryKey struct{}
+
+// explainingKey marks the EXPLAIN queries, which aren't traced themselves
+type explainingKey struct{}
+
+// tracedQuery is kept from the start of a query for when it ends
+type tracedQuery struct {
+	start time.Time
+	sql   string
+	args  []any
+}
+
+// slowQueryTracer logs queries that take longer than the threshold, with
+// their SQL normalized so a statement always logs the same way. When
+// explaining, the plan of each distinct slow statement is also logged, once.
+type slowQueryTracer struct {
+	threshold time.Duration
+	logger    *zerolog.Logger
+	explain   bool
+	// pool runs the EXPLAIN statements once the primary is connected
+	pool      atomic.Pointer[pgxpool.Pool]
+	explained sync.Map
+}
+
+func newSlowQueryTracer(threshold time.Duration, logger *zerolog.Logger, explain bool) *slowQueryTracer {
+	return &slowQueryTracer{
+		threshold: threshold,
+		logger:    logger,
+		explain:   explain,
+	}
+}
+
+func (t *slowQueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if ctx.Value(explainingKey{}) != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, slowQueryKey{}, &tracedQuery{start: time.Now(), sql: data.SQL, args: data.Args})
+}
+
+func (t *slowQueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	query, ok := ctx.Value(slowQueryKey{}).(*tracedQuery)
+	if !ok {
+		return
+	}
+	duration := time.Since(query.start)
+	if duration < t.threshold {
+		return
+	}
+
+	normalized := NormalizeSQL(query.sql)
+	t.logger.Warn().
+		Err(data.Err).
+		Dur("duration", duration).
+		Dur("threshold", t.threshold).
+		Str("sql", normalized).
+		Msg("slow query")
+
+	if t.explain && explainable.MatchString(normalized) {
+		if _, seen := t.explained.LoadOrStore(normalized, true); !seen {
+			go t.explainQuery(context.WithoutCancel(ctx), normalized, query)
+		}
+	}
+}
+
+// explainQuery logs the plan of a slow query, planned with its original
+// arguments and the scope of its context
+func (t *slowQueryTracer) explainQuery(ctx context.Context, normalized string, query *tracedQuery) {
+	pool := t.pool.Load()
+	if pool == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithValue(ctx, explainingKey{}, true), explainTimeout)
+	defer cancel()
+
+	rows, err := pool.Query(ctx, "EXPLAIN (ANALYZE off) "+query.sql, query.args...)
+	if err != nil {
+		t.logger.Debug().Err(err).Str("sql", normalized).Msg("failed to explain slow query")
+		return
+	}
+	lines, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		t.logger.Debug().Err(err).Str("sql", normalized).Msg("failed to explain slow query")
+		return
+	}
+
+	t.logger.Warn().
+		Str("sql", normalized).
+		Str("plan", strings.Join(lines, "\n")).
+		Msg("slow query plan")
+}
+
+// NormalizeSQL collapses whitespace and replaces literals with ?, so a
+// statement reads the same whatever values it was written with. Parameters
+// are kept as they are.
+func NormalizeSQL(sql string) string {
+	sql = stringLiteral.ReplaceAllString(sql, "?")
+	sql = numericLiteral.ReplaceAllStringFunc(sql, func(match string) string {
+		if strings.HasPrefix(match, "$") {
+			return match
+		}
+		return "?"
+	})
+	return strings.TrimSpace(whitespace.ReplaceAllString(sql, " "))
+}
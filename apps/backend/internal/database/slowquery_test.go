@@ -0,0 +1,38 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/mabhi256/tasker/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeSQL(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{
+			name: "collapses whitespace",
+			sql:  "\n\t\tSELECT\n\t\t\t*\n\t\tFROM\n\t\t\ttodos\n\t",
+			want: "SELECT * FROM todos",
+		},
+		{
+			name: "replaces literals",
+			sql:  "SELECT * FROM todos WHERE title = 'it''s' AND priority > 2 AND score < 0.5",
+			want: "SELECT * FROM todos WHERE title = ? AND priority > ? AND score < ?",
+		},
+		{
+			name: "keeps parameters and identifiers",
+			sql:  "SELECT t1.id FROM todos t1 WHERE t1.workspace_id = $1 AND t1.title = @title LIMIT $2",
+			want: "SELECT t1.id FROM todos t1 WHERE t1.workspace_id = $1 AND t1.title = @title LIMIT $2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, database.NormalizeSQL(tt.sql))
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package database
+
+import (
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mabhi256/tasker/internal/logging"
+	"github.com/rs/zerolog"
+)
+
+// statsReporter logs the stats of the database's pools and records them as
+// New Relic custom metrics under Custom/Database/<pool>/. Counters are
+// reported as their change since the previous report, so each report covers
+// one interval. Reports are logged at debug level, unless queries had to
+// wait for a connection, which means the pool is too small for the load.
+type statsReporter struct {
+	db            *Database
+	logger        *zerolog.Logger
+	loggerService *logging.LoggerService
+	previous      map[string]*pgxpool.Stat
+
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func newStatsReporter(db *Database, logger *zerolog.Logger, loggerService *logging.LoggerService) *statsReporter {
+	return &statsReporter{
+		db:            db,
+		logger:        logger,
+		loggerService: loggerService,
+		previous:      make(map[string]*pgxpool.Stat),
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+}
+
+func (r *statsReporter) run(interval time.Duration) {
+	defer close(r.stopped)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+			r.report("primary", r.db.Pool)
+			if r.db.Replica != nil {
+				r.report("replica", r.db.Replica)
+			}
+		}
+	}
+}
+
+func (r *statsReporter) stop() {
+	close(r.done)
+	<-r.stopped
+}
+
+func (r *statsReporter) report(name string, pool *pgxpool.Pool) {
+	stat := pool.Stat()
+
+	acquires := stat.AcquireCount()
+	acquireTime := stat.AcquireDuration()
+	waits := stat.EmptyAcquireCount()
+	waitTime := stat.EmptyAcquireWaitTime()
+	if prev := r.previous[name]; prev != nil {
+		acquires -= prev.AcquireCount()
+		acquireTime -= prev.AcquireDuration()
+		waits -= prev.EmptyAcquireCount()
+		waitTime -= prev.EmptyAcquireWaitTime()
+	}
+	r.previous[name] = stat
+
+	var avgAcquireTime time.Duration
+	if acquires > 0 {
+		avgAcquireTime = acquireTime / time.Duration(acquires)
+	}
+
+	event := r.logger.Debug()
+	if waits > 0 {
+		event = r.logger.Warn()
+	}
+	event.
+		Str("pool", name).
+		Int32("max_conns", stat.MaxConns()).
+		Int32("total_conns", stat.TotalConns()).
+		Int32("acquired_conns", stat.AcquiredConns()).
+		Int32("idle_conns", stat.IdleConns()).
+		Int64("acquires", acquires).
+		Int64("waits", waits).
+		Dur("wait_time", waitTime).
+		Dur("avg_acquire_time", avgAcquireTime).
+		Msg("database pool stats")
+
+	if r.loggerService == nil || r.loggerService.GetApplication() == nil {
+		return
+	}
+
+	app := r.loggerService.GetApplication()
+	prefix := "Custom/Database/" + name + "/"
+	app.RecordCustomMetric(prefix+"TotalConns", float64(stat.TotalConns()))
+	app.RecordCustomMetric(prefix+"AcquiredConns", float64(stat.AcquiredConns()))
+	app.RecordCustomMetric(prefix+"IdleConns", float64(stat.IdleConns()))
+	app.RecordCustomMetric(prefix+"Acquires", float64(acquires))
+	app.RecordCustomMetric(prefix+"Waits", float64(waits))
+	app.RecordCustomMetric(prefix+"WaitTimeMs", float64(waitTime.Milliseconds()))
+	app.RecordCustomMetric(prefix+"AvgAcquireTimeMs", float64(avgAcquireTime.Milliseconds()))
+}
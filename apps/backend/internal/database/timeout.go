@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type queryTimeoutKey struct{}
+
+// WithQueryTimeout replaces the timeout of each statement run with the
+// returned context, which otherwise depends on whether it reads or writes.
+// Zero lifts the timeout, for work bounded by a timeout of its own whose
+// statements may take longer than a request's.
+func WithQueryTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, queryTimeoutKey{}, timeout)
+}
+
+// withTimeout bounds each statement q runs with ctx by timeout, unless ctx
+// replaces it
+func withTimeout(ctx context.Context, q Querier, timeout time.Duration) Querier {
+	if override, ok := ctx.Value(queryTimeoutKey{}).(time.Duration); ok {
+		timeout = override
+	}
+	if timeout <= 0 {
+		return q
+	}
+	return &timeoutQuerier{q: q, timeout: timeout}
+}
+
+// timeoutQuerier runs each statement with a deadline. A query's deadline
+// lasts until its rows are read or closed. Transactions aren't bounded, as
+// their statements are, and Begin only starts one.
+type timeoutQuerier struct {
+	q       Querier
+	timeout time.Duration
+}
+
+func (t *timeoutQuerier) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	return t.q.Exec(ctx, sql, args...)
+}
+
+func (t *timeoutQuerier) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	rows, err := t.q.Query(ctx, sql, args...)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &timeoutRows{Rows: rows, cancel: cancel}, nil
+}
+
+func (t *timeoutQuerier) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	return &timeoutRow{row: t.q.QueryRow(ctx, sql, args...), cancel: cancel}
+}
+
+func (t *timeoutQuerier) Begin(ctx context.Context) (pgx.Tx, error) {
+	return t.q.Begin(ctx)
+}
+
+type timeoutRows struct {
+	pgx.Rows
+	cancel context.CancelFunc
+}
+
+func (r *timeoutRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.cancel()
+	return false
+}
+
+func (r *timeoutRows) Close() {
+	r.Rows.Close()
+	r.cancel()
+}
+
+type timeoutRow struct {
+	row    pgx.Row
+	cancel context.CancelFunc
+}
+
+func (r *timeoutRow) Scan(dest ...any) error {
+	defer r.cancel()
+	return r.row.Scan(dest...)
+}
@@ -0,0 +1,61 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mabhi256/tasker/internal/database"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/sqlerr"
+	testutil "github.com/mabhi256/tasker/internal/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryTimeout(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping query timeout tests in short mode")
+	}
+
+	testDB, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+
+	db := &database.Database{
+		Pool:         testDB.Pool,
+		ReadTimeout:  50 * time.Millisecond,
+		WriteTimeout: time.Second,
+	}
+
+	ctx := context.Background()
+	sleep := func(q database.Querier, ctx context.Context) error {
+		_, err := q.Exec(ctx, `SELECT pg_sleep(0.2)`)
+		return err
+	}
+
+	// Reads time out, and are reported as a 504
+	err := sleep(db.Reader(ctx), ctx)
+	require.Error(t, err)
+	var httpErr *errs.HTTPError
+	require.True(t, errors.As(sqlerr.HandleError(err), &httpErr))
+	assert.Equal(t, http.StatusGatewayTimeout, httpErr.Status)
+
+	// Writes have a longer timeout
+	assert.NoError(t, sleep(db.Conn(ctx), ctx))
+
+	// Lifted for work bounded otherwise
+	lifted := database.WithQueryTimeout(ctx, 0)
+	assert.NoError(t, sleep(db.Reader(lifted), lifted))
+
+	// Rows stay readable until they are closed
+	rows, err := db.Reader(ctx).Query(ctx, `SELECT generate_series(1, 3)`)
+	require.NoError(t, err)
+	n := 0
+	for rows.Next() {
+		n++
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, 3, n)
+}
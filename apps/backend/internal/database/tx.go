@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Querier runs queries on the pool, or on the transaction of a unit of work.
+// Begin on a transaction starts a savepoint, so repository methods that need
+// their own transaction nest inside the unit of work.
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+type txKey struct{}
+
+// txFromContext returns the transaction of the unit of work ctx is part of
+func txFromContext(ctx context.Context) (pgx.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(pgx.Tx)
+	return tx, ok
+}
+
+// InTx reports whether ctx is part of a unit of work
+func InTx(ctx context.Context) bool {
+	_, ok := txFromContext(ctx)
+	return ok
+}
+
+// Conn returns what queries run with ctx should use: the transaction of its
+// unit of work, or the pool. Each statement is bounded by WriteTimeout, and
+// statements on the pool are retried while the database fails over.
+func (db *Database) Conn(ctx context.Context) Querier {
+	if tx, ok := txFromContext(ctx); ok {
+		return withTimeout(ctx, tx, db.WriteTimeout)
+	}
+	return db.withFailoverRetries(withTimeout(ctx, db.Pool, db.WriteTimeout), db.Pool)
+}
+
+// UnitOfWork runs fn in a transaction that every query run with the context
+// it is passed shares. The transaction commits when fn returns nil and rolls
+// back when it returns an error. Within another unit of work it is a
+// savepoint of the outer transaction.
+//
+// A transaction runs one query at a time, so fn must not query concurrently.
+func (db *Database) UnitOfWork(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx, err := db.Conn(ctx).Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin unit of work: %w", err)
+	}
+	defer func() {
+		// Does nothing once the transaction is committed
+		_ = tx.Rollback(context.WithoutCancel(ctx))
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit unit of work: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mabhi256/tasker/internal/database"
+	testutil "github.com/mabhi256/tasker/internal/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitOfWork(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping unit of work tests in short mode")
+	}
+
+	testDB, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+
+	db := &database.Database{Pool: testDB.Pool}
+
+	ctx := context.Background()
+	_, err := testDB.Pool.Exec(ctx, `CREATE TABLE uow_items (name TEXT PRIMARY KEY)`)
+	require.NoError(t, err)
+
+	insert := func(ctx context.Context, name string) error {
+		_, err := db.Conn(ctx).Exec(ctx, `INSERT INTO uow_items (name) VALUES ($1)`, name)
+		return err
+	}
+	count := func() int {
+		var n int
+		require.NoError(t, testDB.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM uow_items`).Scan(&n))
+		return n
+	}
+
+	// Committed together
+	err = db.UnitOfWork(ctx, func(ctx context.Context) error {
+		assert.True(t, database.InTx(ctx))
+		require.NoError(t, insert(ctx, "a"))
+		require.NoError(t, insert(ctx, "b"))
+
+		// Reads within the unit of work see its writes
+		var n int
+		require.NoError(t, db.Reader(ctx).QueryRow(ctx, `SELECT COUNT(*) FROM uow_items`).Scan(&n))
+		assert.Equal(t, 2, n)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, count())
+
+	// Rolled back together
+	failure := errors.New("failed")
+	err = db.UnitOfWork(ctx, func(ctx context.Context) error {
+		require.NoError(t, insert(ctx, "c"))
+		return failure
+	})
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, 2, count())
+
+	// A nested unit of work is a savepoint, rolled back without the outer one
+	err = db.UnitOfWork(ctx, func(ctx context.Context) error {
+		require.NoError(t, insert(ctx, "d"))
+		nestedErr := db.UnitOfWork(ctx, func(ctx context.Context) error {
+			require.NoError(t, insert(ctx, "e"))
+			return failure
+		})
+		assert.ErrorIs(t, nestedErr, failure)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, count())
+}
@@ -34,6 +34,11 @@ func NewForbiddenError(message string, override bool) *HTTPError {
 	return newSimpleError(http.StatusForbidden, message, override)
 }
 
+// The user may not make the request until they take the action, e.g. signing in again
+func NewForbiddenActionError(message string, override bool, code *string, action *Action) *HTTPError {
+	return newError(http.StatusForbidden, message, override, code, nil, action)
+}
+
 // Malformed request - bad JSON, wrong types - {"name": "John", "age": }
 func NewBadRequestError(message string, override bool, code *string, errors []BindError, action *Action) *HTTPError {
 	return newError(http.StatusBadRequest, message, override, code, errors, action)
@@ -63,6 +68,32 @@ func NewUnprocessableError(message string, override bool, code *string, errors [
 	return newError(http.StatusUnprocessableEntity, message, false, code, errors, action)
 }
 
+// The client is too old to be served and has to be upgraded first, e.g. by
+// following the action to its store page
+func NewUpgradeRequiredError(message string, override bool, action *Action) *HTTPError {
+	return newError(http.StatusUpgradeRequired, message, override, nil, nil, action)
+}
+
+// A dependency is briefly unavailable, e.g. the database is failing over.
+// Retrying shortly should succeed.
+func NewServiceUnavailableError(message string, override bool, code *string) *HTTPError {
+	return newError(http.StatusServiceUnavailable, message, override, code, nil, nil)
+}
+
+// The request ran out of time, e.g. a query hit its timeout. Retrying later may succeed.
+func NewGatewayTimeoutError(message string, override bool, code *string) *HTTPError {
+	return newError(http.StatusGatewayTimeout, message, override, code, nil, nil)
+}
+
+// StatusClientClosedRequest answers requests the client gave up on. The
+// response is never read, but logs and metrics record it.
+const StatusClientClosedRequest = 499
+
+func NewClientClosedRequestError() *HTTPError {
+	code := "CLIENT_CLOSED_REQUEST"
+	return newError(StatusClientClosedRequest, "The request was canceled", false, &code, nil, nil)
+}
+
 func NewInternalServerError() *HTTPError {
 	text := http.StatusText(http.StatusInternalServerError)
 	return newSimpleError(http.StatusInternalServerError, text, false)
@@ -30,6 +30,11 @@ type HTTPError struct {
 	Override bool        `json:"override"`
 	Errors   []BindError `json:"errors"`
 	Action   *Action     `json:"action"` // action to be taken
+	// RequestID identifies the request in the logs, for support
+	RequestID string `json:"requestId,omitempty"`
+	// Detail is the underlying error of a server error, only sent outside
+	// production
+	Detail string `json:"detail,omitempty"`
 }
 
 func (e *HTTPError) Error() string {
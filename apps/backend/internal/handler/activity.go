@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/activity"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/mabhi256/tasker/internal/service"
+)
+
+type ActivityHandler struct {
+	Handler
+	activityService *service.ActivityService
+}
+
+func NewActivityHandler(s *server.Server, activityService *service.ActivityService) *ActivityHandler {
+	return &ActivityHandler{
+		Handler:         NewHandler(s),
+		activityService: activityService,
+	}
+}
+
+func (h *ActivityHandler) GetFeed(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, query *activity.GetFeedQuery) (*activity.Feed, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.activityService.GetFeed(c, workspaceID, query)
+		},
+		http.StatusOK,
+		&activity.GetFeedQuery{},
+	)(c)
+}
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model"
+	"github.com/mabhi256/tasker/internal/model/admin"
+	"github.com/mabhi256/tasker/internal/model/todo"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/mabhi256/tasker/internal/service"
+)
+
+type AdminHandler struct {
+	Handler
+	adminService *service.AdminService
+}
+
+func NewAdminHandler(s *server.Server, adminService *service.AdminService) *AdminHandler {
+	return &AdminHandler{
+		Handler:      NewHandler(s),
+		adminService: adminService,
+	}
+}
+
+func (h *AdminHandler) GetUsers(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, query *admin.GetUsersQuery) (*model.PaginatedResponse[admin.UserSummary], error) {
+			return h.adminService.GetUsers(c, query)
+		},
+		http.StatusOK,
+		&admin.GetUsersQuery{},
+	)(c)
+}
+
+func (h *AdminHandler) GetUserTodos(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, query *admin.GetUserTodosQuery) (*model.PaginatedResponse[todo.PopulatedTodo], error) {
+			return h.adminService.GetUserTodos(c, query)
+		},
+		http.StatusOK,
+		&admin.GetUserTodosQuery{},
+	)(c)
+}
+
+func (h *AdminHandler) StartImpersonation(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *admin.StartImpersonationPayload) (*admin.ImpersonationSession, error) {
+			adminID := middleware.GetUserID(c)
+			return h.adminService.StartImpersonation(c, adminID, payload)
+		},
+		http.StatusCreated,
+		&admin.StartImpersonationPayload{},
+	)(c)
+}
+
+func (h *AdminHandler) StopImpersonation(c echo.Context) error {
+	return HandleNoContent(
+		h.Handler,
+		func(c echo.Context, payload *admin.StopImpersonationPayload) error {
+			adminID := middleware.GetUserID(c)
+			return h.adminService.StopImpersonation(c, adminID, payload.Token)
+		},
+		http.StatusNoContent,
+		&admin.StopImpersonationPayload{},
+	)(c)
+}
+
+func (h *AdminHandler) GetSystemStats(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *admin.GetSystemStatsPayload) (*admin.SystemStats, error) {
+			return h.adminService.GetSystemStats(c)
+		},
+		http.StatusOK,
+		&admin.GetSystemStatsPayload{},
+	)(c)
+}
+
+func (h *AdminHandler) GetAttachmentIntegritySummary(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *admin.GetAttachmentIntegrityPayload) (*admin.AttachmentIntegritySummary, error) {
+			return h.adminService.GetAttachmentIntegritySummary(c)
+		},
+		http.StatusOK,
+		&admin.GetAttachmentIntegrityPayload{},
+	)(c)
+}
+
+func (h *AdminHandler) GetAttachmentIntegrityIssues(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, query *admin.GetAttachmentIntegrityIssuesQuery) (*model.PaginatedResponse[admin.AttachmentIntegrityIssue], error) {
+			return h.adminService.GetAttachmentIntegrityIssues(c, query)
+		},
+		http.StatusOK,
+		&admin.GetAttachmentIntegrityIssuesQuery{},
+	)(c)
+}
+
+func (h *AdminHandler) GetCostAttribution(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, query *admin.GetCostAttributionQuery) (*admin.CostAttributionReport, error) {
+			return h.adminService.GetCostAttribution(c, query)
+		},
+		http.StatusOK,
+		&admin.GetCostAttributionQuery{},
+	)(c)
+}
+
+func (h *AdminHandler) GetReadOnly(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *admin.GetReadOnlyPayload) (*admin.ReadOnlyStatus, error) {
+			return h.adminService.GetReadOnly(c)
+		},
+		http.StatusOK,
+		&admin.GetReadOnlyPayload{},
+	)(c)
+}
+
+func (h *AdminHandler) SetReadOnly(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *admin.SetReadOnlyPayload) (*admin.ReadOnlyStatus, error) {
+			adminID := middleware.GetUserID(c)
+			return h.adminService.SetReadOnly(c, adminID, payload)
+		},
+		http.StatusOK,
+		&admin.SetReadOnlyPayload{},
+	)(c)
+}
+
+func (h *AdminHandler) GetScheduledJobs(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *admin.GetScheduledJobsPayload) ([]admin.ScheduledJob, error) {
+			return h.adminService.GetScheduledJobs(c)
+		},
+		http.StatusOK,
+		&admin.GetScheduledJobsPayload{},
+	)(c)
+}
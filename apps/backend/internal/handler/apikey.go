@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/apikey"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/mabhi256/tasker/internal/service"
+)
+
+type APIKeyHandler struct {
+	Handler
+	apiKeyService *service.APIKeyService
+}
+
+func NewAPIKeyHandler(s *server.Server, apiKeyService *service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		Handler:       NewHandler(s),
+		apiKeyService: apiKeyService,
+	}
+}
+
+func (h *APIKeyHandler) CreateKey(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *apikey.CreateKeyPayload) (*apikey.KeyWithSecret, error) {
+			userID := middleware.GetUserID(c)
+			return h.apiKeyService.CreateKey(c, userID, payload)
+		},
+		http.StatusCreated,
+		&apikey.CreateKeyPayload{},
+	)(c)
+}
+
+func (h *APIKeyHandler) GetKeys(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *apikey.GetKeysPayload) ([]apikey.Key, error) {
+			userID := middleware.GetUserID(c)
+			return h.apiKeyService.GetKeys(c, userID)
+		},
+		http.StatusOK,
+		&apikey.GetKeysPayload{},
+	)(c)
+}
+
+func (h *APIKeyHandler) RevokeKey(c echo.Context) error {
+	return HandleNoContent(
+		h.Handler,
+		func(c echo.Context, payload *apikey.RevokeKeyPayload) error {
+			userID := middleware.GetUserID(c)
+			return h.apiKeyService.RevokeKey(c, userID, payload)
+		},
+		http.StatusNoContent,
+		&apikey.RevokeKeyPayload{},
+	)(c)
+}
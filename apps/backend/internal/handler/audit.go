@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/audit"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/mabhi256/tasker/internal/service"
+)
+
+// AuditHandler manages audit forwarders. The workspace operations serve a
+// workspace's own forwarders; the Global operations serve the admin API.
+type AuditHandler struct {
+	Handler
+	auditService *service.AuditService
+}
+
+func NewAuditHandler(s *server.Server, auditService *service.AuditService) *AuditHandler {
+	return &AuditHandler{
+		Handler:      NewHandler(s),
+		auditService: auditService,
+	}
+}
+
+func (h *AuditHandler) CreateForwarder(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *audit.CreateForwarderPayload) (*audit.Forwarder, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			userID := middleware.GetUserID(c)
+			return h.auditService.CreateForwarder(c, &workspaceID, userID, payload)
+		},
+		http.StatusCreated,
+		&audit.CreateForwarderPayload{},
+	)(c)
+}
+
+func (h *AuditHandler) GetForwarders(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *audit.GetForwardersPayload) ([]audit.Forwarder, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.auditService.GetForwarders(c, &workspaceID)
+		},
+		http.StatusOK,
+		&audit.GetForwardersPayload{},
+	)(c)
+}
+
+func (h *AuditHandler) GetForwarderByID(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *audit.GetForwarderByIDPayload) (*audit.Forwarder, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.auditService.GetForwarderByID(c, &workspaceID, payload.ID)
+		},
+		http.StatusOK,
+		&audit.GetForwarderByIDPayload{},
+	)(c)
+}
+
+func (h *AuditHandler) UpdateForwarder(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *audit.UpdateForwarderPayload) (*audit.Forwarder, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.auditService.UpdateForwarder(c, &workspaceID, payload)
+		},
+		http.StatusOK,
+		&audit.UpdateForwarderPayload{},
+	)(c)
+}
+
+func (h *AuditHandler) DeleteForwarder(c echo.Context) error {
+	return HandleNoContent(
+		h.Handler,
+		func(c echo.Context, payload *audit.DeleteForwarderPayload) error {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.auditService.DeleteForwarder(c, &workspaceID, payload.ID)
+		},
+		http.StatusNoContent,
+		&audit.DeleteForwarderPayload{},
+	)(c)
+}
+
+func (h *AuditHandler) CreateGlobalForwarder(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *audit.CreateForwarderPayload) (*audit.Forwarder, error) {
+			userID := middleware.GetUserID(c)
+			return h.auditService.CreateForwarder(c, nil, userID, payload)
+		},
+		http.StatusCreated,
+		&audit.CreateForwarderPayload{},
+	)(c)
+}
+
+func (h *AuditHandler) GetGlobalForwarders(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *audit.GetForwardersPayload) ([]audit.Forwarder, error) {
+			return h.auditService.GetForwarders(c, nil)
+		},
+		http.StatusOK,
+		&audit.GetForwardersPayload{},
+	)(c)
+}
+
+func (h *AuditHandler) GetGlobalForwarderByID(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *audit.GetForwarderByIDPayload) (*audit.Forwarder, error) {
+			return h.auditService.GetForwarderByID(c, nil, payload.ID)
+		},
+		http.StatusOK,
+		&audit.GetForwarderByIDPayload{},
+	)(c)
+}
+
+func (h *AuditHandler) UpdateGlobalForwarder(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *audit.UpdateForwarderPayload) (*audit.Forwarder, error) {
+			return h.auditService.UpdateForwarder(c, nil, payload)
+		},
+		http.StatusOK,
+		&audit.UpdateForwarderPayload{},
+	)(c)
+}
+
+func (h *AuditHandler) DeleteGlobalForwarder(c echo.Context) error {
+	return HandleNoContent(
+		h.Handler,
+		func(c echo.Context, payload *audit.DeleteForwarderPayload) error {
+			return h.auditService.DeleteForwarder(c, nil, payload.ID)
+		},
+		http.StatusNoContent,
+		&audit.DeleteForwarderPayload{},
+	)(c)
+}
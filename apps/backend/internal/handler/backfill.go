@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/model/admin"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/mabhi256/tasker/internal/service"
+)
+
+type BackfillHandler struct {
+	Handler
+	backfillService *service.BackfillService
+}
+
+func NewBackfillHandler(s *server.Server, backfillService *service.BackfillService) *BackfillHandler {
+	return &BackfillHandler{
+		Handler:         NewHandler(s),
+		backfillService: backfillService,
+	}
+}
+
+func (h *BackfillHandler) GetBackfills(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *admin.GetBackfillsPayload) ([]admin.Backfill, error) {
+			return h.backfillService.GetBackfills(c)
+		},
+		http.StatusOK,
+		&admin.GetBackfillsPayload{},
+	)(c)
+}
+
+func (h *BackfillHandler) StartBackfill(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *admin.BackfillPayload) (*admin.Backfill, error) {
+			return h.backfillService.StartBackfill(c, payload.Name)
+		},
+		http.StatusAccepted,
+		&admin.BackfillPayload{},
+	)(c)
+}
+
+func (h *BackfillHandler) PauseBackfill(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *admin.BackfillPayload) (*admin.Backfill, error) {
+			return h.backfillService.PauseBackfill(c, payload.Name)
+		},
+		http.StatusOK,
+		&admin.BackfillPayload{},
+	)(c)
+}
+
+func (h *BackfillHandler) ResumeBackfill(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *admin.BackfillPayload) (*admin.Backfill, error) {
+			return h.backfillService.ResumeBackfill(c, payload.Name)
+		},
+		http.StatusAccepted,
+		&admin.BackfillPayload{},
+	)(c)
+}
+
+func (h *BackfillHandler) AbortBackfill(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *admin.BackfillPayload) (*admin.Backfill, error) {
+			return h.backfillService.AbortBackfill(c, payload.Name)
+		},
+		http.StatusOK,
+		&admin.BackfillPayload{},
+	)(c)
+}
@@ -1,9 +1,12 @@
 package handler
 
 import (
+	"errors"
 	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/lib/fieldstats"
 	"github.com/mabhi256/tasker/internal/middleware"
 	"github.com/mabhi256/tasker/internal/server"
 	"github.com/mabhi256/tasker/internal/validation"
@@ -96,8 +99,49 @@ func (h FileResponseHandler) AddAttributes(txn *newrelic.Transaction, result any
 	}
 }
 
+// BlobResponseHandler handles raw responses shown inline, such as feeds
+type BlobResponseHandler struct {
+	status      int
+	contentType string
+}
+
+func (h BlobResponseHandler) Handle(c echo.Context, result any) error {
+	return c.Blob(h.status, h.contentType, result.([]byte))
+}
+
+func (h BlobResponseHandler) GetOperation() string {
+	return "handler_blob"
+}
+
+func (h BlobResponseHandler) AddAttributes(txn *newrelic.Transaction, result any) {
+	if txn != nil {
+		txn.AddAttribute("blob.content_type", h.contentType)
+		if data, ok := result.([]byte); ok {
+			txn.AddAttribute("blob.size_bytes", len(data))
+		}
+	}
+}
+
+// RedirectResponseHandler redirects to the URL the handler returns
+type RedirectResponseHandler struct {
+	status int
+}
+
+func (h RedirectResponseHandler) Handle(c echo.Context, result any) error {
+	return c.Redirect(h.status, result.(string))
+}
+
+func (h RedirectResponseHandler) GetOperation() string {
+	return "handler_redirect"
+}
+
+func (h RedirectResponseHandler) AddAttributes(txn *newrelic.Transaction, result any) {
+	// http.status_code is already set by tracing middleware
+}
+
 // handleRequest is the unified handler function that eliminates code duplication
 func handleRequest[Req validation.Validatable](
+	h Handler,
 	c echo.Context,
 	req Req,
 	handler func(c echo.Context, req Req) (any, error),
@@ -151,6 +195,8 @@ func handleRequest[Req validation.Validatable](
 			txn.AddAttribute("validation.status", "failed")
 			txn.AddAttribute("validation.duration_ms", validationDuration.Milliseconds())
 		}
+
+		h.recordValidationFailures(c, err)
 		return err
 	}
 
@@ -206,6 +252,34 @@ func handleRequest[Req validation.Validatable](
 	return responseHandler.Handle(c, result)
 }
 
+// recordValidationFailures counts the fields of a request that failed
+// binding or validation, by route and client, for the metrics and the
+// validation failure report
+func (h Handler) recordValidationFailures(c echo.Context, err error) {
+	var httpErr *errs.HTTPError
+	if !errors.As(err, &httpErr) {
+		return
+	}
+
+	method := c.Request().Method
+	route := c.Path()
+	client := fieldstats.Client(c.Request().Header)
+	for _, bindErr := range httpErr.Errors {
+		source, field := validation.FieldOf(bindErr)
+		unknown := bindErr.Error == validation.ErrUnknownField
+
+		h.server.Metrics.ValidationFailure(method, route, field, unknown)
+		h.server.FieldStats.Add(fieldstats.Failure{
+			Method:  method,
+			Route:   route,
+			Source:  source,
+			Field:   field,
+			Unknown: unknown,
+			Client:  client,
+		})
+	}
+}
+
 // Handle wraps a handler with validation, error handling, logging, metrics, and tracing
 func Handle[Req validation.Validatable, Res any](
 	h Handler,
@@ -214,7 +288,7 @@ func Handle[Req validation.Validatable, Res any](
 	req Req,
 ) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		return handleRequest(c, req, func(c echo.Context, req Req) (any, error) {
+		return handleRequest(h, c, req, func(c echo.Context, req Req) (any, error) {
 			return handler(c, req)
 		}, JSONResponseHandler{status: status})
 	}
@@ -229,7 +303,7 @@ func HandleFile[Req validation.Validatable](
 	contentType string,
 ) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		return handleRequest(c, req, func(c echo.Context, req Req) (any, error) {
+		return handleRequest(h, c, req, func(c echo.Context, req Req) (any, error) {
 			return handler(c, req)
 		}, FileResponseHandler{
 			status:      status,
@@ -239,6 +313,40 @@ func HandleFile[Req validation.Validatable](
 	}
 }
 
+// HandleBlob wraps a handler whose response is raw content of contentType,
+// sent inline rather than as a download
+func HandleBlob[Req validation.Validatable](
+	h Handler,
+	handler HandlerFunc[Req, []byte],
+	status int,
+	req Req,
+	contentType string,
+) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return handleRequest(h, c, req, func(c echo.Context, req Req) (any, error) {
+			return handler(c, req)
+		}, BlobResponseHandler{
+			status:      status,
+			contentType: contentType,
+		})
+	}
+}
+
+// HandleRedirect wraps a handler that returns the URL to redirect to, with
+// a 3xx status
+func HandleRedirect[Req validation.Validatable](
+	h Handler,
+	handler HandlerFunc[Req, string],
+	status int,
+	req Req,
+) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return handleRequest(h, c, req, func(c echo.Context, req Req) (any, error) {
+			return handler(c, req)
+		}, RedirectResponseHandler{status: status})
+	}
+}
+
 // HandleNoContent wraps a handler with validation, error handling, logging, metrics, and tracing for endpoints that don't return content
 func HandleNoContent[Req validation.Validatable](
 	h Handler,
@@ -247,7 +355,7 @@ func HandleNoContent[Req validation.Validatable](
 	req Req,
 ) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		return handleRequest(c, req, func(c echo.Context, req Req) (any, error) {
+		return handleRequest(h, c, req, func(c echo.Context, req Req) (any, error) {
 			err := handler(c, req)
 			return nil, err
 		}, NoContentResponseHandler{status: status})
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/capability"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/mabhi256/tasker/internal/service"
+)
+
+type CapabilityHandler struct {
+	Handler
+	capabilityService *service.CapabilityService
+}
+
+func NewCapabilityHandler(s *server.Server, capabilityService *service.CapabilityService) *CapabilityHandler {
+	return &CapabilityHandler{
+		Handler:           NewHandler(s),
+		capabilityService: capabilityService,
+	}
+}
+
+func (h *CapabilityHandler) GetCapabilities(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *capability.GetCapabilitiesPayload) (*capability.Capabilities, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.capabilityService.GetCapabilities(c, workspaceID)
+		},
+		http.StatusOK,
+		&capability.GetCapabilitiesPayload{},
+	)(c)
+}
@@ -27,8 +27,9 @@ func (h *CategoryHandler) CreateCategory(c echo.Context) error {
 	return Handle(
 		h.Handler,
 		func(c echo.Context, payload *category.CreateCategoryPayload) (*category.Category, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
 			userID := middleware.GetUserID(c)
-			return h.categoryService.CreateCategory(c, userID, payload)
+			return h.categoryService.CreateCategory(c, workspaceID, userID, payload)
 		},
 		http.StatusCreated,
 		&category.CreateCategoryPayload{},
@@ -41,8 +42,8 @@ func (h *CategoryHandler) GetCategories(c echo.Context) error {
 		func(c echo.Context, query *category.GetCategoriesQuery) (
 			*model.PaginatedResponse[category.Category], error,
 		) {
-			userID := middleware.GetUserID(c)
-			return h.categoryService.GetCategories(c, userID, query)
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.categoryService.GetCategories(c, workspaceID, query)
 		},
 		http.StatusOK,
 		&category.GetCategoriesQuery{},
@@ -53,8 +54,8 @@ func (h *CategoryHandler) UpdateCategory(c echo.Context) error {
 	return Handle(
 		h.Handler,
 		func(c echo.Context, payload *category.UpdateCategoryPayload) (*category.Category, error) {
-			userID := middleware.GetUserID(c)
-			return h.categoryService.UpdateCategory(c, userID, payload.ID, payload)
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.categoryService.UpdateCategory(c, workspaceID, payload.ID, payload)
 		},
 		http.StatusOK,
 		&category.UpdateCategoryPayload{},
@@ -65,8 +66,8 @@ func (h *CategoryHandler) DeleteCategory(c echo.Context) error {
 	return HandleNoContent(
 		h.Handler,
 		func(c echo.Context, payload *category.DeleteCategoryPayload) error {
-			userID := middleware.GetUserID(c)
-			return h.categoryService.DeleteCategory(c, userID, payload.ID)
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.categoryService.DeleteCategory(c, workspaceID, payload.ID)
 		},
 		http.StatusNoContent,
 		&category.DeleteCategoryPayload{},
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/model/changelog"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/mabhi256/tasker/internal/service"
+)
+
+const contentTypeRSS = "application/rss+xml"
+
+type ChangelogHandler struct {
+	Handler
+	changelogService *service.ChangelogService
+}
+
+func NewChangelogHandler(s *server.Server, changelogService *service.ChangelogService) *ChangelogHandler {
+	return &ChangelogHandler{
+		Handler:          NewHandler(s),
+		changelogService: changelogService,
+	}
+}
+
+func (h *ChangelogHandler) GetChangelog(c echo.Context) error {
+	if wantsFeed(c) {
+		return HandleBlob(
+			h.Handler,
+			func(c echo.Context, payload *changelog.GetChangelogPayload) ([]byte, error) {
+				link := c.Scheme() + "://" + c.Request().Host + c.Request().URL.Path
+				return h.changelogService.GetChangelogFeed(c, payload, link)
+			},
+			http.StatusOK,
+			&changelog.GetChangelogPayload{},
+			contentTypeRSS+"; charset=utf-8",
+		)(c)
+	}
+
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *changelog.GetChangelogPayload) (*changelog.Changelog, error) {
+			return h.changelogService.GetChangelog(c, payload)
+		},
+		http.StatusOK,
+		&changelog.GetChangelogPayload{},
+	)(c)
+}
+
+// wantsFeed reports whether the changelog is asked for as RSS, with
+// format=rss or, without a format, by accepting RSS
+func wantsFeed(c echo.Context) bool {
+	switch c.QueryParam("format") {
+	case "rss":
+		return true
+	case "":
+		return strings.Contains(c.Request().Header.Get(echo.HeaderAccept), contentTypeRSS)
+	default:
+		return false
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model"
 	"github.com/mabhi256/tasker/internal/model/comment"
 	"github.com/mabhi256/tasker/internal/server"
 	"github.com/mabhi256/tasker/internal/service"
@@ -26,8 +27,9 @@ func (h *CommentHandler) AddComment(c echo.Context) error {
 	return Handle(
 		h.Handler,
 		func(c echo.Context, payload *comment.AddCommentPayload) (*comment.Comment, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
 			userID := middleware.GetUserID(c)
-			return h.commentService.AddComment(c, userID, payload.TodoID, payload)
+			return h.commentService.AddComment(c, workspaceID, userID, payload.TodoID, payload)
 		},
 		http.StatusCreated,
 		&comment.AddCommentPayload{},
@@ -38,8 +40,8 @@ func (h *CommentHandler) GetCommentsByTodoID(c echo.Context) error {
 	return Handle(
 		h.Handler,
 		func(c echo.Context, payload *comment.GetCommentsByTodoIDPayload) ([]comment.Comment, error) {
-			userID := middleware.GetUserID(c)
-			return h.commentService.GetCommentsByTodoID(c, userID, payload.TodoID)
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.commentService.GetCommentsByTodoID(c, workspaceID, payload.TodoID, payload.Render != nil)
 		},
 		http.StatusOK,
 		&comment.GetCommentsByTodoIDPayload{},
@@ -50,8 +52,9 @@ func (h *CommentHandler) UpdateComment(c echo.Context) error {
 	return Handle(
 		h.Handler,
 		func(c echo.Context, payload *comment.UpdateCommentPayload) (*comment.Comment, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
 			userID := middleware.GetUserID(c)
-			return h.commentService.UpdateComment(c, userID, payload.ID, payload.Content)
+			return h.commentService.UpdateComment(c, workspaceID, userID, payload.ID, payload.Content)
 		},
 		http.StatusOK,
 		&comment.UpdateCommentPayload{},
@@ -62,10 +65,63 @@ func (h *CommentHandler) DeleteComment(c echo.Context) error {
 	return HandleNoContent(
 		h.Handler,
 		func(c echo.Context, payload *comment.DeleteCommentPayload) error {
+			workspaceID := middleware.GetWorkspaceID(c)
 			userID := middleware.GetUserID(c)
-			return h.commentService.DeleteComment(c, userID, payload.ID)
+			return h.commentService.DeleteComment(c, workspaceID, userID, payload.ID)
 		},
 		http.StatusNoContent,
 		&comment.DeleteCommentPayload{},
 	)(c)
 }
+
+func (h *CommentHandler) GetMentions(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, query *comment.GetMentionsQuery) (*model.PaginatedResponse[comment.Comment], error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			userID := middleware.GetUserID(c)
+			return h.commentService.GetMentions(c, workspaceID, userID, query)
+		},
+		http.StatusOK,
+		&comment.GetMentionsQuery{},
+	)(c)
+}
+
+func (h *CommentHandler) GetThread(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *comment.GetCommentThreadPayload) ([]comment.ThreadComment, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			userID := middleware.GetUserID(c)
+			return h.commentService.GetThread(c, workspaceID, userID, payload.ID, payload.Render != nil)
+		},
+		http.StatusOK,
+		&comment.GetCommentThreadPayload{},
+	)(c)
+}
+
+func (h *CommentHandler) AddReaction(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *comment.AddReactionPayload) ([]comment.ReactionSummary, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			userID := middleware.GetUserID(c)
+			return h.commentService.AddReaction(c, workspaceID, userID, payload.ID, payload.Emoji)
+		},
+		http.StatusOK,
+		&comment.AddReactionPayload{},
+	)(c)
+}
+
+func (h *CommentHandler) RemoveReaction(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *comment.RemoveReactionPayload) ([]comment.ReactionSummary, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			userID := middleware.GetUserID(c)
+			return h.commentService.RemoveReaction(c, workspaceID, userID, payload.ID, payload.Emoji)
+		},
+		http.StatusOK,
+		&comment.RemoveReactionPayload{},
+	)(c)
+}
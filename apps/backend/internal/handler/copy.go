@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/todo"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/mabhi256/tasker/internal/service"
+)
+
+type CopyHandler struct {
+	Handler
+	copyService *service.CopyService
+}
+
+func NewCopyHandler(s *server.Server, copyService *service.CopyService) *CopyHandler {
+	return &CopyHandler{
+		Handler:     NewHandler(s),
+		copyService: copyService,
+	}
+}
+
+func (h *CopyHandler) CopyTodo(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *todo.CopyTodoPayload) (*todo.CopiedTodo, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			userID := middleware.GetUserID(c)
+			return h.copyService.CopyTodo(c, workspaceID, userID, payload)
+		},
+		http.StatusCreated,
+		&todo.CopyTodoPayload{},
+	)(c)
+}
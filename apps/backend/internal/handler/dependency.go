@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/todo"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/mabhi256/tasker/internal/service"
+)
+
+type DependencyHandler struct {
+	Handler
+	dependencyService *service.DependencyService
+}
+
+func NewDependencyHandler(s *server.Server, dependencyService *service.DependencyService) *DependencyHandler {
+	return &DependencyHandler{
+		Handler:           NewHandler(s),
+		dependencyService: dependencyService,
+	}
+}
+
+func (h *DependencyHandler) GetDependencies(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *todo.GetTodoDependenciesPayload) (*todo.Dependencies, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.dependencyService.GetDependencies(c, workspaceID, payload.TodoID)
+		},
+		http.StatusOK,
+		&todo.GetTodoDependenciesPayload{},
+	)(c)
+}
+
+func (h *DependencyHandler) AddDependency(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *todo.AddTodoDependencyPayload) (*todo.Dependencies, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.dependencyService.AddDependency(c, workspaceID, payload)
+		},
+		http.StatusOK,
+		&todo.AddTodoDependencyPayload{},
+	)(c)
+}
+
+func (h *DependencyHandler) RemoveDependency(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *todo.RemoveTodoDependencyPayload) (*todo.Dependencies, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.dependencyService.RemoveDependency(c, workspaceID, payload)
+		},
+		http.StatusOK,
+		&todo.RemoveTodoDependencyPayload{},
+	)(c)
+}
+
+func (h *DependencyHandler) GetWorkList(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, query *todo.GetWorkListQuery) ([]todo.DependentTodo, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.dependencyService.GetWorkList(c, workspaceID, query)
+		},
+		http.StatusOK,
+		&todo.GetWorkListQuery{},
+	)(c)
+}
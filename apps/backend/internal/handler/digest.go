@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/digest"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/mabhi256/tasker/internal/service"
+)
+
+type DigestHandler struct {
+	Handler
+	digestService *service.DigestService
+}
+
+func NewDigestHandler(s *server.Server, digestService *service.DigestService) *DigestHandler {
+	return &DigestHandler{
+		Handler:       NewHandler(s),
+		digestService: digestService,
+	}
+}
+
+func (h *DigestHandler) GetSubscription(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *digest.GetSubscriptionPayload) (*digest.Subscription, error) {
+			userID := middleware.GetUserID(c)
+			return h.digestService.GetSubscription(c, userID)
+		},
+		http.StatusOK,
+		&digest.GetSubscriptionPayload{},
+	)(c)
+}
+
+func (h *DigestHandler) PutSubscription(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *digest.PutSubscriptionPayload) (*digest.Subscription, error) {
+			userID := middleware.GetUserID(c)
+			return h.digestService.PutSubscription(c, userID, payload)
+		},
+		http.StatusOK,
+		&digest.PutSubscriptionPayload{},
+	)(c)
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/model/emailtracking"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/mabhi256/tasker/internal/service"
+)
+
+type EmailTrackingHandler struct {
+	Handler
+	trackingService *service.EmailTrackingService
+}
+
+func NewEmailTrackingHandler(s *server.Server, trackingService *service.EmailTrackingService) *EmailTrackingHandler {
+	return &EmailTrackingHandler{
+		Handler:         NewHandler(s),
+		trackingService: trackingService,
+	}
+}
+
+func (h *EmailTrackingHandler) RecordOpen(c echo.Context) error {
+	return HandleBlob(
+		h.Handler,
+		func(c echo.Context, payload *emailtracking.RecordOpenPayload) ([]byte, error) {
+			// Each open is counted, so the pixel isn't cached
+			c.Response().Header().Set("Cache-Control", "no-store")
+			return h.trackingService.RecordOpen(c, payload)
+		},
+		http.StatusOK,
+		&emailtracking.RecordOpenPayload{},
+		"image/gif",
+	)(c)
+}
+
+func (h *EmailTrackingHandler) RecordClick(c echo.Context) error {
+	return HandleRedirect(
+		h.Handler,
+		func(c echo.Context, payload *emailtracking.RecordClickPayload) (string, error) {
+			return h.trackingService.RecordClick(c, payload)
+		},
+		http.StatusFound,
+		&emailtracking.RecordClickPayload{},
+	)(c)
+}
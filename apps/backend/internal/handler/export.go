@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model"
+	"github.com/mabhi256/tasker/internal/model/export"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/mabhi256/tasker/internal/service"
+)
+
+type ExportHandler struct {
+	Handler
+	exportService *service.ExportService
+}
+
+func NewExportHandler(s *server.Server, exportService *service.ExportService) *ExportHandler {
+	return &ExportHandler{
+		Handler:       NewHandler(s),
+		exportService: exportService,
+	}
+}
+
+func (h *ExportHandler) CreateSchedule(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *export.CreateSchedulePayload) (*export.Schedule, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			userID := middleware.GetUserID(c)
+			return h.exportService.CreateSchedule(c, workspaceID, userID, payload)
+		},
+		http.StatusCreated,
+		&export.CreateSchedulePayload{},
+	)(c)
+}
+
+func (h *ExportHandler) GetSchedules(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *export.GetSchedulesPayload) ([]export.Schedule, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.exportService.GetSchedules(c, workspaceID)
+		},
+		http.StatusOK,
+		&export.GetSchedulesPayload{},
+	)(c)
+}
+
+func (h *ExportHandler) GetScheduleByID(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *export.GetScheduleByIDPayload) (*export.Schedule, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.exportService.GetScheduleByID(c, workspaceID, payload.ID)
+		},
+		http.StatusOK,
+		&export.GetScheduleByIDPayload{},
+	)(c)
+}
+
+func (h *ExportHandler) UpdateSchedule(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *export.UpdateSchedulePayload) (*export.Schedule, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.exportService.UpdateSchedule(c, workspaceID, payload)
+		},
+		http.StatusOK,
+		&export.UpdateSchedulePayload{},
+	)(c)
+}
+
+func (h *ExportHandler) DeleteSchedule(c echo.Context) error {
+	return HandleNoContent(
+		h.Handler,
+		func(c echo.Context, payload *export.DeleteSchedulePayload) error {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.exportService.DeleteSchedule(c, workspaceID, payload.ID)
+		},
+		http.StatusNoContent,
+		&export.DeleteSchedulePayload{},
+	)(c)
+}
+
+func (h *ExportHandler) TriggerRun(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *export.TriggerRunPayload) (*export.Run, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.exportService.TriggerRun(c, workspaceID, payload.ID)
+		},
+		http.StatusAccepted,
+		&export.TriggerRunPayload{},
+	)(c)
+}
+
+func (h *ExportHandler) GetRuns(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, query *export.GetRunsPayload) (*model.PaginatedResponse[export.Run], error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.exportService.GetRuns(c, workspaceID, query)
+		},
+		http.StatusOK,
+		&export.GetRunsPayload{},
+	)(c)
+}
@@ -6,19 +6,77 @@ import (
 )
 
 type Handlers struct {
-	Health   *HealthHandler
-	OpenAPI  *OpenAPIHandler
-	Todo     *TodoHandler
-	Comment  *CommentHandler
-	Category *CategoryHandler
+	Health        *HealthHandler
+	OpenAPI       *OpenAPIHandler
+	Todo          *TodoHandler
+	Comment       *CommentHandler
+	Category      *CategoryHandler
+	Admin         *AdminHandler
+	Workspace     *WorkspaceHandler
+	Export        *ExportHandler
+	Webhook       *WebhookHandler
+	JobAdmin      *JobAdminHandler
+	Realtime      *RealtimeHandler
+	Digest        *DigestHandler
+	Push          *PushHandler
+	Rollout       *RolloutHandler
+	Backfill      *BackfillHandler
+	Search        *SearchHandler
+	Dependency    *DependencyHandler
+	Audit         *AuditHandler
+	Provisioning  *ProvisioningHandler
+	SSO           *SSOHandler
+	IPAllowlist   *IPAllowlistHandler
+	Session       *SessionHandler
+	APIKey        *APIKeyHandler
+	Resolve       *ResolveHandler
+	Capability    *CapabilityHandler
+	Quota         *QuotaHandler
+	Report        *ReportHandler
+	Snapshot      *SnapshotHandler
+	Copy          *CopyHandler
+	Changelog     *ChangelogHandler
+	Tag           *TagHandler
+	Trial         *TrialHandler
+	Activity      *ActivityHandler
+	EmailTracking *EmailTrackingHandler
 }
 
 func NewHandlers(s *server.Server, services *service.Services) *Handlers {
 	return &Handlers{
-		Health:   NewHealthHandler(s),
-		OpenAPI:  NewOpenAPIHandler(s),
-		Todo:     NewTodoHandler(s, services.Todo),
-		Comment:  NewCommentHandler(s, services.Comment),
-		Category: NewCategoryHandler(s, services.Category),
+		Health:        NewHealthHandler(s),
+		OpenAPI:       NewOpenAPIHandler(s),
+		Todo:          NewTodoHandler(s, services.Todo),
+		Comment:       NewCommentHandler(s, services.Comment),
+		Category:      NewCategoryHandler(s, services.Category),
+		Admin:         NewAdminHandler(s, services.Admin),
+		Workspace:     NewWorkspaceHandler(s, services.Workspace),
+		Export:        NewExportHandler(s, services.Export),
+		Webhook:       NewWebhookHandler(s, services.Webhook),
+		JobAdmin:      NewJobAdminHandler(s, services.JobAdmin),
+		Realtime:      NewRealtimeHandler(s, services.Realtime),
+		Digest:        NewDigestHandler(s, services.Digest),
+		Push:          NewPushHandler(s, services.Push),
+		Rollout:       NewRolloutHandler(s, services.Rollout),
+		Backfill:      NewBackfillHandler(s, services.Backfill),
+		Search:        NewSearchHandler(s, services.Search),
+		Dependency:    NewDependencyHandler(s, services.Dependency),
+		Audit:         NewAuditHandler(s, services.Audit),
+		Provisioning:  NewProvisioningHandler(s, services.Provisioning),
+		SSO:           NewSSOHandler(s, services.SSO),
+		IPAllowlist:   NewIPAllowlistHandler(s, services.IPAllowlist),
+		Session:       NewSessionHandler(s, services.Session),
+		APIKey:        NewAPIKeyHandler(s, services.APIKey),
+		Resolve:       NewResolveHandler(s, services.Resolve),
+		Capability:    NewCapabilityHandler(s, services.Capability),
+		Quota:         NewQuotaHandler(s, services.Quota),
+		Report:        NewReportHandler(s, services.Report),
+		Snapshot:      NewSnapshotHandler(s, services.Snapshot),
+		Copy:          NewCopyHandler(s, services.Copy),
+		Changelog:     NewChangelogHandler(s, services.Changelog),
+		Tag:           NewTagHandler(s, services.Tag),
+		Trial:         NewTrialHandler(s, services.Trial),
+		Activity:      NewActivityHandler(s, services.Activity),
+		EmailTracking: NewEmailTrackingHandler(s, services.EmailTracking),
 	}
 }
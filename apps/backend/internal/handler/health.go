@@ -1,12 +1,12 @@
 package handler
 
 import (
-	"context"
 	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/health"
 	"github.com/mabhi256/tasker/internal/middleware"
 	"github.com/mabhi256/tasker/internal/server"
 )
@@ -21,90 +21,22 @@ func NewHealthHandler(s *server.Server) *HealthHandler {
 	}
 }
 
+// healthResponse is a report along with the environment answering it
+type healthResponse struct {
+	health.Report
+	Environment string `json:"environment"`
+}
+
+// CheckHealth runs every check now, rather than answering from the latest
+// background run
 func (h *HealthHandler) CheckHealth(c echo.Context) error {
 	start := time.Now()
 	logger := middleware.GetLogger(c).With().
 		Str("operation", "health_check").
 		Logger()
 
-	response := map[string]any{
-		"status":      "healthy",
-		"timestamp":   time.Now().UTC(),
-		"environment": h.server.Config.Primary.Env,
-		"checks":      make(map[string]any),
-	}
-
-	checks := response["checks"].(map[string]any)
-	isHealthy := true
-
-	// Check database connectivity
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	dbStart := time.Now()
-	if err := h.server.DB.Pool.Ping(ctx); err != nil {
-		checks["database"] = map[string]any{
-			"status":        "unhealthy",
-			"response_time": time.Since(dbStart).String(),
-			"error":         err.Error(),
-		}
-		isHealthy = false
-		logger.Error().Err(err).Dur("response_time", time.Since(dbStart)).Msg("database health check failed")
-		if h.server.LoggerService != nil && h.server.LoggerService.GetApplication() != nil {
-			h.server.LoggerService.GetApplication().RecordCustomEvent(
-				"HealthCheckError", map[string]any{
-					"check_type":       "database",
-					"operation":        "health_check",
-					"error_type":       "database_unhealthy",
-					"response_time_ms": time.Since(dbStart).Milliseconds(),
-					"error_message":    err.Error(),
-				})
-		}
-	} else {
-		checks["database"] = map[string]any{
-			"status":        "healthy",
-			"response_time": time.Since(dbStart).String(),
-		}
-		logger.Info().Dur("response_time", time.Since(dbStart)).Msg("database health check passed")
-	}
-
-	// Database connection metrics are automatically captured by New Relic nrpgx5 integration
-
-	// Check Redis connectivity
-	if h.server.Redis != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-
-		redisStart := time.Now()
-		if err := h.server.Redis.Ping(ctx).Err(); err != nil {
-			checks["redis"] = map[string]any{
-				"status":        "unhealthy",
-				"response_time": time.Since(redisStart).String(),
-				"error":         err.Error(),
-			}
-			logger.Error().Err(err).Dur("response_time", time.Since(redisStart)).Msg("redis health check failed")
-			if h.server.LoggerService != nil && h.server.LoggerService.GetApplication() != nil {
-				h.server.LoggerService.GetApplication().RecordCustomEvent(
-					"HealthCheckError", map[string]any{
-						"check_type":       "redis",
-						"operation":        "health_check",
-						"error_type":       "redis_unhealthy",
-						"response_time_ms": time.Since(redisStart).Milliseconds(),
-						"error_message":    err.Error(),
-					})
-			}
-		} else {
-			checks["redis"] = map[string]any{
-				"status":        "healthy",
-				"response_time": time.Since(redisStart).String(),
-			}
-			logger.Info().Dur("response_time", time.Since(redisStart)).Msg("redis health check passed")
-		}
-	}
-
-	// Set overall status
-	if !isHealthy {
-		response["status"] = "unhealthy"
+	report := h.server.Health.Check(c.Request().Context())
+	if !report.Healthy() {
 		logger.Warn().
 			Dur("total_duration", time.Since(start)).
 			Msg("health check failed")
@@ -117,16 +49,42 @@ func (h *HealthHandler) CheckHealth(c echo.Context) error {
 					"total_duration_ms": time.Since(start).Milliseconds(),
 				})
 		}
-		return c.JSON(http.StatusServiceUnavailable, response)
+	} else {
+		logger.Info().
+			Dur("total_duration", time.Since(start)).
+			Msg("health check passed")
 	}
 
-	logger.Info().
-		Dur("total_duration", time.Since(start)).
-		Msg("health check passed")
+	return h.writeReport(c, report)
+}
+
+// Liveness answers the liveness probe, which only fails when the process
+// can't serve requests at all
+func (h *HealthHandler) Liveness(c echo.Context) error {
+	return h.writeReport(c, h.server.Health.Liveness())
+}
+
+// Readiness answers the readiness probe from the checks' latest results
+func (h *HealthHandler) Readiness(c echo.Context) error {
+	return h.writeReport(c, h.server.Health.Readiness(c.Request().Context()))
+}
+
+// Startup answers the startup probe, which passes once every check has
+func (h *HealthHandler) Startup(c echo.Context) error {
+	return h.writeReport(c, h.server.Health.Startup(c.Request().Context()))
+}
+
+func (h *HealthHandler) writeReport(c echo.Context, report health.Report) error {
+	status := http.StatusOK
+	if !report.Healthy() {
+		status = http.StatusServiceUnavailable
+	}
 
-	err := c.JSON(http.StatusOK, response)
-	if err != nil {
-		logger.Error().Err(err).Msg("failed to write JSON response")
+	response := healthResponse{
+		Report:      report,
+		Environment: h.server.Config.Primary.Env,
+	}
+	if err := c.JSON(status, response); err != nil {
 		if h.server.LoggerService != nil && h.server.LoggerService.GetApplication() != nil {
 			h.server.LoggerService.GetApplication().RecordCustomEvent(
 				"HealthCheckError", map[string]any{
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/ipallowlist"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/mabhi256/tasker/internal/service"
+)
+
+type IPAllowlistHandler struct {
+	Handler
+	ipAllowlistService *service.IPAllowlistService
+}
+
+func NewIPAllowlistHandler(s *server.Server, ipAllowlistService *service.IPAllowlistService) *IPAllowlistHandler {
+	return &IPAllowlistHandler{
+		Handler:            NewHandler(s),
+		ipAllowlistService: ipAllowlistService,
+	}
+}
+
+func (h *IPAllowlistHandler) GetAllowlist(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *ipallowlist.GetAllowlistPayload) (*ipallowlist.Allowlist, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.ipAllowlistService.GetAllowlist(c, workspaceID)
+		},
+		http.StatusOK,
+		&ipallowlist.GetAllowlistPayload{},
+	)(c)
+}
+
+func (h *IPAllowlistHandler) UpdateAllowlist(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *ipallowlist.UpdateAllowlistPayload) (*ipallowlist.Allowlist, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			userID := middleware.GetUserID(c)
+			return h.ipAllowlistService.UpdateAllowlist(c, workspaceID, userID, payload)
+		},
+		http.StatusOK,
+		&ipallowlist.UpdateAllowlistPayload{},
+	)(c)
+}
+
+func (h *IPAllowlistHandler) CreateEntry(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *ipallowlist.CreateEntryPayload) (*ipallowlist.Entry, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			userID := middleware.GetUserID(c)
+			return h.ipAllowlistService.CreateEntry(c, workspaceID, userID, payload)
+		},
+		http.StatusCreated,
+		&ipallowlist.CreateEntryPayload{},
+	)(c)
+}
+
+func (h *IPAllowlistHandler) DeleteEntry(c echo.Context) error {
+	return HandleNoContent(
+		h.Handler,
+		func(c echo.Context, payload *ipallowlist.DeleteEntryPayload) error {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.ipAllowlistService.DeleteEntry(c, workspaceID, payload)
+		},
+		http.StatusNoContent,
+		&ipallowlist.DeleteEntryPayload{},
+	)(c)
+}
+
+func (h *IPAllowlistHandler) StartBreakGlass(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *ipallowlist.BreakGlassPayload) (*ipallowlist.Settings, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			userID := middleware.GetUserID(c)
+			return h.ipAllowlistService.StartBreakGlass(c, workspaceID, userID, payload)
+		},
+		http.StatusOK,
+		&ipallowlist.BreakGlassPayload{},
+	)(c)
+}
+
+func (h *IPAllowlistHandler) EndBreakGlass(c echo.Context) error {
+	return HandleNoContent(
+		h.Handler,
+		func(c echo.Context, payload *ipallowlist.EndBreakGlassPayload) error {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.ipAllowlistService.EndBreakGlass(c, workspaceID)
+		},
+		http.StatusNoContent,
+		&ipallowlist.EndBreakGlassPayload{},
+	)(c)
+}
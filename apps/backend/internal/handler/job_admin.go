@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/model"
+	"github.com/mabhi256/tasker/internal/model/admin"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/mabhi256/tasker/internal/service"
+)
+
+type JobAdminHandler struct {
+	Handler
+	jobAdminService *service.JobAdminService
+}
+
+func NewJobAdminHandler(s *server.Server, jobAdminService *service.JobAdminService) *JobAdminHandler {
+	return &JobAdminHandler{
+		Handler:         NewHandler(s),
+		jobAdminService: jobAdminService,
+	}
+}
+
+func (h *JobAdminHandler) GetQueues(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *admin.GetQueuesPayload) ([]admin.QueueStats, error) {
+			return h.jobAdminService.GetQueues(c)
+		},
+		http.StatusOK,
+		&admin.GetQueuesPayload{},
+	)(c)
+}
+
+func (h *JobAdminHandler) GetQueueTasks(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, query *admin.GetQueueTasksQuery) (*model.PaginatedResponse[admin.JobTask], error) {
+			return h.jobAdminService.GetQueueTasks(c, query)
+		},
+		http.StatusOK,
+		&admin.GetQueueTasksQuery{},
+	)(c)
+}
+
+func (h *JobAdminHandler) RetryTask(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *admin.QueueTaskPayload) (*admin.JobTask, error) {
+			return h.jobAdminService.RetryTask(c, payload)
+		},
+		http.StatusOK,
+		&admin.QueueTaskPayload{},
+	)(c)
+}
+
+func (h *JobAdminHandler) DeleteTask(c echo.Context) error {
+	return HandleNoContent(
+		h.Handler,
+		func(c echo.Context, payload *admin.QueueTaskPayload) error {
+			return h.jobAdminService.DeleteTask(c, payload)
+		},
+		http.StatusNoContent,
+		&admin.QueueTaskPayload{},
+	)(c)
+}
+
+func (h *JobAdminHandler) PauseQueue(c echo.Context) error {
+	return HandleNoContent(
+		h.Handler,
+		func(c echo.Context, payload *admin.QueuePayload) error {
+			return h.jobAdminService.PauseQueue(c, payload.Queue)
+		},
+		http.StatusNoContent,
+		&admin.QueuePayload{},
+	)(c)
+}
+
+func (h *JobAdminHandler) ResumeQueue(c echo.Context) error {
+	return HandleNoContent(
+		h.Handler,
+		func(c echo.Context, payload *admin.QueuePayload) error {
+			return h.jobAdminService.ResumeQueue(c, payload.Queue)
+		},
+		http.StatusNoContent,
+		&admin.QueuePayload{},
+	)(c)
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/provisioning"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/mabhi256/tasker/internal/service"
+)
+
+// ProvisioningHandler serves the SCIM API to identity providers, see
+// scim.go, lets workspace admins manage the SCIM token and map provisioned
+// groups to roles, and lets invited users accept their invitations
+type ProvisioningHandler struct {
+	Handler
+	provisioningService *service.ProvisioningService
+}
+
+func NewProvisioningHandler(s *server.Server, provisioningService *service.ProvisioningService) *ProvisioningHandler {
+	return &ProvisioningHandler{
+		Handler:             NewHandler(s),
+		provisioningService: provisioningService,
+	}
+}
+
+func (h *ProvisioningHandler) CreateToken(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *provisioning.CreateTokenPayload) (*provisioning.TokenWithSecret, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			userID := middleware.GetUserID(c)
+			return h.provisioningService.CreateToken(c, workspaceID, userID)
+		},
+		http.StatusCreated,
+		&provisioning.CreateTokenPayload{},
+	)(c)
+}
+
+func (h *ProvisioningHandler) GetToken(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *provisioning.GetTokenPayload) (*provisioning.Token, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.provisioningService.GetToken(c, workspaceID)
+		},
+		http.StatusOK,
+		&provisioning.GetTokenPayload{},
+	)(c)
+}
+
+func (h *ProvisioningHandler) DeleteToken(c echo.Context) error {
+	return HandleNoContent(
+		h.Handler,
+		func(c echo.Context, payload *provisioning.DeleteTokenPayload) error {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.provisioningService.DeleteToken(c, workspaceID)
+		},
+		http.StatusNoContent,
+		&provisioning.DeleteTokenPayload{},
+	)(c)
+}
+
+func (h *ProvisioningHandler) GetGroups(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *provisioning.GetGroupsPayload) ([]provisioning.Group, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.provisioningService.GetGroups(c, workspaceID)
+		},
+		http.StatusOK,
+		&provisioning.GetGroupsPayload{},
+	)(c)
+}
+
+func (h *ProvisioningHandler) UpdateGroupRole(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *provisioning.UpdateGroupRolePayload) (*provisioning.Group, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.provisioningService.UpdateGroupRole(c, workspaceID, payload)
+		},
+		http.StatusOK,
+		&provisioning.UpdateGroupRolePayload{},
+	)(c)
+}
+
+func (h *ProvisioningHandler) GetInvitations(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *provisioning.GetInvitationsPayload) ([]provisioning.User, error) {
+			userID := middleware.GetUserID(c)
+			return h.provisioningService.GetInvitations(c, userID)
+		},
+		http.StatusOK,
+		&provisioning.GetInvitationsPayload{},
+	)(c)
+}
+
+func (h *ProvisioningHandler) AcceptInvitation(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *provisioning.AcceptInvitationPayload) (*provisioning.User, error) {
+			userID := middleware.GetUserID(c)
+			return h.provisioningService.AcceptInvitation(c, userID, payload)
+		},
+		http.StatusOK,
+		&provisioning.AcceptInvitationPayload{},
+	)(c)
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/device"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/mabhi256/tasker/internal/service"
+)
+
+type PushHandler struct {
+	Handler
+	pushService *service.PushService
+}
+
+func NewPushHandler(s *server.Server, pushService *service.PushService) *PushHandler {
+	return &PushHandler{
+		Handler:     NewHandler(s),
+		pushService: pushService,
+	}
+}
+
+func (h *PushHandler) GetPushConfig(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *device.GetPushConfigPayload) (*device.PushConfig, error) {
+			return h.pushService.GetPushConfig(c)
+		},
+		http.StatusOK,
+		&device.GetPushConfigPayload{},
+	)(c)
+}
+
+func (h *PushHandler) RegisterDevice(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *device.RegisterDevicePayload) (*device.Device, error) {
+			userID := middleware.GetUserID(c)
+			return h.pushService.RegisterDevice(c, userID, payload)
+		},
+		http.StatusCreated,
+		&device.RegisterDevicePayload{},
+	)(c)
+}
+
+func (h *PushHandler) GetDevices(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *device.GetDevicesPayload) ([]device.Device, error) {
+			userID := middleware.GetUserID(c)
+			return h.pushService.GetDevices(c, userID)
+		},
+		http.StatusOK,
+		&device.GetDevicesPayload{},
+	)(c)
+}
+
+func (h *PushHandler) DeleteDevice(c echo.Context) error {
+	return HandleNoContent(
+		h.Handler,
+		func(c echo.Context, payload *device.DeleteDevicePayload) error {
+			userID := middleware.GetUserID(c)
+			return h.pushService.DeleteDevice(c, userID, payload.ID)
+		},
+		http.StatusNoContent,
+		&device.DeleteDevicePayload{},
+	)(c)
+}
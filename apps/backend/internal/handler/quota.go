@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/quota"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/mabhi256/tasker/internal/service"
+)
+
+type QuotaHandler struct {
+	Handler
+	quotaService *service.QuotaService
+}
+
+func NewQuotaHandler(s *server.Server, quotaService *service.QuotaService) *QuotaHandler {
+	return &QuotaHandler{
+		Handler:      NewHandler(s),
+		quotaService: quotaService,
+	}
+}
+
+func (h *QuotaHandler) GetUsage(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *quota.GetUsagePayload) (*quota.Usage, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			userID := middleware.GetUserID(c)
+			return h.quotaService.GetUsage(c, workspaceID, userID)
+		},
+		http.StatusOK,
+		&quota.GetUsagePayload{},
+	)(c)
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/presence"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/mabhi256/tasker/internal/service"
+)
+
+type RealtimeHandler struct {
+	Handler
+	realtimeService *service.RealtimeService
+}
+
+func NewRealtimeHandler(s *server.Server, realtimeService *service.RealtimeService) *RealtimeHandler {
+	return &RealtimeHandler{
+		Handler:         NewHandler(s),
+		realtimeService: realtimeService,
+	}
+}
+
+// Connect upgrades the request to a websocket that receives the workspace's
+// events and messages sent to the user. It is closed when the session or
+// API key it was opened with is revoked.
+func (h *RealtimeHandler) Connect(c echo.Context) error {
+	userID := middleware.GetUserID(c)
+	sessionID := middleware.GetSessionID(c)
+	apiKeyID := middleware.GetAPIKeyID(c)
+	workspaceID := middleware.GetWorkspaceID(c)
+
+	h.server.Realtime.Handler(userID, sessionID, apiKeyID, workspaceID).ServeHTTP(c.Response(), c.Request())
+	return nil
+}
+
+func (h *RealtimeHandler) GetPresence(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *presence.GetPresencePayload) ([]presence.Presence, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.realtimeService.GetPresence(c, workspaceID)
+		},
+		http.StatusOK,
+		&presence.GetPresencePayload{},
+	)(c)
+}
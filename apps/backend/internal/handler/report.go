@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/accessreport"
+	"github.com/mabhi256/tasker/internal/model/report"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/mabhi256/tasker/internal/service"
+)
+
+type ReportHandler struct {
+	Handler
+	reportService *service.ReportService
+}
+
+func NewReportHandler(s *server.Server, reportService *service.ReportService) *ReportHandler {
+	return &ReportHandler{
+		Handler:       NewHandler(s),
+		reportService: reportService,
+	}
+}
+
+func (h *ReportHandler) CreateReport(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *report.CreateReportPayload) (*report.Report, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			userID := middleware.GetUserID(c)
+			return h.reportService.CreateReport(c, workspaceID, userID, payload)
+		},
+		http.StatusAccepted,
+		&report.CreateReportPayload{},
+	)(c)
+}
+
+func (h *ReportHandler) GetReport(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *report.GetReportPayload) (*report.Report, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			userID := middleware.GetUserID(c)
+			return h.reportService.GetReport(c, workspaceID, userID, payload.ID)
+		},
+		http.StatusOK,
+		&report.GetReportPayload{},
+	)(c)
+}
+
+func (h *ReportHandler) GetAccessReport(c echo.Context) error {
+	if c.QueryParam("format") == "csv" {
+		return HandleFile(
+			h.Handler,
+			func(c echo.Context, payload *accessreport.GetAccessReportPayload) ([]byte, error) {
+				workspaceID := middleware.GetWorkspaceID(c)
+				return h.reportService.GetAccessReportCSV(c, workspaceID)
+			},
+			http.StatusOK,
+			&accessreport.GetAccessReportPayload{},
+			"access-report.csv",
+			"text/csv",
+		)(c)
+	}
+
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *accessreport.GetAccessReportPayload) (*accessreport.AccessReport, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.reportService.GetAccessReport(c, workspaceID)
+		},
+		http.StatusOK,
+		&accessreport.GetAccessReportPayload{},
+	)(c)
+}
+
+func (h *ReportHandler) CreateAccessReport(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *report.CreateReportPayload) (*report.Report, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			userID := middleware.GetUserID(c)
+			return h.reportService.CreateAccessReport(c, workspaceID, userID, payload)
+		},
+		http.StatusAccepted,
+		&report.CreateReportPayload{},
+	)(c)
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/resolve"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/mabhi256/tasker/internal/service"
+)
+
+type ResolveHandler struct {
+	Handler
+	resolveService *service.ResolveService
+}
+
+func NewResolveHandler(s *server.Server, resolveService *service.ResolveService) *ResolveHandler {
+	return &ResolveHandler{
+		Handler:        NewHandler(s),
+		resolveService: resolveService,
+	}
+}
+
+func (h *ResolveHandler) Resolve(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *resolve.ResolvePayload) (*resolve.Response, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.resolveService.Resolve(c, workspaceID, payload)
+		},
+		http.StatusOK,
+		&resolve.ResolvePayload{},
+	)(c)
+}
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/model/admin"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/mabhi256/tasker/internal/service"
+)
+
+type RolloutHandler struct {
+	Handler
+	rolloutService *service.RolloutService
+}
+
+func NewRolloutHandler(s *server.Server, rolloutService *service.RolloutService) *RolloutHandler {
+	return &RolloutHandler{
+		Handler:        NewHandler(s),
+		rolloutService: rolloutService,
+	}
+}
+
+func (h *RolloutHandler) GetRollouts(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *admin.GetRolloutsPayload) ([]admin.Rollout, error) {
+			return h.rolloutService.GetRollouts(c)
+		},
+		http.StatusOK,
+		&admin.GetRolloutsPayload{},
+	)(c)
+}
+
+func (h *RolloutHandler) StartBackfill(c echo.Context) error {
+	return HandleNoContent(
+		h.Handler,
+		func(c echo.Context, payload *admin.RolloutPayload) error {
+			return h.rolloutService.StartBackfill(c, payload.Name)
+		},
+		http.StatusAccepted,
+		&admin.RolloutPayload{},
+	)(c)
+}
+
+func (h *RolloutHandler) VerifyRollout(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *admin.RolloutPayload) (*admin.Rollout, error) {
+			return h.rolloutService.VerifyRollout(c, payload.Name)
+		},
+		http.StatusOK,
+		&admin.RolloutPayload{},
+	)(c)
+}
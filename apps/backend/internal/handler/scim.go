@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/lib/scim"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/service"
+	"github.com/mabhi256/tasker/internal/sqlerr"
+)
+
+// SCIM requests bypass Handle: identity providers send attributes the API
+// doesn't store, which the binder would reject, and expect errors in the
+// SCIM format rather than the API's own.
+
+// maxSCIMBody bounds request bodies; a group replaced with thousands of
+// members is the largest identity providers send
+const maxSCIMBody = 1 << 20
+
+func (h *ProvisioningHandler) GetServiceProviderConfig(c echo.Context) error {
+	return writeSCIM(c, http.StatusOK, scim.NewServiceProviderConfig(service.MaxSCIMResults))
+}
+
+func (h *ProvisioningHandler) GetSCIMUsers(c echo.Context) error {
+	startIndex, count, err := scimListParams(c)
+	if err != nil {
+		return h.writeSCIMError(c, err)
+	}
+
+	result, err := h.provisioningService.GetSCIMUsers(c, middleware.GetWorkspaceID(c), c.QueryParam("filter"),
+		startIndex, count)
+	if err != nil {
+		return h.writeSCIMError(c, err)
+	}
+	return writeSCIM(c, http.StatusOK, result)
+}
+
+func (h *ProvisioningHandler) GetSCIMUser(c echo.Context) error {
+	result, err := h.provisioningService.GetSCIMUser(c, middleware.GetWorkspaceID(c), c.Param("id"))
+	if err != nil {
+		return h.writeSCIMError(c, err)
+	}
+	return writeSCIM(c, http.StatusOK, result)
+}
+
+func (h *ProvisioningHandler) CreateSCIMUser(c echo.Context) error {
+	var input scim.User
+	if err := decodeSCIM(c, &input); err != nil {
+		return h.writeSCIMError(c, err)
+	}
+
+	result, err := h.provisioningService.CreateSCIMUser(c, middleware.GetWorkspaceID(c), &input)
+	if err != nil {
+		return h.writeSCIMError(c, err)
+	}
+	return writeSCIM(c, http.StatusCreated, result)
+}
+
+func (h *ProvisioningHandler) ReplaceSCIMUser(c echo.Context) error {
+	var input scim.User
+	if err := decodeSCIM(c, &input); err != nil {
+		return h.writeSCIMError(c, err)
+	}
+
+	result, err := h.provisioningService.ReplaceSCIMUser(c, middleware.GetWorkspaceID(c), c.Param("id"), &input)
+	if err != nil {
+		return h.writeSCIMError(c, err)
+	}
+	return writeSCIM(c, http.StatusOK, result)
+}
+
+func (h *ProvisioningHandler) PatchSCIMUser(c echo.Context) error {
+	var patch scim.PatchRequest
+	if err := decodeSCIM(c, &patch); err != nil {
+		return h.writeSCIMError(c, err)
+	}
+
+	result, err := h.provisioningService.PatchSCIMUser(c, middleware.GetWorkspaceID(c), c.Param("id"), &patch)
+	if err != nil {
+		return h.writeSCIMError(c, err)
+	}
+	return writeSCIM(c, http.StatusOK, result)
+}
+
+func (h *ProvisioningHandler) DeleteSCIMUser(c echo.Context) error {
+	if err := h.provisioningService.DeleteSCIMUser(c, middleware.GetWorkspaceID(c), c.Param("id")); err != nil {
+		return h.writeSCIMError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *ProvisioningHandler) GetSCIMGroups(c echo.Context) error {
+	startIndex, count, err := scimListParams(c)
+	if err != nil {
+		return h.writeSCIMError(c, err)
+	}
+
+	result, err := h.provisioningService.GetSCIMGroups(c, middleware.GetWorkspaceID(c), c.QueryParam("filter"),
+		startIndex, count)
+	if err != nil {
+		return h.writeSCIMError(c, err)
+	}
+	return writeSCIM(c, http.StatusOK, result)
+}
+
+func (h *ProvisioningHandler) GetSCIMGroup(c echo.Context) error {
+	result, err := h.provisioningService.GetSCIMGroup(c, middleware.GetWorkspaceID(c), c.Param("id"))
+	if err != nil {
+		return h.writeSCIMError(c, err)
+	}
+	return writeSCIM(c, http.StatusOK, result)
+}
+
+func (h *ProvisioningHandler) CreateSCIMGroup(c echo.Context) error {
+	var input scim.Group
+	if err := decodeSCIM(c, &input); err != nil {
+		return h.writeSCIMError(c, err)
+	}
+
+	result, err := h.provisioningService.CreateSCIMGroup(c, middleware.GetWorkspaceID(c), &input)
+	if err != nil {
+		return h.writeSCIMError(c, err)
+	}
+	return writeSCIM(c, http.StatusCreated, result)
+}
+
+func (h *ProvisioningHandler) ReplaceSCIMGroup(c echo.Context) error {
+	var input scim.Group
+	if err := decodeSCIM(c, &input); err != nil {
+		return h.writeSCIMError(c, err)
+	}
+
+	result, err := h.provisioningService.ReplaceSCIMGroup(c, middleware.GetWorkspaceID(c), c.Param("id"), &input)
+	if err != nil {
+		return h.writeSCIMError(c, err)
+	}
+	return writeSCIM(c, http.StatusOK, result)
+}
+
+func (h *ProvisioningHandler) PatchSCIMGroup(c echo.Context) error {
+	var patch scim.PatchRequest
+	if err := decodeSCIM(c, &patch); err != nil {
+		return h.writeSCIMError(c, err)
+	}
+
+	result, err := h.provisioningService.PatchSCIMGroup(c, middleware.GetWorkspaceID(c), c.Param("id"), &patch)
+	if err != nil {
+		return h.writeSCIMError(c, err)
+	}
+	return writeSCIM(c, http.StatusOK, result)
+}
+
+func (h *ProvisioningHandler) DeleteSCIMGroup(c echo.Context) error {
+	if err := h.provisioningService.DeleteSCIMGroup(c, middleware.GetWorkspaceID(c), c.Param("id")); err != nil {
+		return h.writeSCIMError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// writeSCIMError responds with err as a SCIM error. The API's own errors
+// keep their status and message; conflicts are uniqueness errors.
+func (h *ProvisioningHandler) writeSCIMError(c echo.Context, err error) error {
+	var scimErr *scim.Error
+	if !errors.As(err, &scimErr) {
+		var httpErr *errs.HTTPError
+		if errors.As(sqlerr.HandleError(err), &httpErr) {
+			scimType := ""
+			if httpErr.Status == http.StatusConflict {
+				scimType = scim.ErrorTypeUniqueness
+			}
+			scimErr = scim.NewError(httpErr.Status, scimType, httpErr.Message)
+		} else {
+			scimErr = scim.NewError(http.StatusInternalServerError, "", "Internal server error")
+		}
+	}
+
+	if scimErr.StatusCode() >= http.StatusInternalServerError {
+		middleware.GetLogger(c).Error().
+			Err(err).
+			Str("path", c.Path()).
+			Msg("SCIM request failed")
+	}
+
+	return middleware.WriteSCIMError(c, scimErr)
+}
+
+func writeSCIM(c echo.Context, status int, result any) error {
+	c.Response().Header().Set(echo.HeaderContentType, scim.ContentType)
+	return c.JSON(status, result)
+}
+
+// decodeSCIM decodes a request body, ignoring attributes dst doesn't have
+func decodeSCIM(c echo.Context, dst any) error {
+	if err := json.NewDecoder(io.LimitReader(c.Request().Body, maxSCIMBody)).Decode(dst); err != nil {
+		return scim.NewError(http.StatusBadRequest, scim.ErrorTypeInvalidSyntax, "The request body is not valid JSON")
+	}
+	return nil
+}
+
+// scimListParams reads the 1-based startIndex and the count of a list
+// request, which default to the first page of the most results
+func scimListParams(c echo.Context) (int, int, error) {
+	startIndex, count := 1, service.MaxSCIMResults
+
+	if raw := c.QueryParam("startIndex"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return 0, 0, scim.NewError(http.StatusBadRequest, scim.ErrorTypeInvalidValue, "startIndex must be an integer")
+		}
+		startIndex = n
+	}
+
+	if raw := c.QueryParam("count"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return 0, 0, scim.NewError(http.StatusBadRequest, scim.ErrorTypeInvalidValue, "count must be an integer")
+		}
+		count = n
+	}
+
+	return startIndex, count, nil
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/todo"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/mabhi256/tasker/internal/service"
+)
+
+type SearchHandler struct {
+	Handler
+	searchService *service.SearchService
+}
+
+func NewSearchHandler(s *server.Server, searchService *service.SearchService) *SearchHandler {
+	return &SearchHandler{
+		Handler:       NewHandler(s),
+		searchService: searchService,
+	}
+}
+
+func (h *SearchHandler) SearchTodos(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, query *todo.SearchTodosQuery) (*todo.SearchResponse, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.searchService.SearchTodos(c, workspaceID, query)
+		},
+		http.StatusOK,
+		&todo.SearchTodosQuery{},
+	)(c)
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/session"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/mabhi256/tasker/internal/service"
+)
+
+type SessionHandler struct {
+	Handler
+	sessionService *service.SessionService
+}
+
+func NewSessionHandler(s *server.Server, sessionService *service.SessionService) *SessionHandler {
+	return &SessionHandler{
+		Handler:        NewHandler(s),
+		sessionService: sessionService,
+	}
+}
+
+func (h *SessionHandler) GetSessions(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *session.GetSessionsPayload) ([]session.Session, error) {
+			userID := middleware.GetUserID(c)
+			sessionID := middleware.GetSessionID(c)
+			return h.sessionService.GetSessions(c, userID, sessionID)
+		},
+		http.StatusOK,
+		&session.GetSessionsPayload{},
+	)(c)
+}
+
+func (h *SessionHandler) RevokeSession(c echo.Context) error {
+	return HandleNoContent(
+		h.Handler,
+		func(c echo.Context, payload *session.RevokeSessionPayload) error {
+			userID := middleware.GetUserID(c)
+			return h.sessionService.RevokeSession(c, userID, payload)
+		},
+		http.StatusNoContent,
+		&session.RevokeSessionPayload{},
+	)(c)
+}
+
+func (h *SessionHandler) RevokeUserSessions(c echo.Context) error {
+	return HandleNoContent(
+		h.Handler,
+		func(c echo.Context, payload *session.RevokeUserSessionsPayload) error {
+			return h.sessionService.RevokeUserSessions(c, payload)
+		},
+		http.StatusNoContent,
+		&session.RevokeUserSessionsPayload{},
+	)(c)
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/snapshot"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/mabhi256/tasker/internal/service"
+)
+
+type SnapshotHandler struct {
+	Handler
+	snapshotService *service.SnapshotService
+}
+
+func NewSnapshotHandler(s *server.Server, snapshotService *service.SnapshotService) *SnapshotHandler {
+	return &SnapshotHandler{
+		Handler:         NewHandler(s),
+		snapshotService: snapshotService,
+	}
+}
+
+func (h *SnapshotHandler) GetSnapshot(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *snapshot.GetSnapshotPayload) (*snapshot.Snapshot, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			userID := middleware.GetUserID(c)
+			return h.snapshotService.CreateSnapshot(c, workspaceID, userID, payload)
+		},
+		http.StatusOK,
+		&snapshot.GetSnapshotPayload{},
+	)(c)
+}
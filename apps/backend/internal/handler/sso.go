@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/sso"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/mabhi256/tasker/internal/service"
+)
+
+// SSOHandler lets workspace admins configure single sign-on, and members
+// sign in with the workspace's identity provider
+type SSOHandler struct {
+	Handler
+	ssoService *service.SSOService
+}
+
+func NewSSOHandler(s *server.Server, ssoService *service.SSOService) *SSOHandler {
+	return &SSOHandler{
+		Handler:    NewHandler(s),
+		ssoService: ssoService,
+	}
+}
+
+func (h *SSOHandler) GetConfig(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *sso.GetConfigPayload) (*sso.Config, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.ssoService.GetConfig(c, workspaceID)
+		},
+		http.StatusOK,
+		&sso.GetConfigPayload{},
+	)(c)
+}
+
+func (h *SSOHandler) UpsertConfig(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *sso.UpsertConfigPayload) (*sso.Config, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			userID := middleware.GetUserID(c)
+			return h.ssoService.UpsertConfig(c, workspaceID, userID, payload)
+		},
+		http.StatusOK,
+		&sso.UpsertConfigPayload{},
+	)(c)
+}
+
+func (h *SSOHandler) DeleteConfig(c echo.Context) error {
+	return HandleNoContent(
+		h.Handler,
+		func(c echo.Context, payload *sso.DeleteConfigPayload) error {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.ssoService.DeleteConfig(c, workspaceID)
+		},
+		http.StatusNoContent,
+		&sso.DeleteConfigPayload{},
+	)(c)
+}
+
+func (h *SSOHandler) VerifyDomain(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *sso.VerifyDomainPayload) (*sso.Config, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.ssoService.VerifyDomain(c, workspaceID, payload)
+		},
+		http.StatusOK,
+		&sso.VerifyDomainPayload{},
+	)(c)
+}
+
+func (h *SSOHandler) GetLogin(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *sso.GetLoginPayload) (*sso.Login, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			sessionID := middleware.GetSessionID(c)
+			return h.ssoService.GetLogin(c, workspaceID, sessionID)
+		},
+		http.StatusOK,
+		&sso.GetLoginPayload{},
+	)(c)
+}
+
+func (h *SSOHandler) Verify(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *sso.VerifyPayload) (*sso.Verification, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			userID := middleware.GetUserID(c)
+			sessionID := middleware.GetSessionID(c)
+			return h.ssoService.Verify(c, workspaceID, userID, sessionID, payload)
+		},
+		http.StatusOK,
+		&sso.VerifyPayload{},
+	)(c)
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/tag"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/mabhi256/tasker/internal/service"
+)
+
+type TagHandler struct {
+	Handler
+	tagService *service.TagService
+}
+
+func NewTagHandler(s *server.Server, tagService *service.TagService) *TagHandler {
+	return &TagHandler{
+		Handler:    NewHandler(s),
+		tagService: tagService,
+	}
+}
+
+func (h *TagHandler) RenameTag(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *tag.RenameTagPayload) (*tag.Change, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.tagService.RenameTag(c, workspaceID, payload)
+		},
+		http.StatusOK,
+		&tag.RenameTagPayload{},
+	)(c)
+}
+
+func (h *TagHandler) MergeTag(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *tag.MergeTagPayload) (*tag.Change, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.tagService.MergeTag(c, workspaceID, payload)
+		},
+		http.StatusOK,
+		&tag.MergeTagPayload{},
+	)(c)
+}
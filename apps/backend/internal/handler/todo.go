@@ -1,10 +1,14 @@
 package handler
 
 import (
+	"errors"
+	"io"
 	"net/http"
+	"strings"
 
 	"github.com/labstack/echo/v4"
 	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/lib/content"
 	"github.com/mabhi256/tasker/internal/middleware"
 	"github.com/mabhi256/tasker/internal/model"
 	"github.com/mabhi256/tasker/internal/model/todo"
@@ -28,8 +32,9 @@ func (h *TodoHandler) CreateTodo(c echo.Context) error {
 	return Handle(
 		h.Handler,
 		func(c echo.Context, payload *todo.CreateTodoPayload) (*todo.Todo, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
 			userID := middleware.GetUserID(c)
-			return h.todoService.CreateTodo(c, userID, payload)
+			return h.todoService.CreateTodo(c, workspaceID, userID, payload)
 		},
 		http.StatusCreated,
 		&todo.CreateTodoPayload{},
@@ -40,8 +45,8 @@ func (h *TodoHandler) GetTodoByID(c echo.Context) error {
 	return Handle(
 		h.Handler,
 		func(c echo.Context, payload *todo.GetTodoByIDPayload) (*todo.PopulatedTodo, error) {
-			userID := middleware.GetUserID(c)
-			return h.todoService.GetTodoByID(c, userID, payload.ID)
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.todoService.GetTodoByID(c, workspaceID, payload.ID, payload.Render != nil)
 		},
 		http.StatusOK,
 		&todo.GetTodoByIDPayload{},
@@ -52,8 +57,8 @@ func (h *TodoHandler) GetTodos(c echo.Context) error {
 	return Handle(
 		h.Handler,
 		func(c echo.Context, query *todo.GetTodosQuery) (*model.PaginatedResponse[todo.PopulatedTodo], error) {
-			userID := middleware.GetUserID(c)
-			return h.todoService.GetTodos(c, userID, query)
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.todoService.GetTodos(c, workspaceID, query)
 		},
 		http.StatusOK,
 		&todo.GetTodosQuery{},
@@ -64,8 +69,8 @@ func (h *TodoHandler) UpdateTodo(c echo.Context) error {
 	return Handle(
 		h.Handler,
 		func(c echo.Context, payload *todo.UpdateTodoPayload) (*todo.Todo, error) {
-			userID := middleware.GetUserID(c)
-			return h.todoService.UpdateTodo(c, userID, payload)
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.todoService.UpdateTodo(c, workspaceID, payload)
 		},
 		http.StatusOK,
 		&todo.UpdateTodoPayload{},
@@ -76,8 +81,8 @@ func (h *TodoHandler) DeleteTodo(c echo.Context) error {
 	return HandleNoContent(
 		h.Handler,
 		func(c echo.Context, payload *todo.DeleteTodoPayload) error {
-			userID := middleware.GetUserID(c)
-			return h.todoService.DeleteTodo(c, userID, payload.ID)
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.todoService.DeleteTodo(c, workspaceID, payload.ID)
 		},
 		http.StatusNoContent,
 		&todo.DeleteTodoPayload{},
@@ -88,8 +93,8 @@ func (h *TodoHandler) GetTodoStats(c echo.Context) error {
 	return Handle(
 		h.Handler,
 		func(c echo.Context, payload *todo.GetTodoStatsPayload) (*todo.TodoStats, error) {
-			userID := middleware.GetUserID(c)
-			return h.todoService.GetTodoStats(c, userID)
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.todoService.GetTodoStats(c, workspaceID)
 		},
 		http.StatusOK,
 		&todo.GetTodoStatsPayload{},
@@ -100,6 +105,7 @@ func (h *TodoHandler) UploadTodoAttachment(c echo.Context) error {
 	return Handle(
 		h.Handler,
 		func(c echo.Context, payload *todo.UploadTodoAttachmentPayload) (*todo.TodoAttachment, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
 			userID := middleware.GetUserID(c)
 
 			// 400 - Can't parse the request as multipart form
@@ -118,19 +124,83 @@ func (h *TodoHandler) UploadTodoAttachment(c echo.Context) error {
 				return nil, errs.NewUnprocessableError("only one file allowed per upload", false, nil, nil, nil)
 			}
 
-			return h.todoService.UploadTodoAttachment(c, userID, payload.TodoID, files[0])
+			return h.todoService.UploadTodoAttachment(c, workspaceID, userID, payload.TodoID, files[0])
 		},
 		http.StatusCreated,
 		&todo.UploadTodoAttachmentPayload{},
 	)(c)
 }
 
+func (h *TodoHandler) PasteTodoImage(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *todo.PasteTodoImagePayload) (*todo.PastedImage, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			userID := middleware.GetUserID(c)
+
+			var name string
+			if payload.Name != nil {
+				name = *payload.Name
+			}
+
+			var data []byte
+			if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+				form, err := c.MultipartForm()
+				if err != nil {
+					return nil, errs.NewBadRequestError("multipart form not found", false, nil, nil, nil)
+				}
+
+				files := form.File["file"]
+				if len(files) != 1 {
+					return nil, errs.NewUnprocessableError("exactly one image file is required", false, nil, nil, nil)
+				}
+				if files[0].Size > content.MaxImageSize {
+					code := "IMAGE_TOO_LARGE"
+					return nil, errs.NewUnprocessableError(content.ErrImageTooLarge.Error(), false, &code, nil, nil)
+				}
+				if name == "" {
+					name = files[0].Filename
+				}
+
+				src, err := files[0].Open()
+				if err != nil {
+					return nil, errs.NewBadRequestError("failed to open uploaded file", false, nil, nil, nil)
+				}
+				defer src.Close()
+
+				data, err = io.ReadAll(src)
+				if err != nil {
+					return nil, errs.NewBadRequestError("failed to read uploaded file", false, nil, nil, nil)
+				}
+			} else {
+				if payload.Data == nil {
+					return nil, errs.NewUnprocessableError("no image found", false, nil, nil, nil)
+				}
+
+				var err error
+				data, err = content.DecodeImage(*payload.Data)
+				if err != nil {
+					code := "INVALID_IMAGE"
+					if errors.Is(err, content.ErrImageTooLarge) {
+						code = "IMAGE_TOO_LARGE"
+					}
+					return nil, errs.NewUnprocessableError(err.Error(), false, &code, nil, nil)
+				}
+			}
+
+			return h.todoService.PasteTodoImage(c, workspaceID, userID, payload.TodoID, name, data)
+		},
+		http.StatusCreated,
+		&todo.PasteTodoImagePayload{},
+	)(c)
+}
+
 func (h *TodoHandler) DeleteTodoAttachment(c echo.Context) error {
 	return HandleNoContent(
 		h.Handler,
 		func(c echo.Context, payload *todo.DeleteTodoAttachmentPayload) error {
-			userID := middleware.GetUserID(c)
-			return h.todoService.DeleteTodoAttachment(c, userID, payload.TodoID, payload.AttachmentID)
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.todoService.DeleteTodoAttachment(c, workspaceID, payload.TodoID, payload.AttachmentID)
 		},
 		http.StatusNoContent,
 		&todo.DeleteTodoAttachmentPayload{},
@@ -144,8 +214,8 @@ func (h *TodoHandler) GetAttachmentPresignedURL(c echo.Context) error {
 			URL string `json:"url"`
 		}, error,
 		) {
-			userID := middleware.GetUserID(c)
-			url, err := h.todoService.GetAttachmentPresignedURL(c, userID, payload.TodoID, payload.AttachmentID)
+			workspaceID := middleware.GetWorkspaceID(c)
+			url, err := h.todoService.GetAttachmentPresignedURL(c, workspaceID, payload.TodoID, payload.AttachmentID)
 			if err != nil {
 				return nil, err
 			}
@@ -157,3 +227,24 @@ func (h *TodoHandler) GetAttachmentPresignedURL(c echo.Context) error {
 		&todo.GetAttachmentPresignedURLPayload{},
 	)(c)
 }
+
+func (h *TodoHandler) GetAttachmentThumbnail(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *todo.GetAttachmentThumbnailPayload) (*struct {
+			URL string `json:"url"`
+		}, error,
+		) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			url, err := h.todoService.GetAttachmentThumbnailURL(c, workspaceID, payload.TodoID, payload.AttachmentID)
+			if err != nil {
+				return nil, err
+			}
+			return &struct {
+				URL string `json:"url"`
+			}{URL: url}, nil
+		},
+		http.StatusOK,
+		&todo.GetAttachmentThumbnailPayload{},
+	)(c)
+}
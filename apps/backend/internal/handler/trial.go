@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/trial"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/mabhi256/tasker/internal/service"
+)
+
+type TrialHandler struct {
+	Handler
+	trialService *service.TrialService
+}
+
+func NewTrialHandler(s *server.Server, trialService *service.TrialService) *TrialHandler {
+	return &TrialHandler{
+		Handler:      NewHandler(s),
+		trialService: trialService,
+	}
+}
+
+func (h *TrialHandler) StartTrial(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *trial.StartTrialPayload) (*trial.TrialWithToken, error) {
+			return h.trialService.StartTrial(c)
+		},
+		http.StatusCreated,
+		&trial.StartTrialPayload{},
+	)(c)
+}
+
+func (h *TrialHandler) ClaimTrial(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *trial.ClaimTrialPayload) (*trial.Claimed, error) {
+			userID := middleware.GetUserID(c)
+			return h.trialService.ClaimTrial(c, userID, payload)
+		},
+		http.StatusOK,
+		&trial.ClaimTrialPayload{},
+	)(c)
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model"
+	"github.com/mabhi256/tasker/internal/model/webhook"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/mabhi256/tasker/internal/service"
+)
+
+type WebhookHandler struct {
+	Handler
+	webhookService *service.WebhookService
+}
+
+func NewWebhookHandler(s *server.Server, webhookService *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		Handler:        NewHandler(s),
+		webhookService: webhookService,
+	}
+}
+
+func (h *WebhookHandler) CreateWebhook(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *webhook.CreateWebhookPayload) (*webhook.WebhookWithSecret, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			userID := middleware.GetUserID(c)
+			return h.webhookService.CreateWebhook(c, workspaceID, userID, payload)
+		},
+		http.StatusCreated,
+		&webhook.CreateWebhookPayload{},
+	)(c)
+}
+
+func (h *WebhookHandler) GetWebhooks(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *webhook.GetWebhooksPayload) ([]webhook.Webhook, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.webhookService.GetWebhooks(c, workspaceID)
+		},
+		http.StatusOK,
+		&webhook.GetWebhooksPayload{},
+	)(c)
+}
+
+func (h *WebhookHandler) GetWebhookByID(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *webhook.GetWebhookByIDPayload) (*webhook.Webhook, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.webhookService.GetWebhookByID(c, workspaceID, payload.ID)
+		},
+		http.StatusOK,
+		&webhook.GetWebhookByIDPayload{},
+	)(c)
+}
+
+func (h *WebhookHandler) UpdateWebhook(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *webhook.UpdateWebhookPayload) (*webhook.Webhook, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.webhookService.UpdateWebhook(c, workspaceID, payload)
+		},
+		http.StatusOK,
+		&webhook.UpdateWebhookPayload{},
+	)(c)
+}
+
+func (h *WebhookHandler) DeleteWebhook(c echo.Context) error {
+	return HandleNoContent(
+		h.Handler,
+		func(c echo.Context, payload *webhook.DeleteWebhookPayload) error {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.webhookService.DeleteWebhook(c, workspaceID, payload.ID)
+		},
+		http.StatusNoContent,
+		&webhook.DeleteWebhookPayload{},
+	)(c)
+}
+
+func (h *WebhookHandler) RotateSecret(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *webhook.RotateSecretPayload) (*webhook.WebhookWithSecret, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.webhookService.RotateSecret(c, workspaceID, payload.ID)
+		},
+		http.StatusOK,
+		&webhook.RotateSecretPayload{},
+	)(c)
+}
+
+func (h *WebhookHandler) GetDeliveries(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, query *webhook.GetDeliveriesQuery) (*model.PaginatedResponse[webhook.Delivery], error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.webhookService.GetDeliveries(c, workspaceID, query)
+		},
+		http.StatusOK,
+		&webhook.GetDeliveriesQuery{},
+	)(c)
+}
+
+func (h *WebhookHandler) Redeliver(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *webhook.RedeliverPayload) (*webhook.Delivery, error) {
+			workspaceID := middleware.GetWorkspaceID(c)
+			return h.webhookService.Redeliver(c, workspaceID, payload)
+		},
+		http.StatusAccepted,
+		&webhook.RedeliverPayload{},
+	)(c)
+}
+
+func (h *WebhookHandler) GetEventCatalog(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *webhook.GetEventCatalogPayload) (*webhook.EventCatalog, error) {
+			return h.webhookService.GetEventCatalog(c)
+		},
+		http.StatusOK,
+		&webhook.GetEventCatalogPayload{},
+	)(c)
+}
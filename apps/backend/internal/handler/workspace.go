@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/workspace"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/mabhi256/tasker/internal/service"
+)
+
+type WorkspaceHandler struct {
+	Handler
+	workspaceService *service.WorkspaceService
+}
+
+func NewWorkspaceHandler(s *server.Server, workspaceService *service.WorkspaceService) *WorkspaceHandler {
+	return &WorkspaceHandler{
+		Handler:          NewHandler(s),
+		workspaceService: workspaceService,
+	}
+}
+
+func (h *WorkspaceHandler) CreateWorkspace(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *workspace.CreateWorkspacePayload) (*workspace.Workspace, error) {
+			userID := middleware.GetUserID(c)
+			return h.workspaceService.CreateWorkspace(c, userID, payload)
+		},
+		http.StatusCreated,
+		&workspace.CreateWorkspacePayload{},
+	)(c)
+}
+
+func (h *WorkspaceHandler) GetWorkspaces(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *workspace.GetWorkspacesPayload) ([]workspace.WorkspaceWithRole, error) {
+			userID := middleware.GetUserID(c)
+			return h.workspaceService.GetWorkspaces(c, userID)
+		},
+		http.StatusOK,
+		&workspace.GetWorkspacesPayload{},
+	)(c)
+}
+
+func (h *WorkspaceHandler) GetWorkspaceByID(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *workspace.GetWorkspaceByIDPayload) (*workspace.Workspace, error) {
+			return h.workspaceService.GetWorkspaceByID(c, payload.ID)
+		},
+		http.StatusOK,
+		&workspace.GetWorkspaceByIDPayload{},
+	)(c)
+}
+
+func (h *WorkspaceHandler) UpdateWorkspace(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *workspace.UpdateWorkspacePayload) (*workspace.Workspace, error) {
+			return h.workspaceService.UpdateWorkspace(c, payload)
+		},
+		http.StatusOK,
+		&workspace.UpdateWorkspacePayload{},
+	)(c)
+}
+
+func (h *WorkspaceHandler) DeleteWorkspace(c echo.Context) error {
+	return HandleNoContent(
+		h.Handler,
+		func(c echo.Context, payload *workspace.DeleteWorkspacePayload) error {
+			return h.workspaceService.DeleteWorkspace(c, payload.ID)
+		},
+		http.StatusNoContent,
+		&workspace.DeleteWorkspacePayload{},
+	)(c)
+}
+
+func (h *WorkspaceHandler) GetMembers(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *workspace.GetMembersPayload) ([]workspace.Member, error) {
+			return h.workspaceService.GetMembers(c, payload.WorkspaceID)
+		},
+		http.StatusOK,
+		&workspace.GetMembersPayload{},
+	)(c)
+}
+
+func (h *WorkspaceHandler) AddMember(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *workspace.AddMemberPayload) (*workspace.Member, error) {
+			return h.workspaceService.AddMember(c, payload)
+		},
+		http.StatusCreated,
+		&workspace.AddMemberPayload{},
+	)(c)
+}
+
+func (h *WorkspaceHandler) UpdateMember(c echo.Context) error {
+	return Handle(
+		h.Handler,
+		func(c echo.Context, payload *workspace.UpdateMemberPayload) (*workspace.Member, error) {
+			return h.workspaceService.UpdateMember(c, payload)
+		},
+		http.StatusOK,
+		&workspace.UpdateMemberPayload{},
+	)(c)
+}
+
+func (h *WorkspaceHandler) RemoveMember(c echo.Context) error {
+	return HandleNoContent(
+		h.Handler,
+		func(c echo.Context, payload *workspace.RemoveMemberPayload) error {
+			userID := middleware.GetUserID(c)
+			return h.workspaceService.RemoveMember(c, userID, payload)
+		},
+		http.StatusNoContent,
+		&workspace.RemoveMemberPayload{},
+	)(c)
+}
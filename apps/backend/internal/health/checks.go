@@ -0,0 +1,41 @@
+package health
+
+import (
+	"context"
+
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// Database pings the primary database
+func Database(pool *pgxpool.Pool) Check {
+	return pool.Ping
+}
+
+func Redis(client *redis.Client) Check {
+	return func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	}
+}
+
+// Queue lists the job queues, which reaches the broker the way the job
+// server does
+func Queue(inspector *asynq.Inspector) Check {
+	return func(ctx context.Context) error {
+		_, err := inspector.Queues()
+		return err
+	}
+}
+
+// BucketHeader is the part of the storage client the storage check uses
+type BucketHeader interface {
+	HeadBucket(ctx context.Context, bucket string) error
+}
+
+// Storage checks that the bucket exists and the credentials can reach it
+func Storage(client BucketHeader, bucket string) Check {
+	return func(ctx context.Context) error {
+		return client.HeadBucket(ctx, bucket)
+	}
+}
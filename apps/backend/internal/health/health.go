@@ -0,0 +1,312 @@
+// Package health checks that the service's dependencies are reachable, for
+// the liveness, readiness and startup probes.
+//
+// Checks are registered by name and only run when the configuration lists
+// them. When polling is enabled they run in the background at the configured
+// interval, and probes answer from the latest results, so a burst of probes
+// never becomes a burst of pings. Otherwise they run when a probe asks.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mabhi256/tasker/internal/config"
+	"github.com/mabhi256/tasker/internal/logging"
+	"github.com/rs/zerolog"
+)
+
+// Names of the checks the configuration can list
+const (
+	CheckDatabase = "database"
+	CheckRedis    = "redis"
+	CheckQueue    = "queue"
+	CheckStorage  = "storage"
+)
+
+type Status string
+
+const (
+	StatusHealthy   Status = "healthy"
+	StatusUnhealthy Status = "unhealthy"
+	// StatusStarting is reported by the startup probe until every check has
+	// passed once
+	StatusStarting Status = "starting"
+)
+
+// Check returns an error when the dependency it checks can't be reached
+type Check func(ctx context.Context) error
+
+// Result is the outcome of a check's latest run
+type Result struct {
+	Status       Status    `json:"status"`
+	ResponseTime string    `json:"response_time"`
+	Error        string    `json:"error,omitempty"`
+	CheckedAt    time.Time `json:"checked_at"`
+}
+
+// Report is the overall status along with the result of each check
+type Report struct {
+	Status    Status            `json:"status"`
+	Timestamp time.Time         `json:"timestamp"`
+	Checks    map[string]Result `json:"checks"`
+}
+
+// Healthy reports whether the probe the report answers passed
+func (r Report) Healthy() bool {
+	return r.Status == StatusHealthy
+}
+
+type Monitor struct {
+	cfg           config.HealthCheckConfig
+	logger        *zerolog.Logger
+	loggerService *logging.LoggerService
+
+	names  []string
+	checks map[string]Check
+
+	mu      sync.RWMutex
+	results map[string]Result
+	// passed records the checks that have passed at least once
+	passed map[string]bool
+
+	// polling is set while the background loop keeps the results current
+	polling atomic.Bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+func New(cfg config.HealthCheckConfig, logger *zerolog.Logger, loggerService *logging.LoggerService) *Monitor {
+	return &Monitor{
+		cfg:           cfg,
+		logger:        logger,
+		loggerService: loggerService,
+		checks:        make(map[string]Check),
+		results:       make(map[string]Result),
+		passed:        make(map[string]bool),
+	}
+}
+
+// Register adds a check, unless the configuration doesn't list its name.
+// Checks must be registered before Start.
+func (m *Monitor) Register(name string, check Check) {
+	if !slices.Contains(m.cfg.Checks, name) {
+		return
+	}
+	if _, ok := m.checks[name]; !ok {
+		m.names = append(m.names, name)
+	}
+	m.checks[name] = check
+}
+
+// Start runs the checks now and then at the configured interval, when
+// polling is enabled
+func (m *Monitor) Start() {
+	if !m.cfg.Enabled || m.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	m.done = make(chan struct{})
+	m.polling.Store(true)
+
+	go m.run(ctx)
+}
+
+func (m *Monitor) Stop() {
+	if m.cancel == nil {
+		return
+	}
+
+	m.polling.Store(false)
+	m.cancel()
+	<-m.done
+	m.cancel = nil
+}
+
+func (m *Monitor) run(ctx context.Context) {
+	defer close(m.done)
+
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		m.Check(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check runs every check now, concurrently, and reports their results
+func (m *Monitor) Check(ctx context.Context) Report {
+	results := make(map[string]Result, len(m.names))
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for _, name := range m.names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := m.runCheck(ctx, name, m.checks[name])
+
+			mu.Lock()
+			results[name] = result
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	m.record(results)
+
+	return newReport(results)
+}
+
+// runCheck runs a check bounded by the configured timeout. Checks that
+// ignore their context are abandoned once it expires.
+func (m *Monitor) runCheck(ctx context.Context, name string, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s", m.cfg.Timeout)
+	}
+
+	result := Result{
+		Status:       StatusHealthy,
+		ResponseTime: time.Since(start).String(),
+		CheckedAt:    time.Now().UTC(),
+	}
+	if err != nil {
+		result.Status = StatusUnhealthy
+		result.Error = err.Error()
+
+		if m.loggerService != nil && m.loggerService.GetApplication() != nil {
+			m.loggerService.GetApplication().RecordCustomEvent(
+				"HealthCheckError", map[string]any{
+					"check_type":       name,
+					"operation":        "health_check",
+					"error_type":       name + "_unhealthy",
+					"response_time_ms": time.Since(start).Milliseconds(),
+					"error_message":    err.Error(),
+				})
+		}
+	}
+
+	return result
+}
+
+// record keeps the latest results, logging the checks whose status changed
+func (m *Monitor) record(results map[string]Result) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for name, result := range results {
+		previous, seen := m.results[name]
+		m.results[name] = result
+		if result.Status == StatusHealthy {
+			m.passed[name] = true
+		}
+
+		if seen && previous.Status == result.Status {
+			continue
+		}
+		if result.Status == StatusHealthy {
+			m.logger.Info().Str("check", name).Str("response_time", result.ResponseTime).Msg("health check passed")
+		} else {
+			m.logger.Error().Str("check", name).Str("error", result.Error).Msg("health check failed")
+		}
+	}
+}
+
+// latest returns the results of the checks' latest runs, running them when
+// they aren't kept current in the background or haven't run yet
+func (m *Monitor) latest(ctx context.Context) map[string]Result {
+	if !m.polling.Load() {
+		return m.Check(ctx).Checks
+	}
+
+	m.mu.RLock()
+	results := make(map[string]Result, len(m.results))
+	for name, result := range m.results {
+		results[name] = result
+	}
+	m.mu.RUnlock()
+
+	if len(results) < len(m.names) {
+		return m.Check(ctx).Checks
+	}
+	return results
+}
+
+// Liveness reports whether the process is alive. It checks no dependencies,
+// since restarting the process wouldn't bring them back.
+func (m *Monitor) Liveness() Report {
+	return Report{
+		Status:    StatusHealthy,
+		Timestamp: time.Now().UTC(),
+		Checks:    map[string]Result{},
+	}
+}
+
+// Readiness reports whether every check passed its latest run, so the
+// instance can take traffic
+func (m *Monitor) Readiness(ctx context.Context) Report {
+	return newReport(m.latest(ctx))
+}
+
+// Startup reports whether every check has passed at least once. It stays
+// healthy after that, leaving later failures to the readiness probe.
+func (m *Monitor) Startup(ctx context.Context) Report {
+	report := newReport(m.latest(ctx))
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, name := range m.names {
+		if !m.passed[name] {
+			report.Status = StatusStarting
+			return report
+		}
+	}
+	report.Status = StatusHealthy
+
+	return report
+}
+
+func newReport(results map[string]Result) Report {
+	status := StatusHealthy
+	for _, result := range results {
+		if result.Status != StatusHealthy {
+			status = StatusUnhealthy
+		}
+	}
+
+	return Report{
+		Status:    status,
+		Timestamp: time.Now().UTC(),
+		Checks:    results,
+	}
+}
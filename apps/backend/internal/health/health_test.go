@@ -0,0 +1,129 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mabhi256/tasker/internal/config"
+	"github.com/mabhi256/tasker/internal/health"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMonitor(enabled bool, interval time.Duration, checks ...string) *health.Monitor {
+	logger := zerolog.Nop()
+	return health.New(config.HealthCheckConfig{
+		Enabled:  enabled,
+		Interval: interval,
+		Timeout:  50 * time.Millisecond,
+		Checks:   checks,
+	}, &logger, nil)
+}
+
+// toggle returns a check that fails while failing is set, counting its runs
+func toggle(failing *atomic.Bool, runs *atomic.Int64) health.Check {
+	return func(ctx context.Context) error {
+		runs.Add(1)
+		if failing.Load() {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+}
+
+func TestCheckOnlyRunsConfiguredChecks(t *testing.T) {
+	m := newMonitor(false, time.Minute, health.CheckDatabase, health.CheckRedis)
+
+	ok := func(ctx context.Context) error { return nil }
+	m.Register(health.CheckDatabase, ok)
+	m.Register(health.CheckRedis, func(ctx context.Context) error { return errors.New("connection refused") })
+	m.Register(health.CheckStorage, ok)
+
+	report := m.Check(context.Background())
+	assert.Equal(t, health.StatusUnhealthy, report.Status)
+	require.Len(t, report.Checks, 2)
+	assert.Equal(t, health.StatusHealthy, report.Checks[health.CheckDatabase].Status)
+	assert.Equal(t, health.StatusUnhealthy, report.Checks[health.CheckRedis].Status)
+	assert.Equal(t, "connection refused", report.Checks[health.CheckRedis].Error)
+}
+
+func TestCheckTimesOut(t *testing.T) {
+	m := newMonitor(false, time.Minute, health.CheckQueue)
+
+	// A check that ignores its context is abandoned
+	m.Register(health.CheckQueue, func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	start := time.Now()
+	report := m.Check(context.Background())
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, health.StatusUnhealthy, report.Status)
+	assert.Contains(t, report.Checks[health.CheckQueue].Error, "timed out")
+}
+
+func TestStartupPassesOnceEveryCheckHas(t *testing.T) {
+	m := newMonitor(false, time.Minute, health.CheckDatabase)
+
+	var failing atomic.Bool
+	var runs atomic.Int64
+	failing.Store(true)
+	m.Register(health.CheckDatabase, toggle(&failing, &runs))
+
+	ctx := context.Background()
+	assert.Equal(t, health.StatusStarting, m.Startup(ctx).Status)
+
+	failing.Store(false)
+	assert.Equal(t, health.StatusHealthy, m.Startup(ctx).Status)
+
+	// Later failures are left to the readiness probe
+	failing.Store(true)
+	assert.Equal(t, health.StatusHealthy, m.Startup(ctx).Status)
+	assert.Equal(t, health.StatusUnhealthy, m.Readiness(ctx).Status)
+
+	assert.True(t, m.Liveness().Healthy())
+}
+
+func TestPollingKeepsResultsCurrent(t *testing.T) {
+	m := newMonitor(true, 10*time.Millisecond, health.CheckRedis)
+
+	var failing atomic.Bool
+	var runs atomic.Int64
+	m.Register(health.CheckRedis, toggle(&failing, &runs))
+
+	m.Start()
+	t.Cleanup(m.Stop)
+
+	ctx := context.Background()
+	require.Eventually(t, func() bool { return runs.Load() >= 2 }, time.Second, 5*time.Millisecond)
+	assert.True(t, m.Readiness(ctx).Healthy())
+
+	failing.Store(true)
+	require.Eventually(t, func() bool {
+		return m.Readiness(ctx).Status == health.StatusUnhealthy
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestProbesAnswerFromPolling(t *testing.T) {
+	m := newMonitor(true, time.Hour, health.CheckDatabase)
+
+	var failing atomic.Bool
+	var runs atomic.Int64
+	m.Register(health.CheckDatabase, toggle(&failing, &runs))
+
+	m.Start()
+	t.Cleanup(m.Stop)
+	require.Eventually(t, func() bool { return runs.Load() == 1 }, time.Second, 5*time.Millisecond)
+
+	ctx := context.Background()
+	for range 5 {
+		assert.True(t, m.Readiness(ctx).Healthy())
+		assert.True(t, m.Startup(ctx).Healthy())
+	}
+	assert.Equal(t, int64(1), runs.Load())
+}
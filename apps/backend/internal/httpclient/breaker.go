@@ -0,0 +1,115 @@
+package httpclient
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned when requests to a host are short-circuited
+// because it has failed too many times in a row
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type breakerState int
+
+const (
+	stateClosed breakerState = iota
+	stateOpen
+	stateHalfOpen
+)
+
+// outcome is how an attempt counts towards the breaker
+type outcome int
+
+const (
+	outcomeSuccess outcome = iota
+	outcomeFailure
+	// outcomeNeutral says nothing about the host's health, such as an
+	// attempt the caller canceled or policy refused to send
+	outcomeNeutral
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case stateOpen:
+		return "open"
+	case stateHalfOpen:
+		return "half_open"
+	default:
+		return "closed"
+	}
+}
+
+// breaker is a consecutive-failure circuit breaker. After threshold failures
+// the circuit opens for cooldown, then a single trial request is let through
+// (half-open) to decide whether to close it again.
+type breaker struct {
+	mu        sync.Mutex
+	state     breakerState
+	failures  int
+	trialing  bool
+	openedAt  time.Time
+	threshold int
+	cooldown  time.Duration
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// allow reports whether a request may be attempted
+func (b *breaker) allow() bool {
+	if b.threshold <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case stateOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = stateHalfOpen
+		b.trialing = true
+		return true
+	case stateHalfOpen:
+		// Only one trial request at a time
+		if b.trialing {
+			return false
+		}
+		b.trialing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// record updates the breaker with the outcome of an attempt and returns the resulting state.
+// A neutral outcome frees the trial slot of a half-open breaker without deciding it.
+func (b *breaker) record(result outcome) breakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trialing = false
+	switch result {
+	case outcomeNeutral:
+		return b.state
+	case outcomeSuccess:
+		b.failures = 0
+		b.state = stateClosed
+		return b.state
+	}
+
+	b.failures++
+	if b.state == stateHalfOpen || (b.threshold > 0 && b.failures >= b.threshold) {
+		b.state = stateOpen
+		b.openedAt = time.Now()
+	}
+
+	return b.state
+}
@@ -0,0 +1,232 @@
+package httpclient
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mabhi256/tasker/internal/config"
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/rs/zerolog"
+	"golang.org/x/time/rate"
+)
+
+// Client is the outbound HTTP client shared by all integrations. It adds
+// per-host rate limiting, a per-host circuit breaker, retries with backoff
+// and New Relic external segments on top of net/http.
+//
+// The state of the most recently used hosts is kept, up to MaxHosts, since
+// the fetcher sends requests to any host users choose.
+type Client struct {
+	httpClient *http.Client
+	cfg        config.HTTPClientConfig
+	logger     *zerolog.Logger
+
+	mu    sync.Mutex
+	hosts map[string]*list.Element
+	order *list.List
+}
+
+// hostState is the rate limiter and circuit breaker of one host
+type hostState struct {
+	host    string
+	limiter *rate.Limiter
+	breaker *breaker
+}
+
+type Option func(*Client)
+
+// WithTransport replaces the base transport, e.g. with one that restricts
+// which addresses may be dialed. Tracing is layered on top of it.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Client) {
+		c.httpClient.Transport = newrelic.NewRoundTripper(transport)
+	}
+}
+
+// WithCheckRedirect sets the redirect policy of the underlying client
+func WithCheckRedirect(fn func(req *http.Request, via []*http.Request) error) Option {
+	return func(c *Client) {
+		c.httpClient.CheckRedirect = fn
+	}
+}
+
+func New(cfg *config.HTTPClientConfig, logger *zerolog.Logger, opts ...Option) *Client {
+	if cfg == nil {
+		cfg = config.DefaultHTTPClientConfig()
+	}
+
+	normalized := *cfg
+	if normalized.MaxRetries < 0 {
+		normalized.MaxRetries = 0
+	}
+	if normalized.RetryWaitMax < normalized.RetryWaitMin {
+		normalized.RetryWaitMax = normalized.RetryWaitMin
+	}
+	if normalized.MaxHosts <= 0 {
+		normalized.MaxHosts = config.DefaultHTTPClientConfig().MaxHosts
+	}
+
+	c := &Client{
+		httpClient: &http.Client{
+			Timeout:   normalized.Timeout,
+			Transport: newrelic.NewRoundTripper(http.DefaultTransport.(*http.Transport).Clone()),
+		},
+		cfg:    normalized,
+		logger: logger,
+		hosts:  make(map[string]*list.Element),
+		order:  list.New(),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Get issues a GET request bound to ctx
+func (c *Client) Get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+
+	return c.Do(req)
+}
+
+// Do sends the request, waiting for the host's rate limiter and retrying
+// transient failures. The request context must carry the New Relic
+// transaction (if any) for the external segment to be recorded.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	host := req.URL.Host
+	state := c.hostFor(host)
+	hostBreaker := state.breaker
+	limiter := state.limiter
+
+	maxAttempts := 1
+	if canRetryRequest(req) {
+		maxAttempts += c.cfg.MaxRetries
+	}
+
+	logger := c.logger.With().
+		Str("component", "httpclient").
+		Str("method", req.Method).
+		Str("host", host).
+		Logger()
+
+	var resp *http.Response
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if !hostBreaker.allow() {
+			logger.Warn().Msg("outbound request short-circuited")
+			return nil, fmt.Errorf("%s %s: %w", req.Method, host, ErrCircuitOpen)
+		}
+
+		if waitErr := limiter.Wait(ctx); waitErr != nil {
+			hostBreaker.record(outcomeNeutral)
+			return nil, fmt.Errorf("rate limiter wait for host %s: %w", host, waitErr)
+		}
+
+		attemptReq, buildErr := c.prepareAttempt(req, attempt)
+		if buildErr != nil {
+			hostBreaker.record(outcomeNeutral)
+			return nil, buildErr
+		}
+
+		start := time.Now()
+		resp, err = c.httpClient.Do(attemptReq)
+		duration := time.Since(start)
+
+		state := hostBreaker.record(classify(resp, err))
+		if state == stateOpen {
+			logger.Warn().Int("attempt", attempt).Msg("circuit breaker opened for host")
+		}
+
+		if !isRetryable(resp, err) || attempt == maxAttempts {
+			break
+		}
+
+		wait := backoff(attempt, c.cfg.RetryWaitMin, c.cfg.RetryWaitMax, resp)
+
+		event := logger.Warn().
+			Int("attempt", attempt).
+			Dur("duration", duration).
+			Dur("retry_in", wait)
+		if err != nil {
+			event = event.Err(err)
+		} else {
+			event = event.Int("status", resp.StatusCode)
+		}
+		event.Msg("outbound request failed, retrying")
+
+		// Drain and close so the connection can be reused
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+
+		if sleepErr := sleep(ctx, wait); sleepErr != nil {
+			return nil, sleepErr
+		}
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s %s failed: %w", req.Method, req.URL.Redacted(), err)
+	}
+
+	return resp, nil
+}
+
+// prepareAttempt returns the request to send for the given attempt,
+// rewinding the body for retries
+func (c *Client) prepareAttempt(req *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 1 || req.GetBody == nil {
+		return req, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("failed to rewind request body: %w", err)
+	}
+
+	clone := req.Clone(req.Context())
+	clone.Body = body
+	return clone, nil
+}
+
+// hostFor returns the state of host, forgetting the least recently used
+// host when there are more than MaxHosts
+func (c *Client) hostFor(host string) *hostState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.hosts[host]; ok {
+		c.order.MoveToFront(elem)
+		return elem.Value.(*hostState)
+	}
+
+	limit := rate.Inf
+	if c.cfg.RateLimit > 0 {
+		limit = rate.Limit(c.cfg.RateLimit)
+	}
+	state := &hostState{
+		host:    host,
+		limiter: rate.NewLimiter(limit, max(c.cfg.RateBurst, 1)),
+		breaker: newBreaker(c.cfg.BreakerThreshold, c.cfg.BreakerCooldown),
+	}
+	c.hosts[host] = c.order.PushFront(state)
+
+	for c.order.Len() > c.cfg.MaxHosts {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.hosts, oldest.Value.(*hostState).host)
+	}
+
+	return state
+}
@@ -0,0 +1,100 @@
+package httpclient_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mabhi256/tasker/internal/config"
+	"github.com/mabhi256/tasker/internal/httpclient"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClientForgetsHosts opens the breaker of a host, then forgets it once
+// more recently used hosts take its place
+func TestClientForgetsHosts(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer healthy.Close()
+
+	logger := zerolog.Nop()
+	client := httpclient.New(&config.HTTPClientConfig{
+		Timeout:          5 * time.Second,
+		BreakerThreshold: 1,
+		BreakerCooldown:  time.Hour,
+		MaxHosts:         1,
+	}, &logger)
+
+	get := func(url string) error {
+		resp, err := client.Get(context.Background(), url)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	require.NoError(t, get(failing.URL))
+	assert.ErrorIs(t, get(failing.URL), httpclient.ErrCircuitOpen)
+
+	require.NoError(t, get(healthy.URL))
+	assert.NoError(t, get(failing.URL), "the failing host's breaker was forgotten")
+}
+
+// TestClientCanceledTrial cancels the trial request of a half-open breaker,
+// which must leave the breaker half-open for the next trial to decide
+func TestClientCanceledTrial(t *testing.T) {
+	var hang atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hang.Load() {
+			<-r.Context().Done()
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	logger := zerolog.Nop()
+	client := httpclient.New(&config.HTTPClientConfig{
+		Timeout:          5 * time.Second,
+		BreakerThreshold: 2,
+		BreakerCooldown:  10 * time.Millisecond,
+	}, &logger)
+
+	get := func(ctx context.Context) error {
+		resp, err := client.Get(ctx, server.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	require.NoError(t, get(context.Background()))
+	require.NoError(t, get(context.Background()))
+	require.ErrorIs(t, get(context.Background()), httpclient.ErrCircuitOpen)
+	time.Sleep(20 * time.Millisecond)
+
+	hang.Store(true)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	require.ErrorIs(t, get(ctx), context.Canceled)
+
+	// A single failed trial reopens the breaker, where a closed one would
+	// have let the failure through under its threshold
+	hang.Store(false)
+	require.NoError(t, get(context.Background()))
+	assert.ErrorIs(t, get(context.Background()), httpclient.ErrCircuitOpen)
+}
@@ -0,0 +1,241 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/mabhi256/tasker/internal/config"
+	"github.com/rs/zerolog"
+)
+
+var (
+	ErrUnsupportedURL   = errors.New("url is not allowed")
+	ErrBodyTooLarge     = errors.New("response body exceeds size limit")
+	ErrTooManyRedirects = errors.New("too many redirects")
+)
+
+// Fetcher makes requests to user-supplied URLs. Only http(s) URLs on
+// public addresses are reachable, the resolved IP is pinned for the
+// connection, redirects are re-checked and response bodies are capped.
+// Every feature that fetches a URL provided by a user must go through it.
+type Fetcher struct {
+	client *Client
+	dialer *safeDialer
+	cfg    config.FetcherConfig
+	logger *zerolog.Logger
+}
+
+// FetchResult is a fully read response
+type FetchResult struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// FinalURL is the URL after redirects
+	FinalURL *url.URL
+}
+
+func NewFetcher(cfg *config.FetcherConfig, httpCfg *config.HTTPClientConfig, logger *zerolog.Logger) *Fetcher {
+	if cfg == nil {
+		cfg = config.DefaultFetcherConfig()
+	}
+	if httpCfg == nil {
+		httpCfg = config.DefaultHTTPClientConfig()
+	}
+
+	clientCfg := *httpCfg
+	clientCfg.Timeout = cfg.Timeout
+
+	dialer := newSafeDialer(cfg.Timeout, cfg.AllowPrivateNetworks)
+
+	transport := &http.Transport{
+		// A proxy would do its own resolution and bypass the pinned dial
+		Proxy:                  nil,
+		DialContext:            dialer.DialContext,
+		ForceAttemptHTTP2:      true,
+		MaxIdleConns:           50,
+		IdleConnTimeout:        90 * time.Second,
+		TLSHandshakeTimeout:    cfg.Timeout,
+		ResponseHeaderTimeout:  cfg.Timeout,
+		ExpectContinueTimeout:  time.Second,
+		MaxResponseHeaderBytes: 64 << 10,
+	}
+
+	f := &Fetcher{
+		dialer: dialer,
+		cfg:    *cfg,
+		logger: logger,
+	}
+
+	f.client = New(&clientCfg, logger,
+		WithTransport(transport),
+		WithCheckRedirect(f.checkRedirect),
+	)
+
+	return f
+}
+
+// ValidateURL checks that rawURL may be fetched, resolving its host. Use it
+// to reject bad URLs when they are saved; requests are checked again at
+// dial time since DNS answers can change.
+func (f *Fetcher) ValidateURL(ctx context.Context, rawURL string) (*url.URL, error) {
+	u, err := parseFetchURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := f.dialer.resolve(ctx, u.Hostname()); err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// ValidateHost checks that a bare host name or IP, as used by non-HTTP
+// destinations, resolves only to allowed addresses
+func (f *Fetcher) ValidateHost(ctx context.Context, host string) error {
+	if host == "" {
+		return fmt.Errorf("%w: missing host", ErrUnsupportedURL)
+	}
+
+	_, err := f.dialer.resolve(ctx, host)
+	return err
+}
+
+// DialContext opens a connection with the same address checks as HTTP
+// requests, for protocols that can't go through Do
+func (f *Fetcher) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	conn, err := f.dialer.DialContext(ctx, network, address)
+	if err != nil && errors.Is(err, ErrBlockedAddress) {
+		f.logger.Warn().
+			Str("component", "fetcher").
+			Str("address", address).
+			Err(err).
+			Msg("blocked connection to disallowed address")
+	}
+	return conn, err
+}
+
+// Get fetches rawURL and reads the whole body
+func (f *Fetcher) Get(ctx context.Context, rawURL string) (*FetchResult, error) {
+	u, err := parseFetchURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+
+	resp, err := f.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FetchResult{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+		FinalURL:   resp.Request.URL,
+	}, nil
+}
+
+// Do sends req with the fetcher's restrictions. Reading more than the
+// configured limit from the response body fails with ErrBodyTooLarge.
+func (f *Fetcher) Do(req *http.Request) (*http.Response, error) {
+	if _, err := parseFetchURL(req.URL.String()); err != nil {
+		return nil, err
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrBlockedAddress) {
+			f.logger.Warn().
+				Str("component", "fetcher").
+				Str("host", req.URL.Hostname()).
+				Err(err).
+				Msg("blocked request to disallowed address")
+		}
+		return nil, err
+	}
+
+	if f.cfg.MaxBodyBytes > 0 {
+		if resp.ContentLength > f.cfg.MaxBodyBytes {
+			resp.Body.Close()
+			return nil, fmt.Errorf("%w: content length %d", ErrBodyTooLarge, resp.ContentLength)
+		}
+		resp.Body = &limitedBody{ReadCloser: resp.Body, remaining: f.cfg.MaxBodyBytes}
+	}
+
+	return resp, nil
+}
+
+func (f *Fetcher) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) > f.cfg.MaxRedirects {
+		return ErrTooManyRedirects
+	}
+
+	// The target address is vetted when dialing, only the scheme needs checking here
+	_, err := parseFetchURL(req.URL.String())
+	return err
+}
+
+func parseFetchURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedURL, err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("%w: scheme %q", ErrUnsupportedURL, u.Scheme)
+	}
+
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("%w: missing host", ErrUnsupportedURL)
+	}
+
+	// Credentials in URLs end up in logs and are never needed here
+	if u.User != nil {
+		return nil, fmt.Errorf("%w: credentials are not allowed", ErrUnsupportedURL)
+	}
+
+	return u, nil
+}
+
+// limitedBody fails once more than remaining bytes have been read instead of
+// silently truncating like io.LimitReader
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, ErrBodyTooLarge
+	}
+
+	// Read one byte past the limit to tell "exactly at limit" from "over"
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n + int(b.remaining), ErrBodyTooLarge
+	}
+
+	return n, err
+}
@@ -0,0 +1,112 @@
+package httpclient_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mabhi256/tasker/internal/config"
+	"github.com/mabhi256/tasker/internal/httpclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testServers are on loopback, which the fetcher only reaches with private
+// networks allowed
+func localFetcherConfig() *config.FetcherConfig {
+	cfg := config.DefaultFetcherConfig()
+	cfg.AllowPrivateNetworks = true
+	cfg.MaxRedirects = 2
+	cfg.MaxBodyBytes = 16
+	return cfg
+}
+
+// TestFetcherRedirects checks every redirect's target again, and stops
+// following them past the limit
+func TestFetcherRedirects(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/done":
+			w.WriteHeader(http.StatusOK)
+		case r.URL.Path == "/file":
+			http.Redirect(w, r, "file:///etc/passwd", http.StatusFound)
+		case r.URL.Path == "/credentials":
+			http.Redirect(w, r, "http://user:secret@"+r.Host+"/done", http.StatusFound)
+		case strings.HasPrefix(r.URL.Path, "/hops/"):
+			n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/hops/"))
+			target := "/done"
+			if n > 1 {
+				target = "/hops/" + strconv.Itoa(n-1)
+			}
+			http.Redirect(w, r, target, http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	fetcher := newFetcher(t, localFetcherConfig())
+
+	tests := []struct {
+		name string
+		path string
+		err  error
+	}{
+		{name: "within limit", path: "/hops/2"},
+		{name: "past limit", path: "/hops/5", err: httpclient.ErrTooManyRedirects},
+		{name: "to other scheme", path: "/file", err: httpclient.ErrUnsupportedURL},
+		{name: "to credentials", path: "/credentials", err: httpclient.ErrUnsupportedURL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := fetcher.Get(context.Background(), srv.URL+tt.path)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "/done", result.FinalURL.Path)
+		})
+	}
+}
+
+// TestFetcherBodyLimit fails bodies over the limit, whether or not they
+// announce their length
+func TestFetcherBodyLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		size, _ := strconv.Atoi(r.URL.Query().Get("size"))
+		if r.URL.Query().Has("chunked") {
+			// Flushing before writing leaves out the Content-Length
+			w.(http.Flusher).Flush()
+		} else {
+			w.Header().Set("Content-Length", strconv.Itoa(size))
+		}
+		_, _ = w.Write([]byte(strings.Repeat("a", size)))
+	}))
+	defer srv.Close()
+
+	fetcher := newFetcher(t, localFetcherConfig())
+
+	tests := []struct {
+		name  string
+		query string
+		err   error
+	}{
+		{name: "at limit", query: "size=16"},
+		{name: "at limit chunked", query: "size=16&chunked"},
+		{name: "over limit", query: "size=17", err: httpclient.ErrBodyTooLarge},
+		{name: "over limit chunked", query: "size=4096&chunked", err: httpclient.ErrBodyTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := fetcher.Get(context.Background(), srv.URL+"/?"+tt.query)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, result.Body, 16)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// isRetryable reports whether an attempt that ended with resp/err should be retried
+func isRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		// Cancellation by the caller and policy rejections are final
+		return !errors.Is(err, context.Canceled) &&
+			!errors.Is(err, context.DeadlineExceeded) &&
+			!errors.Is(err, ErrBlockedAddress)
+	}
+
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// classify reports how the outcome counts towards the host's circuit breaker.
+// Client errors (4xx) are the caller's fault and don't indicate an unhealthy host,
+// while cancellation and policy rejections say nothing about it either way.
+func classify(resp *http.Response, err error) outcome {
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, ErrBlockedAddress) {
+			return outcomeNeutral
+		}
+		return outcomeFailure
+	}
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return outcomeFailure
+	}
+	return outcomeSuccess
+}
+
+// canRetryRequest reports whether the request may be sent more than once
+func canRetryRequest(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		// Non-idempotent requests are retried only when the caller opts in with an idempotency key
+		return req.Header.Get("Idempotency-Key") != ""
+	}
+}
+
+// backoff returns the wait before the given retry attempt (1-based) using
+// exponential backoff with full jitter, honoring Retry-After when present
+func backoff(attempt int, minWait, maxWait time.Duration, resp *http.Response) time.Duration {
+	if resp != nil {
+		if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			return min(wait, maxWait)
+		}
+	}
+
+	wait := minWait << (attempt - 1)
+	if wait <= 0 || wait > maxWait {
+		wait = maxWait
+	}
+
+	return minWait + rand.N(wait-minWait+1)
+}
+
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+
+	return 0, false
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,152 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+var ErrBlockedAddress = errors.New("destination address is not allowed")
+
+// blockedPrefixes covers special-purpose ranges not already reported by the
+// netip.Addr helpers used in isPublicAddr
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"),   // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // TEST-NET-1
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // TEST-NET-2
+	netip.MustParsePrefix("203.0.113.0/24"),  // TEST-NET-3
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved, includes broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64, can embed private IPv4
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // local-use NAT64
+	netip.MustParsePrefix("2001:db8::/32"),   // documentation
+	netip.MustParsePrefix("2002::/16"),       // 6to4, can embed private IPv4
+}
+
+// isPublicAddr reports whether addr is a globally routable unicast address
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	if !addr.IsValid() ||
+		addr.IsUnspecified() ||
+		addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() {
+		return false
+	}
+
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// safeDialer resolves the host once, rejects it if any of its addresses is
+// not public, and then dials the vetted IPs directly so a second DNS lookup
+// can't swap in an internal address (DNS rebinding).
+type safeDialer struct {
+	dialer       *net.Dialer
+	resolver     *net.Resolver
+	allowPrivate bool
+}
+
+func newSafeDialer(timeout time.Duration, allowPrivate bool) *safeDialer {
+	d := &safeDialer{
+		resolver:     net.DefaultResolver,
+		allowPrivate: allowPrivate,
+	}
+
+	d.dialer = &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+		// Last line of defence: check the address actually being connected to
+		Control: func(network, address string, _ syscall.RawConn) error {
+			return d.checkAddress(address)
+		},
+	}
+
+	return d
+}
+
+func (d *safeDialer) checkAddress(address string) error {
+	if d.allowPrivate {
+		return nil
+	}
+
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, address)
+	}
+
+	if !isPublicAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, addrPort.Addr())
+	}
+
+	return nil
+}
+
+// resolve returns the vetted addresses for host
+func (d *safeDialer) resolve(ctx context.Context, host string) ([]netip.Addr, error) {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !d.allowPrivate && !isPublicAddr(addr) {
+			return nil, fmt.Errorf("%w: %s", ErrBlockedAddress, addr)
+		}
+		return []netip.Addr{addr}, nil
+	}
+
+	addrs, err := d.resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve host %s: %w", host, err)
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no addresses found for host %s", host)
+	}
+
+	// Reject the host outright if any record points inward, rather than
+	// picking the public ones, so a mixed answer can't be used to probe
+	if !d.allowPrivate {
+		for _, addr := range addrs {
+			if !isPublicAddr(addr) {
+				return nil, fmt.Errorf("%w: %s resolves to %s", ErrBlockedAddress, host, addr.Unmap())
+			}
+		}
+	}
+
+	return addrs, nil
+}
+
+// DialContext is used as the transport's dialer
+func (d *safeDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs, err := d.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for _, addr := range addrs {
+		conn, err := d.dialer.DialContext(ctx, network, net.JoinHostPort(addr.Unmap().String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+
+	return nil, lastErr
+}
@@ -0,0 +1,68 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/mabhi256/tasker/internal/config"
+	"github.com/mabhi256/tasker/internal/httpclient"
+	"github.com/rs/zerolog"
+)
+
+// Notifier sends operational alerts to the configured channel. Every alert
+// is logged as well, so nothing is lost when no channel is configured.
+type Notifier struct {
+	webhookURL string
+	client     *httpclient.Client
+	logger     *zerolog.Logger
+}
+
+func NewNotifier(cfg *config.AlertsConfig, client *httpclient.Client, logger *zerolog.Logger) *Notifier {
+	return &Notifier{
+		webhookURL: cfg.WebhookURL,
+		client:     client,
+		logger:     logger,
+	}
+}
+
+// Send posts the alert. title is a one-line summary and text the details.
+func (n *Notifier) Send(ctx context.Context, title string, text string) error {
+	n.logger.Error().
+		Str("alert", title).
+		Str("details", text).
+		Msg("Operational alert raised")
+
+	if n.webhookURL == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"text": fmt.Sprintf("*%s*\n%s", title, text),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create alert request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send alert: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("alert webhook responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
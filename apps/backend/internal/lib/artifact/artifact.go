@@ -0,0 +1,59 @@
+// Package artifact derives files from uploaded attachments, such as image
+// thumbnails and the text of PDFs for search, and holds attachments to the
+// size and type policy.
+package artifact
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+type Kind string
+
+const (
+	KindThumbnail Kind = "thumbnail"
+	KindText      Kind = "text"
+)
+
+// MaxSourceSize bounds how much of an attachment is read to derive its
+// artifacts. Larger files get none.
+const MaxSourceSize = 20 << 20
+
+var (
+	ErrTooLarge       = errors.New("attachment is too large")
+	ErrTypeNotAllowed = errors.New("attachment type is not allowed")
+)
+
+// Policy is what attachments can be. AllowedTypes are media types such as
+// application/pdf, or a type's wildcard such as image/*, and allow any type
+// when empty.
+type Policy struct {
+	MaxSize      int64
+	AllowedTypes []string
+}
+
+// Check returns ErrTooLarge or ErrTypeNotAllowed, wrapped with the details,
+// for a file the policy doesn't allow
+func (p Policy) Check(size int64, mimeType string) error {
+	if p.MaxSize > 0 && size > p.MaxSize {
+		return fmt.Errorf("%w: %d bytes is over the limit of %d", ErrTooLarge, size, p.MaxSize)
+	}
+	if len(p.AllowedTypes) == 0 {
+		return nil
+	}
+
+	mediaType, _, _ := strings.Cut(mimeType, ";")
+	mediaType = strings.TrimSpace(strings.ToLower(mediaType))
+	for _, allowed := range p.AllowedTypes {
+		allowed = strings.ToLower(allowed)
+		if allowed == mediaType {
+			return nil
+		}
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok && path.Dir(mediaType) == prefix {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrTypeNotAllowed, mediaType)
+}
@@ -0,0 +1,101 @@
+package artifact_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/mabhi256/tasker/internal/lib/artifact"
+	"github.com/mabhi256/tasker/internal/lib/pdf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy(t *testing.T) {
+	policy := artifact.Policy{MaxSize: 100, AllowedTypes: []string{"application/pdf", "image/*"}}
+
+	assert.NoError(t, policy.Check(100, "application/pdf"))
+	assert.NoError(t, policy.Check(10, "image/png"))
+	assert.NoError(t, policy.Check(10, "IMAGE/JPEG; charset=binary"))
+	assert.ErrorIs(t, policy.Check(101, "image/png"), artifact.ErrTooLarge)
+	assert.ErrorIs(t, policy.Check(10, "text/html; charset=utf-8"), artifact.ErrTypeNotAllowed)
+	assert.ErrorIs(t, policy.Check(10, "imagex/png"), artifact.ErrTypeNotAllowed)
+
+	assert.NoError(t, artifact.Policy{}.Check(1<<40, "application/octet-stream"))
+}
+
+func TestNewThumbnail(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 400, 200))
+	for y := range 200 {
+		for x := range 400 {
+			// The right half is transparent
+			if x < 200 {
+				src.SetNRGBA(x, y, color.NRGBA{R: 0xff, A: 0xff})
+			}
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, src))
+
+	thumb, err := artifact.NewThumbnail(&buf, 100)
+	require.NoError(t, err)
+	assert.Equal(t, 100, thumb.Width)
+	assert.Equal(t, 50, thumb.Height)
+
+	decoded, err := jpeg.Decode(bytes.NewReader(thumb.Data))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 100, 50), decoded.Bounds())
+
+	r, g, b, _ := decoded.At(10, 25).RGBA()
+	assert.Greater(t, r, uint32(0xe000))
+	assert.Less(t, g, uint32(0x2000))
+	assert.Less(t, b, uint32(0x2000))
+	r, g, b, _ = decoded.At(90, 25).RGBA()
+	assert.Greater(t, min(r, g, b), uint32(0xe000), "transparency isn't white")
+
+	// Small images keep their size
+	buf.Reset()
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 20, 30))))
+	thumb, err = artifact.NewThumbnail(&buf, 100)
+	require.NoError(t, err)
+	assert.Equal(t, 20, thumb.Width)
+	assert.Equal(t, 30, thumb.Height)
+
+	_, err = artifact.NewThumbnail(strings.NewReader("not an image"), 100)
+	assert.Error(t, err)
+}
+
+func TestPDFText(t *testing.T) {
+	doc := pdf.New("Quarterly report")
+	doc.Write(pdf.TitleStyle, "Quarterly report")
+	doc.Write(pdf.BodyStyle, "Revenue (net) grew by 12%")
+
+	text, err := artifact.PDFText(bytes.NewReader(doc.Bytes()), 1000)
+	require.NoError(t, err)
+	assert.Contains(t, text, "Quarterly report\nRevenue (net) grew by 12%")
+
+	// Compressed content with kerned and hex strings
+	var content bytes.Buffer
+	zw := zlib.NewWriter(&content)
+	_, _ = zw.Write([]byte("BT /F1 12 Tf 72 700 Td [(Hel) 20 (lo) -300 (world)] TJ T* <FEFF00E9007400E9> Tj ET"))
+	require.NoError(t, zw.Close())
+	compressed := fmt.Sprintf("%%PDF-1.7\n4 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream\nendobj\n%%%%EOF",
+		content.Len(), content.Bytes())
+
+	text, err = artifact.PDFText(strings.NewReader(compressed), 1000)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello world\nété", text)
+
+	text, err = artifact.PDFText(strings.NewReader(compressed), 9)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello wor", text)
+
+	_, err = artifact.PDFText(strings.NewReader("plain text"), 1000)
+	assert.Error(t, err)
+}
@@ -0,0 +1,307 @@
+package artifact
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const (
+	// maxStreamSize bounds an inflated content stream
+	maxStreamSize = 16 << 20
+	// wordGap is the adjustment between the strings of a TJ array, in
+	// thousandths of the font size, beyond which they are separate words
+	wordGap = -200
+)
+
+// PDFText extracts the text a PDF shows, a line per line of text, up to
+// maxLen bytes. Text is read from the strings of the text operators of the
+// content streams that are uncompressed or Flate compressed, and decoded as
+// WinAnsi, or as UTF-16 when marked so. Text set in fonts with their own
+// encoding, as embedded subsets often are, isn't recovered.
+func PDFText(r io.Reader, maxLen int) (string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxSourceSize))
+	if err != nil {
+		return "", fmt.Errorf("failed to read PDF: %w", err)
+	}
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return "", errors.New("not a PDF")
+	}
+
+	var text strings.Builder
+	for _, content := range contentStreams(data) {
+		showText(&text, content)
+		if text.Len() >= maxLen {
+			break
+		}
+	}
+
+	return truncate(normalize(text.String()), maxLen), nil
+}
+
+// contentStreams returns the decoded streams that may be page contents,
+// skipping images, fonts and streams in filters that can't be decoded
+func contentStreams(data []byte) [][]byte {
+	var streams [][]byte
+
+	for rest := data; ; {
+		start := bytes.Index(rest, []byte("stream"))
+		if start < 0 {
+			break
+		}
+		// The stream's dictionary is in the object before it
+		dict := rest[:start]
+		if i := bytes.LastIndex(dict, []byte(" obj")); i >= 0 {
+			dict = dict[i:]
+		}
+
+		body := rest[start+len("stream"):]
+		body = bytes.TrimPrefix(body, []byte("\r"))
+		body = bytes.TrimPrefix(body, []byte("\n"))
+		end := bytes.Index(body, []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		rest = body[end+len("endstream"):]
+		body = body[:end]
+
+		if bytes.Contains(dict, []byte("/Subtype")) || bytes.Contains(dict, []byte("/Length1")) {
+			continue
+		}
+		switch {
+		case !bytes.Contains(dict, []byte("/Filter")):
+			streams = append(streams, body)
+		case bytes.Contains(dict, []byte("/FlateDecode")) && bytes.Count(dict, []byte("Decode")) == 1:
+			if inflated, err := inflate(body); err == nil {
+				streams = append(streams, inflated)
+			}
+		}
+	}
+
+	return streams
+}
+
+func inflate(data []byte) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	inflated, err := io.ReadAll(io.LimitReader(zr, maxStreamSize))
+	// A stream cut short still holds the text before the cut
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+	return inflated, nil
+}
+
+// showText writes the strings shown by the content's text operators. The
+// strings are operands, so they are collected until their operator.
+func showText(w *strings.Builder, content []byte) {
+	var operands []string
+	inArray := false
+
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case c == '(':
+			s, n := literalString(content[i:])
+			operands = append(operands, s)
+			i += n
+		case c == '<' && i+1 < len(content) && content[i+1] != '<':
+			s, n := hexString(content[i:])
+			operands = append(operands, s)
+			i += n
+		case c == '[':
+			inArray = true
+			i++
+		case c == ']':
+			inArray = false
+			i++
+		case c == '-' || c == '.' || c >= '0' && c <= '9':
+			start := i
+			for i < len(content) && (content[i] == '-' || content[i] == '.' || content[i] >= '0' && content[i] <= '9') {
+				i++
+			}
+			// A large gap between the strings of a TJ array is a space
+			if inArray && len(operands) > 0 {
+				if n, err := strconv.ParseFloat(string(content[start:i]), 64); err == nil && n < wordGap {
+					operands = append(operands, " ")
+				}
+			}
+		case isRegular(c):
+			start := i
+			for i < len(content) && isRegular(content[i]) {
+				i++
+			}
+			switch string(content[start:i]) {
+			case "Tj", "TJ":
+				w.WriteString(strings.Join(operands, ""))
+			case "'", `"`:
+				w.WriteByte('\n')
+				w.WriteString(strings.Join(operands, ""))
+			case "T*", "Td", "TD", "ET":
+				w.WriteByte('\n')
+			}
+			operands = operands[:0]
+		default:
+			i++
+		}
+	}
+}
+
+// isRegular reports whether c is part of an operator or name, rather than a
+// delimiter or white space
+func isRegular(c byte) bool {
+	return c > ' ' && c < 0x7f && !strings.ContainsRune("()<>[]{}/%", rune(c))
+}
+
+// literalString decodes the (string) at the start of s, returning it and
+// the number of bytes it took
+func literalString(s []byte) (string, int) {
+	var out []byte
+	depth := 0
+
+	i := 0
+	for ; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch e := s[i]; e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// A line continuation
+			default:
+				if e >= '0' && e <= '7' {
+					n := 0
+					for j := 0; j < 3 && i < len(s) && s[i] >= '0' && s[i] <= '7'; j++ {
+						n = n*8 + int(s[i]-'0')
+						i++
+					}
+					i--
+					out = append(out, byte(n))
+				} else {
+					out = append(out, e)
+				}
+			}
+		case c == '(':
+			if depth > 0 {
+				out = append(out, c)
+			}
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return decodeText(out), i + 1
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+
+	return decodeText(out), i
+}
+
+// hexString decodes the <hex string> at the start of s
+func hexString(s []byte) (string, int) {
+	end := bytes.IndexByte(s, '>')
+	if end < 0 {
+		end = len(s) - 1
+	}
+
+	var digits []byte
+	for _, c := range s[1:end] {
+		if unhex(c) >= 0 {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	for i := range out {
+		out[i] = byte(unhex(digits[2*i])<<4 | unhex(digits[2*i+1]))
+	}
+
+	return decodeText(out), end + 1
+}
+
+func unhex(c byte) int {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0')
+	case c >= 'a' && c <= 'f':
+		return int(c - 'a' + 10)
+	case c >= 'A' && c <= 'F':
+		return int(c - 'A' + 10)
+	}
+	return -1
+}
+
+// decodeText decodes a string as UTF-16 when it starts with its byte order
+// mark, and otherwise as WinAnsi, whose printable characters mostly match
+// Latin-1
+func decodeText(b []byte) string {
+	if len(b) >= 2 && b[0] == 0xfe && b[1] == 0xff {
+		units := make([]uint16, 0, len(b)/2)
+		for i := 2; i+1 < len(b); i += 2 {
+			units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+
+	runes := make([]rune, 0, len(b))
+	for _, c := range b {
+		runes = append(runes, rune(c))
+	}
+	return string(runes)
+}
+
+// normalize collapses the white space of each line, and drops blank lines
+// and control characters
+func normalize(text string) string {
+	var lines []string
+	for line := range strings.Lines(text) {
+		line = strings.Map(func(r rune) rune {
+			if unicode.IsControl(r) && r != '\t' {
+				return -1
+			}
+			return r
+		}, line)
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// truncate cuts text to at most n bytes, at a character boundary
+func truncate(text string, n int) string {
+	if len(text) <= n {
+		return text
+	}
+	for n > 0 && !utf8.RuneStart(text[n]) {
+		n--
+	}
+	return text[:n]
+}
@@ -0,0 +1,64 @@
+// Package authn authenticates API requests with the provider the deployment
+// is configured for: Clerk, any OpenID Connect provider, or a static token
+// for local development. Providers verify the bearer token of a request
+// and return who it identifies; sessions, roles and single sign-on are
+// enforced on top of that by the auth middleware.
+package authn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mabhi256/tasker/internal/config"
+	"github.com/mabhi256/tasker/internal/lib/oidc"
+)
+
+// RoleAdmin is the role granted access to the admin API, named after the
+// Clerk organization role it is with Clerk
+const RoleAdmin = "org:admin"
+
+var (
+	// ErrNoCredentials is returned for requests without a bearer token
+	ErrNoCredentials = errors.New("no credentials")
+	// ErrInvalidCredentials is returned for bearer tokens that don't verify
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Identity is who a request was authenticated as. SessionID is empty for
+// providers without sessions.
+type Identity struct {
+	UserID      string
+	SessionID   string
+	Role        string
+	Permissions []string
+}
+
+// Authenticator verifies the credentials of a request
+type Authenticator interface {
+	Authenticate(ctx context.Context, r *http.Request) (*Identity, error)
+}
+
+// New returns the authenticator of the configured provider. The OIDC
+// provider's documents are fetched with httpClient.
+func New(cfg *config.AuthConfig, httpClient oidc.Doer) (Authenticator, error) {
+	switch cfg.Provider {
+	case config.AuthProviderClerk:
+		return NewClerk(cfg.SecretKey), nil
+	case config.AuthProviderOIDC:
+		return NewOIDC(cfg.OIDC, oidc.NewVerifier(httpClient, oidcProviderTTL)), nil
+	case config.AuthProviderDev:
+		return NewDev(cfg.Dev), nil
+	default:
+		return nil, fmt.Errorf("unknown auth provider: %s", cfg.Provider)
+	}
+}
+
+// BearerToken returns the token of the request's Authorization header
+func BearerToken(r *http.Request) string {
+	authorization := strings.TrimSpace(r.Header.Get("Authorization"))
+	token, _ := strings.CutPrefix(authorization, "Bearer ")
+	return token
+}
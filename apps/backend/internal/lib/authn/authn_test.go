@@ -0,0 +1,198 @@
+package authn_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/mabhi256/tasker/internal/config"
+	"github.com/mabhi256/tasker/internal/lib/authn"
+	"github.com/mabhi256/tasker/internal/lib/oidc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	issuer   = "https://idp.example.com"
+	audience = "tasker-api"
+)
+
+type doerFunc func(req *http.Request) (*http.Response, error)
+
+func (f doerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func requestWithToken(token string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return r
+}
+
+func TestDev(t *testing.T) {
+	a, err := authn.New(&config.AuthConfig{
+		Provider: config.AuthProviderDev,
+		Dev:      &config.DevAuthConfig{Token: "dev-token", UserID: "user_dev", Admin: true},
+	}, nil)
+	require.NoError(t, err)
+
+	identity, err := a.Authenticate(context.Background(), requestWithToken("dev-token"))
+	require.NoError(t, err)
+	assert.Equal(t, "user_dev", identity.UserID)
+	assert.Equal(t, authn.RoleAdmin, identity.Role)
+	assert.Empty(t, identity.SessionID)
+
+	_, err = a.Authenticate(context.Background(), requestWithToken("wrong-token"))
+	assert.ErrorIs(t, err, authn.ErrInvalidCredentials)
+
+	_, err = a.Authenticate(context.Background(), requestWithToken(""))
+	assert.ErrorIs(t, err, authn.ErrNoCredentials)
+}
+
+func TestOIDC(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	respond := func(v any) *http.Response {
+		body, err := json.Marshal(v)
+		require.NoError(t, err)
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(string(body)))}
+	}
+	doer := doerFunc(func(req *http.Request) (*http.Response, error) {
+		switch req.URL.String() {
+		case issuer + oidc.WellKnownPath:
+			return respond(oidc.Provider{Issuer: issuer, JWKSURI: issuer + "/keys"}), nil
+		case issuer + "/keys":
+			return respond(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+				{Key: &key.PublicKey, KeyID: "key-1", Algorithm: string(jose.RS256), Use: "sig"},
+			}}), nil
+		default:
+			return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader(""))}, nil
+		}
+	})
+
+	a, err := authn.New(&config.AuthConfig{
+		Provider: config.AuthProviderOIDC,
+		OIDC: &config.OIDCAuthConfig{
+			Issuer:        issuer + "/",
+			Audience:      audience,
+			AdminSubjects: []string{"admin-1"},
+		},
+	}, doer)
+	require.NoError(t, err)
+
+	sign := func(subject string, aud string) string {
+		signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key},
+			(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "key-1"))
+		require.NoError(t, err)
+
+		now := time.Now()
+		token, err := jwt.Signed(signer).Claims(jwt.Claims{
+			Issuer:   issuer,
+			Subject:  subject,
+			Audience: jwt.Audience{aud},
+			IssuedAt: jwt.NewNumericDate(now),
+			Expiry:   jwt.NewNumericDate(now.Add(time.Hour)),
+		}).Claims(map[string]any{"sid": "session-1"}).CompactSerialize()
+		require.NoError(t, err)
+		return token
+	}
+
+	identity, err := a.Authenticate(context.Background(), requestWithToken(sign("user-1", audience)))
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", identity.UserID)
+	assert.Equal(t, "session-1", identity.SessionID)
+	assert.Empty(t, identity.Role)
+
+	identity, err = a.Authenticate(context.Background(), requestWithToken(sign("admin-1", audience)))
+	require.NoError(t, err)
+	assert.Equal(t, authn.RoleAdmin, identity.Role)
+
+	_, err = a.Authenticate(context.Background(), requestWithToken(sign("user-1", "someone-else")))
+	assert.ErrorIs(t, err, authn.ErrInvalidCredentials)
+
+	_, err = a.Authenticate(context.Background(), requestWithToken(""))
+	assert.ErrorIs(t, err, authn.ErrNoCredentials)
+}
+
+func TestNewUnknownProvider(t *testing.T) {
+	_, err := authn.New(&config.AuthConfig{Provider: "saml"}, nil)
+	assert.Error(t, err)
+}
+
+func TestMachine(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	respond := func(v any) *http.Response {
+		body, err := json.Marshal(v)
+		require.NoError(t, err)
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(string(body)))}
+	}
+	doer := doerFunc(func(req *http.Request) (*http.Response, error) {
+		switch req.URL.String() {
+		case issuer + oidc.WellKnownPath:
+			return respond(oidc.Provider{Issuer: issuer, JWKSURI: issuer + "/keys"}), nil
+		case issuer + "/keys":
+			return respond(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+				{Key: &key.PublicKey, KeyID: "key-1", Algorithm: string(jose.RS256), Use: "sig"},
+			}}), nil
+		default:
+			return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader(""))}, nil
+		}
+	})
+
+	assert.Nil(t, authn.NewMachine(nil, doer))
+	a := authn.NewMachine(&config.MachineAuthConfig{Issuer: issuer, Audience: audience}, doer)
+
+	sign := func(iss string, extra map[string]any) string {
+		signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key},
+			(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "key-1"))
+		require.NoError(t, err)
+
+		now := time.Now()
+		token, err := jwt.Signed(signer).Claims(jwt.Claims{
+			Issuer:   iss,
+			Subject:  "ci@clients",
+			Audience: jwt.Audience{audience},
+			IssuedAt: jwt.NewNumericDate(now),
+			Expiry:   jwt.NewNumericDate(now.Add(time.Hour)),
+		}).Claims(extra).CompactSerialize()
+		require.NoError(t, err)
+		return token
+	}
+
+	r := requestWithToken(sign(issuer, map[string]any{"client_id": "ci", "scope": "admin:jobs admin:rollouts"}))
+	assert.True(t, a.Accepts(r))
+	identity, err := a.Authenticate(context.Background(), r)
+	require.NoError(t, err)
+	assert.Equal(t, "ci", identity.ClientID)
+	assert.True(t, identity.HasScopes("admin:jobs", "admin:rollouts"))
+	assert.False(t, identity.HasScopes("admin:jobs", "admin:backfills"))
+
+	// Scopes may be a list in scp, and the client only the subject
+	identity, err = a.Authenticate(context.Background(), requestWithToken(sign(issuer, map[string]any{
+		"scp": []string{"admin:usage"},
+	})))
+	require.NoError(t, err)
+	assert.Equal(t, "ci@clients", identity.ClientID)
+	assert.True(t, identity.HasScopes("admin:usage"))
+
+	// Users' tokens are told apart by their issuer
+	assert.False(t, a.Accepts(requestWithToken(sign("https://users.example.com", nil))))
+	assert.False(t, a.Accepts(requestWithToken("not-a-token")))
+
+	_, err = a.Authenticate(context.Background(), requestWithToken(""))
+	assert.ErrorIs(t, err, authn.ErrNoCredentials)
+}
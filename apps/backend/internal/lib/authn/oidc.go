@@ -0,0 +1,61 @@
+package authn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mabhi256/tasker/internal/config"
+	"github.com/mabhi256/tasker/internal/lib/oidc"
+)
+
+// oidcProviderTTL is how long the provider's discovery document and keys
+// are cached. Keys are refetched sooner when a token is signed with a new
+// one.
+const oidcProviderTTL = time.Hour
+
+// OIDC verifies the tokens of an OpenID Connect provider against the keys
+// it publishes
+type OIDC struct {
+	discoveryURL  string
+	audience      string
+	adminSubjects []string
+	verifier      *oidc.Verifier
+}
+
+func NewOIDC(cfg *config.OIDCAuthConfig, verifier *oidc.Verifier) *OIDC {
+	return &OIDC{
+		discoveryURL:  strings.TrimSuffix(cfg.Issuer, "/") + oidc.WellKnownPath,
+		audience:      cfg.Audience,
+		adminSubjects: cfg.AdminSubjects,
+		verifier:      verifier,
+	}
+}
+
+func (a *OIDC) Authenticate(ctx context.Context, r *http.Request) (*Identity, error) {
+	token := BearerToken(r)
+	if token == "" {
+		return nil, ErrNoCredentials
+	}
+
+	claims, err := a.verifier.Verify(ctx, a.discoveryURL, a.audience, token)
+	if err != nil {
+		if errors.Is(err, oidc.ErrInvalidToken) {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+		}
+		return nil, err
+	}
+
+	identity := &Identity{
+		UserID:    claims.Subject,
+		SessionID: claims.SessionID,
+	}
+	if slices.Contains(a.adminSubjects, claims.Subject) {
+		identity.Role = RoleAdmin
+	}
+	return identity, nil
+}
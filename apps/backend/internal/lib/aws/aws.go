@@ -3,16 +3,20 @@ package aws
 import (
 	"context"
 
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
 	aws "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/mabhi256/tasker/internal/server"
 )
 
 type AWS struct {
-	S3 *S3Client
+	S3 ObjectStore
 }
 
-func NewAWS(server *server.Server) (*AWS, error) {
+// NewAWS builds the clients, bounded by ctx while it prepares the upload
+// bucket
+func NewAWS(ctx context.Context, server *server.Server) (*AWS, error) {
 	awsConfig := server.Config.AWS
 
 	configOptions := []func(*aws.LoadOptions) error{
@@ -22,6 +26,12 @@ func NewAWS(server *server.Server) (*AWS, error) {
 			awsConfig.SecretAccessKey,
 			"",
 		)),
+		aws.WithRetryer(func() awssdk.Retryer {
+			return retry.NewStandard(func(o *retry.StandardOptions) {
+				o.MaxAttempts = awsConfig.MaxAttempts
+				o.MaxBackoff = awsConfig.MaxBackoff
+			})
+		}),
 	}
 
 	// Add custom endpoint if provided (for S3-compatible services like Sevalla)
@@ -29,12 +39,38 @@ func NewAWS(server *server.Server) (*AWS, error) {
 		configOptions = append(configOptions, aws.WithBaseEndpoint(awsConfig.EndpointURL))
 	}
 
-	cfg, err := aws.LoadDefaultConfig(context.TODO(), configOptions...)
+	cfg, err := aws.LoadDefaultConfig(ctx, configOptions...)
 	if err != nil {
 		return nil, err
 	}
 
+	s3Client := NewS3Client(server, cfg)
+
+	// A local MinIO or LocalStack starts without the upload bucket
+	if server.Config.Primary.Env == "local" {
+		if err := s3Client.EnsureBucket(ctx, awsConfig.UploadBucket); err != nil {
+			return nil, err
+		}
+	}
+
+	if lifecycle := awsConfig.Lifecycle; lifecycle != nil && lifecycle.Enabled {
+		err := s3Client.ApplyLifecycle(ctx, awsConfig.UploadBucket,
+			LifecycleRule{
+				ID:              "expire-temp-uploads",
+				Prefix:          lifecycle.TempPrefix,
+				ExpireAfterDays: lifecycle.TempExpirationDays,
+			},
+			LifecycleRule{
+				ID:                       "abort-incomplete-uploads",
+				AbortIncompleteAfterDays: lifecycle.AbortIncompleteDays,
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &AWS{
-		S3: NewS3Client(server, cfg),
+		S3: s3Client,
 	}, nil
 }
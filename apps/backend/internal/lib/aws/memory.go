@@ -0,0 +1,136 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// MemoryStore is an ObjectStore that keeps objects in memory, for unit
+// tests of the code storing files. Its presigned URLs point nowhere.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]map[string][]byte
+}
+
+var _ ObjectStore = (*MemoryStore)(nil)
+
+// NewMemoryStore returns a store with the given buckets, which are empty
+func NewMemoryStore(buckets ...string) *MemoryStore {
+	m := &MemoryStore{buckets: make(map[string]map[string][]byte)}
+	for _, bucket := range buckets {
+		m.buckets[bucket] = make(map[string][]byte)
+	}
+	return m
+}
+
+// Object returns the content of an object, and whether it exists
+func (m *MemoryStore) Object(bucket string, key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, ok := m.buckets[bucket][key]
+	return bytes.Clone(data), ok
+}
+
+// Len returns how many objects the bucket holds
+func (m *MemoryStore) Len(bucket string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.buckets[bucket])
+}
+
+func (m *MemoryStore) bucket(name string) (map[string][]byte, error) {
+	objects, ok := m.buckets[name]
+	if !ok {
+		return nil, fmt.Errorf("bucket %s does not exist", name)
+	}
+	return objects, nil
+}
+
+func (m *MemoryStore) UploadFile(ctx context.Context, bucket string, fileName string, file io.Reader) (string, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	objects, err := m.bucket(bucket)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload file: %w", err)
+	}
+
+	// Keys are made as S3Client makes them
+	fileKey := fmt.Sprintf("%s_%d", fileName, time.Now().Unix())
+	objects[fileKey] = data
+
+	return fileKey, nil
+}
+
+func (m *MemoryStore) CreatePresignedUrl(ctx context.Context, bucket string, objectKey string) (string, error) {
+	return (&url.URL{Scheme: "memory", Host: bucket, Path: "/" + objectKey}).String(), nil
+}
+
+func (m *MemoryStore) DeleteObject(ctx context.Context, bucket string, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	objects, err := m.bucket(bucket)
+	if err != nil {
+		return fmt.Errorf("failed to delete object %s: %w", key, err)
+	}
+
+	// Deleting a missing object succeeds, as in S3
+	delete(objects, key)
+	return nil
+}
+
+func (m *MemoryStore) GetObject(ctx context.Context, bucket string, key string) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	objects, err := m.bucket(bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object %s: %w", key, err)
+	}
+	data, ok := objects[key]
+	if !ok {
+		return nil, fmt.Errorf("failed to get object %s: %w", key, ErrObjectNotFound)
+	}
+
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m *MemoryStore) CopyObject(ctx context.Context, bucket string, srcKey string, dstKey string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	objects, err := m.bucket(bucket)
+	if err != nil {
+		return fmt.Errorf("failed to copy object %s: %w", srcKey, err)
+	}
+	data, ok := objects[srcKey]
+	if !ok {
+		return fmt.Errorf("failed to copy object %s: %w", srcKey, ErrObjectNotFound)
+	}
+
+	objects[dstKey] = bytes.Clone(data)
+	return nil
+}
+
+func (m *MemoryStore) HeadBucket(ctx context.Context, bucket string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.bucket(bucket); err != nil {
+		return fmt.Errorf("failed to head bucket %s: %w", bucket, err)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package aws_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/mabhi256/tasker/internal/lib/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := aws.NewMemoryStore("uploads")
+
+	require.NoError(t, store.HeadBucket(ctx, "uploads"))
+	assert.Error(t, store.HeadBucket(ctx, "missing"))
+
+	key, err := store.UploadFile(ctx, "uploads", "notes.txt", strings.NewReader("hello"))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, "notes.txt_"))
+
+	body, err := store.GetObject(ctx, "uploads", key)
+	require.NoError(t, err)
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+
+	require.NoError(t, store.CopyObject(ctx, "uploads", key, "copy.txt"))
+	copied, ok := store.Object("uploads", "copy.txt")
+	require.True(t, ok)
+	assert.Equal(t, "hello", string(copied))
+	assert.Equal(t, 2, store.Len("uploads"))
+
+	url, err := store.CreatePresignedUrl(ctx, "uploads", key)
+	require.NoError(t, err)
+	assert.Equal(t, "memory://uploads/"+key, url)
+
+	require.NoError(t, store.DeleteObject(ctx, "uploads", key))
+	require.NoError(t, store.DeleteObject(ctx, "uploads", key))
+	_, err = store.GetObject(ctx, "uploads", key)
+	assert.ErrorIs(t, err, aws.ErrObjectNotFound)
+	assert.ErrorIs(t, store.CopyObject(ctx, "uploads", key, "again.txt"), aws.ErrObjectNotFound)
+
+	_, err = store.UploadFile(ctx, "missing", "notes.txt", strings.NewReader("hello"))
+	assert.Error(t, err)
+}
@@ -3,16 +3,35 @@ package aws
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/mabhi256/tasker/internal/server"
 )
 
+var ErrObjectNotFound = errors.New("object not found")
+
+// ObjectStore stores the files of todos, snapshots and reports. S3Client
+// stores them in S3 or an S3-compatible service, and MemoryStore in memory
+// for unit tests.
+type ObjectStore interface {
+	UploadFile(ctx context.Context, bucket string, fileName string, file io.Reader) (string, error)
+	CreatePresignedUrl(ctx context.Context, bucket string, objectKey string) (string, error)
+	DeleteObject(ctx context.Context, bucket string, key string) error
+	GetObject(ctx context.Context, bucket string, key string) (io.ReadCloser, error)
+	CopyObject(ctx context.Context, bucket string, srcKey string, dstKey string) error
+	HeadBucket(ctx context.Context, bucket string) error
+}
+
+var _ ObjectStore = (*S3Client)(nil)
+
 type S3Client struct {
 	server *server.Server
 	client *s3.Client
@@ -21,25 +40,43 @@ type S3Client struct {
 func NewS3Client(server *server.Server, cfg aws.Config) *S3Client {
 	return &S3Client{
 		server: server,
-		client: s3.NewFromConfig(cfg),
+		client: s3.NewFromConfig(cfg, func(o *s3.Options) {
+			o.UsePathStyle = server.Config.AWS.UsePathStyle
+		}),
 	}
 }
 
+// UploadFile stores the file under fileName with the upload time appended.
+// Files up to the multipart threshold are uploaded in one request, and
+// larger ones in parts, read from file one part at a time.
 func (s *S3Client) UploadFile(ctx context.Context, bucket string, fileName string, file io.Reader) (string, error) {
 	fileKey := fmt.Sprintf("%s_%d", fileName, time.Now().Unix())
+	threshold := s.server.Config.AWS.MultipartThreshold
 
 	var buffer bytes.Buffer
-	_, err := io.Copy(&buffer, file)
-	if err != nil {
+	n, err := io.CopyN(&buffer, file, threshold+1)
+	if err != nil && !errors.Is(err, io.EOF) {
 		return "", fmt.Errorf("failed to read file: %w", err)
 	}
+	contentType := http.DetectContentType(buffer.Bytes())
 
-	_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
+	if n > threshold {
+		body := io.MultiReader(&buffer, file)
+		if err := s.uploadMultipart(ctx, bucket, fileKey, contentType, body); err != nil {
+			return "", err
+		}
+		return fileKey, nil
+	}
+
+	input := &s3.PutObjectInput{
 		Bucket:      aws.String(bucket),
 		Key:         aws.String(fileKey),
 		Body:        bytes.NewReader(buffer.Bytes()),
-		ContentType: aws.String(http.DetectContentType(buffer.Bytes())),
-	})
+		ContentType: aws.String(contentType),
+	}
+	input.ServerSideEncryption, input.SSEKMSKeyId = s.encryption()
+
+	_, err = s.client.PutObject(ctx, input)
 	if err != nil {
 		return "", fmt.Errorf("failed to upload file to S3: %w", err)
 	}
@@ -47,6 +84,99 @@ func (s *S3Client) UploadFile(ctx context.Context, bucket string, fileName strin
 	return fileKey, nil
 }
 
+// uploadMultipart uploads body in parts of the configured size. A failed
+// upload is aborted, so its parts aren't kept, and billed, until the
+// bucket's lifecycle rules clear them.
+func (s *S3Client) uploadMultipart(ctx context.Context, bucket, key, contentType string, body io.Reader) error {
+	input := &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+	}
+	input.ServerSideEncryption, input.SSEKMSKeyId = s.encryption()
+
+	upload, err := s.client.CreateMultipartUpload(ctx, input)
+	if err != nil {
+		return fmt.Errorf("failed to start multipart upload of %s: %w", key, err)
+	}
+
+	parts, err := s.uploadParts(ctx, bucket, key, upload.UploadId, body)
+	if err == nil {
+		_, err = s.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:          aws.String(bucket),
+			Key:             aws.String(key),
+			UploadId:        upload.UploadId,
+			MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+		})
+		if err != nil {
+			err = fmt.Errorf("failed to complete multipart upload of %s: %w", key, err)
+		}
+	}
+	if err != nil {
+		// Aborted even when the upload was canceled
+		_, abortErr := s.client.AbortMultipartUpload(context.WithoutCancel(ctx), &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(bucket),
+			Key:      aws.String(key),
+			UploadId: upload.UploadId,
+		})
+		if abortErr != nil {
+			s.server.Logger.Warn().Err(abortErr).Str("key", key).Msg("failed to abort multipart upload")
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *S3Client) uploadParts(ctx context.Context, bucket, key string, uploadID *string,
+	body io.Reader,
+) ([]types.CompletedPart, error) {
+	var parts []types.CompletedPart
+	part := make([]byte, s.server.Config.AWS.PartSize)
+
+	for number := int32(1); ; number++ {
+		n, readErr := io.ReadFull(body, part)
+		if n > 0 {
+			output, err := s.client.UploadPart(ctx, &s3.UploadPartInput{
+				Bucket:     aws.String(bucket),
+				Key:        aws.String(key),
+				UploadId:   uploadID,
+				PartNumber: aws.Int32(number),
+				Body:       bytes.NewReader(part[:n]),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to upload part %d of %s: %w", number, key, err)
+			}
+			parts = append(parts, types.CompletedPart{
+				ETag:       output.ETag,
+				PartNumber: aws.Int32(number),
+			})
+		}
+
+		switch {
+		case errors.Is(readErr, io.EOF), errors.Is(readErr, io.ErrUnexpectedEOF):
+			return parts, nil
+		case readErr != nil:
+			return nil, fmt.Errorf("failed to read file: %w", readErr)
+		}
+	}
+}
+
+// encryption returns the server-side encryption of stored objects, empty to
+// leave it to the bucket's default
+func (s *S3Client) encryption() (types.ServerSideEncryption, *string) {
+	awsConfig := s.server.Config.AWS
+	if awsConfig.ServerSideEncryption == "" {
+		return "", nil
+	}
+
+	var keyID *string
+	if awsConfig.KMSKeyID != "" {
+		keyID = aws.String(awsConfig.KMSKeyID)
+	}
+	return types.ServerSideEncryption(awsConfig.ServerSideEncryption), keyID
+}
+
 func (s *S3Client) CreatePresignedUrl(ctx context.Context, bucket string, objectKey string) (string, error) {
 	presignClient := s3.NewPresignClient(s.client)
 
@@ -76,3 +206,136 @@ func (s *S3Client) DeleteObject(ctx context.Context, bucket string, key string)
 
 	return nil
 }
+
+// GetObject opens an object for reading. The caller must close the body.
+// A missing object returns ErrObjectNotFound.
+func (s *S3Client) GetObject(ctx context.Context, bucket string, key string) (io.ReadCloser, error) {
+	output, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, fmt.Errorf("failed to get object %s: %w", key, ErrObjectNotFound)
+		}
+		return nil, fmt.Errorf("failed to get object %s: %w", key, err)
+	}
+
+	return output.Body, nil
+}
+
+// CopyObject copies an object within the bucket to dstKey
+func (s *S3Client) CopyObject(ctx context.Context, bucket string, srcKey string, dstKey string) error {
+	// The source is a URL path, so its key is escaped
+	source := (&url.URL{Path: bucket + "/" + srcKey}).EscapedPath()
+
+	input := &s3.CopyObjectInput{
+		Bucket:     aws.String(bucket),
+		Key:        aws.String(dstKey),
+		CopySource: aws.String(source),
+	}
+	// Copies aren't encrypted like their source unless asked
+	input.ServerSideEncryption, input.SSEKMSKeyId = s.encryption()
+
+	_, err := s.client.CopyObject(ctx, input)
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return fmt.Errorf("failed to copy object %s: %w", srcKey, ErrObjectNotFound)
+		}
+		return fmt.Errorf("failed to copy object %s: %w", srcKey, err)
+	}
+
+	return nil
+}
+
+// HeadBucket checks that the bucket exists and the credentials can reach it
+func (s *S3Client) HeadBucket(ctx context.Context, bucket string) error {
+	_, err := s.client.HeadBucket(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to head bucket %s: %w", bucket, err)
+	}
+
+	return nil
+}
+
+// EnsureBucket creates the bucket unless it exists, for local S3-compatible
+// services that start empty
+func (s *S3Client) EnsureBucket(ctx context.Context, bucket string) error {
+	_, err := s.client.HeadBucket(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(bucket),
+	})
+	if err == nil {
+		return nil
+	}
+	var notFound *types.NotFound
+	if !errors.As(err, &notFound) {
+		return fmt.Errorf("failed to head bucket %s: %w", bucket, err)
+	}
+
+	input := &s3.CreateBucketInput{
+		Bucket: aws.String(bucket),
+	}
+	// us-east-1 is the default location, which can't be named
+	if region := s.server.Config.AWS.Region; region != "us-east-1" {
+		input.CreateBucketConfiguration = &types.CreateBucketConfiguration{
+			LocationConstraint: types.BucketLocationConstraint(region),
+		}
+	}
+
+	_, err = s.client.CreateBucket(ctx, input)
+	if err != nil {
+		var owned *types.BucketAlreadyOwnedByYou
+		if errors.As(err, &owned) {
+			return nil
+		}
+		return fmt.Errorf("failed to create bucket %s: %w", bucket, err)
+	}
+
+	return nil
+}
+
+// LifecycleRule expires the objects under Prefix ExpireAfterDays after they
+// are stored, and aborts the multipart uploads under it left incomplete for
+// AbortIncompleteAfterDays. A zero number of days leaves that out.
+type LifecycleRule struct {
+	ID                       string
+	Prefix                   string
+	ExpireAfterDays          int
+	AbortIncompleteAfterDays int
+}
+
+// ApplyLifecycle sets the lifecycle rules of the bucket, replacing any it
+// has
+func (s *S3Client) ApplyLifecycle(ctx context.Context, bucket string, rules ...LifecycleRule) error {
+	configuration := &types.BucketLifecycleConfiguration{}
+	for _, rule := range rules {
+		r := types.LifecycleRule{
+			ID:     aws.String(rule.ID),
+			Status: types.ExpirationStatusEnabled,
+			Filter: &types.LifecycleRuleFilter{Prefix: aws.String(rule.Prefix)},
+		}
+		if rule.ExpireAfterDays > 0 {
+			r.Expiration = &types.LifecycleExpiration{Days: aws.Int32(int32(rule.ExpireAfterDays))}
+		}
+		if rule.AbortIncompleteAfterDays > 0 {
+			r.AbortIncompleteMultipartUpload = &types.AbortIncompleteMultipartUpload{
+				DaysAfterInitiation: aws.Int32(int32(rule.AbortIncompleteAfterDays)),
+			}
+		}
+		configuration.Rules = append(configuration.Rules, r)
+	}
+
+	_, err := s.client.PutBucketLifecycleConfiguration(ctx, &s3.PutBucketLifecycleConfigurationInput{
+		Bucket:                 aws.String(bucket),
+		LifecycleConfiguration: configuration,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply lifecycle rules to bucket %s: %w", bucket, err)
+	}
+
+	return nil
+}
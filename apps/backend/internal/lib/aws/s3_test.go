@@ -0,0 +1,127 @@
+package aws_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mabhi256/tasker/internal/config"
+	"github.com/mabhi256/tasker/internal/lib/aws"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 records the requests of the object and multipart upload calls
+type fakeS3 struct {
+	mu       sync.Mutex
+	requests []string
+	parts    []string
+	failPart string
+	headers  http.Header
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	query := r.URL.Query()
+	body, _ := io.ReadAll(r.Body)
+
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		f.requests = append(f.requests, "create")
+		f.headers = r.Header.Clone()
+		_, _ = w.Write([]byte(`<InitiateMultipartUploadResult><Bucket>uploads</Bucket><Key>k</Key><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>`))
+	case r.Method == http.MethodPut && query.Has("partNumber"):
+		f.requests = append(f.requests, "part "+query.Get("partNumber"))
+		if query.Get("partNumber") == f.failPart {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`<Error><Code>AccessDenied</Code><Message>denied</Message></Error>`))
+			return
+		}
+		f.parts = append(f.parts, string(body))
+		w.Header().Set("ETag", `"etag-`+query.Get("partNumber")+`"`)
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		f.requests = append(f.requests, "complete")
+		_, _ = w.Write([]byte(`<CompleteMultipartUploadResult><Bucket>uploads</Bucket><Key>k</Key><ETag>"etag"</ETag></CompleteMultipartUploadResult>`))
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		f.requests = append(f.requests, "abort")
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		f.requests = append(f.requests, "put")
+		f.headers = r.Header.Clone()
+		f.parts = append(f.parts, string(body))
+		w.Header().Set("ETag", `"etag"`)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func newS3(t *testing.T, fake *fakeS3) aws.ObjectStore {
+	t.Helper()
+
+	ts := httptest.NewServer(fake)
+	t.Cleanup(ts.Close)
+
+	logger := zerolog.Nop()
+	srv := &server.Server{
+		Logger: &logger,
+		Config: &config.Config{
+			Primary: config.Primary{Env: "test"},
+			AWS: config.AWSConfig{
+				Region:               "eu-west-1",
+				AccessKeyID:          "AKIDEXAMPLE",
+				SecretAccessKey:      "secret",
+				UploadBucket:         "uploads",
+				EndpointURL:          ts.URL,
+				UsePathStyle:         true,
+				MultipartThreshold:   8,
+				PartSize:             5,
+				MaxAttempts:          1,
+				ServerSideEncryption: "AES256",
+			},
+		},
+	}
+
+	client, err := aws.NewAWS(context.Background(), srv)
+	require.NoError(t, err)
+	return client.S3
+}
+
+func TestUploadFile(t *testing.T) {
+	fake := &fakeS3{}
+	store := newS3(t, fake)
+
+	_, err := store.UploadFile(context.Background(), "uploads", "small.txt", strings.NewReader("12345678"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"put"}, fake.requests)
+	assert.Equal(t, []string{"12345678"}, fake.parts)
+	assert.Equal(t, "AES256", fake.headers.Get("X-Amz-Server-Side-Encryption"))
+}
+
+func TestUploadFileMultipart(t *testing.T) {
+	fake := &fakeS3{}
+	store := newS3(t, fake)
+
+	key, err := store.UploadFile(context.Background(), "uploads", "large.txt", strings.NewReader("abcdefghijkl"))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, "large.txt_"))
+	assert.Equal(t, []string{"create", "part 1", "part 2", "part 3", "complete"}, fake.requests)
+	assert.Equal(t, []string{"abcde", "fghij", "kl"}, fake.parts)
+	assert.Equal(t, "AES256", fake.headers.Get("X-Amz-Server-Side-Encryption"))
+}
+
+func TestUploadFileMultipartAborted(t *testing.T) {
+	fake := &fakeS3{failPart: "2"}
+	store := newS3(t, fake)
+
+	_, err := store.UploadFile(context.Background(), "uploads", "large.txt", strings.NewReader("abcdefghijkl"))
+	assert.ErrorContains(t, err, "failed to upload part 2")
+	assert.Equal(t, []string{"create", "part 1", "part 2", "abort"}, fake.requests)
+}
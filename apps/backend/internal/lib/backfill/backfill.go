@@ -0,0 +1,200 @@
+// Package backfill runs data migrations over large tables in the
+// background. A backfill walks its table in keyset order, one batch at a
+// time, and each batch commits together with the checkpoint it reached, so a
+// backfill that is paused, interrupted or retried picks up after the last
+// batch that committed and never applies one twice.
+package backfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Iterator returns up to limit keys after the checkpoint, in keyset order.
+// An empty checkpoint starts from the first row, and no keys ends the
+// backfill.
+type Iterator func(ctx context.Context, tx pgx.Tx, after string, limit int) ([]string, error)
+
+// BatchFunc processes one batch of keys and returns how many rows it
+// changed. It runs in the transaction that saves the checkpoint.
+type BatchFunc func(ctx context.Context, tx pgx.Tx, keys []string) (int64, error)
+
+type Backfill struct {
+	// Name identifies the backfill in the admin API and its checkpoint
+	Name        string
+	Description string
+	// BatchSize overrides the configured batch size when set
+	BatchSize int
+	Iterate   Iterator
+	Process   BatchFunc
+}
+
+func (b *Backfill) Validate() error {
+	if !namePattern.MatchString(b.Name) {
+		return fmt.Errorf("invalid backfill name %q", b.Name)
+	}
+	if b.Iterate == nil || b.Process == nil {
+		return fmt.Errorf("backfill %s needs both an iterator and a batch function", b.Name)
+	}
+	if b.BatchSize < 0 {
+		return fmt.Errorf("backfill %s has a negative batch size", b.Name)
+	}
+	return nil
+}
+
+// Batch is the outcome of one batch
+type Batch struct {
+	Keys int
+	Rows int64
+	// Checkpoint is the last key of the batch, or the previous checkpoint
+	// when the batch was empty
+	Checkpoint string
+	Done       bool
+}
+
+// RunBatch processes the batch after the checkpoint. The caller commits tx
+// together with the returned checkpoint.
+func (b *Backfill) RunBatch(ctx context.Context, tx pgx.Tx, after string, limit int) (Batch, error) {
+	keys, err := b.Iterate(ctx, tx, after, limit)
+	if err != nil {
+		return Batch{}, fmt.Errorf("failed to read batch of backfill %s after %q: %w", b.Name, after, err)
+	}
+
+	if len(keys) == 0 {
+		return Batch{Checkpoint: after, Done: true}, nil
+	}
+
+	rows, err := b.Process(ctx, tx, keys)
+	if err != nil {
+		return Batch{}, fmt.Errorf("failed to process batch of backfill %s after %q: %w", b.Name, after, err)
+	}
+
+	return Batch{
+		Keys:       len(keys),
+		Rows:       rows,
+		Checkpoint: keys[len(keys)-1],
+		// A short batch was the last one
+		Done: len(keys) < limit,
+	}, nil
+}
+
+// UUIDKeyset iterates the ids of the rows of table that match where, in id
+// order. table and where are trusted, as they only come from code.
+func UUIDKeyset(table, where string) Iterator {
+	stmt := fmt.Sprintf(`
+		SELECT
+			id::TEXT
+		FROM
+			%s
+		WHERE
+			id > @after
+			AND (%s)
+		ORDER BY
+			id
+		LIMIT
+			@limit
+	`, table, where)
+
+	return func(ctx context.Context, tx pgx.Tx, after string, limit int) ([]string, error) {
+		afterID := uuid.Nil
+		if after != "" {
+			var err error
+			if afterID, err = uuid.Parse(after); err != nil {
+				return nil, fmt.Errorf("invalid checkpoint %q: %w", after, err)
+			}
+		}
+
+		rows, err := tx.Query(ctx, stmt, pgx.NamedArgs{
+			"after": afterID,
+			"limit": limit,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return pgx.CollectRows(rows, pgx.RowTo[string])
+	}
+}
+
+// UpdateByID is a BatchFunc that applies the SET clause to the rows of
+// table with the batch's ids
+func UpdateByID(table, set string) BatchFunc {
+	stmt := fmt.Sprintf(`
+		UPDATE %s
+		SET
+			%s
+		WHERE
+			id = ANY (@ids::UUID[])
+	`, table, set)
+
+	return func(ctx context.Context, tx pgx.Tx, keys []string) (int64, error) {
+		tag, err := tx.Exec(ctx, stmt, pgx.NamedArgs{"ids": keys})
+		if err != nil {
+			return 0, err
+		}
+		return tag.RowsAffected(), nil
+	}
+}
+
+// Registry holds the backfills that can be run
+type Registry struct {
+	backfills map[string]*Backfill
+}
+
+// NewRegistry checks each backfill and that no two share a name
+func NewRegistry(backfills ...Backfill) (*Registry, error) {
+	registry := &Registry{backfills: make(map[string]*Backfill, len(backfills))}
+
+	var errList []error
+	for i := range backfills {
+		b := &backfills[i]
+		if err := b.Validate(); err != nil {
+			errList = append(errList, err)
+			continue
+		}
+		if _, ok := registry.backfills[b.Name]; ok {
+			errList = append(errList, fmt.Errorf("backfill %s is defined twice", b.Name))
+			continue
+		}
+		registry.backfills[b.Name] = b
+	}
+
+	if err := errors.Join(errList...); err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
+
+// MustRegistry is NewRegistry for backfills declared in code
+func MustRegistry(backfills ...Backfill) *Registry {
+	registry, err := NewRegistry(backfills...)
+	if err != nil {
+		panic(err)
+	}
+	return registry
+}
+
+func (r *Registry) Get(name string) (*Backfill, bool) {
+	b, ok := r.backfills[name]
+	return b, ok
+}
+
+// List returns the backfills ordered by name
+func (r *Registry) List() []*Backfill {
+	backfills := make([]*Backfill, 0, len(r.backfills))
+	for _, b := range r.backfills {
+		backfills = append(backfills, b)
+	}
+	sort.Slice(backfills, func(i, j int) bool {
+		return backfills[i].Name < backfills[j].Name
+	})
+	return backfills
+}
@@ -0,0 +1,122 @@
+package backfill_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mabhi256/tasker/internal/lib/backfill"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sliceBackfill walks keys in order and records the batches it processed
+func sliceBackfill(keys []string, processed *[][]string) backfill.Backfill {
+	return backfill.Backfill{
+		Name: "letters",
+		Iterate: func(ctx context.Context, tx pgx.Tx, after string, limit int) ([]string, error) {
+			var batch []string
+			for _, k := range keys {
+				if k > after && len(batch) < limit {
+					batch = append(batch, k)
+				}
+			}
+			return batch, nil
+		},
+		Process: func(ctx context.Context, tx pgx.Tx, batch []string) (int64, error) {
+			*processed = append(*processed, batch)
+			return int64(len(batch)), nil
+		},
+	}
+}
+
+func TestRunBatchWalksFromCheckpoint(t *testing.T) {
+	var processed [][]string
+	b := sliceBackfill([]string{"a", "b", "c", "d", "e"}, &processed)
+	ctx := context.Background()
+
+	first, err := b.RunBatch(ctx, nil, "", 2)
+	require.NoError(t, err)
+	assert.Equal(t, backfill.Batch{Keys: 2, Rows: 2, Checkpoint: "b"}, first)
+
+	second, err := b.RunBatch(ctx, nil, first.Checkpoint, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "d", second.Checkpoint)
+	assert.False(t, second.Done)
+
+	last, err := b.RunBatch(ctx, nil, second.Checkpoint, 2)
+	require.NoError(t, err)
+	assert.Equal(t, backfill.Batch{Keys: 1, Rows: 1, Checkpoint: "e", Done: true}, last)
+
+	assert.Equal(t, [][]string{{"a", "b"}, {"c", "d"}, {"e"}}, processed)
+}
+
+func TestRunBatchEmpty(t *testing.T) {
+	var processed [][]string
+	b := sliceBackfill([]string{"a", "b"}, &processed)
+
+	batch, err := b.RunBatch(context.Background(), nil, "b", 2)
+	require.NoError(t, err)
+	assert.Equal(t, backfill.Batch{Checkpoint: "b", Done: true}, batch)
+	assert.Empty(t, processed)
+}
+
+func TestRunBatchKeepsCheckpointOnError(t *testing.T) {
+	var processed [][]string
+	b := sliceBackfill([]string{"a", "b"}, &processed)
+	b.Process = func(ctx context.Context, tx pgx.Tx, keys []string) (int64, error) {
+		return 0, errors.New("deadlock detected")
+	}
+
+	batch, err := b.RunBatch(context.Background(), nil, "", 2)
+	assert.ErrorContains(t, err, "deadlock detected")
+	assert.Empty(t, batch.Checkpoint)
+}
+
+func TestNewRegistry(t *testing.T) {
+	var processed [][]string
+	first := sliceBackfill(nil, &processed)
+	second := sliceBackfill(nil, &processed)
+	second.Name = "digits"
+
+	registry, err := backfill.NewRegistry(first, second)
+	require.NoError(t, err)
+
+	b, ok := registry.Get("letters")
+	require.True(t, ok)
+	assert.Equal(t, "letters", b.Name)
+
+	_, ok = registry.Get("missing")
+	assert.False(t, ok)
+
+	list := registry.List()
+	require.Len(t, list, 2)
+	assert.Equal(t, "digits", list[0].Name)
+	assert.Equal(t, "letters", list[1].Name)
+}
+
+func TestNewRegistryRejectsInvalidBackfills(t *testing.T) {
+	var processed [][]string
+
+	badName := sliceBackfill(nil, &processed)
+	badName.Name = "Bad Name"
+
+	noProcess := sliceBackfill(nil, &processed)
+	noProcess.Name = "no_process"
+	noProcess.Process = nil
+
+	negative := sliceBackfill(nil, &processed)
+	negative.Name = "negative"
+	negative.BatchSize = -1
+
+	for _, b := range []backfill.Backfill{badName, noProcess, negative} {
+		_, err := backfill.NewRegistry(b)
+		assert.Error(t, err, b.Name)
+	}
+
+	_, err := backfill.NewRegistry(sliceBackfill(nil, &processed), sliceBackfill(nil, &processed))
+	assert.ErrorContains(t, err, "defined twice")
+
+	assert.Panics(t, func() { backfill.MustRegistry(badName) })
+}
@@ -0,0 +1,154 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/mabhi256/tasker/internal/lib/reqdebug"
+	"github.com/mabhi256/tasker/internal/logging"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	keyPrefix = "cache:"
+	// loadTimeout bounds a load shared by coalesced callers, since it no
+	// longer follows any single caller's context
+	loadTimeout = 10 * time.Second
+)
+
+// Cache is a read-through cache in Redis. Concurrent misses for the same key
+// in this process share one load, so a burst of identical requests costs a
+// single query. Redis errors are logged and fall through to the loader.
+type Cache struct {
+	redis         *redis.Client
+	logger        *zerolog.Logger
+	loggerService *logging.LoggerService
+	group         singleflight.Group
+
+	hits       atomic.Int64
+	misses     atomic.Int64
+	coalesced  atomic.Int64
+	loadErrors atomic.Int64
+}
+
+// Stats are counted since the process started
+type Stats struct {
+	Hits       int64
+	Misses     int64
+	Coalesced  int64
+	LoadErrors int64
+}
+
+func New(redisClient *redis.Client, logger *zerolog.Logger, loggerService *logging.LoggerService) *Cache {
+	return &Cache{
+		redis:         redisClient,
+		logger:        logger,
+		loggerService: loggerService,
+	}
+}
+
+// GetOrLoad returns the cached value for key, calling load on a miss and
+// caching its result for ttl. Callers that miss while a load for the same
+// key is running wait for it instead of loading again.
+func GetOrLoad[T any](ctx context.Context, c *Cache, key string, ttl time.Duration,
+	load func(ctx context.Context) (T, error),
+) (T, error) {
+	var zero T
+	redisKey := keyPrefix + key
+
+	raw, err := c.redis.Get(ctx, redisKey).Bytes()
+	switch {
+	case err == nil:
+		var value T
+		if err := json.Unmarshal(raw, &value); err == nil {
+			c.hits.Add(1)
+			c.recordMetric("Hit")
+			reqdebug.FromContext(ctx).CacheHit()
+			return value, nil
+		}
+		c.logger.Warn().Err(err).Str("key", key).Msg("discarding unreadable cache entry")
+	case !errors.Is(err, redis.Nil):
+		c.logger.Warn().Err(err).Str("key", key).Msg("failed to read from cache")
+	}
+
+	c.misses.Add(1)
+	c.recordMetric("Miss")
+	reqdebug.FromContext(ctx).CacheMiss()
+
+	executed := false
+	ch := c.group.DoChan(key, func() (any, error) {
+		executed = true
+
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), loadTimeout)
+		defer cancel()
+
+		value, err := load(loadCtx)
+		if err != nil {
+			return nil, err
+		}
+
+		if raw, err := json.Marshal(value); err != nil {
+			c.logger.Warn().Err(err).Str("key", key).Msg("failed to encode cache entry")
+		} else if err := c.redis.Set(loadCtx, redisKey, raw, ttl).Err(); err != nil {
+			c.logger.Warn().Err(err).Str("key", key).Msg("failed to write to cache")
+		}
+
+		return value, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case result := <-ch:
+		// executed is only written by the load, which has finished once the
+		// result is received
+		if !executed {
+			c.coalesced.Add(1)
+			c.recordMetric("Coalesced")
+		}
+
+		if result.Err != nil {
+			if executed {
+				c.loadErrors.Add(1)
+			}
+			return zero, result.Err
+		}
+
+		return result.Val.(T), nil
+	}
+}
+
+// Delete removes cached entries so the next read loads fresh data
+func (c *Cache) Delete(ctx context.Context, keys ...string) error {
+	redisKeys := make([]string, len(keys))
+	for i, key := range keys {
+		redisKeys[i] = keyPrefix + key
+	}
+
+	if err := c.redis.Del(ctx, redisKeys...).Err(); err != nil {
+		return fmt.Errorf("failed to delete cache keys: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Cache) Stats() Stats {
+	return Stats{
+		Hits:       c.hits.Load(),
+		Misses:     c.misses.Load(),
+		Coalesced:  c.coalesced.Load(),
+		LoadErrors: c.loadErrors.Load(),
+	}
+}
+
+func (c *Cache) recordMetric(outcome string) {
+	if c.loggerService != nil && c.loggerService.GetApplication() != nil {
+		c.loggerService.GetApplication().RecordCustomMetric("Custom/Cache/"+outcome, 1)
+	}
+}
@@ -0,0 +1,127 @@
+// Package changelog holds the API changelog, kept with the code in
+// entries.json so an entry ships with the change it announces, and renders
+// it as an RSS feed.
+package changelog
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mabhi256/tasker/internal/model/changelog"
+)
+
+//go:embed entries.json
+var entries []byte
+
+// Load returns the changelog's entries, newest first
+func Load() ([]changelog.Entry, error) {
+	return Parse(entries)
+}
+
+// Parse reads entries, checking each is complete, has a unique ID and a
+// known kind, and that only deprecations have a sunset. They are returned
+// newest first.
+func Parse(data []byte) ([]changelog.Entry, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var parsed []changelog.Entry
+	if err := decoder.Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse changelog: %w", err)
+	}
+
+	seen := make(map[string]bool, len(parsed))
+	for _, entry := range parsed {
+		switch {
+		case entry.ID == "" || entry.Title == "" || entry.Description == "" || entry.PublishedAt.IsZero():
+			return nil, fmt.Errorf("changelog entry %q needs an id, title, description and publishedAt", entry.ID)
+		case seen[entry.ID]:
+			return nil, fmt.Errorf("changelog entry %q is listed twice", entry.ID)
+		case !entry.Kind.Valid():
+			return nil, fmt.Errorf("changelog entry %q has unknown kind %q", entry.ID, entry.Kind)
+		case entry.SunsetAt != nil && entry.Kind != changelog.KindDeprecated:
+			return nil, fmt.Errorf("changelog entry %q has a sunset but isn't a deprecation", entry.ID)
+		}
+		seen[entry.ID] = true
+	}
+
+	slices.SortStableFunc(parsed, func(a, b changelog.Entry) int {
+		return b.PublishedAt.Compare(a.PublishedAt)
+	})
+
+	return parsed, nil
+}
+
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	Categories  []string `xml:"category"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// RSS renders entries as an RSS 2.0 feed served at link. Each item links to
+// the entry in the JSON changelog.
+func RSS(entries []changelog.Entry, link string) ([]byte, error) {
+	channel := rssChannel{
+		Title:       "Tasker API changelog",
+		Link:        link,
+		Description: "Changes and deprecations of the Tasker API",
+		Items:       make([]rssItem, 0, len(entries)),
+	}
+	if len(entries) > 0 {
+		channel.LastBuildDate = entries[0].PublishedAt.Format(time.RFC1123Z)
+	}
+
+	for _, entry := range entries {
+		description := entry.Description
+		if len(entry.Endpoints) > 0 {
+			description += "\n\nAffects: " + strings.Join(entry.Endpoints, ", ")
+		}
+		if entry.SunsetAt != nil {
+			description += "\n\nStops working on " + entry.SunsetAt.Format(time.DateOnly) + "."
+		}
+
+		channel.Items = append(channel.Items, rssItem{
+			Title:       fmt.Sprintf("[%s] %s", entry.Kind, entry.Title),
+			Link:        link + "#" + entry.ID,
+			Description: description,
+			Categories:  []string{string(entry.Kind)},
+			GUID:        rssGUID{Value: entry.ID},
+			PubDate:     entry.PublishedAt.Format(time.RFC1123Z),
+		})
+	}
+
+	body, err := xml.MarshalIndent(rss{Version: "2.0", Channel: channel}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to render changelog feed: %w", err)
+	}
+
+	return append([]byte(xml.Header), body...), nil
+}
@@ -0,0 +1,69 @@
+package changelog_test
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/mabhi256/tasker/internal/lib/changelog"
+	model "github.com/mabhi256/tasker/internal/model/changelog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEntriesLoad(t *testing.T) {
+	entries, err := changelog.Load()
+	require.NoError(t, err)
+	require.NotEmpty(t, entries)
+
+	for i := 1; i < len(entries); i++ {
+		assert.False(t, entries[i].PublishedAt.After(entries[i-1].PublishedAt), "entries aren't newest first")
+	}
+}
+
+func TestParseRejectsInvalidEntries(t *testing.T) {
+	for name, data := range map[string]string{
+		"missing title": `[{"id":"a","kind":"added","description":"d","publishedAt":"2026-01-01T00:00:00Z"}]`,
+		"unknown kind":  `[{"id":"a","kind":"tweaked","title":"t","description":"d","publishedAt":"2026-01-01T00:00:00Z"}]`,
+		"duplicate id": `[{"id":"a","kind":"added","title":"t","description":"d","publishedAt":"2026-01-01T00:00:00Z"},
+			{"id":"a","kind":"fixed","title":"t","description":"d","publishedAt":"2026-01-02T00:00:00Z"}]`,
+		"sunset on an addition": `[{"id":"a","kind":"added","title":"t","description":"d","publishedAt":"2026-01-01T00:00:00Z","sunsetAt":"2026-06-01T00:00:00Z"}]`,
+		"unknown field":         `[{"id":"a","kind":"added","title":"t","description":"d","publishedAt":"2026-01-01T00:00:00Z","owner":"me"}]`,
+	} {
+		_, err := changelog.Parse([]byte(data))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestRSS(t *testing.T) {
+	entries, err := changelog.Parse([]byte(`[
+		{"id":"old","kind":"added","title":"Todos","description":"Todos & more","publishedAt":"2026-01-01T00:00:00Z"},
+		{"id":"new","kind":"deprecated","title":"Old <search>","description":"Use the new one","endpoints":["GET /api/v1/todos/search"],
+		 "publishedAt":"2026-02-01T00:00:00Z","sunsetAt":"2026-08-01T00:00:00Z"}
+	]`))
+	require.NoError(t, err)
+	assert.Equal(t, model.KindDeprecated, entries[0].Kind)
+
+	feed, err := changelog.RSS(entries, "https://api.example.com/api/v1/changelog")
+	require.NoError(t, err)
+
+	var parsed struct {
+		Channel struct {
+			Items []struct {
+				Title       string `xml:"title"`
+				Link        string `xml:"link"`
+				Description string `xml:"description"`
+				GUID        string `xml:"guid"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	require.NoError(t, xml.Unmarshal(feed, &parsed))
+	require.Len(t, parsed.Channel.Items, 2)
+
+	item := parsed.Channel.Items[0]
+	assert.Equal(t, "[deprecated] Old <search>", item.Title)
+	assert.Equal(t, "https://api.example.com/api/v1/changelog#new", item.Link)
+	assert.Equal(t, "new", item.GUID)
+	assert.Contains(t, item.Description, "GET /api/v1/todos/search")
+	assert.Contains(t, item.Description, "2026-08-01")
+	assert.Equal(t, "Todos & more", parsed.Channel.Items[1].Description)
+}
@@ -0,0 +1,84 @@
+// Package clientversion reads the platform and version clients name
+// themselves with, so versions too old to be served safely can be turned
+// away.
+package clientversion
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Header is sent by clients as platform/version, such as ios/2.3.1
+const Header = "X-Client-Version"
+
+var ErrInvalid = errors.New("invalid client version")
+
+// Version is a major.minor.patch version. Parts left out are zero.
+type Version [3]int
+
+// ParseVersion parses versions such as 2, 2.3, v2.3.1 and 2.3.1-beta.2.
+// Pre-release and build suffixes are ignored.
+func ParseVersion(s string) (Version, error) {
+	var v Version
+
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		s = s[:i]
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) > len(v) {
+		return v, fmt.Errorf("%w: %q has more than three parts", ErrInvalid, s)
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("%w: %q is not a number", ErrInvalid, part)
+		}
+		v[i] = n
+	}
+
+	return v, nil
+}
+
+// Compare returns -1, 0 or 1 as v is older than, the same as or newer than o
+func (v Version) Compare(o Version) int {
+	for i := range v {
+		switch {
+		case v[i] < o[i]:
+			return -1
+		case v[i] > o[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v[0], v[1], v[2])
+}
+
+// Client is the platform and version a client names itself with
+type Client struct {
+	// Platform is lowercase, such as ios
+	Platform string
+	Version  Version
+}
+
+// Parse parses a Header value
+func Parse(value string) (Client, error) {
+	platform, version, ok := strings.Cut(strings.TrimSpace(value), "/")
+	platform = strings.ToLower(strings.TrimSpace(platform))
+	if !ok || platform == "" {
+		return Client{}, fmt.Errorf("%w: %q is not platform/version", ErrInvalid, value)
+	}
+
+	v, err := ParseVersion(version)
+	if err != nil {
+		return Client{}, err
+	}
+
+	return Client{Platform: platform, Version: v}, nil
+}
@@ -0,0 +1,55 @@
+package clientversion_test
+
+import (
+	"testing"
+
+	"github.com/mabhi256/tasker/internal/lib/clientversion"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := map[string]clientversion.Version{
+		"2":            {2, 0, 0},
+		"2.3":          {2, 3, 0},
+		"2.3.1":        {2, 3, 1},
+		"v2.3.1":       {2, 3, 1},
+		"2.3.1-beta.2": {2, 3, 1},
+		"2.3.1+1234":   {2, 3, 1},
+	}
+	for input, want := range tests {
+		t.Run(input, func(t *testing.T) {
+			got, err := clientversion.ParseVersion(input)
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+		})
+	}
+
+	for _, input := range []string{"", "two", "2.x", "1.2.3.4", "-1"} {
+		t.Run("invalid "+input, func(t *testing.T) {
+			_, err := clientversion.ParseVersion(input)
+			assert.ErrorIs(t, err, clientversion.ErrInvalid)
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	v := clientversion.Version{2, 3, 1}
+
+	assert.Equal(t, 0, v.Compare(clientversion.Version{2, 3, 1}))
+	assert.Equal(t, -1, v.Compare(clientversion.Version{2, 4, 0}))
+	assert.Equal(t, -1, v.Compare(clientversion.Version{10, 0, 0}))
+	assert.Equal(t, 1, v.Compare(clientversion.Version{2, 3, 0}))
+	assert.Equal(t, "2.3.1", v.String())
+}
+
+func TestParse(t *testing.T) {
+	client, err := clientversion.Parse(" iOS/2.3.1 ")
+	require.NoError(t, err)
+	assert.Equal(t, clientversion.Client{Platform: "ios", Version: clientversion.Version{2, 3, 1}}, client)
+
+	for _, input := range []string{"2.3.1", "/2.3.1", "ios/", "ios/latest"} {
+		_, err := clientversion.Parse(input)
+		assert.ErrorIs(t, err, clientversion.ErrInvalid, input)
+	}
+}
@@ -0,0 +1,53 @@
+// Package collation matches user locales to the ICU collations titles are
+// sorted with, which migration 036 creates for each of Languages
+package collation
+
+import (
+	"slices"
+
+	"golang.org/x/text/language"
+)
+
+// Root is the language of the Unicode root collation, which serves the
+// languages without a collation of their own, such as English
+const Root = "und"
+
+// Languages have a collation of their own
+var Languages = []string{
+	Root, "ar", "cs", "da", "de", "el", "es", "fi", "fr", "he", "hi", "hu", "it", "ja",
+	"ko", "nb", "nl", "pl", "pt", "ro", "ru", "sk", "sv", "th", "tr", "uk", "vi", "zh",
+}
+
+// Match returns the collation language of the first preference that
+// parses. A preference is a BCP 47 tag, such as pt-BR, or an
+// Accept-Language header, whose most preferred language is used. Languages
+// without their own collation get Root, as does no preference.
+func Match(preferences ...string) string {
+	for _, preference := range preferences {
+		tags, _, err := language.ParseAcceptLanguage(preference)
+		if err != nil || len(tags) == 0 {
+			continue
+		}
+
+		base, _ := tags[0].Base()
+		lang := base.String()
+		// Norwegian is also tagged no
+		if lang == "no" {
+			lang = "nb"
+		}
+		if !slices.Contains(Languages, lang) {
+			lang = Root
+		}
+		return lang
+	}
+	return Root
+}
+
+// Name is the collation of lang, one of Languages, to follow COLLATE. Other
+// languages get the root collation.
+func Name(lang string) string {
+	if !slices.Contains(Languages, lang) {
+		lang = Root
+	}
+	return `"locale_` + lang + `"`
+}
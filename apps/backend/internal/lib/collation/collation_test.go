@@ -0,0 +1,43 @@
+package collation_test
+
+import (
+	"testing"
+
+	"github.com/mabhi256/tasker/internal/lib/collation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		preferences []string
+		want        string
+	}{
+		{name: "none", want: collation.Root},
+		{name: "empty header", preferences: []string{""}, want: collation.Root},
+		{name: "tag", preferences: []string{"de"}, want: "de"},
+		{name: "regional tag", preferences: []string{"pt-BR"}, want: "pt"},
+		{name: "script tag", preferences: []string{"zh-Hant-TW"}, want: "zh"},
+		{name: "norwegian", preferences: []string{"no"}, want: "nb"},
+		{name: "english", preferences: []string{"en-US"}, want: collation.Root},
+		{name: "unsupported language", preferences: []string{"sw"}, want: collation.Root},
+		{name: "accept language", preferences: []string{"fr-CH, fr;q=0.9, en;q=0.8"}, want: "fr"},
+		{name: "accept language by quality", preferences: []string{"en;q=0.5, sv"}, want: "sv"},
+		// English is preferred, and sorted by the root collation
+		{name: "first language without collation", preferences: []string{"en-GB, de;q=0.8"}, want: collation.Root},
+		{name: "locale over header", preferences: []string{"tr", "de-DE"}, want: "tr"},
+		{name: "invalid locale skipped", preferences: []string{"not a tag!", "es-MX"}, want: "es"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, collation.Match(tt.preferences...))
+		})
+	}
+}
+
+func TestName(t *testing.T) {
+	assert.Equal(t, `"locale_de"`, collation.Name("de"))
+	assert.Equal(t, `"locale_und"`, collation.Name(collation.Root))
+	// Only collations the migration created are named
+	assert.Equal(t, `"locale_und"`, collation.Name(`x"; DROP TABLE todos; --`))
+}
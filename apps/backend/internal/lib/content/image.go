@@ -0,0 +1,98 @@
+package content
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// MaxImageSize is the largest image that can be pasted into a description
+// or comment, in bytes
+const MaxImageSize = 10 << 20
+
+// imageExtensions are the image types that can be pasted, by their MIME
+// type, with the extension their attachments are named with
+var imageExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+var (
+	ErrImageEmpty    = errors.New("image is empty")
+	ErrImageTooLarge = errors.New("image is larger than 10 MB")
+	ErrImageEncoding = errors.New("image is not valid base64")
+	ErrImageType     = errors.New("image must be a PNG, JPEG, GIF or WebP")
+)
+
+// DecodeImage decodes a pasted image sent as base64, either bare or as a
+// data URL such as data:image/png;base64,iVBOR...
+func DecodeImage(encoded string) ([]byte, error) {
+	if rest, ok := strings.CutPrefix(encoded, "data:"); ok {
+		_, data, found := strings.Cut(rest, ",")
+		if !found {
+			return nil, ErrImageEncoding
+		}
+		encoded = data
+	}
+	encoded = strings.Join(strings.Fields(encoded), "")
+
+	if encoded == "" {
+		return nil, ErrImageEmpty
+	}
+	// DecodedLen can overestimate by the two bytes of padding
+	if base64.StdEncoding.DecodedLen(len(encoded)) > MaxImageSize+2 {
+		return nil, ErrImageTooLarge
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		// Some clients leave out the padding
+		data, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(encoded, "="))
+		if err != nil {
+			return nil, ErrImageEncoding
+		}
+	}
+	return data, nil
+}
+
+// CheckImage checks the size and content of an image, returning its MIME
+// type and the extension to name it with. The type is sniffed from the
+// content, as the one a client declares can't be trusted.
+func CheckImage(data []byte) (mimeType string, ext string, err error) {
+	switch {
+	case len(data) == 0:
+		return "", "", ErrImageEmpty
+	case len(data) > MaxImageSize:
+		return "", "", ErrImageTooLarge
+	}
+
+	mimeType = http.DetectContentType(data)
+	ext, ok := imageExtensions[mimeType]
+	if !ok {
+		return "", "", ErrImageType
+	}
+	return mimeType, ext, nil
+}
+
+// ImageMarkdown returns the markdown that embeds the image at url, with alt
+// as its text
+func ImageMarkdown(alt string, url string) string {
+	var b strings.Builder
+	b.WriteString("![")
+	for _, r := range alt {
+		switch r {
+		case '\\', '[', ']', '*', '_', '`', '~':
+			b.WriteByte('\\')
+		case '\n', '\r':
+			r = ' '
+		}
+		b.WriteRune(r)
+	}
+	b.WriteString("](")
+	b.WriteString(url)
+	b.WriteString(")")
+	return b.String()
+}
@@ -0,0 +1,74 @@
+package content_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+
+	"github.com/mabhi256/tasker/internal/lib/content"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// png is the start of a PNG file, enough for its type to be detected
+var png = append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 24)...)
+
+func TestDecodeImage(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString(png)
+
+	tests := []struct {
+		name    string
+		encoded string
+		err     error
+	}{
+		{name: "bare", encoded: encoded},
+		{name: "data url", encoded: "data:image/png;base64," + encoded},
+		{name: "wrapped lines", encoded: encoded[:20] + "\n" + encoded[20:]},
+		{name: "no padding", encoded: base64.RawStdEncoding.EncodeToString(png)},
+		{name: "empty", encoded: "data:image/png;base64,", err: content.ErrImageEmpty},
+		{name: "not base64", encoded: "not an image!", err: content.ErrImageEncoding},
+		{name: "data url without data", encoded: "data:image/png;base64", err: content.ErrImageEncoding},
+		{
+			name:    "too large",
+			encoded: base64.StdEncoding.EncodeToString(make([]byte, content.MaxImageSize+3)),
+			err:     content.ErrImageTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := content.DecodeImage(tt.encoded)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, png, data)
+		})
+	}
+}
+
+func TestCheckImage(t *testing.T) {
+	mimeType, ext, err := content.CheckImage(png)
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", mimeType)
+	assert.Equal(t, ".png", ext)
+
+	_, _, err = content.CheckImage([]byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"))
+	assert.ErrorIs(t, err, content.ErrImageType)
+
+	_, _, err = content.CheckImage(nil)
+	assert.ErrorIs(t, err, content.ErrImageEmpty)
+
+	_, _, err = content.CheckImage(append(bytes.Clone(png), make([]byte, content.MaxImageSize)...))
+	assert.ErrorIs(t, err, content.ErrImageTooLarge)
+}
+
+func TestImageMarkdown(t *testing.T) {
+	markdown := content.ImageMarkdown("my [first]\nshot_1", "/api/v1/todos/1/attachments/2/download")
+	assert.Equal(t, `![my \[first\] shot\_1](/api/v1/todos/1/attachments/2/download)`, markdown)
+
+	html := content.Render(markdown).HTML
+	assert.Contains(t, html, `href="/api/v1/todos/1/attachments/2/download"`)
+	assert.Contains(t, html, "my [first] shot_1")
+}
@@ -0,0 +1,394 @@
+package content
+
+import (
+	"bytes"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// linkRel keeps rendered links from passing the page on to the target
+const linkRel = "nofollow noopener noreferrer"
+
+// emphasis delimiters, longest first so ** isn't read as two *
+var emphasis = []struct {
+	delim string
+	tag   string
+}{
+	{"**", "strong"},
+	{"__", "strong"},
+	{"~~", "del"},
+	{"*", "em"},
+	{"_", "em"},
+}
+
+// appendInline writes the inline markdown of s. inLink is set inside link
+// text, where links can't nest.
+func appendInline(dst []byte, s string, inLink bool) []byte {
+	for i := 0; i < len(s); {
+		c := s[i]
+
+		switch c {
+		case '\\':
+			if i+1 < len(s) && s[i+1] == '\n' {
+				dst = append(dst, "<br>\n"...)
+				i += 2
+				continue
+			}
+			if i+1 < len(s) && isPunct(s[i+1]) {
+				dst = appendEscaped(dst, s[i+1:i+2])
+				i += 2
+				continue
+			}
+
+		case '\n':
+			if strings.HasSuffix(s[:i], "  ") {
+				dst = bytes.TrimRight(dst, " ")
+				dst = append(dst, "<br>\n"...)
+			} else {
+				dst = append(dst, '\n')
+			}
+			i++
+			continue
+
+		case '`':
+			if code, n, ok := codeSpan(s[i:]); ok {
+				dst = append(dst, "<code>"...)
+				dst = appendEscaped(dst, code)
+				dst = append(dst, "</code>"...)
+				i += n
+				continue
+			}
+			// An unmatched run of backticks is text
+			run := len(s[i:]) - len(strings.TrimLeft(s[i:], "`"))
+			dst = append(dst, s[i:i+run]...)
+			i += run
+			continue
+
+		case '!', '[':
+			if inLink {
+				break
+			}
+			start := i
+			if c == '!' {
+				if i+1 >= len(s) || s[i+1] != '[' {
+					break
+				}
+				start++
+			}
+			if text, href, title, n, ok := link(s[start:]); ok {
+				dst = appendLink(dst, text, href, title)
+				i = start + n
+				continue
+			}
+
+		case '<':
+			if inLink {
+				break
+			}
+			if end := strings.IndexByte(s[i:], '>'); end > 0 {
+				target := s[i+1 : i+end]
+				if hasScheme(target) && !strings.ContainsAny(target, " \n<") {
+					if href, ok := safeURL(target); ok {
+						dst = appendAnchor(dst, href, "")
+						dst = appendEscaped(dst, target)
+						dst = append(dst, "</a>"...)
+						i += end + 1
+						continue
+					}
+				}
+			}
+
+		case 'h':
+			if inLink || (i > 0 && isAlnum(lastRune(s[:i]))) {
+				break
+			}
+			if url := bareURL(s[i:]); url != "" {
+				if href, ok := safeURL(url); ok {
+					dst = appendAnchor(dst, href, "")
+					dst = appendEscaped(dst, url)
+					dst = append(dst, "</a>"...)
+					i += len(url)
+					continue
+				}
+			}
+
+		case '*', '_', '~':
+			if inner, tag, n, ok := emphasized(s, i); ok {
+				dst = append(dst, "<"+tag+">"...)
+				dst = appendInline(dst, inner, inLink)
+				dst = append(dst, "</"+tag+">"...)
+				i += n
+				continue
+			}
+			// Write the whole run, so a closer can't be matched inside it
+			run := len(s[i:]) - len(strings.TrimLeft(s[i:], string(c)))
+			dst = append(dst, s[i:i+run]...)
+			i += run
+			continue
+		}
+
+		dst = appendEscaped(dst, s[i:i+1])
+		i++
+	}
+
+	return dst
+}
+
+// codeSpan matches a code span at the start of s, closed by a backtick run
+// of the same length
+func codeSpan(s string) (string, int, bool) {
+	run := len(s) - len(strings.TrimLeft(s, "`"))
+
+	for j := run; j < len(s); {
+		k := strings.IndexByte(s[j:], '`')
+		if k < 0 {
+			return "", 0, false
+		}
+		k += j
+		closing := len(s[k:]) - len(strings.TrimLeft(s[k:], "`"))
+		if closing == run {
+			code := strings.ReplaceAll(s[run:k], "\n", " ")
+			if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+				code = code[1 : len(code)-1]
+			}
+			return code, k + closing, true
+		}
+		j = k + closing
+	}
+	return "", 0, false
+}
+
+// link matches [text](href "title") at the start of s
+func link(s string) (text string, href string, title string, n int, ok bool) {
+	depth := 0
+	closeText := -1
+	for j := 0; j < len(s) && closeText < 0; j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				closeText = j
+			}
+		}
+	}
+	if closeText < 0 || closeText+1 >= len(s) || s[closeText+1] != '(' {
+		return "", "", "", 0, false
+	}
+
+	depth = 0
+	closeDest := -1
+	for j := closeText + 1; j < len(s) && closeDest < 0; j++ {
+		switch s[j] {
+		case '\n':
+			return "", "", "", 0, false
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				closeDest = j
+			}
+		}
+	}
+	if closeDest < 0 {
+		return "", "", "", 0, false
+	}
+
+	dest := strings.TrimSpace(s[closeText+2 : closeDest])
+	if k := strings.IndexAny(dest, " "); k >= 0 {
+		t := strings.TrimSpace(dest[k:])
+		if len(t) < 2 || t[0] != '"' || t[len(t)-1] != '"' {
+			return "", "", "", 0, false
+		}
+		title = t[1 : len(t)-1]
+		dest = dest[:k]
+	}
+	dest = strings.TrimSuffix(strings.TrimPrefix(dest, "<"), ">")
+
+	return s[1:closeText], dest, title, closeDest + 1, true
+}
+
+// appendLink writes a link, or only its text when the target isn't safe
+func appendLink(dst []byte, text string, href string, title string) []byte {
+	safe, ok := safeURL(href)
+	if !ok {
+		return appendInline(dst, text, true)
+	}
+
+	dst = appendAnchor(dst, safe, title)
+	dst = appendInline(dst, text, true)
+	return append(dst, "</a>"...)
+}
+
+func appendAnchor(dst []byte, href string, title string) []byte {
+	dst = append(dst, `<a href="`...)
+	dst = appendEscaped(dst, href)
+	dst = append(dst, '"')
+	if title != "" {
+		dst = append(dst, ` title="`...)
+		dst = appendEscaped(dst, title)
+		dst = append(dst, '"')
+	}
+	return append(dst, ` rel="`+linkRel+`">`...)
+}
+
+// bareURL matches an http(s) URL at the start of s, leaving out punctuation
+// that ends the sentence around it
+func bareURL(s string) string {
+	if !strings.HasPrefix(s, "http://") && !strings.HasPrefix(s, "https://") {
+		return ""
+	}
+
+	end := strings.IndexFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '<'
+	})
+	if end < 0 {
+		end = len(s)
+	}
+	url := s[:end]
+
+	for url != "" {
+		last := url[len(url)-1]
+		switch {
+		case strings.IndexByte(".,:;!?'\"*_~", last) >= 0:
+			url = url[:len(url)-1]
+		case last == ')' && strings.Count(url, ")") > strings.Count(url, "("):
+			url = url[:len(url)-1]
+		default:
+			if strings.HasSuffix(url, "://") {
+				return ""
+			}
+			return url
+		}
+	}
+	return ""
+}
+
+// emphasized matches the emphasis opened at s[i]. An opener has to be
+// followed by text and its closer preceded by text, and _ doesn't work
+// inside words.
+func emphasized(s string, i int) (inner string, tag string, n int, ok bool) {
+	for _, e := range emphasis {
+		d := e.delim
+		if !strings.HasPrefix(s[i:], d) {
+			continue
+		}
+
+		after := s[i+len(d):]
+		if after == "" || unicode.IsSpace(firstRune(after)) || (len(d) == 1 && strings.HasPrefix(after, d)) {
+			continue
+		}
+		if d[0] == '_' && i > 0 && isAlnum(lastRune(s[:i])) {
+			continue
+		}
+
+		for j := i + len(d); j < len(s); {
+			k := strings.Index(s[j:], d)
+			if k < 0 {
+				break
+			}
+			k += j
+			closer := k + len(d)
+			fits := !unicode.IsSpace(lastRune(s[:k])) && k > i+len(d)
+			if len(d) == 1 {
+				// A single delimiter doesn't close at a double one
+				fits = fits && !strings.HasPrefix(s[closer:], d) && s[k-1] != d[0]
+			}
+			if d[0] == '_' && closer < len(s) && isAlnum(firstRune(s[closer:])) {
+				fits = false
+			}
+			if fits {
+				return s[i+len(d) : k], e.tag, closer - i, true
+			}
+			j = k + 1
+		}
+	}
+	return "", "", 0, false
+}
+
+// safeURL checks a link target. Relative URLs are allowed, and absolute ones
+// only with the http, https and mailto schemes.
+func safeURL(raw string) (string, bool) {
+	u := strings.TrimSpace(raw)
+	if u == "" {
+		return "", false
+	}
+	for _, r := range u {
+		if r < 0x20 || r == 0x7f || unicode.IsSpace(r) {
+			return "", false
+		}
+	}
+
+	colon := strings.IndexByte(u, ':')
+	if colon < 0 {
+		return u, true
+	}
+	if sep := strings.IndexAny(u, "/?#"); sep >= 0 && sep < colon {
+		return u, true
+	}
+
+	switch strings.ToLower(u[:colon]) {
+	case "http", "https", "mailto":
+		return u, true
+	}
+	return "", false
+}
+
+// hasScheme reports whether s starts with a URL scheme, like autolinks do
+func hasScheme(s string) bool {
+	colon := strings.IndexByte(s, ':')
+	if colon < 2 {
+		return false
+	}
+	for j := 0; j < colon; j++ {
+		c := s[j]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || j > 0 && (c >= '0' && c <= '9' || c == '+' || c == '.' || c == '-')) {
+			return false
+		}
+	}
+	return true
+}
+
+// appendEscaped writes s escaped for HTML text and attribute values
+func appendEscaped(dst []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '&':
+			dst = append(dst, "&amp;"...)
+		case '<':
+			dst = append(dst, "&lt;"...)
+		case '>':
+			dst = append(dst, "&gt;"...)
+		case '"':
+			dst = append(dst, "&quot;"...)
+		case '\'':
+			dst = append(dst, "&#39;"...)
+		default:
+			dst = append(dst, s[i])
+		}
+	}
+	return dst
+}
+
+func isPunct(c byte) bool {
+	return c < utf8.RuneSelf && unicode.IsPunct(rune(c)) || strings.IndexByte("$+<=>^`|~", c) >= 0
+}
+
+func isAlnum(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func firstRune(s string) rune {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r
+}
+
+func lastRune(s string) rune {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return r
+}
@@ -0,0 +1,513 @@
+// Package content renders the markdown stored in todo descriptions and
+// comments. Raw markdown is what the API stores and returns by default;
+// clients that can't render it ask for HTML with ?render=html.
+//
+// The renderer supports the subset of GitHub flavoured markdown the editor
+// produces: headings, emphasis, strikethrough, code, links, quotes, rules and
+// nested lists, including task list items (- [ ] / - [x]). It never passes
+// HTML from the input through. All text is escaped and only the tags the
+// renderer writes itself reach the output, with link targets limited to
+// http, https, mailto and relative URLs, so the HTML is safe to insert into
+// a page as is.
+package content
+
+import (
+	"strconv"
+	"strings"
+)
+
+// FormatHTML is the value of the render query parameter that asks for HTML
+const FormatHTML = "html"
+
+// ChecklistItem is a task list item, numbered in document order. Index
+// matches the data-checklist-index attribute of the item's checkbox in the
+// rendered HTML.
+type ChecklistItem struct {
+	Index   int    `json:"index"`
+	Text    string `json:"text"`
+	Checked bool   `json:"checked"`
+}
+
+// Rendered is markdown rendered for display
+type Rendered struct {
+	HTML      string
+	Checklist []ChecklistItem
+}
+
+// Render converts markdown to sanitized HTML and extracts its checklist
+func Render(markdown string) Rendered {
+	blocks := parseBlocks(splitLines(markdown))
+
+	r := &renderer{}
+	r.blocks(blocks)
+
+	return Rendered{
+		HTML:      strings.TrimSuffix(string(r.dst), "\n"),
+		Checklist: r.checklist,
+	}
+}
+
+// Checklist returns the task list items of markdown
+func Checklist(markdown string) []ChecklistItem {
+	return Render(markdown).Checklist
+}
+
+// splitLines normalizes line endings, tabs and invalid UTF-8
+func splitLines(markdown string) []string {
+	markdown = strings.ToValidUTF8(markdown, "�")
+	markdown = strings.ReplaceAll(markdown, "\r\n", "\n")
+	markdown = strings.ReplaceAll(markdown, "\r", "\n")
+	markdown = strings.ReplaceAll(markdown, "\t", "    ")
+	return strings.Split(markdown, "\n")
+}
+
+type blockKind int
+
+const (
+	paragraphBlock blockKind = iota
+	headingBlock
+	codeBlock
+	quoteBlock
+	listBlock
+	itemBlock
+	ruleBlock
+)
+
+type block struct {
+	kind blockKind
+	// text is the raw text of paragraphs and headings, and the code of code
+	// blocks
+	text     string
+	level    int
+	lang     string
+	ordered  bool
+	start    int
+	task     bool
+	checked  bool
+	children []*block
+}
+
+func parseBlocks(lines []string) []*block {
+	var blocks []*block
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+
+		if isBlank(line) {
+			i++
+			continue
+		}
+
+		if fence, lang, ok := openFence(line); ok {
+			var code []string
+			i++
+			for i < len(lines) && !closesFence(lines[i], fence) {
+				code = append(code, lines[i])
+				i++
+			}
+			// Skip the closing fence; an unclosed block runs to the end
+			i++
+			blocks = append(blocks, &block{kind: codeBlock, text: strings.Join(code, "\n"), lang: lang})
+			continue
+		}
+
+		if level, text, ok := heading(line); ok {
+			blocks = append(blocks, &block{kind: headingBlock, level: level, text: text})
+			i++
+			continue
+		}
+
+		if isRule(line) {
+			blocks = append(blocks, &block{kind: ruleBlock})
+			i++
+			continue
+		}
+
+		if _, ok := quoteLine(line); ok {
+			var quoted []string
+			for i < len(lines) {
+				text, ok := quoteLine(lines[i])
+				if !ok {
+					break
+				}
+				quoted = append(quoted, text)
+				i++
+			}
+			blocks = append(blocks, &block{kind: quoteBlock, children: parseBlocks(quoted)})
+			continue
+		}
+
+		if _, _, ok := listMarker(line); ok {
+			var list *block
+			list, i = parseList(lines, i)
+			blocks = append(blocks, list)
+			continue
+		}
+
+		// Trailing spaces are kept for hard line breaks
+		paragraph := []string{strings.TrimLeft(line, " ")}
+		i++
+		for i < len(lines) && !isBlank(lines[i]) && !startsBlock(lines[i]) {
+			paragraph = append(paragraph, strings.TrimLeft(lines[i], " "))
+			i++
+		}
+		text := strings.TrimRight(strings.Join(paragraph, "\n"), " ")
+		blocks = append(blocks, &block{kind: paragraphBlock, text: text})
+	}
+
+	return blocks
+}
+
+// parseList reads the list starting at lines[i]. Lines indented past an
+// item's marker belong to the item and are parsed as blocks of their own,
+// which is how lists nest.
+func parseList(lines []string, i int) (*block, int) {
+	first, _, _ := listMarker(lines[i])
+	list := &block{kind: listBlock, ordered: first.ordered, start: first.start}
+
+	for i < len(lines) {
+		m, text, ok := listMarker(lines[i])
+		if !ok || !m.sameList(first) {
+			break
+		}
+
+		itemLines := []string{text}
+		i++
+		for i < len(lines) {
+			line := lines[i]
+			if isBlank(line) {
+				// A blank line only stays in the item when the item goes on
+				// after it
+				next := i
+				for next < len(lines) && isBlank(lines[next]) {
+					next++
+				}
+				if next == len(lines) || indentOf(lines[next]) < m.width {
+					break
+				}
+				for ; i < next; i++ {
+					itemLines = append(itemLines, "")
+				}
+				continue
+			}
+
+			if indentOf(line) >= m.width {
+				itemLines = append(itemLines, line[m.width:])
+				i++
+				continue
+			}
+
+			// A paragraph can run on without indentation
+			if !startsBlock(line) && !isBlank(itemLines[len(itemLines)-1]) {
+				itemLines = append(itemLines, strings.TrimLeft(line, " "))
+				i++
+				continue
+			}
+
+			break
+		}
+
+		item := &block{kind: itemBlock}
+		item.task, item.checked, itemLines[0] = taskMarker(itemLines[0])
+		item.children = parseBlocks(itemLines)
+		list.children = append(list.children, item)
+
+		// Items of a loose list are separated by blank lines
+		next := i
+		for next < len(lines) && isBlank(lines[next]) {
+			next++
+		}
+		if next < len(lines) {
+			if m, _, ok := listMarker(lines[next]); ok && m.sameList(first) {
+				i = next
+			}
+		}
+	}
+
+	return list, i
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// startsBlock reports whether line interrupts a paragraph
+func startsBlock(line string) bool {
+	if _, _, ok := openFence(line); ok {
+		return true
+	}
+	if _, _, ok := heading(line); ok {
+		return true
+	}
+	if _, ok := quoteLine(line); ok {
+		return true
+	}
+	if _, _, ok := listMarker(line); ok {
+		return true
+	}
+	return isRule(line)
+}
+
+func openFence(line string) (fence string, lang string, ok bool) {
+	if indentOf(line) > 3 {
+		return "", "", false
+	}
+
+	trimmed := strings.TrimSpace(line)
+	for _, f := range []string{"```", "~~~"} {
+		if strings.HasPrefix(trimmed, f) {
+			info := strings.TrimSpace(strings.TrimLeft(trimmed, f[:1]))
+			if f == "```" && strings.Contains(info, "`") {
+				return "", "", false
+			}
+			lang, _, _ := strings.Cut(info, " ")
+			return f, lang, true
+		}
+	}
+	return "", "", false
+}
+
+func closesFence(line string, fence string) bool {
+	trimmed := strings.TrimSpace(line)
+	return indentOf(line) <= 3 && strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == ""
+}
+
+func heading(line string) (int, string, bool) {
+	if indentOf(line) > 3 {
+		return 0, "", false
+	}
+
+	trimmed := strings.TrimSpace(line)
+	level := len(trimmed) - len(strings.TrimLeft(trimmed, "#"))
+	if level == 0 || level > 6 {
+		return 0, "", false
+	}
+
+	text := trimmed[level:]
+	if text != "" && text[0] != ' ' {
+		return 0, "", false
+	}
+
+	// Closing hashes are decoration
+	text = strings.TrimSpace(text)
+	if closed := strings.TrimRight(text, "#"); closed == "" || strings.HasSuffix(closed, " ") {
+		text = strings.TrimSpace(closed)
+	}
+	return level, text, true
+}
+
+func isRule(line string) bool {
+	if indentOf(line) > 3 {
+		return false
+	}
+
+	compact := strings.ReplaceAll(strings.TrimSpace(line), " ", "")
+	if len(compact) < 3 {
+		return false
+	}
+	c := compact[0]
+	return (c == '-' || c == '*' || c == '_') && strings.Count(compact, string(c)) == len(compact)
+}
+
+func quoteLine(line string) (string, bool) {
+	if indentOf(line) > 3 {
+		return "", false
+	}
+
+	trimmed := strings.TrimLeft(line, " ")
+	if !strings.HasPrefix(trimmed, ">") {
+		return "", false
+	}
+	text := trimmed[1:]
+	return strings.TrimPrefix(text, " "), true
+}
+
+type marker struct {
+	ordered bool
+	bullet  byte
+	start   int
+	// width is where the item's text starts, which its continuation lines
+	// are indented to
+	width int
+}
+
+func (m marker) sameList(first marker) bool {
+	return m.ordered == first.ordered && m.bullet == first.bullet
+}
+
+func listMarker(line string) (marker, string, bool) {
+	indent := indentOf(line)
+	if indent > 3 || isRule(line) {
+		return marker{}, "", false
+	}
+
+	rest := line[indent:]
+	var m marker
+	var markerLen int
+
+	switch {
+	case rest == "":
+		return marker{}, "", false
+	case rest[0] == '-' || rest[0] == '*' || rest[0] == '+':
+		m.bullet = rest[0]
+		markerLen = 1
+	default:
+		digits := len(rest) - len(strings.TrimLeft(rest, "0123456789"))
+		if digits == 0 || digits > 9 || digits == len(rest) || (rest[digits] != '.' && rest[digits] != ')') {
+			return marker{}, "", false
+		}
+		m.ordered = true
+		m.bullet = rest[digits]
+		m.start, _ = strconv.Atoi(rest[:digits])
+		markerLen = digits + 1
+	}
+
+	text := rest[markerLen:]
+	if text == "" {
+		m.width = indent + markerLen + 1
+		return m, "", true
+	}
+	if text[0] != ' ' {
+		return marker{}, "", false
+	}
+
+	spaces := indentOf(text)
+	if spaces > 4 {
+		// Indented code in an item isn't supported, so keep the text
+		spaces = 1
+	}
+	m.width = indent + markerLen + spaces
+	return m, text[spaces:], true
+}
+
+// taskMarker strips a leading [ ] or [x] from the first line of an item
+func taskMarker(text string) (task bool, checked bool, rest string) {
+	if len(text) < 3 || text[0] != '[' || text[2] != ']' {
+		return false, false, text
+	}
+	if len(text) > 3 && text[3] != ' ' {
+		return false, false, text
+	}
+
+	switch text[1] {
+	case ' ':
+		return true, false, strings.TrimPrefix(text[3:], " ")
+	case 'x', 'X':
+		return true, true, strings.TrimPrefix(text[3:], " ")
+	}
+	return false, false, text
+}
+
+type renderer struct {
+	dst       []byte
+	checklist []ChecklistItem
+}
+
+func (r *renderer) blocks(blocks []*block) {
+	for _, b := range blocks {
+		r.block(b)
+	}
+}
+
+func (r *renderer) block(b *block) {
+	switch b.kind {
+	case paragraphBlock:
+		r.dst = append(r.dst, "<p>"...)
+		r.dst = appendInline(r.dst, b.text, false)
+		r.dst = append(r.dst, "</p>\n"...)
+
+	case headingBlock:
+		level := strconv.Itoa(b.level)
+		r.dst = append(r.dst, "<h"+level+">"...)
+		r.dst = appendInline(r.dst, b.text, false)
+		r.dst = append(r.dst, "</h"+level+">\n"...)
+
+	case codeBlock:
+		r.dst = append(r.dst, "<pre><code"...)
+		if lang := codeLanguage(b.lang); lang != "" {
+			r.dst = append(r.dst, ` class="language-`+lang+`"`...)
+		}
+		r.dst = append(r.dst, '>')
+		if b.text != "" {
+			r.dst = appendEscaped(r.dst, b.text+"\n")
+		}
+		r.dst = append(r.dst, "</code></pre>\n"...)
+
+	case quoteBlock:
+		r.dst = append(r.dst, "<blockquote>\n"...)
+		r.blocks(b.children)
+		r.dst = append(r.dst, "</blockquote>\n"...)
+
+	case listBlock:
+		switch {
+		case !b.ordered:
+			r.dst = append(r.dst, "<ul>\n"...)
+		case b.start != 1:
+			r.dst = append(r.dst, `<ol start="`+strconv.Itoa(b.start)+`">`+"\n"...)
+		default:
+			r.dst = append(r.dst, "<ol>\n"...)
+		}
+		for _, item := range b.children {
+			r.item(item)
+		}
+		if b.ordered {
+			r.dst = append(r.dst, "</ol>\n"...)
+		} else {
+			r.dst = append(r.dst, "</ul>\n"...)
+		}
+
+	case ruleBlock:
+		r.dst = append(r.dst, "<hr>\n"...)
+	}
+}
+
+// item writes a list item. A paragraph leading the item is written without
+// <p>, so simple lists stay compact.
+func (r *renderer) item(item *block) {
+	children := item.children
+
+	if item.task {
+		index := len(r.checklist)
+		text := ""
+		if len(children) > 0 && children[0].kind == paragraphBlock {
+			text = children[0].text
+		}
+		r.checklist = append(r.checklist, ChecklistItem{Index: index, Text: text, Checked: item.checked})
+
+		r.dst = append(r.dst, `<li class="task-list-item"><input type="checkbox" disabled`...)
+		if item.checked {
+			r.dst = append(r.dst, " checked"...)
+		}
+		r.dst = append(r.dst, ` data-checklist-index="`+strconv.Itoa(index)+`"> `...)
+	} else {
+		r.dst = append(r.dst, "<li>"...)
+	}
+
+	if len(children) > 0 && children[0].kind == paragraphBlock {
+		r.dst = appendInline(r.dst, children[0].text, false)
+		children = children[1:]
+	}
+	if len(children) > 0 {
+		r.dst = append(r.dst, '\n')
+		r.blocks(children)
+	}
+
+	r.dst = append(r.dst, "</li>\n"...)
+}
+
+// codeLanguage keeps the language of a code block if it is a plain name
+func codeLanguage(lang string) string {
+	if lang == "" || len(lang) > 32 {
+		return ""
+	}
+	for _, c := range lang {
+		if !isAlnum(c) && c != '-' && c != '_' && c != '+' && c != '#' && c != '.' {
+			return ""
+		}
+	}
+	return lang
+}
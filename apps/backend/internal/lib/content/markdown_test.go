@@ -0,0 +1,189 @@
+package content_test
+
+import (
+	"testing"
+
+	"github.com/mabhi256/tasker/internal/lib/content"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		html     string
+	}{
+		{
+			name:     "paragraphs",
+			markdown: "First line\nsame paragraph\n\nSecond paragraph",
+			html:     "<p>First line\nsame paragraph</p>\n<p>Second paragraph</p>",
+		},
+		{
+			name:     "headings",
+			markdown: "# Title\n### Section ###\n#not a heading",
+			html:     "<h1>Title</h1>\n<h3>Section</h3>\n<p>#not a heading</p>",
+		},
+		{
+			name:     "emphasis",
+			markdown: "**bold** *italic* __strong__ _em_ ~~gone~~ snake_case_name",
+			html:     "<p><strong>bold</strong> <em>italic</em> <strong>strong</strong> <em>em</em> <del>gone</del> snake_case_name</p>",
+		},
+		{
+			name:     "unmatched emphasis",
+			markdown: "2 * 3 * 4 and **open",
+			html:     "<p>2 * 3 * 4 and **open</p>",
+		},
+		{
+			name:     "code span",
+			markdown: "run `go test ./...` or ``a ` b``",
+			html:     "<p>run <code>go test ./...</code> or <code>a ` b</code></p>",
+		},
+		{
+			name:     "code block",
+			markdown: "```go\nif a < b {\n\treturn\n}\n```",
+			html:     "<pre><code class=\"language-go\">if a &lt; b {\n    return\n}\n</code></pre>",
+		},
+		{
+			name:     "code block language is sanitized",
+			markdown: "```\"><script>\nx\n```",
+			html:     "<pre><code>x\n</code></pre>",
+		},
+		{
+			name:     "quote",
+			markdown: "> quoted\n> **text**",
+			html:     "<blockquote>\n<p>quoted\n<strong>text</strong></p>\n</blockquote>",
+		},
+		{
+			name:     "rule",
+			markdown: "above\n\n---\n\nbelow",
+			html:     "<p>above</p>\n<hr>\n<p>below</p>",
+		},
+		{
+			name:     "lists",
+			markdown: "- one\n- two\n  1. nested\n  2. list\n\n3. three\n4. four",
+			html: "<ul>\n<li>one</li>\n<li>two\n<ol>\n<li>nested</li>\n<li>list</li>\n</ol>\n</li>\n</ul>\n" +
+				"<ol start=\"3\">\n<li>three</li>\n<li>four</li>\n</ol>",
+		},
+		{
+			name:     "links",
+			markdown: "[docs](https://example.com/docs \"The docs\") and [board](/workspaces/1)",
+			html: "<p><a href=\"https://example.com/docs\" title=\"The docs\" rel=\"nofollow noopener noreferrer\">docs</a> and " +
+				"<a href=\"/workspaces/1\" rel=\"nofollow noopener noreferrer\">board</a></p>",
+		},
+		{
+			name:     "bare and angle links",
+			markdown: "See https://example.com/a_(b). Or <mailto:team@example.com>",
+			html: "<p>See <a href=\"https://example.com/a_(b)\" rel=\"nofollow noopener noreferrer\">https://example.com/a_(b)</a>. " +
+				"Or <a href=\"mailto:team@example.com\" rel=\"nofollow noopener noreferrer\">mailto:team@example.com</a></p>",
+		},
+		{
+			name:     "images become links",
+			markdown: "![diagram](https://example.com/d.png)",
+			html:     "<p><a href=\"https://example.com/d.png\" rel=\"nofollow noopener noreferrer\">diagram</a></p>",
+		},
+		{
+			name:     "hard line breaks",
+			markdown: "one  \ntwo\\\nthree",
+			html:     "<p>one<br>\ntwo<br>\nthree</p>",
+		},
+		{
+			name:     "backslash escapes",
+			markdown: "\\*not italic\\* and \\[not a link\\](x)",
+			html:     "<p>*not italic* and [not a link](x)</p>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.html, content.Render(tt.markdown).HTML)
+		})
+	}
+}
+
+func TestRenderSanitizes(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		html     string
+	}{
+		{
+			name:     "raw html is escaped",
+			markdown: "<script>alert('x')</script>",
+			html:     "<p>&lt;script&gt;alert(&#39;x&#39;)&lt;/script&gt;</p>",
+		},
+		{
+			name:     "attributes can't be injected",
+			markdown: "<img src=x onerror=alert(1)>",
+			html:     "<p>&lt;img src=x onerror=alert(1)&gt;</p>",
+		},
+		{
+			name:     "javascript links keep only their text",
+			markdown: "[click](javascript:alert(1)) [caps](JavaScript:alert(1))",
+			html:     "<p>click caps</p>",
+		},
+		{
+			name:     "data and vbscript links keep only their text",
+			markdown: "[a](data:text/html;base64,PHNjcmlwdD4=) [b](vbscript:msgbox)",
+			html:     "<p>a b</p>",
+		},
+		{
+			name:     "links with control characters are dropped",
+			markdown: "[x](java\x00script:alert(1))",
+			html:     "<p>x</p>",
+		},
+		{
+			name:     "quotes in urls are escaped",
+			markdown: "[x](https://example.com/\"onmouseover=\"alert(1))",
+			html:     "<p><a href=\"https://example.com/&quot;onmouseover=&quot;alert(1)\" rel=\"nofollow noopener noreferrer\">x</a></p>",
+		},
+		{
+			name:     "angle links need a safe scheme",
+			markdown: "<javascript:alert(1)>",
+			html:     "<p>&lt;javascript:alert(1)&gt;</p>",
+		},
+		{
+			name:     "html in code is escaped",
+			markdown: "`<b>` and\n\n```\n</code><script>\n```",
+			html:     "<p><code>&lt;b&gt;</code> and</p>\n<pre><code>&lt;/code&gt;&lt;script&gt;\n</code></pre>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.html, content.Render(tt.markdown).HTML)
+		})
+	}
+}
+
+func TestRenderChecklist(t *testing.T) {
+	markdown := "Release:\n\n" +
+		"- [x] Tag the build\n" +
+		"- [ ] Write **notes**\n" +
+		"  - [X] Collect PRs\n" +
+		"- [not a task]\n" +
+		"\n" +
+		"1. [ ] Announce"
+
+	rendered := content.Render(markdown)
+
+	assert.Equal(t, []content.ChecklistItem{
+		{Index: 0, Text: "Tag the build", Checked: true},
+		{Index: 1, Text: "Write **notes**", Checked: false},
+		{Index: 2, Text: "Collect PRs", Checked: true},
+		{Index: 3, Text: "Announce", Checked: false},
+	}, rendered.Checklist)
+
+	assert.Equal(t, "<p>Release:</p>\n<ul>\n"+
+		"<li class=\"task-list-item\"><input type=\"checkbox\" disabled checked data-checklist-index=\"0\"> Tag the build</li>\n"+
+		"<li class=\"task-list-item\"><input type=\"checkbox\" disabled data-checklist-index=\"1\"> Write <strong>notes</strong>\n"+
+		"<ul>\n<li class=\"task-list-item\"><input type=\"checkbox\" disabled checked data-checklist-index=\"2\"> Collect PRs</li>\n</ul>\n</li>\n"+
+		"<li>[not a task]</li>\n</ul>\n"+
+		"<ol>\n<li class=\"task-list-item\"><input type=\"checkbox\" disabled data-checklist-index=\"3\"> Announce</li>\n</ol>",
+		rendered.HTML)
+}
+
+func TestChecklistEmpty(t *testing.T) {
+	assert.Empty(t, content.Checklist(""))
+	assert.Empty(t, content.Checklist("- plain item\n- [] not a task"))
+	assert.Equal(t, "", content.Render("").HTML)
+}
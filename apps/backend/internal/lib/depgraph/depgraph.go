@@ -0,0 +1,110 @@
+// Package depgraph orders work by what it is blocked by.
+package depgraph
+
+import "container/heap"
+
+// Graph holds "node is blocked by" edges
+type Graph[K comparable] struct {
+	blockedBy map[K][]K
+}
+
+func New[K comparable]() *Graph[K] {
+	return &Graph[K]{blockedBy: make(map[K][]K)}
+}
+
+// Add records that node can't be done before blocker
+func (g *Graph[K]) Add(node, blocker K) {
+	g.blockedBy[node] = append(g.blockedBy[node], blocker)
+}
+
+// DependsOn reports whether node is blocked by other, directly or through
+// the nodes blocking it. Adding "other is blocked by node" then makes a cycle.
+func (g *Graph[K]) DependsOn(node, other K) bool {
+	seen := map[K]bool{node: true}
+	stack := []K{node}
+
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		for _, blocker := range g.blockedBy[current] {
+			if blocker == other {
+				return true
+			}
+			if !seen[blocker] {
+				seen[blocker] = true
+				stack = append(stack, blocker)
+			}
+		}
+	}
+
+	return false
+}
+
+// Sort orders nodes so each comes after the nodes blocking it, and keeps them
+// in the given order otherwise. Blockers missing from nodes are ignored.
+// Nodes caught in a cycle can't be ordered, and are appended in the given
+// order.
+func (g *Graph[K]) Sort(nodes []K) []K {
+	index := make(map[K]int, len(nodes))
+	for i, node := range nodes {
+		index[node] = i
+	}
+
+	waitingOn := make([]int, len(nodes))
+	unblocks := make([][]int, len(nodes))
+	for i, node := range nodes {
+		for _, blocker := range g.blockedBy[node] {
+			if j, ok := index[blocker]; ok {
+				waitingOn[i]++
+				unblocks[j] = append(unblocks[j], i)
+			}
+		}
+	}
+
+	ready := &minHeap{}
+	for i := range nodes {
+		if waitingOn[i] == 0 {
+			heap.Push(ready, i)
+		}
+	}
+
+	sorted := make([]K, 0, len(nodes))
+	placed := make([]bool, len(nodes))
+	for ready.Len() > 0 {
+		i := heap.Pop(ready).(int)
+		sorted = append(sorted, nodes[i])
+		placed[i] = true
+
+		for _, j := range unblocks[i] {
+			waitingOn[j]--
+			if waitingOn[j] == 0 {
+				heap.Push(ready, j)
+			}
+		}
+	}
+
+	for i, node := range nodes {
+		if !placed[i] {
+			sorted = append(sorted, node)
+		}
+	}
+
+	return sorted
+}
+
+// minHeap pops the earliest of the nodes that are ready
+type minHeap []int
+
+func (h minHeap) Len() int           { return len(h) }
+func (h minHeap) Less(i, j int) bool { return h[i] < h[j] }
+func (h minHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *minHeap) Push(x any)        { *h = append(*h, x.(int)) }
+
+func (h *minHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
@@ -0,0 +1,55 @@
+package depgraph_test
+
+import (
+	"testing"
+
+	"github.com/mabhi256/tasker/internal/lib/depgraph"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDependsOn(t *testing.T) {
+	g := depgraph.New[string]()
+	g.Add("deploy", "test")
+	g.Add("test", "build")
+	g.Add("docs", "build")
+
+	assert.True(t, g.DependsOn("deploy", "test"))
+	assert.True(t, g.DependsOn("deploy", "build"))
+	assert.False(t, g.DependsOn("build", "deploy"))
+	assert.False(t, g.DependsOn("docs", "test"))
+	assert.False(t, g.DependsOn("unknown", "build"))
+}
+
+func TestSort(t *testing.T) {
+	g := depgraph.New[string]()
+	g.Add("deploy", "test")
+	g.Add("test", "build")
+	g.Add("deploy", "docs")
+
+	sorted := g.Sort([]string{"deploy", "docs", "test", "build", "email"})
+	assert.Equal(t, []string{"docs", "build", "test", "deploy", "email"}, sorted)
+}
+
+func TestSortKeepsOrderWithoutDependencies(t *testing.T) {
+	g := depgraph.New[int]()
+
+	assert.Equal(t, []int{3, 1, 2}, g.Sort([]int{3, 1, 2}))
+	assert.Empty(t, g.Sort(nil))
+}
+
+func TestSortIgnoresMissingBlockers(t *testing.T) {
+	g := depgraph.New[string]()
+	g.Add("b", "done")
+	g.Add("a", "b")
+
+	assert.Equal(t, []string{"b", "a"}, g.Sort([]string{"a", "b"}))
+}
+
+func TestSortAppendsCycles(t *testing.T) {
+	g := depgraph.New[string]()
+	g.Add("a", "b")
+	g.Add("b", "a")
+	g.Add("c", "a")
+
+	assert.Equal(t, []string{"d", "a", "b", "c"}, g.Sort([]string{"a", "b", "c", "d"}))
+}
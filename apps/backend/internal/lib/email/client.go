@@ -1,51 +1,141 @@
 package email
 
 import (
-	"bytes"
+	"context"
 	"fmt"
-	"html/template"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"time"
 
 	"github.com/mabhi256/tasker/internal/config"
+	"github.com/mabhi256/tasker/internal/lib/usage"
+	"github.com/mabhi256/tasker/templates"
+	"github.com/newrelic/go-agent/v3/newrelic"
 	"github.com/resend/resend-go/v2"
 	"github.com/rs/zerolog"
 )
 
+const sendTimeout = 30 * time.Second
+
 type Client struct {
-	client *resend.Client
-	logger *zerolog.Logger
+	client    *resend.Client
+	templates *Registry
+	logger    *zerolog.Logger
+	meter     *usage.Meter
+	// appURL resolves the relative links of emails, when set
+	appURL *url.URL
+	// links and tracker track the emails sent to a recipient, when tracking
+	// is enabled
+	links   *TrackingLinks
+	tracker Tracker
 }
 
-func NewClient(cfg *config.Config, logger *zerolog.Logger) *Client {
-	return &Client{
-		client: resend.NewClient(cfg.Email.ResendAPIKey),
-		logger: logger,
+// NewClient loads the embedded email templates and fails if any of them is
+// broken. Sends are counted by meter, which may be nil.
+func NewClient(cfg *config.Config, logger *zerolog.Logger, meter *usage.Meter) (*Client, error) {
+	emails, err := fs.Sub(templates.Emails, "emails")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open email templates: %w", err)
 	}
+
+	registry, err := NewRegistry(emails)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load email templates: %w", err)
+	}
+
+	var appURL *url.URL
+	if cfg.Email.AppURL != "" {
+		appURL, err = url.Parse(cfg.Email.AppURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse email app URL: %w", err)
+		}
+	}
+
+	var links *TrackingLinks
+	if tracking := cfg.Email.Tracking; tracking != nil && tracking.Enabled {
+		links = NewTrackingLinks(tracking.BaseURL, tracking.SigningKey)
+	}
+
+	return &Client{
+		// Sends show up as external calls of the transaction in their context,
+		// continuing the trace of the request or job that sent them
+		client: resend.NewCustomClient(&http.Client{
+			Timeout:   sendTimeout,
+			Transport: newrelic.NewRoundTripper(http.DefaultTransport),
+		}, cfg.Email.ResendAPIKey),
+		templates: registry,
+		logger:    logger,
+		meter:     meter,
+		appURL:    appURL,
+		links:     links,
+	}, nil
+}
+
+// SetTracker tracks the emails sent to a recipient with tracker, if
+// tracking is enabled
+func (c *Client) SetTracker(tracker Tracker) {
+	c.tracker = tracker
+}
+
+type localeKey struct{}
+
+// WithLocale returns a context in which emails are rendered in the variant
+// for locale, if there is one
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
 }
 
-func (c *Client) SendEmail(to, subject string, templateName Template, data map[string]any) error {
-	tmplPath := fmt.Sprintf("templates/emails/%s.html", templateName)
+func (c *Client) SendEmail(ctx context.Context, to, subject string, templateName Template, data map[string]any) error {
+	locale, _ := ctx.Value(localeKey{}).(string)
 
-	tmpl, err := template.ParseFiles(tmplPath)
+	body, err := c.templates.Render(templateName, locale, data)
 	if err != nil {
-		return fmt.Errorf("failed to parse email template %s: %w", templateName, err)
+		return err
 	}
 
-	var body bytes.Buffer
-	if err := tmpl.Execute(&body, data); err != nil {
-		return fmt.Errorf("failed to execute email template %s: %w", templateName, err)
+	if c.appURL != nil {
+		body.HTML = resolveLinks(body.HTML, c.appURL)
 	}
+	body.HTML = c.track(ctx, to, templateName, body.HTML)
 
 	params := &resend.SendEmailRequest{
 		From:    fmt.Sprintf("%s <%s>", "Tasker", "onboarding@resend.dev"),
 		To:      []string{to},
 		Subject: subject,
-		Html:    body.String(),
+		Html:    body.HTML,
+		Text:    body.Text,
 	}
 
-	_, err = c.client.Emails.Send(params)
+	_, err = c.client.Emails.SendWithContext(ctx, params)
 	if err != nil {
 		return fmt.Errorf("failed to send email: %w", err)
 	}
 
+	c.meter.AddEmailSent(ctx)
+
 	return nil
 }
+
+// track instruments the HTML body of an email to a recipient who is
+// tracked. The plaintext alternative is never tracked. An email that can't
+// be recorded is sent untracked rather than not at all.
+func (c *Client) track(ctx context.Context, to string, templateName Template, body string) string {
+	userID, ok := ctx.Value(recipientKey{}).(string)
+	if !ok || c.links == nil || c.tracker == nil {
+		return body
+	}
+
+	id, tracked, err := c.tracker.Track(ctx, userID, to, templateName)
+	if err != nil {
+		c.logger.Warn().Err(err).
+			Str("template", string(templateName)).
+			Msg("failed to record tracked email, sending it untracked")
+		return body
+	}
+	if !tracked {
+		return body
+	}
+
+	return c.links.Instrument(body, id)
+}
@@ -1,19 +1,22 @@
 package email
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/mabhi256/tasker/internal/model/todo"
+	"github.com/mabhi256/tasker/internal/model/weeklyreport"
 )
 
-func (c *Client) SendWelcomeEmail(to, firstName string) error {
+func (c *Client) SendWelcomeEmail(ctx context.Context, to, firstName string) error {
 	data := map[string]any{
 		"UserFirstName": firstName,
 	}
 
 	return c.SendEmail(
+		ctx,
 		to,
 		"Welcome to Tasker!",
 		TemplateWelcome,
@@ -21,7 +24,9 @@ func (c *Client) SendWelcomeEmail(to, firstName string) error {
 	)
 }
 
-func (c *Client) SendDueDateReminderEmail(to, todoTitle string, todoID uuid.UUID, dueDate time.Time) error {
+func (c *Client) SendDueDateReminderEmail(ctx context.Context, to, todoTitle string, todoID uuid.UUID,
+	dueDate time.Time,
+) error {
 	data := map[string]any{
 		"TodoTitle":    todoTitle,
 		"TodoID":       todoID.String(),
@@ -30,6 +35,7 @@ func (c *Client) SendDueDateReminderEmail(to, todoTitle string, todoID uuid.UUID
 	}
 
 	return c.SendEmail(
+		ctx,
 		to,
 		fmt.Sprintf("Reminder: '%s' is due soon", todoTitle),
 		TemplateDueDateReminder,
@@ -37,7 +43,9 @@ func (c *Client) SendDueDateReminderEmail(to, todoTitle string, todoID uuid.UUID
 	)
 }
 
-func (c *Client) SendOverdueNotificationEmail(to, todoTitle string, todoID uuid.UUID, dueDate time.Time) error {
+func (c *Client) SendOverdueNotificationEmail(ctx context.Context, to, todoTitle string, todoID uuid.UUID,
+	dueDate time.Time,
+) error {
 	data := map[string]any{
 		"TodoTitle":   todoTitle,
 		"TodoID":      todoID.String(),
@@ -46,6 +54,7 @@ func (c *Client) SendOverdueNotificationEmail(to, todoTitle string, todoID uuid.
 	}
 
 	return c.SendEmail(
+		ctx,
 		to,
 		fmt.Sprintf("Overdue: '%s' needs your attention", todoTitle),
 		TemplateOverdueNotification,
@@ -53,26 +62,166 @@ func (c *Client) SendOverdueNotificationEmail(to, todoTitle string, todoID uuid.
 	)
 }
 
-func (c *Client) SendWeeklyReportEmail(to string, weekStart, weekEnd time.Time,
-	completedCount, activeCount, overdueCount int, completedTodos, overdueTodos []todo.PopulatedTodo,
-) error {
+// SendWeeklyReportEmail sends the report, whose week is on the wall clock of
+// the recipient's timezone
+func (c *Client) SendWeeklyReportEmail(ctx context.Context, to string, report *weeklyreport.Report) error {
+	// The week ends at the start of the next Monday, so it is titled up to
+	// the Sunday before
+	lastDay := report.WeekEnd.AddDate(0, 0, -1)
+
 	data := map[string]any{
-		"WeekStart":      weekStart.Format("January 2, 2006"),
-		"WeekEnd":        weekEnd.Format("January 2, 2006"),
-		"CompletedCount": completedCount,
-		"ActiveCount":    activeCount,
-		"OverdueCount":   overdueCount,
-		"CompletedTodos": completedTodos,
-		"OverdueTodos":   overdueTodos,
-		"HasCompleted":   completedCount > 0,
-		"HasOverdue":     overdueCount > 0,
+		"WeekStart":      report.WeekStart.Format("January 2, 2006"),
+		"WeekEnd":        lastDay.Format("January 2, 2006"),
+		"CreatedCount":   report.CreatedCount,
+		"CompletedCount": report.CompletedCount,
+		"ActiveCount":    report.ActiveCount,
+		"OverdueCount":   report.OverdueCount,
+		"CompletionRate": report.CompletionRate(),
+		"ActiveDays":     report.ActiveDays,
+		"Streak":         report.Streak,
+		"TopCategories":  report.TopCategories,
 	}
 
 	return c.SendEmail(
+		ctx,
 		to,
 		fmt.Sprintf("Your Weekly Productivity Report (%s - %s)",
-			weekStart.Format("Jan 2"), weekEnd.Format("Jan 2")),
+			report.WeekStart.Format("Jan 2"), lastDay.Format("Jan 2")),
 		TemplateWeeklyReport,
 		data,
 	)
 }
+
+func (c *Client) SendExportFailedEmail(ctx context.Context, to, scheduleName string, scheduleID uuid.UUID,
+	destination string, failedAt time.Time, attempts int, errMsg string,
+) error {
+	data := map[string]any{
+		"ScheduleName": scheduleName,
+		"ScheduleID":   scheduleID.String(),
+		"Destination":  destination,
+		"FailedAt":     failedAt.UTC().Format("Monday, January 2, 2006 at 3:04 PM MST"),
+		"Attempts":     attempts,
+		"Error":        errMsg,
+	}
+
+	return c.SendEmail(
+		ctx,
+		to,
+		fmt.Sprintf("Export failed: '%s'", scheduleName),
+		TemplateExportFailed,
+		data,
+	)
+}
+
+func (c *Client) SendJobFailedEmail(ctx context.Context, to, taskType, queue, taskID string,
+	failedAt time.Time, attempts, occurrences int, errMsg string,
+) error {
+	data := map[string]any{
+		"TaskType":    taskType,
+		"Queue":       queue,
+		"TaskID":      taskID,
+		"FailedAt":    failedAt.UTC().Format("Monday, January 2, 2006 at 3:04 PM MST"),
+		"Attempts":    attempts,
+		"Occurrences": occurrences,
+		"Error":       errMsg,
+	}
+
+	return c.SendEmail(
+		ctx,
+		to,
+		fmt.Sprintf("Background task failed: %s", taskType),
+		TemplateJobFailed,
+		data,
+	)
+}
+
+// DigestItem is a todo listed in a digest, with When already formatted in the
+// recipient's timezone
+type DigestItem struct {
+	ID    string
+	Title string
+	When  string
+}
+
+func (c *Client) SendDigestEmail(ctx context.Context, to, frequency string, periodStart, periodEnd time.Time,
+	overdue, dueToday, completed []DigestItem,
+) error {
+	// A daily digest is titled with the day it is sent, a weekly one with
+	// the week it covers
+	period := periodEnd.Format("Monday, January 2, 2006")
+	if periodEnd.Sub(periodStart) > 24*time.Hour {
+		period = fmt.Sprintf("%s - %s", periodStart.Format("January 2"), periodEnd.Format("January 2, 2006"))
+	}
+
+	frequencyTitle := strings.ToUpper(frequency[:1]) + frequency[1:]
+
+	data := map[string]any{
+		"Frequency":      frequency,
+		"FrequencyTitle": frequencyTitle,
+		"Period":         period,
+		"Overdue":        overdue,
+		"DueToday":       dueToday,
+		"Completed":      completed,
+	}
+
+	return c.SendEmail(
+		ctx,
+		to,
+		fmt.Sprintf("Your %s digest: %d overdue, %d due today", frequency, len(overdue), len(dueToday)),
+		TemplateDigest,
+		data,
+	)
+}
+
+func (c *Client) SendMentionEmail(ctx context.Context, to, author, todoTitle string, todoID uuid.UUID,
+	excerpt string,
+) error {
+	data := map[string]any{
+		"Author":    author,
+		"TodoTitle": todoTitle,
+		"TodoID":    todoID.String(),
+		"Excerpt":   excerpt,
+	}
+
+	return c.SendEmail(
+		ctx,
+		to,
+		fmt.Sprintf("%s mentioned you on '%s'", author, todoTitle),
+		TemplateMention,
+		data,
+	)
+}
+
+func (c *Client) SendReportReadyEmail(ctx context.Context, to, workspaceName, downloadURL string) error {
+	data := map[string]any{
+		"WorkspaceName": workspaceName,
+		"DownloadURL":   downloadURL,
+		"ExpiresIn":     "1 hour",
+	}
+
+	return c.SendEmail(
+		ctx,
+		to,
+		fmt.Sprintf("Your report for '%s' is ready", workspaceName),
+		TemplateReportReady,
+		data,
+	)
+}
+
+func (c *Client) SendAttachmentQuarantinedEmail(ctx context.Context, to, fileName, signature string,
+	todoID uuid.UUID,
+) error {
+	data := map[string]any{
+		"FileName":  fileName,
+		"Signature": signature,
+		"TodoID":    todoID.String(),
+	}
+
+	return c.SendEmail(
+		ctx,
+		to,
+		fmt.Sprintf("'%s' was quarantined", fileName),
+		TemplateAttachmentQuarantined,
+		data,
+	)
+}
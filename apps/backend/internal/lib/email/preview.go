@@ -1,7 +1,86 @@
 package email
 
-var PreviewData = map[string]map[string]string{
-	"welcome": {
+import "github.com/mabhi256/tasker/internal/model/weeklyreport"
+
+// PreviewData holds sample data for every template. It is what the registry
+// renders each template with when it loads, so it must provide every field
+// the template uses.
+var PreviewData = map[Template]map[string]any{
+	TemplateWelcome: {
 		"UserFirstName": "John",
 	},
+	TemplateDueDateReminder: {
+		"TodoTitle":    "Finish the quarterly report",
+		"TodoID":       "123e4567-e89b-12d3-a456-426614174000",
+		"DueDate":      "Friday, January 12, 2025 at 3:00 PM",
+		"DaysUntilDue": 2,
+	},
+	TemplateOverdueNotification: {
+		"TodoTitle":   "Finish the quarterly report",
+		"TodoID":      "123e4567-e89b-12d3-a456-426614174000",
+		"DueDate":     "Friday, January 12, 2025 at 3:00 PM",
+		"DaysOverdue": 3,
+	},
+	TemplateWeeklyReport: {
+		"WeekStart":      "January 6, 2025",
+		"WeekEnd":        "January 12, 2025",
+		"CreatedCount":   7,
+		"CompletedCount": 5,
+		"ActiveCount":    8,
+		"OverdueCount":   2,
+		"CompletionRate": 38,
+		"ActiveDays":     4,
+		"Streak":         6,
+		"TopCategories": []weeklyreport.Category{
+			{Name: "Work", Color: "#3b82f6", CompletedCount: 3},
+			{Name: "Personal", Color: "#10b981", CompletedCount: 2},
+		},
+	},
+	TemplateExportFailed: {
+		"ScheduleName": "Nightly backup",
+		"ScheduleID":   "123e4567-e89b-12d3-a456-426614174000",
+		"Destination":  "s3://tasker-exports",
+		"FailedAt":     "Friday, January 12, 2025 at 3:00 AM UTC",
+		"Attempts":     3,
+		"Error":        "endpoint responded with status 503",
+	},
+	TemplateJobFailed: {
+		"TaskType":    "webhook:deliver",
+		"Queue":       "default",
+		"TaskID":      "123e4567-e89b-12d3-a456-426614174000",
+		"FailedAt":    "Friday, January 12, 2025 at 3:00 AM UTC",
+		"Attempts":    9,
+		"Occurrences": 1,
+		"Error":       "endpoint responded with status 503",
+	},
+	TemplateDigest: {
+		"Frequency":      "daily",
+		"FrequencyTitle": "Daily",
+		"Period":         "Friday, January 12, 2025",
+		"Overdue": []DigestItem{
+			{ID: "123e4567-e89b-12d3-a456-426614174000", Title: "Finish the quarterly report", When: "Due Jan 10"},
+		},
+		"DueToday": []DigestItem{
+			{ID: "123e4567-e89b-12d3-a456-426614174001", Title: "Review pull requests", When: "Due 3:00 PM"},
+		},
+		"Completed": []DigestItem{
+			{ID: "123e4567-e89b-12d3-a456-426614174002", Title: "Book flights", When: "Completed Jan 11"},
+		},
+	},
+	TemplateMention: {
+		"Author":    "jane@example.com",
+		"TodoTitle": "Finish the quarterly report",
+		"TodoID":    "123e4567-e89b-12d3-a456-426614174000",
+		"Excerpt":   "@user_2abc could you double check the revenue numbers before Friday?",
+	},
+	TemplateReportReady: {
+		"WorkspaceName": "Acme Inc",
+		"DownloadURL":   "https://tasker-uploads.s3.amazonaws.com/reports/report.pdf",
+		"ExpiresIn":     "1 hour",
+	},
+	TemplateAttachmentQuarantined: {
+		"FileName":  "invoice.pdf",
+		"Signature": "Win.Test.EICAR_HDB-1",
+		"TodoID":    "123e4567-e89b-12d3-a456-426614174000",
+	},
 }
@@ -1,10 +1,295 @@
 package email
 
+import (
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Template names an email. Its HTML body, <name>.html, and plaintext
+// alternative, <name>.txt, define the "content" blocks rendered by the
+// layouts in layouts/, and the HTML body also defines a "preheader". The
+// HTML layout can use the partials in partials/.
+//
+// A variant for a locale lives in locales/<locale>/ and redefines any of
+// those blocks, falling back to the default for the rest.
 type Template string
 
 const (
-	TemplateWelcome             Template = "welcome"
-	TemplateDueDateReminder     Template = "due-date-reminder"
-	TemplateOverdueNotification Template = "overdue-notification"
-	TemplateWeeklyReport        Template = "weekly-report"
+	TemplateWelcome               Template = "welcome"
+	TemplateDueDateReminder       Template = "due-date-reminder"
+	TemplateOverdueNotification   Template = "overdue-notification"
+	TemplateWeeklyReport          Template = "weekly-report"
+	TemplateExportFailed          Template = "export-failed"
+	TemplateJobFailed             Template = "job-failed"
+	TemplateDigest                Template = "digest"
+	TemplateMention               Template = "mention"
+	TemplateReportReady           Template = "report-ready"
+	TemplateAttachmentQuarantined Template = "attachment-quarantined"
 )
+
+// Templates lists every email that can be sent. The registry refuses to load
+// unless each one exists.
+var Templates = []Template{
+	TemplateWelcome,
+	TemplateDueDateReminder,
+	TemplateOverdueNotification,
+	TemplateWeeklyReport,
+	TemplateExportFailed,
+	TemplateJobFailed,
+	TemplateDigest,
+	TemplateMention,
+	TemplateReportReady,
+	TemplateAttachmentQuarantined,
+}
+
+// DefaultLocale is used when no variant matches the recipient's locale
+const DefaultLocale = "en"
+
+// Rendered is an email body in both formats
+type Rendered struct {
+	HTML string
+	Text string
+}
+
+type variant struct {
+	name   Template
+	locale string
+}
+
+// Registry holds every email template parsed and checked up front, so a
+// broken template stops the server from starting instead of failing a send.
+type Registry struct {
+	html map[variant]*htmltemplate.Template
+	text map[variant]*texttemplate.Template
+}
+
+var templateFuncs = map[string]any{
+	"dict":        dict,
+	"currentYear": func() int { return time.Now().Year() },
+}
+
+// NewRegistry parses the templates in fsys and renders each variant with
+// its PreviewData to make sure it executes, e.g. that it only refers to
+// fields senders provide.
+func NewRegistry(fsys fs.FS) (*Registry, error) {
+	htmlBase, err := htmltemplate.New("").
+		Funcs(templateFuncs).
+		Option("missingkey=error").
+		ParseFS(fsys, "layouts/*.html", "partials/*.html")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse email layouts: %w", err)
+	}
+
+	textBase, err := texttemplate.New("").
+		Funcs(templateFuncs).
+		Option("missingkey=error").
+		ParseFS(fsys, "layouts/*.txt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse email layouts: %w", err)
+	}
+
+	r := &Registry{
+		html: make(map[variant]*htmltemplate.Template),
+		text: make(map[variant]*texttemplate.Template),
+	}
+
+	var errList []error
+
+	for _, name := range Templates {
+		errList = append(errList, r.parse(fsys, variant{name: name}, htmlBase, textBase))
+	}
+
+	locales, err := fs.ReadDir(fsys, "locales")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read email locales: %w", err)
+	}
+
+	for _, locale := range locales {
+		if !locale.IsDir() {
+			continue
+		}
+		errList = append(errList, r.parseLocale(fsys, locale.Name()))
+	}
+
+	if err := errors.Join(errList...); err != nil {
+		return nil, err
+	}
+
+	// Executing also escapes the HTML templates, so sends don't pay for it
+	for v := range r.html {
+		if _, err := r.render(v, PreviewData[v.name]); err != nil {
+			errList = append(errList, err)
+		}
+	}
+
+	if err := errors.Join(errList...); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// parse adds the default variant of a template on top of the base layouts
+func (r *Registry) parse(fsys fs.FS, v variant, htmlBase *htmltemplate.Template,
+	textBase *texttemplate.Template,
+) error {
+	html, err := htmlBase.Clone()
+	if err == nil {
+		html, err = html.ParseFS(fsys, string(v.name)+".html")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse email template %s: %w", v, err)
+	}
+
+	text, err := textBase.Clone()
+	if err == nil {
+		text, err = text.ParseFS(fsys, string(v.name)+".txt")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse email template %s: %w", v, err)
+	}
+
+	r.html[v] = html
+	r.text[v] = text
+	return nil
+}
+
+// parseLocale adds the variants in locales/<locale>. A variant may override
+// only one format; the other is taken from the default.
+func (r *Registry) parseLocale(fsys fs.FS, locale string) error {
+	dir := path.Join("locales", locale)
+
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return fmt.Errorf("failed to read email locale %s: %w", locale, err)
+	}
+
+	var errList []error
+
+	for _, entry := range entries {
+		ext := path.Ext(entry.Name())
+		name := Template(strings.TrimSuffix(entry.Name(), ext))
+		v := variant{name: name, locale: locale}
+
+		base := variant{name: name}
+		if r.html[base] == nil {
+			errList = append(errList, fmt.Errorf("email template %s has no default", v))
+			continue
+		}
+
+		// The default may have failed to parse, which is reported already
+		if r.html[v] == nil {
+			r.html[v] = r.html[base]
+			r.text[v] = r.text[base]
+		}
+
+		file := path.Join(dir, entry.Name())
+
+		switch ext {
+		case ".html":
+			html, err := r.html[v].Clone()
+			if err == nil {
+				html, err = html.ParseFS(fsys, file)
+			}
+			if err != nil {
+				errList = append(errList, fmt.Errorf("failed to parse email template %s: %w", v, err))
+				continue
+			}
+			r.html[v] = html
+		case ".txt":
+			text, err := r.text[v].Clone()
+			if err == nil {
+				text, err = text.ParseFS(fsys, file)
+			}
+			if err != nil {
+				errList = append(errList, fmt.Errorf("failed to parse email template %s: %w", v, err))
+				continue
+			}
+			r.text[v] = text
+		default:
+			errList = append(errList, fmt.Errorf("unexpected email template file %s", file))
+		}
+	}
+
+	return errors.Join(errList...)
+}
+
+// Render renders the variant of name that best matches locale: the exact
+// locale, then its language, then the default
+func (r *Registry) Render(name Template, locale string, data map[string]any) (*Rendered, error) {
+	for _, candidate := range localeCandidates(locale) {
+		v := variant{name: name, locale: candidate}
+		if _, ok := r.html[v]; ok {
+			return r.render(v, data)
+		}
+	}
+
+	return nil, fmt.Errorf("unknown email template %s", name)
+}
+
+func (r *Registry) render(v variant, data map[string]any) (*Rendered, error) {
+	// Layouts set the document language from the locale
+	locale := v.locale
+	if locale == "" {
+		locale = DefaultLocale
+	}
+	withLocale := make(map[string]any, len(data)+1)
+	for key, value := range data {
+		withLocale[key] = value
+	}
+	withLocale["Locale"] = locale
+
+	var html, text strings.Builder
+	if err := r.html[v].ExecuteTemplate(&html, "layout", withLocale); err != nil {
+		return nil, fmt.Errorf("failed to execute email template %s: %w", v, err)
+	}
+	if err := r.text[v].ExecuteTemplate(&text, "layout", withLocale); err != nil {
+		return nil, fmt.Errorf("failed to execute email template %s: %w", v, err)
+	}
+
+	return &Rendered{HTML: html.String(), Text: text.String()}, nil
+}
+
+func (v variant) String() string {
+	if v.locale == "" {
+		return string(v.name)
+	}
+	return v.locale + "/" + string(v.name)
+}
+
+// localeCandidates returns the variants to try for a locale such as
+// "pt-BR": "pt-BR", "pt" and the default
+func localeCandidates(locale string) []string {
+	var candidates []string
+	if locale != "" {
+		candidates = append(candidates, locale)
+		if language, _, ok := strings.Cut(locale, "-"); ok {
+			candidates = append(candidates, language)
+		}
+	}
+	return append(candidates, "")
+}
+
+// dict builds a map from key value pairs, for passing several values to a
+// partial
+func dict(pairs ...any) (map[string]any, error) {
+	if len(pairs)%2 != 0 {
+		return nil, errors.New("dict expects key value pairs")
+	}
+
+	m := make(map[string]any, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict key %v is not a string", pairs[i])
+		}
+		m[key] = pairs[i+1]
+	}
+	return m, nil
+}
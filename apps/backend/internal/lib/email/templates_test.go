@@ -0,0 +1,159 @@
+package email_test
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/mabhi256/tasker/internal/lib/email"
+	"github.com/mabhi256/tasker/templates"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func embeddedEmails(t *testing.T) fs.FS {
+	t.Helper()
+
+	emails, err := fs.Sub(templates.Emails, "emails")
+	require.NoError(t, err)
+	return emails
+}
+
+// withLocaleFiles copies the embedded templates and adds files under locales/
+func withLocaleFiles(t *testing.T, files map[string]string) fs.FS {
+	t.Helper()
+
+	fsys := fstest.MapFS{}
+	err := fs.WalkDir(embeddedEmails(t), ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(embeddedEmails(t), path)
+		if err != nil {
+			return err
+		}
+		fsys[path] = &fstest.MapFile{Data: data}
+		return nil
+	})
+	require.NoError(t, err)
+
+	for path, data := range files {
+		fsys["locales/"+path] = &fstest.MapFile{Data: []byte(data)}
+	}
+	return fsys
+}
+
+func TestEmbeddedTemplatesRender(t *testing.T) {
+	registry, err := email.NewRegistry(embeddedEmails(t))
+	require.NoError(t, err)
+
+	for _, name := range email.Templates {
+		t.Run(string(name), func(t *testing.T) {
+			rendered, err := registry.Render(name, "", email.PreviewData[name])
+			require.NoError(t, err)
+
+			assert.Contains(t, rendered.HTML, `<html dir="ltr" lang="en">`)
+			assert.Contains(t, rendered.HTML, "Tasker. All rights reserved.")
+			assert.NotContains(t, rendered.HTML, "<no value>")
+			assert.Contains(t, rendered.Text, "Tasker. All rights reserved.")
+			assert.NotContains(t, rendered.Text, "<")
+		})
+	}
+}
+
+func TestRenderEscapesData(t *testing.T) {
+	registry, err := email.NewRegistry(embeddedEmails(t))
+	require.NoError(t, err)
+
+	data := map[string]any{"UserFirstName": "<script>alert(1)</script>"}
+
+	rendered, err := registry.Render(email.TemplateWelcome, "", data)
+	require.NoError(t, err)
+
+	assert.NotContains(t, rendered.HTML, "<script>")
+	assert.Contains(t, rendered.HTML, "&lt;script&gt;")
+	// The plaintext alternative isn't HTML, so it isn't escaped
+	assert.Contains(t, rendered.Text, "Hi <script>alert(1)</script>,")
+}
+
+func TestRenderPicksLocaleVariant(t *testing.T) {
+	fsys := withLocaleFiles(t, map[string]string{
+		"es/welcome.html": `{{define "preheader"}}Bienvenido a Tasker{{end}}`,
+		"es/welcome.txt":  `{{define "content"}}¡Hola {{.UserFirstName}}!{{end}}`,
+	})
+
+	registry, err := email.NewRegistry(fsys)
+	require.NoError(t, err)
+
+	data := email.PreviewData[email.TemplateWelcome]
+
+	tests := []struct {
+		locale    string
+		wantLang  string
+		wantText  string
+		preheader string
+	}{
+		{locale: "es", wantLang: "es", wantText: "¡Hola John!", preheader: "Bienvenido a Tasker"},
+		{locale: "es-MX", wantLang: "es", wantText: "¡Hola John!", preheader: "Bienvenido a Tasker"},
+		{locale: "fr", wantLang: "en", wantText: "Hi John,", preheader: "Welcome to Tasker"},
+		{locale: "", wantLang: "en", wantText: "Hi John,", preheader: "Welcome to Tasker"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			rendered, err := registry.Render(email.TemplateWelcome, tt.locale, data)
+			require.NoError(t, err)
+
+			assert.Contains(t, rendered.HTML, `lang="`+tt.wantLang+`"`)
+			assert.Contains(t, rendered.HTML, tt.preheader)
+			assert.Contains(t, rendered.Text, tt.wantText)
+		})
+	}
+
+	// Blocks a variant doesn't redefine come from the default
+	rendered, err := registry.Render(email.TemplateWelcome, "es", data)
+	require.NoError(t, err)
+	assert.Contains(t, rendered.HTML, "Thank you for joining!")
+}
+
+func TestNewRegistryRejectsBrokenTemplates(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr string
+	}{
+		{
+			name:    "syntax error",
+			files:   map[string]string{"es/welcome.html": `{{define "content"}}{{.UserFirstName}{{end}}`},
+			wantErr: "es/welcome",
+		},
+		{
+			name:    "unknown field",
+			files:   map[string]string{"es/welcome.txt": `{{define "content"}}{{.UserLastName}}{{end}}`},
+			wantErr: "UserLastName",
+		},
+		{
+			name:    "no default",
+			files:   map[string]string{"es/goodbye.html": `{{define "content"}}{{end}}`},
+			wantErr: "es/goodbye has no default",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := email.NewRegistry(withLocaleFiles(t, tt.files))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestNewRegistryRequiresEveryTemplate(t *testing.T) {
+	fsys := withLocaleFiles(t, nil).(fstest.MapFS)
+	delete(fsys, string(email.TemplateJobFailed)+".txt")
+
+	_, err := email.NewRegistry(fsys)
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), string(email.TemplateJobFailed)))
+}
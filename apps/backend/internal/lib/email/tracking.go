@@ -0,0 +1,114 @@
+package email
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Tracker records the emails whose opens and clicks are tracked
+type Tracker interface {
+	// Track records an email of template about to be sent to the user at
+	// address. It reports whether the email is tracked, under which ID.
+	Track(ctx context.Context, userID, address string, template Template) (uuid.UUID, bool, error)
+}
+
+type recipientKey struct{}
+
+// WithRecipient returns a context in which emails are sent to the user,
+// and tracked unless the user opted out. Emails sent without a recipient
+// aren't tracked.
+func WithRecipient(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, recipientKey{}, userID)
+}
+
+// TrackingLinks builds the signed links that report opens and clicks to
+// the API
+type TrackingLinks struct {
+	baseURL string
+	key     []byte
+}
+
+// NewTrackingLinks returns links to the API at baseURL, signed with key
+func NewTrackingLinks(baseURL, key string) *TrackingLinks {
+	return &TrackingLinks{
+		baseURL: strings.TrimSuffix(baseURL, "/") + "/api/v1/email",
+		key:     []byte(key),
+	}
+}
+
+// OpenURL is the pixel that reports an open of the message
+func (l *TrackingLinks) OpenURL(id uuid.UUID) string {
+	return l.baseURL + "/open/" + id.String() + "?" + url.Values{
+		"s": {l.sign(id, "")},
+	}.Encode()
+}
+
+// ClickURL reports a click of the message's link to target, then redirects
+// to it
+func (l *TrackingLinks) ClickURL(id uuid.UUID, target string) string {
+	return l.baseURL + "/click/" + id.String() + "?" + url.Values{
+		"u": {target},
+		"s": {l.sign(id, target)},
+	}.Encode()
+}
+
+// VerifyOpen reports whether signature is the one of the message's pixel
+func (l *TrackingLinks) VerifyOpen(id uuid.UUID, signature string) bool {
+	return hmac.Equal([]byte(signature), []byte(l.sign(id, "")))
+}
+
+// VerifyClick reports whether signature is the one of the message's link
+// to target, so the redirect only goes where emails link to
+func (l *TrackingLinks) VerifyClick(id uuid.UUID, target, signature string) bool {
+	return hmac.Equal([]byte(signature), []byte(l.sign(id, target)))
+}
+
+func (l *TrackingLinks) sign(id uuid.UUID, target string) string {
+	mac := hmac.New(sha256.New, l.key)
+	mac.Write(id[:])
+	mac.Write([]byte(target))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+var linkHref = regexp.MustCompile(`(<a\b[^>]*?\bhref=")([^"]*)(")`)
+
+// Instrument wraps the HTML body's http(s) links in click links and adds
+// the open pixel to it
+func (l *TrackingLinks) Instrument(body string, id uuid.UUID) string {
+	body = linkHref.ReplaceAllStringFunc(body, func(match string) string {
+		parts := linkHref.FindStringSubmatch(match)
+		target := html.UnescapeString(parts[2])
+		if !strings.HasPrefix(target, "https://") && !strings.HasPrefix(target, "http://") {
+			return match
+		}
+		return parts[1] + html.EscapeString(l.ClickURL(id, target)) + parts[3]
+	})
+
+	pixel := `<img src="` + html.EscapeString(l.OpenURL(id)) +
+		`" width="1" height="1" alt="" style="display:block;border:0;width:1px;height:1px" />`
+	if i := strings.LastIndex(body, "</body>"); i >= 0 {
+		return body[:i] + pixel + body[i:]
+	}
+	return body + pixel
+}
+
+// resolveLinks resolves the HTML body's relative links against base, the
+// web app's URL
+func resolveLinks(body string, base *url.URL) string {
+	return linkHref.ReplaceAllStringFunc(body, func(match string) string {
+		parts := linkHref.FindStringSubmatch(match)
+		ref, err := url.Parse(html.UnescapeString(parts[2]))
+		if err != nil || ref.IsAbs() || !strings.HasPrefix(ref.Path, "/") {
+			return match
+		}
+		return parts[1] + html.EscapeString(base.ResolveReference(ref).String()) + parts[3]
+	})
+}
@@ -0,0 +1,57 @@
+package email_test
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/lib/email"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrackingLinksSigned(t *testing.T) {
+	links := email.NewTrackingLinks("https://api.example.com/", "secret")
+	id := uuid.New()
+
+	open, err := url.Parse(links.OpenURL(id))
+	require.NoError(t, err)
+	assert.Equal(t, "/api/v1/email/open/"+id.String(), open.Path)
+	assert.True(t, links.VerifyOpen(id, open.Query().Get("s")))
+	assert.False(t, links.VerifyOpen(uuid.New(), open.Query().Get("s")))
+
+	click, err := url.Parse(links.ClickURL(id, "https://app.example.com/todos?id=1&x=2"))
+	require.NoError(t, err)
+	assert.Equal(t, "https://app.example.com/todos?id=1&x=2", click.Query().Get("u"))
+	assert.True(t, links.VerifyClick(id, click.Query().Get("u"), click.Query().Get("s")))
+
+	// The signature only redirects to the link's own target
+	assert.False(t, links.VerifyClick(id, "https://evil.example.com", click.Query().Get("s")))
+	assert.False(t, email.NewTrackingLinks("https://api.example.com", "other").
+		VerifyClick(id, click.Query().Get("u"), click.Query().Get("s")))
+}
+
+func TestInstrument(t *testing.T) {
+	links := email.NewTrackingLinks("https://api.example.com", "secret")
+	id := uuid.New()
+
+	body := `<html><head><link rel="preload" href="https://cdn.example.com/logo.png" /></head><body>` +
+		`<a class="button" href="https://app.example.com/todos?id=1&amp;x=2">Open</a>` +
+		`<a href="mailto:help@example.com">Help</a></body></html>`
+	instrumented := links.Instrument(body, id)
+
+	// Only http(s) links are wrapped, with the target unescaped
+	hrefs := regexp.MustCompile(`<a [^>]*href="([^"]*)"`).FindAllStringSubmatch(instrumented, -1)
+	require.Len(t, hrefs, 2)
+	click, err := url.Parse(html.UnescapeString(hrefs[0][1]))
+	require.NoError(t, err)
+	assert.Equal(t, "/api/v1/email/click/"+id.String(), click.Path)
+	assert.Equal(t, "https://app.example.com/todos?id=1&x=2", click.Query().Get("u"))
+	assert.Equal(t, "mailto:help@example.com", hrefs[1][1])
+	assert.Contains(t, instrumented, `href="https://cdn.example.com/logo.png"`)
+
+	// The pixel ends the body
+	assert.Regexp(t, `<img src="https://api\.example\.com/api/v1/email/open/`+id.String()+`\?s=[^"]+"[^>]*/></body></html>$`, instrumented)
+}
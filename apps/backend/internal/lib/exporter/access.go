@@ -0,0 +1,72 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"time"
+
+	"github.com/mabhi256/tasker/internal/model/accessreport"
+)
+
+var accessCSVHeader = []string{
+	"kind",
+	"id",
+	"name",
+	"email",
+	"role",
+	"joined_at",
+	"last_active_at",
+	"api_keys",
+	"api_keys_last_used_at",
+}
+
+// EncodeAccessReportCSV renders an access report with a row per member or
+// service account. Their API keys are listed by name and scope in one
+// column, with when any of them was last used in the next.
+func EncodeAccessReportCSV(report *accessreport.AccessReport) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	if err := w.Write(accessCSVHeader); err != nil {
+		return nil, err
+	}
+
+	for _, e := range report.Entries {
+		keys := make([]string, len(e.APIKeys))
+		var lastUsed *time.Time
+		for i, k := range e.APIKeys {
+			keys[i] = k.Name + " (" + string(k.Scope) + ")"
+			if k.LastUsedAt != nil && (lastUsed == nil || k.LastUsedAt.After(*lastUsed)) {
+				lastUsed = k.LastUsedAt
+			}
+		}
+
+		role := ""
+		if e.Role != nil {
+			role = string(*e.Role)
+		}
+
+		record := []string{
+			string(e.Kind),
+			e.ID,
+			sanitizeCSVField(stringOrEmpty(e.Name)),
+			sanitizeCSVField(stringOrEmpty(e.Email)),
+			role,
+			timeOrEmpty(&e.JoinedAt),
+			timeOrEmpty(e.LastActiveAt),
+			sanitizeCSVField(strings.Join(keys, ";")),
+			timeOrEmpty(lastUsed),
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,129 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/model/export"
+	"github.com/mabhi256/tasker/internal/model/todo"
+)
+
+var csvHeader = []string{
+	"id",
+	"created_at",
+	"updated_at",
+	"user_id",
+	"title",
+	"description",
+	"status",
+	"priority",
+	"due_date",
+	"completed_at",
+	"parent_todo_id",
+	"category_id",
+	"sort_order",
+	"tags",
+}
+
+// Encode renders todos in the requested format
+func Encode(format export.Format, todos []todo.Todo) ([]byte, error) {
+	switch format {
+	case export.FormatJSON:
+		return json.Marshal(todos)
+	case export.FormatCSV:
+		return encodeCSV(todos)
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+// FileExtension returns the extension including the leading dot
+func FileExtension(format export.Format) string {
+	return "." + string(format)
+}
+
+func ContentType(format export.Format) string {
+	if format == export.FormatCSV {
+		return "text/csv"
+	}
+	return "application/json"
+}
+
+func encodeCSV(todos []todo.Todo) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	if err := w.Write(csvHeader); err != nil {
+		return nil, err
+	}
+
+	for _, t := range todos {
+		var tags []string
+		if t.Metadata != nil {
+			tags = t.Metadata.Tags
+		}
+
+		record := []string{
+			t.ID.String(),
+			t.CreatedAt.UTC().Format(time.RFC3339),
+			t.UpdatedAt.UTC().Format(time.RFC3339),
+			t.UserID,
+			sanitizeCSVField(t.Title),
+			sanitizeCSVField(stringOrEmpty(t.Description)),
+			string(t.Status),
+			string(t.Priority),
+			timeOrEmpty(t.DueDate),
+			timeOrEmpty(t.CompletedAt),
+			uuidOrEmpty(t.ParentTodoID),
+			uuidOrEmpty(t.CategoryID),
+			strconv.Itoa(t.SortOrder),
+			sanitizeCSVField(strings.Join(tags, ";")),
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// sanitizeCSVField stops user text from being run as a formula when the
+// file is opened in a spreadsheet
+func sanitizeCSVField(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func stringOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func timeOrEmpty(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func uuidOrEmpty(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
@@ -0,0 +1,102 @@
+package exporter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mabhi256/tasker/internal/httpclient"
+	"github.com/mabhi256/tasker/internal/model/export"
+	"github.com/mabhi256/tasker/internal/model/todo"
+)
+
+// deliveryTimeout bounds a single upload, including connection setup
+const deliveryTimeout = 5 * time.Minute
+
+// Exporter encodes workspace data and delivers it to a customer-owned
+// destination. Destinations are user-supplied, so every connection is made
+// through the fetcher's dialer.
+type Exporter struct {
+	fetcher    *httpclient.Fetcher
+	httpClient *http.Client
+	timeout    time.Duration
+}
+
+func NewExporter(fetcher *httpclient.Fetcher) *Exporter {
+	return &Exporter{
+		fetcher: fetcher,
+		httpClient: &http.Client{
+			Timeout: deliveryTimeout,
+			Transport: &http.Transport{
+				Proxy:               nil,
+				DialContext:         fetcher.DialContext,
+				ForceAttemptHTTP2:   true,
+				MaxIdleConns:        10,
+				IdleConnTimeout:     90 * time.Second,
+				TLSHandshakeTimeout: 10 * time.Second,
+			},
+		},
+		timeout: 30 * time.Second,
+	}
+}
+
+// Export encodes the todos and uploads them, returning the delivery receipt
+func (e *Exporter) Export(ctx context.Context, schedule *export.Schedule, run *export.Run,
+	todos []todo.Todo,
+) (*export.Receipt, error) {
+	ctx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+	defer cancel()
+
+	body, err := Encode(schedule.Format, todos)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode export: %w", err)
+	}
+
+	sum := sha256.Sum256(body)
+	checksum := hex.EncodeToString(sum[:])
+	fileName := FileName(schedule, run)
+
+	var location string
+	switch schedule.DestinationType {
+	case export.DestinationS3:
+		if schedule.Destination.S3 == nil {
+			return nil, fmt.Errorf("export schedule %s has no S3 destination", schedule.ID)
+		}
+		key := fileName
+		if prefix := schedule.Destination.S3.Prefix; prefix != nil && *prefix != "" {
+			key = strings.TrimSuffix(*prefix, "/") + "/" + fileName
+		}
+		location, err = e.uploadS3(ctx, schedule.Destination.S3, key, body, ContentType(schedule.Format), checksum)
+	case export.DestinationSFTP:
+		if schedule.Destination.SFTP == nil {
+			return nil, fmt.Errorf("export schedule %s has no SFTP destination", schedule.ID)
+		}
+		location, err = e.uploadSFTP(ctx, schedule.Destination.SFTP, fileName, body)
+	default:
+		return nil, fmt.Errorf("unsupported export destination: %s", schedule.DestinationType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &export.Receipt{
+		Location:       location,
+		RowCount:       len(todos),
+		SizeBytes:      int64(len(body)),
+		ChecksumSHA256: checksum,
+	}, nil
+}
+
+// FileName is derived from the run only, so retries overwrite their own
+// partial output and never another run's file
+func FileName(schedule *export.Schedule, run *export.Run) string {
+	return fmt.Sprintf("tasker-todos-%s-%s%s",
+		run.CreatedAt.UTC().Format("20060102T150405Z"),
+		run.ID.String(),
+		FileExtension(schedule.Format),
+	)
+}
@@ -0,0 +1,51 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/mabhi256/tasker/internal/model/export"
+)
+
+// uploadS3 writes the file with the customer's own credentials. Connections
+// go through the fetcher so a custom endpoint can't reach internal hosts.
+func (e *Exporter) uploadS3(ctx context.Context, dest *export.S3Destination, key string,
+	body []byte, contentType string, checksum string,
+) (string, error) {
+	cfg := aws.Config{
+		Region: dest.Region,
+		Credentials: credentials.NewStaticCredentialsProvider(
+			dest.AccessKeyID,
+			dest.SecretAccessKey,
+			"",
+		),
+		HTTPClient: e.httpClient,
+	}
+
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if dest.Endpoint != nil {
+			o.BaseEndpoint = dest.Endpoint
+			o.UsePathStyle = true
+		}
+	})
+
+	_, err := client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(dest.Bucket),
+		Key:           aws.String(key),
+		Body:          bytes.NewReader(body),
+		ContentLength: aws.Int64(int64(len(body))),
+		ContentType:   aws.String(contentType),
+		Metadata: map[string]string{
+			"sha256": checksum,
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload export to S3: %w", err)
+	}
+
+	return fmt.Sprintf("s3://%s/%s", dest.Bucket, key), nil
+}
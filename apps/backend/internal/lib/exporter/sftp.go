@@ -0,0 +1,92 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/mabhi256/tasker/internal/model/export"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// uploadSFTP writes the file under a temporary name and renames it once
+// complete, so consumers polling the directory never see a partial file
+func (e *Exporter) uploadSFTP(ctx context.Context, dest *export.SFTPDestination, fileName string, body []byte) (string, error) {
+	hostKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(dest.HostKey))
+	if err != nil {
+		return "", fmt.Errorf("invalid SFTP host key: %w", err)
+	}
+
+	auth := []ssh.AuthMethod{}
+	if dest.PrivateKey != nil {
+		signer, err := ssh.ParsePrivateKey([]byte(*dest.PrivateKey))
+		if err != nil {
+			return "", fmt.Errorf("invalid SFTP private key: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if dest.Password != nil {
+		auth = append(auth, ssh.Password(*dest.Password))
+	}
+
+	port := dest.Port
+	if port == 0 {
+		port = 22
+	}
+	address := net.JoinHostPort(dest.Host, strconv.Itoa(port))
+
+	conn, err := e.fetcher.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to SFTP server: %w", err)
+	}
+
+	// Closing the connection unblocks any pending read when ctx is done
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, address, &ssh.ClientConfig{
+		User:            dest.Username,
+		Auth:            auth,
+		HostKeyCallback: ssh.FixedHostKey(hostKey),
+		Timeout:         e.timeout,
+	})
+	if err != nil {
+		conn.Close()
+		return "", fmt.Errorf("failed to establish SSH session: %w", err)
+	}
+	client := ssh.NewClient(sshConn, chans, reqs)
+	defer client.Close()
+
+	finalPath := path.Join(dest.Directory, fileName)
+	partPath := finalPath + ".part"
+
+	sftpClient, err := sftp.NewClient(client)
+	if err != nil {
+		return "", fmt.Errorf("failed to start SFTP subsystem: %w", err)
+	}
+	defer sftpClient.Close()
+
+	f, err := sftpClient.OpenFile(partPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", partPath, err)
+	}
+
+	if _, err := f.Write(body); err != nil {
+		f.Close()
+		return "", fmt.Errorf("SFTP write failed: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("SFTP close failed: %w", err)
+	}
+
+	if err := sftpClient.Rename(partPath, finalPath); err != nil {
+		return "", fmt.Errorf("SFTP rename failed: %w", err)
+	}
+
+	return fmt.Sprintf("sftp://%s%s", address, finalPath), nil
+}
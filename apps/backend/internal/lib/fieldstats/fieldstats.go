@@ -0,0 +1,128 @@
+// Package fieldstats counts the request fields that fail binding or
+// validation, by route, field and client version, so fields that confuse
+// API users and clients that send broken requests show up in a report
+// rather than in support tickets.
+//
+// A Collector only counts in memory. Its counts are drained periodically
+// into a report logged by every instance.
+package fieldstats
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/mabhi256/tasker/internal/lib/clientversion"
+)
+
+// UnknownClient is the client of requests that name none
+const UnknownClient = "unknown"
+
+// maxClientLength bounds the client names kept, since clients choose them
+const maxClientLength = 64
+
+// Failure is a field of a request to a route that failed
+type Failure struct {
+	Method string
+	// Route is the route template, such as /api/v1/todos/:id
+	Route string
+	// Source is where the field was read from: json, query, param, form or
+	// header
+	Source string
+	Field  string
+	// Unknown is set for fields the route doesn't accept
+	Unknown bool
+	Client  string
+}
+
+// Count is the number of times a failure occurred
+type Count struct {
+	Failure
+	Count int64
+}
+
+// Collector counts failures. It keeps at most limit distinct failures
+// between drains, and counts the ones beyond as dropped, since clients
+// choose the names of unknown fields and their versions. A nil Collector
+// counts nothing.
+type Collector struct {
+	mu      sync.Mutex
+	limit   int
+	counts  map[Failure]int64
+	dropped int64
+}
+
+func NewCollector(limit int) *Collector {
+	return &Collector{
+		limit:  limit,
+		counts: make(map[Failure]int64),
+	}
+}
+
+// Add counts an occurrence of the failure
+func (c *Collector) Add(f Failure) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.counts[f]; !ok && len(c.counts) >= c.limit {
+		c.dropped++
+		return
+	}
+	c.counts[f]++
+}
+
+// Drain returns the counts, most frequent first, and how many failures were
+// dropped, and resets them
+func (c *Collector) Drain() ([]Count, int64) {
+	if c == nil {
+		return nil, 0
+	}
+
+	c.mu.Lock()
+	counts, dropped := c.counts, c.dropped
+	c.counts = make(map[Failure]int64)
+	c.dropped = 0
+	c.mu.Unlock()
+
+	drained := make([]Count, 0, len(counts))
+	for f, n := range counts {
+		drained = append(drained, Count{Failure: f, Count: n})
+	}
+	slices.SortFunc(drained, func(a, b Count) int {
+		return cmp.Or(
+			cmp.Compare(b.Count, a.Count),
+			cmp.Compare(a.Route, b.Route),
+			cmp.Compare(a.Method, b.Method),
+			cmp.Compare(a.Field, b.Field),
+			cmp.Compare(a.Source, b.Source),
+			cmp.Compare(a.Client, b.Client),
+		)
+	})
+
+	return drained, dropped
+}
+
+// Client names the client that sent a request with header: the platform and
+// version it sends in X-Client-Version, the first product of its User-Agent,
+// such as curl/8.5.0, or UnknownClient
+func Client(header http.Header) string {
+	client := strings.TrimSpace(header.Get(clientversion.Header))
+	if client == "" {
+		if product, _, _ := strings.Cut(strings.TrimSpace(header.Get("User-Agent")), " "); product != "" {
+			client = product
+		}
+	}
+	if client == "" {
+		return UnknownClient
+	}
+	if len(client) > maxClientLength {
+		client = strings.ToValidUTF8(client[:maxClientLength], "")
+	}
+	return client
+}
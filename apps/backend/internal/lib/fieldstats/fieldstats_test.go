@@ -0,0 +1,114 @@
+package fieldstats_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/mabhi256/tasker/internal/lib/clientversion"
+	"github.com/mabhi256/tasker/internal/lib/fieldstats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func failure(field, client string) fieldstats.Failure {
+	return fieldstats.Failure{
+		Method: http.MethodPost,
+		Route:  "/api/v1/todos",
+		Source: "json",
+		Field:  field,
+		Client: client,
+	}
+}
+
+func TestCollectorDrain(t *testing.T) {
+	collector := fieldstats.NewCollector(10)
+
+	collector.Add(failure("title", "tasker-web/1.0.0"))
+	collector.Add(failure("dueDate", "tasker-web/1.0.0"))
+	collector.Add(failure("dueDate", "tasker-web/1.0.0"))
+	collector.Add(failure("dueDate", "tasker-web/1.1.0"))
+
+	counts, dropped := collector.Drain()
+	assert.Zero(t, dropped)
+	require.Len(t, counts, 3)
+
+	// Most frequent first
+	assert.Equal(t, failure("dueDate", "tasker-web/1.0.0"), counts[0].Failure)
+	assert.Equal(t, int64(2), counts[0].Count)
+	assert.Equal(t, failure("dueDate", "tasker-web/1.1.0"), counts[1].Failure)
+	assert.Equal(t, failure("title", "tasker-web/1.0.0"), counts[2].Failure)
+
+	// Draining resets the counts
+	counts, _ = collector.Drain()
+	assert.Empty(t, counts)
+}
+
+func TestCollectorLimit(t *testing.T) {
+	collector := fieldstats.NewCollector(2)
+
+	collector.Add(failure("a", "cli"))
+	collector.Add(failure("b", "cli"))
+	collector.Add(failure("c", "cli"))
+	collector.Add(failure("d", "cli"))
+	// Failures already counted still are
+	collector.Add(failure("a", "cli"))
+
+	counts, dropped := collector.Drain()
+	require.Len(t, counts, 2)
+	assert.Equal(t, int64(2), counts[0].Count)
+	assert.Equal(t, int64(2), dropped)
+
+	// Dropped failures are reset with the counts
+	_, dropped = collector.Drain()
+	assert.Zero(t, dropped)
+}
+
+func TestNilCollector(t *testing.T) {
+	var collector *fieldstats.Collector
+
+	assert.NotPanics(t, func() {
+		collector.Add(failure("title", "cli"))
+		counts, dropped := collector.Drain()
+		assert.Nil(t, counts)
+		assert.Zero(t, dropped)
+	})
+}
+
+func TestClient(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{
+			name:    "client version",
+			headers: map[string]string{clientversion.Header: "tasker-web/1.4.2", "User-Agent": "curl/8.5.0"},
+			want:    "tasker-web/1.4.2",
+		},
+		{
+			name:    "user agent product",
+			headers: map[string]string{"User-Agent": "tasker-cli/0.3.0 (linux; amd64)"},
+			want:    "tasker-cli/0.3.0",
+		},
+		{
+			name: "none",
+			want: fieldstats.UnknownClient,
+		},
+		{
+			name:    "too long",
+			headers: map[string]string{clientversion.Header: strings.Repeat("x", 100)},
+			want:    strings.Repeat("x", 64),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for name, value := range tt.headers {
+				header.Set(name, value)
+			}
+			assert.Equal(t, tt.want, fieldstats.Client(header))
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+)
+
+const (
+	TaskAttachmentPreview = "attachment:preview"
+	TaskAttachmentProcess = "attachment:process"
+
+	TaskAttachmentQuarantinedEmail = "email:attachment_quarantined"
+)
+
+type AttachmentPreviewerInterface interface {
+	// ExtractAttachmentPreview stores the preview of the attachment's file.
+	// Files that have no preview are recorded as such, and only failures
+	// worth retrying are returned.
+	ExtractAttachmentPreview(ctx context.Context, attachmentID uuid.UUID) error
+}
+
+type AttachmentProcessorInterface interface {
+	// ProcessAttachment scans the attachment, holds it to the attachment
+	// policy, and stores the files derived from it. Only failures worth
+	// retrying are returned.
+	ProcessAttachment(ctx context.Context, attachmentID uuid.UUID) error
+}
+
+type AttachmentPreviewTask struct {
+	TaskMeta
+	AttachmentID uuid.UUID `json:"attachment_id" validate:"required"`
+}
+
+func (p *AttachmentPreviewTask) Type() string {
+	return TaskAttachmentPreview
+}
+
+// Options uses the attachment id as the task id so an attachment is never
+// queued twice
+func (p *AttachmentPreviewTask) Options() []asynq.Option {
+	return []asynq.Option{
+		asynq.TaskID(p.AttachmentID.String()),
+		asynq.MaxRetry(3),
+		asynq.Queue("low"),
+		asynq.Timeout(2 * time.Minute),
+	}
+}
+
+func (j *JobService) handleAttachmentPreviewTask(ctx context.Context, t *asynq.Task) error {
+	var p AttachmentPreviewTask
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal attachment preview payload: %w", err)
+	}
+
+	j.logger.Info().
+		Str("type", "attachment_preview").
+		Str("attachment_id", p.AttachmentID.String()).
+		Msg("Processing attachment preview task")
+
+	if err := j.attachmentPreviewer.ExtractAttachmentPreview(ctx, p.AttachmentID); err != nil {
+		j.logger.Error().
+			Str("type", "attachment_preview").
+			Str("attachment_id", p.AttachmentID.String()).
+			Err(err).
+			Msg("Failed to extract attachment preview")
+		return err
+	}
+
+	j.logger.Info().
+		Str("type", "attachment_preview").
+		Str("attachment_id", p.AttachmentID.String()).
+		Msg("Successfully extracted attachment preview")
+	return nil
+}
+
+type AttachmentProcessTask struct {
+	TaskMeta
+	AttachmentID uuid.UUID `json:"attachment_id" validate:"required"`
+}
+
+func (p *AttachmentProcessTask) Type() string {
+	return TaskAttachmentProcess
+}
+
+// Options dedupes on the attachment id, prefixed as the preview task shares
+// the queue
+func (p *AttachmentProcessTask) Options() []asynq.Option {
+	return []asynq.Option{
+		asynq.TaskID("process:" + p.AttachmentID.String()),
+		asynq.MaxRetry(3),
+		asynq.Queue("low"),
+		asynq.Timeout(5 * time.Minute),
+	}
+}
+
+func (j *JobService) handleAttachmentProcessTask(ctx context.Context, t *asynq.Task) error {
+	var p AttachmentProcessTask
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal attachment process payload: %w", err)
+	}
+
+	j.logger.Info().
+		Str("type", "attachment_process").
+		Str("attachment_id", p.AttachmentID.String()).
+		Msg("Processing attachment process task")
+
+	if err := j.attachmentProcessor.ProcessAttachment(ctx, p.AttachmentID); err != nil {
+		j.logger.Error().
+			Str("type", "attachment_process").
+			Str("attachment_id", p.AttachmentID.String()).
+			Err(err).
+			Msg("Failed to process attachment")
+		return err
+	}
+
+	j.logger.Info().
+		Str("type", "attachment_process").
+		Str("attachment_id", p.AttachmentID.String()).
+		Msg("Successfully processed attachment")
+	return nil
+}
+
+// AttachmentQuarantinedEmailTask tells the uploader of an attachment that it
+// was found infected and quarantined
+type AttachmentQuarantinedEmailTask struct {
+	TaskMeta
+	UserID       string    `json:"user_id" validate:"required"`
+	AttachmentID uuid.UUID `json:"attachment_id" validate:"required"`
+	TodoID       uuid.UUID `json:"todo_id" validate:"required"`
+	FileName     string    `json:"file_name" validate:"required"`
+	Signature    string    `json:"signature" validate:"required"`
+}
+
+func (p *AttachmentQuarantinedEmailTask) Type() string {
+	return TaskAttachmentQuarantinedEmail
+}
+
+func (p *AttachmentQuarantinedEmailTask) Options() []asynq.Option {
+	return []asynq.Option{
+		asynq.TaskID("quarantined:" + p.AttachmentID.String()),
+		asynq.MaxRetry(3),
+		asynq.Queue("critical"),
+		asynq.Timeout(30 * time.Second),
+	}
+}
+
+func (j *JobService) handleAttachmentQuarantinedEmailTask(ctx context.Context, t *asynq.Task) error {
+	var p AttachmentQuarantinedEmailTask
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal attachment quarantined email payload: %w", err)
+	}
+
+	j.logger.Info().
+		Str("type", "attachment_quarantined").
+		Str("user_id", p.UserID).
+		Str("attachment_id", p.AttachmentID.String()).
+		Msg("Processing attachment quarantined email task")
+
+	userEmail, err := j.authService.GetUserEmail(ctx, p.UserID)
+	if err != nil {
+		j.logger.Error().
+			Str("type", "attachment_quarantined").
+			Str("user_id", p.UserID).
+			Err(err).
+			Msg("Failed to resolve user email")
+		return fmt.Errorf("failed to resolve user email for user %s: %w", p.UserID, err)
+	}
+
+	err = j.emailClient.SendAttachmentQuarantinedEmail(ctx, userEmail, p.FileName, p.Signature, p.TodoID)
+	if err != nil {
+		j.logger.Error().
+			Str("type", "attachment_quarantined").
+			Str("user_id", p.UserID).
+			Str("attachment_id", p.AttachmentID.String()).
+			Err(err).
+			Msg("Failed to send attachment quarantined email")
+		return err
+	}
+
+	j.logger.Info().
+		Str("type", "attachment_quarantined").
+		Str("user_id", p.UserID).
+		Str("attachment_id", p.AttachmentID.String()).
+		Msg("Successfully sent attachment quarantined email")
+	return nil
+}
@@ -0,0 +1,90 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/mabhi256/tasker/internal/model/audit"
+)
+
+const TaskAuditForward = "audit:forward"
+
+// auditMaxRetry with the default exponential backoff of audit forwarding
+// keeps retrying for about two hours, long enough to ride out a SIEM restart
+const auditMaxRetry = 8
+
+type AuditForwarderInterface interface {
+	// ForwardAuditEvent sends the event to one forwarder. An error means the
+	// attempt should be retried; final is set for the last one allowed.
+	ForwardAuditEvent(ctx context.Context, forwarderID uuid.UUID, event *audit.Event, final bool) error
+}
+
+// AuditForwardTask carries the event itself, since audit events are only
+// kept in the outbox until they are published
+type AuditForwardTask struct {
+	TaskMeta
+	ForwarderID uuid.UUID   `json:"forwarder_id" validate:"required"`
+	Event       audit.Event `json:"event"`
+}
+
+func (p *AuditForwardTask) Type() string {
+	return TaskAuditForward
+}
+
+// Options uses the forwarder and event as the task id so an event that is
+// republished by the outbox isn't forwarded twice
+func (p *AuditForwardTask) Options() []asynq.Option {
+	return []asynq.Option{
+		asynq.TaskID(p.ForwarderID.String() + ":" + p.Event.ID.String()),
+		asynq.MaxRetry(auditMaxRetry),
+		asynq.Queue(QueueAudit),
+		asynq.Timeout(30 * time.Second),
+	}
+}
+
+// EnqueueAuditForward queues an event for a forwarder, treating one that is
+// already queued as success
+func EnqueueAuditForward(ctx context.Context, client *asynq.Client, task *AuditForwardTask) error {
+	err := Enqueue(ctx, client, task)
+	if errors.Is(err, asynq.ErrTaskIDConflict) {
+		return nil
+	}
+	return err
+}
+
+func (j *JobService) handleAuditForwardTask(ctx context.Context, t *asynq.Task) error {
+	var p AuditForwardTask
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal audit forward payload: %w", err)
+	}
+
+	retried, _ := asynq.GetRetryCount(ctx)
+	maxRetry, _ := asynq.GetMaxRetry(ctx)
+	final := retried >= maxRetry
+
+	j.logger.Info().
+		Str("type", "audit_forward").
+		Str("forwarder_id", p.ForwarderID.String()).
+		Str("event_id", p.Event.ID.String()).
+		Int("retried", retried).
+		Msg("Processing audit forward task")
+
+	if err := j.auditForwarder.ForwardAuditEvent(ctx, p.ForwarderID, &p.Event, final); err != nil {
+		j.logger.Warn().
+			Str("type", "audit_forward").
+			Str("forwarder_id", p.ForwarderID.String()).
+			Str("event_id", p.Event.ID.String()).
+			Int("retried", retried).
+			Bool("final", final).
+			Err(err).
+			Msg("Audit forward attempt failed")
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+const TaskBackfillRun = "backfill:run"
+
+type BackfillRunnerInterface interface {
+	// RunBackfill runs batches of the backfill for a limited time. more
+	// reports that it is still running and another task should continue it.
+	RunBackfill(ctx context.Context, name string) (more bool, err error)
+	// RecordBackfillFailure stores the error on the backfill's run. When
+	// final is set the run is marked failed.
+	RecordBackfillFailure(ctx context.Context, name string, runErr error, final bool) error
+}
+
+// BackfillRunTask runs part of a backfill, then queues another to continue
+// from its checkpoint
+type BackfillRunTask struct {
+	TaskMeta
+	Name string `json:"name" validate:"required"`
+}
+
+func (p *BackfillRunTask) Type() string {
+	return TaskBackfillRun
+}
+
+func (p *BackfillRunTask) Options() []asynq.Option {
+	return []asynq.Option{
+		asynq.MaxRetry(5),
+		asynq.Queue("low"),
+		asynq.Timeout(15 * time.Minute),
+	}
+}
+
+func (j *JobService) handleBackfillRunTask(ctx context.Context, t *asynq.Task) error {
+	var p BackfillRunTask
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal backfill run payload: %w", err)
+	}
+
+	j.logger.Info().
+		Str("type", "backfill_run").
+		Str("backfill", p.Name).
+		Msg("Processing backfill run task")
+
+	more, runErr := j.backfillRunner.RunBackfill(ctx, p.Name)
+	if runErr != nil {
+		retried, _ := asynq.GetRetryCount(ctx)
+		maxRetry, _ := asynq.GetMaxRetry(ctx)
+		final := retried >= maxRetry
+
+		j.logger.Error().
+			Str("type", "backfill_run").
+			Str("backfill", p.Name).
+			Int("retried", retried).
+			Bool("final", final).
+			Err(runErr).
+			Msg("Failed to run backfill")
+
+		if err := j.backfillRunner.RecordBackfillFailure(ctx, p.Name, runErr, final); err != nil {
+			j.logger.Error().
+				Str("type", "backfill_run").
+				Str("backfill", p.Name).
+				Err(err).
+				Msg("Failed to record backfill failure")
+		}
+
+		return runErr
+	}
+
+	if more {
+		if err := Enqueue(ctx, j.Client, &BackfillRunTask{Name: p.Name}); err != nil {
+			return fmt.Errorf("failed to queue backfill continuation: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+const TaskDigestEmail = "email:digest"
+
+type DigestSenderInterface interface {
+	// SendDigest emails the user their digest for the period ending at
+	// periodEnd. Nothing is sent when there's nothing to report.
+	SendDigest(ctx context.Context, userID string, periodEnd time.Time) error
+}
+
+type DigestEmailTask struct {
+	TaskMeta
+	UserID    string    `json:"user_id" validate:"required"`
+	PeriodEnd time.Time `json:"period_end" validate:"required"`
+}
+
+func (p *DigestEmailTask) Type() string {
+	return TaskDigestEmail
+}
+
+// Options uses the user and period as the task id so a digest is never
+// queued twice
+func (p *DigestEmailTask) Options() []asynq.Option {
+	return []asynq.Option{
+		asynq.TaskID("digest:" + p.UserID + ":" + strconv.FormatInt(p.PeriodEnd.Unix(), 10)),
+		asynq.MaxRetry(3),
+		asynq.Queue("default"),
+		asynq.Timeout(time.Minute),
+	}
+}
+
+// EnqueueDigestEmail queues a digest, treating one that is already queued as
+// success
+func EnqueueDigestEmail(ctx context.Context, client *asynq.Client, task *DigestEmailTask) error {
+	err := Enqueue(ctx, client, task)
+	if errors.Is(err, asynq.ErrTaskIDConflict) {
+		return nil
+	}
+	return err
+}
+
+func (j *JobService) handleDigestEmailTask(ctx context.Context, t *asynq.Task) error {
+	var p DigestEmailTask
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal digest email payload: %w", err)
+	}
+
+	j.logger.Info().
+		Str("type", "digest").
+		Str("user_id", p.UserID).
+		Time("period_end", p.PeriodEnd).
+		Msg("Processing digest email task")
+
+	if err := j.digestSender.SendDigest(ctx, p.UserID, p.PeriodEnd); err != nil {
+		j.logger.Error().
+			Str("type", "digest").
+			Str("user_id", p.UserID).
+			Err(err).
+			Msg("Failed to send digest email")
+		return err
+	}
+
+	j.logger.Info().
+		Str("type", "digest").
+		Str("user_id", p.UserID).
+		Msg("Successfully processed digest email")
+	return nil
+}
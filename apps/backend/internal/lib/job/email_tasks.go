@@ -1,85 +1,143 @@
 package job
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/hibiken/asynq"
-	"github.com/mabhi256/tasker/internal/model/todo"
 )
 
 const (
 	TaskWelcome           = "email:welcome"
 	TaskReminderEmail     = "email:reminder"
 	TaskWeeklyReportEmail = "email:weekly_report"
+	TaskMentionEmail      = "email:mention"
 )
 
 type WelcomeEmailPayload struct {
-	To        string `json:"to"`
-	FirstName string `json:"first_name"`
+	TaskMeta
+	To        string `json:"to" validate:"required,email"`
+	FirstName string `json:"first_name" validate:"required"`
 }
 
-func NewWelcomeEmailTask(to, firstName string) (*asynq.Task, error) {
-	payload, err := json.Marshal(WelcomeEmailPayload{
-		To:        to,
-		FirstName: firstName,
-	})
-	if err != nil {
-		return nil, err
-	}
+func (p *WelcomeEmailPayload) Type() string {
+	return TaskWelcome
+}
 
-	return asynq.NewTask(TaskWelcome, payload,
+func (p *WelcomeEmailPayload) Options() []asynq.Option {
+	return []asynq.Option{
 		asynq.MaxRetry(3),
-		asynq.Queue("defaut"),
-		asynq.Timeout(30*time.Second)), nil
+		asynq.Queue("default"),
+		asynq.Timeout(30 * time.Second),
+	}
 }
 
+// OverdueNotificationTTL is how long an overdue notification stays worth
+// sending; the next day's run notifies about the todo again
+const OverdueNotificationTTL = 24 * time.Hour
+
 type ReminderEmailTask struct {
-	UserID    string    `json:"user_id"`
-	TodoID    uuid.UUID `json:"todo_id"`
-	TodoTitle string    `json:"todo_title"`
-	DueDate   time.Time `json:"due_date"`
-	TaskType  string    `json:"task_type"` // "due_date_reminder" or "overdue_notification"
+	TaskMeta
+	UserID    string    `json:"user_id" validate:"required"`
+	TodoID    uuid.UUID `json:"todo_id" validate:"required"`
+	TodoTitle string    `json:"todo_title" validate:"required"`
+	DueDate   time.Time `json:"due_date" validate:"required"`
+	TaskType  string    `json:"task_type" validate:"oneof=due_date_reminder overdue_notification"`
+}
+
+func (p *ReminderEmailTask) Type() string {
+	return TaskReminderEmail
 }
 
-func EnqueueReminderEmail(client *asynq.Client, task *ReminderEmailTask) error {
-	payload, err := json.Marshal(task)
-	if err != nil {
-		return err
+// Options drops a due date reminder that hasn't been sent by the time the
+// todo is due, and an overdue notification that is more than a day late.
+// A due date reminder uses the todo's reminder id, which is kept until the
+// todo is due, so the todo gets one however often it's scheduled.
+func (p *ReminderEmailTask) Options() []asynq.Option {
+	if p.TaskType == "overdue_notification" {
+		return []asynq.Option{
+			asynq.MaxRetry(3),
+			asynq.Queue(reminderQueue),
+			asynq.Timeout(30 * time.Second),
+			Deadline(time.Now().Add(OverdueNotificationTTL)),
+		}
 	}
 
-	asynqTask := asynq.NewTask(TaskReminderEmail, payload,
+	return []asynq.Option{
+		asynq.TaskID(ReminderTaskID(p.TodoID)),
 		asynq.MaxRetry(3),
-		asynq.Queue("default"),
-		asynq.Timeout(30*time.Second))
+		asynq.Queue(reminderQueue),
+		asynq.Timeout(30 * time.Second),
+		asynq.Retention(time.Until(p.DueDate)),
+		Deadline(p.DueDate),
+	}
+}
 
-	_, err = client.Enqueue(asynqTask)
-	return err
+type WeeklyReportSenderInterface interface {
+	// SendWeeklyReport emails the user their report for the week from
+	// weekStart to weekEnd in the timezone. Nothing is sent when there's
+	// nothing to report.
+	SendWeeklyReport(ctx context.Context, userID string, weekStart, weekEnd time.Time, timezone string) error
 }
 
+// WeeklyReportEmailTask reports on the week, which is aggregated when the
+// task runs
 type WeeklyReportEmailTask struct {
-	UserID         string               `json:"user_id"`
-	WeekStart      time.Time            `json:"week_start"`
-	WeekEnd        time.Time            `json:"week_end"`
-	CompletedCount int                  `json:"completed_count"`
-	ActiveCount    int                  `json:"active_count"`
-	OverdueCount   int                  `json:"overdue_count"`
-	CompletedTodos []todo.PopulatedTodo `json:"completed_todos"`
-	OverdueTodos   []todo.PopulatedTodo `json:"overdue_todos"`
+	TaskMeta
+	UserID    string    `json:"user_id" validate:"required"`
+	WeekStart time.Time `json:"week_start" validate:"required"`
+	WeekEnd   time.Time `json:"week_end" validate:"required,gtfield=WeekStart"`
+	Timezone  string    `json:"timezone" validate:"required,timezone"`
 }
 
-func EnqueueWeeklyReportEmail(client *asynq.Client, task *WeeklyReportEmailTask) error {
-	payload, err := json.Marshal(task)
-	if err != nil {
-		return err
-	}
+func (p *WeeklyReportEmailTask) Type() string {
+	return TaskWeeklyReportEmail
+}
 
-	asynqTask := asynq.NewTask(TaskWeeklyReportEmail, payload,
+// Options uses the user and week as the task id so a report is never queued
+// twice
+func (p *WeeklyReportEmailTask) Options() []asynq.Option {
+	return []asynq.Option{
+		asynq.TaskID("weekly_report:" + p.UserID + ":" + strconv.FormatInt(p.WeekEnd.Unix(), 10)),
 		asynq.MaxRetry(3),
 		asynq.Queue("default"),
-		asynq.Timeout(60*time.Second)) // Longer timeout for report generation
+		asynq.Timeout(60 * time.Second), // Longer timeout for report generation
+	}
+}
 
-	_, err = client.Enqueue(asynqTask)
+// EnqueueWeeklyReportEmail queues a weekly report, treating one that is
+// already queued as success
+func EnqueueWeeklyReportEmail(ctx context.Context, client *asynq.Client, task *WeeklyReportEmailTask) error {
+	err := Enqueue(ctx, client, task)
+	if errors.Is(err, asynq.ErrTaskIDConflict) {
+		return nil
+	}
 	return err
 }
+
+// MentionEmailTask tells a user they were mentioned in a comment. Excerpt is
+// the comment, shortened to fit an email preview.
+type MentionEmailTask struct {
+	TaskMeta
+	UserID    string    `json:"user_id" validate:"required"`
+	AuthorID  string    `json:"author_id" validate:"required"`
+	CommentID uuid.UUID `json:"comment_id" validate:"required"`
+	TodoID    uuid.UUID `json:"todo_id" validate:"required"`
+	TodoTitle string    `json:"todo_title" validate:"required"`
+	Excerpt   string    `json:"excerpt" validate:"required"`
+}
+
+func (p *MentionEmailTask) Type() string {
+	return TaskMentionEmail
+}
+
+func (p *MentionEmailTask) Options() []asynq.Option {
+	return []asynq.Option{
+		asynq.MaxRetry(3),
+		asynq.Queue("default"),
+		asynq.Timeout(30 * time.Second),
+	}
+}
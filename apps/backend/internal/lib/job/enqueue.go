@@ -0,0 +1,181 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/mabhi256/tasker/internal/lib/tracing"
+	"github.com/mabhi256/tasker/internal/lib/usage"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+var payloadValidator = validator.New()
+
+// Payload is a task payload that knows its task type and how it is queued.
+// Payload types embed TaskMeta and use validate tags for their fields.
+type Payload interface {
+	Type() string
+	Options() []asynq.Option
+	taskMeta() *TaskMeta
+}
+
+// TaskMeta carries metadata about the enqueueing request alongside a task's
+// payload. asynq tasks have no headers, so it is stored in the payload itself
+// and ignored by the handlers.
+type TaskMeta struct {
+	// Trace holds the New Relic distributed tracing headers of the request
+	// that enqueued the task
+	Trace map[string]string `json:"trace,omitempty"`
+	// WorkspaceID is the workspace the task's work is charged to, if any
+	WorkspaceID uuid.UUID `json:"workspace_id,omitzero"`
+	// Deadline is when the task stops being worth running, see Deadline
+	Deadline time.Time `json:"deadline,omitzero"`
+}
+
+// ErrTaskExpired is returned for a task enqueued after its deadline
+var ErrTaskExpired = errors.New("task deadline has passed")
+
+// Deadline drops a task that hasn't started by t, for work that is useless
+// once late, like a reminder that arrives after the todo is due. The task
+// is logged and counted as expired instead of run, and isn't retried.
+// Unlike asynq's deadline it doesn't fail the task, so an outage doesn't
+// end with expired tasks archived as failures.
+func Deadline(t time.Time) asynq.Option {
+	return asynq.Deadline(t)
+}
+
+// takeDeadline removes deadline options, which are kept with the payload
+// rather than given to asynq, and returns the last one
+func takeDeadline(opts []asynq.Option) ([]asynq.Option, time.Time) {
+	var deadline time.Time
+	kept := make([]asynq.Option, 0, len(opts))
+	for _, opt := range opts {
+		if opt.Type() == asynq.DeadlineOpt {
+			deadline, _ = opt.Value().(time.Time)
+			continue
+		}
+		kept = append(kept, opt)
+	}
+	return kept, deadline
+}
+
+func (m *TaskMeta) taskMeta() *TaskMeta {
+	return m
+}
+
+// NewTask validates payload and builds its task. The trace of the
+// transaction in ctx, if any, is attached so the worker continues it, and
+// so is the workspace ctx charges work to. A task whose deadline has
+// already passed isn't built, and ErrTaskExpired is returned.
+func NewTask[P any, PT interface {
+	*P
+	Payload
+}](ctx context.Context, payload PT, opts ...asynq.Option) (*asynq.Task, error) {
+	if err := payloadValidator.Struct(payload); err != nil {
+		return nil, fmt.Errorf("invalid %s payload: %w", payload.Type(), err)
+	}
+
+	// The configured policy overrides the payload's defaults, and options
+	// passed by the caller override both
+	options := append(payload.Options(), policyOptions(payload.Type())...)
+	options, deadline := takeDeadline(append(options, opts...))
+	if !deadline.IsZero() && !deadline.After(time.Now()) {
+		return nil, fmt.Errorf("%s task: %w", payload.Type(), ErrTaskExpired)
+	}
+
+	payload.taskMeta().Trace = tracing.Headers(ctx)
+	payload.taskMeta().WorkspaceID = usage.WorkspaceFromContext(ctx)
+	payload.taskMeta().Deadline = deadline
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s payload: %w", payload.Type(), err)
+	}
+
+	return asynq.NewTask(payload.Type(), data, options...), nil
+}
+
+// Enqueue validates payload and queues its task, for example:
+//
+//	job.Enqueue(ctx, client, &job.WelcomeEmailPayload{To: to, FirstName: name})
+func Enqueue[P any, PT interface {
+	*P
+	Payload
+}](ctx context.Context, client *asynq.Client, payload PT, opts ...asynq.Option) error {
+	task, err := NewTask[P](ctx, payload, opts...)
+	if err != nil {
+		return err
+	}
+
+	_, err = client.EnqueueContext(ctx, task)
+	return err
+}
+
+// traceTasks runs each task in a New Relic transaction that continues the
+// trace of the request that enqueued it
+func (j *JobService) traceTasks(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		// A payload that doesn't decode just starts a new trace
+		var meta TaskMeta
+		_ = json.Unmarshal(t.Payload(), &meta)
+
+		ctx, txn := tracing.StartTransaction(ctx, j.nrApp, "job/"+t.Type(), newrelic.TransportQueue, meta.Trace)
+		defer txn.End()
+
+		if taskID, ok := asynq.GetTaskID(ctx); ok {
+			txn.AddAttribute("task.id", taskID)
+		}
+		if queue, ok := asynq.GetQueueName(ctx); ok {
+			txn.AddAttribute("task.queue", queue)
+		}
+
+		err := next.ProcessTask(ctx, t)
+		if err != nil {
+			txn.NoticeError(err)
+		}
+		return err
+	})
+}
+
+// dropExpired drops a task whose deadline passed while it was queued,
+// completing it without running it
+func (j *JobService) dropExpired(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		var meta TaskMeta
+		_ = json.Unmarshal(t.Payload(), &meta)
+
+		if meta.Deadline.IsZero() || time.Now().Before(meta.Deadline) {
+			return next.ProcessTask(ctx, t)
+		}
+
+		taskID, _ := asynq.GetTaskID(ctx)
+		j.logger.Warn().
+			Str("task_type", t.Type()).
+			Str("task_id", taskID).
+			Time("deadline", meta.Deadline).
+			Dur("late_by", time.Since(meta.Deadline)).
+			Msg("Dropping task that missed its deadline")
+		j.metrics.TaskExpired(t.Type())
+		return nil
+	})
+}
+
+// meterTasks counts each task execution, and charges it and the work it does
+// to the workspace it was enqueued for
+func (j *JobService) meterTasks(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		var meta TaskMeta
+		_ = json.Unmarshal(t.Payload(), &meta)
+
+		ctx = usage.WithWorkspace(ctx, meta.WorkspaceID)
+		j.meter.AddJobExecution(ctx)
+
+		return next.ProcessTask(ctx, t)
+	})
+}
@@ -0,0 +1,157 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+)
+
+const (
+	TaskExportRun         = "export:run"
+	TaskExportFailedEmail = "email:export_failed"
+)
+
+type ExportRunnerInterface interface {
+	RunExport(ctx context.Context, runID uuid.UUID) error
+	// RecordExportFailure stores the error on the run. When final is set the
+	// run is marked failed and the schedule owner is alerted.
+	RecordExportFailure(ctx context.Context, runID uuid.UUID, runErr error, final bool) error
+}
+
+type ExportRunTask struct {
+	TaskMeta
+	RunID uuid.UUID `json:"run_id" validate:"required"`
+}
+
+func (p *ExportRunTask) Type() string {
+	return TaskExportRun
+}
+
+// Options uses the run id as the task id so a run is never queued twice
+func (p *ExportRunTask) Options() []asynq.Option {
+	return []asynq.Option{
+		asynq.TaskID(p.RunID.String()),
+		asynq.MaxRetry(3),
+		asynq.Queue("low"),
+		asynq.Timeout(10 * time.Minute),
+	}
+}
+
+type ExportFailedEmailTask struct {
+	TaskMeta
+	UserID       string    `json:"user_id" validate:"required"`
+	ScheduleID   uuid.UUID `json:"schedule_id" validate:"required"`
+	ScheduleName string    `json:"schedule_name" validate:"required"`
+	Destination  string    `json:"destination" validate:"required"`
+	FailedAt     time.Time `json:"failed_at" validate:"required"`
+	Attempts     int       `json:"attempts" validate:"min=0"`
+	Error        string    `json:"error"`
+}
+
+func (p *ExportFailedEmailTask) Type() string {
+	return TaskExportFailedEmail
+}
+
+func (p *ExportFailedEmailTask) Options() []asynq.Option {
+	return []asynq.Option{
+		asynq.MaxRetry(3),
+		asynq.Queue("critical"),
+		asynq.Timeout(30 * time.Second),
+	}
+}
+
+func (j *JobService) handleExportRunTask(ctx context.Context, t *asynq.Task) error {
+	var p ExportRunTask
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal export run payload: %w", err)
+	}
+
+	j.logger.Info().
+		Str("type", "export_run").
+		Str("run_id", p.RunID.String()).
+		Msg("Processing export run task")
+
+	runErr := j.exportRunner.RunExport(ctx, p.RunID)
+	if runErr == nil {
+		j.logger.Info().
+			Str("type", "export_run").
+			Str("run_id", p.RunID.String()).
+			Msg("Successfully delivered export")
+		return nil
+	}
+
+	retried, _ := asynq.GetRetryCount(ctx)
+	maxRetry, _ := asynq.GetMaxRetry(ctx)
+	final := retried >= maxRetry
+
+	j.logger.Error().
+		Str("type", "export_run").
+		Str("run_id", p.RunID.String()).
+		Int("retried", retried).
+		Bool("final", final).
+		Err(runErr).
+		Msg("Failed to deliver export")
+
+	if err := j.exportRunner.RecordExportFailure(ctx, p.RunID, runErr, final); err != nil {
+		j.logger.Error().
+			Str("type", "export_run").
+			Str("run_id", p.RunID.String()).
+			Err(err).
+			Msg("Failed to record export failure")
+	}
+
+	return runErr
+}
+
+func (j *JobService) handleExportFailedEmailTask(ctx context.Context, t *asynq.Task) error {
+	var p ExportFailedEmailTask
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal export failed email payload: %w", err)
+	}
+
+	j.logger.Info().
+		Str("type", "export_failed").
+		Str("user_id", p.UserID).
+		Str("schedule_id", p.ScheduleID.String()).
+		Msg("Processing export failed email task")
+
+	userEmail, err := j.authService.GetUserEmail(ctx, p.UserID)
+	if err != nil {
+		j.logger.Error().
+			Str("type", "export_failed").
+			Str("user_id", p.UserID).
+			Err(err).
+			Msg("Failed to resolve user email")
+		return fmt.Errorf("failed to resolve user email for user %s: %w", p.UserID, err)
+	}
+
+	err = j.emailClient.SendExportFailedEmail(
+		ctx,
+		userEmail,
+		p.ScheduleName,
+		p.ScheduleID,
+		p.Destination,
+		p.FailedAt,
+		p.Attempts,
+		p.Error,
+	)
+	if err != nil {
+		j.logger.Error().
+			Str("type", "export_failed").
+			Str("user_id", p.UserID).
+			Err(err).
+			Msg("Failed to send export failed email")
+		return err
+	}
+
+	j.logger.Info().
+		Str("type", "export_failed").
+		Str("user_id", p.UserID).
+		Str("schedule_id", p.ScheduleID.String()).
+		Msg("Successfully sent export failed email")
+	return nil
+}
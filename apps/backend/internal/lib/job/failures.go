@@ -0,0 +1,210 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+const (
+	// failureWatchPageSize is how many archived tasks are read per request
+	failureWatchPageSize = 100
+	// failureWatchLookback bounds the first scan after startup, so a restart
+	// doesn't walk the whole archive
+	failureWatchLookback = 24 * time.Hour
+	failureRecordTimeout = 30 * time.Second
+	// failureLogPayloadLimit keeps large payloads out of the logs
+	failureLogPayloadLimit = 2048
+)
+
+// FailureRecorderInterface stores tasks that have been given up on. A
+// failure can be reported by both the error handler and the archive
+// watcher, so implementations ignore one they already recorded and report
+// whether it was new.
+type FailureRecorderInterface interface {
+	RecordJobFailure(ctx context.Context, failure *JobFailure) (bool, error)
+}
+
+// JobFailure is a task that ran out of retries and was archived
+type JobFailure struct {
+	TaskID   string
+	Queue    string
+	Type     string
+	Payload  json.RawMessage
+	Error    string
+	Retried  int
+	MaxRetry int
+	FailedAt time.Time
+}
+
+func (j *JobService) SetFailureRecorder(failureRecorder FailureRecorderInterface) {
+	j.failureRecorder = failureRecorder
+}
+
+// handleTaskError runs after every failed attempt and records the task
+// once asynq is about to archive it
+func (j *JobService) handleTaskError(ctx context.Context, t *asynq.Task, err error) {
+	retried, _ := asynq.GetRetryCount(ctx)
+	maxRetry, _ := asynq.GetMaxRetry(ctx)
+	if retried < maxRetry && !errors.Is(err, asynq.SkipRetry) {
+		return
+	}
+
+	taskID, _ := asynq.GetTaskID(ctx)
+	queue, _ := asynq.GetQueueName(ctx)
+
+	// The task's context is done when it timed out, which shouldn't stop the
+	// failure from being recorded
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), failureRecordTimeout)
+	defer cancel()
+
+	j.recordFailure(ctx, &JobFailure{
+		TaskID:   taskID,
+		Queue:    queue,
+		Type:     t.Type(),
+		Payload:  failurePayload(t.Payload()),
+		Error:    err.Error(),
+		Retried:  retried,
+		MaxRetry: maxRetry,
+		FailedAt: time.Now(),
+	})
+}
+
+func (j *JobService) recordFailure(ctx context.Context, failure *JobFailure) {
+	j.logFailure(failure)
+
+	if j.failureRecorder == nil {
+		return
+	}
+
+	if _, err := j.failureRecorder.RecordJobFailure(ctx, failure); err != nil {
+		j.logRecordError(failure, err)
+	}
+}
+
+func (j *JobService) logFailure(failure *JobFailure) {
+	payload := string(failure.Payload)
+	if len(payload) > failureLogPayloadLimit {
+		payload = payload[:failureLogPayloadLimit] + "..."
+	}
+
+	j.logger.Error().
+		Str("type", "job_failure").
+		Str("task_id", failure.TaskID).
+		Str("queue", failure.Queue).
+		Str("task_type", failure.Type).
+		Str("payload", payload).
+		Int("retried", failure.Retried).
+		Int("max_retry", failure.MaxRetry).
+		Str("error", failure.Error).
+		Msg("Background task failed permanently and was archived")
+}
+
+func (j *JobService) logRecordError(failure *JobFailure, err error) {
+	j.logger.Error().
+		Str("type", "job_failure").
+		Str("task_id", failure.TaskID).
+		Str("queue", failure.Queue).
+		Err(err).
+		Msg("Failed to record job failure")
+}
+
+// watchFailures periodically records the state of the queues and the
+// archived tasks the error handler never saw, such as tasks whose worker
+// died mid-run and that asynq archived on recovery
+func (j *JobService) watchFailures(ctx context.Context) {
+	defer close(j.failureWatchDone)
+
+	ticker := time.NewTicker(j.failureWatchInterval)
+	defer ticker.Stop()
+
+	since := time.Now().Add(-failureWatchLookback)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		j.recordQueueStates()
+
+		if j.failureRecorder == nil {
+			continue
+		}
+
+		scanStart := time.Now()
+		if err := j.scanArchivedTasks(ctx, since); err != nil {
+			j.logger.Error().Err(err).Msg("failed to scan archived tasks")
+			continue
+		}
+		// Overlap scans so a task archived while the last one ran isn't
+		// missed; repeats are ignored by the recorder
+		since = scanStart.Add(-j.failureWatchInterval)
+	}
+}
+
+func (j *JobService) scanArchivedTasks(ctx context.Context, since time.Time) error {
+	queues, err := j.Inspector.Queues()
+	if err != nil {
+		return err
+	}
+
+	for _, queue := range queues {
+		for page := 1; ctx.Err() == nil; page++ {
+			tasks, err := j.Inspector.ListArchivedTasks(queue,
+				asynq.PageSize(failureWatchPageSize), asynq.Page(page))
+			if err != nil {
+				return err
+			}
+
+			for _, info := range tasks {
+				if info.LastFailedAt.Before(since) {
+					continue
+				}
+
+				failure := &JobFailure{
+					TaskID:   info.ID,
+					Queue:    info.Queue,
+					Type:     info.Type,
+					Payload:  failurePayload(info.Payload),
+					Error:    info.LastErr,
+					Retried:  info.Retried,
+					MaxRetry: info.MaxRetry,
+					FailedAt: info.LastFailedAt,
+				}
+
+				// Most archived tasks were already reported by the error
+				// handler, so only log the ones that are new
+				recorded, err := j.failureRecorder.RecordJobFailure(ctx, failure)
+				if err != nil {
+					j.logRecordError(failure, err)
+					continue
+				}
+				if recorded {
+					j.logFailure(failure)
+				}
+			}
+
+			if len(tasks) < failureWatchPageSize {
+				break
+			}
+		}
+	}
+
+	return ctx.Err()
+}
+
+// failurePayload stores a task payload as JSON. Every task this service
+// enqueues has a JSON payload, but one that doesn't is kept as a string.
+func failurePayload(payload []byte) json.RawMessage {
+	if json.Valid(payload) {
+		return payload
+	}
+
+	encoded, _ := json.Marshal(string(payload))
+	return encoded
+}
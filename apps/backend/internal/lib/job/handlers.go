@@ -6,15 +6,12 @@ import (
 	"fmt"
 
 	"github.com/hibiken/asynq"
-	"github.com/mabhi256/tasker/internal/config"
 	"github.com/mabhi256/tasker/internal/lib/email"
-	"github.com/rs/zerolog"
+	"github.com/mabhi256/tasker/internal/lib/metrics"
 )
 
-var emailClient *email.Client
-
-func (j *JobService) InitHandlers(cfg *config.Config, logger *zerolog.Logger) {
-	emailClient = email.NewClient(cfg, logger)
+func (j *JobService) InitHandlers(emailClient *email.Client) {
+	j.emailClient = emailClient
 }
 
 func (j *JobService) handleWelcomeEmailTask(ctx context.Context, t *asynq.Task) error {
@@ -30,7 +27,7 @@ func (j *JobService) handleWelcomeEmailTask(ctx context.Context, t *asynq.Task)
 		Str("to", p.To).
 		Msg("Processing welcome email task")
 
-	err = emailClient.SendWelcomeEmail(p.To, p.FirstName)
+	err = j.emailClient.SendWelcomeEmail(ctx, p.To, p.FirstName)
 	if err != nil {
 		j.logger.Error().
 			Str("type", "welcome").
@@ -72,9 +69,11 @@ func (j *JobService) handleReminderEmailTask(ctx context.Context, t *asynq.Task)
 		return fmt.Errorf("failed to resolve user email for user %s: %w", p.UserID, err)
 	}
 
+	ctx = email.WithRecipient(ctx, p.UserID)
 	switch p.TaskType {
 	case "due_date_reminder":
 		err = j.emailClient.SendDueDateReminderEmail(
+			ctx,
 			userEmail,
 			p.TodoTitle,
 			p.TodoID,
@@ -82,6 +81,7 @@ func (j *JobService) handleReminderEmailTask(ctx context.Context, t *asynq.Task)
 		)
 	case "overdue_notification":
 		err = j.emailClient.SendOverdueNotificationEmail(
+			ctx,
 			userEmail,
 			p.TodoTitle,
 			p.TodoID,
@@ -98,9 +98,12 @@ func (j *JobService) handleReminderEmailTask(ctx context.Context, t *asynq.Task)
 			Str("todo_id", p.TodoID.String()).
 			Err(err).
 			Msg("Failed to send reminder email")
+		j.metrics.Inc(metrics.RemindersFailed)
 		return err
 	}
 
+	j.metrics.Inc(metrics.RemindersSent)
+
 	j.logger.Info().
 		Str("type", p.TaskType).
 		Str("user_id", p.UserID).
@@ -118,43 +121,75 @@ func (j *JobService) handleWeeklyReportEmailTask(ctx context.Context, t *asynq.T
 	j.logger.Info().
 		Str("type", "weekly_report").
 		Str("user_id", p.UserID).
-		Int("completed_count", p.CompletedCount).
-		Int("active_count", p.ActiveCount).
-		Int("overdue_count", p.OverdueCount).
+		Time("week_end", p.WeekEnd).
+		Str("timezone", p.Timezone).
 		Msg("Processing weekly report email task")
 
-	userEmail, err := j.authService.GetUserEmail(ctx, p.UserID)
+	err := j.weeklyReportSender.SendWeeklyReport(ctx, p.UserID, p.WeekStart, p.WeekEnd, p.Timezone)
 	if err != nil {
 		j.logger.Error().
 			Str("type", "weekly_report").
 			Str("user_id", p.UserID).
 			Err(err).
+			Msg("Failed to send weekly report email")
+		return err
+	}
+
+	j.logger.Info().
+		Str("type", "weekly_report").
+		Str("user_id", p.UserID).
+		Msg("Successfully processed weekly report email")
+	return nil
+}
+
+func (j *JobService) handleMentionEmailTask(ctx context.Context, t *asynq.Task) error {
+	var p MentionEmailTask
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal mention email payload: %w", err)
+	}
+
+	j.logger.Info().
+		Str("type", "mention").
+		Str("user_id", p.UserID).
+		Str("comment_id", p.CommentID.String()).
+		Msg("Processing mention email task")
+
+	userEmail, err := j.authService.GetUserEmail(ctx, p.UserID)
+	if err != nil {
+		j.logger.Error().
+			Str("type", "mention").
+			Str("user_id", p.UserID).
+			Err(err).
 			Msg("Failed to resolve user email")
 		return fmt.Errorf("failed to resolve user email for user %s: %w", p.UserID, err)
 	}
 
-	err = j.emailClient.SendWeeklyReportEmail(
-		userEmail,
-		p.WeekStart,
-		p.WeekEnd,
-		p.CompletedCount,
-		p.ActiveCount,
-		p.OverdueCount,
-		p.CompletedTodos,
-		p.OverdueTodos,
-	)
+	authorEmail, err := j.authService.GetUserEmail(ctx, p.AuthorID)
 	if err != nil {
 		j.logger.Error().
-			Str("type", "weekly_report").
+			Str("type", "mention").
+			Str("user_id", p.AuthorID).
+			Err(err).
+			Msg("Failed to resolve author email")
+		return fmt.Errorf("failed to resolve author email for user %s: %w", p.AuthorID, err)
+	}
+
+	ctx = email.WithRecipient(ctx, p.UserID)
+	err = j.emailClient.SendMentionEmail(ctx, userEmail, authorEmail, p.TodoTitle, p.TodoID, p.Excerpt)
+	if err != nil {
+		j.logger.Error().
+			Str("type", "mention").
 			Str("user_id", p.UserID).
+			Str("comment_id", p.CommentID.String()).
 			Err(err).
-			Msg("Failed to send weekly report email")
+			Msg("Failed to send mention email")
 		return err
 	}
 
 	j.logger.Info().
-		Str("type", "weekly_report").
+		Str("type", "mention").
 		Str("user_id", p.UserID).
-		Msg("Successfully sent weekly report email")
+		Str("comment_id", p.CommentID.String()).
+		Msg("Successfully sent mention email")
 	return nil
 }
@@ -2,61 +2,226 @@ package job
 
 import (
 	"context"
+	"fmt"
+	"sync"
+	"time"
 
 	"github.com/hibiken/asynq"
 	"github.com/mabhi256/tasker/internal/config"
+	"github.com/mabhi256/tasker/internal/database"
 	"github.com/mabhi256/tasker/internal/lib/email"
+	"github.com/mabhi256/tasker/internal/lib/metrics"
+	"github.com/mabhi256/tasker/internal/lib/recovery"
+	"github.com/mabhi256/tasker/internal/lib/usage"
+	"github.com/newrelic/go-agent/v3/newrelic"
 	"github.com/rs/zerolog"
 )
 
 type JobService struct {
-	Client      *asynq.Client
-	server      *asynq.Server
-	logger      *zerolog.Logger
-	authService AuthServiceInterface
-	emailClient *email.Client
+	Client              *asynq.Client
+	Inspector           *asynq.Inspector
+	server              *asynq.Server
+	integrations        []*integrationServer
+	logger              *zerolog.Logger
+	nrApp               *newrelic.Application
+	authService         AuthServiceInterface
+	exportRunner        ExportRunnerInterface
+	webhookDeliverer    WebhookDelivererInterface
+	auditForwarder      AuditForwarderInterface
+	digestSender        DigestSenderInterface
+	weeklyReportSender  WeeklyReportSenderInterface
+	pushSender          PushSenderInterface
+	rolloutBackfiller   RolloutBackfillerInterface
+	backfillRunner      BackfillRunnerInterface
+	reportGenerator     ReportGeneratorInterface
+	attachmentPreviewer AttachmentPreviewerInterface
+	attachmentProcessor AttachmentProcessorInterface
+	cronRunner          CronRunnerInterface
+	failureRecorder     FailureRecorderInterface
+	emailClient         *email.Client
+	metrics             *metrics.Recorder
+	meter               *usage.Meter
+	scheduler           *scheduler
+
+	failureWatchInterval time.Duration
+	failureWatchCancel   context.CancelFunc
+	failureWatchDone     chan struct{}
 }
 
 type AuthServiceInterface interface {
 	GetUserEmail(ctx context.Context, userID string) (string, error)
 }
 
-func NewJobService(cfg *config.Config, logger *zerolog.Logger) *JobService {
+// NewJobService creates the job client and server. nrApp may be nil, in
+// which case tasks aren't traced, and so may meter.
+func NewJobService(cfg *config.Config, logger *zerolog.Logger, nrApp *newrelic.Application,
+	meter *usage.Meter,
+) (*JobService, error) {
 	redisAddr := cfg.Redis.Address
+	SetPolicies(cfg.Jobs)
+
+	jobsCfg := cfg.Jobs
+	if jobsCfg == nil {
+		jobsCfg = config.DefaultJobsConfig()
+	}
+	shutdownCfg := cfg.Shutdown
+	if shutdownCfg == nil {
+		shutdownCfg = config.DefaultShutdownConfig()
+	}
 
 	client := asynq.NewClient(asynq.RedisClientOpt{
 		Addr: redisAddr,
 	})
 
-	server := asynq.NewServer(
+	inspector := asynq.NewInspector(asynq.RedisClientOpt{Addr: redisAddr})
+
+	sched, err := newScheduler(asynq.RedisClientOpt{Addr: redisAddr}, cfg.Scheduler.Location, cfg.Scheduler.Schedules)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &JobService{
+		Client:               client,
+		Inspector:            inspector,
+		logger:               logger,
+		nrApp:                nrApp,
+		metrics:              metrics.New(nrApp),
+		meter:                meter,
+		scheduler:            sched,
+		failureWatchInterval: cfg.JobFailures.WatchInterval,
+	}
+
+	j.server = asynq.NewServer(
 		asynq.RedisClientOpt{Addr: redisAddr},
 		asynq.Config{
-			Concurrency: 10,
+			Concurrency: jobsCfg.Concurrency,
 			Queues: map[string]int{
 				"critical": 6, // Higher priority queue for important emails
 				"default":  3, // Default priority for most emails
 				"low":      1, // Lower priority for non-urgent emails
 			},
+			RetryDelayFunc: retryDelay,
+			ErrorHandler:   asynq.ErrorHandlerFunc(j.handleTaskError),
+			// Active tasks get this long to finish on shutdown, then go
+			// back to their queue
+			ShutdownTimeout: shutdownCfg.Jobs,
 		},
 	)
+	j.newIntegrationServers(asynq.RedisClientOpt{Addr: redisAddr}, jobsCfg.Queues, shutdownCfg.Jobs)
 
-	return &JobService{
-		Client: client,
-		server: server,
-		logger: logger,
-	}
+	return j, nil
 }
 
 func (j *JobService) SetAuthService(authService AuthServiceInterface) {
 	j.authService = authService
 }
 
+func (j *JobService) SetExportRunner(exportRunner ExportRunnerInterface) {
+	j.exportRunner = exportRunner
+}
+
+func (j *JobService) SetWebhookDeliverer(webhookDeliverer WebhookDelivererInterface) {
+	j.webhookDeliverer = webhookDeliverer
+}
+
+func (j *JobService) SetAuditForwarder(auditForwarder AuditForwarderInterface) {
+	j.auditForwarder = auditForwarder
+}
+
+func (j *JobService) SetDigestSender(digestSender DigestSenderInterface) {
+	j.digestSender = digestSender
+}
+
+func (j *JobService) SetWeeklyReportSender(weeklyReportSender WeeklyReportSenderInterface) {
+	j.weeklyReportSender = weeklyReportSender
+}
+
+func (j *JobService) SetPushSender(pushSender PushSenderInterface) {
+	j.pushSender = pushSender
+}
+
+func (j *JobService) SetRolloutBackfiller(rolloutBackfiller RolloutBackfillerInterface) {
+	j.rolloutBackfiller = rolloutBackfiller
+}
+
+func (j *JobService) SetBackfillRunner(backfillRunner BackfillRunnerInterface) {
+	j.backfillRunner = backfillRunner
+}
+
+func (j *JobService) SetReportGenerator(reportGenerator ReportGeneratorInterface) {
+	j.reportGenerator = reportGenerator
+}
+
+func (j *JobService) SetAttachmentPreviewer(attachmentPreviewer AttachmentPreviewerInterface) {
+	j.attachmentPreviewer = attachmentPreviewer
+}
+
+func (j *JobService) SetAttachmentProcessor(attachmentProcessor AttachmentProcessorInterface) {
+	j.attachmentProcessor = attachmentProcessor
+}
+
+// liftQueryTimeouts lets tasks run statements longer than a request could,
+// as each task is bounded by its own timeout
+func liftQueryTimeouts(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		return next.ProcessTask(database.WithQueryTimeout(ctx, 0), t)
+	})
+}
+
+// recoverTasks turns a task's panic into an error, so one bad task fails and
+// is retried like any other instead of taking the worker down. The error
+// keeps the stack it happened on, for the log and for traceTasks to report
+// to New Relic.
+func (j *JobService) recoverTasks(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) (err error) {
+		defer func() {
+			if v := recover(); v != nil {
+				panicErr := recovery.New(v)
+				taskID, _ := asynq.GetTaskID(ctx)
+				j.logger.Error().
+					Err(panicErr).
+					Str("task_type", t.Type()).
+					Str("task_id", taskID).
+					Str("panic_stack", string(panicErr.Stack)).
+					Msg("Recovered from task panic")
+				err = panicErr
+			}
+		}()
+
+		return next.ProcessTask(ctx, t)
+	})
+}
+
 func (j *JobService) Start() error {
 	// Register task handlers
 	mux := asynq.NewServeMux()
+	if j.nrApp != nil {
+		mux.Use(j.traceTasks)
+	}
+	mux.Use(j.dropExpired)
+	mux.Use(j.recordTasks)
+	mux.Use(j.meterTasks)
+	mux.Use(liftQueryTimeouts)
+	mux.Use(j.recoverTasks)
 	mux.HandleFunc(TaskWelcome, j.handleWelcomeEmailTask)
 	mux.HandleFunc(TaskReminderEmail, j.handleReminderEmailTask)
 	mux.HandleFunc(TaskWeeklyReportEmail, j.handleWeeklyReportEmailTask)
+	mux.HandleFunc(TaskMentionEmail, j.handleMentionEmailTask)
+	mux.HandleFunc(TaskExportRun, j.handleExportRunTask)
+	mux.HandleFunc(TaskExportFailedEmail, j.handleExportFailedEmailTask)
+	mux.HandleFunc(TaskReportGenerate, j.handleReportGenerateTask)
+	mux.HandleFunc(TaskReportReadyEmail, j.handleReportReadyEmailTask)
+	mux.HandleFunc(TaskAttachmentPreview, j.handleAttachmentPreviewTask)
+	mux.HandleFunc(TaskAttachmentProcess, j.handleAttachmentProcessTask)
+	mux.HandleFunc(TaskAttachmentQuarantinedEmail, j.handleAttachmentQuarantinedEmailTask)
+	mux.HandleFunc(TaskWebhookDelivery, j.handleWebhookDeliveryTask)
+	mux.HandleFunc(TaskAuditForward, j.handleAuditForwardTask)
+	mux.HandleFunc(TaskDigestEmail, j.handleDigestEmailTask)
+	mux.HandleFunc(TaskPushNotification, j.handlePushNotificationTask)
+	mux.HandleFunc(TaskPushDelivery, j.handlePushDeliveryTask)
+	mux.HandleFunc(TaskRolloutBackfill, j.handleRolloutBackfillTask)
+	mux.HandleFunc(TaskBackfillRun, j.handleBackfillRunTask)
+	mux.HandleFunc(TaskCronJob, j.handleCronJobTask)
 
 	j.logger.Info().Msg("Starting background job server")
 	err := j.server.Start(mux)
@@ -64,11 +229,60 @@ func (j *JobService) Start() error {
 		return err
 	}
 
+	for _, integration := range j.integrations {
+		integration.breaker.reset()
+		if err := integration.server.Start(integration.breaker.middleware(mux)); err != nil {
+			return fmt.Errorf("failed to start %s queue server: %w", integration.queue, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	j.failureWatchCancel = cancel
+	j.failureWatchDone = make(chan struct{})
+	go j.watchFailures(ctx)
+
 	return nil
 }
 
+// Stop stops scheduling cron jobs and taking tasks, then waits for the
+// active tasks of every queue server, up to the shutdown timeout the servers
+// were created with. The client stays open for Close, as what is flushed
+// after the tasks are done may still enqueue.
 func (j *JobService) Stop() {
 	j.logger.Info().Msg("Stopping background job server")
-	j.server.Shutdown()
+	j.StopScheduler()
+	if j.failureWatchCancel != nil {
+		j.failureWatchCancel()
+		<-j.failureWatchDone
+	}
+
+	servers := []*asynq.Server{j.server}
+	for _, integration := range j.integrations {
+		servers = append(servers, integration.server)
+	}
+
+	// No server takes new tasks while the others wait for theirs
+	for _, server := range servers {
+		server.Stop()
+	}
+
+	var wg sync.WaitGroup
+	for _, server := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			server.Shutdown()
+		}()
+	}
+	wg.Wait()
+
+	for _, integration := range j.integrations {
+		integration.breaker.reset()
+	}
+}
+
+// Close closes the job client and inspector
+func (j *JobService) Close() {
 	j.Client.Close()
+	j.Inspector.Close()
 }
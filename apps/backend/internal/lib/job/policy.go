@@ -0,0 +1,77 @@
+package job
+
+import (
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/mabhi256/tasker/internal/config"
+)
+
+// policies holds the configured retry policies. Tasks are enqueued through
+// bare asynq clients, so they are kept for the process rather than on the
+// job service.
+var policies atomic.Pointer[config.JobsConfig]
+
+// SetPolicies sets the retry policies applied to tasks as they are enqueued
+// and retried. Until it is called the default policies apply.
+func SetPolicies(cfg *config.JobsConfig) {
+	policies.Store(cfg)
+}
+
+var defaultPolicies = config.DefaultJobsConfig()
+
+func policyFor(taskType string) (config.JobPolicy, bool) {
+	cfg := policies.Load()
+	if cfg == nil {
+		cfg = defaultPolicies
+	}
+	policy, ok := cfg.Policies[taskType]
+	return policy, ok
+}
+
+// policyOptions returns the options the policy of taskType sets, which
+// override the ones the task declares
+func policyOptions(taskType string) []asynq.Option {
+	policy, ok := policyFor(taskType)
+	if !ok {
+		return nil
+	}
+
+	var opts []asynq.Option
+	if policy.MaxRetry != nil {
+		opts = append(opts, asynq.MaxRetry(*policy.MaxRetry))
+	}
+	if policy.Timeout > 0 {
+		opts = append(opts, asynq.Timeout(policy.Timeout))
+	}
+	if policy.Retention > 0 {
+		opts = append(opts, asynq.Retention(policy.Retention))
+	}
+	return opts
+}
+
+// retryDelay uses the backoff of the task type's policy where one is set
+func retryDelay(n int, err error, t *asynq.Task) time.Duration {
+	policy, _ := policyFor(t.Type())
+	switch policy.Backoff {
+	case config.BackoffConstant:
+		return policy.BackoffBase
+	case config.BackoffExponential:
+		return exponentialDelay(n, policy.BackoffBase, policy.BackoffMax)
+	}
+	return asynq.DefaultRetryDelayFunc(n, err, t)
+}
+
+// exponentialDelay doubles the wait after each failed attempt, with jitter
+// so receivers recovering from an outage aren't hit by every retry at once
+func exponentialDelay(n int, base, maxWait time.Duration) time.Duration {
+	wait := base << n
+	if wait <= 0 || wait > maxWait {
+		wait = maxWait
+	}
+
+	jitter := time.Duration(rand.Int64N(int64(wait/5) + 1))
+	return wait + jitter
+}
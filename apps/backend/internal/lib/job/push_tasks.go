@@ -0,0 +1,117 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/mabhi256/tasker/internal/lib/push"
+)
+
+const (
+	TaskPushNotification = "push:notify"
+	TaskPushDelivery     = "push:deliver"
+)
+
+type PushSenderInterface interface {
+	// NotifyUser queues a delivery of msg to each of the user's devices
+	NotifyUser(ctx context.Context, userID string, msg *push.Message) error
+	// DeliverPush makes one attempt to send msg to the device. An error means
+	// the attempt should be retried; final is set for the last one allowed.
+	// A device its push service no longer accepts is forgotten instead.
+	DeliverPush(ctx context.Context, deviceID uuid.UUID, msg *push.Message, final bool) error
+}
+
+// PushNotificationTask fans a notification out to the user's devices, which
+// are then delivered and retried separately
+type PushNotificationTask struct {
+	TaskMeta
+	UserID  string       `json:"user_id" validate:"required"`
+	Message push.Message `json:"message"`
+}
+
+func (p *PushNotificationTask) Type() string {
+	return TaskPushNotification
+}
+
+func (p *PushNotificationTask) Options() []asynq.Option {
+	return []asynq.Option{
+		asynq.MaxRetry(3),
+		asynq.Queue("default"),
+		asynq.Timeout(30 * time.Second),
+	}
+}
+
+type PushDeliveryTask struct {
+	TaskMeta
+	DeviceID uuid.UUID    `json:"device_id" validate:"required"`
+	Message  push.Message `json:"message"`
+}
+
+func (p *PushDeliveryTask) Type() string {
+	return TaskPushDelivery
+}
+
+func (p *PushDeliveryTask) Options() []asynq.Option {
+	return []asynq.Option{
+		asynq.MaxRetry(5),
+		asynq.Queue(QueuePush),
+		asynq.Timeout(30 * time.Second),
+	}
+}
+
+func (j *JobService) handlePushNotificationTask(ctx context.Context, t *asynq.Task) error {
+	var p PushNotificationTask
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal push notification payload: %w", err)
+	}
+
+	j.logger.Info().
+		Str("type", "push_notification").
+		Str("user_id", p.UserID).
+		Msg("Processing push notification task")
+
+	if err := j.pushSender.NotifyUser(ctx, p.UserID, &p.Message); err != nil {
+		j.logger.Error().
+			Str("type", "push_notification").
+			Str("user_id", p.UserID).
+			Err(err).
+			Msg("Failed to queue push deliveries")
+		return err
+	}
+
+	return nil
+}
+
+func (j *JobService) handlePushDeliveryTask(ctx context.Context, t *asynq.Task) error {
+	var p PushDeliveryTask
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal push delivery payload: %w", err)
+	}
+
+	retried, _ := asynq.GetRetryCount(ctx)
+	maxRetry, _ := asynq.GetMaxRetry(ctx)
+	final := retried >= maxRetry
+
+	j.logger.Info().
+		Str("type", "push_delivery").
+		Str("device_id", p.DeviceID.String()).
+		Int("retried", retried).
+		Msg("Processing push delivery task")
+
+	if err := j.pushSender.DeliverPush(ctx, p.DeviceID, &p.Message, final); err != nil {
+		j.logger.Warn().
+			Str("type", "push_delivery").
+			Str("device_id", p.DeviceID.String()).
+			Int("retried", retried).
+			Bool("final", final).
+			Err(err).
+			Msg("Push delivery attempt failed")
+		return err
+	}
+
+	return nil
+}
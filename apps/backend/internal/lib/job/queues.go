@@ -0,0 +1,176 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/mabhi256/tasker/internal/config"
+	"github.com/mabhi256/tasker/internal/lib/metrics"
+	"github.com/rs/zerolog"
+)
+
+// Queues of outbound integrations. Each is processed by a server of its own,
+// with its own workers, so a slow or failing third party only holds up its
+// own tasks and never reminder emails. Slack alerts are sent inline by the
+// alert notifier rather than as tasks, so they have no queue.
+const (
+	QueueWebhooks = "webhooks"
+	QueueAudit    = "audit"
+	QueuePush     = "push"
+)
+
+// integrationServer processes one integration queue
+type integrationServer struct {
+	queue   string
+	server  *asynq.Server
+	breaker *queueBreaker
+}
+
+func (j *JobService) newIntegrationServers(redis asynq.RedisClientOpt, queues map[string]config.QueueConfig,
+	shutdownTimeout time.Duration,
+) {
+	for name, queue := range queues {
+		j.integrations = append(j.integrations, &integrationServer{
+			queue: name,
+			server: asynq.NewServer(redis, asynq.Config{
+				Concurrency:     queue.Concurrency,
+				Queues:          map[string]int{name: 1},
+				RetryDelayFunc:  retryDelay,
+				ErrorHandler:    asynq.ErrorHandlerFunc(j.handleTaskError),
+				ShutdownTimeout: shutdownTimeout,
+			}),
+			breaker: &queueBreaker{
+				queue:     name,
+				threshold: queue.BreakerThreshold,
+				cooldown:  queue.BreakerCooldown,
+				inspector: j.Inspector,
+				logger:    j.logger,
+				metrics:   j.metrics,
+			},
+		})
+	}
+}
+
+// recordTasks records each task's outcome and duration by queue
+func (j *JobService) recordTasks(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		queue, _ := asynq.GetQueueName(ctx)
+		start := time.Now()
+		err := next.ProcessTask(ctx, t)
+		j.metrics.Task(queue, err != nil, time.Since(start))
+		return err
+	})
+}
+
+// recordQueueStates records the size and latency of every queue
+func (j *JobService) recordQueueStates() {
+	queues, err := j.Inspector.Queues()
+	if err != nil {
+		j.logger.Error().Err(err).Msg("failed to list queues")
+		return
+	}
+
+	for _, queue := range queues {
+		info, err := j.Inspector.GetQueueInfo(queue)
+		if err != nil {
+			j.logger.Error().Err(err).Str("queue", queue).Msg("failed to read queue info")
+			continue
+		}
+		j.metrics.QueueState(queue, info.Size, info.Latency, info.Paused)
+	}
+}
+
+// queueBreaker pauses its queue after threshold tasks in a row fail, so the
+// workers stop calling a third party that is down, and resumes it after the
+// cooldown. The first task to fail after that pauses it again. A threshold
+// of 0 disables the breaker.
+type queueBreaker struct {
+	queue     string
+	threshold int
+	cooldown  time.Duration
+	inspector *asynq.Inspector
+	logger    *zerolog.Logger
+	metrics   *metrics.Recorder
+
+	mu       sync.Mutex
+	failures int
+	timer    *time.Timer
+}
+
+func (b *queueBreaker) middleware(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		err := next.ProcessTask(ctx, t)
+		// A task that can't succeed says nothing about the third party
+		if b.threshold > 0 && !errors.Is(err, asynq.SkipRetry) {
+			b.record(err == nil)
+		}
+		return err
+	})
+}
+
+func (b *queueBreaker) record(succeeded bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if succeeded {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.failures < b.threshold || b.timer != nil {
+		return
+	}
+
+	if err := b.inspector.PauseQueue(b.queue); err != nil {
+		b.logger.Error().Err(err).Str("queue", b.queue).Msg("failed to pause queue")
+		return
+	}
+	b.metrics.BreakerOpened(b.queue)
+	b.logger.Warn().
+		Str("queue", b.queue).
+		Int("failures", b.failures).
+		Dur("cooldown", b.cooldown).
+		Msg("queue paused after repeated task failures")
+
+	b.timer = time.AfterFunc(b.cooldown, b.resume)
+}
+
+// resume unpauses the queue, leaving it one failure from pausing again
+func (b *queueBreaker) resume() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.timer = nil
+	b.failures = b.threshold - 1
+	if err := b.inspector.UnpauseQueue(b.queue); err != nil {
+		b.logger.Error().Err(err).Str("queue", b.queue).Msg("failed to unpause queue")
+		return
+	}
+	b.logger.Info().Str("queue", b.queue).Msg("queue resumed")
+}
+
+// reset unpauses the queue if a previous process left it paused, or stops
+// the cooldown and unpauses it on shutdown
+func (b *queueBreaker) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	b.failures = 0
+
+	// A queue that doesn't exist yet has no state to clear
+	info, err := b.inspector.GetQueueInfo(b.queue)
+	if err != nil || !info.Paused {
+		return
+	}
+	if err := b.inspector.UnpauseQueue(b.queue); err != nil {
+		b.logger.Error().Err(err).Str("queue", b.queue).Msg("failed to unpause queue")
+	}
+}
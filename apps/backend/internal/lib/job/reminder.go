@@ -0,0 +1,53 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+)
+
+const reminderQueue = "default"
+
+// reminderScheduleAttempts bounds how often scheduling a reminder replaces
+// one scheduled concurrently for the same todo
+const reminderScheduleAttempts = 3
+
+// ReminderTaskID is the task id of the todo's due date reminder. Only one
+// task can hold it, which is what keeps a todo to one pending reminder.
+func ReminderTaskID(todoID uuid.UUID) string {
+	return "reminder:" + todoID.String()
+}
+
+// ScheduleReminder replaces the todo's due date reminder, whether pending or
+// already sent, with one sent at. A time that has passed sends it now.
+func (j *JobService) ScheduleReminder(ctx context.Context, task *ReminderEmailTask, at time.Time) error {
+	for range reminderScheduleAttempts {
+		if err := j.CancelReminder(ctx, task.TodoID); err != nil {
+			return err
+		}
+
+		// Another update may schedule the todo's reminder between the two,
+		// in which case it is replaced again
+		err := Enqueue(ctx, j.Client, task, asynq.ProcessAt(at))
+		if !errors.Is(err, asynq.ErrTaskIDConflict) {
+			return err
+		}
+	}
+
+	return fmt.Errorf("failed to schedule reminder for todo %s: replaced concurrently %d times",
+		task.TodoID, reminderScheduleAttempts)
+}
+
+// CancelReminder removes the todo's due date reminder. A todo without one
+// is left as is.
+func (j *JobService) CancelReminder(ctx context.Context, todoID uuid.UUID) error {
+	err := j.Inspector.DeleteTask(reminderQueue, ReminderTaskID(todoID))
+	if err == nil || errors.Is(err, asynq.ErrTaskNotFound) || errors.Is(err, asynq.ErrQueueNotFound) {
+		return nil
+	}
+	return fmt.Errorf("failed to cancel reminder for todo %s: %w", todoID, err)
+}
@@ -0,0 +1,145 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+)
+
+const (
+	TaskReportGenerate   = "report:generate"
+	TaskReportReadyEmail = "email:report_ready"
+)
+
+type ReportGeneratorInterface interface {
+	GenerateReport(ctx context.Context, reportID uuid.UUID) error
+	// RecordReportFailure stores the error on the report. When final is set
+	// the report is marked failed.
+	RecordReportFailure(ctx context.Context, reportID uuid.UUID, reportErr error, final bool) error
+}
+
+type ReportGenerateTask struct {
+	TaskMeta
+	ReportID uuid.UUID `json:"report_id" validate:"required"`
+}
+
+func (p *ReportGenerateTask) Type() string {
+	return TaskReportGenerate
+}
+
+// Options uses the report id as the task id so a report is never queued twice
+func (p *ReportGenerateTask) Options() []asynq.Option {
+	return []asynq.Option{
+		asynq.TaskID(p.ReportID.String()),
+		asynq.MaxRetry(3),
+		asynq.Queue("low"),
+		asynq.Timeout(10 * time.Minute),
+	}
+}
+
+type ReportReadyEmailTask struct {
+	TaskMeta
+	UserID        string    `json:"user_id" validate:"required"`
+	ReportID      uuid.UUID `json:"report_id" validate:"required"`
+	WorkspaceName string    `json:"workspace_name" validate:"required"`
+	DownloadURL   string    `json:"download_url" validate:"required,url"`
+}
+
+func (p *ReportReadyEmailTask) Type() string {
+	return TaskReportReadyEmail
+}
+
+func (p *ReportReadyEmailTask) Options() []asynq.Option {
+	return []asynq.Option{
+		asynq.MaxRetry(3),
+		asynq.Queue("critical"),
+		asynq.Timeout(30 * time.Second),
+	}
+}
+
+func (j *JobService) handleReportGenerateTask(ctx context.Context, t *asynq.Task) error {
+	var p ReportGenerateTask
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal report generate payload: %w", err)
+	}
+
+	j.logger.Info().
+		Str("type", "report_generate").
+		Str("report_id", p.ReportID.String()).
+		Msg("Processing report generate task")
+
+	reportErr := j.reportGenerator.GenerateReport(ctx, p.ReportID)
+	if reportErr == nil {
+		j.logger.Info().
+			Str("type", "report_generate").
+			Str("report_id", p.ReportID.String()).
+			Msg("Successfully generated report")
+		return nil
+	}
+
+	retried, _ := asynq.GetRetryCount(ctx)
+	maxRetry, _ := asynq.GetMaxRetry(ctx)
+	final := retried >= maxRetry
+
+	j.logger.Error().
+		Str("type", "report_generate").
+		Str("report_id", p.ReportID.String()).
+		Int("retried", retried).
+		Bool("final", final).
+		Err(reportErr).
+		Msg("Failed to generate report")
+
+	if err := j.reportGenerator.RecordReportFailure(ctx, p.ReportID, reportErr, final); err != nil {
+		j.logger.Error().
+			Str("type", "report_generate").
+			Str("report_id", p.ReportID.String()).
+			Err(err).
+			Msg("Failed to record report failure")
+	}
+
+	return reportErr
+}
+
+func (j *JobService) handleReportReadyEmailTask(ctx context.Context, t *asynq.Task) error {
+	var p ReportReadyEmailTask
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal report ready email payload: %w", err)
+	}
+
+	j.logger.Info().
+		Str("type", "report_ready").
+		Str("user_id", p.UserID).
+		Str("report_id", p.ReportID.String()).
+		Msg("Processing report ready email task")
+
+	userEmail, err := j.authService.GetUserEmail(ctx, p.UserID)
+	if err != nil {
+		j.logger.Error().
+			Str("type", "report_ready").
+			Str("user_id", p.UserID).
+			Err(err).
+			Msg("Failed to resolve user email")
+		return fmt.Errorf("failed to resolve user email for user %s: %w", p.UserID, err)
+	}
+
+	err = j.emailClient.SendReportReadyEmail(ctx, userEmail, p.WorkspaceName, p.DownloadURL)
+	if err != nil {
+		j.logger.Error().
+			Str("type", "report_ready").
+			Str("user_id", p.UserID).
+			Err(err).
+			Msg("Failed to send report ready email")
+		return err
+	}
+
+	j.logger.Info().
+		Str("type", "report_ready").
+		Str("user_id", p.UserID).
+		Str("report_id", p.ReportID.String()).
+		Msg("Successfully sent report ready email")
+	return nil
+}
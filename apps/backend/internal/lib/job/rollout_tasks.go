@@ -0,0 +1,90 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+const TaskRolloutBackfill = "rollout:backfill"
+
+type RolloutBackfillerInterface interface {
+	// RunBackfill backfills batches of the rollout for a limited time. more
+	// reports that rows are left for another task to continue with.
+	RunBackfill(ctx context.Context, name string) (more bool, err error)
+	// RecordBackfillFailure stores the error on the rollout's progress. When
+	// final is set the rollout is marked failed.
+	RecordBackfillFailure(ctx context.Context, name string, runErr error, final bool) error
+}
+
+// RolloutBackfillTask runs part of an expand/contract backfill, then queues
+// another to continue from where it stopped
+type RolloutBackfillTask struct {
+	TaskMeta
+	Name string `json:"name" validate:"required"`
+}
+
+func (p *RolloutBackfillTask) Type() string {
+	return TaskRolloutBackfill
+}
+
+func (p *RolloutBackfillTask) Options() []asynq.Option {
+	return []asynq.Option{
+		asynq.MaxRetry(5),
+		asynq.Queue("low"),
+		asynq.Timeout(15 * time.Minute),
+	}
+}
+
+func (j *JobService) handleRolloutBackfillTask(ctx context.Context, t *asynq.Task) error {
+	var p RolloutBackfillTask
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal rollout backfill payload: %w", err)
+	}
+
+	j.logger.Info().
+		Str("type", "rollout_backfill").
+		Str("rollout", p.Name).
+		Msg("Processing rollout backfill task")
+
+	more, runErr := j.rolloutBackfiller.RunBackfill(ctx, p.Name)
+	if runErr != nil {
+		retried, _ := asynq.GetRetryCount(ctx)
+		maxRetry, _ := asynq.GetMaxRetry(ctx)
+		final := retried >= maxRetry
+
+		j.logger.Error().
+			Str("type", "rollout_backfill").
+			Str("rollout", p.Name).
+			Int("retried", retried).
+			Bool("final", final).
+			Err(runErr).
+			Msg("Failed to backfill rollout")
+
+		if err := j.rolloutBackfiller.RecordBackfillFailure(ctx, p.Name, runErr, final); err != nil {
+			j.logger.Error().
+				Str("type", "rollout_backfill").
+				Str("rollout", p.Name).
+				Err(err).
+				Msg("Failed to record rollout backfill failure")
+		}
+
+		return runErr
+	}
+
+	if more {
+		if err := Enqueue(ctx, j.Client, &RolloutBackfillTask{Name: p.Name}); err != nil {
+			return fmt.Errorf("failed to queue rollout backfill continuation: %w", err)
+		}
+		return nil
+	}
+
+	j.logger.Info().
+		Str("type", "rollout_backfill").
+		Str("rollout", p.Name).
+		Msg("Rollout backfill finished")
+	return nil
+}
@@ -0,0 +1,290 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+	robfigcron "github.com/robfig/cron/v3"
+)
+
+const TaskCronJob = "cron:run"
+
+const (
+	// Only the replica holding the lease runs the scheduler, so a job is
+	// enqueued once per tick however many replicas are up. A replica that
+	// dies stops renewing and another takes over when the lease expires.
+	schedulerLeaderKey  = "tasker:scheduler:leader"
+	schedulerLeaseTTL   = 30 * time.Second
+	schedulerRenewEvery = 10 * time.Second
+)
+
+// acquireLease takes the lease if it is free and extends it if this
+// replica already holds it
+var acquireLease = redis.NewScript(`
+	if redis.call("GET", KEYS[1]) == ARGV[1] then
+		return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	end
+	if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+		return 1
+	end
+	return 0
+`)
+
+var releaseLease = redis.NewScript(`
+	if redis.call("GET", KEYS[1]) == ARGV[1] then
+		return redis.call("DEL", KEYS[1])
+	end
+	return 0
+`)
+
+type CronRunnerInterface interface {
+	RunCronJob(ctx context.Context, name string) error
+}
+
+type CronJobTask struct {
+	TaskMeta
+	Name string `json:"name" validate:"required"`
+}
+
+func (p *CronJobTask) Type() string {
+	return TaskCronJob
+}
+
+// Options doesn't retry cron jobs, a failed run waits for the next tick
+func (p *CronJobTask) Options() []asynq.Option {
+	return []asynq.Option{
+		asynq.MaxRetry(0),
+		asynq.Queue("low"),
+		asynq.Timeout(30 * time.Minute),
+	}
+}
+
+// ScheduledJob is a recurring job with its most recent and upcoming runs as
+// seen by the scheduler currently in charge
+type ScheduledJob struct {
+	Name      string
+	Spec      string
+	NextRunAt *time.Time
+	LastRunAt *time.Time
+}
+
+// scheduler runs the configured cron jobs through an asynq.Scheduler while
+// this replica is the leader
+type scheduler struct {
+	redisOpt   asynq.RedisClientOpt
+	redis      *redis.Client
+	location   *time.Location
+	schedules  map[string]string
+	instanceID string
+
+	current *asynq.Scheduler
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+func newScheduler(redisOpt asynq.RedisClientOpt, location string, schedules map[string]string) (*scheduler, error) {
+	loc, err := time.LoadLocation(location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scheduler location %q: %w", location, err)
+	}
+
+	active := make(map[string]string, len(schedules))
+	for name, spec := range schedules {
+		if spec == "" {
+			continue
+		}
+		if _, err := robfigcron.ParseStandard(spec); err != nil {
+			return nil, fmt.Errorf("invalid schedule %q for cron job %s: %w", spec, name, err)
+		}
+		active[name] = spec
+	}
+
+	return &scheduler{
+		redisOpt:   redisOpt,
+		redis:      redis.NewClient(&redis.Options{Addr: redisOpt.Addr}),
+		location:   loc,
+		schedules:  active,
+		instanceID: uuid.NewString(),
+	}, nil
+}
+
+func (j *JobService) SetCronRunner(cronRunner CronRunnerInterface) {
+	j.cronRunner = cronRunner
+}
+
+// StartScheduler begins competing for the scheduler lease. Call it once the
+// cron runner is set.
+func (j *JobService) StartScheduler() {
+	s := j.scheduler
+	if len(s.schedules) == 0 {
+		j.logger.Info().Msg("No cron jobs scheduled")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	go j.leadScheduler(ctx)
+}
+
+// StopScheduler stops scheduling and hands the lease to another replica
+func (j *JobService) StopScheduler() {
+	s := j.scheduler
+	if s.cancel != nil {
+		s.cancel()
+		<-s.done
+	}
+	s.redis.Close()
+}
+
+func (j *JobService) leadScheduler(ctx context.Context) {
+	s := j.scheduler
+	defer close(s.done)
+
+	ticker := time.NewTicker(schedulerRenewEvery)
+	defer ticker.Stop()
+
+	for {
+		leader, err := acquireLease.Run(ctx, s.redis, []string{schedulerLeaderKey},
+			s.instanceID, schedulerLeaseTTL.Milliseconds()).Bool()
+		if err != nil && ctx.Err() == nil {
+			// Without Redis the lease can't be confirmed, so step down
+			j.logger.Error().Err(err).Msg("failed to renew scheduler lease")
+			leader = false
+		}
+
+		switch {
+		case leader && s.current == nil:
+			if err := j.startLeading(); err != nil {
+				j.logger.Error().Err(err).Msg("failed to start scheduler")
+			}
+		case !leader && s.current != nil:
+			j.logger.Info().Msg("Lost scheduler lease, stopping scheduler")
+			s.current.Shutdown()
+			s.current = nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if s.current != nil {
+				s.current.Shutdown()
+				s.current = nil
+			}
+			if err := releaseLease.Run(context.Background(), s.redis, []string{schedulerLeaderKey}, s.instanceID).Err(); err != nil {
+				j.logger.Error().Err(err).Msg("failed to release scheduler lease")
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *JobService) startLeading() error {
+	s := j.scheduler
+
+	sched := asynq.NewScheduler(s.redisOpt, &asynq.SchedulerOpts{
+		Location: s.location,
+		PostEnqueueFunc: func(info *asynq.TaskInfo, err error) {
+			if err != nil {
+				j.logger.Error().Err(err).Msg("failed to enqueue scheduled cron job")
+			}
+		},
+	})
+
+	for name, spec := range s.schedules {
+		task, err := NewTask(context.Background(), &CronJobTask{Name: name})
+		if err != nil {
+			return err
+		}
+
+		if _, err := sched.Register(spec, task); err != nil {
+			return fmt.Errorf("failed to register cron job %s: %w", name, err)
+		}
+	}
+
+	if err := sched.Start(); err != nil {
+		return err
+	}
+	s.current = sched
+
+	j.logger.Info().
+		Str("instance_id", s.instanceID).
+		Int("job_count", len(s.schedules)).
+		Msg("Acquired scheduler lease, scheduling cron jobs")
+	return nil
+}
+
+// ScheduledJobs lists the configured cron jobs by name. Run times come from
+// whichever replica currently holds the lease.
+func (j *JobService) ScheduledJobs() ([]ScheduledJob, error) {
+	entries, err := j.Inspector.SchedulerEntries()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scheduler entries: %w", err)
+	}
+
+	byName := make(map[string]*asynq.SchedulerEntry, len(entries))
+	for _, entry := range entries {
+		if entry.Task.Type() != TaskCronJob {
+			continue
+		}
+		var p CronJobTask
+		if err := json.Unmarshal(entry.Task.Payload(), &p); err != nil {
+			continue
+		}
+		byName[p.Name] = entry
+	}
+
+	jobs := make([]ScheduledJob, 0, len(j.scheduler.schedules))
+	for name, spec := range j.scheduler.schedules {
+		job := ScheduledJob{Name: name, Spec: spec}
+		if entry, ok := byName[name]; ok {
+			next := entry.Next
+			job.NextRunAt = &next
+			if !entry.Prev.IsZero() {
+				prev := entry.Prev
+				job.LastRunAt = &prev
+			}
+		}
+		jobs = append(jobs, job)
+	}
+
+	sort.Slice(jobs, func(a, b int) bool {
+		return jobs[a].Name < jobs[b].Name
+	})
+
+	return jobs, nil
+}
+
+func (j *JobService) handleCronJobTask(ctx context.Context, t *asynq.Task) error {
+	var p CronJobTask
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal cron job payload: %w", err)
+	}
+
+	if j.cronRunner == nil {
+		return fmt.Errorf("no cron runner set for cron job %s", p.Name)
+	}
+
+	j.logger.Info().
+		Str("type", "cron_job").
+		Str("job", p.Name).
+		Msg("Processing scheduled cron job")
+
+	if err := j.cronRunner.RunCronJob(ctx, p.Name); err != nil {
+		j.logger.Error().
+			Str("type", "cron_job").
+			Str("job", p.Name).
+			Err(err).
+			Msg("Scheduled cron job failed")
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+)
+
+const TaskWebhookDelivery = "webhook:deliver"
+
+// webhookMaxRetry with the default exponential backoff of webhook deliveries
+// spreads attempts over about two hours
+const webhookMaxRetry = 8
+
+type WebhookDelivererInterface interface {
+	// DeliverWebhook makes one attempt and records it. An error means the
+	// attempt should be retried; final is set for the last one allowed.
+	DeliverWebhook(ctx context.Context, deliveryID uuid.UUID, final bool) error
+}
+
+type WebhookDeliveryTask struct {
+	TaskMeta
+	DeliveryID uuid.UUID `json:"delivery_id" validate:"required"`
+}
+
+func (p *WebhookDeliveryTask) Type() string {
+	return TaskWebhookDelivery
+}
+
+// Options uses the delivery id as the task id so a delivery that is already
+// queued isn't queued again when its event is republished
+func (p *WebhookDeliveryTask) Options() []asynq.Option {
+	return []asynq.Option{
+		asynq.TaskID(p.DeliveryID.String()),
+		asynq.MaxRetry(webhookMaxRetry),
+		asynq.Queue(QueueWebhooks),
+		asynq.Timeout(30 * time.Second),
+	}
+}
+
+// EnqueueWebhookDelivery queues a delivery, treating one that is already
+// queued as success
+func EnqueueWebhookDelivery(ctx context.Context, client *asynq.Client, task *WebhookDeliveryTask) error {
+	err := Enqueue(ctx, client, task)
+	if errors.Is(err, asynq.ErrTaskIDConflict) {
+		return nil
+	}
+	return err
+}
+
+func (j *JobService) handleWebhookDeliveryTask(ctx context.Context, t *asynq.Task) error {
+	var p WebhookDeliveryTask
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("failed to unmarshal webhook delivery payload: %w", err)
+	}
+
+	retried, _ := asynq.GetRetryCount(ctx)
+	maxRetry, _ := asynq.GetMaxRetry(ctx)
+	final := retried >= maxRetry
+
+	j.logger.Info().
+		Str("type", "webhook_delivery").
+		Str("delivery_id", p.DeliveryID.String()).
+		Int("retried", retried).
+		Msg("Processing webhook delivery task")
+
+	if err := j.webhookDeliverer.DeliverWebhook(ctx, p.DeliveryID, final); err != nil {
+		j.logger.Warn().
+			Str("type", "webhook_delivery").
+			Str("delivery_id", p.DeliveryID.String()).
+			Int("retried", retried).
+			Bool("final", final).
+			Err(err).
+			Msg("Webhook delivery attempt failed")
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,266 @@
+// Package jsonenc encodes the hottest response types without reflection.
+//
+// Types opt in by implementing Appender, which writes the same bytes
+// encoding/json would into a caller supplied buffer. The implementations
+// for the model types are only compiled with the fastjson build tag:
+//
+//	go build -tags fastjson ./...
+//
+// Without the tag nothing implements Appender and Serializer behaves
+// exactly like echo's default serializer. Encoders must be kept in step
+// with the struct fields and tags they mirror; the tests in this package
+// compare their output with encoding/json.
+package jsonenc
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// Appender is implemented by types that encode themselves as JSON
+type Appender interface {
+	AppendJSON(dst []byte) ([]byte, error)
+}
+
+// maxPooledBuffer keeps the odd very large response from pinning memory
+const maxPooledBuffer = 1 << 20
+
+var bufferPool = sync.Pool{
+	New: func() any {
+		buf := make([]byte, 0, 16<<10)
+		return &buf
+	},
+}
+
+// Serializer is an echo.JSONSerializer that encodes Appender values into a
+// pooled buffer and falls back to encoding/json for everything else,
+// including indented output. Requests for the compact view are served the
+// compact view of the response, see Compacter.
+type Serializer struct {
+	fallback echo.DefaultJSONSerializer
+}
+
+func (s Serializer) Serialize(c echo.Context, i any, indent string) error {
+	if IsCompact(c) {
+		i = compactValue(i)
+	}
+
+	appender, ok := i.(Appender)
+	if !ok || indent != "" {
+		return s.fallback.Serialize(c, i, indent)
+	}
+
+	bufp := bufferPool.Get().(*[]byte)
+	defer func() {
+		if cap(*bufp) <= maxPooledBuffer {
+			bufferPool.Put(bufp)
+		}
+	}()
+
+	buf, err := appender.AppendJSON((*bufp)[:0])
+	if err != nil {
+		return err
+	}
+	// json.Encoder ends every value with a newline
+	buf = append(buf, '\n')
+	*bufp = buf
+
+	_, err = c.Response().Write(buf)
+	return err
+}
+
+func (s Serializer) Deserialize(c echo.Context, i any) error {
+	return s.fallback.Deserialize(c, i)
+}
+
+// AppendSlice encodes items as an array, using AppendJSON where the element
+// type implements it and encoding/json otherwise. A nil slice is null.
+func AppendSlice[T any](dst []byte, items []T) ([]byte, error) {
+	if items == nil {
+		return append(dst, "null"...), nil
+	}
+
+	dst = append(dst, '[')
+	for i := range items {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+
+		var err error
+		if appender, ok := any(&items[i]).(Appender); ok {
+			dst, err = appender.AppendJSON(dst)
+		} else {
+			dst, err = appendMarshal(dst, &items[i])
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return append(dst, ']'), nil
+}
+
+// AppendValue encodes v with AppendJSON if it implements it and
+// encoding/json otherwise. A nil pointer is null.
+func AppendValue[T any](dst []byte, v *T) ([]byte, error) {
+	if v == nil {
+		return append(dst, "null"...), nil
+	}
+	if appender, ok := any(v).(Appender); ok {
+		return appender.AppendJSON(dst)
+	}
+	return appendMarshal(dst, v)
+}
+
+func appendMarshal(dst []byte, v any) ([]byte, error) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append(dst, encoded...), nil
+}
+
+func AppendInt(dst []byte, n int64) []byte {
+	return strconv.AppendInt(dst, n, 10)
+}
+
+func AppendIntPtr[T ~int | ~int64](dst []byte, n *T) []byte {
+	if n == nil {
+		return append(dst, "null"...)
+	}
+	return strconv.AppendInt(dst, int64(*n), 10)
+}
+
+func AppendStringPtr(dst []byte, s *string) []byte {
+	if s == nil {
+		return append(dst, "null"...)
+	}
+	return AppendString(dst, *s)
+}
+
+func AppendStrings(dst []byte, items []string) []byte {
+	if items == nil {
+		return append(dst, "null"...)
+	}
+
+	dst = append(dst, '[')
+	for i, s := range items {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = AppendString(dst, s)
+	}
+	return append(dst, ']')
+}
+
+// AppendTime matches time.Time.MarshalJSON for years 0 through 9999
+func AppendTime(dst []byte, t time.Time) []byte {
+	dst = append(dst, '"')
+	dst = t.AppendFormat(dst, time.RFC3339Nano)
+	return append(dst, '"')
+}
+
+func AppendTimePtr(dst []byte, t *time.Time) []byte {
+	if t == nil {
+		return append(dst, "null"...)
+	}
+	return AppendTime(dst, *t)
+}
+
+func AppendUUID(dst []byte, id uuid.UUID) []byte {
+	var buf [36]byte
+	hex.Encode(buf[0:8], id[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], id[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], id[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], id[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], id[10:])
+
+	dst = append(dst, '"')
+	dst = append(dst, buf[:]...)
+	return append(dst, '"')
+}
+
+func AppendUUIDPtr(dst []byte, id *uuid.UUID) []byte {
+	if id == nil {
+		return append(dst, "null"...)
+	}
+	return AppendUUID(dst, *id)
+}
+
+const hexDigits = "0123456789abcdef"
+
+// AppendString quotes s the way encoding/json does with HTML escaping on,
+// which is what echo's default serializer uses
+func AppendString(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if safeASCII(b) {
+				i++
+				continue
+			}
+
+			dst = append(dst, s[start:i]...)
+			switch b {
+			case '\\', '"':
+				dst = append(dst, '\\', b)
+			case '\b':
+				dst = append(dst, '\\', 'b')
+			case '\f':
+				dst = append(dst, '\\', 'f')
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\r':
+				dst = append(dst, '\\', 'r')
+			case '\t':
+				dst = append(dst, '\\', 't')
+			default:
+				// Control characters and <, > and &
+				dst = append(dst, '\\', 'u', '0', '0', hexDigits[b>>4], hexDigits[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+
+		// U+2028 and U+2029 are valid JSON but end lines in JavaScript
+		if r == '\u2028' || r == '\u2029' {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, '\\', 'u', '2', '0', '2', hexDigits[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+
+		i += size
+	}
+
+	dst = append(dst, s[start:]...)
+	return append(dst, '"')
+}
+
+func safeASCII(b byte) bool {
+	return b >= 0x20 && b != '"' && b != '\\' && b != '<' && b != '>' && b != '&'
+}
@@ -0,0 +1,313 @@
+//go:build fastjson
+
+package jsonenc_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/lib/jsonenc"
+	"github.com/mabhi256/tasker/internal/lib/preview"
+	"github.com/mabhi256/tasker/internal/model"
+	"github.com/mabhi256/tasker/internal/model/category"
+	"github.com/mabhi256/tasker/internal/model/comment"
+	"github.com/mabhi256/tasker/internal/model/todo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Strings that exercise every escaping rule of encoding/json
+var trickyStrings = []string{
+	"",
+	"plain text",
+	`quote " and \ backslash`,
+	"<script>alert('x') && 1</script>",
+	"tab\tnewline\ncr\rbackspace\bformfeed\f",
+	"control \x00 \x01 \x1f \x7f",
+	"unicode é 日本語 🚀",
+	"separators \u2028 and \u2029",
+	"invalid \xff\xfe utf-8",
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func newBase(i int) model.Base {
+	created := time.Date(2025, 3, 14, 15, 9, 26, 535897932, time.UTC).Add(time.Duration(i) * time.Hour)
+	return model.Base{
+		BaseWithId:        model.BaseWithId{ID: uuid.New()},
+		BaseWithCreatedAt: model.BaseWithCreatedAt{CreatedAt: created},
+		BaseWithUpdatedAt: model.BaseWithUpdatedAt{UpdatedAt: created.In(time.FixedZone("IST", 5*3600+1800))},
+	}
+}
+
+func newTodo(i int) todo.Todo {
+	t := todo.Todo{
+		Base:        newBase(i),
+		WorkspaceID: uuid.New(),
+		UserID:      fmt.Sprintf("user_%d", i),
+		Title:       trickyStrings[i%len(trickyStrings)],
+		Status:      todo.StatusActive,
+		Priority:    todo.PriorityHigh,
+		SortOrder:   i,
+	}
+
+	if i%2 == 0 {
+		t.Description = ptr("Description with <html> & \"quotes\"")
+		t.DueDate = ptr(t.CreatedAt.Add(72 * time.Hour))
+		t.CategoryID = ptr(uuid.New())
+		t.PriorityRank = ptr(int16(3))
+		t.SearchVector = ptr("'descript':1B 'html':3B")
+		t.Metadata = &todo.Metadata{
+			Tags:       []string{"work", trickyStrings[(i+1)%len(trickyStrings)]},
+			Color:      ptr("#ff0000"),
+			Difficulty: ptr(3),
+		}
+	}
+	if i%3 == 0 {
+		t.CompletedAt = ptr(t.CreatedAt.Add(time.Hour))
+		t.ParentTodoID = ptr(uuid.New())
+		t.Metadata = &todo.Metadata{Tags: []string{}}
+	}
+
+	return t
+}
+
+func newPopulatedTodo(i int) todo.PopulatedTodo {
+	p := todo.PopulatedTodo{Todo: newTodo(i)}
+
+	if i%2 == 0 {
+		p.Category = &category.Category{
+			Base:        newBase(i),
+			WorkspaceID: p.WorkspaceID,
+			UserID:      p.UserID,
+			Name:        "Work",
+			Color:       "#6b7280",
+		}
+	}
+
+	p.Children = []todo.Todo{newTodo(i + 1), newTodo(i + 2)}
+	p.Comments = []comment.Comment{{
+		Base:        newBase(i),
+		WorkspaceID: p.WorkspaceID,
+		TodoID:      p.ID,
+		UserID:      p.UserID,
+		Content:     trickyStrings[(i+2)%len(trickyStrings)],
+	}}
+	if i%3 == 0 {
+		p.Comments[0].ParentCommentID = ptr(uuid.New())
+	}
+	p.Attachments = []todo.TodoAttachment{{
+		Base:             newBase(i),
+		TodoID:           p.ID,
+		WorkspaceID:      p.WorkspaceID,
+		Name:             "report.pdf",
+		UploadedBy:       p.UserID,
+		DownloadKey:      "todos/attachments/report.pdf",
+		FileSize:         ptr(int64(1 << 20)),
+		MimeType:         ptr("application/pdf"),
+		IntegrityStatus:  todo.IntegrityStatusOK,
+		IntegrityError:   ptr("not serialized"),
+		ProcessingStatus: todo.ProcessingStatusPending,
+		ScanStatus:       todo.ScanStatusUnscanned,
+	}}
+	if i%2 == 0 {
+		p.Attachments[0].Preview = &preview.Preview{Kind: preview.KindPDF, PageCount: ptr(12)}
+		p.Attachments[0].PreviewExtractedAt = ptr(p.CreatedAt)
+		p.Attachments[0].ProcessingStatus = todo.ProcessingStatusRejected
+		p.Attachments[0].ProcessingError = ptr("attachment is too large")
+		p.Attachments[0].ProcessedAt = ptr(p.CreatedAt)
+	}
+	if i%3 == 1 {
+		p.Attachments[0].ProcessingStatus = todo.ProcessingStatusRejected
+		p.Attachments[0].ScanStatus = todo.ScanStatusInfected
+		p.Attachments[0].ScanSignature = ptr("Eicar-Signature")
+		p.Attachments[0].ScannedAt = ptr(p.CreatedAt)
+	}
+	if i%4 == 0 {
+		p.Children = nil
+		p.Attachments = []todo.TodoAttachment{}
+	}
+
+	return p
+}
+
+func newTodoPage(n int) *model.PaginatedResponse[todo.PopulatedTodo] {
+	data := make([]todo.PopulatedTodo, n)
+	for i := range data {
+		data[i] = newPopulatedTodo(i)
+	}
+
+	return &model.PaginatedResponse[todo.PopulatedTodo]{
+		Data:       data,
+		Page:       2,
+		Limit:      n,
+		Total:      5 * n,
+		TotalPages: 5,
+	}
+}
+
+// normalizeReplacement hides the one difference between Go releases:
+// invalid UTF-8 is written as an escaped or a literal U+FFFD, which decode
+// to the same string
+func normalizeReplacement(encoded []byte) string {
+	return strings.ReplaceAll(string(encoded), `\ufffd`, "\uFFFD")
+}
+
+func assertMatchesEncodingJSON(t *testing.T, v jsonenc.Appender) {
+	t.Helper()
+
+	want, err := json.Marshal(v)
+	require.NoError(t, err)
+
+	got, err := v.AppendJSON(nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, normalizeReplacement(want), normalizeReplacement(got))
+}
+
+func TestAppendString(t *testing.T) {
+	for _, s := range trickyStrings {
+		want, err := json.Marshal(s)
+		require.NoError(t, err)
+
+		got := jsonenc.AppendString(nil, s)
+		assert.Equal(t, normalizeReplacement(want), normalizeReplacement(got), "string %q", s)
+	}
+}
+
+func TestAppendJSONMatchesEncodingJSON(t *testing.T) {
+	t.Run("todo page", func(t *testing.T) {
+		assertMatchesEncodingJSON(t, newTodoPage(20))
+	})
+
+	t.Run("empty page", func(t *testing.T) {
+		assertMatchesEncodingJSON(t, &model.PaginatedResponse[todo.PopulatedTodo]{
+			Data: []todo.PopulatedTodo{},
+		})
+	})
+
+	t.Run("nil data", func(t *testing.T) {
+		assertMatchesEncodingJSON(t, &model.PaginatedResponse[todo.PopulatedTodo]{})
+	})
+
+	t.Run("category page", func(t *testing.T) {
+		assertMatchesEncodingJSON(t, &model.PaginatedResponse[category.Category]{
+			Data: []category.Category{
+				{Base: newBase(1), Name: "Home", Color: "#00ff00", Description: ptr("Chores & <errands>")},
+				{Base: newBase(2), Name: trickyStrings[8]},
+			},
+			Page:  1,
+			Limit: 20,
+			Total: 2,
+		})
+	})
+
+	t.Run("single todo", func(t *testing.T) {
+		item := newPopulatedTodo(0)
+		assertMatchesEncodingJSON(t, &item)
+	})
+
+	t.Run("search response", func(t *testing.T) {
+		assertMatchesEncodingJSON(t, &todo.SearchResponse{
+			PaginatedResponse: *newTodoPage(3),
+			Meta:              todo.SearchMeta{Engine: todo.SearchEnginePostgres, Degraded: true},
+		})
+		assertMatchesEncodingJSON(t, &todo.SearchResponse{
+			Meta: todo.SearchMeta{Engine: todo.SearchEngineExternal},
+		})
+	})
+
+	t.Run("dependent todo", func(t *testing.T) {
+		blocked := todo.DependentTodo{Todo: newTodo(2), Blocked: true}
+		assertMatchesEncodingJSON(t, &blocked)
+
+		unblocked := todo.DependentTodo{Todo: newTodo(3)}
+		assertMatchesEncodingJSON(t, &unblocked)
+	})
+
+	t.Run("rendered todo page", func(t *testing.T) {
+		page := newTodoPage(6)
+		for i := range page.Data {
+			page.Data[i].RenderContent()
+		}
+		page.Data[0].Description = ptr("- [x] Done\n- [ ] Check <inputs> & \"quotes\"")
+		page.Data[0].RenderContent()
+		assertMatchesEncodingJSON(t, page)
+	})
+
+	t.Run("thread comment", func(t *testing.T) {
+		reply := comment.ThreadComment{
+			Comment:    newPopulatedTodo(3).Comments[0],
+			Depth:      2,
+			ReplyCount: 1,
+			Reactions: []comment.ReactionSummary{
+				{Emoji: "👍", Count: 3, Reacted: true},
+				{Emoji: "👨‍👩‍👧", Count: 1},
+			},
+		}
+		assertMatchesEncodingJSON(t, &reply)
+
+		reply.Reactions = nil
+		assertMatchesEncodingJSON(t, &reply)
+	})
+}
+
+func TestSerializer(t *testing.T) {
+	e := echo.New()
+	e.JSONSerializer = jsonenc.Serializer{}
+	page := newTodoPage(3)
+
+	for _, target := range []string{"/", "/?pretty"} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+
+		fast := httptest.NewRecorder()
+		require.NoError(t, e.NewContext(req, fast).JSON(http.StatusOK, page))
+
+		fallback := httptest.NewRecorder()
+		c := e.NewContext(req, fallback)
+		require.NoError(t, echo.DefaultJSONSerializer{}.Serialize(c, page, indentFor(target)))
+
+		assert.Equal(t, fallback.Body.String(), fast.Body.String(), "response for %s", target)
+	}
+}
+
+func indentFor(target string) string {
+	if target == "/?pretty" {
+		return "  "
+	}
+	return ""
+}
+
+// Run with: go test -tags fastjson -bench . -benchmem ./internal/lib/jsonenc
+func BenchmarkTodoPage(b *testing.B) {
+	page := newTodoPage(20)
+
+	b.Run("encoding_json", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := json.Marshal(page); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("appender", func(b *testing.B) {
+		b.ReportAllocs()
+		buf := make([]byte, 0, 64<<10)
+		for b.Loop() {
+			var err error
+			if buf, err = page.AppendJSON(buf[:0]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
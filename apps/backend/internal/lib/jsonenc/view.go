@@ -0,0 +1,69 @@
+package jsonenc
+
+import (
+	"reflect"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ViewParam is the query parameter that picks the view of a response.
+// ViewCompact trades the full resources for the minimal ones types define
+// with Compact, for mobile clients listing many of them.
+const (
+	ViewParam   = "view"
+	ViewFull    = "full"
+	ViewCompact = "compact"
+)
+
+// Compacter is implemented by types with a compact view. Compact returns a
+// minimal DTO of ids, titles, key dates and counts; wrappers such as pages
+// return themselves with their items compacted.
+type Compacter interface {
+	Compact() any
+}
+
+// IsCompact reports whether the request asks for the compact view
+func IsCompact(c echo.Context) bool {
+	return c.QueryParam(ViewParam) == ViewCompact
+}
+
+// CompactSlice returns the compact views of items. Items without one are
+// kept as they are, and a nil slice stays nil.
+func CompactSlice[T any](items []T) []any {
+	if items == nil {
+		return nil
+	}
+
+	compact := make([]any, len(items))
+	for i := range items {
+		if compacter, ok := any(&items[i]).(Compacter); ok {
+			compact[i] = compacter.Compact()
+		} else {
+			compact[i] = items[i]
+		}
+	}
+	return compact
+}
+
+// compactValue returns the compact view of a response. Responses that are
+// slices are compacted item by item, and responses without a compact view
+// are returned as they are.
+func compactValue(i any) any {
+	if compacter, ok := i.(Compacter); ok {
+		return compacter.Compact()
+	}
+
+	v := reflect.ValueOf(i)
+	if v.Kind() != reflect.Slice || v.IsNil() {
+		return i
+	}
+	if !reflect.PointerTo(v.Type().Elem()).Implements(reflect.TypeFor[Compacter]()) {
+		return i
+	}
+
+	compact := make([]any, v.Len())
+	for j := range compact {
+		compact[j] = v.Index(j).Addr().Interface().(Compacter).Compact()
+	}
+	return compact
+}
@@ -0,0 +1,107 @@
+package jsonenc_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/lib/jsonenc"
+	"github.com/mabhi256/tasker/internal/model"
+	"github.com/mabhi256/tasker/internal/model/category"
+	"github.com/mabhi256/tasker/internal/model/comment"
+	"github.com/mabhi256/tasker/internal/model/todo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serialize(t *testing.T, target string, v any) map[string]any {
+	t.Helper()
+	e := echo.New()
+	e.JSONSerializer = jsonenc.Serializer{}
+
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, target, nil), rec)
+	require.NoError(t, c.JSON(http.StatusOK, v))
+
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &decoded))
+	return decoded
+}
+
+func populatedTodo() todo.PopulatedTodo {
+	return todo.PopulatedTodo{
+		Todo: todo.Todo{
+			Base:        model.Base{BaseWithId: model.BaseWithId{ID: uuid.New()}},
+			WorkspaceID: uuid.New(),
+			Title:       "Write the report",
+			Description: new(string),
+			Status:      todo.StatusActive,
+			Priority:    todo.PriorityHigh,
+		},
+		Children: []todo.Todo{{Title: "Outline"}, {Title: "Draft"}},
+		Comments: []comment.Comment{{Content: "Due Friday"}},
+	}
+}
+
+func TestCompactView(t *testing.T) {
+	page := &model.PaginatedResponse[todo.PopulatedTodo]{
+		Data:  []todo.PopulatedTodo{populatedTodo()},
+		Page:  1,
+		Limit: 20,
+		Total: 1,
+	}
+
+	full := serialize(t, "/", page)
+	assert.Contains(t, full["data"].([]any)[0], "description")
+
+	compact := serialize(t, "/?view=compact", page)
+	assert.EqualValues(t, 1, compact["total"])
+
+	item := compact["data"].([]any)[0].(map[string]any)
+	assert.Equal(t, "Write the report", item["title"])
+	assert.Equal(t, "active", item["status"])
+	assert.EqualValues(t, 2, item["childCount"])
+	assert.EqualValues(t, 1, item["commentCount"])
+	assert.EqualValues(t, 0, item["attachmentCount"])
+	assert.NotContains(t, item, "description")
+	assert.NotContains(t, item, "children")
+	assert.NotContains(t, item, "workspaceId")
+}
+
+func TestCompactViewOfSlices(t *testing.T) {
+	e := echo.New()
+	e.JSONSerializer = jsonenc.Serializer{}
+
+	categories := []category.Category{{Name: "Work", Color: "#ff0000", Description: new(string)}}
+
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/?view=compact", nil), rec)
+	require.NoError(t, c.JSON(http.StatusOK, categories))
+
+	var decoded []map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &decoded))
+	require.Len(t, decoded, 1)
+	assert.Equal(t, "Work", decoded[0]["name"])
+	assert.NotContains(t, decoded[0], "description")
+}
+
+func TestCompactViewKeepsWrapperFields(t *testing.T) {
+	response := &todo.SearchResponse{
+		PaginatedResponse: model.PaginatedResponse[todo.PopulatedTodo]{
+			Data: []todo.PopulatedTodo{populatedTodo()},
+		},
+		Meta: todo.SearchMeta{Engine: todo.SearchEnginePostgres, Degraded: true},
+	}
+
+	compact := serialize(t, "/?view=compact", response)
+	assert.Equal(t, map[string]any{"engine": "postgres", "degraded": true}, compact["meta"])
+	assert.NotContains(t, compact["data"].([]any)[0], "description")
+}
+
+func TestCompactViewWithoutCompacter(t *testing.T) {
+	stats := &todo.TodoStats{Total: 3, Active: 2}
+	assert.Equal(t, serialize(t, "/", stats), serialize(t, "/?view=compact", stats))
+}
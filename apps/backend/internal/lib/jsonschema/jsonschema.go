@@ -0,0 +1,213 @@
+// Package jsonschema describes Go types as JSON Schema (draft 2020-12).
+//
+// Schemas follow encoding/json: field names and omission come from json
+// tags, embedded structs are flattened and pointers and slices, which may
+// encode as null, are nullable. Types that marshal themselves are described
+// as any value unless they are known, such as time.Time and uuid.UUID.
+package jsonschema
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Draft is the dialect of the generated schemas, for the root's $schema
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is the subset of JSON Schema the generator produces. Type is a
+// string, or a list of strings when the value may also be null.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 any                `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Const                any                `json:"const,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+}
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	uuidType          = reflect.TypeFor[uuid.UUID]()
+	rawMessageType    = reflect.TypeFor[json.RawMessage]()
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// Generator builds schemas, listing the allowed values of the enum types it
+// has been told about
+type Generator struct {
+	enums map[reflect.Type][]any
+}
+
+func NewGenerator() *Generator {
+	return &Generator{enums: make(map[reflect.Type][]any)}
+}
+
+// Enum records the values allowed for their type, e.g. every todo status.
+// All values must be of the same type.
+func (g *Generator) Enum(values ...any) *Generator {
+	if len(values) > 0 {
+		g.enums[reflect.TypeOf(values[0])] = values
+	}
+	return g
+}
+
+// Generate describes the type of v
+func (g *Generator) Generate(v any) *Schema {
+	return g.schema(reflect.TypeOf(v), map[reflect.Type]bool{})
+}
+
+// inProgress holds the structs being described, so a recursive type is cut
+// off as any value instead of recursing forever
+func (g *Generator) schema(t reflect.Type, inProgress map[reflect.Type]bool) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+
+	if t.Kind() == reflect.Pointer {
+		return nullable(g.schema(t.Elem(), inProgress))
+	}
+
+	if values, ok := g.enums[t]; ok {
+		s := g.kindSchema(t)
+		s.Enum = values
+		return s
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case uuidType:
+		return &Schema{Type: "string", Format: "uuid"}
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	if t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType) {
+		return &Schema{}
+	}
+	if t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if inProgress[t] {
+			return &Schema{}
+		}
+		inProgress[t] = true
+		defer delete(inProgress, t)
+
+		s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		g.addFields(s, t, inProgress)
+		return s
+	case reflect.Slice, reflect.Array:
+		// encoding/json writes byte slices as base64 strings
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return nullable(&Schema{Type: "string", Format: "byte"})
+		}
+		s := &Schema{Type: "array", Items: g.schema(t.Elem(), inProgress)}
+		if t.Kind() == reflect.Slice {
+			return nullable(s)
+		}
+		return s
+	case reflect.Map:
+		return nullable(&Schema{Type: "object", AdditionalProperties: g.schema(t.Elem(), inProgress)})
+	default:
+		return g.kindSchema(t)
+	}
+}
+
+// kindSchema describes the scalar kinds, and anything else as any value
+func (g *Generator) kindSchema(t reflect.Type) *Schema {
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	default:
+		return &Schema{}
+	}
+}
+
+// addFields adds the fields of struct t, and of the structs it embeds, to s
+func (g *Generator) addFields(s *Schema, t reflect.Type, inProgress map[reflect.Type]bool) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				g.addFields(s, embedded, inProgress)
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		var fieldSchema *Schema
+		if hasOption(opts, "string") {
+			fieldSchema = &Schema{Type: "string"}
+		} else {
+			fieldSchema = g.schema(field.Type, inProgress)
+		}
+
+		s.Properties[name] = fieldSchema
+		if !hasOption(opts, "omitempty") && !hasOption(opts, "omitzero") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+func hasOption(opts, option string) bool {
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
+
+// nullable lets s also be null. A schema without a type already allows it.
+func nullable(s *Schema) *Schema {
+	switch typ := s.Type.(type) {
+	case string:
+		s.Type = []string{typ, "null"}
+	case []string:
+		s.Type = append(typ, "null")
+	}
+	if s.Enum != nil {
+		s.Enum = append(s.Enum, nil)
+	}
+	return s
+}
@@ -0,0 +1,139 @@
+package jsonschema_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/lib/jsonschema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type color string
+
+type base struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type node struct {
+	Name     string `json:"name"`
+	Children []node `json:"children"`
+	Parent   *node  `json:"parent"`
+}
+
+type item struct {
+	base
+	Title    string          `json:"title"`
+	Note     *string         `json:"note,omitempty"`
+	Count    int64           `json:"count,string"`
+	Score    float64         `json:"score"`
+	Done     bool            `json:"done"`
+	Color    color           `json:"color"`
+	Shade    *color          `json:"shade"`
+	Tags     []string        `json:"tags"`
+	Labels   map[string]int  `json:"labels"`
+	Raw      json.RawMessage `json:"raw"`
+	Blob     []byte          `json:"blob"`
+	Tree     node            `json:"tree"`
+	Untagged string
+	Hidden   string `json:"-"`
+	private  string
+}
+
+// generate returns the schema as the JSON it is served as
+func generate(t *testing.T, v any) map[string]any {
+	t.Helper()
+
+	schema := jsonschema.NewGenerator().Enum(color("red"), color("green")).Generate(v)
+
+	data, err := json.Marshal(schema)
+	require.NoError(t, err)
+
+	var out map[string]any
+	require.NoError(t, json.Unmarshal(data, &out))
+	return out
+}
+
+func TestGenerate(t *testing.T) {
+	schema := generate(t, item{})
+	props := schema["properties"].(map[string]any)
+
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{field: "id", want: `{"type":"string","format":"uuid"}`},
+		{field: "createdAt", want: `{"type":"string","format":"date-time"}`},
+		{field: "title", want: `{"type":"string"}`},
+		{field: "note", want: `{"type":["string","null"]}`},
+		{field: "count", want: `{"type":"string"}`},
+		{field: "score", want: `{"type":"number"}`},
+		{field: "done", want: `{"type":"boolean"}`},
+		{field: "color", want: `{"type":"string","enum":["red","green"]}`},
+		{field: "shade", want: `{"type":["string","null"],"enum":["red","green",null]}`},
+		{field: "tags", want: `{"type":["array","null"],"items":{"type":"string"}}`},
+		{field: "labels", want: `{"type":["object","null"],"additionalProperties":{"type":"integer"}}`},
+		{field: "raw", want: `{}`},
+		{field: "blob", want: `{"type":["string","null"],"format":"byte"}`},
+		{field: "Untagged", want: `{"type":"string"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			require.Contains(t, props, tt.field)
+			got, err := json.Marshal(props[tt.field])
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(got))
+		})
+	}
+
+	assert.Equal(t, "object", schema["type"])
+	assert.NotContains(t, props, "Hidden")
+	assert.NotContains(t, props, "private")
+	assert.NotContains(t, props, "base")
+
+	assert.ElementsMatch(t, []any{
+		"id", "createdAt", "title", "count", "score", "done", "color", "shade",
+		"tags", "labels", "raw", "blob", "tree", "Untagged",
+	}, schema["required"])
+}
+
+func TestGenerateRecursiveType(t *testing.T) {
+	schema := generate(t, node{})
+	props := schema["properties"].(map[string]any)
+
+	// The recursive references are cut off rather than expanded forever
+	children := props["children"].(map[string]any)
+	assert.Equal(t, map[string]any{}, children["items"])
+	assert.Equal(t, map[string]any{}, props["parent"])
+}
+
+func TestGenerateMatchesEncoding(t *testing.T) {
+	note := "note"
+	shade := color("green")
+	v := item{
+		Title: "title",
+		Note:  &note,
+		Shade: &shade,
+		Tags:  []string{"a"},
+	}
+
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+
+	var encoded map[string]any
+	require.NoError(t, json.Unmarshal(data, &encoded))
+
+	// Every key encoding/json writes is described, and every required key is
+	// written
+	props := generate(t, v)["properties"].(map[string]any)
+	for key := range encoded {
+		assert.Contains(t, props, key)
+	}
+	for _, key := range generate(t, v)["required"].([]any) {
+		assert.Contains(t, encoded, key)
+	}
+}
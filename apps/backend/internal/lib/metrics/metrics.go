@@ -0,0 +1,146 @@
+// Package metrics records domain metrics, such as todos created or reminders
+// sent, as New Relic custom metrics. They chart and alert on counts that were
+// previously only recoverable by counting log lines.
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+// prefix groups the metrics, which the agent reports under Custom/
+const prefix = "Tasker/"
+
+// Metric names a count. New Relic sums the values recorded for a metric
+// within each harvest.
+type Metric string
+
+const (
+	TodosCreated            Metric = "todos_created"
+	CommentsAdded           Metric = "comments_added"
+	MentionsNotified        Metric = "mentions_notified"
+	RemindersSent           Metric = "reminders_sent"
+	RemindersFailed         Metric = "reminders_failed"
+	WeeklyReportsSent       Metric = "weekly_reports_sent"
+	DigestsSent             Metric = "digests_sent"
+	DigestsHeld             Metric = "digests_held"
+	EmailsOpened            Metric = "emails_opened"
+	EmailsClicked           Metric = "emails_clicked"
+	ExportsFailed           Metric = "exports_failed"
+	WebhookDeliveriesFailed Metric = "webhook_deliveries_failed"
+	PushesSent              Metric = "pushes_sent"
+	PushesFailed            Metric = "pushes_failed"
+	SearchesDegraded        Metric = "searches_degraded"
+	AuditForwardsFailed     Metric = "audit_forwards_failed"
+)
+
+// Recorder records metrics. Without a New Relic application, as in
+// development, recording does nothing.
+type Recorder struct {
+	app *newrelic.Application
+}
+
+// New creates a recorder. app may be nil.
+func New(app *newrelic.Application) *Recorder {
+	return &Recorder{app: app}
+}
+
+// Count adds n to the metric
+func (r *Recorder) Count(metric Metric, n int) {
+	if r == nil || r.app == nil || n == 0 {
+		return
+	}
+	r.app.RecordCustomMetric(prefix+string(metric), float64(n))
+}
+
+// Inc adds one to the metric
+func (r *Recorder) Inc(metric Metric) {
+	r.Count(metric, 1)
+}
+
+// TaskExpired counts a task dropped because its deadline passed before it
+// ran, in total and by task type, e.g. Tasker/tasks_expired/email:reminder
+func (r *Recorder) TaskExpired(taskType string) {
+	if r == nil || r.app == nil {
+		return
+	}
+
+	name := prefix + "tasks_expired"
+	r.app.RecordCustomMetric(name, 1)
+	r.app.RecordCustomMetric(name+"/"+taskType, 1)
+}
+
+// Task records a processed task by queue, e.g. Tasker/queues/webhooks/tasks,
+// whose count is the number of tasks and average their duration in seconds,
+// and counts the failed ones under Tasker/queues/webhooks/failed
+func (r *Recorder) Task(queue string, failed bool, duration time.Duration) {
+	if r == nil || r.app == nil {
+		return
+	}
+
+	name := prefix + "queues/" + queue
+	r.app.RecordCustomMetric(name+"/tasks", duration.Seconds())
+	if failed {
+		r.app.RecordCustomMetric(name+"/failed", 1)
+	}
+}
+
+// QueueState records how many tasks wait in a queue, how long the oldest
+// has waited in seconds, and whether the queue is paused
+func (r *Recorder) QueueState(queue string, size int, latency time.Duration, paused bool) {
+	if r == nil || r.app == nil {
+		return
+	}
+
+	name := prefix + "queues/" + queue
+	r.app.RecordCustomMetric(name+"/size", float64(size))
+	r.app.RecordCustomMetric(name+"/latency", latency.Seconds())
+	pausedValue := 0.0
+	if paused {
+		pausedValue = 1
+	}
+	r.app.RecordCustomMetric(name+"/paused", pausedValue)
+}
+
+// BreakerOpened counts a queue paused by its circuit breaker
+func (r *Recorder) BreakerOpened(queue string) {
+	if r == nil || r.app == nil {
+		return
+	}
+	r.app.RecordCustomMetric(prefix+"queues/"+queue+"/breaker_opened", 1)
+}
+
+// Request records a served request under its route template, such as
+// /api/v1/todos/:id, never its URL, so the number of metrics is bounded by
+// the routes. The first metric's count is the number of requests and its
+// average their latency in seconds. The second counts them by status class,
+// e.g. Tasker/http/GET /api/v1/todos/:id/5xx.
+func (r *Recorder) Request(method, route string, status int, latency time.Duration) {
+	if r == nil || r.app == nil {
+		return
+	}
+
+	name := prefix + "http/" + method + " " + route
+	r.app.RecordCustomMetric(name, latency.Seconds())
+	r.app.RecordCustomMetric(name+"/"+strconv.Itoa(status/100)+"xx", 1)
+}
+
+// ValidationFailure counts a request field that failed binding or
+// validation, in total and by route and field, e.g.
+// Tasker/validation_failures/POST /api/v1/todos/dueDate. Fields the route
+// doesn't accept are named by the client, so they are counted together
+// under _unknown to keep the number of metrics bounded.
+func (r *Recorder) ValidationFailure(method, route, field string, unknown bool) {
+	if r == nil || r.app == nil {
+		return
+	}
+
+	if unknown {
+		field = "_unknown"
+	}
+	name := prefix + "validation_failures"
+	r.app.RecordCustomMetric(name, 1)
+	r.app.RecordCustomMetric(name+"/"+method+" "+route+"/"+field, 1)
+}
@@ -0,0 +1,285 @@
+// Package oidc verifies ID tokens from OpenID Connect identity providers.
+// Providers are found through their discovery document and their signing
+// keys are cached, refetched when a token is signed with a key that isn't
+// known yet. Discovery URLs are customer-supplied, so requests go through
+// the fetcher.
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+const (
+	// WellKnownPath is where providers serve their discovery document
+	WellKnownPath = "/.well-known/openid-configuration"
+
+	// keyRefreshInterval bounds how often an unknown key id refetches the
+	// provider's keys, so tokens with made up key ids can't hammer it
+	keyRefreshInterval = time.Minute
+	maxDocumentSize    = 1 << 20
+)
+
+var (
+	ErrDiscovery    = errors.New("OIDC discovery failed")
+	ErrInvalidToken = errors.New("invalid ID token")
+)
+
+// signingAlgorithms are the asymmetric algorithms tokens may be signed
+// with. Symmetric ones would verify with a key the client also holds.
+var signingAlgorithms = []string{
+	string(jose.RS256), string(jose.RS384), string(jose.RS512),
+	string(jose.PS256), string(jose.PS384), string(jose.PS512),
+	string(jose.ES256), string(jose.ES384), string(jose.ES512),
+	string(jose.EdDSA),
+}
+
+// Doer sends HTTP requests, e.g. an httpclient.Fetcher
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Provider is the part of a discovery document needed to sign users in and
+// verify their tokens
+type Provider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Claims are the verified claims of an ID token
+type Claims struct {
+	Issuer   string
+	Subject  string
+	Email    string
+	Nonce    string
+	IssuedAt time.Time
+	// SessionID is the provider's session, when it says
+	SessionID string
+	// ClientID is the client the token was issued to, from client_id or azp
+	ClientID string
+	// Scopes are the access token's scopes, from scope or scp
+	Scopes []string
+	// EmailVerified is nil when the provider doesn't say
+	EmailVerified *bool
+}
+
+type cachedProvider struct {
+	provider  Provider
+	keys      jose.JSONWebKeySet
+	fetchedAt time.Time
+}
+
+type Verifier struct {
+	http Doer
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu        sync.Mutex
+	providers map[string]*cachedProvider
+}
+
+// NewVerifier returns a verifier that caches providers for ttl
+func NewVerifier(httpClient Doer, ttl time.Duration) *Verifier {
+	return &Verifier{
+		http:      httpClient,
+		ttl:       ttl,
+		now:       time.Now,
+		providers: make(map[string]*cachedProvider),
+	}
+}
+
+// Discover returns the provider's metadata
+func (v *Verifier) Discover(ctx context.Context, discoveryURL string) (*Provider, error) {
+	cached, err := v.provider(ctx, discoveryURL, false)
+	if err != nil {
+		return nil, err
+	}
+	return &cached.provider, nil
+}
+
+// Verify checks that rawToken was signed by the provider for clientID and
+// hasn't expired, and returns its claims
+func (v *Verifier) Verify(ctx context.Context, discoveryURL string, clientID string,
+	rawToken string,
+) (*Claims, error) {
+	token, err := jwt.ParseSigned(rawToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if len(token.Headers) != 1 {
+		return nil, fmt.Errorf("%w: expected one signature", ErrInvalidToken)
+	}
+	header := token.Headers[0]
+	if !slices.Contains(signingAlgorithms, header.Algorithm) {
+		return nil, fmt.Errorf("%w: unsupported algorithm %s", ErrInvalidToken, header.Algorithm)
+	}
+
+	cached, err := v.provider(ctx, discoveryURL, false)
+	if err != nil {
+		return nil, err
+	}
+
+	key := findKey(&cached.keys, header.KeyID)
+	if key == nil && v.now().Sub(cached.fetchedAt) > keyRefreshInterval {
+		// The provider may have rotated its keys
+		if cached, err = v.provider(ctx, discoveryURL, true); err != nil {
+			return nil, err
+		}
+		key = findKey(&cached.keys, header.KeyID)
+	}
+	if key == nil {
+		return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalidToken, header.KeyID)
+	}
+
+	var std jwt.Claims
+	var extra struct {
+		Email         string `json:"email"`
+		EmailVerified any    `json:"email_verified"`
+		Nonce         string `json:"nonce"`
+		SessionID     string `json:"sid"`
+		ClientID      string `json:"client_id"`
+		AuthorizedBy  string `json:"azp"`
+		Scope         string `json:"scope"`
+		Scp           any    `json:"scp"`
+	}
+	if err := token.Claims(key, &std, &extra); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if std.Expiry == nil {
+		return nil, fmt.Errorf("%w: missing exp", ErrInvalidToken)
+	}
+	err = std.ValidateWithLeeway(jwt.Expected{
+		Issuer:   cached.provider.Issuer,
+		Audience: jwt.Audience{clientID},
+		Time:     v.now(),
+	}, jwt.DefaultLeeway)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	claims := &Claims{
+		Issuer:    std.Issuer,
+		Subject:   std.Subject,
+		Email:     extra.Email,
+		Nonce:     extra.Nonce,
+		SessionID: extra.SessionID,
+		ClientID:  extra.ClientID,
+		Scopes:    strings.Fields(extra.Scope),
+	}
+	if claims.ClientID == "" {
+		claims.ClientID = extra.AuthorizedBy
+	}
+	// Some providers send their scopes in scp, as a list or a string
+	switch scp := extra.Scp.(type) {
+	case string:
+		claims.Scopes = append(claims.Scopes, strings.Fields(scp)...)
+	case []any:
+		for _, scope := range scp {
+			if scope, ok := scope.(string); ok {
+				claims.Scopes = append(claims.Scopes, scope)
+			}
+		}
+	}
+	if std.IssuedAt != nil {
+		claims.IssuedAt = std.IssuedAt.Time()
+	}
+	// Some providers send email_verified as a string
+	switch verified := extra.EmailVerified.(type) {
+	case bool:
+		claims.EmailVerified = &verified
+	case string:
+		b := strings.EqualFold(verified, "true")
+		claims.EmailVerified = &b
+	}
+
+	return claims, nil
+}
+
+func findKey(keys *jose.JSONWebKeySet, keyID string) *jose.JSONWebKey {
+	if keyID == "" {
+		// Only unambiguous without a key id
+		if len(keys.Keys) == 1 {
+			return &keys.Keys[0]
+		}
+		return nil
+	}
+	if found := keys.Key(keyID); len(found) > 0 {
+		return &found[0]
+	}
+	return nil
+}
+
+// provider returns the cached provider, fetching it when it is missing,
+// stale or refresh is set
+func (v *Verifier) provider(ctx context.Context, discoveryURL string, refresh bool) (*cachedProvider, error) {
+	v.mu.Lock()
+	cached, ok := v.providers[discoveryURL]
+	v.mu.Unlock()
+	if ok && !refresh && v.now().Sub(cached.fetchedAt) < v.ttl {
+		return cached, nil
+	}
+
+	var provider Provider
+	if err := v.getJSON(ctx, discoveryURL, &provider); err != nil {
+		return nil, err
+	}
+	if provider.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: discovery document has no jwks_uri", ErrDiscovery)
+	}
+	// The issuer must be the URL the document was found under, or one
+	// provider could issue tokens in another's name
+	if strings.TrimSuffix(provider.Issuer, "/") != strings.TrimSuffix(strings.TrimSuffix(discoveryURL, WellKnownPath), "/") {
+		return nil, fmt.Errorf("%w: issuer %q does not match the discovery URL", ErrDiscovery, provider.Issuer)
+	}
+
+	var keys jose.JSONWebKeySet
+	if err := v.getJSON(ctx, provider.JWKSURI, &keys); err != nil {
+		return nil, err
+	}
+
+	cached = &cachedProvider{provider: provider, keys: keys, fetchedAt: v.now()}
+
+	v.mu.Lock()
+	v.providers[discoveryURL] = cached
+	v.mu.Unlock()
+
+	return cached, nil
+}
+
+func (v *Verifier) getJSON(ctx context.Context, rawURL string, dst any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDiscovery, err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := v.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDiscovery, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s returned status %d", ErrDiscovery, rawURL, resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxDocumentSize)).Decode(dst); err != nil {
+		return fmt.Errorf("%w: failed to decode %s: %v", ErrDiscovery, rawURL, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,169 @@
+package oidc_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/mabhi256/tasker/internal/lib/oidc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	issuer       = "https://idp.example.com"
+	discoveryURL = issuer + oidc.WellKnownPath
+	clientID     = "tasker"
+)
+
+type doerFunc func(req *http.Request) (*http.Response, error)
+
+func (f doerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func jsonResponse(t *testing.T, v any) *http.Response {
+	t.Helper()
+	body, err := json.Marshal(v)
+	require.NoError(t, err)
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(string(body)))}
+}
+
+type provider struct {
+	key      *rsa.PrivateKey
+	issuer   string
+	requests int
+}
+
+func newProvider(t *testing.T) *provider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return &provider{key: key, issuer: issuer}
+}
+
+func (p *provider) verifier(t *testing.T) *oidc.Verifier {
+	return oidc.NewVerifier(doerFunc(func(req *http.Request) (*http.Response, error) {
+		p.requests++
+		switch req.URL.String() {
+		case discoveryURL:
+			return jsonResponse(t, oidc.Provider{
+				Issuer:                p.issuer,
+				AuthorizationEndpoint: issuer + "/authorize",
+				TokenEndpoint:         issuer + "/token",
+				JWKSURI:               issuer + "/keys",
+			}), nil
+		case issuer + "/keys":
+			return jsonResponse(t, jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+				{Key: &p.key.PublicKey, KeyID: "key-1", Algorithm: string(jose.RS256), Use: "sig"},
+			}}), nil
+		default:
+			return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader(""))}, nil
+		}
+	}), time.Hour)
+}
+
+type extraClaims struct {
+	Email         string `json:"email,omitempty"`
+	EmailVerified any    `json:"email_verified,omitempty"`
+	Nonce         string `json:"nonce,omitempty"`
+}
+
+func sign(t *testing.T, key any, alg jose.SignatureAlgorithm, keyID string, claims jwt.Claims, extra extraClaims) string {
+	t.Helper()
+	opts := (&jose.SignerOptions{}).WithType("JWT")
+	if keyID != "" {
+		opts = opts.WithHeader("kid", keyID)
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: alg, Key: key}, opts)
+	require.NoError(t, err)
+
+	token, err := jwt.Signed(signer).Claims(claims).Claims(extra).CompactSerialize()
+	require.NoError(t, err)
+	return token
+}
+
+func validClaims() jwt.Claims {
+	now := time.Now()
+	return jwt.Claims{
+		Issuer:   issuer,
+		Subject:  "user-1",
+		Audience: jwt.Audience{clientID},
+		IssuedAt: jwt.NewNumericDate(now),
+		Expiry:   jwt.NewNumericDate(now.Add(time.Hour)),
+	}
+}
+
+func TestVerify(t *testing.T) {
+	p := newProvider(t)
+	verifier := p.verifier(t)
+
+	token := sign(t, p.key, jose.RS256, "key-1", validClaims(), extraClaims{
+		Email:         "ada@example.com",
+		EmailVerified: "true",
+		Nonce:         "nonce-1",
+	})
+
+	claims, err := verifier.Verify(context.Background(), discoveryURL, clientID, token)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", claims.Subject)
+	assert.Equal(t, "ada@example.com", claims.Email)
+	require.NotNil(t, claims.EmailVerified)
+	assert.True(t, *claims.EmailVerified)
+	assert.Equal(t, "nonce-1", claims.Nonce)
+
+	// The provider is cached
+	_, err = verifier.Verify(context.Background(), discoveryURL, clientID, token)
+	require.NoError(t, err)
+	assert.Equal(t, 2, p.requests)
+}
+
+func TestVerifyRejects(t *testing.T) {
+	p := newProvider(t)
+	verifier := p.verifier(t)
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	wrongAudience := validClaims()
+	wrongAudience.Audience = jwt.Audience{"someone-else"}
+
+	wrongIssuer := validClaims()
+	wrongIssuer.Issuer = "https://evil.example.com"
+
+	expired := validClaims()
+	expired.Expiry = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+
+	noExpiry := validClaims()
+	noExpiry.Expiry = nil
+
+	for name, token := range map[string]string{
+		"audience":    sign(t, p.key, jose.RS256, "key-1", wrongAudience, extraClaims{}),
+		"issuer":      sign(t, p.key, jose.RS256, "key-1", wrongIssuer, extraClaims{}),
+		"expired":     sign(t, p.key, jose.RS256, "key-1", expired, extraClaims{}),
+		"no expiry":   sign(t, p.key, jose.RS256, "key-1", noExpiry, extraClaims{}),
+		"signature":   sign(t, otherKey, jose.RS256, "key-1", validClaims(), extraClaims{}),
+		"unknown key": sign(t, otherKey, jose.RS256, "key-2", validClaims(), extraClaims{}),
+		"symmetric":   sign(t, []byte("0123456789abcdef0123456789abcdef"), jose.HS256, "key-1", validClaims(), extraClaims{}),
+		"malformed":   "not-a-token",
+	} {
+		_, err := verifier.Verify(context.Background(), discoveryURL, clientID, token)
+		assert.ErrorIs(t, err, oidc.ErrInvalidToken, name)
+	}
+}
+
+func TestDiscoverIssuerMismatch(t *testing.T) {
+	p := newProvider(t)
+	p.issuer = "https://evil.example.com"
+
+	_, err := p.verifier(t).Discover(context.Background(), discoveryURL)
+	assert.ErrorIs(t, err, oidc.ErrDiscovery)
+}
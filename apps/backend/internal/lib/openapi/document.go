@@ -0,0 +1,199 @@
+// Package openapi checks HTTP responses against an OpenAPI 3.0 document, so
+// tests can catch handlers drifting from the documented contract. It knows
+// the parts of the specification the served document uses: paths with
+// templated parameters, responses by status code and JSON schemas.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Document is a parsed OpenAPI document
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Servers    []Server                        `json:"servers"`
+	Paths      map[string]map[string]Operation `json:"paths"`
+	Components Components                      `json:"components"`
+
+	// basePath is the path of the first server's URL, which the document's
+	// paths are relative to
+	basePath string
+	routes   []route
+}
+
+type Server struct {
+	URL string `json:"url"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is the subset of the OpenAPI 3.0 schema object that responses are
+// validated with
+type Schema struct {
+	Ref        string             `json:"$ref"`
+	Type       string             `json:"type"`
+	Format     string             `json:"format"`
+	Nullable   bool               `json:"nullable"`
+	Enum       []any              `json:"enum"`
+	Properties map[string]*Schema `json:"properties"`
+	Required   []string           `json:"required"`
+	Items      *Schema            `json:"items"`
+	AllOf      []*Schema          `json:"allOf"`
+	AnyOf      []*Schema          `json:"anyOf"`
+	OneOf      []*Schema          `json:"oneOf"`
+
+	// AdditionalProperties is either a boolean or a schema
+	AdditionalProperties json.RawMessage `json:"additionalProperties"`
+}
+
+// Load reads and parses the document at path
+func Load(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OpenAPI document: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse parses a JSON OpenAPI document
+func Parse(data []byte) (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI version %q", doc.OpenAPI)
+	}
+
+	if len(doc.Servers) > 0 {
+		u, err := url.Parse(doc.Servers[0].URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid server URL %q: %w", doc.Servers[0].URL, err)
+		}
+		doc.basePath = strings.TrimSuffix(u.Path, "/")
+	}
+
+	for template, operations := range doc.Paths {
+		doc.routes = append(doc.routes, newRoute(template, operations))
+	}
+
+	return &doc, nil
+}
+
+// Operation returns the operation documented for method on path, along with
+// its path template. Paths are matched with the server's base path removed,
+// when they have it.
+func (d *Document) Operation(method, path string) (*Operation, string, bool) {
+	candidates := []string{path}
+	if d.basePath != "" && strings.HasPrefix(path, d.basePath+"/") {
+		candidates = []string{strings.TrimPrefix(path, d.basePath), path}
+	}
+
+	for _, candidate := range candidates {
+		if r := d.match(candidate); r != nil {
+			operation, ok := r.operations[strings.ToLower(method)]
+			if !ok {
+				return nil, r.template, false
+			}
+			return &operation, r.template, true
+		}
+	}
+
+	return nil, "", false
+}
+
+// resolve follows a schema's $ref to the components it points to
+func (d *Document) resolve(schema *Schema) (*Schema, error) {
+	for seen := 0; schema.Ref != ""; seen++ {
+		if seen > 32 {
+			return nil, fmt.Errorf("$ref %q is circular", schema.Ref)
+		}
+		name, ok := strings.CutPrefix(schema.Ref, "#/components/schemas/")
+		if !ok {
+			return nil, fmt.Errorf("unsupported $ref %q", schema.Ref)
+		}
+		target, ok := d.Components.Schemas[name]
+		if !ok {
+			return nil, fmt.Errorf("$ref %q points to no schema", schema.Ref)
+		}
+		schema = target
+	}
+	return schema, nil
+}
+
+// route is a path template split into segments, where a nil segment stands
+// for a parameter
+type route struct {
+	template   string
+	segments   []*string
+	operations map[string]Operation
+}
+
+func newRoute(template string, operations map[string]Operation) route {
+	r := route{template: template, operations: operations}
+	for _, part := range strings.Split(strings.Trim(template, "/"), "/") {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			r.segments = append(r.segments, nil)
+			continue
+		}
+		r.segments = append(r.segments, &part)
+	}
+	return r
+}
+
+// match returns the route matching path. When several do, the one with the
+// most literal segments wins, so /todos/stats is preferred to /todos/{id}.
+func (d *Document) match(path string) *route {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+
+	var best *route
+	bestLiterals := -1
+	for i := range d.routes {
+		r := &d.routes[i]
+		if len(r.segments) != len(parts) {
+			continue
+		}
+
+		literals := 0
+		matched := true
+		for j, segment := range r.segments {
+			if segment == nil {
+				if parts[j] == "" {
+					matched = false
+					break
+				}
+				continue
+			}
+			if *segment != parts[j] {
+				matched = false
+				break
+			}
+			literals++
+		}
+
+		if matched && literals > bestLiterals {
+			best, bestLiterals = r, literals
+		}
+	}
+	return best
+}
@@ -0,0 +1,149 @@
+package openapi_test
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/mabhi256/tasker/internal/lib/openapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const document = `{
+	"openapi": "3.0.2",
+	"servers": [{"url": "http://localhost:8080/api"}],
+	"paths": {
+		"/v1/todos/{id}": {
+			"get": {"responses": {"200": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Todo"}}}}}},
+			"delete": {"responses": {"204": {"content": {"application/json": {"schema": {}}}}}}
+		},
+		"/v1/todos/stats": {
+			"get": {"responses": {
+				"200": {"content": {"application/json": {"schema": {
+					"type": "object",
+					"properties": {"total": {"type": "integer"}},
+					"required": ["total"],
+					"additionalProperties": false
+				}}}},
+				"4XX": {"content": {"application/json": {"schema": {
+					"type": "object",
+					"properties": {"message": {"type": "string"}},
+					"required": ["message"]
+				}}}}
+			}}
+		}
+	},
+	"components": {"schemas": {"Todo": {
+		"type": "object",
+		"properties": {
+			"id": {"type": "string", "format": "uuid"},
+			"status": {"type": "string", "enum": ["draft", "active"]},
+			"dueDate": {"type": "string", "format": "date-time", "nullable": true},
+			"tags": {"type": "array", "items": {"type": "string"}},
+			"sortOrder": {"type": "number"}
+		},
+		"required": ["id", "status", "dueDate"]
+	}}}
+}`
+
+var jsonHeader = http.Header{"Content-Type": []string{"application/json; charset=UTF-8"}}
+
+func parse(t *testing.T) *openapi.Document {
+	t.Helper()
+
+	doc, err := openapi.Parse([]byte(document))
+	require.NoError(t, err)
+	return doc
+}
+
+func problems(t *testing.T, err error) []string {
+	t.Helper()
+
+	var validationErr *openapi.ValidationError
+	require.True(t, errors.As(err, &validationErr), "expected a validation error, got %v", err)
+	return validationErr.Problems
+}
+
+func TestOperationMatchesTemplates(t *testing.T) {
+	doc := parse(t)
+
+	_, template, ok := doc.Operation(http.MethodGet, "/api/v1/todos/stats")
+	require.True(t, ok)
+	assert.Equal(t, "/v1/todos/stats", template, "literal segments win over parameters")
+
+	_, template, ok = doc.Operation(http.MethodGet, "/api/v1/todos/0b8e6d1c-4b5a-4f43-9d6e-2c1f0a9b7e31")
+	require.True(t, ok)
+	assert.Equal(t, "/v1/todos/{id}", template)
+
+	_, template, ok = doc.Operation(http.MethodPatch, "/api/v1/todos/stats")
+	assert.False(t, ok)
+	assert.Equal(t, "/v1/todos/stats", template)
+
+	_, _, ok = doc.Operation(http.MethodGet, "/api/v1/categories")
+	assert.False(t, ok)
+}
+
+func TestValidateResponseAcceptsDocumentedResponses(t *testing.T) {
+	doc := parse(t)
+
+	body := `{"id": "0b8e6d1c-4b5a-4f43-9d6e-2c1f0a9b7e31", "status": "draft", "dueDate": null,
+		"tags": ["home"], "sortOrder": 3, "workspaceId": "extra properties are allowed"}`
+	assert.NoError(t, doc.ValidateResponse(http.MethodGet, "/api/v1/todos/0b8e6d1c-4b5a-4f43-9d6e-2c1f0a9b7e31",
+		http.StatusOK, jsonHeader, []byte(body)))
+
+	assert.NoError(t, doc.ValidateResponse(http.MethodDelete, "/api/v1/todos/0b8e6d1c-4b5a-4f43-9d6e-2c1f0a9b7e31",
+		http.StatusNoContent, http.Header{}, nil))
+
+	assert.NoError(t, doc.ValidateResponse(http.MethodGet, "/api/v1/todos/stats",
+		http.StatusNotFound, jsonHeader, []byte(`{"message": "falls back to the status range"}`)))
+}
+
+func TestValidateResponseReportsDrift(t *testing.T) {
+	doc := parse(t)
+	path := "/api/v1/todos/0b8e6d1c-4b5a-4f43-9d6e-2c1f0a9b7e31"
+
+	body := `{"id": "not-a-uuid", "status": "done", "tags": ["home", 1], "sortOrder": "3"}`
+	err := doc.ValidateResponse(http.MethodGet, path, http.StatusOK, jsonHeader, []byte(body))
+	assert.ElementsMatch(t, []string{
+		`/: is missing required property "dueDate"`,
+		`/id: "not-a-uuid" is not a valid uuid: invalid UUID length: 10`,
+		`/status: done is not one of [draft active]`,
+		`/tags/1: is a number, not a string`,
+		`/sortOrder: is a string, not a number`,
+	}, problems(t, err))
+
+	err = doc.ValidateResponse(http.MethodGet, "/api/v1/todos/stats", http.StatusOK, jsonHeader,
+		[]byte(`{"total": 1.5, "overdue": 0}`))
+	assert.ElementsMatch(t, []string{
+		`/total: 1.5 is not an integer`,
+		`/overdue: is not a documented property`,
+	}, problems(t, err))
+
+	err = doc.ValidateResponse(http.MethodGet, path, http.StatusCreated, jsonHeader, []byte(`{}`))
+	assert.Equal(t, []string{"status 201 isn't documented for GET /v1/todos/{id}"}, problems(t, err))
+
+	err = doc.ValidateResponse(http.MethodGet, path, http.StatusOK, http.Header{"Content-Type": []string{"text/plain"}},
+		[]byte("ok"))
+	assert.Equal(t, []string{`content type "text/plain" isn't documented`}, problems(t, err))
+
+	err = doc.ValidateResponse(http.MethodGet, path, http.StatusOK, jsonHeader, nil)
+	assert.Equal(t, []string{"the body is empty, though content is documented"}, problems(t, err))
+
+	err = doc.ValidateResponse(http.MethodGet, "/api/v1/categories", http.StatusOK, jsonHeader, []byte(`[]`))
+	assert.Equal(t, []string{"the path isn't documented"}, problems(t, err))
+}
+
+func TestParseRejectsOtherVersions(t *testing.T) {
+	_, err := openapi.Parse([]byte(`{"swagger": "2.0"}`))
+	assert.Error(t, err)
+}
+
+func TestLoadServedDocument(t *testing.T) {
+	doc, err := openapi.Load(filepath.Join("..", "..", "..", "static", "openapi.json"))
+	require.NoError(t, err)
+
+	_, _, ok := doc.Operation(http.MethodGet, "/api/v1/todos")
+	assert.True(t, ok)
+}
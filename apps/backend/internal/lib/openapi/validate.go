@@ -0,0 +1,355 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ValidationError lists every way a response broke its documented contract
+type ValidationError struct {
+	Method   string
+	Path     string
+	Status   int
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s %s returned %d, breaking its contract:\n  %s",
+		e.Method, e.Path, e.Status, strings.Join(e.Problems, "\n  "))
+}
+
+// ValidateResponse checks that the document has an operation for method and
+// path, that it documents the response's status code, and that the body
+// matches the schema documented for the response's content type. It returns
+// a *ValidationError when the response breaks the contract.
+func (d *Document) ValidateResponse(method, path string, status int, header http.Header, body []byte) error {
+	fail := func(problems ...string) error {
+		return &ValidationError{Method: method, Path: path, Status: status, Problems: problems}
+	}
+
+	operation, template, ok := d.Operation(method, path)
+	if !ok {
+		if template != "" {
+			return fail(fmt.Sprintf("%s isn't documented for %s", method, template))
+		}
+		return fail("the path isn't documented")
+	}
+
+	response, ok := operation.response(status)
+	if !ok {
+		return fail(fmt.Sprintf("status %d isn't documented for %s %s", status, method, template))
+	}
+
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		// Documented content is only required of responses that can have it
+		if status == http.StatusNoContent || status == http.StatusNotModified || len(response.Content) == 0 {
+			return nil
+		}
+		if media, ok := response.Content["application/json"]; ok && media.Schema != nil && media.Schema.empty() {
+			return nil
+		}
+		return fail("the body is empty, though content is documented")
+	}
+
+	if len(response.Content) == 0 {
+		return fail("the body isn't empty, though no content is documented")
+	}
+
+	contentType := header.Get("Content-Type")
+	media, ok := response.mediaType(contentType)
+	if !ok {
+		return fail(fmt.Sprintf("content type %q isn't documented", contentType))
+	}
+	if media.Schema == nil || !isJSON(contentType) {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return fail(fmt.Sprintf("the body isn't valid JSON: %v", err))
+	}
+
+	v := &validator{doc: d}
+	v.validate(media.Schema, value, "")
+	if len(v.problems) > 0 {
+		return fail(v.problems...)
+	}
+	return nil
+}
+
+// response returns the response documented for status, falling back to its
+// range, like 4XX, and then to the default response
+func (o *Operation) response(status int) (Response, bool) {
+	code := strconv.Itoa(status)
+	for _, key := range []string{code, code[:1] + "XX", "default"} {
+		if response, ok := o.Responses[key]; ok {
+			return response, true
+		}
+	}
+	return Response{}, false
+}
+
+// mediaType returns the media type documented for contentType, falling back
+// to wildcards like image/* and */*
+func (r *Response) mediaType(contentType string) (MediaType, bool) {
+	parsed, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return MediaType{}, false
+	}
+
+	kind, _, _ := strings.Cut(parsed, "/")
+	for _, key := range []string{parsed, kind + "/*", "*/*"} {
+		if media, ok := r.Content[key]; ok {
+			return media, true
+		}
+	}
+	return MediaType{}, false
+}
+
+func isJSON(contentType string) bool {
+	parsed, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (parsed == "application/json" || strings.HasSuffix(parsed, "+json"))
+}
+
+// empty reports whether the schema accepts anything
+func (s *Schema) empty() bool {
+	return s.Ref == "" && s.Type == "" && len(s.Enum) == 0 && len(s.Properties) == 0 &&
+		len(s.Required) == 0 && s.Items == nil && len(s.AllOf) == 0 && len(s.AnyOf) == 0 && len(s.OneOf) == 0
+}
+
+// validator collects the problems found validating a value, each prefixed
+// with the JSON pointer to where it was found
+type validator struct {
+	doc      *Document
+	problems []string
+}
+
+func (v *validator) report(pointer, format string, args ...any) {
+	if pointer == "" {
+		pointer = "/"
+	}
+	v.problems = append(v.problems, pointer+": "+fmt.Sprintf(format, args...))
+}
+
+// valid reports whether value matches schema, without keeping the problems
+func (v *validator) valid(schema *Schema, value any, pointer string) bool {
+	branch := &validator{doc: v.doc}
+	branch.validate(schema, value, pointer)
+	return len(branch.problems) == 0
+}
+
+func (v *validator) validate(schema *Schema, value any, pointer string) {
+	schema, err := v.doc.resolve(schema)
+	if err != nil {
+		v.report(pointer, "%v", err)
+		return
+	}
+
+	if value == nil {
+		if !schema.Nullable && schema.Type != "" {
+			v.report(pointer, "is null, but isn't nullable")
+		}
+		return
+	}
+
+	for _, sub := range schema.AllOf {
+		v.validate(sub, value, pointer)
+	}
+	if len(schema.AnyOf) > 0 {
+		if !slices.ContainsFunc(schema.AnyOf, func(sub *Schema) bool { return v.valid(sub, value, pointer) }) {
+			v.report(pointer, "matches none of anyOf")
+		}
+	}
+	if len(schema.OneOf) > 0 {
+		matches := 0
+		for _, sub := range schema.OneOf {
+			if v.valid(sub, value, pointer) {
+				matches++
+			}
+		}
+		if matches != 1 {
+			v.report(pointer, "matches %d of oneOf, not exactly one", matches)
+		}
+	}
+
+	if len(schema.Enum) > 0 && !slices.ContainsFunc(schema.Enum, func(option any) bool { return equal(option, value) }) {
+		v.report(pointer, "%v is not one of %v", value, schema.Enum)
+	}
+
+	switch schema.Type {
+	case "":
+		// Untyped schemas only constrain values through the keywords above
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			v.report(pointer, "is %s, not an object", kindOf(value))
+			return
+		}
+		v.validateObject(schema, object, pointer)
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			v.report(pointer, "is %s, not an array", kindOf(value))
+			return
+		}
+		if schema.Items != nil {
+			for i, item := range array {
+				v.validate(schema.Items, item, pointer+"/"+strconv.Itoa(i))
+			}
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			v.report(pointer, "is %s, not a string", kindOf(value))
+			return
+		}
+		if err := checkFormat(schema.Format, s); err != nil {
+			v.report(pointer, "%q is not a valid %s: %v", s, schema.Format, err)
+		}
+	case "number", "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			v.report(pointer, "is %s, not a %s", kindOf(value), schema.Type)
+			return
+		}
+		f, err := n.Float64()
+		if err != nil {
+			v.report(pointer, "%s is not a valid number", n)
+			return
+		}
+		if schema.Type == "integer" && f != math.Trunc(f) {
+			v.report(pointer, "%s is not an integer", n)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			v.report(pointer, "is %s, not a boolean", kindOf(value))
+		}
+	default:
+		v.report(pointer, "has unsupported schema type %q", schema.Type)
+	}
+}
+
+func (v *validator) validateObject(schema *Schema, object map[string]any, pointer string) {
+	for _, name := range schema.Required {
+		if _, ok := object[name]; !ok {
+			v.report(pointer, "is missing required property %q", name)
+		}
+	}
+
+	var additional *Schema
+	closed := false
+	if raw := bytes.TrimSpace(schema.AdditionalProperties); len(raw) > 0 {
+		switch string(raw) {
+		case "true":
+		case "false":
+			closed = true
+		default:
+			additional = &Schema{}
+			if err := json.Unmarshal(raw, additional); err != nil {
+				v.report(pointer, "has an invalid additionalProperties schema: %v", err)
+				return
+			}
+		}
+	}
+
+	// Properties are checked in order so problems are reported the same way
+	// every run
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		child := pointer + "/" + escapePointer(name)
+		if property, ok := schema.Properties[name]; ok {
+			v.validate(property, object[name], child)
+			continue
+		}
+		switch {
+		case closed:
+			v.report(child, "is not a documented property")
+		case additional != nil:
+			v.validate(additional, object[name], child)
+		}
+	}
+}
+
+// checkFormat checks the string formats responses use. Unknown formats are
+// only annotations, as the specification allows.
+func checkFormat(format, s string) error {
+	switch format {
+	case "uuid":
+		_, err := uuid.Parse(s)
+		return err
+	case "date-time":
+		_, err := time.Parse(time.RFC3339Nano, s)
+		return err
+	case "date":
+		_, err := time.Parse(time.DateOnly, s)
+		return err
+	case "uri":
+		u, err := url.Parse(s)
+		if err != nil {
+			return err
+		}
+		if !u.IsAbs() {
+			return errors.New("not absolute")
+		}
+	}
+	return nil
+}
+
+func equal(option, value any) bool {
+	if n, ok := value.(json.Number); ok {
+		f, err := n.Float64()
+		if err != nil {
+			return false
+		}
+		switch o := option.(type) {
+		case float64:
+			return o == f
+		case json.Number:
+			of, err := o.Float64()
+			return err == nil && of == f
+		}
+		return false
+	}
+	return option == value
+}
+
+func kindOf(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "an object"
+	case []any:
+		return "an array"
+	case string:
+		return "a string"
+	case json.Number:
+		return "a number"
+	case bool:
+		return "a boolean"
+	}
+	return fmt.Sprintf("a %T", value)
+}
+
+func escapePointer(name string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
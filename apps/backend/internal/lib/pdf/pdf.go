@@ -0,0 +1,291 @@
+// Package pdf writes simple text documents as PDF. Text is set in Helvetica,
+// one of the standard fonts every reader has, so no fonts are embedded, and
+// is wrapped and broken across pages as it is written. Characters outside
+// the WinAnsi encoding print as "?".
+package pdf
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// A4 in points, with equal margins
+const (
+	pageWidth  = 595.0
+	pageHeight = 842.0
+	margin     = 50.0
+
+	// footerSize is the size of the page numbers at the bottom of each page
+	footerSize = 8.0
+)
+
+type Font int
+
+const (
+	Regular Font = iota
+	Bold
+)
+
+// resource is the font's name in the pages' resources
+func (f Font) resource() string {
+	if f == Bold {
+		return "F2"
+	}
+	return "F1"
+}
+
+// Style is how text is set. Gray is 0 for black up to 1 for white.
+type Style struct {
+	Font Font
+	Size float64
+	Gray float64
+}
+
+var (
+	TitleStyle   = Style{Font: Bold, Size: 20}
+	HeadingStyle = Style{Font: Bold, Size: 13}
+	BodyStyle    = Style{Font: Regular, Size: 10}
+	MutedStyle   = Style{Font: Regular, Size: 9, Gray: 0.45}
+)
+
+// Document is written top to bottom, starting new pages as they fill up
+type Document struct {
+	title string
+	pages []*bytes.Buffer
+	// y is where the next line's top goes on the current page
+	y float64
+}
+
+func New(title string) *Document {
+	d := &Document{title: title}
+	d.newPage()
+	return d
+}
+
+func (d *Document) newPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.y = pageHeight - margin
+}
+
+// Write sets the text in the style, wrapped to the page width. Line breaks
+// in the text start new lines.
+func (d *Document) Write(style Style, text string) {
+	lineHeight := style.Size * 1.4
+	for _, paragraph := range strings.Split(text, "\n") {
+		for _, line := range wrap(style, paragraph, pageWidth-2*margin) {
+			if d.y-lineHeight < margin {
+				d.newPage()
+			}
+			d.y -= lineHeight
+			d.writeLine(style, margin, d.y+(lineHeight-style.Size)/2, line)
+		}
+	}
+}
+
+// Space leaves a gap before whatever is written next
+func (d *Document) Space(points float64) {
+	d.y -= points
+}
+
+func (d *Document) writeLine(style Style, x, y float64, line []byte) {
+	page := d.pages[len(d.pages)-1]
+	fmt.Fprintf(page, "BT %.2f g /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n",
+		style.Gray, style.Font.resource(), style.Size, x, y, escape(line))
+}
+
+// WriteTo writes the document, numbering its pages
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	out := &countingWriter{w: bufio.NewWriter(w)}
+	// The first objects are the catalog, page tree, fonts and info, then
+	// each page is followed by its content
+	const firstPage = 6
+	offsets := []int64{}
+	object := func(body string) {
+		offsets = append(offsets, out.n)
+		fmt.Fprintf(out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	fmt.Fprint(out, "%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	object(fmt.Sprintf("<< /Title (%s) /Producer (Tasker) >>", escape(encode(d.title))))
+
+	for i, page := range d.pages {
+		content := bytes.NewBuffer(append([]byte{}, page.Bytes()...))
+		footer := encode(fmt.Sprintf("Page %d of %d", i+1, len(d.pages)))
+		x := pageWidth - margin - width(MutedStyle.Font, footer)*footerSize/1000
+		fmt.Fprintf(content, "BT %.2f g /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n",
+			MutedStyle.Gray, MutedStyle.Font.resource(), footerSize, x, margin/2, escape(footer))
+
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, firstPage+2*i+1))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.Bytes()))
+	}
+
+	xref := out.n
+	fmt.Fprintf(out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(out, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(offsets)+1, xref)
+
+	if out.err != nil {
+		return out.n, out.err
+	}
+	return out.n, out.w.Flush()
+}
+
+// Bytes returns the written document
+func (d *Document) Bytes() []byte {
+	var buf bytes.Buffer
+	_, _ = d.WriteTo(&buf)
+	return buf.Bytes()
+}
+
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
+
+// wrap breaks the text into WinAnsi lines no wider than maxWidth, breaking
+// at spaces, or anywhere in words too long for a line
+func wrap(style Style, text string, maxWidth float64) [][]byte {
+	limit := maxWidth * 1000 / style.Size
+	lines := [][]byte{}
+	var line []byte
+	for _, word := range strings.Fields(text) {
+		w := encode(word)
+		candidate := w
+		if len(line) > 0 {
+			candidate = append(append(append([]byte{}, line...), ' '), w...)
+		}
+		if width(style.Font, candidate) <= limit {
+			line = candidate
+			continue
+		}
+
+		if len(line) > 0 {
+			lines = append(lines, line)
+		}
+		for width(style.Font, w) > limit {
+			n := 1
+			for n < len(w) && width(style.Font, w[:n+1]) <= limit {
+				n++
+			}
+			lines = append(lines, w[:n])
+			w = w[n:]
+		}
+		line = w
+	}
+	if len(line) > 0 || len(lines) == 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// width is the text's width in thousandths of the font size
+func width(font Font, text []byte) float64 {
+	widths := &helveticaWidths
+	if font == Bold {
+		widths = &helveticaBoldWidths
+	}
+
+	total := 0.0
+	for _, c := range text {
+		if c >= 32 && c <= 126 {
+			total += float64(widths[c-32])
+		} else {
+			total += 556
+		}
+	}
+	return total
+}
+
+// winAnsi maps the characters WinAnsi places in 0x80-0x9f. From 0xa0 up it
+// matches Latin-1.
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9a, '›': 0x9b, 'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
+
+// encode converts UTF-8 text to WinAnsi
+func encode(text string) []byte {
+	out := make([]byte, 0, len(text))
+	for len(text) > 0 {
+		r, size := utf8.DecodeRuneInString(text)
+		text = text[size:]
+		switch {
+		case r == '\t':
+			out = append(out, ' ')
+		case r >= 32 && r <= 126, r >= 0xa0 && r <= 0xff:
+			out = append(out, byte(r))
+		case winAnsi[r] != 0:
+			out = append(out, winAnsi[r])
+		default:
+			out = append(out, '?')
+		}
+	}
+	return out
+}
+
+// escape makes WinAnsi text safe inside a PDF string literal
+func escape(text []byte) string {
+	var b strings.Builder
+	for _, c := range text {
+		switch {
+		case c == '\\' || c == '(' || c == ')':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 32 || c > 126:
+			fmt.Fprintf(&b, "\\%03o", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// Glyph widths of the printable ASCII characters, from the fonts' AFM files
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
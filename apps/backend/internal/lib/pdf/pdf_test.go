@@ -0,0 +1,80 @@
+package pdf_test
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mabhi256/tasker/internal/lib/pdf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// checkXref verifies that every offset in the cross-reference table points
+// at the object it lists
+func checkXref(t *testing.T, doc []byte) int {
+	t.Helper()
+
+	m := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(doc)
+	require.NotNil(t, m, "missing startxref")
+	xref, err := strconv.Atoi(string(m[1]))
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(doc[xref:], []byte("xref\n")))
+
+	lines := strings.Split(string(doc[xref:]), "\n")
+	count, err := strconv.Atoi(strings.Fields(lines[1])[1])
+	require.NoError(t, err)
+	for i := 1; i < count; i++ {
+		entry := lines[2+i]
+		require.Len(t, entry, 19, "entries are 20 bytes with the newline")
+		offset, err := strconv.Atoi(entry[:10])
+		require.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(doc[offset:], []byte(strconv.Itoa(i)+" 0 obj\n")), "object %d", i)
+	}
+	return count - 1
+}
+
+func TestDocument(t *testing.T) {
+	d := pdf.New("Report (Q1)")
+	d.Write(pdf.TitleStyle, "Weekly report")
+	d.Write(pdf.BodyStyle, `Ship the "café" release – see (notes) \ backlog`)
+
+	doc := d.Bytes()
+	assert.True(t, bytes.HasPrefix(doc, []byte("%PDF-1.4\n")))
+	checkXref(t, doc)
+
+	assert.Contains(t, string(doc), "/Title (Report \\(Q1\\))")
+	assert.Contains(t, string(doc), "(Weekly report) Tj")
+	// Latin-1 and WinAnsi characters are encoded, parentheses and
+	// backslashes escaped
+	assert.Contains(t, string(doc), `(Ship the "caf\351" release \226 see \(notes\) \\ backlog) Tj`)
+	assert.Contains(t, string(doc), "(Page 1 of 1) Tj")
+
+	// Writing twice gives the same document
+	assert.Equal(t, doc, d.Bytes())
+}
+
+func TestDocumentWrapsAndBreaksPages(t *testing.T) {
+	d := pdf.New("Long")
+	d.Write(pdf.BodyStyle, strings.Repeat("lorem ipsum dolor sit amet ", 40))
+	for i := 0; i < 80; i++ {
+		d.Write(pdf.BodyStyle, "line "+strconv.Itoa(i))
+	}
+	d.Write(pdf.BodyStyle, strings.Repeat("x", 500))
+	d.Write(pdf.BodyStyle, "日本")
+
+	doc := d.Bytes()
+	objects := checkXref(t, doc)
+	pages := (objects - 5) / 2
+	assert.Equal(t, 2, pages)
+	assert.Contains(t, string(doc), "/Count 2")
+	assert.Contains(t, string(doc), "(Page 2 of 2) Tj")
+	assert.Contains(t, string(doc), "(??) Tj")
+
+	// No line runs past the right margin: a long word is split over lines
+	for _, m := range regexp.MustCompile(`\((x+)\) Tj`).FindAllSubmatch(doc, -1) {
+		assert.LessOrEqual(t, len(m[1]), int((595.0-100)*1000/10/500))
+	}
+}
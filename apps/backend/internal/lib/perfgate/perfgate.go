@@ -0,0 +1,152 @@
+// Package perfgate compares benchmark results against checked-in baselines,
+// reporting the benchmarks whose latency or allocations regressed past the
+// baselines' thresholds. Latency depends on the machine, so baselines are
+// recorded on the machine that runs the gate.
+package perfgate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"testing"
+)
+
+// ErrNoBaseline is returned for benchmarks the baseline has no measurement
+// of
+var ErrNoBaseline = errors.New("no baseline recorded")
+
+// Measurement is the cost of one operation of a benchmark
+type Measurement struct {
+	NsPerOp     int64 `json:"nsPerOp"`
+	AllocsPerOp int64 `json:"allocsPerOp"`
+	BytesPerOp  int64 `json:"bytesPerOp"`
+}
+
+// Thresholds are how much worse than its baseline, as a fraction, a
+// measurement can be. 0.3 lets latency grow by 30%.
+type Thresholds struct {
+	Latency float64 `json:"latency"`
+	Allocs  float64 `json:"allocs"`
+}
+
+// Baseline is the measurements benchmarks are held to, by name
+type Baseline struct {
+	Thresholds Thresholds             `json:"thresholds"`
+	Benchmarks map[string]Measurement `json:"benchmarks"`
+}
+
+// Regression is a benchmark whose measurement passed a threshold
+type Regression struct {
+	Benchmark string
+	Metric    string
+	Baseline  int64
+	Measured  int64
+	Threshold float64
+}
+
+func (r Regression) String() string {
+	change := float64(r.Measured-r.Baseline) / float64(r.Baseline) * 100
+	return fmt.Sprintf("%s: %s went from %d to %d (%+.1f%%, threshold %.0f%%)",
+		r.Benchmark, r.Metric, r.Baseline, r.Measured, change, r.Threshold*100)
+}
+
+// Load reads the baseline at path
+func Load(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %w", err)
+	}
+
+	var baseline Baseline
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline %s: %w", path, err)
+	}
+	if baseline.Benchmarks == nil {
+		baseline.Benchmarks = map[string]Measurement{}
+	}
+
+	return &baseline, nil
+}
+
+// Save writes the baseline to path, with the benchmarks sorted by name
+func (b *Baseline) Save(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode baseline: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write baseline: %w", err)
+	}
+	return nil
+}
+
+// Compare returns the regressions of the benchmark's measurement, or
+// ErrNoBaseline
+func (b *Baseline) Compare(name string, m Measurement) ([]Regression, error) {
+	base, ok := b.Benchmarks[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", name, ErrNoBaseline)
+	}
+
+	var regressions []Regression
+	if exceeds(base.NsPerOp, m.NsPerOp, b.Thresholds.Latency) {
+		regressions = append(regressions, Regression{
+			Benchmark: name,
+			Metric:    "ns/op",
+			Baseline:  base.NsPerOp,
+			Measured:  m.NsPerOp,
+			Threshold: b.Thresholds.Latency,
+		})
+	}
+	if exceeds(base.AllocsPerOp, m.AllocsPerOp, b.Thresholds.Allocs) {
+		regressions = append(regressions, Regression{
+			Benchmark: name,
+			Metric:    "allocs/op",
+			Baseline:  base.AllocsPerOp,
+			Measured:  m.AllocsPerOp,
+			Threshold: b.Thresholds.Allocs,
+		})
+	}
+
+	return regressions, nil
+}
+
+// exceeds reports whether measured is worse than base by more than the
+// threshold. A baseline of 0 allows nothing more.
+func exceeds(base, measured int64, threshold float64) bool {
+	return float64(measured) > float64(base)*(1+threshold)
+}
+
+// Measure is the median of the runs of a benchmark, which a noisy run
+// doesn't move
+func Measure(runs []testing.BenchmarkResult) Measurement {
+	if len(runs) == 0 {
+		return Measurement{}
+	}
+
+	ns := make([]int64, len(runs))
+	allocs := make([]int64, len(runs))
+	bytes := make([]int64, len(runs))
+	for i, run := range runs {
+		ns[i] = run.NsPerOp()
+		allocs[i] = run.AllocsPerOp()
+		bytes[i] = run.AllocedBytesPerOp()
+	}
+
+	return Measurement{
+		NsPerOp:     median(ns),
+		AllocsPerOp: median(allocs),
+		BytesPerOp:  median(bytes),
+	}
+}
+
+func median(values []int64) int64 {
+	slices.Sort(values)
+	mid := len(values) / 2
+	if len(values)%2 == 0 {
+		return (values[mid-1] + values[mid]) / 2
+	}
+	return values[mid]
+}
@@ -0,0 +1,92 @@
+package perfgate_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mabhi256/tasker/internal/lib/perfgate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompare(t *testing.T) {
+	baseline := &perfgate.Baseline{
+		Thresholds: perfgate.Thresholds{Latency: 0.3, Allocs: 0.1},
+		Benchmarks: map[string]perfgate.Measurement{
+			"ListTodos": {NsPerOp: 1000, AllocsPerOp: 200, BytesPerOp: 4096},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		measure perfgate.Measurement
+		metrics []string
+	}{
+		{name: "unchanged", measure: perfgate.Measurement{NsPerOp: 1000, AllocsPerOp: 200}},
+		{name: "within thresholds", measure: perfgate.Measurement{NsPerOp: 1300, AllocsPerOp: 220}},
+		{name: "faster", measure: perfgate.Measurement{NsPerOp: 500, AllocsPerOp: 100}},
+		{name: "slower", measure: perfgate.Measurement{NsPerOp: 2000, AllocsPerOp: 200}, metrics: []string{"ns/op"}},
+		{name: "more allocations", measure: perfgate.Measurement{NsPerOp: 1000, AllocsPerOp: 221}, metrics: []string{"allocs/op"}},
+		{
+			name:    "both",
+			measure: perfgate.Measurement{NsPerOp: 2000, AllocsPerOp: 400},
+			metrics: []string{"ns/op", "allocs/op"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			regressions, err := baseline.Compare("ListTodos", tt.measure)
+			require.NoError(t, err)
+
+			metrics := make([]string, len(regressions))
+			for i, r := range regressions {
+				metrics[i] = r.Metric
+			}
+			assert.ElementsMatch(t, tt.metrics, metrics)
+		})
+	}
+
+	_, err := baseline.Compare("GetTodo", perfgate.Measurement{NsPerOp: 1})
+	assert.ErrorIs(t, err, perfgate.ErrNoBaseline)
+}
+
+func TestRegressionString(t *testing.T) {
+	r := perfgate.Regression{Benchmark: "ListTodos", Metric: "ns/op", Baseline: 1000, Measured: 2000, Threshold: 0.3}
+	assert.Equal(t, "ListTodos: ns/op went from 1000 to 2000 (+100.0%, threshold 30%)", r.String())
+}
+
+func TestMeasure(t *testing.T) {
+	run := func(n int, total time.Duration, allocs uint64) testing.BenchmarkResult {
+		return testing.BenchmarkResult{N: n, T: total, MemAllocs: allocs, MemBytes: allocs * 64}
+	}
+
+	// The outlier run doesn't move the median
+	m := perfgate.Measure([]testing.BenchmarkResult{
+		run(100, 100*time.Microsecond, 1000),
+		run(100, 10*time.Millisecond, 1200),
+		run(100, 120*time.Microsecond, 1100),
+	})
+	assert.Equal(t, perfgate.Measurement{NsPerOp: 1200, AllocsPerOp: 11, BytesPerOp: 704}, m)
+
+	assert.Equal(t, perfgate.Measurement{}, perfgate.Measure(nil))
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+
+	baseline := &perfgate.Baseline{
+		Thresholds: perfgate.Thresholds{Latency: 0.3, Allocs: 0.1},
+		Benchmarks: map[string]perfgate.Measurement{
+			"ListTodos": {NsPerOp: 1000, AllocsPerOp: 200, BytesPerOp: 4096},
+		},
+	}
+	require.NoError(t, baseline.Save(path))
+
+	loaded, err := perfgate.Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, baseline, loaded)
+
+	_, err = perfgate.Load(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
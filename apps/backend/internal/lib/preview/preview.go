@@ -0,0 +1,157 @@
+// Package preview extracts what clients need to preview a file without
+// downloading it: the page count of a PDF, the dimensions of an image and
+// the start of a text file.
+package preview
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"  // registers the GIF decoder
+	_ "image/jpeg" // registers the JPEG decoder
+	_ "image/png"  // registers the PNG decoder
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type Kind string
+
+const (
+	KindPDF   Kind = "pdf"
+	KindImage Kind = "image"
+	KindText  Kind = "text"
+)
+
+// Preview describes a file. Only the fields of its kind are set.
+type Preview struct {
+	Kind      Kind    `json:"kind"`
+	PageCount *int    `json:"pageCount,omitempty"`
+	Width     *int    `json:"width,omitempty"`
+	Height    *int    `json:"height,omitempty"`
+	Excerpt   *string `json:"excerpt,omitempty"`
+}
+
+const (
+	// MaxPDFSize bounds how much of a PDF is read for its page count
+	MaxPDFSize = 20 << 20
+	// ExcerptLength is how many characters of a text file are kept
+	ExcerptLength = 500
+)
+
+// ErrUnsupported is returned for files of a kind that has no preview
+var ErrUnsupported = errors.New("no preview for this kind of file")
+
+// KindOf returns the kind of preview of a file with the given MIME type and
+// name, if it has one. Markdown is sniffed as plain text, so text files are
+// recognized by their extension too.
+func KindOf(mimeType, name string) (Kind, bool) {
+	mediaType, _, _ := strings.Cut(mimeType, ";")
+	mediaType = strings.TrimSpace(strings.ToLower(mediaType))
+	ext := strings.ToLower(path.Ext(name))
+
+	switch {
+	case mediaType == "application/pdf" || ext == ".pdf":
+		return KindPDF, true
+	case mediaType == "image/png" || mediaType == "image/jpeg" || mediaType == "image/gif":
+		return KindImage, true
+	case ext == ".txt" || ext == ".md" || ext == ".markdown":
+		return KindText, true
+	}
+	return "", false
+}
+
+// Extract reads the preview of a file of the given kind from r. Only as much
+// of the file as the preview needs is read.
+func Extract(kind Kind, r io.Reader) (*Preview, error) {
+	switch kind {
+	case KindPDF:
+		return extractPDF(r)
+	case KindImage:
+		return extractImage(r)
+	case KindText:
+		return extractText(r)
+	}
+	return nil, ErrUnsupported
+}
+
+func extractImage(r io.Reader) (*Preview, error) {
+	// Only the header is decoded
+	cfg, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image header: %w", err)
+	}
+
+	return &Preview{Kind: KindImage, Width: &cfg.Width, Height: &cfg.Height}, nil
+}
+
+func extractText(r io.Reader) (*Preview, error) {
+	// Enough bytes for the excerpt in any encoding of its characters
+	data, err := io.ReadAll(io.LimitReader(r, ExcerptLength*utf8.UTFMax))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read text: %w", err)
+	}
+
+	excerpt := make([]rune, 0, ExcerptLength)
+	for len(data) > 0 && len(excerpt) < ExcerptLength {
+		c, size := utf8.DecodeRune(data)
+		if c == utf8.RuneError && size <= 1 {
+			// A character cut off at the end of what was read ends the
+			// excerpt, and anything else means the file isn't UTF-8 text
+			if !utf8.FullRune(data) {
+				break
+			}
+			return nil, errors.New("text is not UTF-8")
+		}
+		excerpt = append(excerpt, c)
+		data = data[size:]
+	}
+
+	// A byte order mark isn't part of the text
+	text := strings.TrimSpace(strings.TrimPrefix(string(excerpt), "\ufeff"))
+	return &Preview{Kind: KindText, Excerpt: &text}, nil
+}
+
+var (
+	pagesType = regexp.MustCompile(`/Type\s*/Pages\b`)
+	pageType  = regexp.MustCompile(`/Type\s*/Page\b`)
+	count     = regexp.MustCompile(`/Count\s+(\d+)`)
+)
+
+// extractPDF counts the pages of a PDF. The root of the page tree counts
+// every page, so it is the largest count of a page tree node. Documents
+// whose page tree is compressed in object streams are counted by their
+// page objects, if they can be found.
+func extractPDF(r io.Reader) (*Preview, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxPDFSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return nil, errors.New("not a PDF")
+	}
+
+	pages := 0
+	for _, object := range bytes.Split(data, []byte("endobj")) {
+		if !pagesType.Match(object) {
+			continue
+		}
+		for _, match := range count.FindAllSubmatch(object, -1) {
+			if n, err := strconv.Atoi(string(match[1])); err == nil && n > pages {
+				pages = n
+			}
+		}
+	}
+	if pages == 0 {
+		pages = len(pageType.FindAll(data, -1))
+	}
+	if pages == 0 {
+		return nil, errors.New("no pages found in PDF")
+	}
+
+	return &Preview{Kind: KindPDF, PageCount: &pages}, nil
+}
@@ -0,0 +1,78 @@
+package preview_test
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/mabhi256/tasker/internal/lib/pdf"
+	"github.com/mabhi256/tasker/internal/lib/preview"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKindOf(t *testing.T) {
+	for _, tc := range []struct {
+		mimeType string
+		name     string
+		kind     preview.Kind
+		ok       bool
+	}{
+		{"application/pdf", "report.pdf", preview.KindPDF, true},
+		{"image/png", "screenshot.png", preview.KindImage, true},
+		{"image/jpeg", "photo", preview.KindImage, true},
+		{"text/plain; charset=utf-8", "notes.md", preview.KindText, true},
+		{"text/plain; charset=utf-8", "notes.TXT", preview.KindText, true},
+		{"text/plain; charset=utf-8", "main.go", "", false},
+		{"image/webp", "pasted.webp", "", false},
+		{"application/zip", "archive.zip", "", false},
+	} {
+		kind, ok := preview.KindOf(tc.mimeType, tc.name)
+		assert.Equal(t, tc.kind, kind, tc.name)
+		assert.Equal(t, tc.ok, ok, tc.name)
+	}
+}
+
+func TestExtractPDF(t *testing.T) {
+	doc := pdf.New("Report")
+	for range 200 {
+		doc.Write(pdf.BodyStyle, "A line of the report, long enough to wrap at least once on the page it is written on.")
+	}
+
+	p, err := preview.Extract(preview.KindPDF, bytes.NewReader(doc.Bytes()))
+	require.NoError(t, err)
+	require.NotNil(t, p.PageCount)
+	assert.Greater(t, *p.PageCount, 1)
+	assert.Equal(t, strings.Count(string(doc.Bytes()), "/Type /Page "), *p.PageCount)
+
+	_, err = preview.Extract(preview.KindPDF, strings.NewReader("not a pdf"))
+	assert.Error(t, err)
+}
+
+func TestExtractImage(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 64, 48))))
+
+	p, err := preview.Extract(preview.KindImage, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, preview.KindImage, p.Kind)
+	assert.Equal(t, 64, *p.Width)
+	assert.Equal(t, 48, *p.Height)
+	assert.Nil(t, p.PageCount)
+}
+
+func TestExtractText(t *testing.T) {
+	p, err := preview.Extract(preview.KindText, strings.NewReader("\ufeff# Notes\n\nSome text\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "# Notes\n\nSome text", *p.Excerpt)
+
+	// Long text is cut at the excerpt length, between characters
+	p, err = preview.Extract(preview.KindText, strings.NewReader(strings.Repeat("é", 2*preview.ExcerptLength)))
+	require.NoError(t, err)
+	assert.Equal(t, strings.Repeat("é", preview.ExcerptLength), *p.Excerpt)
+
+	_, err = preview.Extract(preview.KindText, bytes.NewReader([]byte{0xff, 0xfe, 'a'}))
+	assert.Error(t, err)
+}
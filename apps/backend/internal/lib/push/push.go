@@ -0,0 +1,112 @@
+// Package push delivers notifications to users' devices: apps through
+// Firebase Cloud Messaging and browsers through Web Push with VAPID.
+package push
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/mabhi256/tasker/internal/config"
+	"github.com/mabhi256/tasker/internal/model/device"
+)
+
+var (
+	// ErrNotConfigured is returned for a device on a platform whose backend
+	// has no credentials
+	ErrNotConfigured = errors.New("push platform is not configured")
+	// ErrGone means the device's token or subscription is no longer valid,
+	// so the device should be forgotten rather than retried
+	ErrGone = errors.New("push device is no longer registered")
+)
+
+// Message is a notification. Browsers get it as the JSON payload of the push
+// event for the service worker to show.
+type Message struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	// URL is opened when the notification is clicked
+	URL string `json:"url,omitempty"`
+	// Tag groups notifications, so a newer one replaces an older one with
+	// the same tag instead of stacking up
+	Tag string `json:"tag,omitempty"`
+}
+
+// Doer sends HTTP requests, e.g. an httpclient.Client
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Client sends messages through the backend of each device's platform
+type Client struct {
+	fcm     *FCM
+	webPush *WebPush
+}
+
+// NewClient sets up the backends that have credentials. FCM requests only go
+// to Google, while Web Push endpoints are supplied by browsers, so webClient
+// must refuse internal addresses.
+func NewClient(cfg *config.PushConfig, client Doer, webClient Doer) (*Client, error) {
+	c := &Client{}
+
+	if cfg.FCM.CredentialsJSON != "" {
+		fcm, err := NewFCM([]byte(cfg.FCM.CredentialsJSON), client)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up FCM: %w", err)
+		}
+		c.fcm = fcm
+	}
+
+	if cfg.WebPush.VAPIDPrivateKey != "" {
+		webPush, err := NewWebPush(cfg.WebPush.VAPIDPrivateKey, cfg.WebPush.Subject, cfg.WebPush.TTL, webClient)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up Web Push: %w", err)
+		}
+		c.webPush = webPush
+	}
+
+	return c, nil
+}
+
+// Platforms lists the platforms messages can be sent to
+func (c *Client) Platforms() []device.Platform {
+	platforms := []device.Platform{}
+	if c.fcm != nil {
+		platforms = append(platforms, device.PlatformFCM)
+	}
+	if c.webPush != nil {
+		platforms = append(platforms, device.PlatformWeb)
+	}
+	return platforms
+}
+
+// VAPIDPublicKey returns the key browsers subscribe with, or "" when Web
+// Push is disabled
+func (c *Client) VAPIDPublicKey() string {
+	if c.webPush == nil {
+		return ""
+	}
+	return c.webPush.PublicKey()
+}
+
+// Send delivers msg to d
+func (c *Client) Send(ctx context.Context, d *device.Device, msg *Message) error {
+	switch d.Platform {
+	case device.PlatformFCM:
+		if c.fcm == nil {
+			return ErrNotConfigured
+		}
+		return c.fcm.Send(ctx, d.Token, msg)
+	case device.PlatformWeb:
+		if c.webPush == nil {
+			return ErrNotConfigured
+		}
+		if d.P256dh == nil || d.Auth == nil {
+			return fmt.Errorf("web push device %s has no keys", d.ID)
+		}
+		return c.webPush.Send(ctx, &Subscription{Endpoint: d.Token, P256dh: *d.P256dh, Auth: *d.Auth}, msg)
+	default:
+		return fmt.Errorf("unsupported push platform: %s", d.Platform)
+	}
+}
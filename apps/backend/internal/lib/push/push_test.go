@@ -0,0 +1,284 @@
+package push_test
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mabhi256/tasker/internal/lib/push"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type doerFunc func(req *http.Request) (*http.Response, error)
+
+func (f doerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func respond(status int, body string) *http.Response {
+	return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body))}
+}
+
+// browser is the receiving side of a web push subscription
+type browser struct {
+	key  *ecdh.PrivateKey
+	auth []byte
+}
+
+func newBrowser(t *testing.T) *browser {
+	t.Helper()
+
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	auth := make([]byte, 16)
+	_, err = rand.Read(auth)
+	require.NoError(t, err)
+
+	return &browser{key: key, auth: auth}
+}
+
+func (b *browser) subscription(endpoint string) *push.Subscription {
+	return &push.Subscription{
+		Endpoint: endpoint,
+		P256dh:   base64.RawURLEncoding.EncodeToString(b.key.PublicKey().Bytes()),
+		Auth:     base64.RawURLEncoding.EncodeToString(b.auth),
+	}
+}
+
+// decrypt follows RFC 8291 from the user agent's side
+func (b *browser) decrypt(t *testing.T, body []byte) []byte {
+	t.Helper()
+
+	salt := body[:16]
+	recordSize := binary.BigEndian.Uint32(body[16:20])
+	keyLen := int(body[20])
+	asPublicBytes := body[21 : 21+keyLen]
+	ciphertext := body[21+keyLen:]
+	assert.Equal(t, uint32(4096), recordSize)
+
+	asPublic, err := ecdh.P256().NewPublicKey(asPublicBytes)
+	require.NoError(t, err)
+	sharedSecret, err := b.key.ECDH(asPublic)
+	require.NoError(t, err)
+
+	keyInfo := "WebPush: info\x00" + string(b.key.PublicKey().Bytes()) + string(asPublicBytes)
+	ikm, err := hkdf.Key(sha256.New, sharedSecret, b.auth, keyInfo, 32)
+	require.NoError(t, err)
+	cek, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	require.NoError(t, err)
+	nonce, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+	require.NoError(t, err)
+
+	block, err := aes.NewCipher(cek)
+	require.NoError(t, err)
+	gcm, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	require.NoError(t, err)
+	require.Equal(t, byte(0x02), plaintext[len(plaintext)-1])
+	return plaintext[:len(plaintext)-1]
+}
+
+func newVAPIDKey(t *testing.T) string {
+	t.Helper()
+
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return base64.RawURLEncoding.EncodeToString(key.Bytes())
+}
+
+// verifyVAPID checks the JWT in the Authorization header against the key
+// sent with it
+func verifyVAPID(t *testing.T, header string, publicKey string) map[string]any {
+	t.Helper()
+
+	require.True(t, strings.HasPrefix(header, "vapid t="))
+	token, key, ok := strings.Cut(strings.TrimPrefix(header, "vapid t="), ", k=")
+	require.True(t, ok)
+	assert.Equal(t, publicKey, key)
+
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+
+	keyBytes, err := base64.RawURLEncoding.DecodeString(key)
+	require.NoError(t, err)
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	require.NoError(t, err)
+	require.Len(t, signature, 64)
+
+	public := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(keyBytes[1:33]),
+		Y:     new(big.Int).SetBytes(keyBytes[33:]),
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r := new(big.Int).SetBytes(signature[:32])
+	s := new(big.Int).SetBytes(signature[32:])
+	assert.True(t, ecdsa.Verify(public, digest[:], r, s))
+
+	claims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	var out map[string]any
+	require.NoError(t, json.Unmarshal(claims, &out))
+	return out
+}
+
+func TestWebPushSend(t *testing.T) {
+	b := newBrowser(t)
+
+	var got *http.Request
+	var body []byte
+	client := doerFunc(func(req *http.Request) (*http.Response, error) {
+		got = req
+		var err error
+		body, err = io.ReadAll(req.Body)
+		require.NoError(t, err)
+		return respond(http.StatusCreated, ""), nil
+	})
+
+	webPush, err := push.NewWebPush(newVAPIDKey(t), "mailto:ops@example.com", time.Hour, client)
+	require.NoError(t, err)
+
+	msg := &push.Message{Title: "Due soon", Body: "Ship it", URL: "https://app.example.com/todos/1", Tag: "todo-1"}
+	err = webPush.Send(context.Background(), b.subscription("https://push.example.com/send/abc"), msg)
+	require.NoError(t, err)
+
+	assert.Equal(t, "aes128gcm", got.Header.Get("Content-Encoding"))
+	assert.Equal(t, "3600", got.Header.Get("TTL"))
+	assert.Equal(t, "todo-1", got.Header.Get("Topic"))
+
+	claims := verifyVAPID(t, got.Header.Get("Authorization"), webPush.PublicKey())
+	assert.Equal(t, "https://push.example.com", claims["aud"])
+	assert.Equal(t, "mailto:ops@example.com", claims["sub"])
+
+	var decrypted push.Message
+	require.NoError(t, json.Unmarshal(b.decrypt(t, body), &decrypted))
+	assert.Equal(t, *msg, decrypted)
+}
+
+func TestWebPushSendGone(t *testing.T) {
+	b := newBrowser(t)
+
+	for _, status := range []int{http.StatusNotFound, http.StatusGone} {
+		client := doerFunc(func(req *http.Request) (*http.Response, error) {
+			return respond(status, ""), nil
+		})
+
+		webPush, err := push.NewWebPush(newVAPIDKey(t), "mailto:ops@example.com", time.Hour, client)
+		require.NoError(t, err)
+
+		err = webPush.Send(context.Background(), b.subscription("https://push.example.com/send/abc"), &push.Message{})
+		assert.ErrorIs(t, err, push.ErrGone)
+	}
+}
+
+func TestEncryptRejectsLargePayload(t *testing.T) {
+	b := newBrowser(t)
+
+	_, err := push.Encrypt(b.subscription("https://push.example.com"), make([]byte, 4096))
+	assert.Error(t, err)
+}
+
+func newServiceAccount(t *testing.T) (string, *rsa.PublicKey) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	account, err := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"project_id":   "tasker-test",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"client_email": "push@tasker-test.iam.gserviceaccount.com",
+		"token_uri":    "https://oauth2.example.com/token",
+	})
+	require.NoError(t, err)
+	return string(account), &key.PublicKey
+}
+
+func TestFCMSend(t *testing.T) {
+	account, _ := newServiceAccount(t)
+
+	tokenRequests := 0
+	var sent []map[string]any
+	client := doerFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Host == "oauth2.example.com" {
+			tokenRequests++
+			require.NoError(t, req.ParseForm())
+			assert.Equal(t, "urn:ietf:params:oauth:grant-type:jwt-bearer", req.PostForm.Get("grant_type"))
+			return respond(http.StatusOK, `{"access_token":"access","expires_in":3600}`), nil
+		}
+
+		assert.Equal(t, "/v1/projects/tasker-test/messages:send", req.URL.Path)
+		assert.Equal(t, "Bearer access", req.Header.Get("Authorization"))
+
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&body))
+		sent = append(sent, body)
+
+		if len(sent) == 2 {
+			return respond(http.StatusNotFound,
+				`{"error":{"status":"NOT_FOUND","details":[{"errorCode":"UNREGISTERED"}]}}`), nil
+		}
+		return respond(http.StatusOK, `{"name":"projects/tasker-test/messages/1"}`), nil
+	})
+
+	fcm, err := push.NewFCM([]byte(account), client)
+	require.NoError(t, err)
+
+	msg := &push.Message{Title: "Due soon", Body: "Ship it", URL: "https://app.example.com/todos/1"}
+	require.NoError(t, fcm.Send(context.Background(), "device-token", msg))
+
+	err = fcm.Send(context.Background(), "stale-token", msg)
+	assert.ErrorIs(t, err, push.ErrGone)
+
+	// The access token is reused until it expires
+	assert.Equal(t, 1, tokenRequests)
+
+	require.Len(t, sent, 2)
+	message := sent[0]["message"].(map[string]any)
+	assert.Equal(t, "device-token", message["token"])
+	assert.Equal(t, map[string]any{"title": "Due soon", "body": "Ship it"}, message["notification"])
+	assert.Equal(t, map[string]any{"url": "https://app.example.com/todos/1"}, message["data"])
+}
+
+func TestNewFCMRejectsBadCredentials(t *testing.T) {
+	_, err := push.NewFCM([]byte(`{"project_id":"tasker-test"}`), nil)
+	assert.Error(t, err)
+}
+
+func TestSubscriptionValidate(t *testing.T) {
+	valid := newBrowser(t).subscription("https://push.example.com")
+	require.NoError(t, valid.Validate())
+
+	badKey := *valid
+	badKey.P256dh = base64.RawURLEncoding.EncodeToString(make([]byte, 65))
+	assert.Error(t, badKey.Validate())
+
+	shortAuth := *valid
+	shortAuth.Auth = base64.RawURLEncoding.EncodeToString(make([]byte, 8))
+	assert.Error(t, shortAuth.Validate())
+}
@@ -0,0 +1,279 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// webPushRecordSize is the single record a message is encrypted into
+	webPushRecordSize = 4096
+	// webPushMaxPayload leaves room in the record for the padding delimiter
+	// and the AEAD tag
+	webPushMaxPayload = webPushRecordSize - 17
+	// vapidTokenLifetime is well under the 24 hours push services accept
+	vapidTokenLifetime = 12 * time.Hour
+)
+
+// topicPattern is what push services accept in the Topic header
+var topicPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// Subscription is a browser's push subscription. P256dh and Auth are the
+// base64url encoded keys from PushSubscription.toJSON().
+type Subscription struct {
+	Endpoint string
+	P256dh   string
+	Auth     string
+}
+
+// WebPush sends encrypted messages to browser push services (RFC 8030),
+// encrypted with aes128gcm (RFC 8291) and identified with VAPID (RFC 8292)
+type WebPush struct {
+	client    Doer
+	key       *ecdsa.PrivateKey
+	publicKey string
+	subject   string
+	ttl       time.Duration
+}
+
+// NewWebPush takes the VAPID private key as its raw scalar, base64url
+// encoded, and derives the public key from it
+func NewWebPush(privateKey string, subject string, ttl time.Duration, client Doer) (*WebPush, error) {
+	raw, err := decodeKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode VAPID private key: %w", err)
+	}
+
+	ecdhKey, err := ecdh.P256().NewPrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+	public := ecdhKey.PublicKey().Bytes()
+
+	if !strings.HasPrefix(subject, "mailto:") && !strings.HasPrefix(subject, "https://") {
+		return nil, errors.New("VAPID subject must be a mailto: or https: URL")
+	}
+
+	return &WebPush{
+		client: client,
+		key: &ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{
+				Curve: elliptic.P256(),
+				X:     new(big.Int).SetBytes(public[1:33]),
+				Y:     new(big.Int).SetBytes(public[33:]),
+			},
+			D: new(big.Int).SetBytes(raw),
+		},
+		publicKey: base64.RawURLEncoding.EncodeToString(public),
+		subject:   subject,
+		ttl:       ttl,
+	}, nil
+}
+
+// PublicKey is the uncompressed VAPID public key, base64url encoded
+func (w *WebPush) PublicKey() string {
+	return w.publicKey
+}
+
+// Send encrypts msg for the subscription and posts it to its push service
+func (w *WebPush) Send(ctx context.Context, sub *Subscription, msg *Message) error {
+	endpoint, err := url.Parse(sub.Endpoint)
+	if err != nil || endpoint.Scheme != "https" || endpoint.Host == "" {
+		return fmt.Errorf("invalid web push endpoint: %s", sub.Endpoint)
+	}
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal web push message: %w", err)
+	}
+
+	body, err := Encrypt(sub, payload)
+	if err != nil {
+		return err
+	}
+
+	authorization, err := w.authorization(endpoint, time.Now())
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create web push request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("TTL", strconv.Itoa(int(w.ttl.Seconds())))
+	req.Header.Set("Urgency", "normal")
+	if topicPattern.MatchString(msg.Tag) {
+		req.Header.Set("Topic", msg.Tag)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send web push message: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusNotFound, resp.StatusCode == http.StatusGone:
+		return ErrGone
+	default:
+		return fmt.Errorf("web push service responded with status %d", resp.StatusCode)
+	}
+}
+
+// authorization is the VAPID header for the push service of endpoint: a JWT
+// for its origin signed with the VAPID key, and the public key to check it
+func (w *WebPush) authorization(endpoint *url.URL, now time.Time) (string, error) {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"typ":"JWT","alg":"ES256"}`))
+
+	claims, err := json.Marshal(map[string]any{
+		"aud": endpoint.Scheme + "://" + endpoint.Host,
+		"exp": now.Add(vapidTokenLifetime).Unix(),
+		"sub": w.subject,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal VAPID claims: %w", err)
+	}
+
+	signingInput := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	r, s, err := ecdsa.Sign(rand.Reader, w.key, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign VAPID token: %w", err)
+	}
+
+	// JWS uses the fixed size concatenation of r and s, not ASN.1
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	token := signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+	return fmt.Sprintf("vapid t=%s, k=%s", token, w.publicKey), nil
+}
+
+// Encrypt encrypts payload for the subscription as a single aes128gcm
+// record. Each message uses a new key pair and salt.
+func Encrypt(sub *Subscription, payload []byte) ([]byte, error) {
+	if len(payload) > webPushMaxPayload {
+		return nil, fmt.Errorf("web push payload of %d bytes exceeds %d", len(payload), webPushMaxPayload)
+	}
+
+	uaPublic, authSecret, err := sub.keys()
+	if err != nil {
+		return nil, err
+	}
+	uaPublicBytes := uaPublic.Bytes()
+
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate web push key: %w", err)
+	}
+	asPublic := asPrivate.PublicKey().Bytes()
+
+	sharedSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive web push shared secret: %w", err)
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate web push salt: %w", err)
+	}
+
+	keyInfo := "WebPush: info\x00" + string(uaPublicBytes) + string(asPublic)
+	ikm, err := hkdf.Key(sha256.New, sharedSecret, authSecret, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// The header carries the salt, record size and sender public key the
+	// browser needs to derive the same key
+	header := make([]byte, 0, 16+4+1+len(asPublic))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, webPushRecordSize)
+	header = append(header, byte(len(asPublic)))
+	header = append(header, asPublic...)
+
+	// 0x02 marks the last record, and there is only one
+	plaintext := append(payload[:len(payload):len(payload)], 0x02)
+
+	return gcm.Seal(header, nonce, plaintext, nil), nil
+}
+
+// Validate checks that the subscription's keys can be encrypted for
+func (sub *Subscription) Validate() error {
+	_, _, err := sub.keys()
+	return err
+}
+
+func (sub *Subscription) keys() (*ecdh.PublicKey, []byte, error) {
+	p256dh, err := decodeKey(sub.P256dh)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode subscription p256dh key: %w", err)
+	}
+	public, err := ecdh.P256().NewPublicKey(p256dh)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid subscription p256dh key: %w", err)
+	}
+
+	auth, err := decodeKey(sub.Auth)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode subscription auth secret: %w", err)
+	}
+	if len(auth) != 16 {
+		return nil, nil, fmt.Errorf("subscription auth secret is %d bytes, expected 16", len(auth))
+	}
+
+	return public, auth, nil
+}
+
+// decodeKey accepts base64url with or without padding, as browsers and key
+// generators differ
+func decodeKey(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
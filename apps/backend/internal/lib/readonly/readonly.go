@@ -0,0 +1,255 @@
+// Package readonly switches the API to read-only mode during incident
+// recovery, in which writes are rejected and reads served as usual.
+//
+// Three sources engage the mode: the config, an admin, whose switch is
+// shared with every instance through Redis, and the database, which engages
+// it when it rejects a write for being read-only, such as while a replica
+// is promoted. The database releases it once it accepts writes again. The
+// mode lasts while any source engages it.
+package readonly
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mabhi256/tasker/internal/config"
+	"github.com/mabhi256/tasker/internal/sqlerr"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+)
+
+// adminKey holds the admin's engagement, shared by every instance
+const adminKey = "read_only:admin"
+
+// Source is what engaged read-only mode
+type Source string
+
+const (
+	SourceConfig   Source = "config"
+	SourceAdmin    Source = "admin"
+	SourceDatabase Source = "database"
+)
+
+type Engagement struct {
+	Source Source    `json:"source"`
+	Reason string    `json:"reason"`
+	Since  time.Time `json:"since"`
+}
+
+// Probe reports whether the database is still read-only
+type Probe func(ctx context.Context) (bool, error)
+
+type Switch struct {
+	cfg    *config.ReadOnlyConfig
+	redis  *redis.Client
+	logger *zerolog.Logger
+	probe  Probe
+
+	mu       sync.Mutex
+	engaged  map[Source]Engagement
+	readOnly atomic.Bool
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New returns a switch engaged when cfg enables read-only mode. With a nil
+// client an admin's switch only applies to this instance.
+func New(cfg *config.ReadOnlyConfig, redisClient *redis.Client, logger *zerolog.Logger, probe Probe) *Switch {
+	s := &Switch{
+		cfg:     cfg,
+		redis:   redisClient,
+		logger:  logger,
+		probe:   probe,
+		engaged: make(map[Source]Engagement),
+	}
+	s.Set(SourceConfig, cfg.Enabled, cfg.Reason)
+
+	return s
+}
+
+// Start syncs the admin's switch from the other instances and checks
+// whether a read-only database accepts writes again
+func (s *Switch) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	go s.run(ctx)
+}
+
+func (s *Switch) Stop() {
+	if s.cancel == nil {
+		return
+	}
+
+	s.cancel()
+	<-s.done
+}
+
+// ReadOnly reports whether writes are rejected
+func (s *Switch) ReadOnly() bool {
+	return s.readOnly.Load()
+}
+
+// Engagements are the sources engaging read-only mode, by source
+func (s *Switch) Engagements() []Engagement {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	engagements := make([]Engagement, 0, len(s.engaged))
+	for _, e := range s.engaged {
+		engagements = append(engagements, e)
+	}
+	slices.SortFunc(engagements, func(a, b Engagement) int {
+		return strings.Compare(string(a.Source), string(b.Source))
+	})
+
+	return engagements
+}
+
+// Set engages or releases read-only mode for the source on this instance
+func (s *Switch) Set(source Source, engaged bool, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.engaged[source]
+	switch {
+	case engaged && !ok:
+		s.engaged[source] = Engagement{Source: source, Reason: reason, Since: time.Now().UTC()}
+		s.logger.Warn().
+			Str("source", string(source)).
+			Str("reason", reason).
+			Msg("read-only mode engaged, rejecting writes")
+	case engaged && current.Reason != reason:
+		current.Reason = reason
+		s.engaged[source] = current
+	case !engaged && ok:
+		delete(s.engaged, source)
+		s.logger.Info().
+			Str("source", string(source)).
+			Dur("duration", time.Since(current.Since)).
+			Bool("read_only", len(s.engaged) > 0).
+			Msg("Read-only mode released")
+	}
+
+	s.readOnly.Store(len(s.engaged) > 0)
+}
+
+// SetShared engages or releases the admin's read-only mode. This instance
+// applies it at once and the others within the check interval.
+func (s *Switch) SetShared(ctx context.Context, engaged bool, reason string) error {
+	if s.redis != nil {
+		var err error
+		if engaged {
+			var data []byte
+			data, err = json.Marshal(Engagement{Source: SourceAdmin, Reason: reason, Since: time.Now().UTC()})
+			if err != nil {
+				return fmt.Errorf("failed to marshal read-only engagement: %w", err)
+			}
+			err = s.redis.Set(ctx, adminKey, data, 0).Err()
+		} else {
+			err = s.redis.Del(ctx, adminKey).Err()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to share read-only mode: %w", err)
+		}
+	}
+
+	s.Set(SourceAdmin, engaged, reason)
+	return nil
+}
+
+// QueryTracer returns a pgx tracer that engages read-only mode when the
+// database rejects a write for being read-only
+func (s *Switch) QueryTracer() pgx.QueryTracer {
+	return queryTracer{s: s}
+}
+
+type queryTracer struct {
+	s *Switch
+}
+
+func (t queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryStartData) context.Context {
+	return ctx
+}
+
+func (t queryTracer) TraceQueryEnd(_ context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	if sqlerr.IsReadOnly(data.Err) {
+		t.s.Set(SourceDatabase, true, "the database rejected a write for being read-only")
+	}
+}
+
+func (s *Switch) run(ctx context.Context) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.cfg.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.syncAdmin(ctx)
+			s.checkDatabase(ctx)
+		}
+	}
+}
+
+// syncAdmin applies the admin's switch, which may have been set on another
+// instance. It is kept as it is while Redis can't be reached.
+func (s *Switch) syncAdmin(ctx context.Context) {
+	if s.redis == nil {
+		return
+	}
+
+	data, err := s.redis.Get(ctx, adminKey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		s.Set(SourceAdmin, false, "")
+		return
+	}
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Error().Err(err).Msg("failed to sync read-only mode")
+		}
+		return
+	}
+
+	var engagement Engagement
+	if err := json.Unmarshal(data, &engagement); err != nil {
+		s.logger.Error().Err(err).Msg("failed to decode read-only engagement")
+		return
+	}
+	s.Set(SourceAdmin, true, engagement.Reason)
+}
+
+// checkDatabase releases the database's engagement once it accepts writes
+func (s *Switch) checkDatabase(ctx context.Context) {
+	s.mu.Lock()
+	_, engaged := s.engaged[SourceDatabase]
+	s.mu.Unlock()
+
+	if !engaged || s.probe == nil {
+		return
+	}
+
+	readOnly, err := s.probe(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Warn().Err(err).Msg("failed to check whether the database accepts writes")
+		}
+		return
+	}
+	if !readOnly {
+		s.Set(SourceDatabase, false, "")
+	}
+}
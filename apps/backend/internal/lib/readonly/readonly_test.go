@@ -0,0 +1,82 @@
+package readonly_test
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mabhi256/tasker/internal/config"
+	"github.com/mabhi256/tasker/internal/lib/readonly"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSwitch(cfg *config.ReadOnlyConfig, probe readonly.Probe) *readonly.Switch {
+	logger := zerolog.Nop()
+	return readonly.New(cfg, nil, &logger, probe)
+}
+
+func TestSwitch(t *testing.T) {
+	s := newSwitch(config.DefaultReadOnlyConfig(), nil)
+	assert.False(t, s.ReadOnly())
+	assert.Empty(t, s.Engagements())
+
+	s.Set(readonly.SourceConfig, true, "restoring backups")
+	require.NoError(t, s.SetShared(context.Background(), true, "investigating"))
+	assert.True(t, s.ReadOnly())
+
+	engagements := s.Engagements()
+	require.Len(t, engagements, 2)
+	assert.Equal(t, readonly.SourceAdmin, engagements[0].Source)
+	assert.Equal(t, "investigating", engagements[0].Reason)
+	assert.Equal(t, readonly.SourceConfig, engagements[1].Source)
+
+	// The mode lasts while any source engages it
+	s.Set(readonly.SourceConfig, false, "")
+	assert.True(t, s.ReadOnly())
+	require.NoError(t, s.SetShared(context.Background(), false, ""))
+	assert.False(t, s.ReadOnly())
+}
+
+func TestEngagedByConfig(t *testing.T) {
+	cfg := config.DefaultReadOnlyConfig()
+	cfg.Enabled = true
+	cfg.Reason = "migrating"
+
+	s := newSwitch(cfg, nil)
+	assert.True(t, s.ReadOnly())
+	assert.Equal(t, "migrating", s.Engagements()[0].Reason)
+}
+
+func TestEngagedByDatabase(t *testing.T) {
+	cfg := config.DefaultReadOnlyConfig()
+	cfg.CheckInterval = 10 * time.Millisecond
+
+	var readOnly atomic.Bool
+	readOnly.Store(true)
+	s := newSwitch(cfg, func(ctx context.Context) (bool, error) {
+		return readOnly.Load(), nil
+	})
+	s.Start()
+	defer s.Stop()
+
+	tracer := s.QueryTracer()
+	tracer.TraceQueryEnd(context.Background(), nil, pgx.TraceQueryEndData{Err: fmt.Errorf("insert: %w", &pgconn.PgError{Code: "23505"})})
+	assert.False(t, s.ReadOnly(), "another error engaged read-only mode")
+
+	tracer.TraceQueryEnd(context.Background(), nil, pgx.TraceQueryEndData{Err: fmt.Errorf("insert: %w", &pgconn.PgError{Code: "25006"})})
+	require.True(t, s.ReadOnly())
+	assert.Equal(t, readonly.SourceDatabase, s.Engagements()[0].Source)
+
+	// Still read-only when checked
+	time.Sleep(5 * cfg.CheckInterval)
+	assert.True(t, s.ReadOnly())
+
+	readOnly.Store(false)
+	assert.Eventually(t, func() bool { return !s.ReadOnly() }, time.Second, cfg.CheckInterval)
+}
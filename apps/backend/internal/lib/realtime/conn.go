@@ -0,0 +1,222 @@
+package realtime
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/config"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	writeTimeout = 10 * time.Second
+	// pingInterval keeps idle connections open through proxies and finds
+	// clients that went away without closing
+	pingInterval = 30 * time.Second
+)
+
+var pingMessage = []byte(`{"type":"ping"}`)
+
+// outgoing is a queued message. Messages with the same key replace each
+// other while they wait, so a client that falls behind gets the latest
+// state of a todo rather than every intermediate update.
+type outgoing struct {
+	key  string
+	data []byte
+}
+
+// enqueueResult reports what became of a message offered to a connection
+type enqueueResult struct {
+	coalesced  bool
+	dropped    int
+	disconnect bool
+}
+
+// Conn is a client's websocket connection to a workspace, made in one of
+// the user's sessions or with one of their API keys
+type Conn struct {
+	ID          string
+	UserID      string
+	SessionID   string
+	APIKeyID    string
+	WorkspaceID uuid.UUID
+
+	ws     *websocket.Conn
+	limits *config.RealtimeConfig
+
+	mu          sync.Mutex
+	queue       []outgoing
+	queuedBytes int
+	// closing is set once the last message was queued, the connection
+	// closes after it is sent
+	closing bool
+	wake    chan struct{}
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func newConn(ws *websocket.Conn, limits *config.RealtimeConfig, userID string, sessionID string,
+	apiKeyID string, workspaceID uuid.UUID,
+) *Conn {
+	return &Conn{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		SessionID:   sessionID,
+		APIKeyID:    apiKeyID,
+		WorkspaceID: workspaceID,
+		ws:          ws,
+		limits:      limits,
+		wake:        make(chan struct{}, 1),
+		closed:      make(chan struct{}),
+	}
+}
+
+// enqueue queues a message without blocking. When the send buffer is full
+// the slow consumer policy decides whether messages are dropped or the
+// connection should be closed.
+func (c *Conn) enqueue(key string, data []byte) enqueueResult {
+	var result enqueueResult
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	select {
+	case <-c.closed:
+		return result
+	default:
+	}
+	if c.closing {
+		return result
+	}
+
+	if key != "" {
+		for i := range c.queue {
+			if c.queue[i].key == key {
+				c.queuedBytes += len(data) - len(c.queue[i].data)
+				c.queue[i].data = data
+				result.coalesced = true
+				return result
+			}
+		}
+	}
+
+	// A message that could never fit is dropped whatever the policy
+	if len(data) > c.limits.SendBufferBytes {
+		result.dropped = 1
+		return result
+	}
+
+	for len(c.queue) >= c.limits.SendBufferSize || c.queuedBytes+len(data) > c.limits.SendBufferBytes {
+		switch c.limits.SlowConsumerPolicy {
+		case config.SlowConsumerDropOldest:
+			c.queuedBytes -= len(c.queue[0].data)
+			c.queue[0] = outgoing{}
+			c.queue = c.queue[1:]
+			result.dropped++
+		case config.SlowConsumerDropNewest:
+			result.dropped++
+			return result
+		default:
+			result.disconnect = true
+			return result
+		}
+	}
+
+	c.queue = append(c.queue, outgoing{key: key, data: data})
+	c.queuedBytes += len(data)
+
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+
+	return result
+}
+
+// closeAfter queues a last message, whatever the send buffer holds, and
+// closes the connection once it is sent. Later messages are discarded.
+func (c *Conn) closeAfter(data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closing {
+		return
+	}
+	c.queue = append(c.queue, outgoing{data: data})
+	c.queuedBytes += len(data)
+	c.closing = true
+
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+// take removes every queued message, leaving later ones to queue and
+// coalesce while these are written. last is set when the connection closes
+// after them.
+func (c *Conn) take() (batch []outgoing, last bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	batch = c.queue
+	c.queue = nil
+	c.queuedBytes = 0
+	return batch, c.closing
+}
+
+func (c *Conn) close() {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.ws.Close()
+	})
+}
+
+// writeLoop sends queued messages until the connection closes or a write
+// fails
+func (c *Conn) writeLoop() {
+	defer c.close()
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.closed:
+			return
+		case <-c.wake:
+			batch, last := c.take()
+			for _, message := range batch {
+				if !c.write(message.data) {
+					return
+				}
+			}
+			if last {
+				return
+			}
+		case <-ticker.C:
+			if !c.write(pingMessage) {
+				return
+			}
+		}
+	}
+}
+
+func (c *Conn) write(data []byte) bool {
+	c.ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return websocket.Message.Send(c.ws, string(data)) == nil
+}
+
+// readLoop discards what the client sends and returns once it disconnects
+func (c *Conn) readLoop() {
+	defer c.close()
+
+	for {
+		var message string
+		if err := websocket.Message.Receive(c.ws, &message); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,517 @@
+// Package realtime serves websocket connections and routes events to them
+// across instances.
+//
+// Workspace events are published on a Redis channel per workspace, and an
+// instance only subscribes to the channels of workspaces it holds
+// connections for. Messages for one user are sent to the channel of each
+// instance holding one of their connections, found through a registry in
+// Redis, so they aren't broadcast to every instance.
+//
+// Each connection has a bounded send buffer. Updates to the same todo that
+// are still waiting are coalesced, and when the buffer is full the
+// configured slow consumer policy drops messages or closes the connection,
+// so one slow client can't hold on to an unbounded backlog.
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/config"
+	"github.com/mabhi256/tasker/internal/logging"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	heartbeatInterval = instanceTTL / 3
+	// registryTimeout bounds registry updates made outside any request
+	registryTimeout = 5 * time.Second
+)
+
+// targetedMessage is sent to an instance channel for the connections of one
+// user, optionally only those to one workspace or made in one session or
+// with one API key. Close closes the connections once the message is sent.
+type targetedMessage struct {
+	UserID      string          `json:"user_id"`
+	WorkspaceID *uuid.UUID      `json:"workspace_id,omitempty"`
+	SessionID   string          `json:"session_id,omitempty"`
+	APIKeyID    string          `json:"api_key_id,omitempty"`
+	Close       bool            `json:"close,omitempty"`
+	Message     json.RawMessage `json:"message"`
+}
+
+// coalescedEvent is the part of a workspace event that identifies what it
+// is about
+type coalescedEvent struct {
+	Type string `json:"type"`
+	Data struct {
+		ID string `json:"id"`
+	} `json:"data"`
+}
+
+type Hub struct {
+	cfg           *config.RealtimeConfig
+	redis         *redis.Client
+	logger        *zerolog.Logger
+	loggerService *logging.LoggerService
+	instanceID    string
+	registry      *registry
+
+	mu         sync.RWMutex
+	conns      map[string]*Conn
+	workspaces map[uuid.UUID]map[string]*Conn
+	users      map[string]map[string]*Conn
+
+	// subMu serializes changes to the workspace channel subscriptions
+	subMu      sync.Mutex
+	pubsub     *redis.PubSub
+	subscribed map[string]uuid.UUID
+
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	dropped      atomic.Int64
+	coalesced    atomic.Int64
+	disconnected atomic.Int64
+}
+
+// Stats are counted since the process started
+type Stats struct {
+	Connections int
+	// Dropped counts messages discarded because a send buffer was full
+	Dropped int64
+	// Coalesced counts messages that replaced a queued one for the same todo
+	Coalesced int64
+	// Disconnected counts connections closed for falling behind
+	Disconnected int64
+}
+
+func NewHub(cfg *config.RealtimeConfig, redisClient *redis.Client, logger *zerolog.Logger,
+	loggerService *logging.LoggerService,
+) *Hub {
+	instanceID := uuid.New().String()
+
+	return &Hub{
+		cfg:           cfg,
+		redis:         redisClient,
+		logger:        logger,
+		loggerService: loggerService,
+		instanceID:    instanceID,
+		registry:      &registry{redis: redisClient, instanceID: instanceID},
+		conns:         make(map[string]*Conn),
+		workspaces:    make(map[uuid.UUID]map[string]*Conn),
+		users:         make(map[string]map[string]*Conn),
+		subscribed:    make(map[string]uuid.UUID),
+	}
+}
+
+func (h *Hub) Stats() Stats {
+	h.mu.RLock()
+	connections := len(h.conns)
+	h.mu.RUnlock()
+
+	return Stats{
+		Connections:  connections,
+		Dropped:      h.dropped.Load(),
+		Coalesced:    h.coalesced.Load(),
+		Disconnected: h.disconnected.Load(),
+	}
+}
+
+func (h *Hub) InstanceID() string {
+	return h.instanceID
+}
+
+// Start subscribes to this instance's channel and starts heartbeating, which
+// makes the connections it registers visible to other instances
+func (h *Hub) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+	h.done = make(chan struct{})
+
+	h.pubsub = h.redis.Subscribe(ctx, instanceChannel(h.instanceID))
+
+	go h.run(ctx)
+
+	h.logger.Info().
+		Str("instance_id", h.instanceID).
+		Msg("Starting realtime hub")
+}
+
+// Stop closes every connection this instance holds and removes them from
+// the registry
+func (h *Hub) Stop() {
+	if h.cancel == nil {
+		return
+	}
+
+	h.logger.Info().Msg("Stopping realtime hub")
+	h.cancel()
+	<-h.done
+
+	h.mu.RLock()
+	conns := make([]*Conn, 0, len(h.conns))
+	for _, conn := range h.conns {
+		conns = append(conns, conn)
+	}
+	h.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), registryTimeout)
+	defer cancel()
+
+	for _, conn := range conns {
+		conn.close()
+		if err := h.registry.unregister(ctx, conn); err != nil {
+			h.logger.Warn().Err(err).Msg("failed to unregister realtime connection")
+		}
+	}
+	if err := h.registry.leave(ctx); err != nil {
+		h.logger.Warn().Err(err).Msg("failed to remove realtime instance")
+	}
+
+	h.pubsub.Close()
+}
+
+func (h *Hub) run(ctx context.Context) {
+	defer close(h.done)
+
+	h.heartbeat(ctx)
+
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	messages := h.pubsub.Channel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.heartbeat(ctx)
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			h.dispatch(msg)
+		}
+	}
+}
+
+func (h *Hub) heartbeat(ctx context.Context) {
+	h.mu.RLock()
+	conns := make([]*Conn, 0, len(h.conns))
+	for _, conn := range h.conns {
+		conns = append(conns, conn)
+	}
+	h.mu.RUnlock()
+
+	if err := h.registry.heartbeat(ctx, conns); err != nil && ctx.Err() == nil {
+		h.logger.Error().Err(err).Msg("failed to send realtime heartbeat")
+	}
+}
+
+// dispatch delivers a message received from Redis to local connections
+func (h *Hub) dispatch(msg *redis.Message) {
+	if msg.Channel == instanceChannel(h.instanceID) {
+		var targeted targetedMessage
+		if err := json.Unmarshal([]byte(msg.Payload), &targeted); err != nil {
+			h.logger.Error().Err(err).Msg("failed to decode targeted realtime message")
+			return
+		}
+		h.deliverToUser(&targeted)
+		return
+	}
+
+	h.subMu.Lock()
+	workspaceID, ok := h.subscribed[msg.Channel]
+	h.subMu.Unlock()
+	if !ok {
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	message := []byte(msg.Payload)
+	key := coalesceKey(message)
+	for _, conn := range h.workspaces[workspaceID] {
+		h.deliver(conn, key, message)
+	}
+}
+
+// coalesceKey lets a queued todo event be replaced by a later one of the
+// same type for the same todo. Other messages are never coalesced.
+func coalesceKey(message []byte) string {
+	var event coalescedEvent
+	if err := json.Unmarshal(message, &event); err != nil {
+		return ""
+	}
+	if !strings.HasPrefix(event.Type, "todo.") || event.Data.ID == "" {
+		return ""
+	}
+	return event.Type + ":" + event.Data.ID
+}
+
+func (h *Hub) deliverToUser(targeted *targetedMessage) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, conn := range h.users[targeted.UserID] {
+		if targeted.WorkspaceID != nil && conn.WorkspaceID != *targeted.WorkspaceID {
+			continue
+		}
+		if targeted.SessionID != "" && conn.SessionID != targeted.SessionID {
+			continue
+		}
+		if targeted.APIKeyID != "" && conn.APIKeyID != targeted.APIKeyID {
+			continue
+		}
+		if targeted.Close {
+			conn.closeAfter(targeted.Message)
+			continue
+		}
+		h.deliver(conn, "", targeted.Message)
+	}
+}
+
+func (h *Hub) deliver(conn *Conn, key string, message []byte) {
+	result := conn.enqueue(key, message)
+
+	if result.coalesced {
+		h.coalesced.Add(1)
+		h.recordMetric("Coalesced", 1)
+	}
+
+	if result.dropped > 0 {
+		h.dropped.Add(int64(result.dropped))
+		h.recordMetric("Dropped", result.dropped)
+		h.logger.Warn().
+			Str("connection_id", conn.ID).
+			Str("user_id", conn.UserID).
+			Int("dropped", result.dropped).
+			Msg("realtime send buffer full, dropped messages")
+	}
+
+	if result.disconnect {
+		h.disconnected.Add(1)
+		h.recordMetric("Disconnected", 1)
+		h.logger.Warn().
+			Str("connection_id", conn.ID).
+			Str("user_id", conn.UserID).
+			Msg("realtime send buffer full, disconnecting slow client")
+		conn.close()
+	}
+}
+
+func (h *Hub) recordMetric(outcome string, count int) {
+	if h.loggerService != nil && h.loggerService.GetApplication() != nil {
+		h.loggerService.GetApplication().RecordCustomMetric("Custom/Realtime/"+outcome, float64(count))
+	}
+}
+
+// SendToUser sends message to every connection of a user, on whichever
+// instances hold them. workspaceID limits it to connections to one
+// workspace when set. A user with no connections isn't an error.
+func (h *Hub) SendToUser(ctx context.Context, userID string, workspaceID *uuid.UUID, message any) error {
+	encoded, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal realtime message: %w", err)
+	}
+
+	return h.sendTargeted(ctx, &targetedMessage{
+		UserID:      userID,
+		WorkspaceID: workspaceID,
+		Message:     encoded,
+	})
+}
+
+// CloseSession sends message to the connections made in one of a user's
+// sessions, on whichever instances hold them, and closes them once it is
+// sent
+func (h *Hub) CloseSession(ctx context.Context, userID string, sessionID string, message any) error {
+	encoded, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal realtime message: %w", err)
+	}
+
+	return h.sendTargeted(ctx, &targetedMessage{
+		UserID:    userID,
+		SessionID: sessionID,
+		Close:     true,
+		Message:   encoded,
+	})
+}
+
+// CloseAPIKey sends message to the connections made with one of a user's
+// API keys, on whichever instances hold them, and closes them once it is
+// sent
+func (h *Hub) CloseAPIKey(ctx context.Context, userID string, apiKeyID string, message any) error {
+	encoded, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal realtime message: %w", err)
+	}
+
+	return h.sendTargeted(ctx, &targetedMessage{
+		UserID:   userID,
+		APIKeyID: apiKeyID,
+		Close:    true,
+		Message:  encoded,
+	})
+}
+
+func (h *Hub) sendTargeted(ctx context.Context, targeted *targetedMessage) error {
+	instances, err := h.registry.userInstances(ctx, targeted.UserID)
+	if err != nil {
+		return err
+	}
+
+	var payload []byte
+	for _, instanceID := range instances {
+		if instanceID == h.instanceID {
+			h.deliverToUser(targeted)
+			continue
+		}
+
+		if payload == nil {
+			if payload, err = json.Marshal(targeted); err != nil {
+				return fmt.Errorf("failed to marshal targeted realtime message: %w", err)
+			}
+		}
+		if err := h.redis.Publish(ctx, instanceChannel(instanceID), payload).Err(); err != nil {
+			return fmt.Errorf("failed to publish realtime message to instance %s: %w", instanceID, err)
+		}
+	}
+
+	return nil
+}
+
+// Presence returns the users connected to a workspace on any instance, with
+// how many connections each has open
+func (h *Hub) Presence(ctx context.Context, workspaceID uuid.UUID) (map[string]int, error) {
+	return h.registry.workspaceUsers(ctx, workspaceID)
+}
+
+// Handler upgrades a request to a websocket connection to a workspace for
+// a user authenticated in the session, or with the API key, and serves it
+// until either side closes it
+func (h *Hub) Handler(userID string, sessionID string, apiKeyID string, workspaceID uuid.UUID) http.Handler {
+	return websocket.Server{
+		Handler: func(ws *websocket.Conn) {
+			h.serve(newConn(ws, h.cfg, userID, sessionID, apiKeyID, workspaceID))
+		},
+	}
+}
+
+func (h *Hub) serve(conn *Conn) {
+	// The HTTP server's timeouts were meant for the upgrade request
+	conn.ws.SetDeadline(time.Time{})
+
+	h.add(conn)
+	defer h.remove(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), registryTimeout)
+	err := h.registry.register(ctx, conn)
+	cancel()
+	if err != nil {
+		// Workspace events still reach the connection, only targeted messages
+		// and presence miss it
+		h.logger.Error().Err(err).Str("connection_id", conn.ID).Msg("failed to register realtime connection")
+	}
+
+	h.logger.Info().
+		Str("connection_id", conn.ID).
+		Str("user_id", conn.UserID).
+		Str("workspace_id", conn.WorkspaceID.String()).
+		Msg("Realtime connection opened")
+
+	go conn.writeLoop()
+	conn.readLoop()
+
+	ctx, cancel = context.WithTimeout(context.Background(), registryTimeout)
+	defer cancel()
+	if err := h.registry.unregister(ctx, conn); err != nil {
+		h.logger.Warn().Err(err).Str("connection_id", conn.ID).Msg("failed to unregister realtime connection")
+	}
+
+	h.logger.Info().
+		Str("connection_id", conn.ID).
+		Str("user_id", conn.UserID).
+		Msg("Realtime connection closed")
+}
+
+func (h *Hub) add(conn *Conn) {
+	h.mu.Lock()
+	h.conns[conn.ID] = conn
+	if h.workspaces[conn.WorkspaceID] == nil {
+		h.workspaces[conn.WorkspaceID] = make(map[string]*Conn)
+	}
+	h.workspaces[conn.WorkspaceID][conn.ID] = conn
+	if h.users[conn.UserID] == nil {
+		h.users[conn.UserID] = make(map[string]*Conn)
+	}
+	h.users[conn.UserID][conn.ID] = conn
+	h.mu.Unlock()
+
+	h.syncSubscription(conn.WorkspaceID)
+}
+
+func (h *Hub) remove(conn *Conn) {
+	h.mu.Lock()
+	delete(h.conns, conn.ID)
+	delete(h.workspaces[conn.WorkspaceID], conn.ID)
+	if len(h.workspaces[conn.WorkspaceID]) == 0 {
+		delete(h.workspaces, conn.WorkspaceID)
+	}
+	delete(h.users[conn.UserID], conn.ID)
+	if len(h.users[conn.UserID]) == 0 {
+		delete(h.users, conn.UserID)
+	}
+	h.mu.Unlock()
+
+	h.syncSubscription(conn.WorkspaceID)
+}
+
+// syncSubscription subscribes to a workspace's channel while this instance
+// holds connections to it, and unsubscribes once the last one closes
+func (h *Hub) syncSubscription(workspaceID uuid.UUID) {
+	if h.pubsub == nil {
+		return
+	}
+
+	h.subMu.Lock()
+	defer h.subMu.Unlock()
+
+	h.mu.RLock()
+	wanted := len(h.workspaces[workspaceID]) > 0
+	h.mu.RUnlock()
+
+	channel := WorkspaceEventsChannel(workspaceID)
+	_, subscribed := h.subscribed[channel]
+
+	ctx, cancel := context.WithTimeout(context.Background(), registryTimeout)
+	defer cancel()
+
+	switch {
+	case wanted && !subscribed:
+		if err := h.pubsub.Subscribe(ctx, channel); err != nil {
+			h.logger.Error().Err(err).Str("workspace_id", workspaceID.String()).Msg("failed to subscribe to workspace events")
+			return
+		}
+		h.subscribed[channel] = workspaceID
+	case !wanted && subscribed:
+		if err := h.pubsub.Unsubscribe(ctx, channel); err != nil {
+			h.logger.Error().Err(err).Str("workspace_id", workspaceID.String()).Msg("failed to unsubscribe from workspace events")
+			return
+		}
+		delete(h.subscribed, channel)
+	}
+}
@@ -0,0 +1,81 @@
+package realtime_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/config"
+	"github.com/mabhi256/tasker/internal/lib/realtime"
+	testutil "github.com/mabhi256/tasker/internal/testing"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+// TestCloseAPIKey closes the connections opened with a revoked API key,
+// leaving those of the user's session open
+func TestCloseAPIKey(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping realtime tests in short mode")
+	}
+
+	redisClient := redis.NewClient(&redis.Options{Addr: testutil.SetupTestRedis(t)})
+	defer redisClient.Close()
+
+	logger := zerolog.Nop()
+	hub := realtime.NewHub(config.DefaultRealtimeConfig(), redisClient, &logger, nil)
+	hub.Start()
+	defer hub.Stop()
+
+	ctx := context.Background()
+	userID := "user-1"
+	workspaceID := uuid.New()
+	apiKeyID := uuid.NewString()
+
+	connect := func(sessionID, apiKeyID string) *websocket.Conn {
+		srv := httptest.NewServer(hub.Handler(userID, sessionID, apiKeyID, workspaceID))
+		t.Cleanup(srv.Close)
+
+		ws, err := websocket.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), "", srv.URL)
+		require.NoError(t, err)
+		t.Cleanup(func() { ws.Close() })
+		return ws
+	}
+	receive := func(ws *websocket.Conn) (string, error) {
+		require.NoError(t, ws.SetReadDeadline(time.Now().Add(5*time.Second)))
+		var message string
+		err := websocket.Message.Receive(ws, &message)
+		return message, err
+	}
+
+	keyConn := connect("", apiKeyID)
+	sessionConn := connect("sess_1", "")
+
+	require.Eventually(t, func() bool {
+		users, err := hub.Presence(ctx, workspaceID)
+		return err == nil && users[userID] == 2
+	}, 5*time.Second, 50*time.Millisecond)
+
+	err := hub.CloseAPIKey(ctx, userID, apiKeyID, map[string]string{"type": "api_key.revoked"})
+	require.NoError(t, err)
+
+	message, err := receive(keyConn)
+	require.NoError(t, err)
+	assert.Contains(t, message, "api_key.revoked")
+
+	_, err = receive(keyConn)
+	assert.Error(t, err, "the key's connection is closed after the revocation")
+
+	err = hub.SendToUser(ctx, userID, nil, map[string]string{"type": "hello"})
+	require.NoError(t, err)
+
+	message, err = receive(sessionConn)
+	require.NoError(t, err)
+	assert.Contains(t, message, "hello", "the session's connection stays open")
+}
@@ -0,0 +1,241 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// instanceTTL is how long an instance counts as alive after its last
+	// heartbeat. Connections registered by an instance that stopped
+	// heartbeating are ignored and pruned when read.
+	instanceTTL = 30 * time.Second
+	// connectionsTTL expires the connection sets of users and workspaces
+	// nobody is connected to anymore. Instances refresh it on every heartbeat
+	// for the connections they hold.
+	connectionsTTL = 24 * time.Hour
+)
+
+// WorkspaceEventsChannel is the Redis pub/sub channel a workspace's events
+// are published on
+func WorkspaceEventsChannel(workspaceID uuid.UUID) string {
+	return fmt.Sprintf("tasker:workspace:%s:events", workspaceID.String())
+}
+
+// instanceChannel receives the targeted messages for connections held by
+// one instance
+func instanceChannel(instanceID string) string {
+	return fmt.Sprintf("tasker:realtime:instance:%s:messages", instanceID)
+}
+
+func instanceKey(instanceID string) string {
+	return fmt.Sprintf("tasker:realtime:instance:%s", instanceID)
+}
+
+// userConnectionsKey is a hash of connection id to the instance holding it
+func userConnectionsKey(userID string) string {
+	return fmt.Sprintf("tasker:realtime:user:%s:connections", userID)
+}
+
+// workspaceConnectionsKey is a hash of connection id to its registration
+func workspaceConnectionsKey(workspaceID uuid.UUID) string {
+	return fmt.Sprintf("tasker:realtime:workspace:%s:connections", workspaceID.String())
+}
+
+type registration struct {
+	InstanceID string `json:"instance_id"`
+	UserID     string `json:"user_id"`
+}
+
+// registry records in Redis which instance holds each connection, so any
+// instance can route a message to a user or tell who is online
+type registry struct {
+	redis      *redis.Client
+	instanceID string
+}
+
+func (r *registry) register(ctx context.Context, conn *Conn) error {
+	value, err := json.Marshal(&registration{InstanceID: r.instanceID, UserID: conn.UserID})
+	if err != nil {
+		return err
+	}
+
+	userKey := userConnectionsKey(conn.UserID)
+	workspaceKey := workspaceConnectionsKey(conn.WorkspaceID)
+
+	_, err = r.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, userKey, conn.ID, r.instanceID)
+		pipe.Expire(ctx, userKey, connectionsTTL)
+		pipe.HSet(ctx, workspaceKey, conn.ID, value)
+		pipe.Expire(ctx, workspaceKey, connectionsTTL)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to register connection %s: %w", conn.ID, err)
+	}
+
+	return nil
+}
+
+func (r *registry) unregister(ctx context.Context, conn *Conn) error {
+	_, err := r.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HDel(ctx, userConnectionsKey(conn.UserID), conn.ID)
+		pipe.HDel(ctx, workspaceConnectionsKey(conn.WorkspaceID), conn.ID)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to unregister connection %s: %w", conn.ID, err)
+	}
+
+	return nil
+}
+
+// heartbeat marks this instance alive and keeps the connection sets of the
+// given connections from expiring
+func (r *registry) heartbeat(ctx context.Context, conns []*Conn) error {
+	_, err := r.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, instanceKey(r.instanceID), time.Now().Unix(), instanceTTL)
+
+		seen := make(map[string]bool)
+		for _, conn := range conns {
+			for _, key := range []string{userConnectionsKey(conn.UserID), workspaceConnectionsKey(conn.WorkspaceID)} {
+				if !seen[key] {
+					seen[key] = true
+					pipe.Expire(ctx, key, connectionsTTL)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send realtime heartbeat: %w", err)
+	}
+
+	return nil
+}
+
+// leave removes this instance's liveness key, so its connections stop being
+// counted straight away rather than when the key expires
+func (r *registry) leave(ctx context.Context) error {
+	return r.redis.Del(ctx, instanceKey(r.instanceID)).Err()
+}
+
+// userInstances returns the live instances holding connections of a user
+func (r *registry) userInstances(ctx context.Context, userID string) ([]string, error) {
+	key := userConnectionsKey(userID)
+
+	conns, err := r.redis.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read connections of user %s: %w", userID, err)
+	}
+
+	alive, err := r.aliveInstances(ctx, conns)
+	if err != nil {
+		return nil, err
+	}
+
+	var instances []string
+	var dead []string
+	seen := make(map[string]bool)
+	for connID, instanceID := range conns {
+		if !alive[instanceID] {
+			dead = append(dead, connID)
+			continue
+		}
+		if !seen[instanceID] {
+			seen[instanceID] = true
+			instances = append(instances, instanceID)
+		}
+	}
+
+	r.prune(ctx, key, dead)
+
+	return instances, nil
+}
+
+// workspaceUsers returns the users with a live connection to a workspace
+// and how many connections each has
+func (r *registry) workspaceUsers(ctx context.Context, workspaceID uuid.UUID) (map[string]int, error) {
+	key := workspaceConnectionsKey(workspaceID)
+
+	values, err := r.redis.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read connections of workspace %s: %w", workspaceID.String(), err)
+	}
+
+	conns := make(map[string]registration, len(values))
+	instances := make(map[string]string, len(values))
+	var dead []string
+	for connID, value := range values {
+		var reg registration
+		if err := json.Unmarshal([]byte(value), &reg); err != nil {
+			dead = append(dead, connID)
+			continue
+		}
+		conns[connID] = reg
+		instances[connID] = reg.InstanceID
+	}
+
+	alive, err := r.aliveInstances(ctx, instances)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make(map[string]int)
+	for connID, reg := range conns {
+		if !alive[reg.InstanceID] {
+			dead = append(dead, connID)
+			continue
+		}
+		users[reg.UserID]++
+	}
+
+	r.prune(ctx, key, dead)
+
+	return users, nil
+}
+
+// aliveInstances checks which of the instances named in conns are still
+// heartbeating
+func (r *registry) aliveInstances(ctx context.Context, conns map[string]string) (map[string]bool, error) {
+	alive := make(map[string]bool)
+
+	var instanceIDs []string
+	for _, instanceID := range conns {
+		if _, ok := alive[instanceID]; !ok {
+			alive[instanceID] = false
+			instanceIDs = append(instanceIDs, instanceID)
+		}
+	}
+	if len(instanceIDs) == 0 {
+		return alive, nil
+	}
+
+	keys := make([]string, len(instanceIDs))
+	for i, instanceID := range instanceIDs {
+		keys[i] = instanceKey(instanceID)
+	}
+
+	values, err := r.redis.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check realtime instances: %w", err)
+	}
+	for i, value := range values {
+		alive[instanceIDs[i]] = value != nil
+	}
+
+	return alive, nil
+}
+
+// prune drops connections left behind by instances that died. It is best
+// effort, as they are skipped on every read anyway.
+func (r *registry) prune(ctx context.Context, key string, connIDs []string) {
+	if len(connIDs) > 0 {
+		r.redis.HDel(ctx, key, connIDs...)
+	}
+}
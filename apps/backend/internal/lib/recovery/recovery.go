@@ -0,0 +1,67 @@
+// Package recovery turns panics into errors that keep the stack they
+// happened on, so a panic in a request or a task is logged and reported to
+// New Relic like any other error instead of taking the process down.
+package recovery
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// maxDepth bounds the frames kept for New Relic
+const maxDepth = 64
+
+// Error is a recovered panic
+type Error struct {
+	// Value is what was passed to panic
+	Value any
+	// Stack is the formatted stack of the goroutine that panicked
+	Stack []byte
+
+	pcs []uintptr
+}
+
+// New wraps a value recovered from a panic. It has to be called from the
+// deferred function that recovered it, while the stack that panicked is
+// still there to capture.
+func New(value any) *Error {
+	pcs := make([]uintptr, maxDepth)
+	// Skips runtime.Callers and New
+	n := runtime.Callers(2, pcs)
+
+	return &Error{
+		Value: value,
+		Stack: debug.Stack(),
+		pcs:   pcs[:n],
+	}
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the value panicked with when it is an error
+func (e *Error) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// StackTrace is where the panic happened. New Relic reports it as the
+// error's stack, rather than where the error was noticed.
+func (e *Error) StackTrace() []uintptr {
+	return e.pcs
+}
+
+// ErrorClass groups panics apart from other errors in New Relic
+func (e *Error) ErrorClass() string {
+	return "panic"
+}
+
+// As returns the recovered panic in err's chain, if there is one
+func As(err error) (*Error, bool) {
+	var recovered *Error
+	ok := errors.As(err, &recovered)
+	return recovered, ok
+}
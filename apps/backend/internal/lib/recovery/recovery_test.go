@@ -0,0 +1,58 @@
+package recovery_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/mabhi256/tasker/internal/lib/recovery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func explode(value any) {
+	panic(value)
+}
+
+func recovered(value any) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = recovery.New(v)
+		}
+	}()
+	explode(value)
+	return nil
+}
+
+func TestNew(t *testing.T) {
+	err := recovered("boom")
+
+	var recoveredErr *recovery.Error
+	require.ErrorAs(t, err, &recoveredErr)
+	assert.Equal(t, "panic: boom", err.Error())
+	assert.Equal(t, "panic", recoveredErr.ErrorClass())
+
+	// The stack is the one that panicked
+	assert.Contains(t, string(recoveredErr.Stack), "recovery_test.explode")
+	assert.NotEmpty(t, recoveredErr.StackTrace())
+}
+
+func TestUnwrap(t *testing.T) {
+	err := recovered(io.ErrUnexpectedEOF)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	err = recovered(42)
+	assert.Nil(t, errors.Unwrap(err))
+}
+
+func TestAs(t *testing.T) {
+	err := fmt.Errorf("task failed: %w", recovered("boom"))
+
+	recoveredErr, ok := recovery.As(err)
+	require.True(t, ok)
+	assert.Equal(t, "boom", recoveredErr.Value)
+
+	_, ok = recovery.As(io.EOF)
+	assert.False(t, ok)
+}
@@ -0,0 +1,170 @@
+// Package redact removes sensitive values, such as passwords, tokens and
+// email addresses, from request data before it is logged.
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"mime"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Placeholder replaces redacted values
+const Placeholder = "[REDACTED]"
+
+var escapedPlaceholder = url.QueryEscape(Placeholder)
+
+// DefaultFields are always redacted. A field is sensitive when its name,
+// ignoring case, underscores and dashes, contains one of them, so password
+// covers newPassword and token covers refresh_token.
+var DefaultFields = []string{
+	"password",
+	"passwd",
+	"secret",
+	"token",
+	"apikey",
+	"authorization",
+	"cookie",
+	"credential",
+	"email",
+}
+
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+type Redactor struct {
+	fields []string
+}
+
+// New returns a redactor for the default fields and extra
+func New(extra ...string) *Redactor {
+	fields := make([]string, 0, len(DefaultFields)+len(extra))
+	for _, field := range slices.Concat(DefaultFields, extra) {
+		if field = normalize(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return &Redactor{fields: fields}
+}
+
+func normalize(name string) string {
+	name = strings.ToLower(name)
+	name = strings.ReplaceAll(name, "_", "")
+	return strings.ReplaceAll(name, "-", "")
+}
+
+// Sensitive reports whether values of the field named name are redacted
+func (r *Redactor) Sensitive(name string) bool {
+	name = normalize(name)
+	for _, field := range r.fields {
+		if strings.Contains(name, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// String redacts the email addresses in s
+func (r *Redactor) String(s string) string {
+	return emailPattern.ReplaceAllString(s, Placeholder)
+}
+
+// Query redacts the values of sensitive parameters in a raw query string,
+// and the email addresses in the others
+func (r *Redactor) Query(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return Placeholder
+	}
+
+	names := slices.Sorted(maps.Keys(values))
+	params := make([]string, 0, len(values))
+	for _, name := range names {
+		for _, value := range values[name] {
+			if r.Sensitive(name) {
+				value = Placeholder
+			} else {
+				// Escaped, but keeping the placeholder readable
+				value = strings.ReplaceAll(url.QueryEscape(r.String(value)), escapedPlaceholder, Placeholder)
+			}
+			params = append(params, url.QueryEscape(name)+"="+value)
+		}
+	}
+
+	return strings.Join(params, "&")
+}
+
+// Body redacts a request or response body of the content type. JSON and
+// form bodies have their sensitive fields redacted, and text bodies their
+// email addresses. Other bodies, and JSON that doesn't parse, such as a
+// body cut off when it was captured, are summarized rather than logged.
+func (r *Redactor) Body(contentType string, body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "application/octet-stream"
+	}
+
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+
+		var value any
+		if err := decoder.Decode(&value); err != nil {
+			return summarize(mediaType, body)
+		}
+		redacted, err := json.Marshal(r.value(value))
+		if err != nil {
+			return summarize(mediaType, body)
+		}
+		return string(redacted)
+
+	case mediaType == "application/x-www-form-urlencoded":
+		return r.Query(string(body))
+
+	case strings.HasPrefix(mediaType, "text/"):
+		return r.String(string(body))
+
+	default:
+		return summarize(mediaType, body)
+	}
+}
+
+func (r *Redactor) value(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if r.Sensitive(key) {
+				v[key] = Placeholder
+			} else {
+				v[key] = r.value(field)
+			}
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = r.value(item)
+		}
+		return v
+	case string:
+		return r.String(v)
+	default:
+		return v
+	}
+}
+
+func summarize(mediaType string, body []byte) string {
+	return fmt.Sprintf("[%d bytes of %s]", len(body), mediaType)
+}
@@ -0,0 +1,57 @@
+package redact_test
+
+import (
+	"testing"
+
+	"github.com/mabhi256/tasker/internal/lib/redact"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSensitive(t *testing.T) {
+	r := redact.New("phone")
+
+	for _, name := range []string{"password", "newPassword", "refresh_token", "X-API-Key", "Authorization", "userEmail", "phone_number"} {
+		assert.True(t, r.Sensitive(name), name)
+	}
+	for _, name := range []string{"title", "description", "priority", "id"} {
+		assert.False(t, r.Sensitive(name), name)
+	}
+}
+
+func TestBodyJSON(t *testing.T) {
+	r := redact.New()
+
+	body := `{"title":"Call ana@example.com","password":"hunter2","nested":{"accessToken":"abc","count":12345678901234567890},"items":[{"email":"x@y.io"}]}`
+	assert.JSONEq(t,
+		`{"title":"Call [REDACTED]","password":"[REDACTED]","nested":{"accessToken":"[REDACTED]","count":12345678901234567890},"items":[{"email":"[REDACTED]"}]}`,
+		r.Body("application/json; charset=utf-8", []byte(body)))
+}
+
+func TestBodyLeavesOutWhatItCantRedact(t *testing.T) {
+	r := redact.New()
+
+	// Cut off when it was captured
+	truncated := `{"title":"Report","password":"hun`
+	assert.Equal(t, "[33 bytes of application/json]", r.Body("application/json", []byte(truncated)))
+
+	assert.Equal(t, "[4 bytes of image/png]", r.Body("image/png", []byte{0x89, 'P', 'N', 'G'}))
+	assert.Equal(t, "", r.Body("application/json", nil))
+}
+
+func TestBodyFormAndText(t *testing.T) {
+	r := redact.New()
+
+	assert.Equal(t, "password=[REDACTED]&user=bob",
+		r.Body("application/x-www-form-urlencoded", []byte("user=bob&password=hunter2")))
+	assert.Equal(t, "mail [REDACTED] today", r.Body("text/plain", []byte("mail bob@example.com today")))
+}
+
+func TestQuery(t *testing.T) {
+	r := redact.New()
+
+	query := r.Query("q=report+due&token=abc&invite=ana%40example.com")
+	require.NotContains(t, query, "abc")
+	assert.Equal(t, "invite=[REDACTED]&q=report+due&token=[REDACTED]", query)
+	assert.Equal(t, "", r.Query(""))
+}
@@ -0,0 +1,307 @@
+// Package refcache keeps rarely changing reference data, such as workspace
+// policies, in process memory, so requests don't query it again every time.
+//
+// The cache holds a bounded number of entries and evicts the least recently
+// used first. Writers invalidate what they change, which evicts it here and,
+// through a Redis channel, on every other instance. Entries also expire
+// after a TTL, which bounds how stale one can get when an invalidation is
+// missed, such as while the subscription reconnects.
+package refcache
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/config"
+	"github.com/mabhi256/tasker/internal/lib/reqdebug"
+	"github.com/mabhi256/tasker/internal/logging"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	invalidationChannel = "refcache:invalidate"
+	// loadTimeout bounds a load shared by coalesced callers, since it no
+	// longer follows any single caller's context
+	loadTimeout = 10 * time.Second
+)
+
+// invalidation is published for the other instances, which ignore their
+// own
+type invalidation struct {
+	InstanceID string   `json:"instance_id"`
+	Prefixes   []string `json:"prefixes"`
+}
+
+type entry struct {
+	key       string
+	value     any
+	expiresAt time.Time
+}
+
+// Cache is an in-process LRU cache invalidated across instances. Values are
+// shared by every caller, so they must not be modified.
+type Cache struct {
+	cfg           *config.RefCacheConfig
+	redis         *redis.Client
+	logger        *zerolog.Logger
+	loggerService *logging.LoggerService
+	instanceID    string
+	group         singleflight.Group
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+	// generation counts invalidations, so a load that started before one
+	// doesn't store what it read
+	generation uint64
+
+	pubsub *redis.PubSub
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	hits          atomic.Int64
+	misses        atomic.Int64
+	evictions     atomic.Int64
+	invalidations atomic.Int64
+}
+
+// Stats are counted since the process started
+type Stats struct {
+	Entries int
+	Hits    int64
+	Misses  int64
+	// Evictions counts entries dropped to make room for others
+	Evictions int64
+	// Invalidations counts invalidations received, made here or elsewhere
+	Invalidations int64
+}
+
+// New returns a cache that invalidates other instances through Redis. With
+// a nil client invalidations only reach this instance.
+func New(cfg *config.RefCacheConfig, redisClient *redis.Client, logger *zerolog.Logger,
+	loggerService *logging.LoggerService,
+) *Cache {
+	return &Cache{
+		cfg:           cfg,
+		redis:         redisClient,
+		logger:        logger,
+		loggerService: loggerService,
+		instanceID:    uuid.New().String(),
+		entries:       make(map[string]*list.Element),
+		order:         list.New(),
+	}
+}
+
+// Start subscribes to the invalidations of other instances
+func (c *Cache) Start() {
+	if c.redis == nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.done = make(chan struct{})
+	c.pubsub = c.redis.Subscribe(ctx, invalidationChannel)
+
+	go c.run(ctx)
+}
+
+func (c *Cache) Stop() {
+	if c.cancel == nil {
+		return
+	}
+
+	c.cancel()
+	<-c.done
+	c.pubsub.Close()
+}
+
+func (c *Cache) run(ctx context.Context) {
+	defer close(c.done)
+
+	messages := c.pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+
+			var inv invalidation
+			if err := json.Unmarshal([]byte(msg.Payload), &inv); err != nil {
+				c.logger.Error().Err(err).Msg("failed to decode reference cache invalidation")
+				continue
+			}
+			if inv.InstanceID != c.instanceID {
+				c.evict(inv.Prefixes)
+			}
+		}
+	}
+}
+
+// GetOrLoad returns the cached value for key, calling load on a miss.
+// Callers that miss while a load for the same key is running wait for it
+// instead of loading again.
+func GetOrLoad[T any](ctx context.Context, c *Cache, key string,
+	load func(ctx context.Context) (T, error),
+) (T, error) {
+	var zero T
+
+	value, generation, ok := c.get(key)
+	if ok {
+		if typed, ok := value.(T); ok {
+			c.hits.Add(1)
+			c.recordMetric("Hit")
+			reqdebug.FromContext(ctx).CacheHit()
+			return typed, nil
+		}
+	}
+
+	c.misses.Add(1)
+	c.recordMetric("Miss")
+	reqdebug.FromContext(ctx).CacheMiss()
+
+	// Callers arriving after an invalidation start a load of their own
+	// rather than wait for one that may have read the invalidated data
+	ch := c.group.DoChan(key+"@"+strconv.FormatUint(generation, 10), func() (any, error) {
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), loadTimeout)
+		defer cancel()
+
+		value, err := load(loadCtx)
+		if err != nil {
+			return nil, err
+		}
+
+		c.set(key, value, generation)
+		return value, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case result := <-ch:
+		if result.Err != nil {
+			return zero, result.Err
+		}
+		return result.Val.(T), nil
+	}
+}
+
+// Invalidate evicts every entry whose key starts with one of the prefixes,
+// on this instance and the others. A key is its own prefix.
+func (c *Cache) Invalidate(ctx context.Context, prefixes ...string) error {
+	c.evict(prefixes)
+
+	if c.redis == nil {
+		return nil
+	}
+
+	raw, err := json.Marshal(invalidation{InstanceID: c.instanceID, Prefixes: prefixes})
+	if err != nil {
+		return fmt.Errorf("failed to encode reference cache invalidation: %w", err)
+	}
+	if err := c.redis.Publish(ctx, invalidationChannel, raw).Err(); err != nil {
+		return fmt.Errorf("failed to publish reference cache invalidation: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	entries := c.order.Len()
+	c.mu.Unlock()
+
+	return Stats{
+		Entries:       entries,
+		Hits:          c.hits.Load(),
+		Misses:        c.misses.Load(),
+		Evictions:     c.evictions.Load(),
+		Invalidations: c.invalidations.Load(),
+	}
+}
+
+// get returns the live entry for key, and the generation a load for it
+// must be stored with
+func (c *Cache) get(key string) (any, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, c.generation, false
+	}
+
+	e := elem.Value.(*entry)
+	if time.Now().After(e.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return nil, c.generation, false
+	}
+
+	c.order.MoveToFront(elem)
+	return e.value, c.generation, true
+}
+
+func (c *Cache) set(key string, value any, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	expiresAt := time.Now().Add(c.cfg.TTL)
+	if elem, ok := c.entries[key]; ok {
+		e := elem.Value.(*entry)
+		e.value = value
+		e.expiresAt = expiresAt
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&entry{key: key, value: value, expiresAt: expiresAt})
+
+	for c.order.Len() > c.cfg.Size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry).key)
+		c.evictions.Add(1)
+	}
+}
+
+func (c *Cache) evict(prefixes []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.invalidations.Add(1)
+
+	for key, elem := range c.entries {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(key, prefix) {
+				c.order.Remove(elem)
+				delete(c.entries, key)
+				break
+			}
+		}
+	}
+}
+
+func (c *Cache) recordMetric(outcome string) {
+	if c.loggerService != nil && c.loggerService.GetApplication() != nil {
+		c.loggerService.GetApplication().RecordCustomMetric("Custom/RefCache/"+outcome, 1)
+	}
+}
@@ -0,0 +1,139 @@
+package refcache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mabhi256/tasker/internal/config"
+	"github.com/mabhi256/tasker/internal/lib/refcache"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCache(size int, ttl time.Duration) *refcache.Cache {
+	logger := zerolog.Nop()
+	return refcache.New(&config.RefCacheConfig{Size: size, TTL: ttl}, nil, &logger, nil)
+}
+
+// counter returns a loader of the key that counts its calls
+func counter(loads *int) func(ctx context.Context) (int, error) {
+	return func(ctx context.Context) (int, error) {
+		*loads++
+		return *loads, nil
+	}
+}
+
+func TestGetOrLoad(t *testing.T) {
+	ctx := context.Background()
+	c := newCache(10, time.Minute)
+
+	loads := 0
+	for range 3 {
+		v, err := refcache.GetOrLoad(ctx, c, "a", counter(&loads))
+		require.NoError(t, err)
+		assert.Equal(t, 1, v)
+	}
+	assert.Equal(t, 1, loads)
+
+	stats := c.Stats()
+	assert.Equal(t, 1, stats.Entries)
+	assert.Equal(t, int64(2), stats.Hits)
+	assert.Equal(t, int64(1), stats.Misses)
+}
+
+func TestEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c := newCache(2, time.Minute)
+
+	loads := map[string]int{}
+	get := func(key string) {
+		n := loads[key]
+		_, err := refcache.GetOrLoad(ctx, c, key, counter(&n))
+		require.NoError(t, err)
+		loads[key] = n
+	}
+
+	get("a")
+	get("b")
+	get("a")
+	// b is the least recently used, so c takes its place
+	get("c")
+	get("a")
+	get("b")
+
+	assert.Equal(t, map[string]int{"a": 1, "b": 2, "c": 1}, loads)
+	assert.Equal(t, 2, c.Stats().Entries)
+	assert.Equal(t, int64(2), c.Stats().Evictions)
+}
+
+func TestExpires(t *testing.T) {
+	ctx := context.Background()
+	c := newCache(10, 10*time.Millisecond)
+
+	loads := 0
+	_, err := refcache.GetOrLoad(ctx, c, "a", counter(&loads))
+	require.NoError(t, err)
+
+	time.Sleep(20 * time.Millisecond)
+
+	v, err := refcache.GetOrLoad(ctx, c, "a", counter(&loads))
+	require.NoError(t, err)
+	assert.Equal(t, 2, v)
+}
+
+func TestInvalidate(t *testing.T) {
+	ctx := context.Background()
+	c := newCache(10, time.Minute)
+
+	loads := map[string]int{}
+	get := func(key string) int {
+		n := loads[key]
+		v, err := refcache.GetOrLoad(ctx, c, key, counter(&n))
+		require.NoError(t, err)
+		loads[key] = n
+		return v
+	}
+
+	get("categories:ws-1:page-1")
+	get("categories:ws-1:page-2")
+	get("categories:ws-2:page-1")
+
+	require.NoError(t, c.Invalidate(ctx, "categories:ws-1:"))
+
+	assert.Equal(t, 2, get("categories:ws-1:page-1"))
+	assert.Equal(t, 2, get("categories:ws-1:page-2"))
+	assert.Equal(t, 1, get("categories:ws-2:page-1"))
+}
+
+func TestInvalidateDuringLoad(t *testing.T) {
+	ctx := context.Background()
+	c := newCache(10, time.Minute)
+
+	// The load reads before the invalidation and returns after it
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan int)
+	go func() {
+		v, err := refcache.GetOrLoad(ctx, c, "a", func(ctx context.Context) (int, error) {
+			close(started)
+			<-release
+			return 1, nil
+		})
+		assert.NoError(t, err)
+		done <- v
+	}()
+
+	<-started
+	require.NoError(t, c.Invalidate(ctx, "a"))
+	close(release)
+	assert.Equal(t, 1, <-done)
+
+	// What it read isn't cached
+	v, err := refcache.GetOrLoad(ctx, c, "a", func(ctx context.Context) (int, error) {
+		return 2, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, v)
+}
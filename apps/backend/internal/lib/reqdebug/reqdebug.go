@@ -0,0 +1,132 @@
+// Package reqdebug measures what a single request costs, for the debug
+// reports admins ask for to diagnose slow endpoints. Work is counted by the
+// Budget carried in its context; without one nothing is counted, so
+// ordinary requests only pay for a context lookup.
+package reqdebug
+
+import (
+	"context"
+	"fmt"
+	"runtime/metrics"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// heapAllocsMetric counts the bytes allocated on the heap by the process
+const heapAllocsMetric = "/gc/heap/allocs:bytes"
+
+type budgetKey struct{}
+
+// Budget counts the work done for a request. Queries may run concurrently,
+// so the counters are atomic.
+type Budget struct {
+	start       time.Time
+	startAllocs uint64
+
+	queries     atomic.Int64
+	dbTime      atomic.Int64 // nanoseconds
+	cacheHits   atomic.Int64
+	cacheMisses atomic.Int64
+}
+
+// Report is what a request cost up to the moment it was taken
+type Report struct {
+	TotalMs     float64 `json:"totalMs"`
+	DBMs        float64 `json:"dbMs"`
+	Queries     int64   `json:"queries"`
+	CacheHits   int64   `json:"cacheHits"`
+	CacheMisses int64   `json:"cacheMisses"`
+	// AllocBytes is what the whole process allocated meanwhile, so it is
+	// only an estimate when other requests are running
+	AllocBytes uint64 `json:"allocBytes"`
+}
+
+// WithBudget starts counting the work done with the returned context
+func WithBudget(ctx context.Context) (context.Context, *Budget) {
+	b := &Budget{
+		start:       time.Now(),
+		startAllocs: heapAllocs(),
+	}
+	return context.WithValue(ctx, budgetKey{}, b), b
+}
+
+// FromContext returns the budget work done with ctx is counted by, or nil
+func FromContext(ctx context.Context) *Budget {
+	b, _ := ctx.Value(budgetKey{}).(*Budget)
+	return b
+}
+
+func (b *Budget) AddQuery(d time.Duration) {
+	if b == nil {
+		return
+	}
+	b.queries.Add(1)
+	b.dbTime.Add(int64(d))
+}
+
+func (b *Budget) CacheHit() {
+	if b != nil {
+		b.cacheHits.Add(1)
+	}
+}
+
+func (b *Budget) CacheMiss() {
+	if b != nil {
+		b.cacheMisses.Add(1)
+	}
+}
+
+func (b *Budget) Report() Report {
+	return Report{
+		TotalMs:     milliseconds(time.Since(b.start)),
+		DBMs:        milliseconds(time.Duration(b.dbTime.Load())),
+		Queries:     b.queries.Load(),
+		CacheHits:   b.cacheHits.Load(),
+		CacheMisses: b.cacheMisses.Load(),
+		AllocBytes:  heapAllocs() - b.startAllocs,
+	}
+}
+
+// ServerTiming formats the report as a Server-Timing header value, which
+// browser developer tools show with the request
+func (r Report) ServerTiming() string {
+	return fmt.Sprintf(`total;dur=%.2f, db;dur=%.2f;desc="%d queries"`, r.TotalMs, r.DBMs, r.Queries)
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func heapAllocs() uint64 {
+	sample := []metrics.Sample{{Name: heapAllocsMetric}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
+
+type queryStartKey struct{}
+
+// QueryTracer returns a pgx tracer that counts each query against the
+// budget in its context
+func QueryTracer() pgx.QueryTracer {
+	return queryTracer{}
+}
+
+type queryTracer struct{}
+
+func (queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryStartData) context.Context {
+	if FromContext(ctx) == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, queryStartKey{}, time.Now())
+}
+
+func (queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryEndData) {
+	if start, ok := ctx.Value(queryStartKey{}).(time.Time); ok {
+		FromContext(ctx).AddQuery(time.Since(start))
+	}
+}
@@ -0,0 +1,43 @@
+package reqdebug_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mabhi256/tasker/internal/lib/reqdebug"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudget(t *testing.T) {
+	ctx, budget := reqdebug.WithBudget(context.Background())
+	assert.Same(t, budget, reqdebug.FromContext(ctx))
+
+	reqdebug.FromContext(ctx).AddQuery(2 * time.Millisecond)
+	reqdebug.FromContext(ctx).AddQuery(3 * time.Millisecond)
+	reqdebug.FromContext(ctx).CacheHit()
+	reqdebug.FromContext(ctx).CacheMiss()
+	reqdebug.FromContext(ctx).CacheMiss()
+
+	report := budget.Report()
+	assert.Equal(t, int64(2), report.Queries)
+	assert.InDelta(t, 5.0, report.DBMs, 0.001)
+	assert.Equal(t, int64(1), report.CacheHits)
+	assert.Equal(t, int64(2), report.CacheMisses)
+	assert.GreaterOrEqual(t, report.TotalMs, 0.0)
+	assert.True(t, strings.HasPrefix(report.ServerTiming(), "total;dur="))
+	assert.Contains(t, report.ServerTiming(), `db;dur=5.00;desc="2 queries"`)
+}
+
+func TestWithoutBudget(t *testing.T) {
+	ctx := context.Background()
+	assert.Nil(t, reqdebug.FromContext(ctx))
+
+	// Counting without a budget does nothing
+	assert.NotPanics(t, func() {
+		reqdebug.FromContext(ctx).AddQuery(time.Millisecond)
+		reqdebug.FromContext(ctx).CacheHit()
+		reqdebug.FromContext(ctx).CacheMiss()
+	})
+}
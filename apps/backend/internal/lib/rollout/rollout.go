@@ -0,0 +1,218 @@
+// Package rollout supports expand/contract schema changes, which change a
+// large table without locking it or breaking instances still running the
+// previous release. A change ships in three steps:
+//
+//  1. An expand migration adds the new column, and a trigger that writes it
+//     whenever a row is written, so new and updated rows are covered from
+//     then on (dual write).
+//  2. The rollout's backfill fills in the existing rows in small batches,
+//     recording how far it got so it resumes where it stopped.
+//  3. A contract migration, whose first line is "-- rollout:contract <name>",
+//     drops the trigger and the old column. Migrate holds it back until no
+//     rows are left pending.
+package rollout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// contractMarker starts the first line of a contract migration
+const contractMarker = "-- rollout:contract "
+
+var (
+	namePattern  = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+	tablePattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+)
+
+// Rollout describes the backfill of an expand/contract change. Pending and
+// Set are SQL fragments written against Table and are trusted, as they only
+// come from code.
+type Rollout struct {
+	// Name identifies the rollout in contract migrations and in its progress
+	Name string
+	// Table is walked in batches in order of its UUID id column
+	Table string
+	// Pending matches the rows that still need the backfill, for example
+	// "title_search IS NULL"
+	Pending string
+	// Set is the SET clause that backfills a row, for example
+	// "title_search = to_tsvector('simple', title)"
+	Set string
+}
+
+func (r *Rollout) Validate() error {
+	if !namePattern.MatchString(r.Name) {
+		return fmt.Errorf("invalid rollout name %q", r.Name)
+	}
+	if !tablePattern.MatchString(r.Table) {
+		return fmt.Errorf("invalid table %q for rollout %s", r.Table, r.Name)
+	}
+	if strings.TrimSpace(r.Pending) == "" || strings.TrimSpace(r.Set) == "" {
+		return fmt.Errorf("rollout %s needs both a pending condition and a SET clause", r.Name)
+	}
+	return nil
+}
+
+// Querier runs statements on a pool, connection or transaction
+type Querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// BatchStatement backfills up to @batch_size pending rows with ids after
+// @after. It returns how many rows it updated and the last id it reached,
+// which is NULL once no pending rows are left after @after.
+func (r *Rollout) BatchStatement() string {
+	return fmt.Sprintf(`
+		WITH
+			batch AS (
+				SELECT
+					id
+				FROM
+					%[1]s
+				WHERE
+					id > @after
+					AND (%[2]s)
+				ORDER BY
+					id
+				LIMIT
+					@batch_size
+			),
+			updated AS (
+				UPDATE %[1]s
+				SET
+					%[3]s
+				WHERE
+					id IN (
+						SELECT
+							id
+						FROM
+							batch
+					)
+				RETURNING
+					1
+			)
+		SELECT
+			(
+				SELECT
+					COUNT(*)
+				FROM
+					updated
+			),
+			(
+				SELECT
+					id
+				FROM
+					batch
+				ORDER BY
+					id DESC
+				LIMIT
+					1
+			)
+	`, r.Table, r.Pending, r.Set)
+}
+
+// PendingStatement counts the rows that still need the backfill
+func (r *Rollout) PendingStatement() string {
+	return fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE (%s)`, r.Table, r.Pending)
+}
+
+// RunBatch backfills the next batch after the given id. last is nil when
+// there was nothing left to backfill after it.
+func (r *Rollout) RunBatch(ctx context.Context, q Querier, after uuid.UUID, batchSize int) (int64, *uuid.UUID, error) {
+	var updated int64
+	var last *uuid.UUID
+	err := q.QueryRow(ctx, r.BatchStatement(), pgx.NamedArgs{
+		"after":      after,
+		"batch_size": batchSize,
+	}).Scan(&updated, &last)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to backfill batch of rollout %s after id=%s: %w", r.Name, after, err)
+	}
+
+	return updated, last, nil
+}
+
+// CountPending is the contract check: the rollout may be contracted once it
+// returns zero
+func (r *Rollout) CountPending(ctx context.Context, q Querier) (int64, error) {
+	var pending int64
+	if err := q.QueryRow(ctx, r.PendingStatement()).Scan(&pending); err != nil {
+		return 0, fmt.Errorf("failed to count pending rows of rollout %s: %w", r.Name, err)
+	}
+	return pending, nil
+}
+
+// ContractOf returns the rollout a migration contracts, when its first line
+// marks it as a contract migration
+func ContractOf(sql string) (string, bool) {
+	line, _, _ := strings.Cut(strings.TrimSpace(sql), "\n")
+	name, ok := strings.CutPrefix(strings.TrimSpace(line), contractMarker)
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return "", false
+	}
+	return name, true
+}
+
+// Registry holds the rollouts the application knows how to backfill
+type Registry struct {
+	rollouts map[string]*Rollout
+}
+
+// NewRegistry checks each rollout and that no two share a name
+func NewRegistry(rollouts ...Rollout) (*Registry, error) {
+	registry := &Registry{rollouts: make(map[string]*Rollout, len(rollouts))}
+
+	var errList []error
+	for i := range rollouts {
+		r := &rollouts[i]
+		if err := r.Validate(); err != nil {
+			errList = append(errList, err)
+			continue
+		}
+		if _, ok := registry.rollouts[r.Name]; ok {
+			errList = append(errList, fmt.Errorf("rollout %s is defined twice", r.Name))
+			continue
+		}
+		registry.rollouts[r.Name] = r
+	}
+
+	if err := errors.Join(errList...); err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
+
+// MustRegistry is NewRegistry for rollouts declared in code
+func MustRegistry(rollouts ...Rollout) *Registry {
+	registry, err := NewRegistry(rollouts...)
+	if err != nil {
+		panic(err)
+	}
+	return registry
+}
+
+func (r *Registry) Get(name string) (*Rollout, bool) {
+	rollout, ok := r.rollouts[name]
+	return rollout, ok
+}
+
+// List returns the rollouts ordered by name
+func (r *Registry) List() []*Rollout {
+	rollouts := make([]*Rollout, 0, len(r.rollouts))
+	for _, rollout := range r.rollouts {
+		rollouts = append(rollouts, rollout)
+	}
+	sort.Slice(rollouts, func(i, j int) bool {
+		return rollouts[i].Name < rollouts[j].Name
+	})
+	return rollouts
+}
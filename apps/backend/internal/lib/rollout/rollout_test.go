@@ -0,0 +1,99 @@
+package rollout_test
+
+import (
+	"testing"
+
+	"github.com/mabhi256/tasker/internal/lib/rollout"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func titleSearch() rollout.Rollout {
+	return rollout.Rollout{
+		Name:    "todo_title_search",
+		Table:   "todos",
+		Pending: "title_search IS NULL",
+		Set:     "title_search = to_tsvector('simple', title)",
+	}
+}
+
+func TestContractOf(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want string
+		ok   bool
+	}{
+		{"marker", "-- rollout:contract todo_title_search\nALTER TABLE todos DROP COLUMN title_tsv;", "todo_title_search", true},
+		{"leading blank lines", "\n\n-- rollout:contract todo_title_search  \r\nDROP TRIGGER x ON todos;", "todo_title_search", true},
+		{"no name", "-- rollout:contract\nDROP TRIGGER x ON todos;", "", false},
+		{"not first line", "ALTER TABLE todos DROP COLUMN x;\n-- rollout:contract todo_title_search", "", false},
+		{"ordinary migration", "CREATE TABLE things (id UUID PRIMARY KEY);", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, ok := rollout.ContractOf(tt.sql)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, name)
+		})
+	}
+}
+
+func TestBatchStatement(t *testing.T) {
+	r := titleSearch()
+	stmt := r.BatchStatement()
+
+	assert.Contains(t, stmt, "FROM\n\t\t\t\t\ttodos")
+	assert.Contains(t, stmt, "AND (title_search IS NULL)")
+	assert.Contains(t, stmt, "title_search = to_tsvector('simple', title)")
+	assert.Contains(t, stmt, "id > @after")
+	assert.Contains(t, stmt, "@batch_size")
+
+	assert.Equal(t, "SELECT COUNT(*) FROM todos WHERE (title_search IS NULL)", r.PendingStatement())
+}
+
+func TestNewRegistry(t *testing.T) {
+	second := titleSearch()
+	second.Name = "comment_body"
+	second.Table = "todo_comments"
+
+	registry, err := rollout.NewRegistry(titleSearch(), second)
+	require.NoError(t, err)
+
+	r, ok := registry.Get("todo_title_search")
+	require.True(t, ok)
+	assert.Equal(t, "todos", r.Table)
+
+	_, ok = registry.Get("missing")
+	assert.False(t, ok)
+
+	list := registry.List()
+	require.Len(t, list, 2)
+	assert.Equal(t, "comment_body", list[0].Name)
+	assert.Equal(t, "todo_title_search", list[1].Name)
+}
+
+func TestNewRegistryRejectsInvalidRollouts(t *testing.T) {
+	badName := titleSearch()
+	badName.Name = "Title Search"
+
+	badTable := titleSearch()
+	badTable.Name = "bad_table"
+	badTable.Table = "todos; DROP TABLE todos"
+
+	noSet := titleSearch()
+	noSet.Name = "no_set"
+	noSet.Set = " "
+
+	for _, r := range []rollout.Rollout{badName, badTable, noSet} {
+		_, err := rollout.NewRegistry(r)
+		assert.Error(t, err, r.Name)
+	}
+
+	_, err := rollout.NewRegistry(titleSearch(), titleSearch())
+	assert.ErrorContains(t, err, "defined twice")
+
+	assert.Panics(t, func() { rollout.MustRegistry(badName) })
+	assert.NotPanics(t, func() { rollout.MustRegistry() })
+}
@@ -0,0 +1,158 @@
+// Package scanner scans uploaded files for malware, through a ClamAV daemon
+// or not at all. The scanner in use is chosen by the config.
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/mabhi256/tasker/internal/config"
+)
+
+// ErrTooLarge is returned for files larger than the scanner accepts, which
+// scanning again won't change
+var ErrTooLarge = errors.New("file is too large to scan")
+
+// Result is the verdict on one file
+type Result struct {
+	// Scanned is false when no scanner looked at the file
+	Scanned  bool
+	Infected bool
+	// Signature names what was found in an infected file
+	Signature string
+}
+
+type Scanner interface {
+	// Scan reads the file to its end and returns the verdict. An error means
+	// the file couldn't be scanned.
+	Scan(ctx context.Context, r io.Reader) (*Result, error)
+}
+
+// New returns the scanner selected by the config
+func New(cfg *config.AttachmentScanConfig) Scanner {
+	if cfg == nil || cfg.Provider != config.ScanProviderClamAV {
+		return Nop{}
+	}
+	return NewClamAV(cfg.Address, cfg.Timeout)
+}
+
+// Nop scans nothing, leaving every file unscanned
+type Nop struct{}
+
+func (Nop) Scan(ctx context.Context, r io.Reader) (*Result, error) {
+	return &Result{}, nil
+}
+
+// chunkSize is how much of the file goes in each INSTREAM chunk
+const chunkSize = 64 << 10
+
+// ClamAV streams files to clamd over TCP with the INSTREAM command
+type ClamAV struct {
+	address string
+	timeout time.Duration
+	dialer  net.Dialer
+}
+
+// NewClamAV returns a scanner for the clamd listening on address. Timeout
+// bounds each scan, including the connection.
+func NewClamAV(address string, timeout time.Duration) *ClamAV {
+	return &ClamAV{address: address, timeout: timeout}
+}
+
+func (c *ClamAV) Scan(ctx context.Context, r io.Reader) (*Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	conn, err := c.dialer.DialContext(ctx, "tcp", c.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if err := stream(conn, r); err != nil {
+		// clamd replies and hangs up when the stream breaks its limits, which
+		// explains the failed write better
+		if reply, replyErr := readReply(conn); replyErr == nil && reply != "" {
+			return parseReply(reply)
+		}
+		return nil, err
+	}
+
+	reply, err := readReply(conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+	return parseReply(reply)
+}
+
+// stream sends the INSTREAM command and the file as length-prefixed chunks,
+// ending with an empty chunk
+func stream(conn net.Conn, r io.Reader) error {
+	if _, err := io.WriteString(conn, "zINSTREAM\x00"); err != nil {
+		return fmt.Errorf("failed to send clamd command: %w", err)
+	}
+
+	buf := make([]byte, 4+chunkSize)
+	for {
+		n, err := io.ReadFull(r, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf, uint32(n))
+			if _, werr := conn.Write(buf[:4+n]); werr != nil {
+				return fmt.Errorf("failed to stream file to clamd: %w", werr)
+			}
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+	}
+
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return fmt.Errorf("failed to end clamd stream: %w", err)
+	}
+	return nil
+}
+
+// readReply reads the reply to a z-prefixed command, which ends with a NUL
+func readReply(conn net.Conn) (string, error) {
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && !(errors.Is(err, io.EOF) && reply != "") {
+		return "", err
+	}
+	return strings.TrimSpace(strings.TrimSuffix(reply, "\x00")), nil
+}
+
+// parseReply reads a reply such as "stream: OK",
+// "stream: Eicar-Signature FOUND" or "INSTREAM size limit exceeded. ERROR"
+func parseReply(reply string) (*Result, error) {
+	msg := strings.TrimPrefix(reply, "stream: ")
+
+	switch {
+	case msg == "OK":
+		return &Result{Scanned: true}, nil
+	case strings.HasSuffix(msg, " FOUND"):
+		return &Result{
+			Scanned:   true,
+			Infected:  true,
+			Signature: strings.TrimSuffix(msg, " FOUND"),
+		}, nil
+	case strings.Contains(msg, "size limit exceeded"):
+		return nil, fmt.Errorf("clamd: %s: %w", msg, ErrTooLarge)
+	case strings.HasSuffix(msg, " ERROR"):
+		return nil, fmt.Errorf("clamd: %s", strings.TrimSuffix(msg, " ERROR"))
+	default:
+		return nil, fmt.Errorf("unexpected clamd reply: %q", reply)
+	}
+}
@@ -0,0 +1,138 @@
+package scanner_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mabhi256/tasker/internal/config"
+	"github.com/mabhi256/tasker/internal/lib/scanner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eicar is the standard antivirus test file
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// fakeClamd serves INSTREAM like clamd, finding eicar in streams that
+// contain it and refusing streams over maxStream bytes. It returns its
+// address.
+func fakeClamd(t *testing.T, maxStream int) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveClamd(conn, maxStream)
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+func serveClamd(conn net.Conn, maxStream int) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	command, err := r.ReadString(0)
+	if err != nil || command != "zINSTREAM\x00" {
+		_, _ = io.WriteString(conn, "UNKNOWN COMMAND\x00")
+		return
+	}
+
+	var stream bytes.Buffer
+	for {
+		var size uint32
+		if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+			return
+		}
+		if size == 0 {
+			break
+		}
+		if stream.Len()+int(size) > maxStream {
+			_, _ = io.WriteString(conn, "INSTREAM size limit exceeded. ERROR\x00")
+			return
+		}
+		if _, err := io.CopyN(&stream, r, int64(size)); err != nil {
+			return
+		}
+	}
+
+	if strings.Contains(stream.String(), eicar) {
+		_, _ = io.WriteString(conn, "stream: Win.Test.EICAR_HDB-1 FOUND\x00")
+		return
+	}
+	_, _ = io.WriteString(conn, "stream: OK\x00")
+}
+
+func TestClamAV(t *testing.T) {
+	clamav := scanner.NewClamAV(fakeClamd(t, 1<<20), 5*time.Second)
+
+	// Large enough to span chunks, with the signature across a boundary
+	infected := strings.Repeat("a", 64<<10-10) + eicar
+
+	tests := []struct {
+		name      string
+		file      string
+		infected  bool
+		signature string
+	}{
+		{name: "clean", file: "just some notes"},
+		{name: "empty", file: ""},
+		{name: "infected", file: eicar, infected: true, signature: "Win.Test.EICAR_HDB-1"},
+		{name: "infected across chunks", file: infected, infected: true, signature: "Win.Test.EICAR_HDB-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := clamav.Scan(context.Background(), strings.NewReader(tt.file))
+			require.NoError(t, err)
+			assert.True(t, result.Scanned)
+			assert.Equal(t, tt.infected, result.Infected)
+			assert.Equal(t, tt.signature, result.Signature)
+		})
+	}
+}
+
+func TestClamAVTooLarge(t *testing.T) {
+	clamav := scanner.NewClamAV(fakeClamd(t, 100<<10), 5*time.Second)
+
+	_, err := clamav.Scan(context.Background(), bytes.NewReader(make([]byte, 1<<20)))
+	assert.ErrorIs(t, err, scanner.ErrTooLarge)
+}
+
+func TestClamAVUnreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := ln.Addr().String()
+	ln.Close()
+
+	_, err = scanner.NewClamAV(address, time.Second).Scan(context.Background(), strings.NewReader("notes"))
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, scanner.ErrTooLarge)
+}
+
+func TestNew(t *testing.T) {
+	cfg := config.DefaultAttachmentScanConfig()
+	assert.IsType(t, scanner.Nop{}, scanner.New(cfg))
+
+	result, err := scanner.New(cfg).Scan(context.Background(), strings.NewReader(eicar))
+	require.NoError(t, err)
+	assert.False(t, result.Scanned)
+	assert.False(t, result.Infected)
+
+	cfg.Provider = config.ScanProviderClamAV
+	assert.IsType(t, &scanner.ClamAV{}, scanner.New(cfg))
+}
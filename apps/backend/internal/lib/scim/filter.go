@@ -0,0 +1,57 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Filter is an equality filter, like userName eq "ada@example.com". It is
+// the only kind identity providers send when provisioning, to look up a
+// resource before creating it.
+type Filter struct {
+	Attribute string
+	Value     string
+}
+
+// Is reports whether the filter is on attribute. Attribute names are case
+// insensitive.
+func (f *Filter) Is(attribute string) bool {
+	return strings.EqualFold(f.Attribute, attribute)
+}
+
+// ParseFilter parses an equality filter on a string attribute. Other
+// operators and logical expressions are rejected as invalidFilter.
+func ParseFilter(raw string) (*Filter, error) {
+	raw = strings.TrimSpace(raw)
+
+	attribute, rest, ok := strings.Cut(raw, " ")
+	if !ok || attribute == "" {
+		return nil, invalidFilter("expected an expression like userName eq \"value\"")
+	}
+
+	operator, value, ok := strings.Cut(strings.TrimLeft(rest, " "), " ")
+	if !ok {
+		return nil, invalidFilter("expected an expression like userName eq \"value\"")
+	}
+	if !strings.EqualFold(operator, "eq") {
+		return nil, invalidFilter("only the eq operator is supported")
+	}
+
+	value = strings.TrimSpace(value)
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return nil, invalidFilter("the value must be a quoted string")
+	}
+
+	// SCIM strings are JSON strings, with the same escapes
+	var unquoted string
+	if err := json.Unmarshal([]byte(value), &unquoted); err != nil {
+		return nil, invalidFilter("the value must be a quoted string")
+	}
+
+	return &Filter{Attribute: attribute, Value: unquoted}, nil
+}
+
+func invalidFilter(detail string) *Error {
+	return NewError(http.StatusBadRequest, ErrorTypeInvalidFilter, "Unsupported filter: "+detail)
+}
@@ -0,0 +1,313 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// PatchRequest is the body of a PATCH request
+type PatchRequest struct {
+	Schemas    []string    `json:"schemas"`
+	Operations []Operation `json:"Operations"`
+}
+
+// Operation is one change of a PATCH request. Without a path, value is an
+// object of the attributes to change.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+const (
+	opAdd     = "add"
+	opRemove  = "remove"
+	opReplace = "replace"
+)
+
+func (r *PatchRequest) Validate() error {
+	if !slices.Contains(r.Schemas, SchemaPatchOp) {
+		return NewError(http.StatusBadRequest, ErrorTypeInvalidSyntax, "PATCH requests must use the PatchOp schema")
+	}
+	if len(r.Operations) == 0 {
+		return NewError(http.StatusBadRequest, ErrorTypeInvalidSyntax, "PATCH requests need at least one operation")
+	}
+	for _, op := range r.Operations {
+		switch strings.ToLower(op.Op) {
+		case opAdd, opReplace:
+			if len(op.Value) == 0 {
+				return invalidValue(op.Op + " operations need a value")
+			}
+		case opRemove:
+			if op.Path == "" {
+				return NewError(http.StatusBadRequest, "noTarget", "remove operations need a path")
+			}
+		default:
+			return NewError(http.StatusBadRequest, ErrorTypeInvalidSyntax, "unknown operation "+op.Op)
+		}
+	}
+	return nil
+}
+
+// ApplyToUser applies the operations to u. Attributes that aren't stored,
+// like those of schema extensions, are ignored so identity providers can
+// send their usual mappings.
+func (r *PatchRequest) ApplyToUser(u *User) error {
+	for _, op := range r.Operations {
+		verb := strings.ToLower(op.Op)
+
+		if verb == opRemove {
+			if err := removeUserAttribute(u, op.Path); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if op.Path != "" {
+			if err := setUserAttribute(u, verb, op.Path, op.Value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var values map[string]json.RawMessage
+		if err := json.Unmarshal(op.Value, &values); err != nil {
+			return invalidValue("operations without a path need an object value")
+		}
+		for path, value := range values {
+			if err := setUserAttribute(u, verb, path, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func setUserAttribute(u *User, verb string, path string, value json.RawMessage) error {
+	lower := strings.ToLower(path)
+
+	switch {
+	case lower == "active":
+		active, err := decodeBool(value)
+		if err != nil {
+			return err
+		}
+		u.Active = &active
+	case lower == "username":
+		userName, err := decodeString(value)
+		if err != nil {
+			return err
+		}
+		if userName == "" {
+			return invalidValue("userName is required")
+		}
+		u.UserName = userName
+	case lower == "displayname":
+		return decodeInto(value, &u.DisplayName)
+	case lower == "externalid":
+		return decodeInto(value, &u.ExternalID)
+	case lower == "name":
+		var name Name
+		if err := json.Unmarshal(value, &name); err != nil {
+			return invalidValue("name must be an object")
+		}
+		u.Name = &name
+	case strings.HasPrefix(lower, "name."):
+		if u.Name == nil {
+			u.Name = &Name{}
+		}
+		switch strings.TrimPrefix(lower, "name.") {
+		case "givenname":
+			return decodeInto(value, &u.Name.GivenName)
+		case "familyname":
+			return decodeInto(value, &u.Name.FamilyName)
+		case "formatted":
+			return decodeInto(value, &u.Name.Formatted)
+		}
+	case lower == "emails":
+		var emails []Email
+		if err := json.Unmarshal(value, &emails); err != nil {
+			return invalidValue("emails must be an array")
+		}
+		if verb == opAdd {
+			for _, email := range emails {
+				if !slices.ContainsFunc(u.Emails, func(e Email) bool { return strings.EqualFold(e.Value, email.Value) }) {
+					u.Emails = append(u.Emails, email)
+				}
+			}
+		} else {
+			u.Emails = emails
+		}
+	case strings.HasPrefix(lower, "emails[") && strings.HasSuffix(lower, "].value"):
+		// emails[type eq "work"].value sets the address of that type
+		filter, err := ParseFilter(path[len("emails[") : len(path)-len("].value")])
+		if err != nil || !filter.Is("type") {
+			return NewError(http.StatusBadRequest, ErrorTypeInvalidPath, "unsupported path "+path)
+		}
+		address, err := decodeString(value)
+		if err != nil {
+			return err
+		}
+		i := slices.IndexFunc(u.Emails, func(e Email) bool { return strings.EqualFold(e.Type, filter.Value) })
+		if i < 0 {
+			u.Emails = append(u.Emails, Email{Value: address, Type: filter.Value, Primary: len(u.Emails) == 0})
+		} else {
+			u.Emails[i].Value = address
+		}
+	}
+
+	return nil
+}
+
+func removeUserAttribute(u *User, path string) error {
+	switch strings.ToLower(path) {
+	case "username", "active":
+		return NewError(http.StatusBadRequest, ErrorTypeMutability, path+" cannot be removed")
+	case "displayname":
+		u.DisplayName = ""
+	case "externalid":
+		u.ExternalID = ""
+	case "name":
+		u.Name = nil
+	case "name.givenname":
+		if u.Name != nil {
+			u.Name.GivenName = ""
+		}
+	case "name.familyname":
+		if u.Name != nil {
+			u.Name.FamilyName = ""
+		}
+	case "name.formatted":
+		if u.Name != nil {
+			u.Name.Formatted = ""
+		}
+	case "emails":
+		u.Emails = nil
+	}
+	return nil
+}
+
+// ApplyToGroup applies the operations to g. Members are matched by value,
+// the id of the user.
+func (r *PatchRequest) ApplyToGroup(g *Group) error {
+	for _, op := range r.Operations {
+		verb := strings.ToLower(op.Op)
+		lower := strings.ToLower(op.Path)
+
+		switch {
+		case verb == opRemove && lower == "members":
+			if len(op.Value) == 0 {
+				g.Members = nil
+				continue
+			}
+			var refs []Ref
+			if err := json.Unmarshal(op.Value, &refs); err != nil {
+				return invalidValue("members must be an array")
+			}
+			g.Members = slices.DeleteFunc(g.Members, func(m Ref) bool {
+				return slices.ContainsFunc(refs, func(r Ref) bool { return r.Value == m.Value })
+			})
+		case verb == opRemove && strings.HasPrefix(lower, "members[") && strings.HasSuffix(lower, "]"):
+			filter, err := ParseFilter(op.Path[len("members[") : len(op.Path)-1])
+			if err != nil || !filter.Is("value") {
+				return NewError(http.StatusBadRequest, ErrorTypeInvalidPath, "unsupported path "+op.Path)
+			}
+			g.Members = slices.DeleteFunc(g.Members, func(m Ref) bool { return m.Value == filter.Value })
+		case verb == opRemove && lower == "displayname":
+			return NewError(http.StatusBadRequest, ErrorTypeMutability, "displayName cannot be removed")
+		case verb == opRemove && lower == "externalid":
+			g.ExternalID = ""
+		case verb == opRemove:
+			// Nothing else is stored
+		case op.Path != "":
+			if err := setGroupAttribute(g, verb, op.Path, op.Value); err != nil {
+				return err
+			}
+		default:
+			var values map[string]json.RawMessage
+			if err := json.Unmarshal(op.Value, &values); err != nil {
+				return invalidValue("operations without a path need an object value")
+			}
+			for path, value := range values {
+				if err := setGroupAttribute(g, verb, path, value); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func setGroupAttribute(g *Group, verb string, path string, value json.RawMessage) error {
+	switch strings.ToLower(path) {
+	case "displayname":
+		displayName, err := decodeString(value)
+		if err != nil {
+			return err
+		}
+		if displayName == "" {
+			return invalidValue("displayName is required")
+		}
+		g.DisplayName = displayName
+	case "externalid":
+		return decodeInto(value, &g.ExternalID)
+	case "members":
+		var refs []Ref
+		if err := json.Unmarshal(value, &refs); err != nil {
+			return invalidValue("members must be an array")
+		}
+		if verb == opReplace {
+			g.Members = nil
+		}
+		for _, ref := range refs {
+			if !slices.ContainsFunc(g.Members, func(m Ref) bool { return m.Value == ref.Value }) {
+				g.Members = append(g.Members, ref)
+			}
+		}
+	}
+	return nil
+}
+
+// decodeBool accepts JSON booleans and, as some identity providers send,
+// the strings "true" and "false"
+func decodeBool(value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		switch strings.ToLower(s) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+	}
+
+	return false, invalidValue("expected a boolean")
+}
+
+func decodeString(value json.RawMessage) (string, error) {
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return "", invalidValue("expected a string")
+	}
+	return s, nil
+}
+
+func decodeInto(value json.RawMessage, dst *string) error {
+	s, err := decodeString(value)
+	if err != nil {
+		return err
+	}
+	*dst = s
+	return nil
+}
+
+func invalidValue(detail string) *Error {
+	return NewError(http.StatusBadRequest, ErrorTypeInvalidValue, detail)
+}
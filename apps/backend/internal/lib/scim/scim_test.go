@@ -0,0 +1,142 @@
+package scim_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/mabhi256/tasker/internal/lib/scim"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFilter(t *testing.T) {
+	filter, err := scim.ParseFilter(`userName eq "ada@example.com"`)
+	require.NoError(t, err)
+	assert.True(t, filter.Is("username"))
+	assert.Equal(t, "ada@example.com", filter.Value)
+
+	filter, err = scim.ParseFilter(`externalId EQ "a \"quoted\" id"`)
+	require.NoError(t, err)
+	assert.True(t, filter.Is("externalId"))
+	assert.Equal(t, `a "quoted" id`, filter.Value)
+
+	for _, raw := range []string{
+		``,
+		`userName`,
+		`userName sw "ada"`,
+		`userName eq ada`,
+		`userName eq "ada" and active eq "true"`,
+	} {
+		_, err := scim.ParseFilter(raw)
+		var scimErr *scim.Error
+		require.ErrorAs(t, err, &scimErr, raw)
+		assert.Equal(t, scim.ErrorTypeInvalidFilter, scimErr.ScimType, raw)
+		assert.Equal(t, http.StatusBadRequest, scimErr.StatusCode(), raw)
+	}
+}
+
+func parsePatch(t *testing.T, body string) *scim.PatchRequest {
+	t.Helper()
+	var req scim.PatchRequest
+	require.NoError(t, json.Unmarshal([]byte(body), &req))
+	require.NoError(t, req.Validate())
+	return &req
+}
+
+func TestPatchUser(t *testing.T) {
+	user := &scim.User{
+		UserName: "ada@example.com",
+		Name:     &scim.Name{GivenName: "Ada", FamilyName: "Byron"},
+		Emails:   []scim.Email{{Value: "ada@example.com", Type: "work", Primary: true}},
+	}
+
+	// Operations with paths, with a boolean sent as a string
+	require.NoError(t, parsePatch(t, `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [
+			{"op": "Replace", "path": "active", "value": "False"},
+			{"op": "replace", "path": "name.familyName", "value": "Lovelace"},
+			{"op": "replace", "path": "emails[type eq \"work\"].value", "value": "ada@lovelace.dev"},
+			{"op": "add", "path": "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department", "value": "R&D"}
+		]
+	}`).ApplyToUser(user))
+
+	assert.False(t, user.IsActive())
+	assert.Equal(t, "Lovelace", user.Name.FamilyName)
+	assert.Equal(t, "ada@lovelace.dev", user.PrimaryEmail())
+
+	// An operation without a path carries an object of attributes
+	require.NoError(t, parsePatch(t, `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [{"op": "replace", "value": {"active": true, "userName": "ada@lovelace.dev", "name.givenName": "Augusta"}}]
+	}`).ApplyToUser(user))
+
+	assert.True(t, user.IsActive())
+	assert.Equal(t, "ada@lovelace.dev", user.UserName)
+	assert.Equal(t, "Augusta", user.Name.GivenName)
+
+	err := parsePatch(t, `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [{"op": "remove", "path": "userName"}]
+	}`).ApplyToUser(user)
+	var scimErr *scim.Error
+	require.ErrorAs(t, err, &scimErr)
+	assert.Equal(t, scim.ErrorTypeMutability, scimErr.ScimType)
+}
+
+func TestPatchGroup(t *testing.T) {
+	group := &scim.Group{DisplayName: "Engineering", Members: []scim.Ref{{Value: "u1"}}}
+
+	require.NoError(t, parsePatch(t, `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [
+			{"op": "add", "path": "members", "value": [{"value": "u1"}, {"value": "u2"}, {"value": "u3"}]},
+			{"op": "remove", "path": "members[value eq \"u2\"]"},
+			{"op": "remove", "path": "members", "value": [{"value": "u3"}]},
+			{"op": "replace", "value": {"displayName": "Platform"}}
+		]
+	}`).ApplyToGroup(group))
+
+	assert.Equal(t, "Platform", group.DisplayName)
+	assert.Equal(t, []scim.Ref{{Value: "u1"}}, group.Members)
+
+	require.NoError(t, parsePatch(t, `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [{"op": "replace", "path": "members", "value": [{"value": "u4"}]}]
+	}`).ApplyToGroup(group))
+	assert.Equal(t, []scim.Ref{{Value: "u4"}}, group.Members)
+
+	require.NoError(t, parsePatch(t, `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [{"op": "remove", "path": "members"}]
+	}`).ApplyToGroup(group))
+	assert.Empty(t, group.Members)
+}
+
+func TestPatchValidate(t *testing.T) {
+	for _, body := range []string{
+		`{"schemas": [], "Operations": [{"op": "add", "path": "active", "value": true}]}`,
+		`{"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"], "Operations": []}`,
+		`{"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"], "Operations": [{"op": "move", "path": "active"}]}`,
+		`{"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"], "Operations": [{"op": "remove"}]}`,
+	} {
+		var req scim.PatchRequest
+		require.NoError(t, json.Unmarshal([]byte(body), &req))
+		assert.Error(t, req.Validate(), body)
+	}
+}
+
+func TestPrimaryEmail(t *testing.T) {
+	user := &scim.User{
+		UserName: "ada",
+		Emails:   []scim.Email{{Value: "home@example.com"}, {Value: "work@example.com", Primary: true}},
+	}
+	assert.Equal(t, "work@example.com", user.PrimaryEmail())
+
+	user.Emails = nil
+	assert.Empty(t, user.PrimaryEmail())
+
+	user.UserName = "ada@example.com"
+	assert.Equal(t, "ada@example.com", user.PrimaryEmail())
+}
@@ -0,0 +1,108 @@
+// Package seal encrypts secrets the app stores in the database with
+// AES-256-GCM, so a dump, backup or replica of the database doesn't give
+// them away. The key is kept out of the database, usually in a secret store.
+package seal
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Prefix marks sealed values, and the version of their format
+const Prefix = "sealed:v1:"
+
+// KeySize is the size of keys, before they are encoded in base64
+const KeySize = 32
+
+var (
+	// ErrNoKey is returned by a nil Sealer, which has no key to seal with
+	ErrNoKey = errors.New("no encryption key configured")
+	// ErrInvalid is returned for sealed values that can't be opened with
+	// the key, because they were changed or sealed with another key
+	ErrInvalid = errors.New("sealed value is invalid")
+)
+
+// Sealer seals values with its key. A nil Sealer refuses to seal, so
+// secrets are never stored in the clear by mistake.
+type Sealer struct {
+	aead cipher.AEAD
+}
+
+// New returns a sealer with the key, KeySize bytes encoded in base64
+func New(key string) (*Sealer, error) {
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key must be base64 encoded: %w", err)
+	}
+	if len(raw) != KeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", KeySize, len(raw))
+	}
+
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gcm: %w", err)
+	}
+
+	return &Sealer{aead: aead}, nil
+}
+
+// NewOptional returns a sealer with the key like New, or nil without one
+func NewOptional(key string) (*Sealer, error) {
+	if key == "" {
+		return nil, nil
+	}
+	return New(key)
+}
+
+// IsSealed reports whether the value was sealed
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+// Seal encrypts the value with a random nonce
+func (s *Sealer) Seal(value string) (string, error) {
+	if s == nil {
+		return "", ErrNoKey
+	}
+
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := s.aead.Seal(nonce, nonce, []byte(value), nil)
+	return Prefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a sealed value. Values that aren't sealed, stored before
+// they were, are returned as they are.
+func (s *Sealer) Open(value string) (string, error) {
+	if !IsSealed(value) {
+		return value, nil
+	}
+	if s == nil {
+		return "", ErrNoKey
+	}
+
+	raw, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(value, Prefix))
+	if err != nil || len(raw) < s.aead.NonceSize() {
+		return "", ErrInvalid
+	}
+
+	nonce, sealed := raw[:s.aead.NonceSize()], raw[s.aead.NonceSize():]
+	opened, err := s.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", ErrInvalid
+	}
+
+	return string(opened), nil
+}
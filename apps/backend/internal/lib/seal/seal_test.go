@@ -0,0 +1,80 @@
+package seal_test
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+
+	"github.com/mabhi256/tasker/internal/lib/seal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newKey(t *testing.T) string {
+	t.Helper()
+
+	key := make([]byte, seal.KeySize)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+func TestSealOpen(t *testing.T) {
+	sealer, err := seal.New(newKey(t))
+	require.NoError(t, err)
+
+	sealed, err := sealer.Seal("hunter2")
+	require.NoError(t, err)
+	assert.True(t, seal.IsSealed(sealed))
+	assert.NotContains(t, sealed, "hunter2")
+
+	again, err := sealer.Seal("hunter2")
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, again, "nonces are random")
+
+	opened, err := sealer.Open(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", opened)
+
+	// Values stored before sealing are read as they are
+	opened, err = sealer.Open("legacy")
+	require.NoError(t, err)
+	assert.Equal(t, "legacy", opened)
+}
+
+func TestOpenInvalid(t *testing.T) {
+	sealer, err := seal.New(newKey(t))
+	require.NoError(t, err)
+	other, err := seal.New(newKey(t))
+	require.NoError(t, err)
+
+	sealed, err := sealer.Seal("hunter2")
+	require.NoError(t, err)
+
+	_, err = other.Open(sealed)
+	assert.ErrorIs(t, err, seal.ErrInvalid)
+
+	_, err = sealer.Open(sealed[:len(sealed)-2])
+	assert.ErrorIs(t, err, seal.ErrInvalid)
+
+	_, err = sealer.Open(seal.Prefix + "!")
+	assert.ErrorIs(t, err, seal.ErrInvalid)
+}
+
+func TestNilSealer(t *testing.T) {
+	var sealer *seal.Sealer
+
+	_, err := sealer.Seal("hunter2")
+	assert.ErrorIs(t, err, seal.ErrNoKey)
+
+	_, err = sealer.Open(seal.Prefix + "AAAA")
+	assert.ErrorIs(t, err, seal.ErrNoKey)
+}
+
+func TestNewRejectsBadKeys(t *testing.T) {
+	_, err := seal.New("not base64!")
+	assert.Error(t, err)
+
+	_, err = seal.New(base64.StdEncoding.EncodeToString([]byte("too short")))
+	assert.Error(t, err)
+}
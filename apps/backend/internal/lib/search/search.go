@@ -0,0 +1,134 @@
+// Package search queries the external search engine todos are indexed in.
+// The engine speaks the Meilisearch search API, and its index holds a
+// document per todo with the todo's id and workspaceId as filterable
+// attributes. Keeping the index up to date is left to the engine's
+// connector.
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/config"
+)
+
+// ErrNotConfigured is returned when no engine URL is set
+var ErrNotConfigured = errors.New("search engine is not configured")
+
+// Query searches one workspace's todos
+type Query struct {
+	WorkspaceID uuid.UUID
+	Text        string
+	Limit       int
+	Offset      int
+}
+
+// Result lists the matching todos by relevance. Total can be an estimate.
+type Result struct {
+	IDs   []uuid.UUID
+	Total int
+}
+
+// Doer sends HTTP requests, e.g. an httpclient.Client
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type Client struct {
+	endpoint string
+	apiKey   string
+	client   Doer
+}
+
+// NewClient returns a client for the engine in cfg. Without a URL it is
+// returned unconfigured, and Search fails with ErrNotConfigured.
+func NewClient(cfg *config.SearchConfig, client Doer) *Client {
+	c := &Client{apiKey: cfg.APIKey, client: client}
+	if cfg.URL != "" {
+		c.endpoint = strings.TrimRight(cfg.URL, "/") + "/indexes/" + url.PathEscape(cfg.Index) + "/search"
+	}
+	return c
+}
+
+func (c *Client) Configured() bool {
+	return c.endpoint != ""
+}
+
+type searchRequest struct {
+	Q                    string   `json:"q"`
+	Filter               string   `json:"filter"`
+	Limit                int      `json:"limit"`
+	Offset               int      `json:"offset"`
+	AttributesToRetrieve []string `json:"attributesToRetrieve"`
+}
+
+type searchResponse struct {
+	Hits []struct {
+		ID string `json:"id"`
+	} `json:"hits"`
+	EstimatedTotalHits int `json:"estimatedTotalHits"`
+}
+
+func (c *Client) Search(ctx context.Context, q Query) (*Result, error) {
+	if !c.Configured() {
+		return nil, ErrNotConfigured
+	}
+
+	body, err := json.Marshal(searchRequest{
+		Q:                    q.Text,
+		Filter:               fmt.Sprintf("workspaceId = %q", q.WorkspaceID.String()),
+		Limit:                q.Limit,
+		Offset:               q.Offset,
+		AttributesToRetrieve: []string{"id"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal search request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create search request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send search request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		return nil, fmt.Errorf("search engine responded with status %d", resp.StatusCode)
+	}
+
+	var decoded searchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("failed to decode search response: %w", err)
+	}
+
+	result := &Result{
+		IDs:   make([]uuid.UUID, 0, len(decoded.Hits)),
+		Total: decoded.EstimatedTotalHits,
+	}
+	for _, hit := range decoded.Hits {
+		id, err := uuid.Parse(hit.ID)
+		if err != nil {
+			return nil, fmt.Errorf("search engine returned invalid todo id %q: %w", hit.ID, err)
+		}
+		result.IDs = append(result.IDs, id)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,106 @@
+package search_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/config"
+	"github.com/mabhi256/tasker/internal/lib/search"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type doerFunc func(req *http.Request) (*http.Response, error)
+
+func (f doerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func respond(status int, body string) *http.Response {
+	return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body))}
+}
+
+func newConfig() *config.SearchConfig {
+	cfg := config.DefaultSearchConfig()
+	cfg.URL = "https://search.example.com/"
+	cfg.APIKey = "key"
+	return cfg
+}
+
+func TestSearch(t *testing.T) {
+	workspaceID := uuid.New()
+	ids := []uuid.UUID{uuid.New(), uuid.New()}
+
+	var sent map[string]any
+	client := doerFunc(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, http.MethodPost, req.Method)
+		assert.Equal(t, "https://search.example.com/indexes/todos/search", req.URL.String())
+		assert.Equal(t, "Bearer key", req.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&sent))
+
+		return respond(http.StatusOK, `{"hits":[{"id":"`+ids[0].String()+`"},{"id":"`+ids[1].String()+`"}],"estimatedTotalHits":42}`), nil
+	})
+
+	result, err := search.NewClient(newConfig(), client).Search(context.Background(), search.Query{
+		WorkspaceID: workspaceID,
+		Text:        "quarterly report",
+		Limit:       20,
+		Offset:      40,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, ids, result.IDs)
+	assert.Equal(t, 42, result.Total)
+
+	assert.Equal(t, "quarterly report", sent["q"])
+	assert.Equal(t, `workspaceId = "`+workspaceID.String()+`"`, sent["filter"])
+	assert.Equal(t, float64(20), sent["limit"])
+	assert.Equal(t, float64(40), sent["offset"])
+}
+
+func TestSearchFailures(t *testing.T) {
+	unreachable := errors.New("connection refused")
+
+	tests := []struct {
+		name   string
+		client doerFunc
+	}{
+		{"unreachable", func(req *http.Request) (*http.Response, error) {
+			return nil, unreachable
+		}},
+		{"server error", func(req *http.Request) (*http.Response, error) {
+			return respond(http.StatusServiceUnavailable, "down"), nil
+		}},
+		{"bad body", func(req *http.Request) (*http.Response, error) {
+			return respond(http.StatusOK, "<html>"), nil
+		}},
+		{"bad id", func(req *http.Request) (*http.Response, error) {
+			return respond(http.StatusOK, `{"hits":[{"id":"nope"}]}`), nil
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := search.NewClient(newConfig(), tt.client).Search(context.Background(), search.Query{})
+			assert.Error(t, err)
+			assert.Nil(t, result)
+		})
+	}
+}
+
+func TestSearchNotConfigured(t *testing.T) {
+	client := search.NewClient(config.DefaultSearchConfig(), doerFunc(func(req *http.Request) (*http.Response, error) {
+		t.Fatal("unexpected request")
+		return nil, nil
+	}))
+
+	assert.False(t, client.Configured())
+	_, err := client.Search(context.Background(), search.Query{})
+	assert.ErrorIs(t, err, search.ErrNotConfigured)
+}
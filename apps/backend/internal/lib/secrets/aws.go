@@ -0,0 +1,121 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+)
+
+// AWS gets secrets from AWS Secrets Manager, by their name or ARN. Only the
+// current version of a secret is read.
+type AWS struct {
+	credentials aws.CredentialsProvider
+	region      string
+	endpoint    string
+	signer      *v4.Signer
+	client      *http.Client
+}
+
+// NewAWS returns a provider for Secrets Manager in region, authenticating
+// with the default credentials, such as the instance's or task's role. An
+// endpoint overrides the region's, such as for a VPC endpoint.
+func NewAWS(ctx context.Context, region, endpoint string, client *http.Client) (*AWS, error) {
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(region))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load aws config: %w", err)
+	}
+	if cfg.Region == "" {
+		return nil, fmt.Errorf("no aws region configured for secrets manager")
+	}
+
+	if endpoint == "" {
+		endpoint = "https://secretsmanager." + cfg.Region + ".amazonaws.com"
+	}
+
+	return &AWS{
+		credentials: cfg.Credentials,
+		region:      cfg.Region,
+		endpoint:    strings.TrimSuffix(endpoint, "/"),
+		signer:      v4.NewSigner(),
+		client:      client,
+	}, nil
+}
+
+// Get returns the secret's string, or its binary for secrets stored as one
+func (a *AWS) Get(ctx context.Context, name string) (string, error) {
+	payload, err := json.Marshal(map[string]string{"SecretId": name})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal secrets manager request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint+"/", bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to create secrets manager request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+
+	creds, err := a.credentials.Retrieve(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve aws credentials: %w", err)
+	}
+	hash := sha256.Sum256(payload)
+	if err := a.signer.SignHTTP(ctx, creds, req, hex.EncodeToString(hash[:]), "secretsmanager", a.region, time.Now()); err != nil {
+		return "", fmt.Errorf("failed to sign secrets manager request: %w", err)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach secrets manager: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read secrets manager response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var failure struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(body, &failure)
+		// Types are prefixed with the service's namespace, such as
+		// com.amazonaws.secretsmanager#ResourceNotFoundException
+		kind := failure.Type[strings.LastIndex(failure.Type, "#")+1:]
+		return "", fmt.Errorf("secrets manager responded %d %s: %s", resp.StatusCode, kind, failure.Message)
+	}
+
+	var secret struct {
+		SecretString *string `json:"SecretString"`
+		SecretBinary *string `json:"SecretBinary"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return "", fmt.Errorf("failed to decode secrets manager response: %w", err)
+	}
+
+	switch {
+	case secret.SecretString != nil:
+		return *secret.SecretString, nil
+	case secret.SecretBinary != nil:
+		binary, err := base64.StdEncoding.DecodeString(*secret.SecretBinary)
+		if err != nil {
+			return "", fmt.Errorf("failed to decode binary secret %s: %w", name, err)
+		}
+		return string(binary), nil
+	default:
+		return "", fmt.Errorf("secret %s has no value", name)
+	}
+}
@@ -0,0 +1,111 @@
+// Package secrets resolves references to secrets kept in a secret store,
+// such as aws-sm://prod/tasker#db_password or vault://secret/tasker#resend,
+// so config values can name a secret rather than hold it.
+//
+// A reference is a store's scheme, the secret's name in the store and,
+// after #, a field of the secret. A secret with a field is a JSON object,
+// as Vault's always are, and resolves to the field's value. One without
+// resolves to the whole secret.
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Schemes of the supported stores
+const (
+	SchemeAWS   = "aws-sm"
+	SchemeVault = "vault"
+)
+
+// Provider fetches secrets from a store
+type Provider interface {
+	// Get returns the secret named name as it is stored
+	Get(ctx context.Context, name string) (string, error)
+}
+
+// Ref refers to a secret, or a field of one, in a store
+type Ref struct {
+	Scheme string
+	Name   string
+	Field  string
+}
+
+// ParseRef parses value as a reference to a secret of one of schemes. ok
+// is false for values that aren't, which are used as they are.
+func ParseRef(value string, schemes ...string) (ref Ref, ok bool) {
+	scheme, rest, found := strings.Cut(value, "://")
+	if !found {
+		return Ref{}, false
+	}
+	for _, s := range schemes {
+		if s != scheme {
+			continue
+		}
+
+		name, field, _ := strings.Cut(rest, "#")
+		if name == "" {
+			return Ref{}, false
+		}
+		return Ref{Scheme: scheme, Name: name, Field: field}, true
+	}
+	return Ref{}, false
+}
+
+func (r Ref) String() string {
+	if r.Field == "" {
+		return r.Scheme + "://" + r.Name
+	}
+	return r.Scheme + "://" + r.Name + "#" + r.Field
+}
+
+// Resolver resolves references with the provider of their scheme
+type Resolver struct {
+	providers map[string]Provider
+}
+
+func NewResolver() *Resolver {
+	return &Resolver{providers: make(map[string]Provider)}
+}
+
+// Register resolves references of scheme with provider
+func (r *Resolver) Register(scheme string, provider Provider) {
+	r.providers[scheme] = provider
+}
+
+// Resolve fetches the secret ref refers to
+func (r *Resolver) Resolve(ctx context.Context, ref Ref) (string, error) {
+	provider, ok := r.providers[ref.Scheme]
+	if !ok {
+		return "", fmt.Errorf("no secret store configured for %s", ref.Scheme)
+	}
+
+	secret, err := provider.Get(ctx, ref.Name)
+	if err != nil {
+		return "", fmt.Errorf("failed to get secret %s: %w", ref, err)
+	}
+	if ref.Field == "" {
+		return secret, nil
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", fmt.Errorf("secret %s is not a JSON object: %w", ref.Name, err)
+	}
+	value, ok := fields[ref.Field]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no field %s", ref.Name, ref.Field)
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case float64, bool:
+		return fmt.Sprint(v), nil
+	default:
+		return "", fmt.Errorf("field %s of secret %s is not a string", ref.Field, ref.Name)
+	}
+}
@@ -0,0 +1,148 @@
+package secrets_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mabhi256/tasker/internal/lib/secrets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticProvider map[string]string
+
+func (p staticProvider) Get(_ context.Context, name string) (string, error) {
+	secret, ok := p[name]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	return secret, nil
+}
+
+func TestParseRef(t *testing.T) {
+	ref, ok := secrets.ParseRef("vault://secret/tasker#db_password", secrets.SchemeAWS, secrets.SchemeVault)
+	require.True(t, ok)
+	assert.Equal(t, secrets.Ref{Scheme: secrets.SchemeVault, Name: "secret/tasker", Field: "db_password"}, ref)
+	assert.Equal(t, "vault://secret/tasker#db_password", ref.String())
+
+	ref, ok = secrets.ParseRef("aws-sm://prod/resend", secrets.SchemeAWS)
+	require.True(t, ok)
+	assert.Equal(t, secrets.Ref{Scheme: secrets.SchemeAWS, Name: "prod/resend"}, ref)
+
+	for _, value := range []string{"hunter2", "https://example.com", "vault://", "aws-sm://x"} {
+		_, ok := secrets.ParseRef(value, secrets.SchemeVault)
+		assert.False(t, ok, value)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	resolver := secrets.NewResolver()
+	resolver.Register(secrets.SchemeAWS, staticProvider{
+		"plain": "hunter2",
+		"db":    `{"password": "s3cret", "port": 5432}`,
+	})
+	ctx := context.Background()
+
+	resolve := func(value string) (string, error) {
+		ref, ok := secrets.ParseRef(value, secrets.SchemeAWS, secrets.SchemeVault)
+		require.True(t, ok, value)
+		return resolver.Resolve(ctx, ref)
+	}
+
+	secret, err := resolve("aws-sm://plain")
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", secret)
+
+	secret, err = resolve("aws-sm://db#password")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", secret)
+
+	secret, err = resolve("aws-sm://db#port")
+	require.NoError(t, err)
+	assert.Equal(t, "5432", secret)
+
+	for _, value := range []string{"aws-sm://missing", "aws-sm://db#user", "aws-sm://plain#password", "vault://secret/db#password"} {
+		_, err := resolve(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestVault(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors": ["permission denied"]}`))
+			return
+		}
+		if r.URL.Path != "/v1/secret/data/tasker" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors": []}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"data": {"resend_api_key": "re_123"}, "metadata": {"version": 3}}}`))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	resolver := secrets.NewResolver()
+	resolver.Register(secrets.SchemeVault, secrets.NewVault(server.URL, "token", "", server.Client()))
+
+	secret, err := resolver.Resolve(ctx, secrets.Ref{Scheme: secrets.SchemeVault, Name: "secret/tasker", Field: "resend_api_key"})
+	require.NoError(t, err)
+	assert.Equal(t, "re_123", secret)
+
+	_, err = resolver.Resolve(ctx, secrets.Ref{Scheme: secrets.SchemeVault, Name: "secret/other", Field: "key"})
+	assert.ErrorContains(t, err, "404")
+
+	_, err = secrets.NewVault(server.URL, "wrong", "", server.Client()).Get(ctx, "secret/tasker")
+	assert.ErrorContains(t, err, "permission denied")
+}
+
+func TestAWS(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secretsmanager.GetSecretValue", r.Header.Get("X-Amz-Target"))
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/"))
+		assert.Contains(t, r.Header.Get("Authorization"), "/eu-west-1/secretsmanager/aws4_request")
+
+		var req struct {
+			SecretID string `json:"SecretId"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		switch req.SecretID {
+		case "prod/tasker":
+			_, _ = w.Write([]byte(`{"Name": "prod/tasker", "SecretString": "{\"db_password\": \"s3cret\"}"}`))
+		case "prod/binary":
+			_, _ = w.Write([]byte(`{"Name": "prod/binary", "SecretBinary": "aHVudGVyMg=="}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type": "ResourceNotFoundException", "message": "Secrets Manager can't find the specified secret."}`))
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	provider, err := secrets.NewAWS(ctx, "eu-west-1", server.URL, server.Client())
+	require.NoError(t, err)
+	resolver := secrets.NewResolver()
+	resolver.Register(secrets.SchemeAWS, provider)
+
+	secret, err := resolver.Resolve(ctx, secrets.Ref{Scheme: secrets.SchemeAWS, Name: "prod/tasker", Field: "db_password"})
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", secret)
+
+	secret, err = provider.Get(ctx, "prod/binary")
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", secret)
+
+	_, err = provider.Get(ctx, "prod/missing")
+	assert.ErrorContains(t, err, "ResourceNotFoundException")
+}
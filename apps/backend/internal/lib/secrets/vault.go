@@ -0,0 +1,81 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Vault gets secrets from a HashiCorp Vault KV version 2 secrets engine.
+// Secrets are named by the engine's mount and their path in it, such as
+// secret/tasker for the secret tasker in the engine mounted at secret.
+type Vault struct {
+	address   string
+	token     string
+	namespace string
+	client    *http.Client
+}
+
+// NewVault returns a provider for the Vault at address, such as
+// https://vault.internal:8200, authenticating with token. namespace is only
+// needed for Vault Enterprise namespaces.
+func NewVault(address, token, namespace string, client *http.Client) *Vault {
+	return &Vault{
+		address:   strings.TrimSuffix(address, "/"),
+		token:     token,
+		namespace: namespace,
+		client:    client,
+	}
+}
+
+// Get returns the latest version of the secret's data as a JSON object
+func (v *Vault) Get(ctx context.Context, name string) (string, error) {
+	mount, path, ok := strings.Cut(strings.Trim(name, "/"), "/")
+	if !ok {
+		return "", fmt.Errorf("vault secret %s is not named by its mount and path", name)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.address+"/v1/"+mount+"/data/"+path, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create vault request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read vault response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var failure struct {
+			Errors []string `json:"errors"`
+		}
+		_ = json.Unmarshal(body, &failure)
+		return "", fmt.Errorf("vault responded %d: %s", resp.StatusCode, strings.Join(failure.Errors, "; "))
+	}
+
+	var secret struct {
+		Data struct {
+			Data json.RawMessage `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return "", fmt.Errorf("failed to decode vault response: %w", err)
+	}
+	if len(secret.Data.Data) == 0 || string(secret.Data.Data) == "null" {
+		return "", fmt.Errorf("vault secret %s has no data", name)
+	}
+
+	return string(secret.Data.Data), nil
+}
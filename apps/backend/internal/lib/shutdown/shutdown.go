@@ -0,0 +1,92 @@
+// Package shutdown runs the stages of a graceful shutdown in order, each
+// bounded by its own timeout, so a dependency is only stopped once nothing
+// that uses it is running anymore.
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// ErrTimeout is returned for a stage that ran out of time
+var ErrTimeout = errors.New("shutdown stage timed out")
+
+// Stage is a step of a shutdown
+type Stage struct {
+	Name string
+	// Timeout bounds the stage. Zero only bounds it by the shutdown's
+	// context.
+	Timeout time.Duration
+	// Run stops what the stage is about. It should return once ctx is done.
+	Run func(ctx context.Context) error
+}
+
+// Stop adapts a stop function that bounds itself, or can't be bounded, to
+// a stage's Run
+func Stop(stop func()) func(ctx context.Context) error {
+	return func(context.Context) error {
+		stop()
+		return nil
+	}
+}
+
+// Run runs the stages in order. A stage that fails or runs out of time is
+// logged and left behind, and the next one runs anyway, so connections are
+// still closed when draining them took too long. It returns the stages'
+// errors.
+func Run(ctx context.Context, logger *zerolog.Logger, stages ...Stage) error {
+	start := time.Now()
+	var errs []error
+
+	for _, stage := range stages {
+		stageStart := time.Now()
+		err := run(ctx, stage)
+		duration := time.Since(stageStart)
+
+		if err != nil {
+			logger.Error().Err(err).
+				Str("stage", stage.Name).
+				Dur("duration", duration).
+				Msg("shutdown stage failed")
+			errs = append(errs, fmt.Errorf("%s: %w", stage.Name, err))
+			continue
+		}
+
+		logger.Info().
+			Str("stage", stage.Name).
+			Dur("duration", duration).
+			Msg("shutdown stage completed")
+	}
+
+	logger.Info().
+		Dur("duration", time.Since(start)).
+		Int("failed_stages", len(errs)).
+		Msg("shutdown completed")
+
+	return errors.Join(errs...)
+}
+
+func run(ctx context.Context, stage Stage) error {
+	if stage.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, stage.Timeout)
+		defer cancel()
+	}
+
+	// Buffered, so a stage that overruns doesn't block once it returns
+	done := make(chan error, 1)
+	go func() {
+		done <- stage.Run(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("%w after %s", ErrTimeout, stage.Timeout)
+	}
+}
@@ -0,0 +1,83 @@
+package shutdown_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mabhi256/tasker/internal/lib/shutdown"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunInOrder(t *testing.T) {
+	logger := zerolog.Nop()
+	var order []string
+
+	stage := func(name string) shutdown.Stage {
+		return shutdown.Stage{
+			Name:    name,
+			Timeout: time.Second,
+			Run: shutdown.Stop(func() {
+				order = append(order, name)
+			}),
+		}
+	}
+
+	err := shutdown.Run(context.Background(), &logger, stage("http"), stage("jobs"), stage("close"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"http", "jobs", "close"}, order)
+}
+
+func TestRunContinuesAfterFailures(t *testing.T) {
+	logger := zerolog.Nop()
+	failed := errors.New("failed")
+	closed := false
+
+	err := shutdown.Run(context.Background(), &logger,
+		shutdown.Stage{
+			Name: "http",
+			Run: func(context.Context) error {
+				return failed
+			},
+		},
+		shutdown.Stage{
+			Name:    "jobs",
+			Timeout: 10 * time.Millisecond,
+			// Overruns its timeout
+			Run: func(context.Context) error {
+				time.Sleep(time.Second)
+				return nil
+			},
+		},
+		shutdown.Stage{
+			Name: "close",
+			Run: shutdown.Stop(func() {
+				closed = true
+			}),
+		},
+	)
+
+	assert.ErrorIs(t, err, failed)
+	assert.ErrorIs(t, err, shutdown.ErrTimeout)
+	assert.ErrorContains(t, err, "jobs: ")
+	assert.True(t, closed, "a later stage was skipped")
+}
+
+func TestStageContext(t *testing.T) {
+	logger := zerolog.Nop()
+
+	var deadline time.Time
+	err := shutdown.Run(context.Background(), &logger, shutdown.Stage{
+		Name:    "outbox",
+		Timeout: time.Minute,
+		Run: func(ctx context.Context) error {
+			deadline, _ = ctx.Deadline()
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, 5*time.Second)
+}
@@ -0,0 +1,250 @@
+// Package siem ships audit events to security information and event
+// management systems, as RFC 5424 syslog, JSON over HTTP or to a Splunk HTTP
+// Event Collector. Endpoints are customer-supplied, so connections go through
+// the fetcher, which only reaches public addresses.
+package siem
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/mabhi256/tasker/internal/model/audit"
+)
+
+const (
+	appName = "tasker"
+	// facilityLogAudit is the syslog facility for audit messages
+	facilityLogAudit = 13
+	// sendTimeout bounds one event, including connection setup
+	sendTimeout = 10 * time.Second
+)
+
+var ErrInvalidEndpoint = errors.New("invalid SIEM endpoint")
+
+// Doer sends HTTP requests, e.g. an httpclient.Fetcher
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Dialer opens connections, e.g. an httpclient.Fetcher
+type Dialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+type Client struct {
+	http     Doer
+	dialer   Dialer
+	hostname string
+}
+
+// NewClient returns a client that names itself hostname in syslog messages
+// and HEC events
+func NewClient(httpClient Doer, dialer Dialer, hostname string) *Client {
+	return &Client{http: httpClient, dialer: dialer, hostname: hostname}
+}
+
+// Send ships one event to the forwarder's endpoint
+func (c *Client) Send(ctx context.Context, forwarder *audit.Forwarder, event *audit.Event) error {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	switch forwarder.Format {
+	case audit.FormatSyslog:
+		return c.sendSyslog(ctx, forwarder.Endpoint, event)
+	case audit.FormatHTTP:
+		body, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to marshal audit event: %w", err)
+		}
+		return c.post(ctx, forwarder.Endpoint, "Bearer", forwarder.Token, body)
+	case audit.FormatSplunkHEC:
+		body, err := FormatHEC(event, c.hostname)
+		if err != nil {
+			return err
+		}
+		return c.post(ctx, forwarder.Endpoint, "Splunk", forwarder.Token, body)
+	default:
+		return fmt.Errorf("unsupported SIEM format: %s", forwarder.Format)
+	}
+}
+
+// SyslogEndpoint is where syslog messages are sent
+type SyslogEndpoint struct {
+	// Network is udp or tcp
+	Network string
+	Host    string
+	Port    string
+	TLS     bool
+}
+
+func (e *SyslogEndpoint) Address() string {
+	return net.JoinHostPort(e.Host, e.Port)
+}
+
+// ParseSyslogEndpoint parses udp://host:port, tcp://host:port or
+// tls://host:port
+func ParseSyslogEndpoint(raw string) (*SyslogEndpoint, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEndpoint, err)
+	}
+
+	endpoint := &SyslogEndpoint{Host: u.Hostname(), Port: u.Port()}
+	switch u.Scheme {
+	case "udp", "tcp":
+		endpoint.Network = u.Scheme
+	case "tls":
+		endpoint.Network = "tcp"
+		endpoint.TLS = true
+	default:
+		return nil, fmt.Errorf("%w: syslog endpoints use udp://, tcp:// or tls://", ErrInvalidEndpoint)
+	}
+
+	if endpoint.Host == "" || endpoint.Port == "" {
+		return nil, fmt.Errorf("%w: syslog endpoints need a host and port", ErrInvalidEndpoint)
+	}
+	if (u.Path != "" && u.Path != "/") || u.User != nil || u.RawQuery != "" {
+		return nil, fmt.Errorf("%w: syslog endpoints are only a host and port", ErrInvalidEndpoint)
+	}
+
+	return endpoint, nil
+}
+
+// FormatSyslog writes the event as an RFC 5424 message with the event as its
+// JSON body
+func FormatSyslog(event *audit.Event, hostname string) ([]byte, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal audit event: %w", err)
+	}
+
+	priority := facilityLogAudit*8 + int(event.Type.Severity())
+
+	msg := make([]byte, 0, len(body)+128)
+	msg = append(msg, '<')
+	msg = strconv.AppendInt(msg, int64(priority), 10)
+	msg = append(msg, ">1 "...)
+	msg = event.Time.UTC().AppendFormat(msg, "2006-01-02T15:04:05.000000Z07:00")
+	msg = append(msg, ' ')
+	msg = append(msg, syslogField(hostname, 255)...)
+	msg = append(msg, " "+appName+" - audit - "...)
+	msg = append(msg, body...)
+	return msg, nil
+}
+
+// syslogField keeps the printable ASCII RFC 5424 header fields allow, or is
+// the nil value "-"
+func syslogField(s string, maxLen int) string {
+	field := make([]byte, 0, len(s))
+	for i := 0; i < len(s) && len(field) < maxLen; i++ {
+		if s[i] >= 33 && s[i] <= 126 {
+			field = append(field, s[i])
+		}
+	}
+	if len(field) == 0 {
+		return "-"
+	}
+	return string(field)
+}
+
+type hecEvent struct {
+	Time       float64      `json:"time"`
+	Host       string       `json:"host,omitempty"`
+	Source     string       `json:"source"`
+	SourceType string       `json:"sourcetype"`
+	Event      *audit.Event `json:"event"`
+}
+
+// FormatHEC wraps the event in the envelope of Splunk's HTTP Event Collector
+func FormatHEC(event *audit.Event, hostname string) ([]byte, error) {
+	body, err := json.Marshal(hecEvent{
+		Time:       float64(event.Time.UnixMicro()) / 1e6,
+		Host:       hostname,
+		Source:     appName,
+		SourceType: appName + ":audit",
+		Event:      event,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal HEC event: %w", err)
+	}
+	return body, nil
+}
+
+func (c *Client) sendSyslog(ctx context.Context, rawEndpoint string, event *audit.Event) error {
+	endpoint, err := ParseSyslogEndpoint(rawEndpoint)
+	if err != nil {
+		return err
+	}
+
+	msg, err := FormatSyslog(event, c.hostname)
+	if err != nil {
+		return err
+	}
+
+	conn, err := c.dialer.DialContext(ctx, endpoint.Network, endpoint.Address())
+	if err != nil {
+		return fmt.Errorf("failed to connect to syslog endpoint: %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if endpoint.TLS {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: endpoint.Host, MinVersion: tls.VersionTLS12})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return fmt.Errorf("failed TLS handshake with syslog endpoint: %w", err)
+		}
+		conn = tlsConn
+	}
+
+	// A datagram holds one message. Streams frame each message with its
+	// length, as in RFC 6587 octet counting.
+	if endpoint.Network == "tcp" {
+		msg = append(strconv.AppendInt(nil, int64(len(msg)), 10), append([]byte{' '}, msg...)...)
+	}
+
+	if _, err := conn.Write(msg); err != nil {
+		return fmt.Errorf("failed to write to syslog endpoint: %w", err)
+	}
+
+	return nil
+}
+
+// post sends body to an HTTP collector. Any response other than 2xx is an
+// error.
+func (c *Client) post(ctx context.Context, endpoint string, scheme string, token *string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build SIEM request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Tasker-Audit/1.0")
+	if token != nil && *token != "" {
+		req.Header.Set("Authorization", scheme+" "+*token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send SIEM request: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("SIEM endpoint responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+// Package snapshotter renders todo snapshots into the self-contained files
+// that are stored for audit and sharing. HTML snapshots inline their styles
+// and print cleanly, so they can be opened without the app.
+package snapshotter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"time"
+
+	"github.com/mabhi256/tasker/internal/model/snapshot"
+	"github.com/mabhi256/tasker/templates"
+)
+
+var templateFuncs = map[string]any{
+	"formatTime": func(t time.Time) string {
+		return t.UTC().Format("January 2, 2006 at 3:04 PM MST")
+	},
+	// sanitized marks content that was already sanitized when it was rendered
+	// from markdown, see content.Render
+	"sanitized": func(html string) template.HTML {
+		return template.HTML(html)
+	},
+}
+
+type Snapshotter struct {
+	html *template.Template
+}
+
+// New loads the embedded snapshot templates
+func New() (*Snapshotter, error) {
+	snapshots, err := fs.Sub(templates.Snapshots, "snapshots")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot templates: %w", err)
+	}
+
+	html, err := template.New("todo.html").
+		Funcs(templateFuncs).
+		Option("missingkey=error").
+		ParseFS(snapshots, "todo.html")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot templates: %w", err)
+	}
+
+	return &Snapshotter{html: html}, nil
+}
+
+// Render returns the document in format. HTML documents should have their
+// content rendered, see todo.PopulatedTodo.RenderContent.
+func (s *Snapshotter) Render(format snapshot.Format, doc *snapshot.Document) ([]byte, error) {
+	switch format {
+	case snapshot.FormatJSON:
+		body, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal snapshot: %w", err)
+		}
+		return body, nil
+	case snapshot.FormatHTML:
+		var buf bytes.Buffer
+		if err := s.html.Execute(&buf, doc); err != nil {
+			return nil, fmt.Errorf("failed to render snapshot: %w", err)
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("unknown snapshot format %q", format)
+	}
+}
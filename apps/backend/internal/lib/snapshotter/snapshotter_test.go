@@ -0,0 +1,91 @@
+package snapshotter_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/lib/snapshotter"
+	"github.com/mabhi256/tasker/internal/model/comment"
+	"github.com/mabhi256/tasker/internal/model/snapshot"
+	"github.com/mabhi256/tasker/internal/model/todo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSnapshotter(t *testing.T) *snapshotter.Snapshotter {
+	t.Helper()
+
+	s, err := snapshotter.New()
+	require.NoError(t, err)
+	return s
+}
+
+func document() *snapshot.Document {
+	due := time.Date(2025, 1, 12, 15, 0, 0, 0, time.UTC)
+	description := "Check the **revenue** numbers <script>alert(1)</script>"
+	size := int64(2048)
+	mimeType := "application/pdf"
+
+	populated := &todo.PopulatedTodo{
+		Todo: todo.Todo{
+			Title:       "Finish the <quarterly> report",
+			Description: &description,
+			Status:      todo.StatusActive,
+			Priority:    todo.PriorityHigh,
+			DueDate:     &due,
+			UserID:      "user-1",
+		},
+		Comments: []comment.Comment{
+			{UserID: "user-2", Content: "Looks good"},
+		},
+		Attachments: []todo.TodoAttachment{
+			{Name: "report.pdf", UploadedBy: "user-1", FileSize: &size, MimeType: &mimeType},
+		},
+	}
+	populated.RenderContent()
+
+	return &snapshot.Document{
+		SnapshotID:  uuid.MustParse("123e4567-e89b-12d3-a456-426614174000"),
+		TakenAt:     time.Date(2025, 1, 10, 9, 30, 0, 0, time.UTC),
+		TakenBy:     "user-1",
+		WorkspaceID: uuid.New(),
+		Todo:        populated,
+	}
+}
+
+func TestRenderHTML(t *testing.T) {
+	body, err := newSnapshotter(t).Render(snapshot.FormatHTML, document())
+	require.NoError(t, err)
+
+	html := string(body)
+	assert.Contains(t, html, "<title>Finish the &lt;quarterly&gt; report</title>")
+	assert.Contains(t, html, "<strong>revenue</strong>")
+	assert.NotContains(t, html, "<script>")
+	assert.Contains(t, html, "January 12, 2025 at 3:00 PM UTC")
+	assert.Contains(t, html, "Looks good")
+	assert.Contains(t, html, "report.pdf")
+	assert.Contains(t, html, "2048 bytes")
+	assert.Contains(t, html, "application/pdf")
+	assert.Contains(t, html, "Snapshot 123e4567-e89b-12d3-a456-426614174000")
+}
+
+func TestRenderJSON(t *testing.T) {
+	doc := document()
+
+	body, err := newSnapshotter(t).Render(snapshot.FormatJSON, doc)
+	require.NoError(t, err)
+
+	var decoded snapshot.Document
+	require.NoError(t, json.Unmarshal(body, &decoded))
+	assert.Equal(t, doc.SnapshotID, decoded.SnapshotID)
+	assert.Equal(t, doc.Todo.Title, decoded.Todo.Title)
+	require.Len(t, decoded.Todo.Attachments, 1)
+	assert.Equal(t, "report.pdf", decoded.Todo.Attachments[0].Name)
+}
+
+func TestRenderUnknownFormat(t *testing.T) {
+	_, err := newSnapshotter(t).Render(snapshot.Format("pdf"), document())
+	assert.Error(t, err)
+}
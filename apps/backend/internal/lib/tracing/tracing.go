@@ -0,0 +1,56 @@
+// Package tracing carries New Relic distributed traces across work that
+// leaves the request, such as queued jobs and outbox events, so a whole
+// user visible flow shows up as one trace.
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+// Headers returns the distributed tracing headers of the transaction in
+// ctx, to be stored with the work it hands off. It returns nil outside a
+// transaction.
+func Headers(ctx context.Context) map[string]string {
+	txn := newrelic.FromContext(ctx)
+	if txn == nil {
+		return nil
+	}
+
+	headers := http.Header{}
+	txn.InsertDistributedTraceHeaders(headers)
+	if len(headers) == 0 {
+		return nil
+	}
+
+	trace := make(map[string]string, len(headers))
+	for key := range headers {
+		trace[key] = headers.Get(key)
+	}
+	return trace
+}
+
+// StartTransaction starts a background transaction that continues the
+// trace headers were taken from, and returns a context carrying it. The
+// transaction is nil, which is safe to use, when app is.
+func StartTransaction(ctx context.Context, app *newrelic.Application, name string,
+	transport newrelic.TransportType, trace map[string]string,
+) (context.Context, *newrelic.Transaction) {
+	if app == nil {
+		return ctx, nil
+	}
+
+	txn := app.StartTransaction(name)
+
+	if len(trace) > 0 {
+		headers := http.Header{}
+		for key, value := range trace {
+			headers.Set(key, value)
+		}
+		txn.AcceptDistributedTraceHeaders(transport, headers)
+	}
+
+	return newrelic.NewContext(ctx, txn), txn
+}
@@ -0,0 +1,168 @@
+// Package usage attributes resource use to the workspaces that cause it, for
+// cost attribution. Work is charged to the workspace carried by its context:
+// requests get it from the workspace middleware, and tasks from the context
+// they were enqueued with.
+//
+// A Meter only counts in memory. Its counters are drained periodically into
+// the workspace_usage_daily table, where every instance adds its own.
+package usage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Unattributed collects use that no workspace could be charged for, such as
+// per-user emails and housekeeping queries
+var Unattributed = uuid.Nil
+
+type workspaceKey struct{}
+
+// WithWorkspace charges work done with the returned context to workspaceID
+func WithWorkspace(ctx context.Context, workspaceID uuid.UUID) context.Context {
+	return context.WithValue(ctx, workspaceKey{}, workspaceID)
+}
+
+// WorkspaceFromContext returns the workspace work done with ctx is charged
+// to, or Unattributed
+func WorkspaceFromContext(ctx context.Context) uuid.UUID {
+	if workspaceID, ok := ctx.Value(workspaceKey{}).(uuid.UUID); ok {
+		return workspaceID
+	}
+	return Unattributed
+}
+
+// Counters is the use counted for a workspace on a day
+type Counters struct {
+	DBTime        time.Duration
+	JobExecutions int64
+	EmailsSent    int64
+	APIRequests   int64
+}
+
+// Record is a workspace's counters for a UTC day
+type Record struct {
+	Day         time.Time
+	WorkspaceID uuid.UUID
+	Counters
+}
+
+type key struct {
+	day         time.Time
+	workspaceID uuid.UUID
+}
+
+// Meter counts use per workspace and UTC day. A nil Meter counts nothing.
+type Meter struct {
+	mu       sync.Mutex
+	counters map[key]*Counters
+}
+
+func NewMeter() *Meter {
+	return &Meter{counters: make(map[key]*Counters)}
+}
+
+func (m *Meter) add(ctx context.Context, update func(c *Counters)) {
+	if m == nil {
+		return
+	}
+
+	k := key{
+		day:         time.Now().UTC().Truncate(24 * time.Hour),
+		workspaceID: WorkspaceFromContext(ctx),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.counters[k]
+	if !ok {
+		c = &Counters{}
+		m.counters[k] = c
+	}
+	update(c)
+}
+
+func (m *Meter) AddDBTime(ctx context.Context, d time.Duration) {
+	m.add(ctx, func(c *Counters) { c.DBTime += d })
+}
+
+func (m *Meter) AddJobExecution(ctx context.Context) {
+	m.add(ctx, func(c *Counters) { c.JobExecutions++ })
+}
+
+func (m *Meter) AddEmailSent(ctx context.Context) {
+	m.add(ctx, func(c *Counters) { c.EmailsSent++ })
+}
+
+func (m *Meter) AddAPIRequest(ctx context.Context) {
+	m.add(ctx, func(c *Counters) { c.APIRequests++ })
+}
+
+// Drain returns the counters and resets them
+func (m *Meter) Drain() []Record {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	counters := m.counters
+	m.counters = make(map[key]*Counters)
+	m.mu.Unlock()
+
+	records := make([]Record, 0, len(counters))
+	for k, c := range counters {
+		records = append(records, Record{Day: k.day, WorkspaceID: k.workspaceID, Counters: *c})
+	}
+	return records
+}
+
+// Restore adds back records that were drained but couldn't be stored, so
+// they go out with the next drain
+func (m *Meter) Restore(records []Record) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, r := range records {
+		k := key{day: r.Day, workspaceID: r.WorkspaceID}
+		c, ok := m.counters[k]
+		if !ok {
+			c = &Counters{}
+			m.counters[k] = c
+		}
+		c.DBTime += r.DBTime
+		c.JobExecutions += r.JobExecutions
+		c.EmailsSent += r.EmailsSent
+		c.APIRequests += r.APIRequests
+	}
+}
+
+type queryStartKey struct{}
+
+// QueryTracer returns a pgx tracer that charges the time of each query to
+// the workspace in its context
+func (m *Meter) QueryTracer() pgx.QueryTracer {
+	return &queryTracer{meter: m}
+}
+
+type queryTracer struct {
+	meter *Meter
+}
+
+func (t *queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryStartKey{}, time.Now())
+}
+
+func (t *queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryEndData) {
+	if start, ok := ctx.Value(queryStartKey{}).(time.Time); ok {
+		t.meter.AddDBTime(ctx, time.Since(start))
+	}
+}
@@ -0,0 +1,65 @@
+package usage_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/lib/usage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeterChargesContextWorkspace(t *testing.T) {
+	meter := usage.NewMeter()
+	workspaceID := uuid.New()
+	ctx := usage.WithWorkspace(context.Background(), workspaceID)
+
+	meter.AddDBTime(ctx, 2*time.Second)
+	meter.AddDBTime(ctx, time.Second)
+	meter.AddJobExecution(ctx)
+	meter.AddAPIRequest(ctx)
+	meter.AddEmailSent(context.Background())
+
+	records := meter.Drain()
+	require.Len(t, records, 2)
+
+	byWorkspace := map[uuid.UUID]usage.Counters{}
+	for _, r := range records {
+		assert.Equal(t, time.Now().UTC().Truncate(24*time.Hour), r.Day)
+		byWorkspace[r.WorkspaceID] = r.Counters
+	}
+
+	assert.Equal(t, usage.Counters{DBTime: 3 * time.Second, JobExecutions: 1, APIRequests: 1}, byWorkspace[workspaceID])
+	assert.Equal(t, usage.Counters{EmailsSent: 1}, byWorkspace[usage.Unattributed])
+
+	// Draining resets the counters
+	assert.Empty(t, meter.Drain())
+}
+
+func TestMeterRestore(t *testing.T) {
+	meter := usage.NewMeter()
+	ctx := usage.WithWorkspace(context.Background(), uuid.New())
+
+	meter.AddJobExecution(ctx)
+	records := meter.Drain()
+
+	// Use counted while the drained records were being stored is kept
+	meter.AddJobExecution(ctx)
+	meter.Restore(records)
+
+	restored := meter.Drain()
+	require.Len(t, restored, 1)
+	assert.Equal(t, int64(2), restored[0].JobExecutions)
+}
+
+func TestNilMeter(t *testing.T) {
+	var meter *usage.Meter
+
+	assert.NotPanics(t, func() {
+		meter.AddEmailSent(context.Background())
+		meter.Restore([]usage.Record{{}})
+	})
+	assert.Nil(t, meter.Drain())
+}
@@ -75,19 +75,7 @@ func NewLogger(level string, isProd bool) zerolog.Logger {
 }
 
 func NewLoggerWithService(cfg *config.ObservabilityConfig, loggerService *LoggerService) zerolog.Logger {
-	var logLevel zerolog.Level
-	switch cfg.GetLogLevel() {
-	case "debug":
-		logLevel = zerolog.DebugLevel
-	case "info":
-		logLevel = zerolog.InfoLevel
-	case "warn":
-		logLevel = zerolog.WarnLevel
-	case "error":
-		logLevel = zerolog.ErrorLevel
-	default:
-		logLevel = zerolog.InfoLevel
-	}
+	SetLevel(cfg.GetLogLevel())
 
 	zerolog.TimeFieldFormat = "1000-01-01 10:00:00"
 	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
@@ -110,8 +98,10 @@ func NewLoggerWithService(cfg *config.ObservabilityConfig, loggerService *Logger
 	}
 
 	// Note: New Relic log forwarding is now handled automatically by zerologWriter integration
+	// The level is set globally, so SetLevel changes it for the loggers
+	// derived from this one too. Debug is the lowest it can be set to.
 	logger := zerolog.New(writer).
-		Level(logLevel).
+		Level(zerolog.DebugLevel).
 		With().Timestamp().
 		Str("service", cfg.ServiceName).
 		Str("environment", cfg.Environment).
@@ -125,6 +115,27 @@ func NewLoggerWithService(cfg *config.ObservabilityConfig, loggerService *Logger
 	return logger
 }
 
+// SetLevel sets the level logged at, which is one of debug, info, warn and
+// error, and info for anything else
+func SetLevel(level string) {
+	zerolog.SetGlobalLevel(parseLevel(level))
+}
+
+func parseLevel(level string) zerolog.Level {
+	switch level {
+	case "debug":
+		return zerolog.DebugLevel
+	case "info":
+		return zerolog.InfoLevel
+	case "warn":
+		return zerolog.WarnLevel
+	case "error":
+		return zerolog.ErrorLevel
+	default:
+		return zerolog.InfoLevel
+	}
+}
+
 // WithTraceContext adds New Relic transaction context to logger
 func WithTraceContext(logger zerolog.Logger, txn *newrelic.Transaction) zerolog.Logger {
 	if txn == nil {
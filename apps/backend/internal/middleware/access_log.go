@@ -0,0 +1,179 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/config"
+	"github.com/mabhi256/tasker/internal/lib/redact"
+	"github.com/mabhi256/tasker/internal/reqctx"
+	"github.com/rs/zerolog"
+)
+
+// AccessLog logs each request with its method, path, status, latency, body
+// sizes and user. Requests are sampled by status class as configured, and
+// at the debug level their request and response bodies are logged too.
+// Sensitive fields are redacted from the query string and bodies before
+// anything is logged.
+func (global *GlobalMiddlewares) AccessLog() echo.MiddlewareFunc {
+	cfg := global.server.Config.Observability.Logging.Access
+	if cfg == nil {
+		cfg = config.DefaultAccessLogConfig()
+	}
+	redactor := redact.New(cfg.RedactFields...)
+	captureBodies := global.server.Config.Observability.GetLogLevel() == "debug"
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			res := c.Response()
+
+			var reqBody, resBody *cappedBuffer
+			if captureBodies {
+				reqBody = &cappedBuffer{limit: cfg.MaxBodySize}
+				req.Body = &teeReadCloser{ReadCloser: req.Body, w: reqBody}
+
+				resBody = &cappedBuffer{limit: cfg.MaxBodySize}
+				res.Writer = &capturingWriter{ResponseWriter: res.Writer, w: resBody}
+			}
+
+			start := time.Now()
+			err := next(c)
+			latency := time.Since(start)
+
+			status := responseStatus(res.Status, err)
+			if !sampled(cfg.SampleRates, status) {
+				return err
+			}
+
+			// Get enhanced logger from context
+			logger := GetLogger(c)
+
+			var e *zerolog.Event
+			switch {
+			case status >= 500:
+				e = logger.Error().Err(err)
+			case status >= 400:
+				e = logger.Warn()
+			default:
+				e = logger.Info()
+			}
+
+			// Add request ID if available
+			if requestID := GetRequestID(c); requestID != "" {
+				e = e.Str(reqctx.RequestID.Name(), requestID)
+			}
+
+			// Add user context if available
+			if userID := GetUserID(c); userID != "" {
+				e = e.Str(reqctx.UserID.Name(), userID)
+			}
+
+			uri := req.URL.Path
+			if query := redactor.Query(req.URL.RawQuery); query != "" {
+				uri += "?" + query
+			}
+
+			e = e.Dur("latency", latency).
+				Int("status", status).
+				Str("method", req.Method).
+				Str("path", c.Path()).
+				Str("uri", uri).
+				Str("host", req.Host).
+				Str("ip", c.RealIP()).
+				Str("user_agent", req.UserAgent()).
+				Int64("bytes_in", req.ContentLength).
+				Int64("bytes_out", res.Size)
+
+			if captureBodies {
+				e = e.Str("request_body", redactor.Body(req.Header.Get(echo.HeaderContentType), reqBody.Bytes())).
+					Str("response_body", redactor.Body(res.Header().Get(echo.HeaderContentType), resBody.Bytes()))
+			}
+
+			e.Msg("API")
+
+			return err
+		}
+	}
+}
+
+// responseStatus is the status the request is answered with. A handler's
+// error is only written by the global error handler once the middleware
+// returned, so its status is taken from the error.
+// See https://github.com/labstack/echo/issues/2310#issuecomment-1288196898
+func responseStatus(written int, err error) int {
+	if err == nil {
+		return written
+	}
+	return errorStatus(err)
+}
+
+// sampled decides whether a request answered with status is logged
+func sampled(rates map[string]float64, status int) bool {
+	rate, ok := rates[fmt.Sprintf("%dxx", status/100)]
+	if !ok {
+		return true
+	}
+	return rand.Float64() < rate
+}
+
+// cappedBuffer keeps the first limit bytes written to it and drops the rest
+type cappedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); room > 0 {
+		b.Buffer.Write(p[:min(room, len(p))])
+	}
+	return len(p), nil
+}
+
+// teeReadCloser copies what is read from the request body to w
+type teeReadCloser struct {
+	io.ReadCloser
+	w io.Writer
+}
+
+func (t *teeReadCloser) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	if n > 0 {
+		_, _ = t.w.Write(p[:n])
+	}
+	return n, err
+}
+
+// capturingWriter copies the response body to w. It keeps flushing and
+// hijacking available, which streamed responses and websockets need.
+type capturingWriter struct {
+	http.ResponseWriter
+	w io.Writer
+}
+
+func (cw *capturingWriter) Write(p []byte) (int, error) {
+	n, err := cw.ResponseWriter.Write(p)
+	if n > 0 {
+		_, _ = cw.w.Write(p[:n])
+	}
+	return n, err
+}
+
+func (cw *capturingWriter) Flush() {
+	_ = http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+func (cw *capturingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(cw.ResponseWriter).Hijack()
+}
+
+func (cw *capturingWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
@@ -1,30 +1,213 @@
 package middleware
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"slices"
+	"strings"
 	"time"
 
-	"github.com/clerk/clerk-sdk-go/v2"
-	clerkhttp "github.com/clerk/clerk-sdk-go/v2/http"
 	"github.com/labstack/echo/v4"
 	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/lib/authn"
+	"github.com/mabhi256/tasker/internal/model/admin"
+	"github.com/mabhi256/tasker/internal/model/apikey"
+	"github.com/mabhi256/tasker/internal/model/session"
+	"github.com/mabhi256/tasker/internal/model/trial"
+	"github.com/mabhi256/tasker/internal/reqctx"
 	"github.com/mabhi256/tasker/internal/server"
+	"github.com/redis/go-redis/v9"
 )
 
+const (
+	// RoleAdmin is the role granted access to the admin API
+	RoleAdmin = authn.RoleAdmin
+
+	ImpersonationHeader = "X-Impersonation-Token"
+)
+
+// Scopes machine tokens need for the admin route groups they can call
+const (
+	ScopeUsage     = "admin:usage"
+	ScopeJobs      = "admin:jobs"
+	ScopeRollouts  = "admin:rollouts"
+	ScopeBackfills = "admin:backfills"
+)
+
+// APIKeyResolver looks up the API key a request was made with. Unknown keys
+// are reported as a not found error.
+type APIKeyResolver interface {
+	ResolveAPIKey(ctx context.Context, key string) (*apikey.Key, error)
+}
+
+// TrialResolver looks up the anonymous trial a request was made with.
+// Unknown, expired and claimed trials are reported as a not found error.
+type TrialResolver interface {
+	ResolveTrial(ctx context.Context, token string) (*trial.Trial, error)
+}
+
 type AuthMiddleware struct {
-	server *server.Server
+	server      *server.Server
+	consistency *ConsistencyMiddleware
+	apiKeys     APIKeyResolver
+	trials      TrialResolver
 }
 
-func NewAuthMiddleware(s *server.Server) *AuthMiddleware {
-	return &AuthMiddleware{server: s}
+func NewAuthMiddleware(s *server.Server, consistency *ConsistencyMiddleware, apiKeys APIKeyResolver,
+	trials TrialResolver,
+) *AuthMiddleware {
+	return &AuthMiddleware{server: s, consistency: consistency, apiKeys: apiKeys, trials: trials}
 }
 
+// RequireAuth authenticates the user by their session, by one of their API
+// keys, or as an anonymous trial by its token. Single sign-on is enforced by
+// each workspace, see WorkspaceMiddleware. Authenticated requests also read
+// their own writes, see ConsistencyMiddleware.
 func (auth *AuthMiddleware) RequireAuth(next echo.HandlerFunc) echo.HandlerFunc {
-	clerkMiddleware := clerkhttp.WithHeaderAuthorization(
-		clerkhttp.AuthorizationFailureHandler(auth.handleAuthFailure()),
-	)
-	return echo.WrapMiddleware(clerkMiddleware)(auth.authSuccessHandler(next))
+	return auth.requireAuth(next, true)
+}
+
+// RequireSession authenticates the user by their session only, for the
+// routes an API key or a trial must not reach, such as managing API keys
+func (auth *AuthMiddleware) RequireSession(next echo.HandlerFunc) echo.HandlerFunc {
+	return auth.requireAuth(next, false)
+}
+
+func (auth *AuthMiddleware) requireAuth(next echo.HandlerFunc, allowTokens bool) echo.HandlerFunc {
+	sessionAuth := auth.authSuccessHandler(auth.consistency.ReadYourWrites(next))
+	if !allowTokens || (auth.apiKeys == nil && auth.trials == nil) {
+		return sessionAuth
+	}
+
+	var keyAuth, trialAuth echo.HandlerFunc
+	if auth.apiKeys != nil {
+		keyAuth = auth.apiKeyHandler(auth.consistency.ReadYourWrites(next))
+	}
+	if auth.trials != nil {
+		trialAuth = auth.trialHandler(auth.consistency.ReadYourWrites(next))
+	}
+	return func(c echo.Context) error {
+		// API keys and trial tokens are told apart from session tokens by
+		// their prefix
+		token := bearerToken(c)
+		switch {
+		case keyAuth != nil && strings.HasPrefix(token, apikey.Prefix):
+			return keyAuth(c)
+		case trialAuth != nil && strings.HasPrefix(token, trial.Prefix):
+			return trialAuth(c)
+		default:
+			return sessionAuth(c)
+		}
+	}
+}
+
+func bearerToken(c echo.Context) string {
+	token, _ := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+	return token
+}
+
+// apiKeyHandler authenticates the request as the user who created the API
+// key. A key has no session, so it can't impersonate or sign in with a
+// workspace's identity provider, and no organization role, so it can't reach
+// the admin API.
+func (auth *AuthMiddleware) apiKeyHandler(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+		key, err := auth.apiKeys.ResolveAPIKey(c.Request().Context(), bearerToken(c))
+		if err != nil {
+			var httpErr *errs.HTTPError
+			if errors.As(err, &httpErr) && httpErr.Status == http.StatusNotFound {
+				auth.server.Logger.Warn().
+					Str("function", "RequireAuth").
+					Str("request_id", GetRequestID(c)).
+					Str("ip", c.RealIP()).
+					Msg("unknown API key")
+				return errs.NewUnauthorizedError("Invalid API key", false)
+			}
+
+			auth.server.Logger.Error().
+				Err(err).
+				Str("function", "RequireAuth").
+				Str("request_id", GetRequestID(c)).
+				Msg("could not resolve API key")
+			return err
+		}
+
+		if c.Request().Header.Get(ImpersonationHeader) != "" {
+			return errs.NewForbiddenError("API keys cannot impersonate", false)
+		}
+
+		if !key.Scope.Allows(c.Request().Method) {
+			auth.server.Logger.Warn().
+				Str("function", "RequireAuth").
+				Str("user_id", key.UserID).
+				Str("api_key_id", key.ID.String()).
+				Str("request_id", GetRequestID(c)).
+				Str("method", c.Request().Method).
+				Msg("write request made with a read-only API key")
+			return errs.NewForbiddenError("API key is read-only", false)
+		}
+
+		reqctx.UserID.Set(c, key.UserID)
+		reqctx.APIKeyID.Set(c, key.ID.String())
+
+		auth.server.Logger.Info().
+			Str("function", "RequireAuth").
+			Str("user_id", key.UserID).
+			Str("api_key_id", key.ID.String()).
+			Str("request_id", GetRequestID(c)).
+			Dur("duration", time.Since(start)).
+			Msg("user authenticated with API key")
+
+		return next(c)
+	}
+}
+
+// trialHandler authenticates the request as the user the trial acts as. A
+// trial has no session or organization role, so like an API key it can't
+// impersonate, sign in with a workspace's identity provider or reach the
+// admin API.
+func (auth *AuthMiddleware) trialHandler(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+		item, err := auth.trials.ResolveTrial(c.Request().Context(), bearerToken(c))
+		if err != nil {
+			var httpErr *errs.HTTPError
+			if errors.As(err, &httpErr) && httpErr.Status == http.StatusNotFound {
+				auth.server.Logger.Warn().
+					Str("function", "RequireAuth").
+					Str("request_id", GetRequestID(c)).
+					Str("ip", c.RealIP()).
+					Msg("unknown, expired or claimed trial token")
+				return errs.NewUnauthorizedError("Trial has expired or was claimed", false)
+			}
+
+			auth.server.Logger.Error().
+				Err(err).
+				Str("function", "RequireAuth").
+				Str("request_id", GetRequestID(c)).
+				Msg("could not resolve trial")
+			return err
+		}
+
+		if c.Request().Header.Get(ImpersonationHeader) != "" {
+			return errs.NewForbiddenError("Trials cannot impersonate", false)
+		}
+
+		reqctx.UserID.Set(c, item.UserID)
+
+		auth.server.Logger.Info().
+			Str("function", "RequireAuth").
+			Str("user_id", item.UserID).
+			Str("trial_id", item.ID.String()).
+			Str("request_id", GetRequestID(c)).
+			Dur("duration", time.Since(start)).
+			Msg("user authenticated with trial token")
+
+		return next(c)
+	}
 }
 
 func (auth *AuthMiddleware) handleAuthFailure() http.HandlerFunc {
@@ -46,11 +229,6 @@ func (auth *AuthMiddleware) handleAuthFailure() http.HandlerFunc {
 				Str("function", "RequireAuth").
 				Dur("duration", time.Since(start)).
 				Msg("failed to write JSON response")
-		} else {
-			auth.server.Logger.Error().
-				Str("function", "RequireAuth").
-				Dur("duration", time.Since(start)).
-				Msg("could not get session claims from context")
 		}
 	}
 }
@@ -58,23 +236,66 @@ func (auth *AuthMiddleware) handleAuthFailure() http.HandlerFunc {
 func (auth *AuthMiddleware) authSuccessHandler(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		start := time.Now()
-		claims, ok := clerk.SessionClaimsFromContext(c.Request().Context())
-		if !ok {
+		identity, err := auth.server.Authenticator.Authenticate(c.Request().Context(), c.Request())
+		if err != nil {
+			if errors.Is(err, authn.ErrNoCredentials) {
+				auth.server.Logger.Error().
+					Str("function", "RequireAuth").
+					Str("request_id", GetRequestID(c)).
+					Dur("duration", time.Since(start)).
+					Msg("request has no credentials")
+				return errs.NewUnauthorizedError("Unauthorized", false)
+			}
+
 			auth.server.Logger.Error().
+				Err(err).
 				Str("function", "RequireAuth").
 				Str("request_id", GetRequestID(c)).
-				Dur("duration", time.Since(start)).
-				Msg("could not get session claims from context")
-			return errs.NewUnauthorizedError("Unauthorized", false)
+				Msg("could not authenticate request")
+			auth.handleAuthFailure()(c.Response(), c.Request())
+			return nil
+		}
+
+		if auth.isRevoked(c, identity.SessionID) {
+			auth.server.Logger.Warn().
+				Str("function", "RequireAuth").
+				Str("user_id", identity.UserID).
+				Str("session_id", identity.SessionID).
+				Str("request_id", GetRequestID(c)).
+				Msg("token of a revoked session used")
+			return errs.NewUnauthorizedError("Session has been revoked", false)
+		}
+
+		userID, role, permissions := identity.UserID, identity.Role, identity.Permissions
+		if token := c.Request().Header.Get(ImpersonationHeader); token != "" {
+			session, err := auth.resolveImpersonation(c, identity, token)
+			if err != nil {
+				return err
+			}
+
+			// The admin's role and permissions stay with the admin, the
+			// impersonated user acts with none
+			userID, role, permissions = session.UserID, "", nil
+			reqctx.ImpersonatorID.Set(c, identity.UserID)
+
+			auth.server.Logger.Warn().
+				Str("function", "RequireAuth").
+				Str("impersonator_id", identity.UserID).
+				Str("user_id", session.UserID).
+				Str("request_id", GetRequestID(c)).
+				Str("method", c.Request().Method).
+				Str("path", c.Path()).
+				Msg("request made on behalf of user")
 		}
 
-		c.Set("user_id", claims.Subject)
-		c.Set("user_role", claims.ActiveOrganizationRole)
-		c.Set("permission", claims.Claims.ActiveOrganizationPermissions)
+		reqctx.UserID.Set(c, userID)
+		reqctx.UserRole.Set(c, role)
+		reqctx.Permissions.Set(c, permissions)
+		reqctx.SessionID.Set(c, identity.SessionID)
 
 		auth.server.Logger.Info().
 			Str("function", "RequireAuth").
-			Str("user_id", claims.Subject).
+			Str("user_id", userID).
 			Str("request_id", GetRequestID(c)).
 			Dur("duration", time.Since(start)).
 			Msg("user authenticated successfully")
@@ -83,6 +304,172 @@ func (auth *AuthMiddleware) authSuccessHandler(next echo.HandlerFunc) echo.Handl
 	}
 }
 
+// isRevoked reports whether the session is on the revoked session denylist.
+// The auth provider revokes sessions too, just not the tokens it already
+// issued, so the check fails open when Redis can't be reached.
+func (auth *AuthMiddleware) isRevoked(c echo.Context, sessionID string) bool {
+	if auth.server.Redis == nil || sessionID == "" {
+		return false
+	}
+
+	n, err := auth.server.Redis.Exists(c.Request().Context(), session.RevokedKey(sessionID)).Result()
+	if err != nil {
+		auth.server.Logger.Error().
+			Err(err).
+			Str("function", "RequireAuth").
+			Str("request_id", GetRequestID(c)).
+			Msg("could not check the revoked session denylist")
+		return false
+	}
+
+	return n > 0
+}
+
+// resolveImpersonation loads the impersonation session for the token and checks
+// that it was started by the authenticated user, who must still be an admin
+func (auth *AuthMiddleware) resolveImpersonation(c echo.Context, identity *authn.Identity, token string) (*admin.ImpersonationSession, error) {
+	if identity.Role != RoleAdmin {
+		auth.server.Logger.Warn().
+			Str("function", "RequireAuth").
+			Str("user_id", identity.UserID).
+			Str("user_role", identity.Role).
+			Str("request_id", GetRequestID(c)).
+			Msg("impersonation token used by a non-admin")
+		return nil, errs.NewForbiddenError("Impersonation session is invalid or expired", false)
+	}
+
+	if auth.server.Redis == nil {
+		return nil, errs.NewForbiddenError("Impersonation is unavailable", false)
+	}
+
+	raw, err := auth.server.Redis.Get(c.Request().Context(), admin.ImpersonationKey(token)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, errs.NewForbiddenError("Impersonation session is invalid or expired", false)
+		}
+		return nil, err
+	}
+
+	var session admin.ImpersonationSession
+	if err := json.Unmarshal(raw, &session); err != nil {
+		return nil, err
+	}
+
+	if session.AdminID != identity.UserID {
+		auth.server.Logger.Warn().
+			Str("function", "RequireAuth").
+			Str("user_id", identity.UserID).
+			Str("request_id", GetRequestID(c)).
+			Msg("impersonation token used by a different user")
+		return nil, errs.NewForbiddenError("Impersonation session is invalid or expired", false)
+	}
+
+	return &session, nil
+}
+
+// RequireAdmin allows authenticated admins and, when scopes are given,
+// machine tokens granted all of them. Machine tokens act on no user's
+// behalf, so they only reach the route groups that name their scopes.
+func (auth *AuthMiddleware) RequireAdmin(scopes ...string) echo.MiddlewareFunc {
+	requireRole := auth.RequireRole(RoleAdmin)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		userAuth := auth.RequireAuth(requireRole(next))
+		if len(scopes) == 0 || auth.server.Machine == nil {
+			return userAuth
+		}
+
+		machineAuth := auth.RequireMachine(scopes...)(next)
+		return func(c echo.Context) error {
+			if auth.server.Machine.Accepts(c.Request()) {
+				return machineAuth(c)
+			}
+			return userAuth(c)
+		}
+	}
+}
+
+// RequireMachine allows only machine tokens granted all of the scopes, for
+// internal services and CI scripts calling the API without a user
+func (auth *AuthMiddleware) RequireMachine(scopes ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			if auth.server.Machine == nil {
+				return errs.NewUnauthorizedError("Machine tokens are not accepted", false)
+			}
+
+			identity, err := auth.server.Machine.Authenticate(c.Request().Context(), c.Request())
+			if err != nil {
+				if errors.Is(err, authn.ErrNoCredentials) {
+					return errs.NewUnauthorizedError("Unauthorized", false)
+				}
+
+				auth.server.Logger.Error().
+					Err(err).
+					Str("function", "RequireMachine").
+					Str("request_id", GetRequestID(c)).
+					Msg("could not authenticate machine token")
+				auth.handleAuthFailure()(c.Response(), c.Request())
+				return nil
+			}
+
+			if !identity.HasScopes(scopes...) {
+				auth.server.Logger.Warn().
+					Str("function", "RequireMachine").
+					Str("client_id", identity.ClientID).
+					Str("request_id", GetRequestID(c)).
+					Strs("scopes", identity.Scopes).
+					Strs("required_scopes", scopes).
+					Msg("machine token does not have the required scopes")
+				return errs.NewForbiddenError("Forbidden", false)
+			}
+
+			reqctx.MachineClientID.Set(c, identity.ClientID)
+
+			auth.server.Logger.Info().
+				Str("function", "RequireMachine").
+				Str("client_id", identity.ClientID).
+				Str("request_id", GetRequestID(c)).
+				Dur("duration", time.Since(start)).
+				Msg("machine authenticated successfully")
+
+			return next(c)
+		}
+	}
+}
+
+// RequireRole allows the request only if the authenticated user has one of the given roles.
+// It must be registered after RequireAuth.
+func (auth *AuthMiddleware) RequireRole(roles ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			role := GetUserRole(c)
+
+			if impersonatorID := GetImpersonatorID(c); impersonatorID != "" {
+				auth.server.Logger.Warn().
+					Str("function", "RequireRole").
+					Str("impersonator_id", impersonatorID).
+					Str("request_id", GetRequestID(c)).
+					Msg("privileged route requested while impersonating")
+				return errs.NewForbiddenError("Privileged routes are unavailable while impersonating", false)
+			}
+
+			if !slices.Contains(roles, role) {
+				auth.server.Logger.Warn().
+					Str("function", "RequireRole").
+					Str("user_id", GetUserID(c)).
+					Str("user_role", role).
+					Str("request_id", GetRequestID(c)).
+					Strs("required_roles", roles).
+					Msg("user does not have the required role")
+				return errs.NewForbiddenError("Forbidden", false)
+			}
+
+			return next(c)
+		}
+	}
+}
+
 // todo: All logging must be done by a request loggin middleware
 // func (auth *AuthMiddleware) RequireAuth(next echo.HandlerFunc) echo.HandlerFunc {
 // 	return echo.WrapMiddleware(clerkhttp.WithHeaderAuthorization())(func(c echo.Context) error {
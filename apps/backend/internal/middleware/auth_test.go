@@ -0,0 +1,182 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/database"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/lib/authn"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model"
+	"github.com/mabhi256/tasker/internal/model/apikey"
+	"github.com/mabhi256/tasker/internal/model/trial"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAPIKeys resolves the keys it holds by their hash, like the service
+type fakeAPIKeys map[string]*apikey.Key
+
+func (f fakeAPIKeys) ResolveAPIKey(_ context.Context, key string) (*apikey.Key, error) {
+	item, ok := f[apikey.Hash(key)]
+	if !ok {
+		code := "API_KEY_NOT_FOUND"
+		return nil, errs.NewNotFoundError("API key not found", false, &code)
+	}
+	return item, nil
+}
+
+// fakeTrials resolves the trials it holds by their token
+type fakeTrials map[string]*trial.Trial
+
+func (f fakeTrials) ResolveTrial(_ context.Context, token string) (*trial.Trial, error) {
+	item, ok := f[token]
+	if !ok {
+		code := "TRIAL_NOT_FOUND"
+		return nil, errs.NewNotFoundError("Trial not found", false, &code)
+	}
+	return item, nil
+}
+
+func newTestServer() *server.Server {
+	logger := zerolog.Nop()
+	return &server.Server{Logger: &logger, DB: &database.Database{}}
+}
+
+// serveAuth sends a request with the bearer token through RequireAuth,
+// returning the status and the user the handler saw
+func serveAuth(t *testing.T, auth *middleware.AuthMiddleware, method, token string,
+	header http.Header,
+) (int, string) {
+	t.Helper()
+
+	e := echo.New()
+	req := httptest.NewRequest(method, "/", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	for key, vals := range header {
+		req.Header[key] = vals
+	}
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	var userID string
+	err := auth.RequireAuth(func(c echo.Context) error {
+		userID = middleware.GetUserID(c)
+		return c.NoContent(http.StatusNoContent)
+	})(c)
+	if err != nil {
+		var httpErr *errs.HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		return httpErr.Status, ""
+	}
+	return rec.Code, userID
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	s := newTestServer()
+
+	readOnly, err := apikey.Generate()
+	require.NoError(t, err)
+	readWrite, err := apikey.Generate()
+	require.NoError(t, err)
+
+	keys := fakeAPIKeys{
+		apikey.Hash(readOnly): {
+			BaseWithId: model.BaseWithId{ID: uuid.New()},
+			UserID:     "user-1",
+			Scope:      apikey.ScopeReadOnly,
+		},
+		apikey.Hash(readWrite): {
+			BaseWithId: model.BaseWithId{ID: uuid.New()},
+			UserID:     "user-2",
+			Scope:      apikey.ScopeReadWrite,
+		},
+	}
+	auth := middleware.NewAuthMiddleware(s, middleware.NewConsistencyMiddleware(s), keys, nil)
+
+	impersonating := http.Header{middleware.ImpersonationHeader: []string{"token"}}
+	unknown := apikey.Prefix + "unknown"
+
+	tests := []struct {
+		name   string
+		method string
+		token  string
+		header http.Header
+		status int
+		userID string
+	}{
+		{name: "read with read-only key", method: http.MethodGet, token: readOnly, status: http.StatusNoContent, userID: "user-1"},
+		{name: "write with read-only key", method: http.MethodPost, token: readOnly, status: http.StatusForbidden},
+		{name: "delete with read-only key", method: http.MethodDelete, token: readOnly, status: http.StatusForbidden},
+		{name: "write with read-write key", method: http.MethodPatch, token: readWrite, status: http.StatusNoContent, userID: "user-2"},
+		{name: "unknown key", method: http.MethodGet, token: unknown, status: http.StatusUnauthorized},
+		{
+			name:   "impersonating with key",
+			method: http.MethodGet,
+			token:  readWrite,
+			header: impersonating,
+			status: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, userID := serveAuth(t, auth, tt.method, tt.token, tt.header)
+			assert.Equal(t, tt.status, status)
+			assert.Equal(t, tt.userID, userID)
+		})
+	}
+}
+
+// fakeAuthenticator authenticates every request as its identity
+type fakeAuthenticator struct {
+	identity *authn.Identity
+}
+
+func (f fakeAuthenticator) Authenticate(context.Context, *http.Request) (*authn.Identity, error) {
+	return f.identity, nil
+}
+
+func TestImpersonationRequiresAdmin(t *testing.T) {
+	impersonating := http.Header{middleware.ImpersonationHeader: []string{"token"}}
+
+	tests := []struct {
+		name    string
+		role    string
+		message string
+	}{
+		// Without Redis the admin gets as far as looking up the session
+		{name: "admin", role: middleware.RoleAdmin, message: "Impersonation is unavailable"},
+		{name: "demoted admin", role: "member", message: "Impersonation session is invalid or expired"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer()
+			s.Authenticator = fakeAuthenticator{identity: &authn.Identity{UserID: "admin-1", Role: tt.role}}
+			auth := middleware.NewAuthMiddleware(s, middleware.NewConsistencyMiddleware(s), nil, nil)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(echo.HeaderAuthorization, "Bearer session-token")
+			for key, vals := range impersonating {
+				req.Header[key] = vals
+			}
+			c := e.NewContext(req, httptest.NewRecorder())
+
+			err := auth.RequireAuth(func(c echo.Context) error {
+				return c.NoContent(http.StatusNoContent)
+			})(c)
+
+			var httpErr *errs.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, http.StatusForbidden, httpErr.Status)
+			assert.Equal(t, tt.message, httpErr.Message)
+		})
+	}
+}
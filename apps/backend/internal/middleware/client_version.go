@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/lib/clientversion"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// minVersion is the oldest version of a platform served
+type minVersion struct {
+	version    clientversion.Version
+	upgradeURL string
+}
+
+// ClientVersionMiddleware turns away clients older than their platform's
+// configured minimum version, whose sync logic would corrupt data
+type ClientVersionMiddleware struct {
+	server *server.Server
+	// minimums maps a lowercase platform to its minimum version
+	minimums map[string]minVersion
+}
+
+func NewClientVersionMiddleware(s *server.Server) *ClientVersionMiddleware {
+	minimums := make(map[string]minVersion)
+
+	// Versions were validated when the config was loaded
+	if cfg := s.Config.ClientVersions; cfg != nil {
+		for platform, p := range cfg.Platforms {
+			client, err := clientversion.Parse(platform + "/" + p.MinVersion)
+			if err != nil {
+				continue
+			}
+			minimums[client.Platform] = minVersion{version: client.Version, upgradeURL: p.UpgradeURL}
+		}
+	}
+
+	return &ClientVersionMiddleware{
+		server:   s,
+		minimums: minimums,
+	}
+}
+
+// RequireMinVersion answers requests from obsolete clients with a 426
+// UPGRADE_REQUIRED error, whose action sends them to upgrade. Requests that
+// name no client, or one that doesn't parse, are served, as browsers and
+// API users don't send the header.
+func (m *ClientVersionMiddleware) RequireMinVersion() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if len(m.minimums) == 0 {
+				return next(c)
+			}
+
+			header := c.Request().Header.Get(clientversion.Header)
+			if header == "" {
+				return next(c)
+			}
+
+			client, err := clientversion.Parse(header)
+			if err != nil {
+				GetLogger(c).Debug().Err(err).Str("client_version", header).Msg("ignoring invalid client version")
+				return next(c)
+			}
+
+			minimum, ok := m.minimums[client.Platform]
+			if !ok || client.Version.Compare(minimum.version) >= 0 {
+				return next(c)
+			}
+
+			var action *errs.Action
+			if minimum.upgradeURL != "" {
+				action = &errs.Action{
+					Type:    errs.ActionTypeRedirect,
+					Message: "Update the app to continue",
+					Value:   minimum.upgradeURL,
+				}
+			}
+
+			GetLogger(c).Info().
+				Str("platform", client.Platform).
+				Str("client_version", client.Version.String()).
+				Str("min_version", minimum.version.String()).
+				Msg("rejecting obsolete client")
+
+			return errs.NewUpgradeRequiredError("This version of the app is no longer supported", false, action)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/database"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// recentWriteKeyPrefix marks users whose reads go to the primary
+const recentWriteKeyPrefix = "consistency:wrote:"
+
+// ConsistencyMiddleware lets users read their own writes while lists are
+// served from a read replica. A write marks the user in Redis for the sticky
+// window, and until the mark expires their reads go to the primary.
+type ConsistencyMiddleware struct {
+	server *server.Server
+}
+
+func NewConsistencyMiddleware(s *server.Server) *ConsistencyMiddleware {
+	return &ConsistencyMiddleware{server: s}
+}
+
+// ReadYourWrites must run after the user is authenticated. It does nothing
+// without a read replica.
+func (cm *ConsistencyMiddleware) ReadYourWrites(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userID := GetUserID(c)
+		if cm.server.DB.Replica == nil || cm.server.Redis == nil || userID == "" {
+			return next(c)
+		}
+
+		key := recentWriteKeyPrefix + userID
+		req := c.Request()
+
+		switch req.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			if cm.wroteRecently(c, key) {
+				c.SetRequest(req.WithContext(database.WithPrimaryReads(req.Context())))
+			}
+			return next(c)
+		}
+
+		// The mark is set before the write too, as the client may send its
+		// next read before the one after the write is stored. The one after
+		// starts the window when the write is done.
+		cm.markWrite(c, req.Context(), key)
+		err := next(c)
+		cm.markWrite(c, context.WithoutCancel(req.Context()), key)
+
+		return err
+	}
+}
+
+// wroteRecently reads from the primary when Redis can't tell
+func (cm *ConsistencyMiddleware) wroteRecently(c echo.Context, key string) bool {
+	n, err := cm.server.Redis.Exists(c.Request().Context(), key).Result()
+	if err != nil {
+		GetLogger(c).Warn().Err(err).Msg("failed to check recent writes, reading from the primary")
+		return true
+	}
+	return n > 0
+}
+
+func (cm *ConsistencyMiddleware) markWrite(c echo.Context, ctx context.Context, key string) {
+	window := cm.server.Config.Database.ReadReplica.StickyWindow
+	if err := cm.server.Redis.Set(ctx, key, 1, window).Err(); err != nil {
+		GetLogger(c).Warn().Err(err).Msg("failed to mark write, later reads may not see it")
+	}
+}
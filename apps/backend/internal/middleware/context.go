@@ -1,24 +1,14 @@
 package middleware
 
 import (
-	"context"
-
 	"github.com/labstack/echo/v4"
 	"github.com/mabhi256/tasker/internal/logging"
+	"github.com/mabhi256/tasker/internal/reqctx"
 	"github.com/mabhi256/tasker/internal/server"
 	"github.com/newrelic/go-agent/v3/newrelic"
 	"github.com/rs/zerolog"
 )
 
-// Define custom types for context keys to avoid collisions
-type contextKey string
-
-const (
-	UserIDKey   contextKey = "user_id"
-	UserRoleKey contextKey = "user_role"
-	LoggerKey   contextKey = "logger"
-)
-
 type ContextEnhancer struct {
 	server *server.Server
 }
@@ -50,20 +40,16 @@ func (ce *ContextEnhancer) EnhanceContext() echo.MiddlewareFunc {
 			// Extract user information from JWT token or session
 			userID := ce.extractUserID(c)
 			if userID != "" {
-				contextLogger = contextLogger.With().Str(string(UserIDKey), userID).Logger()
+				contextLogger = contextLogger.With().Str(reqctx.UserID.Name(), userID).Logger()
 			}
 
 			userRole := ce.extractUserRole(c)
 			if userRole != "" {
-				contextLogger = contextLogger.With().Str(string(UserRoleKey), userRole).Logger()
+				contextLogger = contextLogger.With().Str(reqctx.UserRole.Name(), userRole).Logger()
 			}
 
 			// Store the enhanced logger in context
-			c.Set(string(LoggerKey), &contextLogger)
-
-			// Create a new context with the logger
-			ctx := context.WithValue(c.Request().Context(), LoggerKey, &contextLogger)
-			c.SetRequest(c.Request().WithContext(ctx))
+			reqctx.Logger.Set(c, &contextLogger)
 
 			return next(c)
 		}
@@ -75,23 +61,43 @@ func (ce *ContextEnhancer) extractUserID(c echo.Context) string {
 }
 
 func GetUserID(c echo.Context) string {
-	// Check if user_id was already set by auth middleware (Clerk)
-	if userID, ok := c.Get(string(UserIDKey)).(string); ok {
-		return userID
-	}
-	return ""
+	// Set by auth middleware (Clerk)
+	return reqctx.UserID.Value(c)
 }
 
 func (ce *ContextEnhancer) extractUserRole(c echo.Context) string {
-	// Check if user_role was already set by auth middleware (Clerk)
-	if userRole, ok := c.Get(string(UserRoleKey)).(string); ok {
-		return userRole
-	}
-	return ""
+	return GetUserRole(c)
+}
+
+func GetUserRole(c echo.Context) string {
+	// Set by auth middleware (Clerk)
+	return reqctx.UserRole.Value(c)
+}
+
+// GetImpersonatorID returns the admin user ID when the request is made on behalf of another user
+func GetImpersonatorID(c echo.Context) string {
+	return reqctx.ImpersonatorID.Value(c)
+}
+
+// GetSessionID returns the auth provider's id for the session the request was made in
+func GetSessionID(c echo.Context) string {
+	return reqctx.SessionID.Value(c)
+}
+
+// GetAPIKeyID returns the id of the API key the request was authenticated
+// with, if it wasn't authenticated by a session
+func GetAPIKeyID(c echo.Context) string {
+	return reqctx.APIKeyID.Value(c)
+}
+
+// GetMachineClientID returns the client a machine token was issued to, if
+// the request was authenticated with one rather than as a user
+func GetMachineClientID(c echo.Context) string {
+	return reqctx.MachineClientID.Value(c)
 }
 
 func GetLogger(c echo.Context) *zerolog.Logger {
-	if logger, ok := c.Get(string(LoggerKey)).(*zerolog.Logger); ok {
+	if logger, ok := reqctx.Logger.Get(c); ok && logger != nil {
 		return logger
 	}
 	// Fallback to a basic logger if not found
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"encoding/json"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/lib/reqdebug"
+)
+
+const (
+	// HeaderDebug set to 1 asks for a report of what the request cost
+	HeaderDebug        = "X-Debug"
+	HeaderDebugInfo    = "X-Debug-Info"
+	HeaderServerTiming = "Server-Timing"
+)
+
+// DebugBudget reports what a request cost when an admin asks for it with
+// X-Debug: 1. Server-Timing carries the total and database time, which
+// browser developer tools chart, and X-Debug-Info a JSON block that adds
+// the query count, cache hits and misses and an allocation estimate.
+//
+// Whether the caller is an admin is only known once authentication ran, so
+// the report is added when the response is written. It covers the work
+// done until then, which excludes encoding the body.
+func (global *GlobalMiddlewares) DebugBudget() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if c.Request().Header.Get(HeaderDebug) != "1" {
+				return next(c)
+			}
+
+			ctx, budget := reqdebug.WithBudget(c.Request().Context())
+			c.SetRequest(c.Request().WithContext(ctx))
+
+			c.Response().Before(func() {
+				if GetUserRole(c) != RoleAdmin || GetImpersonatorID(c) != "" {
+					return
+				}
+
+				report := budget.Report()
+				raw, err := json.Marshal(report)
+				if err != nil {
+					GetLogger(c).Warn().Err(err).Msg("failed to encode debug report")
+					return
+				}
+
+				header := c.Response().Header()
+				header.Set(HeaderServerTiming, report.ServerTiming())
+				header.Set(HeaderDebugInfo, string(raw))
+				// Lets the timings show for the web client, served from
+				// another origin
+				if origin := c.Request().Header.Get(echo.HeaderOrigin); origin != "" {
+					header.Set("Timing-Allow-Origin", origin)
+				}
+			})
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+const (
+	HeaderLink = "Link"
+
+	// workspaceScopedPrefix is where the workspace scoped copies of the
+	// /api/v1 routes are registered
+	workspaceScopedPrefix = "/api/v1/workspaces/:workspaceId"
+)
+
+// EarlyHintsMiddleware sends 103 Early Hints listing the origins a route's
+// response will lead the client to, and repeats them as Link headers on the
+// final response for clients that ignore informational responses
+type EarlyHintsMiddleware struct {
+	server *server.Server
+	// links maps a route pattern to its Link header values
+	links map[string][]string
+}
+
+func NewEarlyHintsMiddleware(s *server.Server) *EarlyHintsMiddleware {
+	links := make(map[string][]string)
+
+	if cfg := s.Config.EarlyHints; cfg != nil && cfg.Enabled {
+		seen := make(map[string]bool)
+		for _, rule := range cfg.Rules {
+			for _, path := range rule.Paths {
+				path = strings.TrimSpace(path)
+				for _, origin := range rule.Origins {
+					origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+					if path == "" || origin == "" || seen[path+" "+origin] {
+						continue
+					}
+					seen[path+" "+origin] = true
+
+					links[path] = append(links[path], fmt.Sprintf("<%s>; rel=preconnect", origin))
+				}
+			}
+		}
+	}
+
+	return &EarlyHintsMiddleware{
+		server: s,
+		links:  links,
+	}
+}
+
+// SendEarlyHints must run after routing, as rules are matched on the route
+// pattern rather than the request path
+func (e *EarlyHintsMiddleware) SendEarlyHints() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if len(e.links) == 0 || (req.Method != http.MethodGet && req.Method != http.MethodHead) {
+				return next(c)
+			}
+
+			links, ok := e.links[c.Path()]
+			if !ok {
+				links = e.links[strings.Replace(c.Path(), workspaceScopedPrefix, "/api/v1", 1)]
+			}
+			if len(links) == 0 {
+				return next(c)
+			}
+
+			header := c.Response().Header()
+			for _, link := range links {
+				header.Add(HeaderLink, link)
+			}
+
+			// HTTP/1.0 clients can't parse informational responses
+			if req.ProtoAtLeast(1, 1) {
+				// Write to the underlying connection, as every wrapper in
+				// between would take 103 for the final status
+				unwrapWriter(c.Response().Writer).WriteHeader(http.StatusEarlyHints)
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// unwrapWriter returns the net/http response writer under any wrappers
+func unwrapWriter(w http.ResponseWriter) http.ResponseWriter {
+	for {
+		wrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return w
+		}
+		w = wrapper.Unwrap()
+	}
+}
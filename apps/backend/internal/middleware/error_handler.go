@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/lib/recovery"
+	"github.com/mabhi256/tasker/internal/sqlerr"
+)
+
+// Recover turns a handler's panic into an error, which the middlewares
+// before it see like any other: EnhanceTracing reports it to New Relic with
+// the stack it happened on, and GlobalErrorHandler logs the stack and
+// answers with a 500
+func (global *GlobalMiddlewares) Recover() echo.MiddlewareFunc {
+	return middleware.RecoverWithConfig(middleware.RecoverConfig{
+		DisableStackAll:     true,
+		DisableErrorHandler: true,
+		// Called while the handler's stack is still there to capture
+		LogErrorFunc: func(c echo.Context, err error, stack []byte) error {
+			return recovery.New(err)
+		},
+	})
+}
+
+// toHTTPError converts err to the error the client is answered with:
+// HTTPErrors as they are, echo's errors and database errors to their
+// equivalent, canceled requests to a 499 and anything else, panics
+// included, to a 500
+func toHTTPError(err error) *errs.HTTPError {
+	var httpErr *errs.HTTPError
+	var echoErr *echo.HTTPError
+	var panicErr *recovery.Error
+
+	switch {
+	case errors.As(err, &httpErr):
+		return httpErr
+
+	case errors.As(err, &panicErr):
+		return errs.NewInternalServerError()
+
+	case errors.As(err, &echoErr):
+		if echoErr.Code == http.StatusNotFound {
+			return errs.NewNotFoundError("Route not found", false, nil)
+		}
+
+		message, ok := echoErr.Message.(string)
+		if !ok {
+			message = http.StatusText(echoErr.Code)
+		}
+		return &errs.HTTPError{
+			Code:    errs.MakeUpperSnakeCase(http.StatusText(echoErr.Code)),
+			Message: message,
+			Status:  echoErr.Code,
+		}
+
+	case errors.Is(err, context.Canceled):
+		return errs.NewClientClosedRequestError()
+
+	default:
+		// Database errors, and timeouts of the request's context, which
+		// usually expire while a query runs
+		var converted *errs.HTTPError
+		if errors.As(sqlerr.HandleError(err), &converted) {
+			return converted
+		}
+		return errs.NewInternalServerError()
+	}
+}
+
+// GlobalErrorHandler answers failed requests with the standard error
+// envelope, carrying the request's ID. Server errors are logged with their
+// underlying error, which the response only includes outside production.
+func (global *GlobalMiddlewares) GlobalErrorHandler(err error, c echo.Context) {
+	httpErr := toHTTPError(err)
+
+	// Use enhanced logger from context which already includes request_id, method, path, ip, user context, and trace context
+	logger := *GetLogger(c)
+
+	switch {
+	case httpErr.Status >= http.StatusInternalServerError:
+		event := logger.Error().Stack().Err(err)
+		if panicErr, ok := recovery.As(err); ok {
+			event = event.Str("panic_stack", string(panicErr.Stack))
+		}
+		event.Int("status", httpErr.Status).
+			Str("error_code", httpErr.Code).
+			Msg(httpErr.Message)
+
+	case httpErr.Status == errs.StatusClientClosedRequest:
+		logger.Info().Err(err).
+			Int("status", httpErr.Status).
+			Str("error_code", httpErr.Code).
+			Msg(httpErr.Message)
+
+	default:
+		logger.Warn().Err(err).
+			Int("status", httpErr.Status).
+			Str("error_code", httpErr.Code).
+			Msg(httpErr.Message)
+	}
+
+	if c.Response().Committed || httpErr.Status == errs.StatusClientClosedRequest {
+		return
+	}
+
+	response := *httpErr
+	response.RequestID = GetRequestID(c)
+	if httpErr.Status >= http.StatusInternalServerError && !global.server.Config.Observability.IsProduction() {
+		response.Detail = fmt.Sprintf("%v", err)
+	}
+
+	_ = c.JSON(response.Status, response)
+}
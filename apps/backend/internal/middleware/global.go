@@ -1,15 +1,11 @@
 package middleware
 
 import (
-	"errors"
-	"net/http"
-
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/lib/jsonenc"
 	"github.com/mabhi256/tasker/internal/server"
-	"github.com/mabhi256/tasker/internal/sqlerr"
-	"github.com/rs/zerolog"
 )
 
 type GlobalMiddlewares struct {
@@ -25,149 +21,29 @@ func NewGlobalMiddlewares(s *server.Server) *GlobalMiddlewares {
 func (global *GlobalMiddlewares) CORS() echo.MiddlewareFunc {
 	return middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins: global.server.Config.Server.CorsAllowedOrigins,
+		// Lets the web client read the preconnect hints sent by EarlyHints
+		// and the reports sent by DebugBudget
+		ExposeHeaders: []string{HeaderLink, HeaderServerTiming, HeaderDebugInfo},
 	})
 }
 
-func (global *GlobalMiddlewares) RequestLogger() echo.MiddlewareFunc {
-	return middleware.RequestLoggerWithConfig(middleware.RequestLoggerConfig{
-		LogURI:        true,
-		LogStatus:     true,
-		LogError:      true,
-		LogLatency:    true,
-		LogHost:       true,
-		LogMethod:     true,
-		LogURIPath:    true,
-		LogValuesFunc: logValuesFunc,
-	})
-}
-
-func logValuesFunc(c echo.Context, v middleware.RequestLoggerValues) error {
-	statusCode := v.Status
-
-	// note that the status code is not set yet as it gets picked up by the global err handler
-	// see here: https://github.com/labstack/echo/issues/2310#issuecomment-1288196898
-	if v.Error != nil {
-		var httpErr *errs.HTTPError
-		var echoErr *echo.HTTPError
-		if errors.As(v.Error, &httpErr) {
-			statusCode = httpErr.Status
-		} else if errors.As(v.Error, &echoErr) {
-			statusCode = echoErr.Code
-		}
-	}
-
-	// Get enhanced logger from context
-	logger := GetLogger(c)
-
-	var e *zerolog.Event
-	switch {
-	case statusCode >= 500:
-		e = logger.Error().Err(v.Error)
-	case statusCode >= 400:
-		e = logger.Warn()
-	default:
-		e = logger.Info()
-	}
-
-	// Add request ID if available
-	if requestID := GetRequestID(c); requestID != "" {
-		e = e.Str(RequestIDKey, requestID)
-	}
-
-	// Add user context if available
-	if userID := GetUserID(c); userID != "" {
-		e = e.Str(string(UserIDKey), userID)
-	}
-
-	e.Dur("latency", v.Latency).
-		Int("status", statusCode).
-		Str("method", v.Method).
-		Str("uri", v.URI).
-		Str("host", v.Host).
-		Str("ip", c.RealIP()).
-		Str("user_agent", v.UserAgent). //  c.Request().UserAgent()
-		Msg("API")
-
-	return nil
-}
-
-func (global *GlobalMiddlewares) Recover() echo.MiddlewareFunc {
-	return middleware.Recover()
-}
-
 func (global *GlobalMiddlewares) Secure() echo.MiddlewareFunc {
 	return middleware.Secure()
 }
 
-func (global *GlobalMiddlewares) GlobalErrorHandler(err error, c echo.Context) {
-	// First try to handle database errors and convert them to appropriate HTTP errors
-	originalErr := err
-
-	// Try to handle known database errors
-	// Only do this for errors that haven't already been converted to HTTPError
-	var httpErr *errs.HTTPError
-	if !errors.As(err, &httpErr) {
-		var echoErr *echo.HTTPError
-		if errors.As(err, &echoErr) {
-			if echoErr.Code == http.StatusNotFound {
-				err = errs.NewNotFoundError("Route not found", false, nil)
+// ValidateView rejects requests for a view other than the full and compact
+// ones, which every endpoint accepts. The view is applied when the response
+// is serialized, see jsonenc.Compacter.
+func (global *GlobalMiddlewares) ValidateView() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			switch c.QueryParam(jsonenc.ViewParam) {
+			case "", jsonenc.ViewFull, jsonenc.ViewCompact:
+				return next(c)
+			default:
+				code := "INVALID_VIEW"
+				return errs.NewBadRequestError("view must be one of: full, compact", false, &code, nil, nil)
 			}
-		} else {
-			// Here we call our sqlerr handler which will convert database errors
-			// to appropriate application errors
-			err = sqlerr.HandleError(err)
 		}
 	}
-
-	// Now process the possibly converted error
-	var echoErr *echo.HTTPError
-	var status int
-	var code string
-	var message string
-	var fieldErrors []errs.BindError
-	var action *errs.Action
-
-	switch {
-	case errors.As(err, &httpErr):
-		status = httpErr.Status
-		code = httpErr.Code
-		message = httpErr.Message
-		fieldErrors = httpErr.Errors
-		action = httpErr.Action
-
-	case errors.As(err, &echoErr):
-		status = echoErr.Code
-		code = errs.MakeUpperSnakeCase(http.StatusText(status))
-		if msg, ok := echoErr.Message.(string); ok {
-			message = msg
-		} else {
-			message = http.StatusText(echoErr.Code)
-		}
-
-	default:
-		status = http.StatusInternalServerError
-		message = http.StatusText(http.StatusInternalServerError)
-		code = errs.MakeUpperSnakeCase(message)
-	}
-
-	// Log the original error to help with debugging
-	// Use enhanced logger from context which already includes request_id, method, path, ip, user context, and trace context
-	logger := *GetLogger(c)
-
-	logger.Error().Stack().
-		Err(originalErr).
-		Int("status", status).
-		Str("error_code", code).
-		Msg(message)
-
-	if !c.Response().Committed {
-		_ = c.JSON(status, errs.HTTPError{
-			Code:     code,
-			Message:  message,
-			Status:   status,
-			Override: httpErr != nil && httpErr.Override,
-			Errors:   fieldErrors,
-			Action:   action,
-		})
-	}
 }
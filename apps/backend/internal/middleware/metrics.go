@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// unmatchedRoute is the route template requests that matched no route are
+// recorded under, as their paths are arbitrary
+const unmatchedRoute = "unmatched"
+
+// RouteMetrics records the count, latency and status of requests per route
+// template. The workspace scoped copies of routes are recorded with the
+// top level ones, as they are served by the same handlers.
+func (global *GlobalMiddlewares) RouteMetrics() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			route := strings.Replace(c.Path(), workspaceScopedPrefix, "/api/v1", 1)
+			if route == "" || errors.Is(err, echo.ErrNotFound) || errors.Is(err, echo.ErrMethodNotAllowed) {
+				route = unmatchedRoute
+			}
+
+			status := c.Response().Status
+			if err != nil {
+				status = errorStatus(err)
+			}
+
+			global.server.Metrics.Request(c.Request().Method, route, status, time.Since(start))
+
+			return err
+		}
+	}
+}
+
+// errorStatus is the status GlobalErrorHandler responds to err with
+func errorStatus(err error) int {
+	return toHTTPError(err).Status
+}
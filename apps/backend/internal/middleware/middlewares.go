@@ -11,20 +11,39 @@ type Middlewares struct {
 	ContextEnhancer *ContextEnhancer
 	Tracing         *TracingMiddleware
 	RateLimit       *RateLimitMiddleware
+	Workspace       *WorkspaceMiddleware
+	EarlyHints      *EarlyHintsMiddleware
+	Consistency     *ConsistencyMiddleware
+	SCIM            *SCIMMiddleware
+	Transaction     *TransactionMiddleware
+	ClientVersion   *ClientVersionMiddleware
+	ReadOnly        *ReadOnlyMiddleware
 }
 
-func NewMiddlewares(s *server.Server) *Middlewares {
+func NewMiddlewares(s *server.Server, workspaceResolver WorkspaceResolver,
+	scimTokenResolver SCIMTokenResolver, ssoEnforcer SSOEnforcer, ipAllowlist IPAllowlistEnforcer,
+	apiKeyResolver APIKeyResolver, trialResolver TrialResolver,
+) *Middlewares {
 	// Get New Relic application instance from server
 	var nrApp *newrelic.Application
 	if s.LoggerService != nil {
 		nrApp = s.LoggerService.GetApplication()
 	}
 
+	consistency := NewConsistencyMiddleware(s)
+
 	return &Middlewares{
 		Global:          NewGlobalMiddlewares(s),
-		Auth:            NewAuthMiddleware(s),
+		Auth:            NewAuthMiddleware(s, consistency, apiKeyResolver, trialResolver),
 		ContextEnhancer: NewContextEnhancer(s),
 		Tracing:         NewTracingMiddleware(s, nrApp),
 		RateLimit:       NewRateLimitMiddleware(s),
+		Workspace:       NewWorkspaceMiddleware(s, workspaceResolver, ssoEnforcer, ipAllowlist),
+		EarlyHints:      NewEarlyHintsMiddleware(s),
+		Consistency:     consistency,
+		SCIM:            NewSCIMMiddleware(s, scimTokenResolver, ipAllowlist),
+		Transaction:     NewTransactionMiddleware(s),
+		ClientVersion:   NewClientVersionMiddleware(s),
+		ReadOnly:        NewReadOnlyMiddleware(s),
 	}
 }
@@ -1,17 +1,32 @@
 package middleware
 
-import "github.com/mabhi256/tasker/internal/server"
+import (
+	"sync"
+
+	echoMiddleware "github.com/labstack/echo/v4/middleware"
+	"github.com/mabhi256/tasker/internal/config"
+	"github.com/mabhi256/tasker/internal/server"
+	"golang.org/x/time/rate"
+)
 
 type RateLimitMiddleware struct {
 	server *server.Server
+	store  *rateLimitStore
 }
 
 func NewRateLimitMiddleware(s *server.Server) *RateLimitMiddleware {
 	return &RateLimitMiddleware{
 		server: s,
+		store:  &rateLimitStore{runtime: s.Runtime},
 	}
 }
 
+// Store limits requests by the rate limit in the runtime config, so a
+// reloaded limit applies without a restart
+func (r *RateLimitMiddleware) Store() echoMiddleware.RateLimiterStore {
+	return r.store
+}
+
 func (r *RateLimitMiddleware) RecordRateLimitHit(endpoint string) {
 	if r.server.LoggerService != nil && r.server.LoggerService.GetApplication() != nil {
 		r.server.LoggerService.GetApplication().RecordCustomEvent("RateLimitHit", map[string]any{
@@ -19,3 +34,31 @@ func (r *RateLimitMiddleware) RecordRateLimitHit(endpoint string) {
 		})
 	}
 }
+
+// rateLimitStore keeps a memory store for the current rate limit. A changed
+// limit replaces the store, so clients start afresh under the new limit.
+type rateLimitStore struct {
+	runtime *config.Runtime
+
+	mu    sync.Mutex
+	limit config.RateLimitConfig
+	store echoMiddleware.RateLimiterStore
+}
+
+func (s *rateLimitStore) Allow(identifier string) (bool, error) {
+	limit := s.runtime.Values().RateLimit
+
+	s.mu.Lock()
+	if s.store == nil || limit != s.limit {
+		s.limit = limit
+		s.store = echoMiddleware.NewRateLimiterMemoryStoreWithConfig(echoMiddleware.RateLimiterMemoryStoreConfig{
+			Rate:      rate.Limit(limit.Rate),
+			Burst:     limit.Burst,
+			ExpiresIn: limit.ExpiresIn,
+		})
+	}
+	store := s.store
+	s.mu.Unlock()
+
+	return store.Allow(identifier)
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// readOnlyExempt are the routes served in read-only mode despite their
+// method: switching the mode, and reads that take their input as a body
+var readOnlyExempt = map[string]bool{
+	"/admin/v1/read-only":                     true,
+	"/api/v1/resolve":                         true,
+	"/api/v1/workspaces/:workspaceId/resolve": true,
+}
+
+type ReadOnlyMiddleware struct {
+	server *server.Server
+}
+
+func NewReadOnlyMiddleware(s *server.Server) *ReadOnlyMiddleware {
+	return &ReadOnlyMiddleware{
+		server: s,
+	}
+}
+
+// RejectWrites answers writes with 503 READ_ONLY while the API is in
+// read-only mode. Reads are served as usual.
+func (m *ReadOnlyMiddleware) RejectWrites() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !m.server.ReadOnly.ReadOnly() {
+				return next(c)
+			}
+
+			switch c.Request().Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				return next(c)
+			}
+			if readOnlyExempt[c.Path()] {
+				return next(c)
+			}
+
+			code := "READ_ONLY"
+			return errs.NewServiceUnavailableError(
+				"Changes can't be saved while the service recovers from an incident, please try again later",
+				true, &code)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/config"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/lib/readonly"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRejectWrites(t *testing.T) {
+	s := newTestServer()
+	s.ReadOnly = readonly.New(&config.ReadOnlyConfig{Enabled: true, Reason: "restore"}, nil, s.Logger, nil)
+
+	rejectWrites := middleware.NewReadOnlyMiddleware(s).RejectWrites()
+
+	// The exemptions match on the route, so the requests are routed first
+	e := echo.New()
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusNoContent) }
+	e.GET("/api/v1/tasks", ok)
+	e.POST("/api/v1/tasks", ok)
+	e.POST("/api/v1/resolve", ok)
+	e.POST("/api/v1/workspaces/:workspaceId/resolve", ok)
+	e.POST("/api/v1/workspaces/:workspaceId/tasks", ok)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+	}{
+		{name: "read", method: http.MethodGet, path: "/api/v1/tasks", status: http.StatusNoContent},
+		{name: "write", method: http.MethodPost, path: "/api/v1/tasks", status: http.StatusServiceUnavailable},
+		{name: "resolve", method: http.MethodPost, path: "/api/v1/resolve", status: http.StatusNoContent},
+		{
+			name:   "resolve in workspace",
+			method: http.MethodPost,
+			path:   "/api/v1/workspaces/0b7c5a52-8a8e-4a55-9d8b-2f3c3f0e7b11/resolve",
+			status: http.StatusNoContent,
+		},
+		{
+			name:   "write in workspace",
+			method: http.MethodPost,
+			path:   "/api/v1/workspaces/0b7c5a52-8a8e-4a55-9d8b-2f3c3f0e7b11/tasks",
+			status: http.StatusServiceUnavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			e.Router().Find(tt.method, tt.path, c)
+
+			err := rejectWrites(c.Handler())(c)
+			if err != nil {
+				var httpErr *errs.HTTPError
+				require.ErrorAs(t, err, &httpErr)
+				assert.Equal(t, tt.status, httpErr.Status)
+				return
+			}
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+}
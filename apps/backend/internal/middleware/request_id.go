@@ -3,12 +3,10 @@ package middleware
 import (
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/reqctx"
 )
 
-const (
-	RequestIDHeader = "X-Request-ID"
-	RequestIDKey    = "request_id"
-)
+const RequestIDHeader = "X-Request-ID"
 
 func RequestID() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -18,8 +16,8 @@ func RequestID() echo.MiddlewareFunc {
 				requestID = uuid.New().String() // 4c90fc3f-39cc-4b04-af21-c83ee64aa67e
 			}
 
-			c.Set(RequestIDKey, requestID)
-			c.Response().Header().Set(RequestIDKey, requestID)
+			reqctx.RequestID.Set(c, requestID)
+			c.Response().Header().Set(reqctx.RequestID.Name(), requestID)
 
 			return next(c)
 		}
@@ -27,10 +25,5 @@ func RequestID() echo.MiddlewareFunc {
 }
 
 func GetRequestID(ctx echo.Context) string {
-	reqID := ctx.Get(RequestIDKey)
-
-	if id, ok := reqID.(string); ok {
-		return id
-	}
-	return ""
+	return reqctx.RequestID.Value(ctx)
 }
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/lib/scim"
+	"github.com/mabhi256/tasker/internal/lib/usage"
+	"github.com/mabhi256/tasker/internal/model/provisioning"
+	"github.com/mabhi256/tasker/internal/reqctx"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// SCIMTokenResolver looks up the workspace a SCIM token provisions. Unknown
+// tokens are reported as a not found error.
+type SCIMTokenResolver interface {
+	ResolveSCIMToken(ctx context.Context, token string) (uuid.UUID, error)
+}
+
+type SCIMMiddleware struct {
+	server      *server.Server
+	resolver    SCIMTokenResolver
+	ipAllowlist IPAllowlistEnforcer
+}
+
+func NewSCIMMiddleware(s *server.Server, resolver SCIMTokenResolver,
+	ipAllowlist IPAllowlistEnforcer,
+) *SCIMMiddleware {
+	return &SCIMMiddleware{
+		server:      s,
+		resolver:    resolver,
+		ipAllowlist: ipAllowlist,
+	}
+}
+
+// RequireToken authenticates an identity provider by its workspace's SCIM
+// token. The request acts as provisioning.ActorID in that workspace, with
+// no workspace role, so only the SCIM endpoints can be reached with it. The
+// workspace's IP allowlist applies to identity providers too.
+func (sm *SCIMMiddleware) RequireToken(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		token, ok := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+		if !ok || token == "" {
+			return WriteSCIMError(c, scim.NewError(http.StatusUnauthorized, "", "A bearer token is required"))
+		}
+
+		workspaceID, err := sm.resolver.ResolveSCIMToken(c.Request().Context(), token)
+		if err != nil {
+			var httpErr *errs.HTTPError
+			if errors.As(err, &httpErr) && httpErr.Status == http.StatusNotFound {
+				sm.server.Logger.Warn().
+					Str("function", "RequireToken").
+					Str("request_id", GetRequestID(c)).
+					Str("ip", c.RealIP()).
+					Msg("unknown SCIM token")
+				return WriteSCIMError(c, scim.NewError(http.StatusUnauthorized, "", "Invalid bearer token"))
+			}
+
+			sm.server.Logger.Error().
+				Err(err).
+				Str("function", "RequireToken").
+				Str("request_id", GetRequestID(c)).
+				Msg("could not resolve SCIM token")
+			return WriteSCIMError(c, scim.NewError(http.StatusInternalServerError, "", "Internal server error"))
+		}
+
+		reqctx.UserID.Set(c, provisioning.ActorID)
+		reqctx.WorkspaceID.Set(c, workspaceID)
+
+		// Charge the request's queries to the workspace
+		c.SetRequest(c.Request().WithContext(usage.WithWorkspace(c.Request().Context(), workspaceID)))
+
+		if sm.ipAllowlist != nil {
+			if err := sm.ipAllowlist.EnforceIPAllowlist(c, workspaceID); err != nil {
+				var httpErr *errs.HTTPError
+				if errors.As(err, &httpErr) && httpErr.Status == http.StatusForbidden {
+					sm.server.Logger.Warn().
+						Str("function", "RequireToken").
+						Str("workspace_id", workspaceID.String()).
+						Str("ip", c.RealIP()).
+						Str("request_id", GetRequestID(c)).
+						Msg("SCIM request denied by IP allowlist")
+					return WriteSCIMError(c, scim.NewError(http.StatusForbidden, "", httpErr.Message))
+				}
+
+				sm.server.Logger.Error().
+					Err(err).
+					Str("function", "RequireToken").
+					Str("request_id", GetRequestID(c)).
+					Msg("could not check IP allowlist")
+				return WriteSCIMError(c, scim.NewError(http.StatusInternalServerError, "", "Internal server error"))
+			}
+		}
+
+		return next(c)
+	}
+}
+
+// WriteSCIMError responds with err in the SCIM error format, which identity
+// providers expect instead of the API's own
+func WriteSCIMError(c echo.Context, err *scim.Error) error {
+	c.Response().Header().Set(echo.HeaderContentType, scim.ContentType)
+	return c.JSON(err.StatusCode(), err)
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/lib/recovery"
 	"github.com/mabhi256/tasker/internal/server"
 	"github.com/newrelic/go-agent/v3/integrations/nrecho-v4"
 	"github.com/newrelic/go-agent/v3/integrations/nrpkgerrors"
@@ -57,8 +58,11 @@ func (tm *TracingMiddleware) EnhanceTracing() echo.MiddlewareFunc {
 
 			// Execute next handler
 			err := next(c)
-			// Record error if any with enhanced stack traces
-			if err != nil {
+			// Record error if any with enhanced stack traces. A panic
+			// already carries the stack it happened on.
+			if panicErr, ok := recovery.As(err); ok {
+				txn.NoticeError(panicErr)
+			} else if err != nil {
 				txn.NoticeError(nrpkgerrors.Wrap(err))
 			}
 
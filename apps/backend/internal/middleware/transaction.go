@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// errRollback rolls back a unit of work whose handler wrote an error response
+// instead of returning an error
+var errRollback = errors.New("handler responded with an error")
+
+// TransactionMiddleware runs the queries of a request as one unit of work,
+// so the repository calls of a handler either all take effect or none do.
+// Routes opt in; by default each query runs on the pool by itself.
+type TransactionMiddleware struct {
+	server *server.Server
+}
+
+func NewTransactionMiddleware(s *server.Server) *TransactionMiddleware {
+	return &TransactionMiddleware{server: s}
+}
+
+// Transactional commits when the handler succeeds and rolls back when it
+// fails. The response is held back until the commit, so a client never sees
+// success for a write that was rolled back. It must run after
+// ResolveWorkspace, for the transaction to carry the request's scope, and
+// can't be used by handlers that stream or query concurrently.
+func (tm *TransactionMiddleware) Transactional(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		res := c.Response()
+		original := res.Writer
+		buffered := &bufferedWriter{ResponseWriter: original}
+		res.Writer = buffered
+
+		var handlerErr error
+		err := tm.server.DB.UnitOfWork(c.Request().Context(), func(ctx context.Context) error {
+			req := c.Request()
+			c.SetRequest(req.WithContext(ctx))
+			defer c.SetRequest(req)
+
+			if handlerErr = next(c); handlerErr != nil {
+				return handlerErr
+			}
+			if res.Status >= http.StatusBadRequest {
+				return errRollback
+			}
+			return nil
+		})
+		res.Writer = original
+
+		if handlerErr == nil && err != nil && !errors.Is(err, errRollback) {
+			// The commit failed, so the buffered success response is dropped
+			// for the error handler's
+			res.Committed = false
+			res.Status = http.StatusOK
+			res.Size = 0
+			return err
+		}
+
+		if res.Committed {
+			if err := buffered.flush(); err != nil {
+				return err
+			}
+		}
+
+		return handlerErr
+	}
+}
+
+// bufferedWriter holds a response back until flush. Headers go straight to
+// the wrapped writer's header map, which isn't sent until flush either.
+type bufferedWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *bufferedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *bufferedWriter) flush() error {
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+	_, err := w.ResponseWriter.Write(w.body.Bytes())
+	return err
+}
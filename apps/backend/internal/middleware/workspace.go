@@ -0,0 +1,182 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/database"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/lib/usage"
+	"github.com/mabhi256/tasker/internal/model/workspace"
+	"github.com/mabhi256/tasker/internal/reqctx"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+const (
+	WorkspaceHeader = "X-Workspace-ID"
+	WorkspaceParam  = "workspaceId"
+)
+
+// WorkspaceResolver looks up the caller's membership in a workspace. A nil
+// workspaceID resolves to the caller's personal workspace.
+type WorkspaceResolver interface {
+	ResolveMembership(ctx context.Context, userID string, workspaceID *uuid.UUID) (*workspace.Member, error)
+}
+
+// IPAllowlistEnforcer checks the request's IP against the workspace's
+// allowlist, returning an error when the workspace doesn't accept it
+type IPAllowlistEnforcer interface {
+	EnforceIPAllowlist(c echo.Context, workspaceID uuid.UUID) error
+}
+
+// SSOEnforcer checks that the session signed in with the workspace's
+// identity provider when the workspace enforces single sign-on for the user.
+// Otherwise it returns an error, whose action sends the user to sign in when
+// they have a session. Requests made with a token have none, which is empty.
+type SSOEnforcer interface {
+	EnforceSSO(ctx context.Context, workspaceID uuid.UUID, userID string, sessionID string) error
+}
+
+type WorkspaceMiddleware struct {
+	server      *server.Server
+	resolver    WorkspaceResolver
+	sso         SSOEnforcer
+	ipAllowlist IPAllowlistEnforcer
+}
+
+func NewWorkspaceMiddleware(s *server.Server, resolver WorkspaceResolver, sso SSOEnforcer,
+	ipAllowlist IPAllowlistEnforcer,
+) *WorkspaceMiddleware {
+	return &WorkspaceMiddleware{
+		server:      s,
+		resolver:    resolver,
+		sso:         sso,
+		ipAllowlist: ipAllowlist,
+	}
+}
+
+// ssoPolicy reports whether the request has to have signed in with the
+// workspace's identity provider
+type ssoPolicy func(c echo.Context) bool
+
+func requireSSO(echo.Context) bool { return true }
+
+func skipSSO(echo.Context) bool { return false }
+
+// unlessLeaving doesn't hold members to single sign-on when they remove
+// themselves, so a workspace can't keep a member it added from leaving
+func unlessLeaving(c echo.Context) bool {
+	return c.Param("userId") != GetUserID(c)
+}
+
+// ResolveWorkspace selects the workspace for the request from the :workspaceId
+// path param or the X-Workspace-ID header, falling back to the caller's personal
+// workspace, and enforces its single sign-on and IP allowlist. It must be
+// registered after RequireAuth.
+func (wm *WorkspaceMiddleware) ResolveWorkspace(next echo.HandlerFunc) echo.HandlerFunc {
+	return wm.resolveWorkspace(next, requireSSO, true)
+}
+
+// ResolveWorkspaceWithoutAllowlist resolves the workspace like
+// ResolveWorkspace without enforcing its IP allowlist, for breaking glass
+// when the allowlist locked the owner out
+func (wm *WorkspaceMiddleware) ResolveWorkspaceWithoutAllowlist(next echo.HandlerFunc) echo.HandlerFunc {
+	return wm.resolveWorkspace(next, requireSSO, false)
+}
+
+// ResolveWorkspaceWithoutSSO resolves the workspace like ResolveWorkspace
+// without enforcing its single sign-on, for the routes that sign in with its
+// identity provider
+func (wm *WorkspaceMiddleware) ResolveWorkspaceWithoutSSO(next echo.HandlerFunc) echo.HandlerFunc {
+	return wm.resolveWorkspace(next, skipSSO, true)
+}
+
+// ResolveWorkspaceToLeave resolves the workspace like ResolveWorkspace for
+// removing a member, without enforcing single sign-on on members removing
+// themselves
+func (wm *WorkspaceMiddleware) ResolveWorkspaceToLeave(next echo.HandlerFunc) echo.HandlerFunc {
+	return wm.resolveWorkspace(next, unlessLeaving, true)
+}
+
+func (wm *WorkspaceMiddleware) resolveWorkspace(next echo.HandlerFunc, enforceSSO ssoPolicy,
+	enforceAllowlist bool,
+) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		raw := c.Param(WorkspaceParam)
+		if raw == "" {
+			raw = c.Request().Header.Get(WorkspaceHeader)
+		}
+
+		var workspaceID *uuid.UUID
+		if raw != "" {
+			id, err := uuid.Parse(raw)
+			if err != nil {
+				return errs.NewBadRequestError("Invalid workspace ID", false, nil, nil, nil)
+			}
+			workspaceID = &id
+		}
+
+		member, err := wm.resolver.ResolveMembership(c.Request().Context(), GetUserID(c), workspaceID)
+		if err != nil {
+			wm.server.Logger.Warn().
+				Err(err).
+				Str("function", "ResolveWorkspace").
+				Str("user_id", GetUserID(c)).
+				Str("request_id", GetRequestID(c)).
+				Msg("could not resolve workspace for request")
+			return err
+		}
+
+		reqctx.WorkspaceID.Set(c, member.WorkspaceID)
+		reqctx.WorkspaceRole.Set(c, member.Role)
+
+		// Charge the request's queries and the tasks it enqueues to the
+		// workspace, and keep its queries to the workspace's rows
+		ctx := usage.WithWorkspace(c.Request().Context(), member.WorkspaceID)
+		ctx = database.WithScope(ctx, database.Scope{WorkspaceID: member.WorkspaceID, UserID: GetUserID(c)})
+		c.SetRequest(c.Request().WithContext(ctx))
+
+		// Admins impersonating a user don't sign in as them, the impersonation
+		// is audited instead
+		if wm.sso != nil && enforceSSO(c) && GetImpersonatorID(c) == "" {
+			err := wm.sso.EnforceSSO(ctx, member.WorkspaceID, GetUserID(c), GetSessionID(c))
+			if err != nil {
+				wm.server.Logger.Warn().
+					Err(err).
+					Str("function", "ResolveWorkspace").
+					Str("user_id", GetUserID(c)).
+					Str("workspace_id", member.WorkspaceID.String()).
+					Str("request_id", GetRequestID(c)).
+					Msg("single sign-on required")
+				return err
+			}
+		}
+
+		if enforceAllowlist && wm.ipAllowlist != nil {
+			if err := wm.ipAllowlist.EnforceIPAllowlist(c, member.WorkspaceID); err != nil {
+				wm.server.Logger.Warn().
+					Err(err).
+					Str("function", "ResolveWorkspace").
+					Str("user_id", GetUserID(c)).
+					Str("workspace_id", member.WorkspaceID.String()).
+					Str("ip", c.RealIP()).
+					Str("request_id", GetRequestID(c)).
+					Msg("request denied by IP allowlist")
+				return err
+			}
+		}
+
+		wm.server.Usage.AddAPIRequest(ctx)
+
+		return next(c)
+	}
+}
+
+func GetWorkspaceID(c echo.Context) uuid.UUID {
+	return reqctx.WorkspaceID.Value(c)
+}
+
+func GetWorkspaceRole(c echo.Context) workspace.Role {
+	return reqctx.WorkspaceRole.Value(c)
+}
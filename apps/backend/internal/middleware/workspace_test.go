@@ -0,0 +1,161 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model"
+	"github.com/mabhi256/tasker/internal/model/apikey"
+	"github.com/mabhi256/tasker/internal/model/sso"
+	"github.com/mabhi256/tasker/internal/model/trial"
+	"github.com/mabhi256/tasker/internal/model/workspace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeWorkspaces makes every user the member of the workspace they ask for,
+// falling back to a personal workspace of their own
+type fakeWorkspaces struct {
+	personal uuid.UUID
+}
+
+func (f fakeWorkspaces) ResolveMembership(_ context.Context, userID string,
+	workspaceID *uuid.UUID,
+) (*workspace.Member, error) {
+	if workspaceID == nil {
+		return &workspace.Member{WorkspaceID: f.personal, UserID: userID, Role: workspace.RoleOwner}, nil
+	}
+	return &workspace.Member{WorkspaceID: *workspaceID, UserID: userID, Role: workspace.RoleMember}, nil
+}
+
+// fakeSSO requires the members of the workspaces it holds to sign in with
+// single sign-on, which no session of the tests has
+type fakeSSO map[uuid.UUID]bool
+
+func (f fakeSSO) EnforceSSO(_ context.Context, workspaceID uuid.UUID, _ string, _ string) error {
+	if f[workspaceID] {
+		code := sso.CodeSSORequired
+		return errs.NewForbiddenActionError("Sign in with your organization's identity provider to continue",
+			false, &code, nil)
+	}
+	return nil
+}
+
+// serveWorkspace sends a request with the bearer token through RequireAuth
+// and the workspace middleware, returning the status
+func serveWorkspace(t *testing.T, auth *middleware.AuthMiddleware,
+	resolve echo.MiddlewareFunc, method, token string, workspaceID *uuid.UUID, userID string,
+) int {
+	t.Helper()
+
+	e := echo.New()
+	req := httptest.NewRequest(method, "/", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	if workspaceID != nil {
+		req.Header.Set(middleware.WorkspaceHeader, workspaceID.String())
+	}
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	if userID != "" {
+		c.SetParamNames("userId")
+		c.SetParamValues(userID)
+	}
+
+	err := auth.RequireAuth(resolve(func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	}))(c)
+	if err != nil {
+		var httpErr *errs.HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		return httpErr.Status
+	}
+	return rec.Code
+}
+
+// TestWorkspaceSSO holds the members of a workspace to its single sign-on
+// only within it, so it can't lock them out of their other workspaces
+func TestWorkspaceSSO(t *testing.T) {
+	s := newTestServer()
+
+	key, err := apikey.Generate()
+	require.NoError(t, err)
+
+	keys := fakeAPIKeys{
+		apikey.Hash(key): {
+			BaseWithId: model.BaseWithId{ID: uuid.New()},
+			UserID:     "user-1",
+			Scope:      apikey.ScopeReadWrite,
+		},
+	}
+	trials := fakeTrials{
+		trial.Prefix + "token": {BaseWithId: model.BaseWithId{ID: uuid.New()}, UserID: "trial_1"},
+	}
+	auth := middleware.NewAuthMiddleware(s, middleware.NewConsistencyMiddleware(s), keys, trials)
+
+	enforced := uuid.New()
+	other := uuid.New()
+	ws := middleware.NewWorkspaceMiddleware(s, fakeWorkspaces{personal: uuid.New()},
+		fakeSSO{enforced: true}, nil)
+
+	tests := []struct {
+		name        string
+		resolve     echo.MiddlewareFunc
+		method      string
+		token       string
+		workspaceID *uuid.UUID
+		userID      string
+		status      int
+	}{
+		{name: "personal workspace", resolve: ws.ResolveWorkspace, token: key, status: http.StatusNoContent},
+		{name: "other workspace", resolve: ws.ResolveWorkspace, token: key, workspaceID: &other, status: http.StatusNoContent},
+		{name: "enforced workspace", resolve: ws.ResolveWorkspace, token: key, workspaceID: &enforced, status: http.StatusForbidden},
+		{
+			name:        "trial in enforced workspace",
+			resolve:     ws.ResolveWorkspace,
+			token:       trial.Prefix + "token",
+			workspaceID: &enforced,
+			status:      http.StatusForbidden,
+		},
+		{
+			name:        "signing in to enforced workspace",
+			resolve:     ws.ResolveWorkspaceWithoutSSO,
+			token:       key,
+			workspaceID: &enforced,
+			status:      http.StatusNoContent,
+		},
+		{
+			name:        "leaving enforced workspace",
+			resolve:     ws.ResolveWorkspaceToLeave,
+			method:      http.MethodDelete,
+			token:       key,
+			workspaceID: &enforced,
+			userID:      "user-1",
+			status:      http.StatusNoContent,
+		},
+		{
+			name:        "removing member of enforced workspace",
+			resolve:     ws.ResolveWorkspaceToLeave,
+			method:      http.MethodDelete,
+			token:       key,
+			workspaceID: &enforced,
+			userID:      "user-2",
+			status:      http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			status := serveWorkspace(t, auth, tt.resolve, method, tt.token, tt.workspaceID, tt.userID)
+			assert.Equal(t, tt.status, status)
+		})
+	}
+}
@@ -0,0 +1,58 @@
+// Package accessreport lists everyone with access to a workspace, for
+// admins' periodic access reviews. Todos aren't shared by link, so access
+// is only held by members, through their API keys too, and by the
+// workspace's SCIM token.
+package accessreport
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/model/apikey"
+	"github.com/mabhi256/tasker/internal/model/workspace"
+)
+
+// MaxInlineEntries caps the entries of a report served in the response.
+// Larger workspaces have theirs generated in the background.
+const MaxInlineEntries = 500
+
+type Kind string
+
+const (
+	KindMember Kind = "member"
+	// KindServiceAccount is the workspace's SCIM token, which provisions
+	// members on behalf of the identity provider
+	KindServiceAccount Kind = "service_account"
+)
+
+// APIKey is a key a member can act with, without its hash
+type APIKey struct {
+	ID         uuid.UUID    `json:"id"`
+	Name       string       `json:"name"`
+	Scope      apikey.Scope `json:"scope"`
+	Hint       string       `json:"hint"`
+	CreatedAt  time.Time    `json:"createdAt"`
+	LastUsedAt *time.Time   `json:"lastUsedAt"`
+}
+
+// Entry is a member or service account. ID is the member's user id or the
+// SCIM token's id, and service accounts have no role. A member's Email is
+// only known when the identity provider provisioned them, and their Name
+// when it did or the auth provider has one. LastActiveAt is the member's latest change
+// recorded in the workspace, or when the token was last used.
+type Entry struct {
+	Kind         Kind            `json:"kind" db:"kind"`
+	ID           string          `json:"id" db:"id"`
+	Name         *string         `json:"name" db:"name"`
+	Email        *string         `json:"email" db:"email"`
+	Role         *workspace.Role `json:"role" db:"role"`
+	JoinedAt     time.Time       `json:"joinedAt" db:"joined_at"`
+	LastActiveAt *time.Time      `json:"lastActiveAt" db:"last_active_at"`
+	APIKeys      []APIKey        `json:"apiKeys" db:"api_keys"`
+}
+
+type AccessReport struct {
+	WorkspaceID uuid.UUID `json:"workspaceId"`
+	GeneratedAt time.Time `json:"generatedAt"`
+	Entries     []Entry   `json:"entries"`
+}
@@ -0,0 +1,17 @@
+package accessreport
+
+import (
+	"github.com/go-playground/validator/v10"
+)
+
+// ------------------------------------------------------------
+
+type GetAccessReportPayload struct {
+	// Format is json or csv, which is downloaded as a file
+	Format *string `query:"format" validate:"omitempty,oneof=json csv"`
+}
+
+func (p *GetAccessReportPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
@@ -0,0 +1,71 @@
+package activity
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/model"
+)
+
+// Verb is what the actor did to the object
+type Verb string
+
+const (
+	VerbCreated   Verb = "created"
+	VerbCompleted Verb = "completed"
+	VerbCommented Verb = "commented"
+	VerbRenamed   Verb = "renamed"
+	VerbMerged    Verb = "merged"
+	VerbAdded     Verb = "added"
+	// VerbChangedRole changes a member's role in the workspace
+	VerbChangedRole Verb = "changed_role"
+	VerbRemoved     Verb = "removed"
+)
+
+type ObjectType string
+
+const (
+	ObjectTodo    ObjectType = "todo"
+	ObjectComment ObjectType = "comment"
+	ObjectTag     ObjectType = "tag"
+	ObjectMember  ObjectType = "member"
+)
+
+// Object is what an activity is about
+type Object struct {
+	Type ObjectType `json:"type"`
+	ID   string     `json:"id"`
+	// Name is what the object is shown as, such as a todo's title
+	Name string `json:"name,omitempty"`
+}
+
+// Activity is an event of a workspace shaped for display, as in "alice
+// commented on Buy milk": the actor, the verb, the object and, when the
+// object belongs to another one, the target
+type Activity struct {
+	ID   uuid.UUID `json:"id"`
+	Time time.Time `json:"time"`
+	// ActorID is the user who acted. It is unset for events without one,
+	// such as a scheduled todo being published.
+	ActorID *string `json:"actorId"`
+	Verb    Verb    `json:"verb"`
+	Object  Object  `json:"object"`
+	Target  *Object `json:"target,omitempty"`
+	// Details describe the change, such as the new name of a renamed tag or
+	// the role of an added member
+	Details map[string]string `json:"details,omitempty"`
+}
+
+type Feed = model.PaginatedResponse[Activity]
+
+// Event is an outbox event of a workspace an activity is shaped from
+type Event struct {
+	ID        uuid.UUID       `db:"id"`
+	CreatedAt time.Time       `db:"created_at"`
+	EventType string          `db:"event_type"`
+	ActorID   *string         `db:"actor_id"`
+	Payload   json.RawMessage `db:"payload"`
+	// TodoTitle is the current title of the todo a comment event is about
+	TodoTitle *string `db:"todo_title"`
+}
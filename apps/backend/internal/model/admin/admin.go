@@ -0,0 +1,267 @@
+package admin
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/lib/readonly"
+	"github.com/mabhi256/tasker/internal/model/todo"
+)
+
+const ImpersonationKeyPrefix = "impersonation:"
+
+// ImpersonationKey returns the Redis key that stores an impersonation session
+func ImpersonationKey(token string) string {
+	return ImpersonationKeyPrefix + token
+}
+
+type UserSummary struct {
+	UserID         string     `json:"userId" db:"user_id"`
+	TodoCount      int        `json:"todoCount" db:"todo_count"`
+	CompletedCount int        `json:"completedCount" db:"completed_count"`
+	CategoryCount  int        `json:"categoryCount" db:"category_count"`
+	CommentCount   int        `json:"commentCount" db:"comment_count"`
+	LastActivityAt *time.Time `json:"lastActivityAt" db:"last_activity_at"`
+}
+
+type ImpersonationSession struct {
+	Token     string    `json:"token"`
+	AdminID   string    `json:"adminId"`
+	UserID    string    `json:"userId"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type QueueStats struct {
+	Queue     string `json:"queue"`
+	Size      int    `json:"size"`
+	Pending   int    `json:"pending"`
+	Active    int    `json:"active"`
+	Scheduled int    `json:"scheduled"`
+	Retry     int    `json:"retry"`
+	Archived  int    `json:"archived"`
+	Processed int    `json:"processed"`
+	Failed    int    `json:"failed"`
+	Paused    bool   `json:"paused"`
+	LatencyMs int64  `json:"latencyMs"`
+}
+
+type DatabasePoolStats struct {
+	MaxConns             int32 `json:"maxConns"`
+	TotalConns           int32 `json:"totalConns"`
+	AcquiredConns        int32 `json:"acquiredConns"`
+	IdleConns            int32 `json:"idleConns"`
+	ConstructingConns    int32 `json:"constructingConns"`
+	AcquireCount         int64 `json:"acquireCount"`
+	EmptyAcquireCount    int64 `json:"emptyAcquireCount"`
+	CanceledAcquireCount int64 `json:"canceledAcquireCount"`
+	AcquireDurationMs    int64 `json:"acquireDurationMs"`
+}
+
+// CacheStats are counted by this instance since it started
+type CacheStats struct {
+	Hits       int64 `json:"hits"`
+	Misses     int64 `json:"misses"`
+	Coalesced  int64 `json:"coalesced"`
+	LoadErrors int64 `json:"loadErrors"`
+}
+
+// RefCacheStats are counted by this instance since it started
+type RefCacheStats struct {
+	Entries       int   `json:"entries"`
+	Hits          int64 `json:"hits"`
+	Misses        int64 `json:"misses"`
+	Evictions     int64 `json:"evictions"`
+	Invalidations int64 `json:"invalidations"`
+}
+
+// RealtimeStats are counted by this instance since it started
+type RealtimeStats struct {
+	Connections  int   `json:"connections"`
+	Dropped      int64 `json:"dropped"`
+	Coalesced    int64 `json:"coalesced"`
+	Disconnected int64 `json:"disconnected"`
+}
+
+// ReadOnlyStatus is whether this instance rejects writes, and which sources
+// engage read-only mode
+type ReadOnlyStatus struct {
+	ReadOnly    bool                  `json:"readOnly"`
+	Engagements []readonly.Engagement `json:"engagements"`
+}
+
+type SystemStats struct {
+	Queues      []QueueStats      `json:"queues"`
+	Database    DatabasePoolStats `json:"database"`
+	Cache       CacheStats        `json:"cache"`
+	RefCache    RefCacheStats     `json:"refCache"`
+	Realtime    RealtimeStats     `json:"realtime"`
+	GeneratedAt time.Time         `json:"generatedAt"`
+}
+
+// AttachmentIntegrityIssue is an attachment whose stored object failed its
+// last integrity check
+type AttachmentIntegrityIssue struct {
+	AttachmentID       uuid.UUID            `json:"attachmentId" db:"attachment_id"`
+	TodoID             uuid.UUID            `json:"todoId" db:"todo_id"`
+	WorkspaceID        uuid.UUID            `json:"workspaceId" db:"workspace_id"`
+	Name               string               `json:"name" db:"name"`
+	DownloadKey        string               `json:"downloadKey" db:"download_key"`
+	FileSize           *int64               `json:"fileSize" db:"file_size"`
+	ChecksumSHA256     *string              `json:"checksumSha256" db:"checksum_sha256"`
+	IntegrityStatus    todo.IntegrityStatus `json:"integrityStatus" db:"integrity_status"`
+	IntegrityError     *string              `json:"integrityError" db:"integrity_error"`
+	IntegrityCheckedAt *time.Time           `json:"integrityCheckedAt" db:"integrity_checked_at"`
+	UploadedAt         time.Time            `json:"uploadedAt" db:"uploaded_at"`
+}
+
+type AttachmentIntegritySummary struct {
+	Total         int        `json:"total" db:"total"`
+	Unverified    int        `json:"unverified" db:"unverified"`
+	OK            int        `json:"ok" db:"ok"`
+	Missing       int        `json:"missing" db:"missing"`
+	Corrupt       int        `json:"corrupt" db:"corrupt"`
+	LastCheckedAt *time.Time `json:"lastCheckedAt" db:"last_checked_at"`
+}
+
+// ScheduledJob is a recurring cron job run by the job server
+type ScheduledJob struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Schedule    string     `json:"schedule"`
+	NextRunAt   *time.Time `json:"nextRunAt"`
+	LastRunAt   *time.Time `json:"lastRunAt"`
+}
+
+type TaskState string
+
+const (
+	TaskStatePending   TaskState = "pending"
+	TaskStateActive    TaskState = "active"
+	TaskStateScheduled TaskState = "scheduled"
+	TaskStateRetry     TaskState = "retry"
+	// TaskStateArchived holds dead tasks that ran out of retries
+	TaskStateArchived TaskState = "archived"
+)
+
+// JobTask is a background task as stored by the job queue
+type JobTask struct {
+	ID            string          `json:"id"`
+	Queue         string          `json:"queue"`
+	Type          string          `json:"type"`
+	Payload       json.RawMessage `json:"payload"`
+	State         TaskState       `json:"state"`
+	MaxRetry      int             `json:"maxRetry"`
+	Retried       int             `json:"retried"`
+	LastError     *string         `json:"lastError"`
+	LastFailedAt  *time.Time      `json:"lastFailedAt"`
+	NextProcessAt *time.Time      `json:"nextProcessAt"`
+}
+
+// UsageTotals is resource use summed over workspaces
+type UsageTotals struct {
+	DBTimeMs      int64 `json:"dbTimeMs" db:"db_time_ms"`
+	JobExecutions int64 `json:"jobExecutions" db:"job_executions"`
+	EmailsSent    int64 `json:"emailsSent" db:"emails_sent"`
+	StorageBytes  int64 `json:"storageBytes" db:"storage_bytes"`
+}
+
+// UsageShare is a workspace's fraction, from 0 to 1, of each total
+type UsageShare struct {
+	DBTime        float64 `json:"dbTime"`
+	JobExecutions float64 `json:"jobExecutions"`
+	EmailsSent    float64 `json:"emailsSent"`
+	StorageBytes  float64 `json:"storageBytes"`
+}
+
+// WorkspaceUsage is the resource use charged to a workspace on a day. The
+// nil workspace ID holds use no workspace could be charged for; it and
+// deleted workspaces have no name.
+type WorkspaceUsage struct {
+	WorkspaceID   uuid.UUID  `json:"workspaceId" db:"workspace_id"`
+	WorkspaceName *string    `json:"workspaceName" db:"workspace_name"`
+	DBTimeMs      int64      `json:"dbTimeMs" db:"db_time_ms"`
+	JobExecutions int64      `json:"jobExecutions" db:"job_executions"`
+	EmailsSent    int64      `json:"emailsSent" db:"emails_sent"`
+	StorageBytes  int64      `json:"storageBytes" db:"storage_bytes"`
+	Share         UsageShare `json:"share" db:"-"`
+}
+
+// CostAttributionReport breaks down a UTC day's resource use by workspace
+type CostAttributionReport struct {
+	Day        string           `json:"day"`
+	Totals     UsageTotals      `json:"totals"`
+	Workspaces []WorkspaceUsage `json:"workspaces"`
+}
+
+type RolloutStatus string
+
+const (
+	RolloutStatusPending    RolloutStatus = "pending"
+	RolloutStatusRunning    RolloutStatus = "running"
+	RolloutStatusBackfilled RolloutStatus = "backfilled"
+	// RolloutStatusVerified means no rows were left pending when last
+	// checked, so the contract migration can ship
+	RolloutStatusVerified RolloutStatus = "verified"
+	RolloutStatusFailed   RolloutStatus = "failed"
+)
+
+// RolloutProgress is how far the backfill of an expand/contract change got
+type RolloutProgress struct {
+	Name         string        `json:"-" db:"name"`
+	CreatedAt    time.Time     `json:"createdAt" db:"created_at"`
+	UpdatedAt    time.Time     `json:"updatedAt" db:"updated_at"`
+	Status       RolloutStatus `json:"status" db:"status"`
+	LastID       uuid.UUID     `json:"lastId" db:"last_id"`
+	RowsDone     int64         `json:"rowsDone" db:"rows_done"`
+	Batches      int           `json:"batches" db:"batches"`
+	PendingRows  *int64        `json:"pendingRows" db:"pending_rows"`
+	LastError    *string       `json:"lastError" db:"last_error"`
+	StartedAt    *time.Time    `json:"startedAt" db:"started_at"`
+	BackfilledAt *time.Time    `json:"backfilledAt" db:"backfilled_at"`
+	VerifiedAt   *time.Time    `json:"verifiedAt" db:"verified_at"`
+}
+
+// Rollout is an expand/contract change the application knows about. A
+// rollout whose backfill never ran has no progress.
+type Rollout struct {
+	Name     string           `json:"name"`
+	Table    string           `json:"table"`
+	Progress *RolloutProgress `json:"progress"`
+}
+
+type BackfillStatus string
+
+const (
+	BackfillStatusRunning   BackfillStatus = "running"
+	BackfillStatusPaused    BackfillStatus = "paused"
+	BackfillStatusCompleted BackfillStatus = "completed"
+	BackfillStatusAborted   BackfillStatus = "aborted"
+	// BackfillStatusFailed means the backfill ran out of retries. It can be
+	// resumed from its checkpoint.
+	BackfillStatusFailed BackfillStatus = "failed"
+)
+
+// BackfillRun is the checkpoint of the latest run of a backfill
+type BackfillRun struct {
+	Name       string         `json:"-" db:"name"`
+	CreatedAt  time.Time      `json:"createdAt" db:"created_at"`
+	UpdatedAt  time.Time      `json:"updatedAt" db:"updated_at"`
+	Status     BackfillStatus `json:"status" db:"status"`
+	Checkpoint string         `json:"checkpoint" db:"checkpoint"`
+	RowsDone   int64          `json:"rowsDone" db:"rows_done"`
+	Batches    int            `json:"batches" db:"batches"`
+	LastError  *string        `json:"lastError" db:"last_error"`
+	StartedAt  time.Time      `json:"startedAt" db:"started_at"`
+	FinishedAt *time.Time     `json:"finishedAt" db:"finished_at"`
+}
+
+// Backfill is a data migration the application can run. A backfill that
+// never ran has no run.
+type Backfill struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Run         *BackfillRun `json:"run"`
+}
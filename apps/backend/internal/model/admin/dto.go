@@ -0,0 +1,300 @@
+package admin
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/mabhi256/tasker/internal/model/todo"
+)
+
+// ------------------------------------------------------------
+
+type GetUsersQuery struct {
+	Page   *int    `query:"page" validate:"omitempty,min=1"`
+	Limit  *int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Search *string `query:"search" validate:"omitempty,min=1"`
+}
+
+func (q *GetUsersQuery) Validate() error {
+	validate := validator.New()
+
+	if err := validate.Struct(q); err != nil {
+		return err
+	}
+
+	// Set defaults for pagination
+	if q.Page == nil {
+		defaultPage := 1
+		q.Page = &defaultPage
+	}
+	if q.Limit == nil {
+		defaultLimit := 20
+		q.Limit = &defaultLimit
+	}
+
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type GetUserTodosQuery struct {
+	UserID   string         `param:"userId" validate:"required,min=1"`
+	Page     *int           `query:"page" validate:"omitempty,min=1"`
+	Limit    *int           `query:"limit" validate:"omitempty,min=1,max=100"`
+	Sort     *string        `query:"sort" validate:"omitempty,oneof=created_at updated_at title priority due_date status"`
+	Order    *string        `query:"order" validate:"omitempty,oneof=asc desc"`
+	Search   *string        `query:"search" validate:"omitempty,min=1"`
+	Status   *todo.Status   `query:"status" validate:"omitempty,oneof=draft active completed archived scheduled"`
+	Priority *todo.Priority `query:"priority" validate:"omitempty,oneof=low medium high"`
+}
+
+func (q *GetUserTodosQuery) Validate() error {
+	validate := validator.New()
+	return validate.Struct(q)
+}
+
+// ToTodosQuery converts the admin query into the regular todo listing query
+func (q *GetUserTodosQuery) ToTodosQuery() (*todo.GetTodosQuery, error) {
+	query := &todo.GetTodosQuery{
+		Page:     q.Page,
+		Limit:    q.Limit,
+		Sort:     q.Sort,
+		Order:    q.Order,
+		Search:   q.Search,
+		Status:   q.Status,
+		Priority: q.Priority,
+	}
+
+	// Applies the same defaults as the user-facing endpoint
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	return query, nil
+}
+
+// ------------------------------------------------------------
+
+type StartImpersonationPayload struct {
+	UserID string `param:"userId" validate:"required,min=1"`
+	Reason string `json:"reason" validate:"required,min=3,max=500"`
+}
+
+func (p *StartImpersonationPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type StopImpersonationPayload struct {
+	Token string `param:"token" validate:"required,hexadecimal,len=64"`
+}
+
+func (p *StopImpersonationPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type GetSystemStatsPayload struct{}
+
+func (p *GetSystemStatsPayload) Validate() error {
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type GetAttachmentIntegrityPayload struct{}
+
+func (p *GetAttachmentIntegrityPayload) Validate() error {
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type GetAttachmentIntegrityIssuesQuery struct {
+	Page   *int    `query:"page" validate:"omitempty,min=1"`
+	Limit  *int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Status *string `query:"status" validate:"omitempty,oneof=missing corrupt"`
+}
+
+func (q *GetAttachmentIntegrityIssuesQuery) Validate() error {
+	validate := validator.New()
+
+	if err := validate.Struct(q); err != nil {
+		return err
+	}
+
+	// Set defaults for pagination
+	if q.Page == nil {
+		defaultPage := 1
+		q.Page = &defaultPage
+	}
+	if q.Limit == nil {
+		defaultLimit := 20
+		q.Limit = &defaultLimit
+	}
+
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type GetReadOnlyPayload struct{}
+
+func (p *GetReadOnlyPayload) Validate() error {
+	return nil
+}
+
+// ------------------------------------------------------------
+
+// SetReadOnlyPayload switches the admin's read-only mode. Read-only mode
+// engaged by the config or the database is left as it is.
+type SetReadOnlyPayload struct {
+	Enabled *bool  `json:"enabled" validate:"required"`
+	Reason  string `json:"reason" validate:"required_if=Enabled true,omitempty,min=3,max=500"`
+}
+
+func (p *SetReadOnlyPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type GetScheduledJobsPayload struct{}
+
+func (p *GetScheduledJobsPayload) Validate() error {
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type GetQueuesPayload struct{}
+
+func (p *GetQueuesPayload) Validate() error {
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type GetQueueTasksQuery struct {
+	Queue string  `param:"queue" validate:"required,min=1"`
+	State *string `query:"state" validate:"omitempty,oneof=pending active scheduled retry archived"`
+	Page  *int    `query:"page" validate:"omitempty,min=1"`
+	Limit *int    `query:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+func (q *GetQueueTasksQuery) Validate() error {
+	validate := validator.New()
+
+	if err := validate.Struct(q); err != nil {
+		return err
+	}
+
+	// Set defaults
+	if q.State == nil {
+		defaultState := string(TaskStateArchived)
+		q.State = &defaultState
+	}
+	if q.Page == nil {
+		defaultPage := 1
+		q.Page = &defaultPage
+	}
+	if q.Limit == nil {
+		defaultLimit := 20
+		q.Limit = &defaultLimit
+	}
+
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type QueueTaskPayload struct {
+	Queue  string `param:"queue" validate:"required,min=1"`
+	TaskID string `param:"taskId" validate:"required,min=1"`
+}
+
+func (p *QueueTaskPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type QueuePayload struct {
+	Queue string `param:"queue" validate:"required,min=1"`
+}
+
+func (p *QueuePayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+// GetCostAttributionQuery selects the UTC day to report on, yesterday by
+// default, and how many of the workspaces using the most of Sort to list
+type GetCostAttributionQuery struct {
+	Day   *string `query:"day" validate:"omitempty,datetime=2006-01-02"`
+	Limit *int    `query:"limit" validate:"omitempty,min=1,max=500"`
+	Sort  *string `query:"sort" validate:"omitempty,oneof=db_time_ms job_executions emails_sent storage_bytes"`
+}
+
+func (q *GetCostAttributionQuery) Validate() error {
+	validate := validator.New()
+
+	if err := validate.Struct(q); err != nil {
+		return err
+	}
+
+	if q.Limit == nil {
+		defaultLimit := 100
+		q.Limit = &defaultLimit
+	}
+	if q.Sort == nil {
+		defaultSort := "db_time_ms"
+		q.Sort = &defaultSort
+	}
+
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type GetRolloutsPayload struct{}
+
+func (p *GetRolloutsPayload) Validate() error {
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type RolloutPayload struct {
+	Name string `param:"name" validate:"required,min=1"`
+}
+
+func (p *RolloutPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type GetBackfillsPayload struct{}
+
+func (p *GetBackfillsPayload) Validate() error {
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type BackfillPayload struct {
+	Name string `param:"name" validate:"required,min=1"`
+}
+
+func (p *BackfillPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
@@ -0,0 +1,83 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/mabhi256/tasker/internal/model"
+)
+
+// Prefix starts every API key, so that it can be told apart from the auth
+// provider's session tokens in the Authorization header
+const Prefix = "tasker_sk_"
+
+type Scope string
+
+const (
+	ScopeReadOnly  Scope = "read_only"
+	ScopeReadWrite Scope = "read_write"
+)
+
+// Allows reports whether a request with the method may be made with a key
+// of the scope. Read-only keys can only make safe requests.
+func (s Scope) Allows(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	default:
+		return s == ScopeReadWrite
+	}
+}
+
+// Generate returns a new key, 32 random bytes after Prefix
+func Generate() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return Prefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Hash is how keys are stored and looked up. Keys are random, so a fast
+// hash is enough.
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// EventRevoked is pushed to the realtime connections opened with a key
+// before they are closed
+const EventRevoked = "api_key.revoked"
+
+// Key authenticates requests as the user who created it
+type Key struct {
+	model.BaseWithId
+	model.BaseWithCreatedAt
+	UserID     string     `json:"userId" db:"user_id"`
+	Name       string     `json:"name" db:"name"`
+	Scope      Scope      `json:"scope" db:"scope"`
+	KeyHash    string     `json:"-" db:"key_hash"`
+	Hint       string     `json:"hint" db:"hint"`
+	LastUsedAt *time.Time `json:"lastUsedAt" db:"last_used_at"`
+}
+
+// KeyWithSecret is returned when a key is created, the only time it is shown
+type KeyWithSecret struct {
+	Key
+	Secret string `json:"key"`
+}
+
+// RevokedEvent is the realtime message of EventRevoked
+type RevokedEvent struct {
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Data      RevokedData `json:"data"`
+}
+
+type RevokedData struct {
+	APIKeyID string `json:"apiKeyId"`
+}
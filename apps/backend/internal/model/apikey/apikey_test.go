@@ -0,0 +1,56 @@
+package apikey_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/mabhi256/tasker/internal/model/apikey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	first, err := apikey.Generate()
+	require.NoError(t, err)
+	second, err := apikey.Generate()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(first, apikey.Prefix))
+	// 32 bytes take 43 characters of unpadded base64
+	assert.Len(t, first, len(apikey.Prefix)+43)
+	assert.NotEqual(t, first, second)
+}
+
+func TestHash(t *testing.T) {
+	key := apikey.Prefix + "abc"
+
+	assert.Equal(t, "f932d84c0b1168eb35d5c1b21c68dfaaddc297c0a850c834e94409869181b8f3", apikey.Hash(key))
+	assert.Equal(t, apikey.Hash(key), apikey.Hash(key))
+	assert.NotEqual(t, apikey.Hash(key), apikey.Hash(key+"d"))
+	assert.NotContains(t, apikey.Hash(key), key)
+}
+
+func TestScopeAllows(t *testing.T) {
+	tests := []struct {
+		method    string
+		readOnly  bool
+		readWrite bool
+	}{
+		{method: http.MethodGet, readOnly: true, readWrite: true},
+		{method: http.MethodHead, readOnly: true, readWrite: true},
+		{method: http.MethodOptions, readOnly: true, readWrite: true},
+		{method: http.MethodPost, readWrite: true},
+		{method: http.MethodPut, readWrite: true},
+		{method: http.MethodPatch, readWrite: true},
+		{method: http.MethodDelete, readWrite: true},
+		{method: "PROPFIND", readWrite: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			assert.Equal(t, tt.readOnly, apikey.ScopeReadOnly.Allows(tt.method))
+			assert.Equal(t, tt.readWrite, apikey.ScopeReadWrite.Allows(tt.method))
+		})
+	}
+	assert.False(t, apikey.Scope("").Allows(http.MethodPost))
+}
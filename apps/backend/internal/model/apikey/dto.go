@@ -0,0 +1,37 @@
+package apikey
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// ------------------------------------------------------------
+
+type CreateKeyPayload struct {
+	Name  string `json:"name" validate:"required,min=1,max=100"`
+	Scope Scope  `json:"scope" validate:"required,oneof=read_only read_write"`
+}
+
+func (p *CreateKeyPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type GetKeysPayload struct{}
+
+func (p *GetKeysPayload) Validate() error {
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type RevokeKeyPayload struct {
+	ID uuid.UUID `param:"id" validate:"required,uuid"`
+}
+
+func (p *RevokeKeyPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
@@ -0,0 +1,142 @@
+package audit
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/model"
+)
+
+// EventType names an audit event. Audit events share the outbox with domain
+// events and are told apart by the audit. prefix.
+type EventType string
+
+const eventTypePrefix = "audit."
+
+const (
+	EventMemberAdded          EventType = "audit.member.added"
+	EventMemberRoleChanged    EventType = "audit.member.role_changed"
+	EventMemberRemoved        EventType = "audit.member.removed"
+	EventWorkspaceDeleted     EventType = "audit.workspace.deleted"
+	EventWebhookCreated       EventType = "audit.webhook.created"
+	EventWebhookSecretRotated EventType = "audit.webhook.secret_rotated"
+	EventWebhookDeleted       EventType = "audit.webhook.deleted"
+	EventForwarderCreated     EventType = "audit.forwarder.created"
+	EventForwarderUpdated     EventType = "audit.forwarder.updated"
+	EventForwarderDeleted     EventType = "audit.forwarder.deleted"
+	EventImpersonationStarted EventType = "audit.admin.impersonation_started"
+	EventImpersonationStopped EventType = "audit.admin.impersonation_stopped"
+	EventSCIMTokenCreated     EventType = "audit.scim.token_created"
+	EventSCIMTokenDeleted     EventType = "audit.scim.token_deleted"
+	EventSCIMGroupRoleChanged EventType = "audit.scim.group_role_changed"
+	EventSSOConfigUpdated     EventType = "audit.sso.config_updated"
+	EventSSOConfigDeleted     EventType = "audit.sso.config_deleted"
+	EventSSODomainVerified    EventType = "audit.sso.domain_verified"
+	EventIPAllowlistUpdated   EventType = "audit.ip_allowlist.updated"
+	EventIPEntryAdded         EventType = "audit.ip_allowlist.entry_added"
+	EventIPEntryRemoved       EventType = "audit.ip_allowlist.entry_removed"
+	EventIPDenied             EventType = "audit.ip_allowlist.denied"
+	EventBreakGlassStarted    EventType = "audit.ip_allowlist.break_glass_started"
+	EventBreakGlassEnded      EventType = "audit.ip_allowlist.break_glass_ended"
+	EventSessionRevoked       EventType = "audit.session.revoked"
+	EventUserSessionsRevoked  EventType = "audit.admin.user_sessions_revoked"
+	EventReadOnlyEngaged      EventType = "audit.admin.read_only_engaged"
+	EventReadOnlyReleased     EventType = "audit.admin.read_only_released"
+	EventAPIKeyCreated        EventType = "audit.api_key.created"
+	EventAPIKeyRevoked        EventType = "audit.api_key.revoked"
+	EventTagRenamed           EventType = "audit.tag.renamed"
+	EventTagMerged            EventType = "audit.tag.merged"
+	EventTrialClaimed         EventType = "audit.trial.claimed"
+)
+
+// IsAuditEvent reports whether an outbox event type is an audit event
+func IsAuditEvent(eventType string) bool {
+	return strings.HasPrefix(eventType, eventTypePrefix)
+}
+
+type Severity int
+
+// Severities follow syslog, lower is more severe
+const (
+	SeverityWarning Severity = 4
+	SeverityNotice  Severity = 5
+)
+
+// Severity is raised for events that grant access to other users' data,
+// change how members sign in or where from, change where the audit trail
+// goes, or are requests that were denied
+func (t EventType) Severity() Severity {
+	switch t {
+	case EventImpersonationStarted, EventForwarderUpdated, EventForwarderDeleted,
+		EventSCIMTokenCreated, EventSCIMGroupRoleChanged, EventSSOConfigUpdated,
+		EventSSOConfigDeleted, EventSSODomainVerified, EventIPAllowlistUpdated, EventIPEntryAdded,
+		EventIPDenied, EventBreakGlassStarted:
+		return SeverityWarning
+	default:
+		return SeverityNotice
+	}
+}
+
+// Actor is who caused the event. ImpersonatorID is set when an admin acted
+// as UserID, and APIKeyID when UserID acted with one of their API keys.
+// ClientID is set instead of UserID for machine clients.
+type Actor struct {
+	UserID         string `json:"userId"`
+	ImpersonatorID string `json:"impersonatorId,omitempty"`
+	APIKeyID       string `json:"apiKeyId,omitempty"`
+	ClientID       string `json:"clientId,omitempty"`
+	IP             string `json:"ip,omitempty"`
+	UserAgent      string `json:"userAgent,omitempty"`
+	RequestID      string `json:"requestId,omitempty"`
+}
+
+// Target is what the event happened to
+type Target struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// Record is what an audit event holds besides its identity, and is stored as
+// the outbox payload
+type Record struct {
+	Actor   Actor             `json:"actor"`
+	Target  Target            `json:"target"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// Event is an audit event as forwarded to SIEMs
+type Event struct {
+	ID          uuid.UUID  `json:"id"`
+	Type        EventType  `json:"type"`
+	Time        time.Time  `json:"time"`
+	WorkspaceID *uuid.UUID `json:"workspaceId"`
+	Record
+}
+
+type Format string
+
+const (
+	// FormatSyslog sends RFC 5424 messages to a udp://, tcp:// or tls://
+	// host:port endpoint
+	FormatSyslog Format = "syslog"
+	// FormatHTTP posts each event as JSON to an https URL
+	FormatHTTP Format = "http"
+	// FormatSplunkHEC posts events to a Splunk HTTP Event Collector
+	FormatSplunkHEC Format = "splunk_hec"
+)
+
+// Forwarder ships audit events to a SIEM. Without a workspace it is global
+// and receives the events of every workspace and of the admin API.
+type Forwarder struct {
+	model.Base
+	WorkspaceID     *uuid.UUID `json:"workspaceId" db:"workspace_id"`
+	CreatedBy       string     `json:"createdBy" db:"created_by"`
+	Name            string     `json:"name" db:"name"`
+	Format          Format     `json:"format" db:"format"`
+	Endpoint        string     `json:"endpoint" db:"endpoint"`
+	Token           *string    `json:"-" db:"token"`
+	Enabled         bool       `json:"enabled" db:"enabled"`
+	LastForwardedAt *time.Time `json:"lastForwardedAt" db:"last_forwarded_at"`
+	LastError       *string    `json:"lastError" db:"last_error"`
+}
@@ -0,0 +1,66 @@
+package audit
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// ------------------------------------------------------------
+
+type CreateForwarderPayload struct {
+	Name     string  `json:"name" validate:"required,min=1,max=100"`
+	Format   Format  `json:"format" validate:"required,oneof=syslog http splunk_hec"`
+	Endpoint string  `json:"endpoint" validate:"required,url,max=2048"`
+	Token    *string `json:"token" validate:"omitempty,max=4096"`
+	Enabled  *bool   `json:"enabled"`
+}
+
+func (p *CreateForwarderPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type GetForwardersPayload struct{}
+
+func (p *GetForwardersPayload) Validate() error {
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type GetForwarderByIDPayload struct {
+	ID uuid.UUID `param:"id" validate:"required,uuid"`
+}
+
+func (p *GetForwarderByIDPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type UpdateForwarderPayload struct {
+	ID       uuid.UUID `param:"id" validate:"required,uuid"`
+	Name     *string   `json:"name" validate:"omitempty,min=1,max=100"`
+	Endpoint *string   `json:"endpoint" validate:"omitempty,url,max=2048"`
+	Token    *string   `json:"token" validate:"omitempty,max=4096"`
+	Enabled  *bool     `json:"enabled"`
+}
+
+func (p *UpdateForwarderPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type DeleteForwarderPayload struct {
+	ID uuid.UUID `param:"id" validate:"required,uuid"`
+}
+
+func (p *DeleteForwarderPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
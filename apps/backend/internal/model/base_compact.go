@@ -0,0 +1,14 @@
+package model
+
+import "github.com/mabhi256/tasker/internal/lib/jsonenc"
+
+// Compact implements jsonenc.Compacter, compacting the page's items
+func (p *PaginatedResponse[T]) Compact() any {
+	return &PaginatedResponse[any]{
+		Data:       jsonenc.CompactSlice(p.Data),
+		Page:       p.Page,
+		Limit:      p.Limit,
+		Total:      p.Total,
+		TotalPages: p.TotalPages,
+	}
+}
@@ -0,0 +1,34 @@
+//go:build fastjson
+
+package model
+
+import "github.com/mabhi256/tasker/internal/lib/jsonenc"
+
+// AppendBase writes the fields of Base, without braces, for the encoders of
+// types that embed it
+func AppendBase(dst []byte, b *Base) []byte {
+	dst = append(dst, `"id":`...)
+	dst = jsonenc.AppendUUID(dst, b.ID)
+	dst = append(dst, `,"createdAt":`...)
+	dst = jsonenc.AppendTime(dst, b.CreatedAt)
+	dst = append(dst, `,"updatedAt":`...)
+	dst = jsonenc.AppendTime(dst, b.UpdatedAt)
+	return dst
+}
+
+func (p *PaginatedResponse[T]) AppendJSON(dst []byte) ([]byte, error) {
+	dst = append(dst, `{"data":`...)
+	dst, err := jsonenc.AppendSlice(dst, p.Data)
+	if err != nil {
+		return nil, err
+	}
+	dst = append(dst, `,"page":`...)
+	dst = jsonenc.AppendInt(dst, int64(p.Page))
+	dst = append(dst, `,"limit":`...)
+	dst = jsonenc.AppendInt(dst, int64(p.Limit))
+	dst = append(dst, `,"total":`...)
+	dst = jsonenc.AppendInt(dst, int64(p.Total))
+	dst = append(dst, `,"totalPages":`...)
+	dst = jsonenc.AppendInt(dst, int64(p.TotalPages))
+	return append(dst, '}'), nil
+}
@@ -0,0 +1,72 @@
+package capability
+
+import (
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/authz"
+	"github.com/mabhi256/tasker/internal/model/device"
+	"github.com/mabhi256/tasker/internal/model/todo"
+	"github.com/mabhi256/tasker/internal/model/workspace"
+)
+
+// Reason is why a feature is unavailable, so clients can tell an empty
+// state from one that points to what would enable the feature
+type Reason string
+
+const (
+	// ReasonNotConfigured means the deployment hasn't enabled the feature
+	ReasonNotConfigured Reason = "not_configured"
+	// ReasonPersonalWorkspace means the feature needs a shared workspace
+	ReasonPersonalWorkspace Reason = "personal_workspace"
+	// ReasonRole means the feature needs a higher role in the workspace
+	ReasonRole Reason = "role"
+)
+
+// Feature says whether a feature can be used, and why not when it can't
+type Feature struct {
+	Available bool    `json:"available"`
+	Reason    *Reason `json:"reason,omitempty"`
+	// RequiredRole is the workspace role the feature needs, if any
+	RequiredRole *workspace.Role `json:"requiredRole,omitempty"`
+}
+
+// Features are keyed by name: push, members, sso, provisioning,
+// ipAllowlist, webhooks, exports and auditLog
+type Features map[string]Feature
+
+// Limits are the largest values the API accepts
+type Limits struct {
+	TodoTitleLength       int `json:"todoTitleLength"`
+	TodoDescriptionLength int `json:"todoDescriptionLength"`
+	CommentLength         int `json:"commentLength"`
+	CategoryNameLength    int `json:"categoryNameLength"`
+	MentionsPerComment    int `json:"mentionsPerComment"`
+	ResolveIDs            int `json:"resolveIds"`
+	PageSize              int `json:"pageSize"`
+	ReminderMinutes       int `json:"reminderMinutes"`
+}
+
+// TodoDefaults describe how the fields a new todo is created without are
+// filled in: from its category's defaults, and failing those from the
+// values here
+type TodoDefaults struct {
+	Priority        todo.Priority `json:"priority"`
+	ReminderMinutes int           `json:"reminderMinutes"`
+	// CategoryFields are the fields a category's defaults can fill in
+	CategoryFields []string `json:"categoryFields"`
+}
+
+// Capabilities describe what the user can do in the request's workspace,
+// so clients don't hardcode which features are available
+type Capabilities struct {
+	WorkspaceID   uuid.UUID         `json:"workspaceId"`
+	Role          workspace.Role    `json:"role"`
+	IsPersonal    bool              `json:"isPersonal"`
+	PushPlatforms []device.Platform `json:"pushPlatforms"`
+	Features      Features          `json:"features"`
+	Limits        Limits            `json:"limits"`
+	TodoDefaults  TodoDefaults      `json:"todoDefaults"`
+	// Actions the user may perform on any resource of the workspace. Some
+	// actions not listed, like editing a comment, are still allowed on the
+	// user's own resources.
+	Actions []authz.Action `json:"actions"`
+}
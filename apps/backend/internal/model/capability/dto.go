@@ -0,0 +1,9 @@
+package capability
+
+// ------------------------------------------------------------
+
+type GetCapabilitiesPayload struct{}
+
+func (p *GetCapabilitiesPayload) Validate() error {
+	return nil
+}
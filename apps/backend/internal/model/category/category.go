@@ -1,11 +1,20 @@
 package category
 
-import "github.com/mabhi256/tasker/internal/model"
+import (
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/model"
+)
 
 type Category struct {
 	model.Base
-	UserID      string  `json:"userId" db:"user_id"`
-	Name        string  `json:"name" db:"name"`
-	Color       string  `json:"color" db:"color"`
-	Description *string `json:"description" db:"description"`
+	WorkspaceID uuid.UUID `json:"workspaceId" db:"workspace_id"`
+	UserID      string    `json:"userId" db:"user_id"`
+	Name        string    `json:"name" db:"name"`
+	Color       string    `json:"color" db:"color"`
+	Description *string   `json:"description" db:"description"`
+
+	// DefaultPriority and DefaultReminderMinutes are given to the todos
+	// created in the category without their own
+	DefaultPriority        *string `json:"defaultPriority" db:"default_priority"`
+	DefaultReminderMinutes *int    `json:"defaultReminderMinutes" db:"default_reminder_minutes"`
 }
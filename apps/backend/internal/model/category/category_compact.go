@@ -0,0 +1,19 @@
+package category
+
+import "github.com/google/uuid"
+
+// CompactCategory is the compact view of a category
+type CompactCategory struct {
+	ID    uuid.UUID `json:"id"`
+	Name  string    `json:"name"`
+	Color string    `json:"color"`
+}
+
+// Compact implements jsonenc.Compacter
+func (c *Category) Compact() any {
+	return &CompactCategory{
+		ID:    c.ID,
+		Name:  c.Name,
+		Color: c.Color,
+	}
+}
@@ -0,0 +1,28 @@
+//go:build fastjson
+
+package category
+
+import (
+	"github.com/mabhi256/tasker/internal/lib/jsonenc"
+	"github.com/mabhi256/tasker/internal/model"
+)
+
+func (c *Category) AppendJSON(dst []byte) ([]byte, error) {
+	dst = append(dst, '{')
+	dst = model.AppendBase(dst, &c.Base)
+	dst = append(dst, `,"workspaceId":`...)
+	dst = jsonenc.AppendUUID(dst, c.WorkspaceID)
+	dst = append(dst, `,"userId":`...)
+	dst = jsonenc.AppendString(dst, c.UserID)
+	dst = append(dst, `,"name":`...)
+	dst = jsonenc.AppendString(dst, c.Name)
+	dst = append(dst, `,"color":`...)
+	dst = jsonenc.AppendString(dst, c.Color)
+	dst = append(dst, `,"description":`...)
+	dst = jsonenc.AppendStringPtr(dst, c.Description)
+	dst = append(dst, `,"defaultPriority":`...)
+	dst = jsonenc.AppendStringPtr(dst, c.DefaultPriority)
+	dst = append(dst, `,"defaultReminderMinutes":`...)
+	dst = jsonenc.AppendIntPtr(dst, c.DefaultReminderMinutes)
+	return append(dst, '}'), nil
+}
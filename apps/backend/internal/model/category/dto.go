@@ -3,13 +3,21 @@ package category
 import (
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/model"
 )
 
+// MaxNameLength limits category names, as the validate tags below enforce it
+const MaxNameLength = 100
+
 // ------------------------------------------------------------
 type CreateCategoryPayload struct {
 	Name        string  `json:"name" validate:"required,min=1,max=100"`
 	Color       string  `json:"color" validate:"required,hexcolor"`
 	Description *string `json:"description" validate:"omitempty,max=255"`
+	// DefaultPriority and DefaultReminderMinutes are given to the todos
+	// created in the category without their own
+	DefaultPriority        *string `json:"defaultPriority" validate:"omitempty,oneof=low medium high"`
+	DefaultReminderMinutes *int    `json:"defaultReminderMinutes" validate:"omitempty,min=1,max=40320"`
 }
 
 func (p *CreateCategoryPayload) Validate() error {
@@ -24,6 +32,13 @@ type UpdateCategoryPayload struct {
 	Name        *string   `json:"name" validate:"omitempty,min=1,max=100"`
 	Color       *string   `json:"color" validate:"omitempty,hexcolor"`
 	Description *string   `json:"description" validate:"omitempty,max=255"`
+
+	DefaultPriority        *string `json:"defaultPriority" validate:"omitempty,oneof=low medium high"`
+	DefaultReminderMinutes *int    `json:"defaultReminderMinutes" validate:"omitempty,min=1,max=40320"`
+	// ClearDefaultPriority and ClearDefaultReminderMinutes remove the
+	// defaults, which leaving them out of the update keeps
+	ClearDefaultPriority        bool `json:"clearDefaultPriority" validate:"excluded_with=DefaultPriority"`
+	ClearDefaultReminderMinutes bool `json:"clearDefaultReminderMinutes" validate:"excluded_with=DefaultReminderMinutes"`
 }
 
 func (p *UpdateCategoryPayload) Validate() error {
@@ -36,13 +51,18 @@ func (p *UpdateCategoryPayload) Validate() error {
 type GetCategoriesQuery struct {
 	Page   *int    `query:"page" validate:"omitempty,min=1"`
 	Limit  *int    `query:"limit" validate:"omitempty,min=1,max=100"`
-	Sort   *string `query:"sort" validate:"omitempty,oneof=created_at updated_at name"`
+	Sort   *string `query:"sort" validate:"omitempty,sort=created_at updated_at name id"`
 	Order  *string `query:"order" validate:"omitempty,oneof=asc desc"`
 	Search *string `query:"search" validate:"omitempty,min=1"`
+	// Locale, such as de-AT, selects how names sort, in place of the
+	// Accept-Language header. The service replaces it with the collation
+	// language it matched.
+	Locale *string `query:"locale" validate:"omitempty,bcp47_language_tag"`
 }
 
 func (q *GetCategoriesQuery) Validate() error {
 	validate := validator.New()
+	model.RegisterSortValidation(validate)
 
 	if err := validate.Struct(q); err != nil {
 		return err
@@ -69,6 +89,16 @@ func (q *GetCategoriesQuery) Validate() error {
 	return nil
 }
 
+// SortFields are the fields categories can be sorted by, as the sort tag
+// above enforces them
+var SortFields = []string{"created_at", "updated_at", "name", "id"}
+
+// SortKeys returns the fields to sort by, once the query is validated
+func (q *GetCategoriesQuery) SortKeys() []model.SortKey {
+	keys, _ := model.ParseSort(*q.Sort, SortFields, *q.Order == "desc")
+	return keys
+}
+
 type DeleteCategoryPayload struct {
 	ID uuid.UUID `param:"id" validate:"required,uuid"`
 }
@@ -0,0 +1,81 @@
+package category_test
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/model"
+	"github.com/mabhi256/tasker/internal/model/category"
+	testutil "github.com/mabhi256/tasker/internal/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetCategoriesQuerySortKeys(t *testing.T) {
+	tests := []struct {
+		name  string
+		sort  *string
+		order *string
+		keys  []model.SortKey
+	}{
+		{name: "default", keys: []model.SortKey{{Field: "name"}}},
+		{
+			name:  "single field descending",
+			sort:  testutil.Ptr("created_at"),
+			order: testutil.Ptr("desc"),
+			keys:  []model.SortKey{{Field: "created_at", Desc: true}},
+		},
+		{
+			name: "mixed directions",
+			sort: testutil.Ptr("-updated_at,name"),
+			keys: []model.SortKey{{Field: "updated_at", Desc: true}, {Field: "name"}},
+		},
+		{
+			name:  "order ignored for several fields",
+			sort:  testutil.Ptr("name,-created_at"),
+			order: testutil.Ptr("desc"),
+			keys:  []model.SortKey{{Field: "name"}, {Field: "created_at", Desc: true}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := &category.GetCategoriesQuery{Sort: tt.sort, Order: tt.order}
+			require.NoError(t, query.Validate())
+			assert.Equal(t, tt.keys, query.SortKeys())
+		})
+	}
+}
+
+func TestUpdateCategoryPayloadClear(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload category.UpdateCategoryPayload
+		valid   bool
+	}{
+		{name: "clear priority", payload: category.UpdateCategoryPayload{ClearDefaultPriority: true}, valid: true},
+		{
+			name:    "clear reminder and set priority",
+			payload: category.UpdateCategoryPayload{ClearDefaultReminderMinutes: true, DefaultPriority: testutil.Ptr("low")},
+			valid:   true,
+		},
+		{
+			name:    "set and clear priority",
+			payload: category.UpdateCategoryPayload{ClearDefaultPriority: true, DefaultPriority: testutil.Ptr("low")},
+		},
+		{
+			name:    "set and clear reminder",
+			payload: category.UpdateCategoryPayload{ClearDefaultReminderMinutes: true, DefaultReminderMinutes: testutil.Ptr(30)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.payload.ID = uuid.New()
+			err := tt.payload.Validate()
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
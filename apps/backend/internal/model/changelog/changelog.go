@@ -0,0 +1,42 @@
+package changelog
+
+import (
+	"time"
+)
+
+// Kind is what an entry announces, as in Keep a Changelog
+type Kind string
+
+const (
+	KindAdded      Kind = "added"
+	KindChanged    Kind = "changed"
+	KindDeprecated Kind = "deprecated"
+	KindRemoved    Kind = "removed"
+	KindFixed      Kind = "fixed"
+)
+
+func (k Kind) Valid() bool {
+	switch k {
+	case KindAdded, KindChanged, KindDeprecated, KindRemoved, KindFixed:
+		return true
+	}
+	return false
+}
+
+// Entry announces a change API consumers may need to act on
+type Entry struct {
+	ID          string `json:"id"`
+	Kind        Kind   `json:"kind"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	// Endpoints are the affected routes, as METHOD /path
+	Endpoints   []string  `json:"endpoints"`
+	PublishedAt time.Time `json:"publishedAt"`
+	// SunsetAt is when a deprecated behavior stops working
+	SunsetAt *time.Time `json:"sunsetAt"`
+}
+
+// Changelog lists entries newest first
+type Changelog struct {
+	Entries []Entry `json:"entries"`
+}
@@ -0,0 +1,21 @@
+package changelog
+
+import (
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type GetChangelogPayload struct {
+	// Since leaves out entries published before it
+	Since *time.Time `query:"since"`
+	Kind  *Kind      `query:"kind" validate:"omitempty,oneof=added changed deprecated removed fixed"`
+	// Format is json or rss. Without it, RSS is served to clients that
+	// accept application/rss+xml.
+	Format *string `query:"format" validate:"omitempty,oneof=json rss"`
+}
+
+func (p *GetChangelogPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
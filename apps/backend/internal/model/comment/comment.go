@@ -2,12 +2,34 @@ package comment
 
 import (
 	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/lib/content"
 	"github.com/mabhi256/tasker/internal/model"
 )
 
 type Comment struct {
 	model.Base
-	TodoID  uuid.UUID `json:"todoId" db:"todo_id"`
-	UserID  string    `json:"userId" db:"user_id"`
-	Content string    `json:"content" db:"content"`
+	WorkspaceID     uuid.UUID  `json:"workspaceId" db:"workspace_id"`
+	TodoID          uuid.UUID  `json:"todoId" db:"todo_id"`
+	UserID          string     `json:"userId" db:"user_id"`
+	Content         string     `json:"content" db:"content"`
+	ParentCommentID *uuid.UUID `json:"parentCommentId" db:"parent_comment_id"`
+
+	// ContentHTML is only set when the request asks for rendered content
+	ContentHTML *string `json:"contentHtml,omitempty" db:"-"`
+}
+
+// RenderContent renders the content to sanitized HTML
+func (c *Comment) RenderContent() {
+	html := content.Render(c.Content).HTML
+	c.ContentHTML = &html
+}
+
+// ThreadComment is a comment listed as part of a thread. Depth is 0 for the
+// comment the thread was listed from, and ReplyCount only counts direct
+// replies.
+type ThreadComment struct {
+	Comment
+	Depth      int               `json:"depth" db:"depth"`
+	ReplyCount int               `json:"replyCount" db:"reply_count"`
+	Reactions  []ReactionSummary `json:"reactions" db:"reactions"`
 }
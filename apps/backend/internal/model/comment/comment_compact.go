@@ -0,0 +1,50 @@
+package comment
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CompactComment is the compact view of a comment. The content is kept, as
+// it is what a comment is for.
+type CompactComment struct {
+	ID              uuid.UUID  `json:"id"`
+	TodoID          uuid.UUID  `json:"todoId"`
+	UserID          string     `json:"userId"`
+	Content         string     `json:"content"`
+	ParentCommentID *uuid.UUID `json:"parentCommentId"`
+	CreatedAt       time.Time  `json:"createdAt"`
+}
+
+// Compact implements jsonenc.Compacter
+func (c *Comment) Compact() any {
+	return c.compact()
+}
+
+func (c *Comment) compact() *CompactComment {
+	return &CompactComment{
+		ID:              c.ID,
+		TodoID:          c.TodoID,
+		UserID:          c.UserID,
+		Content:         c.Content,
+		ParentCommentID: c.ParentCommentID,
+		CreatedAt:       c.CreatedAt,
+	}
+}
+
+// CompactThreadComment is the compact view of a comment in a thread
+type CompactThreadComment struct {
+	*CompactComment
+	Depth      int `json:"depth"`
+	ReplyCount int `json:"replyCount"`
+}
+
+// Compact implements jsonenc.Compacter
+func (c *ThreadComment) Compact() any {
+	return &CompactThreadComment{
+		CompactComment: c.Comment.compact(),
+		Depth:          c.Depth,
+		ReplyCount:     c.ReplyCount,
+	}
+}
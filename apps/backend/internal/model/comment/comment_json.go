@@ -0,0 +1,50 @@
+//go:build fastjson
+
+package comment
+
+import (
+	"github.com/mabhi256/tasker/internal/lib/jsonenc"
+	"github.com/mabhi256/tasker/internal/model"
+)
+
+func (c *Comment) AppendJSON(dst []byte) ([]byte, error) {
+	dst = append(dst, '{')
+	dst = c.appendFields(dst)
+	return append(dst, '}'), nil
+}
+
+// appendFields writes the fields of Comment without braces, so
+// ThreadComment can add its own after them
+func (c *Comment) appendFields(dst []byte) []byte {
+	dst = model.AppendBase(dst, &c.Base)
+	dst = append(dst, `,"workspaceId":`...)
+	dst = jsonenc.AppendUUID(dst, c.WorkspaceID)
+	dst = append(dst, `,"todoId":`...)
+	dst = jsonenc.AppendUUID(dst, c.TodoID)
+	dst = append(dst, `,"userId":`...)
+	dst = jsonenc.AppendString(dst, c.UserID)
+	dst = append(dst, `,"content":`...)
+	dst = jsonenc.AppendString(dst, c.Content)
+	dst = append(dst, `,"parentCommentId":`...)
+	dst = jsonenc.AppendUUIDPtr(dst, c.ParentCommentID)
+	if c.ContentHTML != nil {
+		dst = append(dst, `,"contentHtml":`...)
+		dst = jsonenc.AppendStringPtr(dst, c.ContentHTML)
+	}
+	return dst
+}
+
+func (c *ThreadComment) AppendJSON(dst []byte) ([]byte, error) {
+	dst = append(dst, '{')
+	dst = c.appendFields(dst)
+	dst = append(dst, `,"depth":`...)
+	dst = jsonenc.AppendInt(dst, int64(c.Depth))
+	dst = append(dst, `,"replyCount":`...)
+	dst = jsonenc.AppendInt(dst, int64(c.ReplyCount))
+	dst = append(dst, `,"reactions":`...)
+	dst, err := jsonenc.AppendSlice(dst, c.Reactions)
+	if err != nil {
+		return nil, err
+	}
+	return append(dst, '}'), nil
+}
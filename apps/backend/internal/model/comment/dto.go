@@ -5,11 +5,16 @@ import (
 	"github.com/google/uuid"
 )
 
+// MaxContentLength limits comments, as the validate tags below enforce it
+const MaxContentLength = 1000
+
 // ------------------------------------------------------------
 
 type AddCommentPayload struct {
 	TodoID  uuid.UUID `param:"id" validate:"required,uuid"`
 	Content string    `json:"content" validate:"required,min=1,max=1000"`
+	// ParentCommentID makes the comment a reply, to a comment on the same todo
+	ParentCommentID *uuid.UUID `json:"parentCommentId" validate:"omitempty,uuid"`
 }
 
 func (p *AddCommentPayload) Validate() error {
@@ -21,6 +26,7 @@ func (p *AddCommentPayload) Validate() error {
 
 type GetCommentsByTodoIDPayload struct {
 	TodoID uuid.UUID `param:"id" validate:"required,uuid"`
+	Render *string   `query:"render" validate:"omitempty,oneof=html"`
 }
 
 func (p *GetCommentsByTodoIDPayload) Validate() error {
@@ -50,3 +56,72 @@ func (p *DeleteCommentPayload) Validate() error {
 	validate := validator.New()
 	return validate.Struct(p)
 }
+
+// ------------------------------------------------------------
+
+type GetMentionsQuery struct {
+	Page  *int `query:"page" validate:"omitempty,min=1"`
+	Limit *int `query:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+func (q *GetMentionsQuery) Validate() error {
+	validate := validator.New()
+
+	if err := validate.Struct(q); err != nil {
+		return err
+	}
+
+	// Set defaults
+	if q.Page == nil {
+		defaultPage := 1
+		q.Page = &defaultPage
+	}
+	if q.Limit == nil {
+		defaultLimit := 20
+		q.Limit = &defaultLimit
+	}
+
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type GetCommentThreadPayload struct {
+	ID     uuid.UUID `param:"id" validate:"required,uuid"`
+	Render *string   `query:"render" validate:"omitempty,oneof=html"`
+}
+
+func (p *GetCommentThreadPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type AddReactionPayload struct {
+	ID    uuid.UUID `param:"id" validate:"required,uuid"`
+	Emoji string    `json:"emoji" validate:"required,emoji"`
+}
+
+func (p *AddReactionPayload) Validate() error {
+	return newReactionValidator().Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type RemoveReactionPayload struct {
+	ID    uuid.UUID `param:"id" validate:"required,uuid"`
+	Emoji string    `query:"emoji" validate:"required,emoji"`
+}
+
+func (p *RemoveReactionPayload) Validate() error {
+	return newReactionValidator().Struct(p)
+}
+
+func newReactionValidator() *validator.Validate {
+	validate := validator.New()
+	_ = validate.RegisterValidation("emoji", func(fl validator.FieldLevel) bool {
+		return IsEmoji(fl.Field().String())
+	})
+	return validate
+}
@@ -0,0 +1,46 @@
+package comment
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxMentions caps how many users one comment can notify
+const MaxMentions = 20
+
+// EventMentioned is the type of the realtime message a mentioned user gets
+const EventMentioned = "comment.mentioned"
+
+// mentionPattern matches @<userId> tokens, which is what the mention picker
+// inserts. The @ must not follow a word character, so email addresses in a
+// comment aren't read as mentions.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@(user_[A-Za-z0-9]+)`)
+
+// ParseMentions returns the users mentioned in content, in order of first
+// mention and without duplicates
+func ParseMentions(content string) []string {
+	matches := mentionPattern.FindAllStringSubmatch(content, -1)
+
+	seen := make(map[string]bool, len(matches))
+	userIDs := make([]string, 0, len(matches))
+	for _, match := range matches {
+		if seen[match[1]] {
+			continue
+		}
+		seen[match[1]] = true
+		userIDs = append(userIDs, match[1])
+	}
+
+	return userIDs
+}
+
+// MentionedEvent is sent to the connections of a user mentioned in a comment,
+// shaped like the workspace events they already receive
+type MentionedEvent struct {
+	Type        string    `json:"type"`
+	CreatedAt   time.Time `json:"createdAt"`
+	WorkspaceID uuid.UUID `json:"workspaceId"`
+	Data        *Comment  `json:"data"`
+}
@@ -0,0 +1,53 @@
+package comment
+
+import "unicode/utf8"
+
+// maxEmojiLength fits the longest emoji sequences, such as family and flag
+// sequences, in bytes
+const maxEmojiLength = 64
+
+// ReactionSummary is how many users reacted to a comment with an emoji, and
+// whether the current user is one of them
+type ReactionSummary struct {
+	Emoji   string `json:"emoji"`
+	Count   int    `json:"count"`
+	Reacted bool   `json:"reacted"`
+}
+
+// IsEmoji reports whether s is made up of emoji code points, along with the
+// joiners, modifiers and tags that combine them into one emoji
+func IsEmoji(s string) bool {
+	if s == "" || len(s) > maxEmojiLength || !utf8.ValidString(s) {
+		return false
+	}
+
+	pictographs, keycapBases, keycaps := 0, 0, 0
+	for _, r := range s {
+		switch {
+		case r >= 0x1F000 && r <= 0x1FAFF, // pictographs, flags, skin tones
+			r >= 0x2600 && r <= 0x27BF, // misc symbols, dingbats
+			r >= 0x2300 && r <= 0x23FF, // misc technical
+			r >= 0x2190 && r <= 0x21FF, // arrows
+			r >= 0x2B00 && r <= 0x2BFF, // arrows, stars
+			r == 0x00A9, r == 0x00AE, r == 0x203C, r == 0x2049,
+			r == 0x2122, r == 0x2139, r == 0x3030, r == 0x303D,
+			r == 0x3297, r == 0x3299:
+			pictographs++
+		case r >= '0' && r <= '9', r == '#', r == '*':
+			keycapBases++
+		case r == 0x20E3: // combining keycap
+			keycaps++
+		case r == 0x200D, // zero width joiner
+			r == 0xFE0F,                  // emoji presentation
+			r >= 0xE0020 && r <= 0xE007F: // tag sequences
+		default:
+			return false
+		}
+	}
+
+	// Digits, # and * are only emoji as the base of a keycap, like 1️⃣
+	if keycapBases != keycaps {
+		return false
+	}
+	return pictographs+keycaps > 0
+}
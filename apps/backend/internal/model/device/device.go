@@ -0,0 +1,37 @@
+package device
+
+import (
+	"time"
+
+	"github.com/mabhi256/tasker/internal/model"
+)
+
+type Platform string
+
+const (
+	// PlatformFCM is an Android or iOS app registered with Firebase Cloud
+	// Messaging
+	PlatformFCM Platform = "fcm"
+	// PlatformWeb is a browser subscribed through the Push API
+	PlatformWeb Platform = "web"
+)
+
+// Device is a user's device registered for push notifications. Its token and
+// keys address and encrypt messages, so they're never returned to clients.
+type Device struct {
+	model.Base
+	UserID     string     `json:"userId" db:"user_id"`
+	Platform   Platform   `json:"platform" db:"platform"`
+	Token      string     `json:"-" db:"token"`
+	P256dh     *string    `json:"-" db:"p256dh"`
+	Auth       *string    `json:"-" db:"auth"`
+	Name       *string    `json:"name" db:"name"`
+	LastUsedAt *time.Time `json:"lastUsedAt" db:"last_used_at"`
+}
+
+// PushConfig tells clients which platforms they can register for, and the
+// VAPID public key browsers pass as applicationServerKey when subscribing
+type PushConfig struct {
+	Platforms      []Platform `json:"platforms"`
+	VAPIDPublicKey *string    `json:"vapidPublicKey"`
+}
@@ -0,0 +1,58 @@
+package device
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// RegisterDevicePayload registers an app by its FCM registration token, or a
+// browser by its PushSubscription as serialized by toJSON(). Registering a
+// device again updates it.
+type RegisterDevicePayload struct {
+	Platform     Platform             `json:"platform" validate:"required,oneof=fcm web"`
+	Token        *string              `json:"token" validate:"required_if=Platform fcm,excluded_unless=Platform fcm,omitempty,max=4096"`
+	Subscription *WebPushSubscription `json:"subscription" validate:"required_if=Platform web,excluded_unless=Platform web"`
+	Name         *string              `json:"name" validate:"omitempty,max=255"`
+}
+
+// WebPushSubscription holds the endpoint messages are posted to and the
+// browser's keys, base64url encoded
+type WebPushSubscription struct {
+	Endpoint string `json:"endpoint" validate:"required,url,startswith=https://,max=2048"`
+	Keys     struct {
+		P256dh string `json:"p256dh" validate:"required,max=128"`
+		Auth   string `json:"auth" validate:"required,max=64"`
+	} `json:"keys"`
+}
+
+func (p *RegisterDevicePayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type GetDevicesPayload struct{}
+
+func (p *GetDevicesPayload) Validate() error {
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type DeleteDevicePayload struct {
+	ID uuid.UUID `param:"id" validate:"required,uuid"`
+}
+
+func (p *DeleteDevicePayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type GetPushConfigPayload struct{}
+
+func (p *GetPushConfigPayload) Validate() error {
+	return nil
+}
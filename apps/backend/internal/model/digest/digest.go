@@ -0,0 +1,80 @@
+package digest
+
+import (
+	"time"
+
+	"github.com/mabhi256/tasker/internal/model"
+	"github.com/mabhi256/tasker/internal/model/todo"
+)
+
+type Frequency string
+
+const (
+	FrequencyDaily  Frequency = "daily"
+	FrequencyWeekly Frequency = "weekly"
+)
+
+// Subscription is a user's digest email settings. Hour and Weekday are in
+// the user's Timezone.
+type Subscription struct {
+	model.Base
+	UserID     string     `json:"userId" db:"user_id"`
+	Frequency  Frequency  `json:"frequency" db:"frequency"`
+	Hour       int        `json:"hour" db:"hour"`
+	Weekday    *int       `json:"weekday" db:"weekday"`
+	Timezone   string     `json:"timezone" db:"timezone"`
+	Enabled    bool       `json:"enabled" db:"enabled"`
+	NextRunAt  time.Time  `json:"nextRunAt" db:"next_run_at"`
+	LastSentAt *time.Time `json:"lastSentAt" db:"last_sent_at"`
+}
+
+// Location returns the subscription's timezone, falling back to UTC for one
+// that no longer loads
+func (s *Subscription) Location() *time.Location {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// NextRun returns the first scheduled time strictly after the given time.
+// The slot is computed on the wall clock of loc, so it follows DST changes.
+func NextRun(frequency Frequency, hour int, weekday *int, loc *time.Location, after time.Time) time.Time {
+	after = after.In(loc)
+	next := time.Date(after.Year(), after.Month(), after.Day(), hour, 0, 0, 0, loc)
+
+	if frequency == FrequencyWeekly && weekday != nil {
+		days := (*weekday - int(next.Weekday()) + 7) % 7
+		next = next.AddDate(0, 0, days)
+		if !next.After(after) {
+			next = next.AddDate(0, 0, 7)
+		}
+		return next
+	}
+
+	if !next.After(after) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// PeriodStart returns the start of the period a digest sent at the given
+// time covers
+func PeriodStart(frequency Frequency, at time.Time) time.Time {
+	if frequency == FrequencyWeekly {
+		return at.AddDate(0, 0, -7)
+	}
+	return at.AddDate(0, 0, -1)
+}
+
+// Todos is what a digest reports on
+type Todos struct {
+	Overdue   []todo.Todo
+	DueToday  []todo.Todo
+	Completed []todo.Todo
+}
+
+func (t *Todos) Empty() bool {
+	return len(t.Overdue) == 0 && len(t.DueToday) == 0 && len(t.Completed) == 0
+}
@@ -0,0 +1,28 @@
+package digest
+
+import (
+	"github.com/go-playground/validator/v10"
+)
+
+type GetSubscriptionPayload struct{}
+
+func (p *GetSubscriptionPayload) Validate() error {
+	return nil
+}
+
+// ------------------------------------------------------------
+
+// PutSubscriptionPayload creates or replaces the user's digest settings.
+// Timezone is an IANA name such as "Europe/Berlin".
+type PutSubscriptionPayload struct {
+	Frequency Frequency `json:"frequency" validate:"required,oneof=daily weekly"`
+	Hour      int       `json:"hour" validate:"min=0,max=23"`
+	Weekday   *int      `json:"weekday" validate:"required_if=Frequency weekly,excluded_if=Frequency daily,omitempty,min=0,max=6"`
+	Timezone  string    `json:"timezone" validate:"required,timezone"`
+	Enabled   *bool     `json:"enabled"`
+}
+
+func (p *PutSubscriptionPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
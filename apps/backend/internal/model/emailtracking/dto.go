@@ -0,0 +1,33 @@
+package emailtracking
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// ------------------------------------------------------------
+
+// RecordOpenPayload is the request of a message's tracking pixel
+type RecordOpenPayload struct {
+	ID        uuid.UUID `param:"id" validate:"required,uuid"`
+	Signature string    `query:"s"`
+}
+
+func (p *RecordOpenPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+// RecordClickPayload is the request of a message's tracking link to Target
+type RecordClickPayload struct {
+	ID        uuid.UUID `param:"id" validate:"required,uuid"`
+	Target    string    `query:"u" validate:"required,url"`
+	Signature string    `query:"s" validate:"required"`
+}
+
+func (p *RecordClickPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
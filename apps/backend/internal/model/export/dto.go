@@ -0,0 +1,142 @@
+package export
+
+import (
+	"errors"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// validateDestination checks that the settings for destinationType are present
+// and that no other destination is set alongside them
+func validateDestination(destinationType DestinationType, d *Destination) error {
+	switch destinationType {
+	case DestinationS3:
+		if d.S3 == nil || d.SFTP != nil {
+			return errors.New("destination.s3 is required for s3 exports")
+		}
+	case DestinationSFTP:
+		if d.SFTP == nil || d.S3 != nil {
+			return errors.New("destination.sftp is required for sftp exports")
+		}
+		if d.SFTP.Password == nil && d.SFTP.PrivateKey == nil {
+			return errors.New("destination.sftp requires a password or privateKey")
+		}
+	}
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type CreateSchedulePayload struct {
+	Name            string          `json:"name" validate:"required,min=1,max=100"`
+	Format          Format          `json:"format" validate:"required,oneof=json csv"`
+	DestinationType DestinationType `json:"destinationType" validate:"required,oneof=s3 sftp"`
+	Destination     Destination     `json:"destination" validate:"required"`
+	Frequency       Frequency       `json:"frequency" validate:"required,oneof=daily weekly"`
+	HourUTC         int             `json:"hourUtc" validate:"min=0,max=23"`
+	Weekday         *int            `json:"weekday" validate:"required_if=Frequency weekly,excluded_if=Frequency daily,omitempty,min=0,max=6"`
+	Enabled         *bool           `json:"enabled"`
+}
+
+func (p *CreateSchedulePayload) Validate() error {
+	validate := validator.New()
+	if err := validate.Struct(p); err != nil {
+		return err
+	}
+	return validateDestination(p.DestinationType, &p.Destination)
+}
+
+// ------------------------------------------------------------
+
+type GetSchedulesPayload struct{}
+
+func (p *GetSchedulesPayload) Validate() error {
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type GetScheduleByIDPayload struct {
+	ID uuid.UUID `param:"id" validate:"required,uuid"`
+}
+
+func (p *GetScheduleByIDPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+// UpdateSchedulePayload replaces the destination as a whole when it is given,
+// since the stored credentials are never sent back to be merged client-side
+type UpdateSchedulePayload struct {
+	ID              uuid.UUID        `param:"id" validate:"required,uuid"`
+	Name            *string          `json:"name" validate:"omitempty,min=1,max=100"`
+	Format          *Format          `json:"format" validate:"omitempty,oneof=json csv"`
+	DestinationType *DestinationType `json:"destinationType" validate:"required_with=Destination,omitempty,oneof=s3 sftp"`
+	Destination     *Destination     `json:"destination" validate:"required_with=DestinationType"`
+	Frequency       *Frequency       `json:"frequency" validate:"omitempty,oneof=daily weekly"`
+	HourUTC         *int             `json:"hourUtc" validate:"omitempty,min=0,max=23"`
+	Weekday         *int             `json:"weekday" validate:"omitempty,min=0,max=6"`
+	Enabled         *bool            `json:"enabled"`
+}
+
+func (p *UpdateSchedulePayload) Validate() error {
+	validate := validator.New()
+	if err := validate.Struct(p); err != nil {
+		return err
+	}
+	if p.DestinationType != nil && p.Destination != nil {
+		return validateDestination(*p.DestinationType, p.Destination)
+	}
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type DeleteSchedulePayload struct {
+	ID uuid.UUID `param:"id" validate:"required,uuid"`
+}
+
+func (p *DeleteSchedulePayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type TriggerRunPayload struct {
+	ID uuid.UUID `param:"id" validate:"required,uuid"`
+}
+
+func (p *TriggerRunPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type GetRunsPayload struct {
+	ID    uuid.UUID `param:"id" validate:"required,uuid"`
+	Page  *int      `query:"page" validate:"omitempty,min=1"`
+	Limit *int      `query:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+func (p *GetRunsPayload) Validate() error {
+	validate := validator.New()
+	if err := validate.Struct(p); err != nil {
+		return err
+	}
+
+	if p.Page == nil {
+		defaultPage := 1
+		p.Page = &defaultPage
+	}
+	if p.Limit == nil {
+		defaultLimit := 20
+		p.Limit = &defaultLimit
+	}
+
+	return nil
+}
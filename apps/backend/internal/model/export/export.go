@@ -0,0 +1,196 @@
+package export
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/model"
+)
+
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatCSV  Format = "csv"
+)
+
+type DestinationType string
+
+const (
+	DestinationS3   DestinationType = "s3"
+	DestinationSFTP DestinationType = "sftp"
+)
+
+type Frequency string
+
+const (
+	FrequencyDaily  Frequency = "daily"
+	FrequencyWeekly Frequency = "weekly"
+)
+
+type RunStatus string
+
+const (
+	RunStatusPending   RunStatus = "pending"
+	RunStatusRunning   RunStatus = "running"
+	RunStatusSucceeded RunStatus = "succeeded"
+	RunStatusFailed    RunStatus = "failed"
+)
+
+// S3Destination is a bucket owned by the customer. Endpoint is only needed
+// for S3-compatible services.
+type S3Destination struct {
+	Bucket          string  `json:"bucket" validate:"required,min=3,max=63"`
+	Region          string  `json:"region" validate:"required"`
+	Prefix          *string `json:"prefix" validate:"omitempty,max=512"`
+	Endpoint        *string `json:"endpoint" validate:"omitempty,url,startswith=https://"`
+	AccessKeyID     string  `json:"accessKeyId,omitempty" validate:"required"`
+	SecretAccessKey string  `json:"secretAccessKey,omitempty" validate:"required"`
+}
+
+// SFTPDestination authenticates with a password or a PEM private key. HostKey
+// is the server's public key in authorized_keys format and is always verified.
+type SFTPDestination struct {
+	Host       string  `json:"host" validate:"required,hostname|ip"`
+	Port       int     `json:"port" validate:"omitempty,min=1,max=65535"`
+	Username   string  `json:"username" validate:"required"`
+	Password   *string `json:"password,omitempty" validate:"omitempty,min=1"`
+	PrivateKey *string `json:"privateKey,omitempty" validate:"omitempty,min=1"`
+	HostKey    string  `json:"hostKey" validate:"required"`
+	Directory  string  `json:"directory" validate:"required,startswith=/"`
+}
+
+type Destination struct {
+	S3   *S3Destination   `json:"s3,omitempty"`
+	SFTP *SFTPDestination `json:"sftp,omitempty"`
+}
+
+// Seal returns a copy with its credentials sealed by seal, to store them
+// encrypted
+func (d Destination) Seal(seal func(string) (string, error)) (Destination, error) {
+	return d.mapCredentials(seal)
+}
+
+// Open returns a copy with its sealed credentials opened by open
+func (d Destination) Open(open func(string) (string, error)) (Destination, error) {
+	return d.mapCredentials(open)
+}
+
+func (d Destination) mapCredentials(f func(string) (string, error)) (Destination, error) {
+	apply := func(value *string) error {
+		if value == nil || *value == "" {
+			return nil
+		}
+		mapped, err := f(*value)
+		if err != nil {
+			return err
+		}
+		*value = mapped
+		return nil
+	}
+	applyOptional := func(value **string) error {
+		if *value == nil {
+			return nil
+		}
+		copied := **value
+		*value = &copied
+		return apply(&copied)
+	}
+
+	if d.S3 != nil {
+		s3 := *d.S3
+		if err := apply(&s3.AccessKeyID); err != nil {
+			return Destination{}, err
+		}
+		if err := apply(&s3.SecretAccessKey); err != nil {
+			return Destination{}, err
+		}
+		d.S3 = &s3
+	}
+	if d.SFTP != nil {
+		sftp := *d.SFTP
+		if err := applyOptional(&sftp.Password); err != nil {
+			return Destination{}, err
+		}
+		if err := applyOptional(&sftp.PrivateKey); err != nil {
+			return Destination{}, err
+		}
+		d.SFTP = &sftp
+	}
+	return d, nil
+}
+
+// Redacted returns a copy without credentials, safe to send to clients
+func (d Destination) Redacted() Destination {
+	if d.S3 != nil {
+		s3 := *d.S3
+		s3.AccessKeyID = ""
+		s3.SecretAccessKey = ""
+		d.S3 = &s3
+	}
+	if d.SFTP != nil {
+		sftp := *d.SFTP
+		sftp.Password = nil
+		sftp.PrivateKey = nil
+		d.SFTP = &sftp
+	}
+	return d
+}
+
+type Schedule struct {
+	model.Base
+	WorkspaceID     uuid.UUID       `json:"workspaceId" db:"workspace_id"`
+	CreatedBy       string          `json:"createdBy" db:"created_by"`
+	Name            string          `json:"name" db:"name"`
+	Format          Format          `json:"format" db:"format"`
+	DestinationType DestinationType `json:"destinationType" db:"destination_type"`
+	Destination     Destination     `json:"destination" db:"destination"`
+	Frequency       Frequency       `json:"frequency" db:"frequency"`
+	HourUTC         int             `json:"hourUtc" db:"hour_utc"`
+	Weekday         *int            `json:"weekday" db:"weekday"`
+	Enabled         bool            `json:"enabled" db:"enabled"`
+	NextRunAt       time.Time       `json:"nextRunAt" db:"next_run_at"`
+	LastRunAt       *time.Time      `json:"lastRunAt" db:"last_run_at"`
+}
+
+// NextRun returns the first scheduled time strictly after the given time
+func NextRun(frequency Frequency, hourUTC int, weekday *int, after time.Time) time.Time {
+	after = after.UTC()
+	next := time.Date(after.Year(), after.Month(), after.Day(), hourUTC, 0, 0, 0, time.UTC)
+
+	if frequency == FrequencyWeekly && weekday != nil {
+		days := (*weekday - int(next.Weekday()) + 7) % 7
+		next = next.AddDate(0, 0, days)
+		if !next.After(after) {
+			next = next.AddDate(0, 0, 7)
+		}
+		return next
+	}
+
+	if !next.After(after) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+type Run struct {
+	model.Base
+	ScheduleID     uuid.UUID  `json:"scheduleId" db:"schedule_id"`
+	Status         RunStatus  `json:"status" db:"status"`
+	Attempts       int        `json:"attempts" db:"attempts"`
+	StartedAt      *time.Time `json:"startedAt" db:"started_at"`
+	FinishedAt     *time.Time `json:"finishedAt" db:"finished_at"`
+	Location       *string    `json:"location" db:"location"`
+	RowCount       *int       `json:"rowCount" db:"row_count"`
+	SizeBytes      *int64     `json:"sizeBytes" db:"size_bytes"`
+	ChecksumSHA256 *string    `json:"checksumSha256" db:"checksum_sha256"`
+	Error          *string    `json:"error" db:"error"`
+}
+
+// Receipt describes a successful delivery
+type Receipt struct {
+	Location       string
+	RowCount       int
+	SizeBytes      int64
+	ChecksumSHA256 string
+}
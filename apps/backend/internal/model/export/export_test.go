@@ -0,0 +1,30 @@
+package export_test
+
+import (
+	"testing"
+
+	"github.com/mabhi256/tasker/internal/model/export"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDestinationSeal(t *testing.T) {
+	password := "hunter2"
+	dest := export.Destination{
+		S3:   &export.S3Destination{Bucket: "exports", AccessKeyID: "AKIA", SecretAccessKey: "secret"},
+		SFTP: &export.SFTPDestination{Host: "sftp.example.com", Password: &password},
+	}
+	seal := func(value string) (string, error) { return "sealed:" + value, nil }
+
+	sealed, err := dest.Seal(seal)
+	require.NoError(t, err)
+	assert.Equal(t, "sealed:AKIA", sealed.S3.AccessKeyID)
+	assert.Equal(t, "sealed:secret", sealed.S3.SecretAccessKey)
+	assert.Equal(t, "sealed:hunter2", *sealed.SFTP.Password)
+	assert.Nil(t, sealed.SFTP.PrivateKey)
+	assert.Equal(t, "exports", sealed.S3.Bucket)
+
+	// The original keeps its credentials
+	assert.Equal(t, "secret", dest.S3.SecretAccessKey)
+	assert.Equal(t, "hunter2", password)
+}
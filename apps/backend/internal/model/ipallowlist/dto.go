@@ -0,0 +1,72 @@
+package ipallowlist
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// ------------------------------------------------------------
+
+type GetAllowlistPayload struct{}
+
+func (p *GetAllowlistPayload) Validate() error {
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type UpdateAllowlistPayload struct {
+	Enabled *bool `json:"enabled" validate:"required"`
+}
+
+func (p *UpdateAllowlistPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type CreateEntryPayload struct {
+	CIDR        string  `json:"cidr" validate:"required,cidr|ip"`
+	Description *string `json:"description" validate:"omitempty,max=255"`
+}
+
+func (p *CreateEntryPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type DeleteEntryPayload struct {
+	ID uuid.UUID `param:"id" validate:"required,uuid"`
+}
+
+func (p *DeleteEntryPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+// DefaultBreakGlassMinutes is how long break glass lifts the restriction
+// when no duration is given
+const DefaultBreakGlassMinutes = 60
+
+type BreakGlassPayload struct {
+	Reason  string `json:"reason" validate:"required,min=10,max=500"`
+	Minutes *int   `json:"minutes" validate:"omitempty,min=5,max=240"`
+}
+
+func (p *BreakGlassPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type EndBreakGlassPayload struct{}
+
+func (p *EndBreakGlassPayload) Validate() error {
+	return nil
+}
@@ -0,0 +1,128 @@
+package ipallowlist_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/model/ipallowlist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAllowlist(t *testing.T, cidrs ...string) *ipallowlist.Allowlist {
+	t.Helper()
+
+	allowlist := &ipallowlist.Allowlist{}
+	for _, cidr := range cidrs {
+		prefix, err := ipallowlist.ParseCIDR(cidr)
+		require.NoError(t, err)
+		allowlist.Entries = append(allowlist.Entries, ipallowlist.Entry{CIDR: prefix})
+	}
+	return allowlist
+}
+
+func TestAllows(t *testing.T) {
+	allowlist := newAllowlist(t, "203.0.113.0/24", "198.51.100.7", "2001:db8:abcd::/48")
+
+	tests := []struct {
+		ip      string
+		allowed bool
+	}{
+		{ip: "203.0.113.1", allowed: true},
+		{ip: "203.0.113.255", allowed: true},
+		{ip: "198.51.100.7", allowed: true},
+		{ip: "2001:db8:abcd:12::1", allowed: true},
+		// IPv4 clients reaching a dual-stack listener show up mapped
+		{ip: "::ffff:203.0.113.9", allowed: true},
+		{ip: "::ffff:198.51.100.7", allowed: true},
+
+		{ip: "203.0.114.1"},
+		{ip: "198.51.100.8"},
+		{ip: "2001:db8:abce::1"},
+		{ip: "::ffff:203.0.114.1"},
+		{ip: "127.0.0.1"},
+		{ip: "not an ip"},
+		{ip: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			assert.Equal(t, tt.allowed, allowlist.Allows(ipallowlist.ClientAddr(tt.ip)))
+		})
+	}
+
+	// Only an allowlist open to every network takes unparsable addresses
+	assert.True(t, newAllowlist(t, "0.0.0.0/0").Allows(ipallowlist.ClientAddr("garbage")))
+	assert.False(t, newAllowlist(t).Allows(ipallowlist.ClientAddr("203.0.113.1")))
+}
+
+func TestParseCIDR(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		err   bool
+	}{
+		{input: "203.0.113.7", want: "203.0.113.7/32"},
+		{input: "2001:db8::1", want: "2001:db8::1/128"},
+		{input: "::ffff:203.0.113.7", want: "203.0.113.7/32"},
+		{input: "203.0.113.7/24", want: "203.0.113.0/24"},
+		{input: "2001:db8::1/32", want: "2001:db8::/32"},
+		{input: "203.0.113.0/33", err: true},
+		{input: "example.com", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			prefix, err := ipallowlist.ParseCIDR(tt.input)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, netip.MustParsePrefix(tt.want), prefix)
+		})
+	}
+}
+
+// TestAllowsBehindProxies takes the client IP the way the router does, from
+// X-Forwarded-For only as far as proxies on private networks added it, so a
+// client can't claim an allowlisted address
+func TestAllowsBehindProxies(t *testing.T) {
+	allowlist := newAllowlist(t, "203.0.113.0/24")
+
+	e := echo.New()
+	e.IPExtractor = echo.ExtractIPFromXFFHeader()
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		allowed      bool
+	}{
+		{name: "direct client", remoteAddr: "203.0.113.5:4000", allowed: true},
+		{name: "direct client outside", remoteAddr: "192.0.2.1:4000"},
+		{name: "spoofed header from public client", remoteAddr: "192.0.2.1:4000", forwardedFor: "203.0.113.5"},
+		{name: "through private proxy", remoteAddr: "10.0.0.2:4000", forwardedFor: "203.0.113.5", allowed: true},
+		{name: "through private proxy outside", remoteAddr: "10.0.0.2:4000", forwardedFor: "192.0.2.1"},
+		{
+			// The proxy appends the client it saw after whatever the client sent
+			name:         "spoofed header through private proxy",
+			remoteAddr:   "10.0.0.2:4000",
+			forwardedFor: "203.0.113.5, 192.0.2.1",
+		},
+		{name: "proxy chain", remoteAddr: "10.0.0.2:4000", forwardedFor: "203.0.113.5, 10.0.0.3", allowed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				req.Header.Set(echo.HeaderXForwardedFor, tt.forwardedFor)
+			}
+			c := e.NewContext(req, httptest.NewRecorder())
+
+			assert.Equal(t, tt.allowed, allowlist.Allows(ipallowlist.ClientAddr(c.RealIP())), "client IP %s", c.RealIP())
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package jobfailure
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/mabhi256/tasker/internal/model"
+)
+
+// JobFailure is a background task that ran out of retries. Occurrences
+// counts how often it failed again after being retried by hand.
+type JobFailure struct {
+	model.Base
+	TaskID      string          `json:"taskId" db:"task_id"`
+	Queue       string          `json:"queue" db:"queue"`
+	TaskType    string          `json:"taskType" db:"task_type"`
+	Payload     json.RawMessage `json:"payload" db:"payload"`
+	Error       string          `json:"error" db:"error"`
+	Retried     int             `json:"retried" db:"retried"`
+	MaxRetry    int             `json:"maxRetry" db:"max_retry"`
+	FailedAt    time.Time       `json:"failedAt" db:"failed_at"`
+	Occurrences int             `json:"occurrences" db:"occurrences"`
+}
@@ -0,0 +1,32 @@
+package outbox
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/model"
+)
+
+// Event is a domain event waiting in the outbox. Payload holds the entity
+// the event is about, serialized when the event was recorded.
+type Event struct {
+	model.BaseWithId
+	model.BaseWithCreatedAt
+	// WorkspaceID is only nil for audit events of the admin API
+	WorkspaceID *uuid.UUID      `json:"workspaceId" db:"workspace_id"`
+	EventType   string          `json:"eventType" db:"event_type"`
+	Payload     json.RawMessage `json:"payload" db:"payload"`
+	PublishedAt *time.Time      `json:"publishedAt" db:"published_at"`
+	Attempts    int             `json:"attempts" db:"attempts"`
+	LastError   *string         `json:"lastError" db:"last_error"`
+	AvailableAt time.Time       `json:"availableAt" db:"available_at"`
+	// DeadAt is when the event ran out of attempts and stopped being
+	// relayed
+	DeadAt *time.Time `json:"deadAt" db:"dead_at"`
+	// Trace holds the distributed tracing headers of the request that
+	// recorded the event
+	Trace map[string]string `json:"-" db:"trace"`
+	// ActorID is the user whose request recorded the event
+	ActorID *string `json:"actorId" db:"actor_id"`
+}
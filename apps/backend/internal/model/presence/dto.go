@@ -0,0 +1,7 @@
+package presence
+
+type GetPresencePayload struct{}
+
+func (p *GetPresencePayload) Validate() error {
+	return nil
+}
@@ -0,0 +1,7 @@
+package presence
+
+// Presence is a user connected to a workspace's realtime channel
+type Presence struct {
+	UserID      string `json:"userId"`
+	Connections int    `json:"connections"`
+}
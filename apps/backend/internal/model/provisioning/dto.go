@@ -0,0 +1,72 @@
+package provisioning
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// ------------------------------------------------------------
+
+type CreateTokenPayload struct{}
+
+func (p *CreateTokenPayload) Validate() error {
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type GetTokenPayload struct{}
+
+func (p *GetTokenPayload) Validate() error {
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type DeleteTokenPayload struct{}
+
+func (p *DeleteTokenPayload) Validate() error {
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type GetGroupsPayload struct{}
+
+func (p *GetGroupsPayload) Validate() error {
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type GetInvitationsPayload struct{}
+
+func (p *GetInvitationsPayload) Validate() error {
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type AcceptInvitationPayload struct {
+	ID uuid.UUID `param:"id" validate:"required,uuid"`
+}
+
+func (p *AcceptInvitationPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+// RoleNone unmaps a group from any workspace role
+const RoleNone = "none"
+
+type UpdateGroupRolePayload struct {
+	ID   uuid.UUID `param:"id" validate:"required,uuid"`
+	Role string    `json:"role" validate:"required,oneof=admin member viewer none"`
+}
+
+func (p *UpdateGroupRolePayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
@@ -0,0 +1,101 @@
+package provisioning
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/model"
+	"github.com/mabhi256/tasker/internal/model/workspace"
+)
+
+// ActorID is the user requests authenticated with a SCIM token act as
+const ActorID = "scim"
+
+// Token authenticates a workspace's identity provider. A workspace has at
+// most one, which is replaced when rotated.
+type Token struct {
+	model.BaseWithId
+	model.BaseWithCreatedAt
+	WorkspaceID uuid.UUID  `json:"workspaceId" db:"workspace_id"`
+	CreatedBy   string     `json:"createdBy" db:"created_by"`
+	TokenHash   string     `json:"-" db:"token_hash"`
+	LastUsedAt  *time.Time `json:"lastUsedAt" db:"last_used_at"`
+}
+
+// TokenWithSecret is returned when a token is created, the only time it is
+// shown
+type TokenWithSecret struct {
+	Token
+	Secret string `json:"token"`
+}
+
+// UserAttributes are the attributes of a user the identity provider sets
+type UserAttributes struct {
+	ExternalID  *string `json:"externalId" db:"external_id"`
+	UserName    string  `json:"userName" db:"user_name"`
+	Email       string  `json:"email" db:"email"`
+	GivenName   *string `json:"givenName" db:"given_name"`
+	FamilyName  *string `json:"familyName" db:"family_name"`
+	DisplayName *string `json:"displayName" db:"display_name"`
+	Active      bool    `json:"active" db:"active"`
+}
+
+// User is a user provisioned by the identity provider. UserID is the user
+// at the auth provider, which is a workspace member while the user is
+// active. Existing accounts outside the workspace's verified domains are
+// Pending, and only become members once they accept the invitation.
+type User struct {
+	model.Base
+	WorkspaceID uuid.UUID `json:"workspaceId" db:"workspace_id"`
+	UserID      string    `json:"userId" db:"user_id"`
+	Pending     bool      `json:"pending" db:"pending"`
+	UserAttributes
+}
+
+// GroupAttributes are the attributes of a group the identity provider sets.
+// Members are the ids of provisioned users.
+type GroupAttributes struct {
+	ExternalID  *string     `json:"externalId" db:"external_id"`
+	DisplayName string      `json:"displayName" db:"display_name"`
+	MemberIDs   []uuid.UUID `json:"memberIds" db:"member_ids"`
+}
+
+// Group is a group pushed by the identity provider. Its members are given
+// Role in the workspace; a group without one doesn't affect roles.
+type Group struct {
+	model.Base
+	WorkspaceID uuid.UUID       `json:"workspaceId" db:"workspace_id"`
+	Role        *workspace.Role `json:"role" db:"role"`
+	GroupAttributes
+}
+
+// MembershipChange is a change provisioning made to a workspace membership.
+// From and To are nil when the user wasn't or is no longer a member.
+type MembershipChange struct {
+	UserID string
+	From   *workspace.Role
+	To     *workspace.Role
+}
+
+// EffectiveRole is the workspace role of a provisioned user: the highest
+// role mapped to one of their groups, or member when none is. Inactive users
+// aren't members at all.
+func EffectiveRole(active bool, groupRoles []workspace.Role) *workspace.Role {
+	if !active {
+		return nil
+	}
+
+	role := workspace.RoleViewer
+	mapped := false
+	for _, groupRole := range groupRoles {
+		if !mapped || groupRole.AtLeast(role) {
+			role = groupRole
+			mapped = true
+		}
+	}
+	if !mapped {
+		role = workspace.RoleMember
+	}
+
+	return &role
+}
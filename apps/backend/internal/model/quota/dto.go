@@ -0,0 +1,9 @@
+package quota
+
+// ------------------------------------------------------------
+
+type GetUsagePayload struct{}
+
+func (p *GetUsagePayload) Validate() error {
+	return nil
+}
@@ -0,0 +1,49 @@
+package quota
+
+import "github.com/google/uuid"
+
+// Usage is what a user and their workspace store and request, against the
+// quotas they're held to
+type Usage struct {
+	WorkspaceID uuid.UUID `json:"workspaceId"`
+	Storage     Storage   `json:"storage"`
+	API         API       `json:"api"`
+}
+
+// Storage is the attachment bytes stored by the user, across workspaces,
+// and in the workspace
+type Storage struct {
+	User      Allowance `json:"user"`
+	Workspace Allowance `json:"workspace"`
+}
+
+// Allowance is the bytes used of a quota. QuotaBytes and Percent are nil
+// when the quota is unlimited.
+type Allowance struct {
+	UsedBytes   int64    `json:"usedBytes"`
+	Attachments int      `json:"attachments"`
+	QuotaBytes  *int64   `json:"quotaBytes"`
+	Percent     *float64 `json:"percent"`
+}
+
+// API is the requests made in the workspace. Counts reach it when instances
+// flush their meters, so the latest requests may be missing.
+type API struct {
+	RequestsToday      int64 `json:"requestsToday"`
+	RequestsLast30Days int64 `json:"requestsLast30Days"`
+}
+
+// StorageUsage is the stored bytes quotas are checked against
+type StorageUsage struct {
+	UserBytes            int64  `db:"user_bytes"`
+	UserAttachments      int    `db:"user_attachments"`
+	WorkspaceBytes       int64  `db:"workspace_bytes"`
+	WorkspaceAttachments int    `db:"workspace_attachments"`
+	OwnerID              string `db:"owner_id"`
+}
+
+// APIUsage is the requests counted for a workspace
+type APIUsage struct {
+	RequestsToday      int64 `db:"requests_today"`
+	RequestsLast30Days int64 `db:"requests_last_30_days"`
+}
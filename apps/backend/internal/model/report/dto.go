@@ -0,0 +1,29 @@
+package report
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// ------------------------------------------------------------
+
+type CreateReportPayload struct {
+	// SendEmail mails the user a link to the report once it is generated
+	SendEmail bool `json:"sendEmail"`
+}
+
+func (p *CreateReportPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type GetReportPayload struct {
+	ID uuid.UUID `param:"id" validate:"required,uuid"`
+}
+
+func (p *GetReportPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
@@ -0,0 +1,51 @@
+package report
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/model"
+)
+
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+type Kind string
+
+const (
+	// KindSummary is a PDF summary of the workspace's todos
+	KindSummary Kind = "summary"
+	// KindAccess is a CSV access review of the workspace
+	KindAccess Kind = "access"
+)
+
+const (
+	// MaxListed caps the overdue and completed todos a report lists
+	MaxListed = 50
+	// CompletedWindow is how far back a report lists completed todos
+	CompletedWindow = 7 * 24 * time.Hour
+)
+
+// Report is a file about a workspace, generated in the background for the
+// user who asked for it
+type Report struct {
+	model.Base
+	WorkspaceID uuid.UUID  `json:"workspaceId" db:"workspace_id"`
+	UserID      string     `json:"userId" db:"user_id"`
+	Kind        Kind       `json:"kind" db:"kind"`
+	Status      Status     `json:"status" db:"status"`
+	SendEmail   bool       `json:"sendEmail" db:"send_email"`
+	Attempts    int        `json:"attempts" db:"attempts"`
+	ObjectKey   *string    `json:"-" db:"object_key"`
+	SizeBytes   *int64     `json:"sizeBytes" db:"size_bytes"`
+	FinishedAt  *time.Time `json:"finishedAt" db:"finished_at"`
+	Error       *string    `json:"error" db:"error"`
+	// DownloadURL is a signed link to the file, set once it is generated
+	DownloadURL *string `json:"downloadUrl,omitempty" db:"-"`
+}
@@ -0,0 +1,28 @@
+package resolve
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// MaxIDs is the most ids one request resolves, across all kinds
+const MaxIDs = 100
+
+// ------------------------------------------------------------
+
+type ResolvePayload struct {
+	Todos      []uuid.UUID `json:"todos" validate:"max=100"`
+	Users      []string    `json:"users" validate:"max=100,dive,required,max=255"`
+	Categories []uuid.UUID `json:"categories" validate:"max=100"`
+	Comments   []uuid.UUID `json:"comments" validate:"max=100"`
+}
+
+func (p *ResolvePayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// Count is how many ids the payload asks to resolve
+func (p *ResolvePayload) Count() int {
+	return len(p.Todos) + len(p.Users) + len(p.Categories) + len(p.Comments)
+}
@@ -0,0 +1,21 @@
+package resolve
+
+import "github.com/google/uuid"
+
+// UserSummary is how a user is shown where they are referenced
+type UserSummary struct {
+	ID       string  `json:"id"`
+	Name     string  `json:"name"`
+	ImageURL *string `json:"imageUrl"`
+}
+
+// Response holds the summaries of the ids that resolved, keyed by id. Todos,
+// categories and comments are summarized by their compact views. IDs that
+// don't exist or aren't in the workspace, and users who aren't members, are
+// left out.
+type Response struct {
+	Todos      map[uuid.UUID]any      `json:"todos"`
+	Users      map[string]UserSummary `json:"users"`
+	Categories map[uuid.UUID]any      `json:"categories"`
+	Comments   map[uuid.UUID]any      `json:"comments"`
+}
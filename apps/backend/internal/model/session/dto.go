@@ -0,0 +1,35 @@
+package session
+
+import (
+	"github.com/go-playground/validator/v10"
+)
+
+// ------------------------------------------------------------
+
+type GetSessionsPayload struct{}
+
+func (p *GetSessionsPayload) Validate() error {
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type RevokeSessionPayload struct {
+	ID string `param:"id" validate:"required,max=255"`
+}
+
+func (p *RevokeSessionPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type RevokeUserSessionsPayload struct {
+	UserID string `param:"userId" validate:"required,max=255"`
+}
+
+func (p *RevokeUserSessionsPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
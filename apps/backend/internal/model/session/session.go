@@ -0,0 +1,45 @@
+package session
+
+import (
+	"time"
+)
+
+// RevokedKeyPrefix prefixes the Redis keys of the revoked session denylist
+const RevokedKeyPrefix = "session:revoked:"
+
+// RevokedKey returns the Redis key that marks a session as revoked until
+// every token issued for it expired
+func RevokedKey(sessionID string) string {
+	return RevokedKeyPrefix + sessionID
+}
+
+// EventRevoked is pushed to a session's realtime connections before they
+// are closed, so the client can sign out
+const EventRevoked = "session.revoked"
+
+// Session is a signed in device or browser of a user
+type Session struct {
+	ID           string    `json:"id"`
+	UserID       string    `json:"userId"`
+	Status       string    `json:"status"`
+	Current      bool      `json:"current"`
+	DeviceType   *string   `json:"deviceType"`
+	Browser      *string   `json:"browser"`
+	IPAddress    *string   `json:"ipAddress"`
+	City         *string   `json:"city"`
+	Country      *string   `json:"country"`
+	CreatedAt    time.Time `json:"createdAt"`
+	LastActiveAt time.Time `json:"lastActiveAt"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+}
+
+// RevokedEvent is the realtime message of EventRevoked
+type RevokedEvent struct {
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Data      RevokedData `json:"data"`
+}
+
+type RevokedData struct {
+	SessionID string `json:"sessionId"`
+}
@@ -0,0 +1,33 @@
+package snapshot
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// ------------------------------------------------------------
+
+type GetSnapshotPayload struct {
+	ID            uuid.UUID `param:"id" validate:"required,uuid"`
+	Format        *Format   `query:"format" validate:"omitempty,oneof=json html"`
+	RetentionDays *int      `query:"retentionDays" validate:"omitempty,min=1,max=365"`
+}
+
+func (p *GetSnapshotPayload) Validate() error {
+	validate := validator.New()
+
+	if err := validate.Struct(p); err != nil {
+		return err
+	}
+
+	if p.Format == nil {
+		defaultFormat := FormatJSON
+		p.Format = &defaultFormat
+	}
+	if p.RetentionDays == nil {
+		defaultRetentionDays := DefaultRetentionDays
+		p.RetentionDays = &defaultRetentionDays
+	}
+
+	return nil
+}
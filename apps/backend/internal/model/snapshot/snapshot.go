@@ -0,0 +1,50 @@
+package snapshot
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/model/todo"
+)
+
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatHTML Format = "html"
+)
+
+const (
+	// DefaultRetentionDays is how long a snapshot is kept unless the request
+	// asks otherwise
+	DefaultRetentionDays = 30
+	// MaxRetentionDays caps how long a snapshot can be kept
+	MaxRetentionDays = 365
+)
+
+// Snapshot is a frozen copy of a todo, with its comments and attachment
+// metadata, stored until ExpiresAt for audit and sharing. It outlives the
+// todo, so TodoID may no longer exist.
+type Snapshot struct {
+	ID             uuid.UUID `json:"id" db:"id"`
+	CreatedAt      time.Time `json:"createdAt" db:"created_at"`
+	WorkspaceID    uuid.UUID `json:"workspaceId" db:"workspace_id"`
+	TodoID         uuid.UUID `json:"todoId" db:"todo_id"`
+	CreatedBy      string    `json:"createdBy" db:"created_by"`
+	Format         Format    `json:"format" db:"format"`
+	ObjectKey      string    `json:"-" db:"object_key"`
+	SizeBytes      int64     `json:"sizeBytes" db:"size_bytes"`
+	ChecksumSHA256 string    `json:"checksumSha256" db:"checksum_sha256"`
+	ExpiresAt      time.Time `json:"expiresAt" db:"expires_at"`
+	// DownloadURL is a signed link to the stored snapshot
+	DownloadURL string `json:"downloadUrl" db:"-"`
+}
+
+// Document is what a snapshot stores
+type Document struct {
+	SnapshotID  uuid.UUID           `json:"snapshotId"`
+	TakenAt     time.Time           `json:"takenAt"`
+	TakenBy     string              `json:"takenBy"`
+	WorkspaceID uuid.UUID           `json:"workspaceId"`
+	Todo        *todo.PopulatedTodo `json:"todo"`
+}
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// SortKey is a field a list is sorted by
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// ParseSort parses a sort query, a comma separated list of fields, such as
+// due_date,-priority,id. Fields prefixed with - are sorted in descending
+// order and the rest in ascending order. desc only applies to a single field
+// without a prefix, for clients that send the field and an order apart.
+func ParseSort(sort string, fields []string, desc bool) ([]SortKey, error) {
+	items := strings.Split(sort, ",")
+	desc = desc && len(items) == 1
+
+	var keys []SortKey
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		key := SortKey{Field: item, Desc: desc}
+		if field, ok := strings.CutPrefix(item, "-"); ok {
+			key = SortKey{Field: field, Desc: true}
+		}
+
+		if !slices.Contains(fields, key.Field) {
+			return nil, fmt.Errorf("can't sort by %q", key.Field)
+		}
+		if slices.ContainsFunc(keys, func(k SortKey) bool { return k.Field == key.Field }) {
+			return nil, fmt.Errorf("%q is sorted by twice", key.Field)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// RegisterSortValidation adds the sort tag, which checks a sort query
+// against the fields given as its parameter, such as sort=name created_at
+func RegisterSortValidation(validate *validator.Validate) {
+	_ = validate.RegisterValidation("sort", func(fl validator.FieldLevel) bool {
+		_, err := ParseSort(fl.Field().String(), strings.Fields(fl.Param()), false)
+		return err == nil
+	})
+}
@@ -0,0 +1,65 @@
+package sso
+
+import (
+	"github.com/go-playground/validator/v10"
+)
+
+// ------------------------------------------------------------
+
+type GetConfigPayload struct{}
+
+func (p *GetConfigPayload) Validate() error {
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type UpsertConfigPayload struct {
+	DiscoveryURL   string   `json:"discoveryUrl" validate:"required,url,max=2048"`
+	ClientID       string   `json:"clientId" validate:"required,min=1,max=255"`
+	AllowedDomains []string `json:"allowedDomains" validate:"required,min=1,max=50,dive,fqdn"`
+	Enforced       *bool    `json:"enforced"`
+}
+
+func (p *UpsertConfigPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type DeleteConfigPayload struct{}
+
+func (p *DeleteConfigPayload) Validate() error {
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type VerifyDomainPayload struct {
+	Domain string `json:"domain" validate:"required,fqdn"`
+}
+
+func (p *VerifyDomainPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type GetLoginPayload struct{}
+
+func (p *GetLoginPayload) Validate() error {
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type VerifyPayload struct {
+	IDToken string `json:"idToken" validate:"required,max=16384"`
+}
+
+func (p *VerifyPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
@@ -0,0 +1,108 @@
+package sso
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/model"
+)
+
+// CodeSSORequired is the error code of requests made before the user
+// signed in with a workspace's identity provider
+const CodeSSORequired = "SSO_REQUIRED"
+
+const (
+	VerificationKeyPrefix = "sso:verified:"
+	NonceKeyPrefix        = "sso:nonce:"
+)
+
+const (
+	// DomainRecordPrefix starts the TXT record that proves a workspace owns
+	// a domain, followed by its domain token
+	DomainRecordPrefix = "tasker-domain-verification="
+	// DomainRecordLabel is prepended to the domain to name the TXT record
+	DomainRecordLabel = "_tasker"
+)
+
+// VerificationKey returns the Redis key that records a session signed in
+// with the workspace's identity provider
+func VerificationKey(sessionID string, workspaceID uuid.UUID) string {
+	return VerificationKeyPrefix + sessionID + ":" + workspaceID.String()
+}
+
+// DomainRecordName returns the name of the TXT record that proves a
+// workspace owns the domain
+func DomainRecordName(domain string) string {
+	return DomainRecordLabel + "." + domain
+}
+
+// NonceKey returns the Redis key that stores the nonce of a session's
+// pending sign in with the workspace's identity provider
+func NonceKey(sessionID string, workspaceID uuid.UUID) string {
+	return NonceKeyPrefix + sessionID + ":" + workspaceID.String()
+}
+
+// Config is a workspace's identity provider. While Enforced, members other
+// than the owner must sign in with it before using the workspace.
+// VerifiedDomains are the allowed domains the workspace proved it owns by
+// publishing its DomainToken, see DomainRecord.
+type Config struct {
+	model.BaseWithCreatedAt
+	model.BaseWithUpdatedAt
+	WorkspaceID     uuid.UUID `json:"workspaceId" db:"workspace_id"`
+	CreatedBy       string    `json:"createdBy" db:"created_by"`
+	DiscoveryURL    string    `json:"discoveryUrl" db:"discovery_url"`
+	ClientID        string    `json:"clientId" db:"client_id"`
+	AllowedDomains  []string  `json:"allowedDomains" db:"allowed_domains"`
+	Enforced        bool      `json:"enforced" db:"enforced"`
+	VerifiedDomains []string  `json:"verifiedDomains" db:"verified_domains"`
+	DomainToken     string    `json:"domainToken" db:"domain_token"`
+}
+
+// AllowsEmail reports whether the email is in one of the allowed domains
+func (c *Config) AllowsEmail(email string) bool {
+	return emailInDomains(email, c.AllowedDomains)
+}
+
+// VerifiesEmail reports whether the email is in one of the verified domains
+func (c *Config) VerifiesEmail(email string) bool {
+	return emailInDomains(email, c.VerifiedDomains)
+}
+
+// DomainRecord returns the value of the TXT record that proves the
+// workspace owns a domain
+func (c *Config) DomainRecord() string {
+	return DomainRecordPrefix + c.DomainToken
+}
+
+func emailInDomains(email string, domains []string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := email[at+1:]
+	for _, allowed := range domains {
+		if strings.EqualFold(allowed, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// Login is what the frontend needs to send the user to the identity
+// provider. The ID token it returns must carry the nonce.
+type Login struct {
+	WorkspaceID           uuid.UUID `json:"workspaceId"`
+	Issuer                string    `json:"issuer"`
+	AuthorizationEndpoint string    `json:"authorizationEndpoint"`
+	ClientID              string    `json:"clientId"`
+	Nonce                 string    `json:"nonce"`
+}
+
+// Verification is a session's sign in with a workspace's identity provider
+type Verification struct {
+	WorkspaceID uuid.UUID `json:"workspaceId"`
+	Email       string    `json:"email"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
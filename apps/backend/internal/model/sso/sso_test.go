@@ -0,0 +1,41 @@
+package sso_test
+
+import (
+	"testing"
+
+	"github.com/mabhi256/tasker/internal/model/sso"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifiesEmail(t *testing.T) {
+	config := &sso.Config{
+		AllowedDomains:  []string{"example.com", "example.org"},
+		VerifiedDomains: []string{"example.com"},
+	}
+
+	tests := []struct {
+		email    string
+		allowed  bool
+		verified bool
+	}{
+		{email: "jane@example.com", allowed: true, verified: true},
+		{email: "Jane@EXAMPLE.com", allowed: true, verified: true},
+		{email: "jane@example.org", allowed: true, verified: false},
+		{email: "jane@sub.example.com", allowed: false, verified: false},
+		{email: "jane@example.com.evil.test", allowed: false, verified: false},
+		{email: "example.com", allowed: false, verified: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.email, func(t *testing.T) {
+			assert.Equal(t, tt.allowed, config.AllowsEmail(tt.email))
+			assert.Equal(t, tt.verified, config.VerifiesEmail(tt.email))
+		})
+	}
+}
+
+func TestDomainRecord(t *testing.T) {
+	config := &sso.Config{DomainToken: "abc123"}
+
+	assert.Equal(t, "_tasker.example.com", sso.DomainRecordName("example.com"))
+	assert.Equal(t, "tasker-domain-verification=abc123", config.DomainRecord())
+}
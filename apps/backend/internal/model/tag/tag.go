@@ -0,0 +1,49 @@
+// Package tag holds the tags todos are labelled with. Tags aren't stored on
+// their own: a tag exists while the metadata of a todo of the workspace
+// lists it, and is identified by its name.
+package tag
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// Change is a tag renamed or merged into another across the workspace's
+// todos
+type Change struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Merged is set when To already existed
+	Merged bool `json:"merged"`
+	// TodoIDs are the todos that were relabelled
+	TodoIDs []uuid.UUID `json:"todoIds"`
+	// Deduplicated counts the todos that had both tags, and now list To once
+	Deduplicated int `json:"deduplicated"`
+}
+
+// ------------------------------------------------------------
+
+// RenameTagPayload renames a tag. Renaming to a tag that exists is refused,
+// as merging two tags is done with MergeTagPayload.
+type RenameTagPayload struct {
+	Tag  string `param:"id" validate:"required,min=1,max=100"`
+	Name string `json:"name" validate:"required,min=1,max=100"`
+}
+
+func (p *RenameTagPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+// MergeTagPayload replaces a tag with another that exists
+type MergeTagPayload struct {
+	Tag    string `param:"id" validate:"required,min=1,max=100"`
+	Target string `param:"targetId" validate:"required,min=1,max=100"`
+}
+
+func (p *MergeTagPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
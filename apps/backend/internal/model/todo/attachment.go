@@ -1,16 +1,86 @@
 package todo
 
 import (
+	"time"
+
 	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/lib/artifact"
+	"github.com/mabhi256/tasker/internal/lib/preview"
 	"github.com/mabhi256/tasker/internal/model"
 )
 
+type IntegrityStatus string
+
+const (
+	IntegrityStatusUnverified IntegrityStatus = "unverified"
+	IntegrityStatusOK         IntegrityStatus = "ok"
+	IntegrityStatusMissing    IntegrityStatus = "missing"
+	IntegrityStatusCorrupt    IntegrityStatus = "corrupt"
+)
+
+// IsFlagged reports whether the stored object failed its last check
+func (s IntegrityStatus) IsFlagged() bool {
+	return s == IntegrityStatusMissing || s == IntegrityStatusCorrupt
+}
+
+type ProcessingStatus string
+
+const (
+	ProcessingStatusPending   ProcessingStatus = "pending"
+	ProcessingStatusProcessed ProcessingStatus = "processed"
+	// ProcessingStatusRejected attachments break the attachment policy, and
+	// can't be downloaded
+	ProcessingStatusRejected ProcessingStatus = "rejected"
+)
+
+type ScanStatus string
+
+const (
+	// ScanStatusUnscanned attachments were uploaded without a scanner, or
+	// were too large for it
+	ScanStatusUnscanned ScanStatus = "unscanned"
+	ScanStatusClean     ScanStatus = "clean"
+	// ScanStatusInfected attachments are quarantined and rejected
+	ScanStatusInfected ScanStatus = "infected"
+)
+
 type TodoAttachment struct {
 	model.Base
-	TodoID      uuid.UUID `json:"todoId" db:"todo_id"`
-	Name        string    `json:"name" db:"name"`
-	UploadedBy  string    `json:"uploadedBy" db:"uploaded_by"`
-	DownloadKey string    `json:"downloadKey" db:"download_key"`
-	FileSize    *int64    `json:"fileSize" db:"file_size"`
-	MimeType    *string   `json:"mimeType" db:"mime_type"`
+	TodoID             uuid.UUID       `json:"todoId" db:"todo_id"`
+	WorkspaceID        uuid.UUID       `json:"workspaceId" db:"workspace_id"`
+	Name               string          `json:"name" db:"name"`
+	UploadedBy         string          `json:"uploadedBy" db:"uploaded_by"`
+	DownloadKey        string          `json:"downloadKey" db:"download_key"`
+	FileSize           *int64          `json:"fileSize" db:"file_size"`
+	MimeType           *string         `json:"mimeType" db:"mime_type"`
+	ChecksumSHA256     *string         `json:"checksumSha256" db:"checksum_sha256"`
+	IntegrityStatus    IntegrityStatus `json:"integrityStatus" db:"integrity_status"`
+	IntegrityCheckedAt *time.Time      `json:"integrityCheckedAt" db:"integrity_checked_at"`
+	IntegrityError     *string         `json:"-" db:"integrity_error"`
+	// Preview is extracted after upload, and stays nil for files that have
+	// none. PreviewExtractedAt is nil until the extraction ran.
+	Preview            *preview.Preview `json:"preview" db:"preview"`
+	PreviewExtractedAt *time.Time       `json:"previewExtractedAt" db:"preview_extracted_at"`
+	// ProcessingError is why the attachment was rejected, or what couldn't
+	// be derived from it
+	ProcessingStatus ProcessingStatus `json:"processingStatus" db:"processing_status"`
+	ProcessingError  *string          `json:"processingError" db:"processing_error"`
+	ProcessedAt      *time.Time       `json:"processedAt" db:"processed_at"`
+	// ScanSignature names the malware found in an infected attachment
+	ScanStatus    ScanStatus `json:"scanStatus" db:"scan_status"`
+	ScanSignature *string    `json:"scanSignature" db:"scan_signature"`
+	ScannedAt     *time.Time `json:"scannedAt" db:"scanned_at"`
+}
+
+// AttachmentArtifact is a file derived from an attachment. Width and Height
+// are set for thumbnails.
+type AttachmentArtifact struct {
+	model.Base
+	AttachmentID uuid.UUID     `json:"attachmentId" db:"attachment_id"`
+	Kind         artifact.Kind `json:"kind" db:"kind"`
+	StorageKey   string        `json:"-" db:"storage_key"`
+	MimeType     string        `json:"mimeType" db:"mime_type"`
+	Size         int64         `json:"size" db:"size"`
+	Width        *int          `json:"width" db:"width"`
+	Height       *int          `json:"height" db:"height"`
 }
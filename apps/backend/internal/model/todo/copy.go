@@ -0,0 +1,27 @@
+package todo
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+type CopyTodoPayload struct {
+	ID                uuid.UUID `param:"id" validate:"required,uuid"`
+	TargetWorkspaceID uuid.UUID `json:"targetWorkspaceId" validate:"required,uuid"`
+	// IncludeAttachments copies the attachments' files too. Defaults to true.
+	IncludeAttachments *bool `json:"includeAttachments"`
+}
+
+func (p *CopyTodoPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// CopiedTodo is a todo copied into another workspace
+type CopiedTodo struct {
+	Todo *PopulatedTodo `json:"todo"`
+	// RemappedUsers are the owners and uploaders of the original who aren't
+	// members of the target workspace, whose todos and attachments the copier
+	// took over
+	RemappedUsers []string `json:"remappedUsers"`
+}
@@ -0,0 +1,34 @@
+package todo
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Dependency records that TodoID can't be done before BlockedByID
+type Dependency struct {
+	TodoID      uuid.UUID `json:"todoId" db:"todo_id"`
+	BlockedByID uuid.UUID `json:"blockedById" db:"blocked_by_id"`
+	CreatedAt   time.Time `json:"createdAt" db:"created_at"`
+	WorkspaceID uuid.UUID `json:"workspaceId" db:"workspace_id"`
+}
+
+// DependentTodo is a todo listed along with whether it is blocked, which it
+// is while any todo blocking it is neither completed nor archived
+type DependentTodo struct {
+	Todo
+	Blocked bool `json:"blocked" db:"blocked"`
+}
+
+// Dependencies are the todos a todo is blocked by and the todos it blocks
+type Dependencies struct {
+	Blocked   bool            `json:"blocked"`
+	BlockedBy []DependentTodo `json:"blockedBy"`
+	Blocks    []DependentTodo `json:"blocks"`
+}
+
+// IsDone reports whether the todo no longer blocks the todos depending on it
+func (t *Todo) IsDone() bool {
+	return t.Status == StatusCompleted || t.Status == StatusArchived
+}
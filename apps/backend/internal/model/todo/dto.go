@@ -5,6 +5,17 @@ import (
 
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/model"
+	"github.com/mabhi256/tasker/internal/model/category"
+)
+
+// Limits of the fields below, as their validate tags enforce them
+const (
+	MaxTitleLength       = 255
+	MaxDescriptionLength = 1000
+	MaxPageSize          = 100
+	// MaxReminderMinutes is four weeks
+	MaxReminderMinutes = 40320
 )
 
 // ------------------------------------------------------------
@@ -17,6 +28,10 @@ type CreateTodoPayload struct {
 	ParentTodoID *uuid.UUID `json:"parentTodoId" validate:"omitempty,uuid"`
 	CategoryID   *uuid.UUID `json:"categoryId" validate:"omitempty,uuid"`
 	Metadata     *Metadata  `json:"metadata"`
+	// ReminderMinutes is how long before it's due the todo's reminder is sent
+	ReminderMinutes *int `json:"reminderMinutes" validate:"omitempty,min=1,max=40320"`
+	// PublishAt creates the todo scheduled, to be published at that time
+	PublishAt *time.Time `json:"publishAt"`
 }
 
 func (p *CreateTodoPayload) Validate() error {
@@ -24,6 +39,26 @@ func (p *CreateTodoPayload) Validate() error {
 	return validate.Struct(p)
 }
 
+// WithCategoryDefaults returns the payload with the fields it leaves out
+// filled in from the defaults of the todo's category, so those given
+// explicitly override them
+func (p *CreateTodoPayload) WithCategoryDefaults(c *category.Category) *CreateTodoPayload {
+	withDefaults := *p
+	if c == nil {
+		return &withDefaults
+	}
+
+	if withDefaults.Priority == nil && c.DefaultPriority != nil {
+		priority := Priority(*c.DefaultPriority)
+		withDefaults.Priority = &priority
+	}
+	if withDefaults.ReminderMinutes == nil {
+		withDefaults.ReminderMinutes = c.DefaultReminderMinutes
+	}
+
+	return &withDefaults
+}
+
 // ------------------------------------------------------------
 
 type UpdateTodoPayload struct {
@@ -36,6 +71,11 @@ type UpdateTodoPayload struct {
 	ParentTodoID *uuid.UUID `json:"parentTodoId" validate:"omitempty,uuid"`
 	CategoryID   *uuid.UUID `json:"categoryId" validate:"omitempty,uuid"`
 	Metadata     *Metadata  `json:"metadata"`
+
+	ReminderMinutes *int `json:"reminderMinutes" validate:"omitempty,min=1,max=40320"`
+	// PublishAt reschedules a scheduled todo. Setting Status publishes it
+	// right away instead.
+	PublishAt *time.Time `json:"publishAt"`
 }
 
 func (p *UpdateTodoPayload) Validate() error {
@@ -48,10 +88,10 @@ func (p *UpdateTodoPayload) Validate() error {
 type GetTodosQuery struct {
 	Page         *int       `query:"page" validate:"omitempty,min=1"`
 	Limit        *int       `query:"limit" validate:"omitempty,min=1,max=100"`
-	Sort         *string    `query:"sort" validate:"omitempty,oneof=created_at updated_at title priority due_date status"`
+	Sort         *string    `query:"sort" validate:"omitempty,sort=created_at updated_at title priority due_date status id"`
 	Order        *string    `query:"order" validate:"omitempty,oneof=asc desc"`
 	Search       *string    `query:"search" validate:"omitempty,min=1"`
-	Status       *Status    `query:"status" validate:"omitempty,oneof=draft active completed archived"`
+	Status       *Status    `query:"status" validate:"omitempty,oneof=draft active completed archived scheduled"`
 	Priority     *Priority  `query:"priority" validate:"omitempty,oneof=low medium high"`
 	CategoryID   *uuid.UUID `query:"categoryId" validate:"omitempty,uuid"`
 	ParentTodoID *uuid.UUID `query:"parentTodoId" validate:"omitempty,uuid"`
@@ -59,10 +99,16 @@ type GetTodosQuery struct {
 	DueTo        *time.Time `query:"dueTo"`
 	Overdue      *bool      `query:"overdue"`
 	Completed    *bool      `query:"completed"`
+	Render       *string    `query:"render" validate:"omitempty,oneof=html"`
+	// Locale, such as de-AT, selects how titles sort, in place of the
+	// Accept-Language header. The service replaces it with the collation
+	// language it matched.
+	Locale *string `query:"locale" validate:"omitempty,bcp47_language_tag"`
 }
 
 func (q *GetTodosQuery) Validate() error {
 	validate := validator.New()
+	model.RegisterSortValidation(validate)
 
 	if err := validate.Struct(q); err != nil {
 		return err
@@ -89,10 +135,49 @@ func (q *GetTodosQuery) Validate() error {
 	return nil
 }
 
+// SortFields are the fields todos can be sorted by, as the sort tag above
+// enforces them
+var SortFields = []string{"created_at", "updated_at", "title", "priority", "due_date", "status", "id"}
+
+// SortKeys returns the fields to sort by, once the query is validated
+func (q *GetTodosQuery) SortKeys() []model.SortKey {
+	keys, _ := model.ParseSort(*q.Sort, SortFields, *q.Order == "desc")
+	return keys
+}
+
+// ------------------------------------------------------------
+
+type SearchTodosQuery struct {
+	Q      string  `query:"q" validate:"required,min=1,max=200"`
+	Page   *int    `query:"page" validate:"omitempty,min=1"`
+	Limit  *int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Render *string `query:"render" validate:"omitempty,oneof=html"`
+}
+
+func (q *SearchTodosQuery) Validate() error {
+	validate := validator.New()
+
+	if err := validate.Struct(q); err != nil {
+		return err
+	}
+
+	if q.Page == nil {
+		defaultPage := 1
+		q.Page = &defaultPage
+	}
+	if q.Limit == nil {
+		defaultLimit := 20
+		q.Limit = &defaultLimit
+	}
+
+	return nil
+}
+
 // ------------------------------------------------------------
 
 type GetTodoByIDPayload struct {
-	ID uuid.UUID `param:"id" validate:"required,uuid"`
+	ID     uuid.UUID `param:"id" validate:"required,uuid"`
+	Render *string   `query:"render" validate:"omitempty,oneof=html"`
 }
 
 func (p *GetTodoByIDPayload) Validate() error {
@@ -134,6 +219,31 @@ func (p *UploadTodoAttachmentPayload) Validate() error {
 
 // ------------------------------------------------------------
 
+// PasteTodoImagePayload uploads an image pasted into the todo's description
+// or one of its comments. The image is sent either as the file of a
+// multipart form or as Data, base64 encoded or as a data URL.
+type PasteTodoImagePayload struct {
+	TodoID uuid.UUID `param:"id" validate:"required,uuid"`
+	Data   *string   `json:"data"`
+	// Name names the attachment, which is otherwise named after the file or
+	// the time it was pasted
+	Name *string `json:"name" validate:"omitempty,min=1,max=255"`
+}
+
+func (p *PasteTodoImagePayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// PastedImage is a pasted image's attachment with the markdown that embeds
+// it, for the client to insert where the image was pasted
+type PastedImage struct {
+	Attachment *TodoAttachment `json:"attachment"`
+	Markdown   string          `json:"markdown"`
+}
+
+// ------------------------------------------------------------
+
 type DeleteTodoAttachmentPayload struct {
 	TodoID       uuid.UUID `param:"id" validate:"required,uuid"`
 	AttachmentID uuid.UUID `param:"attachmentId" validate:"required,uuid"`
@@ -155,3 +265,71 @@ func (p *GetAttachmentPresignedURLPayload) Validate() error {
 	validate := validator.New()
 	return validate.Struct(p)
 }
+
+type GetAttachmentThumbnailPayload struct {
+	TodoID       uuid.UUID `param:"id" validate:"required,uuid"`
+	AttachmentID uuid.UUID `param:"attachmentId" validate:"required,uuid"`
+}
+
+func (p *GetAttachmentThumbnailPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+// Todo Dependency DTOs
+// ------------------------------------------------------------
+
+type GetTodoDependenciesPayload struct {
+	TodoID uuid.UUID `param:"id" validate:"required,uuid"`
+}
+
+func (p *GetTodoDependenciesPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type AddTodoDependencyPayload struct {
+	TodoID      uuid.UUID `param:"id" validate:"required,uuid"`
+	BlockedByID uuid.UUID `json:"blockedById" validate:"required,uuid"`
+}
+
+func (p *AddTodoDependencyPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type RemoveTodoDependencyPayload struct {
+	TodoID      uuid.UUID `param:"id" validate:"required,uuid"`
+	BlockedByID uuid.UUID `param:"blockedById" validate:"required,uuid"`
+}
+
+func (p *RemoveTodoDependencyPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type GetWorkListQuery struct {
+	Limit *int `query:"limit" validate:"omitempty,min=1,max=200"`
+}
+
+func (q *GetWorkListQuery) Validate() error {
+	validate := validator.New()
+
+	if err := validate.Struct(q); err != nil {
+		return err
+	}
+
+	if q.Limit == nil {
+		defaultLimit := 50
+		q.Limit = &defaultLimit
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package todo_test
+
+import (
+	"testing"
+
+	"github.com/mabhi256/tasker/internal/model"
+	"github.com/mabhi256/tasker/internal/model/todo"
+	testutil "github.com/mabhi256/tasker/internal/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTodosQuerySortKeys(t *testing.T) {
+	tests := []struct {
+		name  string
+		sort  *string
+		order *string
+		keys  []model.SortKey
+	}{
+		{name: "default", keys: []model.SortKey{{Field: "created_at", Desc: true}}},
+		{name: "single field", sort: testutil.Ptr("title"), keys: []model.SortKey{{Field: "title", Desc: true}}},
+		{
+			name:  "single field ascending",
+			sort:  testutil.Ptr("title"),
+			order: testutil.Ptr("asc"),
+			keys:  []model.SortKey{{Field: "title"}},
+		},
+		{name: "single field descending", sort: testutil.Ptr("-title"), keys: []model.SortKey{{Field: "title", Desc: true}}},
+		{
+			name: "mixed directions",
+			sort: testutil.Ptr("due_date,-priority"),
+			keys: []model.SortKey{{Field: "due_date"}, {Field: "priority", Desc: true}},
+		},
+		{
+			name:  "order ignored for several fields",
+			sort:  testutil.Ptr("-priority,title,id"),
+			order: testutil.Ptr("desc"),
+			keys:  []model.SortKey{{Field: "priority", Desc: true}, {Field: "title"}, {Field: "id"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := &todo.GetTodosQuery{Sort: tt.sort, Order: tt.order}
+			require.NoError(t, query.Validate())
+			assert.Equal(t, tt.keys, query.SortKeys())
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/lib/content"
 	"github.com/mabhi256/tasker/internal/model"
 	"github.com/mabhi256/tasker/internal/model/category"
 	"github.com/mabhi256/tasker/internal/model/comment"
@@ -16,6 +17,9 @@ const (
 	StatusActive    Status = "active"
 	StatusCompleted Status = "completed"
 	StatusArchived  Status = "archived"
+	// StatusScheduled todos turn active at their PublishAt, when the
+	// creation of those published is announced
+	StatusScheduled Status = "scheduled"
 )
 
 type Priority string
@@ -28,6 +32,7 @@ const (
 
 type Todo struct {
 	model.Base
+	WorkspaceID  uuid.UUID  `json:"workspaceId" db:"workspace_id"`
 	UserID       string     `json:"userId" db:"user_id"`
 	Title        string     `json:"title" db:"title"`
 	Description  *string    `json:"description" db:"description"`
@@ -39,6 +44,21 @@ type Todo struct {
 	CategoryID   *uuid.UUID `json:"categoryId" db:"category_id"`
 	Metadata     *Metadata  `json:"metadata" db:"metadata"`
 	SortOrder    int        `json:"sortOrder" db:"sort_order"`
+	// ReminderMinutes is how long before it's due the todo's reminder is
+	// sent, when it isn't the configured default
+	ReminderMinutes *int `json:"reminderMinutes" db:"reminder_minutes"`
+	// PublishAt is when a scheduled todo is, or was, published
+	PublishAt *time.Time `json:"publishAt" db:"publish_at"`
+
+	// PriorityRank and SearchVector are derived by the database for sorting
+	// and search, and aren't part of the API
+	PriorityRank *int16  `json:"-" db:"priority_rank"`
+	SearchVector *string `json:"-" db:"search_vector"`
+
+	// DescriptionHTML and Checklist are only set when the request asks for
+	// rendered content, see RenderContent
+	DescriptionHTML *string                 `json:"descriptionHtml,omitempty" db:"-"`
+	Checklist       []content.ChecklistItem `json:"checklist,omitempty" db:"-"`
 }
 
 type Metadata struct {
@@ -56,23 +76,37 @@ type PopulatedTodo struct {
 	Attachments []TodoAttachment   `json:"attachments" db:"attachments"`
 }
 
+// SearchEngine names where search results came from
+type SearchEngine string
+
+const (
+	SearchEngineExternal SearchEngine = "external"
+	SearchEnginePostgres SearchEngine = "postgres"
+)
+
+// SearchMeta tells where search results came from. Degraded is set when the
+// external engine failed and Postgres served the search instead, which only
+// matches whole words, so results can differ from the engine's.
+type SearchMeta struct {
+	Engine   SearchEngine `json:"engine"`
+	Degraded bool         `json:"degraded"`
+}
+
+type SearchResponse struct {
+	model.PaginatedResponse[PopulatedTodo]
+	Meta SearchMeta `json:"meta"`
+}
+
 type TodoStats struct {
 	Total     int `json:"total"`
 	Draft     int `json:"draft"`
 	Active    int `json:"active"`
 	Completed int `json:"completed"`
 	Archived  int `json:"archived"`
+	Scheduled int `json:"scheduled"`
 	Overdue   int `json:"overdue"`
 }
 
-type UserWeeklyStats struct {
-	UserID         string `json:"userId" db:"user_id"`
-	CreatedCount   int    `json:"createdCount" db:"created_count"`
-	CompletedCount int    `json:"completedCount" db:"completed_count"`
-	ActiveCount    int    `json:"activeCount" db:"active_count"`
-	OverdueCount   int    `json:"overdueCount" db:"overdue_count"`
-}
-
 func (t *Todo) IsOverdue() bool {
 	return t.DueDate != nil && t.DueDate.Before(time.Now()) && t.Status != StatusCompleted
 }
@@ -80,3 +114,26 @@ func (t *Todo) IsOverdue() bool {
 func (t *Todo) CanHaveChildren() bool {
 	return t.ParentTodoID == nil
 }
+
+// RenderContent renders the description to sanitized HTML and extracts its
+// checklist
+func (t *Todo) RenderContent() {
+	if t.Description == nil {
+		return
+	}
+
+	rendered := content.Render(*t.Description)
+	t.DescriptionHTML = &rendered.HTML
+	t.Checklist = rendered.Checklist
+}
+
+// RenderContent renders the todo along with its children and comments
+func (t *PopulatedTodo) RenderContent() {
+	t.Todo.RenderContent()
+	for i := range t.Children {
+		t.Children[i].RenderContent()
+	}
+	for i := range t.Comments {
+		t.Comments[i].RenderContent()
+	}
+}
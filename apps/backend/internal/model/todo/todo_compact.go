@@ -0,0 +1,108 @@
+package todo
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/lib/jsonenc"
+)
+
+// CompactTodo is the compact view of a todo, enough to list it
+type CompactTodo struct {
+	ID           uuid.UUID  `json:"id"`
+	Title        string     `json:"title"`
+	Status       Status     `json:"status"`
+	Priority     Priority   `json:"priority"`
+	DueDate      *time.Time `json:"dueDate"`
+	CompletedAt  *time.Time `json:"completedAt"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
+	ParentTodoID *uuid.UUID `json:"parentTodoId"`
+	CategoryID   *uuid.UUID `json:"categoryId"`
+}
+
+// Compact implements jsonenc.Compacter
+func (t *Todo) Compact() any {
+	return t.compact()
+}
+
+func (t *Todo) compact() *CompactTodo {
+	return &CompactTodo{
+		ID:           t.ID,
+		Title:        t.Title,
+		Status:       t.Status,
+		Priority:     t.Priority,
+		DueDate:      t.DueDate,
+		CompletedAt:  t.CompletedAt,
+		UpdatedAt:    t.UpdatedAt,
+		ParentTodoID: t.ParentTodoID,
+		CategoryID:   t.CategoryID,
+	}
+}
+
+// CompactPopulatedTodo counts what a populated todo lists instead of
+// listing it
+type CompactPopulatedTodo struct {
+	*CompactTodo
+	ChildCount      int `json:"childCount"`
+	CommentCount    int `json:"commentCount"`
+	AttachmentCount int `json:"attachmentCount"`
+}
+
+// Compact implements jsonenc.Compacter
+func (t *PopulatedTodo) Compact() any {
+	return &CompactPopulatedTodo{
+		CompactTodo:     t.Todo.compact(),
+		ChildCount:      len(t.Children),
+		CommentCount:    len(t.Comments),
+		AttachmentCount: len(t.Attachments),
+	}
+}
+
+// CompactDependentTodo is the compact view of a todo listed as a dependency
+type CompactDependentTodo struct {
+	*CompactTodo
+	Blocked bool `json:"blocked"`
+}
+
+// Compact implements jsonenc.Compacter
+func (t *DependentTodo) Compact() any {
+	return &CompactDependentTodo{
+		CompactTodo: t.Todo.compact(),
+		Blocked:     t.Blocked,
+	}
+}
+
+// Compact implements jsonenc.Compacter, compacting the todos blocking and
+// blocked by the todo
+func (d *Dependencies) Compact() any {
+	return &struct {
+		Blocked   bool  `json:"blocked"`
+		BlockedBy []any `json:"blockedBy"`
+		Blocks    []any `json:"blocks"`
+	}{
+		Blocked:   d.Blocked,
+		BlockedBy: jsonenc.CompactSlice(d.BlockedBy),
+		Blocks:    jsonenc.CompactSlice(d.Blocks),
+	}
+}
+
+// Compact implements jsonenc.Compacter, keeping where the results came from
+func (r *SearchResponse) Compact() any {
+	// The page's fields are spelled out, as embedding the page would promote
+	// its encoder and drop Meta
+	return &struct {
+		Data       []any      `json:"data"`
+		Page       int        `json:"page"`
+		Limit      int        `json:"limit"`
+		Total      int        `json:"total"`
+		TotalPages int        `json:"totalPages"`
+		Meta       SearchMeta `json:"meta"`
+	}{
+		Data:       jsonenc.CompactSlice(r.Data),
+		Page:       r.Page,
+		Limit:      r.Limit,
+		Total:      r.Total,
+		TotalPages: r.TotalPages,
+		Meta:       r.Meta,
+	}
+}
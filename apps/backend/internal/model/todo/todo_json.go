@@ -0,0 +1,185 @@
+//go:build fastjson
+
+package todo
+
+import (
+	"strconv"
+
+	"github.com/mabhi256/tasker/internal/lib/content"
+	"github.com/mabhi256/tasker/internal/lib/jsonenc"
+	"github.com/mabhi256/tasker/internal/model"
+)
+
+func (t *Todo) AppendJSON(dst []byte) ([]byte, error) {
+	dst = append(dst, '{')
+	dst = t.appendFields(dst)
+	return append(dst, '}'), nil
+}
+
+// appendFields writes the fields of Todo without braces, so PopulatedTodo and
+// DependentTodo can add their own after them
+func (t *Todo) appendFields(dst []byte) []byte {
+	dst = model.AppendBase(dst, &t.Base)
+	dst = append(dst, `,"workspaceId":`...)
+	dst = jsonenc.AppendUUID(dst, t.WorkspaceID)
+	dst = append(dst, `,"userId":`...)
+	dst = jsonenc.AppendString(dst, t.UserID)
+	dst = append(dst, `,"title":`...)
+	dst = jsonenc.AppendString(dst, t.Title)
+	dst = append(dst, `,"description":`...)
+	dst = jsonenc.AppendStringPtr(dst, t.Description)
+	dst = append(dst, `,"status":`...)
+	dst = jsonenc.AppendString(dst, string(t.Status))
+	dst = append(dst, `,"priority":`...)
+	dst = jsonenc.AppendString(dst, string(t.Priority))
+	dst = append(dst, `,"dueDate":`...)
+	dst = jsonenc.AppendTimePtr(dst, t.DueDate)
+	dst = append(dst, `,"completedAt":`...)
+	dst = jsonenc.AppendTimePtr(dst, t.CompletedAt)
+	dst = append(dst, `,"parentTodoId":`...)
+	dst = jsonenc.AppendUUIDPtr(dst, t.ParentTodoID)
+	dst = append(dst, `,"categoryId":`...)
+	dst = jsonenc.AppendUUIDPtr(dst, t.CategoryID)
+	dst = append(dst, `,"metadata":`...)
+	dst = t.Metadata.appendJSON(dst)
+	dst = append(dst, `,"sortOrder":`...)
+	dst = jsonenc.AppendInt(dst, int64(t.SortOrder))
+	dst = append(dst, `,"reminderMinutes":`...)
+	dst = jsonenc.AppendIntPtr(dst, t.ReminderMinutes)
+	dst = append(dst, `,"publishAt":`...)
+	dst = jsonenc.AppendTimePtr(dst, t.PublishAt)
+	if t.DescriptionHTML != nil {
+		dst = append(dst, `,"descriptionHtml":`...)
+		dst = jsonenc.AppendStringPtr(dst, t.DescriptionHTML)
+	}
+	if len(t.Checklist) > 0 {
+		dst = append(dst, `,"checklist":`...)
+		dst = appendChecklist(dst, t.Checklist)
+	}
+	return dst
+}
+
+func appendChecklist(dst []byte, items []content.ChecklistItem) []byte {
+	dst = append(dst, '[')
+	for i := range items {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = append(dst, `{"index":`...)
+		dst = jsonenc.AppendInt(dst, int64(items[i].Index))
+		dst = append(dst, `,"text":`...)
+		dst = jsonenc.AppendString(dst, items[i].Text)
+		dst = append(dst, `,"checked":`...)
+		dst = strconv.AppendBool(dst, items[i].Checked)
+		dst = append(dst, '}')
+	}
+	return append(dst, ']')
+}
+
+func (m *Metadata) appendJSON(dst []byte) []byte {
+	if m == nil {
+		return append(dst, "null"...)
+	}
+
+	dst = append(dst, `{"tags":`...)
+	dst = jsonenc.AppendStrings(dst, m.Tags)
+	dst = append(dst, `,"reminder":`...)
+	dst = jsonenc.AppendStringPtr(dst, m.Reminder)
+	dst = append(dst, `,"color":`...)
+	dst = jsonenc.AppendStringPtr(dst, m.Color)
+	dst = append(dst, `,"difficulty":`...)
+	dst = jsonenc.AppendIntPtr(dst, m.Difficulty)
+	return append(dst, '}')
+}
+
+func (t *DependentTodo) AppendJSON(dst []byte) ([]byte, error) {
+	dst = append(dst, '{')
+	dst = t.appendFields(dst)
+	dst = append(dst, `,"blocked":`...)
+	dst = strconv.AppendBool(dst, t.Blocked)
+	return append(dst, '}'), nil
+}
+
+func (t *PopulatedTodo) AppendJSON(dst []byte) ([]byte, error) {
+	dst = append(dst, '{')
+	dst = t.appendFields(dst)
+
+	dst = append(dst, `,"category":`...)
+	dst, err := jsonenc.AppendValue(dst, t.Category)
+	if err != nil {
+		return nil, err
+	}
+	dst = append(dst, `,"children":`...)
+	if dst, err = jsonenc.AppendSlice(dst, t.Children); err != nil {
+		return nil, err
+	}
+	dst = append(dst, `,"comments":`...)
+	if dst, err = jsonenc.AppendSlice(dst, t.Comments); err != nil {
+		return nil, err
+	}
+	dst = append(dst, `,"attachments":`...)
+	if dst, err = jsonenc.AppendSlice(dst, t.Attachments); err != nil {
+		return nil, err
+	}
+
+	return append(dst, '}'), nil
+}
+
+// AppendJSON writes the page with meta added as its last field
+func (r *SearchResponse) AppendJSON(dst []byte) ([]byte, error) {
+	dst, err := r.PaginatedResponse.AppendJSON(dst)
+	if err != nil {
+		return nil, err
+	}
+
+	dst = append(dst[:len(dst)-1], `,"meta":{"engine":`...)
+	dst = jsonenc.AppendString(dst, string(r.Meta.Engine))
+	dst = append(dst, `,"degraded":`...)
+	dst = strconv.AppendBool(dst, r.Meta.Degraded)
+	return append(dst, "}}"...), nil
+}
+
+func (a *TodoAttachment) AppendJSON(dst []byte) ([]byte, error) {
+	dst = append(dst, '{')
+	dst = model.AppendBase(dst, &a.Base)
+	dst = append(dst, `,"todoId":`...)
+	dst = jsonenc.AppendUUID(dst, a.TodoID)
+	dst = append(dst, `,"workspaceId":`...)
+	dst = jsonenc.AppendUUID(dst, a.WorkspaceID)
+	dst = append(dst, `,"name":`...)
+	dst = jsonenc.AppendString(dst, a.Name)
+	dst = append(dst, `,"uploadedBy":`...)
+	dst = jsonenc.AppendString(dst, a.UploadedBy)
+	dst = append(dst, `,"downloadKey":`...)
+	dst = jsonenc.AppendString(dst, a.DownloadKey)
+	dst = append(dst, `,"fileSize":`...)
+	dst = jsonenc.AppendIntPtr(dst, a.FileSize)
+	dst = append(dst, `,"mimeType":`...)
+	dst = jsonenc.AppendStringPtr(dst, a.MimeType)
+	dst = append(dst, `,"checksumSha256":`...)
+	dst = jsonenc.AppendStringPtr(dst, a.ChecksumSHA256)
+	dst = append(dst, `,"integrityStatus":`...)
+	dst = jsonenc.AppendString(dst, string(a.IntegrityStatus))
+	dst = append(dst, `,"integrityCheckedAt":`...)
+	dst = jsonenc.AppendTimePtr(dst, a.IntegrityCheckedAt)
+	dst = append(dst, `,"preview":`...)
+	dst, err := jsonenc.AppendValue(dst, a.Preview)
+	if err != nil {
+		return nil, err
+	}
+	dst = append(dst, `,"previewExtractedAt":`...)
+	dst = jsonenc.AppendTimePtr(dst, a.PreviewExtractedAt)
+	dst = append(dst, `,"processingStatus":`...)
+	dst = jsonenc.AppendString(dst, string(a.ProcessingStatus))
+	dst = append(dst, `,"processingError":`...)
+	dst = jsonenc.AppendStringPtr(dst, a.ProcessingError)
+	dst = append(dst, `,"processedAt":`...)
+	dst = jsonenc.AppendTimePtr(dst, a.ProcessedAt)
+	dst = append(dst, `,"scanStatus":`...)
+	dst = jsonenc.AppendString(dst, string(a.ScanStatus))
+	dst = append(dst, `,"scanSignature":`...)
+	dst = jsonenc.AppendStringPtr(dst, a.ScanSignature)
+	dst = append(dst, `,"scannedAt":`...)
+	dst = jsonenc.AppendTimePtr(dst, a.ScannedAt)
+	return append(dst, '}'), nil
+}
@@ -0,0 +1,24 @@
+package trial
+
+import (
+	"github.com/go-playground/validator/v10"
+)
+
+// ------------------------------------------------------------
+
+type StartTrialPayload struct{}
+
+func (p *StartTrialPayload) Validate() error {
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type ClaimTrialPayload struct {
+	Token string `json:"token" validate:"required,startswith=tasker_trial_"`
+}
+
+func (p *ClaimTrialPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
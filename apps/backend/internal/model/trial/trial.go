@@ -0,0 +1,50 @@
+package trial
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/model"
+	"github.com/mabhi256/tasker/internal/model/workspace"
+)
+
+const (
+	// Prefix starts every trial token, so that it can be told apart from
+	// session tokens and API keys in the Authorization header
+	Prefix = "tasker_trial_"
+	// UserIDPrefix starts the ids of the users trials act as, which the
+	// auth provider doesn't know
+	UserIDPrefix = "trial_"
+)
+
+// IsUser reports whether userID is a trial's rather than a signed up user's
+func IsUser(userID string) bool {
+	return strings.HasPrefix(userID, UserIDPrefix)
+}
+
+// Trial is an anonymous trial. It acts as UserID in its workspace until it
+// expires or is claimed.
+type Trial struct {
+	model.BaseWithId
+	model.BaseWithCreatedAt
+	UserID      string     `json:"userId" db:"user_id"`
+	WorkspaceID uuid.UUID  `json:"workspaceId" db:"workspace_id"`
+	TokenHash   string     `json:"-" db:"token_hash"`
+	ExpiresAt   time.Time  `json:"expiresAt" db:"expires_at"`
+	ClaimedBy   *string    `json:"claimedBy" db:"claimed_by"`
+	ClaimedAt   *time.Time `json:"claimedAt" db:"claimed_at"`
+}
+
+// TrialWithToken is returned when a trial starts, the only time its token
+// is shown
+type TrialWithToken struct {
+	Trial
+	Token string `json:"token"`
+}
+
+// Claimed is the workspace a claimed trial handed to the user. It becomes
+// their personal workspace unless they already have one.
+type Claimed struct {
+	Workspace workspace.Workspace `json:"workspace"`
+}
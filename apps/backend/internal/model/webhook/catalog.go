@@ -0,0 +1,126 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/model"
+	"github.com/mabhi256/tasker/internal/model/comment"
+	"github.com/mabhi256/tasker/internal/model/tag"
+	"github.com/mabhi256/tasker/internal/model/todo"
+)
+
+// EventDefinition documents an event type. Example is a sample of the
+// entity sent as the event's data, and its type is what the catalog
+// describes.
+type EventDefinition struct {
+	Type        EventType
+	Description string
+	Example     any
+}
+
+var (
+	exampleWorkspaceID = uuid.MustParse("3f2c1e6a-8d4b-4c1a-9e7f-2b5d8a6c4e10")
+	exampleTodoID      = uuid.MustParse("123e4567-e89b-12d3-a456-426614174000")
+	exampleCreatedAt   = time.Date(2025, 1, 10, 9, 30, 0, 0, time.UTC)
+	exampleDueDate     = time.Date(2025, 1, 12, 17, 0, 0, 0, time.UTC)
+	exampleDescription = "Summarize revenue and churn for the board"
+)
+
+func exampleTodo(status todo.Status, completedAt *time.Time) todo.Todo {
+	return todo.Todo{
+		Base: model.Base{
+			BaseWithId:        model.BaseWithId{ID: exampleTodoID},
+			BaseWithCreatedAt: model.BaseWithCreatedAt{CreatedAt: exampleCreatedAt},
+			BaseWithUpdatedAt: model.BaseWithUpdatedAt{UpdatedAt: exampleCreatedAt},
+		},
+		WorkspaceID: exampleWorkspaceID,
+		UserID:      "user_2abc123def456",
+		Title:       "Finish the quarterly report",
+		Description: &exampleDescription,
+		Status:      status,
+		Priority:    todo.PriorityHigh,
+		DueDate:     &exampleDueDate,
+		CompletedAt: completedAt,
+		Metadata: &todo.Metadata{
+			Tags: []string{"finance"},
+		},
+	}
+}
+
+// EventDefinitions lists every event type that is published, in the order
+// the catalog shows them
+var EventDefinitions = []EventDefinition{
+	{
+		Type:        EventTodoCreated,
+		Description: "A todo was created, or a scheduled todo was published. The data is the new todo.",
+		Example:     exampleTodo(todo.StatusActive, nil),
+	},
+	{
+		Type:        EventTodoCompleted,
+		Description: "A todo was marked completed. The data is the todo after the update.",
+		Example:     exampleTodo(todo.StatusCompleted, &exampleDueDate),
+	},
+	{
+		Type:        EventCommentAdded,
+		Description: "A comment was added to a todo. The data is the new comment.",
+		Example: comment.Comment{
+			Base: model.Base{
+				BaseWithId:        model.BaseWithId{ID: uuid.MustParse("9b1deb4d-3b7d-4bad-9bdd-2b0d7b3dcb6d")},
+				BaseWithCreatedAt: model.BaseWithCreatedAt{CreatedAt: exampleCreatedAt},
+				BaseWithUpdatedAt: model.BaseWithUpdatedAt{UpdatedAt: exampleCreatedAt},
+			},
+			WorkspaceID: exampleWorkspaceID,
+			TodoID:      exampleTodoID,
+			UserID:      "user_2abc123def456",
+			Content:     "Draft is in the shared folder",
+		},
+	},
+	{
+		Type: EventTagRenamed,
+		Description: "A tag was renamed on every todo of the workspace. The data names the old and new tag " +
+			"and lists the todos that were relabelled.",
+		Example: tag.Change{
+			From:    "finance",
+			To:      "budget",
+			TodoIDs: []uuid.UUID{exampleTodoID},
+		},
+	},
+	{
+		Type: EventTagMerged,
+		Description: "A tag was merged into another on every todo of the workspace. The data is as for " +
+			"tag.renamed, and counts the todos that had both tags and now list the target once.",
+		Example: tag.Change{
+			From:         "finances",
+			To:           "finance",
+			Merged:       true,
+			TodoIDs:      []uuid.UUID{exampleTodoID},
+			Deduplicated: 1,
+		},
+	},
+}
+
+// ExampleEvent wraps the definition's example in the envelope posted to
+// webhook endpoints
+func (d *EventDefinition) ExampleEvent() Event {
+	return Event{
+		ID:          uuid.MustParse("c56a4180-65aa-42ec-a945-5fd21dec0538"),
+		Type:        d.Type,
+		CreatedAt:   exampleCreatedAt,
+		WorkspaceID: exampleWorkspaceID,
+		Data:        d.Example,
+	}
+}
+
+// CatalogEvent describes an event type for webhook and SDK consumers.
+// Schema is the JSON Schema of the whole event as posted.
+type CatalogEvent struct {
+	Type        EventType `json:"type"`
+	Description string    `json:"description"`
+	Schema      any       `json:"schema"`
+	Example     Event     `json:"example"`
+}
+
+type EventCatalog struct {
+	Events []CatalogEvent `json:"events"`
+}
@@ -0,0 +1,123 @@
+package webhook
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// ------------------------------------------------------------
+
+type CreateWebhookPayload struct {
+	URL         string      `json:"url" validate:"required,url,max=2048"`
+	Description *string     `json:"description" validate:"omitempty,max=255"`
+	Events      []EventType `json:"events" validate:"required,min=1,unique,dive,oneof=todo.created todo.completed comment.added tag.renamed tag.merged"`
+	Enabled     *bool       `json:"enabled"`
+}
+
+func (p *CreateWebhookPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type GetWebhooksPayload struct{}
+
+func (p *GetWebhooksPayload) Validate() error {
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type GetWebhookByIDPayload struct {
+	ID uuid.UUID `param:"id" validate:"required,uuid"`
+}
+
+func (p *GetWebhookByIDPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type UpdateWebhookPayload struct {
+	ID          uuid.UUID   `param:"id" validate:"required,uuid"`
+	URL         *string     `json:"url" validate:"omitempty,url,max=2048"`
+	Description *string     `json:"description" validate:"omitempty,max=255"`
+	Events      []EventType `json:"events" validate:"omitempty,min=1,unique,dive,oneof=todo.created todo.completed comment.added tag.renamed tag.merged"`
+	Enabled     *bool       `json:"enabled"`
+}
+
+func (p *UpdateWebhookPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type DeleteWebhookPayload struct {
+	ID uuid.UUID `param:"id" validate:"required,uuid"`
+}
+
+func (p *DeleteWebhookPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type RotateSecretPayload struct {
+	ID uuid.UUID `param:"id" validate:"required,uuid"`
+}
+
+func (p *RotateSecretPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type GetDeliveriesQuery struct {
+	ID     uuid.UUID `param:"id" validate:"required,uuid"`
+	Status *string   `query:"status" validate:"omitempty,oneof=pending succeeded failed"`
+	Page   *int      `query:"page" validate:"omitempty,min=1"`
+	Limit  *int      `query:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+func (q *GetDeliveriesQuery) Validate() error {
+	validate := validator.New()
+	if err := validate.Struct(q); err != nil {
+		return err
+	}
+
+	if q.Page == nil {
+		defaultPage := 1
+		q.Page = &defaultPage
+	}
+	if q.Limit == nil {
+		defaultLimit := 20
+		q.Limit = &defaultLimit
+	}
+
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type RedeliverPayload struct {
+	ID         uuid.UUID `param:"id" validate:"required,uuid"`
+	DeliveryID uuid.UUID `param:"deliveryId" validate:"required,uuid"`
+}
+
+func (p *RedeliverPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type GetEventCatalogPayload struct{}
+
+func (p *GetEventCatalogPayload) Validate() error {
+	return nil
+}
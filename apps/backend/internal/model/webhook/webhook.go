@@ -0,0 +1,83 @@
+package webhook
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/model"
+)
+
+type EventType string
+
+const (
+	EventTodoCreated   EventType = "todo.created"
+	EventTodoCompleted EventType = "todo.completed"
+	EventCommentAdded  EventType = "comment.added"
+	EventTagRenamed    EventType = "tag.renamed"
+	EventTagMerged     EventType = "tag.merged"
+)
+
+type DeliveryStatus string
+
+const (
+	DeliveryStatusPending   DeliveryStatus = "pending"
+	DeliveryStatusSucceeded DeliveryStatus = "succeeded"
+	DeliveryStatusFailed    DeliveryStatus = "failed"
+)
+
+type Webhook struct {
+	model.Base
+	WorkspaceID uuid.UUID   `json:"workspaceId" db:"workspace_id"`
+	CreatedBy   string      `json:"createdBy" db:"created_by"`
+	URL         string      `json:"url" db:"url"`
+	Description *string     `json:"description" db:"description"`
+	Events      []EventType `json:"events" db:"events"`
+	Secret      string      `json:"-" db:"secret"`
+	Enabled     bool        `json:"enabled" db:"enabled"`
+}
+
+func (w *Webhook) Subscribes(eventType EventType) bool {
+	return slices.Contains(w.Events, eventType)
+}
+
+// WebhookWithSecret is returned when a secret is created or rotated, the
+// only times it is shown
+type WebhookWithSecret struct {
+	Webhook
+	Secret string `json:"secret"`
+}
+
+type Delivery struct {
+	model.Base
+	WebhookID      uuid.UUID       `json:"webhookId" db:"webhook_id"`
+	EventID        uuid.UUID       `json:"eventId" db:"event_id"`
+	EventType      EventType       `json:"eventType" db:"event_type"`
+	Payload        json.RawMessage `json:"payload" db:"payload"`
+	Status         DeliveryStatus  `json:"status" db:"status"`
+	Attempts       int             `json:"attempts" db:"attempts"`
+	ResponseStatus *int            `json:"responseStatus" db:"response_status"`
+	ResponseBody   *string         `json:"responseBody" db:"response_body"`
+	Error          *string         `json:"error" db:"error"`
+	DurationMS     *int            `json:"durationMs" db:"duration_ms"`
+	LastAttemptAt  *time.Time      `json:"lastAttemptAt" db:"last_attempt_at"`
+	DeliveredAt    *time.Time      `json:"deliveredAt" db:"delivered_at"`
+}
+
+// Event is the JSON body posted to webhook endpoints
+type Event struct {
+	ID          uuid.UUID `json:"id"`
+	Type        EventType `json:"type"`
+	CreatedAt   time.Time `json:"createdAt"`
+	WorkspaceID uuid.UUID `json:"workspaceId"`
+	Data        any       `json:"data"`
+}
+
+// Attempt is the outcome of a single delivery attempt
+type Attempt struct {
+	ResponseStatus *int
+	ResponseBody   *string
+	Error          *string
+	Duration       time.Duration
+}
@@ -0,0 +1,108 @@
+// Package weeklyreport summarizes a user's week of todos across workspaces.
+// Weeks run Monday to Monday in the user's timezone, which is their digest
+// subscription's or, without one, the scheduler's.
+package weeklyreport
+
+import (
+	"time"
+)
+
+const (
+	// MaxCategories caps the categories a report ranks
+	MaxCategories = 3
+	// StreakWindow is how far back a streak is counted
+	StreakWindow = 90 * 24 * time.Hour
+)
+
+// Recipient is a user due a report, with the timezone it is sent in
+type Recipient struct {
+	UserID   string `db:"user_id"`
+	Timezone string `db:"timezone"`
+}
+
+// Category is a category the user completed todos in during the week
+type Category struct {
+	Name           string `db:"name"`
+	Color          string `db:"color"`
+	CompletedCount int    `db:"completed_count"`
+}
+
+// Counts are the user's todos created and completed during the week, and
+// those still open, of which OverdueCount were due before it ended
+type Counts struct {
+	CreatedCount   int `db:"created_count"`
+	CompletedCount int `db:"completed_count"`
+	ActiveCount    int `db:"active_count"`
+	OverdueCount   int `db:"overdue_count"`
+}
+
+// Report is a user's week. WeekEnd is the start of the Monday after it.
+type Report struct {
+	Counts
+	WeekStart     time.Time
+	WeekEnd       time.Time
+	TopCategories []Category
+	// ActiveDays counts the days of the week the user completed a todo on
+	ActiveDays int
+	// Streak counts the consecutive days the user completed a todo on,
+	// back from the week's last such day
+	Streak int
+}
+
+// Empty reports whether the user has nothing to hear about
+func (r *Report) Empty() bool {
+	return r.CreatedCount == 0 && r.CompletedCount == 0 && r.ActiveCount == 0
+}
+
+// CompletionRate is the percent of the user's workload they completed during
+// the week, counting the todos still open as the rest of it
+func (r *Report) CompletionRate() int {
+	total := r.CompletedCount + r.ActiveCount
+	if total == 0 {
+		return 0
+	}
+	return r.CompletedCount * 100 / total
+}
+
+// Week returns the week before the one at is in, on the wall clock of at's
+// location
+func Week(at time.Time) (start, end time.Time) {
+	day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, at.Location())
+	// Days since Monday
+	offset := (int(day.Weekday()) + 6) % 7
+	end = day.AddDate(0, 0, -offset)
+	return end.AddDate(0, 0, -7), end
+}
+
+// Due reports whether it is hour on a Monday in loc, when reports are sent.
+// The scheduler runs hourly, so each timezone is due once a week.
+func Due(now time.Time, loc *time.Location, hour int) bool {
+	local := now.In(loc)
+	return local.Weekday() == time.Monday && local.Hour() == hour
+}
+
+// Streaks returns the active days and streak of a week from the dates, newest
+// first, the user completed todos on up to its end. Dates are at midnight
+// UTC, as Postgres returns them.
+func Streaks(days []time.Time, weekStart time.Time) (activeDays, streak int) {
+	first := time.Date(weekStart.Year(), weekStart.Month(), weekStart.Day(), 0, 0, 0, 0, time.UTC)
+
+	for i, day := range days {
+		if day.Before(first) {
+			break
+		}
+		activeDays++
+		if i == streak && (i == 0 || day.Equal(days[i-1].AddDate(0, 0, -1))) {
+			streak++
+		}
+	}
+	if activeDays == 0 {
+		return 0, 0
+	}
+
+	// A streak runs on into the days before the week
+	for i := streak; i < len(days) && days[i].Equal(days[i-1].AddDate(0, 0, -1)); i++ {
+		streak++
+	}
+	return activeDays, streak
+}
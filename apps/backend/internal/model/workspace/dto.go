@@ -0,0 +1,115 @@
+package workspace
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// ------------------------------------------------------------
+
+type CreateWorkspacePayload struct {
+	Name        string  `json:"name" validate:"required,min=1,max=100"`
+	Description *string `json:"description" validate:"omitempty,max=255"`
+}
+
+func (p *CreateWorkspacePayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type GetWorkspacesPayload struct{}
+
+func (p *GetWorkspacesPayload) Validate() error {
+	return nil
+}
+
+// ------------------------------------------------------------
+
+type GetWorkspaceByIDPayload struct {
+	ID uuid.UUID `param:"workspaceId" validate:"required,uuid"`
+}
+
+func (p *GetWorkspaceByIDPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type UpdateWorkspacePayload struct {
+	ID          uuid.UUID `param:"workspaceId" validate:"required,uuid"`
+	Name        *string   `json:"name" validate:"omitempty,min=1,max=100"`
+	Description *string   `json:"description" validate:"omitempty,max=255"`
+	// EmailTracking opts the workspace's members in or out of email
+	// tracking
+	EmailTracking *bool `json:"emailTracking"`
+}
+
+func (p *UpdateWorkspacePayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type DeleteWorkspacePayload struct {
+	ID uuid.UUID `param:"workspaceId" validate:"required,uuid"`
+}
+
+func (p *DeleteWorkspacePayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+// Membership DTOs
+// ------------------------------------------------------------
+
+type GetMembersPayload struct {
+	WorkspaceID uuid.UUID `param:"workspaceId" validate:"required,uuid"`
+}
+
+func (p *GetMembersPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type AddMemberPayload struct {
+	WorkspaceID uuid.UUID `param:"workspaceId" validate:"required,uuid"`
+	UserID      string    `json:"userId" validate:"required,min=1"`
+	Role        *Role     `json:"role" validate:"omitempty,oneof=admin member viewer"`
+}
+
+func (p *AddMemberPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type UpdateMemberPayload struct {
+	WorkspaceID uuid.UUID `param:"workspaceId" validate:"required,uuid"`
+	UserID      string    `param:"userId" validate:"required,min=1"`
+	Role        Role      `json:"role" validate:"required,oneof=admin member viewer"`
+}
+
+func (p *UpdateMemberPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+// ------------------------------------------------------------
+
+type RemoveMemberPayload struct {
+	WorkspaceID uuid.UUID `param:"workspaceId" validate:"required,uuid"`
+	UserID      string    `param:"userId" validate:"required,min=1"`
+}
+
+func (p *RemoveMemberPayload) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
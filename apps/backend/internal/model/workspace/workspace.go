@@ -0,0 +1,59 @@
+package workspace
+
+import (
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/model"
+)
+
+type Role string
+
+const (
+	RoleOwner  Role = "owner"
+	RoleAdmin  Role = "admin"
+	RoleMember Role = "member"
+	RoleViewer Role = "viewer"
+)
+
+func (r Role) rank() int {
+	switch r {
+	case RoleOwner:
+		return 4
+	case RoleAdmin:
+		return 3
+	case RoleMember:
+		return 2
+	case RoleViewer:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// AtLeast reports whether r grants at least the permissions of min
+func (r Role) AtLeast(min Role) bool {
+	return r.rank() >= min.rank()
+}
+
+type Workspace struct {
+	model.Base
+	Name        string  `json:"name" db:"name"`
+	Description *string `json:"description" db:"description"`
+	OwnerID     string  `json:"ownerId" db:"owner_id"`
+	IsPersonal  bool    `json:"isPersonal" db:"is_personal"`
+	// EmailTracking allows tracking the opens and clicks of the emails sent
+	// to members
+	EmailTracking bool `json:"emailTracking" db:"email_tracking"`
+}
+
+type WorkspaceWithRole struct {
+	Workspace
+	Role Role `json:"role" db:"role"`
+}
+
+type Member struct {
+	model.BaseWithCreatedAt
+	model.BaseWithUpdatedAt
+	WorkspaceID uuid.UUID `json:"workspaceId" db:"workspace_id"`
+	UserID      string    `json:"userId" db:"user_id"`
+	Role        Role      `json:"role" db:"role"`
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/mabhi256/tasker/internal/model"
+	"github.com/mabhi256/tasker/internal/model/activity"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// ActivityRepository reads the activity feeds of workspaces from the event
+// outbox, so feeds go back as far as published events are kept
+type ActivityRepository struct {
+	server *server.Server
+}
+
+func NewActivityRepository(server *server.Server) *ActivityRepository {
+	return &ActivityRepository{server: server}
+}
+
+// GetWorkspaceEvents returns a page of the workspace's events of the given
+// types, newest first
+func (r *ActivityRepository) GetWorkspaceEvents(ctx context.Context, workspaceID uuid.UUID, eventTypes []string,
+	query *activity.GetFeedQuery,
+) (*model.PaginatedResponse[activity.Event], error) {
+	args := pgx.NamedArgs{
+		"workspace_id": workspaceID,
+		"event_types":  eventTypes,
+		"limit":        *query.Limit,
+		"offset":       (*query.Page - 1) * (*query.Limit),
+	}
+
+	var total int
+	err := r.server.DB.Conn(ctx).QueryRow(ctx, `
+		SELECT
+			COUNT(*)
+		FROM
+			event_outbox
+		WHERE
+			workspace_id=@workspace_id
+			AND event_type=ANY(@event_types)
+	`, args).Scan(&total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get total count of events for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, `
+		SELECT
+			e.id,
+			e.created_at,
+			e.event_type,
+			e.actor_id,
+			e.payload,
+			t.title AS todo_title
+		FROM
+			event_outbox e
+			LEFT JOIN todos t ON t.id=(e.payload->>'todoId')::UUID
+			AND t.workspace_id=e.workspace_id
+		WHERE
+			e.workspace_id=@workspace_id
+			AND e.event_type=ANY(@event_types)
+		ORDER BY
+			e.created_at DESC,
+			e.id DESC
+		LIMIT @limit OFFSET @offset
+	`, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get workspace events query for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	events, err := pgx.CollectRows(rows, pgx.RowToStructByName[activity.Event])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			events = []activity.Event{}
+		} else {
+			return nil, fmt.Errorf("failed to collect rows from table:event_outbox for workspace_id=%s: %w", workspaceID.String(), err)
+		}
+	}
+
+	return &model.PaginatedResponse[activity.Event]{
+		Data:       events,
+		Page:       *query.Page,
+		Limit:      *query.Limit,
+		Total:      total,
+		TotalPages: (total + *query.Limit - 1) / *query.Limit,
+	}, nil
+}
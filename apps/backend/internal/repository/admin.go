@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mabhi256/tasker/internal/model"
+	"github.com/mabhi256/tasker/internal/model/admin"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+type AdminRepository struct {
+	server *server.Server
+}
+
+func NewAdminRepository(server *server.Server) *AdminRepository {
+	return &AdminRepository{server: server}
+}
+
+// GetUsers lists every user that owns data in the system. Users live in Clerk,
+// so the list is derived from the user_id columns of the owned tables.
+func (r *AdminRepository) GetUsers(ctx context.Context, query *admin.GetUsersQuery) (*model.PaginatedResponse[admin.UserSummary], error) {
+	usersCTE := `
+		WITH
+			users AS (
+				SELECT user_id FROM todos
+				UNION
+				SELECT user_id FROM todo_categories
+				UNION
+				SELECT user_id FROM todo_comments
+			)
+	`
+
+	args := pgx.NamedArgs{}
+	condition := ""
+	if query.Search != nil {
+		condition = " WHERE u.user_id ILIKE @search"
+		args["search"] = "%" + *query.Search + "%"
+	}
+
+	var total int
+	err := r.server.DB.Conn(ctx).QueryRow(ctx, usersCTE+"SELECT COUNT(*) FROM users u"+condition, args).Scan(&total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get total count of users: %w", err)
+	}
+
+	stmt := usersCTE + `
+		SELECT
+			u.user_id,
+			(SELECT COUNT(*) FROM todos t WHERE t.user_id=u.user_id) AS todo_count,
+			(SELECT COUNT(*) FROM todos t WHERE t.user_id=u.user_id AND t.status='completed') AS completed_count,
+			(SELECT COUNT(*) FROM todo_categories c WHERE c.user_id=u.user_id) AS category_count,
+			(SELECT COUNT(*) FROM todo_comments com WHERE com.user_id=u.user_id) AS comment_count,
+			GREATEST(
+				(SELECT MAX(t.updated_at) FROM todos t WHERE t.user_id=u.user_id),
+				(SELECT MAX(com.updated_at) FROM todo_comments com WHERE com.user_id=u.user_id)
+			) AS last_activity_at
+		FROM
+			users u
+	` + condition + `
+		ORDER BY
+			last_activity_at DESC NULLS LAST,
+			u.user_id ASC
+		LIMIT @limit OFFSET @offset
+	`
+	args["limit"] = *query.Limit
+	args["offset"] = (*query.Page - 1) * (*query.Limit)
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get users query: %w", err)
+	}
+
+	users, err := pgx.CollectRows(rows, pgx.RowToStructByName[admin.UserSummary])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			users = []admin.UserSummary{}
+		} else {
+			return nil, fmt.Errorf("failed to collect rows for user summaries: %w", err)
+		}
+	}
+
+	return &model.PaginatedResponse[admin.UserSummary]{
+		Data:       users,
+		Page:       *query.Page,
+		Limit:      *query.Limit,
+		Total:      total,
+		TotalPages: (total + *query.Limit - 1) / *query.Limit,
+	}, nil
+}
+
+func (r *AdminRepository) GetAttachmentIntegritySummary(ctx context.Context) (*admin.AttachmentIntegritySummary, error) {
+	stmt := `
+		SELECT
+			COUNT(*) AS total,
+			COUNT(*) FILTER (WHERE integrity_status='unverified') AS unverified,
+			COUNT(*) FILTER (WHERE integrity_status='ok') AS ok,
+			COUNT(*) FILTER (WHERE integrity_status='missing') AS missing,
+			COUNT(*) FILTER (WHERE integrity_status='corrupt') AS corrupt,
+			MAX(integrity_checked_at) AS last_checked_at
+		FROM
+			todo_attachments
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get attachment integrity summary query: %w", err)
+	}
+
+	summary, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[admin.AttachmentIntegritySummary])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:todo_attachments: %w", err)
+	}
+
+	return &summary, nil
+}
+
+// GetAttachmentIntegrityIssues lists attachments flagged missing or corrupt,
+// most recently checked first
+func (r *AdminRepository) GetAttachmentIntegrityIssues(ctx context.Context,
+	query *admin.GetAttachmentIntegrityIssuesQuery,
+) (*model.PaginatedResponse[admin.AttachmentIntegrityIssue], error) {
+	args := pgx.NamedArgs{}
+	condition := " WHERE att.integrity_status IN ('missing', 'corrupt')"
+	if query.Status != nil {
+		condition = " WHERE att.integrity_status=@status"
+		args["status"] = *query.Status
+	}
+
+	var total int
+	err := r.server.DB.Conn(ctx).QueryRow(ctx, "SELECT COUNT(*) FROM todo_attachments att"+condition, args).Scan(&total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get total count of attachment integrity issues: %w", err)
+	}
+
+	stmt := `
+		SELECT
+			att.id AS attachment_id,
+			att.todo_id,
+			t.workspace_id,
+			att.name,
+			att.download_key,
+			att.file_size,
+			att.checksum_sha256,
+			att.integrity_status,
+			att.integrity_error,
+			att.integrity_checked_at,
+			att.created_at AS uploaded_at
+		FROM
+			todo_attachments att
+			JOIN todos t ON t.id=att.todo_id
+	` + condition + `
+		ORDER BY
+			att.integrity_checked_at DESC,
+			att.id ASC
+		LIMIT @limit OFFSET @offset
+	`
+	args["limit"] = *query.Limit
+	args["offset"] = (*query.Page - 1) * (*query.Limit)
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get attachment integrity issues query: %w", err)
+	}
+
+	issues, err := pgx.CollectRows(rows, pgx.RowToStructByName[admin.AttachmentIntegrityIssue])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			issues = []admin.AttachmentIntegrityIssue{}
+		} else {
+			return nil, fmt.Errorf("failed to collect rows from table:todo_attachments: %w", err)
+		}
+	}
+
+	return &model.PaginatedResponse[admin.AttachmentIntegrityIssue]{
+		Data:       issues,
+		Page:       *query.Page,
+		Limit:      *query.Limit,
+		Total:      total,
+		TotalPages: (total + *query.Limit - 1) / *query.Limit,
+	}, nil
+}
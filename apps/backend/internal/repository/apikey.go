@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/model/apikey"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+type APIKeyRepository struct {
+	server *server.Server
+}
+
+func NewAPIKeyRepository(server *server.Server) *APIKeyRepository {
+	return &APIKeyRepository{server: server}
+}
+
+func (r *APIKeyRepository) CreateKey(ctx context.Context, userID string, payload *apikey.CreateKeyPayload,
+	keyHash string, hint string,
+) (*apikey.Key, error) {
+	stmt := `
+		INSERT INTO
+			api_keys (
+				user_id,
+				name,
+				scope,
+				key_hash,
+				hint
+			)
+		VALUES
+			(
+				@user_id,
+				@name,
+				@scope,
+				@key_hash,
+				@hint
+			)
+		RETURNING
+		*
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"user_id":  userID,
+		"name":     payload.Name,
+		"scope":    payload.Scope,
+		"key_hash": keyHash,
+		"hint":     hint,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute create api key query for user_id=%s: %w", userID, err)
+	}
+
+	key, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[apikey.Key])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:api_keys for user_id=%s: %w", userID, err)
+	}
+
+	return &key, nil
+}
+
+func (r *APIKeyRepository) GetKeys(ctx context.Context, userID string) ([]apikey.Key, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			api_keys
+		WHERE
+			user_id=@user_id
+		ORDER BY
+			created_at DESC,
+			id DESC
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"user_id": userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get api keys query for user_id=%s: %w", userID, err)
+	}
+
+	keys, err := pgx.CollectRows(rows, pgx.RowToStructByName[apikey.Key])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:api_keys for user_id=%s: %w", userID, err)
+	}
+
+	return keys, nil
+}
+
+// GetKeyByHash returns the key with the hash
+func (r *APIKeyRepository) GetKeyByHash(ctx context.Context, keyHash string) (*apikey.Key, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			api_keys
+		WHERE
+			key_hash=@key_hash
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"key_hash": keyHash,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get api key query: %w", err)
+	}
+
+	key, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[apikey.Key])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "API_KEY_NOT_FOUND"
+			return nil, errs.NewNotFoundError("API key not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:api_keys: %w", err)
+	}
+
+	return &key, nil
+}
+
+// TouchKey records that the key was used, unless it already was since
+// usedSince. Instances that resolve the key at the same time write it once.
+func (r *APIKeyRepository) TouchKey(ctx context.Context, keyID uuid.UUID, usedSince time.Time) error {
+	_, err := r.server.DB.Conn(ctx).Exec(ctx, `
+		UPDATE api_keys
+		SET
+			last_used_at = CURRENT_TIMESTAMP
+		WHERE
+			id = @id
+			AND (
+				last_used_at IS NULL
+				OR last_used_at < @used_since
+			)
+	`, pgx.NamedArgs{
+		"id":         keyID,
+		"used_since": usedSince,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to execute touch api key query for key_id=%s: %w", keyID.String(), err)
+	}
+
+	return nil
+}
+
+// DeleteKey revokes one of the user's keys. Keys are looked up on every
+// request, so it stops working immediately.
+func (r *APIKeyRepository) DeleteKey(ctx context.Context, userID string, keyID uuid.UUID) error {
+	result, err := r.server.DB.Conn(ctx).Exec(ctx, `
+		DELETE FROM api_keys
+		WHERE id = @id AND user_id = @user_id
+	`, pgx.NamedArgs{
+		"id":      keyID,
+		"user_id": userID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to execute delete api key query for key_id=%s: %w", keyID.String(), err)
+	}
+
+	if result.RowsAffected() == 0 {
+		code := "API_KEY_NOT_FOUND"
+		return errs.NewNotFoundError("API key not found", false, &code)
+	}
+
+	return nil
+}
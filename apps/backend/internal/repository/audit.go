@@ -0,0 +1,307 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/model/audit"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// AuditRepository stores audit forwarders. Forwarders are scoped by
+// workspace; a nil workspace is the global scope of the admin API.
+type AuditRepository struct {
+	server *server.Server
+}
+
+func NewAuditRepository(server *server.Server) *AuditRepository {
+	return &AuditRepository{server: server}
+}
+
+// forwarderScope names the scope of a forwarder in errors
+func forwarderScope(workspaceID *uuid.UUID) string {
+	if workspaceID == nil {
+		return "global"
+	}
+	return workspaceID.String()
+}
+
+func (r *AuditRepository) CreateForwarder(ctx context.Context, workspaceID *uuid.UUID, userID string,
+	payload *audit.CreateForwarderPayload,
+) (*audit.Forwarder, error) {
+	enabled := true
+	if payload.Enabled != nil {
+		enabled = *payload.Enabled
+	}
+
+	stmt := `
+		INSERT INTO
+			audit_forwarders (
+				workspace_id,
+				created_by,
+				name,
+				format,
+				endpoint,
+				token,
+				enabled
+			)
+		VALUES
+			(
+				@workspace_id,
+				@created_by,
+				@name,
+				@format,
+				@endpoint,
+				@token,
+				@enabled
+			)
+		RETURNING
+		*
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+		"created_by":   userID,
+		"name":         payload.Name,
+		"format":       string(payload.Format),
+		"endpoint":     payload.Endpoint,
+		"token":        payload.Token,
+		"enabled":      enabled,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute create audit forwarder query for workspace_id=%s: %w", forwarderScope(workspaceID), err)
+	}
+
+	forwarder, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[audit.Forwarder])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:audit_forwarders for workspace_id=%s: %w", forwarderScope(workspaceID), err)
+	}
+
+	return &forwarder, nil
+}
+
+func (r *AuditRepository) GetForwarders(ctx context.Context, workspaceID *uuid.UUID) ([]audit.Forwarder, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			audit_forwarders
+		WHERE
+			workspace_id IS NOT DISTINCT FROM @workspace_id
+		ORDER BY
+			created_at ASC,
+			id ASC
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get audit forwarders query for workspace_id=%s: %w", forwarderScope(workspaceID), err)
+	}
+
+	forwarders, err := pgx.CollectRows(rows, pgx.RowToStructByName[audit.Forwarder])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return []audit.Forwarder{}, nil
+		}
+		return nil, fmt.Errorf("failed to collect rows from table:audit_forwarders for workspace_id=%s: %w", forwarderScope(workspaceID), err)
+	}
+
+	return forwarders, nil
+}
+
+func (r *AuditRepository) GetForwarderByID(ctx context.Context, workspaceID *uuid.UUID,
+	forwarderID uuid.UUID,
+) (*audit.Forwarder, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			audit_forwarders
+		WHERE
+			id=@id
+			AND workspace_id IS NOT DISTINCT FROM @workspace_id
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"id":           forwarderID,
+		"workspace_id": workspaceID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get audit forwarder by id query for forwarder_id=%s workspace_id=%s: %w", forwarderID.String(), forwarderScope(workspaceID), err)
+	}
+
+	forwarder, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[audit.Forwarder])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "AUDIT_FORWARDER_NOT_FOUND"
+			return nil, errs.NewNotFoundError("audit forwarder not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:audit_forwarders for forwarder_id=%s workspace_id=%s: %w", forwarderID.String(), forwarderScope(workspaceID), err)
+	}
+
+	return &forwarder, nil
+}
+
+// GetForwarder loads a forwarder of any scope for sending
+func (r *AuditRepository) GetForwarder(ctx context.Context, forwarderID uuid.UUID) (*audit.Forwarder, error) {
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, `
+		SELECT
+			*
+		FROM
+			audit_forwarders
+		WHERE
+			id=@id
+	`, pgx.NamedArgs{
+		"id": forwarderID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get audit forwarder query for forwarder_id=%s: %w", forwarderID.String(), err)
+	}
+
+	forwarder, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[audit.Forwarder])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:audit_forwarders for forwarder_id=%s: %w", forwarderID.String(), err)
+	}
+
+	return &forwarder, nil
+}
+
+// GetReceivingForwarders returns the enabled forwarders that receive the
+// audit events of a workspace: its own and the global ones. Events without
+// a workspace only go to global forwarders.
+func (r *AuditRepository) GetReceivingForwarders(ctx context.Context, workspaceID *uuid.UUID) ([]audit.Forwarder, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			audit_forwarders
+		WHERE
+			enabled
+			AND (
+				workspace_id IS NULL
+				OR workspace_id=@workspace_id
+			)
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get receiving audit forwarders query for workspace_id=%s: %w", forwarderScope(workspaceID), err)
+	}
+
+	forwarders, err := pgx.CollectRows(rows, pgx.RowToStructByName[audit.Forwarder])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return []audit.Forwarder{}, nil
+		}
+		return nil, fmt.Errorf("failed to collect rows from table:audit_forwarders for workspace_id=%s: %w", forwarderScope(workspaceID), err)
+	}
+
+	return forwarders, nil
+}
+
+func (r *AuditRepository) UpdateForwarder(ctx context.Context, workspaceID *uuid.UUID,
+	payload *audit.UpdateForwarderPayload,
+) (*audit.Forwarder, error) {
+	stmt := `UPDATE audit_forwarders SET `
+	args := pgx.NamedArgs{
+		"id":           payload.ID,
+		"workspace_id": workspaceID,
+	}
+	setClauses := []string{}
+
+	if payload.Name != nil {
+		setClauses = append(setClauses, "name = @name")
+		args["name"] = *payload.Name
+	}
+	if payload.Endpoint != nil {
+		setClauses = append(setClauses, "endpoint = @endpoint")
+		args["endpoint"] = *payload.Endpoint
+	}
+	if payload.Token != nil {
+		setClauses = append(setClauses, "token = @token")
+		args["token"] = *payload.Token
+	}
+	if payload.Enabled != nil {
+		setClauses = append(setClauses, "enabled = @enabled")
+		args["enabled"] = *payload.Enabled
+	}
+
+	if len(setClauses) == 0 {
+		return nil, fmt.Errorf("no fields to update")
+	}
+
+	stmt += strings.Join(setClauses, ", ")
+	stmt += ` WHERE id = @id AND workspace_id IS NOT DISTINCT FROM @workspace_id RETURNING *`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute update audit forwarder query for forwarder_id=%s workspace_id=%s: %w", payload.ID.String(), forwarderScope(workspaceID), err)
+	}
+
+	forwarder, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[audit.Forwarder])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "AUDIT_FORWARDER_NOT_FOUND"
+			return nil, errs.NewNotFoundError("audit forwarder not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:audit_forwarders for forwarder_id=%s workspace_id=%s: %w", payload.ID.String(), forwarderScope(workspaceID), err)
+	}
+
+	return &forwarder, nil
+}
+
+func (r *AuditRepository) DeleteForwarder(ctx context.Context, workspaceID *uuid.UUID, forwarderID uuid.UUID) error {
+	result, err := r.server.DB.Conn(ctx).Exec(ctx, `
+		DELETE FROM audit_forwarders
+		WHERE id = @id AND workspace_id IS NOT DISTINCT FROM @workspace_id
+	`, pgx.NamedArgs{
+		"id":           forwarderID,
+		"workspace_id": workspaceID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete audit forwarder: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		code := "AUDIT_FORWARDER_NOT_FOUND"
+		return errs.NewNotFoundError("audit forwarder not found", false, &code)
+	}
+
+	return nil
+}
+
+// RecordForwardResult stores the outcome of the latest attempt, which is
+// shown to admins checking their SIEM integration
+func (r *AuditRepository) RecordForwardResult(ctx context.Context, forwarderID uuid.UUID, sendErr error) error {
+	var lastError *string
+	if sendErr != nil {
+		msg := sendErr.Error()
+		lastError = &msg
+	}
+
+	_, err := r.server.DB.Conn(ctx).Exec(ctx, `
+		UPDATE audit_forwarders
+		SET
+			last_error = @last_error,
+			last_forwarded_at = CASE WHEN @last_error::TEXT IS NULL THEN NOW() ELSE last_forwarded_at END
+		WHERE
+			id = @id
+	`, pgx.NamedArgs{
+		"id":         forwarderID,
+		"last_error": lastError,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record audit forward result for forwarder_id=%s: %w", forwarderID.String(), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,273 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/lib/backfill"
+	"github.com/mabhi256/tasker/internal/model/admin"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// BackfillRepository keeps the checkpoints of background backfills
+type BackfillRepository struct {
+	server *server.Server
+}
+
+func NewBackfillRepository(server *server.Server) *BackfillRepository {
+	return &BackfillRepository{server: server}
+}
+
+func (r *BackfillRepository) GetBackfillRuns(ctx context.Context) ([]admin.BackfillRun, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			backfill_runs
+		ORDER BY
+			name
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get backfill runs query: %w", err)
+	}
+
+	runs, err := pgx.CollectRows(rows, pgx.RowToStructByName[admin.BackfillRun])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:backfill_runs: %w", err)
+	}
+
+	return runs, nil
+}
+
+// StartBackfillRun starts a run from the first row. A backfill can only be
+// started again once its previous run completed or was aborted.
+func (r *BackfillRepository) StartBackfillRun(ctx context.Context, name string) (*admin.BackfillRun, error) {
+	stmt := `
+		INSERT INTO
+			backfill_runs (name)
+		VALUES
+			(@name)
+		ON CONFLICT (name) DO UPDATE
+		SET
+			status = 'running',
+			checkpoint = '',
+			rows_done = 0,
+			batches = 0,
+			last_error = NULL,
+			started_at = NOW(),
+			finished_at = NULL
+		WHERE
+			backfill_runs.status IN ('completed', 'aborted')
+		RETURNING
+			*
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"name": name,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute start backfill run query for backfill=%s: %w", name, err)
+	}
+
+	run, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[admin.BackfillRun])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "BACKFILL_IN_PROGRESS"
+			return nil, errs.NewConflictError("backfill has a run in progress, resume or abort it instead",
+				false, &code, nil, nil)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:backfill_runs for backfill=%s: %w", name, err)
+	}
+
+	return &run, nil
+}
+
+// PauseBackfillRun stops a running backfill after its current batch
+func (r *BackfillRepository) PauseBackfillRun(ctx context.Context, name string) (*admin.BackfillRun, error) {
+	return r.setRunStatus(ctx, name, admin.BackfillStatusPaused,
+		[]admin.BackfillStatus{admin.BackfillStatusRunning},
+		"only a running backfill can be paused")
+}
+
+// ResumeBackfillRun continues a run from its checkpoint. Resuming a running
+// backfill leaves it as it is, so it can be used to queue a task again.
+func (r *BackfillRepository) ResumeBackfillRun(ctx context.Context, name string) (*admin.BackfillRun, error) {
+	return r.setRunStatus(ctx, name, admin.BackfillStatusRunning,
+		[]admin.BackfillStatus{admin.BackfillStatusRunning, admin.BackfillStatusPaused, admin.BackfillStatusFailed},
+		"only a paused or failed backfill can be resumed")
+}
+
+// AbortBackfillRun ends a run for good. The rows it already processed stay
+// processed.
+func (r *BackfillRepository) AbortBackfillRun(ctx context.Context, name string) (*admin.BackfillRun, error) {
+	return r.setRunStatus(ctx, name, admin.BackfillStatusAborted,
+		[]admin.BackfillStatus{admin.BackfillStatusRunning, admin.BackfillStatusPaused, admin.BackfillStatusFailed},
+		"backfill has no run in progress")
+}
+
+func (r *BackfillRepository) setRunStatus(ctx context.Context, name string, status admin.BackfillStatus,
+	from []admin.BackfillStatus, conflictMessage string,
+) (*admin.BackfillRun, error) {
+	stmt := `
+		UPDATE backfill_runs
+		SET
+			status = @status::TEXT,
+			last_error = CASE
+				WHEN @status::TEXT = 'running' THEN NULL
+				ELSE last_error
+			END,
+			finished_at = CASE
+				WHEN @status::TEXT = 'aborted' THEN NOW()
+				ELSE finished_at
+			END
+		WHERE
+			name = @name
+			AND status = ANY (@from::TEXT[])
+		RETURNING
+			*
+	`
+
+	fromStatuses := make([]string, len(from))
+	for i, s := range from {
+		fromStatuses[i] = string(s)
+	}
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"name":   name,
+		"status": string(status),
+		"from":   fromStatuses,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute set backfill status query for backfill=%s status=%s: %w", name, status, err)
+	}
+
+	run, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[admin.BackfillRun])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "INVALID_BACKFILL_STATE"
+			return nil, errs.NewConflictError(conflictMessage, false, &code, nil, nil)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:backfill_runs for backfill=%s: %w", name, err)
+	}
+
+	return &run, nil
+}
+
+// RunBackfillBatch runs the next batch of b and saves its checkpoint in the
+// same transaction. The run's row stays locked meanwhile, so batches of one
+// backfill never overlap and a pause or abort takes effect before the next
+// batch. A run that isn't running is returned without running a batch.
+func (r *BackfillRepository) RunBackfillBatch(ctx context.Context, b *backfill.Backfill,
+	batchSize int,
+) (*admin.BackfillRun, error) {
+	var run admin.BackfillRun
+	err := pgx.BeginFunc(ctx, r.server.DB.Conn(ctx), func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT
+				*
+			FROM
+				backfill_runs
+			WHERE
+				name = @name
+			FOR UPDATE
+		`, pgx.NamedArgs{
+			"name": b.Name,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to execute lock backfill run query for backfill=%s: %w", b.Name, err)
+		}
+
+		run, err = pgx.CollectOneRow(rows, pgx.RowToStructByName[admin.BackfillRun])
+		if err != nil {
+			return fmt.Errorf("failed to collect row from table:backfill_runs for backfill=%s: %w", b.Name, err)
+		}
+
+		if run.Status != admin.BackfillStatusRunning {
+			return nil
+		}
+
+		// Keeps updated_at of the rows the batch rewrites
+		if _, err := tx.Exec(ctx, `SELECT set_config('tasker.backfill', 'on', true)`); err != nil {
+			return fmt.Errorf("failed to mark transaction of backfill=%s: %w", b.Name, err)
+		}
+
+		batch, err := b.RunBatch(ctx, tx, run.Checkpoint, batchSize)
+		if err != nil {
+			return err
+		}
+
+		rows, err = tx.Query(ctx, `
+			UPDATE backfill_runs
+			SET
+				checkpoint = @checkpoint,
+				rows_done = rows_done + @rows,
+				batches = batches + CASE
+					WHEN @keys::INT > 0 THEN 1
+					ELSE 0
+				END,
+				status = CASE
+					WHEN @done THEN 'completed'
+					ELSE status
+				END,
+				finished_at = CASE
+					WHEN @done THEN NOW()
+				END
+			WHERE
+				name = @name
+			RETURNING
+				*
+		`, pgx.NamedArgs{
+			"name":       b.Name,
+			"checkpoint": batch.Checkpoint,
+			"rows":       batch.Rows,
+			"keys":       batch.Keys,
+			"done":       batch.Done,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to execute save checkpoint query for backfill=%s: %w", b.Name, err)
+		}
+
+		run, err = pgx.CollectOneRow(rows, pgx.RowToStructByName[admin.BackfillRun])
+		if err != nil {
+			return fmt.Errorf("failed to collect row from table:backfill_runs for backfill=%s: %w", b.Name, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &run, nil
+}
+
+// RecordBackfillError stores the error of a failed attempt. When final is
+// set a running backfill is marked failed, and can be resumed later.
+func (r *BackfillRepository) RecordBackfillError(ctx context.Context, name string, errMsg string, final bool) error {
+	_, err := r.server.DB.Conn(ctx).Exec(ctx, `
+		UPDATE backfill_runs
+		SET
+			last_error = @error,
+			status = CASE
+				WHEN @final
+				AND status = 'running' THEN 'failed'
+				ELSE status
+			END
+		WHERE
+			name = @name
+	`, pgx.NamedArgs{
+		"name":  name,
+		"error": errMsg,
+		"final": final,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record backfill error for backfill=%s: %w", name, err)
+	}
+
+	return nil
+}
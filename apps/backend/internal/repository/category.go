@@ -4,15 +4,23 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"strings"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/mabhi256/tasker/internal/model"
 	"github.com/mabhi256/tasker/internal/model/category"
+	"github.com/mabhi256/tasker/internal/repository/queries"
 	"github.com/mabhi256/tasker/internal/server"
 )
 
+// categorySortColumns are the columns of the fields categories are sorted by
+var categorySortColumns = map[string]string{
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+	"name":       "name",
+	"id":         "id",
+}
+
 type CategoryRepository struct {
 	server *server.Server
 }
@@ -21,74 +29,84 @@ func NewCategoryRepository(server *server.Server) *CategoryRepository {
 	return &CategoryRepository{server: server}
 }
 
-func (r *CategoryRepository) CreateCategory(ctx context.Context, userID string,
+func (r *CategoryRepository) CreateCategory(ctx context.Context, workspaceID uuid.UUID, userID string,
 	payload *category.CreateCategoryPayload,
 ) (*category.Category, error) {
-	stmt := `
-		INSERT INTO
-			todo_categories (
-				user_id,
-				name,
-				color,
-				description
-			)
-		VALUES
-			(
-				@user_id,
-				@name,
-				@color,
-				@description
-			)
-		RETURNING
-		*
-	`
+	row, err := queries.New(r.server.DB.Conn(ctx)).CreateCategory(ctx, queries.CreateCategoryParams{
+		WorkspaceID:            workspaceID,
+		UserID:                 userID,
+		Name:                   payload.Name,
+		Color:                  &payload.Color,
+		Description:            payload.Description,
+		DefaultPriority:        payload.DefaultPriority,
+		DefaultReminderMinutes: int32Ptr(payload.DefaultReminderMinutes),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute create category query for workspace_id=%s name=%s: %w", workspaceID.String(), payload.Name, err)
+	}
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
-		"user_id":     userID,
-		"name":        payload.Name,
-		"color":       payload.Color,
-		"description": payload.Description,
+	categoryItem := categoryFromRow(row)
+	return &categoryItem, nil
+}
+
+func (r *CategoryRepository) GetCategoryByID(ctx context.Context, workspaceID uuid.UUID, categoryID uuid.UUID) (*category.Category, error) {
+	row, err := queries.New(r.server.DB.Conn(ctx)).GetCategoryByID(ctx, queries.GetCategoryByIDParams{
+		ID:          categoryID,
+		WorkspaceID: workspaceID,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute create category query for user_id=%s name=%s: %w", userID, payload.Name, err)
+		return nil, fmt.Errorf("failed to execute get category by id query for category_id=%s workspace_id=%s: %w", categoryID.String(), workspaceID.String(), err)
 	}
 
-	categoryItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[category.Category])
+	categoryItem := categoryFromRow(row)
+	return &categoryItem, nil
+}
+
+// FindCategoryByName returns the workspace's category named name, or nil
+// when it has none
+func (r *CategoryRepository) FindCategoryByName(ctx context.Context, workspaceID uuid.UUID, name string) (*category.Category, error) {
+	row, err := queries.New(r.server.DB.Conn(ctx)).GetCategoryByName(ctx, queries.GetCategoryByNameParams{
+		WorkspaceID: workspaceID,
+		Name:        name,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to collect row from table:todo_categories for user_id=%s name=%s: %w", userID, payload.Name, err)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to execute get category by name query for workspace_id=%s: %w", workspaceID.String(), err)
 	}
 
+	categoryItem := categoryFromRow(row)
 	return &categoryItem, nil
 }
 
-func (r *CategoryRepository) GetCategoryByID(ctx context.Context, userID string, categoryID uuid.UUID) (*category.Category, error) {
-	stmt := `
-		SELECT
-			*
-		FROM
-			todo_categories
-		WHERE
-			id=@id
-			AND user_id=@user_id
-	`
+// GetCategoriesByIDs returns the categories of the workspace among ids
+func (r *CategoryRepository) GetCategoriesByIDs(ctx context.Context, workspaceID uuid.UUID,
+	ids []uuid.UUID,
+) ([]category.Category, error) {
+	if len(ids) == 0 {
+		return []category.Category{}, nil
+	}
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
-		"id":      categoryID,
-		"user_id": userID,
+	rows, err := queries.New(r.server.DB.Reader(ctx)).GetCategoriesByIDs(ctx, queries.GetCategoriesByIDsParams{
+		Ids:         ids,
+		WorkspaceID: workspaceID,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute get category by id query for category_id=%s user_id=%s: %w", categoryID.String(), userID, err)
+		return nil, fmt.Errorf("failed to execute get categories by ids query for workspace_id=%s: %w", workspaceID.String(), err)
 	}
 
-	categoryItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[category.Category])
-	if err != nil {
-		return nil, fmt.Errorf("failed to collect row from table:todo_categories for category_id=%s user_id=%s: %w", categoryID.String(), userID, err)
+	categories := make([]category.Category, 0, len(rows))
+	for _, row := range rows {
+		categories = append(categories, categoryFromRow(row))
 	}
 
-	return &categoryItem, nil
+	return categories, nil
 }
 
-func (r *CategoryRepository) GetCategories(ctx context.Context, userID string,
+// GetCategories builds its statement from the query's filter and sort
+// order, which the generated queries can't express
+func (r *CategoryRepository) GetCategories(ctx context.Context, workspaceID uuid.UUID,
 	query *category.GetCategoriesQuery,
 ) (*model.PaginatedResponse[category.Category], error) {
 	stmt := `
@@ -97,11 +115,11 @@ func (r *CategoryRepository) GetCategories(ctx context.Context, userID string,
 		FROM
 			todo_categories
 		WHERE
-			user_id=@user_id
+			workspace_id=@workspace_id
 	`
 
 	args := pgx.NamedArgs{
-		"user_id": userID,
+		"workspace_id": workspaceID,
 	}
 
 	// Add search filter if provided
@@ -111,24 +129,16 @@ func (r *CategoryRepository) GetCategories(ctx context.Context, userID string,
 	}
 
 	// Add sorting
-	sortColumn := "name"
-	if query.Sort != nil {
-		sortColumn = *query.Sort
-	}
-	sortOrder := "asc"
-	if query.Order != nil {
-		sortOrder = *query.Order
-	}
-	stmt += fmt.Sprintf(" ORDER BY %s %s", sortColumn, sortOrder)
+	stmt += orderBy(query.SortKeys(), collated(categorySortColumns, query.Locale, "name"), "id")
 
 	// Add pagination
 	stmt += ` LIMIT @limit OFFSET @offset`
 	args["limit"] = *query.Limit
 	args["offset"] = (*query.Page - 1) * (*query.Limit)
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, args)
+	rows, err := r.server.DB.Reader(ctx).Query(ctx, stmt, args)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute get categories query for user_id=%s: %w", userID, err)
+		return nil, fmt.Errorf("failed to execute get categories query for workspace_id=%s: %w", workspaceID.String(), err)
 	}
 
 	categories, err := pgx.CollectRows(rows, pgx.RowToStructByName[category.Category])
@@ -142,7 +152,7 @@ func (r *CategoryRepository) GetCategories(ctx context.Context, userID string,
 				TotalPages: 0,
 			}, nil
 		}
-		return nil, fmt.Errorf("failed to collect rows from table:todo_categories for user_id=%s: %w", userID, err)
+		return nil, fmt.Errorf("failed to collect rows from table:todo_categories for workspace_id=%s: %w", workspaceID.String(), err)
 	}
 
 	// Get total count
@@ -152,11 +162,11 @@ func (r *CategoryRepository) GetCategories(ctx context.Context, userID string,
 		FROM
 			todo_categories
 		WHERE
-			user_id=@user_id
+			workspace_id=@workspace_id
 	`
 
 	countArgs := pgx.NamedArgs{
-		"user_id": userID,
+		"workspace_id": workspaceID,
 	}
 
 	if query.Search != nil {
@@ -165,9 +175,9 @@ func (r *CategoryRepository) GetCategories(ctx context.Context, userID string,
 	}
 
 	var total int
-	err = r.server.DB.Pool.QueryRow(ctx, countStmt, countArgs).Scan(&total)
+	err = r.server.DB.Reader(ctx).QueryRow(ctx, countStmt, countArgs).Scan(&total)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get total count of categories for user_id=%s: %w", userID, err)
+		return nil, fmt.Errorf("failed to get total count of categories for workspace_id=%s: %w", workspaceID.String(), err)
 	}
 
 	return &model.PaginatedResponse[category.Category]{
@@ -179,64 +189,64 @@ func (r *CategoryRepository) GetCategories(ctx context.Context, userID string,
 	}, nil
 }
 
-func (r *CategoryRepository) UpdateCategory(ctx context.Context, userID string,
+func (r *CategoryRepository) UpdateCategory(ctx context.Context, workspaceID uuid.UUID,
 	categoryID uuid.UUID, payload *category.UpdateCategoryPayload,
 ) (*category.Category, error) {
-	stmt := `UPDATE todo_categories SET `
-	args := pgx.NamedArgs{
-		"id":      categoryID,
-		"user_id": userID,
-	}
-	setClauses := []string{}
-
-	if payload.Name != nil {
-		setClauses = append(setClauses, "name = @name")
-		args["name"] = *payload.Name
-	}
-	if payload.Color != nil {
-		setClauses = append(setClauses, "color = @color")
-		args["color"] = *payload.Color
-	}
-	if payload.Description != nil {
-		setClauses = append(setClauses, "description = @description")
-		args["description"] = *payload.Description
-	}
-
-	if len(setClauses) == 0 {
+	if payload.Name == nil && payload.Color == nil && payload.Description == nil &&
+		payload.DefaultPriority == nil && payload.DefaultReminderMinutes == nil &&
+		!payload.ClearDefaultPriority && !payload.ClearDefaultReminderMinutes {
 		return nil, fmt.Errorf("no fields to update")
 	}
 
-	stmt += strings.Join(setClauses, ", ")
-	stmt += ` WHERE id = @id AND user_id = @user_id RETURNING *`
-
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, args)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute update category query for category_id=%s user_id=%s: %w", categoryID.String(), userID, err)
-	}
-
-	categoryItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[category.Category])
+	row, err := queries.New(r.server.DB.Conn(ctx)).UpdateCategory(ctx, queries.UpdateCategoryParams{
+		Name:                        payload.Name,
+		Color:                       payload.Color,
+		Description:                 payload.Description,
+		ClearDefaultPriority:        payload.ClearDefaultPriority,
+		DefaultPriority:             payload.DefaultPriority,
+		ClearDefaultReminderMinutes: payload.ClearDefaultReminderMinutes,
+		DefaultReminderMinutes:      int32Ptr(payload.DefaultReminderMinutes),
+		ID:                          categoryID,
+		WorkspaceID:                 workspaceID,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to collect row from table:todo_categories for category_id=%s user_id=%s: %w", categoryID.String(), userID, err)
+		return nil, fmt.Errorf("failed to execute update category query for category_id=%s workspace_id=%s: %w", categoryID.String(), workspaceID.String(), err)
 	}
 
+	categoryItem := categoryFromRow(row)
 	return &categoryItem, nil
 }
 
-func (r *CategoryRepository) DeleteCategory(ctx context.Context, userID string, categoryID uuid.UUID) error {
-	result, err := r.server.DB.Pool.Exec(ctx, `
-		DELETE FROM todo_categories
-		WHERE id = @id AND user_id = @user_id
-	`, pgx.NamedArgs{
-		"id":      categoryID,
-		"user_id": userID,
+func (r *CategoryRepository) DeleteCategory(ctx context.Context, workspaceID uuid.UUID, categoryID uuid.UUID) error {
+	deleted, err := queries.New(r.server.DB.Conn(ctx)).DeleteCategory(ctx, queries.DeleteCategoryParams{
+		ID:          categoryID,
+		WorkspaceID: workspaceID,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to delete category: %w", err)
 	}
 
-	if result.RowsAffected() == 0 {
+	if deleted == 0 {
 		return fmt.Errorf("category not found")
 	}
 
 	return nil
 }
+
+func categoryFromRow(row queries.TodoCategory) category.Category {
+	categoryItem := category.Category{
+		WorkspaceID:            row.WorkspaceID,
+		UserID:                 row.UserID,
+		Name:                   row.Name,
+		Description:            row.Description,
+		DefaultPriority:        row.DefaultPriority,
+		DefaultReminderMinutes: intPtr(row.DefaultReminderMinutes),
+	}
+	categoryItem.ID = row.ID
+	categoryItem.CreatedAt = row.CreatedAt
+	categoryItem.UpdatedAt = row.UpdatedAt
+	if row.Color != nil {
+		categoryItem.Color = *row.Color
+	}
+	return categoryItem
+}
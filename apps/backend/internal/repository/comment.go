@@ -2,11 +2,16 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/model"
 	"github.com/mabhi256/tasker/internal/model/comment"
+	"github.com/mabhi256/tasker/internal/model/webhook"
+	"github.com/mabhi256/tasker/internal/repository/queries"
 	"github.com/mabhi256/tasker/internal/server"
 )
 
@@ -18,144 +23,449 @@ func NewCommentRepository(server *server.Server) *CommentRepository {
 	return &CommentRepository{server: server}
 }
 
-func (r *CommentRepository) AddComment(ctx context.Context, userID string, todoID uuid.UUID,
-	payload *comment.AddCommentPayload,
+// AddComment stores the comment along with who it mentions
+func (r *CommentRepository) AddComment(ctx context.Context, workspaceID uuid.UUID, userID string, todoID uuid.UUID,
+	payload *comment.AddCommentPayload, mentions []string,
 ) (*comment.Comment, error) {
+	var commentItem comment.Comment
+	err := pgx.BeginFunc(ctx, r.server.DB.Conn(ctx), func(tx pgx.Tx) error {
+		row, err := queries.New(tx).AddComment(ctx, queries.AddCommentParams{
+			WorkspaceID:     workspaceID,
+			TodoID:          todoID,
+			UserID:          userID,
+			Content:         payload.Content,
+			ParentCommentID: payload.ParentCommentID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to execute add comment query for todo_id=%s user_id=%s: %w", todoID.String(), userID, err)
+		}
+		commentItem = commentFromRow(row)
+
+		if _, err := replaceMentions(ctx, tx, &commentItem, mentions); err != nil {
+			return err
+		}
+
+		return insertOutboxEvent(ctx, tx, workspaceID, string(webhook.EventCommentAdded), commentItem)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &commentItem, nil
+}
+
+func (r *CommentRepository) GetCommentsByTodoID(ctx context.Context, workspaceID uuid.UUID, todoID uuid.UUID) ([]comment.Comment, error) {
+	rows, err := queries.New(r.server.DB.Reader(ctx)).GetCommentsByTodoID(ctx, queries.GetCommentsByTodoIDParams{
+		TodoID:      todoID,
+		WorkspaceID: workspaceID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get comments by todo id query for todo_id=%s workspace_id=%s: %w", todoID.String(), workspaceID.String(), err)
+	}
+
+	return commentsFromRows(rows), nil
+}
+
+func (r *CommentRepository) GetCommentByID(ctx context.Context, workspaceID uuid.UUID, commentID uuid.UUID) (*comment.Comment, error) {
+	row, err := queries.New(r.server.DB.Conn(ctx)).GetCommentByID(ctx, queries.GetCommentByIDParams{
+		ID:          commentID,
+		WorkspaceID: workspaceID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "COMMENT_NOT_FOUND"
+			return nil, errs.NewNotFoundError("comment not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to execute get comment by id query for comment_id=%s workspace_id=%s: %w", commentID.String(), workspaceID.String(), err)
+	}
+
+	commentItem := commentFromRow(row)
+	return &commentItem, nil
+}
+
+// GetCommentsByIDs returns the comments of the workspace among ids
+func (r *CommentRepository) GetCommentsByIDs(ctx context.Context, workspaceID uuid.UUID,
+	ids []uuid.UUID,
+) ([]comment.Comment, error) {
+	if len(ids) == 0 {
+		return []comment.Comment{}, nil
+	}
+
+	rows, err := queries.New(r.server.DB.Reader(ctx)).GetCommentsByIDs(ctx, queries.GetCommentsByIDsParams{
+		Ids:         ids,
+		WorkspaceID: workspaceID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get comments by ids query for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	return commentsFromRows(rows), nil
+}
+
+// UpdateComment edits the comment and replaces who it mentions. It returns
+// the users who weren't mentioned before the edit.
+func (r *CommentRepository) UpdateComment(ctx context.Context, workspaceID uuid.UUID, userID string,
+	commentID uuid.UUID, content string, mentions []string,
+) (*comment.Comment, []string, error) {
+	var commentItem comment.Comment
+	var added []string
+	err := pgx.BeginFunc(ctx, r.server.DB.Conn(ctx), func(tx pgx.Tx) error {
+		row, err := queries.New(tx).UpdateComment(ctx, queries.UpdateCommentParams{
+			Content:     content,
+			ID:          commentID,
+			WorkspaceID: workspaceID,
+			UserID:      userID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to execute update comment query for comment_id=%s user_id=%s: %w", commentID.String(), userID, err)
+		}
+		commentItem = commentFromRow(row)
+
+		added, err = replaceMentions(ctx, tx, &commentItem, mentions)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &commentItem, added, nil
+}
+
+// replaceMentions makes mentions the comment's mentioned users and returns
+// the ones that are new
+func replaceMentions(ctx context.Context, tx pgx.Tx, commentItem *comment.Comment, mentions []string) ([]string, error) {
+	// A nil slice is sent as NULL, which would keep every existing mention
+	if mentions == nil {
+		mentions = []string{}
+	}
+
+	_, err := tx.Exec(ctx, `
+		DELETE FROM comment_mentions
+		WHERE comment_id = @comment_id AND NOT (user_id = ANY(@user_ids))
+	`, pgx.NamedArgs{
+		"comment_id": commentItem.ID,
+		"user_ids":   mentions,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove mentions for comment_id=%s: %w", commentItem.ID.String(), err)
+	}
+
+	if len(mentions) == 0 {
+		return nil, nil
+	}
+
 	stmt := `
 		INSERT INTO
-			todo_comments (
-				todo_id,
+			comment_mentions (
+				comment_id,
 				user_id,
-				content
-			)
-		VALUES
-			(
-				@todo_id,
-				@user_id,
-				@content
+				workspace_id
 			)
+		SELECT
+			@comment_id,
+			user_id,
+			@workspace_id
+		FROM
+			UNNEST(@user_ids::TEXT[]) AS user_id
+		ON CONFLICT (comment_id, user_id) DO NOTHING
 		RETURNING
-		*
+			user_id
 	`
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
-		"todo_id": todoID,
-		"user_id": userID,
-		"content": payload.Content,
+	rows, err := tx.Query(ctx, stmt, pgx.NamedArgs{
+		"comment_id":   commentItem.ID,
+		"workspace_id": commentItem.WorkspaceID,
+		"user_ids":     mentions,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute add comment query for todo_id=%s user_id=%s: %w", todoID.String(), userID, err)
+		return nil, fmt.Errorf("failed to execute add mentions query for comment_id=%s: %w", commentItem.ID.String(), err)
 	}
 
-	commentItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[comment.Comment])
+	added, err := pgx.CollectRows(rows, pgx.RowTo[string])
 	if err != nil {
-		return nil, fmt.Errorf("failed to collect row from table:todo_comments for todo_id=%s user_id=%s: %w", todoID.String(), userID, err)
+		return nil, fmt.Errorf("failed to collect rows from table:comment_mentions for comment_id=%s: %w", commentItem.ID.String(), err)
 	}
 
-	return &commentItem, nil
+	return added, nil
 }
 
-func (r *CommentRepository) GetCommentsByTodoID(ctx context.Context, userID string, todoID uuid.UUID) ([]comment.Comment, error) {
+// GetMentions lists the comments in the workspace that mention the user,
+// newest first
+func (r *CommentRepository) GetMentions(ctx context.Context, workspaceID uuid.UUID, userID string,
+	query *comment.GetMentionsQuery,
+) (*model.PaginatedResponse[comment.Comment], error) {
 	stmt := `
 		SELECT
-			*
+			c.*
 		FROM
-			todo_comments
+			comment_mentions m
+			JOIN todo_comments c ON c.id = m.comment_id
 		WHERE
-			todo_id=@todo_id
-			AND user_id=@user_id
+			m.user_id=@user_id
+			AND m.workspace_id=@workspace_id
 		ORDER BY
-			created_at ASC
+			m.created_at DESC,
+			c.id DESC
+		LIMIT
+			@limit
+		OFFSET
+			@offset
 	`
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
-		"todo_id": todoID,
-		"user_id": userID,
-	})
+	args := pgx.NamedArgs{
+		"user_id":      userID,
+		"workspace_id": workspaceID,
+		"limit":        *query.Limit,
+		"offset":       (*query.Page - 1) * (*query.Limit),
+	}
+
+	rows, err := r.server.DB.Reader(ctx).Query(ctx, stmt, args)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute get comments by todo id query for todo_id=%s user_id=%s: %w", todoID.String(), userID, err)
+		return nil, fmt.Errorf("failed to execute get mentions query for user_id=%s workspace_id=%s: %w", userID, workspaceID.String(), err)
 	}
 
 	comments, err := pgx.CollectRows(rows, pgx.RowToStructByName[comment.Comment])
 	if err != nil {
-		return nil, fmt.Errorf("failed to collect rows from table:todo_comments for todo_id=%s user_id=%s: %w", todoID.String(), userID, err)
+		return nil, fmt.Errorf("failed to collect rows from table:todo_comments for user_id=%s workspace_id=%s: %w", userID, workspaceID.String(), err)
 	}
 
-	return comments, nil
-}
-
-func (r *CommentRepository) GetCommentByID(ctx context.Context, userID string, commentID uuid.UUID) (*comment.Comment, error) {
-	stmt := `
+	countStmt := `
 		SELECT
-			*
+			COUNT(*)
 		FROM
-			todo_comments
+			comment_mentions
 		WHERE
-			id=@id 
-			AND user_id=@user_id
+			user_id=@user_id
+			AND workspace_id=@workspace_id
 	`
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
-		"id":      commentID,
-		"user_id": userID,
+	var total int
+	err = r.server.DB.Reader(ctx).QueryRow(ctx, countStmt, args).Scan(&total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get total count of mentions for user_id=%s workspace_id=%s: %w", userID, workspaceID.String(), err)
+	}
+
+	return &model.PaginatedResponse[comment.Comment]{
+		Data:       comments,
+		Page:       *query.Page,
+		Limit:      *query.Limit,
+		Total:      total,
+		TotalPages: (total + *query.Limit - 1) / *query.Limit,
+	}, nil
+}
+
+// reactionSummaries aggregates the reactions on the comment whose id is the
+// %s expression, most used first, marking the ones by the user @user_id
+const reactionSummaries = `
+	COALESCE(
+		(
+			SELECT
+				jsonb_agg(
+					jsonb_build_object('emoji', s.emoji, 'count', s.count, 'reacted', s.reacted)
+					ORDER BY
+						s.count DESC,
+						s.first_at,
+						s.emoji
+				)
+			FROM
+				(
+					SELECT
+						cr.emoji,
+						COUNT(*) AS count,
+						BOOL_OR(cr.user_id = @user_id) AS reacted,
+						MIN(cr.created_at) AS first_at
+					FROM
+						comment_reactions cr
+					WHERE
+						cr.comment_id = %s
+					GROUP BY
+						cr.emoji
+				) s
+		),
+		'[]'::JSONB
+	)
+`
+
+// GetThread lists the comment and every reply below it, depth first with
+// replies in the order they were written. The recursion follows
+// idx_todo_comments_parent_comment_id one level at a time.
+func (r *CommentRepository) GetThread(ctx context.Context, workspaceID uuid.UUID, userID string,
+	commentID uuid.UUID,
+) ([]comment.ThreadComment, error) {
+	stmt := fmt.Sprintf(`
+		WITH RECURSIVE
+			thread AS (
+				SELECT
+					c.*,
+					0 AS depth,
+					ARRAY[]::TEXT[] AS path
+				FROM
+					todo_comments c
+				WHERE
+					c.id=@id
+					AND c.workspace_id=@workspace_id
+				UNION ALL
+				SELECT
+					c.*,
+					t.depth + 1,
+					-- Sorts each reply after its parent, by when it was written
+					t.path || (to_char(c.created_at AT TIME ZONE 'UTC', 'YYYYMMDDHH24MISSUS') || c.id::TEXT)
+				FROM
+					todo_comments c
+					JOIN thread t ON c.parent_comment_id=t.id
+			)
+		SELECT
+			t.id,
+			t.created_at,
+			t.updated_at,
+			t.workspace_id,
+			t.todo_id,
+			t.user_id,
+			t.content,
+			t.parent_comment_id,
+			t.depth,
+			(
+				SELECT
+					COUNT(*)
+				FROM
+					todo_comments reply
+				WHERE
+					reply.parent_comment_id=t.id
+			) AS reply_count,
+			%s AS reactions
+		FROM
+			thread t
+		ORDER BY
+			t.path
+	`, fmt.Sprintf(reactionSummaries, "t.id"))
+
+	rows, err := r.server.DB.Reader(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"id":           commentID,
+		"workspace_id": workspaceID,
+		"user_id":      userID,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute get comment by id query for comment_id=%s user_id=%s: %w", commentID.String(), userID, err)
+		return nil, fmt.Errorf("failed to execute get thread query for comment_id=%s workspace_id=%s: %w", commentID.String(), workspaceID.String(), err)
 	}
 
-	commentItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[comment.Comment])
+	thread, err := pgx.CollectRows(rows, pgx.RowToStructByName[comment.ThreadComment])
 	if err != nil {
-		return nil, fmt.Errorf("failed to collect row from table:todo_comments for comment_id=%s user_id=%s: %w", commentID.String(), userID, err)
+		return nil, fmt.Errorf("failed to collect rows from table:todo_comments for comment_id=%s workspace_id=%s: %w", commentID.String(), workspaceID.String(), err)
 	}
 
-	return &commentItem, nil
+	if len(thread) == 0 {
+		code := "COMMENT_NOT_FOUND"
+		return nil, errs.NewNotFoundError("comment not found", false, &code)
+	}
+
+	return thread, nil
 }
 
-func (r *CommentRepository) UpdateComment(ctx context.Context, userID string, commentID uuid.UUID, content string) (*comment.Comment, error) {
-	stmt := `
-		UPDATE
-			todo_comments
-		SET
-			content=@content
-		WHERE
-			id=@id
-			AND user_id=@user_id
-		RETURNING
-		*
-	`
+// AddReaction reacts to the comment for the user. Reacting again with the
+// same emoji changes nothing.
+func (r *CommentRepository) AddReaction(ctx context.Context, commentItem *comment.Comment, userID string,
+	emoji string,
+) error {
+	_, err := r.server.DB.Conn(ctx).Exec(ctx, `
+		INSERT INTO
+			comment_reactions (
+				comment_id,
+				user_id,
+				emoji,
+				workspace_id
+			)
+		VALUES
+			(
+				@comment_id,
+				@user_id,
+				@emoji,
+				@workspace_id
+			)
+		ON CONFLICT (comment_id, user_id, emoji) DO NOTHING
+	`, pgx.NamedArgs{
+		"comment_id":   commentItem.ID,
+		"user_id":      userID,
+		"emoji":        emoji,
+		"workspace_id": commentItem.WorkspaceID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add reaction for comment_id=%s user_id=%s: %w", commentItem.ID.String(), userID, err)
+	}
+
+	return nil
+}
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
-		"id":      commentID,
-		"user_id": userID,
-		"content": content,
+// RemoveReaction takes back the user's reaction, if they reacted with emoji
+func (r *CommentRepository) RemoveReaction(ctx context.Context, commentID uuid.UUID, userID string,
+	emoji string,
+) error {
+	_, err := r.server.DB.Conn(ctx).Exec(ctx, `
+		DELETE FROM comment_reactions
+		WHERE comment_id = @comment_id AND user_id = @user_id AND emoji = @emoji
+	`, pgx.NamedArgs{
+		"comment_id": commentID,
+		"user_id":    userID,
+		"emoji":      emoji,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute update comment query for comment_id=%s user_id=%s: %w", commentID.String(), userID, err)
+		return fmt.Errorf("failed to remove reaction for comment_id=%s user_id=%s: %w", commentID.String(), userID, err)
 	}
 
-	commentItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[comment.Comment])
+	return nil
+}
+
+func (r *CommentRepository) GetReactions(ctx context.Context, commentID uuid.UUID,
+	userID string,
+) ([]comment.ReactionSummary, error) {
+	stmt := `SELECT ` + fmt.Sprintf(reactionSummaries, "@comment_id")
+
+	var reactions []comment.ReactionSummary
+	err := r.server.DB.Conn(ctx).QueryRow(ctx, stmt, pgx.NamedArgs{
+		"comment_id": commentID,
+		"user_id":    userID,
+	}).Scan(&reactions)
 	if err != nil {
-		return nil, fmt.Errorf("failed to collect row from table:todo_comments for comment_id=%s user_id=%s: %w", commentID.String(), userID, err)
+		return nil, fmt.Errorf("failed to get reactions for comment_id=%s: %w", commentID.String(), err)
 	}
 
-	return &commentItem, nil
+	return reactions, nil
 }
 
-func (r *CommentRepository) DeleteComment(ctx context.Context, userID string, commentID uuid.UUID) error {
-	result, err := r.server.DB.Pool.Exec(ctx, `
-		DELETE FROM todo_comments
-		WHERE id = @id AND user_id = @user_id
-	`, pgx.NamedArgs{
-		"id":      commentID,
-		"user_id": userID,
+// DeleteComment deletes the comment along with its replies
+func (r *CommentRepository) DeleteComment(ctx context.Context, workspaceID uuid.UUID, commentID uuid.UUID) error {
+	deleted, err := queries.New(r.server.DB.Conn(ctx)).DeleteComment(ctx, queries.DeleteCommentParams{
+		ID:          commentID,
+		WorkspaceID: workspaceID,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to delete comment: %w", err)
 	}
 
-	if result.RowsAffected() == 0 {
+	if deleted == 0 {
 		return fmt.Errorf("comment not found")
 	}
 
 	return nil
 }
+
+func commentFromRow(row queries.TodoComment) comment.Comment {
+	commentItem := comment.Comment{
+		WorkspaceID:     row.WorkspaceID,
+		TodoID:          row.TodoID,
+		UserID:          row.UserID,
+		Content:         row.Content,
+		ParentCommentID: row.ParentCommentID,
+	}
+	commentItem.ID = row.ID
+	commentItem.CreatedAt = row.CreatedAt
+	commentItem.UpdatedAt = row.UpdatedAt
+	return commentItem
+}
+
+func commentsFromRows(rows []queries.TodoComment) []comment.Comment {
+	comments := make([]comment.Comment, 0, len(rows))
+	for _, row := range rows {
+		comments = append(comments, commentFromRow(row))
+	}
+	return comments
+}
@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/model/device"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+type DeviceRepository struct {
+	server *server.Server
+}
+
+func NewDeviceRepository(server *server.Server) *DeviceRepository {
+	return &DeviceRepository{server: server}
+}
+
+// RegisterDevice adds the device, or updates it when its token is already
+// registered, possibly by another user
+func (r *DeviceRepository) RegisterDevice(ctx context.Context, userID string,
+	payload *device.RegisterDevicePayload,
+) (*device.Device, error) {
+	args := pgx.NamedArgs{
+		"user_id":  userID,
+		"platform": payload.Platform,
+		"name":     payload.Name,
+	}
+	if payload.Platform == device.PlatformWeb {
+		args["token"] = payload.Subscription.Endpoint
+		args["p256dh"] = payload.Subscription.Keys.P256dh
+		args["auth"] = payload.Subscription.Keys.Auth
+	} else {
+		args["token"] = *payload.Token
+		args["p256dh"] = nil
+		args["auth"] = nil
+	}
+
+	stmt := `
+		INSERT INTO
+			push_devices (
+				user_id,
+				platform,
+				token,
+				p256dh,
+				auth,
+				name
+			)
+		VALUES
+			(
+				@user_id,
+				@platform,
+				@token,
+				@p256dh,
+				@auth,
+				@name
+			)
+		ON CONFLICT (token) DO UPDATE
+		SET
+			user_id = EXCLUDED.user_id,
+			platform = EXCLUDED.platform,
+			p256dh = EXCLUDED.p256dh,
+			auth = EXCLUDED.auth,
+			name = COALESCE(EXCLUDED.name, push_devices.name)
+		RETURNING
+			*
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute register push device query for user_id=%s: %w", userID, err)
+	}
+
+	deviceItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[device.Device])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:push_devices for user_id=%s: %w", userID, err)
+	}
+
+	return &deviceItem, nil
+}
+
+func (r *DeviceRepository) GetDevices(ctx context.Context, userID string) ([]device.Device, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			push_devices
+		WHERE
+			user_id=@user_id
+		ORDER BY
+			created_at DESC,
+			id DESC
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"user_id": userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get push devices query for user_id=%s: %w", userID, err)
+	}
+
+	devices, err := pgx.CollectRows(rows, pgx.RowToStructByName[device.Device])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:push_devices for user_id=%s: %w", userID, err)
+	}
+
+	return devices, nil
+}
+
+func (r *DeviceRepository) GetDeviceByID(ctx context.Context, deviceID uuid.UUID) (*device.Device, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			push_devices
+		WHERE
+			id=@id
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"id": deviceID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get push device query for id=%s: %w", deviceID, err)
+	}
+
+	deviceItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[device.Device])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:push_devices for id=%s: %w", deviceID, err)
+	}
+
+	return &deviceItem, nil
+}
+
+func (r *DeviceRepository) DeleteDevice(ctx context.Context, userID string, deviceID uuid.UUID) error {
+	result, err := r.server.DB.Conn(ctx).Exec(ctx, `
+		DELETE FROM push_devices
+		WHERE id = @id AND user_id = @user_id
+	`, pgx.NamedArgs{
+		"id":      deviceID,
+		"user_id": userID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete push device: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		code := "DEVICE_NOT_FOUND"
+		return errs.NewNotFoundError("device not found", false, &code)
+	}
+
+	return nil
+}
+
+// RemoveDevice forgets a device its push service no longer accepts. A device
+// that is already gone is not an error.
+func (r *DeviceRepository) RemoveDevice(ctx context.Context, deviceID uuid.UUID) error {
+	_, err := r.server.DB.Conn(ctx).Exec(ctx, `
+		DELETE FROM push_devices
+		WHERE id = @id
+	`, pgx.NamedArgs{
+		"id": deviceID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove push device: %w", err)
+	}
+
+	return nil
+}
+
+func (r *DeviceRepository) MarkDeviceUsed(ctx context.Context, deviceID uuid.UUID, usedAt time.Time) error {
+	_, err := r.server.DB.Conn(ctx).Exec(ctx, `
+		UPDATE push_devices
+		SET
+			last_used_at = @used_at
+		WHERE
+			id = @id
+	`, pgx.NamedArgs{
+		"id":      deviceID,
+		"used_at": usedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark push device used: %w", err)
+	}
+
+	return nil
+}
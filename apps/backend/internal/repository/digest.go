@@ -0,0 +1,286 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mabhi256/tasker/internal/model/digest"
+	"github.com/mabhi256/tasker/internal/model/todo"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+type DigestRepository struct {
+	server *server.Server
+}
+
+func NewDigestRepository(server *server.Server) *DigestRepository {
+	return &DigestRepository{server: server}
+}
+
+func (r *DigestRepository) GetSubscription(ctx context.Context, userID string) (*digest.Subscription, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			digest_subscriptions
+		WHERE
+			user_id=@user_id
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"user_id": userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get digest subscription query for user_id=%s: %w", userID, err)
+	}
+
+	subscription, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[digest.Subscription])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:digest_subscriptions for user_id=%s: %w", userID, err)
+	}
+
+	return &subscription, nil
+}
+
+// PutSubscription creates or replaces the user's subscription
+func (r *DigestRepository) PutSubscription(ctx context.Context, userID string,
+	payload *digest.PutSubscriptionPayload, nextRunAt time.Time,
+) (*digest.Subscription, error) {
+	enabled := true
+	if payload.Enabled != nil {
+		enabled = *payload.Enabled
+	}
+
+	stmt := `
+		INSERT INTO
+			digest_subscriptions (
+				user_id,
+				frequency,
+				hour,
+				weekday,
+				timezone,
+				enabled,
+				next_run_at
+			)
+		VALUES
+			(
+				@user_id,
+				@frequency,
+				@hour,
+				@weekday,
+				@timezone,
+				@enabled,
+				@next_run_at
+			)
+		ON CONFLICT (user_id) DO UPDATE
+		SET
+			frequency = EXCLUDED.frequency,
+			hour = EXCLUDED.hour,
+			weekday = EXCLUDED.weekday,
+			timezone = EXCLUDED.timezone,
+			enabled = EXCLUDED.enabled,
+			next_run_at = EXCLUDED.next_run_at
+		RETURNING
+		*
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"user_id":     userID,
+		"frequency":   payload.Frequency,
+		"hour":        payload.Hour,
+		"weekday":     payload.Weekday,
+		"timezone":    payload.Timezone,
+		"enabled":     enabled,
+		"next_run_at": nextRunAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute put digest subscription query for user_id=%s: %w", userID, err)
+	}
+
+	subscription, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[digest.Subscription])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:digest_subscriptions for user_id=%s: %w", userID, err)
+	}
+
+	return &subscription, nil
+}
+
+// ClaimDueSubscriptions returns the enabled subscriptions that are due, as
+// they were before being claimed, and moves each to its next slot. Rows are
+// locked with SKIP LOCKED so concurrent schedulers never claim the same
+// subscription twice.
+func (r *DigestRepository) ClaimDueSubscriptions(ctx context.Context, now time.Time, limit int) ([]digest.Subscription, error) {
+	subscriptions := []digest.Subscription{}
+
+	err := pgx.BeginFunc(ctx, r.server.DB.Conn(ctx), func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT
+				*
+			FROM
+				digest_subscriptions
+			WHERE
+				enabled
+				AND next_run_at <= @now
+			ORDER BY
+				next_run_at ASC
+			LIMIT
+				@limit
+			FOR UPDATE SKIP LOCKED
+		`, pgx.NamedArgs{
+			"now":   now,
+			"limit": limit,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to execute get due digest subscriptions query: %w", err)
+		}
+
+		subscriptions, err = pgx.CollectRows(rows, pgx.RowToStructByName[digest.Subscription])
+		if err != nil {
+			return fmt.Errorf("failed to collect rows from table:digest_subscriptions: %w", err)
+		}
+
+		for _, subscription := range subscriptions {
+			nextRunAt := digest.NextRun(subscription.Frequency, subscription.Hour, subscription.Weekday,
+				subscription.Location(), now)
+
+			_, err := tx.Exec(ctx, `
+				UPDATE digest_subscriptions
+				SET
+					next_run_at = @next_run_at
+				WHERE
+					id = @id
+			`, pgx.NamedArgs{
+				"id":          subscription.ID,
+				"next_run_at": nextRunAt,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to advance digest subscription_id=%s: %w", subscription.ID.String(), err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+// MarkSent records when the user was last sent a digest
+func (r *DigestRepository) MarkSent(ctx context.Context, userID string, sentAt time.Time) error {
+	_, err := r.server.DB.Conn(ctx).Exec(ctx, `
+		UPDATE digest_subscriptions
+		SET
+			last_sent_at = @sent_at
+		WHERE
+			user_id = @user_id
+	`, pgx.NamedArgs{
+		"user_id": userID,
+		"sent_at": sentAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark digest sent for user_id=%s: %w", userID, err)
+	}
+
+	return nil
+}
+
+// GetTodos collects the user's open todos due before dayEnd, split into
+// overdue and due today at dayStart, and the todos completed since
+// completedSince. Each list holds at most limit todos.
+func (r *DigestRepository) GetTodos(ctx context.Context, userID string,
+	dayStart, dayEnd, completedSince time.Time, limit int,
+) (*digest.Todos, error) {
+	result := &digest.Todos{}
+
+	queries := []struct {
+		into  *[]todo.Todo
+		label string
+		stmt  string
+	}{
+		{
+			into:  &result.Overdue,
+			label: "overdue",
+			stmt: `
+				SELECT
+					*
+				FROM
+					todos
+				WHERE
+					user_id = @user_id
+					AND due_date < @day_start
+					AND status NOT IN ('completed', 'archived')
+				ORDER BY
+					due_date ASC,
+					id ASC
+				LIMIT
+					@limit
+			`,
+		},
+		{
+			into:  &result.DueToday,
+			label: "due today",
+			stmt: `
+				SELECT
+					*
+				FROM
+					todos
+				WHERE
+					user_id = @user_id
+					AND due_date >= @day_start
+					AND due_date < @day_end
+					AND status NOT IN ('completed', 'archived')
+				ORDER BY
+					due_date ASC,
+					id ASC
+				LIMIT
+					@limit
+			`,
+		},
+		{
+			into:  &result.Completed,
+			label: "completed",
+			stmt: `
+				SELECT
+					*
+				FROM
+					todos
+				WHERE
+					user_id = @user_id
+					AND status = 'completed'
+					AND completed_at >= @completed_since
+				ORDER BY
+					completed_at DESC,
+					id DESC
+				LIMIT
+					@limit
+			`,
+		},
+	}
+
+	args := pgx.NamedArgs{
+		"user_id":         userID,
+		"day_start":       dayStart,
+		"day_end":         dayEnd,
+		"completed_since": completedSince,
+		"limit":           limit,
+	}
+
+	for _, q := range queries {
+		rows, err := r.server.DB.Conn(ctx).Query(ctx, q.stmt, args)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute get %s digest todos query for user_id=%s: %w", q.label, userID, err)
+		}
+
+		todos, err := pgx.CollectRows(rows, pgx.RowToStructByName[todo.Todo])
+		if err != nil {
+			return nil, fmt.Errorf("failed to collect rows from table:todos for user_id=%s: %w", userID, err)
+		}
+		*q.into = todos
+	}
+
+	return result, nil
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// EmailTrackingRepository records the notification emails whose opens and
+// clicks are tracked, and their engagement
+type EmailTrackingRepository struct {
+	server *server.Server
+}
+
+func NewEmailTrackingRepository(server *server.Server) *EmailTrackingRepository {
+	return &EmailTrackingRepository{server: server}
+}
+
+// CreateMessage records an email to the user at address, unless a workspace
+// the user is a member of opted out of tracking. It reports whether the
+// message was recorded, and so is tracked.
+func (r *EmailTrackingRepository) CreateMessage(ctx context.Context, userID, address, template string,
+) (uuid.UUID, bool, error) {
+	stmt := `
+		INSERT INTO
+			email_messages (user_id, address, template)
+		SELECT
+			@user_id,
+			@address,
+			@template
+		WHERE
+			NOT EXISTS (
+				SELECT
+					1
+				FROM
+					workspace_members m
+					JOIN workspaces w ON w.id=m.workspace_id
+				WHERE
+					m.user_id=@user_id
+					AND NOT w.email_tracking
+			)
+		RETURNING
+			id
+	`
+
+	var id uuid.UUID
+	err := r.server.DB.Conn(ctx).QueryRow(ctx, stmt, pgx.NamedArgs{
+		"user_id":  userID,
+		"address":  address,
+		"template": template,
+	}).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, false, nil
+	}
+	if err != nil {
+		return uuid.Nil, false, fmt.Errorf("failed to execute create email message query for user_id=%s: %w", userID, err)
+	}
+
+	return id, true, nil
+}
+
+// RecordOpen counts an open of the message
+func (r *EmailTrackingRepository) RecordOpen(ctx context.Context, id uuid.UUID) error {
+	_, err := r.server.DB.Conn(ctx).Exec(ctx, `
+		UPDATE email_messages
+		SET
+			opened_at=COALESCE(opened_at, CURRENT_TIMESTAMP),
+			open_count=open_count + 1
+		WHERE
+			id=@id
+	`, pgx.NamedArgs{
+		"id": id,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record open of email message id=%s: %w", id.String(), err)
+	}
+
+	return nil
+}
+
+// RecordClick counts a click of a link in the message. A click also opens
+// it, as mail clients that block images never report the open.
+func (r *EmailTrackingRepository) RecordClick(ctx context.Context, id uuid.UUID) error {
+	_, err := r.server.DB.Conn(ctx).Exec(ctx, `
+		UPDATE email_messages
+		SET
+			opened_at=COALESCE(opened_at, CURRENT_TIMESTAMP),
+			clicked_at=COALESCE(clicked_at, CURRENT_TIMESTAMP),
+			click_count=click_count + 1
+		WHERE
+			id=@id
+	`, pgx.NamedArgs{
+		"id": id,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record click of email message id=%s: %w", id.String(), err)
+	}
+
+	return nil
+}
+
+// CountUnengagedToAddress returns how many of the last limit messages to
+// address were neither opened nor clicked
+func (r *EmailTrackingRepository) CountUnengagedToAddress(ctx context.Context, address string, limit int) (int, error) {
+	var count int
+	err := r.server.DB.Conn(ctx).QueryRow(ctx, `
+		SELECT
+			COUNT(*) FILTER (WHERE opened_at IS NULL)
+		FROM
+			(
+				SELECT
+					opened_at
+				FROM
+					email_messages
+				WHERE
+					address=@address
+				ORDER BY
+					created_at DESC,
+					id DESC
+				LIMIT
+					@limit
+			) latest
+	`, pgx.NamedArgs{
+		"address": address,
+		"limit":   limit,
+	}).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unengaged email messages: %w", err)
+	}
+
+	return count, nil
+}
+
+// CountUnengagedOfTemplate returns how many of the last limit messages of
+// template sent to the user were neither opened nor clicked
+func (r *EmailTrackingRepository) CountUnengagedOfTemplate(ctx context.Context, userID, template string,
+	limit int,
+) (int, error) {
+	var count int
+	err := r.server.DB.Conn(ctx).QueryRow(ctx, `
+		SELECT
+			COUNT(*) FILTER (WHERE opened_at IS NULL)
+		FROM
+			(
+				SELECT
+					opened_at
+				FROM
+					email_messages
+				WHERE
+					user_id=@user_id
+					AND template=@template
+				ORDER BY
+					created_at DESC,
+					id DESC
+				LIMIT
+					@limit
+			) latest
+	`, pgx.NamedArgs{
+		"user_id":  userID,
+		"template": template,
+		"limit":    limit,
+	}).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unengaged email messages for user_id=%s: %w", userID, err)
+	}
+
+	return count, nil
+}
+
+// DeleteMessagesBefore deletes up to limit messages sent before the given
+// time, and returns how many it deleted
+func (r *EmailTrackingRepository) DeleteMessagesBefore(ctx context.Context, before time.Time, limit int) (int, error) {
+	result, err := r.server.DB.Conn(ctx).Exec(ctx, `
+		DELETE FROM email_messages
+		WHERE
+			id IN (
+				SELECT
+					id
+				FROM
+					email_messages
+				WHERE
+					created_at < @before
+				LIMIT
+					@limit
+			)
+	`, pgx.NamedArgs{
+		"before": before,
+		"limit":  limit,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute delete email messages query: %w", err)
+	}
+
+	return int(result.RowsAffected()), nil
+}
@@ -0,0 +1,609 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/mabhi256/tasker/internal/lib/seal"
+	"github.com/mabhi256/tasker/internal/model"
+	"github.com/mabhi256/tasker/internal/model/export"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// ExportRepository stores export schedules with the credentials of their
+// destinations sealed, see seal.Sealer. Only GetScheduleForRun opens them.
+type ExportRepository struct {
+	server *server.Server
+}
+
+func NewExportRepository(server *server.Server) *ExportRepository {
+	return &ExportRepository{server: server}
+}
+
+func (r *ExportRepository) CreateSchedule(ctx context.Context, workspaceID uuid.UUID, userID string,
+	payload *export.CreateSchedulePayload, nextRunAt time.Time,
+) (*export.Schedule, error) {
+	enabled := true
+	if payload.Enabled != nil {
+		enabled = *payload.Enabled
+	}
+
+	destination, err := payload.Destination.Seal(r.server.Sealer.Seal)
+	if err != nil {
+		return nil, fmt.Errorf("failed to seal export destination for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	stmt := `
+		INSERT INTO
+			export_schedules (
+				workspace_id,
+				created_by,
+				name,
+				format,
+				destination_type,
+				destination,
+				frequency,
+				hour_utc,
+				weekday,
+				enabled,
+				next_run_at
+			)
+		VALUES
+			(
+				@workspace_id,
+				@created_by,
+				@name,
+				@format,
+				@destination_type,
+				@destination,
+				@frequency,
+				@hour_utc,
+				@weekday,
+				@enabled,
+				@next_run_at
+			)
+		RETURNING
+		*
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"workspace_id":     workspaceID,
+		"created_by":       userID,
+		"name":             payload.Name,
+		"format":           payload.Format,
+		"destination_type": payload.DestinationType,
+		"destination":      destination,
+		"frequency":        payload.Frequency,
+		"hour_utc":         payload.HourUTC,
+		"weekday":          payload.Weekday,
+		"enabled":          enabled,
+		"next_run_at":      nextRunAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute create export schedule query for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	schedule, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[export.Schedule])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:export_schedules for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	return &schedule, nil
+}
+
+func (r *ExportRepository) GetSchedules(ctx context.Context, workspaceID uuid.UUID) ([]export.Schedule, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			export_schedules
+		WHERE
+			workspace_id=@workspace_id
+		ORDER BY
+			created_at ASC,
+			id ASC
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get export schedules query for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	schedules, err := pgx.CollectRows(rows, pgx.RowToStructByName[export.Schedule])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return []export.Schedule{}, nil
+		}
+		return nil, fmt.Errorf("failed to collect rows from table:export_schedules for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	return schedules, nil
+}
+
+func (r *ExportRepository) GetScheduleByID(ctx context.Context, workspaceID uuid.UUID, scheduleID uuid.UUID) (*export.Schedule, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			export_schedules
+		WHERE
+			id=@id
+			AND workspace_id=@workspace_id
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"id":           scheduleID,
+		"workspace_id": workspaceID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get export schedule by id query for schedule_id=%s workspace_id=%s: %w", scheduleID.String(), workspaceID.String(), err)
+	}
+
+	schedule, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[export.Schedule])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:export_schedules for schedule_id=%s workspace_id=%s: %w", scheduleID.String(), workspaceID.String(), err)
+	}
+
+	return &schedule, nil
+}
+
+// GetScheduleForRun loads the schedule behind a run, for use by background jobs
+func (r *ExportRepository) GetScheduleForRun(ctx context.Context, runID uuid.UUID) (*export.Schedule, error) {
+	stmt := `
+		SELECT
+			s.*
+		FROM
+			export_schedules s
+			JOIN export_runs er ON er.schedule_id=s.id
+		WHERE
+			er.id=@run_id
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"run_id": runID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get export schedule for run query for run_id=%s: %w", runID.String(), err)
+	}
+
+	schedule, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[export.Schedule])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:export_schedules for run_id=%s: %w", runID.String(), err)
+	}
+
+	schedule.Destination, err = schedule.Destination.Open(r.server.Sealer.Open)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open export destination for run_id=%s: %w", runID.String(), err)
+	}
+
+	return &schedule, nil
+}
+
+// UpdateSchedule applies the payload. Timing fields are always written
+// because the caller recomputes them whenever frequency or hour change.
+func (r *ExportRepository) UpdateSchedule(ctx context.Context, workspaceID uuid.UUID,
+	payload *export.UpdateSchedulePayload, weekday *int, nextRunAt time.Time,
+) (*export.Schedule, error) {
+	stmt := `UPDATE export_schedules SET `
+	args := pgx.NamedArgs{
+		"id":           payload.ID,
+		"workspace_id": workspaceID,
+		"weekday":      weekday,
+		"next_run_at":  nextRunAt,
+	}
+	setClauses := []string{"weekday = @weekday", "next_run_at = @next_run_at"}
+
+	if payload.Name != nil {
+		setClauses = append(setClauses, "name = @name")
+		args["name"] = *payload.Name
+	}
+	if payload.Format != nil {
+		setClauses = append(setClauses, "format = @format")
+		args["format"] = *payload.Format
+	}
+	if payload.DestinationType != nil && payload.Destination != nil {
+		destination, err := payload.Destination.Seal(r.server.Sealer.Seal)
+		if err != nil {
+			return nil, fmt.Errorf("failed to seal export destination for schedule_id=%s: %w", payload.ID.String(), err)
+		}
+		setClauses = append(setClauses, "destination_type = @destination_type", "destination = @destination")
+		args["destination_type"] = *payload.DestinationType
+		args["destination"] = destination
+	}
+	if payload.Frequency != nil {
+		setClauses = append(setClauses, "frequency = @frequency")
+		args["frequency"] = *payload.Frequency
+	}
+	if payload.HourUTC != nil {
+		setClauses = append(setClauses, "hour_utc = @hour_utc")
+		args["hour_utc"] = *payload.HourUTC
+	}
+	if payload.Enabled != nil {
+		setClauses = append(setClauses, "enabled = @enabled")
+		args["enabled"] = *payload.Enabled
+	}
+
+	stmt += strings.Join(setClauses, ", ")
+	stmt += ` WHERE id = @id AND workspace_id = @workspace_id RETURNING *`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute update export schedule query for schedule_id=%s workspace_id=%s: %w", payload.ID.String(), workspaceID.String(), err)
+	}
+
+	schedule, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[export.Schedule])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:export_schedules for schedule_id=%s workspace_id=%s: %w", payload.ID.String(), workspaceID.String(), err)
+	}
+
+	return &schedule, nil
+}
+
+func (r *ExportRepository) DeleteSchedule(ctx context.Context, workspaceID uuid.UUID, scheduleID uuid.UUID) error {
+	result, err := r.server.DB.Conn(ctx).Exec(ctx, `
+		DELETE FROM export_schedules
+		WHERE id = @id AND workspace_id = @workspace_id
+	`, pgx.NamedArgs{
+		"id":           scheduleID,
+		"workspace_id": workspaceID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete export schedule: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("export schedule not found")
+	}
+
+	return nil
+}
+
+// SealDestinations seals the credentials of up to limit destinations saved
+// before they were sealed, returning how many it sealed
+func (r *ExportRepository) SealDestinations(ctx context.Context, limit int) (int, error) {
+	sealed := 0
+
+	err := pgx.BeginFunc(ctx, r.server.DB.Conn(ctx), func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT
+				*
+			FROM
+				export_schedules
+			WHERE
+				destination->'s3'->>'accessKeyId' NOT LIKE @sealed
+				OR destination->'s3'->>'secretAccessKey' NOT LIKE @sealed
+				OR destination->'sftp'->>'password' NOT LIKE @sealed
+				OR destination->'sftp'->>'privateKey' NOT LIKE @sealed
+			ORDER BY
+				id
+			LIMIT
+				@limit
+			FOR UPDATE SKIP LOCKED
+		`, pgx.NamedArgs{
+			"sealed": seal.Prefix + "%",
+			"limit":  limit,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to execute get unsealed export destinations query: %w", err)
+		}
+
+		schedules, err := pgx.CollectRows(rows, pgx.RowToStructByName[export.Schedule])
+		if err != nil {
+			return fmt.Errorf("failed to collect rows from table:export_schedules: %w", err)
+		}
+
+		for _, schedule := range schedules {
+			destination, err := schedule.Destination.Seal(func(value string) (string, error) {
+				if seal.IsSealed(value) {
+					return value, nil
+				}
+				return r.server.Sealer.Seal(value)
+			})
+			if err != nil {
+				return fmt.Errorf("failed to seal export destination for schedule_id=%s: %w", schedule.ID.String(), err)
+			}
+
+			_, err = tx.Exec(ctx, `
+				UPDATE export_schedules
+				SET
+					destination = @destination
+				WHERE
+					id = @id
+			`, pgx.NamedArgs{
+				"id":          schedule.ID,
+				"destination": destination,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to seal export destination for schedule_id=%s: %w", schedule.ID.String(), err)
+			}
+		}
+
+		sealed = len(schedules)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return sealed, nil
+}
+
+// ClaimDueSchedules creates a pending run for each enabled schedule that is
+// due and moves the schedule to its next slot. Rows are locked with SKIP
+// LOCKED so concurrent schedulers never claim the same schedule twice.
+func (r *ExportRepository) ClaimDueSchedules(ctx context.Context, now time.Time, limit int) ([]export.Run, error) {
+	runs := []export.Run{}
+
+	err := pgx.BeginFunc(ctx, r.server.DB.Conn(ctx), func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT
+				*
+			FROM
+				export_schedules
+			WHERE
+				enabled
+				AND next_run_at <= @now
+			ORDER BY
+				next_run_at ASC
+			LIMIT
+				@limit
+			FOR UPDATE SKIP LOCKED
+		`, pgx.NamedArgs{
+			"now":   now,
+			"limit": limit,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to execute get due export schedules query: %w", err)
+		}
+
+		schedules, err := pgx.CollectRows(rows, pgx.RowToStructByName[export.Schedule])
+		if err != nil {
+			return fmt.Errorf("failed to collect rows from table:export_schedules: %w", err)
+		}
+
+		for _, schedule := range schedules {
+			nextRunAt := export.NextRun(schedule.Frequency, schedule.HourUTC, schedule.Weekday, now)
+
+			_, err := tx.Exec(ctx, `
+				UPDATE export_schedules
+				SET
+					next_run_at = @next_run_at,
+					last_run_at = @now
+				WHERE
+					id = @id
+			`, pgx.NamedArgs{
+				"id":          schedule.ID,
+				"next_run_at": nextRunAt,
+				"now":         now,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to advance export schedule_id=%s: %w", schedule.ID.String(), err)
+			}
+
+			run, err := r.createRun(ctx, tx, schedule.ID)
+			if err != nil {
+				return err
+			}
+			runs = append(runs, *run)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return runs, nil
+}
+
+// CreateRun queues an immediate run outside of the schedule
+func (r *ExportRepository) CreateRun(ctx context.Context, scheduleID uuid.UUID) (*export.Run, error) {
+	return r.createRun(ctx, r.server.DB.Conn(ctx), scheduleID)
+}
+
+type queryer interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+func (r *ExportRepository) createRun(ctx context.Context, q queryer, scheduleID uuid.UUID) (*export.Run, error) {
+	rows, err := q.Query(ctx, `
+		INSERT INTO
+			export_runs (schedule_id)
+		VALUES
+			(@schedule_id)
+		RETURNING
+		*
+	`, pgx.NamedArgs{
+		"schedule_id": scheduleID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute create export run query for schedule_id=%s: %w", scheduleID.String(), err)
+	}
+
+	run, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[export.Run])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:export_runs for schedule_id=%s: %w", scheduleID.String(), err)
+	}
+
+	return &run, nil
+}
+
+func (r *ExportRepository) GetRunByID(ctx context.Context, runID uuid.UUID) (*export.Run, error) {
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, `
+		SELECT
+			*
+		FROM
+			export_runs
+		WHERE
+			id=@id
+	`, pgx.NamedArgs{
+		"id": runID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get export run by id query for run_id=%s: %w", runID.String(), err)
+	}
+
+	run, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[export.Run])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:export_runs for run_id=%s: %w", runID.String(), err)
+	}
+
+	return &run, nil
+}
+
+// StartRun marks the run as running and counts the attempt
+func (r *ExportRepository) StartRun(ctx context.Context, runID uuid.UUID) (*export.Run, error) {
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, `
+		UPDATE export_runs
+		SET
+			status = 'running',
+			attempts = attempts + 1,
+			started_at = COALESCE(started_at, NOW()),
+			error = NULL
+		WHERE
+			id = @id
+			AND status IN ('pending', 'running')
+		RETURNING
+		*
+	`, pgx.NamedArgs{
+		"id": runID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute start export run query for run_id=%s: %w", runID.String(), err)
+	}
+
+	run, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[export.Run])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:export_runs for run_id=%s: %w", runID.String(), err)
+	}
+
+	return &run, nil
+}
+
+// CompleteRun stores the delivery receipt
+func (r *ExportRepository) CompleteRun(ctx context.Context, runID uuid.UUID, receipt *export.Receipt) error {
+	_, err := r.server.DB.Conn(ctx).Exec(ctx, `
+		UPDATE export_runs
+		SET
+			status = 'succeeded',
+			finished_at = NOW(),
+			location = @location,
+			row_count = @row_count,
+			size_bytes = @size_bytes,
+			checksum_sha256 = @checksum_sha256,
+			error = NULL
+		WHERE
+			id = @id
+	`, pgx.NamedArgs{
+		"id":              runID,
+		"location":        receipt.Location,
+		"row_count":       receipt.RowCount,
+		"size_bytes":      receipt.SizeBytes,
+		"checksum_sha256": receipt.ChecksumSHA256,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to complete export run_id=%s: %w", runID.String(), err)
+	}
+
+	return nil
+}
+
+// RecordRunError stores the latest error. The run is only marked failed once
+// no retries are left.
+func (r *ExportRepository) RecordRunError(ctx context.Context, runID uuid.UUID, errMsg string, final bool) error {
+	status := export.RunStatusRunning
+	if final {
+		status = export.RunStatusFailed
+	}
+
+	_, err := r.server.DB.Conn(ctx).Exec(ctx, `
+		UPDATE export_runs
+		SET
+			status = @status,
+			error = @error,
+			finished_at = CASE WHEN @final THEN NOW() ELSE finished_at END
+		WHERE
+			id = @id
+	`, pgx.NamedArgs{
+		"id":     runID,
+		"status": status,
+		"error":  errMsg,
+		"final":  final,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record error for export run_id=%s: %w", runID.String(), err)
+	}
+
+	return nil
+}
+
+func (r *ExportRepository) GetRuns(ctx context.Context, workspaceID uuid.UUID,
+	query *export.GetRunsPayload,
+) (*model.PaginatedResponse[export.Run], error) {
+	args := pgx.NamedArgs{
+		"schedule_id":  query.ID,
+		"workspace_id": workspaceID,
+		"limit":        *query.Limit,
+		"offset":       (*query.Page - 1) * *query.Limit,
+	}
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, `
+		SELECT
+			er.*
+		FROM
+			export_runs er
+			JOIN export_schedules s ON s.id=er.schedule_id
+		WHERE
+			er.schedule_id=@schedule_id
+			AND s.workspace_id=@workspace_id
+		ORDER BY
+			er.created_at DESC,
+			er.id DESC
+		LIMIT
+			@limit
+		OFFSET
+			@offset
+	`, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get export runs query for schedule_id=%s: %w", query.ID.String(), err)
+	}
+
+	runs, err := pgx.CollectRows(rows, pgx.RowToStructByName[export.Run])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			runs = []export.Run{}
+		} else {
+			return nil, fmt.Errorf("failed to collect rows from table:export_runs for schedule_id=%s: %w", query.ID.String(), err)
+		}
+	}
+
+	var total int
+	err = r.server.DB.Conn(ctx).QueryRow(ctx, `
+		SELECT
+			COUNT(*)
+		FROM
+			export_runs er
+			JOIN export_schedules s ON s.id=er.schedule_id
+		WHERE
+			er.schedule_id=@schedule_id
+			AND s.workspace_id=@workspace_id
+	`, args).Scan(&total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get total count of export runs for schedule_id=%s: %w", query.ID.String(), err)
+	}
+
+	return &model.PaginatedResponse[export.Run]{
+		Data:       runs,
+		Page:       *query.Page,
+		Limit:      *query.Limit,
+		Total:      total,
+		TotalPages: (total + *query.Limit - 1) / *query.Limit,
+	}, nil
+}
@@ -0,0 +1,258 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/model/ipallowlist"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+type IPAllowlistRepository struct {
+	server *server.Server
+}
+
+func NewIPAllowlistRepository(server *server.Server) *IPAllowlistRepository {
+	return &IPAllowlistRepository{server: server}
+}
+
+// GetAllowlist returns the workspace's settings and networks. A workspace
+// that never configured an allowlist has it disabled and empty.
+func (r *IPAllowlistRepository) GetAllowlist(ctx context.Context, workspaceID uuid.UUID) (*ipallowlist.Allowlist, error) {
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, `
+		SELECT
+			*
+		FROM
+			workspace_ip_allowlists
+		WHERE
+			workspace_id=@workspace_id
+	`, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get ip allowlist query for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	settings, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[ipallowlist.Settings])
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("failed to collect row from table:workspace_ip_allowlists for workspace_id=%s: %w", workspaceID.String(), err)
+		}
+		settings = ipallowlist.Settings{WorkspaceID: workspaceID}
+	}
+
+	rows, err = r.server.DB.Conn(ctx).Query(ctx, `
+		SELECT
+			*
+		FROM
+			workspace_ip_allowlist_entries
+		WHERE
+			workspace_id=@workspace_id
+		ORDER BY
+			created_at ASC,
+			id ASC
+	`, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get ip allowlist entries query for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	entries, err := pgx.CollectRows(rows, pgx.RowToStructByName[ipallowlist.Entry])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:workspace_ip_allowlist_entries for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	return &ipallowlist.Allowlist{Settings: settings, Entries: entries}, nil
+}
+
+func (r *IPAllowlistRepository) SetEnabled(ctx context.Context, workspaceID uuid.UUID, userID string,
+	enabled bool,
+) (*ipallowlist.Settings, error) {
+	stmt := `
+		INSERT INTO
+			workspace_ip_allowlists (
+				workspace_id,
+				enabled,
+				updated_by
+			)
+		VALUES
+			(
+				@workspace_id,
+				@enabled,
+				@updated_by
+			)
+		ON CONFLICT (workspace_id) DO UPDATE
+		SET
+			enabled = EXCLUDED.enabled,
+			updated_by = EXCLUDED.updated_by
+		RETURNING
+		*
+	`
+
+	return r.upsertSettings(ctx, workspaceID, stmt, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+		"enabled":      enabled,
+		"updated_by":   userID,
+	})
+}
+
+// SetBreakGlass lifts the restriction until the given time
+func (r *IPAllowlistRepository) SetBreakGlass(ctx context.Context, workspaceID uuid.UUID, userID string,
+	until time.Time, reason string,
+) (*ipallowlist.Settings, error) {
+	stmt := `
+		INSERT INTO
+			workspace_ip_allowlists (
+				workspace_id,
+				updated_by,
+				break_glass_until,
+				break_glass_by,
+				break_glass_reason
+			)
+		VALUES
+			(
+				@workspace_id,
+				@user_id,
+				@until,
+				@user_id,
+				@reason
+			)
+		ON CONFLICT (workspace_id) DO UPDATE
+		SET
+			break_glass_until = EXCLUDED.break_glass_until,
+			break_glass_by = EXCLUDED.break_glass_by,
+			break_glass_reason = EXCLUDED.break_glass_reason
+		RETURNING
+		*
+	`
+
+	return r.upsertSettings(ctx, workspaceID, stmt, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+		"user_id":      userID,
+		"until":        until,
+		"reason":       reason,
+	})
+}
+
+// ClearBreakGlass restores the restriction
+func (r *IPAllowlistRepository) ClearBreakGlass(ctx context.Context, workspaceID uuid.UUID) error {
+	result, err := r.server.DB.Conn(ctx).Exec(ctx, `
+		UPDATE
+			workspace_ip_allowlists
+		SET
+			break_glass_until = NULL,
+			break_glass_by = NULL,
+			break_glass_reason = NULL
+		WHERE
+			workspace_id = @workspace_id
+			AND break_glass_until > CURRENT_TIMESTAMP
+	`, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to execute clear break glass query for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	if result.RowsAffected() == 0 {
+		code := "BREAK_GLASS_NOT_ACTIVE"
+		return errs.NewNotFoundError("Break glass is not active", false, &code)
+	}
+
+	return nil
+}
+
+func (r *IPAllowlistRepository) upsertSettings(ctx context.Context, workspaceID uuid.UUID, stmt string,
+	args pgx.NamedArgs,
+) (*ipallowlist.Settings, error) {
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute upsert ip allowlist query for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	settings, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[ipallowlist.Settings])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:workspace_ip_allowlists for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	return &settings, nil
+}
+
+// ------------------------------------------------------------
+// Entries
+
+func (r *IPAllowlistRepository) CreateEntry(ctx context.Context, workspaceID uuid.UUID, userID string,
+	cidr netip.Prefix, description *string,
+) (*ipallowlist.Entry, error) {
+	stmt := `
+		INSERT INTO
+			workspace_ip_allowlist_entries (
+				workspace_id,
+				created_by,
+				cidr,
+				description
+			)
+		VALUES
+			(
+				@workspace_id,
+				@created_by,
+				@cidr,
+				@description
+			)
+		RETURNING
+		*
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+		"created_by":   userID,
+		"cidr":         cidr,
+		"description":  description,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute create ip allowlist entry query for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	entry, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[ipallowlist.Entry])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:workspace_ip_allowlist_entries for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	return &entry, nil
+}
+
+func (r *IPAllowlistRepository) DeleteEntry(ctx context.Context, workspaceID uuid.UUID,
+	entryID uuid.UUID,
+) (*ipallowlist.Entry, error) {
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, `
+		DELETE FROM workspace_ip_allowlist_entries
+		WHERE
+			id = @id
+			AND workspace_id = @workspace_id
+		RETURNING
+		*
+	`, pgx.NamedArgs{
+		"id":           entryID,
+		"workspace_id": workspaceID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute delete ip allowlist entry query for id=%s: %w", entryID.String(), err)
+	}
+
+	entry, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[ipallowlist.Entry])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "IP_ALLOWLIST_ENTRY_NOT_FOUND"
+			return nil, errs.NewNotFoundError("IP allowlist entry not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:workspace_ip_allowlist_entries for id=%s: %w", entryID.String(), err)
+	}
+
+	return &entry, nil
+}
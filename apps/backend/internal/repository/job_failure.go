@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mabhi256/tasker/internal/lib/job"
+	"github.com/mabhi256/tasker/internal/model/jobfailure"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+type JobFailureRepository struct {
+	server *server.Server
+}
+
+func NewJobFailureRepository(server *server.Server) *JobFailureRepository {
+	return &JobFailureRepository{server: server}
+}
+
+// RecordJobFailure stores a failed task and returns nil if it was already
+// recorded. The error handler and the archive watcher report the same
+// failure moments apart, so a failure within a minute of the recorded one
+// is a repeat; a later one means the task was retried and failed again.
+func (r *JobFailureRepository) RecordJobFailure(ctx context.Context, failure *job.JobFailure) (*jobfailure.JobFailure, error) {
+	stmt := `
+		INSERT INTO
+			job_failures (
+				task_id,
+				queue,
+				task_type,
+				payload,
+				error,
+				retried,
+				max_retry,
+				failed_at
+			)
+		VALUES
+			(
+				@task_id,
+				@queue,
+				@task_type,
+				@payload,
+				@error,
+				@retried,
+				@max_retry,
+				@failed_at
+			)
+		ON CONFLICT (queue, task_id) DO UPDATE
+		SET
+			payload = EXCLUDED.payload,
+			error = EXCLUDED.error,
+			retried = EXCLUDED.retried,
+			max_retry = EXCLUDED.max_retry,
+			failed_at = EXCLUDED.failed_at,
+			occurrences = job_failures.occurrences + 1
+		WHERE
+			job_failures.failed_at < EXCLUDED.failed_at - INTERVAL '1 minute'
+		RETURNING
+			*
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"task_id":   failure.TaskID,
+		"queue":     failure.Queue,
+		"task_type": failure.Type,
+		"payload":   failure.Payload,
+		"error":     failure.Error,
+		"retried":   failure.Retried,
+		"max_retry": failure.MaxRetry,
+		"failed_at": failure.FailedAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute record job failure query for task_id=%s queue=%s: %w",
+			failure.TaskID, failure.Queue, err)
+	}
+
+	item, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[jobfailure.JobFailure])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to collect row from table:job_failures for task_id=%s queue=%s: %w",
+			failure.TaskID, failure.Queue, err)
+	}
+
+	return &item, nil
+}
@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/mabhi256/tasker/internal/database"
+	"github.com/mabhi256/tasker/internal/lib/tracing"
+	"github.com/mabhi256/tasker/internal/model/outbox"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// OutboxNotifyChannel is notified by a trigger whenever events are added
+const OutboxNotifyChannel = "event_outbox"
+
+type OutboxRepository struct {
+	server *server.Server
+}
+
+func NewOutboxRepository(server *server.Server) *OutboxRepository {
+	return &OutboxRepository{server: server}
+}
+
+// insertOutboxEvent records an event in the caller's transaction, so the
+// event exists if and only if the change that caused it is committed. The
+// trace of the transaction in ctx is kept so the relay can continue it, and
+// the user of its scope as the event's actor.
+func insertOutboxEvent(ctx context.Context, tx pgx.Tx, workspaceID uuid.UUID, eventType string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox event %s: %w", eventType, err)
+	}
+
+	var actorID *string
+	if scope, ok := database.ScopeFromContext(ctx); ok && scope.UserID != "" {
+		actorID = &scope.UserID
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO
+			event_outbox (workspace_id, event_type, payload, trace, actor_id)
+		VALUES
+			(@workspace_id, @event_type, @payload, @trace, @actor_id)
+	`, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+		"event_type":   eventType,
+		"payload":      payload,
+		"trace":        tracing.Headers(ctx),
+		"actor_id":     actorID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to insert into table:event_outbox for workspace_id=%s event_type=%s: %w",
+			workspaceID.String(), eventType, err)
+	}
+
+	return nil
+}
+
+// InsertAuditEvent records an audit event for the relay to forward. Audit
+// events describe changes that already happened, so they are inserted on
+// their own rather than in the change's transaction. workspaceID is nil for
+// events of the admin API.
+func (r *OutboxRepository) InsertAuditEvent(ctx context.Context, workspaceID *uuid.UUID, eventType string,
+	record any,
+) error {
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit event %s: %w", eventType, err)
+	}
+
+	_, err = r.server.DB.Conn(ctx).Exec(ctx, `
+		INSERT INTO
+			event_outbox (workspace_id, event_type, payload, trace)
+		VALUES
+			(@workspace_id, @event_type, @payload, @trace)
+	`, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+		"event_type":   eventType,
+		"payload":      payload,
+		"trace":        tracing.Headers(ctx),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to insert audit event into table:event_outbox for event_type=%s: %w", eventType, err)
+	}
+
+	return nil
+}
+
+// RelayBatch locks up to limit unpublished events, oldest first, and hands
+// each to relay. Events relay accepts are marked published; the rest are
+// retried later with a backoff, until their maxAttempts-th failure marks
+// them dead. Rows are locked with SKIP LOCKED so several relays can run
+// side by side without publishing an event twice.
+func (r *OutboxRepository) RelayBatch(ctx context.Context, limit, maxAttempts int,
+	relay func(ctx context.Context, event *outbox.Event) error,
+) (int, error) {
+	count := 0
+
+	err := pgx.BeginFunc(ctx, r.server.DB.Conn(ctx), func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT
+				*
+			FROM
+				event_outbox
+			WHERE
+				published_at IS NULL
+				AND dead_at IS NULL
+				AND available_at <= NOW()
+			ORDER BY
+				created_at ASC
+			LIMIT
+				@limit
+			FOR UPDATE SKIP LOCKED
+		`, pgx.NamedArgs{
+			"limit": limit,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to execute get unpublished outbox events query: %w", err)
+		}
+
+		events, err := pgx.CollectRows(rows, pgx.RowToStructByName[outbox.Event])
+		if err != nil {
+			return fmt.Errorf("failed to collect rows from table:event_outbox: %w", err)
+		}
+
+		for i := range events {
+			event := &events[i]
+
+			if relayErr := relay(ctx, event); relayErr != nil {
+				// Back off exponentially, capped at 15 minutes. The exponent
+				// is capped first, as 2^attempts seconds overflows an
+				// interval after enough attempts.
+				_, err := tx.Exec(ctx, `
+					UPDATE event_outbox
+					SET
+						attempts = attempts + 1,
+						last_error = @last_error,
+						available_at = NOW() + LEAST(INTERVAL '1 second' * POWER(2, LEAST(attempts, 10)), INTERVAL '15 minutes'),
+						dead_at = CASE
+							WHEN attempts + 1 >= @max_attempts THEN NOW()
+						END
+					WHERE
+						id = @id
+				`, pgx.NamedArgs{
+					"id":           event.ID,
+					"last_error":   relayErr.Error(),
+					"max_attempts": maxAttempts,
+				})
+				if err != nil {
+					return fmt.Errorf("failed to record outbox event_id=%s error: %w", event.ID.String(), err)
+				}
+				continue
+			}
+
+			_, err := tx.Exec(ctx, `
+				UPDATE event_outbox
+				SET
+					attempts = attempts + 1,
+					last_error = NULL,
+					published_at = NOW()
+				WHERE
+					id = @id
+			`, pgx.NamedArgs{
+				"id": event.ID,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to mark outbox event_id=%s published: %w", event.ID.String(), err)
+			}
+		}
+
+		count = len(events)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// DeletePublishedEvents removes events published, or given up on as dead,
+// before the cutoff
+func (r *OutboxRepository) DeletePublishedEvents(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.server.DB.Conn(ctx).Exec(ctx, `
+		DELETE FROM event_outbox
+		WHERE
+			published_at < @before
+			OR dead_at < @before
+	`, pgx.NamedArgs{
+		"before": before,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete published events from table:event_outbox: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,108 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/model/outbox"
+	"github.com/mabhi256/tasker/internal/repository"
+	testutil "github.com/mabhi256/tasker/internal/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRelayBatchBackoff fails an event long past the point where its backoff
+// would overflow an interval, then until it runs out of attempts, without
+// holding up the events relayed alongside it
+func TestRelayBatchBackoff(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping outbox tests in short mode")
+	}
+
+	testDB, srv, cleanup := testutil.SetupTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	outboxRepo := repository.NewOutboxRepository(srv)
+	const maxAttempts = 100
+
+	var workspaceID uuid.UUID
+	require.NoError(t, testDB.Pool.QueryRow(ctx, `
+		INSERT INTO workspaces (name, owner_id, is_personal)
+		VALUES ('Workspace', 'user-1', TRUE)
+		RETURNING id
+	`).Scan(&workspaceID))
+
+	insert := func(attempts int, createdAt time.Time) uuid.UUID {
+		var id uuid.UUID
+		require.NoError(t, testDB.Pool.QueryRow(ctx, `
+			INSERT INTO event_outbox (workspace_id, event_type, payload, attempts, created_at, available_at)
+			VALUES ($1, 'todo.updated', '{}', $2, $3, $3)
+			RETURNING id
+		`, workspaceID, attempts, createdAt).Scan(&id))
+		return id
+	}
+	state := func(id uuid.UUID) (attempts int, availableAt time.Time, published, dead bool) {
+		require.NoError(t, testDB.Pool.QueryRow(ctx, `
+			SELECT attempts, available_at, published_at IS NOT NULL, dead_at IS NOT NULL
+			FROM event_outbox
+			WHERE id = $1
+		`, id).Scan(&attempts, &availableAt, &published, &dead))
+		return attempts, availableAt, published, dead
+	}
+	due := func(id uuid.UUID) {
+		_, err := testDB.Pool.Exec(ctx, `UPDATE event_outbox SET available_at = NOW() WHERE id = $1`, id)
+		require.NoError(t, err)
+	}
+
+	// The poison event is oldest, so it is relayed first in every batch
+	poison := insert(60, time.Now().Add(-time.Hour))
+	healthy := insert(0, time.Now().Add(-time.Minute))
+
+	relay := func(_ context.Context, event *outbox.Event) error {
+		if event.ID == poison {
+			return errors.New("sink unavailable")
+		}
+		return nil
+	}
+
+	count, err := outboxRepo.RelayBatch(ctx, 10, maxAttempts, relay)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	attempts, availableAt, published, dead := state(poison)
+	assert.Equal(t, 61, attempts)
+	assert.False(t, published)
+	assert.False(t, dead)
+	assert.WithinDuration(t, time.Now().Add(15*time.Minute), availableAt, time.Minute)
+
+	_, _, published, _ = state(healthy)
+	assert.True(t, published, "an event relayed in the same batch as a failing one is published")
+
+	// Its last attempt marks it dead, after which it is no longer relayed
+	_, err = testDB.Pool.Exec(ctx, `UPDATE event_outbox SET attempts = $2 WHERE id = $1`, poison, maxAttempts-1)
+	require.NoError(t, err)
+	due(poison)
+
+	count, err = outboxRepo.RelayBatch(ctx, 10, maxAttempts, relay)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	attempts, _, published, dead = state(poison)
+	assert.Equal(t, maxAttempts, attempts)
+	assert.False(t, published)
+	assert.True(t, dead)
+
+	due(poison)
+	count, err = outboxRepo.RelayBatch(ctx, 10, maxAttempts, relay)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	// Dead events are cleaned up with the published ones
+	deleted, err := outboxRepo.DeletePublishedEvents(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+}
@@ -0,0 +1,1055 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/model/provisioning"
+	"github.com/mabhi256/tasker/internal/model/workspace"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// ProvisioningRepository stores what identity providers provision over
+// SCIM. Every change to a user or group also brings the workspace
+// memberships it affects in line, in the same transaction.
+type ProvisioningRepository struct {
+	server *server.Server
+}
+
+func NewProvisioningRepository(server *server.Server) *ProvisioningRepository {
+	return &ProvisioningRepository{server: server}
+}
+
+// UserQuery selects a page of provisioned users. Set filters are matched
+// exactly, user names and emails without regard to case.
+type UserQuery struct {
+	UserName   *string
+	ExternalID *string
+	Email      *string
+	Offset     int
+	Limit      int
+}
+
+// GroupQuery selects a page of provisioned groups, or all of them without
+// a limit
+type GroupQuery struct {
+	DisplayName *string
+	ExternalID  *string
+	Offset      int
+	Limit       *int
+}
+
+// groupColumns selects a group with the ids of its members, for queries
+// grouped by g.id
+const groupColumns = `
+	g.*,
+	COALESCE(
+		array_agg(gm.scim_user_id ORDER BY gm.scim_user_id) FILTER (WHERE gm.scim_user_id IS NOT NULL),
+		'{}'
+	) AS member_ids
+`
+
+// ------------------------------------------------------------
+// Tokens
+
+// UpsertToken creates the workspace's token, replacing any it had
+func (r *ProvisioningRepository) UpsertToken(ctx context.Context, workspaceID uuid.UUID, userID string,
+	tokenHash string,
+) (*provisioning.Token, error) {
+	stmt := `
+		INSERT INTO
+			scim_tokens (
+				workspace_id,
+				created_by,
+				token_hash
+			)
+		VALUES
+			(
+				@workspace_id,
+				@created_by,
+				@token_hash
+			)
+		ON CONFLICT (workspace_id) DO UPDATE
+		SET
+			id = gen_random_uuid(),
+			created_at = CURRENT_TIMESTAMP,
+			created_by = EXCLUDED.created_by,
+			token_hash = EXCLUDED.token_hash,
+			last_used_at = NULL
+		RETURNING
+		*
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+		"created_by":   userID,
+		"token_hash":   tokenHash,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute upsert scim token query for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	token, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[provisioning.Token])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:scim_tokens for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	return &token, nil
+}
+
+func (r *ProvisioningRepository) GetToken(ctx context.Context, workspaceID uuid.UUID) (*provisioning.Token, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			scim_tokens
+		WHERE
+			workspace_id=@workspace_id
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get scim token query for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	token, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[provisioning.Token])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "SCIM_TOKEN_NOT_FOUND"
+			return nil, errs.NewNotFoundError("SCIM token not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:scim_tokens for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	return &token, nil
+}
+
+// UseToken returns the token with the hash and records that it was used
+func (r *ProvisioningRepository) UseToken(ctx context.Context, tokenHash string) (*provisioning.Token, error) {
+	stmt := `
+		UPDATE
+			scim_tokens
+		SET
+			last_used_at = CURRENT_TIMESTAMP
+		WHERE
+			token_hash=@token_hash
+		RETURNING
+		*
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"token_hash": tokenHash,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute use scim token query: %w", err)
+	}
+
+	token, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[provisioning.Token])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "SCIM_TOKEN_NOT_FOUND"
+			return nil, errs.NewNotFoundError("SCIM token not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:scim_tokens: %w", err)
+	}
+
+	return &token, nil
+}
+
+func (r *ProvisioningRepository) DeleteToken(ctx context.Context, workspaceID uuid.UUID) error {
+	result, err := r.server.DB.Conn(ctx).Exec(ctx, `
+		DELETE FROM scim_tokens
+		WHERE workspace_id = @workspace_id
+	`, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to execute delete scim token query for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	if result.RowsAffected() == 0 {
+		code := "SCIM_TOKEN_NOT_FOUND"
+		return errs.NewNotFoundError("SCIM token not found", false, &code)
+	}
+
+	return nil
+}
+
+// ------------------------------------------------------------
+// Users
+
+func (r *ProvisioningRepository) GetUsers(ctx context.Context, workspaceID uuid.UUID,
+	query *UserQuery,
+) ([]provisioning.User, int, error) {
+	args := pgx.NamedArgs{
+		"workspace_id": workspaceID,
+		"user_name":    query.UserName,
+		"external_id":  query.ExternalID,
+		"email":        query.Email,
+		"offset":       query.Offset,
+		"limit":        query.Limit,
+	}
+
+	where := `
+		WHERE
+			workspace_id=@workspace_id
+			AND (@user_name::TEXT IS NULL OR LOWER(user_name) = LOWER(@user_name))
+			AND (@external_id::TEXT IS NULL OR external_id = @external_id)
+			AND (@email::TEXT IS NULL OR LOWER(email) = LOWER(@email))
+	`
+
+	var total int
+	err := r.server.DB.Conn(ctx).QueryRow(ctx, `SELECT COUNT(*) FROM scim_users`+where, args).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count scim users for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, `
+		SELECT
+			*
+		FROM
+			scim_users
+	`+where+`
+		ORDER BY
+			created_at ASC, id ASC
+		LIMIT
+			@limit
+		OFFSET
+			@offset
+	`, args)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to execute get scim users query for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	users, err := pgx.CollectRows(rows, pgx.RowToStructByName[provisioning.User])
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to collect rows from table:scim_users for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	return users, total, nil
+}
+
+func (r *ProvisioningRepository) GetUserByID(ctx context.Context, workspaceID uuid.UUID,
+	scimUserID uuid.UUID,
+) (*provisioning.User, error) {
+	return getSCIMUser(ctx, r.server.DB.Conn(ctx), workspaceID, scimUserID, false)
+}
+
+// CreateUser provisions the auth provider's user userID, adding them to the
+// workspace if they are active. A pending user isn't added until they
+// accept the invitation.
+func (r *ProvisioningRepository) CreateUser(ctx context.Context, workspaceID uuid.UUID, userID string,
+	attrs *provisioning.UserAttributes, pending bool,
+) (*provisioning.User, *provisioning.MembershipChange, error) {
+	var user provisioning.User
+	var change *provisioning.MembershipChange
+
+	err := pgx.BeginFunc(ctx, r.server.DB.Conn(ctx), func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			INSERT INTO
+				scim_users (
+					workspace_id,
+					user_id,
+					external_id,
+					user_name,
+					email,
+					given_name,
+					family_name,
+					display_name,
+					active,
+					pending
+				)
+			VALUES
+				(
+					@workspace_id,
+					@user_id,
+					@external_id,
+					@user_name,
+					@email,
+					@given_name,
+					@family_name,
+					@display_name,
+					@active,
+					@pending
+				)
+			RETURNING
+			*
+		`, userArgs(workspaceID, userID, attrs, nil, pending))
+		if err != nil {
+			return fmt.Errorf("failed to execute create scim user query for workspace_id=%s user_id=%s: %w", workspaceID.String(), userID, err)
+		}
+
+		user, err = pgx.CollectOneRow(rows, pgx.RowToStructByName[provisioning.User])
+		if err != nil {
+			return fmt.Errorf("failed to collect row from table:scim_users for workspace_id=%s user_id=%s: %w", workspaceID.String(), userID, err)
+		}
+
+		changes, err := syncMemberships(ctx, tx, workspaceID, []uuid.UUID{user.ID})
+		if err != nil {
+			return err
+		}
+		if len(changes) > 0 {
+			change = &changes[0]
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &user, change, nil
+}
+
+// UpdateUser locks the user, lets update change its attributes and stores
+// them. Locking keeps concurrent PATCH requests from losing each other's
+// changes.
+func (r *ProvisioningRepository) UpdateUser(ctx context.Context, workspaceID uuid.UUID, scimUserID uuid.UUID,
+	update func(user *provisioning.User) error,
+) (*provisioning.User, *provisioning.MembershipChange, error) {
+	var user provisioning.User
+	var change *provisioning.MembershipChange
+
+	err := pgx.BeginFunc(ctx, r.server.DB.Conn(ctx), func(tx pgx.Tx) error {
+		current, err := getSCIMUser(ctx, tx, workspaceID, scimUserID, true)
+		if err != nil {
+			return err
+		}
+
+		if err := update(current); err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(ctx, `
+			UPDATE
+				scim_users
+			SET
+				external_id = @external_id,
+				user_name = @user_name,
+				email = @email,
+				given_name = @given_name,
+				family_name = @family_name,
+				display_name = @display_name,
+				active = @active
+			WHERE
+				id=@id
+			RETURNING
+			*
+		`, userArgs(workspaceID, current.UserID, &current.UserAttributes, &scimUserID, current.Pending))
+		if err != nil {
+			return fmt.Errorf("failed to execute update scim user query for scim_user_id=%s: %w", scimUserID.String(), err)
+		}
+
+		user, err = pgx.CollectOneRow(rows, pgx.RowToStructByName[provisioning.User])
+		if err != nil {
+			return fmt.Errorf("failed to collect row from table:scim_users for scim_user_id=%s: %w", scimUserID.String(), err)
+		}
+
+		changes, err := syncMemberships(ctx, tx, workspaceID, []uuid.UUID{user.ID})
+		if err != nil {
+			return err
+		}
+		if len(changes) > 0 {
+			change = &changes[0]
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &user, change, nil
+}
+
+// DeleteUser deprovisions the user, removing them from the workspace unless
+// they never accepted the invitation. The user is left at the auth
+// provider, where they may belong to other workspaces.
+func (r *ProvisioningRepository) DeleteUser(ctx context.Context, workspaceID uuid.UUID,
+	scimUserID uuid.UUID,
+) (*provisioning.MembershipChange, error) {
+	var change *provisioning.MembershipChange
+
+	err := pgx.BeginFunc(ctx, r.server.DB.Conn(ctx), func(tx pgx.Tx) error {
+		user, err := getSCIMUser(ctx, tx, workspaceID, scimUserID, true)
+		if err != nil {
+			return err
+		}
+
+		if !user.Pending {
+			change, err = setMembership(ctx, tx, workspaceID, user.UserID, nil)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.Exec(ctx, `
+			DELETE FROM scim_users
+			WHERE id = @id
+		`, pgx.NamedArgs{"id": scimUserID})
+		if err != nil {
+			return fmt.Errorf("failed to execute delete scim user query for scim_user_id=%s: %w", scimUserID.String(), err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return change, nil
+}
+
+// GetInvitations returns the user's pending invitations, in the order they
+// were provisioned
+func (r *ProvisioningRepository) GetInvitations(ctx context.Context, userID string) ([]provisioning.User, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			scim_users
+		WHERE
+			user_id=@user_id
+			AND pending
+		ORDER BY
+			created_at,
+			id
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"user_id": userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get invitations query for user_id=%s: %w", userID, err)
+	}
+
+	users, err := pgx.CollectRows(rows, pgx.RowToStructByName[provisioning.User])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:scim_users for user_id=%s: %w", userID, err)
+	}
+
+	return users, nil
+}
+
+// AcceptInvitation accepts the user's pending invitation, adding them to
+// the workspace if they are active
+func (r *ProvisioningRepository) AcceptInvitation(ctx context.Context, userID string,
+	scimUserID uuid.UUID,
+) (*provisioning.User, *provisioning.MembershipChange, error) {
+	var user provisioning.User
+	var change *provisioning.MembershipChange
+
+	err := pgx.BeginFunc(ctx, r.server.DB.Conn(ctx), func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			UPDATE
+				scim_users
+			SET
+				pending = FALSE
+			WHERE
+				id=@id
+				AND user_id=@user_id
+				AND pending
+			RETURNING
+			*
+		`, pgx.NamedArgs{
+			"id":      scimUserID,
+			"user_id": userID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to execute accept invitation query for scim_user_id=%s: %w", scimUserID.String(), err)
+		}
+
+		user, err = pgx.CollectOneRow(rows, pgx.RowToStructByName[provisioning.User])
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				code := "INVITATION_NOT_FOUND"
+				return errs.NewNotFoundError("Invitation not found", false, &code)
+			}
+			return fmt.Errorf("failed to collect row from table:scim_users for scim_user_id=%s: %w", scimUserID.String(), err)
+		}
+
+		changes, err := syncMemberships(ctx, tx, user.WorkspaceID, []uuid.UUID{user.ID})
+		if err != nil {
+			return err
+		}
+		if len(changes) > 0 {
+			change = &changes[0]
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &user, change, nil
+}
+
+func userArgs(workspaceID uuid.UUID, userID string, attrs *provisioning.UserAttributes,
+	scimUserID *uuid.UUID, pending bool,
+) pgx.NamedArgs {
+	args := pgx.NamedArgs{
+		"workspace_id": workspaceID,
+		"user_id":      userID,
+		"external_id":  attrs.ExternalID,
+		"user_name":    attrs.UserName,
+		"email":        attrs.Email,
+		"given_name":   attrs.GivenName,
+		"family_name":  attrs.FamilyName,
+		"display_name": attrs.DisplayName,
+		"active":       attrs.Active,
+		"pending":      pending,
+	}
+	if scimUserID != nil {
+		args["id"] = *scimUserID
+	}
+	return args
+}
+
+func getSCIMUser(ctx context.Context, q queryer, workspaceID uuid.UUID, scimUserID uuid.UUID,
+	forUpdate bool,
+) (*provisioning.User, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			scim_users
+		WHERE
+			id=@id
+			AND workspace_id=@workspace_id
+	`
+	if forUpdate {
+		stmt += ` FOR UPDATE`
+	}
+
+	rows, err := q.Query(ctx, stmt, pgx.NamedArgs{
+		"id":           scimUserID,
+		"workspace_id": workspaceID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get scim user query for scim_user_id=%s workspace_id=%s: %w", scimUserID.String(), workspaceID.String(), err)
+	}
+
+	user, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[provisioning.User])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "SCIM_USER_NOT_FOUND"
+			return nil, errs.NewNotFoundError("SCIM user not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:scim_users for scim_user_id=%s workspace_id=%s: %w", scimUserID.String(), workspaceID.String(), err)
+	}
+
+	return &user, nil
+}
+
+// ------------------------------------------------------------
+// Groups
+
+func (r *ProvisioningRepository) GetGroups(ctx context.Context, workspaceID uuid.UUID,
+	query *GroupQuery,
+) ([]provisioning.Group, int, error) {
+	args := pgx.NamedArgs{
+		"workspace_id": workspaceID,
+		"display_name": query.DisplayName,
+		"external_id":  query.ExternalID,
+		"offset":       query.Offset,
+		"limit":        query.Limit,
+	}
+
+	where := `
+		WHERE
+			g.workspace_id=@workspace_id
+			AND (@display_name::TEXT IS NULL OR LOWER(g.display_name) = LOWER(@display_name))
+			AND (@external_id::TEXT IS NULL OR g.external_id = @external_id)
+	`
+
+	var total int
+	err := r.server.DB.Conn(ctx).QueryRow(ctx, `SELECT COUNT(*) FROM scim_groups g`+where, args).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count scim groups for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, `
+		SELECT
+	`+groupColumns+`
+		FROM
+			scim_groups g
+			LEFT JOIN scim_group_members gm ON gm.group_id=g.id
+	`+where+`
+		GROUP BY
+			g.id
+		ORDER BY
+			g.created_at ASC, g.id ASC
+		LIMIT
+			@limit
+		OFFSET
+			@offset
+	`, args)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to execute get scim groups query for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	groups, err := pgx.CollectRows(rows, pgx.RowToStructByName[provisioning.Group])
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to collect rows from table:scim_groups for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	return groups, total, nil
+}
+
+func (r *ProvisioningRepository) GetGroupByID(ctx context.Context, workspaceID uuid.UUID,
+	groupID uuid.UUID,
+) (*provisioning.Group, error) {
+	return getSCIMGroup(ctx, r.server.DB.Conn(ctx), workspaceID, groupID)
+}
+
+// CreateGroup creates a group. Groups start without a role, so adding its
+// members doesn't change their memberships.
+func (r *ProvisioningRepository) CreateGroup(ctx context.Context, workspaceID uuid.UUID,
+	attrs *provisioning.GroupAttributes,
+) (*provisioning.Group, error) {
+	var group *provisioning.Group
+
+	err := pgx.BeginFunc(ctx, r.server.DB.Conn(ctx), func(tx pgx.Tx) error {
+		var groupID uuid.UUID
+		err := tx.QueryRow(ctx, `
+			INSERT INTO
+				scim_groups (
+					workspace_id,
+					external_id,
+					display_name
+				)
+			VALUES
+				(
+					@workspace_id,
+					@external_id,
+					@display_name
+				)
+			RETURNING
+				id
+		`, pgx.NamedArgs{
+			"workspace_id": workspaceID,
+			"external_id":  attrs.ExternalID,
+			"display_name": attrs.DisplayName,
+		}).Scan(&groupID)
+		if err != nil {
+			return fmt.Errorf("failed to execute create scim group query for workspace_id=%s: %w", workspaceID.String(), err)
+		}
+
+		if err := addGroupMembers(ctx, tx, workspaceID, groupID, attrs.MemberIDs); err != nil {
+			return err
+		}
+
+		group, err = getSCIMGroup(ctx, tx, workspaceID, groupID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+// UpdateGroup locks the group, lets update change its attributes and stores
+// them, updating the roles of members who joined or left
+func (r *ProvisioningRepository) UpdateGroup(ctx context.Context, workspaceID uuid.UUID, groupID uuid.UUID,
+	update func(group *provisioning.Group) error,
+) (*provisioning.Group, []provisioning.MembershipChange, error) {
+	var group *provisioning.Group
+	var changes []provisioning.MembershipChange
+
+	err := pgx.BeginFunc(ctx, r.server.DB.Conn(ctx), func(tx pgx.Tx) error {
+		if err := lockSCIMGroup(ctx, tx, workspaceID, groupID); err != nil {
+			return err
+		}
+
+		current, err := getSCIMGroup(ctx, tx, workspaceID, groupID)
+		if err != nil {
+			return err
+		}
+		previousMemberIDs := slices.Clone(current.MemberIDs)
+
+		if err := update(current); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE
+				scim_groups
+			SET
+				external_id = @external_id,
+				display_name = @display_name
+			WHERE
+				id=@id
+		`, pgx.NamedArgs{
+			"id":           groupID,
+			"external_id":  current.ExternalID,
+			"display_name": current.DisplayName,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to execute update scim group query for group_id=%s: %w", groupID.String(), err)
+		}
+
+		var added, removed []uuid.UUID
+		for _, id := range current.MemberIDs {
+			if !slices.Contains(previousMemberIDs, id) && !slices.Contains(added, id) {
+				added = append(added, id)
+			}
+		}
+		for _, id := range previousMemberIDs {
+			if !slices.Contains(current.MemberIDs, id) {
+				removed = append(removed, id)
+			}
+		}
+
+		if len(removed) > 0 {
+			_, err = tx.Exec(ctx, `
+				DELETE FROM scim_group_members
+				WHERE group_id = @group_id AND scim_user_id = ANY(@scim_user_ids)
+			`, pgx.NamedArgs{
+				"group_id":      groupID,
+				"scim_user_ids": removed,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to remove members of scim group_id=%s: %w", groupID.String(), err)
+			}
+		}
+
+		if err := addGroupMembers(ctx, tx, workspaceID, groupID, added); err != nil {
+			return err
+		}
+
+		if current.Role != nil {
+			changes, err = syncMemberships(ctx, tx, workspaceID, append(added, removed...))
+			if err != nil {
+				return err
+			}
+		}
+
+		group, err = getSCIMGroup(ctx, tx, workspaceID, groupID)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return group, changes, nil
+}
+
+// SetGroupRole maps the group to a workspace role, or unmaps it when role
+// is nil, and updates the roles of its members
+func (r *ProvisioningRepository) SetGroupRole(ctx context.Context, workspaceID uuid.UUID, groupID uuid.UUID,
+	role *workspace.Role,
+) (*provisioning.Group, []provisioning.MembershipChange, error) {
+	var group *provisioning.Group
+	var changes []provisioning.MembershipChange
+
+	err := pgx.BeginFunc(ctx, r.server.DB.Conn(ctx), func(tx pgx.Tx) error {
+		if err := lockSCIMGroup(ctx, tx, workspaceID, groupID); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(ctx, `
+			UPDATE
+				scim_groups
+			SET
+				role = @role
+			WHERE
+				id=@id
+		`, pgx.NamedArgs{
+			"id":   groupID,
+			"role": role,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to execute set scim group role query for group_id=%s: %w", groupID.String(), err)
+		}
+
+		group, err = getSCIMGroup(ctx, tx, workspaceID, groupID)
+		if err != nil {
+			return err
+		}
+
+		changes, err = syncMemberships(ctx, tx, workspaceID, group.MemberIDs)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return group, changes, nil
+}
+
+// DeleteGroup deletes the group, updating the roles its members had from it
+func (r *ProvisioningRepository) DeleteGroup(ctx context.Context, workspaceID uuid.UUID,
+	groupID uuid.UUID,
+) ([]provisioning.MembershipChange, error) {
+	var changes []provisioning.MembershipChange
+
+	err := pgx.BeginFunc(ctx, r.server.DB.Conn(ctx), func(tx pgx.Tx) error {
+		if err := lockSCIMGroup(ctx, tx, workspaceID, groupID); err != nil {
+			return err
+		}
+
+		group, err := getSCIMGroup(ctx, tx, workspaceID, groupID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+			DELETE FROM scim_groups
+			WHERE id = @id
+		`, pgx.NamedArgs{"id": groupID})
+		if err != nil {
+			return fmt.Errorf("failed to execute delete scim group query for group_id=%s: %w", groupID.String(), err)
+		}
+
+		if group.Role != nil {
+			changes, err = syncMemberships(ctx, tx, workspaceID, group.MemberIDs)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+func lockSCIMGroup(ctx context.Context, tx pgx.Tx, workspaceID uuid.UUID, groupID uuid.UUID) error {
+	var id uuid.UUID
+	err := tx.QueryRow(ctx, `
+		SELECT
+			id
+		FROM
+			scim_groups
+		WHERE
+			id=@id
+			AND workspace_id=@workspace_id
+		FOR UPDATE
+	`, pgx.NamedArgs{
+		"id":           groupID,
+		"workspace_id": workspaceID,
+	}).Scan(&id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "SCIM_GROUP_NOT_FOUND"
+			return errs.NewNotFoundError("SCIM group not found", false, &code)
+		}
+		return fmt.Errorf("failed to lock scim group_id=%s: %w", groupID.String(), err)
+	}
+	return nil
+}
+
+func getSCIMGroup(ctx context.Context, q queryer, workspaceID uuid.UUID, groupID uuid.UUID) (*provisioning.Group, error) {
+	rows, err := q.Query(ctx, `
+		SELECT
+	`+groupColumns+`
+		FROM
+			scim_groups g
+			LEFT JOIN scim_group_members gm ON gm.group_id=g.id
+		WHERE
+			g.id=@id
+			AND g.workspace_id=@workspace_id
+		GROUP BY
+			g.id
+	`, pgx.NamedArgs{
+		"id":           groupID,
+		"workspace_id": workspaceID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get scim group query for group_id=%s workspace_id=%s: %w", groupID.String(), workspaceID.String(), err)
+	}
+
+	group, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[provisioning.Group])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "SCIM_GROUP_NOT_FOUND"
+			return nil, errs.NewNotFoundError("SCIM group not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:scim_groups for group_id=%s workspace_id=%s: %w", groupID.String(), workspaceID.String(), err)
+	}
+
+	return &group, nil
+}
+
+// addGroupMembers adds provisioned users of the workspace to the group,
+// failing if any isn't one
+func addGroupMembers(ctx context.Context, tx pgx.Tx, workspaceID uuid.UUID, groupID uuid.UUID,
+	scimUserIDs []uuid.UUID,
+) error {
+	if len(scimUserIDs) == 0 {
+		return nil
+	}
+
+	result, err := tx.Exec(ctx, `
+		INSERT INTO
+			scim_group_members (group_id, scim_user_id)
+		SELECT
+			@group_id, id
+		FROM
+			scim_users
+		WHERE
+			workspace_id=@workspace_id
+			AND id = ANY(@scim_user_ids)
+		ON CONFLICT DO NOTHING
+	`, pgx.NamedArgs{
+		"group_id":      groupID,
+		"workspace_id":  workspaceID,
+		"scim_user_ids": scimUserIDs,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add members to scim group_id=%s: %w", groupID.String(), err)
+	}
+
+	if result.RowsAffected() != int64(len(scimUserIDs)) {
+		code := "SCIM_USER_NOT_FOUND"
+		return errs.NewBadRequestError("Group members must be provisioned users", false, &code, nil, nil)
+	}
+
+	return nil
+}
+
+// ------------------------------------------------------------
+// Memberships
+
+type provisionedRoles struct {
+	UserID string   `db:"user_id"`
+	Active bool     `db:"active"`
+	Roles  []string `db:"roles"`
+}
+
+// syncMemberships gives the provisioned users the workspace role their
+// groups and status call for. Pending users are left alone until they
+// accept.
+func syncMemberships(ctx context.Context, tx pgx.Tx, workspaceID uuid.UUID,
+	scimUserIDs []uuid.UUID,
+) ([]provisioning.MembershipChange, error) {
+	if len(scimUserIDs) == 0 {
+		return nil, nil
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT
+			u.user_id,
+			u.active,
+			COALESCE(array_agg(g.role) FILTER (WHERE g.role IS NOT NULL), '{}') AS roles
+		FROM
+			scim_users u
+			LEFT JOIN scim_group_members gm ON gm.scim_user_id=u.id
+			LEFT JOIN scim_groups g ON g.id=gm.group_id
+		WHERE
+			u.workspace_id=@workspace_id
+			AND u.id = ANY(@scim_user_ids)
+			AND NOT u.pending
+		GROUP BY
+			u.id
+		ORDER BY
+			u.id
+	`, pgx.NamedArgs{
+		"workspace_id":  workspaceID,
+		"scim_user_ids": scimUserIDs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get roles of scim users for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	users, err := pgx.CollectRows(rows, pgx.RowToStructByName[provisionedRoles])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:scim_users for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	var changes []provisioning.MembershipChange
+	for _, user := range users {
+		groupRoles := make([]workspace.Role, len(user.Roles))
+		for i, role := range user.Roles {
+			groupRoles[i] = workspace.Role(role)
+		}
+
+		change, err := setMembership(ctx, tx, workspaceID, user.UserID,
+			provisioning.EffectiveRole(user.Active, groupRoles))
+		if err != nil {
+			return nil, err
+		}
+		if change != nil {
+			changes = append(changes, *change)
+		}
+	}
+
+	return changes, nil
+}
+
+// setMembership gives the user role in the workspace, or removes them when
+// role is nil. Owners are never changed. It returns nil when nothing
+// changed.
+func setMembership(ctx context.Context, tx pgx.Tx, workspaceID uuid.UUID, userID string,
+	role *workspace.Role,
+) (*provisioning.MembershipChange, error) {
+	args := pgx.NamedArgs{
+		"workspace_id": workspaceID,
+		"user_id":      userID,
+		"role":         role,
+	}
+
+	var current *workspace.Role
+	err := tx.QueryRow(ctx, `
+		SELECT
+			role
+		FROM
+			workspace_members
+		WHERE
+			workspace_id=@workspace_id
+			AND user_id=@user_id
+		FOR UPDATE
+	`, args).Scan(&current)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get membership for workspace_id=%s user_id=%s: %w", workspaceID.String(), userID, err)
+	}
+
+	switch {
+	case current != nil && *current == workspace.RoleOwner:
+		return nil, nil
+	case current == nil && role == nil:
+		return nil, nil
+	case current != nil && role != nil && *current == *role:
+		return nil, nil
+	case role == nil:
+		_, err = tx.Exec(ctx, `
+			DELETE FROM workspace_members
+			WHERE workspace_id = @workspace_id AND user_id = @user_id
+		`, args)
+	case current == nil:
+		_, err = tx.Exec(ctx, `
+			INSERT INTO
+				workspace_members (workspace_id, user_id, role)
+			VALUES
+				(@workspace_id, @user_id, @role)
+			ON CONFLICT (workspace_id, user_id) DO UPDATE
+			SET
+				role = EXCLUDED.role
+			WHERE
+				workspace_members.role != 'owner'
+		`, args)
+	default:
+		_, err = tx.Exec(ctx, `
+			UPDATE
+				workspace_members
+			SET
+				role=@role
+			WHERE
+				workspace_id=@workspace_id
+				AND user_id=@user_id
+		`, args)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to set membership for workspace_id=%s user_id=%s: %w", workspaceID.String(), userID, err)
+	}
+
+	return &provisioning.MembershipChange{UserID: userID, From: current, To: role}, nil
+}
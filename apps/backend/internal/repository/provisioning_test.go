@@ -0,0 +1,81 @@
+package repository_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/model/provisioning"
+	"github.com/mabhi256/tasker/internal/model/workspace"
+	"github.com/mabhi256/tasker/internal/repository"
+	testutil "github.com/mabhi256/tasker/internal/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSCIMInvitation keeps a pending user out of the workspace, and out of
+// group role changes, until they accept the invitation themselves
+func TestSCIMInvitation(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping provisioning tests in short mode")
+	}
+
+	testDB, srv, cleanup := testutil.SetupTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	provisioningRepo := repository.NewProvisioningRepository(srv)
+	workspaceID := testutil.SeedWorkspace(t, testDB, "owner-1", nil)
+
+	role := func(userID string) *workspace.Role {
+		var current *workspace.Role
+		err := testDB.Pool.QueryRow(ctx, `
+			SELECT role FROM workspace_members WHERE workspace_id = $1 AND user_id = $2
+		`, workspaceID, userID).Scan(&current)
+		if err != nil {
+			return nil
+		}
+		return current
+	}
+
+	attrs := &provisioning.UserAttributes{UserName: "invitee", Email: "invitee@example.com", Active: true}
+	user, change, err := provisioningRepo.CreateUser(ctx, workspaceID, "user-1", attrs, true)
+	require.NoError(t, err)
+	assert.True(t, user.Pending)
+	assert.Nil(t, change)
+	assert.Nil(t, role("user-1"))
+
+	group, err := provisioningRepo.CreateGroup(ctx, workspaceID, &provisioning.GroupAttributes{
+		DisplayName: "Admins",
+		MemberIDs:   []uuid.UUID{user.ID},
+	})
+	require.NoError(t, err)
+	_, changes, err := provisioningRepo.SetGroupRole(ctx, workspaceID, group.ID, testutil.Ptr(workspace.RoleAdmin))
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+	assert.Nil(t, role("user-1"))
+
+	invitations, err := provisioningRepo.GetInvitations(ctx, "user-1")
+	require.NoError(t, err)
+	require.Len(t, invitations, 1)
+	assert.Equal(t, user.ID, invitations[0].ID)
+
+	// Only the invited user can accept
+	_, _, err = provisioningRepo.AcceptInvitation(ctx, "user-2", user.ID)
+	var httpErr *errs.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.Status)
+
+	accepted, change, err := provisioningRepo.AcceptInvitation(ctx, "user-1", user.ID)
+	require.NoError(t, err)
+	assert.False(t, accepted.Pending)
+	require.NotNil(t, change)
+	assert.Equal(t, workspace.RoleAdmin, *change.To)
+	assert.Equal(t, testutil.Ptr(workspace.RoleAdmin), role("user-1"))
+
+	invitations, err = provisioningRepo.GetInvitations(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Empty(t, invitations)
+}
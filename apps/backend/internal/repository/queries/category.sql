@@ -0,0 +1,78 @@
+-- name: CreateCategory :one
+INSERT INTO
+    todo_categories (
+        workspace_id,
+        user_id,
+        name,
+        color,
+        description,
+        default_priority,
+        default_reminder_minutes
+    )
+VALUES
+    (
+        @workspace_id,
+        @user_id,
+        @name,
+        @color,
+        @description,
+        @default_priority,
+        @default_reminder_minutes
+    )
+RETURNING
+    *;
+
+-- name: GetCategoryByID :one
+SELECT
+    *
+FROM
+    todo_categories
+WHERE
+    id = @id
+    AND workspace_id = @workspace_id;
+
+-- name: GetCategoryByName :one
+SELECT
+    *
+FROM
+    todo_categories
+WHERE
+    workspace_id = @workspace_id
+    AND name = @name;
+
+-- name: GetCategoriesByIDs :many
+SELECT
+    *
+FROM
+    todo_categories
+WHERE
+    id = ANY (@ids::UUID[])
+    AND workspace_id = @workspace_id;
+
+-- name: UpdateCategory :one
+-- UpdateCategory leaves the fields given as NULL unchanged. The defaults,
+-- which can be NULL, are cleared by their flags instead.
+UPDATE todo_categories
+SET
+    name = COALESCE(sqlc.narg('name')::TEXT, name),
+    color = COALESCE(sqlc.narg('color')::TEXT, color),
+    description = COALESCE(sqlc.narg('description')::TEXT, description),
+    default_priority = CASE
+        WHEN @clear_default_priority::BOOLEAN THEN NULL
+        ELSE COALESCE(sqlc.narg('default_priority')::TEXT, default_priority)
+    END,
+    default_reminder_minutes = CASE
+        WHEN @clear_default_reminder_minutes::BOOLEAN THEN NULL
+        ELSE COALESCE(sqlc.narg('default_reminder_minutes')::INTEGER, default_reminder_minutes)
+    END
+WHERE
+    id = @id
+    AND workspace_id = @workspace_id
+RETURNING
+    *;
+
+-- name: DeleteCategory :execrows
+DELETE FROM todo_categories
+WHERE
+    id = @id
+    AND workspace_id = @workspace_id;
@@ -0,0 +1,66 @@
+-- name: AddComment :one
+INSERT INTO
+    todo_comments (
+        workspace_id,
+        todo_id,
+        user_id,
+        content,
+        parent_comment_id
+    )
+VALUES
+    (
+        @workspace_id,
+        @todo_id,
+        @user_id,
+        @content,
+        @parent_comment_id
+    )
+RETURNING
+    *;
+
+-- name: GetCommentsByTodoID :many
+SELECT
+    *
+FROM
+    todo_comments
+WHERE
+    todo_id = @todo_id
+    AND workspace_id = @workspace_id
+ORDER BY
+    created_at ASC,
+    id ASC;
+
+-- name: GetCommentByID :one
+SELECT
+    *
+FROM
+    todo_comments
+WHERE
+    id = @id
+    AND workspace_id = @workspace_id;
+
+-- name: GetCommentsByIDs :many
+SELECT
+    *
+FROM
+    todo_comments
+WHERE
+    id = ANY (@ids::UUID[])
+    AND workspace_id = @workspace_id;
+
+-- name: UpdateComment :one
+UPDATE todo_comments
+SET
+    content = @content
+WHERE
+    id = @id
+    AND workspace_id = @workspace_id
+    AND user_id = @user_id
+RETURNING
+    *;
+
+-- name: DeleteComment :execrows
+DELETE FROM todo_comments
+WHERE
+    id = @id
+    AND workspace_id = @workspace_id;
@@ -0,0 +1,82 @@
+-- name: CreateTodo :one
+INSERT INTO
+    todos (
+        workspace_id,
+        user_id,
+        title,
+        description,
+        priority,
+        due_date,
+        parent_todo_id,
+        category_id,
+        metadata,
+        reminder_minutes,
+        status,
+        publish_at
+    )
+VALUES
+    (
+        @workspace_id,
+        @user_id,
+        @title,
+        @description,
+        @priority,
+        @due_date,
+        @parent_todo_id,
+        @category_id,
+        @metadata,
+        @reminder_minutes,
+        @status,
+        @publish_at
+    )
+RETURNING
+    *;
+
+-- name: GetTodo :one
+SELECT
+    *
+FROM
+    todos
+WHERE
+    id = @id
+    AND workspace_id = @workspace_id;
+
+-- name: DeleteTodo :execrows
+DELETE FROM todos
+WHERE
+    id = @id
+    AND workspace_id = @workspace_id;
+
+-- name: GetTodosForExport :many
+-- GetTodosForExport lists every todo in the workspace in a stable order
+SELECT
+    *
+FROM
+    todos
+WHERE
+    workspace_id = @workspace_id
+ORDER BY
+    created_at ASC,
+    id ASC;
+
+-- name: AddDependency :exec
+INSERT INTO
+    todo_dependencies (
+        todo_id,
+        blocked_by_id,
+        workspace_id
+    )
+VALUES
+    (
+        @todo_id,
+        @blocked_by_id,
+        @workspace_id
+    )
+ON CONFLICT (todo_id, blocked_by_id) DO NOTHING;
+
+-- name: RemoveDependency :exec
+DELETE FROM todo_dependencies
+WHERE
+    todo_id = @todo_id
+    AND blocked_by_id = @blocked_by_id
+    AND workspace_id = @workspace_id;
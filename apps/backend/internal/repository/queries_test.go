@@ -0,0 +1,228 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/model/category"
+	"github.com/mabhi256/tasker/internal/model/comment"
+	"github.com/mabhi256/tasker/internal/model/todo"
+	"github.com/mabhi256/tasker/internal/repository"
+	testutil "github.com/mabhi256/tasker/internal/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGeneratedQueries runs the repository methods backed by the sqlc
+// generated queries against the migrated schema, so a query that no longer
+// matches its table, or a row that no longer maps to its model, fails here
+func TestGeneratedQueries(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping generated query tests in short mode")
+	}
+
+	testDB, srv, cleanup := testutil.SetupTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	userID := "user-1"
+
+	var workspaceID uuid.UUID
+	err := testDB.Pool.QueryRow(ctx, `
+		INSERT INTO workspaces (name, owner_id, is_personal)
+		VALUES ('Workspace', $1, TRUE)
+		RETURNING id
+	`, userID).Scan(&workspaceID)
+	require.NoError(t, err)
+
+	categoryRepo := repository.NewCategoryRepository(srv)
+	commentRepo := repository.NewCommentRepository(srv)
+	todoRepo := repository.NewTodoRepository(srv)
+
+	t.Run("categories", func(t *testing.T) {
+		created, err := categoryRepo.CreateCategory(ctx, workspaceID, userID, &category.CreateCategoryPayload{
+			Name:            "Work",
+			Color:           "#ff0000",
+			Description:     testutil.Ptr("Things to do at work"),
+			DefaultPriority: testutil.Ptr("high"),
+		})
+		require.NoError(t, err)
+		testutil.AssertValidUUID(t, created.ID)
+		testutil.AssertTimestampsValid(t, created)
+		assert.Equal(t, workspaceID, created.WorkspaceID)
+		assert.Equal(t, "#ff0000", created.Color)
+		assert.Equal(t, testutil.Ptr("high"), created.DefaultPriority)
+		assert.Nil(t, created.DefaultReminderMinutes)
+
+		found, err := categoryRepo.GetCategoryByID(ctx, workspaceID, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, created, found)
+
+		_, err = categoryRepo.GetCategoryByID(ctx, uuid.New(), created.ID)
+		require.Error(t, err, "another workspace's category is found")
+
+		byName, err := categoryRepo.FindCategoryByName(ctx, workspaceID, "Work")
+		require.NoError(t, err)
+		assert.Equal(t, created, byName)
+
+		byName, err = categoryRepo.FindCategoryByName(ctx, workspaceID, "Home")
+		require.NoError(t, err)
+		assert.Nil(t, byName)
+
+		// Fields left out of an update keep their value
+		updated, err := categoryRepo.UpdateCategory(ctx, workspaceID, created.ID, &category.UpdateCategoryPayload{
+			Name: testutil.Ptr("Office"),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "Office", updated.Name)
+		assert.Equal(t, created.Color, updated.Color)
+		assert.Equal(t, created.Description, updated.Description)
+		assert.Equal(t, created.DefaultPriority, updated.DefaultPriority)
+
+		updated, err = categoryRepo.UpdateCategory(ctx, workspaceID, created.ID, &category.UpdateCategoryPayload{
+			DefaultReminderMinutes: testutil.Ptr(30),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, testutil.Ptr(30), updated.DefaultReminderMinutes)
+
+		// Defaults are only removed by their clear flags
+		updated, err = categoryRepo.UpdateCategory(ctx, workspaceID, created.ID, &category.UpdateCategoryPayload{
+			ClearDefaultPriority: true,
+		})
+		require.NoError(t, err)
+		assert.Nil(t, updated.DefaultPriority)
+		assert.Equal(t, testutil.Ptr(30), updated.DefaultReminderMinutes)
+
+		updated, err = categoryRepo.UpdateCategory(ctx, workspaceID, created.ID, &category.UpdateCategoryPayload{
+			ClearDefaultReminderMinutes: true,
+		})
+		require.NoError(t, err)
+		assert.Nil(t, updated.DefaultReminderMinutes)
+
+		byIDs, err := categoryRepo.GetCategoriesByIDs(ctx, workspaceID, []uuid.UUID{created.ID, uuid.New()})
+		require.NoError(t, err)
+		require.Len(t, byIDs, 1)
+		assert.Equal(t, "Office", byIDs[0].Name)
+
+		require.NoError(t, categoryRepo.DeleteCategory(ctx, workspaceID, created.ID))
+		assert.Error(t, categoryRepo.DeleteCategory(ctx, workspaceID, created.ID))
+	})
+
+	t.Run("todos and comments", func(t *testing.T) {
+		dueDate := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Microsecond)
+		created, err := todoRepo.CreateTodo(ctx, workspaceID, userID, &todo.CreateTodoPayload{
+			Title:           "Write the report",
+			DueDate:         &dueDate,
+			Metadata:        &todo.Metadata{Tags: []string{"writing"}},
+			ReminderMinutes: testutil.Ptr(90),
+		})
+		require.NoError(t, err)
+		testutil.AssertTimestampsValid(t, created)
+		assert.Equal(t, todo.StatusDraft, created.Status)
+		assert.Equal(t, todo.PriorityMedium, created.Priority)
+		require.NotNil(t, created.DueDate)
+		assert.True(t, dueDate.Equal(*created.DueDate))
+		require.NotNil(t, created.Metadata)
+		assert.Equal(t, []string{"writing"}, created.Metadata.Tags)
+		assert.Equal(t, testutil.Ptr(90), created.ReminderMinutes)
+		// Derived by the database
+		assert.NotNil(t, created.PriorityRank)
+		assert.NotNil(t, created.SearchVector)
+
+		found, err := todoRepo.CheckTodoExists(ctx, workspaceID, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, created.ID, found.ID)
+		assert.Equal(t, created.Title, found.Title)
+
+		blocker, err := todoRepo.CreateTodo(ctx, workspaceID, userID, &todo.CreateTodoPayload{Title: "Gather the numbers"})
+		require.NoError(t, err)
+		require.NoError(t, todoRepo.AddDependency(ctx, workspaceID, created.ID, blocker.ID))
+		require.NoError(t, todoRepo.AddDependency(ctx, workspaceID, created.ID, blocker.ID))
+		edges, err := todoRepo.GetDependencyEdges(ctx, workspaceID)
+		require.NoError(t, err)
+		assert.Len(t, edges, 1)
+		require.NoError(t, todoRepo.RemoveDependency(ctx, workspaceID, created.ID, blocker.ID))
+
+		exported, err := todoRepo.GetTodosForExport(ctx, workspaceID)
+		require.NoError(t, err)
+		require.Len(t, exported, 2)
+		assert.Equal(t, created.ID, exported[0].ID)
+
+		added, err := commentRepo.AddComment(ctx, workspaceID, userID, created.ID, &comment.AddCommentPayload{
+			TodoID:  created.ID,
+			Content: "First draft is done",
+		}, nil)
+		require.NoError(t, err)
+		testutil.AssertTimestampsValid(t, added)
+		assert.Nil(t, added.ParentCommentID)
+
+		reply, err := commentRepo.AddComment(ctx, workspaceID, userID, created.ID, &comment.AddCommentPayload{
+			TodoID:          created.ID,
+			Content:         "Looks good",
+			ParentCommentID: &added.ID,
+		}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, &added.ID, reply.ParentCommentID)
+
+		edited, _, err := commentRepo.UpdateComment(ctx, workspaceID, userID, added.ID, "Second draft is done", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "Second draft is done", edited.Content)
+
+		_, _, err = commentRepo.UpdateComment(ctx, workspaceID, "user-2", added.ID, "Not mine", nil)
+		require.Error(t, err, "another user's comment is edited")
+
+		comments, err := commentRepo.GetCommentsByTodoID(ctx, workspaceID, created.ID)
+		require.NoError(t, err)
+		require.Len(t, comments, 2)
+		assert.Equal(t, added.ID, comments[0].ID)
+
+		byIDs, err := commentRepo.GetCommentsByIDs(ctx, workspaceID, []uuid.UUID{reply.ID})
+		require.NoError(t, err)
+		require.Len(t, byIDs, 1)
+
+		fetched, err := commentRepo.GetCommentByID(ctx, workspaceID, reply.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Looks good", fetched.Content)
+
+		// Deleting a comment deletes its replies
+		require.NoError(t, commentRepo.DeleteComment(ctx, workspaceID, added.ID))
+		_, err = commentRepo.GetCommentByID(ctx, workspaceID, reply.ID)
+		assert.Error(t, err)
+
+		require.NoError(t, todoRepo.DeleteTodo(ctx, workspaceID, created.ID))
+		assert.Error(t, todoRepo.DeleteTodo(ctx, workspaceID, created.ID))
+	})
+
+	t.Run("scheduled todos", func(t *testing.T) {
+		later := time.Now().Add(time.Hour).UTC().Truncate(time.Microsecond)
+		scheduled, err := todoRepo.CreateTodo(ctx, workspaceID, userID, &todo.CreateTodoPayload{
+			Title:     "Announce the offsite",
+			PublishAt: &later,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, todo.StatusScheduled, scheduled.Status)
+		require.NotNil(t, scheduled.PublishAt)
+		assert.True(t, later.Equal(*scheduled.PublishAt))
+
+		published, err := todoRepo.PublishDueTodos(ctx, time.Now(), 10)
+		require.NoError(t, err)
+		assert.Empty(t, published, "a todo is published before its time")
+
+		published, err = todoRepo.PublishDueTodos(ctx, later.Add(time.Second), 10)
+		require.NoError(t, err)
+		require.Len(t, published, 1)
+		assert.Equal(t, scheduled.ID, published[0].ID)
+		assert.Equal(t, todo.StatusActive, published[0].Status)
+
+		// Only scheduled todos can be rescheduled
+		_, err = todoRepo.UpdateTodo(ctx, workspaceID, &todo.UpdateTodoPayload{
+			ID:        scheduled.ID,
+			PublishAt: &later,
+		})
+		assert.Error(t, err)
+
+		require.NoError(t, todoRepo.DeleteTodo(ctx, workspaceID, scheduled.ID))
+	})
+}
@@ -0,0 +1,283 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/database"
+	"github.com/mabhi256/tasker/internal/model/activity"
+	"github.com/mabhi256/tasker/internal/model/outbox"
+	"github.com/mabhi256/tasker/internal/model/todo"
+	"github.com/mabhi256/tasker/internal/model/weeklyreport"
+	"github.com/mabhi256/tasker/internal/repository"
+	"github.com/mabhi256/tasker/internal/server"
+	testutil "github.com/mabhi256/tasker/internal/testing"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+// seedStmts fill the database with enough rows, spread the way production
+// data is, that the planner favours indexes wherever one applies. Only a
+// small share of todos is open and most outbox events are published.
+var seedStmts = []string{
+	`INSERT INTO workspaces (name, owner_id, is_personal)
+	SELECT 'Workspace ' || i, 'user-' || i, TRUE
+	FROM generate_series(0, 49) i`,
+
+	`INSERT INTO todos (workspace_id, user_id, title, status, priority, due_date, completed_at)
+	SELECT
+		w.id,
+		w.owner_id,
+		'Todo ' || i,
+		CASE
+			WHEN i % 20 = 0 THEN 'active'
+			WHEN i % 20 = 1 THEN 'draft'
+			WHEN i % 20 < 15 THEN 'completed'
+			ELSE 'archived'
+		END,
+		(ARRAY['low', 'medium', 'high'])[i % 3 + 1],
+		NOW() + make_interval(days => i % 60 - 30),
+		CASE WHEN i % 20 BETWEEN 2 AND 14 THEN NOW() - make_interval(days => i % 90) END
+	FROM
+		generate_series(0, 39999) i
+		JOIN workspaces w ON w.owner_id = 'user-' || (i % 50)`,
+
+	`INSERT INTO todo_comments (todo_id, workspace_id, user_id, content)
+	SELECT id, workspace_id, user_id, 'Comment'
+	FROM todos
+	WHERE status = 'completed'`,
+
+	`INSERT INTO todo_attachments (todo_id, name, uploaded_by, download_key, integrity_checked_at)
+	SELECT
+		id,
+		'file.txt',
+		user_id,
+		'todos/attachments/' || id,
+		CASE WHEN row_number() OVER () % 10 <> 0 THEN NOW() - make_interval(mins => (row_number() OVER ())::int) END
+	FROM todos
+	LIMIT 10000`,
+
+	`INSERT INTO event_outbox (workspace_id, event_type, payload, published_at)
+	SELECT id, 'todo.created', '{}', NOW() - INTERVAL '1 hour'
+	FROM workspaces, generate_series(1, 200)`,
+
+	`INSERT INTO event_outbox (workspace_id, event_type, payload)
+	SELECT id, 'todo.created', '{}'
+	FROM workspaces
+	LIMIT 5`,
+
+	`ANALYZE`,
+}
+
+// TestQueryPlans runs the repository queries that back hot paths and cron
+// jobs against a seeded database and fails if any of them falls back to a
+// sequential scan of a large table, which usually means a query stopped
+// matching the index written for it
+func TestQueryPlans(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping query plan tests in short mode")
+	}
+
+	testDB, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for _, stmt := range seedStmts {
+		_, err := testDB.Pool.Exec(ctx, stmt)
+		require.NoError(t, err, "failed to seed database")
+	}
+
+	var workspaceID uuid.UUID
+	err := testDB.Pool.QueryRow(ctx, `SELECT id FROM workspaces WHERE owner_id = 'user-7'`).Scan(&workspaceID)
+	require.NoError(t, err)
+
+	pool, recorder := testutil.NewRecordingPool(t, testDB)
+	logger := zerolog.Nop()
+	srv := &server.Server{
+		Logger: &logger,
+		DB:     &database.Database{Pool: pool},
+		Config: testDB.Config,
+	}
+
+	todoRepo := repository.NewTodoRepository(srv)
+	outboxRepo := repository.NewOutboxRepository(srv)
+	activityRepo := repository.NewActivityRepository(srv)
+	usageRepo := repository.NewUsageRepository(srv)
+	reportRepo := repository.NewReportRepository(srv)
+	weeklyReportRepo := repository.NewWeeklyReportRepository(srv)
+
+	largeTables := []string{"todos", "todo_attachments", "event_outbox"}
+
+	tests := []struct {
+		name string
+		run  func(ctx context.Context) error
+	}{
+		{
+			name: "GetTodos",
+			run: func(ctx context.Context) error {
+				query := &todo.GetTodosQuery{}
+				if err := query.Validate(); err != nil {
+					return err
+				}
+				_, err := todoRepo.GetTodos(ctx, workspaceID, query)
+				return err
+			},
+		},
+		{
+			name: "GetTodosByPriority",
+			run: func(ctx context.Context) error {
+				query := &todo.GetTodosQuery{Sort: testutil.Ptr("-priority,due_date")}
+				if err := query.Validate(); err != nil {
+					return err
+				}
+				_, err := todoRepo.GetTodos(ctx, workspaceID, query)
+				return err
+			},
+		},
+		{
+			name: "GetTodosForUser",
+			run: func(ctx context.Context) error {
+				query := &todo.GetTodosQuery{}
+				if err := query.Validate(); err != nil {
+					return err
+				}
+				_, err := todoRepo.GetTodosForUser(ctx, "user-7", query)
+				return err
+			},
+		},
+		{
+			name: "GetTodoStats",
+			run: func(ctx context.Context) error {
+				_, err := todoRepo.GetTodoStats(ctx, workspaceID)
+				return err
+			},
+		},
+		{
+			name: "GetTodosDueInHours",
+			run: func(ctx context.Context) error {
+				_, err := todoRepo.GetTodosDueInHours(ctx, 24, 100)
+				return err
+			},
+		},
+		{
+			name: "GetOverdueTodos",
+			run: func(ctx context.Context) error {
+				_, err := todoRepo.GetOverdueTodos(ctx, 100)
+				return err
+			},
+		},
+		{
+			name: "GetOverdueTodosForUser",
+			run: func(ctx context.Context) error {
+				_, err := todoRepo.GetOverdueTodosForUser(ctx, "user-7")
+				return err
+			},
+		},
+		{
+			name: "GetWeeklyReport",
+			run: func(ctx context.Context) error {
+				weekStart, weekEnd := weeklyreport.Week(time.Now().UTC())
+				_, err := weeklyReportRepo.GetReport(ctx, "user-7", weekStart, weekEnd, "UTC")
+				return err
+			},
+		},
+		{
+			name: "GetCompletedTodosOlderThan",
+			run: func(ctx context.Context) error {
+				_, err := todoRepo.GetCompletedTodosOlderThan(ctx, time.Now().AddDate(0, 0, -30), 100)
+				return err
+			},
+		},
+		{
+			name: "GetOverdueTodosForReport",
+			run: func(ctx context.Context) error {
+				_, err := todoRepo.GetOverdueTodosForReport(ctx, workspaceID, 50)
+				return err
+			},
+		},
+		{
+			name: "GetCompletedTodosForReport",
+			run: func(ctx context.Context) error {
+				_, err := todoRepo.GetCompletedTodosForReport(ctx, workspaceID, time.Now().AddDate(0, 0, -7), 50)
+				return err
+			},
+		},
+		{
+			name: "GetStorageUsage",
+			run: func(ctx context.Context) error {
+				_, err := usageRepo.GetStorageUsage(ctx, workspaceID, "user-7")
+				return err
+			},
+		},
+		{
+			name: "GetAccessEntries",
+			run: func(ctx context.Context) error {
+				_, err := reportRepo.GetAccessEntries(ctx, workspaceID)
+				return err
+			},
+		},
+		{
+			name: "GetAttachmentsForIntegrityCheck",
+			run: func(ctx context.Context) error {
+				_, err := todoRepo.GetAttachmentsForIntegrityCheck(ctx, 200)
+				return err
+			},
+		},
+		{
+			name: "GetAttachmentsPendingPreview",
+			run: func(ctx context.Context) error {
+				_, err := todoRepo.GetAttachmentsPendingPreview(ctx, time.Now(), 100)
+				return err
+			},
+		},
+		{
+			name: "GetAttachmentsPendingProcessing",
+			run: func(ctx context.Context) error {
+				_, err := todoRepo.GetAttachmentsPendingProcessing(ctx, time.Now(), 100)
+				return err
+			},
+		},
+		{
+			name: "RelayBatch",
+			run: func(ctx context.Context) error {
+				_, err := outboxRepo.RelayBatch(ctx, 100, 25, func(context.Context, *outbox.Event) error {
+					return nil
+				})
+				return err
+			},
+		},
+		{
+			name: "GetWorkspaceEvents",
+			run: func(ctx context.Context) error {
+				query := &activity.GetFeedQuery{}
+				if err := query.Validate(); err != nil {
+					return err
+				}
+				_, err := activityRepo.GetWorkspaceEvents(ctx, workspaceID, []string{"todo.created"}, query)
+				return err
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder.Take()
+			require.NoError(t, tc.run(ctx))
+
+			explained := 0
+			for _, query := range recorder.Take() {
+				if !testutil.IsExplainable(query) {
+					continue
+				}
+
+				plan := testutil.ExplainQuery(t, testDB.Pool, query)
+				testutil.AssertNoSeqScan(t, plan, query, largeTables...)
+				explained++
+			}
+
+			require.NotZero(t, explained, "no queries were recorded")
+		})
+	}
+}
@@ -0,0 +1,301 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/model/accessreport"
+	"github.com/mabhi256/tasker/internal/model/report"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+type ReportRepository struct {
+	server *server.Server
+}
+
+func NewReportRepository(server *server.Server) *ReportRepository {
+	return &ReportRepository{server: server}
+}
+
+func (r *ReportRepository) CreateReport(ctx context.Context, workspaceID uuid.UUID, userID string,
+	kind report.Kind, payload *report.CreateReportPayload,
+) (*report.Report, error) {
+	stmt := `
+		INSERT INTO
+			reports (
+				workspace_id,
+				user_id,
+				kind,
+				send_email
+			)
+		VALUES
+			(
+				@workspace_id,
+				@user_id,
+				@kind,
+				@send_email
+			)
+		RETURNING
+		*
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+		"user_id":      userID,
+		"kind":         kind,
+		"send_email":   payload.SendEmail,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute create report query for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	reportItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[report.Report])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:reports for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	return &reportItem, nil
+}
+
+// GetReportByID returns one of the user's reports in the workspace
+func (r *ReportRepository) GetReportByID(ctx context.Context, workspaceID uuid.UUID, userID string,
+	reportID uuid.UUID,
+) (*report.Report, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			reports
+		WHERE
+			id=@id
+			AND workspace_id=@workspace_id
+			AND user_id=@user_id
+	`
+
+	rows, err := r.server.DB.Reader(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"id":           reportID,
+		"workspace_id": workspaceID,
+		"user_id":      userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get report by id query for report_id=%s: %w", reportID.String(), err)
+	}
+
+	reportItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[report.Report])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "REPORT_NOT_FOUND"
+			return nil, errs.NewNotFoundError("Report not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:reports for report_id=%s: %w", reportID.String(), err)
+	}
+
+	return &reportItem, nil
+}
+
+// StartReport marks the report as running and counts the attempt
+func (r *ReportRepository) StartReport(ctx context.Context, reportID uuid.UUID) (*report.Report, error) {
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, `
+		UPDATE reports
+		SET
+			status = 'running',
+			attempts = attempts + 1,
+			error = NULL
+		WHERE
+			id = @id
+			AND status IN ('pending', 'running')
+		RETURNING
+		*
+	`, pgx.NamedArgs{
+		"id": reportID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute start report query for report_id=%s: %w", reportID.String(), err)
+	}
+
+	reportItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[report.Report])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:reports for report_id=%s: %w", reportID.String(), err)
+	}
+
+	return &reportItem, nil
+}
+
+// CompleteReport stores where the generated file was uploaded
+func (r *ReportRepository) CompleteReport(ctx context.Context, reportID uuid.UUID, objectKey string,
+	sizeBytes int64,
+) error {
+	_, err := r.server.DB.Conn(ctx).Exec(ctx, `
+		UPDATE reports
+		SET
+			status = 'succeeded',
+			finished_at = NOW(),
+			object_key = @object_key,
+			size_bytes = @size_bytes,
+			error = NULL
+		WHERE
+			id = @id
+	`, pgx.NamedArgs{
+		"id":         reportID,
+		"object_key": objectKey,
+		"size_bytes": sizeBytes,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to complete report_id=%s: %w", reportID.String(), err)
+	}
+
+	return nil
+}
+
+// RecordReportError stores the latest error. The report is only marked
+// failed once no retries are left.
+func (r *ReportRepository) RecordReportError(ctx context.Context, reportID uuid.UUID, errMsg string,
+	final bool,
+) error {
+	status := report.StatusRunning
+	if final {
+		status = report.StatusFailed
+	}
+
+	_, err := r.server.DB.Conn(ctx).Exec(ctx, `
+		UPDATE reports
+		SET
+			status = @status,
+			error = @error,
+			finished_at = CASE WHEN @final THEN NOW() ELSE finished_at END
+		WHERE
+			id = @id
+	`, pgx.NamedArgs{
+		"id":     reportID,
+		"status": status,
+		"error":  errMsg,
+		"final":  final,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record error for report_id=%s: %w", reportID.String(), err)
+	}
+
+	return nil
+}
+
+// CountAccessEntries counts the members and service accounts of the
+// workspace, which its access report lists
+func (r *ReportRepository) CountAccessEntries(ctx context.Context, workspaceID uuid.UUID) (int, error) {
+	stmt := `
+		SELECT
+			(
+				SELECT
+					COUNT(*)
+				FROM
+					workspace_members
+				WHERE
+					workspace_id = @workspace_id
+			) + (
+				SELECT
+					COUNT(*)
+				FROM
+					scim_tokens
+				WHERE
+					workspace_id = @workspace_id
+			)
+	`
+
+	var count int
+	err := r.server.DB.Reader(ctx).QueryRow(ctx, stmt, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+	}).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count access entries for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	return count, nil
+}
+
+// GetAccessEntries lists the members of the workspace with their API keys,
+// oldest first, followed by its SCIM token. Provisioned members are named
+// by the identity provider. Last activity follows
+// idx_event_outbox_workspace_actor.
+func (r *ReportRepository) GetAccessEntries(ctx context.Context, workspaceID uuid.UUID) ([]accessreport.Entry, error) {
+	stmt := `
+		SELECT
+			'member' AS kind,
+			m.user_id AS id,
+			COALESCE(su.display_name, NULLIF(CONCAT_WS(' ', su.given_name, su.family_name), '')) AS name,
+			su.email,
+			m.role,
+			m.created_at AS joined_at,
+			activity.last_active_at,
+			COALESCE(keys.api_keys, '[]'::JSONB) AS api_keys
+		FROM
+			workspace_members m
+			LEFT JOIN scim_users su ON su.workspace_id = m.workspace_id
+			AND su.user_id = m.user_id
+			LEFT JOIN LATERAL (
+				SELECT
+					MAX(e.created_at) AS last_active_at
+				FROM
+					event_outbox e
+				WHERE
+					e.workspace_id = m.workspace_id
+					AND e.actor_id = m.user_id
+			) activity ON TRUE
+			LEFT JOIN LATERAL (
+				SELECT
+					jsonb_agg(
+						jsonb_build_object(
+							'id', k.id,
+							'name', k.name,
+							'scope', k.scope,
+							'hint', k.hint,
+							'createdAt', k.created_at,
+							'lastUsedAt', k.last_used_at
+						)
+						ORDER BY
+							k.created_at,
+							k.id
+					) AS api_keys
+				FROM
+					api_keys k
+				WHERE
+					k.user_id = m.user_id
+			) keys ON TRUE
+		WHERE
+			m.workspace_id = @workspace_id
+		UNION ALL
+		SELECT
+			'service_account',
+			t.id::TEXT,
+			'SCIM provisioning',
+			NULL,
+			NULL,
+			t.created_at,
+			t.last_used_at,
+			'[]'::JSONB
+		FROM
+			scim_tokens t
+		WHERE
+			t.workspace_id = @workspace_id
+		ORDER BY
+			kind,
+			joined_at,
+			id
+	`
+
+	rows, err := r.server.DB.Reader(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get access entries query for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	entries, err := pgx.CollectRows(rows, pgx.RowToStructByName[accessreport.Entry])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect access entries for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	return entries, nil
+}
@@ -5,15 +5,61 @@ import (
 )
 
 type Repositories struct {
-	Todo     *TodoRepository
-	Category *CategoryRepository
-	Comment  *CommentRepository
+	Todo          *TodoRepository
+	Category      *CategoryRepository
+	Comment       *CommentRepository
+	Admin         *AdminRepository
+	Workspace     *WorkspaceRepository
+	Export        *ExportRepository
+	Webhook       *WebhookRepository
+	Outbox        *OutboxRepository
+	JobFailure    *JobFailureRepository
+	Digest        *DigestRepository
+	Usage         *UsageRepository
+	Device        *DeviceRepository
+	Rollout       *RolloutRepository
+	Backfill      *BackfillRepository
+	Audit         *AuditRepository
+	Provisioning  *ProvisioningRepository
+	SSO           *SSORepository
+	IPAllowlist   *IPAllowlistRepository
+	APIKey        *APIKeyRepository
+	Report        *ReportRepository
+	Snapshot      *SnapshotRepository
+	Tag           *TagRepository
+	Trial         *TrialRepository
+	Activity      *ActivityRepository
+	EmailTracking *EmailTrackingRepository
+	WeeklyReport  *WeeklyReportRepository
 }
 
 func NewRepositories(s *server.Server) *Repositories {
 	return &Repositories{
-		Todo:     NewTodoRepository(s),
-		Category: NewCategoryRepository(s),
-		Comment:  NewCommentRepository(s),
+		Todo:          NewTodoRepository(s),
+		Category:      NewCategoryRepository(s),
+		Comment:       NewCommentRepository(s),
+		Admin:         NewAdminRepository(s),
+		Workspace:     NewWorkspaceRepository(s),
+		Export:        NewExportRepository(s),
+		Webhook:       NewWebhookRepository(s),
+		Outbox:        NewOutboxRepository(s),
+		JobFailure:    NewJobFailureRepository(s),
+		Digest:        NewDigestRepository(s),
+		Usage:         NewUsageRepository(s),
+		Device:        NewDeviceRepository(s),
+		Rollout:       NewRolloutRepository(s),
+		Backfill:      NewBackfillRepository(s),
+		Audit:         NewAuditRepository(s),
+		Provisioning:  NewProvisioningRepository(s),
+		SSO:           NewSSORepository(s),
+		IPAllowlist:   NewIPAllowlistRepository(s),
+		APIKey:        NewAPIKeyRepository(s),
+		Report:        NewReportRepository(s),
+		Snapshot:      NewSnapshotRepository(s),
+		Tag:           NewTagRepository(s),
+		Trial:         NewTrialRepository(s),
+		Activity:      NewActivityRepository(s),
+		EmailTracking: NewEmailTrackingRepository(s),
+		WeeklyReport:  NewWeeklyReportRepository(s),
 	}
 }
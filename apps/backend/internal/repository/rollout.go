@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/mabhi256/tasker/internal/model/admin"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// RolloutRepository records the progress of expand/contract backfills
+type RolloutRepository struct {
+	server *server.Server
+}
+
+func NewRolloutRepository(server *server.Server) *RolloutRepository {
+	return &RolloutRepository{server: server}
+}
+
+func (r *RolloutRepository) GetRolloutProgress(ctx context.Context) ([]admin.RolloutProgress, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			schema_rollouts
+		ORDER BY
+			name
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get rollout progress query: %w", err)
+	}
+
+	progress, err := pgx.CollectRows(rows, pgx.RowToStructByName[admin.RolloutProgress])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:schema_rollouts: %w", err)
+	}
+
+	return progress, nil
+}
+
+// StartBackfill marks the rollout running. A backfill that finished before
+// starts over from the first row; any other resumes after the last row it
+// reached.
+func (r *RolloutRepository) StartBackfill(ctx context.Context, name string) (*admin.RolloutProgress, error) {
+	stmt := `
+		INSERT INTO
+			schema_rollouts (name, status, started_at)
+		VALUES
+			(@name, 'running', NOW())
+		ON CONFLICT (name) DO UPDATE
+		SET
+			status = 'running',
+			last_error = NULL,
+			last_id = CASE
+				WHEN schema_rollouts.status IN ('backfilled', 'verified') THEN @first_id
+				ELSE schema_rollouts.last_id
+			END,
+			rows_done = CASE
+				WHEN schema_rollouts.status IN ('backfilled', 'verified') THEN 0
+				ELSE schema_rollouts.rows_done
+			END,
+			batches = CASE
+				WHEN schema_rollouts.status IN ('backfilled', 'verified') THEN 0
+				ELSE schema_rollouts.batches
+			END,
+			started_at = CASE
+				WHEN schema_rollouts.status IN ('backfilled', 'verified')
+				OR schema_rollouts.started_at IS NULL THEN NOW()
+				ELSE schema_rollouts.started_at
+			END,
+			backfilled_at = NULL,
+			verified_at = NULL
+		RETURNING
+			*
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"name":     name,
+		"first_id": uuid.Nil,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute start backfill query for rollout=%s: %w", name, err)
+	}
+
+	progress, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[admin.RolloutProgress])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:schema_rollouts for rollout=%s: %w", name, err)
+	}
+
+	return &progress, nil
+}
+
+func (r *RolloutRepository) RecordBatch(ctx context.Context, name string, lastID uuid.UUID, updated int64) error {
+	_, err := r.server.DB.Conn(ctx).Exec(ctx, `
+		UPDATE schema_rollouts
+		SET
+			last_id = @last_id,
+			rows_done = rows_done + @updated,
+			batches = batches + 1
+		WHERE
+			name = @name
+	`, pgx.NamedArgs{
+		"name":    name,
+		"last_id": lastID,
+		"updated": updated,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record backfill batch for rollout=%s: %w", name, err)
+	}
+
+	return nil
+}
+
+// FinishBackfill records that the backfill reached the last row, and the
+// rows still pending then. With none left the rollout is verified.
+func (r *RolloutRepository) FinishBackfill(ctx context.Context, name string, pending int64) error {
+	_, err := r.server.DB.Conn(ctx).Exec(ctx, `
+		UPDATE schema_rollouts
+		SET
+			status = CASE
+				WHEN @pending::BIGINT = 0 THEN 'verified'
+				ELSE 'backfilled'
+			END,
+			pending_rows = @pending::BIGINT,
+			backfilled_at = NOW(),
+			verified_at = CASE
+				WHEN @pending::BIGINT = 0 THEN NOW()
+			END
+		WHERE
+			name = @name
+	`, pgx.NamedArgs{
+		"name":    name,
+		"pending": pending,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to finish backfill for rollout=%s: %w", name, err)
+	}
+
+	return nil
+}
+
+// RecordVerification stores the rows found pending by a contract check. A
+// rollout with none is verified, and one that was verified but has pending
+// rows again goes back to backfilled.
+func (r *RolloutRepository) RecordVerification(ctx context.Context, name string,
+	pending int64,
+) (*admin.RolloutProgress, error) {
+	stmt := `
+		INSERT INTO
+			schema_rollouts (name, status, pending_rows, verified_at)
+		VALUES
+			(
+				@name,
+				CASE
+					WHEN @pending::BIGINT = 0 THEN 'verified'
+					ELSE 'pending'
+				END,
+				@pending::BIGINT,
+				CASE
+					WHEN @pending::BIGINT = 0 THEN NOW()
+				END
+			)
+		ON CONFLICT (name) DO UPDATE
+		SET
+			status = CASE
+				WHEN schema_rollouts.status = 'running' THEN schema_rollouts.status
+				WHEN @pending::BIGINT = 0 THEN 'verified'
+				WHEN schema_rollouts.status = 'verified' THEN 'backfilled'
+				ELSE schema_rollouts.status
+			END,
+			pending_rows = @pending::BIGINT,
+			verified_at = CASE
+				WHEN @pending::BIGINT = 0 THEN NOW()
+			END
+		RETURNING
+			*
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"name":    name,
+		"pending": pending,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute record verification query for rollout=%s: %w", name, err)
+	}
+
+	progress, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[admin.RolloutProgress])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:schema_rollouts for rollout=%s: %w", name, err)
+	}
+
+	return &progress, nil
+}
+
+// RecordBackfillError stores the error of a failed attempt. When final is
+// set the rollout is marked failed until its backfill is started again.
+func (r *RolloutRepository) RecordBackfillError(ctx context.Context, name string, errMsg string, final bool) error {
+	_, err := r.server.DB.Conn(ctx).Exec(ctx, `
+		UPDATE schema_rollouts
+		SET
+			last_error = @error,
+			status = CASE
+				WHEN @final THEN 'failed'
+				ELSE status
+			END
+		WHERE
+			name = @name
+	`, pgx.NamedArgs{
+		"name":  name,
+		"error": errMsg,
+		"final": final,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record backfill error for rollout=%s: %w", name, err)
+	}
+
+	return nil
+}
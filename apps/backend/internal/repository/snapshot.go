@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/mabhi256/tasker/internal/model/snapshot"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+type SnapshotRepository struct {
+	server *server.Server
+}
+
+func NewSnapshotRepository(server *server.Server) *SnapshotRepository {
+	return &SnapshotRepository{server: server}
+}
+
+func (r *SnapshotRepository) CreateSnapshot(ctx context.Context, item *snapshot.Snapshot) (*snapshot.Snapshot, error) {
+	stmt := `
+		INSERT INTO
+			todo_snapshots (
+				id,
+				workspace_id,
+				todo_id,
+				created_by,
+				format,
+				object_key,
+				size_bytes,
+				checksum_sha256,
+				expires_at
+			)
+		VALUES
+			(
+				@id,
+				@workspace_id,
+				@todo_id,
+				@created_by,
+				@format,
+				@object_key,
+				@size_bytes,
+				@checksum_sha256,
+				@expires_at
+			)
+		RETURNING
+		*
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"id":              item.ID,
+		"workspace_id":    item.WorkspaceID,
+		"todo_id":         item.TodoID,
+		"created_by":      item.CreatedBy,
+		"format":          item.Format,
+		"object_key":      item.ObjectKey,
+		"size_bytes":      item.SizeBytes,
+		"checksum_sha256": item.ChecksumSHA256,
+		"expires_at":      item.ExpiresAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute create snapshot query for todo_id=%s: %w", item.TodoID.String(), err)
+	}
+
+	created, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[snapshot.Snapshot])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:todo_snapshots for todo_id=%s: %w", item.TodoID.String(), err)
+	}
+
+	return &created, nil
+}
+
+// GetExpiredSnapshots returns snapshots past their retention, the longest
+// expired first
+func (r *SnapshotRepository) GetExpiredSnapshots(ctx context.Context, limit int) ([]snapshot.Snapshot, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			todo_snapshots
+		WHERE
+			expires_at < @now
+		ORDER BY
+			expires_at ASC
+		LIMIT
+			@limit
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"now":   time.Now(),
+		"limit": limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get expired snapshots query: %w", err)
+	}
+
+	snapshots, err := pgx.CollectRows(rows, pgx.RowToStructByName[snapshot.Snapshot])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:todo_snapshots: %w", err)
+	}
+
+	return snapshots, nil
+}
+
+func (r *SnapshotRepository) DeleteSnapshot(ctx context.Context, snapshotID uuid.UUID) error {
+	_, err := r.server.DB.Conn(ctx).Exec(ctx, `
+		DELETE FROM todo_snapshots
+		WHERE
+			id = @id
+	`, pgx.NamedArgs{
+		"id": snapshotID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete snapshot_id=%s: %w", snapshotID.String(), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"maps"
+	"strings"
+
+	"github.com/mabhi256/tasker/internal/lib/collation"
+	"github.com/mabhi256/tasker/internal/model"
+)
+
+// orderBy returns the ORDER BY clause of keys, sorting by the columns their
+// fields map to. Rows that tie on every key are then ordered by tiebreak, a
+// unique column, in the direction of the last key, so the pages of a list
+// never share or skip rows.
+func orderBy(keys []model.SortKey, columns map[string]string, tiebreak string) string {
+	terms := make([]string, 0, len(keys)+1)
+	direction := "ASC"
+	unique := false
+	for _, key := range keys {
+		column := columns[key.Field]
+		direction = "ASC"
+		if key.Desc {
+			direction = "DESC"
+		}
+		terms = append(terms, column+" "+direction)
+		unique = unique || column == tiebreak
+	}
+	if !unique {
+		terms = append(terms, tiebreak+" "+direction)
+	}
+
+	return " ORDER BY " + strings.Join(terms, ", ")
+}
+
+// collated returns columns with the columns of the text fields sorted by
+// the collation of lang, a collation language, rather than the database's
+// byte order. Without a language they sort by the root collation.
+func collated(columns map[string]string, lang *string, fields ...string) map[string]string {
+	name := collation.Name(collation.Root)
+	if lang != nil {
+		name = collation.Name(*lang)
+	}
+
+	collated := maps.Clone(columns)
+	for _, field := range fields {
+		collated[field] += " COLLATE " + name
+	}
+	return collated
+}
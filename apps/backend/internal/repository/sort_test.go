@@ -0,0 +1,127 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/model/todo"
+	"github.com/mabhi256/tasker/internal/repository"
+	testutil "github.com/mabhi256/tasker/internal/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStableTodoPages pages through todos created at the same time, which
+// only the id tiebreaker orders, and sorts them by several keys
+func TestStableTodoPages(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping sort tests in short mode")
+	}
+
+	testDB, srv, cleanup := testutil.SetupTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	userID := "user-1"
+
+	var workspaceID uuid.UUID
+	err := testDB.Pool.QueryRow(ctx, `
+		INSERT INTO workspaces (name, owner_id, is_personal)
+		VALUES ('Workspace', $1, TRUE)
+		RETURNING id
+	`, userID).Scan(&workspaceID)
+	require.NoError(t, err)
+
+	todoRepo := repository.NewTodoRepository(srv)
+	priorities := []todo.Priority{todo.PriorityLow, todo.PriorityMedium, todo.PriorityHigh}
+	for i := range 25 {
+		_, err := todoRepo.CreateTodo(ctx, workspaceID, userID, &todo.CreateTodoPayload{
+			Title:    fmt.Sprintf("Todo %02d", i),
+			Priority: &priorities[i%3],
+		})
+		require.NoError(t, err)
+	}
+	_, err = testDB.Pool.Exec(ctx, `UPDATE todos SET created_at = '2026-01-01T00:00:00Z' WHERE workspace_id = $1`, workspaceID)
+	require.NoError(t, err)
+
+	page := func(sort string, n int) []todo.PopulatedTodo {
+		query := &todo.GetTodosQuery{Page: testutil.Ptr(n), Limit: testutil.Ptr(10)}
+		if sort != "" {
+			query.Sort = &sort
+		}
+		require.NoError(t, query.Validate())
+		result, err := todoRepo.GetTodos(ctx, workspaceID, query)
+		require.NoError(t, err)
+		return result.Data
+	}
+
+	seen := map[uuid.UUID]bool{}
+	for n := 1; n <= 3; n++ {
+		for _, item := range page("", n) {
+			assert.False(t, seen[item.ID], "todo %s is on two pages", item.Title)
+			seen[item.ID] = true
+		}
+	}
+	assert.Len(t, seen, 25)
+
+	// Highest priority first, then by title
+	sorted := page("-priority,title", 1)
+	require.Len(t, sorted, 10)
+	assert.Equal(t, "Todo 02", sorted[0].Title)
+	assert.Equal(t, "Todo 05", sorted[1].Title)
+	for _, item := range sorted[:8] {
+		assert.Equal(t, todo.PriorityHigh, item.Priority)
+	}
+
+	query := &todo.GetTodosQuery{Sort: testutil.Ptr("priority,-priority")}
+	assert.Error(t, query.Validate())
+	query = &todo.GetTodosQuery{Sort: testutil.Ptr("due_at")}
+	assert.Error(t, query.Validate())
+}
+
+// TestCollatedTitles sorts titles by the collation of the query's language,
+// which in Swedish puts Ä and Ö after Z
+func TestCollatedTitles(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping sort tests in short mode")
+	}
+
+	testDB, srv, cleanup := testutil.SetupTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	userID := "user-1"
+
+	var workspaceID uuid.UUID
+	err := testDB.Pool.QueryRow(ctx, `
+		INSERT INTO workspaces (name, owner_id, is_personal)
+		VALUES ('Workspace', $1, TRUE)
+		RETURNING id
+	`, userID).Scan(&workspaceID)
+	require.NoError(t, err)
+
+	todoRepo := repository.NewTodoRepository(srv)
+	for _, title := range []string{"zebra", "Öl", "Banana", "Äpfel", "apple"} {
+		_, err := todoRepo.CreateTodo(ctx, workspaceID, userID, &todo.CreateTodoPayload{Title: title})
+		require.NoError(t, err)
+	}
+
+	titles := func(locale *string) []string {
+		query := &todo.GetTodosQuery{Sort: testutil.Ptr("title"), Order: testutil.Ptr("asc"), Locale: locale}
+		require.NoError(t, query.Validate())
+		result, err := todoRepo.GetTodos(ctx, workspaceID, query)
+		require.NoError(t, err)
+
+		titles := make([]string, len(result.Data))
+		for i, item := range result.Data {
+			titles[i] = item.Title
+		}
+		return titles
+	}
+
+	assert.Equal(t, []string{"Äpfel", "apple", "Banana", "Öl", "zebra"}, titles(nil))
+	assert.Equal(t, []string{"Äpfel", "apple", "Banana", "Öl", "zebra"}, titles(testutil.Ptr("de")))
+	assert.Equal(t, []string{"apple", "Banana", "zebra", "Äpfel", "Öl"}, titles(testutil.Ptr("sv")))
+}
@@ -0,0 +1,218 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/model/sso"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+type SSORepository struct {
+	server *server.Server
+}
+
+func NewSSORepository(server *server.Server) *SSORepository {
+	return &SSORepository{server: server}
+}
+
+// UpsertConfig sets the workspace's identity provider. Enforcement is left
+// as it was when payload.Enforced is nil, and off for a new config. Domains
+// stay verified while they are still allowed.
+func (r *SSORepository) UpsertConfig(ctx context.Context, workspaceID uuid.UUID, userID string,
+	payload *sso.UpsertConfigPayload,
+) (*sso.Config, error) {
+	stmt := `
+		INSERT INTO
+			workspace_sso (
+				workspace_id,
+				created_by,
+				discovery_url,
+				client_id,
+				allowed_domains,
+				enforced
+			)
+		VALUES
+			(
+				@workspace_id,
+				@created_by,
+				@discovery_url,
+				@client_id,
+				@allowed_domains,
+				COALESCE(@enforced, FALSE)
+			)
+		ON CONFLICT (workspace_id) DO UPDATE
+		SET
+			discovery_url = EXCLUDED.discovery_url,
+			client_id = EXCLUDED.client_id,
+			allowed_domains = EXCLUDED.allowed_domains,
+			verified_domains = ARRAY(
+				SELECT unnest(workspace_sso.verified_domains)
+				INTERSECT
+				SELECT unnest(EXCLUDED.allowed_domains)
+			),
+			enforced = COALESCE(@enforced, workspace_sso.enforced)
+		RETURNING
+		*
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"workspace_id":    workspaceID,
+		"created_by":      userID,
+		"discovery_url":   payload.DiscoveryURL,
+		"client_id":       payload.ClientID,
+		"allowed_domains": payload.AllowedDomains,
+		"enforced":        payload.Enforced,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute upsert sso config query for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	config, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[sso.Config])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:workspace_sso for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	return &config, nil
+}
+
+func (r *SSORepository) GetConfig(ctx context.Context, workspaceID uuid.UUID) (*sso.Config, error) {
+	config, err := r.FindConfig(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		code := "SSO_CONFIG_NOT_FOUND"
+		return nil, errs.NewNotFoundError("SSO is not configured for this workspace", false, &code)
+	}
+	return config, nil
+}
+
+// FindConfig returns the workspace's config, or nil when it has none
+func (r *SSORepository) FindConfig(ctx context.Context, workspaceID uuid.UUID) (*sso.Config, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			workspace_sso
+		WHERE
+			workspace_id=@workspace_id
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get sso config query for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	config, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[sso.Config])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to collect row from table:workspace_sso for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	return &config, nil
+}
+
+// AddVerifiedDomain marks one of the workspace's allowed domains verified
+func (r *SSORepository) AddVerifiedDomain(ctx context.Context, workspaceID uuid.UUID,
+	domain string,
+) (*sso.Config, error) {
+	stmt := `
+		UPDATE
+			workspace_sso
+		SET
+			verified_domains = CASE
+				WHEN @domain = ANY(verified_domains) THEN verified_domains
+				ELSE array_append(verified_domains, @domain)
+			END
+		WHERE
+			workspace_id=@workspace_id
+			AND @domain = ANY(allowed_domains)
+		RETURNING
+		*
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+		"domain":       domain,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute add verified domain query for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	config, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[sso.Config])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "SSO_DOMAIN_NOT_ALLOWED"
+			return nil, errs.NewNotFoundError("Domain is not allowed for this workspace", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:workspace_sso for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	return &config, nil
+}
+
+func (r *SSORepository) DeleteConfig(ctx context.Context, workspaceID uuid.UUID) error {
+	result, err := r.server.DB.Conn(ctx).Exec(ctx, `
+		DELETE FROM workspace_sso
+		WHERE workspace_id = @workspace_id
+	`, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to execute delete sso config query for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	if result.RowsAffected() == 0 {
+		code := "SSO_CONFIG_NOT_FOUND"
+		return errs.NewNotFoundError("SSO is not configured for this workspace", false, &code)
+	}
+
+	return nil
+}
+
+// GetEnforcedConfigForMember returns the workspace's config when it enforces
+// single sign-on for the member, or nil. Owners are exempt, so a
+// misconfigured identity provider can't lock everyone out.
+func (r *SSORepository) GetEnforcedConfigForMember(ctx context.Context, workspaceID uuid.UUID,
+	userID string,
+) (*sso.Config, error) {
+	stmt := `
+		SELECT
+			s.*
+		FROM
+			workspace_sso s
+			JOIN workspace_members m ON m.workspace_id = s.workspace_id
+		WHERE
+			s.workspace_id = @workspace_id
+			AND m.user_id = @user_id
+			AND m.role <> 'owner'
+			AND s.enforced
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+		"user_id":      userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get enforced sso config query for workspace_id=%s user_id=%s: %w", workspaceID.String(), userID, err)
+	}
+
+	config, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[sso.Config])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to collect row from table:workspace_sso for workspace_id=%s user_id=%s: %w", workspaceID.String(), userID, err)
+	}
+
+	return &config, nil
+}
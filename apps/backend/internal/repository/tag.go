@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/model/tag"
+	"github.com/mabhi256/tasker/internal/model/webhook"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+type TagRepository struct {
+	server *server.Server
+}
+
+func NewTagRepository(server *server.Server) *TagRepository {
+	return &TagRepository{server: server}
+}
+
+// ReplaceTag relabels every todo of the workspace tagged from with to, in
+// one transaction with its event. A todo that already has both tags lists
+// to once, where it first listed either. With merge unset to must not
+// exist, and with merge set it must.
+func (r *TagRepository) ReplaceTag(ctx context.Context, workspaceID uuid.UUID, from string, to string,
+	merge bool,
+) (*tag.Change, error) {
+	args := pgx.NamedArgs{
+		"workspace_id": workspaceID,
+		"from":         from,
+		"to":           to,
+	}
+
+	change := &tag.Change{From: from, To: to, Merged: merge, TodoIDs: []uuid.UUID{}}
+	err := pgx.BeginFunc(ctx, r.server.DB.Conn(ctx), func(tx pgx.Tx) error {
+		var targetExists bool
+		err := tx.QueryRow(ctx, `
+			SELECT
+				EXISTS (
+					SELECT
+						1
+					FROM
+						todos
+					WHERE
+						workspace_id = @workspace_id
+						AND metadata -> 'tags' ? @to
+				)
+		`, args).Scan(&targetExists)
+		if err != nil {
+			return fmt.Errorf("failed to check tag exists in table:todos: %w", err)
+		}
+
+		switch {
+		case merge && !targetExists:
+			code := "TAG_NOT_FOUND"
+			return errs.NewNotFoundError("target tag not found", false, &code)
+		case !merge && targetExists:
+			code := "TAG_EXISTS"
+			return errs.NewConflictError("a tag with this name already exists, merge the tags instead", false, &code,
+				nil, nil)
+		}
+
+		// Lock the tagged todos first, so a todo tagged concurrently is
+		// either relabelled or tagged after the change
+		rows, err := tx.Query(ctx, `
+			WITH
+				tagged AS (
+					SELECT
+						id,
+						metadata -> 'tags' ? @to AS had_target
+					FROM
+						todos
+					WHERE
+						workspace_id = @workspace_id
+						AND metadata -> 'tags' ? @from
+					FOR UPDATE
+				)
+			UPDATE todos t
+			SET
+				metadata = jsonb_set(
+					t.metadata,
+					'{tags}',
+					(
+						SELECT
+							COALESCE(jsonb_agg(relabelled.tag ORDER BY relabelled.ord), '[]'::jsonb)
+						FROM
+							(
+								SELECT
+									CASE
+										WHEN existing.tag = @from THEN @to
+										ELSE existing.tag
+									END AS tag,
+									MIN(existing.ord) AS ord
+								FROM
+									jsonb_array_elements_text(t.metadata -> 'tags') WITH ORDINALITY AS existing (tag, ord)
+								GROUP BY
+									1
+							) relabelled
+					)
+				)
+			FROM
+				tagged
+			WHERE
+				t.id = tagged.id
+			RETURNING
+				t.id,
+				tagged.had_target
+		`, args)
+		if err != nil {
+			return fmt.Errorf("failed to execute replace tag query for workspace_id=%s: %w", workspaceID.String(), err)
+		}
+
+		var (
+			todoID    uuid.UUID
+			hadTarget bool
+		)
+		_, err = pgx.ForEachRow(rows, []any{&todoID, &hadTarget}, func() error {
+			change.TodoIDs = append(change.TodoIDs, todoID)
+			if hadTarget {
+				change.Deduplicated++
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to collect rows from table:todos: %w", err)
+		}
+
+		if len(change.TodoIDs) == 0 {
+			code := "TAG_NOT_FOUND"
+			return errs.NewNotFoundError("tag not found", false, &code)
+		}
+
+		eventType := webhook.EventTagRenamed
+		if merge {
+			eventType = webhook.EventTagMerged
+		}
+		return insertOutboxEvent(ctx, tx, workspaceID, string(eventType), change)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return change, nil
+}
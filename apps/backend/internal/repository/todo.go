@@ -10,11 +10,27 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/lib/artifact"
+	"github.com/mabhi256/tasker/internal/lib/preview"
 	"github.com/mabhi256/tasker/internal/model"
 	"github.com/mabhi256/tasker/internal/model/todo"
+	"github.com/mabhi256/tasker/internal/model/webhook"
+	"github.com/mabhi256/tasker/internal/repository/queries"
 	"github.com/mabhi256/tasker/internal/server"
 )
 
+// todoSortColumns are the columns of the fields todos are sorted by.
+// Priorities sort by rank, from low to high, rather than by name.
+var todoSortColumns = map[string]string{
+	"created_at": "t.created_at",
+	"updated_at": "t.updated_at",
+	"title":      "t.title",
+	"priority":   "COALESCE(t.priority_rank, todo_priority_rank(t.priority))",
+	"due_date":   "t.due_date",
+	"status":     "t.status",
+	"id":         "t.id",
+}
+
 type TodoRepository struct {
 	server *server.Server
 }
@@ -23,63 +39,56 @@ func NewTodoRepository(server *server.Server) *TodoRepository {
 	return &TodoRepository{server: server}
 }
 
-func (tr *TodoRepository) CreateTodo(ctx context.Context, userID string, payload *todo.CreateTodoPayload) (*todo.Todo, error) {
-	stmt := `
-		INSERT INTO
-			todos (
-				user_id,
-				title,
-				description,
-				priority,
-				due_date,
-				parent_todo_id,
-				category_id,
-				metadata
-			)
-		VALUES
-			(
-				@user_id,
-				@title,
-				@description,
-				@priority,
-				@due_date,
-				@parent_todo_id,
-				@category_id,
-				@metadata
-			)
-		RETURNING
-		*
-	`
-
+func (tr *TodoRepository) CreateTodo(ctx context.Context, workspaceID uuid.UUID, userID string,
+	payload *todo.CreateTodoPayload,
+) (*todo.Todo, error) {
 	priority := todo.PriorityMedium
 	if payload.Priority != nil {
 		priority = *payload.Priority
 	}
 
-	rows, err := tr.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
-		"user_id":        userID,
-		"title":          payload.Title,
-		"description":    payload.Description,
-		"priority":       priority,
-		"due_date":       payload.DueDate,
-		"parent_todo_id": payload.ParentTodoID,
-		"category_id":    payload.CategoryID,
-		"metadata":       payload.Metadata,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute create todo query for user_id=%s title=%s: %w",
-			userID, payload.Title, err)
+	status := todo.StatusDraft
+	if payload.PublishAt != nil {
+		status = todo.StatusScheduled
 	}
 
-	todoItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.Todo])
+	var todoItem todo.Todo
+	err := pgx.BeginFunc(ctx, tr.server.DB.Conn(ctx), func(tx pgx.Tx) error {
+		row, err := queries.New(tx).CreateTodo(ctx, queries.CreateTodoParams{
+			WorkspaceID:     workspaceID,
+			UserID:          userID,
+			Title:           payload.Title,
+			Description:     payload.Description,
+			Priority:        priority,
+			DueDate:         payload.DueDate,
+			ParentTodoID:    payload.ParentTodoID,
+			CategoryID:      payload.CategoryID,
+			Metadata:        payload.Metadata,
+			ReminderMinutes: int32Ptr(payload.ReminderMinutes),
+			Status:          status,
+			PublishAt:       payload.PublishAt,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to execute create todo query for workspace_id=%s user_id=%s title=%s: %w",
+				workspaceID.String(), userID, payload.Title, err)
+		}
+		todoItem = todoFromRow(row)
+
+		// The creation of a scheduled todo is announced once it's published
+		if status == todo.StatusScheduled {
+			return nil
+		}
+
+		return insertOutboxEvent(ctx, tx, workspaceID, string(webhook.EventTodoCreated), todoItem)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to collect row from table:todos for user_id=%s title=%s: %w", userID, payload.Title, err)
+		return nil, err
 	}
 
 	return &todoItem, nil
 }
 
-func (r *TodoRepository) GetTodoByID(ctx context.Context, userID string, todoID uuid.UUID) (*todo.PopulatedTodo, error) {
+func (r *TodoRepository) GetTodoByID(ctx context.Context, workspaceID uuid.UUID, todoID uuid.UUID) (*todo.PopulatedTodo, error) {
 	stmt := `
 	SELECT
 		t.*,
@@ -92,7 +101,8 @@ func (r *TodoRepository) GetTodoByID(ctx context.Context, userID string, todoID
 				to_jsonb(camel (child))
 				ORDER BY
 					child.sort_order ASC,
-					child.created_at ASC
+					child.created_at ASC,
+					child.id ASC
 			) FILTER (
 				WHERE
 					child.id IS NOT NULL
@@ -103,7 +113,8 @@ func (r *TodoRepository) GetTodoByID(ctx context.Context, userID string, todoID
 			jsonb_agg(
 				to_jsonb(camel (com))
 				ORDER BY
-					com.created_at ASC
+					com.created_at ASC,
+					com.id ASC
 			) FILTER (
 				WHERE
 					com.id IS NOT NULL
@@ -114,7 +125,8 @@ func (r *TodoRepository) GetTodoByID(ctx context.Context, userID string, todoID
 			jsonb_agg(
 				to_jsonb(camel (att))
 				ORDER BY
-					att.created_at DESC
+					att.created_at DESC,
+					att.id DESC
 			) FILTER (
 				WHERE
 					att.id IS NOT NULL
@@ -124,58 +136,74 @@ func (r *TodoRepository) GetTodoByID(ctx context.Context, userID string, todoID
 	FROM
 		todos t
 		LEFT JOIN todo_categories c ON c.id=t.category_id
-		AND c.user_id=@user_id
+		AND c.workspace_id=t.workspace_id
 		LEFT JOIN todos child ON child.parent_todo_id=t.id
-		AND child.user_id=@user_id
+		AND child.workspace_id=t.workspace_id
 		LEFT JOIN todo_comments com ON com.todo_id=t.id
-		AND com.user_id=@user_id
+		AND com.workspace_id=t.workspace_id
 		LEFT JOIN todo_attachments att ON att.todo_id=t.id
 	WHERE
 		t.id=@id
-		AND t.user_id=@user_id
+		AND t.workspace_id=@workspace_id
 	GROUP BY
 		t.id,
 		c.id
 `
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
-		"id":      todoID,
-		"user_id": userID,
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"id":           todoID,
+		"workspace_id": workspaceID,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute get todo by id query for todo_id=%s user_id=%s: %w", todoID.String(), userID, err)
+		return nil, fmt.Errorf("failed to execute get todo by id query for todo_id=%s workspace_id=%s: %w", todoID.String(), workspaceID.String(), err)
 	}
 
 	todoItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.PopulatedTodo])
 	if err != nil {
-		return nil, fmt.Errorf("failed to collect row from table:todos for todo_id=%s user_id=%s: %w", todoID.String(), userID, err)
+		return nil, fmt.Errorf("failed to collect row from table:todos for todo_id=%s workspace_id=%s: %w", todoID.String(), workspaceID.String(), err)
 	}
 
 	return &todoItem, nil
 }
 
-func (r *TodoRepository) CheckTodoExists(ctx context.Context, userID string, todoID uuid.UUID) (*todo.Todo, error) {
-	stmt := `
-		SELECT * FROM todos WHERE id=@id AND user_id=@user_id
-	`
-
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
-		"id":      todoID,
-		"user_id": userID,
+func (r *TodoRepository) CheckTodoExists(ctx context.Context, workspaceID uuid.UUID, todoID uuid.UUID) (*todo.Todo, error) {
+	row, err := queries.New(r.server.DB.Conn(ctx)).GetTodo(ctx, queries.GetTodoParams{
+		ID:          todoID,
+		WorkspaceID: workspaceID,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to check if todo exists for todo_id=%s user_id=%s: %w", todoID.String(), userID, err)
+		return nil, fmt.Errorf("failed to check if todo exists for todo_id=%s workspace_id=%s: %w", todoID.String(), workspaceID.String(), err)
 	}
 
-	todoItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.Todo])
-	if err != nil {
-		return nil, fmt.Errorf("failed to collect row from table:todos for todo_id=%s user_id=%s: %w", todoID.String(), userID, err)
+	todoItem := todoFromRow(row)
+	return &todoItem, nil
+}
+
+func (r *TodoRepository) GetTodos(ctx context.Context, workspaceID uuid.UUID,
+	query *todo.GetTodosQuery,
+) (*model.PaginatedResponse[todo.PopulatedTodo], error) {
+	args := pgx.NamedArgs{
+		"workspace_id": workspaceID,
 	}
 
-	return &todoItem, nil
+	return r.getTodos(ctx, "t.workspace_id = @workspace_id", args, "workspace_id="+workspaceID.String(), query)
+}
+
+// GetTodosForUser lists todos created by a user across every workspace. It is
+// meant for support tooling; regular requests go through GetTodos.
+func (r *TodoRepository) GetTodosForUser(ctx context.Context, userID string,
+	query *todo.GetTodosQuery,
+) (*model.PaginatedResponse[todo.PopulatedTodo], error) {
+	args := pgx.NamedArgs{
+		"user_id": userID,
+	}
+
+	return r.getTodos(ctx, "t.user_id = @user_id", args, "user_id="+userID, query)
 }
 
-func (r *TodoRepository) GetTodos(ctx context.Context, userID string, query *todo.GetTodosQuery) (*model.PaginatedResponse[todo.PopulatedTodo], error) {
+func (r *TodoRepository) getTodos(ctx context.Context, scope string, args pgx.NamedArgs, scopeLabel string,
+	query *todo.GetTodosQuery,
+) (*model.PaginatedResponse[todo.PopulatedTodo], error) {
 	stmt := `
 	SELECT
 		t.*,
@@ -186,14 +214,14 @@ func (r *TodoRepository) GetTodos(ctx context.Context, userID string, query *tod
 		COALESCE(
 			jsonb_agg(
 				to_jsonb(camel (child))
-				ORDER BY child.sort_order ASC, child.created_at ASC
+				ORDER BY child.sort_order ASC, child.created_at ASC, child.id ASC
 			) FILTER (WHERE child.id IS NOT NULL),
 			'[]'::JSONB
 		) AS children,
 		COALESCE(
 			jsonb_agg(
 				to_jsonb(camel (com))
-				ORDER BY com.created_at ASC
+				ORDER BY com.created_at ASC, com.id ASC
 			) FILTER (WHERE com.id IS NOT NULL),
 			'[]'::JSONB
 		) AS comments,
@@ -201,7 +229,8 @@ func (r *TodoRepository) GetTodos(ctx context.Context, userID string, query *tod
 			jsonb_agg(
 				to_jsonb(camel (att))
 				ORDER BY
-					att.created_at DESC
+					att.created_at DESC,
+					att.id DESC
 			) FILTER (
 				WHERE
 					att.id IS NOT NULL
@@ -211,18 +240,15 @@ func (r *TodoRepository) GetTodos(ctx context.Context, userID string, query *tod
 	FROM
 		todos t
 		LEFT JOIN todo_categories c ON c.id=t.category_id 
-			AND c.user_id=@user_id
+			AND c.workspace_id=t.workspace_id
 		LEFT JOIN todos child ON child.parent_todo_id=t.id 
-			AND child.user_id=@user_id
+			AND child.workspace_id=t.workspace_id
 		LEFT JOIN todo_comments com ON com.todo_id=t.id 
-			AND com.user_id=@user_id
+			AND com.workspace_id=t.workspace_id
 		LEFT JOIN todo_attachments att ON att.todo_id=t.id
 	`
 
-	args := pgx.NamedArgs{
-		"user_id": userID,
-	}
-	conditions := []string{"t.user_id = @user_id"}
+	conditions := []string{scope}
 
 	if query.Status != nil {
 		conditions = append(conditions, "t.status = @status")
@@ -284,31 +310,22 @@ func (r *TodoRepository) GetTodos(ctx context.Context, userID string, query *tod
 	}
 
 	var total int
-	err := r.server.DB.Pool.QueryRow(ctx, countStmt, args).Scan(&total)
+	err := r.server.DB.Reader(ctx).QueryRow(ctx, countStmt, args).Scan(&total)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get total count for todos user_id=%s: %w", userID, err)
+		return nil, fmt.Errorf("failed to get total count for todos %s: %w", scopeLabel, err)
 	}
 
 	stmt += " GROUP BY t.id, c.id"
 
-	if query.Sort != nil {
-		stmt += " ORDER BY t." + *query.Sort
-		if query.Order != nil && *query.Order == "desc" {
-			stmt += " DESC"
-		} else {
-			stmt += " ASC"
-		}
-	} else {
-		stmt += " ORDER BY t.created_at DESC"
-	}
+	stmt += orderBy(query.SortKeys(), collated(todoSortColumns, query.Locale, "title"), "t.id")
 
 	stmt += " LIMIT @limit OFFSET @offset"
 	args["limit"] = *query.Limit
 	args["offset"] = (*query.Page - 1) * (*query.Limit)
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, args)
+	rows, err := r.server.DB.Reader(ctx).Query(ctx, stmt, args)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute get todos query for user_id=%s: %w", userID, err)
+		return nil, fmt.Errorf("failed to execute get todos query for %s: %w", scopeLabel, err)
 	}
 
 	todos, err := pgx.CollectRows(rows, pgx.RowToStructByName[todo.PopulatedTodo])
@@ -322,7 +339,7 @@ func (r *TodoRepository) GetTodos(ctx context.Context, userID string, query *tod
 				TotalPages: 0,
 			}, nil
 		}
-		return nil, fmt.Errorf("failed to collect rows from table:todos for user_id=%s: %w", userID, err)
+		return nil, fmt.Errorf("failed to collect rows from table:todos for %s: %w", scopeLabel, err)
 	}
 
 	return &model.PaginatedResponse[todo.PopulatedTodo]{
@@ -334,11 +351,161 @@ func (r *TodoRepository) GetTodos(ctx context.Context, userID string, query *tod
 	}, nil
 }
 
-func (r *TodoRepository) UpdateTodo(ctx context.Context, userID string, payload *todo.UpdateTodoPayload) (*todo.Todo, error) {
+// SearchTodoIDs ranks the workspace's todos against text with Postgres full
+// text search. Todos the search_vector backfill hasn't reached yet are
+// matched on a vector built on the fly.
+func (r *TodoRepository) SearchTodoIDs(ctx context.Context, workspaceID uuid.UUID, text string,
+	limit int, offset int,
+) ([]uuid.UUID, int, error) {
+	conditions := `
+		t.workspace_id = @workspace_id
+		AND (
+			t.search_vector @@ websearch_to_tsquery('simple', @text)
+			OR (
+				t.search_vector IS NULL
+				AND todo_search_vector(t.title, t.description) @@ websearch_to_tsquery('simple', @text)
+			)
+		)
+	`
+	args := pgx.NamedArgs{
+		"workspace_id": workspaceID,
+		"text":         text,
+		"limit":        limit,
+		"offset":       offset,
+	}
+
+	var total int
+	err := r.server.DB.Reader(ctx).QueryRow(ctx, "SELECT COUNT(*) FROM todos t WHERE "+conditions, args).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get total count for todo search in workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	stmt := `
+		SELECT
+			t.id
+		FROM
+			todos t
+		WHERE
+	` + conditions + `
+		ORDER BY
+			ts_rank(
+				COALESCE(t.search_vector, todo_search_vector(t.title, t.description)),
+				websearch_to_tsquery('simple', @text)
+			) DESC,
+			t.created_at DESC,
+			t.id DESC
+		LIMIT
+			@limit
+		OFFSET
+			@offset
+	`
+
+	rows, err := r.server.DB.Reader(ctx).Query(ctx, stmt, args)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to execute todo search query for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to collect rows from table:todos for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	return ids, total, nil
+}
+
+// GetTodosByIDs loads todos in the order of ids. IDs of todos that were
+// deleted or belong to another workspace are skipped.
+func (r *TodoRepository) GetTodosByIDs(ctx context.Context, workspaceID uuid.UUID,
+	ids []uuid.UUID,
+) ([]todo.PopulatedTodo, error) {
+	if len(ids) == 0 {
+		return []todo.PopulatedTodo{}, nil
+	}
+
+	stmt := `
+	SELECT
+		t.*,
+		CASE
+			WHEN c.id IS NOT NULL THEN to_jsonb(camel (c))
+			ELSE NULL
+		END AS category,
+		COALESCE(
+			jsonb_agg(
+				to_jsonb(camel (child))
+				ORDER BY
+					child.sort_order ASC,
+					child.created_at ASC,
+					child.id ASC
+			) FILTER (
+				WHERE
+					child.id IS NOT NULL
+			),
+			'[]'::JSONB
+		) AS children,
+		COALESCE(
+			jsonb_agg(
+				to_jsonb(camel (com))
+				ORDER BY
+					com.created_at ASC,
+					com.id ASC
+			) FILTER (
+				WHERE
+					com.id IS NOT NULL
+			),
+			'[]'::JSONB
+		) AS comments,
+		COALESCE(
+			jsonb_agg(
+				to_jsonb(camel (att))
+				ORDER BY
+					att.created_at DESC,
+					att.id DESC
+			) FILTER (
+				WHERE
+					att.id IS NOT NULL
+			),
+			'[]'::JSONB
+		) AS attachments
+	FROM
+		todos t
+		LEFT JOIN todo_categories c ON c.id=t.category_id
+		AND c.workspace_id=t.workspace_id
+		LEFT JOIN todos child ON child.parent_todo_id=t.id
+		AND child.workspace_id=t.workspace_id
+		LEFT JOIN todo_comments com ON com.todo_id=t.id
+		AND com.workspace_id=t.workspace_id
+		LEFT JOIN todo_attachments att ON att.todo_id=t.id
+	WHERE
+		t.id = ANY (@ids::UUID[])
+		AND t.workspace_id=@workspace_id
+	GROUP BY
+		t.id,
+		c.id
+	ORDER BY
+		array_position(@ids::UUID[], t.id)
+`
+
+	rows, err := r.server.DB.Reader(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"ids":          ids,
+		"workspace_id": workspaceID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get todos by ids query for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	todos, err := pgx.CollectRows(rows, pgx.RowToStructByName[todo.PopulatedTodo])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:todos for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	return todos, nil
+}
+
+func (r *TodoRepository) UpdateTodo(ctx context.Context, workspaceID uuid.UUID, payload *todo.UpdateTodoPayload) (*todo.Todo, error) {
 	stmt := "UPDATE todos SET "
 	args := pgx.NamedArgs{
-		"todo_id": payload.ID,
-		"user_id": userID,
+		"todo_id":      payload.ID,
+		"workspace_id": workspaceID,
 	}
 	setClauses := []string{}
 
@@ -363,6 +530,13 @@ func (r *TodoRepository) UpdateTodo(ctx context.Context, userID string, payload
 		} else if *payload.Status != todo.StatusCompleted {
 			setClauses = append(setClauses, "completed_at = NULL")
 		}
+
+		// A scheduled todo given a status is published now. The service
+		// doesn't take a publish time along with a status.
+		if payload.PublishAt == nil {
+			setClauses = append(setClauses,
+				"publish_at = CASE WHEN status = 'scheduled' THEN LEAST(publish_at, NOW()) ELSE publish_at END")
+		}
 	}
 
 	if payload.Priority != nil {
@@ -390,41 +564,89 @@ func (r *TodoRepository) UpdateTodo(ctx context.Context, userID string, payload
 		args["metadata"] = payload.Metadata
 	}
 
+	if payload.ReminderMinutes != nil {
+		setClauses = append(setClauses, "reminder_minutes = @reminder_minutes")
+		args["reminder_minutes"] = *payload.ReminderMinutes
+	}
+
+	if payload.PublishAt != nil {
+		setClauses = append(setClauses, "publish_at = @publish_at")
+		args["publish_at"] = *payload.PublishAt
+	}
+
 	if len(setClauses) == 0 {
 		return nil, errs.NewBadRequestError("no fields to update", false, nil, nil, nil)
 	}
 
 	stmt += strings.Join(setClauses, ", ")
-	stmt += " WHERE id = @todo_id AND user_id = @user_id RETURNING *"
+	stmt += " WHERE id = @todo_id AND workspace_id = @workspace_id RETURNING *"
+
+	var updatedTodo todo.Todo
+	err := pgx.BeginFunc(ctx, r.server.DB.Conn(ctx), func(tx pgx.Tx) error {
+		// Lock the row to read the status it had before this update, so only
+		// a transition into completed, or out of scheduled, is reported
+		var previousStatus todo.Status
+		err := tx.QueryRow(ctx, `
+			SELECT
+				status
+			FROM
+				todos
+			WHERE
+				id = @todo_id
+				AND workspace_id = @workspace_id
+			FOR UPDATE
+		`, args).Scan(&previousStatus)
+		if err != nil {
+			return fmt.Errorf("failed to lock row in table:todos: %w", err)
+		}
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, args)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute query: %w", err)
-	}
+		// The creation of a published todo has been announced, so it can't
+		// be scheduled again
+		if payload.PublishAt != nil && previousStatus != todo.StatusScheduled {
+			code := "TODO_NOT_SCHEDULED"
+			return errs.NewConflictError("only scheduled todos can be rescheduled", false, &code, nil, nil)
+		}
+
+		rows, err := tx.Query(ctx, stmt, args)
+		if err != nil {
+			return fmt.Errorf("failed to execute query: %w", err)
+		}
+
+		updatedTodo, err = pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.Todo])
+		if err != nil {
+			return fmt.Errorf("failed to collect row from table:todos: %w", err)
+		}
+
+		if previousStatus == todo.StatusScheduled && updatedTodo.Status != todo.StatusScheduled {
+			err := insertOutboxEvent(ctx, tx, workspaceID, string(webhook.EventTodoCreated), updatedTodo)
+			if err != nil {
+				return err
+			}
+		}
+
+		if updatedTodo.Status == todo.StatusCompleted && previousStatus != todo.StatusCompleted {
+			return insertOutboxEvent(ctx, tx, workspaceID, string(webhook.EventTodoCompleted), updatedTodo)
+		}
 
-	updatedTodo, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.Todo])
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to collect row from table:todos: %w", err)
+		return nil, err
 	}
 
 	return &updatedTodo, nil
 }
 
-func (r *TodoRepository) DeleteTodo(ctx context.Context, userID string, todoID uuid.UUID) error {
-	stmt := `
-		DELETE FROM todos
-		WHERE id=@todo_id AND user_id=@user_id
-	`
-
-	result, err := r.server.DB.Pool.Exec(ctx, stmt, pgx.NamedArgs{
-		"todo_id": todoID,
-		"user_id": userID,
+func (r *TodoRepository) DeleteTodo(ctx context.Context, workspaceID uuid.UUID, todoID uuid.UUID) error {
+	deleted, err := queries.New(r.server.DB.Conn(ctx)).DeleteTodo(ctx, queries.DeleteTodoParams{
+		ID:          todoID,
+		WorkspaceID: workspaceID,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to execute query: %w", err)
 	}
 
-	if result.RowsAffected() == 0 {
+	if deleted == 0 {
 		code := "TODO_NOT_FOUND"
 		return errs.NewNotFoundError("todo not found", false, &code)
 	}
@@ -432,7 +654,7 @@ func (r *TodoRepository) DeleteTodo(ctx context.Context, userID string, todoID u
 	return nil
 }
 
-func (r *TodoRepository) GetTodoStats(ctx context.Context, userID string) (*todo.TodoStats, error) {
+func (r *TodoRepository) GetTodoStats(ctx context.Context, workspaceID uuid.UUID) (*todo.TodoStats, error) {
 	stmt := `
 	SELECT
 		COUNT(*) AS total,
@@ -440,15 +662,16 @@ func (r *TodoRepository) GetTodoStats(ctx context.Context, userID string) (*todo
 		COUNT(*) FILTER (WHERE status='active') AS active,
 		COUNT(*) FILTER (WHERE status='completed') AS completed,
 		COUNT(*) FILTER (WHERE status='archived') AS archived,
+		COUNT(*) FILTER (WHERE status='scheduled') AS scheduled,
 		COUNT(*) FILTER (WHERE due_date < NOW() AND status != 'completed') AS overdue
 	FROM 
 		todos
 	WHERE 
-		user_id=@user_id
+		workspace_id=@workspace_id
 	`
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
-		"user_id": userID,
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"workspace_id": workspaceID,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute query: %w", err)
@@ -477,7 +700,7 @@ func (r *TodoRepository) GetTodoAttachment(
 			AND id = @attachment_id
 	`
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
 		"todo_id":       todoID,
 		"attachment_id": attachmentID,
 	})
@@ -509,10 +732,11 @@ func (r *TodoRepository) GetTodoAttachments(
 		WHERE
 			todo_id = @todo_id
 		ORDER BY
-			created_at DESC
+			created_at DESC,
+			id DESC
 	`
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
 		"todo_id": todoID,
 	})
 	if err != nil {
@@ -542,7 +766,7 @@ func (r *TodoRepository) DeleteTodoAttachment(
 			AND id = @attachment_id
 	`
 
-	result, err := r.server.DB.Pool.Exec(ctx, stmt, pgx.NamedArgs{
+	result, err := r.server.DB.Conn(ctx).Exec(ctx, stmt, pgx.NamedArgs{
 		"todo_id":       todoID,
 		"attachment_id": attachmentID,
 	})
@@ -566,6 +790,7 @@ func (r *TodoRepository) UploadTodoAttachment(
 	fileName string,
 	fileSize int64,
 	mimeType string,
+	checksum string,
 ) (*todo.TodoAttachment, error) {
 	stmt := `
 		INSERT INTO
@@ -575,7 +800,8 @@ func (r *TodoRepository) UploadTodoAttachment(
 				uploaded_by,
 				download_key,
 				file_size,
-				mime_type
+				mime_type,
+				checksum_sha256
 			)
 		VALUES
 			(
@@ -584,19 +810,21 @@ func (r *TodoRepository) UploadTodoAttachment(
 				@uploaded_by,
 				@download_key,
 				@file_size,
-				@mime_type
+				@mime_type,
+				@checksum_sha256
 			)
 		RETURNING
 			*
 	`
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
-		"todo_id":      todoID,
-		"name":         fileName,
-		"uploaded_by":  userID,
-		"download_key": s3Key,
-		"file_size":    fileSize,
-		"mime_type":    mimeType,
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"todo_id":         todoID,
+		"name":            fileName,
+		"uploaded_by":     userID,
+		"download_key":    s3Key,
+		"file_size":       fileSize,
+		"mime_type":       mimeType,
+		"checksum_sha256": checksum,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create todo attachment for todo_id=%s: %w", todoID.String(), err)
@@ -610,6 +838,67 @@ func (r *TodoRepository) UploadTodoAttachment(
 	return &attachment, nil
 }
 
+// CopyTodoAttachment records a copy of source, whose file was copied to
+// s3Key, as an attachment of todoID uploaded by userID
+func (r *TodoRepository) CopyTodoAttachment(
+	ctx context.Context,
+	todoID uuid.UUID,
+	userID string,
+	s3Key string,
+	source *todo.TodoAttachment,
+) (*todo.TodoAttachment, error) {
+	stmt := `
+		INSERT INTO
+			todo_attachments (
+				todo_id,
+				name,
+				uploaded_by,
+				download_key,
+				file_size,
+				mime_type,
+				checksum_sha256,
+				preview,
+				preview_extracted_at
+			)
+		VALUES
+			(
+				@todo_id,
+				@name,
+				@uploaded_by,
+				@download_key,
+				@file_size,
+				@mime_type,
+				@checksum_sha256,
+				@preview,
+				@preview_extracted_at
+			)
+		RETURNING
+			*
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"todo_id":              todoID,
+		"name":                 source.Name,
+		"uploaded_by":          userID,
+		"download_key":         s3Key,
+		"file_size":            source.FileSize,
+		"mime_type":            source.MimeType,
+		"checksum_sha256":      source.ChecksumSHA256,
+		"preview":              source.Preview,
+		"preview_extracted_at": source.PreviewExtractedAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy todo attachment %s to todo_id=%s: %w", source.ID.String(), todoID.String(), err)
+	}
+
+	attachment, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.TodoAttachment])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:todo_attachments: %w", err)
+	}
+
+	return &attachment, nil
+}
+
 // CRON REQUIREMENTS
 
 func (r *TodoRepository) GetTodosDueInHours(ctx context.Context, hours int, limit int) ([]todo.Todo, error) {
@@ -629,7 +918,7 @@ func (r *TodoRepository) GetTodosDueInHours(ctx context.Context, hours int, limi
 			@limit
 	`
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
 		"hours": hours,
 		"limit": limit,
 	})
@@ -648,212 +937,630 @@ func (r *TodoRepository) GetTodosDueInHours(ctx context.Context, hours int, limi
 	return todos, nil
 }
 
-func (r *TodoRepository) GetOverdueTodos(ctx context.Context, limit int) ([]todo.Todo, error) {
-	stmt := `
-		SELECT
-			*
-		FROM
-			todos
-		WHERE
-			due_date IS NOT NULL
-			AND due_date < NOW()
-			AND status NOT IN ('completed', 'archived')
-		ORDER BY
-			due_date ASC
-		LIMIT
-			@limit
-	`
-
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
-		"limit": limit,
-	})
+// GetTodosForExport returns every todo in the workspace in a stable order
+func (r *TodoRepository) GetTodosForExport(ctx context.Context, workspaceID uuid.UUID) ([]todo.Todo, error) {
+	rows, err := queries.New(r.server.DB.Conn(ctx)).GetTodosForExport(ctx, workspaceID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute get overdue todos query: %w", err)
+		return nil, fmt.Errorf("failed to execute get todos for export query for workspace_id=%s: %w", workspaceID.String(), err)
 	}
 
-	todos, err := pgx.CollectRows(rows, pgx.RowToStructByName[todo.Todo])
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return []todo.Todo{}, nil
-		}
-		return nil, fmt.Errorf("failed to collect rows from table:todos: %w", err)
+	todos := make([]todo.Todo, 0, len(rows))
+	for _, row := range rows {
+		todos = append(todos, todoFromRow(row))
 	}
 
 	return todos, nil
 }
 
-func (r *TodoRepository) GetCompletedTodosOlderThan(ctx context.Context, cutoffDate time.Time, limit int) ([]todo.Todo, error) {
+// GetAttachmentsForIntegrityCheck samples attachments to verify, starting
+// with those never checked and then those checked longest ago, so repeated
+// runs cover every attachment
+func (r *TodoRepository) GetAttachmentsForIntegrityCheck(ctx context.Context, limit int) ([]todo.TodoAttachment, error) {
 	stmt := `
 		SELECT
 			*
 		FROM
-			todos
-		WHERE
-			status = 'completed'
-			AND completed_at IS NOT NULL
-			AND completed_at < @cutoff_date
+			todo_attachments
 		ORDER BY
-			completed_at ASC
+			integrity_checked_at ASC NULLS FIRST,
+			random()
 		LIMIT
 			@limit
 	`
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
-		"cutoff_date": cutoffDate,
-		"limit":       limit,
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"limit": limit,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute get completed todos older than %s query: %w", cutoffDate.Format("2006-01-02"), err)
+		return nil, fmt.Errorf("failed to execute get attachments for integrity check query: %w", err)
 	}
 
-	todos, err := pgx.CollectRows(rows, pgx.RowToStructByName[todo.Todo])
+	attachments, err := pgx.CollectRows(rows, pgx.RowToStructByName[todo.TodoAttachment])
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return []todo.Todo{}, nil
+			return []todo.TodoAttachment{}, nil
 		}
-		return nil, fmt.Errorf("failed to collect rows from table:todos: %w", err)
+		return nil, fmt.Errorf("failed to collect rows from table:todo_attachments: %w", err)
 	}
 
-	return todos, nil
+	return attachments, nil
 }
 
-func (r *TodoRepository) ArchiveTodos(ctx context.Context, todoIDs []uuid.UUID) error {
+// RecordAttachmentIntegrity stores the outcome of a check. A checksum is
+// only recorded for attachments uploaded before checksums were kept.
+func (r *TodoRepository) RecordAttachmentIntegrity(ctx context.Context, attachmentID uuid.UUID,
+	status todo.IntegrityStatus, checksum *string, checkErr *string,
+) error {
 	stmt := `
-		UPDATE todos
+		UPDATE todo_attachments
 		SET
-			status = 'archived'
+			integrity_status = @integrity_status,
+			integrity_checked_at = NOW(),
+			integrity_error = @integrity_error,
+			checksum_sha256 = COALESCE(checksum_sha256, @checksum_sha256)
 		WHERE
-			id = ANY(@todo_ids::uuid[])
+			id = @id
 	`
 
-	result, err := r.server.DB.Pool.Exec(ctx, stmt, pgx.NamedArgs{
-		"todo_ids": todoIDs,
+	_, err := r.server.DB.Conn(ctx).Exec(ctx, stmt, pgx.NamedArgs{
+		"id":               attachmentID,
+		"integrity_status": status,
+		"integrity_error":  checkErr,
+		"checksum_sha256":  checksum,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to archive todos: %w", err)
+		return fmt.Errorf("failed to record integrity for attachment_id=%s in table:todo_attachments: %w", attachmentID.String(), err)
 	}
 
-	if result.RowsAffected() != int64(len(todoIDs)) {
-		return fmt.Errorf("expected to archive %d todos, but archived %d", len(todoIDs), result.RowsAffected())
-	}
+	return nil
+}
+
+// GetAttachment returns an attachment of any todo, for jobs working on
+// attachments outside a workspace
+func (r *TodoRepository) GetAttachment(ctx context.Context, attachmentID uuid.UUID) (*todo.TodoAttachment, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			todo_attachments
+		WHERE
+			id = @id
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"id": attachmentID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachment_id=%s: %w", attachmentID.String(), err)
+	}
+
+	attachment, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.TodoAttachment])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:todo_attachments for attachment_id=%s: %w", attachmentID.String(), err)
+	}
+
+	return &attachment, nil
+}
+
+// GetAttachmentsPendingPreview returns attachments uploaded before the
+// given time whose preview was never extracted, oldest first
+func (r *TodoRepository) GetAttachmentsPendingPreview(ctx context.Context, before time.Time, limit int,
+) ([]todo.TodoAttachment, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			todo_attachments
+		WHERE
+			preview_extracted_at IS NULL
+			AND created_at < @before
+		ORDER BY
+			created_at ASC
+		LIMIT
+			@limit
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"before": before,
+		"limit":  limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get attachments pending preview query: %w", err)
+	}
+
+	attachments, err := pgx.CollectRows(rows, pgx.RowToStructByName[todo.TodoAttachment])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return []todo.TodoAttachment{}, nil
+		}
+		return nil, fmt.Errorf("failed to collect rows from table:todo_attachments: %w", err)
+	}
+
+	return attachments, nil
+}
+
+// RecordAttachmentPreview stores the preview extracted from an attachment,
+// which is nil for files that have none
+func (r *TodoRepository) RecordAttachmentPreview(ctx context.Context, attachmentID uuid.UUID,
+	attachmentPreview *preview.Preview,
+) error {
+	stmt := `
+		UPDATE todo_attachments
+		SET
+			preview = @preview,
+			preview_extracted_at = NOW()
+		WHERE
+			id = @id
+	`
+
+	_, err := r.server.DB.Conn(ctx).Exec(ctx, stmt, pgx.NamedArgs{
+		"id":      attachmentID,
+		"preview": attachmentPreview,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record preview for attachment_id=%s in table:todo_attachments: %w", attachmentID.String(), err)
+	}
+
+	return nil
+}
+
+// GetAttachmentsPendingProcessing returns attachments uploaded before the
+// given time that were never processed, oldest first
+func (r *TodoRepository) GetAttachmentsPendingProcessing(ctx context.Context, before time.Time, limit int,
+) ([]todo.TodoAttachment, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			todo_attachments
+		WHERE
+			processing_status = 'pending'
+			AND created_at < @before
+		ORDER BY
+			created_at ASC
+		LIMIT
+			@limit
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"before": before,
+		"limit":  limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get attachments pending processing query: %w", err)
+	}
+
+	attachments, err := pgx.CollectRows(rows, pgx.RowToStructByName[todo.TodoAttachment])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return []todo.TodoAttachment{}, nil
+		}
+		return nil, fmt.Errorf("failed to collect rows from table:todo_attachments: %w", err)
+	}
+
+	return attachments, nil
+}
+
+// RecordAttachmentProcessing stores the outcome of processing an attachment
+func (r *TodoRepository) RecordAttachmentProcessing(ctx context.Context, attachmentID uuid.UUID,
+	status todo.ProcessingStatus, processingErr *string,
+) error {
+	stmt := `
+		UPDATE todo_attachments
+		SET
+			processing_status = @processing_status,
+			processing_error = @processing_error,
+			processed_at = NOW()
+		WHERE
+			id = @id
+	`
+
+	_, err := r.server.DB.Conn(ctx).Exec(ctx, stmt, pgx.NamedArgs{
+		"id":                attachmentID,
+		"processing_status": status,
+		"processing_error":  processingErr,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record processing for attachment_id=%s in table:todo_attachments: %w", attachmentID.String(), err)
+	}
+
+	return nil
+}
+
+// RecordAttachmentScan stores the verdict on an attachment found clean
+func (r *TodoRepository) RecordAttachmentScan(ctx context.Context, attachmentID uuid.UUID,
+	status todo.ScanStatus,
+) error {
+	stmt := `
+		UPDATE todo_attachments
+		SET
+			scan_status = @scan_status,
+			scan_signature = NULL,
+			scanned_at = NOW()
+		WHERE
+			id = @id
+	`
+
+	_, err := r.server.DB.Conn(ctx).Exec(ctx, stmt, pgx.NamedArgs{
+		"id":          attachmentID,
+		"scan_status": status,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record scan for attachment_id=%s in table:todo_attachments: %w", attachmentID.String(), err)
+	}
+
+	return nil
+}
+
+// QuarantineAttachment flags an infected attachment, whose file was moved
+// to quarantineKey, and rejects it so it can't be downloaded
+func (r *TodoRepository) QuarantineAttachment(ctx context.Context, attachmentID uuid.UUID, quarantineKey string,
+	signature string,
+) error {
+	stmt := `
+		UPDATE todo_attachments
+		SET
+			download_key = @download_key,
+			scan_status = 'infected',
+			scan_signature = @scan_signature,
+			scanned_at = NOW(),
+			processing_status = 'rejected',
+			processing_error = @processing_error,
+			processed_at = NOW()
+		WHERE
+			id = @id
+	`
+
+	_, err := r.server.DB.Conn(ctx).Exec(ctx, stmt, pgx.NamedArgs{
+		"id":               attachmentID,
+		"download_key":     quarantineKey,
+		"scan_signature":   signature,
+		"processing_error": "attachment is infected: " + signature,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to quarantine attachment_id=%s in table:todo_attachments: %w", attachmentID.String(), err)
+	}
 
 	return nil
 }
 
-func (r *TodoRepository) GetWeeklyStatsForUsers(ctx context.Context, startDate, endDate time.Time) ([]todo.UserWeeklyStats, error) {
+// SaveAttachmentArtifact records a file derived from an attachment,
+// replacing the one of the same kind. It returns the storage key of the
+// replaced file, if any, so it can be deleted.
+func (r *TodoRepository) SaveAttachmentArtifact(ctx context.Context, a *todo.AttachmentArtifact) (*string, error) {
+	stmt := `
+		WITH
+			previous AS (
+				SELECT
+					storage_key
+				FROM
+					attachment_artifacts
+				WHERE
+					attachment_id = @attachment_id
+					AND kind = @kind
+			),
+			saved AS (
+				INSERT INTO
+					attachment_artifacts (attachment_id, kind, storage_key, mime_type, size, width, height)
+				VALUES
+					(@attachment_id, @kind, @storage_key, @mime_type, @size, @width, @height)
+				ON CONFLICT (attachment_id, kind) DO UPDATE
+				SET
+					storage_key = EXCLUDED.storage_key,
+					mime_type = EXCLUDED.mime_type,
+					size = EXCLUDED.size,
+					width = EXCLUDED.width,
+					height = EXCLUDED.height
+			)
+		SELECT
+			storage_key
+		FROM
+			previous
+	`
+
+	var previousKey *string
+	err := r.server.DB.Conn(ctx).QueryRow(ctx, stmt, pgx.NamedArgs{
+		"attachment_id": a.AttachmentID,
+		"kind":          a.Kind,
+		"storage_key":   a.StorageKey,
+		"mime_type":     a.MimeType,
+		"size":          a.Size,
+		"width":         a.Width,
+		"height":        a.Height,
+	}).Scan(&previousKey)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to save %s artifact for attachment_id=%s: %w", a.Kind, a.AttachmentID.String(), err)
+	}
+
+	return previousKey, nil
+}
+
+// GetAttachmentArtifacts returns the files derived from an attachment
+func (r *TodoRepository) GetAttachmentArtifacts(ctx context.Context, attachmentID uuid.UUID,
+) ([]todo.AttachmentArtifact, error) {
 	stmt := `
 		SELECT
-			user_id,
-			COUNT(*) FILTER (WHERE created_at >= @start_date AND created_at <= @end_date) AS created_count,
-			COUNT(*) FILTER (WHERE status = 'completed' AND completed_at >= @start_date AND completed_at <= @end_date) AS completed_count,
-			COUNT(*) FILTER (WHERE status NOT IN ('completed', 'archived')) AS active_count,
-			COUNT(*) FILTER (WHERE due_date < NOW() AND status NOT IN ('completed', 'archived')) AS overdue_count
+			*
+		FROM
+			attachment_artifacts
+		WHERE
+			attachment_id = @attachment_id
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"attachment_id": attachmentID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get artifacts for attachment_id=%s: %w", attachmentID.String(), err)
+	}
+
+	artifacts, err := pgx.CollectRows(rows, pgx.RowToStructByName[todo.AttachmentArtifact])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return []todo.AttachmentArtifact{}, nil
+		}
+		return nil, fmt.Errorf("failed to collect rows from table:attachment_artifacts: %w", err)
+	}
+
+	return artifacts, nil
+}
+
+// GetAttachmentArtifact returns the attachment's file of the given kind
+func (r *TodoRepository) GetAttachmentArtifact(ctx context.Context, attachmentID uuid.UUID,
+	kind artifact.Kind,
+) (*todo.AttachmentArtifact, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			attachment_artifacts
+		WHERE
+			attachment_id = @attachment_id
+			AND kind = @kind
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"attachment_id": attachmentID,
+		"kind":          kind,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s artifact for attachment_id=%s: %w", kind, attachmentID.String(), err)
+	}
+
+	a, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[todo.AttachmentArtifact])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "ARTIFACT_NOT_FOUND"
+			return nil, errs.NewNotFoundError(fmt.Sprintf("attachment has no %s", kind), false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:attachment_artifacts: %w", err)
+	}
+
+	return &a, nil
+}
+
+func (r *TodoRepository) GetOverdueTodos(ctx context.Context, limit int) ([]todo.Todo, error) {
+	stmt := `
+		SELECT
+			*
 		FROM
 			todos
-		GROUP BY
-			user_id
-		HAVING
-			COUNT(*) > 0
+		WHERE
+			due_date IS NOT NULL
+			AND due_date < NOW()
+			AND status NOT IN ('completed', 'archived')
+		ORDER BY
+			due_date ASC
+		LIMIT
+			@limit
 	`
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
-		"start_date": startDate,
-		"end_date":   endDate,
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"limit": limit,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute get weekly stats query: %w", err)
+		return nil, fmt.Errorf("failed to execute get overdue todos query: %w", err)
 	}
 
-	stats, err := pgx.CollectRows(rows, pgx.RowToStructByName[todo.UserWeeklyStats])
+	todos, err := pgx.CollectRows(rows, pgx.RowToStructByName[todo.Todo])
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return []todo.UserWeeklyStats{}, nil
+			return []todo.Todo{}, nil
 		}
 		return nil, fmt.Errorf("failed to collect rows from table:todos: %w", err)
 	}
 
-	return stats, nil
+	return todos, nil
 }
 
-func (r *TodoRepository) GetCompletedTodosForUser(ctx context.Context, userID string,
-	startDate, endDate time.Time,
-) ([]todo.PopulatedTodo, error) {
+// GetOverdueTodosForReport returns the workspace's open todos past their due
+// date, the longest overdue first
+func (r *TodoRepository) GetOverdueTodosForReport(ctx context.Context, workspaceID uuid.UUID,
+	limit int,
+) ([]todo.Todo, error) {
 	stmt := `
 		SELECT
-			t.*,
-			CASE
-				WHEN c.id IS NOT NULL THEN to_jsonb(camel(c))
-				ELSE NULL
-			END AS category,
-			COALESCE(
-				jsonb_agg(
-					CASE
-						WHEN child.id IS NOT NULL THEN to_jsonb(camel(child))
-						ELSE NULL
-					END
-				) FILTER (
-					WHERE child.id IS NOT NULL
-				),
-				'[]'::JSONB
-			) AS children,
-			COALESCE(
-				jsonb_agg(
-					CASE
-						WHEN com.id IS NOT NULL THEN to_jsonb(camel(com))
-						ELSE NULL
-					END
-				) FILTER (
-					WHERE com.id IS NOT NULL
-				),
-				'[]'::JSONB
-			) AS comments,
-			 		 COALESCE(
-				jsonb_agg(
-					to_jsonb(camel (att))
-					ORDER BY
-						att.created_at DESC
-				) FILTER (
-					WHERE
-						att.id IS NOT NULL
-				),
-				'[]'::JSONB
-			) AS attachments
+			*
 		FROM
-			todos t
-			LEFT JOIN todo_categories c ON c.id = t.category_id AND c.user_id = @user_id
-			LEFT JOIN todos child ON child.parent_todo_id = t.id AND child.user_id = @user_id
-			LEFT JOIN todo_comments com ON com.todo_id = t.id AND com.user_id = @user_id
-			LEFT JOIN todo_attachments att ON att.todo_id=t.id
+			todos
 		WHERE
-			t.user_id = @user_id
-			AND t.status = 'completed'
-			AND t.completed_at >= @start_date
-			AND t.completed_at <= @end_date
-		GROUP BY
-			t.id, c.id
+			workspace_id = @workspace_id
+			AND due_date IS NOT NULL
+			AND due_date < NOW()
+			AND status NOT IN ('completed', 'archived')
 		ORDER BY
-			t.completed_at DESC
-		LIMIT 10
+			due_date ASC
+		LIMIT
+			@limit
 	`
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
-		"user_id":    userID,
-		"start_date": startDate,
-		"end_date":   endDate,
+	rows, err := r.server.DB.Reader(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+		"limit":        limit,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute get completed todos query for user %s: %w", userID, err)
+		return nil, fmt.Errorf("failed to execute get overdue todos query for workspace_id=%s: %w", workspaceID.String(), err)
 	}
 
-	completedTodos, err := pgx.CollectRows(rows, pgx.RowToStructByName[todo.PopulatedTodo])
+	todos, err := pgx.CollectRows(rows, pgx.RowToStructByName[todo.Todo])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:todos for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	return todos, nil
+}
+
+// GetCompletedTodosForReport returns the workspace's todos completed since
+// the time, the most recent first
+func (r *TodoRepository) GetCompletedTodosForReport(ctx context.Context, workspaceID uuid.UUID,
+	since time.Time, limit int,
+) ([]todo.Todo, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			todos
+		WHERE
+			workspace_id = @workspace_id
+			AND status = 'completed'
+			AND completed_at >= @since
+		ORDER BY
+			completed_at DESC
+		LIMIT
+			@limit
+	`
+
+	rows, err := r.server.DB.Reader(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+		"since":        since,
+		"limit":        limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get completed todos query for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	todos, err := pgx.CollectRows(rows, pgx.RowToStructByName[todo.Todo])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:todos for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	return todos, nil
+}
+
+func (r *TodoRepository) GetCompletedTodosOlderThan(ctx context.Context, cutoffDate time.Time, limit int) ([]todo.Todo, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			todos
+		WHERE
+			status = 'completed'
+			AND completed_at IS NOT NULL
+			AND completed_at < @cutoff_date
+		ORDER BY
+			completed_at ASC
+		LIMIT
+			@limit
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"cutoff_date": cutoffDate,
+		"limit":       limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get completed todos older than %s query: %w", cutoffDate.Format("2006-01-02"), err)
+	}
+
+	todos, err := pgx.CollectRows(rows, pgx.RowToStructByName[todo.Todo])
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return []todo.PopulatedTodo{}, nil
+			return []todo.Todo{}, nil
+		}
+		return nil, fmt.Errorf("failed to collect rows from table:todos: %w", err)
+	}
+
+	return todos, nil
+}
+
+// PublishDueTodos publishes up to limit scheduled todos whose publish time
+// has passed, announcing the creation of each. Todos locked by a concurrent
+// run are left to it.
+func (r *TodoRepository) PublishDueTodos(ctx context.Context, now time.Time, limit int) ([]todo.Todo, error) {
+	stmt := `
+		UPDATE todos
+		SET
+			status = 'active'
+		WHERE
+			id IN (
+				SELECT
+					id
+				FROM
+					todos
+				WHERE
+					status = 'scheduled'
+					AND publish_at <= @now
+				ORDER BY
+					publish_at ASC
+				LIMIT
+					@limit
+				FOR UPDATE
+					SKIP LOCKED
+			)
+		RETURNING
+			*
+	`
+
+	var published []todo.Todo
+	err := pgx.BeginFunc(ctx, r.server.DB.Conn(ctx), func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, stmt, pgx.NamedArgs{
+			"now":   now,
+			"limit": limit,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to publish scheduled todos: %w", err)
 		}
-		return nil, fmt.Errorf("failed to collect completed todos for user %s: %w", userID, err)
+
+		published, err = pgx.CollectRows(rows, pgx.RowToStructByName[todo.Todo])
+		if err != nil {
+			return fmt.Errorf("failed to collect rows from table:todos: %w", err)
+		}
+
+		for _, todoItem := range published {
+			if err := insertOutboxEvent(ctx, tx, todoItem.WorkspaceID, string(webhook.EventTodoCreated), todoItem); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return completedTodos, nil
+	return published, nil
+}
+
+func (r *TodoRepository) ArchiveTodos(ctx context.Context, todoIDs []uuid.UUID) error {
+	stmt := `
+		UPDATE todos
+		SET
+			status = 'archived'
+		WHERE
+			id = ANY(@todo_ids::uuid[])
+	`
+
+	result, err := r.server.DB.Conn(ctx).Exec(ctx, stmt, pgx.NamedArgs{
+		"todo_ids": todoIDs,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to archive todos: %w", err)
+	}
+
+	if result.RowsAffected() != int64(len(todoIDs)) {
+		return fmt.Errorf("expected to archive %d todos, but archived %d", len(todoIDs), result.RowsAffected())
+	}
+
+	return nil
 }
 
 func (r *TodoRepository) GetOverdueTodosForUser(ctx context.Context, userID string) ([]todo.PopulatedTodo, error) {
@@ -890,7 +1597,8 @@ func (r *TodoRepository) GetOverdueTodosForUser(ctx context.Context, userID stri
 				jsonb_agg(
 					to_jsonb(camel (att))
 					ORDER BY
-						att.created_at DESC
+						att.created_at DESC,
+						att.id DESC
 				) FILTER (
 					WHERE
 						att.id IS NOT NULL
@@ -899,9 +1607,9 @@ func (r *TodoRepository) GetOverdueTodosForUser(ctx context.Context, userID stri
 			) AS attachments
 		FROM
 			todos t
-			LEFT JOIN todo_categories c ON c.id = t.category_id AND c.user_id = @user_id
-			LEFT JOIN todos child ON child.parent_todo_id = t.id AND child.user_id = @user_id
-			LEFT JOIN todo_comments com ON com.todo_id = t.id AND com.user_id = @user_id
+			LEFT JOIN todo_categories c ON c.id = t.category_id AND c.workspace_id = t.workspace_id
+			LEFT JOIN todos child ON child.parent_todo_id = t.id AND child.workspace_id = t.workspace_id
+			LEFT JOIN todo_comments com ON com.todo_id = t.id AND com.workspace_id = t.workspace_id
 			LEFT JOIN todo_attachments att ON att.todo_id=t.id
 		WHERE
 			t.user_id = @user_id
@@ -910,11 +1618,12 @@ func (r *TodoRepository) GetOverdueTodosForUser(ctx context.Context, userID stri
 		GROUP BY
 			t.id, c.id
 		ORDER BY
-			t.due_date ASC
+			t.due_date ASC,
+			t.id ASC
 		LIMIT 10
 	`
 
-	rows, err := r.server.DB.Pool.Query(ctx, stmt, pgx.NamedArgs{
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
 		"user_id": userID,
 	})
 	if err != nil {
@@ -931,3 +1640,209 @@ func (r *TodoRepository) GetOverdueTodosForUser(ctx context.Context, userID stri
 
 	return overdueTodos, nil
 }
+
+// todoBlocked tells whether todo t waits on a todo that is neither completed
+// nor archived
+const todoBlocked = `
+	EXISTS (
+		SELECT
+			1
+		FROM
+			todo_dependencies d
+			JOIN todos b ON b.id = d.blocked_by_id
+		WHERE
+			d.todo_id = t.id
+			AND b.status NOT IN ('completed', 'archived')
+	) AS blocked
+`
+
+// AddDependency records that the todo is blocked by another todo of its
+// workspace. Adding it again changes nothing.
+func (r *TodoRepository) AddDependency(ctx context.Context, workspaceID uuid.UUID, todoID uuid.UUID,
+	blockedByID uuid.UUID,
+) error {
+	err := queries.New(r.server.DB.Conn(ctx)).AddDependency(ctx, queries.AddDependencyParams{
+		TodoID:      todoID,
+		BlockedByID: blockedByID,
+		WorkspaceID: workspaceID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add dependency for todo_id=%s blocked_by_id=%s: %w", todoID.String(), blockedByID.String(), err)
+	}
+
+	return nil
+}
+
+// RemoveDependency unblocks the todo from another todo, if it was blocked by
+// it
+func (r *TodoRepository) RemoveDependency(ctx context.Context, workspaceID uuid.UUID, todoID uuid.UUID,
+	blockedByID uuid.UUID,
+) error {
+	err := queries.New(r.server.DB.Conn(ctx)).RemoveDependency(ctx, queries.RemoveDependencyParams{
+		TodoID:      todoID,
+		BlockedByID: blockedByID,
+		WorkspaceID: workspaceID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove dependency for todo_id=%s blocked_by_id=%s: %w", todoID.String(), blockedByID.String(), err)
+	}
+
+	return nil
+}
+
+// GetDependencyEdges loads every dependency in the workspace
+func (r *TodoRepository) GetDependencyEdges(ctx context.Context, workspaceID uuid.UUID) ([]todo.Dependency, error) {
+	rows, err := r.server.DB.Reader(ctx).Query(ctx, `
+		SELECT
+			*
+		FROM
+			todo_dependencies
+		WHERE
+			workspace_id = @workspace_id
+	`, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get dependency edges query for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	edges, err := pgx.CollectRows(rows, pgx.RowToStructByName[todo.Dependency])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:todo_dependencies for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	return edges, nil
+}
+
+// GetDependencies lists the todos blocking the todo and the todos it blocks
+func (r *TodoRepository) GetDependencies(ctx context.Context, workspaceID uuid.UUID,
+	todoID uuid.UUID,
+) (*todo.Dependencies, error) {
+	stmt := `
+		SELECT
+			t.*,
+	` + todoBlocked + `
+		FROM
+			todo_dependencies dep
+			JOIN todos t ON t.id = dep.%s
+		WHERE
+			dep.%s = @todo_id
+			AND dep.workspace_id = @workspace_id
+		ORDER BY
+			dep.created_at ASC,
+			t.id ASC
+	`
+	args := pgx.NamedArgs{
+		"todo_id":      todoID,
+		"workspace_id": workspaceID,
+	}
+
+	rows, err := r.server.DB.Reader(ctx).Query(ctx, fmt.Sprintf(stmt, "blocked_by_id", "todo_id"), args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get blocking todos query for todo_id=%s workspace_id=%s: %w", todoID.String(), workspaceID.String(), err)
+	}
+
+	blockedBy, err := pgx.CollectRows(rows, pgx.RowToStructByName[todo.DependentTodo])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:todos for todo_id=%s workspace_id=%s: %w", todoID.String(), workspaceID.String(), err)
+	}
+
+	rows, err = r.server.DB.Reader(ctx).Query(ctx, fmt.Sprintf(stmt, "todo_id", "blocked_by_id"), args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get blocked todos query for todo_id=%s workspace_id=%s: %w", todoID.String(), workspaceID.String(), err)
+	}
+
+	blocks, err := pgx.CollectRows(rows, pgx.RowToStructByName[todo.DependentTodo])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:todos for todo_id=%s workspace_id=%s: %w", todoID.String(), workspaceID.String(), err)
+	}
+
+	dependencies := &todo.Dependencies{
+		BlockedBy: blockedBy,
+		Blocks:    blocks,
+	}
+	for i := range blockedBy {
+		if !blockedBy[i].IsDone() {
+			dependencies.Blocked = true
+		}
+	}
+
+	return dependencies, nil
+}
+
+// GetOpenTodos lists the workspace's todos that aren't done yet, most
+// urgent first: by priority, then due date, then age
+func (r *TodoRepository) GetOpenTodos(ctx context.Context, workspaceID uuid.UUID) ([]todo.DependentTodo, error) {
+	stmt := `
+		SELECT
+			t.*,
+	` + todoBlocked + `
+		FROM
+			todos t
+		WHERE
+			t.workspace_id = @workspace_id
+			AND t.status NOT IN ('completed', 'archived')
+		ORDER BY
+			COALESCE(t.priority_rank, todo_priority_rank(t.priority)) DESC,
+			t.due_date ASC NULLS LAST,
+			t.created_at ASC,
+			t.id ASC
+	`
+
+	rows, err := r.server.DB.Reader(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get open todos query for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	todos, err := pgx.CollectRows(rows, pgx.RowToStructByName[todo.DependentTodo])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:todos for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	return todos, nil
+}
+
+func todoFromRow(row queries.Todo) todo.Todo {
+	todoItem := todo.Todo{
+		WorkspaceID:     row.WorkspaceID,
+		UserID:          row.UserID,
+		Title:           row.Title,
+		Description:     row.Description,
+		Status:          row.Status,
+		Priority:        row.Priority,
+		DueDate:         row.DueDate,
+		CompletedAt:     row.CompletedAt,
+		ParentTodoID:    row.ParentTodoID,
+		CategoryID:      row.CategoryID,
+		Metadata:        row.Metadata,
+		SortOrder:       int(row.SortOrder),
+		PriorityRank:    row.PriorityRank,
+		SearchVector:    row.SearchVector,
+		ReminderMinutes: intPtr(row.ReminderMinutes),
+		PublishAt:       row.PublishAt,
+	}
+	todoItem.ID = row.ID
+	todoItem.CreatedAt = row.CreatedAt
+	todoItem.UpdatedAt = row.UpdatedAt
+	return todoItem
+}
+
+// int32Ptr and intPtr convert between the integers of the generated queries
+// and those of the models
+func int32Ptr(n *int) *int32 {
+	if n == nil {
+		return nil
+	}
+	v := int32(*n)
+	return &v
+}
+
+func intPtr(n *int32) *int {
+	if n == nil {
+		return nil
+	}
+	v := int(*n)
+	return &v
+}
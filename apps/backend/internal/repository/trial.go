@@ -0,0 +1,388 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/model/trial"
+	"github.com/mabhi256/tasker/internal/model/workspace"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// trialAuthorship are the columns naming the user who wrote a row of a
+// trial's workspace, which claiming a trial hands to the claiming user
+var trialAuthorship = []struct {
+	table  string
+	column string
+}{
+	{"todos", "user_id"},
+	{"todo_categories", "user_id"},
+	{"todo_comments", "user_id"},
+	{"export_schedules", "created_by"},
+	{"webhooks", "created_by"},
+	{"reports", "user_id"},
+	{"todo_snapshots", "created_by"},
+	{"event_outbox", "actor_id"},
+}
+
+type TrialRepository struct {
+	server *server.Server
+}
+
+func NewTrialRepository(server *server.Server) *TrialRepository {
+	return &TrialRepository{server: server}
+}
+
+// CreateTrial creates a trial acting as userID, with a personal workspace
+func (r *TrialRepository) CreateTrial(ctx context.Context, userID string, tokenHash string,
+	expiresAt time.Time,
+) (*trial.Trial, error) {
+	stmt := `
+		WITH
+			created_workspace AS (
+				INSERT INTO
+					workspaces (name, owner_id, is_personal)
+				VALUES
+					('Trial', @user_id, TRUE)
+				RETURNING
+					id
+			),
+			owner AS (
+				INSERT INTO
+					workspace_members (workspace_id, user_id, role)
+				SELECT
+					id, @user_id, 'owner'
+				FROM
+					created_workspace
+			)
+		INSERT INTO
+			trials (
+				user_id,
+				workspace_id,
+				token_hash,
+				expires_at
+			)
+		SELECT
+			@user_id,
+			id,
+			@token_hash,
+			@expires_at
+		FROM
+			created_workspace
+		RETURNING
+		*
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"user_id":    userID,
+		"token_hash": tokenHash,
+		"expires_at": expiresAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute create trial query for user_id=%s: %w", userID, err)
+	}
+
+	item, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[trial.Trial])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:trials for user_id=%s: %w", userID, err)
+	}
+
+	return &item, nil
+}
+
+// GetActiveTrial returns the trial with the token hash, unless it expired
+// or was claimed
+func (r *TrialRepository) GetActiveTrial(ctx context.Context, tokenHash string) (*trial.Trial, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			trials
+		WHERE
+			token_hash=@token_hash
+			AND claimed_at IS NULL
+			AND expires_at > CURRENT_TIMESTAMP
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"token_hash": tokenHash,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get trial query: %w", err)
+	}
+
+	item, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[trial.Trial])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			code := "TRIAL_NOT_FOUND"
+			return nil, errs.NewNotFoundError("Trial not found or expired", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:trials: %w", err)
+	}
+
+	return &item, nil
+}
+
+// ClaimTrial hands the trial's workspace and everything the trial wrote in
+// it to userID, and ends the trial. The workspace becomes the user's
+// personal workspace, unless they already have one and it becomes a team
+// workspace they own.
+func (r *TrialRepository) ClaimTrial(ctx context.Context, tokenHash string, userID string) (*workspace.Workspace, error) {
+	var claimed workspace.Workspace
+
+	err := pgx.BeginFunc(ctx, r.server.DB.Conn(ctx), func(tx pgx.Tx) error {
+		// Locked, so a trial is only ever claimed once
+		rows, err := tx.Query(ctx, `
+			SELECT
+				*
+			FROM
+				trials
+			WHERE
+				token_hash=@token_hash
+			FOR UPDATE
+		`, pgx.NamedArgs{"token_hash": tokenHash})
+		if err != nil {
+			return fmt.Errorf("failed to execute get trial query: %w", err)
+		}
+
+		item, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[trial.Trial])
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				code := "TRIAL_NOT_FOUND"
+				return errs.NewNotFoundError("Trial not found or expired", false, &code)
+			}
+			return fmt.Errorf("failed to collect row from table:trials: %w", err)
+		}
+
+		switch {
+		case item.ClaimedAt != nil:
+			code := "TRIAL_ALREADY_CLAIMED"
+			return errs.NewConflictError("Trial has already been claimed", false, &code, nil, nil)
+		case !item.ExpiresAt.After(time.Now()):
+			code := "TRIAL_NOT_FOUND"
+			return errs.NewNotFoundError("Trial not found or expired", false, &code)
+		}
+
+		args := pgx.NamedArgs{
+			"workspace_id":  item.WorkspaceID,
+			"trial_user_id": item.UserID,
+			"user_id":       userID,
+		}
+
+		var hasPersonal bool
+		err = tx.QueryRow(ctx, `
+			SELECT
+				EXISTS (
+					SELECT
+						1
+					FROM
+						workspaces
+					WHERE
+						owner_id=@user_id
+						AND is_personal
+				)
+		`, args).Scan(&hasPersonal)
+		if err != nil {
+			return fmt.Errorf("failed to check personal workspace for user_id=%s: %w", userID, err)
+		}
+
+		name := "Personal"
+		if hasPersonal {
+			name = "Trial"
+		}
+		args["name"] = name
+		args["is_personal"] = !hasPersonal
+
+		rows, err = tx.Query(ctx, `
+			UPDATE
+				workspaces
+			SET
+				owner_id=@user_id,
+				is_personal=@is_personal,
+				name=@name
+			WHERE
+				id=@workspace_id
+			RETURNING
+			*
+		`, args)
+		if err != nil {
+			return fmt.Errorf("failed to hand over workspace of trial_id=%s: %w", item.ID, err)
+		}
+
+		claimed, err = pgx.CollectOneRow(rows, pgx.RowToStructByName[workspace.Workspace])
+		if err != nil {
+			return fmt.Errorf("failed to collect row from table:workspaces for trial_id=%s: %w", item.ID, err)
+		}
+
+		// The user takes over the trial's ownership, whatever role the trial
+		// may have invited them with
+		_, err = tx.Exec(ctx, `
+			DELETE FROM workspace_members
+			WHERE
+				workspace_id=@workspace_id
+				AND user_id=@user_id
+		`, args)
+		if err != nil {
+			return fmt.Errorf("failed to remove existing membership for trial_id=%s: %w", item.ID, err)
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE
+				workspace_members
+			SET
+				user_id=@user_id
+			WHERE
+				workspace_id=@workspace_id
+				AND user_id=@trial_user_id
+		`, args)
+		if err != nil {
+			return fmt.Errorf("failed to hand over membership of trial_id=%s: %w", item.ID, err)
+		}
+
+		for _, authorship := range trialAuthorship {
+			_, err = tx.Exec(ctx, fmt.Sprintf(`
+				UPDATE
+					%[1]s
+				SET
+					%[2]s=@user_id
+				WHERE
+					workspace_id=@workspace_id
+					AND %[2]s=@trial_user_id
+			`, authorship.table, authorship.column), args)
+			if err != nil {
+				return fmt.Errorf("failed to hand over %s of trial_id=%s: %w", authorship.table, item.ID, err)
+			}
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE
+				todo_attachments
+			SET
+				uploaded_by=@user_id
+			WHERE
+				uploaded_by=@trial_user_id
+				AND todo_id IN (
+					SELECT
+						id
+					FROM
+						todos
+					WHERE
+						workspace_id=@workspace_id
+				)
+		`, args)
+		if err != nil {
+			return fmt.Errorf("failed to hand over todo_attachments of trial_id=%s: %w", item.ID, err)
+		}
+
+		// Mentions and reactions are keyed by user, so the trial's are
+		// dropped where the user already has the same one
+		for _, stmt := range []string{
+			`
+				DELETE FROM comment_mentions m
+				WHERE
+					m.workspace_id=@workspace_id
+					AND m.user_id=@trial_user_id
+					AND EXISTS (
+						SELECT
+							1
+						FROM
+							comment_mentions o
+						WHERE
+							o.comment_id=m.comment_id
+							AND o.user_id=@user_id
+					)
+			`,
+			`
+				UPDATE
+					comment_mentions
+				SET
+					user_id=@user_id
+				WHERE
+					workspace_id=@workspace_id
+					AND user_id=@trial_user_id
+			`,
+			`
+				DELETE FROM comment_reactions r
+				WHERE
+					r.workspace_id=@workspace_id
+					AND r.user_id=@trial_user_id
+					AND EXISTS (
+						SELECT
+							1
+						FROM
+							comment_reactions o
+						WHERE
+							o.comment_id=r.comment_id
+							AND o.emoji=r.emoji
+							AND o.user_id=@user_id
+					)
+			`,
+			`
+				UPDATE
+					comment_reactions
+				SET
+					user_id=@user_id
+				WHERE
+					workspace_id=@workspace_id
+					AND user_id=@trial_user_id
+			`,
+		} {
+			if _, err := tx.Exec(ctx, stmt, args); err != nil {
+				return fmt.Errorf("failed to hand over comment activity of trial_id=%s: %w", item.ID, err)
+			}
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE
+				trials
+			SET
+				claimed_by=@user_id,
+				claimed_at=CURRENT_TIMESTAMP
+			WHERE
+				id=@id
+		`, pgx.NamedArgs{"id": item.ID, "user_id": userID})
+		if err != nil {
+			return fmt.Errorf("failed to mark trial_id=%s claimed: %w", item.ID, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &claimed, nil
+}
+
+// DeleteExpiredTrials deletes up to limit unclaimed trials that expired,
+// with their workspaces, and returns how many it deleted
+func (r *TrialRepository) DeleteExpiredTrials(ctx context.Context, limit int) (int, error) {
+	stmt := `
+		DELETE FROM workspaces
+		WHERE
+			id IN (
+				SELECT
+					workspace_id
+				FROM
+					trials
+				WHERE
+					claimed_at IS NULL
+					AND expires_at <= CURRENT_TIMESTAMP
+				LIMIT
+					@limit
+			)
+	`
+
+	result, err := r.server.DB.Conn(ctx).Exec(ctx, stmt, pgx.NamedArgs{
+		"limit": limit,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute delete expired trials query: %w", err)
+	}
+
+	return int(result.RowsAffected()), nil
+}
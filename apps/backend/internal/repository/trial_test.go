@@ -0,0 +1,105 @@
+package repository_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/database/seed"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/repository"
+	testutil "github.com/mabhi256/tasker/internal/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClaimTrial hands a trial's workspace and data to the user claiming it,
+// as their personal workspace when they have none and a team one otherwise
+func TestClaimTrial(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping trial tests in short mode")
+	}
+
+	testDB, srv, cleanup := testutil.SetupTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	trialRepo := repository.NewTrialRepository(srv)
+
+	start := func(userID, tokenHash string) uuid.UUID {
+		item, err := trialRepo.CreateTrial(ctx, userID, tokenHash, time.Now().Add(time.Hour))
+		require.NoError(t, err)
+
+		active, err := trialRepo.GetActiveTrial(ctx, tokenHash)
+		require.NoError(t, err)
+		assert.Equal(t, item.ID, active.ID)
+
+		testutil.SeedFixtures(t, testDB, &seed.Todo{WorkspaceID: item.WorkspaceID, UserID: userID, Title: "Try it out"})
+		return item.WorkspaceID
+	}
+	owner := func(workspaceID uuid.UUID) (string, bool, int) {
+		var (
+			ownerID  string
+			personal bool
+			todos    int
+		)
+		require.NoError(t, testDB.Pool.QueryRow(ctx, `
+			SELECT w.owner_id, w.is_personal, (SELECT COUNT(*) FROM todos WHERE workspace_id = w.id AND user_id = w.owner_id)
+			FROM workspaces w
+			JOIN workspace_members m ON m.workspace_id = w.id AND m.user_id = w.owner_id AND m.role = 'owner'
+			WHERE w.id = $1
+		`, workspaceID).Scan(&ownerID, &personal, &todos))
+		return ownerID, personal, todos
+	}
+
+	// A user who just signed up gets the trial as their personal workspace
+	first := start("trial_1", "hash-1")
+	claimed, err := trialRepo.ClaimTrial(ctx, "hash-1", "user-1")
+	require.NoError(t, err)
+	assert.True(t, claimed.IsPersonal)
+	ownerID, personal, todos := owner(first)
+	assert.Equal(t, "user-1", ownerID)
+	assert.True(t, personal)
+	assert.Equal(t, 1, todos)
+
+	// The token stops working and the trial can't be claimed again
+	_, err = trialRepo.GetActiveTrial(ctx, "hash-1")
+	assertStatus(t, err, http.StatusNotFound)
+	_, err = trialRepo.ClaimTrial(ctx, "hash-1", "user-2")
+	assertStatus(t, err, http.StatusConflict)
+
+	// A user with a personal workspace gets the trial as a team workspace
+	second := start("trial_2", "hash-2")
+	claimed, err = trialRepo.ClaimTrial(ctx, "hash-2", "user-1")
+	require.NoError(t, err)
+	assert.False(t, claimed.IsPersonal)
+	ownerID, personal, todos = owner(second)
+	assert.Equal(t, "user-1", ownerID)
+	assert.False(t, personal)
+	assert.Equal(t, 1, todos)
+
+	// Expired trials are deleted with their workspace, claimed ones kept
+	start("trial_3", "hash-3")
+	_, err = testDB.Pool.Exec(ctx, `UPDATE trials SET expires_at = CURRENT_TIMESTAMP - INTERVAL '1 minute'`)
+	require.NoError(t, err)
+	_, err = trialRepo.ClaimTrial(ctx, "hash-3", "user-2")
+	assertStatus(t, err, http.StatusNotFound)
+
+	deleted, err := trialRepo.DeleteExpiredTrials(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	var workspaces int
+	require.NoError(t, testDB.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM workspaces`).Scan(&workspaces))
+	assert.Equal(t, 2, workspaces)
+}
+
+func assertStatus(t *testing.T, err error, status int) {
+	t.Helper()
+
+	var httpErr *errs.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, status, httpErr.Status)
+}
@@ -0,0 +1,256 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/mabhi256/tasker/internal/lib/usage"
+	"github.com/mabhi256/tasker/internal/model/admin"
+	"github.com/mabhi256/tasker/internal/model/quota"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+type UsageRepository struct {
+	server *server.Server
+}
+
+func NewUsageRepository(server *server.Server) *UsageRepository {
+	return &UsageRepository{server: server}
+}
+
+// AddUsage adds counters drained from a meter to the daily totals. Every
+// instance flushes its own counters, so they are added rather than replaced.
+func (r *UsageRepository) AddUsage(ctx context.Context, records []usage.Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	stmt := `
+		INSERT INTO
+			workspace_usage_daily (
+				day,
+				workspace_id,
+				db_time_ms,
+				job_executions,
+				emails_sent,
+				api_requests
+			)
+		VALUES
+			(
+				@day,
+				@workspace_id,
+				@db_time_ms,
+				@job_executions,
+				@emails_sent,
+				@api_requests
+			)
+		ON CONFLICT (day, workspace_id) DO UPDATE
+		SET
+			db_time_ms = workspace_usage_daily.db_time_ms + EXCLUDED.db_time_ms,
+			job_executions = workspace_usage_daily.job_executions + EXCLUDED.job_executions,
+			emails_sent = workspace_usage_daily.emails_sent + EXCLUDED.emails_sent,
+			api_requests = workspace_usage_daily.api_requests + EXCLUDED.api_requests
+	`
+
+	err := pgx.BeginFunc(ctx, r.server.DB.Conn(ctx), func(tx pgx.Tx) error {
+		for _, record := range records {
+			_, err := tx.Exec(ctx, stmt, pgx.NamedArgs{
+				"day":            record.Day,
+				"workspace_id":   record.WorkspaceID,
+				"db_time_ms":     record.DBTime.Milliseconds(),
+				"job_executions": record.JobExecutions,
+				"emails_sent":    record.EmailsSent,
+				"api_requests":   record.APIRequests,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to execute add usage query for workspace_id=%s: %w", record.WorkspaceID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RecordStorage snapshots the bytes of attachments each workspace stores as
+// its storage for day, replacing any earlier snapshot
+func (r *UsageRepository) RecordStorage(ctx context.Context, day time.Time) (int, error) {
+	stmt := `
+		INSERT INTO
+			workspace_usage_daily (day, workspace_id, storage_bytes)
+		SELECT
+			@day,
+			t.workspace_id,
+			COALESCE(SUM(a.file_size), 0)
+		FROM
+			todo_attachments a
+			JOIN todos t ON t.id = a.todo_id
+		GROUP BY
+			t.workspace_id
+		ON CONFLICT (day, workspace_id) DO UPDATE
+		SET
+			storage_bytes = EXCLUDED.storage_bytes
+	`
+
+	tag, err := r.server.DB.Conn(ctx).Exec(ctx, stmt, pgx.NamedArgs{
+		"day": day,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute record storage query for day=%s: %w", day.Format(time.DateOnly), err)
+	}
+
+	return int(tag.RowsAffected()), nil
+}
+
+// GetStorageUsage returns the attachment bytes userID stored across
+// workspaces and those stored in the workspace, with its owner
+func (r *UsageRepository) GetStorageUsage(ctx context.Context, workspaceID uuid.UUID,
+	userID string,
+) (*quota.StorageUsage, error) {
+	stmt := `
+		SELECT
+			COALESCE(u.bytes, 0)::BIGINT AS user_bytes,
+			COALESCE(u.attachments, 0)::INT AS user_attachments,
+			COALESCE(ws.bytes, 0)::BIGINT AS workspace_bytes,
+			COALESCE(ws.attachments, 0)::INT AS workspace_attachments,
+			w.owner_id
+		FROM
+			workspaces w
+			LEFT JOIN LATERAL (
+				SELECT
+					SUM(bytes) AS bytes,
+					SUM(attachments) AS attachments
+				FROM
+					storage_usage
+				WHERE
+					user_id=@user_id
+			) u ON TRUE
+			LEFT JOIN LATERAL (
+				SELECT
+					SUM(bytes) AS bytes,
+					SUM(attachments) AS attachments
+				FROM
+					storage_usage
+				WHERE
+					workspace_id=w.id
+			) ws ON TRUE
+		WHERE
+			w.id=@workspace_id
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+		"user_id":      userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get storage usage query for workspace_id=%s user_id=%s: %w", workspaceID.String(), userID, err)
+	}
+
+	storage, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[quota.StorageUsage])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:storage_usage for workspace_id=%s user_id=%s: %w", workspaceID.String(), userID, err)
+	}
+
+	return &storage, nil
+}
+
+// GetAPIUsage returns the requests made in the workspace today and over
+// the last 30 days, today included
+func (r *UsageRepository) GetAPIUsage(ctx context.Context, workspaceID uuid.UUID, today time.Time) (*quota.APIUsage, error) {
+	stmt := `
+		SELECT
+			COALESCE(SUM(api_requests) FILTER (
+				WHERE
+					day=@today
+			), 0)::BIGINT AS requests_today,
+			COALESCE(SUM(api_requests), 0)::BIGINT AS requests_last_30_days
+		FROM
+			workspace_usage_daily
+		WHERE
+			workspace_id=@workspace_id
+			AND day > @today::DATE - 30
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+		"today":        today,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get API usage query for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	apiUsage, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[quota.APIUsage])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:workspace_usage_daily for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	return &apiUsage, nil
+}
+
+// GetCostAttribution returns the totals for day and the limit workspaces
+// using the most of sort, a usage column
+func (r *UsageRepository) GetCostAttribution(ctx context.Context, day time.Time, sort string,
+	limit int,
+) (*admin.UsageTotals, []admin.WorkspaceUsage, error) {
+	args := pgx.NamedArgs{
+		"day":   day,
+		"limit": limit,
+	}
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, `
+		SELECT
+			COALESCE(SUM(db_time_ms), 0)::BIGINT AS db_time_ms,
+			COALESCE(SUM(job_executions), 0)::BIGINT AS job_executions,
+			COALESCE(SUM(emails_sent), 0)::BIGINT AS emails_sent,
+			COALESCE(SUM(storage_bytes), 0)::BIGINT AS storage_bytes
+		FROM
+			workspace_usage_daily
+		WHERE
+			day=@day
+	`, args)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to execute get usage totals query for day=%s: %w", day.Format(time.DateOnly), err)
+	}
+
+	totals, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[admin.UsageTotals])
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to collect row from table:workspace_usage_daily for day=%s: %w", day.Format(time.DateOnly), err)
+	}
+
+	// sort is validated against the usage columns, so it is safe to inline
+	rows, err = r.server.DB.Conn(ctx).Query(ctx, `
+		SELECT
+			u.workspace_id,
+			w.name AS workspace_name,
+			u.db_time_ms,
+			u.job_executions,
+			u.emails_sent,
+			u.storage_bytes
+		FROM
+			workspace_usage_daily u
+			LEFT JOIN workspaces w ON w.id = u.workspace_id
+		WHERE
+			u.day=@day
+		ORDER BY
+			u.`+sort+` DESC,
+			u.workspace_id ASC
+		LIMIT
+			@limit
+	`, args)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to execute get workspace usage query for day=%s: %w", day.Format(time.DateOnly), err)
+	}
+
+	workspaces, err := pgx.CollectRows(rows, pgx.RowToStructByName[admin.WorkspaceUsage])
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to collect rows from table:workspace_usage_daily for day=%s: %w", day.Format(time.DateOnly), err)
+	}
+
+	return &totals, workspaces, nil
+}
@@ -0,0 +1,83 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/model/todo"
+	"github.com/mabhi256/tasker/internal/repository"
+	testutil "github.com/mabhi256/tasker/internal/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStorageUsage follows attachments into and out of the storage usage
+// kept by trigger, including those deleted along with their todo
+func TestStorageUsage(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping storage usage tests in short mode")
+	}
+
+	testDB, srv, cleanup := testutil.SetupTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	ownerID := "user-1"
+	memberID := "user-2"
+
+	workspaceIDs := make([]uuid.UUID, 2)
+	for i := range workspaceIDs {
+		err := testDB.Pool.QueryRow(ctx, `
+			INSERT INTO workspaces (name, owner_id, is_personal)
+			VALUES ('Workspace', $1, FALSE)
+			RETURNING id
+		`, ownerID).Scan(&workspaceIDs[i])
+		require.NoError(t, err)
+	}
+
+	todoRepo := repository.NewTodoRepository(srv)
+	usageRepo := repository.NewUsageRepository(srv)
+
+	todoIDs := make([]uuid.UUID, 2)
+	for i, workspaceID := range workspaceIDs {
+		item, err := todoRepo.CreateTodo(ctx, workspaceID, ownerID, &todo.CreateTodoPayload{Title: "Todo"})
+		require.NoError(t, err)
+		todoIDs[i] = item.ID
+	}
+
+	upload := func(todoID uuid.UUID, userID string, size int64) *todo.TodoAttachment {
+		attachment, err := todoRepo.UploadTodoAttachment(ctx, todoID, userID, "todos/attachments/"+uuid.NewString(),
+			"file.txt", size, "text/plain", "")
+		require.NoError(t, err)
+		return attachment
+	}
+
+	first := upload(todoIDs[0], memberID, 100)
+	assert.Equal(t, workspaceIDs[0], first.WorkspaceID)
+	upload(todoIDs[0], ownerID, 20)
+	upload(todoIDs[1], memberID, 5)
+
+	storage, err := usageRepo.GetStorageUsage(ctx, workspaceIDs[0], memberID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(105), storage.UserBytes)
+	assert.Equal(t, 2, storage.UserAttachments)
+	assert.Equal(t, int64(120), storage.WorkspaceBytes)
+	assert.Equal(t, 2, storage.WorkspaceAttachments)
+	assert.Equal(t, ownerID, storage.OwnerID)
+
+	require.NoError(t, todoRepo.DeleteTodoAttachment(ctx, todoIDs[0], first.ID))
+
+	storage, err = usageRepo.GetStorageUsage(ctx, workspaceIDs[0], memberID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), storage.UserBytes)
+	assert.Equal(t, int64(20), storage.WorkspaceBytes)
+
+	require.NoError(t, todoRepo.DeleteTodo(ctx, workspaceIDs[1], todoIDs[1]))
+
+	storage, err = usageRepo.GetStorageUsage(ctx, workspaceIDs[1], memberID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), storage.UserBytes)
+	assert.Equal(t, 0, storage.UserAttachments)
+	assert.Equal(t, int64(0), storage.WorkspaceBytes)
+}
@@ -0,0 +1,500 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/mabhi256/tasker/internal/model"
+	"github.com/mabhi256/tasker/internal/model/webhook"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+type WebhookRepository struct {
+	server *server.Server
+}
+
+func NewWebhookRepository(server *server.Server) *WebhookRepository {
+	return &WebhookRepository{server: server}
+}
+
+func (r *WebhookRepository) CreateWebhook(ctx context.Context, workspaceID uuid.UUID, userID string,
+	payload *webhook.CreateWebhookPayload, secret string,
+) (*webhook.Webhook, error) {
+	enabled := true
+	if payload.Enabled != nil {
+		enabled = *payload.Enabled
+	}
+
+	stmt := `
+		INSERT INTO
+			webhooks (
+				workspace_id,
+				created_by,
+				url,
+				description,
+				events,
+				secret,
+				enabled
+			)
+		VALUES
+			(
+				@workspace_id,
+				@created_by,
+				@url,
+				@description,
+				@events,
+				@secret,
+				@enabled
+			)
+		RETURNING
+		*
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+		"created_by":   userID,
+		"url":          payload.URL,
+		"description":  payload.Description,
+		"events":       payload.Events,
+		"secret":       secret,
+		"enabled":      enabled,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute create webhook query for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	webhookItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[webhook.Webhook])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:webhooks for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	return &webhookItem, nil
+}
+
+func (r *WebhookRepository) GetWebhooks(ctx context.Context, workspaceID uuid.UUID) ([]webhook.Webhook, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			webhooks
+		WHERE
+			workspace_id=@workspace_id
+		ORDER BY
+			created_at ASC,
+			id ASC
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get webhooks query for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	webhooks, err := pgx.CollectRows(rows, pgx.RowToStructByName[webhook.Webhook])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return []webhook.Webhook{}, nil
+		}
+		return nil, fmt.Errorf("failed to collect rows from table:webhooks for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	return webhooks, nil
+}
+
+func (r *WebhookRepository) GetWebhookByID(ctx context.Context, workspaceID uuid.UUID, webhookID uuid.UUID) (*webhook.Webhook, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			webhooks
+		WHERE
+			id=@id
+			AND workspace_id=@workspace_id
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"id":           webhookID,
+		"workspace_id": workspaceID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get webhook by id query for webhook_id=%s workspace_id=%s: %w", webhookID.String(), workspaceID.String(), err)
+	}
+
+	webhookItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[webhook.Webhook])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:webhooks for webhook_id=%s workspace_id=%s: %w", webhookID.String(), workspaceID.String(), err)
+	}
+
+	return &webhookItem, nil
+}
+
+// GetSubscribedWebhooks returns the enabled webhooks of a workspace that
+// listen for the event type
+func (r *WebhookRepository) GetSubscribedWebhooks(ctx context.Context, workspaceID uuid.UUID,
+	eventType webhook.EventType,
+) ([]webhook.Webhook, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			webhooks
+		WHERE
+			workspace_id=@workspace_id
+			AND enabled
+			AND @event_type=ANY(events)
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+		"event_type":   string(eventType),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get subscribed webhooks query for workspace_id=%s event_type=%s: %w", workspaceID.String(), eventType, err)
+	}
+
+	webhooks, err := pgx.CollectRows(rows, pgx.RowToStructByName[webhook.Webhook])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return []webhook.Webhook{}, nil
+		}
+		return nil, fmt.Errorf("failed to collect rows from table:webhooks for workspace_id=%s event_type=%s: %w", workspaceID.String(), eventType, err)
+	}
+
+	return webhooks, nil
+}
+
+func (r *WebhookRepository) UpdateWebhook(ctx context.Context, workspaceID uuid.UUID,
+	payload *webhook.UpdateWebhookPayload,
+) (*webhook.Webhook, error) {
+	stmt := `UPDATE webhooks SET `
+	args := pgx.NamedArgs{
+		"id":           payload.ID,
+		"workspace_id": workspaceID,
+	}
+	setClauses := []string{}
+
+	if payload.URL != nil {
+		setClauses = append(setClauses, "url = @url")
+		args["url"] = *payload.URL
+	}
+	if payload.Description != nil {
+		setClauses = append(setClauses, "description = @description")
+		args["description"] = *payload.Description
+	}
+	if payload.Events != nil {
+		setClauses = append(setClauses, "events = @events")
+		args["events"] = payload.Events
+	}
+	if payload.Enabled != nil {
+		setClauses = append(setClauses, "enabled = @enabled")
+		args["enabled"] = *payload.Enabled
+	}
+
+	if len(setClauses) == 0 {
+		return nil, fmt.Errorf("no fields to update")
+	}
+
+	stmt += strings.Join(setClauses, ", ")
+	stmt += ` WHERE id = @id AND workspace_id = @workspace_id RETURNING *`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute update webhook query for webhook_id=%s workspace_id=%s: %w", payload.ID.String(), workspaceID.String(), err)
+	}
+
+	webhookItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[webhook.Webhook])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:webhooks for webhook_id=%s workspace_id=%s: %w", payload.ID.String(), workspaceID.String(), err)
+	}
+
+	return &webhookItem, nil
+}
+
+func (r *WebhookRepository) RotateSecret(ctx context.Context, workspaceID uuid.UUID, webhookID uuid.UUID,
+	secret string,
+) (*webhook.Webhook, error) {
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, `
+		UPDATE webhooks
+		SET
+			secret = @secret
+		WHERE
+			id = @id
+			AND workspace_id = @workspace_id
+		RETURNING
+		*
+	`, pgx.NamedArgs{
+		"id":           webhookID,
+		"workspace_id": workspaceID,
+		"secret":       secret,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute rotate webhook secret query for webhook_id=%s workspace_id=%s: %w", webhookID.String(), workspaceID.String(), err)
+	}
+
+	webhookItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[webhook.Webhook])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:webhooks for webhook_id=%s workspace_id=%s: %w", webhookID.String(), workspaceID.String(), err)
+	}
+
+	return &webhookItem, nil
+}
+
+func (r *WebhookRepository) DeleteWebhook(ctx context.Context, workspaceID uuid.UUID, webhookID uuid.UUID) error {
+	result, err := r.server.DB.Conn(ctx).Exec(ctx, `
+		DELETE FROM webhooks
+		WHERE id = @id AND workspace_id = @workspace_id
+	`, pgx.NamedArgs{
+		"id":           webhookID,
+		"workspace_id": workspaceID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("webhook not found")
+	}
+
+	return nil
+}
+
+// CreateDeliveries records the event for each webhook. An event that was
+// already recorded for a webhook keeps its delivery, which is returned as is,
+// so publishing is idempotent.
+func (r *WebhookRepository) CreateDeliveries(ctx context.Context, webhookIDs []uuid.UUID,
+	event *webhook.Event,
+) ([]webhook.Delivery, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal webhook event %s: %w", event.ID.String(), err)
+	}
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, `
+		INSERT INTO
+			webhook_deliveries (webhook_id, event_id, event_type, payload)
+		SELECT
+			webhook_id, @event_id, @event_type, @payload
+		FROM
+			UNNEST(@webhook_ids::UUID[]) AS webhook_id
+		ON CONFLICT (webhook_id, event_id) DO UPDATE
+		SET
+			event_type = EXCLUDED.event_type
+		RETURNING
+		*
+	`, pgx.NamedArgs{
+		"webhook_ids": webhookIDs,
+		"event_id":    event.ID,
+		"event_type":  string(event.Type),
+		"payload":     payload,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute create webhook deliveries query for event_id=%s: %w", event.ID.String(), err)
+	}
+
+	deliveries, err := pgx.CollectRows(rows, pgx.RowToStructByName[webhook.Delivery])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:webhook_deliveries for event_id=%s: %w", event.ID.String(), err)
+	}
+
+	return deliveries, nil
+}
+
+// GetDeliveryWithWebhook loads a delivery and its webhook for sending
+func (r *WebhookRepository) GetDeliveryWithWebhook(ctx context.Context, deliveryID uuid.UUID) (*webhook.Delivery, *webhook.Webhook, error) {
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, `
+		SELECT
+			*
+		FROM
+			webhook_deliveries
+		WHERE
+			id=@id
+	`, pgx.NamedArgs{
+		"id": deliveryID,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to execute get webhook delivery query for delivery_id=%s: %w", deliveryID.String(), err)
+	}
+
+	delivery, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[webhook.Delivery])
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to collect row from table:webhook_deliveries for delivery_id=%s: %w", deliveryID.String(), err)
+	}
+
+	rows, err = r.server.DB.Conn(ctx).Query(ctx, `
+		SELECT
+			*
+		FROM
+			webhooks
+		WHERE
+			id=@id
+	`, pgx.NamedArgs{
+		"id": delivery.WebhookID,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to execute get webhook query for webhook_id=%s: %w", delivery.WebhookID.String(), err)
+	}
+
+	webhookItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[webhook.Webhook])
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to collect row from table:webhooks for webhook_id=%s: %w", delivery.WebhookID.String(), err)
+	}
+
+	return &delivery, &webhookItem, nil
+}
+
+func (r *WebhookRepository) GetDeliveryByID(ctx context.Context, workspaceID uuid.UUID, webhookID uuid.UUID,
+	deliveryID uuid.UUID,
+) (*webhook.Delivery, error) {
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, `
+		SELECT
+			d.*
+		FROM
+			webhook_deliveries d
+			JOIN webhooks w ON w.id=d.webhook_id
+		WHERE
+			d.id=@id
+			AND d.webhook_id=@webhook_id
+			AND w.workspace_id=@workspace_id
+	`, pgx.NamedArgs{
+		"id":           deliveryID,
+		"webhook_id":   webhookID,
+		"workspace_id": workspaceID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get webhook delivery by id query for delivery_id=%s: %w", deliveryID.String(), err)
+	}
+
+	delivery, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[webhook.Delivery])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:webhook_deliveries for delivery_id=%s: %w", deliveryID.String(), err)
+	}
+
+	return &delivery, nil
+}
+
+// RecordAttempt stores the outcome of an attempt. A delivery stays pending
+// between retries and is only marked failed by the last one.
+func (r *WebhookRepository) RecordAttempt(ctx context.Context, deliveryID uuid.UUID, attempt *webhook.Attempt,
+	status webhook.DeliveryStatus,
+) error {
+	_, err := r.server.DB.Conn(ctx).Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET
+			status = @status,
+			attempts = attempts + 1,
+			response_status = @response_status,
+			response_body = @response_body,
+			error = @error,
+			duration_ms = @duration_ms,
+			last_attempt_at = NOW(),
+			delivered_at = CASE WHEN @status = 'succeeded' THEN NOW() ELSE delivered_at END
+		WHERE
+			id = @id
+	`, pgx.NamedArgs{
+		"id":              deliveryID,
+		"status":          string(status),
+		"response_status": attempt.ResponseStatus,
+		"response_body":   attempt.ResponseBody,
+		"error":           attempt.Error,
+		"duration_ms":     attempt.Duration.Milliseconds(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record attempt for webhook delivery_id=%s: %w", deliveryID.String(), err)
+	}
+
+	return nil
+}
+
+// ResetDelivery puts a delivery back to pending for a manual redelivery
+func (r *WebhookRepository) ResetDelivery(ctx context.Context, deliveryID uuid.UUID) (*webhook.Delivery, error) {
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, `
+		UPDATE webhook_deliveries
+		SET
+			status = 'pending'
+		WHERE
+			id = @id
+		RETURNING
+		*
+	`, pgx.NamedArgs{
+		"id": deliveryID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute reset webhook delivery query for delivery_id=%s: %w", deliveryID.String(), err)
+	}
+
+	delivery, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[webhook.Delivery])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:webhook_deliveries for delivery_id=%s: %w", deliveryID.String(), err)
+	}
+
+	return &delivery, nil
+}
+
+func (r *WebhookRepository) GetDeliveries(ctx context.Context, workspaceID uuid.UUID,
+	query *webhook.GetDeliveriesQuery,
+) (*model.PaginatedResponse[webhook.Delivery], error) {
+	condition := `
+		WHERE
+			d.webhook_id=@webhook_id
+			AND w.workspace_id=@workspace_id
+	`
+	args := pgx.NamedArgs{
+		"webhook_id":   query.ID,
+		"workspace_id": workspaceID,
+		"limit":        *query.Limit,
+		"offset":       (*query.Page - 1) * *query.Limit,
+	}
+
+	if query.Status != nil {
+		condition += ` AND d.status=@status`
+		args["status"] = *query.Status
+	}
+
+	from := `
+		FROM
+			webhook_deliveries d
+			JOIN webhooks w ON w.id=d.webhook_id
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, `SELECT d.*`+from+condition+`
+		ORDER BY
+			d.created_at DESC,
+			d.id DESC
+		LIMIT
+			@limit
+		OFFSET
+			@offset
+	`, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get webhook deliveries query for webhook_id=%s: %w", query.ID.String(), err)
+	}
+
+	deliveries, err := pgx.CollectRows(rows, pgx.RowToStructByName[webhook.Delivery])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			deliveries = []webhook.Delivery{}
+		} else {
+			return nil, fmt.Errorf("failed to collect rows from table:webhook_deliveries for webhook_id=%s: %w", query.ID.String(), err)
+		}
+	}
+
+	var total int
+	err = r.server.DB.Conn(ctx).QueryRow(ctx, `SELECT COUNT(*)`+from+condition, args).Scan(&total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get total count of webhook deliveries for webhook_id=%s: %w", query.ID.String(), err)
+	}
+
+	return &model.PaginatedResponse[webhook.Delivery]{
+		Data:       deliveries,
+		Page:       *query.Page,
+		Limit:      *query.Limit,
+		Total:      total,
+		TotalPages: (total + *query.Limit - 1) / *query.Limit,
+	}, nil
+}
@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mabhi256/tasker/internal/model/weeklyreport"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+type WeeklyReportRepository struct {
+	server *server.Server
+}
+
+func NewWeeklyReportRepository(server *server.Server) *WeeklyReportRepository {
+	return &WeeklyReportRepository{server: server}
+}
+
+// GetTimezones lists the timezones users set on their digest subscriptions
+func (r *WeeklyReportRepository) GetTimezones(ctx context.Context) ([]string, error) {
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, `
+		SELECT DISTINCT
+			timezone
+		FROM
+			digest_subscriptions
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get digest timezones query: %w", err)
+	}
+
+	timezones, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:digest_subscriptions: %w", err)
+	}
+
+	return timezones, nil
+}
+
+// GetRecipients lists the users with todos whose timezone is one of
+// timezones. Users without a digest subscription are in defaultTimezone,
+// and only scanned for when it is one of them.
+func (r *WeeklyReportRepository) GetRecipients(ctx context.Context, timezones []string,
+	defaultTimezone string,
+) ([]weeklyreport.Recipient, error) {
+	stmt := `
+		SELECT
+			d.user_id,
+			d.timezone
+		FROM
+			digest_subscriptions d
+		WHERE
+			d.timezone = ANY (@timezones)
+			AND EXISTS (
+				SELECT
+					1
+				FROM
+					todos t
+				WHERE
+					t.user_id = d.user_id
+			)
+		UNION ALL
+		SELECT
+			u.user_id,
+			@default_timezone::TEXT
+		FROM
+			(
+				SELECT DISTINCT
+					user_id
+				FROM
+					todos
+			) u
+		WHERE
+			@default_timezone = ANY (@timezones)
+			AND NOT EXISTS (
+				SELECT
+					1
+				FROM
+					digest_subscriptions d
+				WHERE
+					d.user_id = u.user_id
+			)
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"timezones":        timezones,
+		"default_timezone": defaultTimezone,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get weekly report recipients query: %w", err)
+	}
+
+	recipients, err := pgx.CollectRows(rows, pgx.RowToStructByName[weeklyreport.Recipient])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect weekly report recipients: %w", err)
+	}
+
+	return recipients, nil
+}
+
+// GetReport aggregates the user's todos across workspaces for the week from
+// weekStart to weekEnd, which are on the wall clock of timezone. Days are
+// counted in timezone, a name Postgres and time.LoadLocation both know.
+func (r *WeeklyReportRepository) GetReport(ctx context.Context, userID string,
+	weekStart, weekEnd time.Time, timezone string,
+) (*weeklyreport.Report, error) {
+	args := pgx.NamedArgs{
+		"user_id":      userID,
+		"week_start":   weekStart,
+		"week_end":     weekEnd,
+		"timezone":     timezone,
+		"streak_since": weekEnd.Add(-weeklyreport.StreakWindow),
+		"limit":        weeklyreport.MaxCategories,
+	}
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, `
+		SELECT
+			COUNT(*) FILTER (
+				WHERE
+					created_at >= @week_start
+					AND created_at < @week_end
+			) AS created_count,
+			COUNT(*) FILTER (
+				WHERE
+					status = 'completed'
+					AND completed_at >= @week_start
+					AND completed_at < @week_end
+			) AS completed_count,
+			COUNT(*) FILTER (
+				WHERE
+					status NOT IN ('completed', 'archived')
+			) AS active_count,
+			COUNT(*) FILTER (
+				WHERE
+					due_date < @week_end
+					AND status NOT IN ('completed', 'archived')
+			) AS overdue_count
+		FROM
+			todos
+		WHERE
+			user_id = @user_id
+	`, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get weekly counts query for user_id=%s: %w", userID, err)
+	}
+
+	counts, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[weeklyreport.Counts])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect weekly counts for user_id=%s: %w", userID, err)
+	}
+
+	rows, err = r.server.DB.Conn(ctx).Query(ctx, `
+		SELECT
+			c.name,
+			COALESCE(c.color, '#6b7280') AS color,
+			COUNT(*) AS completed_count
+		FROM
+			todos t
+			JOIN todo_categories c ON c.id = t.category_id
+			AND c.workspace_id = t.workspace_id
+		WHERE
+			t.user_id = @user_id
+			AND t.status = 'completed'
+			AND t.completed_at >= @week_start
+			AND t.completed_at < @week_end
+		GROUP BY
+			c.id
+		ORDER BY
+			completed_count DESC,
+			c.name ASC
+		LIMIT
+			@limit
+	`, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get weekly categories query for user_id=%s: %w", userID, err)
+	}
+
+	categories, err := pgx.CollectRows(rows, pgx.RowToStructByName[weeklyreport.Category])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect weekly categories for user_id=%s: %w", userID, err)
+	}
+
+	rows, err = r.server.DB.Conn(ctx).Query(ctx, `
+		SELECT DISTINCT
+			(completed_at AT TIME ZONE @timezone)::DATE AS day
+		FROM
+			todos
+		WHERE
+			user_id = @user_id
+			AND status = 'completed'
+			AND completed_at >= @streak_since
+			AND completed_at < @week_end
+		ORDER BY
+			day DESC
+	`, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get completion days query for user_id=%s: %w", userID, err)
+	}
+
+	days, err := pgx.CollectRows(rows, pgx.RowTo[time.Time])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect completion days for user_id=%s: %w", userID, err)
+	}
+
+	activeDays, streak := weeklyreport.Streaks(days, weekStart)
+
+	return &weeklyreport.Report{
+		Counts:        counts,
+		WeekStart:     weekStart,
+		WeekEnd:       weekEnd,
+		TopCategories: categories,
+		ActiveDays:    activeDays,
+		Streak:        streak,
+	}, nil
+}
@@ -0,0 +1,441 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/model/workspace"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+type WorkspaceRepository struct {
+	server *server.Server
+}
+
+func NewWorkspaceRepository(server *server.Server) *WorkspaceRepository {
+	return &WorkspaceRepository{server: server}
+}
+
+func (r *WorkspaceRepository) CreateWorkspace(ctx context.Context, ownerID string,
+	payload *workspace.CreateWorkspacePayload,
+) (*workspace.Workspace, error) {
+	var workspaceItem workspace.Workspace
+
+	err := pgx.BeginFunc(ctx, r.server.DB.Conn(ctx), func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			INSERT INTO
+				workspaces (
+					name,
+					description,
+					owner_id
+				)
+			VALUES
+				(
+					@name,
+					@description,
+					@owner_id
+				)
+			RETURNING
+			*
+		`, pgx.NamedArgs{
+			"name":        payload.Name,
+			"description": payload.Description,
+			"owner_id":    ownerID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to execute create workspace query for owner_id=%s name=%s: %w", ownerID, payload.Name, err)
+		}
+
+		workspaceItem, err = pgx.CollectOneRow(rows, pgx.RowToStructByName[workspace.Workspace])
+		if err != nil {
+			return fmt.Errorf("failed to collect row from table:workspaces for owner_id=%s name=%s: %w", ownerID, payload.Name, err)
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO
+				workspace_members (workspace_id, user_id, role)
+			VALUES
+				(@workspace_id, @user_id, @role)
+		`, pgx.NamedArgs{
+			"workspace_id": workspaceItem.ID,
+			"user_id":      ownerID,
+			"role":         workspace.RoleOwner,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add owner to workspace_id=%s: %w", workspaceItem.ID.String(), err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &workspaceItem, nil
+}
+
+// GetOrCreatePersonalWorkspace returns the user's membership in their personal
+// workspace, creating the workspace on first use
+func (r *WorkspaceRepository) GetOrCreatePersonalWorkspace(ctx context.Context, userID string) (*workspace.Member, error) {
+	var member workspace.Member
+
+	err := pgx.BeginFunc(ctx, r.server.DB.Conn(ctx), func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO
+				workspaces (name, owner_id, is_personal)
+			VALUES
+				('Personal', @user_id, TRUE)
+			ON CONFLICT (owner_id) WHERE is_personal DO NOTHING
+		`, pgx.NamedArgs{"user_id": userID})
+		if err != nil {
+			return fmt.Errorf("failed to create personal workspace for user_id=%s: %w", userID, err)
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO
+				workspace_members (workspace_id, user_id, role)
+			SELECT
+				id, owner_id, 'owner'
+			FROM
+				workspaces
+			WHERE
+				owner_id=@user_id
+				AND is_personal
+			ON CONFLICT (workspace_id, user_id) DO NOTHING
+		`, pgx.NamedArgs{"user_id": userID})
+		if err != nil {
+			return fmt.Errorf("failed to add owner to personal workspace for user_id=%s: %w", userID, err)
+		}
+
+		rows, err := tx.Query(ctx, `
+			SELECT
+				m.*
+			FROM
+				workspace_members m
+				JOIN workspaces w ON w.id=m.workspace_id
+			WHERE
+				w.owner_id=@user_id
+				AND w.is_personal
+				AND m.user_id=@user_id
+		`, pgx.NamedArgs{"user_id": userID})
+		if err != nil {
+			return fmt.Errorf("failed to get personal workspace for user_id=%s: %w", userID, err)
+		}
+
+		member, err = pgx.CollectOneRow(rows, pgx.RowToStructByName[workspace.Member])
+		if err != nil {
+			return fmt.Errorf("failed to collect row from table:workspace_members for user_id=%s: %w", userID, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &member, nil
+}
+
+func (r *WorkspaceRepository) GetMembership(ctx context.Context, workspaceID uuid.UUID, userID string) (*workspace.Member, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			workspace_members
+		WHERE
+			workspace_id=@workspace_id
+			AND user_id=@user_id
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+		"user_id":      userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get membership query for workspace_id=%s user_id=%s: %w", workspaceID.String(), userID, err)
+	}
+
+	member, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[workspace.Member])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Don't reveal whether the workspace exists to non-members
+			code := "WORKSPACE_NOT_FOUND"
+			return nil, errs.NewNotFoundError("workspace not found", false, &code)
+		}
+		return nil, fmt.Errorf("failed to collect row from table:workspace_members for workspace_id=%s user_id=%s: %w", workspaceID.String(), userID, err)
+	}
+
+	return &member, nil
+}
+
+func (r *WorkspaceRepository) GetWorkspacesForUser(ctx context.Context, userID string) ([]workspace.WorkspaceWithRole, error) {
+	stmt := `
+		SELECT
+			w.*,
+			m.role
+		FROM
+			workspaces w
+			JOIN workspace_members m ON m.workspace_id=w.id
+		WHERE
+			m.user_id=@user_id
+		ORDER BY
+			w.is_personal DESC,
+			w.name ASC,
+			w.id ASC
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"user_id": userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get workspaces query for user_id=%s: %w", userID, err)
+	}
+
+	workspaces, err := pgx.CollectRows(rows, pgx.RowToStructByName[workspace.WorkspaceWithRole])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return []workspace.WorkspaceWithRole{}, nil
+		}
+		return nil, fmt.Errorf("failed to collect rows from table:workspaces for user_id=%s: %w", userID, err)
+	}
+
+	return workspaces, nil
+}
+
+func (r *WorkspaceRepository) GetWorkspaceByID(ctx context.Context, workspaceID uuid.UUID) (*workspace.Workspace, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			workspaces
+		WHERE
+			id=@id
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"id": workspaceID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get workspace by id query for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	workspaceItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[workspace.Workspace])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:workspaces for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	return &workspaceItem, nil
+}
+
+func (r *WorkspaceRepository) UpdateWorkspace(ctx context.Context, payload *workspace.UpdateWorkspacePayload) (*workspace.Workspace, error) {
+	stmt := `UPDATE workspaces SET `
+	args := pgx.NamedArgs{
+		"id": payload.ID,
+	}
+	setClauses := []string{}
+
+	if payload.Name != nil {
+		setClauses = append(setClauses, "name = @name")
+		args["name"] = *payload.Name
+	}
+	if payload.Description != nil {
+		setClauses = append(setClauses, "description = @description")
+		args["description"] = *payload.Description
+	}
+	if payload.EmailTracking != nil {
+		setClauses = append(setClauses, "email_tracking = @email_tracking")
+		args["email_tracking"] = *payload.EmailTracking
+	}
+
+	if len(setClauses) == 0 {
+		return nil, errs.NewBadRequestError("no fields to update", false, nil, nil, nil)
+	}
+
+	stmt += strings.Join(setClauses, ", ")
+	stmt += ` WHERE id = @id RETURNING *`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute update workspace query for workspace_id=%s: %w", payload.ID.String(), err)
+	}
+
+	workspaceItem, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[workspace.Workspace])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:workspaces for workspace_id=%s: %w", payload.ID.String(), err)
+	}
+
+	return &workspaceItem, nil
+}
+
+func (r *WorkspaceRepository) DeleteWorkspace(ctx context.Context, workspaceID uuid.UUID) error {
+	result, err := r.server.DB.Conn(ctx).Exec(ctx, `
+		DELETE FROM workspaces
+		WHERE id = @id AND NOT is_personal
+	`, pgx.NamedArgs{
+		"id": workspaceID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete workspace: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		code := "WORKSPACE_NOT_FOUND"
+		return errs.NewNotFoundError("workspace not found", false, &code)
+	}
+
+	return nil
+}
+
+func (r *WorkspaceRepository) GetMembers(ctx context.Context, workspaceID uuid.UUID) ([]workspace.Member, error) {
+	stmt := `
+		SELECT
+			*
+		FROM
+			workspace_members
+		WHERE
+			workspace_id=@workspace_id
+		ORDER BY
+			created_at ASC,
+			user_id ASC
+	`
+
+	rows, err := r.server.DB.Reader(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get members query for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	members, err := pgx.CollectRows(rows, pgx.RowToStructByName[workspace.Member])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return []workspace.Member{}, nil
+		}
+		return nil, fmt.Errorf("failed to collect rows from table:workspace_members for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	return members, nil
+}
+
+// GetMemberIDs returns which of userIDs are members of the workspace
+func (r *WorkspaceRepository) GetMemberIDs(ctx context.Context, workspaceID uuid.UUID, userIDs []string) ([]string, error) {
+	stmt := `
+		SELECT
+			user_id
+		FROM
+			workspace_members
+		WHERE
+			workspace_id=@workspace_id
+			AND user_id = ANY(@user_ids)
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+		"user_ids":     userIDs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get member ids query for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	memberIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows from table:workspace_members for workspace_id=%s: %w", workspaceID.String(), err)
+	}
+
+	return memberIDs, nil
+}
+
+func (r *WorkspaceRepository) AddMember(ctx context.Context, workspaceID uuid.UUID, userID string,
+	role workspace.Role,
+) (*workspace.Member, error) {
+	stmt := `
+		INSERT INTO
+			workspace_members (
+				workspace_id,
+				user_id,
+				role
+			)
+		VALUES
+			(
+				@workspace_id,
+				@user_id,
+				@role
+			)
+		RETURNING
+		*
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+		"user_id":      userID,
+		"role":         role,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute add member query for workspace_id=%s user_id=%s: %w", workspaceID.String(), userID, err)
+	}
+
+	member, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[workspace.Member])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:workspace_members for workspace_id=%s user_id=%s: %w", workspaceID.String(), userID, err)
+	}
+
+	return &member, nil
+}
+
+func (r *WorkspaceRepository) UpdateMemberRole(ctx context.Context, workspaceID uuid.UUID, userID string,
+	role workspace.Role,
+) (*workspace.Member, error) {
+	stmt := `
+		UPDATE
+			workspace_members
+		SET
+			role=@role
+		WHERE
+			workspace_id=@workspace_id
+			AND user_id=@user_id
+			AND role != 'owner'
+		RETURNING
+		*
+	`
+
+	rows, err := r.server.DB.Conn(ctx).Query(ctx, stmt, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+		"user_id":      userID,
+		"role":         role,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute update member query for workspace_id=%s user_id=%s: %w", workspaceID.String(), userID, err)
+	}
+
+	member, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[workspace.Member])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect row from table:workspace_members for workspace_id=%s user_id=%s: %w", workspaceID.String(), userID, err)
+	}
+
+	return &member, nil
+}
+
+func (r *WorkspaceRepository) RemoveMember(ctx context.Context, workspaceID uuid.UUID, userID string) error {
+	result, err := r.server.DB.Conn(ctx).Exec(ctx, `
+		DELETE FROM workspace_members
+		WHERE workspace_id = @workspace_id AND user_id = @user_id AND role != 'owner'
+	`, pgx.NamedArgs{
+		"workspace_id": workspaceID,
+		"user_id":      userID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove workspace member: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		code := "MEMBER_NOT_FOUND"
+		return errs.NewNotFoundError("member not found", false, &code)
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+// Package reqctx stores values for a request on its echo context under
+// typed keys. A key fixes the type of its value, so a value can't be stored
+// under a mistyped name or read back as the wrong type.
+package reqctx
+
+import (
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/model/workspace"
+	"github.com/rs/zerolog"
+)
+
+// Key names a request value of type T
+type Key[T any] struct {
+	name string
+}
+
+// NewKey declares a key. Names must be unique; they are also used as log
+// field names.
+func NewKey[T any](name string) Key[T] {
+	return Key[T]{name: name}
+}
+
+func (k Key[T]) Name() string {
+	return k.name
+}
+
+// Set stores the value for the request
+func (k Key[T]) Set(c echo.Context, value T) {
+	c.Set(k.name, value)
+}
+
+// Get returns the value stored for the request, and whether there is one
+func (k Key[T]) Get(c echo.Context) (T, bool) {
+	value, ok := c.Get(k.name).(T)
+	return value, ok
+}
+
+// Value returns the value stored for the request, or the zero value
+func (k Key[T]) Value(c echo.Context) T {
+	value, _ := k.Get(c)
+	return value
+}
+
+var (
+	RequestID = NewKey[string]("request_id")
+	Logger    = NewKey[*zerolog.Logger]("logger")
+
+	// Set by authentication
+	UserID      = NewKey[string]("user_id")
+	UserRole    = NewKey[string]("user_role")
+	Permissions = NewKey[[]string]("permissions")
+	SessionID   = NewKey[string]("session_id")
+	// ImpersonatorID is the admin making the request on behalf of UserID
+	ImpersonatorID = NewKey[string]("impersonator_id")
+	// APIKeyID is the key the request was authenticated with instead of a
+	// session
+	APIKeyID = NewKey[string]("api_key_id")
+	// MachineClientID is the client of the machine token the request was
+	// authenticated with instead of as a user
+	MachineClientID = NewKey[string]("machine_client_id")
+
+	// Set by workspace resolution
+	WorkspaceID   = NewKey[uuid.UUID]("workspace_id")
+	WorkspaceRole = NewKey[workspace.Role]("workspace_role")
+
+	// BindingErrors are the fields of the request the binder couldn't
+	// convert, reported with the validation errors
+	BindingErrors = NewKey[[]errs.BindError]("binding_errors")
+)
@@ -0,0 +1,45 @@
+package reqctx_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/model/workspace"
+	"github.com/mabhi256/tasker/internal/reqctx"
+	"github.com/stretchr/testify/assert"
+)
+
+func newContext() echo.Context {
+	return echo.New().NewContext(httptest.NewRequest("GET", "/", nil), httptest.NewRecorder())
+}
+
+func TestKey(t *testing.T) {
+	c := newContext()
+
+	_, ok := reqctx.UserID.Get(c)
+	assert.False(t, ok)
+	assert.Equal(t, "", reqctx.UserID.Value(c))
+	assert.Equal(t, uuid.Nil, reqctx.WorkspaceID.Value(c))
+
+	workspaceID := uuid.New()
+	reqctx.UserID.Set(c, "user_1")
+	reqctx.WorkspaceID.Set(c, workspaceID)
+	reqctx.WorkspaceRole.Set(c, workspace.RoleOwner)
+
+	userID, ok := reqctx.UserID.Get(c)
+	assert.True(t, ok)
+	assert.Equal(t, "user_1", userID)
+	assert.Equal(t, workspaceID, reqctx.WorkspaceID.Value(c))
+	assert.Equal(t, workspace.RoleOwner, reqctx.WorkspaceRole.Value(c))
+}
+
+func TestKeyOfOtherType(t *testing.T) {
+	c := newContext()
+
+	// A value stored under the same name with another type isn't returned
+	c.Set(reqctx.WorkspaceRole.Name(), "owner")
+	_, ok := reqctx.WorkspaceRole.Get(c)
+	assert.False(t, ok)
+}
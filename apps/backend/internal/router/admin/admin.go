@@ -0,0 +1,95 @@
+package admin
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/handler"
+	"github.com/mabhi256/tasker/internal/middleware"
+)
+
+func RegisterAdminRoutes(router *echo.Group, handlers *handler.Handlers, middlewares *middleware.Middlewares) {
+	h := handlers.Admin
+	jobs := handlers.JobAdmin
+	rollouts := handlers.Rollout
+	backfills := handlers.Backfill
+	audits := handlers.Audit
+	sessions := handlers.Session
+
+	// Every admin route requires an authenticated admin. The route groups
+	// internal services and CI scripts call also accept machine tokens
+	// granted the group's scope.
+	auth := middlewares.Auth
+	requireAdmin := auth.RequireAdmin()
+
+	// System stats
+	router.GET("/stats", h.GetSystemStats, requireAdmin)
+
+	// Daily resource use by workspace, populated by the usage flusher and the
+	// cost-attribution cron job
+	router.GET("/usage", h.GetCostAttribution, auth.RequireAdmin(middleware.ScopeUsage))
+
+	// Read-only mode during incident recovery, which these routes are
+	// exempt from so it can be switched off
+	router.GET("/read-only", h.GetReadOnly, requireAdmin)
+	router.PUT("/read-only", h.SetReadOnly, requireAdmin)
+
+	// Recurring jobs run by the job server's scheduler
+	router.GET("/scheduled-jobs", h.GetScheduledJobs, auth.RequireAdmin(middleware.ScopeJobs))
+
+	// User operations
+	users := router.Group("/users", requireAdmin)
+	users.GET("", h.GetUsers)
+
+	dynamicUser := users.Group("/:userId")
+	dynamicUser.GET("/todos", h.GetUserTodos)
+	dynamicUser.POST("/impersonate", h.StartImpersonation)
+	dynamicUser.DELETE("/sessions", sessions.RevokeUserSessions)
+
+	// Attachment integrity, populated by the attachment-integrity cron job
+	integrity := router.Group("/attachments/integrity", requireAdmin)
+	integrity.GET("", h.GetAttachmentIntegritySummary)
+	integrity.GET("/issues", h.GetAttachmentIntegrityIssues)
+
+	// Background job queues
+	queues := router.Group("/jobs/queues", auth.RequireAdmin(middleware.ScopeJobs))
+	queues.GET("", jobs.GetQueues)
+
+	dynamicQueue := queues.Group("/:queue")
+	dynamicQueue.POST("/pause", jobs.PauseQueue)
+	dynamicQueue.POST("/resume", jobs.ResumeQueue)
+	dynamicQueue.GET("/tasks", jobs.GetQueueTasks)
+	dynamicQueue.POST("/tasks/:taskId/retry", jobs.RetryTask)
+	dynamicQueue.DELETE("/tasks/:taskId", jobs.DeleteTask)
+
+	// Backfills of expand/contract schema changes
+	rolloutGroup := router.Group("/rollouts", auth.RequireAdmin(middleware.ScopeRollouts))
+	rolloutGroup.GET("", rollouts.GetRollouts)
+
+	dynamicRollout := rolloutGroup.Group("/:name")
+	dynamicRollout.POST("/backfill", rollouts.StartBackfill)
+	dynamicRollout.POST("/verify", rollouts.VerifyRollout)
+
+	// Data migrations, run in the background from a checkpoint
+	backfillGroup := router.Group("/backfills", auth.RequireAdmin(middleware.ScopeBackfills))
+	backfillGroup.GET("", backfills.GetBackfills)
+
+	dynamicBackfill := backfillGroup.Group("/:name")
+	dynamicBackfill.POST("/start", backfills.StartBackfill)
+	dynamicBackfill.POST("/pause", backfills.PauseBackfill)
+	dynamicBackfill.POST("/resume", backfills.ResumeBackfill)
+	dynamicBackfill.POST("/abort", backfills.AbortBackfill)
+
+	// Global audit forwarders, which receive the audit events of every
+	// workspace and of this API
+	forwarders := router.Group("/audit-forwarders", requireAdmin)
+	forwarders.POST("", audits.CreateGlobalForwarder)
+	forwarders.GET("", audits.GetGlobalForwarders)
+
+	dynamicForwarder := forwarders.Group("/:id")
+	dynamicForwarder.GET("", audits.GetGlobalForwarderByID)
+	dynamicForwarder.PATCH("", audits.UpdateGlobalForwarder)
+	dynamicForwarder.DELETE("", audits.DeleteGlobalForwarder)
+
+	// Impersonation sessions
+	impersonations := router.Group("/impersonations", requireAdmin)
+	impersonations.DELETE("/:token", h.StopImpersonation)
+}
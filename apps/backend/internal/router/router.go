@@ -6,26 +6,31 @@ import (
 	"github.com/labstack/echo/v4"
 	echoMiddleware "github.com/labstack/echo/v4/middleware"
 	"github.com/mabhi256/tasker/internal/handler"
+	"github.com/mabhi256/tasker/internal/lib/jsonenc"
 	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/router/admin"
+	"github.com/mabhi256/tasker/internal/router/scim"
 	v1 "github.com/mabhi256/tasker/internal/router/v1"
 	"github.com/mabhi256/tasker/internal/server"
-	"github.com/mabhi256/tasker/internal/service"
 	"github.com/mabhi256/tasker/internal/validation"
-	"golang.org/x/time/rate"
 )
 
-func NewRouter(s *server.Server, h *handler.Handlers, services *service.Services) *echo.Echo {
-	middlewares := middleware.NewMiddlewares(s)
-
+func NewRouter(s *server.Server, h *handler.Handlers, middlewares *middleware.Middlewares) *echo.Echo {
 	router := echo.New()
+	// Client IPs are taken from X-Forwarded-For only as far as it was added
+	// by proxies on private networks, as IP allowlists rely on them
+	router.IPExtractor = echo.ExtractIPFromXFFHeader()
 	router.Binder = &validation.CustomBinder{}
+	// Encodes the hottest responses without reflection when built with the
+	// fastjson tag, and like echo's default serializer otherwise
+	router.JSONSerializer = jsonenc.Serializer{}
 
 	router.HTTPErrorHandler = middlewares.Global.GlobalErrorHandler
 
 	// global middlewares
 	router.Use(
 		echoMiddleware.RateLimiterWithConfig(echoMiddleware.RateLimiterConfig{
-			Store: echoMiddleware.NewRateLimiterMemoryStore(rate.Limit(20)),
+			Store: middlewares.RateLimit.Store(),
 			DenyHandler: func(c echo.Context, identifier string, err error) error {
 				// Record rate limit hit metrics
 				if rateLimitMiddleware := middlewares.RateLimit; rateLimitMiddleware != nil {
@@ -49,8 +54,14 @@ func NewRouter(s *server.Server, h *handler.Handlers, services *service.Services
 		middlewares.Tracing.NewRelicMiddleware(),
 		middlewares.Tracing.EnhanceTracing(),
 		middlewares.ContextEnhancer.EnhanceContext(),
-		middlewares.Global.RequestLogger(),
+		middlewares.Global.DebugBudget(),
+		middlewares.Global.AccessLog(),
+		middlewares.Global.RouteMetrics(),
 		middlewares.Global.Recover(),
+		middlewares.ClientVersion.RequireMinVersion(),
+		middlewares.ReadOnly.RejectWrites(),
+		middlewares.Global.ValidateView(),
+		middlewares.EarlyHints.SendEarlyHints(),
 	)
 
 	// register system routes
@@ -60,5 +71,13 @@ func NewRouter(s *server.Server, h *handler.Handlers, services *service.Services
 	v1Router := router.Group("/api/v1")
 	v1.RegisterV1Routes(v1Router, h, middlewares)
 
+	// register admin routes
+	adminRouter := router.Group("/admin/v1")
+	admin.RegisterAdminRoutes(adminRouter, h, middlewares)
+
+	// register SCIM routes for identity providers
+	scimRouter := router.Group("/scim/v2")
+	scim.RegisterSCIMRoutes(scimRouter, h, middlewares)
+
 	return router
 }
@@ -0,0 +1,40 @@
+package scim
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/handler"
+	"github.com/mabhi256/tasker/internal/middleware"
+)
+
+// RegisterSCIMRoutes registers the SCIM 2.0 API identity providers provision
+// workspaces with. Each request is authenticated by a workspace's SCIM token
+// and acts on that workspace.
+func RegisterSCIMRoutes(router *echo.Group, handlers *handler.Handlers, middlewares *middleware.Middlewares) {
+	h := handlers.Provisioning
+
+	router.Use(middlewares.SCIM.RequireToken)
+
+	router.GET("/ServiceProviderConfig", h.GetServiceProviderConfig)
+
+	// User operations
+	users := router.Group("/Users")
+	users.GET("", h.GetSCIMUsers)
+	users.POST("", h.CreateSCIMUser)
+
+	dynamicUser := users.Group("/:id")
+	dynamicUser.GET("", h.GetSCIMUser)
+	dynamicUser.PUT("", h.ReplaceSCIMUser)
+	dynamicUser.PATCH("", h.PatchSCIMUser)
+	dynamicUser.DELETE("", h.DeleteSCIMUser)
+
+	// Group operations
+	groups := router.Group("/Groups")
+	groups.GET("", h.GetSCIMGroups)
+	groups.POST("", h.CreateSCIMGroup)
+
+	dynamicGroup := groups.Group("/:id")
+	dynamicGroup.GET("", h.GetSCIMGroup)
+	dynamicGroup.PUT("", h.ReplaceSCIMGroup)
+	dynamicGroup.PATCH("", h.PatchSCIMGroup)
+	dynamicGroup.DELETE("", h.DeleteSCIMGroup)
+}
@@ -8,6 +8,11 @@ import (
 func registerSystemRoutes(r *echo.Echo, h *handler.Handlers) {
 	r.GET("/status", h.Health.CheckHealth)
 
+	// Probes for the orchestrator
+	r.GET("/livez", h.Health.Liveness)
+	r.GET("/readyz", h.Health.Readiness)
+	r.GET("/healthz", h.Health.Startup)
+
 	r.Static("/static", "static")
 
 	r.GET("/docs", h.OpenAPI.ServeOpenAPIUI)
@@ -0,0 +1,14 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/handler"
+	"github.com/mabhi256/tasker/internal/middleware"
+)
+
+func registerActivityRoutes(r *echo.Group, h *handler.ActivityHandler, auth *middleware.AuthMiddleware,
+	ws *middleware.WorkspaceMiddleware,
+) {
+	// The feed is open to every member of the workspace
+	r.GET("/feed", h.GetFeed, auth.RequireAuth, ws.ResolveWorkspace)
+}
@@ -0,0 +1,17 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/handler"
+	"github.com/mabhi256/tasker/internal/middleware"
+)
+
+func registerAPIKeyRoutes(r *echo.Group, h *handler.APIKeyHandler, auth *middleware.AuthMiddleware) {
+	// Keys are managed from a session, so a leaked key can't create more
+	keys := r.Group("/api-keys")
+	keys.Use(auth.RequireSession)
+
+	keys.GET("", h.GetKeys)
+	keys.POST("", h.CreateKey)
+	keys.DELETE("/:id", h.RevokeKey)
+}
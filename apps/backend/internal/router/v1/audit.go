@@ -0,0 +1,25 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/handler"
+	"github.com/mabhi256/tasker/internal/middleware"
+)
+
+func registerAuditRoutes(r *echo.Group, h *handler.AuditHandler, auth *middleware.AuthMiddleware,
+	ws *middleware.WorkspaceMiddleware,
+) {
+	// Audit forwarder operations, restricted to workspace admins by the service
+	forwarders := r.Group("/audit-forwarders")
+	forwarders.Use(auth.RequireAuth, ws.ResolveWorkspace)
+
+	// Audit forwarder collection operations
+	forwarders.POST("", h.CreateForwarder)
+	forwarders.GET("", h.GetForwarders)
+
+	// Individual audit forwarder operations
+	dynamicForwarder := forwarders.Group("/:id")
+	dynamicForwarder.GET("", h.GetForwarderByID)
+	dynamicForwarder.PATCH("", h.UpdateForwarder)
+	dynamicForwarder.DELETE("", h.DeleteForwarder)
+}
@@ -0,0 +1,13 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/handler"
+	"github.com/mabhi256/tasker/internal/middleware"
+)
+
+func registerCapabilityRoutes(r *echo.Group, h *handler.CapabilityHandler, auth *middleware.AuthMiddleware,
+	ws *middleware.WorkspaceMiddleware,
+) {
+	r.GET("/capabilities", h.GetCapabilities, auth.RequireAuth, ws.ResolveWorkspace)
+}
@@ -6,10 +6,12 @@ import (
 	"github.com/mabhi256/tasker/internal/middleware"
 )
 
-func registerCategoryRoutes(r *echo.Group, h *handler.CategoryHandler, auth *middleware.AuthMiddleware) {
+func registerCategoryRoutes(r *echo.Group, h *handler.CategoryHandler, auth *middleware.AuthMiddleware,
+	ws *middleware.WorkspaceMiddleware,
+) {
 	// Category operations
 	categories := r.Group("/categories")
-	categories.Use(auth.RequireAuth)
+	categories.Use(auth.RequireAuth, ws.ResolveWorkspace)
 
 	// Category collection operations
 	categories.POST("", h.CreateCategory)
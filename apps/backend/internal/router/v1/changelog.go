@@ -0,0 +1,11 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/handler"
+)
+
+func registerChangelogRoutes(r *echo.Group, h *handler.ChangelogHandler) {
+	// The changelog documents the API rather than any data, so it's public
+	r.GET("/changelog", h.GetChangelog)
+}
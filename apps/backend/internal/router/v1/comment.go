@@ -6,13 +6,25 @@ import (
 	"github.com/mabhi256/tasker/internal/middleware"
 )
 
-func registerCommentRoutes(r *echo.Group, h *handler.CommentHandler, auth *middleware.AuthMiddleware) {
+func registerCommentRoutes(r *echo.Group, h *handler.CommentHandler, auth *middleware.AuthMiddleware,
+	ws *middleware.WorkspaceMiddleware,
+) {
 	// Comment operations
 	comments := r.Group("/comments")
-	comments.Use(auth.RequireAuth)
+	comments.Use(auth.RequireAuth, ws.ResolveWorkspace)
+
+	// Comments mentioning the current user
+	comments.GET("/mentions", h.GetMentions)
 
 	// Individual comment operations
 	dynamicComment := comments.Group("/:id")
 	dynamicComment.PATCH("", h.UpdateComment)
 	dynamicComment.DELETE("", h.DeleteComment)
+
+	// The comment and its replies
+	dynamicComment.GET("/thread", h.GetThread)
+
+	// Reactions, one per user and emoji
+	dynamicComment.POST("/reactions", h.AddReaction)
+	dynamicComment.DELETE("/reactions", h.RemoveReaction)
 }
@@ -0,0 +1,16 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/handler"
+	"github.com/mabhi256/tasker/internal/middleware"
+)
+
+func registerDigestRoutes(r *echo.Group, h *handler.DigestHandler, auth *middleware.AuthMiddleware) {
+	// Digest settings belong to the user rather than a workspace
+	digest := r.Group("/digest")
+	digest.Use(auth.RequireAuth)
+
+	digest.GET("", h.GetSubscription)
+	digest.PUT("", h.PutSubscription)
+}
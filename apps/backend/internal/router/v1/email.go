@@ -0,0 +1,15 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/handler"
+)
+
+func registerEmailTrackingRoutes(r *echo.Group, h *handler.EmailTrackingHandler) {
+	// Tracking links are followed from mail clients, without signing in, and
+	// are signed instead
+	tracking := r.Group("/email")
+
+	tracking.GET("/open/:id", h.RecordOpen)
+	tracking.GET("/click/:id", h.RecordClick)
+}
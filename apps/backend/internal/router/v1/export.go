@@ -0,0 +1,29 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/handler"
+	"github.com/mabhi256/tasker/internal/middleware"
+)
+
+func registerExportRoutes(r *echo.Group, h *handler.ExportHandler, auth *middleware.AuthMiddleware,
+	ws *middleware.WorkspaceMiddleware,
+) {
+	// Export schedule operations, restricted to workspace admins by the service
+	exports := r.Group("/exports")
+	exports.Use(auth.RequireAuth, ws.ResolveWorkspace)
+
+	// Export schedule collection operations
+	exports.POST("", h.CreateSchedule)
+	exports.GET("", h.GetSchedules)
+
+	// Individual export schedule operations
+	dynamicExport := exports.Group("/:id")
+	dynamicExport.GET("", h.GetScheduleByID)
+	dynamicExport.PATCH("", h.UpdateSchedule)
+	dynamicExport.DELETE("", h.DeleteSchedule)
+
+	// Run history and manual runs
+	dynamicExport.GET("/runs", h.GetRuns)
+	dynamicExport.POST("/runs", h.TriggerRun)
+}
@@ -0,0 +1,24 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/handler"
+	"github.com/mabhi256/tasker/internal/middleware"
+)
+
+func registerIPAllowlistRoutes(r *echo.Group, h *handler.IPAllowlistHandler, auth *middleware.AuthMiddleware,
+	ws *middleware.WorkspaceMiddleware,
+) {
+	allowlist := r.Group("/ip-allowlist")
+
+	// IP allowlist operations, restricted to workspace admins by the service
+	allowlist.GET("", h.GetAllowlist, auth.RequireAuth, ws.ResolveWorkspace)
+	allowlist.PATCH("", h.UpdateAllowlist, auth.RequireAuth, ws.ResolveWorkspace)
+	allowlist.POST("/entries", h.CreateEntry, auth.RequireAuth, ws.ResolveWorkspace)
+	allowlist.DELETE("/entries/:id", h.DeleteEntry, auth.RequireAuth, ws.ResolveWorkspace)
+
+	// Break glass, restricted to the owner by the service, is reachable from
+	// any network so a wrong allowlist can't lock the owner out
+	allowlist.POST("/break-glass", h.StartBreakGlass, auth.RequireAuth, ws.ResolveWorkspaceWithoutAllowlist)
+	allowlist.DELETE("/break-glass", h.EndBreakGlass, auth.RequireAuth, ws.ResolveWorkspace)
+}
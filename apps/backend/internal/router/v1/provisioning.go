@@ -0,0 +1,37 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/handler"
+	"github.com/mabhi256/tasker/internal/middleware"
+)
+
+func registerProvisioningRoutes(r *echo.Group, h *handler.ProvisioningHandler, auth *middleware.AuthMiddleware,
+	ws *middleware.WorkspaceMiddleware,
+) {
+	// SCIM provisioning settings, restricted to workspace admins by the service.
+	// Identity providers use the token with the SCIM API at /scim/v2.
+	provisioning := r.Group("/provisioning")
+	provisioning.Use(auth.RequireAuth, ws.ResolveWorkspace)
+
+	// SCIM token operations
+	token := provisioning.Group("/token")
+	token.POST("", h.CreateToken)
+	token.GET("", h.GetToken)
+	token.DELETE("", h.DeleteToken)
+
+	// Mapping provisioned groups to workspace roles
+	groups := provisioning.Group("/groups")
+	groups.GET("", h.GetGroups)
+	groups.PATCH("/:id", h.UpdateGroupRole)
+}
+
+func registerInvitationRoutes(r *echo.Group, h *handler.ProvisioningHandler, auth *middleware.AuthMiddleware) {
+	// Invitations from workspaces that provisioned an existing account, which
+	// are accepted from a session so an API key can't join workspaces
+	invitations := r.Group("/invitations")
+	invitations.Use(auth.RequireSession)
+
+	invitations.GET("", h.GetInvitations)
+	invitations.POST("/:id/accept", h.AcceptInvitation)
+}
@@ -0,0 +1,20 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/handler"
+	"github.com/mabhi256/tasker/internal/middleware"
+)
+
+func registerPushRoutes(r *echo.Group, h *handler.PushHandler, auth *middleware.AuthMiddleware) {
+	// Devices belong to the user rather than a workspace
+	push := r.Group("/push")
+	push.Use(auth.RequireAuth)
+
+	push.GET("/config", h.GetPushConfig)
+
+	devices := push.Group("/devices")
+	devices.GET("", h.GetDevices)
+	devices.POST("", h.RegisterDevice)
+	devices.DELETE("/:id", h.DeleteDevice)
+}
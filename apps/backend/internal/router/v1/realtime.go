@@ -0,0 +1,21 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/handler"
+	"github.com/mabhi256/tasker/internal/middleware"
+)
+
+func registerRealtimeRoutes(r *echo.Group, h *handler.RealtimeHandler, auth *middleware.AuthMiddleware,
+	ws *middleware.WorkspaceMiddleware,
+) {
+	// Realtime operations
+	realtime := r.Group("/realtime")
+	realtime.Use(auth.RequireAuth, ws.ResolveWorkspace)
+
+	// Websocket connection to the workspace's events
+	realtime.GET("", h.Connect)
+
+	// Users connected to the workspace on any instance
+	realtime.GET("/presence", h.GetPresence)
+}
@@ -0,0 +1,21 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/handler"
+	"github.com/mabhi256/tasker/internal/middleware"
+)
+
+func registerReportRoutes(r *echo.Group, h *handler.ReportHandler, auth *middleware.AuthMiddleware,
+	ws *middleware.WorkspaceMiddleware,
+) {
+	// Reports are generated in the background, so the client polls for the
+	// download link
+	r.POST("/report", h.CreateReport, auth.RequireAuth, ws.ResolveWorkspace)
+	r.GET("/reports/:id", h.GetReport, auth.RequireAuth, ws.ResolveWorkspace)
+
+	// Admins review who has access. Large workspaces can't be listed inline,
+	// so POST queues the CSV as a report instead.
+	r.GET("/access-report", h.GetAccessReport, auth.RequireAuth, ws.ResolveWorkspace)
+	r.POST("/access-report", h.CreateAccessReport, auth.RequireAuth, ws.ResolveWorkspace)
+}
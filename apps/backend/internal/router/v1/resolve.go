@@ -0,0 +1,13 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/handler"
+	"github.com/mabhi256/tasker/internal/middleware"
+)
+
+func registerResolveRoutes(r *echo.Group, h *handler.ResolveHandler, auth *middleware.AuthMiddleware,
+	ws *middleware.WorkspaceMiddleware,
+) {
+	r.POST("/resolve", h.Resolve, auth.RequireAuth, ws.ResolveWorkspace)
+}
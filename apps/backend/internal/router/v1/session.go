@@ -0,0 +1,17 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/handler"
+	"github.com/mabhi256/tasker/internal/middleware"
+)
+
+func registerSessionRoutes(r *echo.Group, h *handler.SessionHandler, auth *middleware.AuthMiddleware) {
+	// Sessions belong to the user rather than a workspace, and are managed
+	// from a session rather than with an API key
+	sessions := r.Group("/sessions")
+	sessions.Use(auth.RequireSession)
+
+	sessions.GET("", h.GetSessions)
+	sessions.DELETE("/:id", h.RevokeSession)
+}
@@ -0,0 +1,24 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/handler"
+	"github.com/mabhi256/tasker/internal/middleware"
+)
+
+func registerSSORoutes(r *echo.Group, h *handler.SSOHandler, auth *middleware.AuthMiddleware,
+	ws *middleware.WorkspaceMiddleware,
+) {
+	sso := r.Group("/sso")
+
+	// Single sign-on settings, restricted to workspace admins by the service
+	sso.GET("", h.GetConfig, auth.RequireAuth, ws.ResolveWorkspace)
+	sso.PUT("", h.UpsertConfig, auth.RequireAuth, ws.ResolveWorkspace)
+	sso.DELETE("", h.DeleteConfig, auth.RequireAuth, ws.ResolveWorkspace)
+	sso.POST("/domains/verify", h.VerifyDomain, auth.RequireAuth, ws.ResolveWorkspace)
+
+	// Signing in with the identity provider, reachable before single
+	// sign-on lets the session through
+	sso.GET("/login", h.GetLogin, auth.RequireSession, ws.ResolveWorkspaceWithoutSSO)
+	sso.POST("/verify", h.Verify, auth.RequireSession, ws.ResolveWorkspaceWithoutSSO)
+}
@@ -0,0 +1,19 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/handler"
+	"github.com/mabhi256/tasker/internal/middleware"
+)
+
+func registerTagRoutes(r *echo.Group, h *handler.TagHandler, auth *middleware.AuthMiddleware,
+	ws *middleware.WorkspaceMiddleware,
+) {
+	// Tags are identified by their name, which is URL escaped in the path
+	tags := r.Group("/tags")
+	tags.Use(auth.RequireAuth, ws.ResolveWorkspace)
+
+	dynamicTag := tags.Group("/:id")
+	dynamicTag.POST("/rename", h.RenameTag)
+	dynamicTag.POST("/merge-into/:targetId", h.MergeTag)
+}
@@ -2,19 +2,29 @@ package v1
 
 import (
 	"github.com/labstack/echo/v4"
+	echoMiddleware "github.com/labstack/echo/v4/middleware"
 	"github.com/mabhi256/tasker/internal/handler"
 	"github.com/mabhi256/tasker/internal/middleware"
 )
 
-func registerTodoRoutes(r *echo.Group, h *handler.TodoHandler, ch *handler.CommentHandler, auth *middleware.AuthMiddleware) {
+// pasteBodyLimit fits the largest image that can be pasted once base64
+// encodes it, with room for the rest of the request
+const pasteBodyLimit = "14M"
+
+func registerTodoRoutes(r *echo.Group, h *handler.TodoHandler, ch *handler.CommentHandler, sh *handler.SearchHandler,
+	dh *handler.DependencyHandler, snh *handler.SnapshotHandler, cph *handler.CopyHandler,
+	auth *middleware.AuthMiddleware, ws *middleware.WorkspaceMiddleware,
+) {
 	// Todo operations
 	todos := r.Group("/todos")
-	todos.Use(auth.RequireAuth)
+	todos.Use(auth.RequireAuth, ws.ResolveWorkspace)
 
 	// Collection operations
 	todos.POST("", h.CreateTodo)
 	todos.GET("", h.GetTodos)
 	todos.GET("/stats", h.GetTodoStats)
+	todos.GET("/search", sh.SearchTodos)
+	todos.GET("/worklist", dh.GetWorkList)
 
 	// Individual todo operations
 	dynamicTodo := todos.Group("/:id")
@@ -22,14 +32,30 @@ func registerTodoRoutes(r *echo.Group, h *handler.TodoHandler, ch *handler.Comme
 	dynamicTodo.PATCH("", h.UpdateTodo)
 	dynamicTodo.DELETE("", h.DeleteTodo)
 
+	// Stores a frozen copy of the todo for audit and sharing
+	dynamicTodo.GET("/snapshot", snh.GetSnapshot)
+
+	// Copies the todo into another workspace the user belongs to
+	dynamicTodo.POST("/copy", cph.CopyTodo)
+
 	// Todo comments
 	todoComments := dynamicTodo.Group("/comments")
 	todoComments.POST("", ch.AddComment)
 	todoComments.GET("", ch.GetCommentsByTodoID)
 
+	// Todo dependencies
+	todoDependencies := dynamicTodo.Group("/dependencies")
+	todoDependencies.GET("", dh.GetDependencies)
+	todoDependencies.POST("", dh.AddDependency)
+	todoDependencies.DELETE("/:blockedById", dh.RemoveDependency)
+
 	// Todo attachments
 	todoAttachments := dynamicTodo.Group("/attachments")
 	todoAttachments.POST("", h.UploadTodoAttachment)
+	// Exchanges an image pasted into a description or comment for an
+	// attachment and the markdown that embeds it
+	todoAttachments.POST("/paste", h.PasteTodoImage, echoMiddleware.BodyLimit(pasteBodyLimit))
 	todoAttachments.DELETE("/:attachmentId", h.DeleteTodoAttachment)
 	todoAttachments.GET("/:attachmentId/download", h.GetAttachmentPresignedURL)
+	todoAttachments.GET("/:attachmentId/thumbnail", h.GetAttachmentThumbnail)
 }
@@ -0,0 +1,17 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/handler"
+	"github.com/mabhi256/tasker/internal/middleware"
+)
+
+func registerTrialRoutes(r *echo.Group, h *handler.TrialHandler, auth *middleware.AuthMiddleware) {
+	trials := r.Group("/trials")
+
+	// Trials start without signing in, limited like any request by the
+	// client's rate limit
+	trials.POST("", h.StartTrial)
+	// and are claimed from the session of the user who signed up
+	trials.POST("/claim", h.ClaimTrial, auth.RequireSession)
+}
@@ -7,12 +7,87 @@ import (
 )
 
 func RegisterV1Routes(router *echo.Group, handlers *handler.Handlers, middleware *middleware.Middlewares) {
-	// Register todo routes
-	registerTodoRoutes(router, handlers.Todo, handlers.Comment, middleware.Auth)
+	// Register workspace routes
+	registerWorkspaceRoutes(router, handlers.Workspace, middleware.Auth, middleware.Workspace, middleware.Transaction)
 
-	// Register category routes
-	registerCategoryRoutes(router, handlers.Category, middleware.Auth)
+	// Register event catalog routes
+	registerEventRoutes(router, handlers.Webhook)
 
-	// Register comment routes
-	registerCommentRoutes(router, handlers.Comment, middleware.Auth)
+	// Register changelog routes
+	registerChangelogRoutes(router, handlers.Changelog)
+
+	// Register digest routes
+	registerDigestRoutes(router, handlers.Digest, middleware.Auth)
+
+	// Register push notification routes
+	registerPushRoutes(router, handlers.Push, middleware.Auth)
+
+	// Register session routes
+	registerSessionRoutes(router, handlers.Session, middleware.Auth)
+
+	// Register API key routes
+	registerAPIKeyRoutes(router, handlers.APIKey, middleware.Auth)
+
+	// Register trial routes
+	registerTrialRoutes(router, handlers.Trial, middleware.Auth)
+
+	// Register invitation routes
+	registerInvitationRoutes(router, handlers.Provisioning, middleware.Auth)
+
+	// Register email tracking routes
+	registerEmailTrackingRoutes(router, handlers.EmailTracking)
+
+	// Workspace scoped resources are served at the top level, where the workspace
+	// comes from the X-Workspace-ID header or defaults to the personal workspace,
+	// and under /workspaces/:workspaceId
+	for _, r := range []*echo.Group{router, router.Group("/workspaces/:workspaceId")} {
+		// Register todo routes
+		registerTodoRoutes(r, handlers.Todo, handlers.Comment, handlers.Search, handlers.Dependency,
+			handlers.Snapshot, handlers.Copy, middleware.Auth, middleware.Workspace)
+
+		// Register category routes
+		registerCategoryRoutes(r, handlers.Category, middleware.Auth, middleware.Workspace)
+
+		// Register tag routes
+		registerTagRoutes(r, handlers.Tag, middleware.Auth, middleware.Workspace)
+
+		// Register comment routes
+		registerCommentRoutes(r, handlers.Comment, middleware.Auth, middleware.Workspace)
+
+		// Register export routes
+		registerExportRoutes(r, handlers.Export, middleware.Auth, middleware.Workspace)
+
+		// Register webhook routes
+		registerWebhookRoutes(r, handlers.Webhook, middleware.Auth, middleware.Workspace)
+
+		// Register audit forwarder routes
+		registerAuditRoutes(r, handlers.Audit, middleware.Auth, middleware.Workspace)
+
+		// Register provisioning routes
+		registerProvisioningRoutes(r, handlers.Provisioning, middleware.Auth, middleware.Workspace)
+
+		// Register single sign-on routes
+		registerSSORoutes(r, handlers.SSO, middleware.Auth, middleware.Workspace)
+
+		// Register IP allowlist routes
+		registerIPAllowlistRoutes(r, handlers.IPAllowlist, middleware.Auth, middleware.Workspace)
+
+		// Register reference resolution routes
+		registerResolveRoutes(r, handlers.Resolve, middleware.Auth, middleware.Workspace)
+
+		// Register capability routes
+		registerCapabilityRoutes(r, handlers.Capability, middleware.Auth, middleware.Workspace)
+
+		// Register storage and API usage routes
+		registerQuotaRoutes(r, handlers.Quota, middleware.Auth, middleware.Workspace)
+
+		// Register report routes
+		registerReportRoutes(r, handlers.Report, middleware.Auth, middleware.Workspace)
+
+		// Register realtime routes
+		registerRealtimeRoutes(r, handlers.Realtime, middleware.Auth, middleware.Workspace)
+
+		// Register activity feed routes
+		registerActivityRoutes(r, handlers.Activity, middleware.Auth, middleware.Workspace)
+	}
 }
@@ -0,0 +1,36 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/handler"
+	"github.com/mabhi256/tasker/internal/middleware"
+)
+
+func registerWebhookRoutes(r *echo.Group, h *handler.WebhookHandler, auth *middleware.AuthMiddleware,
+	ws *middleware.WorkspaceMiddleware,
+) {
+	// Webhook operations, restricted to workspace admins by the service
+	webhooks := r.Group("/webhooks")
+	webhooks.Use(auth.RequireAuth, ws.ResolveWorkspace)
+
+	// Webhook collection operations
+	webhooks.POST("", h.CreateWebhook)
+	webhooks.GET("", h.GetWebhooks)
+
+	// Individual webhook operations
+	dynamicWebhook := webhooks.Group("/:id")
+	dynamicWebhook.GET("", h.GetWebhookByID)
+	dynamicWebhook.PATCH("", h.UpdateWebhook)
+	dynamicWebhook.DELETE("", h.DeleteWebhook)
+	dynamicWebhook.POST("/rotate-secret", h.RotateSecret)
+
+	// Delivery log
+	dynamicWebhook.GET("/deliveries", h.GetDeliveries)
+	dynamicWebhook.POST("/deliveries/:deliveryId/redeliver", h.Redeliver)
+}
+
+func registerEventRoutes(r *echo.Group, h *handler.WebhookHandler) {
+	// The event catalog documents the API rather than any data, so it's public
+	events := r.Group("/events")
+	events.GET("/catalog", h.GetEventCatalog)
+}
@@ -0,0 +1,34 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/handler"
+	"github.com/mabhi256/tasker/internal/middleware"
+)
+
+func registerWorkspaceRoutes(r *echo.Group, h *handler.WorkspaceHandler, auth *middleware.AuthMiddleware,
+	ws *middleware.WorkspaceMiddleware, tx *middleware.TransactionMiddleware,
+) {
+	// Workspace operations
+	workspaces := r.Group("/workspaces")
+	workspaces.Use(auth.RequireAuth)
+
+	// Collection operations
+	workspaces.POST("", h.CreateWorkspace)
+	workspaces.GET("", h.GetWorkspaces)
+
+	// Individual workspace operations, only reachable by members
+	dynamicWorkspace := workspaces.Group("/:workspaceId")
+	dynamicWorkspace.GET("", h.GetWorkspaceByID, ws.ResolveWorkspace)
+	dynamicWorkspace.PATCH("", h.UpdateWorkspace, ws.ResolveWorkspace)
+	dynamicWorkspace.DELETE("", h.DeleteWorkspace, ws.ResolveWorkspace)
+
+	// Workspace members. Changes and their audit events are written together.
+	// Members can leave without signing in with the workspace's identity
+	// provider.
+	members := dynamicWorkspace.Group("/members")
+	members.GET("", h.GetMembers, ws.ResolveWorkspace)
+	members.POST("", h.AddMember, ws.ResolveWorkspace, tx.Transactional)
+	members.PATCH("/:userId", h.UpdateMember, ws.ResolveWorkspace, tx.Transactional)
+	members.DELETE("/:userId", h.RemoveMember, ws.ResolveWorkspaceToLeave, tx.Transactional)
+}
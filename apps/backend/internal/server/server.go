@@ -8,28 +8,68 @@ import (
 
 	"github.com/mabhi256/tasker/internal/config"
 	"github.com/mabhi256/tasker/internal/database"
+	"github.com/mabhi256/tasker/internal/health"
+	"github.com/mabhi256/tasker/internal/httpclient"
+	"github.com/mabhi256/tasker/internal/lib/alert"
+	"github.com/mabhi256/tasker/internal/lib/authn"
+	"github.com/mabhi256/tasker/internal/lib/cache"
+	"github.com/mabhi256/tasker/internal/lib/email"
+	"github.com/mabhi256/tasker/internal/lib/fieldstats"
 	"github.com/mabhi256/tasker/internal/lib/job"
+	"github.com/mabhi256/tasker/internal/lib/metrics"
+	"github.com/mabhi256/tasker/internal/lib/push"
+	"github.com/mabhi256/tasker/internal/lib/readonly"
+	"github.com/mabhi256/tasker/internal/lib/realtime"
+	"github.com/mabhi256/tasker/internal/lib/refcache"
+	"github.com/mabhi256/tasker/internal/lib/reqdebug"
+	"github.com/mabhi256/tasker/internal/lib/seal"
+	"github.com/mabhi256/tasker/internal/lib/search"
+	"github.com/mabhi256/tasker/internal/lib/shutdown"
+	"github.com/mabhi256/tasker/internal/lib/usage"
 	"github.com/mabhi256/tasker/internal/logging"
 	"github.com/newrelic/go-agent/v3/integrations/nrredis-v9"
+	"github.com/newrelic/go-agent/v3/newrelic"
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog"
 )
 
 type Server struct {
 	Config        *config.Config
+	Runtime       *config.Runtime
 	Logger        *zerolog.Logger
 	LoggerService *logging.LoggerService
 	DB            *database.Database
 	Redis         *redis.Client
 	httpServer    *http.Server
 	Job           *job.JobService
+	Email         *email.Client
+	HTTPClient    *httpclient.Client
+	Fetcher       *httpclient.Fetcher
+	Alerts        *alert.Notifier
+	Cache         *cache.Cache
+	RefCache      *refcache.Cache
+	Realtime      *realtime.Hub
+	Metrics       *metrics.Recorder
+	Usage         *usage.Meter
+	FieldStats    *fieldstats.Collector
+	Push          *push.Client
+	Search        *search.Client
+	Authenticator authn.Authenticator
+	// Machine is nil when machine tokens aren't configured
+	Machine *authn.Machine
+	// Health checks the dependencies above. Checks of dependencies built
+	// later, like storage, are registered by whoever builds them.
+	Health *health.Monitor
+	// ReadOnly rejects writes while it is engaged
+	ReadOnly *readonly.Switch
+	// Sealer encrypts the secrets customers save, such as export
+	// credentials. It is nil without a key, and then refuses to.
+	Sealer *seal.Sealer
 }
 
 func New(cfg *config.Config, logger *zerolog.Logger, loggerService *logging.LoggerService) (*Server, error) {
-	db, err := database.New(cfg, logger, loggerService)
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize database: %w", err)
-	}
+	// Resource use is charged to workspaces for cost attribution
+	meter := usage.NewMeter()
 
 	redisClient := redis.NewClient(&redis.Options{
 		Addr: cfg.Redis.Address,
@@ -40,6 +80,20 @@ func New(cfg *config.Config, logger *zerolog.Logger, loggerService *logging.Logg
 		redisClient.AddHook(nrredis.NewHook(redisClient.Options()))
 	}
 
+	// Writes the database rejects for being read-only switch the API to
+	// read-only mode, until the database accepts them again
+	var db *database.Database
+	readOnly := readonly.New(cfg.ReadOnly, redisClient, logger, func(ctx context.Context) (bool, error) {
+		return db.ReadOnly(ctx)
+	})
+
+	// Queries are also counted against the budget of debugged requests
+	db, err := database.New(cfg, logger, loggerService, meter.QueryTracer(), reqdebug.QueryTracer(),
+		readOnly.QueryTracer())
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+
 	// Test Redis connection
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -50,21 +104,84 @@ func New(cfg *config.Config, logger *zerolog.Logger, loggerService *logging.Logg
 		// Don't fail startup if Redis is unavailable
 	}
 
-	// Job service
-	jobService := job.NewJobService(cfg, logger)
-	jobService.InitHandlers(cfg, logger)
-	err = jobService.Start()
+	// Job service, whose client is always available. Only worker processes
+	// start it to process jobs.
+	var nrApp *newrelic.Application
+	if loggerService != nil {
+		nrApp = loggerService.GetApplication()
+	}
+
+	jobService, err := job.NewJobService(cfg, logger, nrApp, meter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize job service: %w", err)
+	}
+
+	// Templates are checked here so a broken one stops startup
+	emailClient, err := email.NewClient(cfg, logger, meter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize email client: %w", err)
+	}
+	jobService.InitHandlers(emailClient)
+
+	httpClient := httpclient.New(cfg.HTTPClient, logger)
+	fetcher := httpclient.NewFetcher(cfg.Fetcher, cfg.HTTPClient, logger)
+
+	// Web Push endpoints come from browsers, so they go through the fetcher
+	pushClient, err := push.NewClient(cfg.Push, httpClient, fetcher)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize push client: %w", err)
+	}
+
+	// The identity provider is the operator's, so it is reached like other
+	// trusted services rather than through the fetcher
+	authenticator, err := authn.New(&cfg.Auth, httpClient)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to initialize authenticator: %w", err)
 	}
 
+	// Searches fall back to Postgres rather than wait, so the engine gets a
+	// client of its own that doesn't retry and opens its breaker sooner
+	searchHTTPClient := httpclient.New(&config.HTTPClientConfig{
+		Timeout:          cfg.Search.Timeout,
+		BreakerThreshold: cfg.Search.BreakerThreshold,
+		BreakerCooldown:  cfg.Search.BreakerCooldown,
+	}, logger)
+
+	sealer, err := seal.NewOptional(cfg.Exports.EncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize sealer: %w", err)
+	}
+
+	monitor := health.New(cfg.Observability.HealthCheck, logger, loggerService)
+	monitor.Register(health.CheckDatabase, health.Database(db.Pool))
+	monitor.Register(health.CheckRedis, health.Redis(redisClient))
+	monitor.Register(health.CheckQueue, health.Queue(jobService.Inspector))
+
 	server := &Server{
 		Config:        cfg,
+		Runtime:       config.NewRuntime(cfg),
 		Logger:        logger,
 		LoggerService: loggerService,
 		DB:            db,
 		Redis:         redisClient,
 		Job:           jobService,
+		Email:         emailClient,
+		HTTPClient:    httpClient,
+		Fetcher:       fetcher,
+		Alerts:        alert.NewNotifier(cfg.Alerts, httpClient, logger),
+		Cache:         cache.New(redisClient, logger, loggerService),
+		RefCache:      refcache.New(cfg.RefCache, redisClient, logger, loggerService),
+		Realtime:      realtime.NewHub(cfg.Realtime, redisClient, logger, loggerService),
+		Metrics:       metrics.New(nrApp),
+		Usage:         meter,
+		FieldStats:    fieldstats.NewCollector(cfg.ValidationReport.MaxFailures),
+		Push:          pushClient,
+		Search:        search.NewClient(cfg.Search, searchHTTPClient),
+		Authenticator: authenticator,
+		Machine:       authn.NewMachine(cfg.Auth.Machine, httpClient),
+		Health:        monitor,
+		ReadOnly:      readOnly,
+		Sealer:        sealer,
 	}
 	// Runtime metrics are automatically collected by New Relic Go agent
 
@@ -94,20 +211,68 @@ func (s *Server) Start() error {
 	return s.httpServer.ListenAndServe()
 }
 
-func (s *Server) Shutdown(ctx context.Context) error {
-	err := s.httpServer.Shutdown(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to shutdown HTTP server: %w", err)
+// Shutdown stops the server in dependency order, each stage bounded by its
+// configured timeout:
+//
+//  1. http stops accepting requests, waits for the in-flight ones and closes
+//     the websockets
+//  2. jobs stops taking tasks and waits for the active ones
+//  3. the flush stages passed in, which store what requests and tasks left
+//     behind, such as outbox events, while Redis and the database are open
+//  4. close stops the background checks and closes the job client, Redis
+//     and the database
+func (s *Server) Shutdown(ctx context.Context, flush ...shutdown.Stage) error {
+	cfg := s.Config.Shutdown
+	if cfg == nil {
+		cfg = config.DefaultShutdownConfig()
 	}
 
-	s.DB.Close()
+	stages := []shutdown.Stage{
+		{Name: "http", Timeout: cfg.HTTP, Run: s.drainHTTP},
+		{Name: "jobs", Timeout: cfg.Jobs, Run: shutdown.Stop(s.stopJobs)},
+	}
+	stages = append(stages, flush...)
+	stages = append(stages, shutdown.Stage{Name: "close", Timeout: cfg.Close, Run: shutdown.Stop(s.close)})
+
+	return shutdown.Run(ctx, s.Logger, stages...)
+}
+
+func (s *Server) drainHTTP(ctx context.Context) error {
+	// Worker processes never set up the HTTP server
+	if s.httpServer != nil {
+		if err := s.httpServer.Shutdown(ctx); err != nil {
+			return fmt.Errorf("failed to shutdown HTTP server: %w", err)
+		}
+	}
+
+	// Websockets were hijacked, so the HTTP server doesn't wait for them
+	if s.Realtime != nil {
+		s.Realtime.Stop()
+	}
+	return nil
+}
+
+func (s *Server) stopJobs() {
 	if s.Job != nil {
 		s.Job.Stop()
 	}
+}
 
+func (s *Server) close() {
+	if s.Health != nil {
+		s.Health.Stop()
+	}
+	if s.ReadOnly != nil {
+		s.ReadOnly.Stop()
+	}
+	if s.RefCache != nil {
+		s.RefCache.Stop()
+	}
+	if s.Job != nil {
+		s.Job.Close()
+	}
 	if s.Redis != nil {
 		s.Redis.Close()
 	}
-
-	return nil
+	s.DB.Close()
 }
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/authz"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/lib/exporter"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/accessreport"
+	"github.com/mabhi256/tasker/internal/model/report"
+)
+
+// identityBatchSize caps the users named per request to the auth provider
+const identityBatchSize = 100
+
+// GetAccessReport lists everyone with access to the workspace. Workspaces
+// with more than accessreport.MaxInlineEntries members have their report
+// generated in the background with CreateAccessReport instead.
+func (s *ReportService) GetAccessReport(ctx echo.Context, workspaceID uuid.UUID) (*accessreport.AccessReport, error) {
+	logger := middleware.GetLogger(ctx)
+	reqCtx := ctx.Request().Context()
+
+	if err := authorize(ctx, authz.AccessReview, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	count, err := s.reportRepo.CountAccessEntries(reqCtx, workspaceID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to count access entries")
+		return nil, err
+	}
+	if count > accessreport.MaxInlineEntries {
+		logger.Warn().Int("entries", count).Msg("access report too large to serve inline")
+		code := "ACCESS_REPORT_TOO_LARGE"
+		return nil, errs.NewUnprocessableError(
+			fmt.Sprintf("The workspace has over %d members, request the access report to have it generated in the background",
+				accessreport.MaxInlineEntries),
+			false, &code, nil, nil,
+		)
+	}
+
+	accessReport, err := s.buildAccessReport(reqCtx, workspaceID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to build access report")
+		return nil, err
+	}
+
+	// Business event log
+	logger.Info().
+		Str("event", "access_report_viewed").
+		Int("entries", len(accessReport.Entries)).
+		Msg("Access report viewed successfully")
+
+	return accessReport, nil
+}
+
+// GetAccessReportCSV is GetAccessReport as a CSV file
+func (s *ReportService) GetAccessReportCSV(ctx echo.Context, workspaceID uuid.UUID) ([]byte, error) {
+	accessReport, err := s.GetAccessReport(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := exporter.EncodeAccessReportCSV(accessReport)
+	if err != nil {
+		middleware.GetLogger(ctx).Error().Err(err).Msg("failed to encode access report")
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// CreateAccessReport queues an access report of the workspace for the user,
+// generated as a CSV file
+func (s *ReportService) CreateAccessReport(ctx echo.Context, workspaceID uuid.UUID, userID string,
+	payload *report.CreateReportPayload,
+) (*report.Report, error) {
+	if err := authorize(ctx, authz.AccessReview, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	return s.createReport(ctx, workspaceID, userID, report.KindAccess, payload)
+}
+
+// buildAccessReport lists the workspace's members and service accounts,
+// naming the members the identity provider didn't provision from the auth
+// provider
+func (s *ReportService) buildAccessReport(ctx context.Context, workspaceID uuid.UUID) (*accessreport.AccessReport, error) {
+	entries, err := s.reportRepo.GetAccessEntries(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	var unnamed []string
+	for _, e := range entries {
+		if e.Kind == accessreport.KindMember && e.Name == nil {
+			unnamed = append(unnamed, e.ID)
+		}
+	}
+
+	names := make(map[string]string, len(unnamed))
+	for batch := range slices.Chunk(unnamed, identityBatchSize) {
+		users, err := s.identity.GetUserSummaries(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			if user.Name != "" {
+				names[user.ID] = user.Name
+			}
+		}
+	}
+
+	for i := range entries {
+		if name, ok := names[entries[i].ID]; ok && entries[i].Kind == accessreport.KindMember {
+			entries[i].Name = &name
+		}
+	}
+
+	return &accessreport.AccessReport{
+		WorkspaceID: workspaceID,
+		GeneratedAt: time.Now(),
+		Entries:     entries,
+	}, nil
+}
+
+// buildAccessReportCSV renders the workspace's access report as a CSV file
+func (s *ReportService) buildAccessReportCSV(ctx context.Context, workspaceID uuid.UUID) ([]byte, error) {
+	accessReport, err := s.buildAccessReport(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	return exporter.EncodeAccessReportCSV(accessReport)
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/activity"
+	"github.com/mabhi256/tasker/internal/model/audit"
+	"github.com/mabhi256/tasker/internal/model/comment"
+	"github.com/mabhi256/tasker/internal/model/tag"
+	"github.com/mabhi256/tasker/internal/model/todo"
+	"github.com/mabhi256/tasker/internal/model/webhook"
+	"github.com/mabhi256/tasker/internal/repository"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// feedEventTypes are the events shown in activity feeds. Tag changes are
+// shown from their domain events, which the audit events duplicate.
+var feedEventTypes = []string{
+	string(webhook.EventTodoCreated),
+	string(webhook.EventTodoCompleted),
+	string(webhook.EventCommentAdded),
+	string(webhook.EventTagRenamed),
+	string(webhook.EventTagMerged),
+	string(audit.EventMemberAdded),
+	string(audit.EventMemberRoleChanged),
+	string(audit.EventMemberRemoved),
+}
+
+// ActivityService serves the activity feeds of workspaces, recent events
+// shaped as actor, verb and object, so clients don't assemble them from the
+// todos, comments and members endpoints
+type ActivityService struct {
+	server       *server.Server
+	activityRepo *repository.ActivityRepository
+}
+
+func NewActivityService(server *server.Server, activityRepo *repository.ActivityRepository) *ActivityService {
+	return &ActivityService{
+		server:       server,
+		activityRepo: activityRepo,
+	}
+}
+
+func (s *ActivityService) GetFeed(ctx echo.Context, workspaceID uuid.UUID,
+	query *activity.GetFeedQuery,
+) (*activity.Feed, error) {
+	logger := middleware.GetLogger(ctx)
+
+	events, err := s.activityRepo.GetWorkspaceEvents(ctx.Request().Context(), workspaceID, feedEventTypes, query)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch workspace events")
+		return nil, err
+	}
+
+	feed := &activity.Feed{
+		Data:       make([]activity.Activity, 0, len(events.Data)),
+		Page:       events.Page,
+		Limit:      events.Limit,
+		Total:      events.Total,
+		TotalPages: events.TotalPages,
+	}
+	for i := range events.Data {
+		item, err := shapeActivity(&events.Data[i])
+		if err != nil {
+			// An event that can't be shown leaves a gap rather than failing
+			// the whole feed
+			logger.Warn().Err(err).
+				Str("event_id", events.Data[i].ID.String()).
+				Str("event_type", events.Data[i].EventType).
+				Msg("failed to shape activity")
+			continue
+		}
+		feed.Data = append(feed.Data, *item)
+	}
+
+	return feed, nil
+}
+
+// shapeActivity describes an event from its payload. Events recorded
+// outside a request have no actor of their own, and are attributed to the
+// author of what they are about.
+func shapeActivity(event *activity.Event) (*activity.Activity, error) {
+	item := &activity.Activity{
+		ID:      event.ID,
+		Time:    event.CreatedAt,
+		ActorID: event.ActorID,
+	}
+
+	switch event.EventType {
+	case string(webhook.EventTodoCreated), string(webhook.EventTodoCompleted):
+		var t todo.Todo
+		if err := json.Unmarshal(event.Payload, &t); err != nil {
+			return nil, fmt.Errorf("failed to decode todo: %w", err)
+		}
+
+		item.Verb = activity.VerbCreated
+		if event.EventType == string(webhook.EventTodoCompleted) {
+			item.Verb = activity.VerbCompleted
+		}
+		item.Object = activity.Object{Type: activity.ObjectTodo, ID: t.ID.String(), Name: t.Title}
+		if item.ActorID == nil {
+			item.ActorID = &t.UserID
+		}
+
+	case string(webhook.EventCommentAdded):
+		var c comment.Comment
+		if err := json.Unmarshal(event.Payload, &c); err != nil {
+			return nil, fmt.Errorf("failed to decode comment: %w", err)
+		}
+
+		item.Verb = activity.VerbCommented
+		item.Object = activity.Object{Type: activity.ObjectComment, ID: c.ID.String()}
+		item.Target = &activity.Object{Type: activity.ObjectTodo, ID: c.TodoID.String()}
+		if event.TodoTitle != nil {
+			item.Target.Name = *event.TodoTitle
+		}
+		if item.ActorID == nil {
+			item.ActorID = &c.UserID
+		}
+
+	case string(webhook.EventTagRenamed), string(webhook.EventTagMerged):
+		var change tag.Change
+		if err := json.Unmarshal(event.Payload, &change); err != nil {
+			return nil, fmt.Errorf("failed to decode tag change: %w", err)
+		}
+
+		item.Verb = activity.VerbRenamed
+		if event.EventType == string(webhook.EventTagMerged) {
+			item.Verb = activity.VerbMerged
+		}
+		item.Object = activity.Object{Type: activity.ObjectTag, ID: change.From, Name: change.From}
+		item.Details = map[string]string{
+			"to":    change.To,
+			"todos": fmt.Sprint(len(change.TodoIDs)),
+		}
+
+	case string(audit.EventMemberAdded), string(audit.EventMemberRoleChanged), string(audit.EventMemberRemoved):
+		var record audit.Record
+		if err := json.Unmarshal(event.Payload, &record); err != nil {
+			return nil, fmt.Errorf("failed to decode audit record: %w", err)
+		}
+
+		switch audit.EventType(event.EventType) {
+		case audit.EventMemberAdded:
+			item.Verb = activity.VerbAdded
+		case audit.EventMemberRoleChanged:
+			item.Verb = activity.VerbChangedRole
+		default:
+			item.Verb = activity.VerbRemoved
+		}
+		item.Object = activity.Object{Type: activity.ObjectMember, ID: record.Target.ID}
+		if role, ok := record.Details["role"]; ok {
+			item.Details = map[string]string{"role": role}
+		}
+		if item.ActorID == nil && record.Actor.UserID != "" {
+			item.ActorID = &record.Actor.UserID
+		}
+
+	default:
+		return nil, fmt.Errorf("no activity for event type %s", event.EventType)
+	}
+
+	return item, nil
+}
@@ -0,0 +1,370 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/cron"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model"
+	"github.com/mabhi256/tasker/internal/model/admin"
+	"github.com/mabhi256/tasker/internal/model/audit"
+	"github.com/mabhi256/tasker/internal/model/todo"
+	"github.com/mabhi256/tasker/internal/repository"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+const ImpersonationTTL = time.Hour
+
+type AdminService struct {
+	server    *server.Server
+	adminRepo *repository.AdminRepository
+	todoRepo  *repository.TodoRepository
+	usageRepo *repository.UsageRepository
+	audit     *AuditService
+}
+
+func NewAdminService(server *server.Server, adminRepo *repository.AdminRepository,
+	todoRepo *repository.TodoRepository, usageRepo *repository.UsageRepository, auditService *AuditService,
+) *AdminService {
+	return &AdminService{
+		server:    server,
+		adminRepo: adminRepo,
+		todoRepo:  todoRepo,
+		usageRepo: usageRepo,
+		audit:     auditService,
+	}
+}
+
+func (s *AdminService) GetUsers(ctx echo.Context, query *admin.GetUsersQuery) (*model.PaginatedResponse[admin.UserSummary], error) {
+	logger := middleware.GetLogger(ctx)
+
+	users, err := s.adminRepo.GetUsers(ctx.Request().Context(), query)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch users")
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (s *AdminService) GetUserTodos(ctx echo.Context, query *admin.GetUserTodosQuery) (*model.PaginatedResponse[todo.PopulatedTodo], error) {
+	logger := middleware.GetLogger(ctx)
+
+	todosQuery, err := query.ToTodosQuery()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to build todos query")
+		return nil, errs.NewValidationError(err)
+	}
+
+	result, err := s.todoRepo.GetTodosForUser(ctx.Request().Context(), query.UserID, todosQuery)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch user todos")
+		return nil, err
+	}
+
+	// Audit log
+	logger.Info().
+		Str("event", "admin_user_todos_viewed").
+		Str("admin_id", middleware.GetUserID(ctx)).
+		Str("target_user_id", query.UserID).
+		Msg("Admin viewed user todos")
+
+	return result, nil
+}
+
+func (s *AdminService) StartImpersonation(ctx echo.Context, adminID string,
+	payload *admin.StartImpersonationPayload,
+) (*admin.ImpersonationSession, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if payload.UserID == adminID {
+		return nil, errs.NewUnprocessableError("Cannot impersonate yourself", false, nil, nil, nil)
+	}
+
+	if s.server.Redis == nil {
+		return nil, errs.NewInternalServerError()
+	}
+
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		logger.Error().Err(err).Msg("failed to generate impersonation token")
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	session := &admin.ImpersonationSession{
+		Token:     hex.EncodeToString(tokenBytes),
+		AdminID:   adminID,
+		UserID:    payload.UserID,
+		Reason:    payload.Reason,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ImpersonationTTL),
+	}
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal impersonation session: %w", err)
+	}
+
+	err = s.server.Redis.Set(ctx.Request().Context(), admin.ImpersonationKey(session.Token), data, ImpersonationTTL).Err()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to store impersonation session")
+		return nil, err
+	}
+
+	s.audit.Record(ctx, nil, audit.EventImpersonationStarted,
+		audit.Target{Type: "user", ID: payload.UserID},
+		map[string]string{"reason": payload.Reason, "expires_at": session.ExpiresAt.Format(time.RFC3339)})
+
+	// Audit log
+	logger.Warn().
+		Str("event", "admin_impersonation_started").
+		Str("admin_id", adminID).
+		Str("target_user_id", payload.UserID).
+		Str("reason", payload.Reason).
+		Time("expires_at", session.ExpiresAt).
+		Msg("Admin started impersonating user")
+
+	return session, nil
+}
+
+func (s *AdminService) StopImpersonation(ctx echo.Context, adminID string, token string) error {
+	logger := middleware.GetLogger(ctx)
+
+	if s.server.Redis == nil {
+		return errs.NewInternalServerError()
+	}
+
+	key := admin.ImpersonationKey(token)
+	raw, err := s.server.Redis.Get(ctx.Request().Context(), key).Bytes()
+	if err != nil {
+		code := "IMPERSONATION_NOT_FOUND"
+		return errs.NewNotFoundError("impersonation session not found", false, &code)
+	}
+
+	var session admin.ImpersonationSession
+	if err := json.Unmarshal(raw, &session); err != nil {
+		return fmt.Errorf("failed to unmarshal impersonation session: %w", err)
+	}
+
+	if session.AdminID != adminID {
+		return errs.NewForbiddenError("Impersonation session belongs to another admin", false)
+	}
+
+	if err := s.server.Redis.Del(ctx.Request().Context(), key).Err(); err != nil {
+		logger.Error().Err(err).Msg("failed to delete impersonation session")
+		return err
+	}
+
+	s.audit.Record(ctx, nil, audit.EventImpersonationStopped,
+		audit.Target{Type: "user", ID: session.UserID}, nil)
+
+	// Audit log
+	logger.Warn().
+		Str("event", "admin_impersonation_stopped").
+		Str("admin_id", adminID).
+		Str("target_user_id", session.UserID).
+		Msg("Admin stopped impersonating user")
+
+	return nil
+}
+
+func (s *AdminService) GetSystemStats(ctx echo.Context) (*admin.SystemStats, error) {
+	logger := middleware.GetLogger(ctx)
+
+	queues, err := getQueueStats(s.server.Job.Inspector)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch job queues")
+		return nil, err
+	}
+
+	poolStat := s.server.DB.Pool.Stat()
+	cacheStats := s.server.Cache.Stats()
+	refCacheStats := s.server.RefCache.Stats()
+	realtimeStats := s.server.Realtime.Stats()
+
+	return &admin.SystemStats{
+		Queues: queues,
+		Database: admin.DatabasePoolStats{
+			MaxConns:             poolStat.MaxConns(),
+			TotalConns:           poolStat.TotalConns(),
+			AcquiredConns:        poolStat.AcquiredConns(),
+			IdleConns:            poolStat.IdleConns(),
+			ConstructingConns:    poolStat.ConstructingConns(),
+			AcquireCount:         poolStat.AcquireCount(),
+			EmptyAcquireCount:    poolStat.EmptyAcquireCount(),
+			CanceledAcquireCount: poolStat.CanceledAcquireCount(),
+			AcquireDurationMs:    poolStat.AcquireDuration().Milliseconds(),
+		},
+		Cache: admin.CacheStats{
+			Hits:       cacheStats.Hits,
+			Misses:     cacheStats.Misses,
+			Coalesced:  cacheStats.Coalesced,
+			LoadErrors: cacheStats.LoadErrors,
+		},
+		RefCache: admin.RefCacheStats{
+			Entries:       refCacheStats.Entries,
+			Hits:          refCacheStats.Hits,
+			Misses:        refCacheStats.Misses,
+			Evictions:     refCacheStats.Evictions,
+			Invalidations: refCacheStats.Invalidations,
+		},
+		Realtime: admin.RealtimeStats{
+			Connections:  realtimeStats.Connections,
+			Dropped:      realtimeStats.Dropped,
+			Coalesced:    realtimeStats.Coalesced,
+			Disconnected: realtimeStats.Disconnected,
+		},
+		GeneratedAt: time.Now().UTC(),
+	}, nil
+}
+
+func (s *AdminService) GetAttachmentIntegritySummary(ctx echo.Context) (*admin.AttachmentIntegritySummary, error) {
+	logger := middleware.GetLogger(ctx)
+
+	summary, err := s.adminRepo.GetAttachmentIntegritySummary(ctx.Request().Context())
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch attachment integrity summary")
+		return nil, err
+	}
+
+	return summary, nil
+}
+
+func (s *AdminService) GetAttachmentIntegrityIssues(ctx echo.Context,
+	query *admin.GetAttachmentIntegrityIssuesQuery,
+) (*model.PaginatedResponse[admin.AttachmentIntegrityIssue], error) {
+	logger := middleware.GetLogger(ctx)
+
+	issues, err := s.adminRepo.GetAttachmentIntegrityIssues(ctx.Request().Context(), query)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch attachment integrity issues")
+		return nil, err
+	}
+
+	return issues, nil
+}
+
+// GetReadOnly reports whether this instance is in read-only mode, and why
+func (s *AdminService) GetReadOnly(ctx echo.Context) (*admin.ReadOnlyStatus, error) {
+	return &admin.ReadOnlyStatus{
+		ReadOnly:    s.server.ReadOnly.ReadOnly(),
+		Engagements: s.server.ReadOnly.Engagements(),
+	}, nil
+}
+
+// SetReadOnly engages or releases the admins' read-only mode on every
+// instance. The API stays read-only while the config or the database
+// engage it too.
+func (s *AdminService) SetReadOnly(ctx echo.Context, adminID string,
+	payload *admin.SetReadOnlyPayload,
+) (*admin.ReadOnlyStatus, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := s.server.ReadOnly.SetShared(ctx.Request().Context(), *payload.Enabled, payload.Reason); err != nil {
+		logger.Error().Err(err).Msg("failed to switch read-only mode")
+		return nil, err
+	}
+
+	// Recorded after the switch, as it may be the database's writes that
+	// needed it
+	eventType := audit.EventReadOnlyReleased
+	details := map[string]string(nil)
+	if *payload.Enabled {
+		eventType = audit.EventReadOnlyEngaged
+		details = map[string]string{"reason": payload.Reason}
+	}
+	s.audit.Record(ctx, nil, eventType, audit.Target{Type: "system", ID: "read_only"}, details)
+
+	// Audit log
+	logger.Warn().
+		Str("event", "admin_read_only_switched").
+		Str("admin_id", adminID).
+		Bool("enabled", *payload.Enabled).
+		Str("reason", payload.Reason).
+		Msg("Admin switched read-only mode")
+
+	return s.GetReadOnly(ctx)
+}
+
+func (s *AdminService) GetScheduledJobs(ctx echo.Context) ([]admin.ScheduledJob, error) {
+	logger := middleware.GetLogger(ctx)
+
+	scheduled, err := s.server.Job.ScheduledJobs()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch scheduled jobs")
+		return nil, err
+	}
+
+	registry := cron.NewJobRegistry()
+
+	jobs := make([]admin.ScheduledJob, 0, len(scheduled))
+	for _, job := range scheduled {
+		description := ""
+		if registered, err := registry.Get(job.Name); err == nil {
+			description = registered.Description()
+		}
+
+		jobs = append(jobs, admin.ScheduledJob{
+			Name:        job.Name,
+			Description: description,
+			Schedule:    job.Spec,
+			NextRunAt:   job.NextRunAt,
+			LastRunAt:   job.LastRunAt,
+		})
+	}
+
+	return jobs, nil
+}
+
+// GetCostAttribution reports a UTC day's resource use by workspace, with
+// each workspace's share of the totals
+func (s *AdminService) GetCostAttribution(ctx echo.Context,
+	query *admin.GetCostAttributionQuery,
+) (*admin.CostAttributionReport, error) {
+	logger := middleware.GetLogger(ctx)
+
+	day := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -1)
+	if query.Day != nil {
+		parsed, err := time.Parse(time.DateOnly, *query.Day)
+		if err != nil {
+			return nil, errs.NewValidationError(err)
+		}
+		day = parsed
+	}
+
+	totals, workspaces, err := s.usageRepo.GetCostAttribution(ctx.Request().Context(), day, *query.Sort, *query.Limit)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch cost attribution")
+		return nil, err
+	}
+
+	for i := range workspaces {
+		w := &workspaces[i]
+		w.Share = admin.UsageShare{
+			DBTime:        share(w.DBTimeMs, totals.DBTimeMs),
+			JobExecutions: share(w.JobExecutions, totals.JobExecutions),
+			EmailsSent:    share(w.EmailsSent, totals.EmailsSent),
+			StorageBytes:  share(w.StorageBytes, totals.StorageBytes),
+		}
+	}
+
+	return &admin.CostAttributionReport{
+		Day:        day.Format(time.DateOnly),
+		Totals:     *totals,
+		Workspaces: workspaces,
+	}, nil
+}
+
+func share(value, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(value) / float64(total)
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/apikey"
+	"github.com/mabhi256/tasker/internal/model/audit"
+	"github.com/mabhi256/tasker/internal/repository"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+const (
+	// apiKeyHintLength is how many of a key's last characters are kept to
+	// tell it apart from the user's other keys
+	apiKeyHintLength = 4
+	// apiKeyTouchInterval is how stale a key's last use may get, so a key
+	// making many requests isn't written on each of them
+	apiKeyTouchInterval = time.Minute
+	apiKeyTouchTimeout  = 5 * time.Second
+)
+
+// APIKeyService manages the keys users create for programmatic access.
+// Requests made with a key act as the user, limited by the key's scope.
+type APIKeyService struct {
+	server     *server.Server
+	apiKeyRepo *repository.APIKeyRepository
+	audit      *AuditService
+}
+
+func NewAPIKeyService(server *server.Server, apiKeyRepo *repository.APIKeyRepository,
+	auditService *AuditService,
+) *APIKeyService {
+	return &APIKeyService{
+		server:     server,
+		apiKeyRepo: apiKeyRepo,
+		audit:      auditService,
+	}
+}
+
+// ResolveAPIKey implements middleware.APIKeyResolver. When the key was last
+// used is recorded in the background, and not at all while writes are
+// rejected, so it never holds up or fails authentication.
+func (s *APIKeyService) ResolveAPIKey(ctx context.Context, key string) (*apikey.Key, error) {
+	item, err := s.apiKeyRepo.GetKeyByHash(ctx, apikey.Hash(key))
+	if err != nil {
+		return nil, err
+	}
+
+	usedSince := time.Now().Add(-apiKeyTouchInterval)
+	if item.LastUsedAt != nil && item.LastUsedAt.After(usedSince) {
+		return item, nil
+	}
+	if s.server.ReadOnly != nil && s.server.ReadOnly.ReadOnly() {
+		return item, nil
+	}
+
+	go func() {
+		touchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), apiKeyTouchTimeout)
+		defer cancel()
+
+		if err := s.apiKeyRepo.TouchKey(touchCtx, item.ID, usedSince); err != nil {
+			s.server.Logger.Warn().Err(err).Str("api_key_id", item.ID.String()).Msg("failed to record API key use")
+		}
+	}()
+
+	return item, nil
+}
+
+func (s *APIKeyService) CreateKey(ctx echo.Context, userID string,
+	payload *apikey.CreateKeyPayload,
+) (*apikey.KeyWithSecret, error) {
+	logger := middleware.GetLogger(ctx)
+
+	secret, err := apikey.Generate()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate API key")
+		return nil, err
+	}
+
+	hint := secret[len(secret)-apiKeyHintLength:]
+	key, err := s.apiKeyRepo.CreateKey(ctx.Request().Context(), userID, payload, apikey.Hash(secret), hint)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to create API key")
+		return nil, err
+	}
+
+	s.audit.Record(ctx, nil, audit.EventAPIKeyCreated,
+		audit.Target{Type: "api_key", ID: key.ID.String()},
+		map[string]string{"name": key.Name, "scope": string(key.Scope)})
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "api_key_created").
+		Str("api_key_id", key.ID.String()).
+		Str("scope", string(key.Scope)).
+		Msg("API key created successfully")
+
+	return &apikey.KeyWithSecret{Key: *key, Secret: secret}, nil
+}
+
+func (s *APIKeyService) GetKeys(ctx echo.Context, userID string) ([]apikey.Key, error) {
+	logger := middleware.GetLogger(ctx)
+
+	keys, err := s.apiKeyRepo.GetKeys(ctx.Request().Context(), userID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch API keys")
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (s *APIKeyService) RevokeKey(ctx echo.Context, userID string, payload *apikey.RevokeKeyPayload) error {
+	logger := middleware.GetLogger(ctx)
+
+	reqCtx := ctx.Request().Context()
+
+	if err := s.apiKeyRepo.DeleteKey(reqCtx, userID, payload.ID); err != nil {
+		logger.Error().Err(err).Msg("failed to revoke API key")
+		return err
+	}
+
+	// Requests stop authenticating with the key as soon as it is deleted,
+	// but the connections it opened stay open until closed
+	event := &apikey.RevokedEvent{
+		Type:      apikey.EventRevoked,
+		CreatedAt: time.Now(),
+		Data:      apikey.RevokedData{APIKeyID: payload.ID.String()},
+	}
+	if err := s.server.Realtime.CloseAPIKey(reqCtx, userID, payload.ID.String(), event); err != nil {
+		logger.Warn().Err(err).Str("api_key_id", payload.ID.String()).Msg("failed to close realtime connections of revoked API key")
+	}
+
+	s.audit.Record(ctx, nil, audit.EventAPIKeyRevoked,
+		audit.Target{Type: "api_key", ID: payload.ID.String()}, nil)
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "api_key_revoked").
+		Str("api_key_id", payload.ID.String()).
+		Msg("API key revoked successfully")
+
+	return nil
+}
@@ -0,0 +1,357 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/authz"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/httpclient"
+	"github.com/mabhi256/tasker/internal/lib/job"
+	"github.com/mabhi256/tasker/internal/lib/metrics"
+	"github.com/mabhi256/tasker/internal/lib/siem"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/audit"
+	"github.com/mabhi256/tasker/internal/repository"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// AuditService records security relevant changes as audit events and
+// forwards them to SIEMs. Events go through the outbox like domain events,
+// and the relay queues one task per forwarder receiving them.
+//
+// Forwarders belong to a workspace, or are global when workspaceID is nil.
+// Global forwarders are managed through the admin API, which only admins
+// reach, and receive every audit event.
+type AuditService struct {
+	server     *server.Server
+	auditRepo  *repository.AuditRepository
+	outboxRepo *repository.OutboxRepository
+	client     *siem.Client
+}
+
+func NewAuditService(server *server.Server, auditRepo *repository.AuditRepository,
+	outboxRepo *repository.OutboxRepository,
+) *AuditService {
+	// The host name tells SIEMs which instance sent an event
+	hostname, _ := os.Hostname()
+
+	return &AuditService{
+		server:     server,
+		auditRepo:  auditRepo,
+		outboxRepo: outboxRepo,
+		client:     siem.NewClient(server.Fetcher, server.Fetcher, hostname),
+	}
+}
+
+// Record adds an audit event for a change made by the request's user. The
+// change has already happened, so failing to record it is logged rather
+// than returned.
+func (s *AuditService) Record(ctx echo.Context, workspaceID *uuid.UUID, eventType audit.EventType,
+	target audit.Target, details map[string]string,
+) {
+	record := &audit.Record{
+		Actor: audit.Actor{
+			UserID:         middleware.GetUserID(ctx),
+			ImpersonatorID: middleware.GetImpersonatorID(ctx),
+			APIKeyID:       middleware.GetAPIKeyID(ctx),
+			ClientID:       middleware.GetMachineClientID(ctx),
+			IP:             ctx.RealIP(),
+			UserAgent:      ctx.Request().UserAgent(),
+			RequestID:      middleware.GetRequestID(ctx),
+		},
+		Target:  target,
+		Details: details,
+	}
+
+	if err := s.outboxRepo.InsertAuditEvent(ctx.Request().Context(), workspaceID, string(eventType), record); err != nil {
+		middleware.GetLogger(ctx).Error().
+			Err(err).
+			Str("audit_event", string(eventType)).
+			Str("target_type", target.Type).
+			Str("target_id", target.ID).
+			Msg("failed to record audit event")
+	}
+}
+
+// requireForwarderScope checks that the user may manage the forwarders of the
+// scope. The admin API, the only caller with a nil workspace, is guarded by
+// role.
+func requireForwarderScope(ctx echo.Context, workspaceID *uuid.UUID) error {
+	if workspaceID == nil {
+		return nil
+	}
+	return authorize(ctx, authz.AuditForward, authz.Resource{})
+}
+
+func (s *AuditService) CreateForwarder(ctx echo.Context, workspaceID *uuid.UUID, userID string,
+	payload *audit.CreateForwarderPayload,
+) (*audit.Forwarder, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := requireForwarderScope(ctx, workspaceID); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkEndpoint(ctx.Request().Context(), payload.Format, payload.Endpoint, payload.Token); err != nil {
+		return nil, err
+	}
+
+	forwarder, err := s.auditRepo.CreateForwarder(ctx.Request().Context(), workspaceID, userID, payload)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to create audit forwarder")
+		return nil, err
+	}
+
+	s.Record(ctx, workspaceID, audit.EventForwarderCreated, forwarderTarget(forwarder), map[string]string{
+		"name":     forwarder.Name,
+		"format":   string(forwarder.Format),
+		"endpoint": forwarder.Endpoint,
+	})
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "audit_forwarder_created").
+		Str("forwarder_id", forwarder.ID.String()).
+		Str("format", string(forwarder.Format)).
+		Bool("global", workspaceID == nil).
+		Msg("Audit forwarder created successfully")
+
+	return forwarder, nil
+}
+
+func (s *AuditService) GetForwarders(ctx echo.Context, workspaceID *uuid.UUID) ([]audit.Forwarder, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := requireForwarderScope(ctx, workspaceID); err != nil {
+		return nil, err
+	}
+
+	forwarders, err := s.auditRepo.GetForwarders(ctx.Request().Context(), workspaceID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch audit forwarders")
+		return nil, err
+	}
+
+	return forwarders, nil
+}
+
+func (s *AuditService) GetForwarderByID(ctx echo.Context, workspaceID *uuid.UUID,
+	forwarderID uuid.UUID,
+) (*audit.Forwarder, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := requireForwarderScope(ctx, workspaceID); err != nil {
+		return nil, err
+	}
+
+	forwarder, err := s.auditRepo.GetForwarderByID(ctx.Request().Context(), workspaceID, forwarderID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch audit forwarder by ID")
+		return nil, err
+	}
+
+	return forwarder, nil
+}
+
+func (s *AuditService) UpdateForwarder(ctx echo.Context, workspaceID *uuid.UUID,
+	payload *audit.UpdateForwarderPayload,
+) (*audit.Forwarder, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := requireForwarderScope(ctx, workspaceID); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.auditRepo.GetForwarderByID(ctx.Request().Context(), workspaceID, payload.ID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch audit forwarder by ID")
+		return nil, err
+	}
+
+	// The endpoint and token are checked together, against the format the
+	// forwarder already has
+	if payload.Endpoint != nil || payload.Token != nil {
+		endpoint, token := existing.Endpoint, existing.Token
+		if payload.Endpoint != nil {
+			endpoint = *payload.Endpoint
+		}
+		if payload.Token != nil {
+			token = payload.Token
+		}
+		if err := s.checkEndpoint(ctx.Request().Context(), existing.Format, endpoint, token); err != nil {
+			return nil, err
+		}
+	}
+
+	forwarder, err := s.auditRepo.UpdateForwarder(ctx.Request().Context(), workspaceID, payload)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to update audit forwarder")
+		return nil, err
+	}
+
+	details := map[string]string{}
+	if payload.Name != nil {
+		details["name"] = forwarder.Name
+	}
+	if payload.Endpoint != nil {
+		details["endpoint"] = forwarder.Endpoint
+	}
+	if payload.Token != nil {
+		details["token"] = "changed"
+	}
+	if payload.Enabled != nil {
+		details["enabled"] = fmt.Sprint(forwarder.Enabled)
+	}
+	s.Record(ctx, workspaceID, audit.EventForwarderUpdated, forwarderTarget(forwarder), details)
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "audit_forwarder_updated").
+		Str("forwarder_id", forwarder.ID.String()).
+		Bool("enabled", forwarder.Enabled).
+		Msg("Audit forwarder updated successfully")
+
+	return forwarder, nil
+}
+
+func (s *AuditService) DeleteForwarder(ctx echo.Context, workspaceID *uuid.UUID, forwarderID uuid.UUID) error {
+	logger := middleware.GetLogger(ctx)
+
+	if err := requireForwarderScope(ctx, workspaceID); err != nil {
+		return err
+	}
+
+	if err := s.auditRepo.DeleteForwarder(ctx.Request().Context(), workspaceID, forwarderID); err != nil {
+		logger.Error().Err(err).Msg("failed to delete audit forwarder")
+		return err
+	}
+
+	s.Record(ctx, workspaceID, audit.EventForwarderDeleted,
+		audit.Target{Type: "audit_forwarder", ID: forwarderID.String()}, nil)
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "audit_forwarder_deleted").
+		Str("forwarder_id", forwarderID.String()).
+		Msg("Audit forwarder deleted successfully")
+
+	return nil
+}
+
+// Publish queues the event for every enabled forwarder receiving it. It is
+// called by the outbox relay and may see the same event more than once,
+// which the task ids dedupe.
+func (s *AuditService) Publish(ctx context.Context, event *audit.Event) error {
+	forwarders, err := s.auditRepo.GetReceivingForwarders(ctx, event.WorkspaceID)
+	if err != nil {
+		return err
+	}
+
+	var errList []error
+	for _, forwarder := range forwarders {
+		err := job.EnqueueAuditForward(ctx, s.server.Job.Client, &job.AuditForwardTask{
+			ForwarderID: forwarder.ID,
+			Event:       *event,
+		})
+		if err != nil {
+			errList = append(errList, fmt.Errorf("failed to enqueue audit forward forwarder_id=%s: %w", forwarder.ID.String(), err))
+		}
+	}
+
+	return errors.Join(errList...)
+}
+
+// ForwardAuditEvent implements job.AuditForwarderInterface. Problems retrying
+// can't fix, like a blocked address, give up on the event right away.
+func (s *AuditService) ForwardAuditEvent(ctx context.Context, forwarderID uuid.UUID, event *audit.Event,
+	final bool,
+) error {
+	forwarder, err := s.auditRepo.GetForwarder(ctx, forwarderID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// The forwarder was deleted after the event was queued
+			return nil
+		}
+		return err
+	}
+
+	if !forwarder.Enabled {
+		return nil
+	}
+
+	sendErr := s.client.Send(ctx, forwarder, event)
+
+	if err := s.auditRepo.RecordForwardResult(ctx, forwarderID, sendErr); err != nil {
+		return err
+	}
+
+	if sendErr == nil {
+		return nil
+	}
+
+	permanent := errors.Is(sendErr, httpclient.ErrBlockedAddress) ||
+		errors.Is(sendErr, httpclient.ErrUnsupportedURL) ||
+		errors.Is(sendErr, siem.ErrInvalidEndpoint)
+	if !final && !permanent {
+		return sendErr
+	}
+
+	s.server.Metrics.Inc(metrics.AuditForwardsFailed)
+	s.server.Logger.Warn().
+		Str("forwarder_id", forwarderID.String()).
+		Str("event_id", event.ID.String()).
+		Str("event_type", string(event.Type)).
+		Err(sendErr).
+		Msg("audit event could not be forwarded")
+
+	return nil
+}
+
+// checkEndpoint rejects endpoints that don't fit the format or that point at
+// addresses the fetcher won't reach. Collectors are sent credentials, so
+// HTTP endpoints must use TLS.
+func (s *AuditService) checkEndpoint(ctx context.Context, format audit.Format, endpoint string,
+	token *string,
+) error {
+	notAllowed := errs.NewBadRequestError("Audit forwarder endpoint is not allowed", false, nil, nil, nil)
+
+	switch format {
+	case audit.FormatSyslog:
+		parsed, err := siem.ParseSyslogEndpoint(endpoint)
+		if err != nil {
+			return errs.NewBadRequestError("Syslog endpoints must be udp://, tcp:// or tls:// with a host and port",
+				false, nil, nil, nil)
+		}
+		if err := s.server.Fetcher.ValidateHost(ctx, parsed.Host); err != nil {
+			return notAllowed
+		}
+	default:
+		u, err := s.server.Fetcher.ValidateURL(ctx, endpoint)
+		if err != nil {
+			return notAllowed
+		}
+		if u.Scheme != "https" {
+			return errs.NewBadRequestError("Audit forwarder endpoint must use https", false, nil, nil, nil)
+		}
+	}
+
+	// 422 - Splunk rejects events without a token
+	if format == audit.FormatSplunkHEC && (token == nil || *token == "") {
+		return errs.NewUnprocessableError("Splunk HEC forwarders need a token", false, nil, nil, nil)
+	}
+
+	return nil
+}
+
+func forwarderTarget(forwarder *audit.Forwarder) audit.Target {
+	return audit.Target{Type: "audit_forwarder", ID: forwarder.ID.String()}
+}
@@ -2,14 +2,49 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/clerk/clerk-sdk-go/v2"
+	clerkSession "github.com/clerk/clerk-sdk-go/v2/session"
 	clerkUser "github.com/clerk/clerk-sdk-go/v2/user"
 
+	"github.com/mabhi256/tasker/internal/model/resolve"
+	"github.com/mabhi256/tasker/internal/model/session"
+	"github.com/mabhi256/tasker/internal/model/trial"
 	"github.com/mabhi256/tasker/internal/server"
 )
 
+// IdentityProvider is the auth provider's user directory, which
+// provisioning finds and creates users in, SSO checks emails against and
+// references to users are resolved from
+type IdentityProvider interface {
+	// GetUserEmail returns the user's primary email
+	GetUserEmail(ctx context.Context, userID string) (string, error)
+	// GetUserSummaries returns the users with the ids, skipping unknown ones
+	GetUserSummaries(ctx context.Context, userIDs []string) ([]resolve.UserSummary, error)
+	// FindUserByEmail returns the id of the user with the verified or
+	// unverified email, or "" when there is none
+	FindUserByEmail(ctx context.Context, email string) (string, error)
+	// CreateUser creates a user who signs in through the auth provider's
+	// usual flows, without a password
+	CreateUser(ctx context.Context, email string, firstName string, lastName string) (string, error)
+}
+
+// SessionProvider is the auth provider's record of signed in sessions
+type SessionProvider interface {
+	// GetSession returns the session, or nil when there is none
+	GetSession(ctx context.Context, sessionID string) (*session.Session, error)
+	// ListActiveSessions returns the user's sessions that are signed in
+	ListActiveSessions(ctx context.Context, userID string) ([]session.Session, error)
+	// RevokeSession signs the session out. Tokens already issued for it stay
+	// valid until they expire.
+	RevokeSession(ctx context.Context, sessionID string) error
+}
+
 type AuthService struct {
 	server *server.Server
 }
@@ -22,6 +57,11 @@ func NewAuthService(s *server.Server) *AuthService {
 }
 
 func (s *AuthService) GetUserEmail(ctx context.Context, userID string) (string, error) {
+	// Trials are anonymous, the auth provider doesn't know them
+	if trial.IsUser(userID) {
+		return "", fmt.Errorf("user %s is a trial and has no email address", userID)
+	}
+
 	user, err := clerkUser.Get(ctx, userID)
 	if err != nil {
 		return "", fmt.Errorf("failed to get user from Clerk: %w", err)
@@ -39,3 +79,131 @@ func (s *AuthService) GetUserEmail(ctx context.Context, userID string) (string,
 
 	return user.EmailAddresses[0].EmailAddress, nil
 }
+
+// GetUserSummaries implements IdentityProvider
+func (s *AuthService) GetUserSummaries(ctx context.Context, userIDs []string) ([]resolve.UserSummary, error) {
+	if len(userIDs) == 0 {
+		return []resolve.UserSummary{}, nil
+	}
+
+	users, err := clerkUser.List(ctx, &clerkUser.ListParams{
+		ListParams: clerk.ListParams{Limit: clerk.Int64(int64(len(userIDs)))},
+		UserIDs:    userIDs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users from Clerk: %w", err)
+	}
+
+	summaries := make([]resolve.UserSummary, 0, len(users.Users))
+	for _, user := range users.Users {
+		var names []string
+		for _, name := range []*string{user.FirstName, user.LastName} {
+			if name != nil && *name != "" {
+				names = append(names, *name)
+			}
+		}
+
+		summaries = append(summaries, resolve.UserSummary{
+			ID:       user.ID,
+			Name:     strings.Join(names, " "),
+			ImageURL: user.ImageURL,
+		})
+	}
+
+	return summaries, nil
+}
+
+// FindUserByEmail implements IdentityProvider
+func (s *AuthService) FindUserByEmail(ctx context.Context, email string) (string, error) {
+	users, err := clerkUser.List(ctx, &clerkUser.ListParams{EmailAddresses: []string{email}})
+	if err != nil {
+		return "", fmt.Errorf("failed to list users from Clerk: %w", err)
+	}
+
+	if len(users.Users) == 0 {
+		return "", nil
+	}
+
+	return users.Users[0].ID, nil
+}
+
+// CreateUser implements IdentityProvider
+func (s *AuthService) CreateUser(ctx context.Context, email string, firstName string, lastName string) (string, error) {
+	params := &clerkUser.CreateParams{
+		EmailAddresses:          &[]string{email},
+		SkipPasswordRequirement: clerk.Bool(true),
+	}
+	if firstName != "" {
+		params.FirstName = clerk.String(firstName)
+	}
+	if lastName != "" {
+		params.LastName = clerk.String(lastName)
+	}
+
+	user, err := clerkUser.Create(ctx, params)
+	if err != nil {
+		return "", fmt.Errorf("failed to create user in Clerk: %w", err)
+	}
+
+	return user.ID, nil
+}
+
+// GetSession implements SessionProvider
+func (s *AuthService) GetSession(ctx context.Context, sessionID string) (*session.Session, error) {
+	item, err := clerkSession.Get(ctx, sessionID)
+	if err != nil {
+		var apiErr *clerk.APIErrorResponse
+		if errors.As(err, &apiErr) && apiErr.HTTPStatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get session from Clerk: %w", err)
+	}
+
+	result := toSession(item)
+	return &result, nil
+}
+
+// ListActiveSessions implements SessionProvider
+func (s *AuthService) ListActiveSessions(ctx context.Context, userID string) ([]session.Session, error) {
+	list, err := clerkSession.List(ctx, &clerkSession.ListParams{
+		UserID: clerk.String(userID),
+		Status: clerk.String("active"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions from Clerk: %w", err)
+	}
+
+	sessions := make([]session.Session, 0, len(list.Sessions))
+	for _, item := range list.Sessions {
+		sessions = append(sessions, toSession(item))
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession implements SessionProvider
+func (s *AuthService) RevokeSession(ctx context.Context, sessionID string) error {
+	if _, err := clerkSession.Revoke(ctx, &clerkSession.RevokeParams{ID: sessionID}); err != nil {
+		return fmt.Errorf("failed to revoke session in Clerk: %w", err)
+	}
+	return nil
+}
+
+func toSession(item *clerk.Session) session.Session {
+	result := session.Session{
+		ID:           item.ID,
+		UserID:       item.UserID,
+		Status:       item.Status,
+		CreatedAt:    time.UnixMilli(item.CreatedAt),
+		LastActiveAt: time.UnixMilli(item.LastActiveAt),
+		ExpiresAt:    time.UnixMilli(item.ExpireAt),
+	}
+	if activity := item.LatestActivity; activity != nil {
+		result.DeviceType = activity.DeviceType
+		result.Browser = activity.BrowserName
+		result.IPAddress = activity.IPAddress
+		result.City = activity.City
+		result.Country = activity.Country
+	}
+	return result
+}
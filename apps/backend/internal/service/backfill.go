@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/database"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/lib/backfill"
+	"github.com/mabhi256/tasker/internal/lib/job"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/admin"
+	"github.com/mabhi256/tasker/internal/repository"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// BackfillService runs the data migrations in database.Backfills and lets
+// admins pause, resume and abort them
+type BackfillService struct {
+	server       *server.Server
+	backfillRepo *repository.BackfillRepository
+}
+
+func NewBackfillService(server *server.Server, backfillRepo *repository.BackfillRepository) *BackfillService {
+	return &BackfillService{
+		server:       server,
+		backfillRepo: backfillRepo,
+	}
+}
+
+func (s *BackfillService) GetBackfills(ctx echo.Context) ([]admin.Backfill, error) {
+	logger := middleware.GetLogger(ctx)
+
+	runs, err := s.backfillRepo.GetBackfillRuns(ctx.Request().Context())
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch backfill runs")
+		return nil, err
+	}
+
+	byName := make(map[string]*admin.BackfillRun, len(runs))
+	for i := range runs {
+		byName[runs[i].Name] = &runs[i]
+	}
+
+	defined := database.Backfills.List()
+	backfills := make([]admin.Backfill, 0, len(defined))
+	for _, b := range defined {
+		backfills = append(backfills, admin.Backfill{
+			Name:        b.Name,
+			Description: b.Description,
+			Run:         byName[b.Name],
+		})
+	}
+
+	return backfills, nil
+}
+
+// StartBackfill starts a run from the first row
+func (s *BackfillService) StartBackfill(ctx echo.Context, name string) (*admin.Backfill, error) {
+	return s.changeRun(ctx, name, "backfill_started", s.backfillRepo.StartBackfillRun, true)
+}
+
+// PauseBackfill stops the run after its current batch. The task running it
+// ends, and ResumeBackfill queues a new one.
+func (s *BackfillService) PauseBackfill(ctx echo.Context, name string) (*admin.Backfill, error) {
+	return s.changeRun(ctx, name, "backfill_paused", s.backfillRepo.PauseBackfillRun, false)
+}
+
+// ResumeBackfill continues the run from its checkpoint
+func (s *BackfillService) ResumeBackfill(ctx echo.Context, name string) (*admin.Backfill, error) {
+	return s.changeRun(ctx, name, "backfill_resumed", s.backfillRepo.ResumeBackfillRun, true)
+}
+
+func (s *BackfillService) AbortBackfill(ctx echo.Context, name string) (*admin.Backfill, error) {
+	return s.changeRun(ctx, name, "backfill_aborted", s.backfillRepo.AbortBackfillRun, false)
+}
+
+// changeRun applies a change to the backfill's run, and queues a task to run
+// it when enqueue is set
+func (s *BackfillService) changeRun(ctx echo.Context, name string, event string,
+	change func(context.Context, string) (*admin.BackfillRun, error), enqueue bool,
+) (*admin.Backfill, error) {
+	logger := middleware.GetLogger(ctx)
+
+	b, err := getBackfill(name)
+	if err != nil {
+		return nil, err
+	}
+
+	run, err := change(ctx.Request().Context(), name)
+	if err != nil {
+		logger.Error().Err(err).Str("backfill", name).Str("event", event).Msg("failed to change backfill run")
+		return nil, err
+	}
+
+	if enqueue {
+		if err := job.Enqueue(ctx.Request().Context(), s.server.Job.Client, &job.BackfillRunTask{Name: name}); err != nil {
+			// The run stays running, so resuming it queues the task again
+			logger.Error().Err(err).Str("backfill", name).Msg("failed to queue backfill run")
+			return nil, err
+		}
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", event).
+		Str("backfill", name).
+		Str("checkpoint", run.Checkpoint).
+		Int64("rows_done", run.RowsDone).
+		Msg("Backfill run changed")
+
+	return &admin.Backfill{Name: b.Name, Description: b.Description, Run: run}, nil
+}
+
+// RunBackfill implements job.BackfillRunnerInterface
+func (s *BackfillService) RunBackfill(ctx context.Context, name string) (bool, error) {
+	b, ok := database.Backfills.Get(name)
+	if !ok {
+		// The backfill was removed after the task was queued
+		s.server.Logger.Warn().Str("backfill", name).Msg("unknown backfill, skipping run")
+		return false, nil
+	}
+
+	cfg := s.server.Config.Backfills
+	batchSize := cfg.BatchSize
+	if b.BatchSize > 0 {
+		batchSize = b.BatchSize
+	}
+
+	deadline := time.Now().Add(cfg.MaxRunTime)
+	for time.Now().Before(deadline) {
+		run, err := s.backfillRepo.RunBackfillBatch(ctx, b, batchSize)
+		if err != nil {
+			return false, err
+		}
+
+		if run.Status != admin.BackfillStatusRunning {
+			s.server.Logger.Info().
+				Str("backfill", name).
+				Str("status", string(run.Status)).
+				Int64("rows_done", run.RowsDone).
+				Int("batches", run.Batches).
+				Msg("backfill run stopped")
+			return false, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(cfg.BatchPause):
+		}
+	}
+
+	s.server.Logger.Info().
+		Str("backfill", name).
+		Msg("backfill run ran out of time, continuing in a new task")
+	return true, nil
+}
+
+// RecordBackfillFailure implements job.BackfillRunnerInterface
+func (s *BackfillService) RecordBackfillFailure(ctx context.Context, name string, runErr error, final bool) error {
+	return s.backfillRepo.RecordBackfillError(ctx, name, runErr.Error(), final)
+}
+
+func getBackfill(name string) (*backfill.Backfill, error) {
+	b, ok := database.Backfills.Get(name)
+	if !ok {
+		code := "BACKFILL_NOT_FOUND"
+		return nil, errs.NewNotFoundError("backfill not found", false, &code)
+	}
+	return b, nil
+}
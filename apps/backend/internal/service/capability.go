@@ -0,0 +1,118 @@
+package service
+
+import (
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/authz"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/capability"
+	"github.com/mabhi256/tasker/internal/model/category"
+	"github.com/mabhi256/tasker/internal/model/comment"
+	"github.com/mabhi256/tasker/internal/model/resolve"
+	"github.com/mabhi256/tasker/internal/model/todo"
+	"github.com/mabhi256/tasker/internal/model/workspace"
+	"github.com/mabhi256/tasker/internal/repository"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// workspaceFeature is a feature limited to some workspaces and roles
+type workspaceFeature struct {
+	name         string
+	shared       bool
+	requiredRole workspace.Role
+}
+
+// workspaceFeatures mirror the checks the features' services make
+var workspaceFeatures = []workspaceFeature{
+	{name: "members", shared: true, requiredRole: workspace.RoleAdmin},
+	{name: "sso", shared: true, requiredRole: workspace.RoleAdmin},
+	{name: "provisioning", shared: true, requiredRole: workspace.RoleAdmin},
+	{name: "ipAllowlist", shared: true, requiredRole: workspace.RoleAdmin},
+	{name: "webhooks", requiredRole: workspace.RoleAdmin},
+	{name: "exports", requiredRole: workspace.RoleAdmin},
+	{name: "auditLog", requiredRole: workspace.RoleAdmin},
+}
+
+type CapabilityService struct {
+	server        *server.Server
+	workspaceRepo *repository.WorkspaceRepository
+}
+
+func NewCapabilityService(server *server.Server, workspaceRepo *repository.WorkspaceRepository) *CapabilityService {
+	return &CapabilityService{
+		server:        server,
+		workspaceRepo: workspaceRepo,
+	}
+}
+
+// GetCapabilities describes the features, limits and actions available to
+// the user in the workspace
+func (s *CapabilityService) GetCapabilities(ctx echo.Context, workspaceID uuid.UUID) (*capability.Capabilities, error) {
+	logger := middleware.GetLogger(ctx)
+
+	workspaceItem, err := s.workspaceRepo.GetWorkspaceByID(ctx.Request().Context(), workspaceID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch workspace")
+		return nil, err
+	}
+
+	role := middleware.GetWorkspaceRole(ctx)
+	platforms := s.server.Push.Platforms()
+
+	features := capability.Features{}
+	if len(platforms) > 0 && s.server.Runtime.FeatureEnabled("push") {
+		features["push"] = capability.Feature{Available: true}
+	} else {
+		features["push"] = unavailable(capability.ReasonNotConfigured, nil)
+	}
+
+	for _, f := range workspaceFeatures {
+		requiredRole := f.requiredRole
+		switch {
+		case !s.server.Runtime.FeatureEnabled(f.name):
+			features[f.name] = unavailable(capability.ReasonNotConfigured, nil)
+		case f.shared && workspaceItem.IsPersonal:
+			features[f.name] = unavailable(capability.ReasonPersonalWorkspace, &requiredRole)
+		case !role.AtLeast(requiredRole):
+			features[f.name] = unavailable(capability.ReasonRole, &requiredRole)
+		default:
+			features[f.name] = capability.Feature{Available: true, RequiredRole: &requiredRole}
+		}
+	}
+
+	subject := authz.Subject{UserID: middleware.GetUserID(ctx), Role: role}
+	actions := []authz.Action{}
+	for _, action := range authz.Actions() {
+		if authz.Allowed(action, subject, authz.Resource{}) {
+			actions = append(actions, action)
+		}
+	}
+
+	return &capability.Capabilities{
+		WorkspaceID:   workspaceID,
+		Role:          role,
+		IsPersonal:    workspaceItem.IsPersonal,
+		PushPlatforms: platforms,
+		Features:      features,
+		Limits: capability.Limits{
+			TodoTitleLength:       todo.MaxTitleLength,
+			TodoDescriptionLength: todo.MaxDescriptionLength,
+			CommentLength:         comment.MaxContentLength,
+			CategoryNameLength:    category.MaxNameLength,
+			MentionsPerComment:    comment.MaxMentions,
+			ResolveIDs:            resolve.MaxIDs,
+			PageSize:              todo.MaxPageSize,
+			ReminderMinutes:       todo.MaxReminderMinutes,
+		},
+		TodoDefaults: capability.TodoDefaults{
+			Priority:        todo.PriorityMedium,
+			ReminderMinutes: s.server.Config.Cron.ReminderHours * 60,
+			CategoryFields:  []string{"priority", "reminderMinutes"},
+		},
+		Actions: actions,
+	}, nil
+}
+
+func unavailable(reason capability.Reason, requiredRole *workspace.Role) capability.Feature {
+	return capability.Feature{Reason: &reason, RequiredRole: requiredRole}
+}
@@ -1,8 +1,13 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
+
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/authz"
+	"github.com/mabhi256/tasker/internal/lib/refcache"
 	"github.com/mabhi256/tasker/internal/middleware"
 	"github.com/mabhi256/tasker/internal/model"
 	"github.com/mabhi256/tasker/internal/model/category"
@@ -10,6 +15,12 @@ import (
 	"github.com/mabhi256/tasker/internal/server"
 )
 
+// Categories are listed with every view of the todos, and change rarely, so
+// the lists are kept in memory until a category of the workspace changes
+func categoriesCacheKey(workspaceID uuid.UUID) string {
+	return "categories:" + workspaceID.String() + ":"
+}
+
 type CategoryService struct {
 	server       *server.Server
 	categoryRepo *repository.CategoryRepository
@@ -22,16 +33,21 @@ func NewCategoryService(server *server.Server, categoryRepo *repository.Category
 	}
 }
 
-func (s *CategoryService) CreateCategory(ctx echo.Context, userID string,
+func (s *CategoryService) CreateCategory(ctx echo.Context, workspaceID uuid.UUID, userID string,
 	payload *category.CreateCategoryPayload,
 ) (*category.Category, error) {
 	logger := middleware.GetLogger(ctx)
 
-	categoryItem, err := s.categoryRepo.CreateCategory(ctx.Request().Context(), userID, payload)
+	if err := authorize(ctx, authz.CategoryCreate, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	categoryItem, err := s.categoryRepo.CreateCategory(ctx.Request().Context(), workspaceID, userID, payload)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to create category")
 		return nil, err
 	}
+	s.invalidateCategories(ctx, workspaceID)
 
 	// Business event log
 	eventLogger := middleware.GetLogger(ctx)
@@ -45,12 +61,24 @@ func (s *CategoryService) CreateCategory(ctx echo.Context, userID string,
 	return categoryItem, nil
 }
 
-func (s *CategoryService) GetCategories(ctx echo.Context, userID string,
+func (s *CategoryService) GetCategories(ctx echo.Context, workspaceID uuid.UUID,
 	query *category.GetCategoriesQuery,
 ) (*model.PaginatedResponse[category.Category], error) {
 	logger := middleware.GetLogger(ctx)
 
-	categories, err := s.categoryRepo.GetCategories(ctx.Request().Context(), userID, query)
+	// The query is in the key, so each page and search is cached on its own,
+	// as is each collation
+	query.Locale = collationLanguage(ctx, query.Locale)
+	rawQuery, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+
+	categories, err := refcache.GetOrLoad(ctx.Request().Context(), s.server.RefCache,
+		categoriesCacheKey(workspaceID)+string(rawQuery),
+		func(loadCtx context.Context) (*model.PaginatedResponse[category.Category], error) {
+			return s.categoryRepo.GetCategories(loadCtx, workspaceID, query)
+		})
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to fetch categories")
 		return nil, err
@@ -59,10 +87,10 @@ func (s *CategoryService) GetCategories(ctx echo.Context, userID string,
 	return categories, nil
 }
 
-func (s *CategoryService) GetCategoryByID(ctx echo.Context, userID string, categoryID uuid.UUID) (*category.Category, error) {
+func (s *CategoryService) GetCategoryByID(ctx echo.Context, workspaceID uuid.UUID, categoryID uuid.UUID) (*category.Category, error) {
 	logger := middleware.GetLogger(ctx)
 
-	categoryItem, err := s.categoryRepo.GetCategoryByID(ctx.Request().Context(), userID, categoryID)
+	categoryItem, err := s.categoryRepo.GetCategoryByID(ctx.Request().Context(), workspaceID, categoryID)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to fetch category by ID")
 		return nil, err
@@ -71,16 +99,21 @@ func (s *CategoryService) GetCategoryByID(ctx echo.Context, userID string, categ
 	return categoryItem, nil
 }
 
-func (s *CategoryService) UpdateCategory(ctx echo.Context, userID string, categoryID uuid.UUID,
+func (s *CategoryService) UpdateCategory(ctx echo.Context, workspaceID uuid.UUID, categoryID uuid.UUID,
 	payload *category.UpdateCategoryPayload,
 ) (*category.Category, error) {
 	logger := middleware.GetLogger(ctx)
 
-	categoryItem, err := s.categoryRepo.UpdateCategory(ctx.Request().Context(), userID, categoryID, payload)
+	if err := authorize(ctx, authz.CategoryUpdate, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	categoryItem, err := s.categoryRepo.UpdateCategory(ctx.Request().Context(), workspaceID, categoryID, payload)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to update category")
 		return nil, err
 	}
+	s.invalidateCategories(ctx, workspaceID)
 
 	// Business event log
 	eventLogger := middleware.GetLogger(ctx)
@@ -93,14 +126,19 @@ func (s *CategoryService) UpdateCategory(ctx echo.Context, userID string, catego
 	return categoryItem, nil
 }
 
-func (s *CategoryService) DeleteCategory(ctx echo.Context, userID string, categoryID uuid.UUID) error {
+func (s *CategoryService) DeleteCategory(ctx echo.Context, workspaceID uuid.UUID, categoryID uuid.UUID) error {
 	logger := middleware.GetLogger(ctx)
 
-	err := s.categoryRepo.DeleteCategory(ctx.Request().Context(), userID, categoryID)
+	if err := authorize(ctx, authz.CategoryDelete, authz.Resource{}); err != nil {
+		return err
+	}
+
+	err := s.categoryRepo.DeleteCategory(ctx.Request().Context(), workspaceID, categoryID)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to delete category")
 		return err
 	}
+	s.invalidateCategories(ctx, workspaceID)
 
 	// Business event log
 	eventLogger := middleware.GetLogger(ctx)
@@ -111,3 +149,12 @@ func (s *CategoryService) DeleteCategory(ctx echo.Context, userID string, catego
 
 	return nil
 }
+
+// invalidateCategories drops the cached lists of the workspace's categories
+// after a change to one. A failure to reach other instances only delays the
+// change there until the entries expire.
+func (s *CategoryService) invalidateCategories(ctx echo.Context, workspaceID uuid.UUID) {
+	if err := s.server.RefCache.Invalidate(ctx.Request().Context(), categoriesCacheKey(workspaceID)); err != nil {
+		middleware.GetLogger(ctx).Warn().Err(err).Msg("failed to invalidate categories cache")
+	}
+}
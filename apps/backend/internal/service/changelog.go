@@ -0,0 +1,59 @@
+package service
+
+import (
+	"github.com/labstack/echo/v4"
+	libchangelog "github.com/mabhi256/tasker/internal/lib/changelog"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/changelog"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// ChangelogService serves the API changelog, so consumers can follow
+// changes and deprecations programmatically. The entries ship with the
+// code, so they are read once.
+type ChangelogService struct {
+	server  *server.Server
+	entries []changelog.Entry
+}
+
+func NewChangelogService(server *server.Server) (*ChangelogService, error) {
+	entries, err := libchangelog.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChangelogService{
+		server:  server,
+		entries: entries,
+	}, nil
+}
+
+func (s *ChangelogService) GetChangelog(ctx echo.Context, payload *changelog.GetChangelogPayload) (*changelog.Changelog, error) {
+	return &changelog.Changelog{Entries: s.filter(payload)}, nil
+}
+
+// GetChangelogFeed renders the changelog as an RSS feed served at link
+func (s *ChangelogService) GetChangelogFeed(ctx echo.Context, payload *changelog.GetChangelogPayload,
+	link string,
+) ([]byte, error) {
+	feed, err := libchangelog.RSS(s.filter(payload), link)
+	if err != nil {
+		middleware.GetLogger(ctx).Error().Err(err).Msg("failed to render changelog feed")
+		return nil, err
+	}
+	return feed, nil
+}
+
+func (s *ChangelogService) filter(payload *changelog.GetChangelogPayload) []changelog.Entry {
+	entries := make([]changelog.Entry, 0, len(s.entries))
+	for _, entry := range s.entries {
+		if payload.Since != nil && entry.PublishedAt.Before(*payload.Since) {
+			continue
+		}
+		if payload.Kind != nil && entry.Kind != *payload.Kind {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
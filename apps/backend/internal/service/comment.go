@@ -1,41 +1,84 @@
 package service
 
 import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/authz"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/lib/job"
+	"github.com/mabhi256/tasker/internal/lib/metrics"
+	"github.com/mabhi256/tasker/internal/lib/push"
 	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model"
 	"github.com/mabhi256/tasker/internal/model/comment"
+	"github.com/mabhi256/tasker/internal/model/todo"
 	"github.com/mabhi256/tasker/internal/repository"
 	"github.com/mabhi256/tasker/internal/server"
 )
 
+// mentionExcerptLength is how much of a comment mention notifications quote
+const mentionExcerptLength = 200
+
 type CommentService struct {
-	server      *server.Server
-	commentRepo *repository.CommentRepository
-	todoRepo    *repository.TodoRepository
+	server        *server.Server
+	commentRepo   *repository.CommentRepository
+	todoRepo      *repository.TodoRepository
+	workspaceRepo *repository.WorkspaceRepository
 }
 
-func NewCommentService(server *server.Server, commentRepo *repository.CommentRepository, todoRepo *repository.TodoRepository) *CommentService {
+func NewCommentService(server *server.Server, commentRepo *repository.CommentRepository,
+	todoRepo *repository.TodoRepository, workspaceRepo *repository.WorkspaceRepository,
+) *CommentService {
 	return &CommentService{
-		server:      server,
-		commentRepo: commentRepo,
-		todoRepo:    todoRepo,
+		server:        server,
+		commentRepo:   commentRepo,
+		todoRepo:      todoRepo,
+		workspaceRepo: workspaceRepo,
 	}
 }
 
-func (s *CommentService) AddComment(ctx echo.Context, userID string, todoID uuid.UUID,
+func (s *CommentService) AddComment(ctx echo.Context, workspaceID uuid.UUID, userID string, todoID uuid.UUID,
 	payload *comment.AddCommentPayload,
 ) (*comment.Comment, error) {
 	logger := middleware.GetLogger(ctx)
 
-	// Validate todo exists and belongs to user
-	_, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), userID, todoID)
+	if err := authorize(ctx, authz.CommentCreate, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	// Validate todo exists and belongs to workspace
+	todoItem, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), workspaceID, todoID)
 	if err != nil {
 		logger.Error().Err(err).Msg("todo validation failed")
 		return nil, err
 	}
 
-	commentItem, err := s.commentRepo.AddComment(ctx.Request().Context(), userID, todoID, payload)
+	if payload.ParentCommentID != nil {
+		parent, err := s.commentRepo.GetCommentByID(ctx.Request().Context(), workspaceID, *payload.ParentCommentID)
+		if err != nil {
+			logger.Error().Err(err).Msg("parent comment validation failed")
+			return nil, err
+		}
+
+		// Replies stay on the todo of the comment they answer
+		if parent.TodoID != todoID {
+			code := "INVALID_PARENT_COMMENT"
+			return nil, errs.NewBadRequestError("Replies must be on the same todo as the comment they answer",
+				false, &code, nil, nil)
+		}
+	}
+
+	mentions, err := s.resolveMentions(ctx, workspaceID, userID, payload.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	commentItem, err := s.commentRepo.AddComment(ctx.Request().Context(), workspaceID, userID, todoID, payload, mentions)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to add comment")
 		return nil, err
@@ -49,39 +92,70 @@ func (s *CommentService) AddComment(ctx echo.Context, userID string, todoID uuid
 		Str("todo_id", todoID.String()).
 		Msg("Comment added successfully")
 
+	s.server.Metrics.Inc(metrics.CommentsAdded)
+
+	s.notifyMentions(ctx, commentItem, todoItem, mentions)
+
 	return commentItem, nil
 }
 
-func (s *CommentService) GetCommentsByTodoID(ctx echo.Context, userID string, todoID uuid.UUID) ([]comment.Comment, error) {
+func (s *CommentService) GetCommentsByTodoID(ctx echo.Context, workspaceID uuid.UUID, todoID uuid.UUID,
+	render bool,
+) ([]comment.Comment, error) {
 	logger := middleware.GetLogger(ctx)
 
-	// Validate todo exists and belongs to user
-	_, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), userID, todoID)
+	// Validate todo exists and belongs to workspace
+	_, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), workspaceID, todoID)
 	if err != nil {
 		logger.Error().Err(err).Msg("todo validation failed")
 		return nil, err
 	}
 
-	comments, err := s.commentRepo.GetCommentsByTodoID(ctx.Request().Context(), userID, todoID)
+	comments, err := s.commentRepo.GetCommentsByTodoID(ctx.Request().Context(), workspaceID, todoID)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to fetch comments by todo ID")
 		return nil, err
 	}
 
+	if render {
+		for i := range comments {
+			comments[i].RenderContent()
+		}
+	}
+
 	return comments, nil
 }
 
-func (s *CommentService) UpdateComment(ctx echo.Context, userID string, commentID uuid.UUID, content string) (*comment.Comment, error) {
+func (s *CommentService) UpdateComment(ctx echo.Context, workspaceID uuid.UUID, userID string,
+	commentID uuid.UUID, content string,
+) (*comment.Comment, error) {
 	logger := middleware.GetLogger(ctx)
 
-	// Validate comment exists and belongs to user
-	_, err := s.commentRepo.GetCommentByID(ctx.Request().Context(), userID, commentID)
+	// Validate comment exists in workspace
+	existing, err := s.commentRepo.GetCommentByID(ctx.Request().Context(), workspaceID, commentID)
 	if err != nil {
 		logger.Error().Err(err).Msg("comment validation failed")
 		return nil, err
 	}
 
-	commentItem, err := s.commentRepo.UpdateComment(ctx.Request().Context(), userID, commentID, content)
+	if err := authorize(ctx, authz.CommentUpdate, authz.Resource{OwnerID: existing.UserID}); err != nil {
+		return nil, err
+	}
+
+	todoItem, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), workspaceID, existing.TodoID)
+	if err != nil {
+		logger.Error().Err(err).Msg("todo validation failed")
+		return nil, err
+	}
+
+	mentions, err := s.resolveMentions(ctx, workspaceID, userID, content)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only users the edit newly mentions are notified
+	commentItem, added, err := s.commentRepo.UpdateComment(ctx.Request().Context(), workspaceID, userID, commentID,
+		content, mentions)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to update comment")
 		return nil, err
@@ -94,20 +168,26 @@ func (s *CommentService) UpdateComment(ctx echo.Context, userID string, commentI
 		Str("comment_id", commentItem.ID.String()).
 		Msg("Comment updated successfully")
 
+	s.notifyMentions(ctx, commentItem, todoItem, added)
+
 	return commentItem, nil
 }
 
-func (s *CommentService) DeleteComment(ctx echo.Context, userID string, commentID uuid.UUID) error {
+func (s *CommentService) DeleteComment(ctx echo.Context, workspaceID uuid.UUID, userID string, commentID uuid.UUID) error {
 	logger := middleware.GetLogger(ctx)
 
-	// Validate comment exists and belongs to user
-	_, err := s.commentRepo.GetCommentByID(ctx.Request().Context(), userID, commentID)
+	// Validate comment exists in workspace
+	existing, err := s.commentRepo.GetCommentByID(ctx.Request().Context(), workspaceID, commentID)
 	if err != nil {
 		logger.Error().Err(err).Msg("comment validation failed")
 		return err
 	}
 
-	err = s.commentRepo.DeleteComment(ctx.Request().Context(), userID, commentID)
+	if err := authorize(ctx, authz.CommentDelete, authz.Resource{OwnerID: existing.UserID}); err != nil {
+		return err
+	}
+
+	err = s.commentRepo.DeleteComment(ctx.Request().Context(), workspaceID, commentID)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to delete comment")
 		return err
@@ -122,3 +202,227 @@ func (s *CommentService) DeleteComment(ctx echo.Context, userID string, commentI
 
 	return nil
 }
+
+// GetThread lists the comment with all the replies below it
+func (s *CommentService) GetThread(ctx echo.Context, workspaceID uuid.UUID, userID string,
+	commentID uuid.UUID, render bool,
+) ([]comment.ThreadComment, error) {
+	logger := middleware.GetLogger(ctx)
+
+	thread, err := s.commentRepo.GetThread(ctx.Request().Context(), workspaceID, userID, commentID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch comment thread")
+		return nil, err
+	}
+
+	if render {
+		for i := range thread {
+			thread[i].RenderContent()
+		}
+	}
+
+	return thread, nil
+}
+
+// AddReaction reacts to the comment with emoji and returns its reactions
+func (s *CommentService) AddReaction(ctx echo.Context, workspaceID uuid.UUID, userID string,
+	commentID uuid.UUID, emoji string,
+) ([]comment.ReactionSummary, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.CommentReact, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	commentItem, err := s.commentRepo.GetCommentByID(ctx.Request().Context(), workspaceID, commentID)
+	if err != nil {
+		logger.Error().Err(err).Msg("comment validation failed")
+		return nil, err
+	}
+
+	if err := s.commentRepo.AddReaction(ctx.Request().Context(), commentItem, userID, emoji); err != nil {
+		logger.Error().Err(err).Msg("failed to add reaction")
+		return nil, err
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "comment_reaction_added").
+		Str("comment_id", commentID.String()).
+		Str("emoji", emoji).
+		Msg("Comment reaction added")
+
+	return s.getReactions(ctx, commentID, userID)
+}
+
+// RemoveReaction takes back the user's reaction and returns the comment's
+// remaining reactions
+func (s *CommentService) RemoveReaction(ctx echo.Context, workspaceID uuid.UUID, userID string,
+	commentID uuid.UUID, emoji string,
+) ([]comment.ReactionSummary, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.CommentReact, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	// Validate comment exists in workspace
+	if _, err := s.commentRepo.GetCommentByID(ctx.Request().Context(), workspaceID, commentID); err != nil {
+		logger.Error().Err(err).Msg("comment validation failed")
+		return nil, err
+	}
+
+	if err := s.commentRepo.RemoveReaction(ctx.Request().Context(), commentID, userID, emoji); err != nil {
+		logger.Error().Err(err).Msg("failed to remove reaction")
+		return nil, err
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "comment_reaction_removed").
+		Str("comment_id", commentID.String()).
+		Str("emoji", emoji).
+		Msg("Comment reaction removed")
+
+	return s.getReactions(ctx, commentID, userID)
+}
+
+func (s *CommentService) getReactions(ctx echo.Context, commentID uuid.UUID,
+	userID string,
+) ([]comment.ReactionSummary, error) {
+	reactions, err := s.commentRepo.GetReactions(ctx.Request().Context(), commentID, userID)
+	if err != nil {
+		middleware.GetLogger(ctx).Error().Err(err).Msg("failed to fetch reactions")
+		return nil, err
+	}
+
+	return reactions, nil
+}
+
+// GetMentions lists the comments in the workspace that mention the user
+func (s *CommentService) GetMentions(ctx echo.Context, workspaceID uuid.UUID, userID string,
+	query *comment.GetMentionsQuery,
+) (*model.PaginatedResponse[comment.Comment], error) {
+	logger := middleware.GetLogger(ctx)
+
+	comments, err := s.commentRepo.GetMentions(ctx.Request().Context(), workspaceID, userID, query)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch mentions")
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+// resolveMentions returns the users content mentions, other than its author.
+// Every one of them must be a member of the workspace, which is what gives
+// them access to the todo.
+func (s *CommentService) resolveMentions(ctx echo.Context, workspaceID uuid.UUID, authorID string,
+	content string,
+) ([]string, error) {
+	mentions := slices.DeleteFunc(comment.ParseMentions(content), func(userID string) bool {
+		return userID == authorID
+	})
+	if len(mentions) == 0 {
+		return mentions, nil
+	}
+
+	if len(mentions) > comment.MaxMentions {
+		code := "TOO_MANY_MENTIONS"
+		return nil, errs.NewBadRequestError(
+			fmt.Sprintf("A comment can mention at most %d users", comment.MaxMentions), false, &code, nil, nil)
+	}
+
+	memberIDs, err := s.workspaceRepo.GetMemberIDs(ctx.Request().Context(), workspaceID, mentions)
+	if err != nil {
+		middleware.GetLogger(ctx).Error().Err(err).Msg("failed to resolve mentioned users")
+		return nil, err
+	}
+
+	var unknown []string
+	for _, userID := range mentions {
+		if !slices.Contains(memberIDs, userID) {
+			unknown = append(unknown, userID)
+		}
+	}
+	if len(unknown) > 0 {
+		code := "INVALID_MENTION"
+		return nil, errs.NewBadRequestError(
+			"Mentioned users must be members of this workspace: "+strings.Join(unknown, ", "), false, &code, nil, nil)
+	}
+
+	return mentions, nil
+}
+
+// notifyMentions tells each mentioned user about the comment in the app, by
+// email and on their devices. The comment is already saved, so a
+// notification that can't be queued is only logged.
+func (s *CommentService) notifyMentions(ctx echo.Context, commentItem *comment.Comment, todoItem *todo.Todo,
+	userIDs []string,
+) {
+	if len(userIDs) == 0 {
+		return
+	}
+
+	logger := middleware.GetLogger(ctx)
+	reqCtx := ctx.Request().Context()
+	excerpt := mentionExcerpt(commentItem.Content)
+
+	for _, userID := range userIDs {
+		event := &comment.MentionedEvent{
+			Type:        comment.EventMentioned,
+			CreatedAt:   time.Now(),
+			WorkspaceID: commentItem.WorkspaceID,
+			Data:        commentItem,
+		}
+		if err := s.server.Realtime.SendToUser(reqCtx, userID, &commentItem.WorkspaceID, event); err != nil {
+			logger.Warn().Err(err).Str("user_id", userID).Msg("failed to send realtime mention")
+		}
+
+		err := job.Enqueue(reqCtx, s.server.Job.Client, &job.MentionEmailTask{
+			UserID:    userID,
+			AuthorID:  commentItem.UserID,
+			CommentID: commentItem.ID,
+			TodoID:    todoItem.ID,
+			TodoTitle: todoItem.Title,
+			Excerpt:   excerpt,
+		})
+		if err != nil {
+			logger.Error().Err(err).Str("user_id", userID).Msg("failed to enqueue mention email")
+		}
+
+		err = job.Enqueue(reqCtx, s.server.Job.Client, &job.PushNotificationTask{
+			UserID: userID,
+			Message: push.Message{
+				Title: "Mentioned on " + todoItem.Title,
+				Body:  excerpt,
+				URL:   "/todos?id=" + todoItem.ID.String(),
+				Tag:   "comment-" + commentItem.ID.String(),
+			},
+		})
+		if err != nil {
+			logger.Error().Err(err).Str("user_id", userID).Msg("failed to enqueue mention push notification")
+		}
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "comment_mentions_notified").
+		Str("comment_id", commentItem.ID.String()).
+		Int("mentions", len(userIDs)).
+		Msg("Mentioned users notified")
+
+	s.server.Metrics.Count(metrics.MentionsNotified, len(userIDs))
+}
+
+// mentionExcerpt shortens content to fit a notification, on a rune boundary
+func mentionExcerpt(content string) string {
+	runes := []rune(strings.TrimSpace(content))
+	if len(runes) <= mentionExcerptLength {
+		return string(runes)
+	}
+	return strings.TrimSpace(string(runes[:mentionExcerptLength])) + "…"
+}
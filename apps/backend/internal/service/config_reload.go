@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/mabhi256/tasker/internal/config"
+	"github.com/mabhi256/tasker/internal/lib/readonly"
+	"github.com/mabhi256/tasker/internal/logging"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// ConfigReloader reloads the config from its sources on SIGHUP and when the
+// config file changes. The reloadable values, the log level, rate limit,
+// feature flags and read-only mode, take effect right away. Changes to the
+// others are logged and take effect on the next restart.
+type ConfigReloader struct {
+	server  *server.Server
+	current *config.Config
+	modTime time.Time
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewConfigReloader(server *server.Server) *ConfigReloader {
+	return &ConfigReloader{
+		server:  server,
+		current: server.Config,
+	}
+}
+
+func (r *ConfigReloader) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+	r.modTime = r.fileModTime()
+
+	go r.run(ctx)
+
+	r.server.Logger.Info().
+		Str("file", r.current.Sources.File).
+		Dur("watch_interval", r.current.Reload.WatchInterval).
+		Msg("Starting config reloader")
+}
+
+func (r *ConfigReloader) Stop() {
+	if r.cancel == nil {
+		return
+	}
+
+	r.server.Logger.Info().Msg("Stopping config reloader")
+	r.cancel()
+	<-r.done
+}
+
+func (r *ConfigReloader) run(ctx context.Context) {
+	defer close(r.done)
+
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+
+	ticker := time.NewTicker(r.current.Reload.WatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangups:
+			r.Reload()
+		case <-ticker.C:
+			// Only a config file is watched, as the environment of a
+			// running process doesn't change
+			if modTime := r.fileModTime(); !modTime.Equal(r.modTime) {
+				r.modTime = modTime
+				r.Reload()
+			}
+		}
+	}
+}
+
+// Reload loads the config again and applies the reloadable values. A config
+// that fails to load or validate is ignored, keeping the current values.
+func (r *ConfigReloader) Reload() {
+	updated, err := config.Load(r.current.Sources)
+	if err != nil {
+		r.server.Logger.Error().Err(err).Msg("failed to reload config, keeping the current config")
+		return
+	}
+
+	changed := config.Changed(r.current, updated)
+	if len(changed) == 0 {
+		r.server.Logger.Info().Msg("Config reloaded without changes")
+		return
+	}
+
+	reloaded := slices.DeleteFunc(slices.Clone(changed), func(key string) bool {
+		return !config.IsReloadable(key)
+	})
+	restart := slices.DeleteFunc(changed, config.IsReloadable)
+
+	r.server.Runtime.Update(updated)
+	logging.SetLevel(r.server.Runtime.Values().LogLevel)
+	r.server.ReadOnly.Set(readonly.SourceConfig, updated.ReadOnly.Enabled, updated.ReadOnly.Reason)
+	r.current = updated
+
+	if len(restart) > 0 {
+		r.server.Logger.Warn().
+			Strs("keys", restart).
+			Msg("config changes take effect on the next restart")
+	}
+	r.server.Logger.Info().
+		Strs("keys", reloaded).
+		Msg("Config reloaded")
+}
+
+// fileModTime is when the config file was last changed, and zero when there
+// is none or it can't be read
+func (r *ConfigReloader) fileModTime() time.Time {
+	if r.current.Sources.File == "" {
+		return time.Time{}
+	}
+
+	info, err := os.Stat(r.current.Sources.File)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
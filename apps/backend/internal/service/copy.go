@@ -0,0 +1,369 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/authz"
+	"github.com/mabhi256/tasker/internal/database"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/lib/aws"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/quota"
+	"github.com/mabhi256/tasker/internal/model/todo"
+	"github.com/mabhi256/tasker/internal/repository"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// CopyService copies todos between workspaces, for users who keep the same
+// todos in several, such as consultants working across client workspaces.
+//
+// Workspace data is only visible within its workspace's scope, so the
+// original is read in the request's workspace and the copy is written in the
+// target's.
+type CopyService struct {
+	server        *server.Server
+	todoRepo      *repository.TodoRepository
+	categoryRepo  *repository.CategoryRepository
+	workspaceRepo *repository.WorkspaceRepository
+	quotaService  *QuotaService
+	awsClient     *aws.AWS
+}
+
+func NewCopyService(server *server.Server, todoRepo *repository.TodoRepository,
+	categoryRepo *repository.CategoryRepository, workspaceRepo *repository.WorkspaceRepository,
+	quotaService *QuotaService, awsClient *aws.AWS,
+) *CopyService {
+	return &CopyService{
+		server:        server,
+		todoRepo:      todoRepo,
+		categoryRepo:  categoryRepo,
+		workspaceRepo: workspaceRepo,
+		quotaService:  quotaService,
+		awsClient:     awsClient,
+	}
+}
+
+// CopyTodo copies the todo, with its subtasks and their attachments, into
+// the target workspace. The copies start as drafts. Their owners and
+// uploaders are kept when they are members of the target and replaced by the
+// copier otherwise, and their categories are the target's categories of the
+// same name, if it has them. The copied attachments count against the
+// target's storage quotas like uploads.
+func (s *CopyService) CopyTodo(ctx echo.Context, workspaceID uuid.UUID, userID string,
+	payload *todo.CopyTodoPayload,
+) (*todo.CopiedTodo, error) {
+	logger := middleware.GetLogger(ctx)
+	reqCtx := ctx.Request().Context()
+	targetID := payload.TargetWorkspaceID
+
+	if err := authorize(ctx, authz.TodoCopy, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	if targetID == workspaceID {
+		code := "SAME_WORKSPACE"
+		return nil, errs.NewUnprocessableError("todo can only be copied to another workspace", false, &code, nil, nil)
+	}
+
+	includeAttachments := payload.IncludeAttachments == nil || *payload.IncludeAttachments
+
+	// The copier has to be allowed to create the copy in the target too
+	member, err := s.workspaceRepo.GetMembership(reqCtx, targetID, userID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch target workspace membership")
+		return nil, err
+	}
+	if err := authorizeMember(ctx, member, authz.TodoCreate, authz.Resource{}); err != nil {
+		return nil, err
+	}
+	if includeAttachments {
+		if err := authorizeMember(ctx, member, authz.TodoAttach, authz.Resource{}); err != nil {
+			return nil, err
+		}
+	}
+
+	source, err := s.todoRepo.GetTodoByID(reqCtx, workspaceID, payload.ID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch todo for copy")
+		return nil, err
+	}
+
+	attachments := map[uuid.UUID][]todo.TodoAttachment{}
+	if includeAttachments {
+		attachments[source.ID] = withoutInfected(source.Attachments)
+		for _, child := range source.Children {
+			childAttachments, err := s.todoRepo.GetTodoAttachments(reqCtx, child.ID)
+			if err != nil {
+				logger.Error().Err(err).Msg("failed to fetch subtask attachments for copy")
+				return nil, err
+			}
+			attachments[child.ID] = withoutInfected(childAttachments)
+		}
+	}
+
+	users, remappedUsers, err := s.remapUsers(reqCtx, targetID, userID, source, attachments)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to remap users for copy")
+		return nil, err
+	}
+
+	targetCtx := database.WithScope(reqCtx, database.Scope{WorkspaceID: targetID, UserID: userID})
+
+	categories, err := s.mapCategories(reqCtx, targetCtx, workspaceID, targetID, source)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to map categories for copy")
+		return nil, err
+	}
+
+	sizes, storage, err := s.checkStorage(ctx, targetID, userID, users, attachments)
+	if err != nil {
+		return nil, err
+	}
+
+	// Files are copied first, so a copy that fails part way leaves no rows
+	// pointing at missing objects
+	bucket := s.server.Config.AWS.UploadBucket
+	keys := map[uuid.UUID]string{}
+	deleteCopiedFiles := func() {
+		cleanupCtx := context.WithoutCancel(reqCtx)
+		for _, key := range keys {
+			if err := s.awsClient.S3.DeleteObject(cleanupCtx, bucket, key); err != nil {
+				logger.Error().Err(err).Str("object_key", key).Msg("failed to delete copied attachment")
+			}
+		}
+	}
+	for _, todoAttachments := range attachments {
+		for _, attachment := range todoAttachments {
+			key := fmt.Sprintf("todos/attachments/%s/%s", uuid.New(), attachment.Name)
+			if err := s.awsClient.S3.CopyObject(reqCtx, bucket, attachment.DownloadKey, key); err != nil {
+				logger.Error().Err(err).Str("attachment_id", attachment.ID.String()).Msg("failed to copy attachment")
+				deleteCopiedFiles()
+
+				if errors.Is(err, aws.ErrObjectNotFound) {
+					code := "ATTACHMENT_MISSING"
+					return nil, errs.NewUnprocessableError(
+						fmt.Sprintf("attachment %s is missing from storage", attachment.Name), false, &code, nil, nil)
+				}
+				return nil, err
+			}
+			keys[attachment.ID] = key
+		}
+	}
+
+	var copyID uuid.UUID
+	err = s.server.DB.UnitOfWork(targetCtx, func(txCtx context.Context) error {
+		created, err := s.copyOne(txCtx, targetID, &source.Todo, nil, users, categories, attachments, keys)
+		if err != nil {
+			return err
+		}
+		copyID = created.ID
+
+		for _, child := range source.Children {
+			if _, err := s.copyOne(txCtx, targetID, &child, &copyID, users, categories, attachments, keys); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to create todo copy")
+		deleteCopiedFiles()
+		return nil, err
+	}
+
+	for uploader, before := range storage {
+		s.quotaService.WarnStorage(ctx, targetID, uploader, before, sizes[uploader])
+	}
+
+	copied, err := s.todoRepo.GetTodoByID(targetCtx, targetID, copyID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch copied todo")
+		return nil, err
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "todo_copied").
+		Str("todo_id", source.ID.String()).
+		Str("copy_id", copied.ID.String()).
+		Str("target_workspace_id", targetID.String()).
+		Int("subtasks", len(source.Children)).
+		Int("attachments", len(keys)).
+		Int("remapped_users", len(remappedUsers)).
+		Msg("Todo copied to another workspace successfully")
+
+	return &todo.CopiedTodo{
+		Todo:          copied,
+		RemappedUsers: remappedUsers,
+	}, nil
+}
+
+// copyOne creates a copy of item under parentID in the target workspace,
+// along with its attachments, whose files were copied to keys
+func (s *CopyService) copyOne(ctx context.Context, targetID uuid.UUID, item *todo.Todo, parentID *uuid.UUID,
+	users map[string]string, categories map[uuid.UUID]uuid.UUID, attachments map[uuid.UUID][]todo.TodoAttachment,
+	keys map[uuid.UUID]string,
+) (*todo.Todo, error) {
+	var categoryID *uuid.UUID
+	if item.CategoryID != nil {
+		if id, ok := categories[*item.CategoryID]; ok {
+			categoryID = &id
+		}
+	}
+
+	created, err := s.todoRepo.CreateTodo(ctx, targetID, users[item.UserID], &todo.CreateTodoPayload{
+		Title:           item.Title,
+		Description:     item.Description,
+		Priority:        &item.Priority,
+		DueDate:         item.DueDate,
+		ParentTodoID:    parentID,
+		CategoryID:      categoryID,
+		Metadata:        item.Metadata,
+		ReminderMinutes: item.ReminderMinutes,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, attachment := range attachments[item.ID] {
+		_, err := s.todoRepo.CopyTodoAttachment(ctx, created.ID, users[attachment.UploadedBy],
+			keys[attachment.ID], &attachment)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return created, nil
+}
+
+// checkStorage refuses a copy whose attachments would take the target
+// workspace, or an uploader of the copies, past its storage quota. The
+// copier is checked for every copied byte, since the copy is theirs to make,
+// and any other uploader the copies keep for their share. It returns each
+// uploader's share and their storage used before the copy, for WarnStorage.
+func (s *CopyService) checkStorage(ctx echo.Context, targetID uuid.UUID, userID string, users map[string]string,
+	attachments map[uuid.UUID][]todo.TodoAttachment,
+) (map[string]int64, map[string]*quota.StorageUsage, error) {
+	sizes := map[string]int64{}
+	var total int64
+	for _, todoAttachments := range attachments {
+		for _, attachment := range todoAttachments {
+			if attachment.FileSize == nil {
+				continue
+			}
+			sizes[users[attachment.UploadedBy]] += *attachment.FileSize
+			total += *attachment.FileSize
+		}
+	}
+	if total == 0 {
+		return nil, nil, nil
+	}
+
+	copierBefore, err := s.quotaService.CheckStorage(ctx, targetID, userID, total)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	storage := make(map[string]*quota.StorageUsage, len(sizes))
+	for uploader, size := range sizes {
+		if uploader == userID {
+			storage[uploader] = copierBefore
+			continue
+		}
+
+		before, err := s.quotaService.CheckStorage(ctx, targetID, uploader, size)
+		if err != nil {
+			return nil, nil, err
+		}
+		storage[uploader] = before
+	}
+
+	return sizes, storage, nil
+}
+
+// remapUsers maps the owners and uploaders of the todo being copied to who
+// they are in the target workspace: themselves when they are members of it,
+// and the copier otherwise. It also returns the users who were replaced.
+func (s *CopyService) remapUsers(ctx context.Context, targetID uuid.UUID, userID string, source *todo.PopulatedTodo,
+	attachments map[uuid.UUID][]todo.TodoAttachment,
+) (map[string]string, []string, error) {
+	userIDs := []string{source.UserID}
+	for _, child := range source.Children {
+		userIDs = append(userIDs, child.UserID)
+	}
+	for _, todoAttachments := range attachments {
+		for _, attachment := range todoAttachments {
+			userIDs = append(userIDs, attachment.UploadedBy)
+		}
+	}
+	slices.Sort(userIDs)
+	userIDs = slices.Compact(userIDs)
+
+	memberIDs, err := s.workspaceRepo.GetMemberIDs(ctx, targetID, userIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	users := make(map[string]string, len(userIDs))
+	remapped := []string{}
+	for _, id := range userIDs {
+		if slices.Contains(memberIDs, id) {
+			users[id] = id
+			continue
+		}
+		users[id] = userID
+		remapped = append(remapped, id)
+	}
+
+	return users, remapped, nil
+}
+
+// mapCategories maps the categories of the todo being copied to the target
+// workspace's categories of the same name. Categories the target has no
+// namesake for are left out, and their todos are copied uncategorized.
+func (s *CopyService) mapCategories(sourceCtx context.Context, targetCtx context.Context, workspaceID uuid.UUID,
+	targetID uuid.UUID, source *todo.PopulatedTodo,
+) (map[uuid.UUID]uuid.UUID, error) {
+	var ids []uuid.UUID
+	for _, item := range append([]todo.Todo{source.Todo}, source.Children...) {
+		if item.CategoryID != nil && !slices.Contains(ids, *item.CategoryID) {
+			ids = append(ids, *item.CategoryID)
+		}
+	}
+
+	sourceCategories, err := s.categoryRepo.GetCategoriesByIDs(sourceCtx, workspaceID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	categories := make(map[uuid.UUID]uuid.UUID, len(sourceCategories))
+	for _, c := range sourceCategories {
+		match, err := s.categoryRepo.FindCategoryByName(targetCtx, targetID, c.Name)
+		if err != nil {
+			return nil, err
+		}
+		if match != nil {
+			categories[c.ID] = match.ID
+		}
+	}
+
+	return categories, nil
+}
+
+// withoutInfected leaves out quarantined attachments, whose files mustn't
+// leave the quarantine
+func withoutInfected(attachments []todo.TodoAttachment) []todo.TodoAttachment {
+	kept := make([]todo.TodoAttachment, 0, len(attachments))
+	for _, attachment := range attachments {
+		if attachment.ScanStatus != todo.ScanStatusInfected {
+			kept = append(kept, attachment)
+		}
+	}
+	return kept
+}
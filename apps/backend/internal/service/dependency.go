@@ -0,0 +1,185 @@
+package service
+
+import (
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/authz"
+	"github.com/mabhi256/tasker/internal/database"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/lib/depgraph"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/todo"
+	"github.com/mabhi256/tasker/internal/repository"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// DependencyService manages which todos block which. Whether a todo is
+// blocked isn't stored, it follows from the status of the todos blocking it.
+type DependencyService struct {
+	server   *server.Server
+	todoRepo *repository.TodoRepository
+}
+
+func NewDependencyService(server *server.Server, todoRepo *repository.TodoRepository) *DependencyService {
+	return &DependencyService{
+		server:   server,
+		todoRepo: todoRepo,
+	}
+}
+
+func (s *DependencyService) GetDependencies(ctx echo.Context, workspaceID uuid.UUID,
+	todoID uuid.UUID,
+) (*todo.Dependencies, error) {
+	logger := middleware.GetLogger(ctx)
+
+	// Validate todo exists in workspace
+	if _, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), workspaceID, todoID); err != nil {
+		logger.Error().Err(err).Msg("todo validation failed")
+		return nil, err
+	}
+
+	dependencies, err := s.todoRepo.GetDependencies(ctx.Request().Context(), workspaceID, todoID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch dependencies")
+		return nil, err
+	}
+
+	return dependencies, nil
+}
+
+// AddDependency blocks the todo by another todo and returns its dependencies.
+// A dependency that would make the todos wait on each other is rejected.
+func (s *DependencyService) AddDependency(ctx echo.Context, workspaceID uuid.UUID,
+	payload *todo.AddTodoDependencyPayload,
+) (*todo.Dependencies, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.TodoLink, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	// 422 - Invalid request data (logical impossibility)
+	if payload.TodoID == payload.BlockedByID {
+		err := errs.NewUnprocessableError("Todo cannot be blocked by itself", false, nil, nil, nil)
+		logger.Warn().Msg("todo cannot be blocked by itself")
+		return nil, err
+	}
+
+	for _, id := range []uuid.UUID{payload.TodoID, payload.BlockedByID} {
+		if _, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), workspaceID, id); err != nil {
+			logger.Error().Err(err).Msg("todo validation failed")
+			return nil, err
+		}
+	}
+
+	// The graph is read from the primary, as an edge added moments ago may
+	// not have reached the replica yet
+	edges, err := s.todoRepo.GetDependencyEdges(database.WithPrimaryReads(ctx.Request().Context()), workspaceID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch dependency graph")
+		return nil, err
+	}
+
+	graph := depgraph.New[uuid.UUID]()
+	for _, edge := range edges {
+		graph.Add(edge.TodoID, edge.BlockedByID)
+	}
+
+	// 409 - Conflict with the existing dependencies
+	if graph.DependsOn(payload.BlockedByID, payload.TodoID) {
+		code := "DEPENDENCY_CYCLE"
+		err := errs.NewConflictError("Dependency would create a cycle", false, &code, nil, nil)
+		logger.Warn().
+			Str("todo_id", payload.TodoID.String()).
+			Str("blocked_by_id", payload.BlockedByID.String()).
+			Msg("dependency would create a cycle")
+		return nil, err
+	}
+
+	if err := s.todoRepo.AddDependency(ctx.Request().Context(), workspaceID, payload.TodoID, payload.BlockedByID); err != nil {
+		logger.Error().Err(err).Msg("failed to add dependency")
+		return nil, err
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "todo_dependency_added").
+		Str("todo_id", payload.TodoID.String()).
+		Str("blocked_by_id", payload.BlockedByID.String()).
+		Msg("Todo dependency added")
+
+	return s.GetDependencies(ctx, workspaceID, payload.TodoID)
+}
+
+// RemoveDependency unblocks the todo from another todo and returns its
+// remaining dependencies
+func (s *DependencyService) RemoveDependency(ctx echo.Context, workspaceID uuid.UUID,
+	payload *todo.RemoveTodoDependencyPayload,
+) (*todo.Dependencies, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.TodoLink, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	if err := s.todoRepo.RemoveDependency(ctx.Request().Context(), workspaceID, payload.TodoID, payload.BlockedByID); err != nil {
+		logger.Error().Err(err).Msg("failed to remove dependency")
+		return nil, err
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "todo_dependency_removed").
+		Str("todo_id", payload.TodoID.String()).
+		Str("blocked_by_id", payload.BlockedByID.String()).
+		Msg("Todo dependency removed")
+
+	return s.GetDependencies(ctx, workspaceID, payload.TodoID)
+}
+
+// GetWorkList orders the todos that aren't done yet so each comes after the
+// todos blocking it, and the most urgent come first otherwise. The todos at
+// the top that aren't blocked can be worked on right away.
+func (s *DependencyService) GetWorkList(ctx echo.Context, workspaceID uuid.UUID,
+	query *todo.GetWorkListQuery,
+) ([]todo.DependentTodo, error) {
+	logger := middleware.GetLogger(ctx)
+
+	todos, err := s.todoRepo.GetOpenTodos(ctx.Request().Context(), workspaceID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch open todos")
+		return nil, err
+	}
+
+	edges, err := s.todoRepo.GetDependencyEdges(ctx.Request().Context(), workspaceID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch dependency graph")
+		return nil, err
+	}
+
+	graph := depgraph.New[uuid.UUID]()
+	for _, edge := range edges {
+		graph.Add(edge.TodoID, edge.BlockedByID)
+	}
+
+	ids := make([]uuid.UUID, len(todos))
+	byID := make(map[uuid.UUID]todo.DependentTodo, len(todos))
+	for i, t := range todos {
+		ids[i] = t.ID
+		byID[t.ID] = t
+	}
+
+	sorted := graph.Sort(ids)
+	if len(sorted) > *query.Limit {
+		sorted = sorted[:*query.Limit]
+	}
+
+	workList := make([]todo.DependentTodo, len(sorted))
+	for i, id := range sorted {
+		workList[i] = byID[id]
+	}
+
+	return workList, nil
+}
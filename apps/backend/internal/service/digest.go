@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/lib/email"
+	"github.com/mabhi256/tasker/internal/lib/metrics"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/digest"
+	"github.com/mabhi256/tasker/internal/model/todo"
+	"github.com/mabhi256/tasker/internal/repository"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// maxDigestItems caps each list in a digest so a backlog of overdue todos
+// doesn't produce an unreadable email
+const maxDigestItems = 20
+
+// DigestService manages users' digest subscriptions and sends the digests.
+// A digest covers all todos the user created, across workspaces.
+type DigestService struct {
+	server          *server.Server
+	digestRepo      *repository.DigestRepository
+	authService     *AuthService
+	trackingService *EmailTrackingService
+}
+
+func NewDigestService(server *server.Server, digestRepo *repository.DigestRepository,
+	authService *AuthService, trackingService *EmailTrackingService,
+) *DigestService {
+	return &DigestService{
+		server:          server,
+		digestRepo:      digestRepo,
+		authService:     authService,
+		trackingService: trackingService,
+	}
+}
+
+func (s *DigestService) GetSubscription(ctx echo.Context, userID string) (*digest.Subscription, error) {
+	logger := middleware.GetLogger(ctx)
+
+	subscription, err := s.digestRepo.GetSubscription(ctx.Request().Context(), userID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch digest subscription")
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+func (s *DigestService) PutSubscription(ctx echo.Context, userID string,
+	payload *digest.PutSubscriptionPayload,
+) (*digest.Subscription, error) {
+	logger := middleware.GetLogger(ctx)
+
+	existing, err := s.digestRepo.GetSubscription(ctx.Request().Context(), userID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		logger.Error().Err(err).Msg("failed to fetch digest subscription for update")
+		return nil, err
+	}
+
+	// Validated by the payload
+	loc, _ := time.LoadLocation(payload.Timezone)
+	nextRunAt := digest.NextRun(payload.Frequency, payload.Hour, payload.Weekday, loc, time.Now())
+
+	// Keep the pending slot unless the timing changed or the subscription is
+	// being re-enabled, in which case a missed slot shouldn't fire immediately
+	if existing != nil && existing.Enabled && sameDigestTiming(existing, payload) {
+		nextRunAt = existing.NextRunAt
+	}
+
+	subscription, err := s.digestRepo.PutSubscription(ctx.Request().Context(), userID, payload, nextRunAt)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to save digest subscription")
+		return nil, err
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "digest_subscription_updated").
+		Str("subscription_id", subscription.ID.String()).
+		Str("frequency", string(subscription.Frequency)).
+		Str("timezone", subscription.Timezone).
+		Bool("enabled", subscription.Enabled).
+		Msg("Digest subscription saved successfully")
+
+	return subscription, nil
+}
+
+func sameDigestTiming(existing *digest.Subscription, payload *digest.PutSubscriptionPayload) bool {
+	if existing.Frequency != payload.Frequency || existing.Hour != payload.Hour ||
+		existing.Timezone != payload.Timezone {
+		return false
+	}
+	if existing.Weekday == nil || payload.Weekday == nil {
+		return existing.Weekday == nil && payload.Weekday == nil
+	}
+	return *existing.Weekday == *payload.Weekday
+}
+
+// SendDigest implements job.DigestSenderInterface
+func (s *DigestService) SendDigest(ctx context.Context, userID string, periodEnd time.Time) error {
+	subscription, err := s.digestRepo.GetSubscription(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			s.server.Logger.Info().Str("user_id", userID).Msg("digest subscription was deleted, skipping")
+			return nil
+		}
+		return err
+	}
+	if !subscription.Enabled {
+		s.server.Logger.Info().Str("user_id", userID).Msg("digest subscription is disabled, skipping")
+		return nil
+	}
+
+	// Daily digests nobody opens back off to weekly, covering the week,
+	frequency, err := s.trackingService.DigestFrequency(ctx, subscription)
+	if err != nil {
+		return err
+	}
+	// with an hour's slack for a send that ran late
+	weekStart := digest.PeriodStart(digest.FrequencyWeekly, periodEnd).Add(time.Hour)
+	if frequency != subscription.Frequency && subscription.LastSentAt != nil &&
+		subscription.LastSentAt.After(weekStart) {
+		s.server.Logger.Info().Str("user_id", userID).Msg("daily digests are unopened, holding until a week passed")
+		s.server.Metrics.Inc(metrics.DigestsHeld)
+		return nil
+	}
+
+	// Days are the user's, so "due today" matches what they see
+	loc := subscription.Location()
+	periodEnd = periodEnd.In(loc)
+	periodStart := digest.PeriodStart(frequency, periodEnd)
+	dayStart := time.Date(periodEnd.Year(), periodEnd.Month(), periodEnd.Day(), 0, 0, 0, 0, loc)
+	dayEnd := dayStart.AddDate(0, 0, 1)
+
+	todos, err := s.digestRepo.GetTodos(ctx, userID, dayStart, dayEnd, periodStart, maxDigestItems)
+	if err != nil {
+		return err
+	}
+
+	if todos.Empty() {
+		s.server.Logger.Info().Str("user_id", userID).Msg("nothing to report in digest, skipping")
+		return nil
+	}
+
+	to, err := s.authService.GetUserEmail(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	dead, err := s.trackingService.AddressDead(ctx, to)
+	if err != nil {
+		return err
+	}
+	if dead {
+		s.server.Logger.Info().Str("user_id", userID).Msg("email address looks dead, skipping digest")
+		s.server.Metrics.Inc(metrics.DigestsHeld)
+		return nil
+	}
+
+	ctx = email.WithRecipient(ctx, userID)
+	err = s.server.Email.SendDigestEmail(ctx, to, string(frequency), periodStart, periodEnd,
+		digestItems(todos.Overdue, func(t *todo.Todo) string {
+			return "Due " + t.DueDate.In(loc).Format("Jan 2")
+		}),
+		digestItems(todos.DueToday, func(t *todo.Todo) string {
+			return "Due " + t.DueDate.In(loc).Format("3:04 PM")
+		}),
+		digestItems(todos.Completed, func(t *todo.Todo) string {
+			return "Completed " + t.CompletedAt.In(loc).Format("Jan 2")
+		}),
+	)
+	if err != nil {
+		return err
+	}
+
+	s.server.Metrics.Inc(metrics.DigestsSent)
+
+	return s.digestRepo.MarkSent(ctx, userID, time.Now())
+}
+
+func digestItems(todos []todo.Todo, when func(t *todo.Todo) string) []email.DigestItem {
+	items := make([]email.DigestItem, len(todos))
+	for i := range todos {
+		items[i] = email.DigestItem{
+			ID:    todos[i].ID.String(),
+			Title: todos[i].Title,
+			When:  when(&todos[i]),
+		}
+	}
+	return items
+}
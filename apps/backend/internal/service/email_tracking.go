@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/lib/email"
+	"github.com/mabhi256/tasker/internal/lib/metrics"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/digest"
+	"github.com/mabhi256/tasker/internal/model/emailtracking"
+	"github.com/mabhi256/tasker/internal/repository"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// trackingPixel is a transparent 1x1 GIF
+var trackingPixel = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+// EmailTrackingService tracks the opens and clicks of notification emails,
+// and uses them to send fewer digests to people who don't read them
+type EmailTrackingService struct {
+	server       *server.Server
+	trackingRepo *repository.EmailTrackingRepository
+	links        *email.TrackingLinks
+}
+
+func NewEmailTrackingService(server *server.Server, trackingRepo *repository.EmailTrackingRepository,
+) *EmailTrackingService {
+	cfg := server.Config.Email.Tracking
+	return &EmailTrackingService{
+		server:       server,
+		trackingRepo: trackingRepo,
+		links:        email.NewTrackingLinks(cfg.BaseURL, cfg.SigningKey),
+	}
+}
+
+// Track implements email.Tracker
+func (s *EmailTrackingService) Track(ctx context.Context, userID, address string, template email.Template,
+) (uuid.UUID, bool, error) {
+	if !s.server.Config.Email.Tracking.Enabled {
+		return uuid.Nil, false, nil
+	}
+	return s.trackingRepo.CreateMessage(ctx, userID, address, string(template))
+}
+
+// RecordOpen counts an open of the message and returns the pixel. The
+// pixel is served even when the open isn't counted, so mail clients don't
+// show a broken image.
+func (s *EmailTrackingService) RecordOpen(ctx echo.Context, payload *emailtracking.RecordOpenPayload) ([]byte, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if !s.links.VerifyOpen(payload.ID, payload.Signature) {
+		logger.Warn().Str("message_id", payload.ID.String()).Msg("ignoring email open with an invalid signature")
+		return trackingPixel, nil
+	}
+
+	if err := s.trackingRepo.RecordOpen(ctx.Request().Context(), payload.ID); err != nil {
+		logger.Error().Err(err).Str("message_id", payload.ID.String()).Msg("failed to record email open")
+		return trackingPixel, nil
+	}
+
+	s.server.Metrics.Inc(metrics.EmailsOpened)
+
+	return trackingPixel, nil
+}
+
+// RecordClick counts a click of the message's link and returns its target.
+// Only targets the link was signed for are returned, so the redirect can't
+// send people elsewhere.
+func (s *EmailTrackingService) RecordClick(ctx echo.Context, payload *emailtracking.RecordClickPayload) (string, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if !s.links.VerifyClick(payload.ID, payload.Target, payload.Signature) {
+		code := "INVALID_TRACKING_LINK"
+		return "", errs.NewBadRequestError("invalid tracking link", false, &code, nil, nil)
+	}
+
+	// A click that isn't counted still goes through
+	if err := s.trackingRepo.RecordClick(ctx.Request().Context(), payload.ID); err != nil {
+		logger.Error().Err(err).Str("message_id", payload.ID.String()).Msg("failed to record email click")
+		return payload.Target, nil
+	}
+
+	s.server.Metrics.Inc(metrics.EmailsClicked)
+
+	return payload.Target, nil
+}
+
+// DigestFrequency returns how often the subscription's digests are sent.
+// Daily digests drop to weekly once the user left enough of them in a row
+// unopened, until they open one again.
+func (s *EmailTrackingService) DigestFrequency(ctx context.Context, subscription *digest.Subscription,
+) (digest.Frequency, error) {
+	cfg := s.server.Config.Email.Tracking
+	if !cfg.Enabled || subscription.Frequency != digest.FrequencyDaily {
+		return subscription.Frequency, nil
+	}
+
+	unengaged, err := s.trackingRepo.CountUnengagedOfTemplate(ctx, subscription.UserID,
+		string(email.TemplateDigest), cfg.DigestBackoffAfter)
+	if err != nil {
+		return "", err
+	}
+	if unengaged >= cfg.DigestBackoffAfter {
+		return digest.FrequencyWeekly, nil
+	}
+
+	return subscription.Frequency, nil
+}
+
+// AddressDead reports whether enough emails in a row to address went
+// unopened that it looks abandoned
+func (s *EmailTrackingService) AddressDead(ctx context.Context, address string) (bool, error) {
+	cfg := s.server.Config.Email.Tracking
+	if !cfg.Enabled {
+		return false, nil
+	}
+
+	unengaged, err := s.trackingRepo.CountUnengagedToAddress(ctx, address, cfg.DeadAfter)
+	if err != nil {
+		return false, err
+	}
+
+	return unengaged >= cfg.DeadAfter, nil
+}
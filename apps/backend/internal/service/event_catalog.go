@@ -0,0 +1,43 @@
+package service
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/lib/jsonschema"
+	"github.com/mabhi256/tasker/internal/model/todo"
+	"github.com/mabhi256/tasker/internal/model/webhook"
+)
+
+// GetEventCatalog lists the event types webhooks can subscribe to, with the
+// schema and an example of each
+func (s *WebhookService) GetEventCatalog(ctx echo.Context) (*webhook.EventCatalog, error) {
+	return s.catalog, nil
+}
+
+// newEventCatalog describes the events in webhook.EventDefinitions. The
+// catalog only changes with the code, so it is built once.
+func newEventCatalog() *webhook.EventCatalog {
+	generator := jsonschema.NewGenerator().
+		Enum(todo.StatusDraft, todo.StatusActive, todo.StatusCompleted, todo.StatusArchived, todo.StatusScheduled).
+		Enum(todo.PriorityLow, todo.PriorityMedium, todo.PriorityHigh)
+
+	catalog := &webhook.EventCatalog{
+		Events: make([]webhook.CatalogEvent, 0, len(webhook.EventDefinitions)),
+	}
+
+	for _, definition := range webhook.EventDefinitions {
+		schema := generator.Generate(webhook.Event{})
+		schema.Schema = jsonschema.Draft
+		schema.Description = definition.Description
+		schema.Properties["type"] = &jsonschema.Schema{Type: "string", Const: definition.Type}
+		schema.Properties["data"] = generator.Generate(definition.Example)
+
+		catalog.Events = append(catalog.Events, webhook.CatalogEvent{
+			Type:        definition.Type,
+			Description: definition.Description,
+			Schema:      schema,
+			Example:     definition.ExampleEvent(),
+		})
+	}
+
+	return catalog
+}
@@ -0,0 +1,418 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/authz"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/lib/exporter"
+	"github.com/mabhi256/tasker/internal/lib/job"
+	"github.com/mabhi256/tasker/internal/lib/metrics"
+	"github.com/mabhi256/tasker/internal/lib/usage"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model"
+	"github.com/mabhi256/tasker/internal/model/export"
+	"github.com/mabhi256/tasker/internal/repository"
+	"github.com/mabhi256/tasker/internal/server"
+	"golang.org/x/crypto/ssh"
+)
+
+// maxExportErrorLength keeps stored errors and alert emails readable
+const maxExportErrorLength = 1000
+
+// ExportService manages scheduled exports of a workspace's todos to storage
+// owned by the workspace. Configuring exports requires the admin role since
+// schedules hold credentials and send all workspace data off-site.
+type ExportService struct {
+	server     *server.Server
+	exportRepo *repository.ExportRepository
+	todoRepo   *repository.TodoRepository
+	exporter   *exporter.Exporter
+}
+
+func NewExportService(server *server.Server, exportRepo *repository.ExportRepository,
+	todoRepo *repository.TodoRepository,
+) *ExportService {
+	return &ExportService{
+		server:     server,
+		exportRepo: exportRepo,
+		todoRepo:   todoRepo,
+		exporter:   exporter.NewExporter(server.Fetcher),
+	}
+}
+
+func (s *ExportService) CreateSchedule(ctx echo.Context, workspaceID uuid.UUID, userID string,
+	payload *export.CreateSchedulePayload,
+) (*export.Schedule, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.ExportManage, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkDestination(ctx.Request().Context(), payload.DestinationType, &payload.Destination); err != nil {
+		return nil, err
+	}
+
+	nextRunAt := export.NextRun(payload.Frequency, payload.HourUTC, payload.Weekday, time.Now())
+
+	schedule, err := s.exportRepo.CreateSchedule(ctx.Request().Context(), workspaceID, userID, payload, nextRunAt)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to create export schedule")
+		return nil, err
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "export_schedule_created").
+		Str("schedule_id", schedule.ID.String()).
+		Str("workspace_id", workspaceID.String()).
+		Str("destination_type", string(schedule.DestinationType)).
+		Str("frequency", string(schedule.Frequency)).
+		Msg("Export schedule created successfully")
+
+	return redactSchedule(schedule), nil
+}
+
+func (s *ExportService) GetSchedules(ctx echo.Context, workspaceID uuid.UUID) ([]export.Schedule, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.ExportManage, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	schedules, err := s.exportRepo.GetSchedules(ctx.Request().Context(), workspaceID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch export schedules")
+		return nil, err
+	}
+
+	for i := range schedules {
+		schedules[i].Destination = schedules[i].Destination.Redacted()
+	}
+
+	return schedules, nil
+}
+
+func (s *ExportService) GetScheduleByID(ctx echo.Context, workspaceID uuid.UUID, scheduleID uuid.UUID) (*export.Schedule, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.ExportManage, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	schedule, err := s.exportRepo.GetScheduleByID(ctx.Request().Context(), workspaceID, scheduleID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch export schedule by ID")
+		return nil, err
+	}
+
+	return redactSchedule(schedule), nil
+}
+
+func (s *ExportService) UpdateSchedule(ctx echo.Context, workspaceID uuid.UUID,
+	payload *export.UpdateSchedulePayload,
+) (*export.Schedule, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.ExportManage, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.exportRepo.GetScheduleByID(ctx.Request().Context(), workspaceID, payload.ID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch export schedule for update")
+		return nil, err
+	}
+
+	if payload.Destination != nil {
+		if err := s.checkDestination(ctx.Request().Context(), *payload.DestinationType, payload.Destination); err != nil {
+			return nil, err
+		}
+	}
+
+	frequency := existing.Frequency
+	if payload.Frequency != nil {
+		frequency = *payload.Frequency
+	}
+	hourUTC := existing.HourUTC
+	if payload.HourUTC != nil {
+		hourUTC = *payload.HourUTC
+	}
+
+	var weekday *int
+	if frequency == export.FrequencyWeekly {
+		weekday = existing.Weekday
+		if payload.Weekday != nil {
+			weekday = payload.Weekday
+		}
+		if weekday == nil {
+			return nil, errs.NewBadRequestError("weekday is required for weekly exports", false, nil, nil, nil)
+		}
+	} else if payload.Weekday != nil {
+		return nil, errs.NewBadRequestError("weekday is only allowed for weekly exports", false, nil, nil, nil)
+	}
+
+	// Keep the pending slot unless the timing changed or the schedule is being
+	// re-enabled, in which case a missed slot shouldn't fire immediately
+	nextRunAt := existing.NextRunAt
+	reenabled := payload.Enabled != nil && *payload.Enabled && !existing.Enabled
+	if payload.Frequency != nil || payload.HourUTC != nil || payload.Weekday != nil || reenabled {
+		nextRunAt = export.NextRun(frequency, hourUTC, weekday, time.Now())
+	}
+
+	schedule, err := s.exportRepo.UpdateSchedule(ctx.Request().Context(), workspaceID, payload, weekday, nextRunAt)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to update export schedule")
+		return nil, err
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "export_schedule_updated").
+		Str("schedule_id", schedule.ID.String()).
+		Str("workspace_id", workspaceID.String()).
+		Bool("destination_changed", payload.Destination != nil).
+		Msg("Export schedule updated successfully")
+
+	return redactSchedule(schedule), nil
+}
+
+func (s *ExportService) DeleteSchedule(ctx echo.Context, workspaceID uuid.UUID, scheduleID uuid.UUID) error {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.ExportManage, authz.Resource{}); err != nil {
+		return err
+	}
+
+	err := s.exportRepo.DeleteSchedule(ctx.Request().Context(), workspaceID, scheduleID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to delete export schedule")
+		return err
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "export_schedule_deleted").
+		Str("schedule_id", scheduleID.String()).
+		Str("workspace_id", workspaceID.String()).
+		Msg("Export schedule deleted successfully")
+
+	return nil
+}
+
+// TriggerRun queues an export right away, e.g. to test a new destination
+func (s *ExportService) TriggerRun(ctx echo.Context, workspaceID uuid.UUID, scheduleID uuid.UUID) (*export.Run, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.ExportManage, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.exportRepo.GetScheduleByID(ctx.Request().Context(), workspaceID, scheduleID); err != nil {
+		logger.Error().Err(err).Msg("failed to fetch export schedule for manual run")
+		return nil, err
+	}
+
+	run, err := s.exportRepo.CreateRun(ctx.Request().Context(), scheduleID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to create export run")
+		return nil, err
+	}
+
+	if err := job.Enqueue(ctx.Request().Context(), s.server.Job.Client, &job.ExportRunTask{RunID: run.ID}); err != nil {
+		logger.Error().Err(err).Str("run_id", run.ID.String()).Msg("failed to enqueue export run")
+		return nil, err
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "export_run_triggered").
+		Str("schedule_id", scheduleID.String()).
+		Str("run_id", run.ID.String()).
+		Msg("Export run triggered successfully")
+
+	return run, nil
+}
+
+func (s *ExportService) GetRuns(ctx echo.Context, workspaceID uuid.UUID,
+	query *export.GetRunsPayload,
+) (*model.PaginatedResponse[export.Run], error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.ExportManage, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.exportRepo.GetScheduleByID(ctx.Request().Context(), workspaceID, query.ID); err != nil {
+		logger.Error().Err(err).Msg("failed to fetch export schedule for runs")
+		return nil, err
+	}
+
+	runs, err := s.exportRepo.GetRuns(ctx.Request().Context(), workspaceID, query)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch export runs")
+		return nil, err
+	}
+
+	return runs, nil
+}
+
+// RunExport implements job.ExportRunnerInterface
+func (s *ExportService) RunExport(ctx context.Context, runID uuid.UUID) error {
+	run, err := s.exportRepo.StartRun(ctx, runID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Already finished, or the schedule was deleted since it was queued
+			s.server.Logger.Info().Str("run_id", runID.String()).Msg("export run is no longer pending, skipping")
+			return nil
+		}
+		return err
+	}
+
+	schedule, err := s.exportRepo.GetScheduleForRun(ctx, runID)
+	if err != nil {
+		return err
+	}
+
+	// Scheduled runs are queued without a workspace, so charge the export here
+	ctx = usage.WithWorkspace(ctx, schedule.WorkspaceID)
+
+	todos, err := s.todoRepo.GetTodosForExport(ctx, schedule.WorkspaceID)
+	if err != nil {
+		return err
+	}
+
+	receipt, err := s.exporter.Export(ctx, schedule, run, todos)
+	if err != nil {
+		return err
+	}
+
+	if err := s.exportRepo.CompleteRun(ctx, runID, receipt); err != nil {
+		return err
+	}
+
+	// Business event log
+	s.server.Logger.Info().
+		Str("event", "export_delivered").
+		Str("schedule_id", schedule.ID.String()).
+		Str("run_id", runID.String()).
+		Str("location", receipt.Location).
+		Int("row_count", receipt.RowCount).
+		Int64("size_bytes", receipt.SizeBytes).
+		Msg("Export delivered successfully")
+
+	return nil
+}
+
+// RecordExportFailure implements job.ExportRunnerInterface
+func (s *ExportService) RecordExportFailure(ctx context.Context, runID uuid.UUID, runErr error, final bool) error {
+	errMsg := runErr.Error()
+	if len(errMsg) > maxExportErrorLength {
+		errMsg = errMsg[:maxExportErrorLength]
+	}
+
+	if err := s.exportRepo.RecordRunError(ctx, runID, errMsg, final); err != nil {
+		return err
+	}
+
+	if !final {
+		return nil
+	}
+
+	s.server.Metrics.Inc(metrics.ExportsFailed)
+
+	schedule, err := s.exportRepo.GetScheduleForRun(ctx, runID)
+	if err != nil {
+		return err
+	}
+
+	attempts := 0
+	if run, err := s.exportRepo.GetRunByID(ctx, runID); err == nil {
+		attempts = run.Attempts
+	}
+
+	err = job.Enqueue(ctx, s.server.Job.Client, &job.ExportFailedEmailTask{
+		UserID:       schedule.CreatedBy,
+		ScheduleID:   schedule.ID,
+		ScheduleName: schedule.Name,
+		Destination:  destinationLabel(schedule),
+		FailedAt:     time.Now(),
+		Attempts:     attempts,
+		Error:        errMsg,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to enqueue export failure alert: %w", err)
+	}
+
+	// Business event log
+	s.server.Logger.Info().
+		Str("event", "export_failed").
+		Str("schedule_id", schedule.ID.String()).
+		Str("run_id", runID.String()).
+		Msg("Export run failed, owner alerted")
+
+	return nil
+}
+
+// checkDestination rejects credentials that can't be parsed and hosts the
+// fetcher won't connect to, so problems surface when the schedule is saved
+// rather than on the first nightly run. Credentials are only saved sealed,
+// which needs an encryption key.
+func (s *ExportService) checkDestination(ctx context.Context, destinationType export.DestinationType,
+	dest *export.Destination,
+) error {
+	if s.server.Sealer == nil {
+		code := "EXPORTS_UNAVAILABLE"
+		return errs.NewServiceUnavailableError("Export destinations can't be saved: no encryption key is configured",
+			false, &code)
+	}
+
+	switch destinationType {
+	case export.DestinationS3:
+		if dest.S3.Endpoint != nil {
+			if _, err := s.server.Fetcher.ValidateURL(ctx, *dest.S3.Endpoint); err != nil {
+				return errs.NewBadRequestError("S3 endpoint is not allowed", false, nil, nil, nil)
+			}
+		}
+	case export.DestinationSFTP:
+		if err := s.server.Fetcher.ValidateHost(ctx, dest.SFTP.Host); err != nil {
+			return errs.NewBadRequestError("SFTP host is not allowed", false, nil, nil, nil)
+		}
+		if _, _, _, _, err := ssh.ParseAuthorizedKey([]byte(dest.SFTP.HostKey)); err != nil {
+			return errs.NewBadRequestError("SFTP host key must be in authorized_keys format", false, nil, nil, nil)
+		}
+		if dest.SFTP.PrivateKey != nil {
+			if _, err := ssh.ParsePrivateKey([]byte(*dest.SFTP.PrivateKey)); err != nil {
+				return errs.NewBadRequestError("SFTP private key must be an unencrypted PEM key", false, nil, nil, nil)
+			}
+		}
+	}
+	return nil
+}
+
+func redactSchedule(schedule *export.Schedule) *export.Schedule {
+	schedule.Destination = schedule.Destination.Redacted()
+	return schedule
+}
+
+func destinationLabel(schedule *export.Schedule) string {
+	switch {
+	case schedule.Destination.S3 != nil:
+		return fmt.Sprintf("S3 bucket %s", schedule.Destination.S3.Bucket)
+	case schedule.Destination.SFTP != nil:
+		return fmt.Sprintf("SFTP host %s", schedule.Destination.SFTP.Host)
+	default:
+		return string(schedule.DestinationType)
+	}
+}
@@ -0,0 +1,359 @@
+package service
+
+import (
+	"context"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/authz"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/lib/refcache"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/audit"
+	"github.com/mabhi256/tasker/internal/model/ipallowlist"
+	"github.com/mabhi256/tasker/internal/repository"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// ipDenialAuditInterval is how often denied requests of one actor from one
+// IP are audited, so a client retrying in a loop can't flood the trail
+const ipDenialAuditInterval = 10 * time.Minute
+
+// The allowlist is checked on every request to the workspace, so it is kept
+// in memory until it changes
+func ipAllowlistCacheKey(workspaceID uuid.UUID) string {
+	return "ip-allowlist:" + workspaceID.String()
+}
+
+// IPAllowlistService restricts workspaces to requests from their
+// allowlisted networks. Admins manage the allowlist, and can't enable it or
+// remove a network in a way that locks themselves out. An owner who is
+// locked out anyway can break glass, which lifts the restriction for a
+// while and is audited.
+type IPAllowlistService struct {
+	server          *server.Server
+	ipAllowlistRepo *repository.IPAllowlistRepository
+	workspaceRepo   *repository.WorkspaceRepository
+	audit           *AuditService
+}
+
+func NewIPAllowlistService(server *server.Server, ipAllowlistRepo *repository.IPAllowlistRepository,
+	workspaceRepo *repository.WorkspaceRepository, auditService *AuditService,
+) *IPAllowlistService {
+	return &IPAllowlistService{
+		server:          server,
+		ipAllowlistRepo: ipAllowlistRepo,
+		workspaceRepo:   workspaceRepo,
+		audit:           auditService,
+	}
+}
+
+// EnforceIPAllowlist implements middleware.IPAllowlistEnforcer
+func (s *IPAllowlistService) EnforceIPAllowlist(ctx echo.Context, workspaceID uuid.UUID) error {
+	addr := requestAddr(ctx)
+
+	allowlist, err := refcache.GetOrLoad(ctx.Request().Context(), s.server.RefCache, ipAllowlistCacheKey(workspaceID),
+		func(loadCtx context.Context) (*ipallowlist.Allowlist, error) {
+			return s.ipAllowlistRepo.GetAllowlist(loadCtx, workspaceID)
+		})
+	if err != nil {
+		return err
+	}
+	if !allowlist.Enabled || allowlist.BreakGlassActive(time.Now()) || allowlist.Allows(addr) {
+		return nil
+	}
+
+	if s.shouldAuditDenial(ctx, workspaceID, addr) {
+		s.audit.Record(ctx, &workspaceID, audit.EventIPDenied,
+			audit.Target{Type: "workspace", ID: workspaceID.String()},
+			map[string]string{
+				"method": ctx.Request().Method,
+				"path":   ctx.Path(),
+			})
+	}
+
+	code := ipallowlist.CodeIPNotAllowed
+	return errs.NewForbiddenActionError("This workspace can't be reached from your network", false, &code, nil)
+}
+
+// shouldAuditDenial reports whether the denial is the first of the actor
+// from the IP in the interval. Without Redis every denial is audited.
+func (s *IPAllowlistService) shouldAuditDenial(ctx echo.Context, workspaceID uuid.UUID, addr netip.Addr) bool {
+	if s.server.Redis == nil {
+		return true
+	}
+
+	key := ipallowlist.DenialKey(workspaceID, middleware.GetUserID(ctx), addr.String())
+	first, err := s.server.Redis.SetNX(ctx.Request().Context(), key, 1, ipDenialAuditInterval).Result()
+	if err != nil {
+		middleware.GetLogger(ctx).Warn().Err(err).Msg("failed to throttle IP denial audit")
+		return true
+	}
+	return first
+}
+
+// ------------------------------------------------------------
+// Allowlist, managed by workspace admins
+
+func (s *IPAllowlistService) GetAllowlist(ctx echo.Context, workspaceID uuid.UUID) (*ipallowlist.Allowlist, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.IPAllowlistManage, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	allowlist, err := s.ipAllowlistRepo.GetAllowlist(ctx.Request().Context(), workspaceID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch IP allowlist")
+		return nil, err
+	}
+
+	return allowlist, nil
+}
+
+// UpdateAllowlist turns the restriction on or off. It can only be turned
+// on from an allowlisted network.
+func (s *IPAllowlistService) UpdateAllowlist(ctx echo.Context, workspaceID uuid.UUID, userID string,
+	payload *ipallowlist.UpdateAllowlistPayload,
+) (*ipallowlist.Allowlist, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := s.requireAllowlistAdmin(ctx, workspaceID); err != nil {
+		return nil, err
+	}
+
+	allowlist, err := s.ipAllowlistRepo.GetAllowlist(ctx.Request().Context(), workspaceID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch IP allowlist")
+		return nil, err
+	}
+
+	if *payload.Enabled && !allowlist.Allows(requestAddr(ctx)) {
+		code := "IP_ALLOWLIST_LOCKOUT"
+		return nil, errs.NewConflictError("Add a network you are connecting from before enabling the allowlist",
+			false, &code, nil, nil)
+	}
+
+	settings, err := s.ipAllowlistRepo.SetEnabled(ctx.Request().Context(), workspaceID, userID, *payload.Enabled)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to update IP allowlist")
+		return nil, err
+	}
+	allowlist.Settings = *settings
+	s.invalidateAllowlist(ctx, workspaceID)
+
+	s.audit.Record(ctx, &workspaceID, audit.EventIPAllowlistUpdated,
+		audit.Target{Type: "ip_allowlist", ID: workspaceID.String()},
+		map[string]string{"enabled": strconv.FormatBool(settings.Enabled)})
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "ip_allowlist_updated").
+		Str("workspace_id", workspaceID.String()).
+		Bool("enabled", settings.Enabled).
+		Msg("IP allowlist updated successfully")
+
+	return allowlist, nil
+}
+
+func (s *IPAllowlistService) CreateEntry(ctx echo.Context, workspaceID uuid.UUID, userID string,
+	payload *ipallowlist.CreateEntryPayload,
+) (*ipallowlist.Entry, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := s.requireAllowlistAdmin(ctx, workspaceID); err != nil {
+		return nil, err
+	}
+
+	cidr, err := ipallowlist.ParseCIDR(payload.CIDR)
+	if err != nil {
+		return nil, errs.NewBadRequestError("Invalid network, expected an IP address or CIDR", false, nil, nil, nil)
+	}
+
+	entry, err := s.ipAllowlistRepo.CreateEntry(ctx.Request().Context(), workspaceID, userID, cidr,
+		payload.Description)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to create IP allowlist entry")
+		return nil, err
+	}
+	s.invalidateAllowlist(ctx, workspaceID)
+
+	s.audit.Record(ctx, &workspaceID, audit.EventIPEntryAdded,
+		audit.Target{Type: "ip_allowlist_entry", ID: entry.ID.String()},
+		map[string]string{"cidr": entry.CIDR.String()})
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "ip_allowlist_entry_added").
+		Str("workspace_id", workspaceID.String()).
+		Str("cidr", entry.CIDR.String()).
+		Msg("IP allowlist entry added successfully")
+
+	return entry, nil
+}
+
+// DeleteEntry removes a network, unless the enabled allowlist would no
+// longer allow the admin's own
+func (s *IPAllowlistService) DeleteEntry(ctx echo.Context, workspaceID uuid.UUID,
+	payload *ipallowlist.DeleteEntryPayload,
+) error {
+	logger := middleware.GetLogger(ctx)
+
+	if err := s.requireAllowlistAdmin(ctx, workspaceID); err != nil {
+		return err
+	}
+
+	allowlist, err := s.ipAllowlistRepo.GetAllowlist(ctx.Request().Context(), workspaceID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch IP allowlist")
+		return err
+	}
+
+	if allowlist.Enabled && !allowlist.BreakGlassActive(time.Now()) {
+		remaining := ipallowlist.Allowlist{Settings: allowlist.Settings}
+		for _, entry := range allowlist.Entries {
+			if entry.ID != payload.ID {
+				remaining.Entries = append(remaining.Entries, entry)
+			}
+		}
+		if !remaining.Allows(requestAddr(ctx)) {
+			code := "IP_ALLOWLIST_LOCKOUT"
+			return errs.NewConflictError("Removing this network would block the network you are connecting from",
+				false, &code, nil, nil)
+		}
+	}
+
+	entry, err := s.ipAllowlistRepo.DeleteEntry(ctx.Request().Context(), workspaceID, payload.ID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to delete IP allowlist entry")
+		return err
+	}
+	s.invalidateAllowlist(ctx, workspaceID)
+
+	s.audit.Record(ctx, &workspaceID, audit.EventIPEntryRemoved,
+		audit.Target{Type: "ip_allowlist_entry", ID: entry.ID.String()},
+		map[string]string{"cidr": entry.CIDR.String()})
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "ip_allowlist_entry_removed").
+		Str("workspace_id", workspaceID.String()).
+		Str("cidr", entry.CIDR.String()).
+		Msg("IP allowlist entry removed successfully")
+
+	return nil
+}
+
+// ------------------------------------------------------------
+// Break glass
+
+// StartBreakGlass lifts the restriction for a while. Only the owner can,
+// from any network, and must give a reason.
+func (s *IPAllowlistService) StartBreakGlass(ctx echo.Context, workspaceID uuid.UUID, userID string,
+	payload *ipallowlist.BreakGlassPayload,
+) (*ipallowlist.Settings, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.IPAllowlistBreakGlass, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	minutes := ipallowlist.DefaultBreakGlassMinutes
+	if payload.Minutes != nil {
+		minutes = *payload.Minutes
+	}
+	until := time.Now().Add(time.Duration(minutes) * time.Minute)
+
+	settings, err := s.ipAllowlistRepo.SetBreakGlass(ctx.Request().Context(), workspaceID, userID, until,
+		payload.Reason)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to break glass")
+		return nil, err
+	}
+	s.invalidateAllowlist(ctx, workspaceID)
+
+	s.audit.Record(ctx, &workspaceID, audit.EventBreakGlassStarted,
+		audit.Target{Type: "ip_allowlist", ID: workspaceID.String()},
+		map[string]string{
+			"reason":  payload.Reason,
+			"minutes": strconv.Itoa(minutes),
+		})
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Warn().
+		Str("event", "ip_allowlist_break_glass_started").
+		Str("workspace_id", workspaceID.String()).
+		Time("until", until).
+		Msg("IP allowlist lifted by break glass")
+
+	return settings, nil
+}
+
+// EndBreakGlass restores the restriction before break glass expires
+func (s *IPAllowlistService) EndBreakGlass(ctx echo.Context, workspaceID uuid.UUID) error {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.IPAllowlistManage, authz.Resource{}); err != nil {
+		return err
+	}
+
+	if err := s.ipAllowlistRepo.ClearBreakGlass(ctx.Request().Context(), workspaceID); err != nil {
+		logger.Error().Err(err).Msg("failed to end break glass")
+		return err
+	}
+	s.invalidateAllowlist(ctx, workspaceID)
+
+	s.audit.Record(ctx, &workspaceID, audit.EventBreakGlassEnded,
+		audit.Target{Type: "ip_allowlist", ID: workspaceID.String()}, nil)
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "ip_allowlist_break_glass_ended").
+		Str("workspace_id", workspaceID.String()).
+		Msg("IP allowlist restored")
+
+	return nil
+}
+
+// invalidateAllowlist drops the cached allowlist after a change to it. A
+// failure to reach other instances only delays the change there until the
+// entry expires.
+func (s *IPAllowlistService) invalidateAllowlist(ctx echo.Context, workspaceID uuid.UUID) {
+	if err := s.server.RefCache.Invalidate(ctx.Request().Context(), ipAllowlistCacheKey(workspaceID)); err != nil {
+		middleware.GetLogger(ctx).Warn().Err(err).Msg("failed to invalidate IP allowlist cache")
+	}
+}
+
+// requireAllowlistAdmin checks that the user may change the workspace's
+// allowlist. Personal workspaces have none.
+func (s *IPAllowlistService) requireAllowlistAdmin(ctx echo.Context, workspaceID uuid.UUID) error {
+	if err := authorize(ctx, authz.IPAllowlistManage, authz.Resource{}); err != nil {
+		return err
+	}
+
+	workspaceItem, err := s.workspaceRepo.GetWorkspaceByID(ctx.Request().Context(), workspaceID)
+	if err != nil {
+		middleware.GetLogger(ctx).Error().Err(err).Msg("failed to fetch workspace by ID")
+		return err
+	}
+
+	if workspaceItem.IsPersonal {
+		return errs.NewBadRequestError("Personal workspaces cannot restrict IPs", false, nil, nil, nil)
+	}
+
+	return nil
+}
+
+// requestAddr returns the client's address, as the router's IP extractor
+// found it
+func requestAddr(ctx echo.Context) netip.Addr {
+	return ipallowlist.ClientAddr(ctx.RealIP())
+}
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/hibiken/asynq"
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model"
+	"github.com/mabhi256/tasker/internal/model/admin"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// JobAdminService lets admins inspect and manage the background job queues
+type JobAdminService struct {
+	server *server.Server
+}
+
+func NewJobAdminService(server *server.Server) *JobAdminService {
+	return &JobAdminService{server: server}
+}
+
+func (s *JobAdminService) GetQueues(ctx echo.Context) ([]admin.QueueStats, error) {
+	logger := middleware.GetLogger(ctx)
+
+	queues, err := getQueueStats(s.server.Job.Inspector)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch job queues")
+		return nil, err
+	}
+
+	return queues, nil
+}
+
+func (s *JobAdminService) GetQueueTasks(ctx echo.Context, query *admin.GetQueueTasksQuery) (*model.PaginatedResponse[admin.JobTask], error) {
+	logger := middleware.GetLogger(ctx)
+	inspector := s.server.Job.Inspector
+
+	info, err := inspector.GetQueueInfo(query.Queue)
+	if err != nil {
+		logger.Error().Err(err).Str("queue", query.Queue).Msg("failed to get queue info")
+		return nil, jobAdminError(err)
+	}
+
+	opts := []asynq.ListOption{asynq.PageSize(*query.Limit), asynq.Page(*query.Page)}
+
+	var tasks []*asynq.TaskInfo
+	var total int
+	switch admin.TaskState(*query.State) {
+	case admin.TaskStatePending:
+		tasks, err = inspector.ListPendingTasks(query.Queue, opts...)
+		total = info.Pending
+	case admin.TaskStateActive:
+		tasks, err = inspector.ListActiveTasks(query.Queue, opts...)
+		total = info.Active
+	case admin.TaskStateScheduled:
+		tasks, err = inspector.ListScheduledTasks(query.Queue, opts...)
+		total = info.Scheduled
+	case admin.TaskStateRetry:
+		tasks, err = inspector.ListRetryTasks(query.Queue, opts...)
+		total = info.Retry
+	case admin.TaskStateArchived:
+		tasks, err = inspector.ListArchivedTasks(query.Queue, opts...)
+		total = info.Archived
+	}
+	if err != nil {
+		logger.Error().Err(err).Str("queue", query.Queue).Str("state", *query.State).Msg("failed to list tasks")
+		return nil, jobAdminError(err)
+	}
+
+	data := make([]admin.JobTask, 0, len(tasks))
+	for _, task := range tasks {
+		data = append(data, toJobTask(task))
+	}
+
+	return &model.PaginatedResponse[admin.JobTask]{
+		Data:       data,
+		Page:       *query.Page,
+		Limit:      *query.Limit,
+		Total:      total,
+		TotalPages: (total + *query.Limit - 1) / *query.Limit,
+	}, nil
+}
+
+// RetryTask queues a dead, retrying or scheduled task to run right away
+func (s *JobAdminService) RetryTask(ctx echo.Context, payload *admin.QueueTaskPayload) (*admin.JobTask, error) {
+	logger := middleware.GetLogger(ctx)
+	inspector := s.server.Job.Inspector
+
+	if err := inspector.RunTask(payload.Queue, payload.TaskID); err != nil {
+		logger.Error().Err(err).Str("queue", payload.Queue).Str("task_id", payload.TaskID).Msg("failed to retry task")
+		return nil, jobAdminError(err)
+	}
+
+	info, err := inspector.GetTaskInfo(payload.Queue, payload.TaskID)
+	if err != nil {
+		logger.Error().Err(err).Str("queue", payload.Queue).Str("task_id", payload.TaskID).Msg("failed to get task info")
+		return nil, jobAdminError(err)
+	}
+
+	// Audit log
+	logger.Info().
+		Str("event", "admin_job_task_retried").
+		Str("admin_id", middleware.GetUserID(ctx)).
+		Str("queue", payload.Queue).
+		Str("task_id", payload.TaskID).
+		Str("task_type", info.Type).
+		Msg("Admin retried background task")
+
+	task := toJobTask(info)
+	return &task, nil
+}
+
+func (s *JobAdminService) DeleteTask(ctx echo.Context, payload *admin.QueueTaskPayload) error {
+	logger := middleware.GetLogger(ctx)
+
+	if err := s.server.Job.Inspector.DeleteTask(payload.Queue, payload.TaskID); err != nil {
+		logger.Error().Err(err).Str("queue", payload.Queue).Str("task_id", payload.TaskID).Msg("failed to delete task")
+		return jobAdminError(err)
+	}
+
+	// Audit log
+	logger.Info().
+		Str("event", "admin_job_task_deleted").
+		Str("admin_id", middleware.GetUserID(ctx)).
+		Str("queue", payload.Queue).
+		Str("task_id", payload.TaskID).
+		Msg("Admin deleted background task")
+
+	return nil
+}
+
+func (s *JobAdminService) PauseQueue(ctx echo.Context, queue string) error {
+	return s.setQueuePaused(ctx, queue, true)
+}
+
+func (s *JobAdminService) ResumeQueue(ctx echo.Context, queue string) error {
+	return s.setQueuePaused(ctx, queue, false)
+}
+
+func (s *JobAdminService) setQueuePaused(ctx echo.Context, queue string, paused bool) error {
+	logger := middleware.GetLogger(ctx)
+	inspector := s.server.Job.Inspector
+
+	// Pausing an unknown queue would create it
+	if _, err := inspector.GetQueueInfo(queue); err != nil {
+		logger.Error().Err(err).Str("queue", queue).Msg("failed to get queue info")
+		return jobAdminError(err)
+	}
+
+	var err error
+	if paused {
+		err = inspector.PauseQueue(queue)
+	} else {
+		err = inspector.UnpauseQueue(queue)
+	}
+	if err != nil {
+		logger.Error().Err(err).Str("queue", queue).Bool("paused", paused).Msg("failed to change queue state")
+		return jobAdminError(err)
+	}
+
+	// Audit log
+	logger.Info().
+		Str("event", "admin_job_queue_paused").
+		Str("admin_id", middleware.GetUserID(ctx)).
+		Str("queue", queue).
+		Bool("paused", paused).
+		Msg("Admin changed background queue state")
+
+	return nil
+}
+
+func getQueueStats(inspector *asynq.Inspector) ([]admin.QueueStats, error) {
+	queueNames, err := inspector.Queues()
+	if err != nil {
+		return nil, err
+	}
+
+	queues := make([]admin.QueueStats, 0, len(queueNames))
+	for _, name := range queueNames {
+		info, err := inspector.GetQueueInfo(name)
+		if err != nil {
+			return nil, err
+		}
+
+		queues = append(queues, admin.QueueStats{
+			Queue:     info.Queue,
+			Size:      info.Size,
+			Pending:   info.Pending,
+			Active:    info.Active,
+			Scheduled: info.Scheduled,
+			Retry:     info.Retry,
+			Archived:  info.Archived,
+			Processed: info.Processed,
+			Failed:    info.Failed,
+			Paused:    info.Paused,
+			LatencyMs: info.Latency.Milliseconds(),
+		})
+	}
+
+	return queues, nil
+}
+
+func toJobTask(info *asynq.TaskInfo) admin.JobTask {
+	task := admin.JobTask{
+		ID:       info.ID,
+		Queue:    info.Queue,
+		Type:     info.Type,
+		State:    admin.TaskState(info.State.String()),
+		MaxRetry: info.MaxRetry,
+		Retried:  info.Retried,
+	}
+
+	// Every task this service enqueues has a JSON payload, but don't
+	// produce invalid JSON for one that doesn't
+	if json.Valid(info.Payload) {
+		task.Payload = info.Payload
+	} else {
+		task.Payload, _ = json.Marshal(string(info.Payload))
+	}
+
+	if info.LastErr != "" {
+		lastErr := info.LastErr
+		task.LastError = &lastErr
+	}
+	if !info.LastFailedAt.IsZero() {
+		lastFailedAt := info.LastFailedAt
+		task.LastFailedAt = &lastFailedAt
+	}
+	if !info.NextProcessAt.IsZero() {
+		nextProcessAt := info.NextProcessAt
+		task.NextProcessAt = &nextProcessAt
+	}
+
+	return task
+}
+
+func jobAdminError(err error) error {
+	switch {
+	case errors.Is(err, asynq.ErrQueueNotFound):
+		return errs.NewNotFoundError("Queue not found", false, nil)
+	case errors.Is(err, asynq.ErrTaskNotFound):
+		return errs.NewNotFoundError("Task not found", false, nil)
+	}
+
+	// The inspector refuses operations the task's state doesn't allow, like
+	// deleting an active task, with a "FAILED_PRECONDITION" error
+	if strings.Contains(err.Error(), "FAILED_PRECONDITION") {
+		return errs.NewConflictError("Task can't be changed in its current state", false, nil, nil, nil)
+	}
+
+	return err
+}
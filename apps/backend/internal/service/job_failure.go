@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/mabhi256/tasker/internal/lib/email"
+	"github.com/mabhi256/tasker/internal/lib/job"
+	"github.com/mabhi256/tasker/internal/model/jobfailure"
+	"github.com/mabhi256/tasker/internal/repository"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// maxJobFailureErrorLength keeps alerts about failed tasks readable
+const maxJobFailureErrorLength = 1000
+
+// JobFailureService records background tasks that ran out of retries and
+// alerts the configured channels about each new one
+type JobFailureService struct {
+	server         *server.Server
+	jobFailureRepo *repository.JobFailureRepository
+	emailClient    *email.Client
+}
+
+func NewJobFailureService(server *server.Server, jobFailureRepo *repository.JobFailureRepository) *JobFailureService {
+	return &JobFailureService{
+		server:         server,
+		jobFailureRepo: jobFailureRepo,
+		emailClient:    server.Email,
+	}
+}
+
+func (s *JobFailureService) RecordJobFailure(ctx context.Context, failure *job.JobFailure) (bool, error) {
+	recorded, err := s.jobFailureRepo.RecordJobFailure(ctx, failure)
+	if err != nil {
+		return false, err
+	}
+	if recorded == nil {
+		return false, nil
+	}
+
+	// Business event log
+	s.server.Logger.Info().
+		Str("event", "job_failure_recorded").
+		Str("job_failure_id", recorded.ID.String()).
+		Str("task_id", recorded.TaskID).
+		Str("queue", recorded.Queue).
+		Str("task_type", recorded.TaskType).
+		Int("occurrences", recorded.Occurrences).
+		Msg("Background task failure recorded")
+
+	if err := s.notify(ctx, recorded); err != nil {
+		// The failure is stored, so a missed alert doesn't lose it
+		s.server.Logger.Error().
+			Str("job_failure_id", recorded.ID.String()).
+			Err(err).
+			Msg("failed to send job failure alerts")
+	}
+
+	return true, nil
+}
+
+// notify alerts each configured channel. Emails are sent directly rather
+// than through the job queue, which may be what is failing.
+func (s *JobFailureService) notify(ctx context.Context, failure *jobfailure.JobFailure) error {
+	cfg := s.server.Config.JobFailures
+
+	errMsg := failure.Error
+	if len(errMsg) > maxJobFailureErrorLength {
+		errMsg = errMsg[:maxJobFailureErrorLength] + "..."
+	}
+
+	var errList []error
+
+	if cfg.SlackAlerts {
+		err := s.server.Alerts.Send(ctx,
+			fmt.Sprintf("Background task %s failed permanently", failure.TaskType),
+			fmt.Sprintf("Queue: %s\nTask: %s\nAttempts: %d\nOccurrences: %d\nError: %s",
+				failure.Queue, failure.TaskID, failure.Retried+1, failure.Occurrences, errMsg))
+		if err != nil {
+			errList = append(errList, err)
+		}
+	}
+
+	for _, to := range cfg.AlertEmails {
+		err := s.emailClient.SendJobFailedEmail(ctx, to, failure.TaskType, failure.Queue, failure.TaskID,
+			failure.FailedAt, failure.Retried+1, failure.Occurrences, errMsg)
+		if err != nil {
+			errList = append(errList, fmt.Errorf("failed to email %s: %w", to, err))
+		}
+	}
+
+	return errors.Join(errList...)
+}
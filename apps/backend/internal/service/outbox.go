@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/lib/realtime"
+	"github.com/mabhi256/tasker/internal/lib/tracing"
+	"github.com/mabhi256/tasker/internal/lib/usage"
+	"github.com/mabhi256/tasker/internal/model/audit"
+	"github.com/mabhi256/tasker/internal/model/outbox"
+	"github.com/mabhi256/tasker/internal/model/webhook"
+	"github.com/mabhi256/tasker/internal/repository"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+// OutboxRelay publishes events recorded in the outbox to webhooks and Redis,
+// and audit events to audit forwarders.
+// It wakes up on NOTIFY from the outbox trigger and polls as a fallback, so
+// events committed while the relay was down are picked up on restart.
+// Delivery is at least once: an event may be published again if the relay
+// stops before marking it, and consumers dedupe on the event id.
+type OutboxRelay struct {
+	server     *server.Server
+	outboxRepo *repository.OutboxRepository
+	webhooks   *WebhookService
+	audit      *AuditService
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewOutboxRelay(server *server.Server, outboxRepo *repository.OutboxRepository, webhooks *WebhookService,
+	auditService *AuditService,
+) *OutboxRelay {
+	return &OutboxRelay{
+		server:     server,
+		outboxRepo: outboxRepo,
+		webhooks:   webhooks,
+		audit:      auditService,
+	}
+}
+
+func (r *OutboxRelay) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+
+	go r.run(ctx)
+
+	r.server.Logger.Info().
+		Dur("poll_interval", r.server.Config.Outbox.PollInterval).
+		Msg("Starting outbox relay")
+}
+
+// Stop cancels the relay and waits for it to exit. A batch cut short is
+// rolled back and relayed again on the next start.
+func (r *OutboxRelay) Stop() {
+	if r.cancel == nil {
+		return
+	}
+
+	r.server.Logger.Info().Msg("Stopping outbox relay")
+	r.cancel()
+	<-r.done
+}
+
+// Shutdown stops the relay, then relays the events the last requests and
+// tasks recorded until the outbox is drained or ctx is done
+func (r *OutboxRelay) Shutdown(ctx context.Context) error {
+	r.Stop()
+	r.drain(ctx)
+	return ctx.Err()
+}
+
+func (r *OutboxRelay) run(ctx context.Context) {
+	defer close(r.done)
+
+	for {
+		err := r.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		r.server.Logger.Error().Err(err).Msg("outbox listener stopped, reconnecting")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.server.Config.Outbox.PollInterval):
+		}
+	}
+}
+
+// listen holds a dedicated connection subscribed to the outbox channel and
+// drains the outbox whenever it is notified or the poll interval passes
+func (r *OutboxRelay) listen(ctx context.Context) error {
+	pooled, err := r.server.DB.Pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire outbox listener connection: %w", err)
+	}
+	// A listening session must not go back to the pool, so take it over and
+	// close it when done
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+repository.OutboxNotifyChannel); err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", repository.OutboxNotifyChannel, err)
+	}
+
+	for {
+		r.drain(ctx)
+
+		waitCtx, cancel := context.WithTimeout(ctx, r.server.Config.Outbox.PollInterval)
+		_, err := conn.WaitForNotification(waitCtx)
+		cancel()
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("failed to wait for outbox notification: %w", err)
+		}
+	}
+}
+
+// drain relays batches until the outbox has nothing left that is due
+func (r *OutboxRelay) drain(ctx context.Context) {
+	batchSize := r.server.Config.Outbox.BatchSize
+	maxAttempts := r.server.Config.Outbox.MaxAttempts
+
+	for ctx.Err() == nil {
+		count, err := r.outboxRepo.RelayBatch(ctx, batchSize, maxAttempts, r.relay)
+		if err != nil {
+			r.server.Logger.Error().Err(err).Msg("failed to relay outbox events")
+			return
+		}
+
+		if count < batchSize {
+			return
+		}
+	}
+}
+
+// relay publishes one event to every sink. An error leaves the event in the
+// outbox to be retried, including for the sinks that succeeded. It runs in a
+// transaction that continues the trace of the request that recorded the
+// event, which deliveries queued from it carry on to the job server.
+func (r *OutboxRelay) relay(ctx context.Context, event *outbox.Event) error {
+	var nrApp *newrelic.Application
+	if r.server.LoggerService != nil {
+		nrApp = r.server.LoggerService.GetApplication()
+	}
+
+	ctx, txn := tracing.StartTransaction(ctx, nrApp, "outbox/"+event.EventType, newrelic.TransportQueue, event.Trace)
+	defer txn.End()
+
+	var errList []error
+
+	if audit.IsAuditEvent(event.EventType) {
+		// Audit events are for security teams only, not for webhooks or
+		// clients of the workspace
+		if err := r.publishAudit(ctx, event); err != nil {
+			errList = append(errList, fmt.Errorf("failed to publish audit event: %w", err))
+		}
+	} else if event.WorkspaceID == nil {
+		errList = append(errList, fmt.Errorf("domain event %s has no workspace", event.EventType))
+	} else {
+		errList = r.publishDomain(ctx, event, *event.WorkspaceID)
+	}
+
+	if err := errors.Join(errList...); err != nil {
+		if event.Attempts+1 >= r.server.Config.Outbox.MaxAttempts {
+			r.server.Logger.Error().
+				Str("event_id", event.ID.String()).
+				Str("event_type", event.EventType).
+				Int("attempts", event.Attempts+1).
+				Err(err).
+				Msg("failed to relay outbox event, giving up")
+		} else {
+			r.server.Logger.Warn().
+				Str("event_id", event.ID.String()).
+				Str("event_type", event.EventType).
+				Int("attempts", event.Attempts+1).
+				Err(err).
+				Msg("failed to relay outbox event, will retry")
+		}
+		txn.NoticeError(err)
+		return err
+	}
+
+	return nil
+}
+
+// publishDomain publishes a domain event to webhooks and Redis
+func (r *OutboxRelay) publishDomain(ctx context.Context, event *outbox.Event, workspaceID uuid.UUID) []error {
+	ctx = usage.WithWorkspace(ctx, workspaceID)
+
+	envelope := &webhook.Event{
+		ID:          event.ID,
+		Type:        webhook.EventType(event.EventType),
+		CreatedAt:   event.CreatedAt,
+		WorkspaceID: workspaceID,
+		Data:        event.Payload,
+	}
+
+	var errList []error
+
+	if err := r.webhooks.Publish(ctx, envelope); err != nil {
+		errList = append(errList, fmt.Errorf("failed to publish webhooks: %w", err))
+	}
+
+	message, err := json.Marshal(envelope)
+	if err != nil {
+		errList = append(errList, fmt.Errorf("failed to marshal realtime event: %w", err))
+	} else if err := r.server.Redis.Publish(ctx, realtime.WorkspaceEventsChannel(workspaceID), message).Err(); err != nil {
+		errList = append(errList, fmt.Errorf("failed to publish realtime event: %w", err))
+	}
+
+	return errList
+}
+
+// publishAudit queues an audit event for the forwarders receiving it
+func (r *OutboxRelay) publishAudit(ctx context.Context, event *outbox.Event) error {
+	if event.WorkspaceID != nil {
+		ctx = usage.WithWorkspace(ctx, *event.WorkspaceID)
+	}
+
+	auditEvent := &audit.Event{
+		ID:          event.ID,
+		Type:        audit.EventType(event.EventType),
+		Time:        event.CreatedAt,
+		WorkspaceID: event.WorkspaceID,
+	}
+	if err := json.Unmarshal(event.Payload, &auditEvent.Record); err != nil {
+		return fmt.Errorf("failed to unmarshal audit record: %w", err)
+	}
+
+	return r.audit.Publish(ctx, auditEvent)
+}
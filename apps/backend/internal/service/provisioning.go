@@ -0,0 +1,838 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/authz"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/lib/scim"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/audit"
+	"github.com/mabhi256/tasker/internal/model/provisioning"
+	"github.com/mabhi256/tasker/internal/model/workspace"
+	"github.com/mabhi256/tasker/internal/repository"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+const (
+	scimTokenPrefix = "scim_"
+
+	// MaxSCIMResults is the most resources a SCIM list returns, and the
+	// page size when the identity provider doesn't ask for one
+	MaxSCIMResults = 100
+)
+
+// ProvisioningService serves the SCIM API identity providers use to
+// provision a workspace's users and groups. Provisioned users are created
+// at the auth provider when they don't have an account, and are members of
+// the workspace while active, with the highest role mapped to their groups.
+// Existing accounts are only provisioned straight in when their email is in
+// one of the workspace's verified domains, see SSOService.VerifyDomain.
+// Others are invited, so a workspace can't pull in accounts it doesn't own.
+type ProvisioningService struct {
+	server           *server.Server
+	provisioningRepo *repository.ProvisioningRepository
+	workspaceRepo    *repository.WorkspaceRepository
+	ssoRepo          *repository.SSORepository
+	identity         IdentityProvider
+	audit            *AuditService
+}
+
+func NewProvisioningService(server *server.Server, provisioningRepo *repository.ProvisioningRepository,
+	workspaceRepo *repository.WorkspaceRepository, ssoRepo *repository.SSORepository, identity IdentityProvider,
+	auditService *AuditService,
+) *ProvisioningService {
+	return &ProvisioningService{
+		server:           server,
+		provisioningRepo: provisioningRepo,
+		workspaceRepo:    workspaceRepo,
+		ssoRepo:          ssoRepo,
+		identity:         identity,
+		audit:            auditService,
+	}
+}
+
+// ResolveSCIMToken implements middleware.SCIMTokenResolver
+func (s *ProvisioningService) ResolveSCIMToken(ctx context.Context, token string) (uuid.UUID, error) {
+	tokenItem, err := s.provisioningRepo.UseToken(ctx, hashSCIMToken(token))
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return tokenItem.WorkspaceID, nil
+}
+
+// ------------------------------------------------------------
+// Token and role mapping, managed by workspace admins
+
+// CreateToken creates the workspace's SCIM token, replacing the one it had
+func (s *ProvisioningService) CreateToken(ctx echo.Context, workspaceID uuid.UUID,
+	userID string,
+) (*provisioning.TokenWithSecret, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.ProvisioningManage, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	workspaceItem, err := s.workspaceRepo.GetWorkspaceByID(ctx.Request().Context(), workspaceID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch workspace by ID")
+		return nil, err
+	}
+
+	if workspaceItem.IsPersonal {
+		return nil, errs.NewBadRequestError("Personal workspaces cannot be provisioned", false, nil, nil, nil)
+	}
+
+	secret, err := generateSCIMToken()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate SCIM token")
+		return nil, err
+	}
+
+	token, err := s.provisioningRepo.UpsertToken(ctx.Request().Context(), workspaceID, userID, hashSCIMToken(secret))
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to create SCIM token")
+		return nil, err
+	}
+
+	s.audit.Record(ctx, &workspaceID, audit.EventSCIMTokenCreated,
+		audit.Target{Type: "scim_token", ID: token.ID.String()}, nil)
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "scim_token_created").
+		Str("workspace_id", workspaceID.String()).
+		Msg("SCIM token created successfully")
+
+	return &provisioning.TokenWithSecret{Token: *token, Secret: secret}, nil
+}
+
+func (s *ProvisioningService) GetToken(ctx echo.Context, workspaceID uuid.UUID) (*provisioning.Token, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.ProvisioningManage, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	token, err := s.provisioningRepo.GetToken(ctx.Request().Context(), workspaceID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch SCIM token")
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// DeleteToken stops provisioning. Provisioned users keep their memberships.
+func (s *ProvisioningService) DeleteToken(ctx echo.Context, workspaceID uuid.UUID) error {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.ProvisioningManage, authz.Resource{}); err != nil {
+		return err
+	}
+
+	if err := s.provisioningRepo.DeleteToken(ctx.Request().Context(), workspaceID); err != nil {
+		logger.Error().Err(err).Msg("failed to delete SCIM token")
+		return err
+	}
+
+	s.audit.Record(ctx, &workspaceID, audit.EventSCIMTokenDeleted,
+		audit.Target{Type: "scim_token", ID: workspaceID.String()}, nil)
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "scim_token_deleted").
+		Str("workspace_id", workspaceID.String()).
+		Msg("SCIM token deleted successfully")
+
+	return nil
+}
+
+// GetGroups lists the provisioned groups for mapping them to roles
+func (s *ProvisioningService) GetGroups(ctx echo.Context, workspaceID uuid.UUID) ([]provisioning.Group, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.ProvisioningManage, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	groups, _, err := s.provisioningRepo.GetGroups(ctx.Request().Context(), workspaceID, &repository.GroupQuery{})
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch SCIM groups")
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+// UpdateGroupRole maps a group to the role its members get in the
+// workspace, changing the roles of its current members
+func (s *ProvisioningService) UpdateGroupRole(ctx echo.Context, workspaceID uuid.UUID,
+	payload *provisioning.UpdateGroupRolePayload,
+) (*provisioning.Group, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.ProvisioningManage, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	var role *workspace.Role
+	if payload.Role != provisioning.RoleNone {
+		r := workspace.Role(payload.Role)
+		role = &r
+	}
+
+	group, changes, err := s.provisioningRepo.SetGroupRole(ctx.Request().Context(), workspaceID, payload.ID, role)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to update SCIM group role")
+		return nil, err
+	}
+
+	s.audit.Record(ctx, &workspaceID, audit.EventSCIMGroupRoleChanged,
+		audit.Target{Type: "scim_group", ID: group.ID.String()},
+		map[string]string{"role": payload.Role})
+	s.recordMembershipChanges(ctx, workspaceID, changes...)
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "scim_group_role_updated").
+		Str("workspace_id", workspaceID.String()).
+		Str("group_id", group.ID.String()).
+		Str("role", payload.Role).
+		Int("memberships_changed", len(changes)).
+		Msg("SCIM group role updated successfully")
+
+	return group, nil
+}
+
+// ------------------------------------------------------------
+// SCIM users
+
+func (s *ProvisioningService) GetSCIMUsers(ctx echo.Context, workspaceID uuid.UUID, filter string,
+	startIndex int, count int,
+) (*scim.ListResponse[scim.User], error) {
+	logger := middleware.GetLogger(ctx)
+
+	startIndex, count = scimPage(startIndex, count)
+	query := &repository.UserQuery{Offset: startIndex - 1, Limit: count}
+
+	if filter != "" {
+		parsed, err := scim.ParseFilter(filter)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case parsed.Is("userName"):
+			query.UserName = &parsed.Value
+		case parsed.Is("externalId"):
+			query.ExternalID = &parsed.Value
+		case parsed.Is("emails"), parsed.Is("emails.value"):
+			query.Email = &parsed.Value
+		default:
+			return nil, scim.NewError(http.StatusBadRequest, scim.ErrorTypeInvalidFilter,
+				"Users can only be filtered by userName, externalId or emails")
+		}
+	}
+
+	users, total, err := s.provisioningRepo.GetUsers(ctx.Request().Context(), workspaceID, query)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch SCIM users")
+		return nil, err
+	}
+
+	resources := make([]scim.User, len(users))
+	for i := range users {
+		resources[i] = toSCIMUser(&users[i])
+	}
+
+	return scim.NewListResponse(resources, total, startIndex), nil
+}
+
+func (s *ProvisioningService) GetSCIMUser(ctx echo.Context, workspaceID uuid.UUID, id string) (*scim.User, error) {
+	logger := middleware.GetLogger(ctx)
+
+	scimUserID, err := parseSCIMID(id, "User")
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.provisioningRepo.GetUserByID(ctx.Request().Context(), workspaceID, scimUserID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch SCIM user")
+		return nil, err
+	}
+
+	result := toSCIMUser(user)
+	return &result, nil
+}
+
+// CreateSCIMUser provisions a user, matched to an existing account at the
+// auth provider by email or given a new one. An existing account outside
+// the workspace's verified domains is invited rather than added.
+func (s *ProvisioningService) CreateSCIMUser(ctx echo.Context, workspaceID uuid.UUID,
+	input *scim.User,
+) (*scim.User, error) {
+	logger := middleware.GetLogger(ctx)
+
+	attrs, err := toUserAttributes(input)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := s.identity.FindUserByEmail(ctx.Request().Context(), attrs.Email)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to find user at auth provider")
+		return nil, err
+	}
+
+	pending := false
+	if userID != "" {
+		config, err := s.ssoRepo.FindConfig(ctx.Request().Context(), workspaceID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to fetch SSO config")
+			return nil, err
+		}
+		pending = config == nil || !config.VerifiesEmail(attrs.Email)
+	} else {
+		var givenName, familyName string
+		if input.Name != nil {
+			givenName, familyName = input.Name.GivenName, input.Name.FamilyName
+		}
+		userID, err = s.identity.CreateUser(ctx.Request().Context(), attrs.Email, givenName, familyName)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to create user at auth provider")
+			return nil, err
+		}
+	}
+
+	user, change, err := s.provisioningRepo.CreateUser(ctx.Request().Context(), workspaceID, userID, attrs, pending)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to create SCIM user")
+		return nil, err
+	}
+
+	s.recordMembershipChanges(ctx, workspaceID, optionalChange(change)...)
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "scim_user_created").
+		Str("workspace_id", workspaceID.String()).
+		Str("scim_user_id", user.ID.String()).
+		Str("member_id", user.UserID).
+		Bool("pending", user.Pending).
+		Msg("SCIM user provisioned successfully")
+
+	result := toSCIMUser(user)
+	return &result, nil
+}
+
+// ReplaceSCIMUser replaces the user's attributes. The account at the auth
+// provider isn't changed; its email stays the one the user signs in with.
+func (s *ProvisioningService) ReplaceSCIMUser(ctx echo.Context, workspaceID uuid.UUID, id string,
+	input *scim.User,
+) (*scim.User, error) {
+	return s.updateSCIMUser(ctx, workspaceID, id, func(*scim.User) (*scim.User, error) {
+		return input, nil
+	})
+}
+
+func (s *ProvisioningService) PatchSCIMUser(ctx echo.Context, workspaceID uuid.UUID, id string,
+	patch *scim.PatchRequest,
+) (*scim.User, error) {
+	if err := patch.Validate(); err != nil {
+		return nil, err
+	}
+
+	return s.updateSCIMUser(ctx, workspaceID, id, func(current *scim.User) (*scim.User, error) {
+		return current, patch.ApplyToUser(current)
+	})
+}
+
+func (s *ProvisioningService) updateSCIMUser(ctx echo.Context, workspaceID uuid.UUID, id string,
+	update func(current *scim.User) (*scim.User, error),
+) (*scim.User, error) {
+	logger := middleware.GetLogger(ctx)
+
+	scimUserID, err := parseSCIMID(id, "User")
+	if err != nil {
+		return nil, err
+	}
+
+	user, change, err := s.provisioningRepo.UpdateUser(ctx.Request().Context(), workspaceID, scimUserID,
+		func(user *provisioning.User) error {
+			current := toSCIMUser(user)
+			updated, err := update(&current)
+			if err != nil {
+				return err
+			}
+			attrs, err := toUserAttributes(updated)
+			if err != nil {
+				return err
+			}
+			user.UserAttributes = *attrs
+			return nil
+		})
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to update SCIM user")
+		return nil, err
+	}
+
+	s.recordMembershipChanges(ctx, workspaceID, optionalChange(change)...)
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "scim_user_updated").
+		Str("workspace_id", workspaceID.String()).
+		Str("scim_user_id", user.ID.String()).
+		Bool("active", user.Active).
+		Msg("SCIM user updated successfully")
+
+	result := toSCIMUser(user)
+	return &result, nil
+}
+
+// DeleteSCIMUser deprovisions the user, removing them from the workspace
+func (s *ProvisioningService) DeleteSCIMUser(ctx echo.Context, workspaceID uuid.UUID, id string) error {
+	logger := middleware.GetLogger(ctx)
+
+	scimUserID, err := parseSCIMID(id, "User")
+	if err != nil {
+		return err
+	}
+
+	change, err := s.provisioningRepo.DeleteUser(ctx.Request().Context(), workspaceID, scimUserID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to delete SCIM user")
+		return err
+	}
+
+	s.recordMembershipChanges(ctx, workspaceID, optionalChange(change)...)
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "scim_user_deleted").
+		Str("workspace_id", workspaceID.String()).
+		Str("scim_user_id", scimUserID.String()).
+		Msg("SCIM user deprovisioned successfully")
+
+	return nil
+}
+
+// ------------------------------------------------------------
+// SCIM groups
+
+func (s *ProvisioningService) GetSCIMGroups(ctx echo.Context, workspaceID uuid.UUID, filter string,
+	startIndex int, count int,
+) (*scim.ListResponse[scim.Group], error) {
+	logger := middleware.GetLogger(ctx)
+
+	startIndex, count = scimPage(startIndex, count)
+	query := &repository.GroupQuery{Offset: startIndex - 1, Limit: &count}
+
+	if filter != "" {
+		parsed, err := scim.ParseFilter(filter)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case parsed.Is("displayName"):
+			query.DisplayName = &parsed.Value
+		case parsed.Is("externalId"):
+			query.ExternalID = &parsed.Value
+		default:
+			return nil, scim.NewError(http.StatusBadRequest, scim.ErrorTypeInvalidFilter,
+				"Groups can only be filtered by displayName or externalId")
+		}
+	}
+
+	groups, total, err := s.provisioningRepo.GetGroups(ctx.Request().Context(), workspaceID, query)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch SCIM groups")
+		return nil, err
+	}
+
+	resources := make([]scim.Group, len(groups))
+	for i := range groups {
+		resources[i] = toSCIMGroup(&groups[i])
+	}
+
+	return scim.NewListResponse(resources, total, startIndex), nil
+}
+
+func (s *ProvisioningService) GetSCIMGroup(ctx echo.Context, workspaceID uuid.UUID, id string) (*scim.Group, error) {
+	logger := middleware.GetLogger(ctx)
+
+	groupID, err := parseSCIMID(id, "Group")
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := s.provisioningRepo.GetGroupByID(ctx.Request().Context(), workspaceID, groupID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch SCIM group")
+		return nil, err
+	}
+
+	result := toSCIMGroup(group)
+	return &result, nil
+}
+
+// CreateSCIMGroup creates a group. It has no role until a workspace admin
+// maps it to one.
+func (s *ProvisioningService) CreateSCIMGroup(ctx echo.Context, workspaceID uuid.UUID,
+	input *scim.Group,
+) (*scim.Group, error) {
+	logger := middleware.GetLogger(ctx)
+
+	attrs, err := toGroupAttributes(input)
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := s.provisioningRepo.CreateGroup(ctx.Request().Context(), workspaceID, attrs)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to create SCIM group")
+		return nil, err
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "scim_group_created").
+		Str("workspace_id", workspaceID.String()).
+		Str("group_id", group.ID.String()).
+		Int("members", len(group.MemberIDs)).
+		Msg("SCIM group created successfully")
+
+	result := toSCIMGroup(group)
+	return &result, nil
+}
+
+func (s *ProvisioningService) ReplaceSCIMGroup(ctx echo.Context, workspaceID uuid.UUID, id string,
+	input *scim.Group,
+) (*scim.Group, error) {
+	return s.updateSCIMGroup(ctx, workspaceID, id, func(*scim.Group) (*scim.Group, error) {
+		return input, nil
+	})
+}
+
+func (s *ProvisioningService) PatchSCIMGroup(ctx echo.Context, workspaceID uuid.UUID, id string,
+	patch *scim.PatchRequest,
+) (*scim.Group, error) {
+	if err := patch.Validate(); err != nil {
+		return nil, err
+	}
+
+	return s.updateSCIMGroup(ctx, workspaceID, id, func(current *scim.Group) (*scim.Group, error) {
+		return current, patch.ApplyToGroup(current)
+	})
+}
+
+func (s *ProvisioningService) updateSCIMGroup(ctx echo.Context, workspaceID uuid.UUID, id string,
+	update func(current *scim.Group) (*scim.Group, error),
+) (*scim.Group, error) {
+	logger := middleware.GetLogger(ctx)
+
+	groupID, err := parseSCIMID(id, "Group")
+	if err != nil {
+		return nil, err
+	}
+
+	group, changes, err := s.provisioningRepo.UpdateGroup(ctx.Request().Context(), workspaceID, groupID,
+		func(group *provisioning.Group) error {
+			current := toSCIMGroup(group)
+			updated, err := update(&current)
+			if err != nil {
+				return err
+			}
+			attrs, err := toGroupAttributes(updated)
+			if err != nil {
+				return err
+			}
+			group.GroupAttributes = *attrs
+			return nil
+		})
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to update SCIM group")
+		return nil, err
+	}
+
+	s.recordMembershipChanges(ctx, workspaceID, changes...)
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "scim_group_updated").
+		Str("workspace_id", workspaceID.String()).
+		Str("group_id", group.ID.String()).
+		Int("members", len(group.MemberIDs)).
+		Int("memberships_changed", len(changes)).
+		Msg("SCIM group updated successfully")
+
+	result := toSCIMGroup(group)
+	return &result, nil
+}
+
+func (s *ProvisioningService) DeleteSCIMGroup(ctx echo.Context, workspaceID uuid.UUID, id string) error {
+	logger := middleware.GetLogger(ctx)
+
+	groupID, err := parseSCIMID(id, "Group")
+	if err != nil {
+		return err
+	}
+
+	changes, err := s.provisioningRepo.DeleteGroup(ctx.Request().Context(), workspaceID, groupID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to delete SCIM group")
+		return err
+	}
+
+	s.recordMembershipChanges(ctx, workspaceID, changes...)
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "scim_group_deleted").
+		Str("workspace_id", workspaceID.String()).
+		Str("group_id", groupID.String()).
+		Int("memberships_changed", len(changes)).
+		Msg("SCIM group deleted successfully")
+
+	return nil
+}
+
+// ------------------------------------------------------------
+// Invitations, accepted by the invited user
+
+// GetInvitations returns the user's pending invitations to workspaces
+func (s *ProvisioningService) GetInvitations(ctx echo.Context, userID string) ([]provisioning.User, error) {
+	logger := middleware.GetLogger(ctx)
+
+	invitations, err := s.provisioningRepo.GetInvitations(ctx.Request().Context(), userID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch invitations")
+		return nil, err
+	}
+
+	return invitations, nil
+}
+
+// AcceptInvitation accepts the user's invitation, making them a member of
+// the workspace that provisioned them
+func (s *ProvisioningService) AcceptInvitation(ctx echo.Context, userID string,
+	payload *provisioning.AcceptInvitationPayload,
+) (*provisioning.User, error) {
+	logger := middleware.GetLogger(ctx)
+
+	user, change, err := s.provisioningRepo.AcceptInvitation(ctx.Request().Context(), userID, payload.ID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to accept invitation")
+		return nil, err
+	}
+
+	s.recordMembershipChanges(ctx, user.WorkspaceID, optionalChange(change)...)
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "invitation_accepted").
+		Str("workspace_id", user.WorkspaceID.String()).
+		Str("scim_user_id", user.ID.String()).
+		Msg("invitation accepted successfully")
+
+	return user, nil
+}
+
+// ------------------------------------------------------------
+
+// recordMembershipChanges audits provisioning's changes to memberships as
+// the member events an admin's changes would produce
+func (s *ProvisioningService) recordMembershipChanges(ctx echo.Context, workspaceID uuid.UUID,
+	changes ...provisioning.MembershipChange,
+) {
+	for _, change := range changes {
+		target := audit.Target{Type: "member", ID: change.UserID}
+		details := map[string]string{"source": provisioning.ActorID}
+
+		switch {
+		case change.To == nil:
+			s.audit.Record(ctx, &workspaceID, audit.EventMemberRemoved, target, details)
+		case change.From == nil:
+			details["role"] = string(*change.To)
+			s.audit.Record(ctx, &workspaceID, audit.EventMemberAdded, target, details)
+		default:
+			details["role"] = string(*change.To)
+			s.audit.Record(ctx, &workspaceID, audit.EventMemberRoleChanged, target, details)
+		}
+	}
+}
+
+func optionalChange(change *provisioning.MembershipChange) []provisioning.MembershipChange {
+	if change == nil {
+		return nil
+	}
+	return []provisioning.MembershipChange{*change}
+}
+
+// scimPage clamps a SCIM page, whose start index is 1-based and whose count
+// is 0 to only get the total
+func scimPage(startIndex int, count int) (int, int) {
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	if count < 0 {
+		count = 0
+	}
+	if count > MaxSCIMResults {
+		count = MaxSCIMResults
+	}
+	return startIndex, count
+}
+
+// parseSCIMID parses a resource id from the path. Ids that can't be ours
+// are reported as not found, as SCIM expects.
+func parseSCIMID(id string, resourceType string) (uuid.UUID, error) {
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, scim.NewError(http.StatusNotFound, "", resourceType+" "+id+" not found")
+	}
+	return parsed, nil
+}
+
+func toSCIMUser(u *provisioning.User) scim.User {
+	active := u.Active
+	user := scim.User{
+		Schemas:     []string{scim.SchemaUser},
+		ID:          u.ID.String(),
+		ExternalID:  stringValue(u.ExternalID),
+		UserName:    u.UserName,
+		DisplayName: stringValue(u.DisplayName),
+		Emails:      []scim.Email{{Value: u.Email, Type: "work", Primary: true}},
+		Active:      &active,
+		Meta: &scim.Meta{
+			ResourceType: "User",
+			Created:      u.CreatedAt,
+			LastModified: u.UpdatedAt,
+		},
+	}
+	if u.GivenName != nil || u.FamilyName != nil {
+		user.Name = &scim.Name{
+			GivenName:  stringValue(u.GivenName),
+			FamilyName: stringValue(u.FamilyName),
+		}
+	}
+	return user
+}
+
+func toUserAttributes(u *scim.User) (*provisioning.UserAttributes, error) {
+	userName := strings.TrimSpace(u.UserName)
+	if userName == "" {
+		return nil, scim.NewError(http.StatusBadRequest, scim.ErrorTypeInvalidValue, "userName is required")
+	}
+
+	email := u.PrimaryEmail()
+	if email == "" {
+		return nil, scim.NewError(http.StatusBadRequest, scim.ErrorTypeInvalidValue, "An email address is required")
+	}
+
+	attrs := &provisioning.UserAttributes{
+		ExternalID:  stringPointer(u.ExternalID),
+		UserName:    userName,
+		Email:       email,
+		DisplayName: stringPointer(u.DisplayName),
+		Active:      u.IsActive(),
+	}
+	if u.Name != nil {
+		attrs.GivenName = stringPointer(u.Name.GivenName)
+		attrs.FamilyName = stringPointer(u.Name.FamilyName)
+	}
+
+	return attrs, nil
+}
+
+func toSCIMGroup(g *provisioning.Group) scim.Group {
+	members := make([]scim.Ref, len(g.MemberIDs))
+	for i, id := range g.MemberIDs {
+		members[i] = scim.Ref{Value: id.String()}
+	}
+
+	return scim.Group{
+		Schemas:     []string{scim.SchemaGroup},
+		ID:          g.ID.String(),
+		ExternalID:  stringValue(g.ExternalID),
+		DisplayName: g.DisplayName,
+		Members:     members,
+		Meta: &scim.Meta{
+			ResourceType: "Group",
+			Created:      g.CreatedAt,
+			LastModified: g.UpdatedAt,
+		},
+	}
+}
+
+func toGroupAttributes(g *scim.Group) (*provisioning.GroupAttributes, error) {
+	displayName := strings.TrimSpace(g.DisplayName)
+	if displayName == "" {
+		return nil, scim.NewError(http.StatusBadRequest, scim.ErrorTypeInvalidValue, "displayName is required")
+	}
+
+	memberIDs := make([]uuid.UUID, 0, len(g.Members))
+	for _, member := range g.Members {
+		id, err := uuid.Parse(member.Value)
+		if err != nil {
+			return nil, scim.NewError(http.StatusBadRequest, scim.ErrorTypeInvalidValue,
+				"Member "+member.Value+" is not a provisioned user")
+		}
+		memberIDs = append(memberIDs, id)
+	}
+
+	return &provisioning.GroupAttributes{
+		ExternalID:  stringPointer(g.ExternalID),
+		DisplayName: displayName,
+		MemberIDs:   memberIDs,
+	}, nil
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func stringPointer(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func generateSCIMToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return scimTokenPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashSCIMToken is how tokens are stored and looked up. Tokens are random,
+// so a fast hash is enough.
+func hashSCIMToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/lib/job"
+	"github.com/mabhi256/tasker/internal/lib/metrics"
+	"github.com/mabhi256/tasker/internal/lib/push"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/device"
+	"github.com/mabhi256/tasker/internal/repository"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// PushService manages the devices users registered for push notifications
+// and delivers notifications to them. Devices belong to the user rather
+// than a workspace.
+type PushService struct {
+	server     *server.Server
+	deviceRepo *repository.DeviceRepository
+}
+
+func NewPushService(server *server.Server, deviceRepo *repository.DeviceRepository) *PushService {
+	return &PushService{
+		server:     server,
+		deviceRepo: deviceRepo,
+	}
+}
+
+func (s *PushService) GetPushConfig(ctx echo.Context) (*device.PushConfig, error) {
+	config := &device.PushConfig{Platforms: s.server.Push.Platforms()}
+	if key := s.server.Push.VAPIDPublicKey(); key != "" {
+		config.VAPIDPublicKey = &key
+	}
+	return config, nil
+}
+
+func (s *PushService) RegisterDevice(ctx echo.Context, userID string,
+	payload *device.RegisterDevicePayload,
+) (*device.Device, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if payload.Platform == device.PlatformWeb {
+		// Messages are posted to the endpoint, so it must be a public host
+		if _, err := s.server.Fetcher.ValidateURL(ctx.Request().Context(), payload.Subscription.Endpoint); err != nil {
+			return nil, errs.NewBadRequestError("Push subscription endpoint is not allowed", false, nil, nil, nil)
+		}
+		subscription := &push.Subscription{
+			Endpoint: payload.Subscription.Endpoint,
+			P256dh:   payload.Subscription.Keys.P256dh,
+			Auth:     payload.Subscription.Keys.Auth,
+		}
+		if err := subscription.Validate(); err != nil {
+			return nil, errs.NewBadRequestError("Push subscription keys are invalid", false, nil, nil, nil)
+		}
+	}
+
+	deviceItem, err := s.deviceRepo.RegisterDevice(ctx.Request().Context(), userID, payload)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to register push device")
+		return nil, err
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "push_device_registered").
+		Str("device_id", deviceItem.ID.String()).
+		Str("platform", string(deviceItem.Platform)).
+		Msg("Push device registered successfully")
+
+	return deviceItem, nil
+}
+
+func (s *PushService) GetDevices(ctx echo.Context, userID string) ([]device.Device, error) {
+	logger := middleware.GetLogger(ctx)
+
+	devices, err := s.deviceRepo.GetDevices(ctx.Request().Context(), userID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch push devices")
+		return nil, err
+	}
+
+	return devices, nil
+}
+
+func (s *PushService) DeleteDevice(ctx echo.Context, userID string, deviceID uuid.UUID) error {
+	logger := middleware.GetLogger(ctx)
+
+	err := s.deviceRepo.DeleteDevice(ctx.Request().Context(), userID, deviceID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to delete push device")
+		return err
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "push_device_deleted").
+		Str("device_id", deviceID.String()).
+		Msg("Push device deleted successfully")
+
+	return nil
+}
+
+// NotifyUser implements job.PushSenderInterface. Each device gets its own
+// task so a failing device is retried without resending to the others.
+func (s *PushService) NotifyUser(ctx context.Context, userID string, msg *push.Message) error {
+	devices, err := s.deviceRepo.GetDevices(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	var errList []error
+	for _, d := range devices {
+		errList = append(errList, job.Enqueue(ctx, s.server.Job.Client, &job.PushDeliveryTask{
+			DeviceID: d.ID,
+			Message:  *msg,
+		}))
+	}
+
+	return errors.Join(errList...)
+}
+
+// DeliverPush implements job.PushSenderInterface
+func (s *PushService) DeliverPush(ctx context.Context, deviceID uuid.UUID, msg *push.Message, final bool) error {
+	d, err := s.deviceRepo.GetDeviceByID(ctx, deviceID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			s.server.Logger.Info().Str("device_id", deviceID.String()).Msg("push device was deleted, skipping")
+			return nil
+		}
+		return err
+	}
+
+	err = s.server.Push.Send(ctx, d, msg)
+	switch {
+	case err == nil:
+		s.server.Metrics.Inc(metrics.PushesSent)
+		return s.deviceRepo.MarkDeviceUsed(ctx, d.ID, time.Now())
+	case errors.Is(err, push.ErrGone):
+		s.server.Logger.Info().
+			Str("device_id", d.ID.String()).
+			Str("user_id", d.UserID).
+			Msg("push device is no longer registered, removing it")
+		return s.deviceRepo.RemoveDevice(ctx, d.ID)
+	case errors.Is(err, push.ErrNotConfigured):
+		s.server.Logger.Warn().
+			Str("device_id", d.ID.String()).
+			Str("platform", string(d.Platform)).
+			Msg("push platform is not configured, skipping")
+		return nil
+	default:
+		if final {
+			s.server.Metrics.Inc(metrics.PushesFailed)
+		}
+		return err
+	}
+}
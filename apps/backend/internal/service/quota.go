@@ -0,0 +1,172 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/lib/job"
+	"github.com/mabhi256/tasker/internal/lib/push"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/quota"
+	"github.com/mabhi256/tasker/internal/repository"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+type QuotaService struct {
+	server    *server.Server
+	usageRepo *repository.UsageRepository
+}
+
+func NewQuotaService(server *server.Server, usageRepo *repository.UsageRepository) *QuotaService {
+	return &QuotaService{
+		server:    server,
+		usageRepo: usageRepo,
+	}
+}
+
+// CheckStorage refuses an upload of size bytes that would take the user or
+// the workspace past its storage quota. It returns the storage used before
+// the upload, for WarnStorage.
+func (s *QuotaService) CheckStorage(ctx echo.Context, workspaceID uuid.UUID, userID string,
+	size int64,
+) (*quota.StorageUsage, error) {
+	logger := middleware.GetLogger(ctx)
+	cfg := s.server.Config.Attachments.Quota
+
+	storage, err := s.usageRepo.GetStorageUsage(ctx.Request().Context(), workspaceID, userID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch storage usage")
+		return nil, err
+	}
+
+	code := "STORAGE_QUOTA_EXCEEDED"
+	if cfg.UserBytes > 0 && storage.UserBytes+size > cfg.UserBytes {
+		logger.Warn().
+			Int64("used_bytes", storage.UserBytes).
+			Int64("quota_bytes", cfg.UserBytes).
+			Int64("size", size).
+			Msg("upload refused over user storage quota")
+		return nil, errs.NewUnprocessableError("attachment would exceed your storage quota", false, &code, nil, nil)
+	}
+	if cfg.WorkspaceBytes > 0 && storage.WorkspaceBytes+size > cfg.WorkspaceBytes {
+		logger.Warn().
+			Int64("used_bytes", storage.WorkspaceBytes).
+			Int64("quota_bytes", cfg.WorkspaceBytes).
+			Int64("size", size).
+			Msg("upload refused over workspace storage quota")
+		return nil, errs.NewUnprocessableError("attachment would exceed the workspace's storage quota", false, &code, nil, nil)
+	}
+
+	return storage, nil
+}
+
+// WarnStorage notifies the uploader when an upload of size bytes took their
+// storage past the warning threshold or used it up, and the workspace's
+// owner when it did so for the workspace
+func (s *QuotaService) WarnStorage(ctx echo.Context, workspaceID uuid.UUID, userID string,
+	before *quota.StorageUsage, size int64,
+) {
+	cfg := s.server.Config.Attachments.Quota
+
+	percent := crossedThreshold(before.UserBytes, size, cfg.UserBytes, cfg.WarnPercent)
+	if percent > 0 {
+		s.enqueueStorageWarning(ctx, userID, "user-"+userID, percent,
+			fmt.Sprintf("You've used %d%% of your attachment storage.", percent))
+	}
+
+	percent = crossedThreshold(before.WorkspaceBytes, size, cfg.WorkspaceBytes, cfg.WarnPercent)
+	if percent > 0 {
+		s.enqueueStorageWarning(ctx, before.OwnerID, "workspace-"+workspaceID.String(), percent,
+			fmt.Sprintf("Your workspace has used %d%% of its attachment storage.", percent))
+	}
+}
+
+// crossedThreshold returns the percent of quotaBytes, 100 or warnPercent,
+// that adding size bytes to used reached, or 0 for none
+func crossedThreshold(used, size, quotaBytes int64, warnPercent int) int {
+	if quotaBytes <= 0 {
+		return 0
+	}
+	for _, percent := range []int{100, warnPercent} {
+		threshold := quotaBytes * int64(percent) / 100
+		if used < threshold && used+size >= threshold {
+			return percent
+		}
+	}
+	return 0
+}
+
+func (s *QuotaService) enqueueStorageWarning(ctx echo.Context, userID, scope string, percent int, body string) {
+	logger := middleware.GetLogger(ctx)
+
+	title := "Storage almost full"
+	if percent >= 100 {
+		title = "Storage full"
+	}
+
+	// Tagged per quota, so a full warning replaces the earlier one
+	err := job.Enqueue(ctx.Request().Context(), s.server.Job.Client, &job.PushNotificationTask{
+		UserID: userID,
+		Message: push.Message{
+			Title: title,
+			Body:  body,
+			URL:   "/settings",
+			Tag:   "storage-quota-" + scope,
+		},
+	})
+	if err != nil {
+		logger.Error().Err(err).Str("user_id", userID).Msg("failed to enqueue storage quota push notification")
+		return
+	}
+
+	logger.Info().
+		Str("user_id", userID).
+		Str("quota", scope).
+		Int("percent", percent).
+		Msg("enqueued storage quota warning")
+}
+
+// GetUsage returns the storage the user and the workspace use against their
+// quotas, and the requests made in the workspace
+func (s *QuotaService) GetUsage(ctx echo.Context, workspaceID uuid.UUID, userID string) (*quota.Usage, error) {
+	logger := middleware.GetLogger(ctx)
+	cfg := s.server.Config.Attachments.Quota
+
+	storage, err := s.usageRepo.GetStorageUsage(ctx.Request().Context(), workspaceID, userID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch storage usage")
+		return nil, err
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	apiUsage, err := s.usageRepo.GetAPIUsage(ctx.Request().Context(), workspaceID, today)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch API usage")
+		return nil, err
+	}
+
+	return &quota.Usage{
+		WorkspaceID: workspaceID,
+		Storage: quota.Storage{
+			User:      allowance(storage.UserBytes, storage.UserAttachments, cfg.UserBytes),
+			Workspace: allowance(storage.WorkspaceBytes, storage.WorkspaceAttachments, cfg.WorkspaceBytes),
+		},
+		API: quota.API{
+			RequestsToday:      apiUsage.RequestsToday,
+			RequestsLast30Days: apiUsage.RequestsLast30Days,
+		},
+	}, nil
+}
+
+func allowance(used int64, attachments int, quotaBytes int64) quota.Allowance {
+	a := quota.Allowance{UsedBytes: used, Attachments: attachments}
+	if quotaBytes > 0 {
+		percent := float64(used) / float64(quotaBytes) * 100
+		a.QuotaBytes = &quotaBytes
+		a.Percent = &percent
+	}
+	return a
+}
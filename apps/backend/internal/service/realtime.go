@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/presence"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// RealtimeService answers questions about and sends messages to clients
+// connected over websockets, whichever instance holds their connection
+type RealtimeService struct {
+	server *server.Server
+}
+
+func NewRealtimeService(server *server.Server) *RealtimeService {
+	return &RealtimeService{server: server}
+}
+
+func (s *RealtimeService) GetPresence(ctx echo.Context, workspaceID uuid.UUID) ([]presence.Presence, error) {
+	logger := middleware.GetLogger(ctx)
+
+	users, err := s.server.Realtime.Presence(ctx.Request().Context(), workspaceID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get workspace presence")
+		return nil, err
+	}
+
+	items := make([]presence.Presence, 0, len(users))
+	for userID, connections := range users {
+		items = append(items, presence.Presence{UserID: userID, Connections: connections})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].UserID < items[j].UserID
+	})
+
+	return items, nil
+}
+
+// SendToUser delivers message to a user's connections to a workspace
+func (s *RealtimeService) SendToUser(ctx context.Context, userID string, workspaceID uuid.UUID, message any) error {
+	return s.server.Realtime.SendToUser(ctx, userID, &workspaceID, message)
+}
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/lib/aws"
+	"github.com/mabhi256/tasker/internal/lib/job"
+	"github.com/mabhi256/tasker/internal/lib/pdf"
+	"github.com/mabhi256/tasker/internal/lib/usage"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/report"
+	"github.com/mabhi256/tasker/internal/model/todo"
+	"github.com/mabhi256/tasker/internal/repository"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// maxReportErrorLength keeps stored errors readable
+const maxReportErrorLength = 1000
+
+// ReportService generates PDF summaries and access reviews of a workspace in
+// the background and hands them out through signed download links
+type ReportService struct {
+	server        *server.Server
+	reportRepo    *repository.ReportRepository
+	todoRepo      *repository.TodoRepository
+	workspaceRepo *repository.WorkspaceRepository
+	awsClient     *aws.AWS
+	identity      IdentityProvider
+}
+
+func NewReportService(server *server.Server, reportRepo *repository.ReportRepository,
+	todoRepo *repository.TodoRepository, workspaceRepo *repository.WorkspaceRepository, awsClient *aws.AWS,
+	identity IdentityProvider,
+) *ReportService {
+	return &ReportService{
+		server:        server,
+		reportRepo:    reportRepo,
+		todoRepo:      todoRepo,
+		workspaceRepo: workspaceRepo,
+		awsClient:     awsClient,
+		identity:      identity,
+	}
+}
+
+// CreateReport queues a summary of the workspace for the user
+func (s *ReportService) CreateReport(ctx echo.Context, workspaceID uuid.UUID, userID string,
+	payload *report.CreateReportPayload,
+) (*report.Report, error) {
+	return s.createReport(ctx, workspaceID, userID, report.KindSummary, payload)
+}
+
+func (s *ReportService) createReport(ctx echo.Context, workspaceID uuid.UUID, userID string,
+	kind report.Kind, payload *report.CreateReportPayload,
+) (*report.Report, error) {
+	logger := middleware.GetLogger(ctx)
+
+	reportItem, err := s.reportRepo.CreateReport(ctx.Request().Context(), workspaceID, userID, kind, payload)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to create report")
+		return nil, err
+	}
+
+	err = job.Enqueue(ctx.Request().Context(), s.server.Job.Client, &job.ReportGenerateTask{ReportID: reportItem.ID})
+	if err != nil {
+		logger.Error().Err(err).Str("report_id", reportItem.ID.String()).Msg("failed to enqueue report")
+		return nil, err
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "report_requested").
+		Str("report_id", reportItem.ID.String()).
+		Str("kind", string(kind)).
+		Bool("send_email", payload.SendEmail).
+		Msg("Report requested successfully")
+
+	return reportItem, nil
+}
+
+// GetReport returns one of the user's reports, with a fresh download link
+// once it has been generated
+func (s *ReportService) GetReport(ctx echo.Context, workspaceID uuid.UUID, userID string,
+	reportID uuid.UUID,
+) (*report.Report, error) {
+	logger := middleware.GetLogger(ctx)
+
+	reportItem, err := s.reportRepo.GetReportByID(ctx.Request().Context(), workspaceID, userID, reportID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch report by ID")
+		return nil, err
+	}
+
+	if reportItem.Status == report.StatusSucceeded && reportItem.ObjectKey != nil {
+		url, err := s.awsClient.S3.CreatePresignedUrl(
+			ctx.Request().Context(),
+			s.server.Config.AWS.UploadBucket,
+			*reportItem.ObjectKey,
+		)
+		if err != nil {
+			logger.Error().Err(err).Str("report_id", reportID.String()).Msg("failed to sign report download url")
+			return nil, err
+		}
+		reportItem.DownloadURL = &url
+	}
+
+	return reportItem, nil
+}
+
+// GenerateReport implements job.ReportGeneratorInterface
+func (s *ReportService) GenerateReport(ctx context.Context, reportID uuid.UUID) error {
+	reportItem, err := s.reportRepo.StartReport(ctx, reportID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			s.server.Logger.Info().Str("report_id", reportID.String()).Msg("report is no longer pending, skipping")
+			return nil
+		}
+		return err
+	}
+
+	// Reports are queued without a workspace, so charge the work here
+	ctx = usage.WithWorkspace(ctx, reportItem.WorkspaceID)
+
+	ws, err := s.workspaceRepo.GetWorkspaceByID(ctx, reportItem.WorkspaceID)
+	if err != nil {
+		return err
+	}
+
+	var body []byte
+	extension := "pdf"
+	switch reportItem.Kind {
+	case report.KindAccess:
+		body, err = s.buildAccessReportCSV(ctx, reportItem.WorkspaceID)
+		extension = "csv"
+	default:
+		body, err = s.buildSummaryReport(ctx, reportItem.WorkspaceID, ws.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	objectKey, err := s.awsClient.S3.UploadFile(
+		ctx,
+		s.server.Config.AWS.UploadBucket,
+		fmt.Sprintf("reports/%s/%s.%s", reportItem.WorkspaceID, reportItem.ID, extension),
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := s.reportRepo.CompleteReport(ctx, reportID, objectKey, int64(len(body))); err != nil {
+		return err
+	}
+
+	if reportItem.SendEmail {
+		url, err := s.awsClient.S3.CreatePresignedUrl(ctx, s.server.Config.AWS.UploadBucket, objectKey)
+		if err != nil {
+			return fmt.Errorf("failed to sign report download url: %w", err)
+		}
+
+		err = job.Enqueue(ctx, s.server.Job.Client, &job.ReportReadyEmailTask{
+			UserID:        reportItem.UserID,
+			ReportID:      reportItem.ID,
+			WorkspaceName: ws.Name,
+			DownloadURL:   url,
+		})
+		if err != nil {
+			// The report is already stored, so retrying would only build it again
+			s.server.Logger.Error().Err(err).Str("report_id", reportID.String()).Msg("failed to enqueue report ready email")
+		}
+	}
+
+	// Business event log
+	s.server.Logger.Info().
+		Str("event", "report_generated").
+		Str("report_id", reportID.String()).
+		Str("workspace_id", reportItem.WorkspaceID.String()).
+		Str("kind", string(reportItem.Kind)).
+		Int("size_bytes", len(body)).
+		Msg("Report generated successfully")
+
+	return nil
+}
+
+// RecordReportFailure implements job.ReportGeneratorInterface
+func (s *ReportService) RecordReportFailure(ctx context.Context, reportID uuid.UUID, reportErr error,
+	final bool,
+) error {
+	errMsg := reportErr.Error()
+	if len(errMsg) > maxReportErrorLength {
+		errMsg = errMsg[:maxReportErrorLength]
+	}
+
+	return s.reportRepo.RecordReportError(ctx, reportID, errMsg, final)
+}
+
+// buildSummaryReport renders the PDF summary of the workspace's todos
+func (s *ReportService) buildSummaryReport(ctx context.Context, workspaceID uuid.UUID,
+	workspaceName string,
+) ([]byte, error) {
+	stats, err := s.todoRepo.GetTodoStats(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	overdue, err := s.todoRepo.GetOverdueTodosForReport(ctx, workspaceID, report.MaxListed)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	completed, err := s.todoRepo.GetCompletedTodosForReport(ctx, workspaceID,
+		now.Add(-report.CompletedWindow), report.MaxListed)
+	if err != nil {
+		return nil, err
+	}
+
+	return renderReport(workspaceName, now, stats, overdue, completed).Bytes(), nil
+}
+
+func renderReport(workspaceName string, now time.Time, stats *todo.TodoStats,
+	overdue []todo.Todo, completed []todo.Todo,
+) *pdf.Document {
+	doc := pdf.New(workspaceName + " report")
+
+	doc.Write(pdf.TitleStyle, workspaceName)
+	doc.Write(pdf.MutedStyle, "Generated "+now.UTC().Format("Monday, January 2, 2006 at 3:04 PM MST"))
+	doc.Space(12)
+
+	doc.Write(pdf.HeadingStyle, "Summary")
+	doc.Write(pdf.BodyStyle, fmt.Sprintf("%d todos: %d active, %d draft, %d completed, %d archived",
+		stats.Total, stats.Active, stats.Draft, stats.Completed, stats.Archived))
+	doc.Write(pdf.BodyStyle, fmt.Sprintf("%d overdue", stats.Overdue))
+	doc.Space(12)
+
+	doc.Write(pdf.HeadingStyle, "Overdue")
+	if len(overdue) == 0 {
+		doc.Write(pdf.MutedStyle, "Nothing is overdue.")
+	}
+	for _, t := range overdue {
+		doc.Write(pdf.BodyStyle, fmt.Sprintf("%s (%s priority)", t.Title, t.Priority))
+		doc.Write(pdf.MutedStyle, "Due "+t.DueDate.UTC().Format("Jan 2, 2006"))
+	}
+	if stats.Overdue > len(overdue) {
+		doc.Write(pdf.MutedStyle, fmt.Sprintf("And %d more.", stats.Overdue-len(overdue)))
+	}
+	doc.Space(12)
+
+	doc.Write(pdf.HeadingStyle, "Completed in the last 7 days")
+	if len(completed) == 0 {
+		doc.Write(pdf.MutedStyle, "Nothing was completed.")
+	}
+	for _, t := range completed {
+		doc.Write(pdf.BodyStyle, t.Title)
+		doc.Write(pdf.MutedStyle, "Completed "+t.CompletedAt.UTC().Format("Jan 2, 2006"))
+	}
+
+	return doc
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/resolve"
+	"github.com/mabhi256/tasker/internal/repository"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// ResolveService turns the ids clients hold in references, such as mentions
+// and links, into summaries in one round trip
+type ResolveService struct {
+	server        *server.Server
+	todoRepo      *repository.TodoRepository
+	categoryRepo  *repository.CategoryRepository
+	commentRepo   *repository.CommentRepository
+	workspaceRepo *repository.WorkspaceRepository
+	identity      IdentityProvider
+}
+
+func NewResolveService(server *server.Server, todoRepo *repository.TodoRepository,
+	categoryRepo *repository.CategoryRepository, commentRepo *repository.CommentRepository,
+	workspaceRepo *repository.WorkspaceRepository, identity IdentityProvider,
+) *ResolveService {
+	return &ResolveService{
+		server:        server,
+		todoRepo:      todoRepo,
+		categoryRepo:  categoryRepo,
+		commentRepo:   commentRepo,
+		workspaceRepo: workspaceRepo,
+		identity:      identity,
+	}
+}
+
+// Resolve summarizes the todos, categories and comments of the workspace
+// and the workspace's members among the payload's ids
+func (s *ResolveService) Resolve(ctx echo.Context, workspaceID uuid.UUID,
+	payload *resolve.ResolvePayload,
+) (*resolve.Response, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if n := payload.Count(); n > resolve.MaxIDs {
+		code := "TOO_MANY_IDS"
+		return nil, errs.NewBadRequestError("At most "+strconv.Itoa(resolve.MaxIDs)+" ids can be resolved at once",
+			false, &code, nil, nil)
+	}
+
+	reqCtx := ctx.Request().Context()
+	response := &resolve.Response{
+		Todos:      make(map[uuid.UUID]any),
+		Users:      make(map[string]resolve.UserSummary),
+		Categories: make(map[uuid.UUID]any),
+		Comments:   make(map[uuid.UUID]any),
+	}
+
+	todos, err := s.todoRepo.GetTodosByIDs(reqCtx, workspaceID, payload.Todos)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch todos by IDs")
+		return nil, err
+	}
+	for i := range todos {
+		response.Todos[todos[i].ID] = todos[i].Compact()
+	}
+
+	categories, err := s.categoryRepo.GetCategoriesByIDs(reqCtx, workspaceID, payload.Categories)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch categories by IDs")
+		return nil, err
+	}
+	for i := range categories {
+		response.Categories[categories[i].ID] = categories[i].Compact()
+	}
+
+	comments, err := s.commentRepo.GetCommentsByIDs(reqCtx, workspaceID, payload.Comments)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch comments by IDs")
+		return nil, err
+	}
+	for i := range comments {
+		response.Comments[comments[i].ID] = comments[i].Compact()
+	}
+
+	// Only members are resolved, so ids can't be used to look up other users
+	if len(payload.Users) > 0 {
+		memberIDs, err := s.workspaceRepo.GetMemberIDs(reqCtx, workspaceID, payload.Users)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to fetch member IDs")
+			return nil, err
+		}
+
+		users, err := s.identity.GetUserSummaries(reqCtx, memberIDs)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to fetch user summaries")
+			return nil, err
+		}
+		for _, user := range users {
+			response.Users[user.ID] = user
+		}
+	}
+
+	return response, nil
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/database"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/lib/job"
+	"github.com/mabhi256/tasker/internal/lib/rollout"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/admin"
+	"github.com/mabhi256/tasker/internal/repository"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// RolloutService runs and reports on the backfills of the expand/contract
+// changes in database.Rollouts
+type RolloutService struct {
+	server      *server.Server
+	rolloutRepo *repository.RolloutRepository
+}
+
+func NewRolloutService(server *server.Server, rolloutRepo *repository.RolloutRepository) *RolloutService {
+	return &RolloutService{
+		server:      server,
+		rolloutRepo: rolloutRepo,
+	}
+}
+
+func (s *RolloutService) GetRollouts(ctx echo.Context) ([]admin.Rollout, error) {
+	logger := middleware.GetLogger(ctx)
+
+	progress, err := s.rolloutRepo.GetRolloutProgress(ctx.Request().Context())
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch rollout progress")
+		return nil, err
+	}
+
+	byName := make(map[string]*admin.RolloutProgress, len(progress))
+	for i := range progress {
+		byName[progress[i].Name] = &progress[i]
+	}
+
+	defined := database.Rollouts.List()
+	rollouts := make([]admin.Rollout, 0, len(defined))
+	for _, r := range defined {
+		rollouts = append(rollouts, admin.Rollout{
+			Name:     r.Name,
+			Table:    r.Table,
+			Progress: byName[r.Name],
+		})
+	}
+
+	return rollouts, nil
+}
+
+// StartBackfill queues the rollout's backfill. It resumes a backfill that
+// stopped, and starts a finished one over.
+func (s *RolloutService) StartBackfill(ctx echo.Context, name string) error {
+	logger := middleware.GetLogger(ctx)
+
+	if _, err := getRollout(name); err != nil {
+		return err
+	}
+
+	if err := job.Enqueue(ctx.Request().Context(), s.server.Job.Client, &job.RolloutBackfillTask{Name: name}); err != nil {
+		logger.Error().Err(err).Str("rollout", name).Msg("failed to queue rollout backfill")
+		return err
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "rollout_backfill_started").
+		Str("rollout", name).
+		Msg("Rollout backfill queued")
+
+	return nil
+}
+
+// VerifyRollout runs the contract check, the same one Migrate runs before
+// applying the rollout's contract migration, and records the result
+func (s *RolloutService) VerifyRollout(ctx echo.Context, name string) (*admin.Rollout, error) {
+	logger := middleware.GetLogger(ctx)
+
+	r, err := getRollout(name)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := r.CountPending(ctx.Request().Context(), s.server.DB.Pool)
+	if err != nil {
+		logger.Error().Err(err).Str("rollout", name).Msg("failed to verify rollout")
+		return nil, err
+	}
+
+	progress, err := s.rolloutRepo.RecordVerification(ctx.Request().Context(), name, pending)
+	if err != nil {
+		logger.Error().Err(err).Str("rollout", name).Msg("failed to record rollout verification")
+		return nil, err
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "rollout_verified").
+		Str("rollout", name).
+		Int64("pending_rows", pending).
+		Msg("Rollout verification recorded")
+
+	return &admin.Rollout{Name: r.Name, Table: r.Table, Progress: progress}, nil
+}
+
+// RunBackfill implements job.RolloutBackfillerInterface. Batches run on one
+// connection holding an advisory lock, so a rollout is only ever backfilled
+// by one task at a time.
+func (s *RolloutService) RunBackfill(ctx context.Context, name string) (bool, error) {
+	r, ok := database.Rollouts.Get(name)
+	if !ok {
+		// The rollout was removed after the task was queued
+		s.server.Logger.Warn().Str("rollout", name).Msg("unknown rollout, skipping backfill")
+		return false, nil
+	}
+
+	cfg := s.server.Config.Rollouts
+
+	conn, err := s.server.DB.Pool.Acquire(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire connection for rollout %s: %w", name, err)
+	}
+	defer conn.Release()
+
+	lockKey := "rollout:" + name
+	var locked bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock(hashtext($1))`, lockKey).Scan(&locked); err != nil {
+		return false, fmt.Errorf("failed to lock rollout %s: %w", name, err)
+	}
+	if !locked {
+		s.server.Logger.Info().Str("rollout", name).Msg("rollout is already being backfilled, skipping")
+		return false, nil
+	}
+	defer func() {
+		// A context that ended mustn't leave the session holding the lock
+		if _, err := conn.Exec(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock(hashtext($1))`, lockKey); err != nil {
+			s.server.Logger.Error().Err(err).Str("rollout", name).Msg("failed to unlock rollout")
+		}
+	}()
+
+	progress, err := s.rolloutRepo.StartBackfill(ctx, name)
+	if err != nil {
+		return false, err
+	}
+
+	deadline := time.Now().Add(cfg.MaxRunTime)
+	after := progress.LastID
+	for time.Now().Before(deadline) {
+		updated, last, err := r.RunBatch(ctx, conn, after, cfg.BatchSize)
+		if err != nil {
+			return false, err
+		}
+
+		if last == nil {
+			return false, s.finishBackfill(ctx, r, conn)
+		}
+
+		after = *last
+		if err := s.rolloutRepo.RecordBatch(ctx, name, after, updated); err != nil {
+			return false, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(cfg.BatchPause):
+		}
+	}
+
+	s.server.Logger.Info().
+		Str("rollout", name).
+		Str("last_id", after.String()).
+		Msg("rollout backfill ran out of time, continuing in a new task")
+	return true, nil
+}
+
+// finishBackfill records the rows left pending once the backfill reached
+// the end of the table. Rows written since are covered by the dual write, so
+// any left were missed by it.
+func (s *RolloutService) finishBackfill(ctx context.Context, r *rollout.Rollout, q rollout.Querier) error {
+	pending, err := r.CountPending(ctx, q)
+	if err != nil {
+		return err
+	}
+
+	if err := s.rolloutRepo.FinishBackfill(ctx, r.Name, pending); err != nil {
+		return err
+	}
+
+	logEvent := s.server.Logger.Info()
+	if pending > 0 {
+		logEvent = s.server.Logger.Warn()
+	}
+	logEvent.
+		Str("rollout", r.Name).
+		Int64("pending_rows", pending).
+		Msg("rollout backfill reached the last row")
+
+	return nil
+}
+
+// RecordBackfillFailure implements job.RolloutBackfillerInterface
+func (s *RolloutService) RecordBackfillFailure(ctx context.Context, name string, runErr error, final bool) error {
+	return s.rolloutRepo.RecordBackfillError(ctx, name, runErr.Error(), final)
+}
+
+func getRollout(name string) (*rollout.Rollout, error) {
+	r, ok := database.Rollouts.Get(name)
+	if !ok {
+		code := "ROLLOUT_NOT_FOUND"
+		return nil, errs.NewNotFoundError("rollout not found", false, &code)
+	}
+	return r, nil
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/httpclient"
+	"github.com/mabhi256/tasker/internal/lib/metrics"
+	"github.com/mabhi256/tasker/internal/lib/search"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model"
+	"github.com/mabhi256/tasker/internal/model/todo"
+	"github.com/mabhi256/tasker/internal/repository"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// SearchService searches todos with the external engine when one is
+// configured, and with Postgres full text search otherwise. A failing engine
+// degrades search to Postgres instead of failing the request. Its client's
+// circuit breaker opens after repeated failures, so searches then go
+// straight to Postgres until the cooldown passes.
+type SearchService struct {
+	server   *server.Server
+	todoRepo *repository.TodoRepository
+}
+
+func NewSearchService(server *server.Server, todoRepo *repository.TodoRepository) *SearchService {
+	return &SearchService{
+		server:   server,
+		todoRepo: todoRepo,
+	}
+}
+
+func (s *SearchService) SearchTodos(ctx echo.Context, workspaceID uuid.UUID,
+	query *todo.SearchTodosQuery,
+) (*todo.SearchResponse, error) {
+	logger := middleware.GetLogger(ctx)
+
+	q := search.Query{
+		WorkspaceID: workspaceID,
+		Text:        query.Q,
+		Limit:       *query.Limit,
+		Offset:      (*query.Page - 1) * *query.Limit,
+	}
+
+	result, meta, err := s.search(ctx, q)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to search todos")
+		return nil, err
+	}
+
+	todos, err := s.todoRepo.GetTodosByIDs(ctx.Request().Context(), workspaceID, result.IDs)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch todos of search results")
+		return nil, err
+	}
+
+	if query.Render != nil {
+		for i := range todos {
+			todos[i].RenderContent()
+		}
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "todos_searched").
+		Str("engine", string(meta.Engine)).
+		Bool("degraded", meta.Degraded).
+		Int("total", result.Total).
+		Msg("Todos searched successfully")
+
+	return &todo.SearchResponse{
+		PaginatedResponse: model.PaginatedResponse[todo.PopulatedTodo]{
+			Data:       todos,
+			Page:       *query.Page,
+			Limit:      *query.Limit,
+			Total:      result.Total,
+			TotalPages: (result.Total + *query.Limit - 1) / *query.Limit,
+		},
+		Meta: meta,
+	}, nil
+}
+
+// search asks the external engine first, and Postgres when there is none or
+// it fails
+func (s *SearchService) search(ctx echo.Context, q search.Query) (*search.Result, todo.SearchMeta, error) {
+	reqCtx := ctx.Request().Context()
+
+	engine := s.server.Search
+	if engine != nil && engine.Configured() {
+		result, err := engine.Search(reqCtx, q)
+		if err == nil {
+			return result, todo.SearchMeta{Engine: todo.SearchEngineExternal}, nil
+		}
+
+		// The client gave up on the request, so there is nobody to fall back for
+		if errors.Is(err, context.Canceled) && reqCtx.Err() != nil {
+			return nil, todo.SearchMeta{}, err
+		}
+
+		event := middleware.GetLogger(ctx).Warn()
+		if !errors.Is(err, httpclient.ErrCircuitOpen) {
+			event = event.Err(err)
+		}
+		event.Bool("circuit_open", errors.Is(err, httpclient.ErrCircuitOpen)).
+			Msg("search engine unavailable, falling back to postgres")
+
+		s.server.Metrics.Inc(metrics.SearchesDegraded)
+
+		result, err = s.searchPostgres(reqCtx, q)
+		return result, todo.SearchMeta{Engine: todo.SearchEnginePostgres, Degraded: true}, err
+	}
+
+	result, err := s.searchPostgres(reqCtx, q)
+	return result, todo.SearchMeta{Engine: todo.SearchEnginePostgres}, err
+}
+
+func (s *SearchService) searchPostgres(ctx context.Context, q search.Query) (*search.Result, error) {
+	ids, total, err := s.todoRepo.SearchTodoIDs(ctx, q.WorkspaceID, q.Text, q.Limit, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+	return &search.Result{IDs: ids, Total: total}, nil
+}
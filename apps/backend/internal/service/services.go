@@ -1,37 +1,50 @@
 package service
 
 import (
-	"fmt"
-
-	"github.com/mabhi256/tasker/internal/lib/aws"
 	"github.com/mabhi256/tasker/internal/lib/job"
-	"github.com/mabhi256/tasker/internal/repository"
-	"github.com/mabhi256/tasker/internal/server"
 )
 
+// Services are built by the app container, which wires each service's
+// dependencies
 type Services struct {
-	Auth     *AuthService
-	Job      *job.JobService
-	Todo     *TodoService
-	Comment  *CommentService
-	Category *CategoryService
-}
-
-func NewServices(s *server.Server, repos *repository.Repositories) (*Services, error) {
-	authService := NewAuthService(s)
-
-	s.Job.SetAuthService(authService)
-
-	awsClient, err := aws.NewAWS(s)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create AWS client: %w", err)
-	}
-
-	return &Services{
-		Job:      s.Job,
-		Auth:     authService,
-		Category: NewCategoryService(s, repos.Category),
-		Comment:  NewCommentService(s, repos.Comment, repos.Todo),
-		Todo:     NewTodoService(s, repos.Todo, repos.Category, awsClient),
-	}, nil
+	Auth          *AuthService
+	Job           *job.JobService
+	Todo          *TodoService
+	Comment       *CommentService
+	Category      *CategoryService
+	Admin         *AdminService
+	Workspace     *WorkspaceService
+	Export        *ExportService
+	Webhook       *WebhookService
+	Outbox        *OutboxRelay
+	JobAdmin      *JobAdminService
+	JobFailure    *JobFailureService
+	Realtime      *RealtimeService
+	Digest        *DigestService
+	WeeklyReport  *WeeklyReportService
+	Usage         *UsageFlusher
+	Quota         *QuotaService
+	Validation    *ValidationReporter
+	Reload        *ConfigReloader
+	Push          *PushService
+	Rollout       *RolloutService
+	Backfill      *BackfillService
+	Search        *SearchService
+	Dependency    *DependencyService
+	Audit         *AuditService
+	Provisioning  *ProvisioningService
+	SSO           *SSOService
+	IPAllowlist   *IPAllowlistService
+	Session       *SessionService
+	APIKey        *APIKeyService
+	Resolve       *ResolveService
+	Capability    *CapabilityService
+	Report        *ReportService
+	Snapshot      *SnapshotService
+	Copy          *CopyService
+	Changelog     *ChangelogService
+	Tag           *TagService
+	Trial         *TrialService
+	Activity      *ActivityService
+	EmailTracking *EmailTrackingService
 }
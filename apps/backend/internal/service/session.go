@@ -0,0 +1,152 @@
+package service
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/audit"
+	"github.com/mabhi256/tasker/internal/model/session"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// revokedSessionTTL is how long a revoked session stays on the denylist. It
+// must outlive the session tokens issued before the revocation, which the
+// auth provider issues for a minute unless configured otherwise.
+const revokedSessionTTL = time.Hour
+
+// SessionService lists and revokes users' sessions. The auth provider
+// revokes a session, but the tokens it already issued verify until they
+// expire, so revoked sessions are also denied by RequireAuth through a
+// denylist in Redis and their realtime connections are closed.
+type SessionService struct {
+	server   *server.Server
+	sessions SessionProvider
+	audit    *AuditService
+}
+
+func NewSessionService(server *server.Server, sessions SessionProvider, auditService *AuditService) *SessionService {
+	return &SessionService{
+		server:   server,
+		sessions: sessions,
+		audit:    auditService,
+	}
+}
+
+// GetSessions lists the user's signed in sessions, marking the one the
+// request was made in
+func (s *SessionService) GetSessions(ctx echo.Context, userID string, currentSessionID string) ([]session.Session, error) {
+	logger := middleware.GetLogger(ctx)
+
+	sessions, err := s.sessions.ListActiveSessions(ctx.Request().Context(), userID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to list sessions")
+		return nil, err
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession signs one of the user's sessions out, which may be the
+// current one
+func (s *SessionService) RevokeSession(ctx echo.Context, userID string, payload *session.RevokeSessionPayload) error {
+	logger := middleware.GetLogger(ctx)
+
+	item, err := s.sessions.GetSession(ctx.Request().Context(), payload.ID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch session")
+		return err
+	}
+
+	if item == nil || item.UserID != userID {
+		code := "SESSION_NOT_FOUND"
+		return errs.NewNotFoundError("Session not found", false, &code)
+	}
+
+	if err := s.revoke(ctx, userID, payload.ID); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, nil, audit.EventSessionRevoked,
+		audit.Target{Type: "session", ID: payload.ID}, nil)
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "session_revoked").
+		Str("session_id", payload.ID).
+		Msg("session revoked successfully")
+
+	return nil
+}
+
+// RevokeUserSessions signs every session of a user out, for admins
+// responding to a compromised account
+func (s *SessionService) RevokeUserSessions(ctx echo.Context, payload *session.RevokeUserSessionsPayload) error {
+	logger := middleware.GetLogger(ctx)
+
+	sessions, err := s.sessions.ListActiveSessions(ctx.Request().Context(), payload.UserID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to list sessions")
+		return err
+	}
+
+	for _, item := range sessions {
+		if err := s.revoke(ctx, payload.UserID, item.ID); err != nil {
+			return err
+		}
+	}
+
+	s.audit.Record(ctx, nil, audit.EventUserSessionsRevoked,
+		audit.Target{Type: "user", ID: payload.UserID},
+		map[string]string{"sessions": strconv.Itoa(len(sessions))})
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "user_sessions_revoked").
+		Str("target_user_id", payload.UserID).
+		Int("sessions", len(sessions)).
+		Msg("user sessions revoked successfully")
+
+	return nil
+}
+
+// revoke revokes the session at the auth provider, denies its tokens until
+// they expire and closes its realtime connections. Once the provider
+// revoked it, failing to do the rest only delays the revocation until the
+// tokens expire, so it is logged rather than returned.
+func (s *SessionService) revoke(ctx echo.Context, userID string, sessionID string) error {
+	logger := middleware.GetLogger(ctx)
+	reqCtx := ctx.Request().Context()
+
+	if err := s.sessions.RevokeSession(reqCtx, sessionID); err != nil {
+		logger.Error().Err(err).Str("session_id", sessionID).Msg("failed to revoke session")
+		return err
+	}
+
+	if s.server.Redis != nil {
+		if err := s.server.Redis.Set(reqCtx, session.RevokedKey(sessionID), userID, revokedSessionTTL).Err(); err != nil {
+			logger.Error().Err(err).Str("session_id", sessionID).Msg("failed to add session to denylist")
+		}
+	} else {
+		logger.Warn().Str("session_id", sessionID).Msg("no Redis for the session denylist, tokens stay valid until they expire")
+	}
+
+	event := &session.RevokedEvent{
+		Type:      session.EventRevoked,
+		CreatedAt: time.Now(),
+		Data:      session.RevokedData{SessionID: sessionID},
+	}
+	if err := s.server.Realtime.CloseSession(reqCtx, userID, sessionID, event); err != nil {
+		logger.Warn().Err(err).Str("session_id", sessionID).Msg("failed to close realtime connections of revoked session")
+	}
+
+	return nil
+}
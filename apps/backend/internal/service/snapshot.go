@@ -0,0 +1,128 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/lib/aws"
+	"github.com/mabhi256/tasker/internal/lib/snapshotter"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/snapshot"
+	"github.com/mabhi256/tasker/internal/repository"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// SnapshotService freezes todos into self-contained files kept in the upload
+// bucket, so what a todo looked like can be shown later even after it has
+// changed or been deleted
+type SnapshotService struct {
+	server       *server.Server
+	todoRepo     *repository.TodoRepository
+	snapshotRepo *repository.SnapshotRepository
+	awsClient    *aws.AWS
+	snapshotter  *snapshotter.Snapshotter
+}
+
+func NewSnapshotService(server *server.Server, todoRepo *repository.TodoRepository,
+	snapshotRepo *repository.SnapshotRepository, awsClient *aws.AWS, snapshotter *snapshotter.Snapshotter,
+) *SnapshotService {
+	return &SnapshotService{
+		server:       server,
+		todoRepo:     todoRepo,
+		snapshotRepo: snapshotRepo,
+		awsClient:    awsClient,
+		snapshotter:  snapshotter,
+	}
+}
+
+// CreateSnapshot stores the todo as it is now, kept for retentionDays, and
+// returns the snapshot with a signed link to it
+func (s *SnapshotService) CreateSnapshot(ctx echo.Context, workspaceID uuid.UUID, userID string,
+	payload *snapshot.GetSnapshotPayload,
+) (*snapshot.Snapshot, error) {
+	logger := middleware.GetLogger(ctx)
+	reqCtx := ctx.Request().Context()
+
+	todoItem, err := s.todoRepo.GetTodoByID(reqCtx, workspaceID, payload.ID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch todo for snapshot")
+		return nil, err
+	}
+
+	format := *payload.Format
+	if format == snapshot.FormatHTML {
+		todoItem.RenderContent()
+	}
+
+	now := time.Now()
+	doc := &snapshot.Document{
+		SnapshotID:  uuid.New(),
+		TakenAt:     now,
+		TakenBy:     userID,
+		WorkspaceID: workspaceID,
+		Todo:        todoItem,
+	}
+
+	body, err := s.snapshotter.Render(format, doc)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to render snapshot")
+		return nil, err
+	}
+
+	objectKey, err := s.awsClient.S3.UploadFile(
+		reqCtx,
+		s.server.Config.AWS.UploadBucket,
+		fmt.Sprintf("snapshots/%s/%s/%s.%s", workspaceID, todoItem.ID, doc.SnapshotID, format),
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to upload snapshot")
+		return nil, err
+	}
+
+	checksum := sha256.Sum256(body)
+	created, err := s.snapshotRepo.CreateSnapshot(reqCtx, &snapshot.Snapshot{
+		ID:             doc.SnapshotID,
+		WorkspaceID:    workspaceID,
+		TodoID:         todoItem.ID,
+		CreatedBy:      userID,
+		Format:         format,
+		ObjectKey:      objectKey,
+		SizeBytes:      int64(len(body)),
+		ChecksumSHA256: hex.EncodeToString(checksum[:]),
+		ExpiresAt:      now.AddDate(0, 0, *payload.RetentionDays),
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to record snapshot")
+
+		// Without its row the object would never be cleaned up
+		if err := s.awsClient.S3.DeleteObject(reqCtx, s.server.Config.AWS.UploadBucket, objectKey); err != nil {
+			logger.Error().Err(err).Str("object_key", objectKey).Msg("failed to delete unrecorded snapshot")
+		}
+		return nil, err
+	}
+
+	url, err := s.awsClient.S3.CreatePresignedUrl(reqCtx, s.server.Config.AWS.UploadBucket, objectKey)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to sign snapshot download url")
+		return nil, err
+	}
+	created.DownloadURL = url
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "todo_snapshot_created").
+		Str("todo_id", todoItem.ID.String()).
+		Str("snapshot_id", created.ID.String()).
+		Str("format", string(format)).
+		Time("expires_at", created.ExpiresAt).
+		Msg("Todo snapshot created successfully")
+
+	return created, nil
+}
@@ -0,0 +1,427 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/authz"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/lib/oidc"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/audit"
+	"github.com/mabhi256/tasker/internal/model/sso"
+	"github.com/mabhi256/tasker/internal/repository"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// ssoVerificationTTL is how long a sign in with a workspace's identity
+	// provider lasts before the member must sign in with it again
+	ssoVerificationTTL = 12 * time.Hour
+	// ssoNonceTTL is how long the user has to sign in with the identity
+	// provider once it was started
+	ssoNonceTTL = 10 * time.Minute
+	// ssoProviderTTL is how long discovery documents and keys are cached
+	ssoProviderTTL = time.Hour
+)
+
+// SSOService configures OpenID Connect single sign-on for workspaces. While
+// a workspace enforces it, its members other than the owner must sign in
+// with its identity provider, in each session, before they can use the
+// workspace. Their API keys are refused in it meanwhile, having no session to
+// sign in with. Enforcement stops at the workspace, so it can't lock the
+// user out of their other workspaces.
+// Admins verify the allowed domains the workspace owns over DNS, which lets
+// SCIM provision existing accounts in them without an invitation.
+type SSOService struct {
+	server        *server.Server
+	ssoRepo       *repository.SSORepository
+	workspaceRepo *repository.WorkspaceRepository
+	identity      IdentityProvider
+	verifier      *oidc.Verifier
+	audit         *AuditService
+	lookupTXT     func(ctx context.Context, name string) ([]string, error)
+}
+
+func NewSSOService(server *server.Server, ssoRepo *repository.SSORepository,
+	workspaceRepo *repository.WorkspaceRepository, identity IdentityProvider, auditService *AuditService,
+) *SSOService {
+	return &SSOService{
+		server:        server,
+		ssoRepo:       ssoRepo,
+		workspaceRepo: workspaceRepo,
+		identity:      identity,
+		verifier:      oidc.NewVerifier(server.Fetcher, ssoProviderTTL),
+		audit:         auditService,
+		lookupTXT:     net.DefaultResolver.LookupTXT,
+	}
+}
+
+// EnforceSSO implements middleware.SSOEnforcer. A session that hasn't
+// signed in with the workspace's identity provider is refused with an error
+// whose action sends the user to sign in with it. API keys and trials have
+// no session to sign in with, so they are refused outright.
+func (s *SSOService) EnforceSSO(ctx context.Context, workspaceID uuid.UUID, userID string,
+	sessionID string,
+) error {
+	config, err := s.ssoRepo.GetEnforcedConfigForMember(ctx, workspaceID, userID)
+	if err != nil {
+		return err
+	}
+	if config == nil {
+		return nil
+	}
+
+	code := sso.CodeSSORequired
+	if sessionID == "" {
+		return errs.NewForbiddenActionError("API keys and trials cannot be used in a workspace that requires single sign-on",
+			false, &code, nil)
+	}
+
+	// Without Redis no session can have signed in, fail closed
+	if s.server.Redis == nil {
+		return errs.NewForbiddenError("Single sign-on is unavailable", false)
+	}
+
+	n, err := s.server.Redis.Exists(ctx, sso.VerificationKey(sessionID, workspaceID)).Result()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+
+	return errs.NewForbiddenActionError("Sign in with your organization's identity provider to continue",
+		false, &code, &errs.Action{
+			Type:    errs.ActionTypeRedirect,
+			Message: "Your organization requires single sign-on",
+			Value:   "/workspaces/" + workspaceID.String() + "/sso",
+		})
+}
+
+// ------------------------------------------------------------
+// Configuration, managed by workspace admins
+
+func (s *SSOService) GetConfig(ctx echo.Context, workspaceID uuid.UUID) (*sso.Config, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.SSOManage, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	config, err := s.ssoRepo.GetConfig(ctx.Request().Context(), workspaceID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch SSO config")
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// UpsertConfig sets the workspace's identity provider, which must serve a
+// valid discovery document. Admins can then sign in with it before they
+// enforce it.
+func (s *SSOService) UpsertConfig(ctx echo.Context, workspaceID uuid.UUID, userID string,
+	payload *sso.UpsertConfigPayload,
+) (*sso.Config, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.SSOManage, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	workspaceItem, err := s.workspaceRepo.GetWorkspaceByID(ctx.Request().Context(), workspaceID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch workspace by ID")
+		return nil, err
+	}
+
+	if workspaceItem.IsPersonal {
+		return nil, errs.NewBadRequestError("Personal workspaces cannot use single sign-on", false, nil, nil, nil)
+	}
+
+	// Either the issuer or its discovery document may be given
+	if !strings.HasPrefix(payload.DiscoveryURL, "https://") {
+		return nil, errs.NewBadRequestError("Discovery URL must use https", false, nil, nil, nil)
+	}
+	if !strings.HasSuffix(payload.DiscoveryURL, oidc.WellKnownPath) {
+		payload.DiscoveryURL = strings.TrimSuffix(payload.DiscoveryURL, "/") + oidc.WellKnownPath
+	}
+	for i, domain := range payload.AllowedDomains {
+		payload.AllowedDomains[i] = strings.ToLower(domain)
+	}
+
+	if _, err := s.verifier.Discover(ctx.Request().Context(), payload.DiscoveryURL); err != nil {
+		logger.Warn().Err(err).Str("discovery_url", payload.DiscoveryURL).Msg("SSO discovery failed")
+		code := "SSO_DISCOVERY_FAILED"
+		return nil, errs.NewBadRequestError("Could not load the identity provider's discovery document", false,
+			&code, nil, nil)
+	}
+
+	config, err := s.ssoRepo.UpsertConfig(ctx.Request().Context(), workspaceID, userID, payload)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to save SSO config")
+		return nil, err
+	}
+
+	s.audit.Record(ctx, &workspaceID, audit.EventSSOConfigUpdated,
+		audit.Target{Type: "sso_config", ID: workspaceID.String()},
+		map[string]string{
+			"discoveryUrl":   config.DiscoveryURL,
+			"allowedDomains": strings.Join(config.AllowedDomains, ","),
+			"enforced":       strconv.FormatBool(config.Enforced),
+		})
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "sso_config_updated").
+		Str("workspace_id", workspaceID.String()).
+		Bool("enforced", config.Enforced).
+		Msg("SSO config updated successfully")
+
+	return config, nil
+}
+
+// VerifyDomain verifies that the workspace owns one of its allowed domains,
+// by the TXT record at sso.DomainRecordName carrying the config's
+// DomainRecord
+func (s *SSOService) VerifyDomain(ctx echo.Context, workspaceID uuid.UUID,
+	payload *sso.VerifyDomainPayload,
+) (*sso.Config, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.SSOManage, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	config, err := s.ssoRepo.GetConfig(ctx.Request().Context(), workspaceID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch SSO config")
+		return nil, err
+	}
+
+	domain := strings.ToLower(payload.Domain)
+	if !slices.Contains(config.AllowedDomains, domain) {
+		code := "SSO_DOMAIN_NOT_ALLOWED"
+		return nil, errs.NewBadRequestError("Only allowed domains can be verified", false, &code, nil, nil)
+	}
+
+	records, err := s.lookupTXT(ctx.Request().Context(), sso.DomainRecordName(domain))
+	if err != nil {
+		var dnsErr *net.DNSError
+		if !errors.As(err, &dnsErr) || !dnsErr.IsNotFound {
+			logger.Error().Err(err).Str("domain", domain).Msg("failed to look up domain verification record")
+			return nil, errs.NewInternalServerError()
+		}
+	}
+	if !slices.Contains(records, config.DomainRecord()) {
+		logger.Warn().Str("domain", domain).Msg("domain verification record not found")
+		code := "SSO_DOMAIN_UNVERIFIED"
+		return nil, errs.NewBadRequestError("Add a TXT record named "+sso.DomainRecordName(domain)+
+			" with the value "+config.DomainRecord()+" to verify the domain", false, &code, nil, nil)
+	}
+
+	config, err = s.ssoRepo.AddVerifiedDomain(ctx.Request().Context(), workspaceID, domain)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to save verified domain")
+		return nil, err
+	}
+
+	s.audit.Record(ctx, &workspaceID, audit.EventSSODomainVerified,
+		audit.Target{Type: "sso_config", ID: workspaceID.String()},
+		map[string]string{"domain": domain})
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "sso_domain_verified").
+		Str("workspace_id", workspaceID.String()).
+		Str("domain", domain).
+		Msg("SSO domain verified successfully")
+
+	return config, nil
+}
+
+// DeleteConfig turns single sign-on off for the workspace
+func (s *SSOService) DeleteConfig(ctx echo.Context, workspaceID uuid.UUID) error {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.SSOManage, authz.Resource{}); err != nil {
+		return err
+	}
+
+	if err := s.ssoRepo.DeleteConfig(ctx.Request().Context(), workspaceID); err != nil {
+		logger.Error().Err(err).Msg("failed to delete SSO config")
+		return err
+	}
+
+	s.audit.Record(ctx, &workspaceID, audit.EventSSOConfigDeleted,
+		audit.Target{Type: "sso_config", ID: workspaceID.String()}, nil)
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "sso_config_deleted").
+		Str("workspace_id", workspaceID.String()).
+		Msg("SSO config deleted successfully")
+
+	return nil
+}
+
+// ------------------------------------------------------------
+// Signing in, reachable before the session has signed in
+
+// GetLogin starts a sign in with the workspace's identity provider. The ID
+// token it returns must be verified in the same session.
+func (s *SSOService) GetLogin(ctx echo.Context, workspaceID uuid.UUID, sessionID string) (*sso.Login, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := s.requireSession(sessionID); err != nil {
+		return nil, err
+	}
+
+	config, err := s.ssoRepo.GetConfig(ctx.Request().Context(), workspaceID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch SSO config")
+		return nil, err
+	}
+
+	provider, err := s.verifier.Discover(ctx.Request().Context(), config.DiscoveryURL)
+	if err != nil {
+		logger.Error().Err(err).Str("discovery_url", config.DiscoveryURL).Msg("SSO discovery failed")
+		return nil, errs.NewInternalServerError()
+	}
+
+	nonce, err := generateSSONonce()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate SSO nonce")
+		return nil, err
+	}
+
+	if err := s.server.Redis.Set(ctx.Request().Context(), sso.NonceKey(sessionID, workspaceID), nonce,
+		ssoNonceTTL).Err(); err != nil {
+		logger.Error().Err(err).Msg("failed to store SSO nonce")
+		return nil, err
+	}
+
+	return &sso.Login{
+		WorkspaceID:           workspaceID,
+		Issuer:                provider.Issuer,
+		AuthorizationEndpoint: provider.AuthorizationEndpoint,
+		ClientID:              config.ClientID,
+		Nonce:                 nonce,
+	}, nil
+}
+
+// Verify completes a sign in with the workspace's identity provider. The
+// ID token must carry the session's nonce and a verified email in an
+// allowed domain that is also the user's own.
+func (s *SSOService) Verify(ctx echo.Context, workspaceID uuid.UUID, userID string, sessionID string,
+	payload *sso.VerifyPayload,
+) (*sso.Verification, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := s.requireSession(sessionID); err != nil {
+		return nil, err
+	}
+
+	config, err := s.ssoRepo.GetConfig(ctx.Request().Context(), workspaceID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch SSO config")
+		return nil, err
+	}
+
+	claims, err := s.verifier.Verify(ctx.Request().Context(), config.DiscoveryURL, config.ClientID, payload.IDToken)
+	if err != nil {
+		if errors.Is(err, oidc.ErrInvalidToken) {
+			logger.Warn().Err(err).Msg("invalid SSO ID token")
+			code := "SSO_INVALID_TOKEN"
+			return nil, errs.NewBadRequestError("The identity provider's token is invalid", false, &code, nil, nil)
+		}
+		logger.Error().Err(err).Str("discovery_url", config.DiscoveryURL).Msg("SSO discovery failed")
+		return nil, errs.NewInternalServerError()
+	}
+
+	// The nonce is single use, so a token can't be replayed into another
+	// session
+	nonce, err := s.server.Redis.GetDel(ctx.Request().Context(), sso.NonceKey(sessionID, workspaceID)).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		logger.Error().Err(err).Msg("failed to fetch SSO nonce")
+		return nil, err
+	}
+	if nonce == "" || subtle.ConstantTimeCompare([]byte(nonce), []byte(claims.Nonce)) != 1 {
+		logger.Warn().Msg("SSO ID token nonce mismatch")
+		code := "SSO_INVALID_TOKEN"
+		return nil, errs.NewBadRequestError("The sign in has expired, please start again", false, &code, nil, nil)
+	}
+
+	if claims.EmailVerified != nil && !*claims.EmailVerified {
+		return nil, errs.NewForbiddenError("Your email is not verified by the identity provider", false)
+	}
+
+	if !config.AllowsEmail(claims.Email) {
+		logger.Warn().Str("email", claims.Email).Msg("SSO email domain is not allowed")
+		return nil, errs.NewForbiddenError("Your email domain is not allowed to sign in to this workspace", false)
+	}
+
+	email, err := s.identity.GetUserEmail(ctx.Request().Context(), userID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch user email")
+		return nil, err
+	}
+
+	if !strings.EqualFold(email, claims.Email) {
+		logger.Warn().Str("email", claims.Email).Msg("SSO email does not match the user")
+		return nil, errs.NewForbiddenError("You signed in to the identity provider as a different user", false)
+	}
+
+	if err := s.server.Redis.Set(ctx.Request().Context(), sso.VerificationKey(sessionID, workspaceID), claims.Email,
+		ssoVerificationTTL).Err(); err != nil {
+		logger.Error().Err(err).Msg("failed to store SSO verification")
+		return nil, err
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "sso_verified").
+		Str("workspace_id", workspaceID.String()).
+		Msg("user signed in with the workspace's identity provider")
+
+	return &sso.Verification{
+		WorkspaceID: workspaceID,
+		Email:       claims.Email,
+		ExpiresAt:   time.Now().Add(ssoVerificationTTL),
+	}, nil
+}
+
+// requireSession checks that sign ins can be tied to the session
+func (s *SSOService) requireSession(sessionID string) error {
+	if s.server.Redis == nil {
+		return errs.NewForbiddenError("Single sign-on is unavailable", false)
+	}
+	if sessionID == "" {
+		return errs.NewBadRequestError("Single sign-on requires a session", false, nil, nil, nil)
+	}
+	return nil
+}
+
+func generateSSONonce() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
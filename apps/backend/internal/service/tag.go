@@ -0,0 +1,85 @@
+package service
+
+import (
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/authz"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/audit"
+	"github.com/mabhi256/tasker/internal/model/tag"
+	"github.com/mabhi256/tasker/internal/repository"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// TagService renames and merges tags across a workspace's todos. Each
+// change publishes a tag event, from which clients and the search index
+// learn which todos were relabelled, and is audited.
+type TagService struct {
+	server  *server.Server
+	tagRepo *repository.TagRepository
+	audit   *AuditService
+}
+
+func NewTagService(server *server.Server, tagRepo *repository.TagRepository, auditService *AuditService) *TagService {
+	return &TagService{
+		server:  server,
+		tagRepo: tagRepo,
+		audit:   auditService,
+	}
+}
+
+func (s *TagService) RenameTag(ctx echo.Context, workspaceID uuid.UUID, payload *tag.RenameTagPayload) (*tag.Change, error) {
+	return s.replaceTag(ctx, workspaceID, payload.Tag, payload.Name, false)
+}
+
+func (s *TagService) MergeTag(ctx echo.Context, workspaceID uuid.UUID, payload *tag.MergeTagPayload) (*tag.Change, error) {
+	return s.replaceTag(ctx, workspaceID, payload.Tag, payload.Target, true)
+}
+
+func (s *TagService) replaceTag(ctx echo.Context, workspaceID uuid.UUID, from string, to string,
+	merge bool,
+) (*tag.Change, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.TagManage, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	if from == to {
+		code := "SAME_TAG"
+		return nil, errs.NewUnprocessableError("a tag can't be renamed or merged into itself", false, &code, nil, nil)
+	}
+
+	change, err := s.tagRepo.ReplaceTag(ctx.Request().Context(), workspaceID, from, to, merge)
+	if err != nil {
+		logger.Error().Err(err).Str("from", from).Str("to", to).Msg("failed to replace tag")
+		return nil, err
+	}
+
+	eventType := audit.EventTagRenamed
+	event := "tag_renamed"
+	if merge {
+		eventType = audit.EventTagMerged
+		event = "tag_merged"
+	}
+	s.audit.Record(ctx, &workspaceID, eventType, audit.Target{Type: "tag", ID: from}, map[string]string{
+		"to":           to,
+		"todos":        strconv.Itoa(len(change.TodoIDs)),
+		"deduplicated": strconv.Itoa(change.Deduplicated),
+	})
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", event).
+		Str("from", from).
+		Str("to", to).
+		Int("todos", len(change.TodoIDs)).
+		Int("deduplicated", change.Deduplicated).
+		Msg("Tag replaced successfully")
+
+	return change, nil
+}
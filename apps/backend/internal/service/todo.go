@@ -1,46 +1,91 @@
 package service
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
+	"path"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/authz"
 	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/lib/artifact"
 	"github.com/mabhi256/tasker/internal/lib/aws"
+	"github.com/mabhi256/tasker/internal/lib/cache"
+	"github.com/mabhi256/tasker/internal/lib/collation"
+	"github.com/mabhi256/tasker/internal/lib/content"
+	"github.com/mabhi256/tasker/internal/lib/job"
+	"github.com/mabhi256/tasker/internal/lib/metrics"
+	"github.com/mabhi256/tasker/internal/lib/preview"
+	"github.com/mabhi256/tasker/internal/lib/scanner"
 	"github.com/mabhi256/tasker/internal/middleware"
 	"github.com/mabhi256/tasker/internal/model"
+	"github.com/mabhi256/tasker/internal/model/category"
 	"github.com/mabhi256/tasker/internal/model/todo"
 	"github.com/mabhi256/tasker/internal/repository"
 	"github.com/mabhi256/tasker/internal/server"
 	"github.com/pkg/errors"
 )
 
+// Stats are also changed by cron jobs that don't invalidate the cache, so
+// they are only cached briefly
+const todoStatsCacheTTL = time.Minute
+
+func todoStatsCacheKey(workspaceID uuid.UUID) string {
+	return "todo-stats:" + workspaceID.String()
+}
+
 type TodoService struct {
 	server       *server.Server
 	todoRepo     *repository.TodoRepository
 	categoryRepo *repository.CategoryRepository
 	awsClient    *aws.AWS
+	fileScanner  scanner.Scanner
+	quotaService *QuotaService
 }
 
 func NewTodoService(server *server.Server, todoRepo *repository.TodoRepository,
-	categoryRepo *repository.CategoryRepository, awsClient *aws.AWS,
+	categoryRepo *repository.CategoryRepository, awsClient *aws.AWS, fileScanner scanner.Scanner,
+	quotaService *QuotaService,
 ) *TodoService {
 	return &TodoService{
 		server:       server,
 		todoRepo:     todoRepo,
 		categoryRepo: categoryRepo,
 		awsClient:    awsClient,
+		fileScanner:  fileScanner,
+		quotaService: quotaService,
 	}
 }
 
-func (s *TodoService) CreateTodo(ctx echo.Context, userID string, payload *todo.CreateTodoPayload) (*todo.Todo, error) {
+func (s *TodoService) CreateTodo(ctx echo.Context, workspaceID uuid.UUID, userID string,
+	payload *todo.CreateTodoPayload,
+) (*todo.Todo, error) {
 	logger := middleware.GetLogger(ctx)
 
-	// Validate parent todo exists and belongs to user (if provided)
+	if err := authorize(ctx, authz.TodoCreate, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	if payload.PublishAt != nil && !payload.PublishAt.After(time.Now()) {
+		err := errs.NewUnprocessableError("publishAt must be in the future", false, nil, nil, nil)
+		logger.Warn().Msg("todo scheduled to be published in the past")
+		return nil, err
+	}
+
+	// Validate parent todo exists and belongs to workspace (if provided)
 	if payload.ParentTodoID != nil {
-		parentTodo, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), userID, *payload.ParentTodoID)
+		parentTodo, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), workspaceID, *payload.ParentTodoID)
 		if err != nil {
 			logger.Error().Err(err).Msg("parent todo validation failed")
 			return nil, err
@@ -53,16 +98,21 @@ func (s *TodoService) CreateTodo(ctx echo.Context, userID string, payload *todo.
 		}
 	}
 
-	// Validate category exists and belongs to user (if provided)
+	// Validate category exists and belongs to workspace (if provided)
+	var categoryItem *category.Category
 	if payload.CategoryID != nil {
-		_, err := s.categoryRepo.GetCategoryByID(ctx.Request().Context(), userID, *payload.CategoryID)
+		var err error
+		categoryItem, err = s.categoryRepo.GetCategoryByID(ctx.Request().Context(), workspaceID, *payload.CategoryID)
 		if err != nil {
 			logger.Error().Err(err).Msg("category validation failed")
 			return nil, err
 		}
 	}
 
-	todoItem, err := s.todoRepo.CreateTodo(ctx.Request().Context(), userID, payload)
+	// The category's defaults fill in what the request leaves out
+	payload = payload.WithCategoryDefaults(categoryItem)
+
+	todoItem, err := s.todoRepo.CreateTodo(ctx.Request().Context(), workspaceID, userID, payload)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to create todo")
 		return nil, err
@@ -81,41 +131,98 @@ func (s *TodoService) CreateTodo(ctx echo.Context, userID string, payload *todo.
 			return ""
 		}()).
 		Str("priority", string(todoItem.Priority)).
+		Str("status", string(todoItem.Status)).
 		Msg("Todo created successfully")
 
+	s.server.Metrics.Inc(metrics.TodosCreated)
+
+	if todoItem.DueDate != nil {
+		s.scheduleReminder(ctx, todoItem)
+	}
+
+	s.invalidateStats(ctx, workspaceID)
+
 	return todoItem, nil
 }
 
-func (s *TodoService) GetTodoByID(ctx echo.Context, userID string, todoID uuid.UUID) (*todo.PopulatedTodo, error) {
+// GetTodoByID fetches the todo. With render set its description and those of
+// its children and comments are rendered to HTML as well.
+func (s *TodoService) GetTodoByID(ctx echo.Context, workspaceID uuid.UUID, todoID uuid.UUID,
+	render bool,
+) (*todo.PopulatedTodo, error) {
 	logger := middleware.GetLogger(ctx)
 
-	todoItem, err := s.todoRepo.GetTodoByID(ctx.Request().Context(), userID, todoID)
+	todoItem, err := s.todoRepo.GetTodoByID(ctx.Request().Context(), workspaceID, todoID)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to fetch todo by ID")
 		return nil, err
 	}
 
+	if render {
+		todoItem.RenderContent()
+	}
+
 	return todoItem, nil
 }
 
-func (s *TodoService) GetTodos(ctx echo.Context, userID string, query *todo.GetTodosQuery) (*model.PaginatedResponse[todo.PopulatedTodo], error) {
+func (s *TodoService) GetTodos(ctx echo.Context, workspaceID uuid.UUID,
+	query *todo.GetTodosQuery,
+) (*model.PaginatedResponse[todo.PopulatedTodo], error) {
 	logger := middleware.GetLogger(ctx)
 
-	result, err := s.todoRepo.GetTodos(ctx.Request().Context(), userID, query)
+	query.Locale = collationLanguage(ctx, query.Locale)
+	result, err := s.todoRepo.GetTodos(ctx.Request().Context(), workspaceID, query)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to fetch todos")
 		return nil, err
 	}
 
+	if query.Render != nil {
+		for i := range result.Data {
+			result.Data[i].RenderContent()
+		}
+	}
+
 	return result, nil
 }
 
-func (s *TodoService) UpdateTodo(ctx echo.Context, userID string, payload *todo.UpdateTodoPayload) (*todo.Todo, error) {
+// collationLanguage is the language whose collation titles sort by for the
+// request: that of locale when given, or else of the Accept-Language header
+func collationLanguage(ctx echo.Context, locale *string) *string {
+	preferences := []string{ctx.Request().Header.Get("Accept-Language")}
+	if locale != nil {
+		preferences = append([]string{*locale}, preferences...)
+	}
+
+	lang := collation.Match(preferences...)
+	return &lang
+}
+
+func (s *TodoService) UpdateTodo(ctx echo.Context, workspaceID uuid.UUID, payload *todo.UpdateTodoPayload) (*todo.Todo, error) {
 	logger := middleware.GetLogger(ctx)
 
-	// Validate parent todo exists and belongs to user (if provided)
+	if err := authorize(ctx, authz.TodoUpdate, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	// A status publishes a scheduled todo now, which a new publish time
+	// would contradict
+	if payload.PublishAt != nil {
+		if payload.Status != nil {
+			err := errs.NewUnprocessableError("publishAt can't be set along with status", false, nil, nil, nil)
+			logger.Warn().Msg("todo given both a status and a publish time")
+			return nil, err
+		}
+		if !payload.PublishAt.After(time.Now()) {
+			err := errs.NewUnprocessableError("publishAt must be in the future", false, nil, nil, nil)
+			logger.Warn().Msg("todo rescheduled to be published in the past")
+			return nil, err
+		}
+	}
+
+	// Validate parent todo exists and belongs to workspace (if provided)
 	if payload.ParentTodoID != nil {
-		parentTodo, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), userID, *payload.ParentTodoID)
+		parentTodo, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), workspaceID, *payload.ParentTodoID)
 		if err != nil {
 			logger.Error().Err(err).Msg("parent todo validation failed")
 			return nil, err
@@ -138,9 +245,9 @@ func (s *TodoService) UpdateTodo(ctx echo.Context, userID string, payload *todo.
 		logger.Debug().Msg("parent todo validation passed")
 	}
 
-	// Validate category exists and belongs to user (if provided)
+	// Validate category exists and belongs to workspace (if provided)
 	if payload.CategoryID != nil {
-		_, err := s.categoryRepo.GetCategoryByID(ctx.Request().Context(), userID, *payload.CategoryID)
+		_, err := s.categoryRepo.GetCategoryByID(ctx.Request().Context(), workspaceID, *payload.CategoryID)
 		if err != nil {
 			logger.Error().Err(err).Msg("category validation failed")
 			return nil, err
@@ -149,7 +256,7 @@ func (s *TodoService) UpdateTodo(ctx echo.Context, userID string, payload *todo.
 		logger.Debug().Msg("category validation passed")
 	}
 
-	updatedTodo, err := s.todoRepo.UpdateTodo(ctx.Request().Context(), userID, payload)
+	updatedTodo, err := s.todoRepo.UpdateTodo(ctx.Request().Context(), workspaceID, payload)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to update todo")
 		return nil, err
@@ -171,13 +278,28 @@ func (s *TodoService) UpdateTodo(ctx echo.Context, userID string, payload *todo.
 		Str("status", string(updatedTodo.Status)).
 		Msg("Todo updated successfully")
 
+	// Only a new due date or reminder time or closing the todo changes its
+	// reminder, so other edits don't send one that was already sent again
+	switch {
+	case payload.Status != nil && updatedTodo.IsDone():
+		s.cancelReminder(ctx, updatedTodo.ID)
+	case (payload.DueDate != nil || payload.ReminderMinutes != nil) && updatedTodo.DueDate != nil:
+		s.scheduleReminder(ctx, updatedTodo)
+	}
+
+	s.invalidateStats(ctx, workspaceID)
+
 	return updatedTodo, nil
 }
 
-func (s *TodoService) DeleteTodo(ctx echo.Context, userID string, todoID uuid.UUID) error {
+func (s *TodoService) DeleteTodo(ctx echo.Context, workspaceID uuid.UUID, todoID uuid.UUID) error {
 	logger := middleware.GetLogger(ctx)
 
-	err := s.todoRepo.DeleteTodo(ctx.Request().Context(), userID, todoID)
+	if err := authorize(ctx, authz.TodoDelete, authz.Resource{}); err != nil {
+		return err
+	}
+
+	err := s.todoRepo.DeleteTodo(ctx.Request().Context(), workspaceID, todoID)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to delete todo")
 		return err
@@ -190,13 +312,20 @@ func (s *TodoService) DeleteTodo(ctx echo.Context, userID string, todoID uuid.UU
 		Str("todo_id", todoID.String()).
 		Msg("Todo deleted successfully")
 
+	s.cancelReminder(ctx, todoID)
+
+	s.invalidateStats(ctx, workspaceID)
+
 	return nil
 }
 
-func (s *TodoService) GetTodoStats(ctx echo.Context, userID string) (*todo.TodoStats, error) {
+func (s *TodoService) GetTodoStats(ctx echo.Context, workspaceID uuid.UUID) (*todo.TodoStats, error) {
 	logger := middleware.GetLogger(ctx)
 
-	stats, err := s.todoRepo.GetTodoStats(ctx.Request().Context(), userID)
+	stats, err := cache.GetOrLoad(ctx.Request().Context(), s.server.Cache, todoStatsCacheKey(workspaceID), todoStatsCacheTTL,
+		func(loadCtx context.Context) (*todo.TodoStats, error) {
+			return s.todoRepo.GetTodoStats(loadCtx, workspaceID)
+		})
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to fetch todo statistics")
 		return nil, err
@@ -207,14 +336,19 @@ func (s *TodoService) GetTodoStats(ctx echo.Context, userID string) (*todo.TodoS
 
 func (s *TodoService) UploadTodoAttachment(
 	ctx echo.Context,
+	workspaceID uuid.UUID,
 	userID string,
 	todoID uuid.UUID,
 	file *multipart.FileHeader,
 ) (*todo.TodoAttachment, error) {
 	logger := middleware.GetLogger(ctx)
 
-	// Verify todo exists and belongs to user
-	_, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), userID, todoID)
+	if err := authorize(ctx, authz.TodoAttach, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	// Verify todo exists and belongs to workspace
+	_, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), workspaceID, todoID)
 	if err != nil {
 		logger.Error().Err(err).Msg("todo validation failed")
 		return nil, err
@@ -248,12 +382,98 @@ func (s *TodoService) UploadTodoAttachment(
 		return nil, errs.NewBadRequestError("failed to process file", false, nil, nil, nil)
 	}
 
-	// Upload to S3
+	return s.storeAttachment(ctx, workspaceID, todoID, userID, file.Filename, src, file.Size, mimeType)
+}
+
+// PasteTodoImage stores an image pasted into the todo's description or a
+// comment as an attachment, and returns the markdown that embeds it. The
+// markdown points at the attachment's download endpoint, which clients
+// exchange for a presigned URL when they display the image.
+func (s *TodoService) PasteTodoImage(
+	ctx echo.Context,
+	workspaceID uuid.UUID,
+	userID string,
+	todoID uuid.UUID,
+	name string,
+	data []byte,
+) (*todo.PastedImage, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.TodoAttach, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	mimeType, ext, err := content.CheckImage(data)
+	if err != nil {
+		code := "INVALID_IMAGE"
+		if errors.Is(err, content.ErrImageTooLarge) {
+			code = "IMAGE_TOO_LARGE"
+		}
+		return nil, errs.NewUnprocessableError(err.Error(), false, &code, nil, nil)
+	}
+
+	// Verify todo exists and belongs to workspace
+	_, err = s.todoRepo.CheckTodoExists(ctx.Request().Context(), workspaceID, todoID)
+	if err != nil {
+		logger.Error().Err(err).Msg("todo validation failed")
+		return nil, err
+	}
+
+	name = strings.TrimSpace(path.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "" || name == "." || name == "/" {
+		name = "pasted-image-" + time.Now().UTC().Format("20060102-150405")
+	}
+	if !strings.EqualFold(path.Ext(name), ext) {
+		name += ext
+	}
+
+	attachment, err := s.storeAttachment(ctx, workspaceID, todoID, userID, name, bytes.NewReader(data), int64(len(data)), mimeType)
+	if err != nil {
+		return nil, err
+	}
+
+	downloadURL := fmt.Sprintf("/api/v1/todos/%s/attachments/%s/download", todoID, attachment.ID)
+	return &todo.PastedImage{
+		Attachment: attachment,
+		Markdown:   content.ImageMarkdown(strings.TrimSuffix(name, path.Ext(name)), downloadURL),
+	}, nil
+}
+
+// storeAttachment uploads an attachment's content and records it, within
+// the storage quotas
+func (s *TodoService) storeAttachment(
+	ctx echo.Context,
+	workspaceID uuid.UUID,
+	todoID uuid.UUID,
+	userID string,
+	name string,
+	src io.Reader,
+	size int64,
+	mimeType string,
+) (*todo.TodoAttachment, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := s.attachmentPolicy().Check(size, mimeType); err != nil {
+		code := "ATTACHMENT_TYPE_NOT_ALLOWED"
+		if errors.Is(err, artifact.ErrTooLarge) {
+			code = "ATTACHMENT_TOO_LARGE"
+		}
+		return nil, errs.NewUnprocessableError(err.Error(), false, &code, nil, nil)
+	}
+
+	storage, err := s.quotaService.CheckStorage(ctx, workspaceID, userID, size)
+	if err != nil {
+		return nil, err
+	}
+
+	// Upload to S3, hashing the content on the way so the stored object
+	// can be verified later
+	hash := sha256.New()
 	s3Key, err := s.awsClient.S3.UploadFile(
 		ctx.Request().Context(),
 		s.server.Config.AWS.UploadBucket,
-		"todos/attachments/"+file.Filename,
-		src,
+		"todos/attachments/"+name,
+		io.TeeReader(src, hash),
 	)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to upload file to S3")
@@ -266,9 +486,10 @@ func (s *TodoService) UploadTodoAttachment(
 		todoID,
 		userID,
 		s3Key,
-		file.Filename,
-		file.Size,
+		name,
+		size,
 		mimeType,
+		hex.EncodeToString(hash.Sum(nil)),
 	)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to create attachment record")
@@ -280,19 +501,296 @@ func (s *TodoService) UploadTodoAttachment(
 		Str("s3_key", s3Key).
 		Msg("uploaded todo attachment")
 
+	s.quotaService.WarnStorage(ctx, workspaceID, userID, storage, size)
+
+	// The cron jobs queue the preview and processing again if this fails
+	err = job.Enqueue(ctx.Request().Context(), s.server.Job.Client, &job.AttachmentPreviewTask{AttachmentID: attachment.ID})
+	if err != nil {
+		logger.Warn().Err(err).Str("attachment_id", attachment.ID.String()).Msg("failed to enqueue attachment preview")
+	}
+	err = job.Enqueue(ctx.Request().Context(), s.server.Job.Client, &job.AttachmentProcessTask{AttachmentID: attachment.ID})
+	if err != nil {
+		logger.Warn().Err(err).Str("attachment_id", attachment.ID.String()).Msg("failed to enqueue attachment processing")
+	}
+
 	return attachment, nil
 }
 
+func (s *TodoService) attachmentPolicy() artifact.Policy {
+	return artifact.Policy{
+		MaxSize:      s.server.Config.Attachments.MaxSize,
+		AllowedTypes: s.server.Config.Attachments.AllowedTypes,
+	}
+}
+
+// ExtractAttachmentPreview implements job.AttachmentPreviewerInterface.
+// A file that is missing or doesn't parse is recorded without a preview,
+// as retrying won't change it.
+func (s *TodoService) ExtractAttachmentPreview(ctx context.Context, attachmentID uuid.UUID) error {
+	attachment, err := s.todoRepo.GetAttachment(ctx, attachmentID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Deleted since it was queued
+			return nil
+		}
+		return err
+	}
+	if attachment.PreviewExtractedAt != nil {
+		return nil
+	}
+
+	mimeType := ""
+	if attachment.MimeType != nil {
+		mimeType = *attachment.MimeType
+	}
+	kind, ok := preview.KindOf(mimeType, attachment.Name)
+	if !ok {
+		return s.todoRepo.RecordAttachmentPreview(ctx, attachmentID, nil)
+	}
+
+	body, err := s.awsClient.S3.GetObject(ctx, s.server.Config.AWS.UploadBucket, attachment.DownloadKey)
+	if err != nil {
+		if errors.Is(err, aws.ErrObjectNotFound) {
+			s.server.Logger.Warn().
+				Str("attachment_id", attachmentID.String()).
+				Msg("attachment object is missing, recording no preview")
+			return s.todoRepo.RecordAttachmentPreview(ctx, attachmentID, nil)
+		}
+		return err
+	}
+	defer body.Close()
+
+	attachmentPreview, err := preview.Extract(kind, body)
+	if err != nil {
+		s.server.Logger.Warn().Err(err).
+			Str("attachment_id", attachmentID.String()).
+			Str("kind", string(kind)).
+			Msg("failed to extract attachment preview, recording no preview")
+		attachmentPreview = nil
+	}
+
+	return s.todoRepo.RecordAttachmentPreview(ctx, attachmentID, attachmentPreview)
+}
+
+// ProcessAttachment implements job.AttachmentProcessorInterface. The file is
+// scanned first, and infected files are quarantined. The policy is checked
+// again against the stored file, with its type sniffed from the content,
+// and so also applies to attachments copied or uploaded before it.
+// Attachments whose file is missing, or that nothing can be derived from,
+// are processed without artifacts, noting why.
+func (s *TodoService) ProcessAttachment(ctx context.Context, attachmentID uuid.UUID) error {
+	logger := s.server.Logger.With().Str("attachment_id", attachmentID.String()).Logger()
+
+	attachment, err := s.todoRepo.GetAttachment(ctx, attachmentID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Deleted since it was queued
+			return nil
+		}
+		return err
+	}
+	if attachment.ProcessingStatus != todo.ProcessingStatusPending {
+		return nil
+	}
+
+	bucket := s.server.Config.AWS.UploadBucket
+	if attachment.ScanStatus != todo.ScanStatusClean {
+		infected, err := s.scanAttachment(ctx, attachment)
+		if err != nil {
+			if errors.Is(err, aws.ErrObjectNotFound) {
+				logger.Warn().Msg("attachment object is missing, processing it without artifacts")
+				return s.recordProcessing(ctx, attachmentID, todo.ProcessingStatusProcessed, "attachment file is missing")
+			}
+			return err
+		}
+		if infected {
+			return nil
+		}
+	}
+
+	body, err := s.awsClient.S3.GetObject(ctx, bucket, attachment.DownloadKey)
+	if err != nil {
+		if errors.Is(err, aws.ErrObjectNotFound) {
+			logger.Warn().Msg("attachment object is missing, processing it without artifacts")
+			return s.recordProcessing(ctx, attachmentID, todo.ProcessingStatusProcessed, "attachment file is missing")
+		}
+		return err
+	}
+	defer body.Close()
+
+	src := bufio.NewReader(body)
+	head, err := src.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to read attachment: %w", err)
+	}
+	mimeType := http.DetectContentType(head)
+
+	var size int64
+	if attachment.FileSize != nil {
+		size = *attachment.FileSize
+	}
+	if err := s.attachmentPolicy().Check(size, mimeType); err != nil {
+		logger.Warn().Err(err).Msg("attachment rejected by the attachment policy")
+		return s.recordProcessing(ctx, attachmentID, todo.ProcessingStatusRejected, err.Error())
+	}
+
+	derived, data, err := s.deriveArtifact(attachment, mimeType, size, src)
+	if err != nil {
+		logger.Warn().Err(err).Msg("failed to derive attachment artifact, processing it without")
+		return s.recordProcessing(ctx, attachmentID, todo.ProcessingStatusProcessed, err.Error())
+	}
+	if derived == nil {
+		return s.todoRepo.RecordAttachmentProcessing(ctx, attachmentID, todo.ProcessingStatusProcessed, nil)
+	}
+
+	derived.StorageKey, err = s.awsClient.S3.UploadFile(ctx, bucket,
+		fmt.Sprintf("todos/artifacts/%s/%s", attachmentID, derived.Kind), bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to upload %s artifact: %w", derived.Kind, err)
+	}
+
+	previousKey, err := s.todoRepo.SaveAttachmentArtifact(ctx, derived)
+	if err != nil {
+		return err
+	}
+	// An earlier run that failed after saving left a file behind
+	if previousKey != nil && *previousKey != derived.StorageKey {
+		if err := s.awsClient.S3.DeleteObject(ctx, bucket, *previousKey); err != nil {
+			logger.Warn().Err(err).Str("s3_key", *previousKey).Msg("failed to delete replaced attachment artifact")
+		}
+	}
+
+	logger.Info().
+		Str("kind", string(derived.Kind)).
+		Int64("size", derived.Size).
+		Msg("stored attachment artifact")
+
+	return s.todoRepo.RecordAttachmentProcessing(ctx, attachmentID, todo.ProcessingStatusProcessed, nil)
+}
+
+// scanAttachment scans the attachment's file, and quarantines it when it is
+// infected, returning whether it was. Files too large for the scanner are
+// left unscanned.
+func (s *TodoService) scanAttachment(ctx context.Context, attachment *todo.TodoAttachment) (bool, error) {
+	logger := s.server.Logger.With().Str("attachment_id", attachment.ID.String()).Logger()
+	bucket := s.server.Config.AWS.UploadBucket
+
+	body, err := s.awsClient.S3.GetObject(ctx, bucket, attachment.DownloadKey)
+	if err != nil {
+		return false, err
+	}
+	result, err := s.fileScanner.Scan(ctx, body)
+	body.Close()
+	if err != nil {
+		if errors.Is(err, scanner.ErrTooLarge) {
+			logger.Warn().Err(err).Msg("attachment is too large to scan, processing it unscanned")
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to scan attachment: %w", err)
+	}
+
+	if !result.Scanned {
+		return false, nil
+	}
+	if !result.Infected {
+		return false, s.todoRepo.RecordAttachmentScan(ctx, attachment.ID, todo.ScanStatusClean)
+	}
+
+	// The file is copied before the record points at it, so a failure
+	// part way is retried from the scan
+	quarantineKey := strings.TrimSuffix(s.server.Config.Attachments.Scan.QuarantinePrefix, "/") + "/" +
+		attachment.DownloadKey
+	if err := s.awsClient.S3.CopyObject(ctx, bucket, attachment.DownloadKey, quarantineKey); err != nil {
+		return false, fmt.Errorf("failed to quarantine attachment: %w", err)
+	}
+	if err := s.todoRepo.QuarantineAttachment(ctx, attachment.ID, quarantineKey, result.Signature); err != nil {
+		return false, err
+	}
+	if err := s.awsClient.S3.DeleteObject(ctx, bucket, attachment.DownloadKey); err != nil {
+		logger.Error().Err(err).Str("s3_key", attachment.DownloadKey).Msg("failed to delete quarantined attachment's original")
+	}
+
+	logger.Warn().
+		Str("signature", result.Signature).
+		Str("quarantine_key", quarantineKey).
+		Msg("quarantined infected attachment")
+
+	err = job.Enqueue(ctx, s.server.Job.Client, &job.AttachmentQuarantinedEmailTask{
+		UserID:       attachment.UploadedBy,
+		AttachmentID: attachment.ID,
+		TodoID:       attachment.TodoID,
+		FileName:     attachment.Name,
+		Signature:    result.Signature,
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to enqueue attachment quarantined email")
+	}
+
+	return true, nil
+}
+
+// deriveArtifact makes the thumbnail of an image or the text of a PDF,
+// returning nil for other files and PDFs without text
+func (s *TodoService) deriveArtifact(attachment *todo.TodoAttachment, mimeType string, size int64,
+	src io.Reader,
+) (*todo.AttachmentArtifact, []byte, error) {
+	kind, ok := preview.KindOf(mimeType, attachment.Name)
+	if !ok || kind == preview.KindText {
+		return nil, nil, nil
+	}
+	if size > artifact.MaxSourceSize {
+		return nil, nil, fmt.Errorf("attachment is too large to derive from: %d bytes", size)
+	}
+
+	cfg := s.server.Config.Attachments
+	derived := &todo.AttachmentArtifact{AttachmentID: attachment.ID}
+
+	if kind == preview.KindImage {
+		thumbnail, err := artifact.NewThumbnail(src, cfg.ThumbnailSize)
+		if err != nil {
+			return nil, nil, err
+		}
+		derived.Kind = artifact.KindThumbnail
+		derived.MimeType = "image/jpeg"
+		derived.Size = int64(len(thumbnail.Data))
+		derived.Width = &thumbnail.Width
+		derived.Height = &thumbnail.Height
+		return derived, thumbnail.Data, nil
+	}
+
+	text, err := artifact.PDFText(src, cfg.MaxTextSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	if text == "" {
+		return nil, nil, nil
+	}
+	derived.Kind = artifact.KindText
+	derived.MimeType = "text/plain; charset=utf-8"
+	derived.Size = int64(len(text))
+	return derived, []byte(text), nil
+}
+
+func (s *TodoService) recordProcessing(ctx context.Context, attachmentID uuid.UUID,
+	status todo.ProcessingStatus, reason string,
+) error {
+	return s.todoRepo.RecordAttachmentProcessing(ctx, attachmentID, status, &reason)
+}
+
 func (s *TodoService) DeleteTodoAttachment(
 	ctx echo.Context,
-	userID string,
+	workspaceID uuid.UUID,
 	todoID uuid.UUID,
 	attachmentID uuid.UUID,
 ) error {
 	logger := middleware.GetLogger(ctx)
 
-	// Verify todo exists and belongs to user
-	_, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), userID, todoID)
+	if err := authorize(ctx, authz.TodoAttach, authz.Resource{}); err != nil {
+		return err
+	}
+
+	// Verify todo exists and belongs to workspace
+	_, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), workspaceID, todoID)
 	if err != nil {
 		logger.Error().Err(err).Msg("todo validation failed")
 		return err
@@ -309,6 +807,13 @@ func (s *TodoService) DeleteTodoAttachment(
 		return err
 	}
 
+	// The artifacts' records go with the attachment's, but not their files
+	artifacts, err := s.todoRepo.GetAttachmentArtifacts(ctx.Request().Context(), attachmentID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get attachment artifacts")
+		return err
+	}
+
 	// Delete attachment record
 	err = s.todoRepo.DeleteTodoAttachment(
 		ctx.Request().Context(),
@@ -321,17 +826,23 @@ func (s *TodoService) DeleteTodoAttachment(
 	}
 
 	// Delete from S3 asynchronously
+	keys := []string{attachment.DownloadKey}
+	for _, a := range artifacts {
+		keys = append(keys, a.StorageKey)
+	}
 	go func() {
-		err := s.awsClient.S3.DeleteObject(
-			ctx.Request().Context(),
-			s.server.Config.AWS.UploadBucket,
-			attachment.DownloadKey,
-		)
-		if err != nil {
-			logger.Error().
-				Err(err).
-				Str("s3_key", attachment.DownloadKey).
-				Msg("failed to delete attachment from S3")
+		for _, key := range keys {
+			err := s.awsClient.S3.DeleteObject(
+				ctx.Request().Context(),
+				s.server.Config.AWS.UploadBucket,
+				key,
+			)
+			if err != nil {
+				logger.Error().
+					Err(err).
+					Str("s3_key", key).
+					Msg("failed to delete attachment from S3")
+			}
 		}
 	}()
 
@@ -342,14 +853,14 @@ func (s *TodoService) DeleteTodoAttachment(
 
 func (s *TodoService) GetAttachmentPresignedURL(
 	ctx echo.Context,
-	userID string,
+	workspaceID uuid.UUID,
 	todoID uuid.UUID,
 	attachmentID uuid.UUID,
 ) (string, error) {
 	logger := middleware.GetLogger(ctx)
 
-	// Verify todo exists and belongs to user
-	_, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), userID, todoID)
+	// Verify todo exists and belongs to workspace
+	_, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), workspaceID, todoID)
 	if err != nil {
 		logger.Error().Err(err).Msg("todo validation failed")
 		return "", err
@@ -365,6 +876,14 @@ func (s *TodoService) GetAttachmentPresignedURL(
 		logger.Error().Err(err).Msg("failed to get attachment details")
 		return "", err
 	}
+	if attachment.ScanStatus == todo.ScanStatusInfected {
+		code := "ATTACHMENT_INFECTED"
+		return "", errs.NewUnprocessableError("attachment was quarantined as it contains malware", false, &code, nil, nil)
+	}
+	if attachment.ProcessingStatus == todo.ProcessingStatusRejected {
+		code := "ATTACHMENT_REJECTED"
+		return "", errs.NewUnprocessableError("attachment was rejected by the attachment policy", false, &code, nil, nil)
+	}
 
 	// Generate presigned URL
 	url, err := s.awsClient.S3.CreatePresignedUrl(
@@ -379,3 +898,89 @@ func (s *TodoService) GetAttachmentPresignedURL(
 
 	return url, nil
 }
+
+// GetAttachmentThumbnailURL returns a presigned URL of the thumbnail of an
+// image attachment, once processing made one
+func (s *TodoService) GetAttachmentThumbnailURL(
+	ctx echo.Context,
+	workspaceID uuid.UUID,
+	todoID uuid.UUID,
+	attachmentID uuid.UUID,
+) (string, error) {
+	logger := middleware.GetLogger(ctx)
+
+	_, err := s.todoRepo.CheckTodoExists(ctx.Request().Context(), workspaceID, todoID)
+	if err != nil {
+		logger.Error().Err(err).Msg("todo validation failed")
+		return "", err
+	}
+
+	// Checks the attachment belongs to the todo
+	_, err = s.todoRepo.GetTodoAttachment(ctx.Request().Context(), todoID, attachmentID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get attachment details")
+		return "", err
+	}
+
+	thumbnail, err := s.todoRepo.GetAttachmentArtifact(ctx.Request().Context(), attachmentID, artifact.KindThumbnail)
+	if err != nil {
+		return "", err
+	}
+
+	url, err := s.awsClient.S3.CreatePresignedUrl(
+		ctx.Request().Context(),
+		s.server.Config.AWS.UploadBucket,
+		thumbnail.StorageKey,
+	)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate presigned URL")
+		return "", err
+	}
+
+	return url, nil
+}
+
+// invalidateStats drops the cached stats after a change to the workspace's
+// todos. A failure only delays the update until the entry expires.
+func (s *TodoService) invalidateStats(ctx echo.Context, workspaceID uuid.UUID) {
+	if err := s.server.Cache.Delete(ctx.Request().Context(), todoStatsCacheKey(workspaceID)); err != nil {
+		middleware.GetLogger(ctx).Warn().Err(err).Msg("failed to invalidate todo stats cache")
+	}
+}
+
+// scheduleReminder keeps the todo's due date reminder in step with its due
+// date: it's sent the todo's ReminderMinutes, or else ReminderHours, before
+// the todo is due, or now if that has passed, and dropped once the todo is
+// due. Failing to only logs, as the daily reminder run sends reminders that
+// weren't scheduled.
+func (s *TodoService) scheduleReminder(ctx echo.Context, todoItem *todo.Todo) {
+	logger := middleware.GetLogger(ctx)
+
+	if todoItem.IsDone() || !todoItem.DueDate.After(time.Now()) {
+		s.cancelReminder(ctx, todoItem.ID)
+		return
+	}
+
+	lead := time.Duration(s.server.Config.Cron.ReminderHours) * time.Hour
+	if todoItem.ReminderMinutes != nil {
+		lead = time.Duration(*todoItem.ReminderMinutes) * time.Minute
+	}
+	err := s.server.Job.ScheduleReminder(ctx.Request().Context(), &job.ReminderEmailTask{
+		UserID:    todoItem.UserID,
+		TodoID:    todoItem.ID,
+		TodoTitle: todoItem.Title,
+		DueDate:   *todoItem.DueDate,
+		TaskType:  "due_date_reminder",
+	}, todoItem.DueDate.Add(-lead))
+	if err != nil {
+		logger.Warn().Err(err).Str("todo_id", todoItem.ID.String()).Msg("failed to schedule due date reminder")
+	}
+}
+
+func (s *TodoService) cancelReminder(ctx echo.Context, todoID uuid.UUID) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := s.server.Job.CancelReminder(ctx.Request().Context(), todoID); err != nil {
+		logger.Warn().Err(err).Str("todo_id", todoID.String()).Msg("failed to cancel due date reminder")
+	}
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/apikey"
+	"github.com/mabhi256/tasker/internal/model/audit"
+	"github.com/mabhi256/tasker/internal/model/trial"
+	"github.com/mabhi256/tasker/internal/repository"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// TrialService starts anonymous trials and hands them to the users who sign
+// up and claim them. A trial acts as a user of its own in its personal
+// workspace, authenticated by its token, so nothing in the API changes for
+// it besides the routes that need a session.
+type TrialService struct {
+	server    *server.Server
+	trialRepo *repository.TrialRepository
+	audit     *AuditService
+}
+
+func NewTrialService(server *server.Server, trialRepo *repository.TrialRepository,
+	auditService *AuditService,
+) *TrialService {
+	return &TrialService{
+		server:    server,
+		trialRepo: trialRepo,
+		audit:     auditService,
+	}
+}
+
+// ResolveTrial implements middleware.TrialResolver
+func (s *TrialService) ResolveTrial(ctx context.Context, token string) (*trial.Trial, error) {
+	if !s.server.Config.Trials.Enabled {
+		code := "TRIAL_NOT_FOUND"
+		return nil, errs.NewNotFoundError("Trial not found or expired", false, &code)
+	}
+	return s.trialRepo.GetActiveTrial(ctx, hashTrialToken(token))
+}
+
+func (s *TrialService) StartTrial(ctx echo.Context) (*trial.TrialWithToken, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if !s.server.Config.Trials.Enabled {
+		return nil, errs.NewForbiddenError("Trials are not available", false)
+	}
+
+	token, err := generateTrialToken()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate trial token")
+		return nil, err
+	}
+
+	userID := trial.UserIDPrefix + uuid.NewString()
+	expiresAt := time.Now().Add(s.server.Config.Trials.TTL)
+	item, err := s.trialRepo.CreateTrial(ctx.Request().Context(), userID, hashTrialToken(token), expiresAt)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to start trial")
+		return nil, err
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "trial_started").
+		Str("trial_id", item.ID.String()).
+		Str("workspace_id", item.WorkspaceID.String()).
+		Time("expires_at", item.ExpiresAt).
+		Msg("Trial started successfully")
+
+	return &trial.TrialWithToken{Trial: *item, Token: token}, nil
+}
+
+// ClaimTrial hands the trial's workspace and data to the signed up user.
+// The trial's token stops working.
+func (s *TrialService) ClaimTrial(ctx echo.Context, userID string, payload *trial.ClaimTrialPayload) (*trial.Claimed, error) {
+	logger := middleware.GetLogger(ctx)
+
+	workspaceItem, err := s.trialRepo.ClaimTrial(ctx.Request().Context(), hashTrialToken(payload.Token), userID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to claim trial")
+		return nil, err
+	}
+
+	s.audit.Record(ctx, &workspaceItem.ID, audit.EventTrialClaimed,
+		audit.Target{Type: "workspace", ID: workspaceItem.ID.String()},
+		map[string]string{"personal": strconv.FormatBool(workspaceItem.IsPersonal)})
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "trial_claimed").
+		Str("workspace_id", workspaceItem.ID.String()).
+		Bool("personal", workspaceItem.IsPersonal).
+		Msg("Trial claimed successfully")
+
+	return &trial.Claimed{Workspace: *workspaceItem}, nil
+}
+
+func generateTrialToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return trial.Prefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashTrialToken is how trial tokens are stored and looked up. Like API
+// keys, they are random, so a fast hash is enough.
+func hashTrialToken(token string) string {
+	return apikey.Hash(token)
+}
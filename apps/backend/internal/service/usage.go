@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/mabhi256/tasker/internal/repository"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// UsageFlusher periodically moves the usage counted by the server's meter
+// into the daily cost-attribution table. Counters that fail to store are put
+// back and retried on the next flush.
+type UsageFlusher struct {
+	server    *server.Server
+	usageRepo *repository.UsageRepository
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewUsageFlusher(server *server.Server, usageRepo *repository.UsageRepository) *UsageFlusher {
+	return &UsageFlusher{
+		server:    server,
+		usageRepo: usageRepo,
+	}
+}
+
+func (f *UsageFlusher) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	f.cancel = cancel
+	f.done = make(chan struct{})
+
+	go f.run(ctx)
+
+	f.server.Logger.Info().
+		Dur("flush_interval", f.server.Config.Usage.FlushInterval).
+		Msg("Starting usage flusher")
+}
+
+// Stop cancels the flusher and flushes what was counted since the last flush
+func (f *UsageFlusher) Stop() {
+	if f.cancel == nil {
+		return
+	}
+
+	f.server.Logger.Info().Msg("Stopping usage flusher")
+	f.cancel()
+	<-f.done
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	f.flush(ctx)
+}
+
+func (f *UsageFlusher) run(ctx context.Context) {
+	defer close(f.done)
+
+	ticker := time.NewTicker(f.server.Config.Usage.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			f.flush(ctx)
+		}
+	}
+}
+
+func (f *UsageFlusher) flush(ctx context.Context) {
+	records := f.server.Usage.Drain()
+	if len(records) == 0 {
+		return
+	}
+
+	if err := f.usageRepo.AddUsage(ctx, records); err != nil {
+		f.server.Usage.Restore(records)
+		f.server.Logger.Error().Err(err).Int("records", len(records)).Msg("failed to flush usage")
+	}
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/rs/zerolog"
+)
+
+// ValidationReporter periodically logs the request fields that failed
+// binding or validation on this instance since the last report, most
+// frequent first, by route, field and client. Fields that confuse API users
+// show up as one field failing across clients, and broken clients as one
+// client failing across fields.
+type ValidationReporter struct {
+	server *server.Server
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewValidationReporter(server *server.Server) *ValidationReporter {
+	return &ValidationReporter{
+		server: server,
+	}
+}
+
+func (r *ValidationReporter) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+
+	go r.run(ctx)
+
+	r.server.Logger.Info().
+		Dur("interval", r.server.Config.ValidationReport.Interval).
+		Msg("Starting validation failure reporter")
+}
+
+// Stop cancels the reporter and reports what was counted since the last
+// report
+func (r *ValidationReporter) Stop() {
+	if r.cancel == nil {
+		return
+	}
+
+	r.server.Logger.Info().Msg("Stopping validation failure reporter")
+	r.cancel()
+	<-r.done
+
+	r.report()
+}
+
+func (r *ValidationReporter) run(ctx context.Context) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.server.Config.ValidationReport.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.report()
+		}
+	}
+}
+
+func (r *ValidationReporter) report() {
+	counts, dropped := r.server.FieldStats.Drain()
+	if len(counts) == 0 && dropped == 0 {
+		return
+	}
+
+	var total int64
+	for _, c := range counts {
+		total += c.Count
+	}
+	top := counts[:min(len(counts), r.server.Config.ValidationReport.Top)]
+
+	failures := zerolog.Arr()
+	for _, c := range top {
+		failures.Dict(zerolog.Dict().
+			Str("method", c.Method).
+			Str("route", c.Route).
+			Str("source", c.Source).
+			Str("field", c.Field).
+			Bool("unknown", c.Unknown).
+			Str("client", c.Client).
+			Int64("count", c.Count))
+	}
+
+	r.server.Logger.Info().
+		Str("event", "validation_failure_report").
+		Int64("failures", total).
+		Int("distinct", len(counts)).
+		Int64("dropped", dropped).
+		Array("top", failures).
+		Msg("Request fields failed validation")
+}
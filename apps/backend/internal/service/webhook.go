@@ -0,0 +1,458 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/authz"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/httpclient"
+	"github.com/mabhi256/tasker/internal/lib/job"
+	"github.com/mabhi256/tasker/internal/lib/metrics"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model"
+	"github.com/mabhi256/tasker/internal/model/audit"
+	"github.com/mabhi256/tasker/internal/model/webhook"
+	"github.com/mabhi256/tasker/internal/repository"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+const (
+	WebhookEventHeader     = "X-Tasker-Event"
+	WebhookDeliveryHeader  = "X-Tasker-Delivery"
+	WebhookSignatureHeader = "X-Tasker-Signature"
+
+	webhookSecretPrefix = "whsec_"
+	// Only the start of the endpoint's response is kept for the delivery log
+	maxWebhookResponseBody = 4 << 10
+)
+
+// WebhookService manages outbound webhooks. Events are recorded as
+// deliveries and sent by the job server, which retries failed attempts.
+type WebhookService struct {
+	server      *server.Server
+	webhookRepo *repository.WebhookRepository
+	catalog     *webhook.EventCatalog
+	audit       *AuditService
+}
+
+func NewWebhookService(server *server.Server, webhookRepo *repository.WebhookRepository,
+	auditService *AuditService,
+) *WebhookService {
+	return &WebhookService{
+		server:      server,
+		webhookRepo: webhookRepo,
+		catalog:     newEventCatalog(),
+		audit:       auditService,
+	}
+}
+
+func (s *WebhookService) CreateWebhook(ctx echo.Context, workspaceID uuid.UUID, userID string,
+	payload *webhook.CreateWebhookPayload,
+) (*webhook.WebhookWithSecret, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.WebhookManage, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkURL(ctx.Request().Context(), payload.URL); err != nil {
+		return nil, err
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate webhook secret")
+		return nil, err
+	}
+
+	webhookItem, err := s.webhookRepo.CreateWebhook(ctx.Request().Context(), workspaceID, userID, payload, secret)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to create webhook")
+		return nil, err
+	}
+
+	s.audit.Record(ctx, &workspaceID, audit.EventWebhookCreated,
+		audit.Target{Type: "webhook", ID: webhookItem.ID.String()},
+		map[string]string{"url": webhookItem.URL})
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "webhook_created").
+		Str("webhook_id", webhookItem.ID.String()).
+		Str("workspace_id", workspaceID.String()).
+		Msg("Webhook created successfully")
+
+	return &webhook.WebhookWithSecret{Webhook: *webhookItem, Secret: secret}, nil
+}
+
+func (s *WebhookService) GetWebhooks(ctx echo.Context, workspaceID uuid.UUID) ([]webhook.Webhook, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.WebhookManage, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	webhooks, err := s.webhookRepo.GetWebhooks(ctx.Request().Context(), workspaceID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch webhooks")
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+func (s *WebhookService) GetWebhookByID(ctx echo.Context, workspaceID uuid.UUID, webhookID uuid.UUID) (*webhook.Webhook, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.WebhookManage, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	webhookItem, err := s.webhookRepo.GetWebhookByID(ctx.Request().Context(), workspaceID, webhookID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch webhook by ID")
+		return nil, err
+	}
+
+	return webhookItem, nil
+}
+
+func (s *WebhookService) UpdateWebhook(ctx echo.Context, workspaceID uuid.UUID,
+	payload *webhook.UpdateWebhookPayload,
+) (*webhook.Webhook, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.WebhookManage, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	if payload.URL != nil {
+		if err := s.checkURL(ctx.Request().Context(), *payload.URL); err != nil {
+			return nil, err
+		}
+	}
+
+	webhookItem, err := s.webhookRepo.UpdateWebhook(ctx.Request().Context(), workspaceID, payload)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to update webhook")
+		return nil, err
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "webhook_updated").
+		Str("webhook_id", webhookItem.ID.String()).
+		Str("workspace_id", workspaceID.String()).
+		Bool("enabled", webhookItem.Enabled).
+		Msg("Webhook updated successfully")
+
+	return webhookItem, nil
+}
+
+func (s *WebhookService) RotateSecret(ctx echo.Context, workspaceID uuid.UUID, webhookID uuid.UUID) (*webhook.WebhookWithSecret, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.WebhookManage, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate webhook secret")
+		return nil, err
+	}
+
+	webhookItem, err := s.webhookRepo.RotateSecret(ctx.Request().Context(), workspaceID, webhookID, secret)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to rotate webhook secret")
+		return nil, err
+	}
+
+	s.audit.Record(ctx, &workspaceID, audit.EventWebhookSecretRotated,
+		audit.Target{Type: "webhook", ID: webhookItem.ID.String()}, nil)
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "webhook_secret_rotated").
+		Str("webhook_id", webhookItem.ID.String()).
+		Str("workspace_id", workspaceID.String()).
+		Msg("Webhook secret rotated successfully")
+
+	return &webhook.WebhookWithSecret{Webhook: *webhookItem, Secret: secret}, nil
+}
+
+func (s *WebhookService) DeleteWebhook(ctx echo.Context, workspaceID uuid.UUID, webhookID uuid.UUID) error {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.WebhookManage, authz.Resource{}); err != nil {
+		return err
+	}
+
+	err := s.webhookRepo.DeleteWebhook(ctx.Request().Context(), workspaceID, webhookID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to delete webhook")
+		return err
+	}
+
+	s.audit.Record(ctx, &workspaceID, audit.EventWebhookDeleted,
+		audit.Target{Type: "webhook", ID: webhookID.String()}, nil)
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "webhook_deleted").
+		Str("webhook_id", webhookID.String()).
+		Str("workspace_id", workspaceID.String()).
+		Msg("Webhook deleted successfully")
+
+	return nil
+}
+
+func (s *WebhookService) GetDeliveries(ctx echo.Context, workspaceID uuid.UUID,
+	query *webhook.GetDeliveriesQuery,
+) (*model.PaginatedResponse[webhook.Delivery], error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.WebhookManage, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.webhookRepo.GetWebhookByID(ctx.Request().Context(), workspaceID, query.ID); err != nil {
+		logger.Error().Err(err).Msg("failed to fetch webhook for deliveries")
+		return nil, err
+	}
+
+	deliveries, err := s.webhookRepo.GetDeliveries(ctx.Request().Context(), workspaceID, query)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch webhook deliveries")
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// Redeliver sends a delivery again with its original payload and event id
+func (s *WebhookService) Redeliver(ctx echo.Context, workspaceID uuid.UUID,
+	payload *webhook.RedeliverPayload,
+) (*webhook.Delivery, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.WebhookManage, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	delivery, err := s.webhookRepo.GetDeliveryByID(ctx.Request().Context(), workspaceID, payload.ID, payload.DeliveryID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch webhook delivery")
+		return nil, err
+	}
+
+	if delivery.Status == webhook.DeliveryStatusPending {
+		return nil, errs.NewConflictError("Delivery is still being retried", false, nil, nil, nil)
+	}
+
+	delivery, err = s.webhookRepo.ResetDelivery(ctx.Request().Context(), delivery.ID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to reset webhook delivery")
+		return nil, err
+	}
+
+	if err := job.EnqueueWebhookDelivery(ctx.Request().Context(), s.server.Job.Client,
+		&job.WebhookDeliveryTask{DeliveryID: delivery.ID}); err != nil {
+		logger.Error().Err(err).Msg("failed to enqueue webhook redelivery")
+		return nil, err
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "webhook_redelivered").
+		Str("webhook_id", payload.ID.String()).
+		Str("delivery_id", delivery.ID.String()).
+		Msg("Webhook delivery requeued successfully")
+
+	return delivery, nil
+}
+
+// Publish records the event for every subscribed webhook in the workspace
+// and queues the deliveries. It is called by the outbox relay and may see
+// the same event more than once; deliveries that already exist are queued
+// again only while still pending.
+func (s *WebhookService) Publish(ctx context.Context, event *webhook.Event) error {
+	webhooks, err := s.webhookRepo.GetSubscribedWebhooks(ctx, event.WorkspaceID, event.Type)
+	if err != nil {
+		return err
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	webhookIDs := make([]uuid.UUID, len(webhooks))
+	for i, w := range webhooks {
+		webhookIDs[i] = w.ID
+	}
+
+	deliveries, err := s.webhookRepo.CreateDeliveries(ctx, webhookIDs, event)
+	if err != nil {
+		return err
+	}
+
+	var errList []error
+	for _, delivery := range deliveries {
+		if delivery.Status != webhook.DeliveryStatusPending {
+			continue
+		}
+
+		err := job.EnqueueWebhookDelivery(ctx, s.server.Job.Client, &job.WebhookDeliveryTask{DeliveryID: delivery.ID})
+		if err != nil {
+			errList = append(errList, fmt.Errorf("failed to enqueue webhook delivery_id=%s: %w", delivery.ID.String(), err))
+		}
+	}
+
+	return errors.Join(errList...)
+}
+
+// DeliverWebhook implements job.WebhookDelivererInterface. Problems retrying
+// can't fix, like a disabled webhook or a blocked address, fail the delivery
+// right away and return nil.
+func (s *WebhookService) DeliverWebhook(ctx context.Context, deliveryID uuid.UUID, final bool) error {
+	delivery, webhookItem, err := s.webhookRepo.GetDeliveryWithWebhook(ctx, deliveryID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// The webhook was deleted along with its deliveries
+			return nil
+		}
+		return err
+	}
+
+	if delivery.Status == webhook.DeliveryStatusSucceeded {
+		return nil
+	}
+
+	if !webhookItem.Enabled {
+		msg := "webhook is disabled"
+		return s.webhookRepo.RecordAttempt(ctx, deliveryID, &webhook.Attempt{Error: &msg}, webhook.DeliveryStatusFailed)
+	}
+
+	attempt, sendErr := s.send(ctx, webhookItem, delivery)
+
+	status := webhook.DeliveryStatusPending
+	switch {
+	case sendErr == nil:
+		status = webhook.DeliveryStatusSucceeded
+	case final || errors.Is(sendErr, httpclient.ErrBlockedAddress) || errors.Is(sendErr, httpclient.ErrUnsupportedURL):
+		status = webhook.DeliveryStatusFailed
+	}
+
+	if err := s.webhookRepo.RecordAttempt(ctx, deliveryID, attempt, status); err != nil {
+		return err
+	}
+
+	if status == webhook.DeliveryStatusPending {
+		return sendErr
+	}
+
+	if status == webhook.DeliveryStatusFailed {
+		s.server.Metrics.Inc(metrics.WebhookDeliveriesFailed)
+		s.server.Logger.Warn().
+			Str("webhook_id", webhookItem.ID.String()).
+			Str("delivery_id", deliveryID.String()).
+			Err(sendErr).
+			Msg("webhook delivery failed permanently")
+	}
+
+	return nil
+}
+
+// send posts the delivery's payload once. Any response other than 2xx is an error.
+func (s *WebhookService) send(ctx context.Context, webhookItem *webhook.Webhook,
+	delivery *webhook.Delivery,
+) (*webhook.Attempt, error) {
+	attempt := &webhook.Attempt{}
+	fail := func(err error) (*webhook.Attempt, error) {
+		msg := err.Error()
+		attempt.Error = &msg
+		return attempt, err
+	}
+
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookItem.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return fail(fmt.Errorf("failed to build webhook request: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Tasker-Webhooks/1.0")
+	req.Header.Set(WebhookEventHeader, string(delivery.EventType))
+	req.Header.Set(WebhookDeliveryHeader, delivery.ID.String())
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(webhookItem.Secret, timestamp, delivery.Payload))
+
+	start := time.Now()
+	resp, err := s.server.Fetcher.Do(req)
+	attempt.Duration = time.Since(start)
+	if err != nil {
+		return fail(err)
+	}
+	defer resp.Body.Close()
+
+	attempt.ResponseStatus = &resp.StatusCode
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookResponseBody))
+	if len(body) > 0 {
+		responseBody := string(bytes.ToValidUTF8(body, nil))
+		attempt.ResponseBody = &responseBody
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fail(fmt.Errorf("endpoint responded with status %d", resp.StatusCode))
+	}
+
+	return attempt, nil
+}
+
+// SignWebhookPayload returns the signature header value. Receivers compute
+// HMAC-SHA256 over "<t>.<body>" with their secret, compare it to v1, and
+// should reject old timestamps to prevent replays.
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	ts := strconv.FormatInt(timestamp, 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *WebhookService) checkURL(ctx context.Context, rawURL string) error {
+	if _, err := s.server.Fetcher.ValidateURL(ctx, rawURL); err != nil {
+		return errs.NewBadRequestError("Webhook URL is not allowed", false, nil, nil, nil)
+	}
+	return nil
+}
+
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
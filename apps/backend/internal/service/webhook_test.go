@@ -0,0 +1,88 @@
+package service_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mabhi256/tasker/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// verifySignature checks a signature header the way receivers are told to:
+// recompute the HMAC over "<t>.<body>" and reject stale timestamps
+func verifySignature(header, secret string, body []byte, now time.Time) bool {
+	var ts, v1 string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			v1 = value
+		}
+	}
+
+	timestamp, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || now.Sub(time.Unix(timestamp, 0)).Abs() > 5*time.Minute {
+		return false
+	}
+	sig, err := hex.DecodeString(v1)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+	return hmac.Equal(sig, mac.Sum(nil))
+}
+
+func TestSignWebhookPayload(t *testing.T) {
+	body := []byte(`{"event":"todo.created"}`)
+
+	header := service.SignWebhookPayload("whsec_test", 1700000000, body)
+	assert.Equal(t, "t=1700000000,v1=0db4dcd7e7c5bc797d5b40bad35628d1b595ae10063a537e47ef00ffc5b800c9", header)
+
+	assert.NotEqual(t, header, service.SignWebhookPayload("whsec_other", 1700000000, body))
+	assert.NotEqual(t, header, service.SignWebhookPayload("whsec_test", 1700000001, body))
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	secret := "whsec_test"
+	body := []byte(`{"event":"todo.created"}`)
+	now := time.Now()
+	header := service.SignWebhookPayload(secret, now.Unix(), body)
+
+	require.True(t, verifySignature(header, secret, body, now))
+
+	tests := []struct {
+		name   string
+		header string
+		secret string
+		body   []byte
+		now    time.Time
+	}{
+		{name: "wrong secret", header: header, secret: "whsec_other", body: body, now: now},
+		{name: "tampered body", header: header, secret: secret, body: []byte(`{"event":"todo.deleted"}`), now: now},
+		{name: "replayed", header: header, secret: secret, body: body, now: now.Add(10 * time.Minute)},
+		{
+			name:   "timestamp swapped",
+			header: strings.Replace(header, "t="+strconv.FormatInt(now.Unix(), 10), "t="+strconv.FormatInt(now.Unix()+1, 10), 1),
+			secret: secret,
+			body:   body,
+			now:    now,
+		},
+		{name: "missing signature", header: "t=" + strconv.FormatInt(now.Unix(), 10), secret: secret, body: body, now: now},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.False(t, verifySignature(tt.header, tt.secret, tt.body, tt.now))
+		})
+	}
+}
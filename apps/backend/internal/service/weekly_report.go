@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/mabhi256/tasker/internal/lib/email"
+	"github.com/mabhi256/tasker/internal/lib/metrics"
+	"github.com/mabhi256/tasker/internal/model/trial"
+	"github.com/mabhi256/tasker/internal/repository"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+// WeeklyReportService sends users the weekly summary of their todos, across
+// workspaces
+type WeeklyReportService struct {
+	server           *server.Server
+	weeklyReportRepo *repository.WeeklyReportRepository
+	authService      *AuthService
+	trackingService  *EmailTrackingService
+}
+
+func NewWeeklyReportService(server *server.Server, weeklyReportRepo *repository.WeeklyReportRepository,
+	authService *AuthService, trackingService *EmailTrackingService,
+) *WeeklyReportService {
+	return &WeeklyReportService{
+		server:           server,
+		weeklyReportRepo: weeklyReportRepo,
+		authService:      authService,
+		trackingService:  trackingService,
+	}
+}
+
+// SendWeeklyReport implements job.WeeklyReportSenderInterface
+func (s *WeeklyReportService) SendWeeklyReport(ctx context.Context, userID string, weekStart, weekEnd time.Time,
+	timezone string,
+) error {
+	// Trials are anonymous, so there's nowhere to send it
+	if trial.IsUser(userID) {
+		s.server.Logger.Info().Str("user_id", userID).Msg("user is a trial, skipping weekly report")
+		return nil
+	}
+
+	// Tasks queued before reports had a timezone are sent in UTC
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return err
+	}
+
+	report, err := s.weeklyReportRepo.GetReport(ctx, userID, weekStart.In(loc), weekEnd.In(loc), loc.String())
+	if err != nil {
+		return err
+	}
+
+	if report.Empty() {
+		s.server.Logger.Info().Str("user_id", userID).Msg("nothing to report in weekly report, skipping")
+		return nil
+	}
+
+	to, err := s.authService.GetUserEmail(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	dead, err := s.trackingService.AddressDead(ctx, to)
+	if err != nil {
+		return err
+	}
+	if dead {
+		s.server.Logger.Info().Str("user_id", userID).Msg("email address looks dead, skipping weekly report")
+		return nil
+	}
+
+	ctx = email.WithRecipient(ctx, userID)
+	if err := s.server.Email.SendWeeklyReportEmail(ctx, to, report); err != nil {
+		return err
+	}
+
+	s.server.Metrics.Inc(metrics.WeeklyReportsSent)
+
+	s.server.Logger.Info().
+		Str("user_id", userID).
+		Int("created", report.CreatedCount).
+		Int("completed", report.CompletedCount).
+		Int("overdue", report.OverdueCount).
+		Int("streak", report.Streak).
+		Msg("Sent weekly report")
+
+	return nil
+}
@@ -0,0 +1,307 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/mabhi256/tasker/internal/authz"
+	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/middleware"
+	"github.com/mabhi256/tasker/internal/model/audit"
+	"github.com/mabhi256/tasker/internal/model/workspace"
+	"github.com/mabhi256/tasker/internal/repository"
+	"github.com/mabhi256/tasker/internal/server"
+)
+
+type WorkspaceService struct {
+	server        *server.Server
+	workspaceRepo *repository.WorkspaceRepository
+	audit         *AuditService
+}
+
+func NewWorkspaceService(server *server.Server, workspaceRepo *repository.WorkspaceRepository,
+	auditService *AuditService,
+) *WorkspaceService {
+	return &WorkspaceService{
+		server:        server,
+		workspaceRepo: workspaceRepo,
+		audit:         auditService,
+	}
+}
+
+// authorize checks the action against its policy for the request's user
+// and their role in the request's workspace
+func authorize(ctx echo.Context, action authz.Action, resource authz.Resource) error {
+	subject := authz.Subject{
+		UserID: middleware.GetUserID(ctx),
+		Role:   middleware.GetWorkspaceRole(ctx),
+	}
+	if !authz.Allowed(action, subject, resource) {
+		middleware.GetLogger(ctx).Warn().
+			Str("workspace_id", middleware.GetWorkspaceID(ctx).String()).
+			Str("workspace_role", string(subject.Role)).
+			Str("action", string(action)).
+			Msg("authorization check failed")
+		return errs.NewForbiddenError("You do not have permission to perform this action in this workspace", false)
+	}
+	return nil
+}
+
+// authorizeMember checks the action against its policy for the request's
+// user and their role in member's workspace, for requests that act on a
+// workspace besides their own
+func authorizeMember(ctx echo.Context, member *workspace.Member, action authz.Action, resource authz.Resource) error {
+	subject := authz.Subject{
+		UserID: member.UserID,
+		Role:   member.Role,
+	}
+	if !authz.Allowed(action, subject, resource) {
+		middleware.GetLogger(ctx).Warn().
+			Str("workspace_id", member.WorkspaceID.String()).
+			Str("workspace_role", string(subject.Role)).
+			Str("action", string(action)).
+			Msg("authorization check failed")
+		return errs.NewForbiddenError("You do not have permission to perform this action in the target workspace", false)
+	}
+	return nil
+}
+
+// ResolveMembership implements middleware.WorkspaceResolver
+func (s *WorkspaceService) ResolveMembership(ctx context.Context, userID string,
+	workspaceID *uuid.UUID,
+) (*workspace.Member, error) {
+	if workspaceID == nil {
+		return s.workspaceRepo.GetOrCreatePersonalWorkspace(ctx, userID)
+	}
+
+	return s.workspaceRepo.GetMembership(ctx, *workspaceID, userID)
+}
+
+func (s *WorkspaceService) CreateWorkspace(ctx echo.Context, userID string,
+	payload *workspace.CreateWorkspacePayload,
+) (*workspace.Workspace, error) {
+	logger := middleware.GetLogger(ctx)
+
+	workspaceItem, err := s.workspaceRepo.CreateWorkspace(ctx.Request().Context(), userID, payload)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to create workspace")
+		return nil, err
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "workspace_created").
+		Str("workspace_id", workspaceItem.ID.String()).
+		Str("name", workspaceItem.Name).
+		Msg("Workspace created successfully")
+
+	return workspaceItem, nil
+}
+
+func (s *WorkspaceService) GetWorkspaces(ctx echo.Context, userID string) ([]workspace.WorkspaceWithRole, error) {
+	logger := middleware.GetLogger(ctx)
+
+	// Make sure the personal workspace shows up even before the user's first write
+	if _, err := s.workspaceRepo.GetOrCreatePersonalWorkspace(ctx.Request().Context(), userID); err != nil {
+		logger.Error().Err(err).Msg("failed to ensure personal workspace")
+		return nil, err
+	}
+
+	workspaces, err := s.workspaceRepo.GetWorkspacesForUser(ctx.Request().Context(), userID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch workspaces")
+		return nil, err
+	}
+
+	return workspaces, nil
+}
+
+func (s *WorkspaceService) GetWorkspaceByID(ctx echo.Context, workspaceID uuid.UUID) (*workspace.Workspace, error) {
+	logger := middleware.GetLogger(ctx)
+
+	workspaceItem, err := s.workspaceRepo.GetWorkspaceByID(ctx.Request().Context(), workspaceID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch workspace by ID")
+		return nil, err
+	}
+
+	return workspaceItem, nil
+}
+
+func (s *WorkspaceService) UpdateWorkspace(ctx echo.Context,
+	payload *workspace.UpdateWorkspacePayload,
+) (*workspace.Workspace, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.WorkspaceUpdate, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	workspaceItem, err := s.workspaceRepo.UpdateWorkspace(ctx.Request().Context(), payload)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to update workspace")
+		return nil, err
+	}
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "workspace_updated").
+		Str("workspace_id", workspaceItem.ID.String()).
+		Msg("Workspace updated successfully")
+
+	return workspaceItem, nil
+}
+
+func (s *WorkspaceService) DeleteWorkspace(ctx echo.Context, workspaceID uuid.UUID) error {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.WorkspaceDelete, authz.Resource{}); err != nil {
+		return err
+	}
+
+	workspaceItem, err := s.workspaceRepo.GetWorkspaceByID(ctx.Request().Context(), workspaceID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch workspace by ID")
+		return err
+	}
+
+	if workspaceItem.IsPersonal {
+		return errs.NewBadRequestError("Personal workspaces cannot be deleted", false, nil, nil, nil)
+	}
+
+	err = s.workspaceRepo.DeleteWorkspace(ctx.Request().Context(), workspaceID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to delete workspace")
+		return err
+	}
+
+	// The workspace and its forwarders are gone, so only global forwarders
+	// receive this event
+	s.audit.Record(ctx, nil, audit.EventWorkspaceDeleted,
+		audit.Target{Type: "workspace", ID: workspaceID.String()},
+		map[string]string{"name": workspaceItem.Name})
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "workspace_deleted").
+		Str("workspace_id", workspaceID.String()).
+		Msg("Workspace deleted successfully")
+
+	return nil
+}
+
+func (s *WorkspaceService) GetMembers(ctx echo.Context, workspaceID uuid.UUID) ([]workspace.Member, error) {
+	logger := middleware.GetLogger(ctx)
+
+	members, err := s.workspaceRepo.GetMembers(ctx.Request().Context(), workspaceID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch workspace members")
+		return nil, err
+	}
+
+	return members, nil
+}
+
+func (s *WorkspaceService) AddMember(ctx echo.Context, payload *workspace.AddMemberPayload) (*workspace.Member, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.MemberAdd, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	workspaceItem, err := s.workspaceRepo.GetWorkspaceByID(ctx.Request().Context(), payload.WorkspaceID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch workspace by ID")
+		return nil, err
+	}
+
+	if workspaceItem.IsPersonal {
+		return nil, errs.NewBadRequestError("Members cannot be added to a personal workspace", false, nil, nil, nil)
+	}
+
+	role := workspace.RoleMember
+	if payload.Role != nil {
+		role = *payload.Role
+	}
+
+	member, err := s.workspaceRepo.AddMember(ctx.Request().Context(), payload.WorkspaceID, payload.UserID, role)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to add workspace member")
+		return nil, err
+	}
+
+	s.audit.Record(ctx, &payload.WorkspaceID, audit.EventMemberAdded,
+		audit.Target{Type: "member", ID: member.UserID},
+		map[string]string{"role": string(member.Role)})
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "workspace_member_added").
+		Str("workspace_id", payload.WorkspaceID.String()).
+		Str("member_id", member.UserID).
+		Str("role", string(member.Role)).
+		Msg("Workspace member added successfully")
+
+	return member, nil
+}
+
+func (s *WorkspaceService) UpdateMember(ctx echo.Context, payload *workspace.UpdateMemberPayload) (*workspace.Member, error) {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.MemberUpdate, authz.Resource{}); err != nil {
+		return nil, err
+	}
+
+	member, err := s.workspaceRepo.UpdateMemberRole(ctx.Request().Context(), payload.WorkspaceID, payload.UserID, payload.Role)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to update workspace member")
+		return nil, err
+	}
+
+	s.audit.Record(ctx, &payload.WorkspaceID, audit.EventMemberRoleChanged,
+		audit.Target{Type: "member", ID: member.UserID},
+		map[string]string{"role": string(member.Role)})
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "workspace_member_updated").
+		Str("workspace_id", payload.WorkspaceID.String()).
+		Str("member_id", member.UserID).
+		Str("role", string(member.Role)).
+		Msg("Workspace member updated successfully")
+
+	return member, nil
+}
+
+func (s *WorkspaceService) RemoveMember(ctx echo.Context, userID string, payload *workspace.RemoveMemberPayload) error {
+	logger := middleware.GetLogger(ctx)
+
+	if err := authorize(ctx, authz.MemberRemove, authz.Resource{OwnerID: payload.UserID}); err != nil {
+		return err
+	}
+
+	err := s.workspaceRepo.RemoveMember(ctx.Request().Context(), payload.WorkspaceID, payload.UserID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to remove workspace member")
+		return err
+	}
+
+	s.audit.Record(ctx, &payload.WorkspaceID, audit.EventMemberRemoved,
+		audit.Target{Type: "member", ID: payload.UserID}, nil)
+
+	// Business event log
+	eventLogger := middleware.GetLogger(ctx)
+	eventLogger.Info().
+		Str("event", "workspace_member_removed").
+		Str("workspace_id", payload.WorkspaceID.String()).
+		Str("member_id", payload.UserID).
+		Msg("Workspace member removed successfully")
+
+	return nil
+}
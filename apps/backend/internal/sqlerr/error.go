@@ -36,6 +36,26 @@ const (
 	// due to reaching the maximum number of connections.
 	// This is different from blocking waiting on a connection pool.
 	TooManyConnections Code = "too_many_connections"
+
+	// QueryCanceled is reported when a statement was canceled, such as by
+	// its timeout.
+	QueryCanceled Code = "query_canceled"
+
+	// AdminShutdown is reported when the server closed the connection
+	// because it is shutting down or restarting, as it does during a failover.
+	AdminShutdown Code = "admin_shutdown"
+
+	// CannotConnectNow is reported when the server is starting up or
+	// recovering and doesn't accept connections yet.
+	CannotConnectNow Code = "cannot_connect_now"
+
+	// ReadOnlyTransaction is reported when a write reaches a server that
+	// became a standby, such as the old primary during a switchover.
+	ReadOnlyTransaction Code = "read_only_transaction"
+
+	// ConnectionFailure is reported when the connection to the server failed
+	// or was lost.
+	ConnectionFailure Code = "connection_failure"
 )
 
 // MapCode maps an underlying database error to a Code.
@@ -57,6 +77,16 @@ func MapCode(code string) Code {
 		return DeadlockDetected
 	case "53300":
 		return TooManyConnections
+	case "57014":
+		return QueryCanceled
+	case "57P01", "57P02":
+		return AdminShutdown
+	case "57P03":
+		return CannotConnectNow
+	case "25006":
+		return ReadOnlyTransaction
+	case "08000", "08001", "08003", "08004", "08006":
+		return ConnectionFailure
 	default:
 		return Other
 	}
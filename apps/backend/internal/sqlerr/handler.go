@@ -1,6 +1,7 @@
 package sqlerr
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -147,6 +148,51 @@ func extractColumnForUniqueViolation(constraintName string) string {
 	return ""
 }
 
+// queryTimeoutError reports a statement that ran out of time, see
+// database.WithQueryTimeout
+func queryTimeoutError() error {
+	code := "QUERY_TIMEOUT"
+	return errs.NewGatewayTimeoutError("The request took too long to complete, please try again", true, &code)
+}
+
+// IsFailover reports whether err is a statement failing because the database
+// is failing over: the server shutting down or not yet accepting
+// connections, a write reaching the old primary, now a standby, or a
+// connection that couldn't be used. None of them ran the statement, so it
+// can be run again on new connections.
+func IsFailover(err error) bool {
+	// A statement whose context ended was given up on, not failed
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var pgerr *pgconn.PgError
+	if errors.As(err, &pgerr) {
+		switch MapCode(pgerr.Code) {
+		case AdminShutdown, CannotConnectNow, ReadOnlyTransaction, ConnectionFailure:
+			return true
+		}
+		return false
+	}
+
+	var connectErr *pgconn.ConnectError
+	return errors.As(err, &connectErr) || pgconn.SafeToRetry(err)
+}
+
+// IsReadOnly reports whether err is a write rejected because the server is
+// read-only, such as a standby while a replica is promoted
+func IsReadOnly(err error) bool {
+	var pgerr *pgconn.PgError
+	return errors.As(err, &pgerr) && MapCode(pgerr.Code) == ReadOnlyTransaction
+}
+
+// unavailableError reports a database that is failing over and didn't come
+// back in time
+func unavailableError() error {
+	code := "DATABASE_UNAVAILABLE"
+	return errs.NewServiceUnavailableError("The service is briefly unavailable, please try again", true, &code)
+}
+
 // HandleError processes a database error into an appropriate application error
 func HandleError(err error) error {
 	// If it's already a custom HTTP error, just return it
@@ -188,6 +234,12 @@ func HandleError(err error) error {
 		case CheckViolation:
 			return errs.NewUnprocessableError(userMessage, true, &errorCode, nil, nil)
 
+		case QueryCanceled:
+			return queryTimeoutError()
+
+		case AdminShutdown, CannotConnectNow, ReadOnlyTransaction, ConnectionFailure:
+			return unavailableError()
+
 		default:
 			return errs.NewInternalServerError()
 		}
@@ -205,6 +257,12 @@ func HandleError(err error) error {
 				entityName), true, nil)
 		}
 		return errs.NewNotFoundError("Resource not found", false, nil)
+
+	case errors.Is(err, context.DeadlineExceeded), pgconn.Timeout(err):
+		return queryTimeoutError()
+
+	case IsFailover(err):
+		return unavailableError()
 	}
 
 	return errs.NewInternalServerError()
@@ -23,7 +23,7 @@ type TestDB struct {
 }
 
 // SetupTestDB creates a Postgres container and applies migrations
-func SetupTestDB(t *testing.T) (*TestDB, func()) {
+func SetupTestDB(t testing.TB) (*TestDB, func()) {
 	t.Helper()
 
 	ctx := context.Background()
@@ -93,6 +93,7 @@ func SetupTestDB(t *testing.T) (*TestDB, func()) {
 			Address: "localhost:6379",
 		},
 		Auth: config.AuthConfig{
+			Provider:  config.AuthProviderClerk,
 			SecretKey: "test-secret",
 		},
 	}
@@ -157,3 +158,36 @@ func (db *TestDB) CleanupTestDB(ctx context.Context, logger *zerolog.Logger) err
 
 	return nil
 }
+
+// SetupTestRedis creates a Redis container and returns its address
+func SetupTestRedis(t testing.TB) string {
+	t.Helper()
+
+	ctx := context.Background()
+
+	req := testcontainers.ContainerRequest{
+		Image:        "redis:7-alpine",
+		ExposedPorts: []string{"6379/tcp"},
+		WaitingFor:   wait.ForLog("Ready to accept connections").WithStartupTimeout(30 * time.Second),
+	}
+
+	redisContainer, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: req,
+		Started:          true,
+	})
+	require.NoError(t, err, "failed to start redis container")
+
+	t.Cleanup(func() {
+		if err := redisContainer.Terminate(ctx); err != nil {
+			t.Logf("failed to terminate container: %v", err)
+		}
+	})
+
+	host, err := redisContainer.Host(ctx)
+	require.NoError(t, err, "failed to get container host")
+
+	mappedPort, err := redisContainer.MappedPort(ctx, "6379")
+	require.NoError(t, err, "failed to get mapped port")
+
+	return fmt.Sprintf("%s:%d", host, mappedPort.Int())
+}
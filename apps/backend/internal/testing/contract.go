@@ -0,0 +1,276 @@
+package testing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mabhi256/tasker/internal/config"
+	"github.com/mabhi256/tasker/internal/lib/openapi"
+	"github.com/mabhi256/tasker/internal/server"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Contract tests authenticate with the dev provider as this user
+const (
+	ContractToken  = "contract-token"
+	ContractUserID = "user_contract"
+)
+
+// Recording is a sequence of requests replayed in order against the router.
+// Values captured from a response can be used by the requests after it as
+// {{name}}, in their path, headers and body.
+type Recording struct {
+	Name      string     `json:"name"`
+	Exchanges []Exchange `json:"exchanges"`
+}
+
+// Exchange is a recorded request and the status it got
+type Exchange struct {
+	Name    string            `json:"name"`
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body"`
+	Status  int               `json:"status"`
+	// Capture names values of the response body by their JSON pointer
+	Capture map[string]string `json:"capture"`
+}
+
+// LoadRecordings reads the recordings in the JSON files under dir
+func LoadRecordings(t *testing.T, dir string) []Recording {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err, "failed to list recordings")
+	require.NotEmpty(t, files, "no recordings under %s", dir)
+	sort.Strings(files)
+
+	recordings := make([]Recording, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		require.NoError(t, err, "failed to read recording")
+
+		var recording Recording
+		require.NoError(t, json.Unmarshal(data, &recording), "failed to parse recording %s", file)
+		if recording.Name == "" {
+			recording.Name = strings.TrimSuffix(filepath.Base(file), ".json")
+		}
+		recordings = append(recordings, recording)
+	}
+
+	return recordings
+}
+
+// SetupContractServer starts a database and Redis and builds a server on
+// them configured the way the API is run locally, with every request bearing
+// ContractToken authenticated as ContractUserID. The working directory is
+// the project root for the rest of the test, so static files are served.
+func SetupContractServer(t testing.TB) (*TestDB, *server.Server) {
+	t.Helper()
+
+	testDB, dbCleanup := SetupTestDB(t)
+	t.Cleanup(dbCleanup)
+	redisAddress := SetupTestRedis(t)
+
+	db := testDB.Config.Database
+	env := map[string]string{
+		"PRIMARY.ENV":                  "local",
+		"SERVER.PORT":                  "8080",
+		"SERVER.READ_TIMEOUT":          "30",
+		"SERVER.WRITE_TIMEOUT":         "30",
+		"SERVER.IDLE_TIMEOUT":          "30",
+		"SERVER.CORS_ALLOWED_ORIGINS":  "*",
+		"DATABASE.HOST":                db.Host,
+		"DATABASE.PORT":                strconv.Itoa(db.Port),
+		"DATABASE.USER":                db.User,
+		"DATABASE.PASSWORD":            db.Password,
+		"DATABASE.NAME":                db.Name,
+		"DATABASE.SSL_MODE":            db.SSLMode,
+		"DATABASE.MAX_OPEN_CONNS":      strconv.Itoa(db.MaxOpenConns),
+		"DATABASE.MAX_IDLE_CONNS":      strconv.Itoa(db.MaxIdleConns),
+		"DATABASE.CONN_MAX_LIFETIME":   strconv.Itoa(db.ConnMaxLifetime),
+		"DATABASE.CONN_MAX_IDLE_TIME":  strconv.Itoa(db.ConnMaxIdleTime),
+		"REDIS.ADDRESS":                redisAddress,
+		"AUTH.PROVIDER":                string(config.AuthProviderDev),
+		"AUTH.DEV.TOKEN":               ContractToken,
+		"AUTH.DEV.USER_ID":             ContractUserID,
+		"EMAIL.RESEND_API_KEY":         "test-key",
+		"AWS.REGION":                   "us-east-1",
+		"AWS.ACCESS_KEY_ID":            "test-access-key",
+		"AWS.SECRET_ACCESS_KEY":        "test-secret-key",
+		"AWS.UPLOAD_BUCKET":            "test-bucket",
+		"OBSERVABILITY.LOGGING.LEVEL":  "warn",
+		"OBSERVABILITY.LOGGING.FORMAT": "json",
+	}
+	for key, value := range env {
+		t.Setenv("TASKER_"+key, value)
+	}
+
+	cfg, err := config.LoadConfig()
+	require.NoError(t, err, "failed to load config")
+
+	logger := zerolog.Nop()
+	srv, err := server.New(cfg, &logger, nil)
+	require.NoError(t, err, "failed to create server")
+	t.Cleanup(func() {
+		if err := srv.Shutdown(context.Background()); err != nil {
+			t.Logf("failed to shut down server: %v", err)
+		}
+	})
+
+	t.Chdir(ProjectRoot(t))
+
+	return testDB, srv
+}
+
+// ServedDocument fetches the OpenAPI document the router serves
+func ServedDocument(t *testing.T, handler http.Handler) *openapi.Document {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/static/openapi.json", nil))
+	require.Equal(t, http.StatusOK, rec.Code, "the OpenAPI document isn't served")
+
+	doc, err := openapi.Parse(rec.Body.Bytes())
+	require.NoError(t, err, "failed to parse the served OpenAPI document")
+
+	return doc
+}
+
+// ReplayContract replays each recording through handler as a subtest, and
+// fails it when a response's status differs from the recorded one or when
+// the response breaks its contract in doc. Every request is sent with
+// header, on top of its own.
+func ReplayContract(t *testing.T, handler http.Handler, doc *openapi.Document, recordings []Recording,
+	header http.Header,
+) {
+	t.Helper()
+
+	sent := 0
+	for _, recording := range recordings {
+		t.Run(recording.Name, func(t *testing.T) {
+			values := map[string]string{}
+			for i, exchange := range recording.Exchanges {
+				name := exchange.Name
+				if name == "" {
+					name = fmt.Sprintf("%d %s %s", i, exchange.Method, exchange.Path)
+				}
+
+				// Each request comes from an address of its own, so a replay
+				// isn't throttled by the per-client rate limit
+				sent++
+				client := fmt.Sprintf("198.51.100.%d:1234", sent%250+1)
+
+				// Later exchanges depend on what earlier ones created, so a
+				// broken exchange ends the recording
+				if !replayExchange(t, handler, doc, exchange, client, header, values) {
+					t.Fatalf("exchange %q failed", name)
+				}
+			}
+		})
+	}
+}
+
+func replayExchange(t *testing.T, handler http.Handler, doc *openapi.Document, exchange Exchange,
+	client string, header http.Header, values map[string]string,
+) bool {
+	t.Helper()
+
+	path := expand(exchange.Path, values)
+	body := bytes.NewReader([]byte(expand(string(exchange.Body), values)))
+
+	req := httptest.NewRequest(exchange.Method, path, body)
+	req.RemoteAddr = client
+	for key, vals := range header {
+		req.Header[key] = vals
+	}
+	if len(exchange.Body) > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range exchange.Headers {
+		req.Header.Set(key, expand(value, values))
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	ok := assert.Equal(t, exchange.Status, rec.Code, "%s %s: unexpected status, body: %s",
+		exchange.Method, path, rec.Body.String())
+	if err := doc.ValidateResponse(exchange.Method, req.URL.Path, rec.Code, rec.Header(), rec.Body.Bytes()); err != nil {
+		t.Error(err)
+		ok = false
+	}
+	if !ok || len(exchange.Capture) == 0 {
+		return ok
+	}
+
+	var response any
+	if !assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response), "failed to parse response to capture from") {
+		return false
+	}
+	for name, pointer := range exchange.Capture {
+		value, found := lookup(response, pointer)
+		if !assert.True(t, found, "%s %s: nothing to capture at %s", exchange.Method, path, pointer) {
+			return false
+		}
+		values[name] = value
+	}
+
+	return true
+}
+
+// expand replaces the {{name}} placeholders in s with captured values
+func expand(s string, values map[string]string) string {
+	for name, value := range values {
+		s = strings.ReplaceAll(s, "{{"+name+"}}", value)
+	}
+	return s
+}
+
+// lookup finds the value at a JSON pointer, formatted for use in a path
+func lookup(value any, pointer string) (string, bool) {
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		if token == "" {
+			continue
+		}
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+
+		switch v := value.(type) {
+		case map[string]any:
+			next, ok := v[token]
+			if !ok {
+				return "", false
+			}
+			value = next
+		case []any:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(v) {
+				return "", false
+			}
+			value = v[i]
+		default:
+			return "", false
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case nil, map[string]any, []any:
+		return "", false
+	default:
+		return fmt.Sprint(v), true
+	}
+}
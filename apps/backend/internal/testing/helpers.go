@@ -47,7 +47,7 @@ func MustMarshalJSON(t *testing.T, v any) []byte {
 }
 
 // ProjectRoot returns the absolute path to the project root
-func ProjectRoot(t *testing.T) string {
+func ProjectRoot(t testing.TB) string {
 	t.Helper()
 
 	dir, err := os.Getwd()
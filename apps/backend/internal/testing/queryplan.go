@@ -0,0 +1,129 @@
+package testing
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RecordedQuery is a statement as sent to Postgres, after named arguments
+// are rewritten to positional ones
+type RecordedQuery struct {
+	SQL  string
+	Args []any
+}
+
+// QueryRecorder is a pgx tracer that keeps every statement run through it
+type QueryRecorder struct {
+	mu      sync.Mutex
+	queries []RecordedQuery
+}
+
+func (r *QueryRecorder) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.queries = append(r.queries, RecordedQuery{SQL: data.SQL, Args: data.Args})
+	return ctx
+}
+
+func (r *QueryRecorder) TraceQueryEnd(context.Context, *pgx.Conn, pgx.TraceQueryEndData) {}
+
+// Take returns the statements recorded so far and starts over
+func (r *QueryRecorder) Take() []RecordedQuery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	queries := r.queries
+	r.queries = nil
+	return queries
+}
+
+// NewRecordingPool opens a second pool on the test database that records
+// every statement, so tests can inspect the queries a repository runs
+func NewRecordingPool(t *testing.T, db *TestDB) (*pgxpool.Pool, *QueryRecorder) {
+	t.Helper()
+
+	recorder := &QueryRecorder{}
+
+	poolConfig := db.Pool.Config()
+	poolConfig.ConnConfig.Tracer = recorder
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+	require.NoError(t, err, "failed to create recording pool")
+	t.Cleanup(pool.Close)
+
+	return pool, recorder
+}
+
+// PlanNode is a node of the JSON output of EXPLAIN
+type PlanNode struct {
+	NodeType     string     `json:"Node Type"`
+	RelationName string     `json:"Relation Name"`
+	IndexName    string     `json:"Index Name"`
+	Plans        []PlanNode `json:"Plans"`
+}
+
+// Walk calls fn for the node and every node below it
+func (n *PlanNode) Walk(fn func(node *PlanNode)) {
+	fn(n)
+	for i := range n.Plans {
+		n.Plans[i].Walk(fn)
+	}
+}
+
+// ExplainQuery returns the plan Postgres picks for a recorded query, using
+// the same arguments. The statement is planned but not run.
+func ExplainQuery(t *testing.T, pool *pgxpool.Pool, query RecordedQuery) *PlanNode {
+	t.Helper()
+
+	var raw []byte
+	err := pool.QueryRow(context.Background(), "EXPLAIN (FORMAT JSON) "+query.SQL, query.Args...).Scan(&raw)
+	require.NoError(t, err, "failed to explain query: %s", query.SQL)
+
+	var plans []struct {
+		Plan PlanNode `json:"Plan"`
+	}
+	require.NoError(t, json.Unmarshal(raw, &plans), "failed to parse query plan")
+	require.Len(t, plans, 1, "expected a single query plan")
+
+	return &plans[0].Plan
+}
+
+// IsExplainable reports whether a recorded statement is one EXPLAIN
+// accepts, as opposed to transaction control and the like
+func IsExplainable(query RecordedQuery) bool {
+	fields := strings.Fields(query.SQL)
+	if len(fields) == 0 {
+		return false
+	}
+
+	switch strings.ToUpper(fields[0]) {
+	case "SELECT", "WITH", "UPDATE", "DELETE", "INSERT":
+		return true
+	}
+	return false
+}
+
+// AssertNoSeqScan fails if the plan reads any of the given tables with a
+// sequential scan
+func AssertNoSeqScan(t *testing.T, plan *PlanNode, query RecordedQuery, tables ...string) {
+	t.Helper()
+
+	plan.Walk(func(node *PlanNode) {
+		if node.NodeType != "Seq Scan" {
+			return
+		}
+		for _, table := range tables {
+			assert.NotEqual(t, table, node.RelationName,
+				"sequential scan on %s in query:\n%s", table, query.SQL)
+		}
+	})
+}
@@ -0,0 +1,40 @@
+package testing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mabhi256/tasker/internal/database/seed"
+	"github.com/mabhi256/tasker/internal/model/workspace"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+// Seed runs the seeders against the test database, failing the test if one
+// fails
+func Seed(t testing.TB, db *TestDB, seeders ...seed.Seeder) {
+	t.Helper()
+
+	logger := zerolog.Nop()
+	err := seed.Run(context.Background(), db.Pool, &logger, seeders...)
+	require.NoError(t, err, "failed to seed test data")
+}
+
+// SeedFixtures seeds the fixtures in order, setting the ids of those that
+// have none
+func SeedFixtures(t *testing.T, db *TestDB, fixtures ...seed.Fixture) {
+	t.Helper()
+
+	Seed(t, db, seed.Fixtures(t.Name(), fixtures...))
+}
+
+// SeedWorkspace seeds a workspace owned by userID, with members joined in
+// their roles, and returns its id
+func SeedWorkspace(t *testing.T, db *TestDB, userID string, members map[string]workspace.Role) uuid.UUID {
+	t.Helper()
+
+	ws := &seed.Workspace{Name: "Workspace", OwnerID: userID, Members: members}
+	SeedFixtures(t, db, ws)
+	return ws.ID
+}
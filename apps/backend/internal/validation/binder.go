@@ -10,9 +10,11 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/reqctx"
 )
 
-const bindingErrorsKey = "binding_errors"
+// ErrUnknownField is the error of a JSON field the payload doesn't have
+const ErrUnknownField = "unknown field"
 
 type CustomBinder struct {
 	echo.DefaultBinder
@@ -33,7 +35,7 @@ func (cb *CustomBinder) Bind(i any, c echo.Context) error {
 	allErrors = append(allErrors, bodyErrs...)
 
 	if len(allErrors) > 0 {
-		c.Set(bindingErrorsKey, allErrors)
+		reqctx.BindingErrors.Set(c, allErrors)
 	}
 	return nil
 }
@@ -88,8 +90,14 @@ func (cb *CustomBinder) getParamValue(c echo.Context, field reflect.StructField,
 	}
 }
 
-// BindBody validates types, checks unknown fields, then unmarshals
+// BindBody validates types, checks unknown fields, then unmarshals. Form
+// bodies are left to the handler, which reads their files.
 func (cb *CustomBinder) BindBody(c echo.Context, i any) ([]errs.BindError, error) {
+	contentType := c.Request().Header.Get(echo.HeaderContentType)
+	if strings.HasPrefix(contentType, echo.MIMEMultipartForm) || strings.HasPrefix(contentType, echo.MIMEApplicationForm) {
+		return nil, nil
+	}
+
 	bodyBytes, err := io.ReadAll(c.Request().Body)
 	if err != nil {
 		return nil, echo.NewHTTPError(400, "failed to read request body")
@@ -126,7 +134,7 @@ func (cb *CustomBinder) validateJSONStructure(i any, rawMap map[string]any) []er
 		if !exists {
 			errors = append(errors, errs.BindError{
 				Field: &fieldName,
-				Error: "unknown field",
+				Error: ErrUnknownField,
 			})
 			continue
 		}
@@ -276,6 +284,25 @@ func createFieldError(fieldName, message, source string) errs.BindError {
 	return fieldError
 }
 
+// FieldOf returns the field a binding or validation error is about, and the
+// part of the request it was read from: json, query, param, form or header
+func FieldOf(e errs.BindError) (source string, field string) {
+	switch {
+	case e.Field != nil:
+		return "json", *e.Field
+	case e.Query != nil:
+		return "query", *e.Query
+	case e.Param != nil:
+		return "param", *e.Param
+	case e.Form != nil:
+		return "form", *e.Form
+	case e.Header != nil:
+		return "header", *e.Header
+	default:
+		return "", ""
+	}
+}
+
 func parseUUID(s string) ([16]byte, error) {
 	var uuid [16]byte
 	s = strings.ReplaceAll(s, "-", "")
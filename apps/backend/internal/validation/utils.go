@@ -9,6 +9,7 @@ import (
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 	"github.com/mabhi256/tasker/internal/errs"
+	"github.com/mabhi256/tasker/internal/reqctx"
 )
 
 type Validatable interface {
@@ -34,7 +35,7 @@ func BindAndValidate(c echo.Context, payload Validatable) error {
 
 	// Retrieve any binding errors from context
 	fieldsWithBindingErrors := make(map[string]bool)
-	if bindingErrs, ok := c.Get(bindingErrorsKey).([]errs.BindError); ok {
+	if bindingErrs, ok := reqctx.BindingErrors.Get(c); ok {
 		allErrors = append(allErrors, bindingErrs...)
 
 		// Track which fields have binding errors
@@ -130,6 +131,12 @@ func formatValidationMessage(err validator.FieldError) string {
 		return "must be a valid UUID"
 	case "uuidList":
 		return "must be a comma-separated list of valid UUIDs"
+	case "sort":
+		return fmt.Sprintf("must be a comma-separated list of: %s, each prefixed with - to sort in descending order", err.Param())
+	case "emoji":
+		return "must be an emoji"
+	case "bcp47_language_tag":
+		return "must be a language tag, such as en or pt-BR"
 	default:
 		if err.Param() != "" {
 			return fmt.Sprintf("%s: %s:%s", strings.ToLower(err.Field()), err.Tag(), err.Param())
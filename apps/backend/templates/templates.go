@@ -0,0 +1,15 @@
+// Package templates embeds the templates the server renders, so the binary
+// doesn't depend on the directory it is started from.
+package templates
+
+import "embed"
+
+// Emails holds the email templates under emails/
+//
+//go:embed emails
+var Emails embed.FS
+
+// Snapshots holds the todo snapshot templates under snapshots/
+//
+//go:embed snapshots
+var Snapshots embed.FS
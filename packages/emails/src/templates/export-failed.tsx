@@ -0,0 +1,115 @@
+import {
+  Body,
+  Button,
+  Container,
+  Head,
+  Heading,
+  Hr,
+  Html,
+  Img,
+  Preview,
+  Section,
+  Text,
+  Tailwind,
+} from "@react-email/components";
+
+interface ExportFailedEmailProps {
+  scheduleName: string;
+  scheduleID: string;
+  destination: string;
+  failedAt: string;
+  attempts: string;
+  errorMessage: string;
+}
+
+export const ExportFailedEmail = ({
+  scheduleName = "{{.ScheduleName}}",
+  scheduleID = "{{.ScheduleID}}",
+  destination = "{{.Destination}}",
+  failedAt = "{{.FailedAt}}",
+  attempts = "{{.Attempts}}",
+  errorMessage = "{{.Error}}",
+}: ExportFailedEmailProps) => {
+  return (
+    <Html>
+      <Head />
+      <Preview>Export failed: "{scheduleName}" could not be delivered</Preview>
+      <Tailwind>
+        <Body className="bg-gray-100 font-sans">
+          <Container className="bg-white p-8 rounded-lg shadow-sm my-10 mx-auto max-w-[600px]">
+            <Section className="mb-6 text-center">
+              <Img
+                src="http://localhost:8080/static/full_logo.png?height=48&width=48"
+                width="48"
+                height="48"
+                alt="Tasker Logo"
+                className="mx-auto"
+              />
+              <Heading className="text-2xl font-bold text-gray-800 mt-4">
+                ⚠️ Export Failed
+              </Heading>
+            </Section>
+
+            <Section className="bg-red-50 border-l-4 border-red-500 p-4 mb-6">
+              <Text className="font-semibold text-red-600 text-lg mb-2">
+                "{scheduleName}" could not be delivered to {destination}
+              </Text>
+              <Text className="text-gray-700 text-base">
+                Failed at: {failedAt} after {attempts} attempts
+              </Text>
+            </Section>
+
+            <Section>
+              <Text className="text-gray-700 text-base">
+                The last attempt returned the following error:
+              </Text>
+              <Text className="bg-gray-50 text-gray-800 text-sm font-mono p-4 rounded-md">
+                {errorMessage}
+              </Text>
+              <Text className="text-gray-700 text-base">
+                Check that the destination is reachable and that its
+                credentials are still valid. The next scheduled export will
+                run as usual.
+              </Text>
+            </Section>
+
+            <Section className="my-8 text-center">
+              <Button
+                className="bg-blue-600 hover:bg-blue-700 text-white font-medium rounded-md px-6 py-3"
+                href={`/settings/exports?id=${scheduleID}`}
+              >
+                View Export
+              </Button>
+            </Section>
+
+            <Hr className="border-gray-200 my-6" />
+
+            <Section>
+              <Text className="text-gray-600 text-sm">
+                You're receiving this alert because you created this export
+                schedule.
+              </Text>
+            </Section>
+
+            <Section className="mt-8 text-center">
+              <Text className="text-gray-500 text-xs">
+                © {new Date().getFullYear()} Tasker. All rights reserved.
+              </Text>
+            </Section>
+          </Container>
+        </Body>
+      </Tailwind>
+    </Html>
+  );
+};
+
+ExportFailedEmail.PreviewProps = {
+  scheduleName: "Nightly CSV backup",
+  scheduleID: "123e4567-e89b-12d3-a456-426614174000",
+  destination: "S3",
+  failedAt: "Friday, January 12, 2025 at 3:00 AM",
+  attempts: "4",
+  errorMessage: "failed to upload export to S3: AccessDenied",
+};
+
+export default ExportFailedEmail;
@@ -0,0 +1,108 @@
+import {
+  Body,
+  Container,
+  Head,
+  Heading,
+  Hr,
+  Html,
+  Img,
+  Preview,
+  Section,
+  Text,
+  Tailwind,
+} from "@react-email/components";
+
+interface JobFailedEmailProps {
+  taskType: string;
+  queue: string;
+  taskID: string;
+  failedAt: string;
+  attempts: string;
+  occurrences: string;
+  errorMessage: string;
+}
+
+export const JobFailedEmail = ({
+  taskType = "{{.TaskType}}",
+  queue = "{{.Queue}}",
+  taskID = "{{.TaskID}}",
+  failedAt = "{{.FailedAt}}",
+  attempts = "{{.Attempts}}",
+  occurrences = "{{.Occurrences}}",
+  errorMessage = "{{.Error}}",
+}: JobFailedEmailProps) => {
+  return (
+    <Html>
+      <Head />
+      <Preview>Background task {taskType} failed permanently</Preview>
+      <Tailwind>
+        <Body className="bg-gray-100 font-sans">
+          <Container className="bg-white p-8 rounded-lg shadow-sm my-10 mx-auto max-w-[600px]">
+            <Section className="mb-6 text-center">
+              <Img
+                src="http://localhost:8080/static/full_logo.png?height=48&width=48"
+                width="48"
+                height="48"
+                alt="Tasker Logo"
+                className="mx-auto"
+              />
+              <Heading className="text-2xl font-bold text-gray-800 mt-4">
+                ⚠️ Background Task Failed
+              </Heading>
+            </Section>
+
+            <Section className="bg-red-50 border-l-4 border-red-500 p-4 mb-6">
+              <Text className="font-semibold text-red-600 text-lg mb-2">
+                {taskType} in queue {queue} ran out of retries
+              </Text>
+              <Text className="text-gray-700 text-base">
+                Failed at: {failedAt} after {attempts} attempts (failure{" "}
+                {occurrences} for this task)
+              </Text>
+            </Section>
+
+            <Section>
+              <Text className="text-gray-700 text-base">
+                The last attempt returned the following error:
+              </Text>
+              <Text className="bg-gray-50 text-gray-800 text-sm font-mono p-4 rounded-md">
+                {errorMessage}
+              </Text>
+              <Text className="text-gray-700 text-base">
+                The task has been archived with id {taskID}. Once the cause is
+                fixed it can be retried from the admin jobs API.
+              </Text>
+            </Section>
+
+            <Hr className="border-gray-200 my-6" />
+
+            <Section>
+              <Text className="text-gray-600 text-sm">
+                You're receiving this alert because your address is listed for
+                background task failures.
+              </Text>
+            </Section>
+
+            <Section className="mt-8 text-center">
+              <Text className="text-gray-500 text-xs">
+                © {new Date().getFullYear()} Tasker. All rights reserved.
+              </Text>
+            </Section>
+          </Container>
+        </Body>
+      </Tailwind>
+    </Html>
+  );
+};
+
+JobFailedEmail.PreviewProps = {
+  taskType: "webhook:deliver",
+  queue: "default",
+  taskID: "123e4567-e89b-12d3-a456-426614174000",
+  failedAt: "Friday, January 12, 2025 at 3:00 AM UTC",
+  attempts: "9",
+  occurrences: "1",
+  errorMessage: "endpoint responded with status 503",
+};
+
+export default JobFailedEmail;
@@ -36,8 +36,12 @@ export const categoryContract = c.router(
         name: true,
         color: true,
         description: true,
+        defaultPriority: true,
+        defaultReminderMinutes: true,
       }).partial({
         description: true,
+        defaultPriority: true,
+        defaultReminderMinutes: true,
       }),
       responses: {
         201: ZTodoCategory,
@@ -65,7 +69,14 @@ export const categoryContract = c.router(
         name: true,
         color: true,
         description: true,
-      }).partial(),
+        defaultPriority: true,
+        defaultReminderMinutes: true,
+      })
+        .partial()
+        .extend({
+          clearDefaultPriority: z.boolean().optional(),
+          clearDefaultReminderMinutes: z.boolean().optional(),
+        }),
       responses: {
         200: ZTodoCategory,
       },
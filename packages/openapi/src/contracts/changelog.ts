@@ -0,0 +1,23 @@
+import { initContract } from "@ts-rest/core";
+import { z } from "zod";
+import { ZChangelog, ZChangelogKind } from "@tasker/zod";
+
+const c = initContract();
+
+export const changelogContract = c.router({
+  getChangelog: {
+    summary: "Get API changelog",
+    path: "/changelog",
+    method: "GET",
+    description:
+      "Changes and deprecations of the API, newest first. Served as an RSS feed with format=rss, or to clients that accept application/rss+xml.",
+    query: z.object({
+      since: z.string().datetime().optional(),
+      kind: ZChangelogKind.optional(),
+      format: z.enum(["json", "rss"]).optional(),
+    }),
+    responses: {
+      200: ZChangelog,
+    },
+  },
+});
@@ -1,5 +1,10 @@
 import { getSecurityMetadata } from "../utils.js";
-import { ZTodoComment } from "@tasker/zod";
+import {
+  ZCommentReaction,
+  ZRenderQuery,
+  ZThreadComment,
+  ZTodoComment,
+} from "@tasker/zod";
 import { initContract } from "@ts-rest/core";
 import z from "zod";
 
@@ -15,6 +20,8 @@ export const commentContract = c.router(
       method: "POST",
       body: ZTodoComment.pick({
         content: true,
+      }).extend({
+        parentCommentId: z.string().uuid().optional(),
       }),
       responses: {
         201: ZTodoComment,
@@ -26,6 +33,7 @@ export const commentContract = c.router(
       summary: "Get comments for todo",
       path: "/todos/:id/comments",
       method: "GET",
+      query: ZRenderQuery,
       responses: {
         200: z.array(ZTodoComment),
       },
@@ -54,6 +62,43 @@ export const commentContract = c.router(
       },
       metadata: metadata,
     },
+
+    getCommentThread: {
+      summary: "Get comment with its replies",
+      path: "/comments/:id/thread",
+      method: "GET",
+      query: ZRenderQuery,
+      responses: {
+        200: z.array(ZThreadComment),
+      },
+      metadata: metadata,
+    },
+
+    addReaction: {
+      summary: "React to comment",
+      path: "/comments/:id/reactions",
+      method: "POST",
+      body: ZCommentReaction.pick({
+        emoji: true,
+      }),
+      responses: {
+        200: z.array(ZCommentReaction),
+      },
+      metadata: metadata,
+    },
+
+    removeReaction: {
+      summary: "Remove reaction from comment",
+      path: "/comments/:id/reactions",
+      method: "DELETE",
+      query: ZCommentReaction.pick({
+        emoji: true,
+      }),
+      responses: {
+        200: z.array(ZCommentReaction),
+      },
+      metadata: metadata,
+    },
   },
   {
     pathPrefix: "/v1",
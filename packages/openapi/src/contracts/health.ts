@@ -10,9 +10,39 @@ export const healthContract = c.router({
     summary: "Get health",
     path: "/status",
     method: "GET",
-    description: "Get health status",
+    description: "Run every health check now and get their results",
     responses: {
       200: ZHealthResponse,
+      503: ZHealthResponse,
+    },
+  },
+  getLiveness: {
+    summary: "Liveness probe",
+    path: "/livez",
+    method: "GET",
+    description: "Passes while the process can serve requests, without checking its dependencies",
+    responses: {
+      200: ZHealthResponse,
+    },
+  },
+  getReadiness: {
+    summary: "Readiness probe",
+    path: "/readyz",
+    method: "GET",
+    description: "Passes when every health check passed its latest run",
+    responses: {
+      200: ZHealthResponse,
+      503: ZHealthResponse,
+    },
+  },
+  getStartup: {
+    summary: "Startup probe",
+    path: "/healthz",
+    method: "GET",
+    description: "Passes once every health check has passed at least once",
+    responses: {
+      200: ZHealthResponse,
+      503: ZHealthResponse,
     },
   },
 });
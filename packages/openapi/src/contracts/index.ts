@@ -1,5 +1,6 @@
 import { initContract } from "@ts-rest/core";
 import { healthContract } from "./health.js";
+import { changelogContract } from "./changelog.js";
 import { todoContract } from "./todo.js";
 import { commentContract } from "./comment.js";
 import { categoryContract } from "./category.js";
@@ -8,6 +9,7 @@ const c = initContract();
 
 export const apiContract = c.router({
   Health: healthContract,
+  Changelog: changelogContract,
   Todo: todoContract,
   Comment: commentContract,
   Category: categoryContract,
@@ -1,10 +1,16 @@
 import { getSecurityMetadata } from "../utils.js";
 import {
   schemaWithPagination,
+  ZCopiedTodo,
+  ZDependentTodo,
   ZPopulatedTodo,
   ZTodo,
   ZTodoAttachment,
+  ZTodoDependencies,
+  ZRenderQuery,
+  ZSearchMeta,
   ZTodoStats,
+  ZTodoStatus,
 } from "@tasker/zod";
 import { initContract } from "@ts-rest/core";
 import z from "zod";
@@ -43,6 +49,7 @@ export const todoContract = c.router(
         dueTo: z.string().datetime().optional(),
         overdue: z.boolean().optional(),
         completed: z.boolean().optional(),
+        render: ZRenderQuery.shape.render,
       }),
       responses: {
         200: schemaWithPagination(ZPopulatedTodo),
@@ -54,7 +61,8 @@ export const todoContract = c.router(
       summary: "Create a new todo",
       path: "/todos",
       method: "POST",
-      description: "Create a new todo",
+      description:
+        "Create a new todo. With publishAt it's created scheduled, and published then.",
       body: ZTodo.pick({
         title: true,
         description: true,
@@ -63,6 +71,8 @@ export const todoContract = c.router(
         parentTodoId: true,
         categoryId: true,
         metadata: true,
+        reminderMinutes: true,
+        publishAt: true,
       })
         .partial()
         .required({
@@ -79,6 +89,7 @@ export const todoContract = c.router(
       path: "/todos/:id",
       method: "GET",
       description: "Get todo by ID",
+      query: ZRenderQuery,
       responses: {
         200: ZPopulatedTodo,
       },
@@ -89,17 +100,23 @@ export const todoContract = c.router(
       summary: "Update todo",
       path: "/todos/:id",
       method: "PATCH",
-      description: "Update todo",
+      description:
+        "Update todo. publishAt reschedules a scheduled todo, and a status publishes it now.",
       body: ZTodo.pick({
         title: true,
         description: true,
-        status: true,
         priority: true,
         dueDate: true,
         parentTodoId: true,
         categoryId: true,
         metadata: true,
-      }).partial(),
+        reminderMinutes: true,
+        publishAt: true,
+      })
+        .partial()
+        .extend({
+          status: ZTodoStatus.exclude(["scheduled"]).optional(),
+        }),
       responses: {
         200: ZTodo,
       },
@@ -117,6 +134,22 @@ export const todoContract = c.router(
       metadata: metadata,
     },
 
+    copyTodo: {
+      summary: "Copy todo to another workspace",
+      path: "/todos/:id/copy",
+      method: "POST",
+      description:
+        "Copy a todo with its subtasks and attachments into another workspace the user can create todos in. Owners and uploaders who aren't members of the target are replaced by the user, and categories are matched by name.",
+      body: z.object({
+        targetWorkspaceId: z.string().uuid(),
+        includeAttachments: z.boolean().optional(),
+      }),
+      responses: {
+        201: ZCopiedTodo,
+      },
+      metadata: metadata,
+    },
+
     getTodoStats: {
       summary: "Get todo statistics",
       path: "/todos/stats",
@@ -128,6 +161,82 @@ export const todoContract = c.router(
       metadata: metadata,
     },
 
+    searchTodos: {
+      summary: "Search todos",
+      path: "/todos/search",
+      method: "GET",
+      description:
+        "Full text search over todos. Falls back to Postgres when the search engine is unavailable, with meta.degraded set.",
+      query: z.object({
+        q: z.string().min(1).max(200),
+        page: z.number().min(1).optional(),
+        limit: z.number().min(1).max(100).optional(),
+        render: ZRenderQuery.shape.render,
+      }),
+      responses: {
+        200: z.object({
+          data: z.array(ZPopulatedTodo),
+          total: z.number(),
+          page: z.number(),
+          limit: z.number(),
+          totalPages: z.number(),
+          meta: ZSearchMeta,
+        }),
+      },
+      metadata: metadata,
+    },
+
+    getWorkList: {
+      summary: "Get work list",
+      path: "/todos/worklist",
+      method: "GET",
+      description:
+        "Todos that aren't done yet, each after the todos blocking it and the most urgent first otherwise",
+      query: z.object({
+        limit: z.number().min(1).max(200).optional(),
+      }),
+      responses: {
+        200: z.array(ZDependentTodo),
+      },
+      metadata: metadata,
+    },
+
+    getTodoDependencies: {
+      summary: "Get todo dependencies",
+      path: "/todos/:id/dependencies",
+      method: "GET",
+      description: "Todos blocking this todo and todos it blocks",
+      responses: {
+        200: ZTodoDependencies,
+      },
+      metadata: metadata,
+    },
+
+    addTodoDependency: {
+      summary: "Block todo by another todo",
+      path: "/todos/:id/dependencies",
+      method: "POST",
+      description:
+        "Block todo by another todo. Dependencies that would form a cycle are rejected with 409.",
+      body: z.object({
+        blockedById: z.string().uuid(),
+      }),
+      responses: {
+        200: ZTodoDependencies,
+      },
+      metadata: metadata,
+    },
+
+    removeTodoDependency: {
+      summary: "Remove todo dependency",
+      path: "/todos/:id/dependencies/:blockedById",
+      method: "DELETE",
+      responses: {
+        200: ZTodoDependencies,
+      },
+      metadata: metadata,
+    },
+
     uploadTodoAttachment: {
       summary: "Upload attachment to todo",
       path: "/todos/:id/attachments",
@@ -6,6 +6,8 @@ export const ZTodoCategory = z.object({
   name: z.string(),
   color: z.string(),
   description: z.string().nullable(),
+  defaultPriority: z.enum(["low", "medium", "high"]).nullable(),
+  defaultReminderMinutes: z.number().int().min(1).max(40320).nullable(),
   createdAt: z.string(),
   updatedAt: z.string(),
 });
@@ -0,0 +1,23 @@
+import { z } from "zod";
+
+export const ZChangelogKind = z.enum([
+  "added",
+  "changed",
+  "deprecated",
+  "removed",
+  "fixed",
+]);
+
+export const ZChangelogEntry = z.object({
+  id: z.string(),
+  kind: ZChangelogKind,
+  title: z.string(),
+  description: z.string(),
+  endpoints: z.array(z.string()),
+  publishedAt: z.string().datetime(),
+  sunsetAt: z.string().datetime().nullable(),
+});
+
+export const ZChangelog = z.object({
+  entries: z.array(ZChangelogEntry),
+});
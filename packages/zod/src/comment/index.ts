@@ -5,6 +5,20 @@ export const ZTodoComment = z.object({
   todoId: z.string().uuid(),
   userId: z.string(),
   content: z.string(),
+  parentCommentId: z.string().uuid().nullable(),
+  contentHtml: z.string().optional(),
   createdAt: z.string(),
   updatedAt: z.string(),
-});
\ No newline at end of file
+});
+
+export const ZCommentReaction = z.object({
+  emoji: z.string(),
+  count: z.number(),
+  reacted: z.boolean(),
+});
+
+export const ZThreadComment = ZTodoComment.extend({
+  depth: z.number(),
+  replyCount: z.number(),
+  reactions: z.array(ZCommentReaction),
+});
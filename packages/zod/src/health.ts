@@ -1,17 +1,20 @@
 import { z } from "zod";
 
 const ZHealthCheck = z.object({
-  status: z.string(),
+  status: z.enum(["healthy", "unhealthy"]),
   response_time: z.string(),
   error: z.string().optional(),
+  checked_at: z.string().datetime(),
 });
 
 export const ZHealthResponse = z.object({
-  status: z.enum(["healthy", "unhealthy"]),
+  status: z.enum(["healthy", "unhealthy", "starting"]),
   timestamp: z.string().datetime(),
   environment: z.string(),
   checks: z.object({
-    database: ZHealthCheck,
+    database: ZHealthCheck.optional(),
     redis: ZHealthCheck.optional(),
+    queue: ZHealthCheck.optional(),
+    storage: ZHealthCheck.optional(),
   }),
 });
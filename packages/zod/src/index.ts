@@ -5,6 +5,7 @@ extendZodWithOpenApi(z);
 
 export * from "./utils.js";
 export * from "./health.js";
+export * from "./changelog.js";
 export * from "./todo/index.js";
 export * from "./category/index.js";
 export * from "./comment/index.js";
\ No newline at end of file
@@ -2,7 +2,13 @@ import { ZTodoCategory } from "../category/index.js";
 import { ZTodoComment } from "../comment/index.js";
 import z from "zod";
 
-export const ZTodoStatus = z.enum(["draft", "active", "completed", "archived"]);
+export const ZTodoStatus = z.enum([
+  "draft",
+  "active",
+  "completed",
+  "archived",
+  "scheduled",
+]);
 
 export const ZTodoPriority = z.enum(["low", "medium", "high"]);
 
@@ -13,6 +19,12 @@ export const ZTodoMetadata = z.object({
   difficulty: z.number().optional(),
 });
 
+export const ZChecklistItem = z.object({
+  index: z.number(),
+  text: z.string(),
+  checked: z.boolean(),
+});
+
 export const ZTodo = z.object({
   id: z.string().uuid(),
   userId: z.string(),
@@ -26,6 +38,10 @@ export const ZTodo = z.object({
   categoryId: z.string().uuid().nullable(),
   metadata: ZTodoMetadata.nullable(),
   sortOrder: z.number(),
+  reminderMinutes: z.number().int().min(1).max(40320).nullable(),
+  publishAt: z.string().datetime().nullable(),
+  descriptionHtml: z.string().optional(),
+  checklist: z.array(ZChecklistItem).optional(),
   createdAt: z.string(),
   updatedAt: z.string(),
 });
@@ -38,6 +54,9 @@ export const ZTodoAttachment = z.object({
   downloadKey: z.string(),
   fileSize: z.number().nullable(),
   mimeType: z.string().nullable(),
+  checksumSha256: z.string().nullable(),
+  integrityStatus: z.enum(["unverified", "ok", "missing", "corrupt"]),
+  integrityCheckedAt: z.string().nullable(),
   createdAt: z.string(),
   updatedAt: z.string(),
 });
@@ -55,5 +74,26 @@ export const ZTodoStats = z.object({
   active: z.number(),
   completed: z.number(),
   archived: z.number(),
+  scheduled: z.number(),
   overdue: z.number(),
+});
+
+export const ZDependentTodo = ZTodo.extend({
+  blocked: z.boolean(),
+});
+
+export const ZTodoDependencies = z.object({
+  blocked: z.boolean(),
+  blockedBy: z.array(ZDependentTodo),
+  blocks: z.array(ZDependentTodo),
+});
+
+export const ZCopiedTodo = z.object({
+  todo: ZPopulatedTodo,
+  remappedUsers: z.array(z.string()),
+});
+
+export const ZSearchMeta = z.object({
+  engine: z.enum(["external", "postgres"]),
+  degraded: z.boolean(),
 });
\ No newline at end of file
@@ -8,6 +8,11 @@ export type PaginatedResponse<T> = {
   totalPages: number;
 };
 
+// Asks for markdown fields to be rendered to sanitized HTML as well
+export const ZRenderQuery = z.object({
+  render: z.enum(["html"]).optional(),
+});
+
 export const schemaWithPagination = <T>(
   schema: z.ZodSchema<T>
 ): z.ZodSchema<PaginatedResponse<T>> =>